@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // AgentDB manages the SQLite database for agents
@@ -38,7 +40,7 @@ func NewAgentDB(dbPath string) (*AgentDB, error) {
 	}
 
 	// Open database connection
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -47,7 +49,7 @@ func NewAgentDB(dbPath string) (*AgentDB, error) {
 
 	// Initialize database schema
 	if err := agentDB.initSchema(); err != nil {
-		db.Close()
+		sqlitedb.Close(db)
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
@@ -102,6 +104,34 @@ func (adb *AgentDB) initSchema() error {
 		return fmt.Errorf("failed to create metrics history table: %w", err)
 	}
 
+	// Create fact tables: the last known stable facts per agent (kept apart
+	// from agents.system_info, which is reset when an agent re-registers)
+	// and the history of changes between them
+	factsSchema := `
+	CREATE TABLE IF NOT EXISTS agent_facts (
+		agent_name TEXT PRIMARY KEY,
+		facts TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS agent_fact_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		agent_name TEXT NOT NULL,
+		fact TEXT NOT NULL,
+		change TEXT NOT NULL,
+		old_value TEXT DEFAULT '',
+		new_value TEXT DEFAULT '',
+		changed_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_fact_history_agent_time ON agent_fact_history(agent_name, changed_at DESC);
+	`
+
+	_, err = adb.db.Exec(factsSchema)
+	if err != nil {
+		return fmt.Errorf("failed to create fact history tables: %w", err)
+	}
+
 	// Migration: Add new columns if they don't exist (for existing databases)
 	migrations := []string{
 		`ALTER TABLE agents ADD COLUMN last_info_collected INTEGER DEFAULT 0`,
@@ -128,7 +158,7 @@ func (adb *AgentDB) RegisterAgent(name, address string) error {
 		COALESCE((SELECT registered_at FROM agents WHERE name = ?), ?), ?)
 	`
 
-	_, err := adb.db.Exec(query, name, address, now, name, now, now)
+	_, err := sqlitedb.Exec(context.Background(), adb.db, query, name, address, now, name, now, now)
 	if err != nil {
 		return fmt.Errorf("failed to register agent: %w", err)
 	}
@@ -138,10 +168,10 @@ func (adb *AgentDB) RegisterAgent(name, address string) error {
 
 // UpdateHeartbeat updates the last heartbeat timestamp for an agent
 func (adb *AgentDB) UpdateHeartbeat(name string) error {
-	query := `UPDATE agents SET last_heartbeat = ?, updated_at = ? WHERE name = ?`
+	query := `UPDATE agents SET status = 'Active', last_heartbeat = ?, updated_at = ? WHERE name = ?`
 
 	now := time.Now().Unix()
-	result, err := adb.db.Exec(query, now, now, name)
+	result, err := sqlitedb.Exec(context.Background(), adb.db, query, now, now, name)
 	if err != nil {
 		return fmt.Errorf("failed to update heartbeat: %w", err)
 	}
@@ -158,12 +188,34 @@ func (adb *AgentDB) UpdateHeartbeat(name string) error {
 	return nil
 }
 
+// MarkOffline marks an agent inactive until its next heartbeat, so tasks
+// aren't sent to it while it is known to be down
+func (adb *AgentDB) MarkOffline(name string) error {
+	query := `UPDATE agents SET status = 'Inactive', last_heartbeat = 0, updated_at = ? WHERE name = ?`
+
+	result, err := sqlitedb.Exec(context.Background(), adb.db, query, time.Now().Unix(), name)
+	if err != nil {
+		return fmt.Errorf("failed to mark agent offline: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("agent not found: %s", name)
+	}
+
+	return nil
+}
+
 // UpdateSystemInfo updates the system information for an agent
 func (adb *AgentDB) UpdateSystemInfo(name string, systemInfo string) error {
 	query := `UPDATE agents SET system_info = ?, last_info_collected = ?, updated_at = ? WHERE name = ?`
 
 	now := time.Now().Unix()
-	result, err := adb.db.Exec(query, systemInfo, now, now, name)
+	result, err := sqlitedb.Exec(context.Background(), adb.db, query, systemInfo, now, now, name)
 	if err != nil {
 		return fmt.Errorf("failed to update system info: %w", err)
 	}
@@ -185,7 +237,7 @@ func (adb *AgentDB) UpdateVersion(name string, version string) error {
 	query := `UPDATE agents SET version = ?, updated_at = ? WHERE name = ?`
 
 	now := time.Now().Unix()
-	result, err := adb.db.Exec(query, version, now, name)
+	result, err := sqlitedb.Exec(context.Background(), adb.db, query, version, now, name)
 	if err != nil {
 		return fmt.Errorf("failed to update version: %w", err)
 	}
@@ -349,7 +401,7 @@ func (adb *AgentDB) CleanupInactiveAgents(maxInactiveHours int) (int, error) {
 // Close closes the database connection
 func (adb *AgentDB) Close() error {
 	if adb.db != nil {
-		return adb.db.Close()
+		return sqlitedb.Close(adb.db)
 	}
 	return nil
 }
@@ -387,7 +439,7 @@ func (adb *AgentDB) SaveMetrics(agentName string, cpuPercent, memoryPercent, dis
 		(agent_name, timestamp, cpu_percent, memory_percent, disk_percent, load_avg_1min, load_avg_5min, load_avg_15min)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := adb.db.Exec(query, agentName, time.Now().Unix(), cpuPercent, memoryPercent, diskPercent, loadAvg1, loadAvg5, loadAvg15)
+	_, err := sqlitedb.Exec(context.Background(), adb.db, query, agentName, time.Now().Unix(), cpuPercent, memoryPercent, diskPercent, loadAvg1, loadAvg5, loadAvg15)
 	if err != nil {
 		return fmt.Errorf("failed to save metrics: %w", err)
 	}
@@ -451,3 +503,121 @@ func (adb *AgentDB) CleanupOldMetrics(daysToKeep int) (int, error) {
 
 	return int(rowsAffected), nil
 }
+
+// RecordFacts stores the latest facts of an agent and records the changes
+// against the previously stored facts. The first facts seen for an agent
+// only establish the baseline and produce no changes.
+func (adb *AgentDB) RecordFacts(agentName string, facts agentInternal.Facts) ([]agentInternal.FactChange, error) {
+	factsJSON, err := facts.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode facts: %w", err)
+	}
+
+	tx, err := adb.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousJSON string
+	err = tx.QueryRow(`SELECT facts FROM agent_facts WHERE agent_name = ?`, agentName).Scan(&previousJSON)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query facts: %w", err)
+	}
+	baseline := err == sql.ErrNoRows
+
+	var changes []agentInternal.FactChange
+	if !baseline {
+		previous, err := agentInternal.FactsFromJSON(previousJSON)
+		if err != nil {
+			// Unreadable previous facts: start over from a new baseline
+			previous = facts
+		}
+		changes = agentInternal.DiffFacts(previous, facts)
+	}
+
+	now := time.Now().Unix()
+	for _, c := range changes {
+		_, err := tx.Exec(`INSERT INTO agent_fact_history (agent_name, fact, change, old_value, new_value, changed_at)
+			VALUES (?, ?, ?, ?, ?, ?)`, agentName, c.Fact, c.Change, c.OldValue, c.NewValue, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record fact change: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`INSERT OR REPLACE INTO agent_facts (agent_name, facts, updated_at) VALUES (?, ?, ?)`,
+		agentName, factsJSON, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store facts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit facts: %w", err)
+	}
+
+	return changes, nil
+}
+
+// FactChangeRecord is a recorded change in an agent fact
+type FactChangeRecord struct {
+	AgentName string `json:"agent_name"`
+	Fact      string `json:"fact"`
+	Change    string `json:"change"`
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	ChangedAt int64  `json:"changed_at"`
+}
+
+// GetFactHistory returns the fact changes of an agent since the given unix
+// time, newest first. factPrefix optionally restricts the facts returned
+// and limit <= 0 returns all matching changes.
+func (adb *AgentDB) GetFactHistory(agentName string, since int64, factPrefix string, limit int) ([]*FactChangeRecord, error) {
+	query := `SELECT agent_name, fact, change, old_value, new_value, changed_at
+		FROM agent_fact_history
+		WHERE agent_name = ? AND changed_at >= ?`
+	args := []interface{}{agentName, since}
+
+	if factPrefix != "" {
+		query += ` AND substr(fact, 1, ?) = ?`
+		args = append(args, len(factPrefix), factPrefix)
+	}
+	query += ` ORDER BY changed_at DESC, id DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := adb.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fact history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*FactChangeRecord
+	for rows.Next() {
+		record := &FactChangeRecord{}
+		if err := rows.Scan(&record.AgentName, &record.Fact, &record.Change, &record.OldValue, &record.NewValue, &record.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan fact history row: %w", err)
+		}
+		history = append(history, record)
+	}
+
+	return history, rows.Err()
+}
+
+// CleanupOldFactHistory removes fact changes older than specified days
+func (adb *AgentDB) CleanupOldFactHistory(daysToKeep int) (int, error) {
+	cutoff := time.Now().Unix() - int64(daysToKeep*24*3600)
+
+	result, err := adb.db.Exec(`DELETE FROM agent_fact_history WHERE changed_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup old fact history: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
 )
 
 func setupTestDB(t *testing.T) (*AgentDB, string) {
@@ -492,3 +494,82 @@ func TestConcurrentOperations(t *testing.T) {
 		<-done
 	}
 }
+
+func TestRecordFacts(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	// First facts only establish the baseline
+	changes, err := db.RecordFacts("agent1", agentInternal.Facts{"kernel_version": "6.1.0", "cpus": "4"})
+	if err != nil {
+		t.Fatalf("RecordFacts failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Expected no changes for baseline, got %v", changes)
+	}
+
+	changes, err = db.RecordFacts("agent1", agentInternal.Facts{"kernel_version": "6.8.0", "disk./data.device": "/dev/sdb1"})
+	if err != nil {
+		t.Fatalf("RecordFacts failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %v", changes)
+	}
+
+	history, err := db.GetFactHistory("agent1", 0, "", 0)
+	if err != nil {
+		t.Fatalf("GetFactHistory failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 history entries, got %d", len(history))
+	}
+
+	history, err = db.GetFactHistory("agent1", 0, "kernel", 0)
+	if err != nil {
+		t.Fatalf("GetFactHistory failed: %v", err)
+	}
+	if len(history) != 1 || history[0].OldValue != "6.1.0" || history[0].NewValue != "6.8.0" || history[0].Change != agentInternal.FactChanged {
+		t.Errorf("Unexpected kernel history: %+v", history)
+	}
+
+	history, err = db.GetFactHistory("agent1", time.Now().Add(time.Hour).Unix(), "", 0)
+	if err != nil {
+		t.Fatalf("GetFactHistory failed: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("Expected no history after since, got %d", len(history))
+	}
+
+	// Re-registering resets system_info but not the fact baseline
+	if err := db.RegisterAgent("agent1", "localhost:50051"); err != nil {
+		t.Fatalf("RegisterAgent failed: %v", err)
+	}
+	changes, err = db.RecordFacts("agent1", agentInternal.Facts{"kernel_version": "6.8.0", "disk./data.device": "/dev/sdb1"})
+	if err != nil {
+		t.Fatalf("RecordFacts failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes after re-registration, got %v", changes)
+	}
+}
+
+func TestCleanupOldFactHistory(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	db.RecordFacts("agent1", agentInternal.Facts{"cpus": "2"})
+	db.RecordFacts("agent1", agentInternal.Facts{"cpus": "4"})
+
+	old := time.Now().Add(-100 * 24 * time.Hour).Unix()
+	if _, err := db.db.Exec(`UPDATE agent_fact_history SET changed_at = ?`, old); err != nil {
+		t.Fatalf("Failed to age history: %v", err)
+	}
+
+	removed, err := db.CleanupOldFactHistory(90)
+	if err != nil {
+		t.Fatalf("CleanupOldFactHistory failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 removed entry, got %d", removed)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListAgentGroups returns the agent groups managed with the group command,
+// which the web UI keeps in the agent database. No groups are returned
+// when it never created its tables.
+func (adb *AgentDB) ListAgentGroups() ([]*pb.AgentGroup, error) {
+	rows, err := adb.db.Query(`SELECT g.name, COALESCE(g.description, ''), g.created_at, COALESCE(m.agent_name, '')
+		FROM agent_groups g LEFT JOIN agent_group_members m ON m.group_id = g.id
+		ORDER BY g.name, m.agent_name`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query agent groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*pb.AgentGroup
+	for rows.Next() {
+		var name, description, agent string
+		var createdAt int64
+		if err := rows.Scan(&name, &description, &createdAt, &agent); err != nil {
+			return nil, fmt.Errorf("failed to scan agent group row: %w", err)
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Name != name {
+			groups = append(groups, &pb.AgentGroup{Name: name, Description: description, CreatedAt: createdAt})
+		}
+		if agent != "" {
+			group := groups[len(groups)-1]
+			group.AgentNames = append(group.AgentNames, agent)
+			group.AgentCount++
+		}
+	}
+	return groups, rows.Err()
+}
+
+// ListAgentGroups lets runs resolve delegate_to = "group:<name>" to the
+// members of the group.
+func (s *agentRegistryServer) ListAgentGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return nil, status.Error(codes.Unavailable, "agent database not available")
+	}
+	groups, err := s.db.ListAgentGroups()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.ListGroupsResponse{Groups: groups}, nil
+}
+
+// AgentGroups returns the agent groups (for taskrunner, to run tasks
+// delegated to a group on each of its agents)
+func (s *agentRegistryServer) AgentGroups() ([]*pb.AgentGroup, error) {
+	resp, err := s.ListAgentGroups(context.Background(), &pb.ListGroupsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetGroups(), nil
+}
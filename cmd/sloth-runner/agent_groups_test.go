@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+func TestAgentRegistryListAgentGroups(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	server := &agentRegistryServer{db: db}
+
+	// The web UI hasn't created the group tables yet
+	resp, err := server.ListAgentGroups(context.Background(), &pb.ListGroupsRequest{})
+	if err != nil {
+		t.Fatalf("ListAgentGroups failed: %v", err)
+	}
+	if len(resp.Groups) != 0 {
+		t.Errorf("Expected no groups, got %v", resp.Groups)
+	}
+
+	// Tables as the web UI creates them for the group command
+	_, err = db.db.Exec(`
+	CREATE TABLE agent_groups (id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, description TEXT, tags TEXT, created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL);
+	CREATE TABLE agent_group_members (group_id TEXT NOT NULL, agent_name TEXT NOT NULL, added_at INTEGER NOT NULL, PRIMARY KEY (group_id, agent_name));
+	INSERT INTO agent_groups VALUES ('g1', 'webservers', 'Web servers', '{}', 1, 1), ('g2', 'empty', NULL, '{}', 2, 2);
+	INSERT INTO agent_group_members VALUES ('g1', 'web-02', 1), ('g1', 'web-01', 1);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := server.AgentGroups()
+	if err != nil {
+		t.Fatalf("AgentGroups failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", groups)
+	}
+	if groups[0].Name != "empty" || groups[0].AgentCount != 0 {
+		t.Errorf("Unexpected group %v", groups[0])
+	}
+	web := groups[1]
+	if web.Name != "webservers" || web.Description != "Web servers" || web.AgentCount != 2 ||
+		len(web.AgentNames) != 2 || web.AgentNames[0] != "web-01" || web.AgentNames[1] != "web-02" {
+		t.Errorf("Unexpected group %v", web)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RebootAgent asks an agent to reboot its host and marks it offline, so
+// no tasks are sent to it until it is back and sends a heartbeat.
+func (s *agentRegistryServer) RebootAgent(ctx context.Context, req *pb.RebootAgentRequest) (*pb.RebootAgentResponse, error) {
+	if s.db == nil {
+		return nil, status.Error(codes.Unavailable, "database not available")
+	}
+
+	s.mu.RLock()
+	agentAddress, err := s.db.GetAgentAddress(req.AgentName)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "agent not found or inactive: %s", req.AgentName)
+	}
+
+	conn, err := grpc.Dial(agentAddress, reliability.AgentDialOptions()...)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to connect to agent: %v", err)
+	}
+	defer conn.Close()
+
+	// The agent's status says why it refused, e.g. tasks still running
+	if _, err := pb.NewAgentClient(conn).Reboot(ctx, &pb.RebootRequest{
+		Drain:               req.Drain,
+		DrainTimeoutSeconds: req.DrainTimeoutSeconds,
+	}); err != nil {
+		return nil, err
+	}
+
+	pterm.Info.Printf("Agent %s is rebooting\n", req.AgentName)
+	if err := s.db.MarkOffline(req.AgentName); err != nil {
+		pterm.Warning.Printf("Failed to mark agent %s offline: %v\n", req.AgentName, err)
+	}
+	return &pb.RebootAgentResponse{AgentAddress: agentAddress}, nil
+}
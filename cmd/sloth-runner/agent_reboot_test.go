@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRebootAgent records reboot requests, refusing them while busy
+type fakeRebootAgent struct {
+	pb.UnimplementedAgentServer
+	busy     bool
+	requests []*pb.RebootRequest
+}
+
+func (a *fakeRebootAgent) Reboot(ctx context.Context, in *pb.RebootRequest) (*pb.RebootResponse, error) {
+	a.requests = append(a.requests, in)
+	if a.busy {
+		return nil, status.Error(codes.FailedPrecondition, "1 task requests still running after 1s, not rebooting")
+	}
+	return &pb.RebootResponse{}, nil
+}
+
+func startFakeRebootAgent(t *testing.T, agent *fakeRebootAgent) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterAgentServer(server, agent)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestAgentRegistryRebootAgent(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	agent := &fakeRebootAgent{}
+	addr := startFakeRebootAgent(t, agent)
+	if err := db.RegisterAgent("web-01", addr); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &agentRegistryServer{db: db}
+	ctx := context.Background()
+	resp, err := server.RebootAgent(ctx, &pb.RebootAgentRequest{AgentName: "web-01", Drain: true, DrainTimeoutSeconds: 60})
+	if err != nil {
+		t.Fatalf("RebootAgent failed: %v", err)
+	}
+	if resp.AgentAddress != addr {
+		t.Errorf("Expected address %s, got %s", addr, resp.AgentAddress)
+	}
+	if len(agent.requests) != 1 || !agent.requests[0].Drain || agent.requests[0].DrainTimeoutSeconds != 60 {
+		t.Errorf("Expected a drained reboot request, got %v", agent.requests)
+	}
+
+	// The agent is offline until its next heartbeat
+	record, err := db.GetAgent("web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != "Inactive" || record.LastHeartbeat != 0 {
+		t.Errorf("Expected the agent to be marked offline, got %s with heartbeat %d", record.Status, record.LastHeartbeat)
+	}
+	if _, err := server.RebootAgent(ctx, &pb.RebootAgentRequest{AgentName: "web-01"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected an offline agent not to be rebooted again, got %v", err)
+	}
+
+	if err := db.UpdateHeartbeat("web-01"); err != nil {
+		t.Fatal(err)
+	}
+	if record, _ := db.GetAgent("web-01"); record.Status != "Active" {
+		t.Errorf("Expected a heartbeat to mark the agent active, got %s", record.Status)
+	}
+}
+
+func TestAgentRegistryRebootAgent_Refused(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	addr := startFakeRebootAgent(t, &fakeRebootAgent{busy: true})
+	if err := db.RegisterAgent("web-01", addr); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &agentRegistryServer{db: db}
+	_, err := server.RebootAgent(context.Background(), &pb.RebootAgentRequest{AgentName: "web-01", Drain: true})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected the agent's refusal, got %v", err)
+	}
+	if record, _ := db.GetAgent("web-01"); record.Status != "Active" {
+		t.Errorf("Expected a refused reboot to leave the agent active, got %s", record.Status)
+	}
+}
@@ -11,16 +11,25 @@ import (
 	"sync"
 	"time"
 
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
 	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/jointoken"
 	"github.com/chalkan3-sloth/sloth-runner/internal/metrics"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
 	"github.com/chalkan3-sloth/sloth-runner/internal/webui/services"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// factHistoryRetentionDays is how long agent fact changes are kept.
+const factHistoryRetentionDays = 90
+
 // agentRegistryServer implements the AgentRegistry service.
 type agentRegistryServer struct {
 	pb.UnimplementedAgentRegistryServer
@@ -30,6 +39,13 @@ type agentRegistryServer struct {
 	dispatcher       *hooks.Dispatcher
 	metricsDB        *metrics.MetricsDB
 	metricsCollector *metrics.Collector
+	artifacts        *artifactIndex
+	joinTokens       *jointoken.Store
+	requireJoinToken bool
+	locks            *lockTable
+	releases         *releases.Index
+	drain            drainGate
+	restart          bool
 }
 
 // newAgentRegistryServer creates a new agentRegistryServer.
@@ -48,6 +64,11 @@ func newAgentRegistryServer() *agentRegistryServer {
 		if removed, err := db.CleanupInactiveAgents(24); err == nil && removed > 0 {
 			pterm.Info.Printf("Cleaned up %d inactive agents\n", removed)
 		}
+
+		// Drop fact changes past the retention period
+		if removed, err := db.CleanupOldFactHistory(factHistoryRetentionDays); err == nil && removed > 0 {
+			pterm.Info.Printf("Cleaned up %d old fact changes\n", removed)
+		}
 	}
 
 	// Initialize global hook dispatcher and wire up event system
@@ -110,12 +131,40 @@ func newAgentRegistryServer() *agentRegistryServer {
 		}
 	}
 
-	return &agentRegistryServer{
+	// Open the join token store used by `agent install-command`
+	joinTokens, err := jointoken.DefaultStore()
+	if err != nil {
+		pterm.Error.Printf("Failed to open join token store: %v\n", err)
+		pterm.Info.Println("Agents presenting a join token will be rejected")
+		joinTokens = nil
+	} else if removed, err := joinTokens.Prune(); err == nil && removed > 0 {
+		pterm.Info.Printf("Removed %d expired join tokens\n", removed)
+	}
+
+	server := &agentRegistryServer{
 		db:               db,
 		dispatcher:       dispatcher,
 		metricsDB:        metricsDB,
 		metricsCollector: metricsCollector,
+		artifacts:        newArtifactIndex(),
+		joinTokens:       joinTokens,
+		requireJoinToken: jointoken.Required(),
+		locks:            newLockTable(),
+		releases:         releases.Default(),
 	}
+
+	// Event triggers start runs, so they go through the drain gate too
+	if dispatcher != nil {
+		triggers, err := hooks.LoadTriggers(config.GetTriggersPath(), server.drain.gateTrigger(hooks.RunTriggerWorkflow))
+		if err != nil {
+			pterm.Error.Printf("Failed to load event triggers: %v\n", err)
+		} else if triggers != nil {
+			dispatcher.SetTriggers(triggers)
+			pterm.Success.Printf("Loaded %d event triggers from %s\n", len(triggers.Status()), config.GetTriggersPath())
+		}
+	}
+
+	return server
 }
 
 // RegisterAgent registers a new agent.
@@ -123,13 +172,29 @@ func (s *agentRegistryServer) RegisterAgent(ctx context.Context, req *pb.Registe
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Agents bootstrapped with a join token redeem it once and register
+	// with the credential they got from then on; with --require-join-token
+	// every agent must present one or the other
+	var credential string
+	var err error
+	switch {
+	case req.AgentCredential != "":
+		err = s.authenticateAgent(req.AgentCredential, req.AgentName)
+	case req.JoinToken != "" || s.requireJoinToken:
+		credential, err = s.redeemJoinToken(req.JoinToken, req.AgentName)
+	}
+	if err != nil {
+		pterm.Warning.Printf("Rejected registration of agent %s: %v\n", req.AgentName, err)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
 	pterm.Success.Printf("Agent registered: %s at %s\n", req.AgentName, req.AgentAddress)
 
 	// Save to SQLite database
 	if s.db != nil {
 		if err := s.db.RegisterAgent(req.AgentName, req.AgentAddress); err != nil {
 			pterm.Error.Printf("Failed to save agent to database: %v\n", err)
-			return &pb.RegisterAgentResponse{Success: false, Message: fmt.Sprintf("Failed to save agent: %v", err)}, nil
+			return &pb.RegisterAgentResponse{Success: false, Message: fmt.Sprintf("Failed to save agent: %v", err), AgentCredential: credential}, nil
 		}
 		pterm.Debug.Printf("Agent %s saved to database\n", req.AgentName)
 	}
@@ -145,39 +210,72 @@ func (s *agentRegistryServer) RegisterAgent(ctx context.Context, req *pb.Registe
 		}
 	}
 
-	return &pb.RegisterAgentResponse{Success: true, Message: "Agent registered successfully"}, nil
+	return &pb.RegisterAgentResponse{Success: true, Message: "Agent registered successfully", AgentCredential: credential}, nil
+}
+
+// redeemJoinToken checks the join token an agent registers with the first
+// time and returns the credential it registers with afterwards
+func (s *agentRegistryServer) redeemJoinToken(token, agentName string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("a join token is required to register with this master; create one with 'sloth-runner agent install-command'")
+	}
+	if s.joinTokens == nil {
+		return "", fmt.Errorf("join tokens are not available on this master")
+	}
+	t, credential, err := s.joinTokens.Redeem(token, agentName)
+	if err != nil {
+		return "", err
+	}
+	slog.Info("Agent joined with token", "agent", agentName, "token", t.ID)
+	return credential, nil
+}
+
+// authenticateAgent checks the credential an agent re-registers with
+func (s *agentRegistryServer) authenticateAgent(credential, agentName string) error {
+	if s.joinTokens == nil {
+		return fmt.Errorf("agent credentials are not available on this master")
+	}
+	return s.joinTokens.Authenticate(credential, agentName)
 }
 
 // ListAgents lists all registered agents.
 func (s *agentRegistryServer) ListAgents(ctx context.Context, req *pb.ListAgentsRequest) (*pb.ListAgentsResponse, error) {
 	pterm.Info.Println("Listing registered agents")
+	agents, err := s.AgentInfos()
+	if err != nil {
+		pterm.Error.Printf("Failed to list agents from database: %v\n", err)
+	}
+	return &pb.ListAgentsResponse{Agents: agents}, nil
+}
+
+// AgentInfos returns the registered agents (for taskrunner, to pick agents
+// by their facts)
+func (s *agentRegistryServer) AgentInfos() ([]*pb.AgentInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var agents []*pb.AgentInfo
-
-	if s.db != nil {
-		// Get agents from SQLite database
-		dbAgents, err := s.db.ListAgents()
-		if err != nil {
-			pterm.Error.Printf("Failed to list agents from database: %v\n", err)
-			return &pb.ListAgentsResponse{Agents: agents}, nil
-		}
-
-		for _, agent := range dbAgents {
-			agents = append(agents, &pb.AgentInfo{
-				AgentName:         agent.Name,
-				AgentAddress:      agent.Address,
-				LastHeartbeat:     agent.LastHeartbeat,
-				Status:            agent.Status,
-				LastInfoCollected: agent.LastInfoCollected,
-				SystemInfoJson:    agent.SystemInfo,
-				Version:           agent.Version,
-			})
-		}
+	if s.db == nil {
+		return agents, nil
 	}
 
-	return &pb.ListAgentsResponse{Agents: agents}, nil
+	dbAgents, err := s.db.ListAgents()
+	if err != nil {
+		return nil, err
+	}
+	for _, agent := range dbAgents {
+		agents = append(agents, &pb.AgentInfo{
+			AgentName:         agent.Name,
+			AgentAddress:      agent.Address,
+			LastHeartbeat:     agent.LastHeartbeat,
+			Status:            agent.Status,
+			LastInfoCollected: agent.LastInfoCollected,
+			SystemInfoJson:    agent.SystemInfo,
+			Version:           agent.Version,
+			CircuitState:      reliability.DefaultAgentRPC().State(agent.Address).String(),
+		})
+	}
+	return agents, nil
 }
 
 // Heartbeat updates the last heartbeat timestamp for an agent.
@@ -196,6 +294,8 @@ func (s *agentRegistryServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRe
 		if req.SystemInfoJson != "" {
 			if err := s.db.UpdateSystemInfo(req.AgentName, req.SystemInfoJson); err != nil {
 				pterm.Debug.Printf("Failed to update system info for agent %s: %v\n", req.AgentName, err)
+			} else {
+				s.recordFacts(req.AgentName, req.SystemInfoJson)
 			}
 		}
 
@@ -213,8 +313,83 @@ func (s *agentRegistryServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRe
 	return &pb.HeartbeatResponse{Success: false, Message: "Database not available"}, nil
 }
 
+// recordFacts records changes in the agent's stable facts and dispatches
+// an agent.facts_changed event when any are found. Callers hold s.mu.
+func (s *agentRegistryServer) recordFacts(agentName, systemInfoJSON string) {
+	info, err := agentInternal.FromJSON(systemInfoJSON)
+	if err != nil {
+		pterm.Debug.Printf("Failed to parse system info for agent %s: %v\n", agentName, err)
+		return
+	}
+
+	changes, err := s.db.RecordFacts(agentName, agentInternal.StableFacts(info))
+	if err != nil {
+		pterm.Debug.Printf("Failed to record facts for agent %s: %v\n", agentName, err)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	slog.Info("Agent facts changed", "agent", agentName, "changes", len(changes))
+
+	if s.dispatcher != nil {
+		address, _ := s.db.GetAgentAddress(agentName)
+		payload := make([]map[string]interface{}, len(changes))
+		for i, c := range changes {
+			payload[i] = map[string]interface{}{
+				"fact":      c.Fact,
+				"change":    c.Change,
+				"old_value": c.OldValue,
+				"new_value": c.NewValue,
+			}
+		}
+		agent := &hooks.AgentEvent{Name: agentName, Address: address}
+		if err := s.dispatcher.DispatchAgentFactsChanged(agent, payload); err != nil {
+			pterm.Debug.Printf("Failed to dispatch agent facts changed event: %v\n", err)
+		}
+	}
+}
+
+// GetFactHistory returns the recorded fact changes of an agent.
+func (s *agentRegistryServer) GetFactHistory(ctx context.Context, req *pb.FactHistoryRequest) (*pb.FactHistoryResponse, error) {
+	if req.AgentName == "" {
+		return nil, fmt.Errorf("agent name is required")
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database not available")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records, err := s.db.GetFactHistory(req.AgentName, req.Since, req.Fact, int(req.Limit))
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]*pb.FactChange, 0, len(records))
+	for _, r := range records {
+		changes = append(changes, &pb.FactChange{
+			AgentName: r.AgentName,
+			Fact:      r.Fact,
+			Change:    r.Change,
+			OldValue:  r.OldValue,
+			NewValue:  r.NewValue,
+			ChangedAt: r.ChangedAt,
+		})
+	}
+
+	return &pb.FactHistoryResponse{Changes: changes}, nil
+}
+
 // ExecuteCommand executes a command on a remote agent and streams the output back to the client.
 func (s *agentRegistryServer) ExecuteCommand(req *pb.ExecuteCommandRequest, stream pb.AgentRegistry_ExecuteCommandServer) error {
+	if !s.drain.enter() {
+		return errMasterDraining
+	}
+	defer s.drain.leave()
+
 	s.mu.RLock()
 	var agentAddress string
 	var err error
@@ -231,7 +406,7 @@ func (s *agentRegistryServer) ExecuteCommand(req *pb.ExecuteCommandRequest, stre
 	}
 	s.mu.RUnlock()
 
-	conn, err := grpc.Dial(agentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(agentAddress, reliability.AgentDialOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to connect to agent: %v", err)
 	}
@@ -283,7 +458,7 @@ func (s *agentRegistryServer) StopAgent(ctx context.Context, req *pb.StopAgentRe
 	}
 	s.mu.RUnlock()
 
-	conn, err := grpc.Dial(agentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(agentAddress, reliability.AgentDialOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to agent: %v", err)
 	}
@@ -322,6 +497,11 @@ func (s *agentRegistryServer) UnregisterAgent(ctx context.Context, req *pb.Unreg
 		return &pb.UnregisterAgentResponse{Success: false, Message: fmt.Sprintf("Failed to unregister agent: %v", err)}, nil
 	}
 
+	// Forget any cached artifacts the agent announced
+	if s.artifacts != nil {
+		s.artifacts.removeAgent(req.AgentName)
+	}
+
 	// Dispatch agent disconnected event
 	if s.dispatcher != nil && agentInfo != nil {
 		agent := &hooks.AgentEvent{
@@ -364,6 +544,7 @@ func (s *agentRegistryServer) GetAgentInfo(ctx context.Context, req *pb.GetAgent
 			LastInfoCollected: agent.LastInfoCollected,
 			SystemInfoJson:    agent.SystemInfo,
 			Version:           agent.Version,
+			CircuitState:      reliability.DefaultAgentRPC().State(agent.Address).String(),
 		},
 	}, nil
 }
@@ -389,6 +570,10 @@ func (s *agentRegistryServer) SendEvent(ctx context.Context, req *pb.SendEventRe
 		}, nil
 	}
 
+	if s.drain.Draining() {
+		return nil, errMasterDraining
+	}
+
 	// Check if dispatcher is available
 	if s.dispatcher == nil {
 		slog.Warn("Event dispatcher not available, event will be dropped",
@@ -468,6 +653,10 @@ func (s *agentRegistryServer) SendEventBatch(ctx context.Context, req *pb.SendEv
 		}, nil
 	}
 
+	if s.drain.Draining() {
+		return nil, errMasterDraining
+	}
+
 	// Check if dispatcher is available
 	if s.dispatcher == nil {
 		slog.Warn("Event dispatcher not available, batch events will be dropped",
@@ -558,9 +747,13 @@ func (s *agentRegistryServer) Start(port int) error {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	pterm.Warning.Println("Starting master in insecure mode.")
+	if mtls.Enabled() {
+		pterm.Info.Println("Starting master with mTLS.")
+	} else {
+		pterm.Warning.Println("Starting master in insecure mode. Use --tls-cert, --tls-key and --tls-ca to require mTLS.")
+	}
 
-	s.grpcServer = grpc.NewServer()
+	s.grpcServer = grpc.NewServer(mtls.ServerOptions()...)
 	pb.RegisterAgentRegistryServer(s.grpcServer, s)
 	pterm.Info.Printf("Agent registry listening at %v\n", lis.Addr())
 	return s.grpcServer.Serve(lis)
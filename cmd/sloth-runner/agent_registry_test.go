@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/jointoken"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewAgentRegistryServer(t *testing.T) {
@@ -207,6 +211,84 @@ func TestRegisterAgentWithoutDB(t *testing.T) {
 	}
 }
 
+func TestRegisterAgentWithJoinToken(t *testing.T) {
+	store, err := jointoken.NewStore(filepath.Join(t.TempDir(), "join_tokens.db"))
+	if err != nil {
+		t.Fatalf("Failed to create join token store: %v", err)
+	}
+	defer store.Close()
+
+	token, _, err := store.Create("web-01", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create join token: %v", err)
+	}
+
+	server := &agentRegistryServer{joinTokens: store}
+	ctx := context.Background()
+
+	for _, req := range []*pb.RegisterAgentRequest{
+		{AgentName: "web-02", AgentAddress: "10.0.0.8:50051", JoinToken: token},
+		{AgentName: "web-01", AgentAddress: "10.0.0.7:50051", JoinToken: token + "x"},
+	} {
+		_, err := server.RegisterAgent(ctx, req)
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Expected PermissionDenied for %s, got %v", req.AgentName, err)
+		}
+	}
+
+	resp, err := server.RegisterAgent(ctx, &pb.RegisterAgentRequest{
+		AgentName: "web-01", AgentAddress: "10.0.0.7:50051", JoinToken: token,
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("RegisterAgent with join token failed: %v", err)
+	}
+	credential := resp.AgentCredential
+	if !strings.HasPrefix(credential, jointoken.CredentialPrefix) {
+		t.Fatalf("Expected a credential for the redeemed token, got %q", credential)
+	}
+
+	// The token can't be used again; the agent re-registers after
+	// restarts with its credential
+	_, err = server.RegisterAgent(ctx, &pb.RegisterAgentRequest{AgentName: "web-01", AgentAddress: "10.0.0.7:50051", JoinToken: token})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a used join token, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		resp, err := server.RegisterAgent(ctx, &pb.RegisterAgentRequest{
+			AgentName: "web-01", AgentAddress: "10.0.0.7:50051", AgentCredential: credential,
+		})
+		if err != nil || !resp.Success || resp.AgentCredential != "" {
+			t.Fatalf("RegisterAgent with credential failed: %v %v", resp, err)
+		}
+	}
+	_, err = server.RegisterAgent(ctx, &pb.RegisterAgentRequest{AgentName: "web-02", AgentAddress: "10.0.0.8:50051", AgentCredential: credential})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for the credential of another agent, got %v", err)
+	}
+
+	// Agents without a token register unless the master requires one
+	resp, err = server.RegisterAgent(ctx, &pb.RegisterAgentRequest{AgentName: "web-03", AgentAddress: "10.0.0.9:50051"})
+	if err != nil || !resp.Success {
+		t.Fatalf("RegisterAgent without join token failed: %v", err)
+	}
+	server.requireJoinToken = true
+	_, err = server.RegisterAgent(ctx, &pb.RegisterAgentRequest{AgentName: "web-03", AgentAddress: "10.0.0.9:50051"})
+	if status.Code(err) != codes.PermissionDenied || !strings.Contains(err.Error(), "join token is required") {
+		t.Errorf("Expected PermissionDenied for a missing join token, got %v", err)
+	}
+	resp, err = server.RegisterAgent(ctx, &pb.RegisterAgentRequest{AgentName: "web-01", AgentAddress: "10.0.0.7:50051", AgentCredential: credential})
+	if err != nil || !resp.Success {
+		t.Fatalf("RegisterAgent with credential failed while required: %v", err)
+	}
+
+	// Without a token store, token registrations are rejected
+	server = &agentRegistryServer{}
+	_, err = server.RegisterAgent(ctx, &pb.RegisterAgentRequest{AgentName: "web-01", JoinToken: token})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied without a token store, got %v", err)
+	}
+}
+
 func TestListAgentsWithoutDB(t *testing.T) {
 	server := &agentRegistryServer{
 		db: nil, // No database
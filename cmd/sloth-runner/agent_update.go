@@ -4,7 +4,6 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,28 +14,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
-	githubAPIURL = "https://api.github.com/repos/chalkan3-sloth/sloth-runner/releases/latest"
 	githubReleaseURL = "https://github.com/chalkan3-sloth/sloth-runner/releases/download"
 )
 
-// Release represents a GitHub release
-type Release struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
-}
-
 var agentUpdateCmd = &cobra.Command{
 	Use:   "update [agent-name]",
 	Short: "Update the sloth-runner agent to the latest version",
@@ -82,25 +71,10 @@ If an agent name is provided, it will execute the update remotely on the agent h
 		spinner.UpdateText(fmt.Sprintf("New version available: %s → %s", currentVersion, latestVersion))
 
 		// Determine the asset to download based on OS and architecture
-		osName := runtime.GOOS
-		archName := runtime.GOARCH
-
-		var downloadURL string
-		var assetName string
-		for _, asset := range release.Assets {
-			// Match pattern: sloth-runner_v{VERSION}_{OS}_{ARCH}.tar.gz
-			if strings.Contains(asset.Name, osName) &&
-			   strings.Contains(asset.Name, archName) &&
-			   strings.HasSuffix(asset.Name, ".tar.gz") {
-				downloadURL = asset.BrowserDownloadURL
-				assetName = asset.Name
-				break
-			}
-		}
-
+		downloadURL, assetName := findReleaseAsset(release)
 		if downloadURL == "" {
-			spinner.Fail(fmt.Sprintf("No suitable release found for %s/%s", osName, archName))
-			return fmt.Errorf("no suitable release found for %s/%s", osName, archName)
+			spinner.Fail(fmt.Sprintf("No suitable release found for %s/%s", runtime.GOOS, runtime.GOARCH))
+			return fmt.Errorf("no suitable release found for %s/%s", runtime.GOOS, runtime.GOARCH)
 		}
 
 		// Download the new binary
@@ -187,39 +161,42 @@ If an agent name is provided, it will execute the update remotely on the agent h
 	},
 }
 
-// fetchLatestRelease fetches the latest release information from GitHub
-func fetchLatestRelease(version string) (*Release, error) {
-	var url string
-	if version != "" && version != "latest" {
-		url = fmt.Sprintf("https://api.github.com/repos/chalkan3-sloth/sloth-runner/releases/tags/%s", version)
-	} else {
-		url = githubAPIURL
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// fetchLatestRelease fetches a release, the latest if version is empty,
+// through the release index, which caches lookups and can be pointed at a
+// GitHub Enterprise Server instance
+func fetchLatestRelease(version string) (*releases.Release, error) {
+	return releases.Default().Release(context.Background(), version)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+// findReleaseAsset returns the download URL and name of the release
+// archive for the current OS and architecture, or "" if there is none
+func findReleaseAsset(release *releases.Release) (string, string) {
+	if asset := release.Archive(runtime.GOOS, runtime.GOARCH); asset != nil {
+		return asset.URL, asset.Name
 	}
+	return "", ""
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// downloadReleaseBinary downloads the sloth-runner binary of a release and
+// returns the path of the extracted binary
+func downloadReleaseBinary(version string) (string, error) {
+	release, err := fetchLatestRelease(version)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to fetch release info: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch release info: %s", resp.Status)
+	downloadURL, _ := findReleaseAsset(release)
+	if downloadURL == "" {
+		return "", fmt.Errorf("no suitable release found for %s/%s", runtime.GOOS, runtime.GOARCH)
 	}
 
-	var release Release
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, err
+	archive, err := downloadFile(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", release.TagName, err)
 	}
+	defer os.Remove(archive)
 
-	return &release, nil
+	return extractBinary(archive)
 }
 
 // getAssetName returns the appropriate asset name for the current OS and architecture
@@ -379,7 +356,7 @@ func updateRemoteAgent(agentName, targetVersion string, force, skipRestart bool)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, agentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.DialContext(ctx, agentAddress, mtls.DialOption())
 	if err != nil {
 		spinner.Fail(fmt.Sprintf("Failed to connect to agent: %v", err))
 		return err
@@ -425,7 +402,7 @@ func getAgentAddress(agentName string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, masterAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.DialContext(ctx, masterAddr, mtls.DialOption())
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to master: %w", err)
 	}
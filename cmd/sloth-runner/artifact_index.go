@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/download"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"google.golang.org/protobuf/proto"
+)
+
+// artifactIndexTTL bounds how long an announcement is trusted without a refresh
+const artifactIndexTTL = 24 * time.Hour
+
+// artifactVerifyTimeout bounds the master's own download of an artifact
+const artifactVerifyTimeout = 30 * time.Minute
+
+// artifactChecksum downloads url and returns its SHA-256 checksum. It is
+// replaced in tests.
+var artifactChecksum = func(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := download.Default().Do(&http.Client{}, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// artifactIndex tracks which agents hold which cached artifacts.
+// It is kept in memory; agents re-announce their caches when they reconnect.
+// The checksums agents announce aren't trusted: the master downloads each
+// artifact once itself, and only hands out peers whose announcement
+// matches what it got, with its own checksum for agents to check the bytes
+// against.
+type artifactIndex struct {
+	mu        sync.RWMutex
+	entries   map[string]map[string]*pb.ArtifactPeer // key -> agent name -> peer
+	verified  map[string]string                      // key -> checksum the master computed
+	verifying map[string]bool
+	pending   sync.WaitGroup
+}
+
+func newArtifactIndex() *artifactIndex {
+	return &artifactIndex{
+		entries:   make(map[string]map[string]*pb.ArtifactPeer),
+		verified:  make(map[string]string),
+		verifying: make(map[string]bool),
+	}
+}
+
+func (i *artifactIndex) announce(req *pb.AnnounceArtifactRequest) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	peers, ok := i.entries[req.Key]
+	if !ok {
+		peers = make(map[string]*pb.ArtifactPeer)
+		i.entries[req.Key] = peers
+	}
+	peers[req.AgentName] = &pb.ArtifactPeer{
+		AgentName:    req.AgentName,
+		CacheAddress: req.CacheAddress,
+		Size:         req.Size,
+		Sha256:       req.Sha256,
+		AnnouncedAt:  time.Now().Unix(),
+	}
+
+	if _, ok := i.verified[req.Key]; !ok && !i.verifying[req.Key] {
+		i.verifying[req.Key] = true
+		i.pending.Add(1)
+		go i.verify(req.Key, req.Url)
+	}
+}
+
+// verify records the checksum of the artifact at url, as the master
+// downloads it. A failure leaves the artifact unverified until the next
+// announcement tries again.
+func (i *artifactIndex) verify(key, url string) {
+	defer i.pending.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), artifactVerifyTimeout)
+	defer cancel()
+	sum, err := artifactChecksum(ctx, url)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.verifying, key)
+	if err != nil {
+		slog.Warn("Failed to verify announced artifact", "url", url, "error", err)
+		return
+	}
+	i.verified[key] = sum
+	for name, peer := range i.entries[key] {
+		if !strings.EqualFold(peer.Sha256, sum) {
+			slog.Warn("Agent announced an artifact with a wrong checksum", "agent", name, "url", url, "announced", peer.Sha256, "verified", sum)
+		}
+	}
+}
+
+// lookup returns fresh peers for key whose announcement matches the
+// verified checksum, most recently announced first. There are none until
+// the master verified the artifact.
+func (i *artifactIndex) lookup(key, exclude string) []*pb.ArtifactPeer {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	sum, ok := i.verified[key]
+	if !ok {
+		return nil
+	}
+	cutoff := time.Now().Add(-artifactIndexTTL).Unix()
+	var peers []*pb.ArtifactPeer
+	for name, peer := range i.entries[key] {
+		if name == exclude || peer.AnnouncedAt < cutoff || !strings.EqualFold(peer.Sha256, sum) {
+			continue
+		}
+		found := proto.Clone(peer).(*pb.ArtifactPeer)
+		found.Sha256 = sum
+		peers = append(peers, found)
+	}
+	sort.Slice(peers, func(a, b int) bool {
+		return peers[a].AnnouncedAt > peers[b].AnnouncedAt
+	})
+	return peers
+}
+
+// removeAgent drops every announcement made by an agent
+func (i *artifactIndex) removeAgent(agentName string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for key, peers := range i.entries {
+		delete(peers, agentName)
+		if len(peers) == 0 {
+			delete(i.entries, key)
+			delete(i.verified, key)
+		}
+	}
+}
+
+// AnnounceArtifact records that an agent holds a cached artifact.
+func (s *agentRegistryServer) AnnounceArtifact(ctx context.Context, req *pb.AnnounceArtifactRequest) (*pb.AnnounceArtifactResponse, error) {
+	if req.AgentName == "" || req.Key == "" || req.CacheAddress == "" {
+		return &pb.AnnounceArtifactResponse{Success: false, Message: "agent_name, key and cache_address are required"}, nil
+	}
+	if agentInternal.ArtifactKey(req.Url) != req.Key {
+		return &pb.AnnounceArtifactResponse{Success: false, Message: "key does not match url"}, nil
+	}
+	if s.artifacts == nil {
+		return &pb.AnnounceArtifactResponse{Success: false, Message: "Artifact index not available"}, nil
+	}
+
+	s.artifacts.announce(req)
+	pterm.Debug.Printf("Agent %s announced artifact %s (%s)\n", req.AgentName, req.Key, req.Url)
+
+	return &pb.AnnounceArtifactResponse{Success: true, Message: "Artifact announced"}, nil
+}
+
+// LookupArtifact returns the agents that can serve a cached artifact.
+func (s *agentRegistryServer) LookupArtifact(ctx context.Context, req *pb.LookupArtifactRequest) (*pb.LookupArtifactResponse, error) {
+	if s.artifacts == nil {
+		return &pb.LookupArtifactResponse{}, nil
+	}
+	return &pb.LookupArtifactResponse{Peers: s.artifacts.lookup(req.Key, req.RequestingAgent)}, nil
+}
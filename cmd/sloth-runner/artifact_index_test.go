@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// stubArtifactChecksum makes the master's own download of an artifact
+// return sum
+func stubArtifactChecksum(t *testing.T, sum string) {
+	orig := artifactChecksum
+	artifactChecksum = func(ctx context.Context, url string) (string, error) { return sum, nil }
+	t.Cleanup(func() { artifactChecksum = orig })
+}
+
+func TestAgentRegistryArtifactIndex(t *testing.T) {
+	stubArtifactChecksum(t, "c0ffee")
+	server := &agentRegistryServer{artifacts: newArtifactIndex()}
+	ctx := context.Background()
+	url := "https://example.com/tool.tgz"
+	key := agentInternal.ArtifactKey(url)
+
+	for _, agent := range []string{"agent-a", "agent-b"} {
+		resp, err := server.AnnounceArtifact(ctx, &pb.AnnounceArtifactRequest{
+			AgentName:    agent,
+			CacheAddress: agent + ":50062",
+			Key:          key,
+			Url:          url,
+			Sha256:       "C0FFEE",
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("AnnounceArtifact failed: %v %v", err, resp)
+		}
+	}
+	server.artifacts.pending.Wait()
+
+	resp, err := server.LookupArtifact(ctx, &pb.LookupArtifactRequest{Key: key, RequestingAgent: "agent-a"})
+	if err != nil {
+		t.Fatalf("LookupArtifact failed: %v", err)
+	}
+	if len(resp.Peers) != 1 || resp.Peers[0].AgentName != "agent-b" || resp.Peers[0].Sha256 != "c0ffee" {
+		t.Errorf("Expected only agent-b with the verified checksum, got %v", resp.Peers)
+	}
+
+	server.artifacts.removeAgent("agent-b")
+	resp, _ = server.LookupArtifact(ctx, &pb.LookupArtifactRequest{Key: key, RequestingAgent: "agent-a"})
+	if len(resp.Peers) != 0 {
+		t.Errorf("Expected no peers after removal, got %v", resp.Peers)
+	}
+}
+
+func TestAgentRegistryArtifactIndexIgnoresWrongChecksums(t *testing.T) {
+	release := make(chan struct{})
+	orig := artifactChecksum
+	artifactChecksum = func(ctx context.Context, url string) (string, error) {
+		<-release
+		return "c0ffee", nil
+	}
+	defer func() { artifactChecksum = orig }()
+	server := &agentRegistryServer{artifacts: newArtifactIndex()}
+	ctx := context.Background()
+	url := "https://example.com/tool.tgz"
+	key := agentInternal.ArtifactKey(url)
+
+	announce := func(agent, sum string) {
+		server.AnnounceArtifact(ctx, &pb.AnnounceArtifactRequest{AgentName: agent, CacheAddress: agent + ":50062", Key: key, Url: url, Sha256: sum})
+	}
+	// Before the master verified the artifact, nobody is handed out
+	announce("agent-a", "c0ffee")
+	if peers := server.artifacts.lookup(key, ""); len(peers) != 0 {
+		t.Errorf("Expected no peers before verification, got %v", peers)
+	}
+	close(release)
+	server.artifacts.pending.Wait()
+
+	announce("evil", "deadbeef")
+	peers := server.artifacts.lookup(key, "")
+	if len(peers) != 1 || peers[0].AgentName != "agent-a" {
+		t.Errorf("Expected only the agent announcing the verified checksum, got %v", peers)
+	}
+
+	resp, _ := server.AnnounceArtifact(ctx, &pb.AnnounceArtifactRequest{AgentName: "evil", CacheAddress: "evil:50062", Key: key, Url: "https://example.com/other"})
+	if resp.Success {
+		t.Error("Expected an announcement whose key isn't its URL's to be rejected")
+	}
+}
+
+func TestAgentRegistryAnnounceArtifactValidation(t *testing.T) {
+	server := &agentRegistryServer{artifacts: newArtifactIndex()}
+
+	resp, err := server.AnnounceArtifact(context.Background(), &pb.AnnounceArtifactRequest{Key: "abc"})
+	if err != nil {
+		t.Fatalf("AnnounceArtifact returned error: %v", err)
+	}
+	if resp.Success {
+		t.Error("Expected announcement without agent name to be rejected")
+	}
+}
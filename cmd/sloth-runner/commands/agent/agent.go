@@ -29,9 +29,14 @@ func NewAgentCommand(ctx *commands.AppContext) *cobra.Command {
 		NewMetricsCommand(ctx),
 		NewUpdateCommand(ctx),
 		NewInstallCommand(ctx),
+		NewInstallCommandCommand(ctx),
 		NewDocsCommand(ctx),
 		NewShellCommand(ctx),
 		NewWatcherCommand(ctx),
+		NewFactsCommand(ctx),
+		NewVerifyCommand(ctx),
+		NewConfigCommand(ctx),
+		NewAskpassCommand(ctx),
 		// TODO: NewArtifactsCommand requires protobuf definitions - temporarily disabled
 		// NewArtifactsCommand(ctx),
 	)
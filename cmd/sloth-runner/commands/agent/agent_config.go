@@ -0,0 +1,327 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// agentConfig is the settings an agent applies again when it reloads, on
+// SIGHUP or 'agent config reload': those of its flags, overridden by those
+// of its config file
+type agentConfig struct {
+	Master        string               `yaml:"master"`
+	Telemetry     bool                 `yaml:"telemetry"`
+	MetricsPort   int                  `yaml:"metrics_port"`
+	WorkflowCache workflowCacheOptions `yaml:"workflow_cache"`
+	Facts         customFactsOptions   `yaml:"facts"`
+	Watchers      []watcherDefinition  `yaml:"watchers,omitempty"`
+}
+
+// watcherDefinition is a watcher of the config file. It is registered when
+// the agent starts, and registered again or removed when a reload finds it
+// changed or gone.
+type watcherDefinition struct {
+	ID              string        `yaml:"id"`
+	Type            string        `yaml:"type"`
+	Conditions      []string      `yaml:"conditions,omitempty"`
+	Interval        time.Duration `yaml:"interval,omitempty"`
+	Group           string        `yaml:"group,omitempty"`
+	FilePath        string        `yaml:"file_path,omitempty"`
+	CheckHash       bool          `yaml:"check_hash,omitempty"`
+	Recursive       bool          `yaml:"recursive,omitempty"`
+	ProcessName     string        `yaml:"process_name,omitempty"`
+	PID             int           `yaml:"pid,omitempty"`
+	Port            int           `yaml:"port,omitempty"`
+	Protocol        string        `yaml:"protocol,omitempty"`
+	CPUThreshold    float64       `yaml:"cpu_threshold,omitempty"`
+	MemoryThreshold float64       `yaml:"memory_threshold,omitempty"`
+	DiskThreshold   float64       `yaml:"disk_threshold,omitempty"`
+}
+
+// watcherTypes are the types config watchers can have
+var watcherTypes = []agentInternal.WatcherType{
+	agentInternal.WatcherTypeFile, agentInternal.WatcherTypeDirectory, agentInternal.WatcherTypeProcess,
+	agentInternal.WatcherTypePort, agentInternal.WatcherTypeService, agentInternal.WatcherTypeLog,
+	agentInternal.WatcherTypeCommand, agentInternal.WatcherTypeCPU, agentInternal.WatcherTypeMemory,
+	agentInternal.WatcherTypeDisk, agentInternal.WatcherTypeNetwork, agentInternal.WatcherTypeConnection,
+	agentInternal.WatcherTypeUser, agentInternal.WatcherTypePackage,
+}
+
+// watcherConfig converts the definition to the config the watcher manager
+// registers
+func (d watcherDefinition) watcherConfig() agentInternal.WatcherConfig {
+	cfg := agentInternal.WatcherConfig{
+		ID:              d.ID,
+		Type:            agentInternal.WatcherType(d.Type),
+		Interval:        d.Interval,
+		Group:           d.Group,
+		FilePath:        d.FilePath,
+		CheckHash:       d.CheckHash,
+		Recursive:       d.Recursive,
+		ProcessName:     d.ProcessName,
+		PID:             d.PID,
+		Port:            d.Port,
+		Protocol:        d.Protocol,
+		CPUThreshold:    d.CPUThreshold,
+		MemoryThreshold: d.MemoryThreshold,
+		DiskThreshold:   d.DiskThreshold,
+	}
+	for _, c := range d.Conditions {
+		cfg.Conditions = append(cfg.Conditions, agentInternal.EventCondition(c))
+	}
+	return cfg
+}
+
+// validate reports the first setting of c that can't be applied
+func (c *agentConfig) validate() error {
+	if c.MetricsPort < 1 || c.MetricsPort > 65535 {
+		return fmt.Errorf("metrics_port %d is not a port", c.MetricsPort)
+	}
+	if c.WorkflowCache.Size < 0 || c.WorkflowCache.TTL < 0 {
+		return errors.New("workflow_cache size and ttl can't be negative")
+	}
+	if c.Facts.Timeout < 0 || c.Facts.TTL < 0 {
+		return errors.New("facts timeout and cache_ttl can't be negative")
+	}
+	ids := make(map[string]bool)
+	for i, w := range c.Watchers {
+		if w.ID == "" {
+			return fmt.Errorf("watcher %d has no id", i+1)
+		}
+		if ids[w.ID] {
+			return fmt.Errorf("watcher %s is defined twice", w.ID)
+		}
+		ids[w.ID] = true
+		if !slices.Contains(watcherTypes, agentInternal.WatcherType(w.Type)) {
+			return fmt.Errorf("watcher %s has unknown type %q", w.ID, w.Type)
+		}
+		if w.Interval < 0 {
+			return fmt.Errorf("watcher %s has a negative interval", w.ID)
+		}
+	}
+	return nil
+}
+
+// agentConfigPath returns the config file of the agent: path when set,
+// otherwise <data-dir>/agent.yaml when it exists, or "" for none
+func agentConfigPath(path string) string {
+	if path != "" {
+		return path
+	}
+	if _, err := os.Stat(config.GetAgentConfigPath()); err == nil {
+		return config.GetAgentConfigPath()
+	}
+	return ""
+}
+
+// loadAgentConfig returns base with the settings of the config file at path
+// over it, or base when path is empty. Unknown settings are errors, so a
+// typo doesn't go unnoticed on reload.
+func loadAgentConfig(path string, base agentConfig) (agentConfig, error) {
+	if path == "" {
+		return base, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return base, fmt.Errorf("failed to read agent config: %w", err)
+	}
+
+	cfg := base
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return base, fmt.Errorf("invalid agent config %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return base, fmt.Errorf("invalid agent config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// configChanges returns the settings whose values differ between old and
+// cfg, by their name in the config file
+func configChanges(old, cfg agentConfig) []string {
+	var changed []string
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(cfg)
+	for i := 0; i < oldValue.NumField(); i++ {
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			name, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// watcherRegistry is the part of the watcher manager config watchers are
+// registered with
+type watcherRegistry interface {
+	RegisterWatcher(config agentInternal.WatcherConfig) error
+	RemoveWatcher(id string) error
+}
+
+// reconcileWatchers registers the watchers of defs that old didn't define,
+// or defined otherwise, and removes those only old defined. Watchers created
+// with 'agent watcher create' are left as they are.
+func reconcileWatchers(reg watcherRegistry, old, defs []watcherDefinition) error {
+	previous := make(map[string]watcherDefinition, len(old))
+	for _, d := range old {
+		previous[d.ID] = d
+	}
+
+	var errs []error
+	for _, d := range defs {
+		p, existed := previous[d.ID]
+		delete(previous, d.ID)
+		if existed && reflect.DeepEqual(p, d) {
+			continue
+		}
+		if err := reg.RegisterWatcher(d.watcherConfig()); err != nil {
+			errs = append(errs, fmt.Errorf("watcher %s: %w", d.ID, err))
+		}
+	}
+	for id := range previous {
+		if err := reg.RemoveWatcher(id); err != nil {
+			errs = append(errs, fmt.Errorf("watcher %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// agentConfigState is the config an agent runs with and where it came from
+type agentConfigState struct {
+	mu       sync.Mutex
+	path     string      // Config file, "" when only flags are used
+	flags    agentConfig // Settings of the flags, which the file overrides
+	applied  agentConfig
+	loadedAt time.Time
+	lastErr  error // Why the last reload failed
+	version  string
+}
+
+// masterAddr returns the address of the master the agent connects to,
+// which changes when a reload sets another one
+func (s *agentServer) masterAddr() string {
+	s.config.mu.Lock()
+	defer s.config.mu.Unlock()
+	return s.config.applied.Master
+}
+
+// reloadConfig reads the config file again and applies the settings that
+// changed, returning them. Settings that fail to load leave the applied
+// ones as they are.
+func (s *agentServer) reloadConfig() ([]string, error) {
+	s.config.mu.Lock()
+	defer s.config.mu.Unlock()
+
+	cfg, err := loadAgentConfig(s.config.path, s.config.flags)
+	if err != nil {
+		s.config.lastErr = err
+		return nil, err
+	}
+	changed := configChanges(s.config.applied, cfg)
+	err = s.applyConfig(s.config.applied, cfg, changed)
+	s.config.applied = cfg
+	s.config.loadedAt = time.Now()
+	s.config.lastErr = err
+	return changed, err
+}
+
+// applyConfig reconfigures what uses the changed settings of cfg
+func (s *agentServer) applyConfig(old, cfg agentConfig, changed []string) error {
+	var errs []error
+	for _, setting := range changed {
+		switch setting {
+		case "master":
+			if cfg.Master == "" {
+				break
+			}
+			configureMasterClients(cfg.Master, s.name)
+			if worker, ok := s.eventWorker.(*agentInternal.EventWorker); ok && worker != nil {
+				if err := worker.SetMasterAddr(cfg.Master); err != nil {
+					errs = append(errs, fmt.Errorf("master: %w", err))
+				}
+			}
+		case "telemetry", "metrics_port":
+			if err := reconfigureTelemetry(cfg.MetricsPort, cfg.Telemetry, s.config.version); err != nil {
+				errs = append(errs, fmt.Errorf("telemetry: %w", err))
+			}
+		case "workflow_cache":
+			cfg.WorkflowCache.apply(s)
+		case "facts":
+			cfg.Facts.apply()
+		case "watchers":
+			mgr, ok := s.watcherManager.(*agentInternal.EventWatcherManager)
+			if !ok || mgr == nil {
+				errs = append(errs, errors.New("watchers: the agent started without a master to send their events to, restart it"))
+				break
+			}
+			if err := reconcileWatchers(mgr, old.Watchers, cfg.Watchers); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reloadOnSignal reloads the config every time a signal arrives on c
+func (s *agentServer) reloadOnSignal(c <-chan os.Signal) {
+	for range c {
+		changed, err := s.reloadConfig()
+		if err != nil {
+			slog.Error("Failed to reload agent config", "error", err, "changed", changed)
+			continue
+		}
+		slog.Info("Agent config reloaded", "changed", changed)
+	}
+}
+
+// ReloadConfig reloads the agent's config, as SIGHUP does
+func (s *agentServer) ReloadConfig(ctx context.Context, in *pb.ReloadConfigRequest) (*pb.AgentConfigResponse, error) {
+	changed, err := s.reloadConfig()
+	if err != nil {
+		slog.Error("Failed to reload agent config", "error", err, "changed", changed)
+	} else {
+		slog.Info("Agent config reloaded via gRPC", "changed", changed)
+	}
+	return s.configResponse(changed)
+}
+
+// GetConfig returns the settings the agent runs with
+func (s *agentServer) GetConfig(ctx context.Context, in *pb.GetConfigRequest) (*pb.AgentConfigResponse, error) {
+	return s.configResponse(nil)
+}
+
+func (s *agentServer) configResponse(changed []string) (*pb.AgentConfigResponse, error) {
+	s.config.mu.Lock()
+	defer s.config.mu.Unlock()
+
+	data, err := yaml.Marshal(s.config.applied)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode agent config: %w", err)
+	}
+	resp := &pb.AgentConfigResponse{
+		Path:       s.config.path,
+		ConfigYaml: string(data),
+		LoadedAt:   s.config.loadedAt.Unix(),
+		Changed:    changed,
+	}
+	if s.config.lastErr != nil {
+		resp.Error = s.config.lastErr.Error()
+	}
+	return resp, nil
+}
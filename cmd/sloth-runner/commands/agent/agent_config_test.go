@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/agent/mocks"
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func testFlagConfig() agentConfig {
+	return agentConfig{
+		Master:        "localhost:50051",
+		MetricsPort:   9090,
+		WorkflowCache: workflowCacheOptions{Enabled: true, Size: 16, TTL: 30 * time.Minute},
+		Facts:         customFactsOptions{Timeout: 10 * time.Second, TTL: 5 * time.Minute},
+	}
+}
+
+func writeAgentConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadAgentConfig(t *testing.T) {
+	path := writeAgentConfig(t, `
+master: master.example.com:50051
+telemetry: true
+workflow_cache:
+  ttl: 5m
+watchers:
+  - id: nginx-conf
+    type: file
+    file_path: /etc/nginx/nginx.conf
+    conditions: [changed]
+    interval: 10s
+`)
+	cfg, err := loadAgentConfig(path, testFlagConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, "master.example.com:50051", cfg.Master)
+	assert.True(t, cfg.Telemetry)
+	assert.Equal(t, 9090, cfg.MetricsPort, "settings the file doesn't set keep the flags")
+	assert.Equal(t, workflowCacheOptions{Enabled: true, Size: 16, TTL: 5 * time.Minute}, cfg.WorkflowCache)
+	require.Len(t, cfg.Watchers, 1)
+	assert.Equal(t, 10*time.Second, cfg.Watchers[0].Interval)
+
+	cfg, err = loadAgentConfig("", testFlagConfig())
+	require.NoError(t, err)
+	assert.Equal(t, testFlagConfig(), cfg)
+
+	cfg, err = loadAgentConfig(writeAgentConfig(t, ""), testFlagConfig())
+	require.NoError(t, err)
+	assert.Equal(t, testFlagConfig(), cfg)
+}
+
+func TestLoadAgentConfig_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown setting":    "metrics_prot: 9100",
+		"bad duration":       "workflow_cache: {ttl: soon}",
+		"bad port":           "metrics_port: 70000",
+		"watcher without id": "watchers: [{type: file}]",
+		"duplicate watcher":  "watchers: [{id: a, type: file}, {id: a, type: port}]",
+		"unknown type":       "watchers: [{id: a, type: files}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := loadAgentConfig(writeAgentConfig(t, content), testFlagConfig())
+			assert.Error(t, err)
+			assert.Equal(t, testFlagConfig(), cfg)
+		})
+	}
+
+	_, err := loadAgentConfig(filepath.Join(t.TempDir(), "missing.yaml"), testFlagConfig())
+	assert.Error(t, err)
+}
+
+func TestConfigChanges(t *testing.T) {
+	old := testFlagConfig()
+	cfg := testFlagConfig()
+	assert.Empty(t, configChanges(old, cfg))
+
+	cfg.Master = "master.example.com:50051"
+	cfg.Facts.TTL = time.Minute
+	cfg.Watchers = []watcherDefinition{{ID: "a", Type: "file"}}
+	assert.Equal(t, []string{"master", "facts", "watchers"}, configChanges(old, cfg))
+}
+
+// fakeWatcherRegistry records the watchers registered and removed
+type fakeWatcherRegistry struct {
+	registered []string
+	removed    []string
+}
+
+func (r *fakeWatcherRegistry) RegisterWatcher(config agentInternal.WatcherConfig) error {
+	r.registered = append(r.registered, config.ID)
+	return nil
+}
+
+func (r *fakeWatcherRegistry) RemoveWatcher(id string) error {
+	r.removed = append(r.removed, id)
+	return nil
+}
+
+func TestReconcileWatchers(t *testing.T) {
+	old := []watcherDefinition{
+		{ID: "kept", Type: "file", FilePath: "/etc/hosts"},
+		{ID: "changed", Type: "port", Port: 80},
+		{ID: "gone", Type: "process", ProcessName: "nginx"},
+	}
+	defs := []watcherDefinition{
+		{ID: "kept", Type: "file", FilePath: "/etc/hosts"},
+		{ID: "changed", Type: "port", Port: 443},
+		{ID: "new", Type: "cpu", CPUThreshold: 90},
+	}
+
+	reg := &fakeWatcherRegistry{}
+	require.NoError(t, reconcileWatchers(reg, old, defs))
+	assert.Equal(t, []string{"changed", "new"}, reg.registered)
+	assert.Equal(t, []string{"gone"}, reg.removed)
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := writeAgentConfig(t, "workflow_cache: {enabled: false}\n")
+	s := &agentServer{name: "web-01"}
+	s.config = agentConfigState{path: path, flags: testFlagConfig(), applied: testFlagConfig()}
+	testFlagConfig().WorkflowCache.apply(s)
+
+	changed, err := s.reloadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"workflow_cache"}, changed)
+	parseCache, statePool := s.workflowCaches()
+	assert.Nil(t, parseCache)
+	assert.Nil(t, statePool)
+
+	// A file that fails to load keeps the applied settings
+	require.NoError(t, os.WriteFile(path, []byte("workflow_cache: {size: -1}\n"), 0644))
+	_, err = s.reloadConfig()
+	assert.Error(t, err)
+
+	resp, err := s.GetConfig(context.Background(), &pb.GetConfigRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, path, resp.Path)
+	assert.Contains(t, resp.ConfigYaml, "enabled: false")
+	assert.Contains(t, resp.ConfigYaml, "ttl: 30m0s")
+	assert.Contains(t, resp.Error, "size and ttl can't be negative")
+}
+
+func TestAgentConfigWithClients(t *testing.T) {
+	regClient := mocks.NewMockAgentRegistryClient()
+	regClient.ListAgentsFunc = func(ctx context.Context, in *pb.ListAgentsRequest, opts ...grpc.CallOption) (*pb.ListAgentsResponse, error) {
+		return &pb.ListAgentsResponse{Agents: []*pb.AgentInfo{{AgentName: "web-01", AgentAddress: "10.0.0.21:50051"}}}, nil
+	}
+	agentClient := mocks.NewMockAgentClient()
+	agentClient.GetConfigFunc = func(ctx context.Context, in *pb.GetConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error) {
+		return &pb.AgentConfigResponse{Path: "/etc/sloth-runner/agent.yaml", ConfigYaml: "master: master.example.com:50051\n"}, nil
+	}
+	agentClient.ReloadConfigFunc = func(ctx context.Context, in *pb.ReloadConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error) {
+		return &pb.AgentConfigResponse{Error: "invalid agent config: metrics_port 0 is not a port"}, nil
+	}
+	factory := func(string) (AgentClient, func(), error) { return agentClient, func() {}, nil }
+
+	var out bytes.Buffer
+	err := agentConfigWithClients(context.Background(), regClient, factory, ConfigOptions{AgentName: "web-01", Writer: &out})
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Config of agent web-01 (/etc/sloth-runner/agent.yaml)")
+	assert.Contains(t, out.String(), "master: master.example.com:50051")
+
+	err = agentConfigWithClients(context.Background(), regClient, factory, ConfigOptions{AgentName: "web-01", Reload: true, Writer: &bytes.Buffer{}})
+	assert.EqualError(t, err, "agent web-01 failed to reload its config: invalid agent config: metrics_port 0 is not a port")
+}
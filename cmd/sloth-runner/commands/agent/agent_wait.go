@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+)
+
+// configureAgentWait makes agent.wait_for on this agent ask its master
+// about the agents it waits for
+func configureAgentWait(masterAddr string) error {
+	conn, err := grpc.Dial(masterAddr, mtls.DialOption())
+	if err != nil {
+		return fmt.Errorf("failed to connect to master for agent lookups: %w", err)
+	}
+	luainterface.SetMasterRegistry(pb.NewAgentRegistryClient(conn))
+	return nil
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// artifactCacheOptions configures the agent-local artifact cache
+type artifactCacheOptions struct {
+	Enabled   bool
+	Dir       string
+	Port      int
+	Discovery string // master, static or none
+	Peers     []string
+	Checksums []string // URL=SHA256 pins for static discovery
+}
+
+func addArtifactCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("artifact-cache", false, "Enable the read-through artifact cache shared with peer agents")
+	cmd.Flags().String("artifact-cache-dir", "", "Directory for cached artifacts (default: <data-dir>/artifact-cache)")
+	cmd.Flags().Int("artifact-cache-port", 50062, "Port on which cached artifacts are served to peers")
+	cmd.Flags().String("artifact-cache-discovery", "master", "Peer discovery mode: master, static or none")
+	cmd.Flags().StringSlice("artifact-cache-peers", nil, "Peer cache addresses (host:port) for static discovery")
+	cmd.Flags().StringArray("artifact-cache-checksum", nil, "URL=SHA256 of an artifact static peers may serve (repeatable)")
+}
+
+func getArtifactCacheOptions(cmd *cobra.Command) artifactCacheOptions {
+	opts := artifactCacheOptions{}
+	opts.Enabled, _ = cmd.Flags().GetBool("artifact-cache")
+	opts.Dir, _ = cmd.Flags().GetString("artifact-cache-dir")
+	opts.Port, _ = cmd.Flags().GetInt("artifact-cache-port")
+	opts.Discovery, _ = cmd.Flags().GetString("artifact-cache-discovery")
+	opts.Peers, _ = cmd.Flags().GetStringSlice("artifact-cache-peers")
+	opts.Checksums, _ = cmd.Flags().GetStringArray("artifact-cache-checksum")
+	return opts
+}
+
+// daemonArgs returns the flags needed to forward the options to a daemon process
+func (o artifactCacheOptions) daemonArgs() []string {
+	if !o.Enabled {
+		return nil
+	}
+	args := []string{"--artifact-cache", "--artifact-cache-port", strconv.Itoa(o.Port), "--artifact-cache-discovery", o.Discovery}
+	if o.Dir != "" {
+		args = append(args, "--artifact-cache-dir", o.Dir)
+	}
+	if len(o.Peers) > 0 {
+		args = append(args, "--artifact-cache-peers", strings.Join(o.Peers, ","))
+	}
+	for _, pin := range o.Checksums {
+		args = append(args, "--artifact-cache-checksum", pin)
+	}
+	return args
+}
+
+// checksums parses the URL=SHA256 pins. URLs may contain '=', checksums
+// can't.
+func (o artifactCacheOptions) checksums() (map[string]string, error) {
+	pins := make(map[string]string, len(o.Checksums))
+	for _, pin := range o.Checksums {
+		i := strings.LastIndex(pin, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid artifact checksum %q: expected URL=SHA256", pin)
+		}
+		sum := pin[i+1:]
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid artifact checksum %q: not a SHA-256 hex digest", pin)
+		}
+		pins[pin[:i]] = sum
+	}
+	return pins, nil
+}
+
+// startArtifactCache creates the cache, starts its peer HTTP server on the
+// agent's bind address, with mutual TLS when the agent has certificates,
+// and publishes it for Lua modules running on this agent
+func startArtifactCache(opts artifactCacheOptions, agentName, masterAddr, bindAddress, agentReportAddress string) (*agentInternal.ArtifactCache, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = config.GetArtifactCacheDir()
+	}
+
+	host, _, err := net.SplitHostPort(agentReportAddress)
+	if err != nil {
+		host = agentReportAddress
+	}
+	cacheAddress := net.JoinHostPort(host, strconv.Itoa(opts.Port))
+
+	var discovery agentInternal.PeerDiscovery
+	switch opts.Discovery {
+	case "master":
+		if masterAddr == "" {
+			return nil, fmt.Errorf("artifact cache discovery 'master' requires --master")
+		}
+		conn, err := grpc.Dial(masterAddr, mtls.DialOption())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to master for artifact discovery: %w", err)
+		}
+		discovery = &agentInternal.MasterPeerDiscovery{
+			Client:       pb.NewAgentRegistryClient(conn),
+			AgentName:    agentName,
+			CacheAddress: cacheAddress,
+		}
+	case "static":
+		// Without an index, only pinned checksums make peers trustworthy
+		pins, err := opts.checksums()
+		if err != nil {
+			return nil, err
+		}
+		if len(pins) == 0 {
+			return nil, fmt.Errorf("artifact cache discovery 'static' requires --artifact-cache-checksum for the artifacts peers may serve")
+		}
+		discovery = agentInternal.NewStaticPeerDiscovery(opts.Peers, pins)
+	case "none", "":
+		discovery = nil
+	default:
+		return nil, fmt.Errorf("unknown artifact cache discovery mode: %s", opts.Discovery)
+	}
+
+	cache, err := agentInternal.NewArtifactCache(agentInternal.ArtifactCacheConfig{
+		Dir:       dir,
+		Discovery: discovery,
+		PeerTLS:   mtls.ClientConfig(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lis, err := net.Listen("tcp", net.JoinHostPort(bindAddress, strconv.Itoa(opts.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for artifact cache: %w", err)
+	}
+	if tlsConfig := mtls.ServerConfig(); tlsConfig != nil {
+		lis = tls.NewListener(lis, tlsConfig)
+	} else {
+		pterm.Warning.Println("⚠ Artifact cache serves peers without TLS; configure mTLS certificates to authenticate them")
+	}
+	go func() {
+		if err := http.Serve(lis, cache.Handler()); err != nil {
+			slog.Error("Artifact cache server stopped", "error", err)
+		}
+	}()
+
+	agentInternal.SetGlobalArtifactCache(cache)
+	pterm.Success.Printf("✓ Artifact cache at %s serving peers on %s (discovery: %s)\n", dir, cacheAddress, opts.Discovery)
+	return cache, nil
+}
@@ -0,0 +1,23 @@
+package agent
+
+import (
+	"os"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sudo"
+	"github.com/spf13/cobra"
+)
+
+// NewAskpassCommand creates the askpass helper sudo runs on agents holding
+// a sudo password for a run
+func NewAskpassCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:    "askpass <socket>",
+		Short:  "Print the sudo password held by the agent",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sudo.Askpass(args[0], os.Stdout)
+		},
+	}
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/blobstore"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// blobClientOptions configures access to the master's blob store
+type blobClientOptions struct {
+	Endpoint string
+	TLS      blobstore.TLSFiles
+}
+
+func addBlobClientFlags(cmd *cobra.Command) {
+	cmd.Flags().String("blob-endpoint", "", "Blob store URL used by artifact.put/get (e.g. https://master:50063)")
+	cmd.Flags().String("blob-tls-cert", "", "Client certificate for the blob store")
+	cmd.Flags().String("blob-tls-key", "", "Client key for the blob store")
+	cmd.Flags().String("blob-tls-ca", "", "CA that signs the blob store certificate")
+}
+
+func getBlobClientOptions(cmd *cobra.Command) blobClientOptions {
+	opts := blobClientOptions{}
+	opts.Endpoint, _ = cmd.Flags().GetString("blob-endpoint")
+	opts.TLS.CertFile, _ = cmd.Flags().GetString("blob-tls-cert")
+	opts.TLS.KeyFile, _ = cmd.Flags().GetString("blob-tls-key")
+	opts.TLS.CAFile, _ = cmd.Flags().GetString("blob-tls-ca")
+	return opts
+}
+
+// daemonArgs returns the flags needed to forward the options to a daemon process
+func (o blobClientOptions) daemonArgs() []string {
+	if o.Endpoint == "" {
+		return nil
+	}
+	args := []string{"--blob-endpoint", o.Endpoint}
+	if o.TLS.CertFile != "" {
+		args = append(args, "--blob-tls-cert", o.TLS.CertFile)
+	}
+	if o.TLS.KeyFile != "" {
+		args = append(args, "--blob-tls-key", o.TLS.KeyFile)
+	}
+	if o.TLS.CAFile != "" {
+		args = append(args, "--blob-tls-ca", o.TLS.CAFile)
+	}
+	return args
+}
+
+// configureBlobClient publishes the blob client for Lua modules running on
+// this agent
+func configureBlobClient(opts blobClientOptions) error {
+	if opts.Endpoint == "" {
+		return nil
+	}
+	client := blobstore.NewClient(opts.Endpoint, nil)
+	if opts.TLS.Enabled() {
+		tlsConfig, err := blobstore.ClientTLSConfig(opts.TLS)
+		if err != nil {
+			return err
+		}
+		client = blobstore.NewClient(opts.Endpoint, tlsConfig)
+	}
+	blobstore.SetGlobalClient(client)
+	pterm.Success.Printf("✓ Blob store at %s\n", client.Endpoint())
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package agent
+
+import "os/exec"
+
+// shellCommand runs a command with bash, as user when set to someone other
+// than root
+func shellCommand(command, user string) (*exec.Cmd, error) {
+	if user != "" && user != "root" {
+		return exec.Command("sudo", "-u", user, "bash", "-c", command), nil
+	}
+	return exec.Command("bash", "-c", command), nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// shellCommand runs a command with PowerShell. Windows has no sudo: commands
+// run as the account of the agent service, and asking for another user,
+// other than root for the agent's own, fails.
+func shellCommand(command, user string) (*exec.Cmd, error) {
+	if user != "" && user != "root" {
+		return nil, fmt.Errorf("running commands as %s is not supported on Windows agents", user)
+	}
+	return powerShellCommand(context.Background(), command), nil
+}
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewConfigCommand creates the agent config command
+func NewConfigCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show and reload the settings of agents",
+		Long: `Agents read their settings from their flags and from their config file,
+--config or <data-dir>/agent.yaml, whose settings override the flags:
+
+  master: master.example.com:50051
+  telemetry: true
+  metrics_port: 9100
+  workflow_cache: {enabled: true, size: 16, ttl: 30m}
+  facts: {dir: /etc/sloth-runner/facts.d, timeout: 10s, cache_ttl: 5m}
+  watchers:
+    - id: nginx-conf
+      type: file
+      file_path: /etc/nginx/nginx.conf
+      conditions: [changed]
+      interval: 10s
+
+Agents re-read the file on SIGHUP or 'agent config reload', applying the
+settings that changed without restarting.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newConfigShowCommand(ctx),
+		newConfigReloadCommand(ctx),
+	)
+
+	return cmd
+}
+
+func newConfigShowCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <agent_name>",
+		Short: "Show the settings an agent runs with",
+		Example: `  sloth-runner agent config show web-01
+  sloth-runner agent config show web-01 -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, _ := cmd.Flags().GetString("output")
+			return runAgentConfig(ConfigOptions{
+				AgentName:    args[0],
+				OutputFormat: outputFormat,
+				Writer:       cmd.OutOrStdout(),
+			}, getMasterAddress(cmd))
+		},
+	}
+
+	addMasterFlag(cmd)
+	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func newConfigReloadCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reload <agent_name>",
+		Short: "Make an agent re-read its config file",
+		Long: `Makes the agent re-read its config file, as SIGHUP does, and apply the
+settings that changed. When the file can't be loaded, the agent keeps the
+settings it had.`,
+		Example: `  sloth-runner agent config reload web-01`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputFormat, _ := cmd.Flags().GetString("output")
+			return runAgentConfig(ConfigOptions{
+				AgentName:    args[0],
+				Reload:       true,
+				OutputFormat: outputFormat,
+				Writer:       cmd.OutOrStdout(),
+			}, getMasterAddress(cmd))
+		},
+	}
+
+	addMasterFlag(cmd)
+	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// ConfigOptions contains options for showing or reloading an agent's config
+type ConfigOptions struct {
+	AgentName    string
+	Reload       bool
+	OutputFormat string
+	Writer       io.Writer
+}
+
+func runAgentConfig(opts ConfigOptions, masterAddr string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	factory := NewDefaultConnectionFactory()
+	registryClient, cleanup, err := factory.CreateRegistryClient(masterAddr)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return agentConfigWithClients(ctx, registryClient, factory.CreateAgentClient, opts)
+}
+
+// agentConfigWithClients shows or reloads an agent's config using injected
+// clients (testable)
+func agentConfigWithClients(
+	ctx context.Context,
+	registryClient AgentRegistryClient,
+	agentClientFactory func(string) (AgentClient, func(), error),
+	opts ConfigOptions,
+) error {
+	agentAddress, err := findAgentAddress(ctx, registryClient, opts.AgentName)
+	if err != nil {
+		return err
+	}
+	agentClient, cleanup, err := agentClientFactory(agentAddress)
+	if err != nil {
+		return fmt.Errorf("failed to connect to agent at %s: %w", agentAddress, err)
+	}
+	defer cleanup()
+
+	var resp *pb.AgentConfigResponse
+	if opts.Reload {
+		resp, err = agentClient.ReloadConfig(ctx, &pb.ReloadConfigRequest{})
+	} else {
+		resp, err = agentClient.GetConfig(ctx, &pb.GetConfigRequest{})
+	}
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return fmt.Errorf("agent %s is too old to reload its config, update it with 'sloth-runner agent update %s'", opts.AgentName, opts.AgentName)
+		}
+		return fmt.Errorf("failed to get config of agent %s: %w", opts.AgentName, err)
+	}
+
+	if opts.OutputFormat == "json" {
+		encoder := json.NewEncoder(opts.Writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	} else {
+		formatAgentConfig(opts.AgentName, opts.Reload, resp, opts.Writer)
+	}
+
+	if opts.Reload && resp.Error != "" {
+		return fmt.Errorf("agent %s failed to reload its config: %s", opts.AgentName, resp.Error)
+	}
+	return nil
+}
+
+// formatAgentConfig displays the settings of an agent (testable)
+func formatAgentConfig(agentName string, reloaded bool, resp *pb.AgentConfigResponse, w io.Writer) {
+	source := resp.Path
+	if source == "" {
+		source = "flags only"
+	}
+	fmt.Fprintf(w, "Config of agent %s (%s)\n", agentName, source)
+	fmt.Fprintf(w, "Applied: %s\n", time.Unix(resp.LoadedAt, 0).Format(time.RFC3339))
+	if reloaded && resp.Error == "" {
+		changed := "nothing"
+		if len(resp.Changed) > 0 {
+			changed = strings.Join(resp.Changed, ", ")
+		}
+		fmt.Fprintf(w, "%s Reloaded, changed: %s\n", pterm.Green("✓"), changed)
+	}
+	if resp.Error != "" {
+		fmt.Fprintf(w, "%s Last reload failed: %s\n", pterm.Red("✗"), resp.Error)
+	}
+	fmt.Fprintf(w, "\n%s", resp.ConfigYaml)
+}
@@ -4,13 +4,14 @@ import (
 	"fmt"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 )
 
 // createGRPCConnection creates a new gRPC connection to the specified address
 func createGRPCConnection(addr string) (*grpc.ClientConn, error) {
 	conn, err := grpc.Dial(addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"time"
+
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/spf13/cobra"
+)
+
+// customFactsOptions configures the scripts whose output is merged into
+// the agent's facts
+type customFactsOptions struct {
+	Dir     string        `yaml:"dir"`
+	Timeout time.Duration `yaml:"timeout"`
+	TTL     time.Duration `yaml:"cache_ttl"`
+}
+
+func addCustomFactsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("facts-dir", agentInternal.DefaultCustomFactsDir, "Directory of custom fact scripts and JSON files (empty to disable)")
+	cmd.Flags().Duration("facts-timeout", agentInternal.DefaultCustomFactsTimeout, "Kill a custom fact script running longer than this")
+	cmd.Flags().Duration("facts-cache-ttl", agentInternal.DefaultCustomFactsTTL, "Reuse a custom fact script's output for this long")
+}
+
+func getCustomFactsOptions(cmd *cobra.Command) customFactsOptions {
+	opts := customFactsOptions{}
+	opts.Dir, _ = cmd.Flags().GetString("facts-dir")
+	opts.Timeout, _ = cmd.Flags().GetDuration("facts-timeout")
+	opts.TTL, _ = cmd.Flags().GetDuration("facts-cache-ttl")
+	return opts
+}
+
+// daemonArgs returns the flags needed to forward the options to a daemon process
+func (o customFactsOptions) daemonArgs() []string {
+	return []string{"--facts-dir", o.Dir, "--facts-timeout", o.Timeout.String(), "--facts-cache-ttl", o.TTL.String()}
+}
+
+// apply sets up the collector used when system info is gathered
+func (o customFactsOptions) apply() {
+	if o.Dir == "" {
+		agentInternal.SetGlobalCustomFacts(nil)
+		return
+	}
+	agentInternal.SetGlobalCustomFacts(agentInternal.NewCustomFactsCollector(o.Dir, o.Timeout, o.TTL))
+}
@@ -7,10 +7,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // SystemInfo represents agent system information
@@ -49,7 +49,7 @@ func displayAgentDashboard(ctx context.Context, client AgentRegistryClient, agen
 	agentInfo := agentResp.AgentInfo
 
 	// Connect to agent directly using pb.AgentClient
-	conn, err := grpc.Dial(agentInfo.AgentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(agentInfo.AgentAddress, mtls.DialOption())
 	if err != nil {
 		return fmt.Errorf("failed to connect to agent: %w", err)
 	}
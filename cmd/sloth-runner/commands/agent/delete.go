@@ -2,7 +2,6 @@ package agent
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log/slog"
 	"os"
@@ -10,9 +9,10 @@ import (
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // NewDeleteCommand creates the agent delete command
@@ -40,7 +40,7 @@ func NewDeleteCommand(ctx *commands.AppContext) *cobra.Command {
 					Message: fmt.Sprintf("Are you sure you want to delete agent '%s'?", agentName),
 					Default: false,
 				}
-				if err := survey.AskOne(prompt, &confirm); err != nil {
+				if err := taskrunner.Ask(nil, taskrunner.Prompt{Name: "confirmation", Flag: "--force", Prompt: prompt}, &confirm); err != nil {
 					return err
 				}
 				if !confirm {
@@ -106,11 +106,11 @@ func deleteAgentFromLocalDB(agentName string, debug bool) error {
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Deleting agent '%s' from local database...", agentName))
 
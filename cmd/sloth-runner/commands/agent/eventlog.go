@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// On Windows the system logs are the System and Application event logs,
+// read with Get-WinEvent newest first in batches of eventLogBatch events.
+// The page token holds the time, in milliseconds, of the event a page ended
+// with.
+const eventLogBatch = 500
+
+// eventLogNames are the event logs read as system logs
+var eventLogNames = []string{"System", "Application"}
+
+// winEvent is an event as eventLogScript prints it
+type winEvent struct {
+	Time     int64  `json:"t"` // Unix milliseconds
+	Level    int    `json:"l"`
+	Provider string `json:"p"`
+	Message  string `json:"m"`
+}
+
+// powerShellCommand runs a PowerShell script without a profile or prompts
+func powerShellCommand(ctx context.Context, script string) *exec.Cmd {
+	return exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+}
+
+// eventLogScript returns the PowerShell script printing as JSON the newest
+// max events of each event log, of provider and levels when set, created
+// from since and before before (Unix milliseconds, no bound when 0)
+func eventLogScript(provider string, levels []int, since, before int64, max int) string {
+	filter := []string{"LogName=$_"}
+	if provider != "" {
+		filter = append(filter, "ProviderName='"+strings.ReplaceAll(provider, "'", "''")+"'")
+	}
+	if len(levels) > 0 {
+		var list []string
+		for _, level := range levels {
+			list = append(list, strconv.Itoa(level))
+		}
+		filter = append(filter, "Level="+strings.Join(list, ","))
+	}
+	if since > 0 {
+		filter = append(filter, fmt.Sprintf("StartTime=[DateTimeOffset]::FromUnixTimeMilliseconds(%d).LocalDateTime", since))
+	}
+	if before > 0 {
+		filter = append(filter, fmt.Sprintf("EndTime=[DateTimeOffset]::FromUnixTimeMilliseconds(%d).LocalDateTime", before-1))
+	}
+	return fmt.Sprintf(`$events = @('%s') | ForEach-Object {
+  Get-WinEvent -FilterHashtable @{%s} -MaxEvents %d -ErrorAction SilentlyContinue
+} | ForEach-Object {
+  @{t=([DateTimeOffset]$_.TimeCreated).ToUnixTimeMilliseconds(); l=[int]$_.Level; p=$_.ProviderName; m=$_.Message}
+}
+ConvertTo-Json -InputObject @($events) -Compress`, strings.Join(eventLogNames, "','"), strings.Join(filter, "; "), max)
+}
+
+// parseWinEvents merges the events of the event logs newest first and keeps
+// the max newest
+func parseWinEvents(data []byte, max int) ([]winEvent, error) {
+	var events []winEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %w", err)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time > events[j].Time })
+	if len(events) > max {
+		events = events[:max]
+	}
+	return events, nil
+}
+
+// queryEventLog returns the newest max events of the event logs
+func queryEventLog(ctx context.Context, provider string, levels []int, since, before int64, max int) ([]winEvent, error) {
+	cmd := powerShellCommand(ctx, eventLogScript(provider, levels, since, before, max))
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Get-WinEvent failed: %w", err)
+	}
+	return parseWinEvents(out, max)
+}
+
+// winEventEntry converts an event, whose level is the Windows one, to a log
+// entry with a syslog level name
+func winEventEntry(e winEvent) *pb.LogEntry {
+	level := "INFO"
+	switch e.Level {
+	case 1:
+		level = "CRITICAL"
+	case 2:
+		level = "ERROR"
+	case 3:
+		level = "WARNING"
+	case 5:
+		level = "DEBUG"
+	}
+	return &pb.LogEntry{
+		Timestamp: e.Time / 1000,
+		Level:     level,
+		Message:   strings.TrimSpace(e.Message),
+		Source:    e.Provider,
+	}
+}
+
+// eventLogReader reads the event logs newest first
+type eventLogReader struct {
+	ctx      context.Context
+	provider string
+	since    int64
+	before   int64 // Time of the last event read
+	batch    []winEvent
+	done     bool
+}
+
+// openEventLogReader reads the events older than before, or the newest
+// ones when it is 0
+func openEventLogReader(ctx context.Context, in *pb.RecentLogsRequest, before int64) *eventLogReader {
+	return &eventLogReader{ctx: ctx, provider: in.SourceFilter, since: in.SinceTimestamp * 1000, before: before}
+}
+
+func (r *eventLogReader) next() (*pb.LogEntry, string, error) {
+	if len(r.batch) == 0 {
+		if r.done {
+			return nil, "", io.EOF
+		}
+		events, err := queryEventLog(r.ctx, r.provider, nil, r.since, r.before, eventLogBatch)
+		if err != nil {
+			return nil, "", err
+		}
+		r.done = len(events) < eventLogBatch
+		if len(events) == 0 {
+			return nil, "", io.EOF
+		}
+		r.batch = events
+	}
+	e := r.batch[0]
+	r.batch = r.batch[1:]
+	r.before = e.Time
+	return winEventEntry(e), logPageToken("eventlog", strconv.FormatInt(e.Time, 10)), nil
+}
+
+func (r *eventLogReader) Close() error { return nil }
+
+// eventLogErrors returns the critical and error events of the event logs,
+// and the warnings when asked for, as GetSystemErrors does with the journal
+func eventLogErrors(ctx context.Context, in *pb.SystemErrorsRequest, maxErrors int) (*pb.SystemErrorsResponse, error) {
+	levels := []int{1, 2}
+	if in.IncludeWarnings {
+		levels = append(levels, 3)
+	}
+	events, err := queryEventLog(ctx, "", levels, in.SinceTimestamp*1000, 0, maxErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.SystemErrorsResponse{}
+	counts := make(map[string]int32)
+	for _, e := range events {
+		entry := winEventEntry(e)
+		severity := "error"
+		if e.Level == 3 {
+			severity = "warning"
+			resp.TotalWarnings++
+		} else {
+			resp.TotalErrors++
+		}
+		resp.Errors = append(resp.Errors, &pb.SystemError{
+			Timestamp:       entry.Timestamp,
+			Severity:        severity,
+			Source:          entry.Source,
+			Message:         entry.Message,
+			OccurrenceCount: 1,
+		})
+		counts[entry.Message]++
+		if counts[entry.Message] > counts[resp.MostCommonError] {
+			resp.MostCommonError = entry.Message
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,253 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+)
+
+// defaultExecParallel is how many agents a fan-out runs the command on at
+// once unless --parallel says otherwise
+const defaultExecParallel = 10
+
+// FanOutOptions contains options for running a command on many agents
+type FanOutOptions struct {
+	Group        string   // Agent group whose agents run the command
+	Agents       []string // Agents running the command, besides the group's
+	Command      string
+	Parallel     int
+	Timeout      time.Duration // Per agent, unlimited when 0
+	OutputFormat string
+	OutputWriter io.Writer
+	ErrorWriter  io.Writer
+}
+
+// AgentCommandResult is the outcome of the command on one agent of a fan-out
+type AgentCommandResult struct {
+	Agent      string `json:"agent"`
+	Success    bool   `json:"success"`
+	ExitCode   int32  `json:"exit_code"`
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// resolveExecTargets returns the agents of group followed by agents, each
+// once
+func resolveExecTargets(ctx context.Context, client AgentRegistryClient, group string, agents []string) ([]string, error) {
+	var targets []string
+	if group != "" {
+		resp, err := client.ListAgentGroups(ctx, &pb.ListGroupsRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list agent groups: %w", err)
+		}
+		var found *pb.AgentGroup
+		for _, g := range resp.GetGroups() {
+			if g.GetName() == group {
+				found = g
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("agent group %s not found", group)
+		}
+		targets = append(targets, found.GetAgentNames()...)
+	}
+	targets = append(targets, agents...)
+
+	seen := make(map[string]bool)
+	unique := targets[:0]
+	for _, name := range targets {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("no agents to run the command on")
+	}
+	return unique, nil
+}
+
+// runFanOutWithClient runs a command on many agents through the master,
+// opts.Parallel at a time, using an injected client (testable). In text
+// output the lines agents print are streamed as they come, prefixed with
+// the agent's name, followed by a summary.
+func runFanOutWithClient(ctx context.Context, client AgentRegistryClient, opts FanOutOptions) error {
+	agents, err := resolveExecTargets(ctx, client, opts.Group, opts.Agents)
+	if err != nil {
+		return err
+	}
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = defaultExecParallel
+	}
+
+	text := opts.OutputFormat != "json"
+	if text {
+		pterm.Info.WithWriter(opts.OutputWriter).Printf("🚀 Executing on %d agents (%d at a time)\n", len(agents), min(parallel, len(agents)))
+		pterm.Info.WithWriter(opts.OutputWriter).Printf("📝 Command: %s\n", opts.Command)
+		fmt.Fprintln(opts.OutputWriter)
+	}
+
+	width := 0
+	for _, name := range agents {
+		width = max(width, len(name))
+	}
+
+	var outMu sync.Mutex
+	results := make([]AgentCommandResult, len(agents))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, name := range agents {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var stdout, stderr io.Writer = io.Discard, io.Discard
+			if text {
+				prefix := fmt.Sprintf("%-*s | ", width, name)
+				out := &linePrefixWriter{w: opts.OutputWriter, mu: &outMu, prefix: prefix}
+				errOut := &linePrefixWriter{w: opts.ErrorWriter, mu: &outMu, prefix: prefix}
+				defer out.Flush()
+				defer errOut.Flush()
+				stdout, stderr = out, errOut
+			}
+			results[i] = runOnAgent(ctx, client, name, opts, stdout, stderr)
+		}(i, name)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, r := range results {
+		if !r.Success {
+			failed = append(failed, r.Agent)
+		}
+	}
+
+	if text {
+		formatFanOutSummary(results, opts.OutputWriter)
+	} else {
+		output := map[string]interface{}{
+			"command":   opts.Command,
+			"agents":    results,
+			"succeeded": len(results) - len(failed),
+			"failed":    len(failed),
+		}
+		jsonOutput, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON output: %w", err)
+		}
+		fmt.Fprintln(opts.OutputWriter, string(jsonOutput))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("command failed on %d of %d agents: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// runOnAgent runs the command on one agent, streaming its output to stdout
+// and stderr
+func runOnAgent(ctx context.Context, client AgentRegistryClient, agent string, opts FanOutOptions, stdout, stderr io.Writer) AgentCommandResult {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result := AgentCommandResult{Agent: agent, ExitCode: -1}
+	stream, err := client.ExecuteCommand(ctx, &pb.ExecuteCommandRequest{
+		AgentName: agent,
+		Command:   opts.Command,
+	})
+	if err == nil {
+		var r *CommandResult
+		r, err = processCommandStream(stream, opts.OutputFormat, stdout, stderr)
+		if err == nil {
+			result.Success = r.Success
+			result.ExitCode = r.ExitCode
+			result.Stdout = r.Stdout
+			result.Stderr = r.Stderr
+			result.Error = r.Error
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
+
+// formatFanOutSummary displays the outcome of the command on each agent
+// (testable)
+func formatFanOutSummary(results []AgentCommandResult, w io.Writer) {
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tSTATUS\tEXIT CODE\tDURATION\tERROR")
+	succeeded := 0
+	for _, r := range results {
+		status := "✅ SUCCESS"
+		if r.Success {
+			succeeded++
+		} else {
+			status = "❌ FAILED"
+		}
+		exitCode := "-"
+		if r.ExitCode >= 0 {
+			exitCode = fmt.Sprint(r.ExitCode)
+		}
+		duration := (time.Duration(r.DurationMs) * time.Millisecond).String()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Agent, status, exitCode, duration, r.Error)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d/%d agents succeeded\n", succeeded, len(results))
+}
+
+// linePrefixWriter writes whole lines to w, each after prefix, so the
+// output of agents running at once doesn't interleave within a line. mu is
+// shared by the writers of all agents.
+type linePrefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (p *linePrefixWriter) Write(data []byte) (int, error) {
+	p.buf.Write(data)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		p.writeLine(p.buf.Next(i + 1))
+	}
+}
+
+// Flush writes the last line when it has no newline
+func (p *linePrefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		p.writeLine(append(p.buf.Bytes(), '\n'))
+		p.buf.Reset()
+	}
+}
+
+func (p *linePrefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/agent/mocks"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// newFanOutMocks returns a registry whose web group has web-01 and web-02,
+// and whose agents answer with responses, or fail to run commands when
+// they have none
+func newFanOutMocks(responses map[string][]*pb.StreamOutputResponse) *mocks.MockAgentRegistryClient {
+	client := mocks.NewMockAgentRegistryClient()
+	client.ListAgentGroupsFunc = func(ctx context.Context, in *pb.ListGroupsRequest, opts ...grpc.CallOption) (*pb.ListGroupsResponse, error) {
+		return &pb.ListGroupsResponse{Groups: []*pb.AgentGroup{{Name: "web", AgentNames: []string{"web-01", "web-02"}}}}, nil
+	}
+	client.ExecuteCommandFunc = func(ctx context.Context, in *pb.ExecuteCommandRequest, opts ...grpc.CallOption) (pb.AgentRegistry_ExecuteCommandClient, error) {
+		r, ok := responses[in.AgentName]
+		if !ok {
+			return nil, errors.New("agent not found")
+		}
+		return &mocks.MockExecuteCommandClient{Responses: r}, nil
+	}
+	return client
+}
+
+func TestResolveExecTargets(t *testing.T) {
+	client := newFanOutMocks(nil)
+
+	agents, err := resolveExecTargets(context.Background(), client, "web", []string{"db-01", "web-02"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-01", "web-02", "db-01"}, agents)
+
+	_, err = resolveExecTargets(context.Background(), client, "api", nil)
+	assert.EqualError(t, err, "agent group api not found")
+}
+
+func TestRunFanOutWithClient(t *testing.T) {
+	responses := map[string][]*pb.StreamOutputResponse{
+		"web-01": {{StdoutChunk: "nginx: configuration ok\nrestar"}, {StdoutChunk: "ted\n"}, {Finished: true}},
+		"web-02": {{StderrChunk: "nginx: [emerg] unknown directive\n"}, {Finished: true, ExitCode: 1}},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		err := runFanOutWithClient(context.Background(), newFanOutMocks(responses), FanOutOptions{
+			Group:        "web",
+			Agents:       []string{"db-01"},
+			Command:      "systemctl restart nginx",
+			Parallel:     2,
+			OutputWriter: &out,
+			ErrorWriter:  &errOut,
+		})
+		assert.EqualError(t, err, "command failed on 2 of 3 agents: web-02, db-01")
+		assert.Contains(t, out.String(), "web-01 | nginx: configuration ok\nweb-01 | restarted\n")
+		assert.Contains(t, errOut.String(), "web-02 | nginx: [emerg] unknown directive\n")
+		assert.Contains(t, out.String(), "agent not found")
+		assert.Contains(t, out.String(), "1/3 agents succeeded")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		err := runFanOutWithClient(context.Background(), newFanOutMocks(responses), FanOutOptions{
+			Group:        "web",
+			Command:      "systemctl restart nginx",
+			OutputFormat: "json",
+			OutputWriter: &out,
+		})
+		assert.Error(t, err)
+
+		var report struct {
+			Agents    []AgentCommandResult `json:"agents"`
+			Succeeded int                  `json:"succeeded"`
+			Failed    int                  `json:"failed"`
+		}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		require.Len(t, report.Agents, 2)
+		assert.Equal(t, "nginx: configuration ok\nrestarted\n", report.Agents[0].Stdout)
+		assert.True(t, report.Agents[0].Success)
+		assert.Equal(t, int32(1), report.Agents[1].ExitCode)
+		assert.Equal(t, 1, report.Succeeded)
+		assert.Equal(t, 1, report.Failed)
+	})
+}
+
+func TestLinePrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &linePrefixWriter{w: &out, mu: &sync.Mutex{}, prefix: "db-01 | "}
+	w.Write([]byte("one\ntw"))
+	assert.Equal(t, "db-01 | one\n", out.String())
+	w.Write([]byte("o\nthree"))
+	w.Flush()
+	assert.Equal(t, "db-01 | one\ndb-01 | two\ndb-01 | three\n", out.String())
+}
@@ -0,0 +1,260 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewFactsCommand creates the agent facts command
+func NewFactsCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "facts",
+		Short: "Inspect agent facts",
+		Long:  `Inspects the facts collected from agents and how they changed over time.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(newFactsHistoryCommand())
+
+	return cmd
+}
+
+func newFactsHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history <agent_name>",
+		Short: "Show how an agent's facts changed over time",
+		Long: `Shows the changes recorded by the master in an agent's facts, such as kernel
+upgrades, IP address changes, added disks or package updates. Volatile values
+like uptime, load and usage are not tracked.
+
+Examples:
+  sloth-runner agent facts history web-01 --since 30d
+  sloth-runner agent facts history web-01 --fact network. -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sinceStr, _ := cmd.Flags().GetString("since")
+			since, err := parseFactsSince(sinceStr, time.Now())
+			if err != nil {
+				return err
+			}
+
+			outputFormat, _ := cmd.Flags().GetString("output")
+			fact, _ := cmd.Flags().GetString("fact")
+			limit, _ := cmd.Flags().GetInt("limit")
+			local, _ := cmd.Flags().GetBool("local")
+
+			opts := FactHistoryOptions{
+				AgentName:    args[0],
+				Since:        since,
+				Fact:         fact,
+				Limit:        limit,
+				OutputFormat: outputFormat,
+				Writer:       os.Stdout,
+			}
+
+			masterAddr := getMasterAddress(cmd)
+			if local || masterAddr == "" {
+				return factHistoryFromLocalDB(opts)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			client, cleanup, err := NewDefaultConnectionFactory().CreateRegistryClient(masterAddr)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			return factHistoryWithClient(ctx, client, opts)
+		},
+	}
+
+	addMasterFlag(cmd)
+	cmd.Flags().String("since", "30d", "Show changes newer than this (e.g. 12h, 7d, 4w); empty for all")
+	cmd.Flags().String("fact", "", "Only show facts starting with this name (e.g. kernel, network.eth0)")
+	cmd.Flags().Int("limit", 0, "Maximum number of changes to show (0 for all)")
+	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	cmd.Flags().Bool("local", false, "Force reading from local database")
+
+	return cmd
+}
+
+// FactHistoryOptions contains options for showing agent fact history
+type FactHistoryOptions struct {
+	AgentName    string
+	Since        time.Time
+	Fact         string
+	Limit        int
+	OutputFormat string
+	Writer       io.Writer
+}
+
+// factHistoryWithClient retrieves fact history using an injected client (testable)
+func factHistoryWithClient(ctx context.Context, client AgentRegistryClient, opts FactHistoryOptions) error {
+	req := &pb.FactHistoryRequest{
+		AgentName: opts.AgentName,
+		Fact:      opts.Fact,
+		Limit:     int32(opts.Limit),
+	}
+	if !opts.Since.IsZero() {
+		req.Since = opts.Since.Unix()
+	}
+
+	resp, err := client.GetFactHistory(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to get fact history: %w", err)
+	}
+
+	return formatFactHistory(resp.GetChanges(), opts)
+}
+
+// factHistoryFromLocalDB reads fact history directly from the local SQLite database
+func factHistoryFromLocalDB(opts FactHistoryOptions) error {
+	dbPath := config.GetAgentDBPath()
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return fmt.Errorf("no local agent database found at: %s", dbPath)
+	}
+
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer sqlitedb.Close(db)
+
+	query := `SELECT agent_name, fact, change, old_value, new_value, changed_at
+		FROM agent_fact_history
+		WHERE agent_name = ? AND changed_at >= ?`
+	args := []interface{}{opts.AgentName, int64(0)}
+	if !opts.Since.IsZero() {
+		args[1] = opts.Since.Unix()
+	}
+	if opts.Fact != "" {
+		query += ` AND substr(fact, 1, ?) = ?`
+		args = append(args, len(opts.Fact), opts.Fact)
+	}
+	query += ` ORDER BY changed_at DESC, id DESC`
+	if opts.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query fact history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*pb.FactChange
+	for rows.Next() {
+		c := &pb.FactChange{}
+		if err := rows.Scan(&c.AgentName, &c.Fact, &c.Change, &c.OldValue, &c.NewValue, &c.ChangedAt); err != nil {
+			return fmt.Errorf("failed to scan fact history row: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read fact history: %w", err)
+	}
+
+	return formatFactHistory(changes, opts)
+}
+
+// formatFactHistory writes fact changes as a table or JSON (testable)
+func formatFactHistory(changes []*pb.FactChange, opts FactHistoryOptions) error {
+	if opts.OutputFormat == "json" {
+		output := make([]map[string]interface{}, 0, len(changes))
+		for _, c := range changes {
+			output = append(output, map[string]interface{}{
+				"agent_name": c.GetAgentName(),
+				"fact":       c.GetFact(),
+				"change":     c.GetChange(),
+				"old_value":  c.GetOldValue(),
+				"new_value":  c.GetNewValue(),
+				"changed_at": c.GetChangedAt(),
+			})
+		}
+		encoder := json.NewEncoder(opts.Writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintf(opts.Writer, "No fact changes recorded for agent %s.\n", opts.AgentName)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(opts.Writer, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "CHANGED AT\tFACT\tCHANGE\tOLD\tNEW")
+	fmt.Fprintln(tw, "----------\t----\t------\t---\t---")
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			formatTimestamp(c.GetChangedAt(), "N/A"),
+			c.GetFact(),
+			formatFactChange(c.GetChange()),
+			factValueOrDash(c.GetOldValue()),
+			factValueOrDash(c.GetNewValue()))
+	}
+
+	return tw.Flush()
+}
+
+func formatFactChange(change string) string {
+	switch change {
+	case "added":
+		return pterm.Green(change)
+	case "removed":
+		return pterm.Red(change)
+	default:
+		return pterm.Yellow(change)
+	}
+}
+
+func factValueOrDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+// parseFactsSince parses a lookback such as 30d, 4w or any Go duration
+// and returns the corresponding point in time. An empty string means no
+// lower bound and returns the zero time.
+func parseFactsSince(since string, now time.Time) (time.Time, error) {
+	since = strings.TrimSpace(since)
+	if since == "" {
+		return time.Time{}, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(since, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(since, suffix))
+			if err != nil || n < 0 {
+				return time.Time{}, fmt.Errorf("invalid --since value %q (use e.g. 12h, 7d, 4w)", since)
+			}
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+
+	d, err := time.ParseDuration(since)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid --since value %q (use e.g. 12h, 7d, 4w)", since)
+	}
+	return now.Add(-d), nil
+}
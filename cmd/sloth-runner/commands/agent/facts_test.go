@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/agent/mocks"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+)
+
+func TestParseFactsSince(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{input: "30d", expected: now.Add(-30 * 24 * time.Hour)},
+		{input: "2w", expected: now.Add(-14 * 24 * time.Hour)},
+		{input: "12h", expected: now.Add(-12 * time.Hour)},
+		{input: "", expected: time.Time{}},
+		{input: "xd", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFactsSince(tt.input, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseFactsSince(%q): expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseFactsSince(%q): unexpected error: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("parseFactsSince(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestFactHistoryWithClient(t *testing.T) {
+	since := time.Unix(1700000000, 0)
+
+	var gotReq *pb.FactHistoryRequest
+	mockClient := mocks.NewMockAgentRegistryClient()
+	mockClient.GetFactHistoryFunc = func(ctx context.Context, in *pb.FactHistoryRequest, opts ...grpc.CallOption) (*pb.FactHistoryResponse, error) {
+		gotReq = in
+		return &pb.FactHistoryResponse{Changes: []*pb.FactChange{
+			{AgentName: "web-01", Fact: "kernel_version", Change: "changed", OldValue: "6.1.0", NewValue: "6.8.0", ChangedAt: 1700000100},
+		}}, nil
+	}
+
+	var buf bytes.Buffer
+	err := factHistoryWithClient(context.Background(), mockClient, FactHistoryOptions{
+		AgentName:    "web-01",
+		Since:        since,
+		Fact:         "kernel",
+		OutputFormat: "json",
+		Writer:       &buf,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if gotReq.AgentName != "web-01" || gotReq.Since != since.Unix() || gotReq.Fact != "kernel" {
+		t.Errorf("Unexpected request: %+v", gotReq)
+	}
+
+	var out []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if len(out) != 1 || out[0]["new_value"] != "6.8.0" {
+		t.Errorf("Unexpected output: %v", out)
+	}
+}
+
+func TestFormatFactHistory_Text(t *testing.T) {
+	var buf bytes.Buffer
+	err := formatFactHistory([]*pb.FactChange{
+		{Fact: "disk./data.device", Change: "added", NewValue: "/dev/sdb1", ChangedAt: 1700000000},
+	}, FactHistoryOptions{AgentName: "web-01", Writer: &buf})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "disk./data.device") || !strings.Contains(buf.String(), "/dev/sdb1") {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+
+	buf.Reset()
+	formatFactHistory(nil, FactHistoryOptions{AgentName: "web-01", Writer: &buf})
+	if !strings.Contains(buf.String(), "No fact changes") {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+}
@@ -10,10 +10,10 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // NewGetCommand creates the agent get command
@@ -96,11 +96,11 @@ func getAgentInfoFromLocalDB(agentName, outputFormat string, debug bool) error {
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	// Query agent
 	query := `SELECT id, name, address, status, last_heartbeat, registered_at, updated_at,
@@ -8,8 +8,8 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
 	"github.com/chalkan3-sloth/sloth-runner/internal/masterdb"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	"github.com/spf13/cobra"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // addMasterFlag adds the --master flag to a command with the correct default value
@@ -93,11 +93,11 @@ func getAgentAddressFromLocalDB(agentName string) (string, error) {
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return "", fmt.Errorf("failed to open database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	// Query agent address
 	var address string
@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// idempotencyTTL is how long the outcome of a keyed request is kept for
+// GetTaskOutcome
+const idempotencyTTL = 10 * time.Minute
+
+// replayTTL is how long the full response of a keyed request is kept for
+// retries of it. Retries come within seconds; later duplicates only learn
+// that the request already ran.
+const replayTTL = 2 * time.Minute
+
+// maxReplayBytes caps the size of the responses kept for retries, which
+// can carry whole workspaces. The oldest are dropped first. It is replaced
+// in tests.
+var maxReplayBytes = 64 << 20
+
+// idempotencyCache runs each keyed request once. A retry of a request that
+// is still running waits for it, and a retry of one that finished gets its
+// outcome, so a task whose response was lost isn't executed again. The
+// zero value is ready to use.
+type idempotencyCache struct {
+	mu          sync.Mutex
+	entries     map[string]*idempotentCall
+	replayBytes int
+}
+
+type idempotentCall struct {
+	done      chan struct{}
+	finished  time.Time
+	forgotten bool
+	// resp is nil once the response was dropped; outcome stays until the
+	// entry expires
+	resp    interface{}
+	size    int
+	err     error
+	outcome *pb.TaskOutcomeResponse
+}
+
+// do runs fn unless a call with the same key ran or is running, and returns
+// its outcome. Requests without a key always run. Calls cancelled with
+// their client are forgotten, as the work was aborted and a retry has to
+// run it, and so are calls refused while the agent reboots. A duplicate
+// arriving after the response was dropped fails with AlreadyExists.
+func (c *idempotencyCache) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	if key == "" {
+		return fn()
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*idempotentCall)
+	}
+	c.expire()
+	if call, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		slog.Info("Duplicate request, waiting for the original outcome", "idempotency_key", key)
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.forgotten {
+			return c.do(ctx, key, fn)
+		}
+		return c.replay(key, call)
+	}
+	call := &idempotentCall{done: make(chan struct{})}
+	c.entries[key] = call
+	c.mu.Unlock()
+
+	resp, err := fn()
+
+	c.mu.Lock()
+	call.resp, call.err = resp, err
+	if errors.Is(err, context.Canceled) || errors.Is(err, errRebooting) || ctx.Err() != nil {
+		call.forgotten = true
+		delete(c.entries, key)
+	} else {
+		call.finished = time.Now()
+		call.outcome = taskOutcome(resp, err)
+		call.size = responseSize(resp)
+		c.replayBytes += call.size
+		c.expire()
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return resp, err
+}
+
+// replay returns the outcome of a finished call to a duplicate of it
+func (c *idempotencyCache) replay(key string, call *idempotentCall) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if call.err == nil && call.resp == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "request %s already ran and its response is no longer kept; GetTaskOutcome reports its outcome", key)
+	}
+	return call.resp, call.err
+}
+
+// outcome returns the call with key once it finished, or whether it is
+// still running. Neither is set for keys the cache doesn't have.
+func (c *idempotencyCache) outcome(key string) (call *idempotentCall, running bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire()
+	call, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if call.finished.IsZero() {
+		return nil, true
+	}
+	return call, false
+}
+
+// expire drops outcomes older than idempotencyTTL, and responses older
+// than replayTTL or past maxReplayBytes. Callers hold c.mu.
+func (c *idempotencyCache) expire() {
+	for key, call := range c.entries {
+		if call.finished.IsZero() {
+			continue
+		}
+		age := time.Since(call.finished)
+		if age > replayTTL {
+			c.dropResponse(call)
+		}
+		if age > idempotencyTTL {
+			delete(c.entries, key)
+		}
+	}
+	for c.replayBytes > maxReplayBytes {
+		var oldest *idempotentCall
+		for _, call := range c.entries {
+			if call.resp != nil && call.size > 0 && (oldest == nil || call.finished.Before(oldest.finished)) {
+				oldest = call
+			}
+		}
+		if oldest == nil {
+			break
+		}
+		c.dropResponse(oldest)
+	}
+}
+
+func (c *idempotencyCache) dropResponse(call *idempotentCall) {
+	if call.resp != nil {
+		c.replayBytes -= call.size
+		call.resp = nil
+	}
+}
+
+// responseSize estimates the memory a response holds, counting the
+// workspace of delegated runs, which dominates it
+func responseSize(resp interface{}) int {
+	switch r := resp.(type) {
+	case proto.Message:
+		return proto.Size(r)
+	case *delegatedRun:
+		return len(r.workspace)
+	}
+	return 0
+}
+
+// taskOutcome summarizes what a finished call returned for GetTaskOutcome
+func taskOutcome(response interface{}, err error) *pb.TaskOutcomeResponse {
+	resp := &pb.TaskOutcomeResponse{State: "finished"}
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	switch r := response.(type) {
+	case *pb.ExecuteTaskResponse:
+		resp.Success = r.GetSuccess()
+		resp.OutputsJson = r.GetOutputsJson()
+		resp.SetOutputsJson = r.GetSetOutputsJson()
+		if !r.GetSuccess() {
+			resp.Error = r.GetOutput()
+		}
+	case *delegatedRun:
+		resp.Success = r.err == nil
+		if r.err != nil {
+			resp.Error = r.err.Error()
+		}
+		for _, result := range r.results {
+			taskResult := &pb.TaskRunResult{
+				TaskName:   result.Name,
+				Status:     result.Status,
+				DurationMs: result.Duration.Milliseconds(),
+			}
+			if result.Error != nil {
+				taskResult.Error = result.Error.Error()
+			}
+			resp.Results = append(resp.Results, taskResult)
+		}
+	}
+	return resp
+}
+
+// GetTaskOutcome tells what became of a task request by its idempotency
+// key, so a run whose runner stopped while waiting for it can be
+// reconciled
+func (s *agentServer) GetTaskOutcome(ctx context.Context, in *pb.TaskOutcomeRequest) (*pb.TaskOutcomeResponse, error) {
+	if in.GetIdempotencyKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency key is required")
+	}
+	call, running := s.idempotency.outcome(in.GetIdempotencyKey())
+	if running {
+		return &pb.TaskOutcomeResponse{State: "running"}, nil
+	}
+	if call == nil {
+		return &pb.TaskOutcomeResponse{State: "unknown"}, nil
+	}
+	return call.outcome, nil
+}
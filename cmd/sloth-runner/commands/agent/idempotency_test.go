@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIdempotencyCache_RunsKeyOnce(t *testing.T) {
+	var c idempotencyCache
+	var runs int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&runs, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "done", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.do(context.Background(), "key-1", fn)
+			if err != nil || resp != "done" {
+				t.Errorf("Unexpected outcome: %v, %v", resp, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := c.do(context.Background(), "key-1", fn); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("Expected one run for a key, got %d", runs)
+	}
+
+	c.do(context.Background(), "key-2", fn)
+	c.do(context.Background(), "", fn)
+	c.do(context.Background(), "", fn)
+	if runs != 4 {
+		t.Errorf("Expected other keys and unkeyed requests to run, got %d runs", runs)
+	}
+}
+
+func TestIdempotencyCache_ForgetsCancelledCalls(t *testing.T) {
+	var c idempotencyCache
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.do(ctx, "key", func() (interface{}, error) { return nil, ctx.Err() })
+
+	ran := false
+	c.do(context.Background(), "key", func() (interface{}, error) {
+		ran = true
+		return nil, nil
+	})
+	if !ran {
+		t.Error("Expected a retry of a cancelled call to run")
+	}
+}
+
+func TestGetTaskOutcome(t *testing.T) {
+	s := &agentServer{}
+	outcome := func(key string) *pb.TaskOutcomeResponse {
+		resp, err := s.GetTaskOutcome(context.Background(), &pb.TaskOutcomeRequest{IdempotencyKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if state := outcome("never-sent").State; state != "unknown" {
+		t.Errorf("Expected an unknown key to be unknown, got %s", state)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go s.idempotency.do(context.Background(), "deploy", func() (interface{}, error) {
+		close(started)
+		<-release
+		return &pb.ExecuteTaskResponse{Success: true, OutputsJson: `{"version":"1.2.3","token":"s3cr3t"}`, SetOutputsJson: `{"token":true}`}, nil
+	})
+	<-started
+	if state := outcome("deploy").State; state != "running" {
+		t.Errorf("Expected a task still running to be running, got %s", state)
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	resp := outcome("deploy")
+	for resp.State == "running" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		resp = outcome("deploy")
+	}
+	if resp.State != "finished" || !resp.Success || resp.OutputsJson != `{"version":"1.2.3","token":"s3cr3t"}` {
+		t.Errorf("Unexpected outcome of a finished task: %v", resp)
+	}
+	if resp.SetOutputsJson != `{"token":true}` {
+		t.Errorf("Expected the outcome to name the sensitive output, got %q", resp.SetOutputsJson)
+	}
+
+	// Tasks aborted with their runner are forgotten: the agent didn't finish them
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.idempotency.do(ctx, "aborted", func() (interface{}, error) { return nil, ctx.Err() })
+	if state := outcome("aborted").State; state != "unknown" {
+		t.Errorf("Expected an aborted task to be unknown, got %s", state)
+	}
+}
+
+func TestIdempotencyCache_CapsKeptResponses(t *testing.T) {
+	defer func(n int) { maxReplayBytes = n }(maxReplayBytes)
+	maxReplayBytes = 1 << 10
+
+	s := &agentServer{}
+	big := func() (interface{}, error) {
+		return &pb.ExecuteTaskResponse{Success: true, Workspace: make([]byte, 800), OutputsJson: `{"ok":true}`}, nil
+	}
+	s.idempotency.do(context.Background(), "first", big)
+	s.idempotency.do(context.Background(), "second", big)
+
+	// The oldest response went over the cap; its duplicate doesn't run the
+	// task again
+	_, err := s.idempotency.do(context.Background(), "first", func() (interface{}, error) {
+		t.Error("Expected the duplicate not to run")
+		return nil, nil
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists for a dropped response, got %v", err)
+	}
+	resp, err := s.idempotency.do(context.Background(), "second", big)
+	if err != nil || len(resp.(*pb.ExecuteTaskResponse).GetWorkspace()) != 800 {
+		t.Errorf("Expected the newest response to be replayed, got %v", err)
+	}
+	if s.idempotency.replayBytes > maxReplayBytes {
+		t.Errorf("Expected at most %d bytes kept, got %d", maxReplayBytes, s.idempotency.replayBytes)
+	}
+
+	// The outcome stays for reconciliation
+	outcome, err := s.GetTaskOutcome(context.Background(), &pb.TaskOutcomeRequest{IdempotencyKey: "first"})
+	if err != nil || outcome.GetState() != "finished" || !outcome.GetSuccess() || outcome.GetOutputsJson() != `{"ok":true}` {
+		t.Errorf("Expected the finished outcome, got %+v, %v", outcome, err)
+	}
+}
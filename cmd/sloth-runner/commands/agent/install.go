@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh"
@@ -80,6 +81,10 @@ type InstallOptions struct {
 	BindAddress   string
 	Port          int
 	ReportAddress string
+	// JoinToken is passed to the agent to register with, see install-command
+	JoinToken string
+	// TLS are the mTLS files the agent serves and connects with
+	TLS mtls.Files
 }
 
 func installAgent(agentName string, opts InstallOptions) error {
@@ -279,21 +284,48 @@ func createSystemdService(client *ssh.Client, agentName string, opts InstallOpti
 		reportAddr = fmt.Sprintf("%s:%d", opts.SSHHost, opts.Port)
 	}
 
-	// Create systemd service content
-	serviceContent := fmt.Sprintf(`[Unit]
+	serviceContent := systemdUnit(agentName, opts, reportAddr)
+
+	// Create service file
+	servicePath := fmt.Sprintf("/etc/systemd/system/sloth-runner-agent-%s.service", agentName)
+	createServiceScript := fmt.Sprintf(`
+cat > %s << 'SLOTH_SERVICE_EOF'
+%s
+SLOTH_SERVICE_EOF
+chmod 644 %s
+`, servicePath, serviceContent, servicePath)
+
+	_, err := runSSHCommand(client, createServiceScript)
+	return err
+}
+
+// systemdUnit returns the systemd service of an agent
+func systemdUnit(agentName string, opts InstallOptions, reportAddr string) string {
+	var flags string
+	for _, arg := range agentServiceArgs(agentName, opts) {
+		flags += fmt.Sprintf(" \\\n  %s", arg)
+	}
+
+	return fmt.Sprintf(`[Unit]
 Description=Sloth Runner Agent - %s
 After=network.target
 Wants=network-online.target
 
 [Service]
-Type=simple
+Type=notify
+NotifyAccess=main
+WatchdogSec=60
+# Tasks run in cgroups of their own, which account for what they consume
+Delegate=yes
 ExecStart=/usr/local/bin/sloth-runner agent start \
   --name %s \
   --bind-address %s \
   --port %d \
   --master %s \
   --report-address %s \
-  --daemon=false
+  --daemon=false%s
+# Re-reads the agent config file
+ExecReload=/bin/kill -HUP $MAINPID
 Restart=always
 RestartSec=5
 StandardOutput=journal
@@ -302,19 +334,21 @@ SyslogIdentifier=sloth-runner-agent-%s
 
 [Install]
 WantedBy=multi-user.target
-`, agentName, agentName, opts.BindAddress, opts.Port, opts.MasterAddr, reportAddr, agentName)
-
-	// Create service file
-	servicePath := fmt.Sprintf("/etc/systemd/system/sloth-runner-agent-%s.service", agentName)
-	createServiceScript := fmt.Sprintf(`
-cat > %s << 'SLOTH_SERVICE_EOF'
-%s
-SLOTH_SERVICE_EOF
-chmod 644 %s
-`, servicePath, serviceContent, servicePath)
+`, agentName, agentName, opts.BindAddress, opts.Port, opts.MasterAddr, reportAddr, flags, agentName)
+}
 
-	_, err := runSSHCommand(client, createServiceScript)
-	return err
+// agentServiceArgs returns the flags of an agent service beyond its
+// addresses: the file its join token is in, which the agent removes once
+// it registered, and its mTLS files
+func agentServiceArgs(agentName string, opts InstallOptions) []string {
+	var args []string
+	if opts.JoinToken != "" {
+		args = append(args, "--join-token-file "+joinTokenFile(agentName))
+	}
+	if opts.TLS.Enabled() {
+		args = append(args, "--tls-cert "+opts.TLS.CertFile, "--tls-key "+opts.TLS.KeyFile, "--tls-ca "+opts.TLS.CAFile)
+	}
+	return args
 }
 
 // enableAndStartService enables and starts the systemd service
@@ -0,0 +1,469 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/apitoken"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/jointoken"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/spf13/cobra"
+)
+
+// joinTokenEnv is the environment variable agents read their join token from
+const joinTokenEnv = "SLOTH_RUNNER_JOIN_TOKEN"
+
+// agentConfigDir is where the bootstrap script writes the join token and
+// the mTLS files of the agent
+const agentConfigDir = "/etc/sloth-runner"
+
+// agentCertificateValidity is how long the certificates issued for
+// bootstrapped agents are valid, as with 'cert issue'
+const agentCertificateValidity = 8760 * time.Hour
+
+// installTargets are the platforms releases are published for
+var installTargets = []string{"linux-amd64", "linux-arm64", "darwin-amd64", "darwin-arm64"}
+
+var (
+	agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	addressPattern   = regexp.MustCompile(`^[A-Za-z0-9.:\[\]-]+$`)
+)
+
+// clipboardCommands are tried in order by --copy
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// NewInstallCommandCommand creates the agent install-command command
+func NewInstallCommandCommand(ctx *commands.AppContext) *cobra.Command {
+	var opts InstallOptions
+	var name, target, version, ttl, checksum, tlsDir string
+	var useSudo, copyToClipboard, insecure bool
+
+	cmd := &cobra.Command{
+		Use:   "install-command",
+		Short: "Print a one-line command that bootstraps an agent on a new host",
+		Long: `Prints a single command to paste into a shell on the new host. It
+downloads the sloth-runner release for the host's platform, installs the
+agent as a service (systemd on Linux, launchd on macOS) and starts it.
+
+The command embeds a one-time join token that the agent registers with.
+The token is bound to the agent name and must be used before it expires;
+the agent then gets a credential of its own from the master and the token
+can't be used again. Tokens are kept in the master's data directory, so
+run this command on the master's host, as the user running the master.
+
+The script checks the downloaded release against its SHA-256, taken from
+the checksums published with the release or given with --sha256.
+
+When the master's CA (see 'sloth-runner cert init') is in --tls-dir, a
+certificate is issued for the agent, valid for --report-address, and the
+agent serves and connects with mTLS. Without a CA, or with --insecure, the
+agent accepts tasks from any host that can reach it.
+
+The bootstrap script is base64 encoded so the command survives copy and
+paste, chat clients and terminals unchanged. Only the command is written to
+stdout; decode it to review the script before running it.`,
+		Example: `  # Bootstrap a Raspberry Pi
+  sloth-runner agent install-command --name web-01 --master 10.0.0.1:50053 --os linux-arm64 --report-address 10.0.0.21:50051
+
+  # Copy the command to the clipboard
+  sloth-runner agent install-command --name mac-01 --master 10.0.0.1:50053 --os darwin-arm64 --copy
+
+  # Review the script
+  sloth-runner agent install-command --name web-01 --master 10.0.0.1:50053 | cut -d' ' -f2 | base64 --decode`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateInstallCommand(name, opts, target); err != nil {
+				return err
+			}
+			tokenTTL, err := apitoken.ParseTTL(ttl)
+			if err != nil {
+				return err
+			}
+			if version == "" {
+				version = ctx.Version
+			}
+			if version == "" || version == "dev" {
+				if version, err = getLatestReleaseVersion(); err != nil {
+					return fmt.Errorf("failed to fetch latest version, set --version: %w", err)
+				}
+			}
+			if !strings.HasPrefix(version, "v") {
+				version = "v" + version
+			}
+			if checksum, err = archiveChecksum(checksum, archiveName(target, version), version); err != nil {
+				return err
+			}
+
+			// Agents redeem their token with the master, which opens the
+			// store in its data directory
+			if _, err := os.Stat(config.GetJoinTokensDBPath()); err != nil {
+				return fmt.Errorf("no join token store at %s: run install-command on the master's host, as the user running the master, or set SLOTH_RUNNER_DATA_DIR to the master's data directory", config.GetJoinTokensDBPath())
+			}
+
+			var files []bootstrapFile
+			if !insecure {
+				if files, err = agentCertificate(tlsDir, name, &opts); err != nil {
+					return err
+				}
+				if files == nil {
+					fmt.Fprintf(os.Stderr, "No CA in %s: the agent will run without mTLS. Create one with 'sloth-runner cert init', or pass --insecure.\n", tlsDir)
+				}
+			}
+
+			store, err := jointoken.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			secret, t, err := store.Create(name, tokenTTL)
+			if err != nil {
+				return err
+			}
+			opts.JoinToken = secret
+			files = append(files, bootstrapFile{Path: joinTokenFile(name), Content: secret})
+
+			line := installOneLiner(bootstrapScript(name, opts, target, version, checksum, files), useSudo)
+			fmt.Fprintln(cmd.OutOrStdout(), line)
+			fmt.Fprintf(os.Stderr, "Join token %s for agent %s expires %s\n",
+				t.ID, t.Agent, t.ExpiresAt.Local().Format(time.RFC1123))
+
+			if copyToClipboard {
+				if err := copyText(ctx, line); err != nil {
+					return err
+				}
+				fmt.Fprintln(os.Stderr, "Copied to the clipboard.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Name of the new agent (required)")
+	cmd.Flags().StringVar(&opts.MasterAddr, "master", "", "Master address the agent registers with (required)")
+	cmd.Flags().StringVar(&target, "os", "linux-amd64", "Platform of the host: "+strings.Join(installTargets, ", "))
+	cmd.Flags().StringVar(&opts.BindAddress, "bind-address", "0.0.0.0", "Agent bind address")
+	cmd.Flags().IntVar(&opts.Port, "port", 50051, "Agent port")
+	cmd.Flags().StringVar(&opts.ReportAddress, "report-address", "", "Address the agent reports to the master (default: the host's first address)")
+	cmd.Flags().StringVar(&version, "version", "", "Release to install (default: this binary's version, or the latest)")
+	cmd.Flags().StringVar(&ttl, "ttl", "1h", "How long the join token can be used, e.g. 1h or 2d")
+	cmd.Flags().StringVar(&checksum, "sha256", "", "SHA-256 of the release archive (default: from the release's checksums)")
+	cmd.Flags().StringVar(&tlsDir, "tls-dir", config.GetTLSDir(), "Certificate directory with the CA issuing the agent's mTLS certificate")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "Install the agent without mTLS")
+	cmd.Flags().BoolVar(&useSudo, "sudo", true, "Run the script with sudo")
+	cmd.Flags().BoolVar(&copyToClipboard, "copy", false, "Also copy the command to the clipboard")
+
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("master")
+
+	return cmd
+}
+
+// validateInstallCommand rejects values that would need quoting in the
+// generated script
+func validateInstallCommand(name string, opts InstallOptions, target string) error {
+	if !agentNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid agent name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	for flag, addr := range map[string]string{"master": opts.MasterAddr, "bind-address": opts.BindAddress, "report-address": opts.ReportAddress} {
+		if addr != "" && !addressPattern.MatchString(addr) {
+			return fmt.Errorf("invalid --%s %q", flag, addr)
+		}
+	}
+	for _, t := range installTargets {
+		if t == target {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported --os %q (use %s)", target, strings.Join(installTargets, ", "))
+}
+
+// joinTokenFile returns the file the bootstrap script writes the join
+// token of an agent to
+func joinTokenFile(agentName string) string {
+	return agentConfigDir + "/join-token-" + agentName
+}
+
+// archiveName returns the name of the release archive for a platform
+func archiveName(target, version string) string {
+	platform, arch, _ := strings.Cut(target, "-")
+	return fmt.Sprintf("sloth-runner_%s_%s_%s.tar.gz", version, platform, arch)
+}
+
+// archiveChecksum returns the SHA-256 of a release archive: sum if set,
+// otherwise the one in the checksums published with the release
+func archiveChecksum(sum, archive, version string) (string, error) {
+	if sum != "" {
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return "", fmt.Errorf("invalid --sha256 %q: not a SHA-256 hex digest", sum)
+		}
+		return strings.ToLower(sum), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	release, err := lookupRelease(ctx, version)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up release %s, set --sha256: %w", version, err)
+	}
+	var url string
+	for _, asset := range release.Assets {
+		if strings.HasSuffix(asset.Name, "checksums.txt") {
+			url = asset.URL
+		}
+	}
+	if url == "" {
+		return "", fmt.Errorf("release %s publishes no checksums, set --sha256", version)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download the checksums of %s: %w", version, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download the checksums of %s: %s", version, resp.Status)
+	}
+
+	// Lines are "<sha256>  <file>", as sha256sum writes them
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == archive {
+			return archiveChecksum(fields[0], archive, version)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read the checksums of %s: %w", version, err)
+	}
+	return "", fmt.Errorf("the checksums of %s don't list %s, set --sha256", version, archive)
+}
+
+// bootstrapFile is a file the bootstrap script writes, readable by root only
+type bootstrapFile struct {
+	Path    string
+	Content string
+}
+
+// agentCertificate issues a certificate for an agent with the CA in dir,
+// valid for its report address, and sets the files the agent uses in opts.
+// It returns the files for the bootstrap script to write, none when dir
+// has no CA.
+func agentCertificate(dir, name string, opts *InstallOptions) ([]bootstrapFile, error) {
+	if _, err := os.Stat(filepath.Join(dir, mtls.CAKeyFile)); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if opts.ReportAddress == "" {
+		return nil, fmt.Errorf("the agent's mTLS certificate must be valid for the address the master connects to: set --report-address, or pass --insecure")
+	}
+	host, _, err := net.SplitHostPort(opts.ReportAddress)
+	if err != nil {
+		host = opts.ReportAddress
+	}
+	hosts := []string{host}
+	for _, local := range []string{"localhost", "127.0.0.1"} {
+		if local != host {
+			hosts = append(hosts, local)
+		}
+	}
+	if err := mtls.Issue(dir, name, hosts, agentCertificateValidity); err != nil {
+		return nil, err
+	}
+
+	tlsDir := agentConfigDir + "/tls"
+	opts.TLS = mtls.Files{
+		CertFile: tlsDir + "/" + name + ".crt",
+		KeyFile:  tlsDir + "/" + name + ".key",
+		CAFile:   tlsDir + "/" + mtls.CACertFile,
+	}
+	var files []bootstrapFile
+	for _, f := range [][2]string{
+		{mtls.CertFile(dir, name), opts.TLS.CertFile},
+		{mtls.KeyFile(dir, name), opts.TLS.KeyFile},
+		{filepath.Join(dir, mtls.CACertFile), opts.TLS.CAFile},
+	} {
+		data, err := os.ReadFile(f[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the agent's certificate: %w", err)
+		}
+		files = append(files, bootstrapFile{Path: f[1], Content: string(data)})
+	}
+	return files, nil
+}
+
+// bootstrapScript returns the shell script that installs and starts an
+// agent, once the release archive matched checksum and files are written
+func bootstrapScript(name string, opts InstallOptions, target, version, checksum string, files []bootstrapFile) string {
+	platform, _, _ := strings.Cut(target, "-")
+	url := fmt.Sprintf("https://github.com/chalkan3-sloth/sloth-runner/releases/download/%s/%s", version, archiveName(target, version))
+
+	detectAddress := "hostname -I 2>/dev/null | awk '{print $1}'"
+	if platform == "darwin" {
+		detectAddress = "ipconfig getifaddr en0 || ipconfig getifaddr en1"
+	}
+	reportAddress := fmt.Sprintf(`report_address=%s
+`, opts.ReportAddress)
+	if opts.ReportAddress == "" {
+		reportAddress = fmt.Sprintf(`host_address=$(%s || true)
+if [ -z "$host_address" ]; then
+  echo "Could not detect the address of this host, use --report-address" >&2
+  exit 1
+fi
+report_address="$host_address:%d"
+`, detectAddress, opts.Port)
+	}
+
+	var service string
+	if platform == "darwin" {
+		label := "com.sloth-runner.agent." + name
+		service = fmt.Sprintf(`plist=/Library/LaunchDaemons/%s.plist
+cat > "$plist" <<SLOTH_SERVICE_EOF
+%sSLOTH_SERVICE_EOF
+launchctl bootout system/%s 2>/dev/null || true
+launchctl bootstrap system "$plist"
+`, label, heredocBody(launchdPlist(label, name, opts, "@REPORT_ADDRESS@")), label)
+	} else {
+		service = fmt.Sprintf(`cat > /etc/systemd/system/sloth-runner-agent-%s.service <<SLOTH_SERVICE_EOF
+%sSLOTH_SERVICE_EOF
+systemctl daemon-reload
+systemctl enable sloth-runner-agent-%s
+systemctl restart sloth-runner-agent-%s
+`, name, heredocBody(systemdUnit(name, opts, "@REPORT_ADDRESS@")), name, name)
+	}
+
+	var written strings.Builder
+	dirs := map[string]bool{}
+	for _, f := range files {
+		if dir := filepath.Dir(f.Path); !dirs[dir] {
+			dirs[dir] = true
+			fmt.Fprintf(&written, "mkdir -p %s\n", dir)
+		}
+		fmt.Fprintf(&written, "cat > %s <<'SLOTH_FILE_EOF'\n%s\nSLOTH_FILE_EOF\n", f.Path, strings.TrimRight(f.Content, "\n"))
+	}
+
+	return fmt.Sprintf(`set -e
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+echo "Installing sloth-runner %s for %s..."
+curl -fsSL "%s" -o "$tmp/sloth-runner.tar.gz"
+if command -v sha256sum >/dev/null 2>&1; then
+  checksum=$(sha256sum "$tmp/sloth-runner.tar.gz" | awk '{print $1}')
+else
+  checksum=$(shasum -a 256 "$tmp/sloth-runner.tar.gz" | awk '{print $1}')
+fi
+if [ "$checksum" != "%s" ]; then
+  echo "The downloaded archive doesn't match the release checksum" >&2
+  exit 1
+fi
+tar -xzf "$tmp/sloth-runner.tar.gz" -C "$tmp"
+mkdir -p /usr/local/bin
+install -m 0755 "$tmp/sloth-runner" /usr/local/bin/sloth-runner
+%s# The join token and the mTLS key are readable by root only
+umask 077
+%s%secho "Agent %s started, registering with %s as $report_address"
+`, version, target, url, checksum, reportAddress, written.String(), service, name, opts.MasterAddr)
+}
+
+// launchdPlist returns the launchd daemon of an agent
+func launchdPlist(label, agentName string, opts InstallOptions, reportAddr string) string {
+	var args strings.Builder
+	for _, arg := range agentServiceArgs(agentName, opts) {
+		flag, value, _ := strings.Cut(arg, " ")
+		fmt.Fprintf(&args, "    <string>%s</string>\n    <string>%s</string>\n", flag, value)
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>%s</string>
+  <key>ProgramArguments</key>
+  <array>
+    <string>/usr/local/bin/sloth-runner</string>
+    <string>agent</string>
+    <string>start</string>
+    <string>--name</string>
+    <string>%s</string>
+    <string>--bind-address</string>
+    <string>%s</string>
+    <string>--port</string>
+    <string>%d</string>
+    <string>--master</string>
+    <string>%s</string>
+    <string>--report-address</string>
+    <string>%s</string>
+    <string>--daemon=false</string>
+%s  </array>
+  <key>RunAtLoad</key>
+  <true/>
+  <key>KeepAlive</key>
+  <true/>
+  <key>StandardOutPath</key>
+  <string>/var/log/sloth-runner-agent-%s.log</string>
+  <key>StandardErrorPath</key>
+  <string>/var/log/sloth-runner-agent-%s.log</string>
+</dict>
+</plist>
+`, label, agentName, opts.BindAddress, opts.Port, opts.MasterAddr, reportAddr, args.String(), agentName, agentName)
+}
+
+// heredocBody escapes text for an unquoted here-document, in which only
+// the @REPORT_ADDRESS@ placeholder is expanded by the shell
+func heredocBody(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "$", `\$`, "`", "\\`").Replace(text)
+	return strings.ReplaceAll(escaped, "@REPORT_ADDRESS@", "${report_address}")
+}
+
+// installOneLiner wraps a script in a command made of characters that
+// survive copy and paste: base64 has no quotes, spaces or shell syntax
+func installOneLiner(script string, useSudo bool) string {
+	shell := "sh"
+	if useSudo {
+		shell = "sudo sh"
+	}
+	return fmt.Sprintf("echo %s | base64 --decode | %s", base64.StdEncoding.EncodeToString([]byte(script)), shell)
+}
+
+// copyText copies text to the clipboard with the first clipboard tool found
+func copyText(ctx *commands.AppContext, text string) error {
+	execCommand := exec.Command
+	if ctx.ExecCommand != nil {
+		execCommand = ctx.ExecCommand
+	}
+	for _, args := range clipboardCommands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		c := execCommand(args[0], args[1:]...)
+		c.Stdin = strings.NewReader(text)
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("failed to copy with %s: %w", args[0], err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no clipboard tool found (pbcopy, wl-copy, xclip, xsel or clip.exe)")
+}
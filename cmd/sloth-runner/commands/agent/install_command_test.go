@@ -0,0 +1,266 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/jointoken"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
+)
+
+// testChecksum stands for the SHA-256 of a release archive
+var testChecksum = strings.Repeat("ab", 32)
+
+// newMasterStore creates the join token store a master opens on start
+func newMasterStore(t *testing.T) {
+	t.Helper()
+	store, err := jointoken.DefaultStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+}
+
+func decodeOneLiner(t *testing.T, line string) string {
+	t.Helper()
+	fields := strings.Fields(line)
+	if len(fields) != 8 || fields[0] != "echo" || strings.Join(fields[2:], " ") != "| base64 --decode | sudo sh" {
+		t.Fatalf("Unexpected command: %q", line)
+	}
+	script, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		t.Fatalf("Failed to decode script: %v", err)
+	}
+	return string(script)
+}
+
+func TestInstallCommand(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", dataDir)
+	newMasterStore(t)
+
+	cmd := NewInstallCommandCommand(&commands.AppContext{Version: "6.3.0"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--name", "web-01", "--master", "10.0.0.1:50053", "--os", "linux-arm64", "--sha256", testChecksum})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	script := decodeOneLiner(t, strings.TrimSpace(out.String()))
+	for _, want := range []string{
+		"/download/v6.3.0/sloth-runner_v6.3.0_linux_arm64.tar.gz",
+		`if [ "$checksum" != "` + testChecksum + `" ]`,
+		"--master 10.0.0.1:50053",
+		"--report-address ${report_address}",
+		"sloth-runner-agent-web-01.service",
+		"cat > /etc/sloth-runner/join-token-web-01 <<'SLOTH_FILE_EOF'\n" + jointoken.Prefix,
+		"--join-token-file /etc/sloth-runner/join-token-web-01",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Script doesn't contain %q:\n%s", want, script)
+		}
+	}
+	// The token isn't kept in the service, whose definition outlives it
+	if strings.Contains(script, "Environment="+joinTokenEnv) {
+		t.Errorf("Expected the join token to stay out of the service:\n%s", script)
+	}
+
+	if sh, err := exec.LookPath("sh"); err == nil {
+		if output, err := exec.Command(sh, "-n", "-c", script).CombinedOutput(); err != nil {
+			t.Errorf("Script isn't valid shell: %v\n%s", err, output)
+		}
+	}
+
+	// The embedded token registers web-01 and nothing else
+	start := strings.Index(script, jointoken.Prefix)
+	token := strings.Fields(script[start:])[0]
+	store, err := jointoken.DefaultStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, _, err := store.Redeem(token, "web-02"); err == nil {
+		t.Error("Expected the token to be bound to web-01")
+	}
+	if _, _, err := store.Redeem(token, "web-01"); err != nil {
+		t.Errorf("Failed to redeem token: %v", err)
+	}
+}
+
+func TestInstallCommand_MTLS(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	newMasterStore(t)
+	if err := mtls.NewCA(config.GetTLSDir(), "test CA", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"--name", "web-01", "--master", "10.0.0.1:50053", "--sha256", testChecksum}
+	cmd := NewInstallCommandCommand(&commands.AppContext{Version: "6.3.0"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--report-address") {
+		t.Errorf("Expected the certificate to need the report address, got %v", err)
+	}
+
+	cmd = NewInstallCommandCommand(&commands.AppContext{Version: "6.3.0"})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs(append(args, "--report-address", "10.0.0.21:50051"))
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	script := decodeOneLiner(t, strings.TrimSpace(out.String()))
+	for _, want := range []string{
+		"cat > /etc/sloth-runner/tls/web-01.crt <<'SLOTH_FILE_EOF'\n-----BEGIN CERTIFICATE-----",
+		"cat > /etc/sloth-runner/tls/web-01.key <<'SLOTH_FILE_EOF'\n-----BEGIN",
+		"cat > /etc/sloth-runner/tls/ca.crt <<'SLOTH_FILE_EOF'\n-----BEGIN CERTIFICATE-----",
+		"--tls-cert /etc/sloth-runner/tls/web-01.crt",
+		"--tls-key /etc/sloth-runner/tls/web-01.key",
+		"--tls-ca /etc/sloth-runner/tls/ca.crt",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Script doesn't contain %q:\n%s", want, script)
+		}
+	}
+
+	cert, err := mtls.ReadCertificate(mtls.CertFile(config.GetTLSDir(), "web-01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hosts := mtls.Hosts(cert); !strings.Contains(strings.Join(hosts, " "), "10.0.0.21") {
+		t.Errorf("Expected the certificate to be valid for the report address, got %v", hosts)
+	}
+}
+
+func TestInstallCommand_NotOnMaster(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+
+	cmd := NewInstallCommandCommand(&commands.AppContext{Version: "6.3.0"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--name", "web-01", "--master", "10.0.0.1:50053", "--sha256", testChecksum})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "no join token store") {
+		t.Errorf("Expected an error without the master's token store, got %v", err)
+	}
+}
+
+func TestArchiveChecksum(t *testing.T) {
+	archive := "sloth-runner_v6.3.0_linux_arm64.tar.gz"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  sloth-runner_v6.3.0_linux_amd64.tar.gz\n%s  %s\n", strings.Repeat("cd", 32), strings.ToUpper(testChecksum), archive)
+	}))
+	defer srv.Close()
+
+	saved := lookupRelease
+	defer func() { lookupRelease = saved }()
+	lookupRelease = func(ctx context.Context, tag string) (*releases.Release, error) {
+		return &releases.Release{TagName: tag, Assets: []releases.Asset{
+			{Name: archive, URL: srv.URL + "/" + archive},
+			{Name: "sloth-runner_v6.3.0_checksums.txt", URL: srv.URL + "/checksums.txt"},
+		}}, nil
+	}
+
+	if sum, err := archiveChecksum("", archive, "v6.3.0"); err != nil || sum != testChecksum {
+		t.Errorf("archiveChecksum = %q, %v; want %q", sum, err, testChecksum)
+	}
+	if _, err := archiveChecksum("", "sloth-runner_v6.3.0_darwin_arm64.tar.gz", "v6.3.0"); err == nil {
+		t.Error("Expected an error for an archive the checksums don't list")
+	}
+	if _, err := archiveChecksum("abc", archive, "v6.3.0"); err == nil {
+		t.Error("Expected an error for an invalid --sha256")
+	}
+
+	lookupRelease = func(ctx context.Context, tag string) (*releases.Release, error) {
+		return nil, errors.New("offline")
+	}
+	if _, err := archiveChecksum("", archive, "v6.3.0"); err == nil || !strings.Contains(err.Error(), "--sha256") {
+		t.Errorf("Expected the error to point at --sha256, got %v", err)
+	}
+}
+
+func TestInstallCommand_Invalid(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+
+	tests := [][]string{
+		{"--name", "web 01", "--master", "10.0.0.1:50053"},
+		{"--name", "web-01", "--master", "10.0.0.1:50053;reboot"},
+		{"--name", "web-01", "--master", "10.0.0.1:50053", "--os", "windows-amd64"},
+		{"--name", "web-01", "--master", "10.0.0.1:50053", "--ttl", "soon"},
+		{"--name", "web-01", "--master", "10.0.0.1:50053", "--sha256", "abc"},
+	}
+	for _, args := range tests {
+		cmd := NewInstallCommandCommand(&commands.AppContext{Version: "6.3.0"})
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestBootstrapScript_Darwin(t *testing.T) {
+	opts := InstallOptions{
+		MasterAddr:    "10.0.0.1:50053",
+		BindAddress:   "0.0.0.0",
+		Port:          50051,
+		ReportAddress: "10.0.0.7:50051",
+		JoinToken:     "slj_abc_def",
+	}
+	files := []bootstrapFile{{Path: joinTokenFile("mac-01"), Content: opts.JoinToken}}
+	script := bootstrapScript("mac-01", opts, "darwin-arm64", "v6.3.0", testChecksum, files)
+
+	for _, want := range []string{
+		"sloth-runner_v6.3.0_darwin_arm64.tar.gz",
+		"shasum -a 256",
+		"report_address=10.0.0.7:50051",
+		"/Library/LaunchDaemons/com.sloth-runner.agent.mac-01.plist",
+		"<string>${report_address}</string>",
+		"<string>--join-token-file</string>\n    <string>/etc/sloth-runner/join-token-mac-01</string>",
+		"cat > /etc/sloth-runner/join-token-mac-01 <<'SLOTH_FILE_EOF'\nslj_abc_def\nSLOTH_FILE_EOF",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Script doesn't contain %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "EnvironmentVariables") {
+		t.Error("Expected the join token to stay out of the launchd daemon")
+	}
+	if strings.Contains(script, "systemctl") {
+		t.Error("Expected no systemd commands on darwin")
+	}
+}
+
+func TestHeredocBody(t *testing.T) {
+	got := heredocBody("a \\\n$HOME `id` @REPORT_ADDRESS@")
+	want := "a \\\\\n\\$HOME \\`id\\` ${report_address}"
+	if got != want {
+		t.Errorf("heredocBody = %q, want %q", got, want)
+	}
+
+	// The shell writes the text back unchanged, with the address expanded
+	if sh, err := exec.LookPath("sh"); err == nil {
+		script := "report_address=10.0.0.7\ncat <<EOF\n" + got + "\nEOF\n"
+		out, err := exec.Command(sh, "-c", script).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != "a \\\n$HOME `id` 10.0.0.7\n" {
+			t.Errorf("Shell output = %q", out)
+		}
+	}
+}
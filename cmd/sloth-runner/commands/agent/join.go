@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/spf13/cobra"
+)
+
+// joinOptions configure how the agent registers with a master that checks
+// join tokens. The token from 'agent install-command' is redeemed once; the
+// master then issues a credential, kept in the data directory, that the
+// agent registers with after restarts.
+type joinOptions struct {
+	Token string
+	// TokenFile holds the token. It is removed once the agent got its
+	// credential, as the token can't be used again.
+	TokenFile string
+}
+
+func addJoinFlags(cmd *cobra.Command) {
+	cmd.Flags().String("join-token", os.Getenv(joinTokenEnv), "One-time token from 'agent install-command' to register with (env "+joinTokenEnv+")")
+	cmd.Flags().String("join-token-file", "", "File holding the join token, removed once the agent registered")
+}
+
+func getJoinOptions(cmd *cobra.Command) joinOptions {
+	opts := joinOptions{}
+	opts.Token, _ = cmd.Flags().GetString("join-token")
+	opts.TokenFile, _ = cmd.Flags().GetString("join-token-file")
+	return opts
+}
+
+// daemonArgs returns the flags needed to forward the options to a daemon process
+func (o joinOptions) daemonArgs() []string {
+	var args []string
+	// A token from the environment is inherited, keeping it out of ps
+	if o.Token != "" && o.Token != os.Getenv(joinTokenEnv) {
+		args = append(args, "--join-token", o.Token)
+	}
+	if o.TokenFile != "" {
+		args = append(args, "--join-token-file", o.TokenFile)
+	}
+	return args
+}
+
+// token returns the join token, "" if there is none
+func (o joinOptions) token() (string, error) {
+	if o.Token != "" || o.TokenFile == "" {
+		return o.Token, nil
+	}
+	data, err := os.ReadFile(o.TokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read join token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// apply sets what agentName registers with: its credential once it has
+// one, the join token before
+func (o joinOptions) apply(agentName string, req *pb.RegisterAgentRequest) error {
+	credential, err := os.ReadFile(config.GetAgentCredentialPath(agentName))
+	if err == nil {
+		req.AgentCredential = strings.TrimSpace(string(credential))
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read agent credential: %w", err)
+	}
+	req.JoinToken, err = o.token()
+	return err
+}
+
+// saveCredential keeps the credential the master issued for agentName and
+// removes the join token file, whose token is now used up
+func (o joinOptions) saveCredential(agentName, credential string) error {
+	path := config.GetAgentCredentialPath(agentName)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create credential directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(credential+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to save agent credential: %w", err)
+	}
+	if o.TokenFile != "" {
+		if err := os.Remove(o.TokenFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove join token file: %w", err)
+		}
+	}
+	return nil
+}
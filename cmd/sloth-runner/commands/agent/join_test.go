@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+func TestJoinOptions_Credential(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	tokenFile := filepath.Join(t.TempDir(), "join-token-web-01")
+	if err := os.WriteFile(tokenFile, []byte("slj_abc_def\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts := joinOptions{TokenFile: tokenFile}
+
+	req := &pb.RegisterAgentRequest{AgentName: "web-01"}
+	if err := opts.apply("web-01", req); err != nil {
+		t.Fatal(err)
+	}
+	if req.JoinToken != "slj_abc_def" || req.AgentCredential != "" {
+		t.Errorf("Expected the join token from the file before the first registration, got %v", req)
+	}
+
+	if err := opts.saveCredential("web-01", "slc_secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tokenFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the used join token file to be removed, got %v", err)
+	}
+
+	req = &pb.RegisterAgentRequest{AgentName: "web-01"}
+	if err := opts.apply("web-01", req); err != nil {
+		t.Fatal(err)
+	}
+	if req.AgentCredential != "slc_secret" || req.JoinToken != "" {
+		t.Errorf("Expected the credential after the first registration, got %v", req)
+	}
+
+	// Other agents on the host have credentials of their own
+	req = &pb.RegisterAgentRequest{AgentName: "web-02"}
+	if err := opts.apply("web-02", req); err != nil {
+		t.Fatal(err)
+	}
+	if req.AgentCredential != "" {
+		t.Errorf("Expected no credential for web-02, got %q", req.AgentCredential)
+	}
+}
+
+func TestJoinOptions_DaemonArgs(t *testing.T) {
+	t.Setenv(joinTokenEnv, "slj_env")
+
+	opts := joinOptions{Token: "slj_env", TokenFile: "/etc/sloth-runner/join-token-web-01"}
+	args := opts.daemonArgs()
+	if len(args) != 2 || args[0] != "--join-token-file" {
+		t.Errorf("Expected the token from the environment to be inherited, got %v", args)
+	}
+
+	opts.Token = "slj_flag"
+	if args := opts.daemonArgs(); len(args) != 4 || args[1] != "slj_flag" {
+		t.Errorf("Expected the token of --join-token to be passed on, got %v", args)
+	}
+}
@@ -2,7 +2,6 @@ package agent
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log/slog"
 	"os"
@@ -10,9 +9,9 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // NewListCommand creates the agent list command
@@ -90,11 +89,11 @@ func listAgentsFromLocalDB(debug bool) error {
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	// Query agents
 	query := `SELECT id, name, address, status, last_heartbeat, registered_at, updated_at,
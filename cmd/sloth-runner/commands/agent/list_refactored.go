@@ -39,8 +39,8 @@ func formatAgentsTable(agents []*pb.AgentInfo, w io.Writer) error {
 	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
 
 	// Write header
-	fmt.Fprintln(tw, "AGENT NAME\tADDRESS\tSTATUS\tVERSION\tUPDATE STATUS\tLAST HEARTBEAT\tLAST INFO COLLECTED")
-	fmt.Fprintln(tw, "------------\t----------\t------\t-------\t-------------\t--------------\t-------------------")
+	fmt.Fprintln(tw, "AGENT NAME\tADDRESS\tSTATUS\tCIRCUIT\tVERSION\tUPDATE STATUS\tLAST HEARTBEAT\tLAST INFO COLLECTED")
+	fmt.Fprintln(tw, "------------\t----------\t------\t-------\t-------\t-------------\t--------------\t-------------------")
 
 	// Write agent rows
 	for _, agent := range agents {
@@ -58,10 +58,11 @@ func formatAgentsTable(agents []*pb.AgentInfo, w io.Writer) error {
 		// TODO: Implement version comparison logic
 		updateStatus := "-"
 
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			agent.GetAgentName(),
 			agent.GetAgentAddress(),
 			coloredStatus,
+			formatCircuitState(agent.GetCircuitState()),
 			version,
 			updateStatus,
 			lastHeartbeat,
@@ -79,6 +80,21 @@ func formatStatus(status string) string {
 	return pterm.Red(status)
 }
 
+// formatCircuitState formats the master's circuit breaker state for an
+// agent with color (testable)
+func formatCircuitState(state string) string {
+	switch state {
+	case "":
+		return "-"
+	case "closed":
+		return pterm.Green(state)
+	case "half-open":
+		return pterm.Yellow(state)
+	default:
+		return pterm.Red(state)
+	}
+}
+
 // formatTimestamp formats a unix timestamp or returns default value (testable)
 func formatTimestamp(timestamp int64, defaultValue string) string {
 	if timestamp > 0 {
@@ -189,6 +189,18 @@ func TestFormatAgentsTable(t *testing.T) {
 	}
 }
 
+// Test formatCircuitState function
+func TestFormatCircuitState(t *testing.T) {
+	if got := formatCircuitState(""); got != "-" {
+		t.Errorf("Expected - for an unknown state, got %q", got)
+	}
+	for _, state := range []string{"closed", "half-open", "open"} {
+		if got := formatCircuitState(state); !strings.Contains(got, state) {
+			t.Errorf("Expected result to contain %q, got %q", state, got)
+		}
+	}
+}
+
 // Test formatStatus function
 func TestFormatStatus(t *testing.T) {
 	tests := []struct {
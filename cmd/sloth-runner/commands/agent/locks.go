@@ -0,0 +1,25 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/locks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+)
+
+// configureLocks makes lock.acquire on this agent take locks on the master,
+// scoped to the agent's name
+func configureLocks(masterAddr, agentName string) error {
+	conn, err := grpc.Dial(masterAddr, mtls.DialOption())
+	if err != nil {
+		return fmt.Errorf("failed to connect to master for locks: %w", err)
+	}
+	locks.SetDefault(&locks.Client{
+		Registry: pb.NewAgentRegistryClient(conn),
+		Scope:    agentName,
+		Holder:   agentName,
+	})
+	return nil
+}
@@ -17,6 +17,8 @@ type MockAgentRegistryClient struct {
 	UnregisterAgentFunc func(ctx context.Context, in *pb.UnregisterAgentRequest, opts ...grpc.CallOption) (*pb.UnregisterAgentResponse, error)
 	RegisterAgentFunc   func(ctx context.Context, in *pb.RegisterAgentRequest, opts ...grpc.CallOption) (*pb.RegisterAgentResponse, error)
 	HeartbeatFunc       func(ctx context.Context, in *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error)
+	GetFactHistoryFunc  func(ctx context.Context, in *pb.FactHistoryRequest, opts ...grpc.CallOption) (*pb.FactHistoryResponse, error)
+	ListAgentGroupsFunc func(ctx context.Context, in *pb.ListGroupsRequest, opts ...grpc.CallOption) (*pb.ListGroupsResponse, error)
 }
 
 func (m *MockAgentRegistryClient) RegisterAgent(ctx context.Context, in *pb.RegisterAgentRequest, opts ...grpc.CallOption) (*pb.RegisterAgentResponse, error) {
@@ -69,6 +71,20 @@ func (m *MockAgentRegistryClient) Heartbeat(ctx context.Context, in *pb.Heartbea
 	return &pb.HeartbeatResponse{}, nil
 }
 
+func (m *MockAgentRegistryClient) GetFactHistory(ctx context.Context, in *pb.FactHistoryRequest, opts ...grpc.CallOption) (*pb.FactHistoryResponse, error) {
+	if m.GetFactHistoryFunc != nil {
+		return m.GetFactHistoryFunc(ctx, in, opts...)
+	}
+	return &pb.FactHistoryResponse{}, nil
+}
+
+func (m *MockAgentRegistryClient) ListAgentGroups(ctx context.Context, in *pb.ListGroupsRequest, opts ...grpc.CallOption) (*pb.ListGroupsResponse, error) {
+	if m.ListAgentGroupsFunc != nil {
+		return m.ListAgentGroupsFunc(ctx, in, opts...)
+	}
+	return &pb.ListGroupsResponse{}, nil
+}
+
 func (m *MockAgentRegistryClient) GetAgentInfo(ctx context.Context, in *pb.GetAgentInfoRequest, opts ...grpc.CallOption) (*pb.GetAgentInfoResponse, error) {
 	if m.GetAgentInfoFunc != nil {
 		return m.GetAgentInfoFunc(ctx, in, opts...)
@@ -117,6 +133,9 @@ type MockAgentClient struct {
 	ListWatchersFunc    func(ctx context.Context, in *pb.ListWatchersRequest, opts ...grpc.CallOption) (*pb.ListWatchersResponse, error)
 	GetWatcherFunc      func(ctx context.Context, in *pb.GetWatcherRequest, opts ...grpc.CallOption) (*pb.GetWatcherResponse, error)
 	RemoveWatcherFunc   func(ctx context.Context, in *pb.RemoveWatcherRequest, opts ...grpc.CallOption) (*pb.RemoveWatcherResponse, error)
+	VerifyToolchainFunc func(ctx context.Context, in *pb.VerifyToolchainRequest, opts ...grpc.CallOption) (*pb.VerifyToolchainResponse, error)
+	GetConfigFunc       func(ctx context.Context, in *pb.GetConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error)
+	ReloadConfigFunc    func(ctx context.Context, in *pb.ReloadConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error)
 }
 
 func (m *MockAgentClient) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest, opts ...grpc.CallOption) (*pb.ExecuteTaskResponse, error) {
@@ -183,6 +202,27 @@ func (m *MockAgentClient) RemoveWatcher(ctx context.Context, in *pb.RemoveWatche
 	}, nil
 }
 
+func (m *MockAgentClient) VerifyToolchain(ctx context.Context, in *pb.VerifyToolchainRequest, opts ...grpc.CallOption) (*pb.VerifyToolchainResponse, error) {
+	if m.VerifyToolchainFunc != nil {
+		return m.VerifyToolchainFunc(ctx, in, opts...)
+	}
+	return &pb.VerifyToolchainResponse{}, nil
+}
+
+func (m *MockAgentClient) GetConfig(ctx context.Context, in *pb.GetConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error) {
+	if m.GetConfigFunc != nil {
+		return m.GetConfigFunc(ctx, in, opts...)
+	}
+	return &pb.AgentConfigResponse{}, nil
+}
+
+func (m *MockAgentClient) ReloadConfig(ctx context.Context, in *pb.ReloadConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error) {
+	if m.ReloadConfigFunc != nil {
+		return m.ReloadConfigFunc(ctx, in, opts...)
+	}
+	return &pb.AgentConfigResponse{}, nil
+}
+
 // NewMockAgentRegistryClient creates a new mock with default implementations
 func NewMockAgentRegistryClient() *MockAgentRegistryClient {
 	return &MockAgentRegistryClient{}
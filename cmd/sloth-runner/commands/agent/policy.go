@@ -0,0 +1,26 @@
+package agent
+
+import (
+	"os/user"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/policy"
+	"github.com/pterm/pterm"
+)
+
+// configurePolicy activates the admission policies checked before
+// privileged module calls on this agent. Agents cannot prompt, so policies
+// requiring approval deny.
+func configurePolicy(policyFile, agentName string) error {
+	engine, err := policy.LoadDefault(policyFile, config.GetPolicyPath())
+	if err != nil || engine == nil {
+		return err
+	}
+	base := policy.Input{Agent: agentName}
+	if u, err := user.Current(); err == nil {
+		base.User = u.Username
+	}
+	policy.SetActive(policy.NewGate(engine, base, nil, nil))
+	pterm.Success.Println("✓ Admission policies loaded")
+	return nil
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os/exec"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errRebooting is returned to task requests while the agent drains for a
+// reboot
+var errRebooting = status.Error(codes.Unavailable, "agent is rebooting")
+
+const (
+	// rebootDelay leaves time for the response to reach the master before
+	// the host goes down
+	rebootDelay = 2 * time.Second
+	// defaultDrainTimeout is how long a drain waits when none is given
+	defaultDrainTimeout = 5 * time.Minute
+	// rebootDrainPollInterval is how often a drain checks for finished tasks
+	rebootDrainPollInterval = 250 * time.Millisecond
+)
+
+// rebootHost reboots the host, replaced in tests
+var rebootHost = func() error {
+	if path, err := exec.LookPath("systemctl"); err == nil {
+		return exec.Command(path, "reboot").Run()
+	}
+	return exec.Command("shutdown", "-r", "now").Run()
+}
+
+// Reboot reboots the host after the running tasks finished when asked to
+// drain. The reboot starts shortly after the response is sent.
+func (s *agentServer) Reboot(ctx context.Context, in *pb.RebootRequest) (*pb.RebootResponse, error) {
+	if !s.rebooting.CompareAndSwap(false, true) {
+		return nil, status.Error(codes.FailedPrecondition, "agent is already rebooting")
+	}
+
+	if in.GetDrain() {
+		timeout := time.Duration(in.GetDrainTimeoutSeconds()) * time.Second
+		if timeout <= 0 {
+			timeout = defaultDrainTimeout
+		}
+		if running := s.drainTasks(ctx, timeout); running > 0 {
+			s.rebooting.Store(false)
+			return nil, status.Errorf(codes.FailedPrecondition, "%d task requests still running after %s, not rebooting", running, timeout)
+		}
+	}
+
+	slog.Warn("Rebooting host", "agent", s.name, "drained", in.GetDrain())
+	go func() {
+		time.Sleep(rebootDelay)
+		if err := rebootHost(); err != nil {
+			slog.Error("Failed to reboot host", "agent", s.name, "error", err)
+			s.rebooting.Store(false)
+		}
+	}()
+	return &pb.RebootResponse{}, nil
+}
+
+// drainTasks waits until no task requests are running, for up to timeout.
+// New task requests are refused while rebooting is set. It returns the
+// number of requests still running.
+func (s *agentServer) drainTasks(ctx context.Context, timeout time.Duration) int32 {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(rebootDrainPollInterval)
+	defer ticker.Stop()
+	for {
+		running := s.running.Load()
+		if running == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return running
+		case <-ticker.C:
+		}
+	}
+}
+
+// enterTask counts a task request as running, failing while the agent
+// drains for a reboot
+func (s *agentServer) enterTask() error {
+	s.running.Add(1)
+	if s.rebooting.Load() {
+		s.running.Add(-1)
+		return errRebooting
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func stubRebootHost(t *testing.T) chan struct{} {
+	t.Helper()
+	rebooted := make(chan struct{}, 1)
+	orig := rebootHost
+	rebootHost = func() error {
+		rebooted <- struct{}{}
+		return nil
+	}
+	t.Cleanup(func() { rebootHost = orig })
+	return rebooted
+}
+
+func TestReboot_DrainsRunningTasks(t *testing.T) {
+	rebooted := stubRebootHost(t)
+	s := &agentServer{name: "web-01"}
+
+	// A task request is running and finishes during the drain
+	if err := s.enterTask(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		s.running.Add(-1)
+	}()
+
+	if _, err := s.Reboot(context.Background(), &pb.RebootRequest{Drain: true, DrainTimeoutSeconds: 5}); err != nil {
+		t.Fatalf("Reboot failed: %v", err)
+	}
+	if err := s.enterTask(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected new tasks to be refused while rebooting, got %v", err)
+	}
+	select {
+	case <-rebooted:
+	case <-time.After(rebootDelay + time.Second):
+		t.Fatal("Expected the host to reboot")
+	}
+}
+
+func TestReboot_RefusedWhileTasksRun(t *testing.T) {
+	rebooted := stubRebootHost(t)
+	s := &agentServer{name: "web-01"}
+	if err := s.enterTask(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.Reboot(context.Background(), &pb.RebootRequest{Drain: true, DrainTimeoutSeconds: 1})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected the reboot to be refused, got %v", err)
+	}
+	if err := s.enterTask(); err != nil {
+		t.Errorf("Expected tasks to be accepted again after a refused reboot, got %v", err)
+	}
+	select {
+	case <-rebooted:
+		t.Error("Expected the host not to reboot")
+	case <-time.After(rebootDelay + 500*time.Millisecond):
+	}
+}
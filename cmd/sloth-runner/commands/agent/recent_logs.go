@@ -0,0 +1,506 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Logs are read newest first and sent in pages of max_lines entries, the
+// page token holding where the previous page stopped: a journal cursor, a
+// line of the syslog file or the time of an event of the Windows event
+// logs. A page also ends once its messages reach
+// maxLogPageBytes so that it stays well below the gRPC message limit.
+const (
+	defaultLogPageLines = 100
+	maxLogPageLines     = 1000
+	maxLogPageBytes     = 1 << 20
+)
+
+// syslogPath is read when the journal isn't available
+var syslogPath = "/var/log/syslog"
+
+// logLevels are the names of the syslog priorities, most severe first
+var logLevels = []string{"EMERG", "ALERT", "CRITICAL", "ERROR", "WARNING", "NOTICE", "INFO", "DEBUG"}
+
+// logPriority returns the syslog priority of a level name or number
+func logPriority(level string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "0", "emerg", "emergency":
+		return 0, true
+	case "1", "alert":
+		return 1, true
+	case "2", "crit", "critical":
+		return 2, true
+	case "3", "err", "error":
+		return 3, true
+	case "4", "warn", "warning":
+		return 4, true
+	case "5", "notice":
+		return 5, true
+	case "6", "info":
+		return 6, true
+	case "7", "debug":
+		return 7, true
+	}
+	return 0, false
+}
+
+// logQuery selects the log entries sent by the log RPCs
+type logQuery struct {
+	maxPriority int // -1 for any level
+	pattern     *regexp.Regexp
+	since       int64
+	until       int64
+}
+
+// newLogQuery validates the filters of a log request
+func newLogQuery(level, pattern string, since, until int64) (*logQuery, error) {
+	q := &logQuery{maxPriority: -1, since: since, until: until}
+	if level != "" {
+		priority, ok := logPriority(level)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown log level %q", level)
+		}
+		q.maxPriority = priority
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid pattern: %v", err)
+		}
+		q.pattern = re
+	}
+	if since > 0 && until > 0 && until < since {
+		return nil, status.Error(codes.InvalidArgument, "until_timestamp is before since_timestamp")
+	}
+	return q, nil
+}
+
+// matches tells whether an entry passes the filters; entries without a
+// known level count as INFO
+func (q *logQuery) matches(e *pb.LogEntry) bool {
+	if q.maxPriority >= 0 {
+		priority, ok := logPriority(e.Level)
+		if !ok {
+			priority = 6
+		}
+		if priority > q.maxPriority {
+			return false
+		}
+	}
+	if e.Timestamp > 0 {
+		if q.since > 0 && e.Timestamp < q.since {
+			return false
+		}
+		if q.until > 0 && e.Timestamp > q.until {
+			return false
+		}
+	}
+	return q.pattern == nil || q.pattern.MatchString(e.Message)
+}
+
+// logReader reads log entries newest first
+type logReader interface {
+	// next returns the next older entry and the position to resume after
+	// it, io.EOF when there are none left
+	next() (*pb.LogEntry, string, error)
+	Close() error
+}
+
+// openLogReader opens the journal, or the syslog file when there is no
+// journal, where the page token left off. Windows agents read the event
+// logs.
+func openLogReader(ctx context.Context, in *pb.RecentLogsRequest) (logReader, error) {
+	if in.PageToken == "" {
+		if runtime.GOOS == "windows" {
+			return openEventLogReader(ctx, in, 0), nil
+		}
+		if _, err := exec.LookPath("journalctl"); err == nil {
+			if r, err := openJournalReader(ctx, in, ""); err == nil {
+				return r, nil
+			}
+		}
+		return openSyslogReader(in.SourceFilter, -1)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(in.PageToken)
+	kind, pos, _ := strings.Cut(string(raw), ":")
+	if err != nil || pos == "" {
+		return nil, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	switch kind {
+	case "journal":
+		return openJournalReader(ctx, in, pos)
+	case "syslog":
+		line, err := strconv.Atoi(pos)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		return openSyslogReader(in.SourceFilter, line)
+	case "eventlog":
+		before, err := strconv.ParseInt(pos, 10, 64)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		return openEventLogReader(ctx, in, before), nil
+	}
+	return nil, status.Error(codes.InvalidArgument, "invalid page token")
+}
+
+func logPageToken(kind, pos string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + ":" + pos))
+}
+
+// journalReader reads the output of journalctl in reverse order
+type journalReader struct {
+	cmd    *exec.Cmd
+	out    *bufio.Reader
+	stderr bytes.Buffer
+	after  string // Cursor of the entry the previous page ended with
+	read   bool
+}
+
+func openJournalReader(ctx context.Context, in *pb.RecentLogsRequest, cursor string) (*journalReader, error) {
+	args := []string{"-r", "-o", "json", "--no-pager"}
+	if in.LevelFilter != "" {
+		if priority, ok := logPriority(in.LevelFilter); ok {
+			args = append(args, "-p", strconv.Itoa(priority))
+		}
+	}
+	if in.SourceFilter != "" {
+		args = append(args, "-u", in.SourceFilter)
+	}
+	if in.SinceTimestamp > 0 {
+		args = append(args, "--since", fmt.Sprintf("@%d", in.SinceTimestamp))
+	}
+	if in.UntilTimestamp > 0 {
+		args = append(args, "--until", fmt.Sprintf("@%d", in.UntilTimestamp))
+	}
+	if cursor != "" {
+		args = append(args, "--cursor", cursor)
+	}
+
+	r := &journalReader{cmd: exec.CommandContext(ctx, "journalctl", args...), after: cursor}
+	r.cmd.Stderr = &r.stderr
+	stdout, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start journalctl: %w", err)
+	}
+	r.out = bufio.NewReaderSize(stdout, 64*1024)
+	return r, nil
+}
+
+func (r *journalReader) next() (*pb.LogEntry, string, error) {
+	for {
+		line, err := r.out.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err == nil {
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				if werr := r.cmd.Wait(); werr != nil && !r.read {
+					return nil, "", fmt.Errorf("journalctl failed: %v: %s", werr, strings.TrimSpace(r.stderr.String()))
+				}
+				return nil, "", io.EOF
+			}
+			return nil, "", err
+		}
+
+		entry, cursor, perr := parseJournalEntry(line)
+		if perr != nil {
+			continue
+		}
+		// --cursor starts at the entry the previous page ended with
+		if cursor == r.after {
+			continue
+		}
+		r.read = true
+		return entry, logPageToken("journal", cursor), nil
+	}
+}
+
+func (r *journalReader) Close() error {
+	if r.cmd.ProcessState == nil && r.cmd.Process != nil {
+		r.cmd.Process.Kill()
+		r.cmd.Wait()
+	}
+	return nil
+}
+
+// parseJournalEntry parses a line of `journalctl -o json`
+func parseJournalEntry(line []byte) (*pb.LogEntry, string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return nil, "", err
+	}
+
+	entry := &pb.LogEntry{Level: "INFO", Message: journalField(fields, "MESSAGE")}
+	if usec, err := strconv.ParseInt(journalField(fields, "__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		entry.Timestamp = usec / 1e6
+	}
+	if priority, err := strconv.Atoi(journalField(fields, "PRIORITY")); err == nil && priority >= 0 && priority < len(logLevels) {
+		entry.Level = logLevels[priority]
+	}
+	for _, key := range []string{"_SYSTEMD_UNIT", "SYSLOG_IDENTIFIER", "_COMM"} {
+		if source := journalField(fields, key); source != "" {
+			entry.Source = source
+			break
+		}
+	}
+	return entry, journalField(fields, "__CURSOR"), nil
+}
+
+// journalField returns a field of a journal entry, which is a string, or
+// an array of bytes when it isn't valid UTF-8
+func journalField(fields map[string]json.RawMessage, key string) string {
+	raw, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+	}
+	return string(b)
+}
+
+// syslogReader reads the lines of the syslog file from the end
+type syslogReader struct {
+	lines  []string
+	line   int
+	source string
+	now    time.Time
+}
+
+// openSyslogReader reads the lines before the given one, or from the end
+// when it is negative
+func openSyslogReader(source string, before int) (*syslogReader, error) {
+	data, err := os.ReadFile(syslogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &syslogReader{}, nil
+		}
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	if before < 0 || before > len(lines) {
+		before = len(lines)
+	}
+	return &syslogReader{lines: lines, line: before, source: source, now: time.Now()}, nil
+}
+
+func (r *syslogReader) next() (*pb.LogEntry, string, error) {
+	for r.line > 0 {
+		r.line--
+		if strings.TrimSpace(r.lines[r.line]) == "" {
+			continue
+		}
+		entry := parseSyslogLine(r.lines[r.line], r.now)
+		if r.source != "" && strings.TrimSuffix(r.source, ".service") != entry.Source {
+			continue
+		}
+		return entry, logPageToken("syslog", strconv.Itoa(r.line)), nil
+	}
+	return nil, "", io.EOF
+}
+
+func (r *syslogReader) Close() error { return nil }
+
+// syslogLine matches the program of a syslog line after its time and host:
+// "Oct 17 10:00:00 web-01 nginx[812]: message"
+var syslogLine = regexp.MustCompile(`^\S+\s+([^\s:\[]+)(?:\[\d+\])?:\s?(.*)$`)
+
+// parseSyslogLine parses a line in the RFC 3339 or traditional syslog
+// format; lines it can't parse are kept whole, without a timestamp
+func parseSyslogLine(line string, now time.Time) *pb.LogEntry {
+	entry := &pb.LogEntry{Level: "INFO", Message: line, Source: "syslog"}
+
+	var rest string
+	if stamp, after, ok := strings.Cut(line, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+			entry.Timestamp = t.Unix()
+			rest = after
+		}
+	}
+	if entry.Timestamp == 0 && len(line) > 16 {
+		if t, err := time.ParseInLocation(time.Stamp, line[:15], now.Location()); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			entry.Timestamp = t.Unix()
+			rest = line[16:]
+		}
+	}
+
+	if m := syslogLine.FindStringSubmatch(rest); m != nil {
+		entry.Source = m[1]
+		entry.Message = m[2]
+	}
+	return entry
+}
+
+// logPager splits the entries of a reader into pages
+type logPager struct {
+	r       logReader
+	q       *logQuery
+	source  string
+	pending *pb.LogEntry
+	pos     string
+	// fallback is set until the first entry of a journal opened without a
+	// page token: if it can't be read, the syslog file is read instead
+	fallback bool
+}
+
+// page returns the next page of at most size entries, in chronological
+// order
+func (p *logPager) page(size int) (*pb.RecentLogsResponse, error) {
+	var logs []*pb.LogEntry
+	var token string
+	pageBytes := 0
+	hasMore := false
+
+	for {
+		entry, pos := p.pending, p.pos
+		p.pending = nil
+		if entry == nil {
+			var err error
+			entry, pos, err = p.r.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil && p.fallback {
+				p.r.Close()
+				if p.r, err = openSyslogReader(p.source, -1); err != nil {
+					return nil, err
+				}
+				p.fallback = false
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			p.fallback = false
+			// Entries are newest first: the rest are too old
+			if p.q.since > 0 && entry.Timestamp > 0 && entry.Timestamp < p.q.since {
+				break
+			}
+			if !p.q.matches(entry) {
+				continue
+			}
+		}
+		if len(logs) == size || (len(logs) > 0 && pageBytes+len(entry.Message) > maxLogPageBytes) {
+			p.pending, p.pos = entry, pos
+			hasMore = true
+			break
+		}
+		logs = append(logs, entry)
+		pageBytes += len(entry.Message)
+		token = pos
+	}
+
+	for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+		logs[i], logs[j] = logs[j], logs[i]
+	}
+	resp := &pb.RecentLogsResponse{Logs: logs, TotalCount: int32(len(logs)), HasMore: hasMore}
+	if hasMore {
+		resp.NextPageToken = token
+	}
+	return resp, nil
+}
+
+// logPageLines returns the page size of a request
+func logPageLines(in *pb.RecentLogsRequest) int {
+	switch {
+	case in.MaxLines <= 0:
+		return defaultLogPageLines
+	case in.MaxLines > maxLogPageLines:
+		return maxLogPageLines
+	}
+	return int(in.MaxLines)
+}
+
+// newLogPager opens the logs a request asks for
+func newLogPager(ctx context.Context, in *pb.RecentLogsRequest) (*logPager, error) {
+	q, err := newLogQuery(in.LevelFilter, in.Pattern, in.SinceTimestamp, in.UntilTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	r, err := openLogReader(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	_, isJournal := r.(*journalReader)
+	return &logPager{r: r, q: q, source: in.SourceFilter, fallback: isJournal && in.PageToken == ""}, nil
+}
+
+// GetRecentLogs returns a page of the system logs, newest first
+func (s *agentServer) GetRecentLogs(ctx context.Context, in *pb.RecentLogsRequest) (*pb.RecentLogsResponse, error) {
+	p, err := newLogPager(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { p.r.Close() }()
+
+	page, err := p.page(logPageLines(in))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read logs: %v", err)
+	}
+	return page, nil
+}
+
+// StreamRecentLogs sends the pages of the system logs one after another;
+// each carries the token to resume from should the stream break
+func (s *agentServer) StreamRecentLogs(in *pb.RecentLogsRequest, stream pb.Agent_StreamRecentLogsServer) error {
+	p, err := newLogPager(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	defer func() { p.r.Close() }()
+
+	sent := 0
+	for {
+		size := logPageLines(in)
+		if in.Limit > 0 && int(in.Limit)-sent < size {
+			size = int(in.Limit) - sent
+		}
+		page, err := p.page(size)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read logs: %v", err)
+		}
+		if err := stream.Send(page); err != nil {
+			return err
+		}
+		sent += len(page.Logs)
+		if !page.HasMore || (in.Limit > 0 && sent >= int(in.Limit)) {
+			return nil
+		}
+	}
+}
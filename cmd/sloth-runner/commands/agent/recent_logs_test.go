@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeLogReader returns its entries from the last one
+type fakeLogReader struct {
+	entries []*pb.LogEntry
+	read    int
+}
+
+func (r *fakeLogReader) next() (*pb.LogEntry, string, error) {
+	if r.read == len(r.entries) {
+		return nil, "", io.EOF
+	}
+	r.read++
+	i := len(r.entries) - r.read
+	return r.entries[i], fmt.Sprint(i), nil
+}
+
+func (r *fakeLogReader) Close() error { return nil }
+
+func messages(logs []*pb.LogEntry) string {
+	var m []string
+	for _, e := range logs {
+		m = append(m, e.Message)
+	}
+	return strings.Join(m, ",")
+}
+
+func TestLogPager(t *testing.T) {
+	var entries []*pb.LogEntry
+	for i := 1; i <= 7; i++ {
+		level := "INFO"
+		if i%2 == 0 {
+			level = "ERROR"
+		}
+		entries = append(entries, &pb.LogEntry{Timestamp: int64(100 + i), Level: level, Message: fmt.Sprintf("m%d", i)})
+	}
+
+	q, err := newLogQuery("", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &logPager{r: &fakeLogReader{entries: entries}, q: q}
+	var pages []string
+	for {
+		page, err := p.page(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, messages(page.Logs))
+		if page.HasMore != (page.NextPageToken != "") {
+			t.Errorf("has_more = %v with token %q", page.HasMore, page.NextPageToken)
+		}
+		if !page.HasMore {
+			break
+		}
+	}
+	if got := strings.Join(pages, " | "); got != "m5,m6,m7 | m2,m3,m4 | m1" {
+		t.Errorf("pages = %s", got)
+	}
+
+	q, _ = newLogQuery("err", `m[2-6]`, 103, 106)
+	p = &logPager{r: &fakeLogReader{entries: entries}, q: q}
+	page, _ := p.page(10)
+	if got := messages(page.Logs); got != "m4,m6" || page.HasMore {
+		t.Errorf("filtered page = %s, has_more %v", got, page.HasMore)
+	}
+}
+
+func TestLogPager_ByteLimit(t *testing.T) {
+	big := strings.Repeat("x", maxLogPageBytes/2+1)
+	entries := []*pb.LogEntry{{Message: big}, {Message: big}, {Message: big}}
+	q, _ := newLogQuery("", "", 0, 0)
+	p := &logPager{r: &fakeLogReader{entries: entries}, q: q}
+
+	page, err := p.page(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Logs) != 1 || !page.HasMore {
+		t.Errorf("page has %d entries, has_more %v; want 1 and more", len(page.Logs), page.HasMore)
+	}
+}
+
+func TestNewLogQuery_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		level, pattern string
+		since, until   int64
+	}{
+		{level: "loud"},
+		{pattern: "("},
+		{since: 200, until: 100},
+	} {
+		_, err := newLogQuery(tc.level, tc.pattern, tc.since, tc.until)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("newLogQuery(%+v) = %v, want InvalidArgument", tc, err)
+		}
+	}
+}
+
+func TestParseJournalEntry(t *testing.T) {
+	line := `{"__CURSOR":"s=abc;i=1","__REALTIME_TIMESTAMP":"1760695200123456","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service","MESSAGE":[104,105]}`
+	entry, cursor, err := parseJournalEntry([]byte(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != "s=abc;i=1" || entry.Timestamp != 1760695200 || entry.Level != "ERROR" || entry.Source != "nginx.service" || entry.Message != "hi" {
+		t.Errorf("entry = %+v, cursor %q", entry, cursor)
+	}
+}
+
+func TestParseWinEvents(t *testing.T) {
+	// System events come before Application ones, each newest first
+	data := `[{"t":1760695200500,"l":2,"p":"Service Control Manager","m":"The W3SVC service terminated.\r\n"},` +
+		`{"t":1760695100000,"l":4,"p":"Service Control Manager","m":"started"},` +
+		`{"t":1760695150000,"l":3,"p":"Application Error","m":"faulting"}]`
+	events, err := parseWinEvents([]byte(data), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Time != 1760695200500 || events[1].Provider != "Application Error" {
+		t.Fatalf("events = %+v", events)
+	}
+	entry := winEventEntry(events[0])
+	if entry.Timestamp != 1760695200 || entry.Level != "ERROR" || entry.Source != "Service Control Manager" || entry.Message != "The W3SVC service terminated." {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry := winEventEntry(events[1]); entry.Level != "WARNING" {
+		t.Errorf("level = %s, want WARNING", entry.Level)
+	}
+}
+
+func TestEventLogScript(t *testing.T) {
+	script := eventLogScript("O'Brien", []int{1, 2}, 1000, 5000, 50)
+	for _, want := range []string{
+		"@('System','Application')",
+		"ProviderName='O''Brien'",
+		"Level=1,2",
+		"FromUnixTimeMilliseconds(1000)",
+		"FromUnixTimeMilliseconds(4999)",
+		"-MaxEvents 50",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script lacks %q:\n%s", want, script)
+		}
+	}
+	if script := eventLogScript("", nil, 0, 0, 50); strings.Contains(script, "StartTime") || strings.Contains(script, "Level=") {
+		t.Errorf("unexpected filters:\n%s", script)
+	}
+}
+
+func TestParseSyslogLine(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	entry := parseSyslogLine("2026-01-02T10:00:00.5+00:00 web-01 nginx[812]: upstream timed out", now)
+	if entry.Timestamp != time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC).Unix() || entry.Source != "nginx" || entry.Message != "upstream timed out" {
+		t.Errorf("RFC 3339 line = %+v", entry)
+	}
+
+	// December is last year's in January
+	entry = parseSyslogLine("Dec 31 23:59:00 web-01 cron: job done", now)
+	if entry.Timestamp != time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC).Unix() || entry.Source != "cron" {
+		t.Errorf("traditional line = %+v", entry)
+	}
+
+	entry = parseSyslogLine("garbage", now)
+	if entry.Timestamp != 0 || entry.Message != "garbage" {
+		t.Errorf("unparsed line = %+v", entry)
+	}
+}
+
+// fakeRecentLogsStream collects the pages sent by StreamRecentLogs
+type fakeRecentLogsStream struct {
+	grpc.ServerStream
+	pages []*pb.RecentLogsResponse
+}
+
+func (s *fakeRecentLogsStream) Context() context.Context { return context.Background() }
+
+func (s *fakeRecentLogsStream) Send(page *pb.RecentLogsResponse) error {
+	s.pages = append(s.pages, page)
+	return nil
+}
+
+func TestRecentLogs_SyslogResume(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 5; i++ {
+		lines = append(lines, fmt.Sprintf("2026-01-02T10:00:0%d+00:00 web-01 app: line %d", i, i))
+	}
+	path := filepath.Join(t.TempDir(), "syslog")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orig := syslogPath
+	syslogPath = path
+	t.Cleanup(func() { syslogPath = orig })
+
+	s := &agentServer{}
+	// Start after the last line, as the first page of the syslog file does
+	start := logPageToken("syslog", fmt.Sprint(len(lines)+1))
+	page, err := s.GetRecentLogs(context.Background(), &pb.RecentLogsRequest{MaxLines: 2, PageToken: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages(page.Logs) != "line 4,line 5" || !page.HasMore {
+		t.Fatalf("first page = %s, has_more %v", messages(page.Logs), page.HasMore)
+	}
+
+	page, err = s.GetRecentLogs(context.Background(), &pb.RecentLogsRequest{MaxLines: 2, PageToken: page.NextPageToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages(page.Logs) != "line 2,line 3" {
+		t.Errorf("second page = %s", messages(page.Logs))
+	}
+
+	stream := &fakeRecentLogsStream{}
+	if err := s.StreamRecentLogs(&pb.RecentLogsRequest{MaxLines: 2, Limit: 3, PageToken: start}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.pages) != 2 || messages(stream.pages[0].Logs) != "line 4,line 5" || messages(stream.pages[1].Logs) != "line 3" {
+		t.Errorf("streamed %d pages", len(stream.pages))
+	}
+	if last := stream.pages[len(stream.pages)-1]; last.NextPageToken == "" {
+		t.Error("the last page before the limit should carry a token to resume")
+	}
+
+	_, err = s.GetRecentLogs(context.Background(), &pb.RecentLogsRequest{PageToken: "not-a-token"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("bad token error = %v", err)
+	}
+}
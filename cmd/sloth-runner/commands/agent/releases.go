@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+)
+
+// lookupRelease returns the release tagged tag, the latest when tag is
+// empty. Agents connected to a master ask its cached index; otherwise
+// releases are looked up on GitHub.
+var lookupRelease = func(ctx context.Context, tag string) (*releases.Release, error) {
+	return releases.Default().Release(ctx, tag)
+}
+
+// configureReleases makes version checks on this agent ask the master's
+// release index, falling back to GitHub when the master can't answer
+func configureReleases(masterAddr string) error {
+	conn, err := grpc.Dial(masterAddr, mtls.DialOption())
+	if err != nil {
+		return fmt.Errorf("failed to connect to master for releases: %w", err)
+	}
+	client := pb.NewAgentRegistryClient(conn)
+
+	lookupRelease = func(ctx context.Context, tag string) (*releases.Release, error) {
+		release, err := releaseFromMaster(ctx, client, tag)
+		if err != nil {
+			slog.Warn("Master release index unavailable, asking GitHub", "error", err)
+			return releases.Default().Release(ctx, tag)
+		}
+		return release, nil
+	}
+	return nil
+}
+
+func releaseFromMaster(ctx context.Context, client pb.AgentRegistryClient, tag string) (*releases.Release, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp, err := client.GetRelease(ctx, &pb.GetReleaseRequest{Tag: tag})
+	if err != nil {
+		return nil, err
+	}
+	release := &releases.Release{
+		TagName:   resp.TagName,
+		Name:      resp.Name,
+		FetchedAt: time.Unix(resp.FetchedAt, 0),
+	}
+	for _, asset := range resp.Assets {
+		release.Assets = append(release.Assets, releases.Asset{Name: asset.Name, URL: asset.Url})
+	}
+	return release, nil
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package agent
+
+import "os"
+
+// notifyReload does nothing: Windows has no SIGHUP, so agents there reload
+// their config with 'agent config reload' only
+func notifyReload(c chan<- os.Signal) {}
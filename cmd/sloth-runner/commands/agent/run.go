@@ -14,15 +14,34 @@ import (
 // NewExecCommand creates the agent exec command
 func NewExecCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "exec <agent_name> <command>",
-		Short: "Executes a command on a remote agent",
-		Long:  `Executes an arbitrary shell command on a specified remote agent. With --local, connects directly to agent using local database.`,
-		Args:  cobra.ExactArgs(2),
+		Use:   "exec [agent_name] <command>",
+		Short: "Executes a command on remote agents",
+		Long: `Executes an arbitrary shell command on a specified remote agent. With --local, connects directly to agent using local database.
+
+With --group or --agent, the command runs on every agent of the group and
+every agent named, --parallel at a time. Their output is streamed line by
+line, prefixed with the agent's name, and followed by the exit code of each
+agent. The command fails when it fails on any agent.`,
+		Example: `  sloth-runner agent exec web-01 "uptime"
+  sloth-runner agent exec --group web "systemctl restart nginx"
+  sloth-runner agent exec --agent web-01 --agent web-02 --parallel 1 "apt-get -y upgrade"
+  sloth-runner agent exec --group web "nginx -v" --output json`,
+		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			agentName := args[0]
-			command := args[1]
 			local, _ := cmd.Flags().GetBool("local")
 			outputFormat, _ := cmd.Flags().GetString("output")
+			group, _ := cmd.Flags().GetString("group")
+			agents, _ := cmd.Flags().GetStringArray("agent")
+
+			fanOut := group != "" || len(agents) > 0
+			if fanOut != (len(args) == 1) {
+				return fmt.Errorf("give an agent name and a command, or only a command with --group or --agent")
+			}
+			if fanOut && local {
+				return fmt.Errorf("--local runs a command on a single agent")
+			}
+			agentName := args[0]
+			command := args[len(args)-1]
 
 			// If --local flag is set, connect directly to agent
 			if local {
@@ -41,6 +60,21 @@ func NewExecCommand(ctx *commands.AppContext) *cobra.Command {
 				return fmt.Errorf("master address not specified. Use --master flag or set SLOTH_RUNNER_MASTER_ADDR environment variable")
 			}
 
+			if fanOut {
+				parallel, _ := cmd.Flags().GetInt("parallel")
+				timeout, _ := cmd.Flags().GetDuration("timeout")
+				return runCommandOnAgents(FanOutOptions{
+					Group:        group,
+					Agents:       agents,
+					Command:      command,
+					Parallel:     parallel,
+					Timeout:      timeout,
+					OutputFormat: outputFormat,
+					OutputWriter: os.Stdout,
+					ErrorWriter:  os.Stderr,
+				}, masterAddr)
+			}
+
 			return runCommandOnAgent(agentName, command, masterAddr, outputFormat)
 		},
 	}
@@ -48,6 +82,10 @@ func NewExecCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd.Flags().String("master", "", "Master server address (or use SLOTH_RUNNER_MASTER_ADDR env var)")
 	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
 	cmd.Flags().Bool("local", false, "Connect directly to agent using local database")
+	cmd.Flags().String("group", "", "Run the command on every agent of this agent group")
+	cmd.Flags().StringArray("agent", nil, "Run the command on this agent (can be used multiple times)")
+	cmd.Flags().Int("parallel", defaultExecParallel, "Number of agents running the command at once")
+	cmd.Flags().Duration("timeout", 5*time.Minute, "Give up on an agent running the command longer than this")
 
 	return cmd
 }
@@ -77,6 +115,19 @@ func runCommandOnAgent(agentName, command, masterAddr, outputFormat string) erro
 	return runCommandWithClient(ctx, client, opts)
 }
 
+// runCommandOnAgents runs a command on the agents of a group and the agents
+// named, through the master
+func runCommandOnAgents(opts FanOutOptions, masterAddr string) error {
+	factory := NewDefaultConnectionFactory()
+	client, cleanup, err := factory.CreateRegistryClient(masterAddr)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return runFanOutWithClient(context.Background(), client, opts)
+}
+
 // runCommandOnAgentDirect connects directly to agent using local database
 func runCommandOnAgentDirect(agentName, command, outputFormat string) error {
 	// Get agent address from local database
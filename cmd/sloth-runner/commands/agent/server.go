@@ -17,24 +17,32 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
 	"github.com/chalkan3-sloth/sloth-runner/internal/core"
 	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sudo"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
 	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/creack/pty"
 	"github.com/pterm/pterm"
 	"github.com/yuin/gopher-lua"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // agentServer implements the gRPC agent server with optimizations
 type agentServer struct {
 	pb.UnimplementedAgentServer
 	grpcServer *grpc.Server
+	name       string
 
 	// Optimizations: cached metrics
 	cachedMetrics     *CachedMetrics
@@ -53,6 +61,30 @@ type agentServer struct {
 	// Event worker for sending events to master
 	eventWorker       interface{} // Will be *agentInternal.EventWorker, using interface{} to avoid import cycle
 	watcherManager    interface{} // Will be *agentInternal.EventWatcherManager, using interface{} to avoid import cycle
+
+	// Workflow cache: parsed task groups and prepared Lua states reused
+	// across delegated tasks (nil when disabled), replaced on reload
+	cachesMu   sync.RWMutex
+	parseCache *luainterface.ParseCache
+	statePool  *luainterface.StatePool
+
+	// Settings of the flags and config file, applied again on reload
+	config agentConfigState
+
+	// Outcomes of task requests by idempotency key, so retried requests
+	// don't run a task twice
+	idempotency idempotencyCache
+
+	// Key runners seal sudo passwords to
+	sudoKey *sudo.KeyPair
+
+	// Interactive shell sessions, which read-only observers attach to
+	shells shellRegistry
+
+	// Delegated task requests in progress, shown in the systemd status
+	running atomic.Int32
+	// Set while the agent drains for a reboot, refusing new task requests
+	rebooting atomic.Bool
 }
 
 // CachedMetrics holds cached resource usage data
@@ -75,16 +107,14 @@ type CachedMetrics struct {
 func (s *agentServer) RunCommand(in *pb.RunCommandRequest, stream pb.Agent_RunCommandServer) error {
 	slog.Info(fmt.Sprintf("Executing command on agent: %s", in.GetCommand()))
 
-	var cmd *exec.Cmd
-
-	// If user is specified and not root, run command as that user
+	// If user is specified and not root, run command as that user; bash on
+	// Unix agents, PowerShell on Windows ones
+	cmd, err := shellCommand(in.GetCommand(), in.GetUser())
+	if err != nil {
+		return err
+	}
 	if in.GetUser() != "" && in.GetUser() != "root" {
-		// Use sudo to run as specific user
-		cmd = exec.Command("sudo", "-u", in.GetUser(), "bash", "-c", in.GetCommand())
 		slog.Info(fmt.Sprintf("Running command as user: %s", in.GetUser()))
-	} else {
-		// Run as current user (typically root for agent)
-		cmd = exec.Command("bash", "-c", in.GetCommand())
 	}
 
 	stdoutPipe, err := cmd.StdoutPipe()
@@ -324,26 +354,13 @@ func getCurrentAgentVersion() string {
 	return "unknown"
 }
 
-// getLatestReleaseVersion fetches the latest release version from GitHub
+// getLatestReleaseVersion returns the latest release version, from the
+// master's release index when connected to one
 func getLatestReleaseVersion() (string, error) {
-	resp, err := http.Get("https://api.github.com/repos/chalkan3-sloth/sloth-runner/releases/latest")
+	release, err := lookupRelease(context.Background(), "")
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned status: %d", resp.StatusCode)
-	}
-
-	var release struct {
-		TagName string `json:"tag_name"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", err
-	}
-
 	return release.TagName, nil
 }
 
@@ -360,9 +377,15 @@ func downloadAgentBinary(version string) (string, error) {
 		arch = "arm64"
 	}
 
-	// Construct download URL
+	// Construct download URL, preferring the asset listed in the release
+	// so GitHub Enterprise Server instances are downloaded from
 	filename := fmt.Sprintf("sloth-runner_%s_%s_%s.tar.gz", version, platform, arch)
 	url := fmt.Sprintf("https://github.com/chalkan3-sloth/sloth-runner/releases/download/%s/%s", version, filename)
+	if release, err := lookupRelease(context.Background(), version); err == nil {
+		if asset := release.Archive(platform, arch); asset != nil {
+			url = asset.URL
+		}
+	}
 
 	slog.Info("Downloading new agent binary", "url", url)
 
@@ -478,7 +501,312 @@ func restartAgent() {
 
 // ExecuteTask executes a complete Lua task with workspace
 func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest) (*pb.ExecuteTaskResponse, error) {
-	slog.Info(fmt.Sprintf("Received task: %s from group: %s", in.GetTaskName(), in.GetTaskGroup()))
+	resp, err := s.idempotency.do(ctx, in.GetIdempotencyKey(), func() (interface{}, error) {
+		return s.executeTask(ctx, in, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.ExecuteTaskResponse), nil
+}
+
+// ExecuteTaskStream runs a task like ExecuteTask, sending output modules
+// write while it runs before the result
+func (s *agentServer) ExecuteTaskStream(in *pb.ExecuteTaskRequest, stream pb.Agent_ExecuteTaskStreamServer) error {
+	// A duplicate of a request only gets the result; the output went to
+	// the original stream
+	out := &taskOutputStream{stream: stream}
+	resp, err := s.idempotency.do(stream.Context(), in.GetIdempotencyKey(), func() (interface{}, error) {
+		return s.executeTask(stream.Context(), in, out)
+	})
+	if err != nil {
+		return err
+	}
+	return stream.Send(&pb.ExecuteTaskEvent{Result: resp.(*pb.ExecuteTaskResponse)})
+}
+
+// taskOutputStream sends each write as an output chunk, and the progress
+// tasks report as progress events
+type taskOutputStream struct {
+	mu     sync.Mutex
+	stream pb.Agent_ExecuteTaskStreamServer
+}
+
+func (t *taskOutputStream) Write(p []byte) (int, error) {
+	if err := t.send(&pb.ExecuteTaskEvent{OutputChunk: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (t *taskOutputStream) progress(p taskctx.Progress) {
+	if err := t.send(&pb.ExecuteTaskEvent{Progress: &pb.TaskProgress{Percent: p.Percent, Message: p.Message}}); err != nil {
+		slog.Debug("Failed to send task progress", "error", err)
+	}
+}
+
+// send sends an event; tasks running concurrently share the stream
+func (t *taskOutputStream) send(event *pb.ExecuteTaskEvent) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stream.Send(event)
+}
+
+// ExecuteTasks runs several tasks of a group with a single workspace
+// transfer, in order or, when parallel is set, concurrently
+func (s *agentServer) ExecuteTasks(ctx context.Context, in *pb.ExecuteTasksRequest) (*pb.ExecuteTasksResponse, error) {
+	if len(in.GetTaskNames()) == 0 {
+		return nil, fmt.Errorf("no tasks to execute")
+	}
+
+	result, err := s.idempotency.do(ctx, in.GetIdempotencyKey(), func() (interface{}, error) {
+		return s.runDelegatedTasks(ctx, in, nil, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	run := result.(*delegatedRun)
+
+	if run.err != nil {
+		slog.Error("Agent batch execution failed", "tasks", in.GetTaskNames(), "group", in.GetTaskGroup(), "error", run.err)
+	} else {
+		slog.Info("Agent batch execution succeeded", "tasks", len(in.GetTaskNames()), "group", in.GetTaskGroup())
+	}
+	return tasksResponse(in, run), nil
+}
+
+// CheckTasks runs tasks in check mode: their module calls report what they
+// would change, with the state they found, and leave the system untouched
+func (s *agentServer) CheckTasks(ctx context.Context, in *pb.ExecuteTasksRequest) (*pb.ExecuteTasksResponse, error) {
+	if len(in.GetTaskNames()) == 0 {
+		return nil, fmt.Errorf("no tasks to check")
+	}
+
+	run, err := s.runDelegatedTasks(ctx, in, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if run.err != nil {
+		slog.Warn("Agent task check failed", "tasks", in.GetTaskNames(), "group", in.GetTaskGroup(), "error", run.err)
+	}
+	return tasksResponse(in, run), nil
+}
+
+// tasksResponse returns a result per task of the request. Tasks without a
+// result were skipped, e.g. because a dependency failed.
+func tasksResponse(in *pb.ExecuteTasksRequest, run *delegatedRun) *pb.ExecuteTasksResponse {
+	byName := make(map[string]types.TaskResult, len(run.results))
+	for _, result := range run.results {
+		byName[result.Name] = result
+	}
+	resp := &pb.ExecuteTasksResponse{Workspace: run.workspace}
+	for _, name := range in.GetTaskNames() {
+		result := &pb.TaskRunResult{TaskName: name, Status: "Skipped"}
+		if r, ok := byName[name]; ok {
+			result.Status = r.Status
+			result.DurationMs = r.Duration.Milliseconds()
+			result.Usage = r.Usage.Proto()
+			result.ChangesJson = encodeChanges(r.Changes)
+			result.SlowCallsJson = encodeSlowCalls(r.SlowCalls)
+			if r.Error != nil {
+				result.Error = r.Error.Error()
+			}
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp
+}
+
+// GetSudoKey returns the key runners seal sudo passwords to
+func (s *agentServer) GetSudoKey(ctx context.Context, in *pb.SudoKeyRequest) (*pb.SudoKeyResponse, error) {
+	if s.sudoKey == nil {
+		return nil, fmt.Errorf("agent has no sudo key")
+	}
+	return &pb.SudoKeyResponse{PublicKey: s.sudoKey.PublicKey()}, nil
+}
+
+// holdSudoPassword opens a sealed sudo password and holds it for the
+// sudo calls of a run until the session is released
+func (s *agentServer) holdSudoPassword(sealed []byte) (*sudo.Session, error) {
+	if s.sudoKey == nil {
+		return nil, fmt.Errorf("agent has no sudo key")
+	}
+	password, err := s.sudoKey.Open(sealed)
+	if err != nil {
+		return nil, err
+	}
+	defer sudo.Wipe(password)
+	return sudo.Hold(password)
+}
+
+// executeTask runs a delegated task. When out is set, modules stream their
+// live output to it.
+func (s *agentServer) executeTask(ctx context.Context, in *pb.ExecuteTaskRequest, out io.Writer) (*pb.ExecuteTaskResponse, error) {
+	var taskNames []string
+	if in.GetTaskName() != "" && in.GetTaskName() != "nil" {
+		taskNames = []string{in.GetTaskName()}
+	}
+	run, err := s.runDelegatedTasks(ctx, &pb.ExecuteTasksRequest{
+		TaskNames: taskNames,
+		TaskGroup: in.GetTaskGroup(),
+		LuaScript: in.GetLuaScript(),
+		Workspace: in.GetWorkspace(),
+		User:         in.GetUser(),
+		Approvals:    in.GetApprovals(),
+		SudoPassword: in.GetSudoPassword(),
+		InputsJson:   in.GetInputsJson(),
+	}, out, false)
+	if err != nil {
+		return nil, err
+	}
+	err = run.err
+
+	// Return response based on execution result
+	if err != nil {
+		slog.Error("AGENT TASK EXECUTION FAILED", "task", in.GetTaskName(), "group", in.GetTaskGroup(), "error", err)
+
+		errorMsg := err.Error()
+		var errorDetails strings.Builder
+
+		errorDetails.WriteString(fmt.Sprintf("╔═══════════════════════════════════════════════════════════════════════════════════\n"))
+		errorDetails.WriteString(fmt.Sprintf("║ ❌ AGENT EXECUTION FAILURE\n"))
+		errorDetails.WriteString(fmt.Sprintf("╠═══════════════════════════════════════════════════════════════════════════════════\n"))
+		errorDetails.WriteString(fmt.Sprintf("║ Task  : %s\n", in.GetTaskName()))
+		errorDetails.WriteString(fmt.Sprintf("║ Group : %s\n", in.GetTaskGroup()))
+		errorDetails.WriteString(fmt.Sprintf("╠═══════════════════════════════════════════════════════════════════════════════════\n"))
+		errorDetails.WriteString(fmt.Sprintf("║ 🔴 ERROR:\n"))
+		errorDetails.WriteString(fmt.Sprintf("║   %s\n", errorMsg))
+		errorDetails.WriteString(fmt.Sprintf("╚═══════════════════════════════════════════════════════════════════════════════════\n"))
+
+		return &pb.ExecuteTaskResponse{
+			Success:       false,
+			Output:        errorDetails.String(),
+			Workspace:     run.workspace,
+			Usage:         run.usage().Proto(),
+			SlowCallsJson: run.slowCallsJSON(),
+		}, nil
+	}
+
+	slog.Info("Agent task execution succeeded", "task", in.GetTaskName(), "group", in.GetTaskGroup())
+	return &pb.ExecuteTaskResponse{
+		Success:     true,
+		Output:      fmt.Sprintf("Task '%s' executed successfully on agent", in.GetTaskName()),
+		Workspace:   run.workspace,
+		Usage:       run.usage().Proto(),
+		OutputsJson:    run.outputsJSON(in.GetTaskName()),
+		ChangesJson:    run.changesJSON(),
+		SlowCallsJson:  run.slowCallsJSON(),
+		SetOutputsJson: run.setOutputsJSON(in.GetTaskName()),
+	}, nil
+}
+
+// delegatedRun is the outcome of running delegated tasks
+type delegatedRun struct {
+	results   []types.TaskResult
+	outputs   map[string]interface{}
+	err       error
+	workspace []byte
+}
+
+// outputsJSON encodes the outputs of a task, empty when it has none
+func (r *delegatedRun) outputsJSON(task string) string {
+	outputs, ok := r.outputs[task]
+	if !ok {
+		return ""
+	}
+	data, err := json.Marshal(outputs)
+	if err != nil {
+		slog.Warn("Failed to encode task outputs", "task", task, "error", err)
+		return ""
+	}
+	return string(data)
+}
+
+// setOutputsJSON encodes the names of the outputs a task set with
+// task.set_output, true for the sensitive ones, empty when it set none
+func (r *delegatedRun) setOutputsJSON(task string) string {
+	for _, result := range r.results {
+		if result.Name != task || len(result.SetOutputs) == 0 {
+			continue
+		}
+		data, err := json.Marshal(result.SetOutputs)
+		if err != nil {
+			slog.Warn("Failed to encode set outputs", "task", task, "error", err)
+			return ""
+		}
+		return string(data)
+	}
+	return ""
+}
+
+// changesJSON encodes the changes the module calls of the tasks made
+func (r *delegatedRun) changesJSON() string {
+	var changes []taskctx.Change
+	for _, result := range r.results {
+		changes = append(changes, result.Changes...)
+	}
+	return encodeChanges(changes)
+}
+
+// encodeChanges encodes changes, empty when there are none
+func encodeChanges(changes []taskctx.Change) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		slog.Warn("Failed to encode task changes", "error", err)
+		return ""
+	}
+	return string(data)
+}
+
+// slowCallsJSON encodes the slow module calls of the tasks
+func (r *delegatedRun) slowCallsJSON() string {
+	var calls []taskctx.SlowCall
+	for _, result := range r.results {
+		calls = append(calls, result.SlowCalls...)
+	}
+	return encodeSlowCalls(calls)
+}
+
+// encodeSlowCalls encodes slow calls, empty when there are none
+func encodeSlowCalls(calls []taskctx.SlowCall) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(calls)
+	if err != nil {
+		slog.Warn("Failed to encode slow calls", "error", err)
+		return ""
+	}
+	return string(data)
+}
+
+// usage adds up what the processes of the tasks consumed, nil when none
+// was measured
+func (r *delegatedRun) usage() *taskusage.Usage {
+	var total *taskusage.Usage
+	for _, result := range r.results {
+		if result.Usage == nil {
+			continue
+		}
+		if total == nil {
+			total = &taskusage.Usage{}
+		}
+		total.Add(*result.Usage)
+	}
+	return total
+}
+
+// runDelegatedTasks unpacks the workspace, runs the named tasks of the
+// group (every task when none is named) and packs the workspace back up
+func (s *agentServer) runDelegatedTasks(ctx context.Context, in *pb.ExecuteTasksRequest, out io.Writer, check bool) (*delegatedRun, error) {
+	slog.Info(fmt.Sprintf("Received tasks: %s from group: %s", strings.Join(in.GetTaskNames(), ", "), in.GetTaskGroup()))
+	if err := s.enterTask(); err != nil {
+		return nil, err
+	}
+	defer s.running.Add(-1)
 
 	// Create a temporary directory for the workspace
 	workDir, err := os.MkdirTemp("", "sloth-runner-agent-")
@@ -492,6 +820,17 @@ func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest
 		return nil, fmt.Errorf("failed to untar workspace: %w", err)
 	}
 
+	// Let the run's sudo calls use its password until the tasks are done
+	var env []string
+	if sealed := in.GetSudoPassword(); len(sealed) > 0 {
+		session, err := s.holdSudoPassword(sealed)
+		if err != nil {
+			return nil, err
+		}
+		defer session.Release()
+		env = session.Env()
+	}
+
 	// Create temporary Lua script file
 	scriptPath := filepath.Join(workDir, "task.lua")
 	if err := os.WriteFile(scriptPath, []byte(in.GetLuaScript()), 0644); err != nil {
@@ -508,7 +847,7 @@ func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest
 	// Set task user as global variable for modules to use
 	if in.GetUser() != "" {
 		L.SetGlobal("__TASK_USER__", lua.LString(in.GetUser()))
-		slog.Info("Task will run with user context", "user", in.GetUser(), "task", in.GetTaskName())
+		slog.Info("Task will run with user context", "user", in.GetUser(), "tasks", in.GetTaskNames())
 	}
 
 	// Store EventWatcherManager in Lua state for watcher registration
@@ -538,11 +877,24 @@ func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest
 		modernDSL.RegisterModernDSL(L)
 	}
 
-	// Parse the Lua script to get task definitions
-	taskGroups, err := luainterface.ParseLuaScript(ctx, scriptPath, nil)
-	if err != nil {
-		slog.Error("Failed to parse lua script on agent", "error", err, "script_path", scriptPath)
-		return nil, fmt.Errorf("failed to load task definitions: %w", err)
+	// Parse the Lua script to get task definitions, reusing an earlier
+	// parse of the same script when the workflow cache is enabled
+	var taskGroups map[string]types.TaskGroup
+	if parseCache, _ := s.workflowCaches(); parseCache != nil {
+		parsed, err := parseCache.Acquire(ctx, scriptPath, []byte(in.GetLuaScript()))
+		if err != nil {
+			slog.Error("Failed to parse lua script on agent", "error", err, "script_path", scriptPath)
+			return nil, fmt.Errorf("failed to load task definitions: %w", err)
+		}
+		defer parseCache.Release(parsed)
+		taskGroups = parsed.TaskGroups
+		slog.Debug("Workflow parse cache", "cached", parsed.Cached, "tasks", in.GetTaskNames())
+	} else {
+		taskGroups, err = luainterface.ParseLuaScript(ctx, scriptPath, nil)
+		if err != nil {
+			slog.Error("Failed to parse lua script on agent", "error", err, "script_path", scriptPath)
+			return nil, fmt.Errorf("failed to load task definitions: %w", err)
+		}
 	}
 
 	// Verify we have task groups
@@ -551,9 +903,14 @@ func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest
 		return nil, fmt.Errorf("expected 'TaskDefinitions' to be a table, got nil")
 	}
 
-	// Remove delegate_to from all tasks to prevent recursive delegation
+	// Remove delegate_to from all groups and tasks to prevent recursive delegation
 	for groupName, group := range taskGroups {
 		slog.Info("Agent checking group for delegate_to", "group", groupName, "task_count", len(group.Tasks))
+		if group.DelegateTo != nil {
+			slog.Info("Removing delegate_to from group on agent", "group", groupName, "delegate_to", group.DelegateTo)
+			group.DelegateTo = nil
+			taskGroups[groupName] = group
+		}
 		for i, task := range group.Tasks {
 			if task.DelegateTo != nil {
 				slog.Info("Removing delegate_to from task on agent", "task", task.Name, "group", groupName, "delegate_to", task.DelegateTo)
@@ -571,14 +928,30 @@ func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest
 	os.Setenv("SLOTH_WORKSPACE", workDir)
 	slog.Info("Workspace path set as environment variable", "workspace", workDir)
 
-	// Filter the task group to contain only the specified task
-	targetTaskName := in.GetTaskName()
-	if targetTaskName != "" && targetTaskName != "nil" {
-		slog.Info("Agent filtering for specific task", "task", targetTaskName, "group", in.GetTaskGroup())
+	// Filter the task group to contain only the delegated tasks, in the
+	// order they were sent. Dependencies on other tasks already ran on the
+	// master, so only those between the delegated tasks are kept.
+	if targetTaskNames := in.GetTaskNames(); len(targetTaskNames) > 0 {
+		slog.Info("Agent filtering for specific tasks", "tasks", targetTaskNames, "group", in.GetTaskGroup())
 		if group, exists := taskGroups[in.GetTaskGroup()]; exists {
+			delegated := make(map[string]bool, len(targetTaskNames))
+			for _, name := range targetTaskNames {
+				delegated[name] = true
+			}
+
 			var filteredTasks []types.Task
-			for _, task := range group.Tasks {
-				if task.Name == targetTaskName {
+			for _, name := range targetTaskNames {
+				for _, task := range group.Tasks {
+					if task.Name != name {
+						continue
+					}
+					var dependsOn []string
+					for _, dep := range task.DependsOn {
+						if delegated[dep] {
+							dependsOn = append(dependsOn, dep)
+						}
+					}
+					task.DependsOn = dependsOn
 					filteredTasks = append(filteredTasks, task)
 					slog.Info("Found target task for agent execution", "task", task.Name)
 					break
@@ -588,17 +961,29 @@ func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest
 			if len(filteredTasks) > 0 {
 				group.Tasks = filteredTasks
 				taskGroups[in.GetTaskGroup()] = group
-				slog.Info("Agent will execute only the delegated task", "task", targetTaskName)
+				slog.Info("Agent will execute only the delegated tasks", "tasks", targetTaskNames)
 			}
 		}
 	}
 
-	// Create task runner
-	runner := taskrunner.NewTaskRunner(L, taskGroups, in.GetTaskGroup(), nil, false, false, &taskrunner.DefaultSurveyAsker{}, in.GetLuaScript())
-
 	// Execute the specific task group
 	slog.Info("Agent executing task group", "group", in.GetTaskGroup())
-	err = runner.Run()
+	runStart := time.Now()
+	var results []types.TaskResult
+	var outputs map[string]interface{}
+	if in.GetParallel() && len(taskGroups[in.GetTaskGroup()].Tasks) > 1 {
+		results, outputs, err = s.runTasksConcurrently(taskGroups, in, workDir, env, out, check)
+	} else {
+		runner := s.newDelegatedRunner(L, taskGroups, in, workDir, env, out, check)
+		err = runner.Run()
+		results, outputs = runner.Results, runner.Outputs
+	}
+
+	// A check changed nothing: it has no metrics, watchers or workspace
+	if check {
+		return &delegatedRun{results: results, outputs: outputs, err: err}, nil
+	}
+	recordTextfileMetrics(in.GetTaskGroup(), results, err, time.Since(runStart))
 
 	// Extract and register watchers after task execution
 	slog.Info("Checking for watchers to register", "watcherManager_nil", s.watcherManager == nil)
@@ -652,36 +1037,100 @@ func (s *agentServer) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest
 		return nil, fmt.Errorf("failed to tar workspace: %w", err)
 	}
 
-	// Return response based on execution result
-	if err != nil {
-		slog.Error("AGENT TASK EXECUTION FAILED", "task", in.GetTaskName(), "group", in.GetTaskGroup(), "error", err)
+	return &delegatedRun{results: results, outputs: outputs, err: err, workspace: buf.Bytes()}, nil
+}
 
-		errorMsg := err.Error()
-		var errorDetails strings.Builder
+// newDelegatedRunner creates the task runner for delegated tasks, which
+// run in check mode when check is set. Their commands add env to their
+// environment.
+func (s *agentServer) newDelegatedRunner(L *lua.LState, taskGroups map[string]types.TaskGroup, in *pb.ExecuteTasksRequest, workDir string, env []string, out io.Writer, check bool) *taskrunner.TaskRunner {
+	runner := taskrunner.NewTaskRunner(L, taskGroups, in.GetTaskGroup(), nil, check, false, &taskrunner.DefaultSurveyAsker{}, in.GetLuaScript())
+	_, runner.StatePool = s.workflowCaches()
+	runner.TrackUsage = true
+	// Assets embedded in the workflow came with the workspace
+	runner.AssetsDir = workDir
+	runner.BaseContext = taskctx.WithApprovals(context.Background(), in.GetApprovals())
+	if s.name != "" {
+		runner.BaseContext = taskctx.WithAgent(runner.BaseContext, s.name)
+	}
+	if len(env) > 0 {
+		runner.BaseContext = taskctx.WithCommandEnv(runner.BaseContext, env...)
+	}
+	if out != nil {
+		runner.BaseContext = taskctx.WithOutput(runner.BaseContext, out)
+	}
+	if stream, ok := out.(*taskOutputStream); ok {
+		runner.BaseContext = taskctx.WithProgress(runner.BaseContext, stream.progress)
+	}
+	// Tasks get the outputs of dependencies that ran elsewhere
+	if data := in.GetInputsJson(); data != "" {
+		if err := json.Unmarshal([]byte(data), &runner.Inputs); err != nil {
+			slog.Warn("Failed to decode task inputs", "error", err)
+		}
+	}
+	return runner
+}
 
-		errorDetails.WriteString(fmt.Sprintf("╔═══════════════════════════════════════════════════════════════════════════════════\n"))
-		errorDetails.WriteString(fmt.Sprintf("║ ❌ AGENT EXECUTION FAILURE\n"))
-		errorDetails.WriteString(fmt.Sprintf("╠═══════════════════════════════════════════════════════════════════════════════════\n"))
-		errorDetails.WriteString(fmt.Sprintf("║ Task  : %s\n", in.GetTaskName()))
-		errorDetails.WriteString(fmt.Sprintf("║ Group : %s\n", in.GetTaskGroup()))
-		errorDetails.WriteString(fmt.Sprintf("╠═══════════════════════════════════════════════════════════════════════════════════\n"))
-		errorDetails.WriteString(fmt.Sprintf("║ 🔴 ERROR:\n"))
-		errorDetails.WriteString(fmt.Sprintf("║   %s\n", errorMsg))
-		errorDetails.WriteString(fmt.Sprintf("╚═══════════════════════════════════════════════════════════════════════════════════\n"))
+// runTasksConcurrently runs each task of the group with its own runner and
+// Lua state, all at once. The tasks must not depend on each other.
+func (s *agentServer) runTasksConcurrently(taskGroups map[string]types.TaskGroup, in *pb.ExecuteTasksRequest, workDir string, env []string, out io.Writer, check bool) ([]types.TaskResult, map[string]interface{}, error) {
+	group := taskGroups[in.GetTaskGroup()]
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var results []types.TaskResult
+	outputs := make(map[string]interface{})
+	var errs []string
+	for _, task := range group.Tasks {
+		taskGroup := group
+		taskGroup.Tasks = []types.Task{task}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			L := lua.NewState()
+			defer L.Close()
+			if in.GetUser() != "" {
+				L.SetGlobal("__TASK_USER__", lua.LString(in.GetUser()))
+			}
 
-		return &pb.ExecuteTaskResponse{
-			Success:   false,
-			Output:    errorDetails.String(),
-			Workspace: buf.Bytes(),
-		}, nil
+			runner := s.newDelegatedRunner(L, map[string]types.TaskGroup{in.GetTaskGroup(): taskGroup}, in, workDir, env, out, check)
+			err := runner.Run()
+
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, runner.Results...)
+			for name, output := range runner.Outputs {
+				outputs[name] = output
+			}
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+		}()
 	}
+	wg.Wait()
 
-	slog.Info("Agent task execution succeeded", "task", in.GetTaskName(), "group", in.GetTaskGroup())
-	return &pb.ExecuteTaskResponse{
-		Success:   true,
-		Output:    fmt.Sprintf("Task '%s' executed successfully on agent", in.GetTaskName()),
-		Workspace: buf.Bytes(),
-	}, nil
+	if len(errs) > 0 {
+		return results, outputs, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return results, outputs, nil
+}
+
+// recordTextfileMetrics updates the node_exporter textfile metrics, if enabled
+func recordTextfileMetrics(group string, results []types.TaskResult, runErr error, duration time.Duration) {
+	exporter := agentInternal.GetGlobalTextfileExporter()
+	if exporter == nil {
+		return
+	}
+
+	for _, result := range results {
+		exporter.RecordTask(group, result.Name, result.Status, result.Duration)
+	}
+	exporter.RecordGroup(group, runErr == nil, duration)
+
+	if err := exporter.Write(); err != nil {
+		slog.Warn("Failed to write textfile metrics", "path", exporter.Path(), "error", err)
+	}
 }
 
 // convertMapToWatcherConfig converts a map[string]interface{} from Lua to WatcherConfig
@@ -695,6 +1144,9 @@ func convertMapToWatcherConfig(m map[string]interface{}) (agentInternal.WatcherC
 	if typeStr, ok := m["type"].(string); ok {
 		config.Type = agentInternal.WatcherType(typeStr)
 	}
+	if group, ok := m["group"].(string); ok {
+		config.Group = group
+	}
 
 	// Extract conditions (when field)
 	if when, ok := m["when"].([]interface{}); ok {
@@ -845,7 +1297,7 @@ func (s *agentServer) GetResourceUsage(ctx context.Context, in *pb.ResourceUsage
 	// For first request or expired cache, get fresh data
 	cpuPercent := getCPUPercent()
 	memInfo, _ := getMemoryInfo()
-	diskInfo, _ := getDiskUsage("/")
+	diskInfo, _ := getDiskUsage(rootDisk())
 	loadAvg := getLoadAverage()
 	processCount := getProcessCount()
 	uptime := getSystemUptime()
@@ -893,7 +1345,7 @@ func (s *agentServer) updateMetricsCache() {
 	}
 
 	// Get disk (optional, more expensive)
-	if diskInfo, err := getDiskUsage("/"); err == nil {
+	if diskInfo, err := getDiskUsage(rootDisk()); err == nil {
 		s.cachedMetrics.DiskPercent = diskInfo.UsedPercent
 		s.cachedMetrics.DiskUsed = diskInfo.Used
 		s.cachedMetrics.DiskTotal = diskInfo.Total
@@ -1068,6 +1520,11 @@ func (s *agentServer) GetDiskInfo(ctx context.Context, in *pb.DiskInfoRequest) (
 
 // StreamLogs streams agent logs
 func (s *agentServer) StreamLogs(in *pb.StreamLogsRequest, stream pb.Agent_StreamLogsServer) error {
+	q, err := newLogQuery(in.LevelFilter, in.Pattern, 0, 0)
+	if err != nil {
+		return err
+	}
+
 	// For now, stream system journal logs
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -1080,12 +1537,15 @@ func (s *agentServer) StreamLogs(in *pb.StreamLogsRequest, stream pb.Agent_Strea
 		case <-stream.Context().Done():
 			return nil
 		case entry := <-logChan:
-			err := stream.Send(&pb.LogEntry{
+			logEntry := &pb.LogEntry{
 				Timestamp: entry.Timestamp,
 				Level:     entry.Level,
 				Message:   entry.Message,
-			})
-			if err != nil {
+			}
+			if !q.matches(logEntry) {
+				continue
+			}
+			if err := stream.Send(logEntry); err != nil {
 				return err
 			}
 		}
@@ -1106,7 +1566,7 @@ func (s *agentServer) StreamMetrics(in *pb.StreamMetricsRequest, stream pb.Agent
 			// Get current metrics
 			cpuPercent := getCPUPercent()
 			memInfo, _ := getMemoryInfo()
-			diskInfo, _ := getDiskUsage("/")
+			diskInfo, _ := getDiskUsage(rootDisk())
 			
 			memPercent := float64(memInfo.Used) / float64(memInfo.Total) * 100
 			
@@ -1358,91 +1818,6 @@ func (s *agentServer) GetDetailedMetrics(ctx context.Context, in *pb.DetailedMet
 	}, nil
 }
 
-// GetRecentLogs returns recent system logs
-func (s *agentServer) GetRecentLogs(ctx context.Context, in *pb.RecentLogsRequest) (*pb.RecentLogsResponse, error) {
-	maxLines := in.MaxLines
-	if maxLines == 0 {
-		maxLines = 100
-	}
-
-	// Build journalctl command
-	args := []string{"-n", strconv.Itoa(int(maxLines)), "-o", "json"}
-
-	// Add level filter
-	if in.LevelFilter != "" {
-		args = append(args, "-p", in.LevelFilter)
-	}
-
-	// Add source filter
-	if in.SourceFilter != "" {
-		args = append(args, "-u", in.SourceFilter)
-	}
-
-	// Add since timestamp
-	if in.SinceTimestamp > 0 {
-		args = append(args, "--since", fmt.Sprintf("@%d", in.SinceTimestamp))
-	}
-
-	cmd := exec.Command("journalctl", args...)
-	output, err := cmd.Output()
-	if err != nil {
-		// Fallback to reading /var/log/syslog
-		data, err := os.ReadFile("/var/log/syslog")
-		if err != nil {
-			return &pb.RecentLogsResponse{
-				Logs:       []*pb.LogEntry{},
-				TotalCount: 0,
-				HasMore:    false,
-			}, nil
-		}
-
-		lines := strings.Split(string(data), "\n")
-		logs := []*pb.LogEntry{}
-		count := 0
-
-		for i := len(lines) - 1; i >= 0 && count < int(maxLines); i-- {
-			if lines[i] == "" {
-				continue
-			}
-			logs = append([]*pb.LogEntry{{
-				Timestamp: time.Now().Unix(),
-				Level:     "INFO",
-				Message:   lines[i],
-				Source:    "syslog",
-			}}, logs...)
-			count++
-		}
-
-		return &pb.RecentLogsResponse{
-			Logs:       logs,
-			TotalCount: int32(count),
-			HasMore:    len(lines) > int(maxLines),
-		}, nil
-	}
-
-	// Parse journalctl output
-	logs := []*pb.LogEntry{}
-	for _, line := range strings.Split(string(output), "\n") {
-		if line == "" {
-			continue
-		}
-
-		// Simple parsing (journalctl -o json returns one JSON per line)
-		logs = append(logs, &pb.LogEntry{
-			Timestamp: time.Now().Unix(),
-			Level:     "INFO",
-			Message:   line,
-			Source:    "journalctl",
-		})
-	}
-
-	return &pb.RecentLogsResponse{
-		Logs:       logs,
-		TotalCount: int32(len(logs)),
-		HasMore:    false,
-	}, nil
-}
-
 // GetActiveConnections returns active network connections
 func (s *agentServer) GetActiveConnections(ctx context.Context, in *pb.ConnectionsRequest) (*pb.ConnectionsResponse, error) {
 	connections := []*pb.ConnectionInfo{}
@@ -1587,6 +1962,15 @@ func (s *agentServer) GetSystemErrors(ctx context.Context, in *pb.SystemErrorsRe
 		maxErrors = 50
 	}
 
+	// Windows agents read the event logs
+	if runtime.GOOS == "windows" {
+		resp, err := eventLogErrors(ctx, in, int(maxErrors))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to read the event logs: %v", err)
+		}
+		return resp, nil
+	}
+
 	errors := []*pb.SystemError{}
 	totalErrors := int32(0)
 	totalWarnings := int32(0)
@@ -1728,7 +2112,7 @@ func (s *agentServer) GetPerformanceHistory(ctx context.Context, in *pb.Performa
 	for i := int32(0); i < dataPoints; i++ {
 		cpuPercent := getCPUPercent()
 		memInfo, _ := getMemoryInfo()
-		diskInfo, _ := getDiskUsage("/")
+		diskInfo, _ := getDiskUsage(rootDisk())
 		loadAvg := getLoadAverage()
 		processCount := getProcessCount()
 
@@ -1866,7 +2250,7 @@ func (s *agentServer) DiagnoseHealth(ctx context.Context, in *pb.HealthDiagnosti
 	}
 
 	// Check disk usage
-	diskInfo, _ := getDiskUsage("/")
+	diskInfo, _ := getDiskUsage(rootDisk())
 	if diskInfo.UsedPercent > 90 {
 		healthScore -= 20
 		totalErrors++
@@ -1986,14 +2370,18 @@ func (s *agentServer) DiagnoseHealth(ctx context.Context, in *pb.HealthDiagnosti
 
 // InteractiveShell provides a bidirectional streaming shell interface with PTY
 func (s *agentServer) InteractiveShell(stream pb.Agent_InteractiveShellServer) error {
-	slog.Info("Starting interactive shell session")
-
 	// Wait for initial message with window size from client
 	initial, err := stream.Recv()
 	if err != nil {
 		return fmt.Errorf("failed to receive initial window size: %w", err)
 	}
 
+	if initial.ReadOnly {
+		return s.observeShell(stream, initial.SessionId)
+	}
+
+	slog.Info("Starting interactive shell session")
+
 	// Use provided window size or fallback to defaults
 	rows, cols := uint16(24), uint16(80)
 	if initial.WindowRows > 0 && initial.WindowCols > 0 {
@@ -2001,6 +2389,38 @@ func (s *agentServer) InteractiveShell(stream pb.Agent_InteractiveShellServer) e
 		slog.Info("Client terminal size", "rows", rows, "cols", cols)
 	}
 
+	// The PTY reader, the observer notices and the final message all send
+	// on the stream, which isn't safe for concurrent use
+	var sendMu sync.Mutex
+	send := func(out *pb.ShellOutput) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(out)
+	}
+
+	session := newShellSession(rows, cols)
+	session.notify = func(message string) {
+		send(&pb.ShellOutput{Stderr: []byte("\r\n[sloth-runner] " + message + "\r\n")})
+	}
+	// Recording is only ever asked for by the client opening the session
+	if initial.Record {
+		session.recording = filepath.Join(config.GetShellRecordingDir(), fmt.Sprintf("%s-%s.cast", time.Now().Format("20060102-150405"), session.id))
+		session.recorder, err = newCastRecorder(session.recording, fmt.Sprintf("%s shell session %s", s.name, session.id), cols, rows)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to record the session: %v", err)
+		}
+		slog.Info("Recording shell session", "session", session.id, "file", session.recording)
+	}
+	defer func() {
+		if err := session.close(); err != nil {
+			slog.Warn("Failed to finish shell recording", "session", session.id, "error", err)
+		}
+	}()
+
+	if err := send(&pb.ShellOutput{SessionId: session.id, Recording: session.recording}); err != nil {
+		return err
+	}
+
 	// Create a shell initialization script that sets up the terminal correctly
 	shellScript := `#!/bin/bash
 # Reset terminal to sane state
@@ -2058,17 +2478,33 @@ exec /bin/bash --norc --noprofile -i
 		slog.Info("Shell session cleanup completed")
 	}()
 
+	// Observers can attach from now on
+	s.shells.add(session)
+	defer s.shells.remove(session.id)
+
 	// Channel to signal when to stop
 	done := make(chan struct{})
+	var doneOnce sync.Once
+	finish := func() { doneOnce.Do(func() { close(done) }) }
 	errChan := make(chan error, 2)
 
+	// The session ends after idleTimeout without input from the client
+	var idle <-chan time.Time
+	idleTimeout := time.Duration(initial.IdleTimeoutSeconds) * time.Second
+	var idleTimer *time.Timer
+	if idleTimeout > 0 {
+		idleTimer = time.NewTimer(idleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
 	// Goroutine to read from client and write to PTY
 	go func() {
 		for {
 			in, err := stream.Recv()
 			if err == io.EOF {
 				slog.Debug("Client closed stream")
-				close(done)
+				finish()
 				return
 			}
 			if err != nil {
@@ -2078,7 +2514,7 @@ exec /bin/bash --norc --noprofile -i
 
 			if in.Terminate {
 				slog.Debug("Client requested termination")
-				close(done)
+				finish()
 				return
 			}
 
@@ -2092,12 +2528,16 @@ exec /bin/bash --norc --noprofile -i
 					slog.Warn("Failed to resize PTY", "error", err)
 				} else {
 					slog.Debug("PTY resized", "rows", in.WindowRows, "cols", in.WindowCols)
+					session.resize(newSize.Rows, newSize.Cols)
 				}
 				continue
 			}
 
 			// Write stdin data to PTY
 			if len(in.StdinData) > 0 {
+				if idleTimer != nil {
+					idleTimer.Reset(idleTimeout)
+				}
 				if _, err := ptmx.Write(in.StdinData); err != nil {
 					slog.Error("Failed to write to PTY", "error", err)
 					errChan <- fmt.Errorf("failed to write to PTY: %w", err)
@@ -2113,7 +2553,8 @@ exec /bin/bash --norc --noprofile -i
 		for {
 			n, err := ptmx.Read(buf)
 			if n > 0 {
-				if sendErr := stream.Send(&pb.ShellOutput{
+				session.output(buf[:n])
+				if sendErr := send(&pb.ShellOutput{
 					Stdout: buf[:n],
 				}); sendErr != nil {
 					slog.Debug("Failed to send output (client disconnected)", "error", sendErr)
@@ -2127,7 +2568,7 @@ exec /bin/bash --norc --noprofile -i
 				} else {
 					slog.Debug("Shell exited (PTY closed)", "error", err)
 				}
-				close(done)
+				finish()
 				return
 			}
 		}
@@ -2138,16 +2579,74 @@ exec /bin/bash --norc --noprofile -i
 	case err := <-errChan:
 		slog.Info("Shell session ended with error", "error", err)
 		return err
+	case <-idle:
+		slog.Info("Shell session ended after idle timeout", "session", session.id, "timeout", idleTimeout)
+		return send(&pb.ShellOutput{
+			Completed: true,
+			Error:     fmt.Sprintf("session closed after %s without input", idleTimeout),
+		})
 	case <-done:
 		slog.Info("Shell session ended normally")
 	}
 
 	// Send completion message
-	return stream.Send(&pb.ShellOutput{
+	return send(&pb.ShellOutput{
 		Completed: true,
 	})
 }
 
+// observeShell streams the output of a running shell session to a
+// read-only observer. Input from the observer is dropped; it only ends
+// the observation by terminating or closing the stream.
+func (s *agentServer) observeShell(stream pb.Agent_InteractiveShellServer, sessionID string) error {
+	session, err := s.shells.find(sessionID)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	slog.Info("Observer attached to shell session", "session", session.id)
+	defer slog.Info("Observer detached from shell session", "session", session.id)
+
+	out, detach := session.observe()
+	defer detach()
+
+	rows, cols := session.size()
+	if err := stream.Send(&pb.ShellOutput{
+		SessionId:  session.id,
+		Recording:  session.recording,
+		WindowRows: uint32(rows),
+		WindowCols: uint32(cols),
+	}); err != nil {
+		return err
+	}
+
+	left := make(chan struct{})
+	go func() {
+		defer close(left)
+		for {
+			in, err := stream.Recv()
+			if err != nil || in.Terminate {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case output, ok := <-out:
+			if !ok {
+				return stream.Send(&pb.ShellOutput{Completed: true})
+			}
+			if err := stream.Send(output); err != nil {
+				return err
+			}
+		case <-left:
+			return nil
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
 // parseDuration parses a duration string with a default fallback
 func parseDuration(durationStr string, defaultDuration time.Duration) time.Duration {
 	if durationStr == "" {
@@ -2186,6 +2685,7 @@ func (s *agentServer) RegisterWatcher(ctx context.Context, in *pb.RegisterWatche
 		Type:       agentInternal.WatcherType(config.GetType()),
 		Conditions: make([]agentInternal.EventCondition, 0),
 		Interval:   parseDuration(config.GetInterval(), 10*time.Second),
+		Group:      config.GetGroup(),
 
 		// File-specific
 		FilePath:  config.GetFilePath(),
@@ -2259,6 +2759,7 @@ func (s *agentServer) ListWatchers(ctx context.Context, in *pb.ListWatchersReque
 				Type:            string(w.Type),
 				Conditions:      conditions,
 				Interval:        w.Interval.String(),
+				Group:           w.Group,
 				FilePath:        w.FilePath,
 				CheckHash:       w.CheckHash,
 				Recursive:       w.Recursive,
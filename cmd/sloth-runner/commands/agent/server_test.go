@@ -3,10 +3,15 @@ package agent
 import (
 	"archive/tar"
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 )
 
 func TestCreateTarData(t *testing.T) {
@@ -276,3 +281,40 @@ func BenchmarkExtractTarData(b *testing.B) {
 		extractTarData(reader, destDir)
 	}
 }
+
+// TestCheckTasks validates that tasks checked on the agent report what they
+// would change, with the state they found, and change nothing
+func TestCheckTasks(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	conf := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(conf, []byte("max_connections=100\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := fmt.Sprintf(`
+workflow.define("web", {
+  tasks = {
+    conf = { command = function()
+      file_ops.lineinfile({path = %q, line = "max_connections=500", regexp = "^max_connections="})
+      return true, "ok"
+    end },
+  },
+})`, conf)
+
+	s := &agentServer{}
+	resp, err := s.CheckTasks(context.Background(), &pb.ExecuteTasksRequest{TaskNames: []string{"conf"}, TaskGroup: "web", LuaScript: script})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetResults()) != 1 || resp.GetResults()[0].GetStatus() != "DryRun" {
+		t.Fatalf("Expected the task to be checked, got %v", resp.GetResults())
+	}
+	if changes := resp.GetResults()[0].GetChangesJson(); !strings.Contains(changes, "lineinfile") || !strings.Contains(changes, "-max_connections=100") {
+		t.Errorf("Expected the line change to be reported, got %s", changes)
+	}
+	if len(resp.GetWorkspace()) != 0 {
+		t.Error("Expected no workspace from a check")
+	}
+	if data, _ := os.ReadFile(conf); string(data) != "max_connections=100\n" {
+		t.Errorf("Expected the file to be left alone, got %q", data)
+	}
+}
@@ -16,6 +16,8 @@ type AgentRegistryClient interface {
 	UnregisterAgent(ctx context.Context, in *pb.UnregisterAgentRequest, opts ...grpc.CallOption) (*pb.UnregisterAgentResponse, error)
 	RegisterAgent(ctx context.Context, in *pb.RegisterAgentRequest, opts ...grpc.CallOption) (*pb.RegisterAgentResponse, error)
 	Heartbeat(ctx context.Context, in *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error)
+	GetFactHistory(ctx context.Context, in *pb.FactHistoryRequest, opts ...grpc.CallOption) (*pb.FactHistoryResponse, error)
+	ListAgentGroups(ctx context.Context, in *pb.ListGroupsRequest, opts ...grpc.CallOption) (*pb.ListGroupsResponse, error)
 }
 
 // AgentClient interface for dependency injection
@@ -24,6 +26,9 @@ type AgentClient interface {
 	RegisterWatcher(ctx context.Context, in *pb.RegisterWatcherRequest, opts ...grpc.CallOption) (*pb.RegisterWatcherResponse, error)
 	ListWatchers(ctx context.Context, in *pb.ListWatchersRequest, opts ...grpc.CallOption) (*pb.ListWatchersResponse, error)
 	RemoveWatcher(ctx context.Context, in *pb.RemoveWatcherRequest, opts ...grpc.CallOption) (*pb.RemoveWatcherResponse, error)
+	VerifyToolchain(ctx context.Context, in *pb.VerifyToolchainRequest, opts ...grpc.CallOption) (*pb.VerifyToolchainResponse, error)
+	GetConfig(ctx context.Context, in *pb.GetConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error)
+	ReloadConfig(ctx context.Context, in *pb.ReloadConfigRequest, opts ...grpc.CallOption) (*pb.AgentConfigResponse, error)
 }
 
 // AgentService provides agent operations with injected dependencies
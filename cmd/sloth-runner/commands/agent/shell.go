@@ -6,11 +6,11 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -19,29 +19,63 @@ func NewShellCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "shell <agent_name>",
 		Short: "Open an interactive shell on a remote agent",
-		Long:  `Opens an interactive bash shell on the specified agent via gRPC streaming. With --local, connects directly using local database.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Opens an interactive bash shell on the specified agent via gRPC streaming. With --local, connects directly using local database.
+
+Each session gets an ID, shown in the banner. Others can watch it with
+--read-only, which streams the output of the session without sending any
+input, e.g. for pair debugging; the owner of the session is told when an
+observer joins or leaves. With --record the agent records the session to an
+asciinema cast file in its shell-recordings directory; sessions are never
+recorded without it.`,
+		Example: `  sloth-runner agent shell web-01
+  sloth-runner agent shell web-01 --record --idle-timeout 15m
+  sloth-runner agent shell web-01 --read-only --session 3f9a1c2e`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			agentName := args[0]
 			local, _ := cmd.Flags().GetBool("local")
 
+			var opts shellOptions
+			opts.record, _ = cmd.Flags().GetBool("record")
+			opts.idleTimeout, _ = cmd.Flags().GetDuration("idle-timeout")
+			opts.readOnly, _ = cmd.Flags().GetBool("read-only")
+			opts.sessionID, _ = cmd.Flags().GetString("session")
+			if opts.readOnly && (opts.record || opts.idleTimeout > 0) {
+				return fmt.Errorf("--record and --idle-timeout apply to new sessions, not to --read-only")
+			}
+			if opts.sessionID != "" && !opts.readOnly {
+				return fmt.Errorf("--session selects the session to observe with --read-only")
+			}
+
 			// If --local flag is set, connect directly
 			if local {
-				return openAgentShellDirect(agentName)
+				return openAgentShellDirect(agentName, opts)
 			}
 
 			masterAddr := getMasterAddress(cmd)
-			return openAgentShell(agentName, masterAddr)
+			return openAgentShell(agentName, masterAddr, opts)
 		},
 	}
 
 	addMasterFlag(cmd)
 	cmd.Flags().Bool("local", false, "Connect directly to agent using local database")
+	cmd.Flags().Bool("record", false, "Record the session to a cast file on the agent")
+	cmd.Flags().Duration("idle-timeout", 0, "Close the session after this long without input (0 to never)")
+	cmd.Flags().Bool("read-only", false, "Watch a running session instead of opening one")
+	cmd.Flags().String("session", "", "Session to watch with --read-only (default: the only running one)")
 
 	return cmd
 }
 
-func openAgentShell(agentName, masterAddr string) error {
+// shellOptions are the settings of an agent shell session
+type shellOptions struct {
+	record      bool
+	idleTimeout time.Duration
+	readOnly    bool
+	sessionID   string
+}
+
+func openAgentShell(agentName, masterAddr string, opts shellOptions) error {
 	ctx := context.Background()
 
 	// Connect to master to get agent address
@@ -62,7 +96,7 @@ func openAgentShell(agentName, masterAddr string) error {
 
 	// Connect to agent directly with keep-alive
 	conn, err := grpc.Dial(agentInfo.AgentAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                30 * time.Second,
 			Timeout:             10 * time.Second,
@@ -82,7 +116,7 @@ func openAgentShell(agentName, masterAddr string) error {
 	}
 
 	// Use the robust interactive shell handler
-	return runInteractiveShellRobust(ctx, stream, agentName, agentInfo.AgentAddress)
+	return runInteractiveShellRobust(ctx, stream, agentName, agentInfo.AgentAddress, opts)
 }
 
 // printShellGoodbye displays a goodbye message when exiting the shell
@@ -92,7 +126,7 @@ func printShellGoodbye() {
 }
 
 // openAgentShellDirect opens shell directly to agent using local database
-func openAgentShellDirect(agentName string) error {
+func openAgentShellDirect(agentName string, opts shellOptions) error {
 	ctx := context.Background()
 
 	// Get agent address from local database
@@ -103,7 +137,7 @@ func openAgentShellDirect(agentName string) error {
 
 	// Connect to agent directly with keep-alive
 	conn, err := grpc.Dial(agentAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                30 * time.Second,
 			Timeout:             10 * time.Second,
@@ -123,5 +157,5 @@ func openAgentShellDirect(agentName string) error {
 	}
 
 	// Use the robust interactive shell handler
-	return runInteractiveShellRobust(ctx, stream, agentName, agentAddr)
+	return runInteractiveShellRobust(ctx, stream, agentName, agentAddr, opts)
 }
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -8,14 +9,16 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"golang.org/x/term"
+	"google.golang.org/grpc/status"
 )
 
 // runInteractiveShellRobust is a rock-solid implementation of the interactive shell
-func runInteractiveShellRobust(ctx context.Context, stream pb.Agent_InteractiveShellClient, agentName, agentAddress string) error {
+func runInteractiveShellRobust(ctx context.Context, stream pb.Agent_InteractiveShellClient, agentName, agentAddress string, opts shellOptions) error {
 	// Check TTY
 	if !term.IsTerminal(int(os.Stdin.Fd())) {
 		return fmt.Errorf("interactive shell requires a TTY")
@@ -27,16 +30,38 @@ func runInteractiveShellRobust(ctx context.Context, stream pb.Agent_InteractiveS
 		width, height = 80, 24
 	}
 
-	// Send initial window size
+	// Send initial window size and session settings
 	if err := stream.Send(&pb.ShellInput{
-		WindowRows: uint32(height),
-		WindowCols: uint32(width),
+		WindowRows:         uint32(height),
+		WindowCols:         uint32(width),
+		Record:             opts.record,
+		IdleTimeoutSeconds: uint32(opts.idleTimeout / time.Second),
+		ReadOnly:           opts.readOnly,
+		SessionId:          opts.sessionID,
 	}); err != nil {
 		return fmt.Errorf("failed to send initial window size: %w", err)
 	}
 
+	// The agent answers with the session it opened or attached to. Agents
+	// predating sessions start with the output of the shell instead.
+	first, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("failed to open shell: %s", status.Convert(err).Message())
+	}
+	if first.Completed && first.Error != "" {
+		return fmt.Errorf("failed to open shell: %s", first.Error)
+	}
+	if opts.record && first.Recording == "" {
+		return fmt.Errorf("agent %s can't record shell sessions, update it or drop --record", agentName)
+	}
+
 	// Print banner
-	printRobustBanner(agentName, agentAddress, width, height)
+	printRobustBanner(agentName, agentAddress, width, height, first, opts)
+	if opts.readOnly && (first.WindowCols > uint32(width) || first.WindowRows > uint32(height)) {
+		pterm.Warning.Printf("The session's terminal is %dx%d, larger than yours, so output may wrap\n", first.WindowCols, first.WindowRows)
+	}
+	os.Stdout.Write(first.Stdout)
+	os.Stderr.Write(first.Stderr)
 
 	// Set raw mode
 	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
@@ -162,6 +187,15 @@ func runInteractiveShellRobust(ctx context.Context, stream pb.Agent_InteractiveS
 				return
 			}
 
+			// Observers only send to stop watching
+			if opts.readOnly {
+				if bytes.ContainsAny(buf[:n], "\x03\x04q") {
+					stream.Send(&pb.ShellInput{Terminate: true})
+					return
+				}
+				continue
+			}
+
 			if n > 0 {
 				// Make a copy to avoid data races
 				data := make([]byte, n)
@@ -185,7 +219,7 @@ mainLoop:
 	for {
 		select {
 		case sig := <-sigChan:
-			if isSigwinch(sig) {
+			if isSigwinch(sig) && !opts.readOnly {
 				// Terminal resized
 				newWidth, newHeight, err := term.GetSize(int(os.Stdin.Fd()))
 				if err == nil {
@@ -195,10 +229,10 @@ mainLoop:
 						WindowCols: uint32(newWidth),
 					})
 				}
-			} else if sig == os.Interrupt {
+			} else if sig == os.Interrupt && !opts.readOnly {
 				// Ctrl+C - send to remote
 				stream.Send(&pb.ShellInput{StdinData: []byte{0x03}})
-			} else if sig == syscall.SIGTERM {
+			} else if sig == syscall.SIGTERM || sig == os.Interrupt {
 				// Terminate gracefully
 				stream.Send(&pb.ShellInput{Terminate: true})
 				cancel()
@@ -237,9 +271,29 @@ mainLoop:
 }
 
 // printRobustBanner shows a clean, informative banner
-func printRobustBanner(agentName, address string, width, height int) {
+func printRobustBanner(agentName, address string, width, height int, session *pb.ShellOutput, opts shellOptions) {
 	hostname, _ := os.Hostname()
 
+	details := ""
+	keys := "[Ctrl+D or 'exit' to quit] [Ctrl+C to interrupt]"
+	if session.SessionId != "" {
+		details += fmt.Sprintf("Session:  %s\n", pterm.FgGreen.Sprint(session.SessionId))
+	}
+	if session.Recording != "" {
+		details += fmt.Sprintf("Recorded: %s\n", pterm.FgRed.Sprint("● "+session.Recording))
+	}
+	if opts.readOnly {
+		details += fmt.Sprintf("Mode:     %s (terminal %dx%d)\n", pterm.FgYellow.Sprint("read-only"), session.WindowCols, session.WindowRows)
+		keys = "[q, Ctrl+C or Ctrl+D to stop watching]"
+	} else {
+		if opts.idleTimeout > 0 {
+			details += fmt.Sprintf("Idle:     closes after %s without input\n", opts.idleTimeout)
+		}
+		if session.SessionId != "" {
+			details += fmt.Sprintf("Watch:    sloth-runner agent shell %s --read-only --session %s\n", agentName, session.SessionId)
+		}
+	}
+
 	fmt.Printf("\033[2J\033[H") // Clear screen and move to top
 
 	banner := pterm.DefaultBox.
@@ -251,13 +305,15 @@ Agent:    %s
 Address:  %s
 Terminal: %dx%d
 Client:   %s
-
-[Ctrl+D or 'exit' to quit] [Ctrl+C to interrupt]
+%s
+%s
 `,
 			pterm.FgGreen.Sprint(agentName),
 			pterm.FgCyan.Sprint(address),
 			width, height,
 			pterm.FgYellow.Sprint(hostname),
+			details,
+			keys,
 		))
 
 	fmt.Println(banner)
@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// observerBuffer is how many output chunks an observer may fall behind
+// before it's disconnected
+const observerBuffer = 256
+
+// castRecorder writes a terminal session to an asciinema v2 cast file: a
+// JSON header line, then one [elapsed, code, data] line per output ("o")
+// or resize ("r") event. Play it back with 'asciinema play'.
+type castRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	start   time.Time
+	pending []byte // incomplete UTF-8 sequence held for the next output
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// newCastRecorder creates the cast file at path, readable only by its
+// owner as sessions may show secrets
+func newCastRecorder(path, title string, cols, rows uint16) (*castRecorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	r := &castRecorder{file: file, w: bufio.NewWriter(file), start: time.Now()}
+	header, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"SHELL": "/bin/bash", "TERM": "xterm-256color"},
+	})
+	r.w.Write(header)
+	r.w.WriteByte('\n')
+	return r, nil
+}
+
+// output records data written to the terminal. A UTF-8 sequence split
+// across reads of the PTY is held back until it's complete, as the cast
+// stores text.
+func (r *castRecorder) output(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data = append(r.pending, data...)
+	complete := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				complete = i
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[complete:]...)
+	if complete > 0 {
+		r.event("o", string(data[:complete]))
+	}
+}
+
+// resize records a change of the terminal size
+func (r *castRecorder) resize(cols, rows uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// event writes an event line. Callers hold r.mu.
+func (r *castRecorder) event(code, data string) {
+	line, _ := json.Marshal([]interface{}{time.Since(r.start).Seconds(), code, data})
+	r.w.Write(line)
+	r.w.WriteByte('\n')
+}
+
+// Close flushes the held back output and closes the cast file
+func (r *castRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) > 0 {
+		r.event("o", string(r.pending))
+		r.pending = nil
+	}
+	if err := r.w.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// shellSession is an interactive shell running on the agent, which
+// read-only observers can attach to
+type shellSession struct {
+	id      string
+	started time.Time
+
+	// recorder is nil unless the session is recorded to recording
+	recorder  *castRecorder
+	recording string
+
+	// notify tells the owner of the session about observers
+	notify func(message string)
+
+	mu        sync.Mutex
+	rows      uint16
+	cols      uint16
+	observers map[chan *pb.ShellOutput]struct{}
+	closed    bool
+}
+
+func newShellSession(rows, cols uint16) *shellSession {
+	return &shellSession{
+		id:        newShellSessionID(),
+		started:   time.Now(),
+		rows:      rows,
+		cols:      cols,
+		observers: make(map[chan *pb.ShellOutput]struct{}),
+		notify:    func(string) {},
+	}
+}
+
+func newShellSessionID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// output records and fans out data written to the terminal. Observers too
+// slow to keep up are disconnected rather than stalling the shell.
+func (s *shellSession) output(data []byte) {
+	if s.recorder != nil {
+		s.recorder.output(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.observers {
+		select {
+		case ch <- &pb.ShellOutput{Stdout: append([]byte(nil), data...)}:
+		default:
+			delete(s.observers, ch)
+			close(ch)
+		}
+	}
+}
+
+// resize records a change of the terminal size and passes it on to
+// observers
+func (s *shellSession) resize(rows, cols uint16) {
+	if s.recorder != nil {
+		s.recorder.resize(cols, rows)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows, s.cols = rows, cols
+	for ch := range s.observers {
+		select {
+		case ch <- &pb.ShellOutput{WindowRows: uint32(rows), WindowCols: uint32(cols)}:
+		default:
+		}
+	}
+}
+
+// size returns the current terminal size
+func (s *shellSession) size() (rows, cols uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rows, s.cols
+}
+
+// observe attaches an observer. The channel receives the output of the
+// session from now on and is closed when the session ends or the observer
+// falls behind; call detach to stop observing earlier.
+func (s *shellSession) observe() (out <-chan *pb.ShellOutput, detach func()) {
+	ch := make(chan *pb.ShellOutput, observerBuffer)
+
+	s.mu.Lock()
+	if s.closed {
+		close(ch)
+		s.mu.Unlock()
+		return ch, func() {}
+	}
+	s.observers[ch] = struct{}{}
+	watching := len(s.observers)
+	s.mu.Unlock()
+	s.notify(fmt.Sprintf("an observer joined this session (%d watching)", watching))
+
+	return ch, func() {
+		s.mu.Lock()
+		_, attached := s.observers[ch]
+		if attached {
+			delete(s.observers, ch)
+			close(ch)
+		}
+		watching := len(s.observers)
+		s.mu.Unlock()
+		if attached {
+			s.notify(fmt.Sprintf("an observer left this session (%d watching)", watching))
+		}
+	}
+}
+
+// close disconnects the observers and finishes the recording
+func (s *shellSession) close() error {
+	s.mu.Lock()
+	s.closed = true
+	for ch := range s.observers {
+		delete(s.observers, ch)
+		close(ch)
+	}
+	s.mu.Unlock()
+
+	if s.recorder != nil {
+		return s.recorder.Close()
+	}
+	return nil
+}
+
+// shellRegistry holds the running shell sessions of the agent. The zero
+// value is ready to use.
+type shellRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*shellSession
+}
+
+func (r *shellRegistry) add(session *shellSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions == nil {
+		r.sessions = make(map[string]*shellSession)
+	}
+	r.sessions[session.id] = session
+}
+
+func (r *shellRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+}
+
+// find returns the session with the given ID, or the only running session
+// when id is empty
+func (r *shellRegistry) find(id string) (*shellSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id != "" {
+		session, ok := r.sessions[id]
+		if !ok {
+			return nil, fmt.Errorf("no shell session %s on this agent", id)
+		}
+		return session, nil
+	}
+
+	switch len(r.sessions) {
+	case 0:
+		return nil, fmt.Errorf("no shell session running on this agent")
+	case 1:
+		for _, session := range r.sessions {
+			return session, nil
+		}
+	}
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return nil, fmt.Errorf("%d shell sessions running, pick one with --session: %s", len(ids), strings.Join(ids, ", "))
+}
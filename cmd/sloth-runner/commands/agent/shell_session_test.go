@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+func TestCastRecorder_WritesAsciicastV2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recordings", "session.cast")
+	r, err := newCastRecorder(path, "web-01 shell session 3f9a1c2e", 120, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "ção" split in the middle of "ç" across two reads of the PTY
+	r.output([]byte("$ echo a\xc3"))
+	r.output([]byte("\xa7\xc3\xa3o\r\n"))
+	r.resize(100, 30)
+	r.output([]byte("tail \xe2\x82"))
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the recording to be private, got %v", info.Mode().Perm())
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected a header and 5 events, got:\n%s", content)
+	}
+
+	var header castHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Timestamp == 0 {
+		t.Errorf("Unexpected header %+v", header)
+	}
+
+	want := [][2]string{
+		{"o", "$ echo a"},
+		{"o", "ção\r\n"},
+		{"r", "100x30"},
+		{"o", "tail "},
+		// An incomplete sequence left at the end is written on close
+		{"o", "\ufffd\ufffd"},
+	}
+	for i, w := range want {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(lines[i+1]), &event); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := event[0].(float64); !ok || event[1] != w[0] || event[2] != w[1] {
+			t.Errorf("Event %d = %v, want [<elapsed> %q %q]", i, event, w[0], w[1])
+		}
+	}
+
+	// An existing recording is never overwritten
+	if _, err := newCastRecorder(path, "", 80, 24); err == nil {
+		t.Error("Expected an error recording over an existing file")
+	}
+}
+
+func TestShellSession_Observers(t *testing.T) {
+	session := newShellSession(24, 80)
+	var notices []string
+	session.notify = func(message string) { notices = append(notices, message) }
+
+	first, detachFirst := session.observe()
+	second, _ := session.observe()
+
+	session.output([]byte("ls\r\n"))
+	session.resize(30, 100)
+	for _, out := range []<-chan *pb.ShellOutput{first, second} {
+		if got := <-out; string(got.Stdout) != "ls\r\n" {
+			t.Errorf("Expected the output, got %v", got)
+		}
+		if got := <-out; got.WindowRows != 30 || got.WindowCols != 100 {
+			t.Errorf("Expected the resize, got %v", got)
+		}
+	}
+	if rows, cols := session.size(); rows != 30 || cols != 100 {
+		t.Errorf("size() = %dx%d, want 30x100", rows, cols)
+	}
+
+	detachFirst()
+	detachFirst()
+	if _, ok := <-first; ok {
+		t.Error("Expected a detached observer's channel to be closed")
+	}
+
+	// An observer that doesn't keep up is dropped instead of blocking the shell
+	for i := 0; i <= observerBuffer; i++ {
+		session.output([]byte("x"))
+	}
+	drained := 0
+	for range second {
+		drained++
+	}
+	if drained != observerBuffer {
+		t.Errorf("Expected %d buffered chunks before the slow observer was dropped, got %d", observerBuffer, drained)
+	}
+
+	third, _ := session.observe()
+	if err := session.close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-third; ok {
+		t.Error("Expected observers to be disconnected when the session ends")
+	}
+	late, _ := session.observe()
+	if _, ok := <-late; ok {
+		t.Error("Expected no output when observing a closed session")
+	}
+
+	want := []string{
+		"an observer joined this session (1 watching)",
+		"an observer joined this session (2 watching)",
+		"an observer left this session (1 watching)",
+		"an observer joined this session (1 watching)",
+	}
+	if strings.Join(notices, "\n") != strings.Join(want, "\n") {
+		t.Errorf("notices = %q, want %q", notices, want)
+	}
+}
+
+func TestShellRegistry_Find(t *testing.T) {
+	var registry shellRegistry
+	if _, err := registry.find(""); err == nil || !strings.Contains(err.Error(), "no shell session running") {
+		t.Errorf("Expected no session to be found, got %v", err)
+	}
+
+	a := newShellSession(24, 80)
+	registry.add(a)
+	if got, err := registry.find(""); err != nil || got != a {
+		t.Errorf("Expected the only session to be found, got %v, %v", got, err)
+	}
+
+	b := newShellSession(24, 80)
+	registry.add(b)
+	if _, err := registry.find(""); err == nil || !strings.Contains(err.Error(), "--session") {
+		t.Errorf("Expected to be asked to pick a session, got %v", err)
+	}
+	if got, err := registry.find(b.id); err != nil || got != b {
+		t.Errorf("Expected session %s, got %v, %v", b.id, got, err)
+	}
+
+	registry.remove(b.id)
+	if _, err := registry.find(b.id); err == nil {
+		t.Error("Expected a removed session not to be found")
+	}
+}
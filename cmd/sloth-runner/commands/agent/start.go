@@ -12,17 +12,22 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sudo"
 	"github.com/chalkan3-sloth/sloth-runner/internal/telemetry"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -41,8 +46,16 @@ func NewStartCommand(ctx *commands.AppContext) *cobra.Command {
 			reportAddress, _ := cmd.Flags().GetString("report-address")
 			telemetryEnabled, _ := cmd.Flags().GetBool("telemetry")
 			metricsPort, _ := cmd.Flags().GetInt("metrics-port")
-
-			return startAgent(ctx, port, masterAddr, agentName, daemon, bindAddress, reportAddress, telemetryEnabled, metricsPort)
+			textfileDir, _ := cmd.Flags().GetString("textfile-dir")
+			cacheOpts := getArtifactCacheOptions(cmd)
+			workflowOpts := getWorkflowCacheOptions(cmd)
+			blobOpts := getBlobClientOptions(cmd)
+			factsOpts := getCustomFactsOptions(cmd)
+			policyFile, _ := cmd.Flags().GetString("policy")
+			joinOpts := getJoinOptions(cmd)
+			configFile, _ := cmd.Flags().GetString("config")
+
+			return startAgent(ctx, port, masterAddr, agentName, daemon, bindAddress, reportAddress, telemetryEnabled, metricsPort, textfileDir, cacheOpts, workflowOpts, blobOpts, factsOpts, policyFile, joinOpts, configFile)
 		},
 	}
 
@@ -54,11 +67,19 @@ func NewStartCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd.Flags().String("report-address", "", "Address to report to master (if different from bind)")
 	cmd.Flags().Bool("telemetry", false, "Enable telemetry and metrics server")
 	cmd.Flags().Int("metrics-port", 9090, "Port for metrics server")
+	cmd.Flags().String("textfile-dir", "", "node_exporter textfile collector directory to write task metrics to")
+	cmd.Flags().String("policy", "", "Admission policy file checked before privileged module calls (default: <data-dir>/policies.yaml if present)")
+	cmd.Flags().String("config", "", "Agent config file overriding the flags, re-read on SIGHUP or 'agent config reload' (default: <data-dir>/agent.yaml if present)")
+	addArtifactCacheFlags(cmd)
+	addWorkflowCacheFlags(cmd)
+	addBlobClientFlags(cmd)
+	addCustomFactsFlags(cmd)
+	addJoinFlags(cmd)
 
 	return cmd
 }
 
-func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string, daemon bool, bindAddress, reportAddress string, telemetryEnabled bool, metricsPort int) error {
+func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string, daemon bool, bindAddress, reportAddress string, telemetryEnabled bool, metricsPort int, textfileDir string, cacheOpts artifactCacheOptions, workflowOpts workflowCacheOptions, blobOpts blobClientOptions, factsOpts customFactsOptions, policyFile string, joinOpts joinOptions, configFile string) error {
 	// Apply runtime optimizations for reduced resource usage
 	configureAgentRuntimeOptimizations()
 
@@ -91,6 +112,21 @@ func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string
 		if metricsPort != 9090 {
 			cmdArgs = append(cmdArgs, "--metrics-port", strconv.Itoa(metricsPort))
 		}
+		if textfileDir != "" {
+			cmdArgs = append(cmdArgs, "--textfile-dir", textfileDir)
+		}
+		cmdArgs = append(cmdArgs, cacheOpts.daemonArgs()...)
+		cmdArgs = append(cmdArgs, workflowOpts.daemonArgs()...)
+		cmdArgs = append(cmdArgs, blobOpts.daemonArgs()...)
+		cmdArgs = append(cmdArgs, factsOpts.daemonArgs()...)
+		cmdArgs = append(cmdArgs, mtls.DaemonArgs()...)
+		if policyFile != "" {
+			cmdArgs = append(cmdArgs, "--policy", policyFile)
+		}
+		if configFile != "" {
+			cmdArgs = append(cmdArgs, "--config", configFile)
+		}
+		cmdArgs = append(cmdArgs, joinOpts.daemonArgs()...)
 
 		command := exec.Command(os.Args[0], cmdArgs...)
 		stdoutFile, err := os.OpenFile("agent.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -122,6 +158,25 @@ func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string
 		return nil
 	}
 
+	// The config file overrides the flags it sets, now and on reloads
+	flagConfig := agentConfig{
+		Master:        masterAddr,
+		Telemetry:     telemetryEnabled,
+		MetricsPort:   metricsPort,
+		WorkflowCache: workflowOpts,
+		Facts:         factsOpts,
+	}
+	configPath := agentConfigPath(configFile)
+	cfg, err := loadAgentConfig(configPath, flagConfig)
+	if err != nil {
+		return err
+	}
+	if configPath != "" {
+		pterm.Success.Printf("✓ Agent config loaded from %s\n", configPath)
+	}
+	masterAddr, telemetryEnabled, metricsPort = cfg.Master, cfg.Telemetry, cfg.MetricsPort
+	workflowOpts, factsOpts = cfg.WorkflowCache, cfg.Facts
+
 	listenAddr := fmt.Sprintf(":%d", port)
 	if bindAddress != "" {
 		listenAddr = fmt.Sprintf("%s:%d", bindAddress, port)
@@ -142,21 +197,53 @@ func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string
 		agentReportAddress = fmt.Sprintf("%s:%d", bindAddress, port)
 	}
 
-	pterm.Warning.Println("Starting agent in insecure mode.")
+	if mtls.Enabled() {
+		pterm.Info.Println("Starting agent with mTLS.")
+	} else {
+		pterm.Warning.Println("Starting agent in insecure mode: any host that can reach it can run tasks. Use --tls-cert, --tls-key and --tls-ca to require mTLS.")
+	}
+
+	// Policies must be active before any Lua state is prepared
+	if err := configurePolicy(policyFile, agentName); err != nil {
+		return fmt.Errorf("failed to load policies: %w", err)
+	}
 
 	if masterAddr != "" {
-		// Start connection manager with reconnection logic
-		go startMasterConnection(ctx, masterAddr, agentName, agentReportAddress)
+		configureMasterClients(masterAddr, agentName)
+	}
+
+	if cacheOpts.Enabled {
+		if _, err := startArtifactCache(cacheOpts, agentName, masterAddr, bindAddress, agentReportAddress); err != nil {
+			pterm.Warning.Printf("⚠ Failed to start artifact cache: %v\n", err)
+			slog.Warn("Artifact cache initialization failed", "error", err)
+		}
+	}
+
+	if err := configureBlobClient(blobOpts); err != nil {
+		pterm.Warning.Printf("⚠ Failed to configure blob store client: %v\n", err)
+		slog.Warn("Blob store client initialization failed", "error", err)
 	}
 
 	// Initialize telemetry server
-	telemetryServer := telemetry.InitGlobal(metricsPort, telemetryEnabled)
+	telemetry.InitGlobal(metricsPort, telemetryEnabled)
 	if telemetryEnabled {
-		if err := telemetryServer.Start(); err != nil {
+		if err := reconfigureTelemetry(metricsPort, true, ctx.Version); err != nil {
 			slog.Error("Failed to start telemetry server", "error", err)
+		}
+	}
+
+	// Initialize node_exporter textfile metrics
+	if textfileDir != "" {
+		exporter, err := agentInternal.NewTextfileExporter(textfileDir, agentName, ctx.Version)
+		if err == nil {
+			err = exporter.Write()
+		}
+		if err != nil {
+			pterm.Warning.Printf("⚠ Failed to initialize textfile metrics: %v\n", err)
+			slog.Warn("Textfile metrics initialization failed", "error", err)
 		} else {
-			telemetry.SetAgentInfo(ctx.Version, runtime.GOOS, runtime.GOARCH)
-			pterm.Success.Printf("✓ Telemetry server started at %s\n", telemetryServer.GetEndpoint())
+			agentInternal.SetGlobalTextfileExporter(exporter)
+			pterm.Success.Printf("✓ Writing task metrics to %s\n", exporter.Path())
 		}
 	}
 
@@ -175,12 +262,33 @@ func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string
 		}),
 	}
 
+	// Runners seal sudo passwords to this key; it lives as long as the
+	// process and is never stored
+	sudoKey, err := sudo.GenerateKeyPair()
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts, mtls.ServerOptions()...)
+
 	s := grpc.NewServer(opts...)
 	server := &agentServer{
 		grpcServer:    s,
+		name:          agentName,
 		cachedMetrics: &CachedMetrics{},
+		sudoKey:       sudoKey,
+	}
+	server.config = agentConfigState{
+		path:     configPath,
+		flags:    flagConfig,
+		applied:  cfg,
+		loadedAt: time.Now(),
+		version:  ctx.Version,
 	}
+	workflowOpts.apply(server)
+	factsOpts.apply()
 	pb.RegisterAgentServer(s, server)
+	healthpb.RegisterHealthServer(s, health.NewServer())
 
 	// Initialize event worker to send events to master
 	var eventWorker *agentInternal.EventWorker
@@ -205,9 +313,20 @@ func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string
 			watcherManager = agentInternal.NewEventWatcherManager(eventWorker)
 			pterm.Success.Println("✓ Event watcher manager initialized")
 			slog.Info("Event watcher manager ready to accept watchers")
+
+			if err := reconcileWatchers(watcherManager, nil, cfg.Watchers); err != nil {
+				pterm.Warning.Printf("⚠ Failed to register config watchers: %v\n", err)
+				slog.Warn("Config watcher registration failed", "error", err)
+			}
 		}
+	} else if len(cfg.Watchers) > 0 {
+		pterm.Warning.Println("⚠ Config watchers need a master to send their events to, they are not registered")
 	}
 
+	// Start connection manager with reconnection logic. It follows the
+	// master of the config, which a reload can change.
+	go startMasterConnection(ctx, server.masterAddr, agentName, agentReportAddress, joinOpts)
+
 	pterm.Success.Printf("✓ Agent '%s' listening at %v\n", agentName, lis.Addr())
 	pterm.Info.Println("Optimizations enabled: 30s metrics cache, batched DB writes, process list caching")
 
@@ -217,6 +336,20 @@ func startAgent(ctx *commands.AppContext, port int, masterAddr, agentName string
 	server.eventWorker = eventWorker
 	server.watcherManager = watcherManager
 
+	// Under systemd (Type=notify), report readiness, health and watchdog
+	// pings so a hung agent gets restarted
+	stopWatchdog, err := startWatchdog(server, lis.Addr())
+	if err != nil {
+		slog.Warn("systemd watchdog disabled", "error", err)
+	} else {
+		defer stopWatchdog()
+	}
+
+	// Reload the config on SIGHUP, as 'agent config reload' does
+	reloads := make(chan os.Signal, 1)
+	notifyReload(reloads)
+	go server.reloadOnSignal(reloads)
+
 	if err := s.Serve(lis); err != nil {
 		return fmt.Errorf("failed to serve: %v", err)
 	}
@@ -254,16 +387,73 @@ func configureAgentRuntimeOptimizations() {
 		"periodic_gc", "30s")
 }
 
-func startMasterConnection(ctx *commands.AppContext, masterAddr, agentName, agentReportAddress string) {
+// configureMasterClients makes locks, release checks and agent lookups on
+// this agent ask the master at masterAddr
+func configureMasterClients(masterAddr, agentName string) {
+	if err := configureLocks(masterAddr, agentName); err != nil {
+		pterm.Warning.Printf("⚠ Failed to configure locks: %v\n", err)
+		slog.Warn("Lock client initialization failed", "error", err)
+	}
+
+	if err := configureReleases(masterAddr); err != nil {
+		pterm.Warning.Printf("⚠ Failed to configure release checks: %v\n", err)
+		slog.Warn("Release client initialization failed", "error", err)
+	}
+
+	if err := configureAgentWait(masterAddr); err != nil {
+		pterm.Warning.Printf("⚠ Failed to configure agent lookups: %v\n", err)
+		slog.Warn("Agent lookup client initialization failed", "error", err)
+	}
+}
+
+// scheduleStats registers the schedule stats collector the first time
+// telemetry is enabled
+var scheduleStats sync.Once
+
+// reconfigureTelemetry starts the metrics server on port, restarting it if
+// it runs, or stops it when disabled
+func reconfigureTelemetry(port int, enabled bool, version string) error {
+	telemetryServer := telemetry.GetGlobal()
+	if err := telemetryServer.Reconfigure(context.Background(), port, enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		pterm.Info.Println("Telemetry server stopped")
+		return nil
+	}
+	telemetry.SetAgentInfo(version, runtime.GOOS, runtime.GOARCH)
+	pterm.Success.Printf("✓ Telemetry server started at %s\n", telemetryServer.GetEndpoint())
+
+	// Export the stats of workflows scheduled on this host
+	scheduleStats.Do(func() {
+		if stats, err := scheduler.DefaultStatsStore(); err != nil {
+			slog.Warn("Schedule stats metrics are disabled", "error", err)
+		} else if err := telemetryServer.Register(scheduler.NewStatsCollector(stats, scheduler.DefaultMetricsWindow)); err != nil {
+			slog.Warn("Failed to register schedule stats metrics", "error", err)
+		}
+	})
+	return nil
+}
+
+// startMasterConnection registers the agent with the master master returns
+// and sends it heartbeats, reconnecting when the connection is lost or the
+// master changes. It waits while there is no master.
+func startMasterConnection(ctx *commands.AppContext, master func() string, agentName, agentReportAddress string, joinOpts joinOptions) {
 	reconnectDelay := 5 * time.Second
 	maxReconnectDelay := 60 * time.Second
 	heartbeatInterval := 5 * time.Second
 
 	for {
+		masterAddr := master()
+		if masterAddr == "" {
+			time.Sleep(reconnectDelay)
+			continue
+		}
+
 		// Create connection context with timeout
 		connCtx, connCancel := context.WithTimeout(context.Background(), 10*time.Second)
 		conn, err := grpc.DialContext(connCtx, masterAddr,
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			mtls.DialOption(),
 			grpc.WithBlock(),
 		)
 		connCancel()
@@ -285,12 +475,22 @@ func startMasterConnection(ctx *commands.AppContext, masterAddr, agentName, agen
 		registryClient := pb.NewAgentRegistryClient(conn)
 
 		// Try to register with master
-		regCtx, regCancel := context.WithTimeout(context.Background(), 10*time.Second)
-		_, err = registryClient.RegisterAgent(regCtx, &pb.RegisterAgentRequest{
+		req := &pb.RegisterAgentRequest{
 			AgentName:    agentName,
 			AgentAddress: agentReportAddress,
-		})
+		}
+		if err := joinOpts.apply(agentName, req); err != nil {
+			slog.Error("Failed to read what to register with", "error", err)
+		}
+		regCtx, regCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		resp, err := registryClient.RegisterAgent(regCtx, req)
 		regCancel()
+		if err == nil && resp.AgentCredential != "" {
+			if err := joinOpts.saveCredential(agentName, resp.AgentCredential); err != nil {
+				slog.Error("Failed to keep the agent credential, the agent can't re-register once restarted", "error", err)
+				pterm.Warning.Printf("⚠ %v\n", err)
+			}
+		}
 
 		if err != nil {
 			slog.Error(fmt.Sprintf("Failed to register with master: %v. Reconnecting...", err))
@@ -315,24 +515,45 @@ func startMasterConnection(ctx *commands.AppContext, masterAddr, agentName, agen
 		}
 		trackAgentRegistration(agentName, host, reportPort, true)
 
+		// Re-announce cached artifacts so the master's index survives restarts
+		if cache := agentInternal.GetGlobalArtifactCache(); cache != nil {
+			go cache.AnnounceAll(context.Background())
+		}
+
 		// Start heartbeat loop
 		connected := true
 		consecutiveFailures := 0
 		maxConsecutiveFailures := 3
 		heartbeatCounter := 0
 		sysInfoCollectInterval := 12 // Collect system info every 12 heartbeats (60 seconds)
+		sysInfoRefreshInterval := 10 // Send unchanged facts every 10 collections (10 minutes)
+		sysInfoCollections := 0
+		lastFactsFingerprint := "" // empty after (re)connecting so the first collection is always sent
 
 		for connected {
 			time.Sleep(heartbeatInterval)
 			heartbeatCounter++
 
-			// Collect system info periodically (every minute)
+			if addr := master(); addr != masterAddr {
+				slog.Info("Master changed, reconnecting", "old_master", masterAddr, "master", addr)
+				pterm.Info.Printf("🔄 Master changed to %s\n", addr)
+				break
+			}
+
+			// Collect system info periodically (every minute). It is only sent
+			// when the stable facts changed, or on the slower refresh interval
+			// so volatile values (uptime, usage) don't go stale on the master.
 			var sysInfoJSON string
+			var factsFingerprint string
 			if heartbeatCounter%sysInfoCollectInterval == 0 {
 				if sysInfo, err := agentInternal.CollectSystemInfo(); err == nil {
-					if jsonStr, err := sysInfo.ToJSON(); err == nil {
-						sysInfoJSON = jsonStr
-						slog.Debug("System info collected and will be sent with heartbeat")
+					factsFingerprint = agentInternal.StableFacts(sysInfo).Fingerprint()
+					sysInfoCollections++
+					if factsFingerprint != lastFactsFingerprint || sysInfoCollections%sysInfoRefreshInterval == 0 {
+						if jsonStr, err := sysInfo.ToJSON(); err == nil {
+							sysInfoJSON = jsonStr
+							slog.Debug("System info collected and will be sent with heartbeat")
+						}
 					}
 				}
 			}
@@ -355,6 +576,9 @@ func startMasterConnection(ctx *commands.AppContext, masterAddr, agentName, agen
 					connected = false
 				}
 			} else {
+				if sysInfoJSON != "" {
+					lastFactsFingerprint = factsFingerprint
+				}
 				if consecutiveFailures > 0 {
 					consecutiveFailures = 0
 					slog.Info("Heartbeat recovered, connection stable")
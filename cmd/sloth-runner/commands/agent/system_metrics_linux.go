@@ -12,7 +12,6 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
-	"time"
 )
 
 // MemoryInfo holds memory statistics
@@ -146,6 +145,11 @@ func getMemoryInfo() (MemoryInfo, error) {
 	}, nil
 }
 
+// rootDisk is the path whose disk usage the agent reports
+func rootDisk() string {
+	return "/"
+}
+
 // getDiskUsage returns disk usage for a given path
 func getDiskUsage(path string) (DiskInfo, error) {
 	var stat syscall.Statfs_t
@@ -406,7 +410,7 @@ func getDiskPartitions() ([]DiskPartitionInfo, error) {
 // collectLogs collects system logs and sends to channel
 func collectLogs(logChan chan LogEntry) {
 	// Read from journalctl or syslog
-	cmd := exec.Command("journalctl", "-f", "-n", "10", "-o", "short")
+	cmd := exec.Command("journalctl", "-f", "-n", "10", "-o", "json")
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		slog.Error("Failed to open journalctl pipe", "error", err)
@@ -419,12 +423,16 @@ func collectLogs(logChan chan LogEntry) {
 	}
 
 	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		line := scanner.Text()
+		entry, _, err := parseJournalEntry(scanner.Bytes())
+		if err != nil {
+			continue
+		}
 		logChan <- LogEntry{
-			Timestamp: time.Now().Unix(),
-			Level:     "INFO",
-			Message:   line,
+			Timestamp: entry.Timestamp,
+			Level:     entry.Level,
+			Message:   entry.Message,
 		}
 	}
 }
@@ -4,7 +4,18 @@
 package agent
 
 import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
 )
 
 // MemoryInfo holds memory statistics
@@ -65,30 +76,51 @@ type LogEntry struct {
 	Message   string
 }
 
-// getCPUPercent returns current CPU usage percentage (stub for Windows)
+// The collectors read Windows performance counters, WMI and the Win32 APIs
+// through gopsutil.
+
+// getCPUPercent returns current CPU usage percentage since the previous call
 func getCPUPercent() float64 {
-	// TODO: Implement Windows-specific CPU monitoring using WMI or Performance Counters
-	return 0.0
+	percents, err := cpu.Percent(0, false)
+	if err != nil || len(percents) == 0 {
+		return 0.0
+	}
+	return percents[0]
 }
 
-// getMemoryInfo returns system memory information (stub for Windows)
+// getMemoryInfo returns system memory information
 func getMemoryInfo() (MemoryInfo, error) {
-	// TODO: Implement Windows-specific memory monitoring using GlobalMemoryStatusEx
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return MemoryInfo{}, err
+	}
 	return MemoryInfo{
-		Total: 0,
-		Used:  0,
-		Free:  0,
+		Total: vm.Total,
+		Used:  vm.Used,
+		Free:  vm.Available,
 	}, nil
 }
 
-// getDiskUsage returns disk usage for a given path (stub for Windows)
+// rootDisk is the path whose disk usage the agent reports: the system drive
+func rootDisk() string {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	return drive + `\`
+}
+
+// getDiskUsage returns disk usage for a given path
 func getDiskUsage(path string) (DiskInfo, error) {
-	// TODO: Implement Windows-specific disk usage monitoring using GetDiskFreeSpaceEx
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return DiskInfo{}, err
+	}
 	return DiskInfo{
-		Total:       0,
-		Used:        0,
-		Free:        0,
-		UsedPercent: 0,
+		Total:       usage.Total,
+		Used:        usage.Used,
+		Free:        usage.Free,
+		UsedPercent: usage.UsedPercent,
 	}, nil
 }
 
@@ -98,54 +130,160 @@ func getLoadAverage() [3]float64 {
 	return [3]float64{0, 0, 0}
 }
 
-// getProcessCount returns the number of running processes (stub for Windows)
+// getProcessCount returns the number of running processes
 func getProcessCount() int {
-	// TODO: Implement Windows-specific process counting using WMI or ToolHelp32
-	return 0
+	pids, err := process.Pids()
+	if err != nil {
+		return 0
+	}
+	return len(pids)
 }
 
-// getSystemUptime returns system uptime in seconds (stub for Windows)
+// getSystemUptime returns system uptime in seconds
 func getSystemUptime() uint64 {
-	// TODO: Implement Windows-specific uptime using GetTickCount64
-	return 0
+	uptime, err := host.Uptime()
+	if err != nil {
+		return 0
+	}
+	return uptime
 }
 
-// getProcesses returns list of running processes (stub for Windows)
+// getProcesses returns list of running processes. Processes the agent may
+// not inspect, such as protected system ones, are listed by name only.
 func getProcesses() ([]ProcessInfo, error) {
-	// TODO: Implement Windows-specific process listing using WMI or ToolHelp32
-	return []ProcessInfo{}, nil
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	processes := make([]ProcessInfo, 0, len(procs))
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		info := ProcessInfo{PID: int(p.Pid), Name: name, Status: "running"}
+		info.CPUPercent, _ = p.CPUPercent()
+		if percent, err := p.MemoryPercent(); err == nil {
+			info.MemoryPercent = float64(percent)
+		}
+		if memInfo, err := p.MemoryInfo(); err == nil {
+			info.MemoryBytes = memInfo.RSS
+		}
+		info.User, _ = p.Username()
+		info.Command, _ = p.Cmdline()
+		if created, err := p.CreateTime(); err == nil {
+			info.StartedAt = created / 1000
+		}
+		processes = append(processes, info)
+	}
+	return processes, nil
 }
 
-// getNetworkInterfaces returns list of network interfaces (stub for Windows)
+// getNetworkInterfaces returns list of network interfaces
 func getNetworkInterfaces() ([]NetworkInterfaceInfo, error) {
-	// TODO: Implement Windows-specific network interface listing using GetAdaptersAddresses
-	return []NetworkInterfaceInfo{}, nil
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string]net.IOCountersStat)
+	if stats, err := net.IOCounters(true); err == nil {
+		for _, stat := range stats {
+			counters[stat.Name] = stat
+		}
+	}
+
+	interfaces := make([]NetworkInterfaceInfo, 0, len(ifaces))
+	for _, iface := range ifaces {
+		ips := make([]string, 0, len(iface.Addrs))
+		for _, addr := range iface.Addrs {
+			ips = append(ips, strings.Split(addr.Addr, "/")[0])
+		}
+		isUp := false
+		for _, flag := range iface.Flags {
+			if flag == "up" {
+				isUp = true
+			}
+		}
+		stats := counters[iface.Name]
+		interfaces = append(interfaces, NetworkInterfaceInfo{
+			Name:        iface.Name,
+			IPAddresses: ips,
+			MACAddress:  iface.HardwareAddr,
+			BytesSent:   stats.BytesSent,
+			BytesRecv:   stats.BytesRecv,
+			IsUp:        isUp,
+		})
+	}
+	return interfaces, nil
 }
 
-// getDiskPartitions returns list of disk partitions (stub for Windows)
+// getDiskPartitions returns list of disk partitions, the fixed drives
 func getDiskPartitions() ([]DiskPartitionInfo, error) {
-	// TODO: Implement Windows-specific disk partition listing using GetLogicalDrives
-	return []DiskPartitionInfo{}, nil
+	parts, err := disk.Partitions(false)
+	if err != nil {
+		return nil, err
+	}
+
+	ioCounters, _ := disk.IOCounters()
+	partitions := make([]DiskPartitionInfo, 0, len(parts))
+	for _, part := range parts {
+		usage, err := disk.Usage(part.Mountpoint)
+		if err != nil {
+			continue
+		}
+		info := DiskPartitionInfo{
+			Device:     part.Device,
+			Mountpoint: part.Mountpoint,
+			FSType:     part.Fstype,
+			TotalBytes: usage.Total,
+			UsedBytes:  usage.Used,
+			FreeBytes:  usage.Free,
+			Percent:    usage.UsedPercent,
+		}
+		if counters, ok := ioCounters[part.Device]; ok {
+			info.IOReadBytes = counters.ReadBytes
+			info.IOWriteBytes = counters.WriteBytes
+		}
+		partitions = append(partitions, info)
+	}
+	return partitions, nil
 }
 
-// collectLogs collects system logs and sends to channel (stub for Windows)
+// collectLogs polls the System and Application event logs and sends the
+// new events to channel
 func collectLogs(logChan chan LogEntry) {
-	// TODO: Implement Windows Event Log collection
-	// For now, send a placeholder log entry every 30 seconds
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
+	since := time.Now().UnixMilli()
 	for range ticker.C {
-		logChan <- LogEntry{
-			Timestamp: time.Now().Unix(),
-			Level:     "INFO",
-			Message:   "Windows Event Log collection not yet implemented",
+		events, err := queryEventLog(context.Background(), "", nil, since, 0, eventLogBatch)
+		if err != nil {
+			slog.Error("Failed to read the event logs", "error", err)
+			continue
+		}
+		// Events come newest first
+		for i := len(events) - 1; i >= 0; i-- {
+			entry := winEventEntry(events[i])
+			logChan <- LogEntry{
+				Timestamp: entry.Timestamp,
+				Level:     entry.Level,
+				Message:   entry.Message,
+			}
+		}
+		if len(events) > 0 {
+			since = events[0].Time + 1
 		}
 	}
 }
 
-// getNetworkBytes returns total network RX and TX bytes across all interfaces (stub for Windows)
+// getNetworkBytes returns total network RX and TX bytes across all interfaces
 func getNetworkBytes() (uint64, uint64) {
-	// TODO: Implement Windows-specific network byte counting using GetIfTable
-	return 0, 0
+	stats, err := net.IOCounters(false)
+	if err != nil || len(stats) == 0 {
+		return 0, 0
+	}
+	return stats[0].BytesRecv, stats[0].BytesSent
 }
@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/toolchain"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VerifyToolchain checks the tools of the requested modules on this host
+func (s *agentServer) VerifyToolchain(ctx context.Context, in *pb.VerifyToolchainRequest) (*pb.VerifyToolchainResponse, error) {
+	modules, err := toolchain.Check(ctx, in.Modules)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &pb.VerifyToolchainResponse{
+		Os:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		CheckTimestamp: time.Now().Unix(),
+	}
+	for _, m := range modules {
+		capability := &pb.ModuleCapability{Module: m.Module, Available: m.Available}
+		for _, t := range m.Tools {
+			capability.Tools = append(capability.Tools, &pb.ToolStatus{
+				Name:       t.Name,
+				Path:       t.Path,
+				Version:    t.Version,
+				Functional: t.Functional,
+				Error:      t.Error,
+			})
+		}
+		resp.Modules = append(resp.Modules, capability)
+	}
+	return resp, nil
+}
+
+// NewVerifyCommand creates the agent verify command
+func NewVerifyCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify <agent_name>",
+		Short: "Check that the tools modules need work on an agent",
+		Long: `Checks on the agent that the tools modules drive are installed and work:
+package managers for pkg, systemctl for systemd, docker and its daemon,
+git, kubectl, terraform and so on, and reports their versions.
+
+With --file, only the modules the workflow uses are checked and the command
+fails when one of them can't run on the agent, so workflows can be
+validated before they are delegated to it.`,
+		Example: `  sloth-runner agent verify web-01
+  sloth-runner agent verify web-01 --file deploy.sloth
+  sloth-runner agent verify web-01 --module docker --module git -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, _ := cmd.Flags().GetString("file")
+			modules, _ := cmd.Flags().GetStringArray("module")
+			outputFormat, _ := cmd.Flags().GetString("output")
+
+			opts := VerifyOptions{
+				AgentName:    args[0],
+				Modules:      modules,
+				OutputFormat: outputFormat,
+				Writer:       cmd.OutOrStdout(),
+			}
+			if file != "" {
+				script, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read workflow: %w", err)
+				}
+				opts.Modules = append(opts.Modules, toolchain.ModulesUsed(string(script))...)
+				if len(opts.Modules) == 0 {
+					pterm.Info.Printf("%s uses none of the modules that need external tools\n", file)
+					return nil
+				}
+			}
+			opts.Required = len(opts.Modules) > 0
+
+			return verifyAgent(opts, getMasterAddress(cmd))
+		},
+	}
+
+	addMasterFlag(cmd)
+	cmd.Flags().StringP("file", "f", "", "Check only the modules this workflow uses, failing when one is unavailable")
+	cmd.Flags().StringArray("module", nil, "Check only this module, failing when it is unavailable (can be used multiple times)")
+	cmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+
+	return cmd
+}
+
+// VerifyOptions contains options for verifying an agent's toolchain
+type VerifyOptions struct {
+	AgentName string
+	Modules   []string
+	// Required fails the verification when a module is unavailable
+	Required     bool
+	OutputFormat string
+	Writer       io.Writer
+}
+
+func verifyAgent(opts VerifyOptions, masterAddr string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	factory := NewDefaultConnectionFactory()
+	registryClient, cleanup, err := factory.CreateRegistryClient(masterAddr)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return verifyAgentWithClients(ctx, registryClient, factory.CreateAgentClient, opts)
+}
+
+// verifyAgentWithClients verifies an agent using injected clients (testable)
+func verifyAgentWithClients(
+	ctx context.Context,
+	registryClient AgentRegistryClient,
+	agentClientFactory func(string) (AgentClient, func(), error),
+	opts VerifyOptions,
+) error {
+	agentAddress, err := findAgentAddress(ctx, registryClient, opts.AgentName)
+	if err != nil {
+		return err
+	}
+	agentClient, cleanup, err := agentClientFactory(agentAddress)
+	if err != nil {
+		return fmt.Errorf("failed to connect to agent at %s: %w", agentAddress, err)
+	}
+	defer cleanup()
+
+	resp, err := agentClient.VerifyToolchain(ctx, &pb.VerifyToolchainRequest{Modules: dedupeModules(opts.Modules)})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return fmt.Errorf("agent %s is too old to verify its toolchain, update it with 'sloth-runner agent update %s'", opts.AgentName, opts.AgentName)
+		}
+		return fmt.Errorf("failed to verify agent %s: %w", opts.AgentName, err)
+	}
+
+	if opts.OutputFormat == "json" {
+		encoder := json.NewEncoder(opts.Writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	} else {
+		formatVerifyResults(opts.AgentName, resp, opts.Writer)
+	}
+
+	if !opts.Required {
+		return nil
+	}
+	var unavailable []string
+	for _, m := range resp.Modules {
+		if !m.Available {
+			unavailable = append(unavailable, m.Module)
+		}
+	}
+	if len(unavailable) > 0 {
+		return fmt.Errorf("modules unavailable on agent %s: %s", opts.AgentName, strings.Join(unavailable, ", "))
+	}
+	return nil
+}
+
+// formatVerifyResults displays the capability report of an agent (testable)
+func formatVerifyResults(agentName string, resp *pb.VerifyToolchainResponse, w io.Writer) {
+	fmt.Fprintf(w, "Toolchain of agent %s (%s/%s)\n\n", agentName, resp.Os, resp.Arch)
+	for _, m := range resp.Modules {
+		mark := pterm.Green("✓")
+		if !m.Available {
+			mark = pterm.Red("✗")
+		}
+		fmt.Fprintf(w, "%s %s\n", mark, pterm.Cyan(m.Module))
+		for _, t := range m.Tools {
+			switch {
+			case t.Functional:
+				fmt.Fprintf(w, "    %s %s (%s)\n", t.Name, t.Version, t.Path)
+			case t.Path == "":
+				fmt.Fprintf(w, "    %s: %s\n", t.Name, pterm.Gray(t.Error))
+			default:
+				fmt.Fprintf(w, "    %s %s: %s\n", t.Name, t.Version, pterm.Yellow(t.Error))
+			}
+		}
+	}
+}
+
+func dedupeModules(modules []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, m := range modules {
+		if !seen[m] {
+			seen[m] = true
+			out = append(out, m)
+		}
+	}
+	return out
+}
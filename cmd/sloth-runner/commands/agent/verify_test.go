@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/agent/mocks"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func newVerifyMocks(report *pb.VerifyToolchainResponse) (*mocks.MockAgentRegistryClient, func(string) (AgentClient, func(), error), *[]string) {
+	regClient := mocks.NewMockAgentRegistryClient()
+	regClient.ListAgentsFunc = func(ctx context.Context, in *pb.ListAgentsRequest, opts ...grpc.CallOption) (*pb.ListAgentsResponse, error) {
+		return &pb.ListAgentsResponse{Agents: []*pb.AgentInfo{{AgentName: "web-01", AgentAddress: "10.0.0.21:50051"}}}, nil
+	}
+	var requested []string
+	agentClient := mocks.NewMockAgentClient()
+	agentClient.VerifyToolchainFunc = func(ctx context.Context, in *pb.VerifyToolchainRequest, opts ...grpc.CallOption) (*pb.VerifyToolchainResponse, error) {
+		requested = in.Modules
+		return report, nil
+	}
+	factory := func(string) (AgentClient, func(), error) { return agentClient, func() {}, nil }
+	return regClient, factory, &requested
+}
+
+func TestVerifyAgentWithClients(t *testing.T) {
+	report := &pb.VerifyToolchainResponse{
+		Os: "linux", Arch: "amd64",
+		Modules: []*pb.ModuleCapability{
+			{Module: "git", Available: true, Tools: []*pb.ToolStatus{{Name: "git", Path: "/usr/bin/git", Version: "2.43.0", Functional: true}}},
+			{Module: "docker", Tools: []*pb.ToolStatus{{Name: "docker", Path: "/usr/bin/docker", Version: "27.1.1", Error: "docker daemon unreachable"}}},
+		},
+	}
+
+	t.Run("report only", func(t *testing.T) {
+		regClient, factory, requested := newVerifyMocks(report)
+		var out bytes.Buffer
+		err := verifyAgentWithClients(context.Background(), regClient, factory, VerifyOptions{AgentName: "web-01", Writer: &out})
+		require.NoError(t, err, "unavailable modules only fail required checks")
+		assert.Empty(t, *requested)
+		assert.Contains(t, out.String(), "git 2.43.0 (/usr/bin/git)")
+		assert.Contains(t, out.String(), "docker daemon unreachable")
+	})
+
+	t.Run("required modules", func(t *testing.T) {
+		regClient, factory, requested := newVerifyMocks(report)
+		opts := VerifyOptions{AgentName: "web-01", Modules: []string{"git", "docker", "git"}, Required: true, OutputFormat: "json", Writer: &bytes.Buffer{}}
+		err := verifyAgentWithClients(context.Background(), regClient, factory, opts)
+		assert.EqualError(t, err, "modules unavailable on agent web-01: docker")
+		assert.Equal(t, []string{"git", "docker"}, *requested)
+	})
+
+	t.Run("unknown agent", func(t *testing.T) {
+		regClient, factory, _ := newVerifyMocks(report)
+		err := verifyAgentWithClients(context.Background(), regClient, factory, VerifyOptions{AgentName: "db-01", Writer: &bytes.Buffer{}})
+		assert.Error(t, err)
+	})
+}
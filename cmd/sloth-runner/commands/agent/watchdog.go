@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startWatchdog notifies systemd while the agent serves: the health check
+// calls the agent's own gRPC health service, so a hung server stops the
+// watchdog pings and systemd restarts the agent. It does nothing unless
+// the agent runs as a Type=notify unit.
+func startWatchdog(server *agentServer, addr net.Addr) (stop func(), err error) {
+	conn, err := grpc.NewClient(loopbackAddress(addr), mtls.DialOption())
+	if err != nil {
+		return nil, err
+	}
+	health := healthpb.NewHealthClient(conn)
+
+	check := func(ctx context.Context) error {
+		resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return fmt.Errorf("grpc server not responding: %w", err)
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("grpc server %s", strings.ToLower(resp.GetStatus().String()))
+		}
+		return nil
+	}
+	status := func() string {
+		return agentStatusLine(server, addr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agentInternal.NewWatchdog(check, status).Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+		conn.Close()
+	}, nil
+}
+
+// agentStatusLine is the STATUS= line shown by 'systemctl status'
+func agentStatusLine(server *agentServer, addr net.Addr) string {
+	parts := []string{
+		fmt.Sprintf("serving on %s", addr),
+		fmt.Sprintf("%d task requests running", server.running.Load()),
+	}
+	if worker, ok := server.eventWorker.(*agentInternal.EventWorker); ok && worker != nil {
+		queued, dropped := worker.Pending()
+		queue := fmt.Sprintf("event queue %d", queued)
+		if dropped > 0 {
+			queue += fmt.Sprintf(" (%d dropped)", dropped)
+		}
+		parts = append(parts, queue)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// loopbackAddress is where the agent reaches its own listener: wildcard
+// binds are reached through the loopback interface
+func loopbackAddress(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return addr.String()
+	}
+	if tcp.IP == nil || tcp.IP.IsUnspecified() {
+		return net.JoinHostPort("127.0.0.1", fmt.Sprint(tcp.Port))
+	}
+	return tcp.String()
+}
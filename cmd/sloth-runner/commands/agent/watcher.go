@@ -11,11 +11,12 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // NewWatcherCommand creates the parent watcher command
@@ -232,9 +233,13 @@ func NewWatcherDeleteCommand(ctx *commands.AppContext) *cobra.Command {
 
 			// Confirm deletion if not forced
 			if !force {
-				result, _ := pterm.DefaultInteractiveConfirm.Show(
+				result, err := taskrunner.Confirm(
 					fmt.Sprintf("Are you sure you want to delete watcher '%s' on agent '%s'?", watcherID, agentName),
+					"--force",
 				)
+				if err != nil {
+					return err
+				}
 				if !result {
 					pterm.Info.Println("Deletion cancelled")
 					return nil
@@ -291,6 +296,7 @@ func NewWatcherCreateCommand(ctx *commands.AppContext) *cobra.Command {
 		interval      string
 		conditions    []string
 		checkHash     bool
+		group         string
 	)
 
 	cmd := &cobra.Command{
@@ -307,7 +313,10 @@ func NewWatcherCreateCommand(ctx *commands.AppContext) *cobra.Command {
   sloth-runner agent watcher create my-agent --type memory --threshold 75 --when above
 
   # Create a process watcher
-  sloth-runner agent watcher create my-agent --type process --process nginx --when created,deleted`,
+  sloth-runner agent watcher create my-agent --type process --process nginx --when created,deleted
+
+  # Create a file watcher in the "site-content" group
+  sloth-runner agent watcher create my-agent --type file --path /srv/www/index.html --when changed --group site-content`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
@@ -344,6 +353,7 @@ func NewWatcherCreateCommand(ctx *commands.AppContext) *cobra.Command {
 				Type:       agentInternal.WatcherType(watcherType),
 				Interval:   intervalDuration,
 				Conditions: parseConditions(conditions),
+				Group:      group,
 			}
 
 			// Type-specific configuration
@@ -430,6 +440,7 @@ func NewWatcherCreateCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd.Flags().StringVarP(&interval, "interval", "i", "5s", "Check interval (e.g., 5s, 1m, 10m)")
 	cmd.Flags().StringSliceVarP(&conditions, "when", "w", []string{}, "Conditions to trigger (comma-separated: created,changed,deleted,above,below)")
 	cmd.Flags().BoolVar(&checkHash, "check-hash", false, "Check file hash for changes (file watchers only)")
+	cmd.Flags().StringVarP(&group, "group", "g", "", "Watcher group, for event triggers that target several watchers")
 
 	cmd.MarkFlagRequired("type")
 	cmd.MarkFlagRequired("when")
@@ -446,15 +457,20 @@ func displayWatcherTable(watchers []*pb.WatcherConfig) {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tTYPE\tCONDITIONS\tINTERVAL\tTARGET")
-	fmt.Fprintln(w, "--\t----\t----------\t--------\t------")
+	fmt.Fprintln(w, "ID\tTYPE\tGROUP\tCONDITIONS\tINTERVAL\tTARGET")
+	fmt.Fprintln(w, "--\t----\t-----\t----------\t--------\t------")
 
 	for _, watcher := range watchers {
 		target := getWatcherTarget(watcher)
 		conditions := strings.Join(watcher.Conditions, ",")
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+		group := watcher.Group
+		if group == "" {
+			group = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 			truncateString(watcher.Id, 12),
 			watcher.Type,
+			group,
 			conditions,
 			watcher.Interval,
 			truncateString(target, 40),
@@ -477,6 +493,9 @@ func displayWatcherDetails(watcher *pb.WatcherConfig) {
 		{"Interval", watcher.Interval},
 		{"Target", getWatcherTarget(watcher)},
 	}
+	if watcher.Group != "" {
+		data = append(data, []string{"Group", watcher.Group})
+	}
 
 	// Add type-specific fields
 	if watcher.FilePath != "" {
@@ -539,6 +558,7 @@ func watcherConfigToProto(config *agentInternal.WatcherConfig) *pb.RegisterWatch
 		Type:       string(config.Type),
 		Conditions: conditions,
 		Interval:   config.Interval.String(),
+		Group:      config.Group,
 
 		// Type-specific fields
 		FilePath:    config.FilePath,
@@ -569,7 +589,7 @@ func resolveAgentAddress(agentName string) (string, error) {
 	}
 
 	// Connect to master
-	conn, err := grpc.Dial(masterAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(masterAddr, mtls.DialOption())
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to master: %w", err)
 	}
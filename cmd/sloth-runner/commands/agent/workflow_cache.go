@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/spf13/cobra"
+)
+
+// workflowCacheOptions configures reuse of parsed workflows and Lua states
+// across delegated tasks
+type workflowCacheOptions struct {
+	Enabled bool          `yaml:"enabled"`
+	Size    int           `yaml:"size"`
+	TTL     time.Duration `yaml:"ttl"`
+}
+
+func addWorkflowCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("workflow-cache", true, "Reuse parsed workflows and prepared Lua states across delegated tasks")
+	cmd.Flags().Int("workflow-cache-size", 16, "Number of distinct workflows kept parsed")
+	cmd.Flags().Duration("workflow-cache-ttl", 30*time.Minute, "Drop parsed workflows unused for this long")
+}
+
+func getWorkflowCacheOptions(cmd *cobra.Command) workflowCacheOptions {
+	opts := workflowCacheOptions{}
+	opts.Enabled, _ = cmd.Flags().GetBool("workflow-cache")
+	opts.Size, _ = cmd.Flags().GetInt("workflow-cache-size")
+	opts.TTL, _ = cmd.Flags().GetDuration("workflow-cache-ttl")
+	return opts
+}
+
+// daemonArgs returns the flags needed to forward the options to a daemon process
+func (o workflowCacheOptions) daemonArgs() []string {
+	if !o.Enabled {
+		return []string{"--workflow-cache=false"}
+	}
+	return []string{"--workflow-cache-size", strconv.Itoa(o.Size), "--workflow-cache-ttl", o.TTL.String()}
+}
+
+// apply sets up the caches on the agent server, replacing those it had
+func (o workflowCacheOptions) apply(s *agentServer) {
+	s.cachesMu.Lock()
+	defer s.cachesMu.Unlock()
+	if !o.Enabled || o.Size <= 0 {
+		s.parseCache, s.statePool = nil, nil
+		return
+	}
+	s.parseCache = luainterface.NewParseCache(o.Size, o.TTL)
+	s.statePool = luainterface.NewStatePool(o.Size)
+}
+
+// workflowCaches returns the caches of the agent server, nil when disabled
+func (s *agentServer) workflowCaches() (*luainterface.ParseCache, *luainterface.StatePool) {
+	s.cachesMu.RLock()
+	defer s.cachesMu.RUnlock()
+	return s.parseCache, s.statePool
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/blobstore"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// blobStoreOptions configures the artifact blob store served by the master
+type blobStoreOptions struct {
+	Enabled  bool
+	Port     int
+	Backend  string // file or s3
+	Dir      string
+	S3       blobstore.S3Config
+	TLS      blobstore.TLSFiles
+	Insecure bool
+}
+
+func addBlobStoreFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("blob-store", false, "Serve a blob store agents use to exchange artifacts")
+	cmd.Flags().Int("blob-port", 50063, "Port for the blob store")
+	cmd.Flags().String("blob-backend", "file", "Blob storage backend: file or s3")
+	cmd.Flags().String("blob-dir", "", "Directory for the file backend (default: <data-dir>/blobs)")
+	cmd.Flags().String("blob-s3-endpoint", "", "S3 endpoint URL for the s3 backend")
+	cmd.Flags().String("blob-s3-bucket", "", "S3 bucket for the s3 backend")
+	cmd.Flags().String("blob-s3-region", "us-east-1", "S3 region for the s3 backend")
+	cmd.Flags().String("blob-s3-prefix", "", "Key prefix for blobs in the S3 bucket")
+	cmd.Flags().String("blob-tls-cert", "", "Server certificate for the blob store")
+	cmd.Flags().String("blob-tls-key", "", "Server key for the blob store")
+	cmd.Flags().String("blob-tls-ca", "", "CA that signs agent client certificates")
+	cmd.Flags().Bool("blob-insecure", false, "Serve the blob store over plain HTTP without client certificates")
+}
+
+func getBlobStoreOptions(cmd *cobra.Command) blobStoreOptions {
+	opts := blobStoreOptions{}
+	opts.Enabled, _ = cmd.Flags().GetBool("blob-store")
+	opts.Port, _ = cmd.Flags().GetInt("blob-port")
+	opts.Backend, _ = cmd.Flags().GetString("blob-backend")
+	opts.Dir, _ = cmd.Flags().GetString("blob-dir")
+	opts.S3.Endpoint, _ = cmd.Flags().GetString("blob-s3-endpoint")
+	opts.S3.Bucket, _ = cmd.Flags().GetString("blob-s3-bucket")
+	opts.S3.Region, _ = cmd.Flags().GetString("blob-s3-region")
+	opts.S3.Prefix, _ = cmd.Flags().GetString("blob-s3-prefix")
+	opts.TLS.CertFile, _ = cmd.Flags().GetString("blob-tls-cert")
+	opts.TLS.KeyFile, _ = cmd.Flags().GetString("blob-tls-key")
+	opts.TLS.CAFile, _ = cmd.Flags().GetString("blob-tls-ca")
+	opts.Insecure, _ = cmd.Flags().GetBool("blob-insecure")
+	return opts
+}
+
+// startBlobStore starts the blob store server in the background
+func startBlobStore(opts blobStoreOptions) error {
+	dir := opts.Dir
+	if dir == "" {
+		dir = config.GetBlobDir()
+	}
+
+	var store blobstore.Store
+	var err error
+	switch opts.Backend {
+	case "file", "":
+		store, err = blobstore.NewFileStore(dir)
+	case "s3":
+		store, err = blobstore.NewS3Store(opts.S3)
+	default:
+		err = fmt.Errorf("unknown blob backend: %s", opts.Backend)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Uploads are buffered on local disk while their digest is computed
+	tmpDir := filepath.Join(dir, "uploads")
+	if err := os.MkdirAll(tmpDir, 0700); err != nil {
+		return fmt.Errorf("failed to create blob upload directory: %w", err)
+	}
+
+	server := &http.Server{Handler: blobstore.NewServer(store, tmpDir).Handler()}
+	if !opts.Insecure {
+		tlsConfig, err := blobstore.ServerTLSConfig(opts.TLS)
+		if err != nil {
+			return fmt.Errorf("blob store: %w (or pass --blob-insecure)", err)
+		}
+		server.TLSConfig = tlsConfig
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for blob store: %w", err)
+	}
+	go func() {
+		var err error
+		if opts.Insecure {
+			err = server.Serve(lis)
+		} else {
+			err = server.ServeTLS(lis, "", "")
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Blob store stopped", "error", err)
+		}
+	}()
+
+	if opts.Insecure {
+		pterm.Warning.Printf("Blob store listening on :%d without TLS\n", opts.Port)
+	} else {
+		pterm.Success.Printf("Blob store listening on :%d (mTLS, backend: %s)\n", opts.Port, opts.Backend)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package cert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewCertCommand creates the cert parent command
+func NewCertCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cert",
+		Short: "Manage mTLS certificates of masters and agents",
+		Long: `Manage the CA and certificates that secure the gRPC channels between the
+CLI, masters and agents with mutual TLS. Each node gets a certificate
+signed by the CA, which it uses both to serve and to connect, and only
+accepts peers presenting one.
+
+Start masters and agents with --tls-cert, --tls-key and --tls-ca, and set
+the same flags or the SLOTH_RUNNER_TLS_CERT, SLOTH_RUNNER_TLS_KEY and
+SLOTH_RUNNER_TLS_CA environment variables for other commands.
+
+Certificates are kept in <data-dir>/tls by default. Keep ca.key on the
+machine issuing certificates only.`,
+	}
+
+	cmd.AddCommand(newInitCmd())
+	cmd.AddCommand(newIssueCmd())
+	cmd.AddCommand(newRotateCmd())
+	cmd.AddCommand(newListCmd())
+
+	return cmd
+}
+
+func newInitCmd() *cobra.Command {
+	var dir, validity string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the CA",
+		Example: `  sloth-runner cert init
+  sloth-runner cert init --dir /etc/sloth-runner/tls --validity 87600h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ttl, err := time.ParseDuration(validity)
+			if err != nil {
+				return fmt.Errorf("invalid --validity: %w", err)
+			}
+			caFile := filepath.Join(dir, mtls.CACertFile)
+			if _, err := os.Stat(caFile); err == nil && !force {
+				return fmt.Errorf("%s already exists; pass --force to replace it, which invalidates every certificate it signed", caFile)
+			}
+			if err := mtls.NewCA(dir, "sloth-runner CA", ttl); err != nil {
+				return err
+			}
+			pterm.Success.Printf("CA created: %s\n", caFile)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", config.GetTLSDir(), "Certificate directory")
+	cmd.Flags().StringVar(&validity, "validity", "87600h", "How long the CA is valid")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing CA")
+
+	return cmd
+}
+
+func newIssueCmd() *cobra.Command {
+	var dir, validity string
+	var hosts []string
+
+	cmd := &cobra.Command{
+		Use:   "issue <name>",
+		Short: "Issue a certificate for a master, an agent or the CLI",
+		Long: `Issue a certificate and key signed by the CA. --host lists the host names
+and IP addresses other nodes connect to, e.g. the address an agent reports
+to the master; localhost and 127.0.0.1 are always included.`,
+		Example: `  sloth-runner cert issue master --host master.example.com --host 10.0.0.10
+  sloth-runner cert issue web-01 --host 10.0.0.21
+  sloth-runner cert issue cli`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ttl, err := time.ParseDuration(validity)
+			if err != nil {
+				return fmt.Errorf("invalid --validity: %w", err)
+			}
+			return issue(dir, args[0], append(hosts, "localhost", "127.0.0.1"), ttl)
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", config.GetTLSDir(), "Certificate directory")
+	cmd.Flags().StringArrayVar(&hosts, "host", nil, "Host name or IP address the certificate is valid for (can be used multiple times)")
+	cmd.Flags().StringVar(&validity, "validity", "8760h", "How long the certificate is valid")
+
+	return cmd
+}
+
+func newRotateCmd() *cobra.Command {
+	var dir, validity string
+
+	cmd := &cobra.Command{
+		Use:   "rotate <name>",
+		Short: "Replace a certificate with a new key, keeping its hosts",
+		Long: `Issue a new key and certificate for the same hosts as the current one.
+Masters and agents read their certificate again when the files change, so
+copying the new files over the old ones rotates it without a restart.`,
+		Example: `  sloth-runner cert rotate web-01
+  scp ~/.sloth-runner/tls/web-01.{crt,key} web-01:/etc/sloth-runner/tls/`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ttl, err := time.ParseDuration(validity)
+			if err != nil {
+				return fmt.Errorf("invalid --validity: %w", err)
+			}
+			current, err := mtls.ReadCertificate(mtls.CertFile(dir, args[0]))
+			if err != nil {
+				return fmt.Errorf("failed to read the certificate of %s: %w", args[0], err)
+			}
+			return issue(dir, args[0], mtls.Hosts(current), ttl)
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", config.GetTLSDir(), "Certificate directory")
+	cmd.Flags().StringVar(&validity, "validity", "8760h", "How long the new certificate is valid")
+
+	return cmd
+}
+
+func issue(dir, name string, hosts []string, validity time.Duration) error {
+	if err := mtls.Issue(dir, name, dedupe(hosts), validity); err != nil {
+		return err
+	}
+	pterm.Success.Printf("Certificate issued: %s\n", mtls.CertFile(dir, name))
+	fmt.Printf("  --tls-cert %s --tls-key %s --tls-ca %s\n",
+		mtls.CertFile(dir, name), mtls.KeyFile(dir, name), filepath.Join(dir, mtls.CACertFile))
+	return nil
+}
+
+func newListCmd() *cobra.Command {
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List certificates and when they expire",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := filepath.Glob(filepath.Join(dir, "*.crt"))
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				fmt.Printf("No certificates in %s\n", dir)
+				return nil
+			}
+			sort.Strings(files)
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tEXPIRES\tHOSTS")
+			for _, file := range files {
+				c, err := mtls.ReadCertificate(file)
+				if err != nil {
+					return err
+				}
+				expires := c.NotAfter.Format("2006-01-02")
+				if time.Until(c.NotAfter) < 30*24*time.Hour {
+					expires += " (soon)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", strings.TrimSuffix(filepath.Base(file), ".crt"), expires, strings.Join(mtls.Hosts(c), ", "))
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", config.GetTLSDir(), "Certificate directory")
+
+	return cmd
+}
+
+func dedupe(items []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, item := range items {
+		if item != "" && !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/backup"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewBackupCommand creates the db backup command
+func NewBackupCommand(ctx *commands.AppContext) *cobra.Command {
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up all sloth-runner databases to one archive",
+		Long: `Back up every database in the data directory (agents, stacks, runs,
+events, secrets, ...) to a single zstd-compressed archive.
+
+Each database is snapshotted consistently, so the master can keep running
+while the backup is taken. Restore the archive with 'sloth-runner db restore'.
+
+Secrets stay encrypted with their stack passwords, which are not part of
+the backup: keep the passwords somewhere other than the backup, or the
+restored secrets can't be decrypted.
+
+Example:
+  sloth-runner db backup --out backup.tar.zst`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if out == "" {
+				out = fmt.Sprintf("sloth-runner-backup-%s.tar.zst", time.Now().Format("20060102-150405"))
+			}
+
+			// Write to a temporary file so a failed backup never leaves a
+			// truncated archive behind
+			tmp := out + ".tmp"
+			f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", out, err)
+			}
+
+			manifest, err := backup.Create(config.GetDataDir(), f, ctx.Version)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(tmp)
+				return err
+			}
+			if err := os.Rename(tmp, out); err != nil {
+				os.Remove(tmp)
+				return fmt.Errorf("failed to write %s: %w", out, err)
+			}
+
+			displayManifest(manifest)
+			pterm.Success.Printf("Backup written to %s\n", out)
+			if manifest.HasDatabase("secrets.db") || manifest.HasDatabase("ssh_profiles.db") {
+				pterm.Warning.Println("The backup contains encrypted secrets and SSH profiles. Store it with restricted access;")
+				pterm.Warning.Println("stack passwords are not included and are needed to decrypt restored secrets.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&out, "out", "", "Archive to write (default: sloth-runner-backup-<timestamp>.tar.zst)")
+
+	return cmd
+}
+
+// NewRestoreCommand creates the db restore command
+func NewRestoreCommand(ctx *commands.AppContext) *cobra.Command {
+	var (
+		force         bool
+		ignoreVersion bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore <backup-file>",
+		Short: "Restore sloth-runner databases from a backup",
+		Long: `Restore the databases in the data directory from an archive created by
+'sloth-runner db backup'.
+
+The archive is verified before anything is replaced. The current databases
+are moved to a pre-restore-<timestamp> directory inside the data directory.
+Stop the master and any local agents before restoring.
+
+Backups made by a newer sloth-runner are refused, since their databases
+may use a schema this version doesn't know; pass --ignore-version to
+restore them anyway.
+
+Example:
+  sloth-runner db restore backup.tar.zst`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			dataDir := config.GetDataDir()
+
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open backup: %w", err)
+			}
+			manifest, err := backup.ReadManifest(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			if err := backup.CheckCompatibility(manifest, ctx.Version, ignoreVersion); err != nil {
+				return err
+			}
+
+			displayManifest(manifest)
+
+			if !force {
+				result, err := taskrunner.Confirm(fmt.Sprintf("Replace the databases in %s with this backup?", dataDir), "--force")
+				if err != nil {
+					return err
+				}
+				if !result {
+					pterm.Info.Println("Operation cancelled")
+					return nil
+				}
+			}
+
+			f, err = os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open backup: %w", err)
+			}
+			defer f.Close()
+
+			result, err := backup.Restore(f, dataDir, backup.RestoreOptions{
+				RunnerVersion: ctx.Version,
+				IgnoreVersion: ignoreVersion,
+			})
+			if err != nil {
+				return err
+			}
+
+			pterm.Success.Printf("Restored %d databases to %s\n", len(result.Manifest.Databases), dataDir)
+			if result.PreviousDir != "" {
+				pterm.Info.Printf("Previous databases moved to %s\n", result.PreviousDir)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation")
+	cmd.Flags().BoolVar(&ignoreVersion, "ignore-version", false, "Restore a backup made by a newer sloth-runner")
+
+	return cmd
+}
+
+// displayManifest renders the contents of a backup
+func displayManifest(m *backup.Manifest) {
+	pterm.DefaultSection.Printf("Backup from %s (sloth-runner %s)", m.CreatedAt.Local().Format("2006-01-02 15:04:05"), m.RunnerVersion)
+
+	tableData := [][]string{{"Database", "Size"}}
+	for _, db := range m.Databases {
+		tableData = append(tableData, []string{db.Name, formatSize(db.Size)})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	pterm.Info.Printf("Total: %s\n", formatSize(m.TotalSize()))
+}
@@ -12,7 +12,8 @@ func NewDBCommand(ctx *commands.AppContext) *cobra.Command {
 		Short: "Interact with sloth-runner databases",
 		Long: `The db command provides tools to query and inspect sloth-runner databases.
 
-You can query agents.db (agent information) or hooks.db (hooks and events).`,
+You can query agents.db (agent information) or hooks.db (hooks and events),
+and back up or restore all databases at once.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
@@ -23,6 +24,9 @@ You can query agents.db (agent information) or hooks.db (hooks and events).`,
 		NewQueryCommand(ctx),
 		NewTablesCommand(ctx),
 		NewSchemaCommand(ctx),
+		NewUsageCommand(ctx),
+		NewBackupCommand(ctx),
+		NewRestoreCommand(ctx),
 	)
 
 	return cmd
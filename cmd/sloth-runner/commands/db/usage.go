@@ -0,0 +1,239 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/diskquota"
+	"github.com/chalkan3-sloth/sloth-runner/internal/runlog"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// DatabaseFile describes the on-disk size of one database
+type DatabaseFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// UsageReport is the output of the db usage command
+type UsageReport struct {
+	DataDir   string         `json:"data_dir"`
+	Databases []DatabaseFile `json:"databases"`
+	RunLogs   *runlog.Usage  `json:"run_logs"`
+	// Quotas are set by the disk section of config.yaml
+	Quotas []diskquota.Status `json:"quotas,omitempty"`
+}
+
+// NewUsageCommand creates the db usage command
+func NewUsageCommand(ctx *commands.AppContext) *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show disk usage of sloth-runner databases and run logs",
+		Long: `Show how much space each database in the data directory takes and
+break down run log storage per stack.
+
+Run logs are stored chunked, zstd-compressed and deduplicated, so the
+stored size is usually far below the raw size of the output.
+
+When the disk section of config.yaml sets quotas, their usage is shown
+as well.
+
+Example:
+  sloth-runner db usage
+  sloth-runner db usage -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := collectUsage(config.GetDataDir(), config.GetRunLogDBPath())
+			if err != nil {
+				return err
+			}
+			if report.Quotas, err = collectQuotas(config.GetConfigPath(), config.GetDataDir()); err != nil {
+				return err
+			}
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal usage: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			case "table":
+				displayUsage(report)
+				return nil
+			default:
+				return fmt.Errorf("unknown format: %s", outputFormat)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table or json")
+
+	return cmd
+}
+
+// collectUsage gathers database file sizes and run log accounting
+func collectUsage(dataDir, runLogDBPath string) (*UsageReport, error) {
+	report := &UsageReport{DataDir: dataDir}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".db") {
+			continue
+		}
+		path := filepath.Join(dataDir, entry.Name())
+		report.Databases = append(report.Databases, DatabaseFile{
+			Name: strings.TrimSuffix(entry.Name(), ".db"),
+			Path: path,
+			Size: databaseSize(path),
+		})
+	}
+	sort.Slice(report.Databases, func(i, j int) bool {
+		return report.Databases[i].Size > report.Databases[j].Size
+	})
+
+	if _, err := os.Stat(runLogDBPath); err == nil {
+		store, err := runlog.NewStore(runLogDBPath)
+		if err != nil {
+			return nil, err
+		}
+		defer store.Close()
+
+		usage, err := store.Usage()
+		if err != nil {
+			return nil, err
+		}
+		report.RunLogs = usage
+	}
+
+	return report, nil
+}
+
+// collectQuotas measures the data directory against the quotas of the
+// config file, if it sets any
+func collectQuotas(configPath, dataDir string) ([]diskquota.Status, error) {
+	cfg, err := diskquota.LoadConfig(configPath)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	usage, err := diskquota.Measure(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Evaluate(usage), nil
+}
+
+// databaseSize returns the size of a SQLite database including its WAL files
+func databaseSize(path string) int64 {
+	var size int64
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if info, err := os.Stat(path + suffix); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// displayUsage renders the usage report as tables
+func displayUsage(report *UsageReport) {
+	pterm.DefaultSection.Printf("Databases in %s", report.DataDir)
+
+	if len(report.Databases) == 0 {
+		pterm.Info.Println("No databases found")
+	} else {
+		var total int64
+		tableData := [][]string{{"Database", "Size"}}
+		for _, db := range report.Databases {
+			tableData = append(tableData, []string{db.Name, formatSize(db.Size)})
+			total += db.Size
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		pterm.Info.Printf("Total: %s\n", formatSize(total))
+	}
+
+	if len(report.Quotas) > 0 {
+		fmt.Println()
+		pterm.DefaultSection.Println("Quotas")
+		tableData := [][]string{{"Quota", "Used", "Limit", "Usage", "Level"}}
+		for _, q := range report.Quotas {
+			level := pterm.Green(q.Level)
+			switch q.Level {
+			case diskquota.LevelWarning:
+				level = pterm.Yellow(q.Level)
+			case diskquota.LevelExceeded:
+				level = pterm.Red(q.Level)
+			}
+			tableData = append(tableData, []string{
+				q.Name,
+				formatSize(q.Used),
+				formatSize(q.Quota),
+				fmt.Sprintf("%.0f%%", q.Percent()),
+				level,
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	}
+
+	fmt.Println()
+	pterm.DefaultSection.Println("Run logs by stack")
+
+	if report.RunLogs == nil || report.RunLogs.Logs == 0 {
+		pterm.Info.Println("No run logs stored")
+		return
+	}
+
+	tableData := [][]string{{"Stack", "Logs", "Raw Size", "Stored Size", "Ratio"}}
+	for _, su := range report.RunLogs.Stacks {
+		stack := su.Stack
+		if stack == "" {
+			stack = "(none)"
+		}
+		tableData = append(tableData, []string{
+			stack,
+			fmt.Sprintf("%d", su.Logs),
+			formatSize(su.Size),
+			formatSize(su.StoredSize),
+			formatRatio(su.Size, su.StoredSize),
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	u := report.RunLogs
+	pterm.Info.Printf("Total: %d logs, %s raw, %s stored in %d unique chunks (%s)\n",
+		u.Logs, formatSize(u.Size), formatSize(u.StoredSize), u.Chunks, formatRatio(u.Size, u.StoredSize))
+}
+
+// formatSize formats bytes to human-readable format
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatRatio formats the raw-to-stored size ratio
+func formatRatio(raw, stored int64) string {
+	if stored <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fx", float64(raw)/float64(stored))
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
@@ -189,7 +190,7 @@ func debugConnection(agentName string, timeout int, verbose bool) error {
 
 	start = time.Now()
 	grpcConn, err := grpc.Dial(address,
-		grpc.WithInsecure(),
+		mtls.DialOption(),
 		grpc.WithBlock(),
 		grpc.WithTimeout(time.Duration(timeout)*time.Second))
 	duration = time.Since(start)
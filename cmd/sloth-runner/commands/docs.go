@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/docs"
+	"github.com/spf13/cobra"
+)
+
+// NewDocsCommand creates the docs command
+func NewDocsCommand(ctx *AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Browse the documentation built into sloth-runner",
+		Long:  `Browse the documentation built into the sloth-runner binary, without internet access.`,
+	}
+
+	cmd.AddCommand(newDocsServeCommand(ctx))
+	return cmd
+}
+
+func newDocsServeCommand(ctx *AppContext) *cobra.Command {
+	var (
+		port int
+		bind string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the documentation as a local website",
+		Long: `Serves the documentation built into this binary as a searchable website:
+
+  - The module reference, as listed by 'sloth-runner modules list'
+  - The workflow DSL reference: workflows, tasks and their fields
+  - Example workflows
+  - The command docs
+
+Everything is generated from the binary, so air-gapped machines get the
+reference matching the installed version.`,
+		Example: `  # Serve the docs on http://localhost:8090
+  sloth-runner docs serve
+
+  # Serve them on another port, to other machines of the network
+  sloth-runner docs serve --port 9000 --bind 0.0.0.0`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDocsServer(bind, port)
+		},
+	}
+
+	cmd.Flags().IntVarP(&port, "port", "p", 8090, "Port for the docs server")
+	cmd.Flags().StringVar(&bind, "bind", "127.0.0.1", "Address to listen on")
+
+	return cmd
+}
+
+func runDocsServer(bind string, port int) error {
+	site, err := docs.NewSite()
+	if err != nil {
+		return fmt.Errorf("failed to build the docs: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(bind, fmt.Sprint(port)))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	server := &http.Server{Handler: site.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	errChan := make(chan error, 1)
+	go func() {
+		log.Printf("📚 Sloth Runner docs: http://%s", listener.Addr())
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			errChan <- err
+		}
+	}()
+
+	select {
+	case <-sigChan:
+	case err := <-errChan:
+		return fmt.Errorf("server error: %w", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/blobstore"
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// eventIngestOptions configures the endpoint external systems post events to
+type eventIngestOptions struct {
+	Enabled   bool
+	Port      int
+	TokenFile string
+	TLS       blobstore.TLSFiles
+	ClientCNs []string
+	Insecure  bool
+}
+
+func addEventIngestFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("events-http", false, "Serve POST /events so external systems can post events")
+	cmd.Flags().Int("events-port", 50064, "Port for the events endpoint")
+	cmd.Flags().String("events-tokens", "", "File of <source>:<token> lines accepted as bearer tokens")
+	cmd.Flags().String("events-tls-cert", "", "Server certificate for the events endpoint")
+	cmd.Flags().String("events-tls-key", "", "Server key for the events endpoint")
+	cmd.Flags().String("events-tls-ca", "", "CA that signs client certificates accepted by the events endpoint")
+	cmd.Flags().StringSlice("events-client-cn", nil, "Only accept client certificates with these common names")
+	cmd.Flags().Bool("events-insecure", false, "Serve the events endpoint over plain HTTP (tokens only)")
+}
+
+func getEventIngestOptions(cmd *cobra.Command) eventIngestOptions {
+	opts := eventIngestOptions{}
+	opts.Enabled, _ = cmd.Flags().GetBool("events-http")
+	opts.Port, _ = cmd.Flags().GetInt("events-port")
+	opts.TokenFile, _ = cmd.Flags().GetString("events-tokens")
+	opts.TLS.CertFile, _ = cmd.Flags().GetString("events-tls-cert")
+	opts.TLS.KeyFile, _ = cmd.Flags().GetString("events-tls-key")
+	opts.TLS.CAFile, _ = cmd.Flags().GetString("events-tls-ca")
+	opts.ClientCNs, _ = cmd.Flags().GetStringSlice("events-client-cn")
+	opts.Insecure, _ = cmd.Flags().GetBool("events-insecure")
+	return opts
+}
+
+// eventIngestAuthenticator builds the authenticators the options enable
+func eventIngestAuthenticator(opts eventIngestOptions) (hooks.Authenticator, error) {
+	var auths []hooks.Authenticator
+	if opts.TokenFile != "" {
+		auth, err := hooks.LoadTokenAuthenticator(opts.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, auth)
+	}
+	if opts.TLS.CAFile != "" && !opts.Insecure {
+		auths = append(auths, hooks.NewClientCertAuthenticator(opts.ClientCNs...))
+	}
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("events endpoint: set --events-tokens or --events-tls-ca to authenticate clients")
+	}
+	return hooks.AnyAuthenticator(auths...), nil
+}
+
+// dispatchIngestedEvent hands an event to the master's hook dispatcher.
+// A disabled dispatcher drops events, so they are refused instead and the
+// sender, told the endpoint is unavailable, can retry.
+func dispatchIngestedEvent(event *hooks.Event) error {
+	dispatcher := hooks.GetGlobalDispatcher()
+	if dispatcher == nil {
+		return fmt.Errorf("hook system is not initialized")
+	}
+	if !dispatcher.IsEnabled() {
+		return fmt.Errorf("hook dispatcher is disabled")
+	}
+	return dispatcher.Dispatch(event)
+}
+
+// startEventIngest starts the events endpoint in the background
+func startEventIngest(opts eventIngestOptions) error {
+	auth, err := eventIngestAuthenticator(opts)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: hooks.NewIngestServer(auth, dispatchIngestedEvent).Handler()}
+	if !opts.Insecure {
+		if opts.TLS.CertFile == "" || opts.TLS.KeyFile == "" {
+			return fmt.Errorf("events endpoint: TLS requires --events-tls-cert and --events-tls-key (or pass --events-insecure)")
+		}
+		tlsFiles := opts.TLS
+		if tlsFiles.CAFile != "" {
+			tlsConfig, err := blobstore.ServerTLSConfig(tlsFiles)
+			if err != nil {
+				return fmt.Errorf("events endpoint: %w", err)
+			}
+			server.TLSConfig = hooks.ClientCertTLSConfig(tlsConfig)
+		}
+		// Without a CA, the certificate and key are passed to ServeTLS
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for events endpoint: %w", err)
+	}
+	go func() {
+		var err error
+		switch {
+		case opts.Insecure:
+			err = server.Serve(lis)
+		case server.TLSConfig != nil:
+			err = server.ServeTLS(lis, "", "")
+		default:
+			err = server.ServeTLS(lis, opts.TLS.CertFile, opts.TLS.KeyFile)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Events endpoint stopped", "error", err)
+		}
+	}()
+
+	if opts.Insecure {
+		pterm.Warning.Printf("Events endpoint listening on :%d without TLS\n", opts.Port)
+	} else {
+		pterm.Success.Printf("Events endpoint listening on :%d (TLS)\n", opts.Port)
+	}
+	return nil
+}
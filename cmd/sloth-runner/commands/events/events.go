@@ -19,6 +19,8 @@ func NewEventsCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd.AddCommand(NewShowCommand(ctx))
 	cmd.AddCommand(NewDeleteCommand(ctx))
 	cmd.AddCommand(NewCleanupCommand(ctx))
+	cmd.AddCommand(NewExportCommand(ctx))
+	cmd.AddCommand(NewVerifyCommand(ctx))
 	cmd.AddCommand(NewDocsCommand(ctx))
 
 	return cmd
@@ -0,0 +1,213 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/auditlog"
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// Sources of the records of an audit export
+const (
+	sourceEvents   = "events"
+	sourceActivity = "stack_activity"
+)
+
+// exportBatch is how many entries are read from a source at a time
+const exportBatch = 500
+
+func NewExportCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <audit-log>",
+		Short: "Append events and stack activity to a hash-chained audit log",
+		Long: `Appends the events of the queue and the activity log of every stack
+(locks, overrides, rollbacks...) to an append-only audit log, one JSON record
+per line. Each record holds the hash of the record before it, so changing,
+removing or reordering records breaks the chain.
+
+An anchor record holding the head of the chain is written every
+--anchor-every records and at the end of each export, and copied to
+<audit-log>.anchors. Keep that file apart from the log, e.g. on write-once
+storage, so a log rewritten from scratch is detected too.
+
+Exports resume after the last entry already in the log, so running the
+command again, or with --follow, only appends what's new. Export before
+'events cleanup' removes old events.`,
+		Example: `  sloth-runner events export /var/log/sloth-runner/audit.log
+  sloth-runner events export audit.log --follow --interval 1m`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			anchorEvery, _ := cmd.Flags().GetInt("anchor-every")
+			follow, _ := cmd.Flags().GetBool("follow")
+			interval, _ := cmd.Flags().GetDuration("interval")
+
+			log, err := auditlog.Open(args[0], anchorEvery)
+			if err != nil {
+				return err
+			}
+			defer log.Close()
+
+			repo, err := hooks.NewRepository()
+			if err != nil {
+				return err
+			}
+			defer repo.Close()
+
+			activity, closeActivity, err := openActivitySource()
+			if err != nil {
+				pterm.Warning.Printf("Stack activity not exported: %v\n", err)
+			} else {
+				defer closeActivity()
+			}
+
+			if !follow {
+				counts, err := exportAudit(log, repo.EventQueue, activity)
+				if err != nil {
+					return err
+				}
+				printExport(args[0], counts, log)
+				return nil
+			}
+
+			runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			pterm.Info.Printf("Exporting to %s every %s, press Ctrl+C to stop\n", args[0], interval)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				counts, err := exportAudit(log, repo.EventQueue, activity)
+				if err != nil {
+					return err
+				}
+				if counts.events+counts.activity > 0 {
+					printExport(args[0], counts, log)
+				}
+				select {
+				case <-runCtx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.Flags().Int("anchor-every", auditlog.DefaultAnchorEvery, "Records between anchors")
+	cmd.Flags().Bool("follow", false, "Keep exporting new entries until interrupted")
+	cmd.Flags().Duration("interval", 30*time.Second, "How often to export with --follow")
+
+	return cmd
+}
+
+// eventSource lists the events of the queue
+type eventSource interface {
+	ListEventsAfter(after int64, limit int) ([]hooks.SequencedEvent, error)
+}
+
+// activitySource lists the activity of every stack
+type activitySource interface {
+	ListActivityAfter(after int64, limit int) ([]stack.ActivityEntry, error)
+}
+
+type exportCounts struct {
+	events   int
+	activity int
+}
+
+// exportAudit appends the entries of the sources not yet in the log and
+// anchors the head of the chain
+func exportAudit(log *auditlog.Log, events eventSource, activity activitySource) (exportCounts, error) {
+	var counts exportCounts
+
+	for {
+		batch, err := events.ListEventsAfter(log.Cursor(sourceEvents), exportBatch)
+		if err != nil {
+			return counts, fmt.Errorf("failed to list events: %w", err)
+		}
+		for _, e := range batch {
+			if err := log.Append(eventRecord(e)); err != nil {
+				return counts, err
+			}
+			counts.events++
+		}
+		if len(batch) < exportBatch {
+			break
+		}
+	}
+
+	for activity != nil {
+		batch, err := activity.ListActivityAfter(log.Cursor(sourceActivity), exportBatch)
+		if err != nil {
+			return counts, fmt.Errorf("failed to list stack activity: %w", err)
+		}
+		for _, e := range batch {
+			if err := log.Append(activityRecord(e)); err != nil {
+				return counts, err
+			}
+			counts.activity++
+		}
+		if len(batch) < exportBatch {
+			break
+		}
+	}
+
+	return counts, log.Anchor()
+}
+
+func eventRecord(e hooks.SequencedEvent) auditlog.Record {
+	data, _ := json.Marshal(e.Data)
+	when := e.Timestamp
+	if when.IsZero() {
+		when = e.CreatedAt
+	}
+	return auditlog.Record{
+		Time:     when,
+		Source:   sourceEvents,
+		SourceID: e.Seq,
+		ID:       e.ID,
+		Type:     string(e.Type),
+		Stack:    e.Stack,
+		Agent:    e.Agent,
+		RunID:    e.RunID,
+		Data:     data,
+	}
+}
+
+func activityRecord(e stack.ActivityEntry) auditlog.Record {
+	details := json.RawMessage(e.Details)
+	if !json.Valid(details) {
+		details, _ = json.Marshal(e.Details)
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"stack_id":    e.StackID,
+		"resource_id": e.ResourceID,
+		"details":     details,
+	})
+	stackName := e.StackName
+	if stackName == "" {
+		stackName = e.StackID
+	}
+	return auditlog.Record{
+		Time:     e.CreatedAt,
+		Source:   sourceActivity,
+		SourceID: e.ID,
+		Type:     e.Type,
+		Stack:    stackName,
+		Actor:    e.User,
+		Data:     data,
+	}
+}
+
+func printExport(path string, counts exportCounts, log *auditlog.Log) {
+	pterm.Success.Printf("Exported %d events and %d stack activity entries to %s (%d records)\n",
+		counts.events, counts.activity, path, log.LastSeq())
+}
@@ -0,0 +1,16 @@
+//go:build cgo
+// +build cgo
+
+package events
+
+import "github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+
+// openActivitySource opens the stack database to export the activity of
+// every stack
+func openActivitySource() (activitySource, func(), error) {
+	stackService, err := services.NewStackService()
+	if err != nil {
+		return nil, nil, err
+	}
+	return stackService, func() { stackService.Close() }, nil
+}
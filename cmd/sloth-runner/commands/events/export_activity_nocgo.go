@@ -0,0 +1,12 @@
+//go:build !cgo
+// +build !cgo
+
+package events
+
+import "fmt"
+
+// openActivitySource is unavailable without CGO, which the stack database
+// needs
+func openActivitySource() (activitySource, func(), error) {
+	return nil, nil, fmt.Errorf("stack database not available in non-CGO builds")
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/auditlog"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+func NewVerifyCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify <audit-log>",
+		Short: "Detect tampering of an exported audit log",
+		Long: `Recomputes the hash chain of an audit log written by 'events export' and
+checks every anchor of the anchors file against it. Changed, removed or
+reordered records break the chain; a truncated or rewritten log no longer
+matches its anchors. The first record that fails is reported and the
+command exits with an error.
+
+The anchors file defaults to <audit-log>.anchors; pass the copy kept apart
+from the log with --anchors.`,
+		Example: `  sloth-runner events verify /var/log/sloth-runner/audit.log
+  sloth-runner events verify audit.log --anchors /mnt/worm/audit.log.anchors`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			anchors, _ := cmd.Flags().GetString("anchors")
+			outputFormat, _ := cmd.Flags().GetString("output")
+
+			if anchors == "" {
+				anchors = auditlog.AnchorsPath(args[0])
+				if _, err := os.Stat(anchors); err != nil {
+					pterm.Warning.Printf("No anchors file at %s, only the chain is checked, which can't detect truncation\n", anchors)
+					anchors = ""
+				}
+			}
+
+			result, err := auditlog.Verify(args[0], anchors)
+			var tamperErr *auditlog.TamperError
+			if err != nil && !errors.As(err, &tamperErr) {
+				return err
+			}
+
+			if outputFormat == "json" {
+				report := map[string]interface{}{
+					"log":    args[0],
+					"valid":  err == nil,
+					"result": result,
+				}
+				if tamperErr != nil {
+					report["tampered_record"] = tamperErr.Seq
+					report["reason"] = tamperErr.Reason
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if encErr := encoder.Encode(report); encErr != nil {
+					return encErr
+				}
+			} else if err == nil {
+				pterm.Success.Printf("✓ %s is intact: %d records, %d anchors, %d matched in the anchors file\n",
+					args[0], result.Records, result.Anchors, result.ExternalAnchors)
+				pterm.Info.Printf("Head: %s (record %d)\n", result.Head, result.LastSeq)
+			}
+
+			if err != nil {
+				return fmt.Errorf("%s was tampered with: %w", args[0], err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("anchors", "", "Anchors file to check the log against (default <audit-log>.anchors)")
+	cmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/apitoken"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// feedsOptions configures the calendar and feeds of scheduled workflows
+type feedsOptions struct {
+	Enabled  bool
+	Port     int
+	Days     int
+	Runs     int
+	CertFile string
+	KeyFile  string
+	Insecure bool
+}
+
+func addFeedsFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("feeds-http", false, "Serve an iCal calendar and Atom/RSS feeds of scheduled workflows under /feeds")
+	cmd.Flags().Int("feeds-port", 50065, "Port for the feeds endpoint")
+	cmd.Flags().Int("feeds-days", scheduler.DefaultFeedDays, "How many days ahead the calendar lists runs")
+	cmd.Flags().Int("feeds-runs", scheduler.DefaultFeedRuns, "How many recent results the feeds carry")
+	cmd.Flags().String("feeds-tls-cert", "", "Server certificate for the feeds endpoint")
+	cmd.Flags().String("feeds-tls-key", "", "Server key for the feeds endpoint")
+	cmd.Flags().Bool("feeds-insecure", false, "Serve the feeds endpoint over plain HTTP")
+}
+
+func getFeedsOptions(cmd *cobra.Command) feedsOptions {
+	opts := feedsOptions{}
+	opts.Enabled, _ = cmd.Flags().GetBool("feeds-http")
+	opts.Port, _ = cmd.Flags().GetInt("feeds-port")
+	opts.Days, _ = cmd.Flags().GetInt("feeds-days")
+	opts.Runs, _ = cmd.Flags().GetInt("feeds-runs")
+	opts.CertFile, _ = cmd.Flags().GetString("feeds-tls-cert")
+	opts.KeyFile, _ = cmd.Flags().GetString("feeds-tls-key")
+	opts.Insecure, _ = cmd.Flags().GetBool("feeds-insecure")
+	return opts
+}
+
+// feedsAuthorizer authenticates feed requests with API tokens, which
+// see the schedules of the stacks and workflows in their scope. Calendar
+// apps and feed readers can't set headers, so the token may also be
+// passed as the token query parameter.
+func feedsAuthorizer(store *apitoken.Store) scheduler.FeedAuthorizer {
+	return func(r *http.Request) (func(*scheduler.WorkflowSchedule) bool, error) {
+		raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			raw = r.URL.Query().Get("token")
+		}
+		if raw == "" {
+			return nil, fmt.Errorf("an API token is required")
+		}
+		token, err := store.Authenticate(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !token.Can(apitoken.ActionRead) {
+			return nil, fmt.Errorf("role %s can't %s", token.Role, apitoken.ActionRead)
+		}
+		return func(s *scheduler.WorkflowSchedule) bool {
+			return token.Allows(apitoken.ActionRead, s.Stack, s.Workflow) == nil
+		}, nil
+	}
+}
+
+// startFeeds starts the feeds endpoint in the background
+func startFeeds(opts feedsOptions) error {
+	if !opts.Insecure && (opts.CertFile == "" || opts.KeyFile == "") {
+		return fmt.Errorf("feeds endpoint: TLS requires --feeds-tls-cert and --feeds-tls-key (or pass --feeds-insecure)")
+	}
+
+	schedules, err := scheduler.DefaultScheduleStore()
+	if err != nil {
+		return fmt.Errorf("feeds endpoint: %w", err)
+	}
+	stats, err := scheduler.DefaultStatsStore()
+	if err != nil {
+		return fmt.Errorf("feeds endpoint: %w", err)
+	}
+	tokens, err := apitoken.DefaultStore()
+	if err != nil {
+		return fmt.Errorf("feeds endpoint: %w", err)
+	}
+
+	feeds := scheduler.NewFeedServer(schedules, stats, feedsAuthorizer(tokens))
+	feeds.Days = opts.Days
+	feeds.Runs = opts.Runs
+	server := &http.Server{Handler: feeds.Handler()}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for feeds endpoint: %w", err)
+	}
+	go func() {
+		var err error
+		if opts.Insecure {
+			err = server.Serve(lis)
+		} else {
+			err = server.ServeTLS(lis, opts.CertFile, opts.KeyFile)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Feeds endpoint stopped", "error", err)
+		}
+	}()
+
+	if opts.Insecure {
+		pterm.Warning.Printf("Feeds endpoint listening on :%d without TLS\n", opts.Port)
+	} else {
+		pterm.Success.Printf("Feeds endpoint listening on :%d (TLS)\n", opts.Port)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luafmt"
+	"github.com/spf13/cobra"
+)
+
+// NewFmtCommand creates the fmt command
+func NewFmtCommand(ctx *AppContext) *cobra.Command {
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "fmt [file-or-dir...]",
+		Short: "Format sloth files canonically",
+		Long: `Rewrite sloth files in the canonical format, so workflow diffs only show
+what changed:
+
+  - four spaces of indentation per block, table or call, one more for
+    method chains such as task("x"):command(...)
+  - no trailing whitespace, at most one blank line in a row
+  - a trailing comma after the last field of multi-line tables
+  - the fields of task definition tables ("tasks = { {...} }") in a fixed
+    order: name, description, placement, dependencies, conditions,
+    settings, then the pre_exec, command and post_exec functions
+
+Comments, strings and the YAML front-matter are kept as they are.
+Directories are searched for *.sloth files; with no arguments the current
+directory is. Files that are not valid Lua are reported and left alone.
+
+With --check nothing is written: the files that need formatting are listed
+and the command fails if there are any, for use in CI.`,
+		Example: `  # Format a workflow in place
+  sloth-runner fmt deploy.sloth
+
+  # Format every sloth file of a repository
+  sloth-runner fmt .
+
+  # Fail a CI job when a workflow isn't formatted
+  sloth-runner fmt --check workflows/`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			files, err := slothFiles(args)
+			if err != nil {
+				return err
+			}
+
+			var unformatted, failed int
+			for _, file := range files {
+				changed, err := formatFile(file, !check)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", file, err)
+					failed++
+					continue
+				}
+				if changed {
+					fmt.Fprintln(cmd.OutOrStdout(), file)
+					unformatted++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d file(s) could not be formatted", failed)
+			}
+			if check && unformatted > 0 {
+				return fmt.Errorf("%d file(s) need formatting, run 'sloth-runner fmt'", unformatted)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&check, "check", false, "List files that need formatting and fail if any, without writing")
+
+	return cmd
+}
+
+// formatFile formats a sloth file, reporting whether its content changes
+func formatFile(path string, write bool) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	out, err := luafmt.Format(src)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(src, out) {
+		return false, nil
+	}
+	if !write {
+		return true, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, out, info.Mode().Perm())
+}
+
+// slothFiles expands directories into the *.sloth files below them,
+// skipping hidden directories such as .git
+func slothFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(d.Name(), ".sloth") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
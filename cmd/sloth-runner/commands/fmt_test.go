@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFmtCommand(t *testing.T) {
+	dir := t.TempDir()
+	messy := filepath.Join(dir, "workflows", "deploy.sloth")
+	tidy := filepath.Join(dir, "tidy.sloth")
+	os.MkdirAll(filepath.Dir(messy), 0755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+	os.WriteFile(messy, []byte("local t = {\n  a = 1\n}\n"), 0644)
+	os.WriteFile(tidy, []byte("print(1)\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".git", "skipped.sloth"), []byte("local x = {\n"), 0644)
+
+	run := func(args ...string) (string, error) {
+		cmd := NewFmtCommand(&AppContext{})
+		cmd.SilenceUsage = true
+		var out, stderr bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// --check lists the file without changing it
+	out, err := run("--check", dir)
+	if err == nil || !strings.Contains(err.Error(), "1 file(s) need formatting") {
+		t.Fatalf("Expected --check to fail, got %v", err)
+	}
+	if strings.TrimSpace(out) != messy {
+		t.Errorf("Expected only %s to be listed, got %q", messy, out)
+	}
+	if content, _ := os.ReadFile(messy); string(content) != "local t = {\n  a = 1\n}\n" {
+		t.Errorf("--check changed the file: %q", content)
+	}
+
+	if _, err := run(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if content, _ := os.ReadFile(messy); string(content) != "local t = {\n    a = 1,\n}\n" {
+		t.Errorf("Unexpected formatting: %q", content)
+	}
+
+	if _, err := run("--check", dir); err != nil {
+		t.Errorf("Expected formatted files to pass --check, got %v", err)
+	}
+}
+
+func TestFmtCommand_InvalidFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "broken.sloth")
+	os.WriteFile(file, []byte("local x = {\n"), 0644)
+
+	cmd := NewFmtCommand(&AppContext{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{file})
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("Expected an error for invalid Lua")
+	}
+	if !strings.Contains(out.String(), "syntax error") {
+		t.Errorf("Expected a syntax error to be reported, got %q", out.String())
+	}
+	if content, _ := os.ReadFile(file); string(content) != "local x = {\n" {
+		t.Errorf("Invalid file was changed: %q", content)
+	}
+}
@@ -0,0 +1,335 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/gitops"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+)
+
+// NewGitOpsCommand creates the gitops parent command
+func NewGitOpsCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gitops",
+		Short: "Sync workflows from a Git repository",
+		Long: `Keep the master's saved workflows in sync with a Git repository.
+
+Once enabled, the master fetches the repository every interval and
+reconciles the .sloth files under the configured path:
+
+  - new and changed files are validated and saved as sloths
+  - files that don't validate keep their last valid version
+  - removed files are deregistered
+  - workflows with a schedule in their front-matter are run by the master
+    on that schedule, on the stack named by 'stack' (the workflow name by
+    default)
+
+Syncing again without new commits changes nothing.`,
+	}
+
+	cmd.AddCommand(newEnableCmd())
+	cmd.AddCommand(newDisableCmd())
+	cmd.AddCommand(newStatusCmd())
+	cmd.AddCommand(newSyncCmd())
+
+	return cmd
+}
+
+func newEnableCmd() *cobra.Command {
+	var repo, branch, path, interval string
+	var noSync bool
+
+	cmd := &cobra.Command{
+		Use:   "enable",
+		Short: "Enable syncing workflows from a repository",
+		Example: `  sloth-runner gitops enable --repo git@github.com:acme/infra.git --path workflows/ --interval 5m
+  sloth-runner gitops enable --repo https://github.com/acme/infra.git --branch release --interval 1h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			every, err := time.ParseDuration(interval)
+			if err != nil {
+				return fmt.Errorf("invalid interval: %w", err)
+			}
+			if every < time.Minute {
+				return fmt.Errorf("interval must be at least 1m")
+			}
+
+			store, err := gitops.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			cfg := &gitops.SyncConfig{
+				Repo:     repo,
+				Branch:   branch,
+				Path:     strings.Trim(path, "/"),
+				Interval: every,
+				Enabled:  true,
+			}
+			if err := store.SaveConfig(cfg); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "GitOps enabled: %s every %s\n", describeSource(cfg), every)
+
+			if noSync {
+				return nil
+			}
+			report, err := reconcile(cmd.Context(), store)
+			if err != nil {
+				return err
+			}
+			printReport(cmd, report)
+			if len(report.Added)+len(report.Updated)+len(report.Unchanged)+len(report.Invalid) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "Warning: no .sloth files found under %q\n", cfg.Path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repo, "repo", "", "Repository URL (required)")
+	cmd.Flags().StringVar(&branch, "branch", "", "Branch to follow (default: the repository's default branch)")
+	cmd.Flags().StringVar(&path, "path", "", "Directory of the repository holding the workflows")
+	cmd.Flags().StringVar(&interval, "interval", "5m", "How often the master fetches the repository")
+	cmd.Flags().BoolVar(&noSync, "no-sync", false, "Don't sync right away")
+	cmd.MarkFlagRequired("repo")
+
+	return cmd
+}
+
+func newDisableCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable",
+		Short: "Stop syncing and scheduling workflows",
+		Long: `Stop syncing the repository and running its scheduled workflows. Synced
+workflows stay saved; 'gitops enable' resumes syncing.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := gitops.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.SetEnabled(false); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "GitOps disabled")
+			return nil
+		},
+	}
+}
+
+func newSyncCmd() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync the repository now",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := gitops.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			report, err := reconcile(cmd.Context(), store)
+			if err != nil {
+				return err
+			}
+			if outputFormat == "json" {
+				return writeJSON(cmd, report)
+			}
+			printReport(cmd, report)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func newStatusCmd() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the sync status of the repository and its workflows",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := gitops.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			cfg, err := store.Config()
+			if err != nil {
+				return err
+			}
+			state, err := store.State()
+			if err != nil {
+				return err
+			}
+			files, err := store.Files()
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				if files == nil {
+					files = []*gitops.SyncedFile{}
+				}
+				return writeJSON(cmd, struct {
+					Config *gitops.SyncConfig   `json:"config"`
+					State  *gitops.SyncState    `json:"state"`
+					Files  []*gitops.SyncedFile `json:"files"`
+				}{cfg, state, files})
+			}
+
+			out := cmd.OutOrStdout()
+			status := "enabled"
+			if !cfg.Enabled {
+				status = "disabled"
+			}
+			fmt.Fprintf(out, "Repository:  %s\n", describeSource(cfg))
+			fmt.Fprintf(out, "Status:      %s, every %s\n", status, cfg.Interval)
+			if state.LastSync.IsZero() {
+				fmt.Fprintln(out, "Last sync:   never")
+			} else {
+				fmt.Fprintf(out, "Last sync:   %s (%s)\n", state.LastSync.Format("2006-01-02 15:04:05"), shortCommit(state.LastCommit))
+				if cfg.Enabled {
+					fmt.Fprintf(out, "Next sync:   %s\n", state.LastSync.Add(cfg.Interval).Format("2006-01-02 15:04:05"))
+				}
+			}
+			if state.LastError != "" {
+				fmt.Fprintf(out, "Last error:  %s\n", state.LastError)
+			}
+
+			if len(files) == 0 {
+				fmt.Fprintln(out, "\nNo workflows synced")
+				return nil
+			}
+
+			fmt.Fprintln(out)
+			tw := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(tw, "FILE\tWORKFLOW\tSTATE\tCOMMIT\tSCHEDULE\tSTACK\tNEXT RUN")
+			for _, f := range files {
+				fileState := "synced"
+				if f.Error != "" {
+					fileState = "invalid"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					f.Path, orDash(f.Name), fileState, orDash(shortCommit(f.Commit)),
+					orDash(f.Schedule), orDash(f.Stack), nextRun(cfg, f))
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			header := "\nInvalid files (their last valid version stays registered):"
+			for _, f := range files {
+				if f.Error != "" {
+					if header != "" {
+						fmt.Fprintln(out, header)
+						header = ""
+					}
+					fmt.Fprintf(out, "  %s: %s\n", f.Path, f.Error)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// reconcile syncs the repository from this process
+func reconcile(ctx context.Context, store *gitops.Store) (*gitops.SyncReport, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// The repository run --sloth reads
+	sloths, err := sloth.NewSQLiteRepository("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sloth repository: %w", err)
+	}
+	defer sloths.Close()
+
+	return gitops.NewReconciler(store, sloths, config.GetGitOpsCheckoutDir()).Reconcile(ctx)
+}
+
+func printReport(cmd *cobra.Command, report *gitops.SyncReport) {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Synced %s: %d added, %d updated, %d removed, %d unchanged\n",
+		shortCommit(report.Commit), len(report.Added), len(report.Updated), len(report.Removed), len(report.Unchanged))
+	for _, path := range report.Added {
+		fmt.Fprintf(out, "  + %s\n", path)
+	}
+	for _, path := range report.Updated {
+		fmt.Fprintf(out, "  ~ %s\n", path)
+	}
+	for _, path := range report.Removed {
+		fmt.Fprintf(out, "  - %s\n", path)
+	}
+	invalid := make([]string, 0, len(report.Invalid))
+	for path := range report.Invalid {
+		invalid = append(invalid, path)
+	}
+	sort.Strings(invalid)
+	for _, path := range invalid {
+		fmt.Fprintf(out, "  ! %s: %s\n", path, report.Invalid[path])
+	}
+}
+
+func writeJSON(cmd *cobra.Command, v interface{}) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func describeSource(cfg *gitops.SyncConfig) string {
+	source := cfg.Repo
+	if cfg.Branch != "" {
+		source += "@" + cfg.Branch
+	}
+	if cfg.Path != "" {
+		source += " (" + cfg.Path + "/)"
+	}
+	return source
+}
+
+func nextRun(cfg *gitops.SyncConfig, f *gitops.SyncedFile) string {
+	if !cfg.Enabled || f.Name == "" || f.Schedule == "" {
+		return "-"
+	}
+	schedule, err := cron.ParseStandard(f.Schedule)
+	if err != nil {
+		return "-"
+	}
+	return schedule.Next(time.Now()).Format("2006-01-02 15:04")
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/spf13/cobra"
 )
 
@@ -18,10 +19,11 @@ func NewDeleteCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !force {
-				fmt.Print("Are you sure you want to delete this group? (yes/no): ")
-				var confirm string
-				fmt.Scanln(&confirm)
-				if confirm != "yes" && confirm != "y" {
+				confirm, err := taskrunner.Confirm("Are you sure you want to delete this group?", "--force")
+				if err != nil {
+					return err
+				}
+				if !confirm {
 					fmt.Println("Cancelled")
 					return nil
 				}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"text/tabwriter"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/spf13/cobra"
 )
 
@@ -123,10 +124,11 @@ func newTemplateDeleteCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !force {
-				fmt.Print("Are you sure you want to delete this template? (yes/no): ")
-				var confirm string
-				fmt.Scanln(&confirm)
-				if confirm != "yes" && confirm != "y" {
+				confirm, err := taskrunner.Confirm("Are you sure you want to delete this template?", "--force")
+				if err != nil {
+					return err
+				}
+				if !confirm {
 					fmt.Println("Cancelled")
 					return nil
 				}
@@ -10,6 +10,7 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/spf13/cobra"
 )
 
@@ -152,10 +153,11 @@ func newWebhookDeleteCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !force {
-				fmt.Print("Are you sure you want to delete this webhook? (yes/no): ")
-				var confirm string
-				fmt.Scanln(&confirm)
-				if confirm != "yes" && confirm != "y" {
+				confirm, err := taskrunner.Confirm("Are you sure you want to delete this webhook?", "--force")
+				if err != nil {
+					return err
+				}
+				if !confirm {
 					fmt.Println("Cancelled")
 					return nil
 				}
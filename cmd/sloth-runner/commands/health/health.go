@@ -13,10 +13,10 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // HealthStatus represents the overall health status
@@ -242,7 +242,7 @@ func checkDatabase() HealthCheck {
 	}
 
 	dbPath := config.GetAgentDBPath()
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		check.Status = "error"
 		check.Error = err.Error()
@@ -250,7 +250,7 @@ func checkDatabase() HealthCheck {
 		check.Duration = time.Since(start)
 		return check
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	if err := db.Ping(); err != nil {
 		check.Status = "error"
@@ -319,7 +319,7 @@ func checkMasterConnection() HealthCheck {
 	defer cancel()
 
 	conn, err := grpc.DialContext(ctx, masterAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 		grpc.WithBlock(),
 	)
 	if err != nil {
@@ -414,11 +414,11 @@ func checkAgentHealth(agentName string) error {
 
 	// Get agent from database
 	dbPath := config.GetAgentDBPath()
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return fmt.Errorf("failed to open agent database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	// Query agent
 	query := `SELECT name, address, status, last_heartbeat FROM agents WHERE name = ?`
@@ -449,7 +449,7 @@ func checkAgentHealth(agentName string) error {
 	defer cancel()
 
 	conn, err := grpc.DialContext(ctx, address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 		grpc.WithBlock(),
 	)
 	if err != nil {
@@ -468,11 +468,11 @@ func checkAgentHealth(agentName string) error {
 
 func checkAllAgents() error {
 	dbPath := config.GetAgentDBPath()
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return fmt.Errorf("failed to open agent database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	query := `SELECT name, address, status, last_heartbeat FROM agents ORDER BY name`
 	rows, err := db.Query(query)
@@ -520,7 +520,7 @@ func checkAllAgents() error {
 		// Quick connectivity check
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		conn, err := grpc.DialContext(ctx, agent.Address,
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			mtls.DialOption(),
 			grpc.WithBlock(),
 		)
 		cancel()
@@ -594,7 +594,7 @@ func checkMasterHealth(address string) error {
 	defer cancel()
 
 	grpcConn, err := grpc.DialContext(ctx, address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 		grpc.WithBlock(),
 	)
 	if err != nil {
@@ -0,0 +1,211 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/spf13/cobra"
+)
+
+func newDiffCmd() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "diff <execution-id> <execution-id>",
+		Short: "Compare two executions of a workflow",
+		Long: `Compare two executions of the same workflow: the tasks added and removed,
+tasks whose status changed, how long each task took in both runs, the lines
+of task outputs that changed and the values and params that changed.
+
+IDs may be shortened to the prefix shown by history list. The first run is
+the baseline, usually the last good one.`,
+		Example: `  # What changed since the last successful deploy?
+  sloth-runner runs diff 3f2a9c1e 8b7d0e44
+
+  # As JSON
+  sloth-runner runs diff 3f2a9c1e 8b7d0e44 -o json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return diffExecutions(cmd.OutOrStdout(), args[0], args[1], outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text|json)")
+
+	return cmd
+}
+
+func diffExecutions(w io.Writer, fromID, toID, outputFormat string) error {
+	db, err := execution.NewHistoryDB(config.GetHistoryDBPath())
+	if err != nil {
+		return fmt.Errorf("failed to open history database: %w", err)
+	}
+	defer db.Close()
+
+	from, fromTasks, err := loadExecution(db, fromID)
+	if err != nil {
+		return err
+	}
+	to, toTasks, err := loadExecution(db, toID)
+	if err != nil {
+		return err
+	}
+	diff := execution.Compare(from, fromTasks, to, toTasks)
+
+	if outputFormat == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+	printRunDiff(w, diff)
+	return nil
+}
+
+func loadExecution(db *execution.HistoryDB, prefix string) (*execution.Execution, []*execution.TaskExecution, error) {
+	id, err := db.ResolveExecutionID(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	exec, err := db.GetExecution(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+	tasks, err := db.GetTaskExecutions(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get task executions: %w", err)
+	}
+	return exec, tasks, nil
+}
+
+func printRunDiff(w io.Writer, diff *execution.RunDiff) {
+	from, to := diff.From, diff.To
+
+	fmt.Fprintf(w, "\nComparing runs of %s\n", to.WorkflowName)
+	if from.WorkflowName != to.WorkflowName {
+		fmt.Fprintf(w, "Warning: the first run is of workflow %s\n", from.WorkflowName)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, run := range []struct {
+		label string
+		exec  *execution.Execution
+	}{{"from", from}, {"to", to}} {
+		fmt.Fprintf(tw, "  %s\t%s\t%s %s\t%s\t%s\n",
+			run.label,
+			shortID(run.exec.ID),
+			getStatusIcon(run.exec.Status), run.exec.Status,
+			time.Unix(run.exec.StartTime, 0).Format("2006-01-02 15:04"),
+			formatDuration(run.exec.Duration),
+		)
+	}
+	tw.Flush()
+
+	if len(diff.Added) > 0 {
+		fmt.Fprintf(w, "\nTasks added:\n")
+		for _, t := range diff.Added {
+			fmt.Fprintf(w, "  + %s (%s %s)\n", t.TaskName, getStatusIcon(t.Status), t.Status)
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Fprintf(w, "\nTasks removed:\n")
+		for _, t := range diff.Removed {
+			fmt.Fprintf(w, "  - %s\n", t.TaskName)
+		}
+	}
+
+	if len(diff.Tasks) > 0 {
+		fmt.Fprintf(w, "\nTasks:\n")
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "TASK\tSTATUS\tDURATION\tDELTA")
+		fmt.Fprintln(tw, "----\t------\t--------\t-----")
+		for _, t := range diff.Tasks {
+			status := fmt.Sprintf("%s %s", getStatusIcon(t.ToStatus), t.ToStatus)
+			if t.StatusChanged() {
+				status = fmt.Sprintf("%s %s → %s", getStatusIcon(t.FromStatus), t.FromStatus, status)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s → %s\t%s\n",
+				t.TaskName,
+				status,
+				formatDuration(t.FromDuration), formatDuration(t.ToDuration),
+				formatDelta(t.ToDuration-t.FromDuration),
+			)
+		}
+		tw.Flush()
+	}
+
+	for _, t := range diff.Tasks {
+		if t.FromError == t.ToError && t.Output == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", t.TaskName)
+		if t.FromError != t.ToError {
+			fmt.Fprintf(w, "  error: %s → %s\n", orNone(t.FromError), orNone(t.ToError))
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(t.Output, "\n"), "\n") {
+			if line != "" {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+	}
+
+	if len(diff.Variables) > 0 {
+		fmt.Fprintf(w, "\nVariables:\n")
+		for _, v := range diff.Variables {
+			switch v.Change {
+			case execution.ChangeAdded:
+				fmt.Fprintf(w, "  + %s: %s\n", v.Name, formatValue(v.To))
+			case execution.ChangeRemoved:
+				fmt.Fprintf(w, "  - %s: %s\n", v.Name, formatValue(v.From))
+			default:
+				fmt.Fprintf(w, "  ~ %s: %s → %s\n", v.Name, formatValue(v.From), formatValue(v.To))
+			}
+		}
+	}
+
+	changed := len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Variables) > 0
+	for _, t := range diff.Tasks {
+		changed = changed || t.Changed()
+	}
+	if !changed {
+		fmt.Fprintf(w, "\nNo changes besides durations\n")
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// formatDelta formats a duration change in milliseconds, with its sign
+func formatDelta(ms int64) string {
+	switch {
+	case ms > 0:
+		return "+" + formatDuration(ms)
+	case ms < 0:
+		return "-" + formatDuration(-ms)
+	}
+	return "same"
+}
+
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -9,19 +9,22 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
 	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/output"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/spf13/cobra"
 )
 
 func NewHistoryCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "history",
-		Aliases: []string{"hist", "executions"},
+		Aliases: []string{"hist", "executions", "runs"},
 		Short:   "View execution history",
 		Long:    `View, search, and analyze workflow execution history.`,
 	}
 
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newShowCmd())
+	cmd.AddCommand(newDiffCmd())
 	cmd.AddCommand(newStatsCmd())
 	cmd.AddCommand(newCleanupCmd())
 
@@ -103,13 +106,18 @@ func newShowCmd() *cobra.Command {
 
 func newStatsCmd() *cobra.Command {
 	var workflow string
+	var stack string
 	var since string
 	var outputFormat string
 
 	cmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Show execution statistics",
-		Long:  `Display statistics about workflow executions including success rates and performance metrics.`,
+		Long: `Display statistics about workflow executions including success rates and performance metrics.
+
+Tasks that look flaky are listed as well: tasks that failed and then passed
+on retry, or whose outcome keeps flipping between runs of a stack. Only the
+last 20 runs of each task are considered.`,
 		Example: `  # Show overall statistics
   sloth-runner history stats
 
@@ -117,13 +125,17 @@ func newStatsCmd() *cobra.Command {
   sloth-runner history stats --workflow deploy-app
 
   # Show stats for last 7 days
-  sloth-runner history stats --since 7d`,
+  sloth-runner history stats --since 7d
+
+  # Show flaky tasks of a stack
+  sloth-runner runs stats --stack production`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return showStats(workflow, since, outputFormat)
+			return showStats(workflow, stack, since, outputFormat)
 		},
 	}
 
 	cmd.Flags().StringVarP(&workflow, "workflow", "w", "", "Filter by workflow name")
+	cmd.Flags().StringVar(&stack, "stack", "", "Show flaky tasks of this stack only")
 	cmd.Flags().StringVar(&since, "since", "", "Statistics since (e.g., 24h, 7d, 30d)")
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text|json)")
 
@@ -308,8 +320,20 @@ func showExecution(id string, verbose bool, outputFormat string) error {
 	if len(tasks) > 0 {
 		fmt.Printf("\nTasks:\n")
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "TASK\tSTATUS\tDURATION\tCHANGED")
-		fmt.Fprintln(w, "----\t------\t--------\t-------")
+		// Resource usage is measured for tasks run on agents
+		withUsage := false
+		for _, task := range tasks {
+			if task.CPUTimeMs > 0 || task.PeakRSSBytes > 0 {
+				withUsage = true
+			}
+		}
+		if withUsage {
+			fmt.Fprintln(w, "TASK\tSTATUS\tDURATION\tCHANGED\tCPU\tPEAK RSS\tREAD\tWRITE")
+			fmt.Fprintln(w, "----\t------\t--------\t-------\t---\t--------\t----\t-----")
+		} else {
+			fmt.Fprintln(w, "TASK\tSTATUS\tDURATION\tCHANGED")
+			fmt.Fprintln(w, "----\t------\t--------\t-------")
+		}
 
 		for _, task := range tasks {
 			statusIcon := getStatusIcon(task.Status)
@@ -322,16 +346,46 @@ func showExecution(id string, verbose bool, outputFormat string) error {
 				changed = "yes"
 			}
 
-			fmt.Fprintf(w, "%s\t%s %s\t%s\t%s\n",
-				task.TaskName,
-				statusIcon, task.Status,
-				duration,
-				changed,
-			)
+			if withUsage {
+				fmt.Fprintf(w, "%s\t%s %s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					task.TaskName,
+					statusIcon, task.Status,
+					duration,
+					changed,
+					formatDuration(task.CPUTimeMs),
+					formatBytes(task.PeakRSSBytes),
+					formatBytes(task.ReadBytes),
+					formatBytes(task.WriteBytes),
+				)
+			} else {
+				fmt.Fprintf(w, "%s\t%s %s\t%s\t%s\n",
+					task.TaskName,
+					statusIcon, task.Status,
+					duration,
+					changed,
+				)
+			}
 
 			if verbose && task.Error != "" {
 				fmt.Fprintf(w, "  Error: %s\n", task.Error)
 			}
+			for _, a := range task.Attempts {
+				fmt.Fprintf(w, "  attempt %d: %s %s", a.Attempt, a.Status, formatDuration(a.Duration))
+				if a.Error != "" {
+					fmt.Fprintf(w, " (%s)", a.Error)
+				}
+				fmt.Fprintln(w)
+			}
+			if verbose {
+				for _, p := range task.Progress {
+					fmt.Fprintf(w, "  %s %s\n",
+						time.UnixMilli(p.Time).Format("15:04:05"),
+						output.FormatProgress(task.TaskName, p.Percent, p.Message))
+				}
+			}
+			for _, c := range task.WorkspaceChanges {
+				fmt.Fprintf(w, "  %s %s\n", workspaceChangeMark(c.Change), c.Path)
+			}
 		}
 
 		w.Flush()
@@ -341,7 +395,7 @@ func showExecution(id string, verbose bool, outputFormat string) error {
 	return nil
 }
 
-func showStats(workflow, since string, outputFormat string) error {
+func showStats(workflow, stack, since string, outputFormat string) error {
 	db, err := execution.NewHistoryDB(config.GetHistoryDBPath())
 	if err != nil {
 		return fmt.Errorf("failed to open history database: %w", err)
@@ -365,6 +419,12 @@ func showStats(workflow, since string, outputFormat string) error {
 		return fmt.Errorf("failed to get statistics: %w", err)
 	}
 
+	flaky, err := db.FlakyTasks(stack)
+	if err != nil {
+		return fmt.Errorf("failed to get flaky tasks: %w", err)
+	}
+	stats["flaky_tasks"] = flaky
+
 	if outputFormat == "json" {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
@@ -405,16 +465,45 @@ func showStats(workflow, since string, outputFormat string) error {
 	}
 	fmt.Printf("\n")
 
+	printFlakyTasks(flaky)
+
 	return nil
 }
 
+func printFlakyTasks(flaky []*execution.FlakyTask) {
+	if len(flaky) == 0 {
+		fmt.Printf("Flaky Tasks:         none\n\n")
+		return
+	}
+
+	fmt.Printf("Flaky Tasks:         %d\n", len(flaky))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "STACK\tTASK\tRUNS\tFAILED\tPASSED ON RETRY\tFLIPS\tSCORE\tLAST")
+	fmt.Fprintln(w, "-----\t----\t----\t------\t---------------\t-----\t-----\t----")
+	for _, f := range flaky {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%.2f\t%s %s\n",
+			f.Stack,
+			f.TaskName,
+			f.Runs,
+			f.Failures,
+			f.Recovered,
+			f.Flips,
+			f.Score,
+			getStatusIcon(f.LastStatus), f.LastStatus,
+		)
+	}
+	w.Flush()
+	fmt.Printf("\nUse 'sloth-runner run <stack> --flaky-policy quarantine' to keep their failures from failing runs\n\n")
+}
+
 func cleanupHistory(days int, force bool) error {
 	if !force {
 		fmt.Printf("This will delete all executions older than %d days.\n", days)
-		fmt.Print("Are you sure? (yes/no): ")
-		var confirm string
-		fmt.Scanln(&confirm)
-		if confirm != "yes" && confirm != "y" {
+		confirm, err := taskrunner.Confirm("Are you sure?", "--force")
+		if err != nil {
+			return err
+		}
+		if !confirm {
 			fmt.Println("Cancelled")
 			return nil
 		}
@@ -450,6 +539,18 @@ func getStatusIcon(status execution.ExecutionStatus) string {
 	}
 }
 
+// workspaceChangeMark is the mark a workspace file is listed with, as in
+// diff: + added, ~ modified, - deleted
+func workspaceChangeMark(change string) string {
+	switch change {
+	case "added":
+		return "+"
+	case "deleted":
+		return "-"
+	}
+	return "~"
+}
+
 func formatDuration(ms int64) string {
 	duration := time.Duration(ms) * time.Millisecond
 
@@ -465,6 +566,20 @@ func formatDuration(ms int64) string {
 	return fmt.Sprintf("%.1fh", duration.Hours())
 }
 
+// formatBytes formats a byte count such as a task's peak memory
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 func parseDuration(s string) (int64, error) {
 	var value int
 	var unit string
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
@@ -21,10 +22,14 @@ func NewAddCommand(ctx *commands.AppContext) *cobra.Command {
 		Short: "Add a new event hook",
 		Long: `Add a new event hook that will be triggered when specific events occur.
 
-The hook file should be a Lua script that defines event handlers.
+The hook file is a Lua (.lua or .sloth) script that defines an
+on_event(event) function, or returns one. It runs with every sloth-runner
+module available (http, exec, notifications, ...) and the event payload
+in the 'event' table. Each run is isolated in its own Lua state and
+stopped after --timeout.
 
 Example:
-  sloth-runner hook add notify-agent-join --file hooks/notify.lua`,
+  sloth-runner hook add notify-agent-join --file hooks/notify.lua --event agent.registered --timeout 1m`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			hookName := args[0]
@@ -33,6 +38,7 @@ Example:
 			description, _ := cmd.Flags().GetString("description")
 			stack, _ := cmd.Flags().GetString("stack")
 			enabled, _ := cmd.Flags().GetBool("enabled")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
 
 			if filePath == "" {
 				return fmt.Errorf("--file flag is required")
@@ -42,6 +48,10 @@ Example:
 				return fmt.Errorf("--event flag is required")
 			}
 
+			if timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+
 			// Validate file exists
 			absPath, err := filepath.Abs(filePath)
 			if err != nil {
@@ -62,6 +72,7 @@ Example:
 				"agent.connected",
 				"agent.version_mismatch",
 				"agent.resource_high",
+				"agent.facts_changed",
 				// Task events
 				"task.started",
 				"task.completed",
@@ -149,6 +160,16 @@ Example:
 				"deploy.completed",
 				"deploy.failed",
 				"deploy.rollback",
+				// Canary events
+				"canary.started",
+				"canary.deployed",
+				"canary.passed",
+				"canary.promoted",
+				"canary.failed",
+				"canary.rolled_back",
+				// Certificate events
+				"cert.expiring",
+				"cert.renewed",
 				// Health check events
 				"health.check_passed",
 				"health.check_failed",
@@ -158,7 +179,8 @@ Example:
 				"custom",
 			}
 
-			validEvent := false
+			// Events posted to the master's events endpoint are custom.<name>
+			validEvent := strings.HasPrefix(eventType, hooks.IngestEventPrefix) && len(eventType) > len(hooks.IngestEventPrefix)
 			for _, e := range validEvents {
 				if eventType == e {
 					validEvent = true
@@ -172,6 +194,7 @@ Example:
 				for _, e := range validEvents {
 					pterm.Info.Printf("  - %s\n", e)
 				}
+				pterm.Info.Println("  - custom.<name> (events posted to the master's events endpoint)")
 				return fmt.Errorf("invalid event type")
 			}
 
@@ -189,6 +212,7 @@ Example:
 				EventType:   hooks.EventType(eventType),
 				FilePath:    absPath,
 				Stack:       stack,
+				Timeout:     timeout,
 				Enabled:     enabled,
 			}
 
@@ -217,6 +241,7 @@ Example:
 	cmd.Flags().StringP("description", "d", "", "Hook description")
 	cmd.Flags().StringP("stack", "s", "", "Stack name for hook isolation")
 	cmd.Flags().Bool("enabled", true, "Enable the hook immediately")
+	cmd.Flags().Duration("timeout", 0, "Maximum hook run time (default 30s)")
 
 	return cmd
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -42,7 +43,10 @@ func NewDeleteCommand(ctx *commands.AppContext) *cobra.Command {
 				pterm.Info.Printf("Event type: %s\n", hook.EventType)
 				pterm.Info.Printf("File: %s\n", hook.FilePath)
 
-				result, _ := pterm.DefaultInteractiveConfirm.Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Delete hook '%s'?", hookName), "--force")
+				if err != nil {
+					return err
+				}
 				if !result {
 					pterm.Info.Println("Deletion cancelled")
 					return nil
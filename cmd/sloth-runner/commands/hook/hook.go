@@ -13,7 +13,7 @@ func NewHookCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "hook",
 		Short: "Manage event hooks",
-		Long:  `The hook command provides subcommands to add, list, get, show, delete, enable and disable event hooks.`,
+		Long:  `The hook command provides subcommands to add, list, get, show, delete, enable and disable event hooks, and to check event triggers.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
@@ -31,6 +31,7 @@ func NewHookCommand(ctx *commands.AppContext) *cobra.Command {
 		NewTestCommand(ctx),
 		NewLogsCommand(ctx),
 		NewDocsCommand(ctx),
+		NewTriggersCommand(ctx),
 	)
 
 	return cmd
@@ -44,6 +44,11 @@ func NewShowCommand(ctx *commands.AppContext) *cobra.Command {
 			pterm.Info.Printf("Description: %s\n", hook.Description)
 			pterm.Info.Printf("Event Type: %s\n", hook.EventType)
 			pterm.Info.Printf("File Path: %s\n", hook.FilePath)
+			if hook.Timeout > 0 {
+				pterm.Info.Printf("Timeout: %s\n", hook.Timeout)
+			} else {
+				pterm.Info.Printf("Timeout: %s (default)\n", hooks.DefaultHookTimeout)
+			}
 
 			if hook.Enabled {
 				pterm.Success.Println("Status: Enabled")
@@ -0,0 +1,96 @@
+//go:build cgo
+// +build cgo
+
+package hook
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewTriggersCommand creates the hook triggers command
+func NewTriggersCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "triggers",
+		Short: "Validate and list event triggers",
+		Long: `Validates the event trigger file and lists its triggers.
+
+Triggers run a workflow when matching events arrive, folding bursts of
+events into single runs. The master reads them from <data-dir>/triggers.yaml
+when it starts.`,
+		Example: `  sloth-runner hook triggers
+  sloth-runner hook triggers --file ./triggers.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("file")
+			if path == "" {
+				path = config.GetTriggersPath()
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read trigger file: %w", err)
+			}
+			file, err := hooks.ParseTriggerFile(data)
+			if err != nil {
+				return fmt.Errorf("invalid trigger file %s: %w", path, err)
+			}
+
+			if len(file.Triggers) == 0 {
+				pterm.Info.Printf("No triggers in %s\n", path)
+				return nil
+			}
+
+			pterm.DefaultSection.Println("Event Triggers")
+
+			tableData := [][]string{
+				{"Name", "Events", "Watcher Group", "Stack", "Workflow", "Debounce", "Throttle"},
+			}
+			for _, t := range file.Triggers {
+				workflow := t.File
+				if t.Sloth != "" {
+					workflow = "sloth:" + t.Sloth
+				}
+				tableData = append(tableData, []string{
+					t.Name,
+					strings.Join(t.Events, ", "),
+					orDash(t.WatcherGroup),
+					t.Stack,
+					workflow,
+					durationOrDash(t.Debounce),
+					durationOrDash(t.Throttle),
+				})
+			}
+			pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+			pterm.Success.Printf("%s is valid (%d triggers)\n", path, len(file.Triggers))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("file", "f", "", "Trigger file (default: <data-dir>/triggers.yaml)")
+
+	return cmd
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func durationOrDash(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.String()
+}
@@ -13,10 +13,11 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // LogEntry represents a single log entry
@@ -674,11 +675,11 @@ func fetchRemoteLogs(agentName, logPath, systemLog string, lines int, follow boo
 func executeRemoteCommand(agentName, command string) (string, error) {
 	// Get agent address from database
 	dbPath := config.GetAgentDBPath()
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return "", fmt.Errorf("failed to open agent database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	var address string
 	err = db.QueryRow("SELECT address FROM agents WHERE name = ?", agentName).Scan(&address)
@@ -694,7 +695,7 @@ func executeRemoteCommand(agentName, command string) (string, error) {
 	defer cancel()
 
 	conn, err := grpc.Dial(address,
-		grpc.WithInsecure(),
+		mtls.DialOption(),
 		grpc.WithBlock(),
 		grpc.WithTimeout(5*time.Second))
 	if err != nil {
@@ -5,7 +5,10 @@ import (
 	"strings"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/jointoken"
 	"github.com/chalkan3-sloth/sloth-runner/internal/masterdb"
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -30,6 +33,7 @@ func NewMasterCommand(ctx *AppContext) *cobra.Command {
 	cmd.AddCommand(newMasterUpdateCommand())
 	cmd.AddCommand(newMasterRemoveCommand())
 	cmd.AddCommand(newMasterStartCommand(ctx))
+	cmd.AddCommand(newMasterUpgradeCommand())
 
 	return cmd
 }
@@ -330,7 +334,7 @@ Examples:
 
 // newMasterRemoveCommand creates the master remove command
 func newMasterRemoveCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:     "remove <name>",
 		Aliases: []string{"rm", "delete"},
 		Short:   "Remove a master server",
@@ -360,13 +364,15 @@ Examples:
 			}
 
 			// Confirm deletion
-			pterm.Warning.Printf("Are you sure you want to remove master '%s' (%s)?\n", name, master.Address)
-			confirm := pterm.DefaultInteractiveConfirm
-			result, _ := confirm.Show()
-
-			if !result {
-				pterm.Info.Println("Cancelled")
-				return nil
+			if force, _ := cmd.Flags().GetBool("force"); !force {
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you sure you want to remove master '%s' (%s)?", name, master.Address), "--force")
+				if err != nil {
+					return err
+				}
+				if !result {
+					pterm.Info.Println("Cancelled")
+					return nil
+				}
 			}
 
 			// Delete master
@@ -379,6 +385,9 @@ Examples:
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+	return cmd
 }
 
 // newMasterStartCommand creates the master start command for starting a master server
@@ -394,6 +403,28 @@ func newMasterStartCommand(ctx *AppContext) *cobra.Command {
 				return fmt.Errorf("master server starter not initialized")
 			}
 
+			if blobOpts := getBlobStoreOptions(cmd); blobOpts.Enabled {
+				if err := startBlobStore(blobOpts); err != nil {
+					return err
+				}
+			}
+
+			if ingestOpts := getEventIngestOptions(cmd); ingestOpts.Enabled {
+				if err := startEventIngest(ingestOpts); err != nil {
+					return err
+				}
+			}
+
+			if feedsOpts := getFeedsOptions(cmd); feedsOpts.Enabled {
+				if err := startFeeds(feedsOpts); err != nil {
+					return err
+				}
+			}
+
+			releases.SetDefault(releases.NewIndex(getReleaseIndexOptions(cmd)))
+			requireJoinToken, _ := cmd.Flags().GetBool("require-join-token")
+			jointoken.SetRequired(requireJoinToken)
+
 			return MasterServerStarter(port)
 		},
 	}
@@ -401,6 +432,11 @@ func newMasterStartCommand(ctx *AppContext) *cobra.Command {
 	cmd.Flags().IntP("port", "p", 50053, "Port for the master gRPC server")
 	cmd.Flags().String("bind", "0.0.0.0", "Address to bind the master server")
 	cmd.Flags().Bool("daemon", false, "Run master server as daemon")
+	cmd.Flags().Bool("require-join-token", false, "Reject agents registering without a valid join token from agent install-command")
+	addBlobStoreFlags(cmd)
+	addEventIngestFlags(cmd)
+	addFeedsFlags(cmd)
+	addReleaseIndexFlags(cmd)
 
 	return cmd
 }
@@ -31,8 +31,8 @@ func TestNewMasterCommand_HasSubcommands(t *testing.T) {
 	cmd := NewMasterCommand(ctx)
 
 	subcommands := cmd.Commands()
-	if len(subcommands) != 7 {
-		t.Errorf("Expected 7 subcommands, got %d", len(subcommands))
+	if len(subcommands) != 8 {
+		t.Errorf("Expected 8 subcommands, got %d", len(subcommands))
 	}
 }
 
@@ -40,7 +40,7 @@ func TestNewMasterCommand_SubcommandNames(t *testing.T) {
 	ctx := &AppContext{}
 	cmd := NewMasterCommand(ctx)
 
-	expectedCommands := []string{"add", "list", "select", "show", "update", "remove", "start"}
+	expectedCommands := []string{"add", "list", "select", "show", "update", "remove", "start", "upgrade"}
 	subcommands := cmd.Commands()
 
 	for _, expected := range expectedCommands {
@@ -529,7 +529,7 @@ func TestNewMasterCommand_SubcommandsIntegrated(t *testing.T) {
 		commandMap[sub.Name()] = true
 	}
 
-	requiredCommands := []string{"add", "list", "select", "show", "update", "remove", "start"}
+	requiredCommands := []string{"add", "list", "select", "show", "update", "remove", "start", "upgrade"}
 	for _, required := range requiredCommands {
 		if !commandMap[required] {
 			t.Errorf("Required command '%s' not found in subcommands", required)
@@ -0,0 +1,277 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/backup"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// MasterBinaryFetcher downloads the sloth-runner binary of a release and
+// returns its path. This will be injected from main package
+var MasterBinaryFetcher func(version string) (string, error)
+
+// masterUpgradeOptions configures master upgrade
+type masterUpgradeOptions struct {
+	To             string
+	MasterAddr     string
+	Binary         string
+	SnapshotDir    string
+	DrainTimeout   time.Duration
+	RestartTimeout time.Duration
+	Force          bool
+}
+
+// newMasterUpgradeCommand creates the master upgrade command
+func newMasterUpgradeCommand() *cobra.Command {
+	var opts masterUpgradeOptions
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the running master without losing state",
+		Long: `Upgrade the master running on this host to another release.
+
+The upgrade:
+  1. downloads the new binary (or uses --binary) and checks it runs
+  2. drains the master: new runs are refused and running ones finish
+  3. snapshots all databases, like 'sloth-runner db backup'
+  4. swaps the master binary, keeping the old one as <binary>.backup
+  5. restarts the master, which migrates the database schemas and
+     resumes accepting runs
+
+Agents keep their events buffered while the master is down and send
+them once it is back.
+
+Examples:
+  sloth-runner master upgrade --to v1.4.0
+  sloth-runner master upgrade --to v1.4.0 --drain-timeout 30m
+  sloth-runner master upgrade --to v1.4.0 --binary ./sloth-runner`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.To == "" {
+				return fmt.Errorf("--to is required")
+			}
+			return upgradeMaster(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.To, "to", "", "Release to upgrade to, e.g. v1.4.0")
+	cmd.Flags().StringVar(&opts.MasterAddr, "master", "localhost:50053", "Address of the master to upgrade")
+	cmd.Flags().StringVar(&opts.Binary, "binary", "", "Use this binary instead of downloading the release")
+	cmd.Flags().StringVar(&opts.SnapshotDir, "snapshot-dir", "", "Where to write the pre-upgrade snapshot (default: <data-dir>/backups)")
+	cmd.Flags().DurationVar(&opts.DrainTimeout, "drain-timeout", 10*time.Minute, "How long to wait for running runs to finish")
+	cmd.Flags().DurationVar(&opts.RestartTimeout, "restart-timeout", time.Minute, "How long to wait for the upgraded master to come back")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Upgrade even if the master already runs the target version")
+
+	return cmd
+}
+
+func upgradeMaster(opts masterUpgradeOptions) error {
+	// Stage the new binary before draining to keep the window short
+	binary, err := stageMasterBinary(opts)
+	if err != nil {
+		return err
+	}
+	if opts.Binary == "" {
+		defer os.Remove(binary)
+	}
+
+	conn, err := grpc.Dial(opts.MasterAddr, mtls.DialOption())
+	if err != nil {
+		return fmt.Errorf("failed to connect to master: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewAgentRegistryClient(conn)
+
+	spinner, _ := pterm.DefaultSpinner.Start("Draining master...")
+	drainCtx, cancel := context.WithTimeout(context.Background(), opts.DrainTimeout+30*time.Second)
+	drain, err := client.DrainMaster(drainCtx, &pb.DrainMasterRequest{TimeoutSeconds: int32(opts.DrainTimeout.Seconds())})
+	cancel()
+	if err != nil {
+		spinner.Fail("Failed to drain master")
+		resumeMaster(client)
+		return fmt.Errorf("failed to drain master: %w", err)
+	}
+	if !drain.Drained {
+		spinner.Fail(fmt.Sprintf("%d runs still running after %s", drain.InFlight, opts.DrainTimeout))
+		resumeMaster(client)
+		return fmt.Errorf("master did not drain; retry with a longer --drain-timeout")
+	}
+	if drain.Version == opts.To && !opts.Force {
+		spinner.Success(fmt.Sprintf("Master already runs %s", opts.To))
+		resumeMaster(client)
+		return nil
+	}
+	spinner.Success(fmt.Sprintf("Master %s drained", drain.Version))
+
+	snapshot, err := snapshotMaster(drain, opts)
+	if err != nil {
+		resumeMaster(client)
+		return err
+	}
+	pterm.Success.Printf("State snapshot written to %s\n", snapshot)
+
+	backupPath, err := installMasterBinary(binary, drain.Executable)
+	if err != nil {
+		resumeMaster(client)
+		return err
+	}
+	pterm.Success.Printf("Installed %s at %s (previous binary kept at %s)\n", opts.To, drain.Executable, backupPath)
+
+	resumeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	_, err = client.ResumeMaster(resumeCtx, &pb.ResumeMasterRequest{Restart: true})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to restart master: %w\nRestore the previous binary from %s and the state from %s if needed", err, backupPath, snapshot)
+	}
+
+	spinner, _ = pterm.DefaultSpinner.Start("Waiting for the master to restart...")
+	running, err := waitForMaster(client, opts.RestartTimeout)
+	if err != nil {
+		spinner.Fail("Master did not come back")
+		return fmt.Errorf("%w\nRestore the previous binary from %s and the state from %s", err, backupPath, snapshot)
+	}
+	spinner.Success(fmt.Sprintf("Master upgraded: %s → %s", drain.Version, running))
+	if running != opts.To {
+		pterm.Warning.Printf("Master reports version %s, expected %s\n", running, opts.To)
+	}
+	return nil
+}
+
+// stageMasterBinary returns a checked binary of the target release
+func stageMasterBinary(opts masterUpgradeOptions) (string, error) {
+	binary := opts.Binary
+	if binary == "" {
+		if MasterBinaryFetcher == nil {
+			return "", fmt.Errorf("master binary fetcher not initialized")
+		}
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Downloading %s...", opts.To))
+		path, err := MasterBinaryFetcher(opts.To)
+		if err != nil {
+			spinner.Fail(fmt.Sprintf("Failed to download %s", opts.To))
+			return "", err
+		}
+		spinner.Success(fmt.Sprintf("Downloaded %s", opts.To))
+		binary = path
+	}
+
+	// Catch a binary for the wrong platform before the master is drained
+	out, err := exec.Command(binary, "version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("new binary %s does not run: %w\n%s", binary, err, out)
+	}
+	if !strings.Contains(string(out), strings.TrimPrefix(opts.To, "v")) {
+		pterm.Warning.Printf("%s does not report version %s\n", binary, opts.To)
+	}
+	return binary, nil
+}
+
+// snapshotMaster backs up the master's databases before the upgrade
+func snapshotMaster(drain *pb.DrainMasterResponse, opts masterUpgradeOptions) (string, error) {
+	dir := opts.SnapshotDir
+	if dir == "" {
+		dir = filepath.Join(drain.DataDir, "backups")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	out := filepath.Join(dir, fmt.Sprintf("pre-upgrade-%s-%s.tar.zst", drain.Version, time.Now().Format("20060102-150405")))
+	tmp := out + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", out, err)
+	}
+
+	_, err = backup.Create(drain.DataDir, f, drain.Version)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to snapshot master state: %w", err)
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	return out, nil
+}
+
+// installMasterBinary replaces exe with binary, keeping a copy of the
+// current one. The new binary is renamed into place so exe is never
+// half-written. It returns the path of the copy.
+func installMasterBinary(binary, exe string) (string, error) {
+	backupPath := exe + ".backup"
+	if err := copyExecutable(exe, backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up master binary: %w", err)
+	}
+
+	staged := exe + ".new"
+	if err := copyExecutable(binary, staged); err != nil {
+		os.Remove(staged)
+		return "", fmt.Errorf("failed to install new master binary: %w", err)
+	}
+	if err := os.Rename(staged, exe); err != nil {
+		os.Remove(staged)
+		return "", fmt.Errorf("failed to install new master binary: %w", err)
+	}
+	return backupPath, nil
+}
+
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// resumeMaster undoes a drain after a failed upgrade step
+func resumeMaster(client pb.AgentRegistryClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := client.ResumeMaster(ctx, &pb.ResumeMasterRequest{}); err != nil {
+		pterm.Warning.Printf("Failed to resume master: %v\n", err)
+		return
+	}
+	pterm.Info.Println("Master resumed on the current version")
+}
+
+// waitForMaster waits until the restarted master answers and returns the
+// version it runs
+func waitForMaster(client pb.AgentRegistryClient, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		resp, err := client.ResumeMaster(ctx, &pb.ResumeMasterRequest{})
+		cancel()
+		if err == nil {
+			return resp.Version, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("master did not come back within %s: %v", timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
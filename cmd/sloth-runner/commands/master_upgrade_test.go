@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallMasterBinary(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "sloth-runner")
+	binary := filepath.Join(dir, "sloth-runner-v2")
+	if err := os.WriteFile(exe, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(binary, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath, err := installMasterBinary(binary, exe)
+	if err != nil {
+		t.Fatalf("installMasterBinary failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(exe); string(data) != "v2" {
+		t.Errorf("Expected the new binary at %s, got %q", exe, data)
+	}
+	if data, _ := os.ReadFile(backupPath); string(data) != "v1" {
+		t.Errorf("Expected the previous binary at %s, got %q", backupPath, data)
+	}
+	if info, err := os.Stat(exe); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected the new binary to be executable, got %v", info.Mode())
+	}
+	if _, err := os.Stat(exe + ".new"); !os.IsNotExist(err) {
+		t.Error("Expected the staged binary to be renamed into place")
+	}
+}
+
+func TestInstallMasterBinary_MissingBinary(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "sloth-runner")
+	if err := os.WriteFile(exe, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := installMasterBinary(filepath.Join(dir, "missing"), exe); err == nil {
+		t.Fatal("Expected an error for a missing binary")
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "v1" {
+		t.Errorf("Expected the current binary to be left in place, got %q", data)
+	}
+}
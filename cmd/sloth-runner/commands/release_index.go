@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
+	"github.com/spf13/cobra"
+)
+
+func addReleaseIndexFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("releases-refresh", 0, "How long agents are answered from the cached release index (default 1h, env SLOTH_RUNNER_RELEASES_REFRESH)")
+	cmd.Flags().String("github-api-url", "", "GitHub API releases are looked up on, e.g. https://ghes.example.com/api/v3 (env SLOTH_RUNNER_GITHUB_API_URL)")
+	cmd.Flags().String("github-repo", "", "Repository releases are published in (default chalkan3-sloth/sloth-runner, env SLOTH_RUNNER_GITHUB_REPO)")
+}
+
+// getReleaseIndexOptions returns the release index options from the
+// environment, overridden by the flags set. The token is only read from
+// the environment so it doesn't show in process listings.
+func getReleaseIndexOptions(cmd *cobra.Command) releases.Options {
+	opts := releases.OptionsFromEnv()
+	if cmd.Flags().Changed("releases-refresh") {
+		opts.Refresh, _ = cmd.Flags().GetDuration("releases-refresh")
+	}
+	if cmd.Flags().Changed("github-api-url") {
+		opts.APIURL, _ = cmd.Flags().GetString("github-api-url")
+	}
+	if cmd.Flags().Changed("github-repo") {
+		opts.Repo, _ = cmd.Flags().GetString("github-repo")
+	}
+	return opts
+}
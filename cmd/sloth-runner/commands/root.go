@@ -2,7 +2,11 @@ package commands
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/output"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/spf13/cobra"
 )
 
@@ -31,5 +35,27 @@ and output manipulation.`,
 	// Add persistent flags
 	cmd.PersistentFlags().BoolP("version", "V", false, "Show version information")
 
+	var noANSI bool
+	cmd.PersistentFlags().BoolVar(&noANSI, "no-ansi", false, "Plain text output without colors, spinners or cursor movement, for CI logs")
+
+	var nonInteractive bool
+	cmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt for input; fail listing the flags needed instead")
+
+	// gRPC channels with masters and agents use mTLS when these are set
+	tlsFiles := mtls.FilesFromEnv()
+	cmd.PersistentFlags().StringVar(&tlsFiles.CertFile, "tls-cert", tlsFiles.CertFile, "Certificate for mTLS with masters and agents (default: $"+mtls.EnvCert+")")
+	cmd.PersistentFlags().StringVar(&tlsFiles.KeyFile, "tls-key", tlsFiles.KeyFile, "Key of the mTLS certificate (default: $"+mtls.EnvKey+")")
+	cmd.PersistentFlags().StringVar(&tlsFiles.CAFile, "tls-ca", tlsFiles.CAFile, "CA that signs the certificates of masters and agents (default: $"+mtls.EnvCA+")")
+	cobra.OnInitialize(func() {
+		output.Setup(noANSI)
+		if nonInteractive {
+			taskrunner.SetNonInteractive(true)
+		}
+		if err := mtls.Configure(tlsFiles); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	})
+
 	return cmd
 }
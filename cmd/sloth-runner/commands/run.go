@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/pterm/pterm"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/handlers"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
 	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
 	coremodules "github.com/chalkan3-sloth/sloth-runner/internal/modules/core"
 )
@@ -29,8 +31,37 @@ A stack name is REQUIRED for all executions to track state and history.
 Tasks can be executed locally or remotely via SSH profiles.
 When using --ssh, tasks will be executed on the remote host.
 
+With --local, every task runs in this process regardless of delegate_to,
+no master or agent is contacted, and state goes to a throwaway database
+that is removed when the run ends. Use it to iterate on a workflow before
+pointing it at real infrastructure.
+
 You can use a saved sloth file with --sloth <name> instead of --file.
-If --sloth is specified, --file will be ignored.`,
+If --sloth is specified, --file will be ignored.
+
+A file may define several named workflows; --workflow <name> runs only
+that one. 'sloth-runner workflow list --file <file>' lists them.
+--task <name> runs only that task and the tasks it depends on, in the
+workflow that defines it.
+
+With --dry-run, tasks run in check mode: module calls such as pkg.install,
+file_ops.lineinfile or systemd.restart report what they would change,
+with diffs of the files they would write, without touching the system.
+Calls that can't tell what they would change are skipped and listed.
+Delegated tasks aren't sent to their agents and the stack isn't updated.
+
+Runs keep a journal of their tasks in <data-dir>/run-journal while they
+go. When the master restarts, it finds the runs it started that were
+interrupted, asks agents what became of the tasks sent to them, and
+resumes the scheduled ones. --resume <run-id> resumes an interrupted run
+by hand: the tasks it completed aren't run again, and their outputs are
+given to the tasks depending on them.
+
+A run locks its stack while it goes, so a second run against the same
+stack fails at once, or waits for the lock with --lock-timeout <duration>.
+The lock expires a few minutes after a crashed run stopped renewing it;
+'sloth-runner stack lock list' shows the locks held and 'sloth-runner
+stack lock release <stack>' releases one.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Extract flags
@@ -44,6 +75,33 @@ If --sloth is specified, --file will be ignored.`,
 			delegateToHosts, _ := cmd.Flags().GetStringArray("delegate-to")
 			sshProfile, _ := cmd.Flags().GetString("ssh")
 			sshPasswordStdin, _ := cmd.Flags().GetBool("ssh-password-stdin")
+			flakyPolicy, _ := cmd.Flags().GetString("flaky-policy")
+			policyFile, _ := cmd.Flags().GetString("policy")
+			approve, _ := cmd.Flags().GetStringArray("approve")
+			local, _ := cmd.Flags().GetBool("local")
+			overrideWindow, _ := cmd.Flags().GetString("override-window")
+			overrideBudget, _ := cmd.Flags().GetString("override-budget")
+			askSudoPass, _ := cmd.Flags().GetBool("ask-sudo-pass")
+			workflowName, _ := cmd.Flags().GetString("workflow")
+			tasks, _ := cmd.Flags().GetStringArray("task")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			resume, _ := cmd.Flags().GetString("resume")
+			schedule, _ := cmd.Flags().GetString("schedule")
+			lockTimeout, _ := cmd.Flags().GetDuration("lock-timeout")
+
+			if cmd.Flags().Changed("override-window") && strings.TrimSpace(overrideWindow) == "" {
+				return fmt.Errorf("--override-window requires a reason")
+			}
+			if cmd.Flags().Changed("override-budget") && strings.TrimSpace(overrideBudget) == "" {
+				return fmt.Errorf("--override-budget requires a reason")
+			}
+
+			if local && (len(delegateToHosts) > 0 || sshProfile != "") {
+				return fmt.Errorf("--local cannot be combined with --delegate-to or --ssh")
+			}
+			if resume != "" && (local || dryRun) {
+				return fmt.Errorf("--resume cannot be combined with --local or --dry-run")
+			}
 
 			// Configure log level based on debug flag
 			if debug {
@@ -83,17 +141,39 @@ If --sloth is specified, --file will be ignored.`,
 				}
 			}
 
+			// Local runs keep the real policies but write state to a
+			// throwaway data directory
+			if local {
+				if policyFile == "" {
+					if _, err := os.Stat(config.GetPolicyPath()); err == nil {
+						policyFile = config.GetPolicyPath()
+					}
+				}
+				cleanup, err := useThrowawayDataDir()
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+			}
+
 			// Get stack name from first argument
 			stackName := args[0]
 
-			// Generate unique run ID for this execution
+			// Generate unique run ID for this execution; a resumed run
+			// keeps the ID of the run it resumes
 			runID := uuid.New().String()
+			if resume != "" {
+				runID = resume
+			}
 			slog.Info("starting workflow execution", "stack", stackName, "run_id", runID)
 
 			// Initialize hook system for event dispatching
 			// This is needed for task.started, task.completed, and task.failed events
-			slog.Info("initializing hook system for event dispatching")
-			if err := hooks.InitializeGlobalDispatcher(); err != nil {
+			// Local runs have no hooks registered and dry runs must not
+			// fire them, so skip it
+			if local || dryRun {
+				slog.Debug("local or dry run: skipping hook system")
+			} else if err := hooks.InitializeGlobalDispatcher(); err != nil {
 				slog.Warn("failed to initialize hook system, events will not be dispatched", "error", err)
 			} else {
 				slog.Info("hook system initialized successfully")
@@ -141,6 +221,21 @@ If --sloth is specified, --file will be ignored.`,
 				Writer:           writer,
 				AgentRegistry:    ctx.AgentRegistry,
 				RunID:            runID,
+				FlakyPolicy:      flakyPolicy,
+				RunnerVersion:    ctx.Version,
+				PolicyFile:       policyFile,
+				Approve:          approve,
+				Local:            local,
+				OverrideWindow:   overrideWindow,
+				OverrideBudget:   overrideBudget,
+				AskSudoPass:      askSudoPass,
+				Workflow:         workflowName,
+				Tasks:            tasks,
+				DryRun:           dryRun,
+				Sloth:            slothName,
+				Schedule:         schedule,
+				Resume:           resume != "",
+				LockTimeout:      lockTimeout,
 			}
 
 			// Create and execute handler
@@ -152,14 +247,51 @@ If --sloth is specified, --file will be ignored.`,
 	// Add flags
 	cmd.Flags().StringP("file", "f", "", "Path to the Lua task file")
 	cmd.Flags().String("sloth", "", "Name of saved sloth file to use (takes precedence over --file)")
+	cmd.Flags().StringP("workflow", "w", "", "Run only this named workflow of the file (default: all of them)")
+	cmd.Flags().StringArrayP("task", "t", []string{}, "Run only this task and the tasks it depends on (can be used multiple times)")
 	cmd.Flags().StringP("values", "v", "", "Path to the values file")
 	cmd.Flags().Bool("yes", false, "Skip confirmation prompts")
 	cmd.Flags().Bool("interactive", false, "Run in interactive mode")
 	cmd.Flags().StringP("output", "o", "basic", "Output style: basic, enhanced, rich, modern, json")
-	cmd.Flags().Bool("debug", false, "Enable debug logging (shows technical details)")
+	cmd.Flags().Bool("debug", false, "Enable debug logging and pause at debug.breakpoint() calls")
 	cmd.Flags().StringArrayP("delegate-to", "d", []string{}, "Execute tasks on specified agents (can be used multiple times)")
 	cmd.Flags().String("ssh", "", "SSH profile name for remote execution")
 	cmd.Flags().Bool("ssh-password-stdin", false, "Read SSH password from stdin (must be followed by -)")
+	cmd.Flags().String("flaky-policy", "warn", "Flaky task policy: off, warn or quarantine (flaky task failures don't fail the run)")
+	cmd.Flags().String("policy", "", "Admission policy file (default: <data-dir>/policies.yaml if present)")
+	cmd.Flags().StringArray("approve", []string{}, "Approve a policy that requires approval (can be used multiple times)")
+	cmd.Flags().Bool("local", false, "Run every task in-process with a throwaway state database, without a master or agents")
+	cmd.Flags().String("override-window", "", "Run outside the stack's or workflow's maintenance windows, giving the reason recorded in the stack activity log")
+	cmd.Flags().String("override-budget", "", "Run over the stack's execution budget, giving the reason recorded in the stack activity log")
+	cmd.Flags().Bool("dry-run", false, "Plan the run: show what each task would change, with file diffs, without changing anything")
+	cmd.Flags().Bool("ask-sudo-pass", false, "Prompt for the sudo password of the agents tasks are delegated to (default: only agents in <data-dir>/sudo.yaml)")
+	cmd.Flags().String("resume", "", "Resume the interrupted run with this ID, without running again the tasks it completed")
+	cmd.Flags().Duration("lock-timeout", 0, "Wait this long for another run to release the stack's lock (default: fail at once)")
+	cmd.Flags().String("schedule", "", "Workflow schedule starting the run")
+	cmd.Flags().MarkHidden("schedule")
 
 	return cmd
 }
+
+// useThrowawayDataDir points the data directory at a temporary directory
+// so local runs never touch the real stack, hook or agent databases. The
+// returned function restores the previous data directory and removes the
+// temporary one.
+func useThrowawayDataDir() (func(), error) {
+	dir, err := os.MkdirTemp("", "sloth-local-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local data directory: %w", err)
+	}
+	previous, hadPrevious := os.LookupEnv("SLOTH_RUNNER_DATA_DIR")
+	os.Setenv("SLOTH_RUNNER_DATA_DIR", dir)
+	slog.Debug("local mode: using throwaway data directory", "dir", dir)
+
+	return func() {
+		if hadPrevious {
+			os.Setenv("SLOTH_RUNNER_DATA_DIR", previous)
+		} else {
+			os.Unsetenv("SLOTH_RUNNER_DATA_DIR")
+		}
+		os.RemoveAll(dir)
+	}, nil
+}
@@ -0,0 +1,112 @@
+//go:build cgo
+// +build cgo
+
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	schedulerInternal "github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewAddCommand creates the scheduler add command
+func NewAddCommand(ctx *commands.AppContext) *cobra.Command {
+	var slothRef, cronExpr, stackName, workflow string
+	var disabled bool
+
+	cmd := &cobra.Command{
+		Use:   "add [name]",
+		Short: "Schedule a workflow to run on a stack",
+		Long: `Schedule a workflow to run on a stack on a cron schedule. The master runs
+it with 'sloth-runner run <stack> --yes' when it's due and records the
+outcome, see 'scheduler runs'.
+
+--sloth takes the name of a saved sloth or the path of a .sloth file; files
+are run from their path on the master, so keep them there. The schedule is
+named <sloth>-<stack> unless a name is given.
+
+--cron takes a standard 5-field expression (minute hour day month weekday)
+in the master's time zone, a CRON_TZ= prefix for another time zone, or a
+descriptor such as @daily or @every 6h.`,
+		Example: `  sloth-runner scheduler add --sloth deploy.sloth --cron "0 3 * * *" --stack prod
+  sloth-runner scheduler add nightly-backup --sloth backup --cron "CRON_TZ=UTC 30 1 * * *" --stack db
+  sloth-runner scheduler add --sloth site.sloth --workflow certs --cron @weekly --stack web`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schedule := &schedulerInternal.WorkflowSchedule{
+				Cron:     cronExpr,
+				Stack:    stackName,
+				Workflow: workflow,
+				Enabled:  !disabled,
+			}
+
+			if info, err := os.Stat(slothRef); err == nil && !info.IsDir() {
+				path, err := filepath.Abs(slothRef)
+				if err != nil {
+					return err
+				}
+				schedule.File = path
+			} else {
+				slothService, err := services.NewSlothService()
+				if err != nil {
+					return fmt.Errorf("failed to initialize sloth service: %w", err)
+				}
+				defer slothService.Close()
+				if _, err := slothService.GetSloth(cmd.Context(), slothRef); err != nil {
+					return fmt.Errorf("%q is neither a workflow file nor a saved sloth: %w", slothRef, err)
+				}
+				schedule.Sloth = slothRef
+			}
+
+			schedule.Name = defaultScheduleName(slothRef, stackName)
+			if len(args) > 0 {
+				schedule.Name = args[0]
+			}
+
+			store, err := schedulerInternal.DefaultScheduleStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.Add(schedule); err != nil {
+				return err
+			}
+
+			pterm.Success.Printf("Scheduled %s to run %s on stack %s (%s)\n",
+				schedule.Source(), schedule.Cron, schedule.Stack, pterm.Bold.Sprint(schedule.Name))
+			if schedule.Enabled {
+				pterm.Info.Printf("Next run: %s, once the master picks up the schedule\n", formatTime(schedule.Next(time.Now())))
+			} else {
+				pterm.Info.Printf("Disabled, run 'sloth-runner scheduler enable %s' to start it\n", schedule.Name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&slothRef, "sloth", "", "Saved sloth name or .sloth file to run (required)")
+	cmd.Flags().StringVar(&cronExpr, "cron", "", "Cron expression, e.g. \"0 3 * * *\" (required)")
+	cmd.Flags().StringVar(&stackName, "stack", "", "Stack to run the workflow on (required)")
+	cmd.Flags().StringVarP(&workflow, "workflow", "w", "", "Run only this named workflow of the file")
+	cmd.Flags().BoolVar(&disabled, "disabled", false, "Add the schedule disabled")
+	cmd.MarkFlagRequired("sloth")
+	cmd.MarkFlagRequired("cron")
+	cmd.MarkFlagRequired("stack")
+
+	return cmd
+}
+
+// defaultScheduleName names a schedule after its sloth and stack, e.g.
+// deploy-prod for deploy.sloth on prod
+func defaultScheduleName(slothRef, stackName string) string {
+	base := strings.TrimSuffix(filepath.Base(slothRef), filepath.Ext(slothRef))
+	return base + "-" + stackName
+}
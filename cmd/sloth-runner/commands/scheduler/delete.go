@@ -5,18 +5,31 @@ package scheduler
 
 import (
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	schedulerInternal "github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
 // NewDeleteCommand creates the scheduler delete command
-// TODO: Extract logic from main.go schedulerDeleteCmd (lines ~548-573)
 func NewDeleteCommand(ctx *commands.AppContext) *cobra.Command {
 	return &cobra.Command{
-		Use:   "delete <stack-name>",
-		Short: "Delete scheduled execution for a stack",
-		Long:  `Delete scheduled execution configuration for a workflow stack.`,
+		Use:   "delete <name>",
+		Short: "Delete a workflow schedule",
+		Long: `Delete a workflow schedule. Its run history is kept for 'scheduler runs'
+and 'scheduler stats'.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement
+			store, err := schedulerInternal.DefaultScheduleStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.Delete(args[0]); err != nil {
+				return err
+			}
+
+			pterm.Success.Printf("Schedule %s deleted\n", args[0])
 			return nil
 		},
 	}
@@ -5,18 +5,31 @@ package scheduler
 
 import (
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	schedulerInternal "github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
 // NewDisableCommand creates the scheduler disable command
-// TODO: Extract logic from main.go schedulerDisableCmd (lines ~501-523)
 func NewDisableCommand(ctx *commands.AppContext) *cobra.Command {
 	return &cobra.Command{
-		Use:   "disable <stack-name>",
-		Short: "Disable scheduled execution for a stack",
-		Long:  `Disable scheduled execution for a workflow stack.`,
+		Use:   "disable <name>",
+		Short: "Pause a workflow schedule",
+		Long: `Pause a workflow schedule, keeping it and its run history. A run in
+progress isn't stopped; 'scheduler enable' resumes the schedule.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement
+			store, err := schedulerInternal.DefaultScheduleStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.SetEnabled(args[0], false); err != nil {
+				return err
+			}
+
+			pterm.Success.Printf("Schedule %s disabled\n", args[0])
 			return nil
 		},
 	}
@@ -4,19 +4,37 @@
 package scheduler
 
 import (
+	"time"
+
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	schedulerInternal "github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
 // NewEnableCommand creates the scheduler enable command
-// TODO: Extract logic from main.go schedulerEnableCmd (lines ~476-500)
 func NewEnableCommand(ctx *commands.AppContext) *cobra.Command {
 	return &cobra.Command{
-		Use:   "enable <stack-name> <cron-expression>",
-		Short: "Enable scheduled execution for a stack",
-		Long:  `Enable scheduled execution for a workflow stack using cron expression.`,
+		Use:   "enable <name>",
+		Short: "Resume a workflow schedule",
+		Long:  `Resume a disabled workflow schedule. The master picks it up within 30 seconds.`,
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement
+			store, err := schedulerInternal.DefaultScheduleStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.SetEnabled(args[0], true); err != nil {
+				return err
+			}
+			schedule, err := store.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			pterm.Success.Printf("Schedule %s enabled, next run: %s\n", schedule.Name, formatTime(schedule.Next(time.Now())))
 			return nil
 		},
 	}
@@ -4,20 +4,113 @@
 package scheduler
 
 import (
+	"encoding/json"
+	"os"
+	"time"
+
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	schedulerInternal "github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
+// scheduleListItem is a schedule with its next and last run
+type scheduleListItem struct {
+	*schedulerInternal.WorkflowSchedule
+	NextRun time.Time                      `json:"next_run,omitempty"`
+	LastRun *schedulerInternal.ScheduleRun `json:"last_run,omitempty"`
+}
+
 // NewListCommand creates the scheduler list command
-// TODO: Extract logic from main.go schedulerListCmd (lines ~524-547)
 func NewListCommand(ctx *commands.AppContext) *cobra.Command {
-	return &cobra.Command{
+	var outputFormat string
+
+	cmd := &cobra.Command{
 		Use:   "list",
-		Short: "List all scheduled workflows",
-		Long:  `List all workflows that have scheduled execution enabled.`,
+		Short: "List workflow schedules",
+		Long:  `List the workflow schedules with their next run and the outcome of their last one.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement
+			store, err := schedulerInternal.DefaultScheduleStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			schedules, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			stats, err := schedulerInternal.DefaultStatsStore()
+			if err != nil {
+				return err
+			}
+			defer stats.Close()
+
+			now := time.Now()
+			items := make([]scheduleListItem, 0, len(schedules))
+			for _, schedule := range schedules {
+				item := scheduleListItem{WorkflowSchedule: schedule}
+				if schedule.Enabled {
+					item.NextRun = schedule.Next(now)
+				}
+				runs, err := stats.Runs(schedule.Name, 1)
+				if err != nil {
+					return err
+				}
+				if len(runs) > 0 {
+					item.LastRun = &runs[0]
+				}
+				items = append(items, item)
+			}
+
+			if outputFormat == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(items)
+			}
+			printSchedules(items)
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table or json")
+
+	return cmd
+}
+
+func printSchedules(items []scheduleListItem) {
+	if len(items) == 0 {
+		pterm.Info.Println("No workflow schedules, add one with 'sloth-runner scheduler add'")
+		return
+	}
+
+	rows := [][]string{{"Name", "Cron", "Stack", "Workflow", "Status", "Next Run", "Last Run"}}
+	for _, item := range items {
+		status := pterm.Green("enabled")
+		if !item.Enabled {
+			status = pterm.Gray("disabled")
+		}
+		lastRun := "-"
+		if item.LastRun != nil {
+			lastRun = formatTime(item.LastRun.StartedAt) + " " + formatOutcome(*item.LastRun)
+		}
+		rows = append(rows, []string{
+			item.Name,
+			item.Cron,
+			item.Stack,
+			item.Source(),
+			status,
+			formatTime(item.NextRun),
+			lastRun,
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+}
+
+func formatOutcome(run schedulerInternal.ScheduleRun) string {
+	if run.Success {
+		return pterm.Green("succeeded")
+	}
+	return pterm.Red("failed")
 }
@@ -0,0 +1,76 @@
+//go:build cgo
+// +build cgo
+
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	schedulerInternal "github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewRunsCommand creates the scheduler runs command
+func NewRunsCommand(ctx *commands.AppContext) *cobra.Command {
+	var outputFormat string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "runs <name>",
+		Short: "Show the run history of a workflow schedule",
+		Long: `Show the most recent runs of a workflow schedule, newest first, with how
+long they took and why failed runs failed.`,
+		Example: `  sloth-runner scheduler runs deploy-prod
+  sloth-runner scheduler runs deploy-prod --limit 100 --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit <= 0 {
+				return fmt.Errorf("--limit must be positive")
+			}
+
+			stats, err := schedulerInternal.DefaultStatsStore()
+			if err != nil {
+				return err
+			}
+			defer stats.Close()
+
+			runs, err := stats.Runs(args[0], limit)
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				if runs == nil {
+					runs = []schedulerInternal.ScheduleRun{}
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(runs)
+			}
+
+			if len(runs) == 0 {
+				pterm.Info.Printf("Schedule %s hasn't run yet\n", args[0])
+				return nil
+			}
+			rows := [][]string{{"Started", "Duration", "Status", "Cause"}}
+			for _, run := range runs {
+				rows = append(rows, []string{
+					formatTime(run.StartedAt),
+					formatDuration(run.Duration),
+					formatOutcome(run),
+					run.Cause,
+				})
+			}
+			return pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "Number of runs to show")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table or json")
+
+	return cmd
+}
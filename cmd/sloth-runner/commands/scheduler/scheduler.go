@@ -14,7 +14,11 @@ func NewSchedulerCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "scheduler",
 		Short: "Manage workflow scheduling",
-		Long:  `The scheduler command provides subcommands to manage scheduled workflow executions.`,
+		Long: `The scheduler command provides subcommands to manage scheduled workflow executions.
+
+Schedules are stored on the master, which runs each enabled schedule's
+workflow on its stack when its cron expression is due and records every
+run. Schedules added, changed or removed are picked up within 30 seconds.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
@@ -22,10 +26,13 @@ func NewSchedulerCommand(ctx *commands.AppContext) *cobra.Command {
 
 	// Add all scheduler subcommands
 	cmd.AddCommand(
+		NewAddCommand(ctx),
 		NewEnableCommand(ctx),
 		NewDisableCommand(ctx),
 		NewListCommand(ctx),
 		NewDeleteCommand(ctx),
+		NewRunsCommand(ctx),
+		NewStatsCommand(ctx),
 	)
 
 	return cmd
@@ -0,0 +1,153 @@
+//go:build cgo
+// +build cgo
+
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	schedulerInternal "github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewStatsCommand creates the scheduler stats command
+func NewStatsCommand(ctx *commands.AppContext) *cobra.Command {
+	var window string
+	var outputFormat string
+	var minSuccessRate float64
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show success rate and duration of scheduled workflows",
+		Long: `Show, for each schedule, its success rate, run duration percentiles and
+the cause of its last failure over a window of recent runs.
+
+With --min-success-rate the command fails when any schedule is below the
+threshold, so it can be used as a check by cron jobs or CI.
+
+Examples:
+  sloth-runner scheduler stats
+  sloth-runner scheduler stats --window 30d --output json
+  sloth-runner scheduler stats --min-success-rate 0.95`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			since, err := parseWindow(window)
+			if err != nil {
+				return err
+			}
+			if minSuccessRate < 0 || minSuccessRate > 1 {
+				return fmt.Errorf("--min-success-rate must be between 0 and 1")
+			}
+
+			store, err := schedulerInternal.DefaultStatsStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			stats, err := store.Stats(time.Now().Add(-since))
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(stats); err != nil {
+					return err
+				}
+			} else {
+				printStats(stats, window)
+			}
+
+			if cmd.Flags().Changed("min-success-rate") {
+				var below []string
+				for _, st := range stats {
+					if st.SuccessRate < minSuccessRate {
+						below = append(below, fmt.Sprintf("%s (%.1f%%)", st.Schedule, st.SuccessRate*100))
+					}
+				}
+				if len(below) > 0 {
+					return fmt.Errorf("success rate below %.1f%%: %s", minSuccessRate*100, strings.Join(below, ", "))
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&window, "window", "7d", "How far back to look (e.g. 24h, 7d, 30d)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table or json")
+	cmd.Flags().Float64Var(&minSuccessRate, "min-success-rate", 0, "Fail if a schedule's success rate is below this ratio (0-1)")
+
+	return cmd
+}
+
+func printStats(stats []schedulerInternal.ScheduleStats, window string) {
+	if len(stats) == 0 {
+		pterm.Info.Printf("No scheduled runs in the last %s\n", window)
+		return
+	}
+
+	rows := [][]string{{"Schedule", "Runs", "Success", "p50", "p90", "p99", "Last Run", "Last Failure"}}
+	for _, st := range stats {
+		rate := fmt.Sprintf("%.1f%%", st.SuccessRate*100)
+		if st.Failures > 0 {
+			rate = pterm.Yellow(rate)
+		} else {
+			rate = pterm.Green(rate)
+		}
+		rows = append(rows, []string{
+			st.Schedule,
+			strconv.Itoa(st.Runs),
+			rate,
+			formatDuration(st.P50),
+			formatDuration(st.P90),
+			formatDuration(st.P99),
+			formatTime(st.LastRun),
+			formatTime(st.LastFailure),
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(rows).Render()
+
+	for _, st := range stats {
+		if st.LastFailureCause != "" {
+			pterm.Printf("%s last failed: %s\n", pterm.Bold.Sprint(st.Schedule), st.LastFailureCause)
+		}
+	}
+}
+
+// parseWindow parses a Go duration, also accepting a number of days such as "30d"
+func parseWindow(window string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(window, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q (use e.g. 24h, 7d)", window)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q (use e.g. 24h, 7d)", window)
+	}
+	return d, nil
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -9,10 +9,11 @@ import (
 	"io"
 	"os"
 	"strings"
-	"syscall"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -119,7 +120,8 @@ Examples:
 
 // getPassword gets password from stdin or prompts user
 func getPassword(stdin bool) (string, error) {
-	if stdin {
+	// A terminal on stdin gets the prompt, so the password isn't echoed
+	if stdin && !term.IsTerminal(int(os.Stdin.Fd())) {
 		reader := bufio.NewReader(os.Stdin)
 		password, err := reader.ReadString('\n')
 		if err != nil && err != io.EOF {
@@ -129,27 +131,28 @@ func getPassword(stdin bool) (string, error) {
 	}
 
 	// Prompt for password
-	fmt.Print("Enter password to encrypt secrets: ")
-	passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Println() // New line after password input
+	var password string
+	err := taskrunner.Ask(nil, taskrunner.Prompt{Name: "password", Flag: "--password-stdin", Prompt: &survey.Password{
+		Message: "Enter password to encrypt secrets:",
+	}}, &password)
 	if err != nil {
-		return "", fmt.Errorf("failed to read password: %w", err)
+		return "", err
 	}
 
-	password := string(passwordBytes)
 	if password == "" {
 		return "", fmt.Errorf("password cannot be empty")
 	}
 
 	// Confirm password
-	fmt.Print("Confirm password: ")
-	confirmBytes, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Println()
+	var confirm string
+	err = taskrunner.Ask(nil, taskrunner.Prompt{Name: "password confirmation", Flag: "--password-stdin", Prompt: &survey.Password{
+		Message: "Confirm password:",
+	}}, &confirm)
 	if err != nil {
-		return "", fmt.Errorf("failed to read password: %w", err)
+		return "", err
 	}
 
-	if password != string(confirmBytes) {
+	if password != confirm {
 		return "", fmt.Errorf("passwords do not match")
 	}
 
@@ -158,14 +161,14 @@ func getPassword(stdin bool) (string, error) {
 
 // addSecretInteractive adds a secret interactively
 func addSecretInteractive(cmd *cobra.Command, service *services.SecretsService, stackID, name, password string, salt []byte) error {
-	fmt.Printf("Enter value for secret '%s': ", name)
-	valueBytes, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Println()
+	var value string
+	err := taskrunner.Ask(nil, taskrunner.Prompt{Name: "secret value", Flag: "--from-file", Prompt: &survey.Password{
+		Message: fmt.Sprintf("Enter value for secret '%s':", name),
+	}}, &value)
 	if err != nil {
-		return fmt.Errorf("failed to read secret value: %w", err)
+		return err
 	}
 
-	value := string(valueBytes)
 	if value == "" {
 		return fmt.Errorf("secret value cannot be empty")
 	}
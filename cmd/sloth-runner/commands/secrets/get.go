@@ -9,10 +9,11 @@ import (
 	"io"
 	"os"
 	"strings"
-	"syscall"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -96,7 +97,8 @@ Examples:
 
 // getDecryptPassword gets password for decryption
 func getDecryptPassword(stdin bool) (string, error) {
-	if stdin {
+	// A terminal on stdin gets the prompt, so the password isn't echoed
+	if stdin && !term.IsTerminal(int(os.Stdin.Fd())) {
 		reader := bufio.NewReader(os.Stdin)
 		password, err := reader.ReadString('\n')
 		if err != nil && err != io.EOF {
@@ -106,14 +108,14 @@ func getDecryptPassword(stdin bool) (string, error) {
 	}
 
 	// Prompt for password
-	fmt.Print("Enter password to decrypt secret: ")
-	passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Println() // New line after password input
+	var password string
+	err := taskrunner.Ask(nil, taskrunner.Prompt{Name: "password", Flag: "--password-stdin", Prompt: &survey.Password{
+		Message: "Enter password to decrypt secret:",
+	}}, &password)
 	if err != nil {
-		return "", fmt.Errorf("failed to read password: %w", err)
+		return "", err
 	}
 
-	password := string(passwordBytes)
 	if password == "" {
 		return "", fmt.Errorf("password cannot be empty")
 	}
@@ -5,9 +5,12 @@ package sloth
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	slothpkg "github.com/chalkan3-sloth/sloth-runner/internal/sloth"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -43,6 +46,10 @@ func NewGetCommand(ctx *commands.AppContext) *cobra.Command {
 			pterm.Info.Printf("ID: %s\n", pterm.Gray(sloth.ID))
 			pterm.Info.Printf("Name: %s\n", pterm.Cyan(sloth.Name))
 			pterm.Info.Printf("Description: %s\n", sloth.Description)
+			if sloth.Version != "" {
+				pterm.Info.Printf("Version: %s\n", sloth.Version)
+			}
+			printFrontMatter(sloth.Content)
 			pterm.Info.Printf("File Path: %s\n", pterm.Gray(sloth.FilePath))
 
 			activeStatus := "No"
@@ -83,3 +90,51 @@ func NewGetCommand(ctx *commands.AppContext) *cobra.Command {
 
 	return cmd
 }
+
+// printFrontMatter shows the requirements and params declared in a v2
+// sloth file's front-matter
+func printFrontMatter(content string) {
+	meta, _, err := slothpkg.ParseFrontMatter([]byte(content))
+	if err != nil {
+		pterm.Warning.Printf("Front-matter: %v\n", err)
+		return
+	}
+	if meta.Format != slothpkg.FormatV2 {
+		return
+	}
+
+	if meta.MinRunnerVersion != "" {
+		pterm.Info.Printf("Min Runner Version: %s\n", meta.MinRunnerVersion)
+	}
+	if len(meta.Modules) > 0 {
+		pterm.Info.Printf("Modules: %s\n", strings.Join(meta.Modules, ", "))
+	}
+	if len(meta.Params) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(meta.Params))
+	for name := range meta.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pterm.Info.Println("Params:")
+	for _, name := range names {
+		p := meta.Params[name]
+		line := "  " + pterm.Cyan(name)
+		if p.Type != "" {
+			line += " (" + p.Type + ")"
+		}
+		if p.Required {
+			line += pterm.Yellow(" required")
+		}
+		if p.Default != nil {
+			line += fmt.Sprintf(" default=%v", p.Default)
+		}
+		if p.Description != "" {
+			line += pterm.Gray(" - " + p.Description)
+		}
+		pterm.Println(line)
+	}
+}
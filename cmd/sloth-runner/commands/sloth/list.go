@@ -51,7 +51,7 @@ func NewListCommand(ctx *commands.AppContext) *cobra.Command {
 
 			// Build table data
 			tableData := pterm.TableData{
-				{"Name", "Description", "Active", "Usage", "Last Used", "Created"},
+				{"Name", "Version", "Description", "Active", "Usage", "Last Used", "Created"},
 			}
 
 			for _, s := range sloths {
@@ -65,6 +65,11 @@ func NewListCommand(ctx *commands.AppContext) *cobra.Command {
 					lastUsed = s.LastUsedAt.Format("2006-01-02 15:04")
 				}
 
+				version := s.Version
+				if version == "" {
+					version = "-"
+				}
+
 				description := s.Description
 				if description == "" {
 					description = "-"
@@ -72,6 +77,7 @@ func NewListCommand(ctx *commands.AppContext) *cobra.Command {
 
 				tableData = append(tableData, []string{
 					s.Name,
+					version,
 					description,
 					activeStatus,
 					pterm.Sprintf("%d", s.UsageCount),
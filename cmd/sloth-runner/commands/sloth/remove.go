@@ -8,6 +8,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -32,9 +33,10 @@ func NewRemoveCommand(ctx *commands.AppContext) *cobra.Command {
 
 			// Confirm if not forced
 			if !force {
-				result, _ := pterm.DefaultInteractiveConfirm.
-					WithDefaultText(fmt.Sprintf("Are you sure you want to remove sloth '%s'?", name)).
-					Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you sure you want to remove sloth '%s'?", name), "--force")
+				if err != nil {
+					return err
+				}
 
 				if !result {
 					pterm.Info.Println("Operation cancelled")
@@ -85,9 +87,10 @@ func NewDeleteCommand(ctx *commands.AppContext) *cobra.Command {
 
 			// Confirm if not forced
 			if !force {
-				result, _ := pterm.DefaultInteractiveConfirm.
-					WithDefaultText(fmt.Sprintf("Are you sure you want to delete sloth '%s'?", name)).
-					Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you sure you want to delete sloth '%s'?", name), "--force")
+				if err != nil {
+					return err
+				}
 
 				if !result {
 					pterm.Info.Println("Operation cancelled")
@@ -0,0 +1,200 @@
+//go:build cgo
+// +build cgo
+
+package stack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	stackpkg "github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewBudgetCommand creates the execution budget command
+func NewBudgetCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "budget",
+		Short: "Manage stack execution budgets",
+		Long: `Cap how much a stack may run: runs per day, task-minutes per day (the sum
+of the durations of every task run) and agents touched by a single run.
+Runs exceeding the budget, scheduled runs included, are refused unless
+--override-budget gives a reason, which is recorded in the stack activity
+log. Days start at local midnight.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		NewBudgetSetCommand(ctx),
+		NewBudgetStatusCommand(ctx),
+		NewBudgetClearCommand(ctx),
+	)
+
+	return cmd
+}
+
+// NewBudgetSetCommand sets the execution budget of a stack
+func NewBudgetSetCommand(ctx *commands.AppContext) *cobra.Command {
+	var budget stackpkg.Budget
+
+	cmd := &cobra.Command{
+		Use:   "set <stack-name>",
+		Short: "Set the execution budget of a stack",
+		Long:  `Sets the limits of a stack's budget. Limits not given keep their value; 0 removes a limit.`,
+		Example: `  sloth-runner stack budget set production --max-runs-per-day 24 --max-task-minutes 600
+  sloth-runner stack budget set production --max-agents-per-run 10`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+			if budget.MaxRunsPerDay < 0 || budget.MaxTaskMinutes < 0 || budget.MaxAgentsPerRun < 0 {
+				return fmt.Errorf("budget limits can't be negative")
+			}
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			current, err := stackService.GetBudget(stackName)
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("max-runs-per-day") {
+				current.MaxRunsPerDay = budget.MaxRunsPerDay
+			}
+			if cmd.Flags().Changed("max-task-minutes") {
+				current.MaxTaskMinutes = budget.MaxTaskMinutes
+			}
+			if cmd.Flags().Changed("max-agents-per-run") {
+				current.MaxAgentsPerRun = budget.MaxAgentsPerRun
+			}
+
+			if err := stackService.SetBudget(stackName, current); err != nil {
+				return fmt.Errorf("failed to set the budget of stack '%s': %w", stackName, err)
+			}
+
+			pterm.Success.Printf("Execution budget set for stack '%s'\n", stackName)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&budget.MaxRunsPerDay, "max-runs-per-day", 0, "Runs allowed per day")
+	cmd.Flags().Float64Var(&budget.MaxTaskMinutes, "max-task-minutes", 0, "Task-minutes allowed per day")
+	cmd.Flags().IntVar(&budget.MaxAgentsPerRun, "max-agents-per-run", 0, "Agents a single run may touch")
+
+	return cmd
+}
+
+// NewBudgetStatusCommand shows the budget of a stack and today's usage
+func NewBudgetStatusCommand(ctx *commands.AppContext) *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "status <stack-name>",
+		Short: "Show the execution budget of a stack and today's usage",
+		Long:  `Displays the limits of a stack's budget against what its runs used today.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if _, err := stackService.GetStackByName(stackName); err != nil {
+				return fmt.Errorf("stack '%s' not found: %w", stackName, err)
+			}
+			budget, err := stackService.GetBudget(stackName)
+			if err != nil {
+				return err
+			}
+			usage, err := stackService.GetBudgetUsage(stackName, stackpkg.BudgetDay(time.Now()))
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(map[string]interface{}{
+					"stack":    stackName,
+					"budget":   budget,
+					"usage":    usage,
+					"exceeded": budget.Violations(usage, 0),
+				})
+			}
+
+			pterm.DefaultHeader.WithFullWidth().Printfln("Execution Budget: %s", stackName)
+			fmt.Println()
+
+			limit := func(n float64) string {
+				if n == 0 {
+					return "unlimited"
+				}
+				return stackpkg.FormatMinutes(n)
+			}
+			data := pterm.TableData{
+				{"Limit", "Used today", "Allowed"},
+				{"Runs per day", strconv.Itoa(usage.Runs), limit(float64(budget.MaxRunsPerDay))},
+				{"Task-minutes per day", stackpkg.FormatMinutes(usage.TaskMinutes), limit(budget.MaxTaskMinutes)},
+				{"Agents per run", fmt.Sprintf("%d (most in a run)", usage.MaxAgents), limit(float64(budget.MaxAgentsPerRun))},
+			}
+			pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+			fmt.Println()
+
+			switch violations := budget.Violations(usage, 0); {
+			case budget.IsZero():
+				pterm.Info.Println("No execution budget, the stack may run without limits")
+			case len(violations) > 0:
+				for _, v := range violations {
+					pterm.Warning.Println(v)
+				}
+				pterm.Warning.Println("Further runs today are refused unless --override-budget gives a reason")
+			default:
+				pterm.Success.Println("✓ Within budget")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table or json")
+
+	return cmd
+}
+
+// NewBudgetClearCommand removes the execution budget of a stack
+func NewBudgetClearCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear <stack-name>",
+		Short: "Remove the execution budget of a stack",
+		Long:  `Removes every limit of a stack's budget so it may run without limits.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if err := stackService.SetBudget(stackName, stackpkg.Budget{}); err != nil {
+				return fmt.Errorf("failed to clear the budget of stack '%s': %w", stackName, err)
+			}
+
+			pterm.Success.Printf("Execution budget cleared for stack '%s'\n", stackName)
+			return nil
+		},
+	}
+}
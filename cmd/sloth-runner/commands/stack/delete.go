@@ -5,54 +5,234 @@ package stack
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/runlog"
 	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
+// deleteOptions configures stack deletion
+type deleteOptions struct {
+	force       bool
+	cleanup     bool
+	dryRun      bool
+	confirmEach bool
+	skip        []string
+}
+
 // NewDeleteCommand creates the stack delete command
 func NewDeleteCommand(ctx *commands.AppContext) *cobra.Command {
+	opts := &deleteOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "delete <stack-name>",
 		Short: "Delete a workflow stack",
-		Long:  `Delete a workflow stack and all its execution history.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Delete a workflow stack and all its execution history.
+
+With --cleanup the resources recorded in the stack (packages, files, users,
+containers, services...) are removed first, using the reverse operation of
+each resource. See 'stack destroy'.`,
+		Example: `  sloth-runner stack delete dev
+  sloth-runner stack delete dev --cleanup --dry-run
+  sloth-runner stack delete dev --cleanup --skip package:nginx --skip user`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			stackName := args[0]
-			force, _ := cmd.Flags().GetBool("force")
+			return deleteStack(args[0], opts)
+		},
+	}
 
-			stackManager, err := stack.NewStackManager("")
-			if err != nil {
-				return fmt.Errorf("failed to initialize stack manager: %w", err)
-			}
-			defer stackManager.Close()
+	addDeleteFlags(cmd, opts)
+	cmd.Flags().BoolVar(&opts.cleanup, "cleanup", false, "Remove the stack's resources before deleting it")
+	return cmd
+}
 
-			stackState, err := stackManager.GetStackByName(stackName)
-			if err != nil {
-				return fmt.Errorf("failed to get stack: %w", err)
-			}
+// NewDestroyCommand creates the stack destroy command
+func NewDestroyCommand(ctx *commands.AppContext) *cobra.Command {
+	opts := &deleteOptions{cleanup: true}
 
-			if !force {
-				pterm.Warning.Printf("This will permanently delete stack '%s' and all its execution history.\n", stackName)
-				confirm := pterm.DefaultInteractiveConfirm.WithDefaultValue(false)
-				result, _ := confirm.Show("Are you sure?")
-				if !result {
-					pterm.Info.Println("Operation cancelled.")
-					return nil
-				}
-			}
+	cmd := &cobra.Command{
+		Use:   "destroy <stack-name>",
+		Short: "Remove a stack's resources and delete the stack",
+		Long: `Compute a cleanup plan from the resources recorded in the stack and run
+the reverse operation of each one, newest first and dependents before what
+they depend on:
 
-			if err := stackManager.DeleteStack(stackState.ID); err != nil {
-				return fmt.Errorf("failed to delete stack: %w", err)
-			}
+  package     removed with the system package manager
+  file        deleted (directories recursively)
+  user/group  deleted with userdel/groupdel
+  container   removed with docker or podman
+  service     stopped and disabled
 
-			pterm.Success.Printf("Stack '%s' deleted successfully.\n", stackName)
-			return nil
+A resource can define its own reverse operation in a destroy_command
+property. Resources without one are only dropped from the state.
+
+Each removed resource is dropped from the state. The stack itself is only
+deleted when every action succeeded or was skipped.`,
+		Example: `  # Review the plan
+  sloth-runner stack destroy dev --dry-run
+
+  # Keep nginx and every user, confirm each remaining resource
+  sloth-runner stack destroy dev --skip package:nginx --skip user --confirm-each`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deleteStack(args[0], opts)
 		},
 	}
 
-	cmd.Flags().Bool("force", false, "Skip confirmation prompt")
+	addDeleteFlags(cmd, opts)
 	return cmd
 }
+
+func addDeleteFlags(cmd *cobra.Command, opts *deleteOptions) {
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Skip confirmation prompt")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Show the cleanup plan without changing anything")
+	cmd.Flags().BoolVar(&opts.confirmEach, "confirm-each", false, "Ask before removing each resource")
+	cmd.Flags().StringArrayVar(&opts.skip, "skip", nil, "Keep a resource, as type:name or a whole type (repeatable)")
+}
+
+func deleteStack(stackName string, opts *deleteOptions) error {
+	stackManager, err := stack.NewStackManager("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize stack manager: %w", err)
+	}
+	defer stackManager.Close()
+
+	stackState, err := stackManager.GetStackByName(stackName)
+	if err != nil {
+		return fmt.Errorf("failed to get stack: %w", err)
+	}
+
+	var plan *stack.CleanupPlan
+	if opts.cleanup {
+		resources, err := stackManager.ListResources(stackState.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list resources: %w", err)
+		}
+		plan = stack.PlanCleanup(stackName, resources)
+		plan.Skip(opts.skip)
+		printCleanupPlan(plan)
+	}
+
+	if opts.dryRun {
+		pterm.Info.Printf("Dry run: stack '%s' was not deleted.\n", stackName)
+		return nil
+	}
+
+	if !opts.force {
+		if plan != nil {
+			pterm.Warning.Printf("This will remove the resources above and permanently delete stack '%s' and all its execution history.\n", stackName)
+		} else {
+			pterm.Warning.Printf("This will permanently delete stack '%s' and all its execution history.\n", stackName)
+		}
+		result, err := taskrunner.Confirm("Are you sure?", "--force")
+		if err != nil {
+			return err
+		}
+		if !result {
+			pterm.Info.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	if plan != nil {
+		if opts.confirmEach {
+			if err := confirmCleanupActions(plan); err != nil {
+				return err
+			}
+		}
+
+		plan.Execute(func(r *stack.Resource) error {
+			return stackManager.DeleteResource(r.ID)
+		})
+		printCleanupResult(plan)
+
+		if failed := plan.Failed(); len(failed) > 0 {
+			return fmt.Errorf("%d resource(s) could not be removed, stack '%s' was kept; fix them or skip them with --skip and run again", len(failed), stackName)
+		}
+	}
+
+	if err := stackManager.DeleteStack(stackState.ID); err != nil {
+		return fmt.Errorf("failed to delete stack: %w", err)
+	}
+
+	// Stored run logs belong to the stack, drop them too
+	if store, err := runlog.NewStore(config.GetRunLogDBPath()); err == nil {
+		if _, err := store.DeleteStack(stackName); err != nil {
+			pterm.Warning.Printf("Failed to delete run logs: %v\n", err)
+		}
+		store.Close()
+	}
+
+	pterm.Success.Printf("Stack '%s' deleted successfully.\n", stackName)
+	return nil
+}
+
+func printCleanupPlan(plan *stack.CleanupPlan) {
+	pterm.DefaultSection.Printf("Cleanup plan for stack '%s'", plan.StackName)
+
+	if len(plan.Actions) == 0 {
+		pterm.Info.Println("The stack has no recorded resources.")
+		return
+	}
+
+	data := pterm.TableData{{"#", "RESOURCE", "ACTION", "COMMAND"}}
+	for i, a := range plan.Actions {
+		command := pterm.Gray("state only")
+		if !a.StateOnly() {
+			command = strings.Join(a.Command, " ")
+		}
+		action := a.Description
+		if a.Status == stack.CleanupSkipped {
+			action = pterm.Yellow("skip")
+			command = ""
+		}
+		data = append(data, []string{fmt.Sprintf("%d", i+1), a.Key(), action, command})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+	fmt.Println()
+}
+
+func confirmCleanupActions(plan *stack.CleanupPlan) error {
+	inputs := taskrunner.NewInputCollector(nil)
+	answers := make(map[*stack.CleanupAction]*bool)
+	for _, a := range plan.Actions {
+		if a.Status != stack.CleanupPending {
+			continue
+		}
+		result := true
+		answers[a] = &result
+		inputs.Ask(taskrunner.Prompt{Name: a.Key(), Flag: "--skip instead of --confirm-each", Prompt: &survey.Confirm{
+			Message: fmt.Sprintf("%s: %s?", a.Key(), a.Description),
+			Default: true,
+		}}, &result)
+	}
+	if err := inputs.Err(); err != nil {
+		return err
+	}
+	for a, result := range answers {
+		if !*result {
+			a.Status = stack.CleanupSkipped
+		}
+	}
+	return nil
+}
+
+func printCleanupResult(plan *stack.CleanupPlan) {
+	for _, a := range plan.Actions {
+		switch a.Status {
+		case stack.CleanupDone:
+			pterm.Success.Printf("%s: %s\n", a.Key(), a.Description)
+		case stack.CleanupSkipped:
+			pterm.Info.Printf("%s: skipped\n", a.Key())
+		case stack.CleanupFailed:
+			pterm.Error.Printf("%s: %s\n", a.Key(), a.Error)
+		}
+	}
+	fmt.Println()
+}
@@ -5,9 +5,13 @@ package stack
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/handlers"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/google/uuid"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -37,15 +41,31 @@ func NewDriftDetectCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "detect <stack-name>",
 		Short: "Detect state drift for a stack",
-		Long:  `Scans the stack to detect if actual state differs from declared state.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Checks the stack's workflow against the live systems and reports the
+resources that diverge from what the stack applied.
+
+The workflow runs in check mode: local tasks run in-process and delegated
+tasks are sent to their agents, whose modules read the current state of
+each resource and report what they would change, without changing it.
+Every change is drift. Drifted resources are recorded in the stack with
+the drift state ('stack drift show' lists them) and are resolved by the
+next check that finds them back in line.
+
+Calls to modules that can't read the current state, and tasks delegated to
+agents too old to check tasks, are listed as not checked.
+
+The workflow is the stack's workflow file unless --file or --sloth names
+another one.`,
+		Example: `  sloth-runner stack drift detect production
+  sloth-runner stack drift detect production --sloth deploy --values prod.yaml
+  sloth-runner stack drift detect production --output json`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			stackName := args[0]
-
-			tracker, err := services.GetGlobalStateTracker()
-			if err != nil {
-				return err
-			}
+			filePath, _ := cmd.Flags().GetString("file")
+			slothName, _ := cmd.Flags().GetString("sloth")
+			values, _ := cmd.Flags().GetString("values")
+			outputStyle, _ := cmd.Flags().GetString("output")
 
 			stackService, err := services.NewStackService()
 			if err != nil {
@@ -53,39 +73,72 @@ func NewDriftDetectCommand(ctx *commands.AppContext) *cobra.Command {
 			}
 			defer stackService.Close()
 
-			stack, err := stackService.GetStackByName(stackName)
+			st, err := stackService.GetStackByName(stackName)
 			if err != nil {
 				return fmt.Errorf("stack '%s' not found: %w", stackName, err)
 			}
 
-			spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Detecting drift for stack '%s'...", stackName))
+			if slothName != "" {
+				slothService, err := services.NewSlothService()
+				if err != nil {
+					return fmt.Errorf("failed to initialize sloth service: %w", err)
+				}
+				defer slothService.Close()
 
-			hasDrift, drifts, err := tracker.DetectDriftWithEvent(stack.ID)
-			if err != nil {
-				spinner.Fail(fmt.Sprintf("Failed to detect drift: %v", err))
-				return err
+				content, err := slothService.UseSloth(cmd.Context(), slothName)
+				if err != nil {
+					return fmt.Errorf("failed to use sloth '%s': %w", slothName, err)
+				}
+				tmpFile, err := slothService.WriteContentToFile(content)
+				if err != nil {
+					return fmt.Errorf("failed to create temp file from sloth: %w", err)
+				}
+				defer os.Remove(tmpFile)
+				filePath = tmpFile
 			}
-
-			if !hasDrift {
-				spinner.Success("No drift detected - state is in sync")
-				return nil
+			if filePath == "" {
+				filePath = st.WorkflowFile
+				if _, err := os.Stat(filePath); filePath == "" || err != nil {
+					return fmt.Errorf("the workflow file of stack '%s' (%s) is gone: name it with --file or --sloth", stackName, filePath)
+				}
 			}
 
-			spinner.Warning(fmt.Sprintf("Drift detected: %d resource(s) have drifted", len(drifts)))
-			fmt.Println()
-
-			pterm.DefaultSection.Println("Drifted Resources")
-			for _, drift := range drifts {
-				pterm.Warning.Printf("  • %s\n", drift)
+			writer := cmd.OutOrStdout()
+			if ctx.TestMode && ctx.OutputWriter != nil {
+				writer = ctx.OutputWriter
 			}
 
-			fmt.Println()
-			pterm.Info.Println("Use 'sloth-runner stack drift fix' to automatically repair drift")
+			handler := handlers.NewRunHandler(stackService, &handlers.RunConfig{
+				StackName:     stackName,
+				FilePath:      filePath,
+				Values:        values,
+				OutputStyle:   outputStyle,
+				YesFlag:       true,
+				Context:       cmd.Context(),
+				Writer:        writer,
+				AgentRegistry: ctx.AgentRegistry,
+				RunID:         uuid.New().String(),
+				RunnerVersion: ctx.Version,
+				DryRun:        true,
+				Drift:         true,
+			})
+			if err := handler.Execute(); err != nil {
+				return err
+			}
 
+			// Raise the drift event for what was found
+			if tracker, err := services.GetGlobalStateTracker(); err == nil {
+				tracker.DetectDriftWithEvent(st.ID)
+			}
 			return nil
 		},
 	}
 
+	cmd.Flags().StringP("file", "f", "", "Workflow to check (default: the stack's workflow file)")
+	cmd.Flags().String("sloth", "", "Name of saved sloth file to check (takes precedence over --file)")
+	cmd.Flags().StringP("values", "v", "", "Path to values file")
+	cmd.Flags().StringP("output", "o", "basic", "Output style: basic or json")
+
 	return cmd
 }
 
@@ -168,9 +221,10 @@ func NewDriftFixCommand(ctx *commands.AppContext) *cobra.Command {
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 			if !force && !dryRun {
-				result, _ := pterm.DefaultInteractiveConfirm.
-					WithDefaultText(fmt.Sprintf("Are you sure you want to fix drift for stack '%s'?", stackName)).
-					Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you sure you want to fix drift for stack '%s'?", stackName), "--force")
+				if err != nil {
+					return err
+				}
 
 				if !result {
 					pterm.Info.Println("Drift fix cancelled")
@@ -0,0 +1,265 @@
+//go:build cgo
+// +build cgo
+
+package stack
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	agentInternal "github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// maxImportedFileSize is the size above which stack import leaves a file
+// out of the workflow
+const maxImportedFileSize = 64 * 1024
+
+// NewImportCommand creates the stack import command
+func NewImportCommand(ctx *commands.AppContext) *cobra.Command {
+	var sel stack.ImportSelection
+	var agentName, output, masterAddr string
+
+	cmd := &cobra.Command{
+		Use:   "import <stack-name>",
+		Short: "Codify an existing server as a workflow and stack",
+		Long: `Scan an agent and write a workflow keeping it as it is now, along with
+the stack state recording that reality.
+
+Packages, services and users come from the facts the agent reports to the
+master and are picked with name patterns (* and ? work like in shell
+globs). Files are read from the agent and written into the workflow with
+their owner and mode; binary files and files over 64KiB are left out.
+
+The workflow is a starting point: review it, split it and parameterize it
+before running it against other hosts.`,
+		Example: `  sloth-runner stack import web-1 --agent web-1 --packages 'nginx*' --services nginx
+  sloth-runner stack import db --agent db-1 --packages 'postgresql*' --users postgres \
+    --file /etc/postgresql/16/main/postgresql.conf -o db.sloth`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+			if agentName == "" {
+				return fmt.Errorf("--agent is required")
+			}
+			if len(sel.Packages)+len(sel.Services)+len(sel.Users)+len(sel.Files) == 0 {
+				return fmt.Errorf("nothing to import: pass --packages, --services, --users or --file")
+			}
+			if err := stack.ValidateImportSelection(sel); err != nil {
+				return err
+			}
+			if output == "" {
+				output = stackName + ".sloth"
+			}
+			if _, err := os.Stat(output); err == nil {
+				return fmt.Errorf("%s already exists", output)
+			}
+			if masterAddr == "" {
+				masterAddr = config.GetMasterAddress()
+			}
+			if masterAddr == "" {
+				masterAddr = "localhost:50053"
+			}
+
+			host, err := scanAgent(cmd.Context(), masterAddr, agentName, sel)
+			if err != nil {
+				return err
+			}
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return fmt.Errorf("failed to initialize stack service: %w", err)
+			}
+			defer stackService.Close()
+
+			workflow := stack.GenerateImportWorkflow(stackName, host)
+			if err := os.WriteFile(output, []byte(workflow), 0644); err != nil {
+				return fmt.Errorf("failed to write workflow: %w", err)
+			}
+
+			stackID, err := stackService.GetOrCreateStack(stackName, stackName, output)
+			if err != nil {
+				return err
+			}
+			resources := stack.ImportedResources(stackID, host)
+			if err := saveImportedResources(stackService.GetManager(), resources); err != nil {
+				return err
+			}
+
+			pterm.Success.Printf("Imported %s into stack '%s'\n", agentName, stackName)
+			pterm.Printf("  Packages: %d\n", len(host.Packages))
+			pterm.Printf("  Services: %d\n", len(host.Services))
+			pterm.Printf("  Users:    %d\n", len(host.Users))
+			pterm.Printf("  Files:    %d\n", len(host.Files))
+			pterm.Printf("  Workflow: %s\n", output)
+			pterm.Printf("\nReview the workflow, then run it with:\n")
+			pterm.Printf("  sloth-runner run %s --file %s\n", stackName, output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&agentName, "agent", "", "Agent to scan")
+	cmd.Flags().StringSliceVar(&sel.Packages, "packages", nil, "Installed packages to import (name patterns)")
+	cmd.Flags().StringSliceVar(&sel.Services, "services", nil, "Services to import (name patterns)")
+	cmd.Flags().StringSliceVar(&sel.Users, "users", nil, "Users to import (name patterns)")
+	cmd.Flags().StringArrayVar(&sel.Files, "file", nil, "File to import (repeatable)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Workflow file to write (default <stack-name>.sloth)")
+	cmd.Flags().StringVar(&masterAddr, "master", "", "Master server address (default from config)")
+
+	return cmd
+}
+
+// scanAgent collects the state of the agent picked by sel
+func scanAgent(ctx context.Context, masterAddr, agentName string, sel stack.ImportSelection) (*stack.ImportedHost, error) {
+	conn, err := grpc.Dial(masterAddr, mtls.DialOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to master: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewAgentRegistryClient(conn)
+
+	infoCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := client.GetAgentInfo(infoCtx, &pb.GetAgentInfoRequest{AgentName: agentName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent %s: %w", agentName, err)
+	}
+	if !resp.Success || resp.AgentInfo == nil {
+		return nil, fmt.Errorf("agent %s not found: %s", agentName, resp.Message)
+	}
+
+	var info *agentInternal.SystemInfo
+	if len(sel.Packages)+len(sel.Services)+len(sel.Users) > 0 {
+		if resp.AgentInfo.SystemInfoJson == "" {
+			return nil, fmt.Errorf("agent %s hasn't reported its facts yet", agentName)
+		}
+		info, err = agentInternal.FromJSON(resp.AgentInfo.SystemInfoJson)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse facts of agent %s: %w", agentName, err)
+		}
+	}
+	host := stack.SelectFacts(agentName, info, sel)
+
+	for _, path := range sel.Files {
+		file, err := readAgentFile(ctx, client, agentName, path)
+		if err != nil {
+			pterm.Warning.Printf("Skipping %s: %v\n", path, err)
+			continue
+		}
+		host.Files = append(host.Files, *file)
+	}
+	return host, nil
+}
+
+// readAgentFile reads a text file and its owner and mode from an agent
+func readAgentFile(ctx context.Context, client pb.AgentRegistryClient, agentName, path string) (*stack.ImportedFile, error) {
+	quoted := "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+	out, err := runOnAgent(ctx, client, agentName, "stat -c '%a %U %G %s' "+quoted)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("unexpected stat output %q", out)
+	}
+	size, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat output %q", out)
+	}
+	if size > maxImportedFileSize {
+		return nil, fmt.Errorf("larger than %d bytes", maxImportedFileSize)
+	}
+
+	out, err = runOnAgent(ctx, client, agentName, "base64 "+quoted)
+	if err != nil {
+		return nil, err
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(out), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode content: %w", err)
+	}
+	if !utf8.Valid(content) || strings.ContainsRune(string(content), 0) {
+		return nil, errors.New("not a text file")
+	}
+
+	mode := fields[0]
+	if len(mode) < 4 {
+		mode = strings.Repeat("0", 4-len(mode)) + mode
+	}
+	return &stack.ImportedFile{
+		Path:    path,
+		Mode:    mode,
+		Owner:   fields[1],
+		Group:   fields[2],
+		Content: string(content),
+	}, nil
+}
+
+// runOnAgent runs command on an agent through the master and returns its
+// output
+func runOnAgent(ctx context.Context, client pb.AgentRegistryClient, agentName, command string) (string, error) {
+	runCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	stream, err := client.ExecuteCommand(runCtx, &pb.ExecuteCommandRequest{AgentName: agentName, Command: command})
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		stdout.WriteString(resp.StdoutChunk)
+		stderr.WriteString(resp.StderrChunk)
+		if resp.Error != "" {
+			return "", errors.New(resp.Error)
+		}
+		if resp.Finished {
+			if resp.ExitCode != 0 {
+				return "", fmt.Errorf("%s", strings.TrimSpace(stderr.String()))
+			}
+			break
+		}
+	}
+	return stdout.String(), nil
+}
+
+// saveImportedResources records resources in the stack, replacing the ones
+// a previous import or run left
+func saveImportedResources(sm *stack.StackManager, resources []*stack.Resource) error {
+	for _, r := range resources {
+		if existing, err := sm.GetResourceByStackAndName(r.StackID, r.Type, r.Name); err == nil && existing != nil {
+			r.ID = existing.ID
+			r.CreatedAt = existing.CreatedAt
+			if err := sm.UpdateResource(r); err != nil {
+				return fmt.Errorf("failed to update resource %s: %w", r.ID, err)
+			}
+			continue
+		}
+		if err := sm.CreateResource(r); err != nil {
+			return fmt.Errorf("failed to create resource %s: %w", r.ID, err)
+		}
+	}
+	return nil
+}
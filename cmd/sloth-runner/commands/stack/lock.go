@@ -5,9 +5,13 @@ package stack
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -17,13 +21,16 @@ func NewLockCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "lock",
 		Short: "Manage state locks (prevent concurrent modifications)",
-		Long:  `Lock and unlock stack state to prevent concurrent modifications (Terraform-like locking).`,
+		Long: `Lock and unlock stack state to prevent concurrent modifications (Terraform-like locking).
+Runs lock their stack while they go and renew the lock; the lock of a run
+that crashed expires after a few minutes, or can be released by hand.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
 	}
 
 	cmd.AddCommand(
+		NewLockListCommand(ctx),
 		NewLockAcquireCommand(ctx),
 		NewLockReleaseCommand(ctx),
 		NewLockStatusCommand(ctx),
@@ -33,6 +40,46 @@ func NewLockCommand(ctx *commands.AppContext) *cobra.Command {
 	return cmd
 }
 
+// NewLockListCommand lists the locks held on stacks
+func NewLockListCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the locks held on stacks",
+		Long:  `Lists the stack locks that haven't expired, with their holders and the operations they are for.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			locks, err := stackService.ListLocks()
+			if err != nil {
+				return err
+			}
+
+			if len(locks) == 0 {
+				pterm.Info.Println("No stack is locked.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "STACK	LOCKED BY	OPERATION	SINCE	EXPIRES IN")
+			fmt.Fprintln(w, "-----	---------	---------	-----	----------")
+			for _, lock := range locks {
+				name := lock.StackName
+				if name == "" {
+					name = lock.StackID
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					name, lock.Who, lock.Operation, lock.CreatedAt.Format("2006-01-02 15:04:05"),
+					time.Until(lock.ExpiresAt).Round(time.Second))
+			}
+			return w.Flush()
+		},
+	}
+}
+
 // NewLockAcquireCommand acquires a lock
 func NewLockAcquireCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
@@ -213,9 +260,10 @@ func NewLockForceUnlockCommand(ctx *commands.AppContext) *cobra.Command {
 				pterm.Warning.Println("⚠️  WARNING: Force unlocking can cause data corruption if operations are in progress!")
 				fmt.Println()
 
-				result, _ := pterm.DefaultInteractiveConfirm.
-					WithDefaultText(fmt.Sprintf("Are you ABSOLUTELY SURE you want to force unlock stack '%s'?", stackName)).
-					Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you ABSOLUTELY SURE you want to force unlock stack '%s'?", stackName), "--force")
+				if err != nil {
+					return err
+				}
 
 				if !result {
 					pterm.Info.Println("Force unlock cancelled")
@@ -0,0 +1,114 @@
+//go:build cgo
+// +build cgo
+
+package stack
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/runlog"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewLogsCommand creates the stack logs command
+func NewLogsCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs <stack-name> [log-id]",
+		Short: "Show stored run logs of a stack",
+		Long: `Show the captured output of runs of a stack.
+
+Without a log ID the output of the most recent run is printed. Use --list
+to see all stored logs of the stack. A log ID prefix is enough to select a log.`,
+		Example: `  sloth-runner stack logs my-stack
+  sloth-runner stack logs my-stack --list
+  sloth-runner stack logs my-stack 3f2a9c`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+			list, _ := cmd.Flags().GetBool("list")
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			store, err := runlog.NewStore(config.GetRunLogDBPath())
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			logs, err := store.List(stackName, 0)
+			if err != nil {
+				return err
+			}
+			if len(logs) == 0 {
+				pterm.Info.Printf("No run logs stored for stack '%s'.\n", stackName)
+				return nil
+			}
+
+			if list {
+				if limit > 0 && len(logs) > limit {
+					logs = logs[:limit]
+				}
+				printLogList(stackName, logs)
+				return nil
+			}
+
+			selected := logs[0]
+			if len(args) == 2 {
+				selected = nil
+				for i := range logs {
+					if strings.HasPrefix(logs[i].ID, args[1]) {
+						if selected != nil {
+							return fmt.Errorf("log ID prefix '%s' is ambiguous", args[1])
+						}
+						selected = logs[i]
+					}
+				}
+				if selected == nil {
+					return fmt.Errorf("log '%s' not found for stack '%s'", args[1], stackName)
+				}
+			}
+
+			r, err := store.Reader(selected.ID)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			_, err = io.Copy(os.Stdout, r)
+			return err
+		},
+	}
+
+	cmd.Flags().Bool("list", false, "List stored logs instead of printing one")
+	cmd.Flags().Int("limit", 20, "Maximum number of logs to list")
+	return cmd
+}
+
+func printLogList(stackName string, logs []*runlog.LogInfo) {
+	pterm.DefaultHeader.WithFullWidth(false).
+		WithBackgroundStyle(pterm.NewStyle(pterm.BgBlue)).
+		WithTextStyle(pterm.NewStyle(pterm.FgWhite)).
+		Printf("Run Logs: %s", stackName)
+
+	pterm.Printf("\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tWORKFLOW\tRUN ID\tSIZE")
+	fmt.Fprintln(w, "--\t-------\t--------\t------\t----")
+
+	for _, l := range logs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n",
+			l.ID[:8],
+			l.CreatedAt.Format("2006-01-02 15:04:05"),
+			l.Name,
+			l.RunID,
+			l.Size,
+		)
+	}
+	w.Flush()
+}
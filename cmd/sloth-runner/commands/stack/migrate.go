@@ -11,6 +11,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -18,7 +19,7 @@ import (
 // NewMigrateCommand creates the migration command
 func NewMigrateCommand(ctx *commands.AppContext) *cobra.Command {
 	var sourceDB, targetDB string
-	var dryRun bool
+	var dryRun, yes bool
 	var outputScript string
 
 	cmd := &cobra.Command{
@@ -69,10 +70,15 @@ This consolidates workflow states, resources, and outputs into a single Pulumi/T
 			pterm.Info.Printfln("Target: %s", targetDB)
 			fmt.Println()
 
-			result, _ := pterm.DefaultInteractiveConfirm.Show("Continue with migration?")
-			if !result {
-				pterm.Info.Println("Migration cancelled")
-				return nil
+			if !yes {
+				result, err := taskrunner.Confirm("Continue with migration?", "--yes")
+				if err != nil {
+					return err
+				}
+				if !result {
+					pterm.Info.Println("Migration cancelled")
+					return nil
+				}
 			}
 
 			// Perform migration
@@ -129,6 +135,7 @@ This consolidates workflow states, resources, and outputs into a single Pulumi/T
 	cmd.Flags().StringVar(&sourceDB, "source", "", "Source database path (default: ~/.sloth-runner/state.db)")
 	cmd.Flags().StringVar(&targetDB, "target", "", "Target database path (default: /etc/sloth-runner/stacks.db)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Perform a dry run without making changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation prompt")
 	cmd.Flags().StringVar(&outputScript, "generate-script", "", "Generate SQL migration script to file")
 
 	return cmd
@@ -7,9 +7,14 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/user"
+	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
 	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -17,11 +22,16 @@ import (
 
 // NewShowCommand creates the stack show command
 func NewShowCommand(ctx *commands.AppContext) *cobra.Command {
-	return &cobra.Command{
+	var reveal bool
+
+	cmd := &cobra.Command{
 		Use:   "show <stack-name>",
 		Short: "Show detailed information about a stack",
-		Long:  `Show detailed information about a specific workflow stack including execution history.`,
-		Args:  cobra.ExactArgs(1),
+		Long: `Show detailed information about a specific workflow stack including execution history.
+
+Outputs marked sensitive with task.set_output are masked. --reveal decrypts
+them and records who revealed them in the stack's activity log.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			stackName := args[0]
 
@@ -61,17 +71,27 @@ func NewShowCommand(ctx *commands.AppContext) *cobra.Command {
 				pterm.Printf("Last Error: %s\n", pterm.Red(stackState.LastError))
 			}
 
-			// Show outputs if any
-			if len(stackState.Outputs) > 0 {
+			// Show outputs if any, sensitive ones masked unless revealed
+			outputs := redact.MaskOutputs(stackState.Outputs)
+			sealed := redact.SealedNames(stackState.Outputs)
+			if reveal && len(sealed) > 0 {
+				if outputs, err = revealOutputs(stackState, sealed); err != nil {
+					return err
+				}
+			}
+			if len(outputs) > 0 {
 				pterm.Printf("\n")
 				pterm.DefaultHeader.WithFullWidth(false).
 					WithBackgroundStyle(pterm.NewStyle(pterm.BgBlue)).
 					WithTextStyle(pterm.NewStyle(pterm.FgWhite)).
 					Printf("Outputs")
 				pterm.Printf("\n")
-				for key, value := range stackState.Outputs {
+				for key, value := range outputs {
 					pterm.Printf("%s: %v\n", pterm.Cyan(key), value)
 				}
+				if !reveal && len(sealed) > 0 {
+					pterm.Printf("%s\n", pterm.Gray(fmt.Sprintf("%d sensitive output(s) masked, use --reveal to show them", len(sealed))))
+				}
 			}
 
 			// Show recent executions
@@ -115,4 +135,35 @@ func NewShowCommand(ctx *commands.AppContext) *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&reveal, "reveal", false, "Decrypt sensitive outputs (recorded in the activity log)")
+
+	return cmd
+}
+
+// revealOutputs decrypts the stack's sensitive outputs. The reveal is
+// recorded in the activity log first, and nothing is shown if it can't be.
+func revealOutputs(stackState *stack.StackState, sealed []string) (map[string]interface{}, error) {
+	key, err := redact.LoadKey(config.GetOutputKeyPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load output key: %w", err)
+	}
+
+	backend, err := stack.NewStateBackend("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize state backend: %w", err)
+	}
+	defer backend.Close()
+
+	who := "cli-user"
+	if u, err := user.Current(); err == nil {
+		who = u.Username
+	}
+	sort.Strings(sealed)
+	details := fmt.Sprintf("Revealed sensitive outputs: %s", strings.Join(sealed, ", "))
+	if err := backend.RecordActivity(stackState.ID, "outputs_revealed", "", details, who); err != nil {
+		return nil, fmt.Errorf("not revealing outputs, failed to audit: %w", err)
+	}
+
+	return redact.RevealOutputs(key, stackState.Outputs)
 }
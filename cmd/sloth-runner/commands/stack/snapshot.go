@@ -11,6 +11,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -253,9 +254,10 @@ func NewSnapshotRestoreCommand(ctx *commands.AppContext) *cobra.Command {
 			}
 
 			if !force {
-				result, _ := pterm.DefaultInteractiveConfirm.
-					WithDefaultText(fmt.Sprintf("Are you sure you want to restore stack '%s' to version %s?", stackName, version)).
-					Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you sure you want to restore stack '%s' to version %s?", stackName, version), "--force")
+				if err != nil {
+					return err
+				}
 
 				if !result {
 					pterm.Info.Println("Restore cancelled")
@@ -317,9 +319,10 @@ func NewSnapshotDeleteCommand(ctx *commands.AppContext) *cobra.Command {
 			force, _ := cmd.Flags().GetBool("force")
 
 			if !force {
-				result, _ := pterm.DefaultInteractiveConfirm.
-					WithDefaultText(fmt.Sprintf("Are you sure you want to delete snapshot version %s?", version)).
-					Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you sure you want to delete snapshot version %s?", version), "--force")
+				if err != nil {
+					return err
+				}
 
 				if !result {
 					pterm.Info.Println("Deletion cancelled")
@@ -27,11 +27,14 @@ func NewStackCommand(ctx *commands.AppContext) *cobra.Command {
 		NewShowCommand(ctx),
 		NewNewCommand(ctx),
 		NewDeleteCommand(ctx),
+		NewDestroyCommand(ctx),
 		NewHistoryCommand(ctx),
+		NewLogsCommand(ctx),
 
 		// State management (Pulumi/Terraform-like)
 		NewStateCommand(ctx),
 		NewMigrateCommand(ctx),
+		NewImportCommand(ctx),     // Codify an existing server
 
 		// Operations tracking
 		NewOperationsCommand(ctx),
@@ -40,6 +43,9 @@ func NewStackCommand(ctx *commands.AppContext) *cobra.Command {
 		NewSnapshotCommand(ctx),   // Snapshot management (create, list, restore, compare)
 		NewDriftCommand(ctx),      // Drift detection and auto-fix
 		NewLockCommand(ctx),       // State locking (prevent concurrent modifications)
+		NewWindowCommand(ctx),     // Maintenance windows (when runs are allowed)
+		NewBudgetCommand(ctx),     // Execution budgets (how much runs may consume)
+		NewTargetCommand(ctx),     // Default target agent of tasks without delegate_to
 		NewValidateCommand(ctx),   // State validation and repair
 		NewEventsCommand(ctx),     // Event viewing and statistics
 		NewDepsCommand(ctx),       // Dependency graph visualization and analysis
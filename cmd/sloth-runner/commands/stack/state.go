@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
 	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -179,7 +180,7 @@ func NewStateShowCommand(ctx *commands.AppContext) *cobra.Command {
 				fmt.Fprintln(w, "KEY\tVALUE")
 				fmt.Fprintln(w, "---\t-----")
 
-				for key, value := range s.Outputs {
+				for key, value := range redact.MaskOutputs(s.Outputs) {
 					fmt.Fprintf(w, "%s\t%v\n", pterm.Cyan(key), value)
 				}
 				w.Flush()
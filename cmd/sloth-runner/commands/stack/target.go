@@ -0,0 +1,128 @@
+//go:build cgo
+// +build cgo
+
+package stack
+
+import (
+	"fmt"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewTargetCommand creates the default target command
+func NewTargetCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "target",
+		Short: "Manage the default target agent of a stack",
+		Long: `Set the agent tasks of a stack run on when they don't set delegate_to, so
+per-host workflows don't repeat it on every task and module call.
+
+A workflow or task delegate_to, and --delegate-to on the command line,
+take precedence over the default target. Tasks with delegate_to = "local"
+keep running on this machine, and --local runs everything here.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		NewTargetSetCommand(ctx),
+		NewTargetShowCommand(ctx),
+		NewTargetClearCommand(ctx),
+	)
+
+	return cmd
+}
+
+// NewTargetSetCommand sets the default target agent of a stack
+func NewTargetSetCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:     "set <stack-name> <agent>",
+		Short:   "Set the default target agent of a stack",
+		Long:    `Sends the tasks of a stack that don't set delegate_to to the given agent.`,
+		Example: `  sloth-runner stack target set web-01-config web-01`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName, agent := args[0], args[1]
+			if agent == "local" {
+				return fmt.Errorf("'local' isn't an agent, use 'stack target clear %s' to run tasks locally", stackName)
+			}
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if err := stackService.SetDefaultTarget(stackName, agent); err != nil {
+				return fmt.Errorf("failed to set default target of stack '%s': %w", stackName, err)
+			}
+
+			pterm.Success.Printf("Tasks of stack '%s' without delegate_to now run on '%s'\n", stackName, agent)
+			return nil
+		},
+	}
+}
+
+// NewTargetShowCommand shows the default target agent of a stack
+func NewTargetShowCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <stack-name>",
+		Short: "Show the default target agent of a stack",
+		Long:  `Displays the agent tasks of a stack run on when they don't set delegate_to.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if _, err := stackService.GetStackByName(stackName); err != nil {
+				return fmt.Errorf("stack '%s' not found: %w", stackName, err)
+			}
+			agent, err := stackService.GetDefaultTarget(stackName)
+			if err != nil {
+				return err
+			}
+
+			if agent == "" {
+				pterm.Info.Printf("Stack '%s' has no default target, tasks without delegate_to run locally\n", stackName)
+				return nil
+			}
+			fmt.Println(agent)
+			return nil
+		},
+	}
+}
+
+// NewTargetClearCommand removes the default target agent of a stack
+func NewTargetClearCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear <stack-name>",
+		Short: "Remove the default target agent of a stack",
+		Long:  `Removes the default target of a stack so tasks without delegate_to run locally.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if err := stackService.SetDefaultTarget(stackName, ""); err != nil {
+				return fmt.Errorf("failed to clear default target of stack '%s': %w", stackName, err)
+			}
+
+			pterm.Success.Printf("Default target cleared for stack '%s'\n", stackName)
+			return nil
+		},
+	}
+}
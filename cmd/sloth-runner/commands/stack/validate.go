@@ -8,6 +8,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -102,9 +103,10 @@ func NewValidateRepairCommand(ctx *commands.AppContext) *cobra.Command {
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 			if !force && !dryRun {
-				result, _ := pterm.DefaultInteractiveConfirm.
-					WithDefaultText(fmt.Sprintf("Are you sure you want to repair stack '%s'?", stackName)).
-					Show()
+				result, err := taskrunner.Confirm(fmt.Sprintf("Are you sure you want to repair stack '%s'?", stackName), "--force")
+				if err != nil {
+					return err
+				}
 
 				if !result {
 					pterm.Info.Println("Repair cancelled")
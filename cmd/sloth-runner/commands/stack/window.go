@@ -0,0 +1,149 @@
+//go:build cgo
+// +build cgo
+
+package stack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewWindowCommand creates the maintenance window command
+func NewWindowCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "window",
+		Short: "Manage stack maintenance windows",
+		Long: `Restrict when a stack may run. Outside its maintenance windows, runs of
+the stack and its scheduled runs are refused unless --override-window gives
+a reason, which is recorded in the stack activity log.
+
+Windows are written as "[days] HH:MM-HH:MM [timezone]", for example
+"mon-fri 22:00-06:00 Europe/Lisbon" or "weekends 00:00-24:00 UTC".`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		NewWindowSetCommand(ctx),
+		NewWindowShowCommand(ctx),
+		NewWindowClearCommand(ctx),
+	)
+
+	return cmd
+}
+
+// NewWindowSetCommand sets the maintenance windows of a stack
+func NewWindowSetCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <stack-name> <window> [window...]",
+		Short: "Set the maintenance windows of a stack",
+		Long:  `Replaces the maintenance windows of a stack. Runs are allowed inside any of them.`,
+		Example: `  sloth-runner stack window set production "mon-fri 22:00-06:00 Europe/Lisbon"
+  sloth-runner stack window set production "sat 02:00-06:00 UTC" "sun 02:00-06:00 UTC"`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName, specs := args[0], args[1:]
+			if _, err := sloth.ParseWindows(specs); err != nil {
+				return err
+			}
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if err := stackService.SetMaintenanceWindows(stackName, specs); err != nil {
+				return fmt.Errorf("failed to set maintenance windows of stack '%s': %w", stackName, err)
+			}
+
+			pterm.Success.Printf("Maintenance windows set for stack '%s'\n", stackName)
+			return nil
+		},
+	}
+}
+
+// NewWindowShowCommand shows the maintenance windows of a stack
+func NewWindowShowCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <stack-name>",
+		Short: "Show the maintenance windows of a stack",
+		Long:  `Displays the maintenance windows of a stack and whether it may run now.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if _, err := stackService.GetStackByName(stackName); err != nil {
+				return fmt.Errorf("stack '%s' not found: %w", stackName, err)
+			}
+			specs, err := stackService.GetMaintenanceWindows(stackName)
+			if err != nil {
+				return err
+			}
+
+			pterm.DefaultHeader.WithFullWidth().Printfln("Maintenance Windows: %s", stackName)
+			fmt.Println()
+
+			if len(specs) == 0 {
+				pterm.Info.Println("No maintenance windows, the stack may run anytime")
+				return nil
+			}
+
+			windows, err := sloth.ParseWindows(specs)
+			if err != nil {
+				return err
+			}
+			for _, w := range windows {
+				fmt.Printf("  • %s\n", w)
+			}
+			fmt.Println()
+
+			now := time.Now()
+			if windows.Allows(now) {
+				pterm.Success.Println("✓ Inside a maintenance window")
+			} else {
+				pterm.Warning.Printf("Outside the maintenance windows, the next one opens %s\n", windows.NextOpen(now).Format(time.RFC1123))
+			}
+			return nil
+		},
+	}
+}
+
+// NewWindowClearCommand removes the maintenance windows of a stack
+func NewWindowClearCommand(ctx *commands.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear <stack-name>",
+		Short: "Remove the maintenance windows of a stack",
+		Long:  `Removes the maintenance windows of a stack so it may run anytime.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stackName := args[0]
+
+			stackService, err := services.NewStackService()
+			if err != nil {
+				return err
+			}
+			defer stackService.Close()
+
+			if err := stackService.SetMaintenanceWindows(stackName, nil); err != nil {
+				return fmt.Errorf("failed to clear maintenance windows of stack '%s': %w", stackName, err)
+			}
+
+			pterm.Success.Printf("Maintenance windows cleared for stack '%s'\n", stackName)
+			return nil
+		},
+	}
+}
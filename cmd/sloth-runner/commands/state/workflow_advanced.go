@@ -12,6 +12,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/state"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -323,7 +324,10 @@ func NewWorkflowPruneCommand(ctx *commands.AppContext) *cobra.Command {
 				if keepSuccessful {
 					pterm.Info.Println("Successful workflows will be kept")
 				}
-				confirm, _ := pterm.DefaultInteractiveConfirm.Show("Continue?")
+				confirm, err := taskrunner.Confirm("Continue?", "--force")
+				if err != nil {
+					return err
+				}
 				if !confirm {
 					return nil
 				}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/state"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -49,7 +50,10 @@ func NewWorkflowDeleteCommand(ctx *commands.AppContext) *cobra.Command {
 				pterm.Error.Println("This action is IRREVERSIBLE and will delete all state, resources, and versions!")
 				fmt.Println()
 
-				confirm, _ := pterm.DefaultInteractiveConfirm.Show("Are you absolutely sure?")
+				confirm, err := taskrunner.Confirm("Are you absolutely sure?", "--force")
+				if err != nil {
+					return err
+				}
 				if !confirm {
 					pterm.Info.Println("Delete cancelled")
 					return nil
@@ -8,6 +8,7 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/internal/state"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -48,7 +49,10 @@ func NewWorkflowRollbackCommand(ctx *commands.AppContext) *cobra.Command {
 				pterm.Warning.Printfln("About to rollback workflow '%s' from version %d to version %d",
 					current.Name, current.Version, version)
 
-				confirm, _ := pterm.DefaultInteractiveConfirm.Show("Do you want to continue?")
+				confirm, err := taskrunner.Confirm("Do you want to continue?", "--force")
+				if err != nil {
+					return err
+				}
 				if !confirm {
 					pterm.Info.Println("Rollback cancelled")
 					return nil
@@ -1,11 +1,12 @@
 package maintenance
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // MaintenanceManager interface para tarefas de manutenção
@@ -123,11 +124,11 @@ func (m *SystemMaintenance) OptimizeDatabase(dbPath string, full bool) error {
 	}
 
 	// Abre conexão com o banco
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
-	defer db.Close()
+	defer sqlitedb.Close(db)
 
 	// Executa VACUUM
 	if _, err := db.Exec("VACUUM"); err != nil {
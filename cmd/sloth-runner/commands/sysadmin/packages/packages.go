@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -389,9 +390,10 @@ func runInstall(cmd *cobra.Command, args []string) error {
 
 	// Confirm installation
 	if !autoYes {
-		confirm := pterm.DefaultInteractiveConfirm
-		confirm.DefaultText = fmt.Sprintf("Install package '%s'?", packageName)
-		result, _ := confirm.Show()
+		result, err := taskrunner.Confirm(fmt.Sprintf("Install package '%s'?", packageName), "--yes")
+		if err != nil {
+			return err
+		}
 		if !result {
 			pterm.Info.Println("Installation cancelled")
 			return nil
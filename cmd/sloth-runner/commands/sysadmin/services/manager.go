@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/rcd"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scm"
 )
 
 // ServiceManagerType representa o tipo de gerenciador de serviços
@@ -13,6 +16,8 @@ const (
 	SYSTEMD ServiceManagerType = "systemd"
 	INITD   ServiceManagerType = "init.d"
 	OPENRC  ServiceManagerType = "openrc"
+	RCD     ServiceManagerType = "rc.d"
+	SCM     ServiceManagerType = "scm"
 	NONE    ServiceManagerType = "none"
 )
 
@@ -52,6 +57,16 @@ type ServiceManager interface {
 
 // DetectServiceManager detecta qual gerenciador de serviços está disponível
 func DetectServiceManager() ServiceManagerType {
+	// FreeBSD e OpenBSD usam rc.d
+	if _, ok := rcd.Detect(); ok {
+		return RCD
+	}
+
+	// Windows usa o Service Control Manager
+	if _, ok := scm.Detect(); ok {
+		return SCM
+	}
+
 	// Verificar systemd
 	if commandExists("systemctl") {
 		// Verificar se systemd está realmente rodando
@@ -85,6 +100,12 @@ func GetServiceManager() (ServiceManager, error) {
 		return &InitdManager{}, nil
 	case OPENRC:
 		return &OpenRCManager{}, nil
+	case RCD:
+		m, _ := rcd.Detect()
+		return &RCDManager{rcd: m}, nil
+	case SCM:
+		m, _ := scm.Detect()
+		return &SCMManager{scm: m}, nil
 	default:
 		return nil, fmt.Errorf("no supported service manager found")
 	}
@@ -315,6 +336,74 @@ func (o *OpenRCManager) Logs(serviceName string, follow bool, lines int) error {
 	return fmt.Errorf("OpenRC support not yet implemented")
 }
 
+// RCDManager implementa ServiceManager para rc.d (FreeBSD, OpenBSD)
+type RCDManager struct {
+	rcd *rcd.Manager
+}
+
+func (r *RCDManager) List() ([]Service, error) {
+	names, err := r.rcd.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	services := make([]Service, 0, len(names))
+	for _, name := range names {
+		service, err := r.Status(name)
+		if err != nil {
+			continue
+		}
+		services = append(services, *service)
+	}
+	return services, nil
+}
+
+func (r *RCDManager) Status(serviceName string) (*Service, error) {
+	running, output, err := r.rcd.Status(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s: %w", serviceName, err)
+	}
+	service := &Service{Name: serviceName, Status: StatusInactive}
+	if running {
+		service.Status = StatusActive
+		// service(8) prints "<name> is running as pid 1234."
+		if i := strings.Index(output, "pid "); i >= 0 {
+			service.PID = strings.TrimRight(strings.Fields(output[i+4:])[0], ".")
+		}
+	}
+	service.Enabled, _ = r.rcd.Enabled(serviceName)
+	return service, nil
+}
+
+func (r *RCDManager) Start(serviceName string) error {
+	return r.rcd.Start(serviceName)
+}
+
+func (r *RCDManager) Stop(serviceName string) error {
+	return r.rcd.Stop(serviceName)
+}
+
+func (r *RCDManager) Restart(serviceName string) error {
+	return r.rcd.Restart(serviceName)
+}
+
+func (r *RCDManager) Reload(serviceName string) error {
+	return r.rcd.Reload(serviceName)
+}
+
+func (r *RCDManager) Enable(serviceName string) error {
+	_, err := r.rcd.Enable(serviceName)
+	return err
+}
+
+func (r *RCDManager) Disable(serviceName string) error {
+	_, err := r.rcd.Disable(serviceName)
+	return err
+}
+
+func (r *RCDManager) Logs(serviceName string, follow bool, lines int) error {
+	return fmt.Errorf("rc.d services log through syslog; see /var/log/messages")
+}
+
 // Helper functions
 
 func parseServiceStatus(statusStr string) ServiceStatus {
@@ -340,3 +429,68 @@ func dirExists(path string) bool {
 	cmd := exec.Command("test", "-d", path)
 	return cmd.Run() == nil
 }
+
+// SCMManager implementa ServiceManager para o Service Control Manager do
+// Windows
+type SCMManager struct {
+	scm *scm.Manager
+}
+
+func (w *SCMManager) List() ([]Service, error) {
+	names, err := w.scm.List()
+	if err != nil {
+		return nil, err
+	}
+	services := make([]Service, 0, len(names))
+	for _, name := range names {
+		service, err := w.Status(name)
+		if err != nil {
+			continue
+		}
+		services = append(services, *service)
+	}
+	return services, nil
+}
+
+func (w *SCMManager) Status(serviceName string) (*Service, error) {
+	running, _, err := w.scm.Status(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of %s: %w", serviceName, err)
+	}
+	service := &Service{Name: serviceName, Status: StatusInactive}
+	if running {
+		service.Status = StatusActive
+	}
+	service.Enabled, _ = w.scm.Enabled(serviceName)
+	return service, nil
+}
+
+func (w *SCMManager) Start(serviceName string) error {
+	return w.scm.Start(serviceName)
+}
+
+func (w *SCMManager) Stop(serviceName string) error {
+	return w.scm.Stop(serviceName)
+}
+
+func (w *SCMManager) Restart(serviceName string) error {
+	return w.scm.Restart(serviceName)
+}
+
+func (w *SCMManager) Reload(serviceName string) error {
+	return w.scm.Reload(serviceName)
+}
+
+func (w *SCMManager) Enable(serviceName string) error {
+	_, err := w.scm.Enable(serviceName)
+	return err
+}
+
+func (w *SCMManager) Disable(serviceName string) error {
+	_, err := w.scm.Disable(serviceName)
+	return err
+}
+
+func (w *SCMManager) Logs(serviceName string, follow bool, lines int) error {
+	return fmt.Errorf("Windows services log to the Event Log; see Get-WinEvent -LogName System")
+}
@@ -13,8 +13,8 @@ func NewServicesCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "services",
 		Aliases: []string{"service", "svc"},
-		Short:   "Manage systemd/init.d services on agents",
-		Long: `Control and monitor services (systemd, init.d, OpenRC) on remote agents.
+		Short:   "Manage systemd/init.d/rc.d services on agents",
+		Long: `Control and monitor services (systemd, init.d, OpenRC, BSD rc.d) on remote agents.
 Start, stop, restart, and check status of services without SSH access.`,
 		Example: `  # List all services
   sloth-runner sysadmin services list --agent web-01
@@ -0,0 +1,257 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	telemetryInternal "github.com/chalkan3-sloth/sloth-runner/internal/telemetry"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewTelemetryCommand creates the parent telemetry command
+func NewTelemetryCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Manage opt-in anonymous usage stats",
+		Long: `Usage stats help prioritize module development. They are disabled by
+default and only sent once enabled with an endpoint.
+
+Reports hold counts of command names, built-in module functions and error
+categories (e.g. timeout, connection), plus the sloth-runner version, OS
+and a random install ID. They never contain arguments, workflow or file
+names, hosts, values or error messages.
+
+Every report can be inspected before and after it is sent. Setting
+DO_NOT_TRACK=1 or SLOTH_RUNNER_TELEMETRY=off disables usage stats
+regardless of these settings.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newStatusCommand(),
+		newEnableCommand(),
+		newDisableCommand(),
+		newInspectCommand(),
+		newSendCommand(),
+	)
+
+	return cmd
+}
+
+func newStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show whether usage stats are enabled and what is pending",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store := telemetryInternal.DefaultUsageStore()
+			cfg, err := store.LoadConfig()
+			if err != nil {
+				return err
+			}
+			pending, err := store.Pending()
+			if err != nil {
+				return err
+			}
+
+			state := pterm.Red("disabled")
+			if cfg.Enabled {
+				state = pterm.Green("enabled")
+			}
+			if telemetryInternal.UsageDisabledByEnv() {
+				state = pterm.Red("disabled by environment")
+			}
+
+			rows := [][]string{
+				{"Usage stats", state},
+				{"Endpoint", valueOr(cfg.Endpoint, "-")},
+				{"Install ID", valueOr(cfg.InstallID, "-")},
+				{"Last sent", formatTime(cfg.LastSent)},
+				{"Pending", fmt.Sprintf("%d commands, %d module calls, %d errors", sum(pending.Commands), sum(pending.Modules), sum(pending.Errors))},
+			}
+			if cfg.Enabled && !cfg.LastSent.IsZero() {
+				rows = append(rows, []string{"Next send", formatTime(cfg.LastSent.Add(telemetryInternal.UsageSendInterval))})
+			}
+			pterm.DefaultTable.WithData(rows).Render()
+			pterm.Info.Println("Run 'sloth-runner telemetry inspect' to see the exact payload")
+			return nil
+		},
+	}
+}
+
+func newEnableCommand() *cobra.Command {
+	var endpoint string
+
+	cmd := &cobra.Command{
+		Use:   "enable",
+		Short: "Enable anonymous usage stats",
+		Long: `Enable anonymous usage stats, sent at most once a day to the endpoint.
+
+Example:
+  sloth-runner telemetry enable --endpoint https://telemetry.example.com/v1/usage`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store := telemetryInternal.DefaultUsageStore()
+			cfg, err := store.LoadConfig()
+			if err != nil {
+				return err
+			}
+			if endpoint != "" {
+				u, err := url.Parse(endpoint)
+				if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+					return fmt.Errorf("invalid endpoint %q: expected an http(s) URL", endpoint)
+				}
+				cfg.Endpoint = endpoint
+			}
+			if cfg.Endpoint == "" {
+				return fmt.Errorf("no endpoint configured; pass --endpoint")
+			}
+
+			cfg.Enabled = true
+			if err := store.SaveConfig(cfg); err != nil {
+				return fmt.Errorf("failed to save telemetry settings: %w", err)
+			}
+			pterm.Success.Printf("Usage stats enabled, sent to %s\n", cfg.Endpoint)
+			if telemetryInternal.UsageDisabledByEnv() {
+				pterm.Warning.Println("DO_NOT_TRACK or SLOTH_RUNNER_TELEMETRY disables usage stats in this environment")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "URL reports are POSTed to")
+
+	return cmd
+}
+
+func newDisableCommand() *cobra.Command {
+	var purge bool
+
+	cmd := &cobra.Command{
+		Use:   "disable",
+		Short: "Disable usage stats",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store := telemetryInternal.DefaultUsageStore()
+			cfg, err := store.LoadConfig()
+			if err != nil {
+				return err
+			}
+			cfg.Enabled = false
+			if err := store.SaveConfig(cfg); err != nil {
+				return fmt.Errorf("failed to save telemetry settings: %w", err)
+			}
+			if purge {
+				if err := store.Reset(); err != nil {
+					return fmt.Errorf("failed to delete usage stats: %w", err)
+				}
+			}
+			pterm.Success.Println("Usage stats disabled")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&purge, "purge", false, "Also delete the pending report and the sent log")
+
+	return cmd
+}
+
+func newInspectCommand() *cobra.Command {
+	var sent bool
+
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Print the exact usage stats payload",
+		Long: `Print the report that will be sent next, exactly as it is sent.
+With --sent, print the last reports already sent instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store := telemetryInternal.DefaultUsageStore()
+			if sent {
+				reports, err := store.Sent()
+				if err != nil {
+					return err
+				}
+				if len(reports) == 0 {
+					pterm.Info.Println("No usage stats have been sent")
+					return nil
+				}
+				return printJSON(reports)
+			}
+
+			pending, err := store.Pending()
+			if err != nil {
+				return err
+			}
+			if pending.Empty() {
+				pterm.Info.Println("No usage stats are pending")
+				return nil
+			}
+			return printJSON(pending)
+		},
+	}
+
+	cmd.Flags().BoolVar(&sent, "sent", false, "Print the reports already sent")
+
+	return cmd
+}
+
+func newSendCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "send",
+		Short: "Send pending usage stats now",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store := telemetryInternal.DefaultUsageStore()
+			cfg, err := store.LoadConfig()
+			if err != nil {
+				return err
+			}
+			if !cfg.Enabled || telemetryInternal.UsageDisabledByEnv() {
+				return fmt.Errorf("usage stats are disabled")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := store.Send(ctx, http.DefaultClient, cfg); err != nil {
+				return err
+			}
+			pterm.Success.Printf("Usage stats sent to %s\n", cfg.Endpoint)
+			return nil
+		},
+	}
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func sum(counts map[string]int64) int64 {
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/conformance"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewTestCommand creates the test parent command
+func NewTestCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Test sloth-runner itself",
+	}
+
+	cmd.AddCommand(newModulesCmd())
+
+	return cmd
+}
+
+func newModulesCmd() *cobra.Command {
+	var distros, suites []string
+	var engine, binary string
+	var integration, keep bool
+	var parallel int
+
+	cmd := &cobra.Command{
+		Use:   "modules",
+		Short: "Run the module conformance suites against containers of several distros",
+		Long: `Run the conformance suites of the pkg, user, file and systemd modules
+against disposable containers of several distributions, to check changes to
+modules behave the same on each of them before they ship.
+
+Each distro is started once, the binary and the suites copied in, and
+snapshotted to an image; each suite then runs in a fresh container of that
+snapshot, so suites can't affect each other. Containers and snapshots are
+removed afterwards unless --keep is given.
+
+The binary must run in the containers: build it for Linux, with
+CGO_ENABLED=0 for distros older than the build host. The systemd suite only
+runs on distros whose image boots systemd, which run privileged.`,
+		Example: `  CGO_ENABLED=0 go build -o /tmp/sloth-runner ./cmd/sloth-runner
+  sloth-runner test modules --integration --binary /tmp/sloth-runner
+  sloth-runner test modules --integration --distro debian-12,rockylinux-9 --suite pkg
+  sloth-runner test modules --integration --distro alpine=alpine:3.20 --engine podman`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !integration {
+				return errors.New("module conformance suites start containers: pass --integration to run them")
+			}
+			opts := conformance.Options{Engine: engine, Binary: binary, Parallel: parallel, Keep: keep}
+			if opts.Binary == "" {
+				exe, err := os.Executable()
+				if err != nil {
+					return fmt.Errorf("failed to find the sloth-runner binary, pass --binary: %w", err)
+				}
+				opts.Binary = exe
+			}
+
+			if len(distros) == 0 {
+				opts.Distros = conformance.Distros
+			}
+			for _, name := range distros {
+				d, err := conformance.FindDistro(name)
+				if err != nil {
+					return err
+				}
+				opts.Distros = append(opts.Distros, d)
+			}
+			all, err := conformance.Suites()
+			if err != nil {
+				return err
+			}
+			opts.Suites, err = selectSuites(all, suites)
+			if err != nil {
+				return err
+			}
+
+			runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			total := len(opts.Distros) * len(opts.Suites)
+			var mu sync.Mutex
+			done := 0
+			opts.Progress = func(r conformance.Result) {
+				mu.Lock()
+				defer mu.Unlock()
+				done++
+				fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] %s %s: %s\n", done, total, r.Distro, r.Suite, r.Status)
+			}
+			pterm.Info.Printf("Running %d suites against %d distros with %s\n", len(opts.Suites), len(opts.Distros), opts.Binary)
+			results := conformance.RunSuites(runCtx, opts)
+			return report(cmd, results)
+		},
+	}
+
+	cmd.Flags().BoolVar(&integration, "integration", false, "Run the suites, which start containers")
+	cmd.Flags().StringSliceVar(&distros, "distro", nil, "Distro to test, or name=image for another image (can be used multiple times, default all)")
+	cmd.Flags().StringSliceVar(&suites, "suite", nil, "Suite to run: pkg, user, file or systemd (can be used multiple times, default all)")
+	cmd.Flags().StringVar(&engine, "engine", "docker", "Container engine: docker or podman")
+	cmd.Flags().StringVar(&binary, "binary", "", "sloth-runner binary to test (default this one)")
+	cmd.Flags().IntVar(&parallel, "parallel", 2, "Distros tested at once")
+	cmd.Flags().BoolVar(&keep, "keep", false, "Keep containers and snapshots for debugging")
+
+	return cmd
+}
+
+// selectSuites returns the suites named, or all of them
+func selectSuites(all []conformance.Suite, names []string) ([]conformance.Suite, error) {
+	if len(names) == 0 {
+		return all, nil
+	}
+	var selected []conformance.Suite
+	for _, name := range names {
+		found := false
+		for _, s := range all {
+			if s.Name == name {
+				selected = append(selected, s)
+				found = true
+			}
+		}
+		if !found {
+			var known []string
+			for _, s := range all {
+				known = append(known, s.Name)
+			}
+			return nil, fmt.Errorf("unknown suite %q: expected one of %s", name, strings.Join(known, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// report prints the results, with the output of failed suites, and fails
+// if any suite did
+func report(cmd *cobra.Command, results []conformance.Result) error {
+	failed := 0
+	for _, r := range results {
+		if r.Status == conformance.Failed {
+			failed++
+			pterm.DefaultSection.Printf("%s: %s", r.Distro, r.Suite)
+			fmt.Fprintln(cmd.OutOrStdout(), strings.TrimSpace(r.Output))
+		}
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout())
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DISTRO\tSUITE\tSTATUS\tDURATION")
+	for _, r := range results {
+		status := string(r.Status)
+		if r.Status == conformance.Skipped {
+			status += " (" + r.Output + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Distro, r.Suite, status, r.Duration.Round(time.Second))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d suites failed", failed, len(results))
+	}
+	pterm.Success.Printf("All %d suites passed\n", len(results))
+	return nil
+}
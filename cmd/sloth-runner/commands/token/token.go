@@ -0,0 +1,262 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/apitoken"
+	"github.com/spf13/cobra"
+)
+
+// NewTokenCommand creates the token parent command
+func NewTokenCommand(ctx *commands.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage scoped API tokens",
+		Long: `Manage API tokens that let CI pipelines trigger workflows through the
+master's API. A token is limited to a role and to the stacks, workflows and agents it
+was created for, expires, and can be rotated or revoked.
+
+Tokens are stored on the master; only a hash of each secret is kept, so a
+token is shown once when it is created.
+
+Roles:
+  viewer   read executions and their logs
+  runner   also trigger workflows`,
+	}
+
+	cmd.AddCommand(newCreateCmd())
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newRevokeCmd())
+	cmd.AddCommand(newRotateCmd())
+
+	return cmd
+}
+
+func newCreateCmd() *cobra.Command {
+	var name, role, expires, outputFormat string
+	var stacks, workflows, agents []string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API token",
+		Example: `  # Token for CI to run any workflow on the prod stack for 90 days
+  sloth-runner token create --stack prod --role runner --expires 90d
+
+  # Only the deploy workflows of the staging stacks
+  sloth-runner token create --name gitlab-deploy --stack 'staging-*' --workflow 'deploy*'
+
+  # Also let it send runs to the web agents or the canary group
+  sloth-runner token create --stack prod --agent 'web-*' --agent group:canary`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := apitoken.ParseRole(role)
+			if err != nil {
+				return err
+			}
+			ttl, err := apitoken.ParseTTL(expires)
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				name = fmt.Sprintf("%s-%s", strings.Join(stacks, "-"), r)
+			}
+
+			store, err := apitoken.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			secret, t, err := store.Create(apitoken.Spec{
+				Name:      name,
+				Role:      r,
+				Stacks:    stacks,
+				Workflows: workflows,
+				Agents:    agents,
+				TTL:       ttl,
+			})
+			if err != nil {
+				return err
+			}
+			return printSecret(cmd, outputFormat, secret, t)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Token name (default: <stacks>-<role>)")
+	cmd.Flags().StringSliceVar(&stacks, "stack", nil, "Stack the token may use, a name or glob (repeatable, required)")
+	cmd.Flags().StringSliceVar(&workflows, "workflow", nil, "Workflow the token may run, a name or glob (repeatable, default: all)")
+	cmd.Flags().StringSliceVar(&agents, "agent", nil, "Agent, or group:<name> agent group, runs may be delegated to; a name or glob (repeatable, default: none)")
+	cmd.Flags().StringVar(&role, "role", string(apitoken.RoleRunner), "Token role: viewer or runner")
+	cmd.Flags().StringVar(&expires, "expires", "90d", "Lifetime, e.g. 90d or 12h; 0 never expires")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+	cmd.MarkFlagRequired("stack")
+
+	return cmd
+}
+
+func newListCmd() *cobra.Command {
+	var all, revoked bool
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List API tokens",
+		Long: `List API tokens. Expired and revoked tokens are hidden unless --all is
+given; --revoked shows only the revocation list.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := apitoken.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			tokens, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			var shown []*apitoken.Token
+			for _, t := range tokens {
+				state := t.State(now)
+				switch {
+				case revoked && state != "revoked":
+					continue
+				case !revoked && !all && state != "active":
+					continue
+				}
+				shown = append(shown, t)
+			}
+
+			if outputFormat == "json" {
+				if shown == nil {
+					shown = []*apitoken.Token{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(shown)
+			}
+
+			if len(shown) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No tokens found")
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tROLE\tSTACKS\tWORKFLOWS\tAGENTS\tSTATE\tEXPIRES\tLAST USED")
+			for _, t := range shown {
+				agents := strings.Join(t.Agents, ",")
+				if agents == "" {
+					agents = "-"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					t.ID, t.Name, t.Role,
+					strings.Join(t.Stacks, ","), strings.Join(t.Workflows, ","), agents,
+					t.State(now), formatTime(t.ExpiresAt, "never"), formatTime(t.LastUsedAt, "-"))
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "Include expired and revoked tokens")
+	cmd.Flags().BoolVar(&revoked, "revoked", false, "Show only revoked tokens")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func newRevokeCmd() *cobra.Command {
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "revoke <token-id>",
+		Short: "Revoke an API token",
+		Long:  `Revoke an API token immediately. It stays in the revocation list shown by 'token list --revoked'.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := apitoken.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			if err := store.Revoke(args[0], reason); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Token %s revoked\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the token is revoked")
+
+	return cmd
+}
+
+func newRotateCmd() *cobra.Command {
+	var grace, outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "rotate <token-id>",
+		Short: "Replace an API token with a new secret",
+		Long: `Create a token with the same name, role, scope and lifetime as an existing
+one, and revoke the existing token once the grace period has passed so
+pipelines can switch to the new secret.`,
+		Example: `  # Replace a token, keeping the old one valid for a day
+  sloth-runner token rotate 3f9a1c2b7d4e --grace 24h`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gracePeriod, err := apitoken.ParseTTL(grace)
+			if err != nil {
+				return err
+			}
+
+			store, err := apitoken.DefaultStore()
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			secret, t, err := store.Rotate(args[0], gracePeriod)
+			if err != nil {
+				return err
+			}
+			return printSecret(cmd, outputFormat, secret, t)
+		},
+	}
+
+	cmd.Flags().StringVar(&grace, "grace", "0", "How long the old token stays valid, e.g. 24h")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func printSecret(cmd *cobra.Command, outputFormat, secret string, t *apitoken.Token) error {
+	if outputFormat == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			*apitoken.Token
+			Secret string `json:"token"`
+		}{t, secret})
+	}
+
+	// The secret goes to stdout alone so scripts can capture it
+	fmt.Fprintln(cmd.OutOrStdout(), secret)
+	fmt.Fprintf(os.Stderr, "Token %s (%s, %s on %s) expires %s\n",
+		t.ID, t.Name, t.Role, strings.Join(t.Stacks, ","), formatTime(t.ExpiresAt, "never"))
+	fmt.Fprintln(os.Stderr, "Store it now: it can't be shown again.")
+	return nil
+}
+
+func formatTime(t *time.Time, empty string) string {
+	if t == nil {
+		return empty
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,381 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// graphNode is a task of a workflow graph, with where it runs. Missing
+// nodes are dependencies no task of the group is named after.
+type graphNode struct {
+	ID      string
+	Name    string
+	Targets []string
+	Missing bool
+}
+
+// graphEdge is a dependency: To starts once From succeeded
+type graphEdge struct {
+	From, To string
+}
+
+// graphGroup is a task group of a workflow graph
+type graphGroup struct {
+	ID          string
+	Name        string
+	Description string
+	Nodes       []graphNode
+	Edges       []graphEdge
+}
+
+// NewGraphCommand creates the graph command
+func NewGraphCommand(ctx *commands.AppContext) *cobra.Command {
+	var format, workflowName string
+
+	cmd := &cobra.Command{
+		Use:   "graph <workflow-file>",
+		Short: "Draw the tasks of a workflow and their dependencies",
+		Long: `Draw the task groups of a workflow file as a graph, with an edge from each
+task to the tasks that depend on it and, on each task, the agents or hosts
+it is delegated to. Nothing is run.
+
+Dependencies on tasks the group doesn't define are drawn dashed in red;
+the run would fail on them.
+
+The dot format is rendered by Graphviz, and mermaid by GitHub, GitLab and
+most Markdown viewers. svg is drawn without any of them.`,
+		Example: `  sloth-runner workflow graph pipeline.sloth
+  sloth-runner workflow graph pipeline.sloth --format dot | dot -Tpng -o pipeline.png
+  sloth-runner workflow graph pipeline.sloth --format mermaid --workflow deploy
+  sloth-runner workflow graph pipeline.sloth --format svg > pipeline.svg`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var write func(io.Writer, []graphGroup)
+			switch format {
+			case "dot":
+				write = writeDOT
+			case "mermaid":
+				write = writeMermaid
+			case "svg":
+				write = writeSVG
+			default:
+				return fmt.Errorf("unknown format: %s (use dot, mermaid or svg)", format)
+			}
+
+			// The graph is meant to be piped, so logs of parsing go to stderr
+			slog.SetDefault(slog.New(pterm.NewSlogHandler(pterm.DefaultLogger.WithWriter(cmd.ErrOrStderr()))))
+
+			groups, err := loadGraph(cmd.Context(), args[0], workflowName)
+			if err != nil {
+				return err
+			}
+			write(cmd.OutOrStdout(), groups)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "dot", "Output format: dot, mermaid or svg")
+	cmd.Flags().StringVar(&workflowName, "workflow", "", "Only draw this workflow of the file")
+
+	return cmd
+}
+
+// loadGraph parses a sloth file into the graphs of its task groups,
+// sorted by name, or of the named one only
+func loadGraph(ctx context.Context, file, workflowName string) ([]graphGroup, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	taskGroups, err := luainterface.ParseLuaScript(ctx, file, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	names := make([]string, 0, len(taskGroups))
+	for name := range taskGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if workflowName != "" {
+		if _, ok := taskGroups[workflowName]; !ok {
+			return nil, fmt.Errorf("workflow %q not found in %s (available: %s)", workflowName, file, strings.Join(names, ", "))
+		}
+		names = []string{workflowName}
+	}
+
+	groups := make([]graphGroup, 0, len(names))
+	for i, name := range names {
+		groups = append(groups, buildGraphGroup(fmt.Sprintf("g%d", i), name, taskGroups[name]))
+	}
+	return groups, nil
+}
+
+func buildGraphGroup(id, name string, group types.TaskGroup) graphGroup {
+	g := graphGroup{ID: id, Name: name, Description: group.Description}
+	ids := make(map[string]string, len(group.Tasks))
+	for i := range group.Tasks {
+		task := &group.Tasks[i]
+		nodeID := fmt.Sprintf("%s_t%d", id, i)
+		ids[task.Name] = nodeID
+		g.Nodes = append(g.Nodes, graphNode{ID: nodeID, Name: task.Name, Targets: delegateTargets(task, group)})
+	}
+
+	for _, task := range group.Tasks {
+		for _, dep := range task.DependsOn {
+			depID, ok := ids[dep]
+			if !ok {
+				depID = fmt.Sprintf("%s_m%d", id, len(ids))
+				ids[dep] = depID
+				g.Nodes = append(g.Nodes, graphNode{ID: depID, Name: dep, Missing: true})
+			}
+			g.Edges = append(g.Edges, graphEdge{From: depID, To: ids[task.Name]})
+		}
+	}
+	return g
+}
+
+// delegateTargets describes where a task runs: its agents, the host it
+// reaches over SSH or the facts its agent is picked by. Tasks running on
+// this machine have none.
+func delegateTargets(task *types.Task, group types.TaskGroup) []string {
+	delegateTo := task.DelegateTo
+	if delegateTo == nil {
+		delegateTo = group.DelegateTo
+	}
+	if m, ok := delegateTo.(map[string]interface{}); ok {
+		if facts, ok := m["facts"].(map[string]interface{}); ok {
+			pairs := make([]string, 0, len(facts))
+			for k, v := range facts {
+				pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+			}
+			sort.Strings(pairs)
+			return []string{"facts " + strings.Join(pairs, ",")}
+		}
+	}
+
+	agents := taskrunner.TaskAgents(task, group)
+	if len(agents) == 1 && agents[0] == "local" {
+		return nil
+	}
+	return agents
+}
+
+// nodeLabel returns the name of a node and the line saying where it runs
+func nodeLabel(n graphNode) (string, string) {
+	if n.Missing {
+		return n.Name + " (missing)", ""
+	}
+	if len(n.Targets) == 0 {
+		return n.Name, ""
+	}
+	return n.Name, "→ " + strings.Join(n.Targets, ", ")
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeDOT(w io.Writer, groups []graphGroup) {
+	fmt.Fprintln(w, "digraph workflow {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, `  node [shape=box, style="rounded", fontname="Helvetica"];`)
+	for _, g := range groups {
+		fmt.Fprintf(w, "  subgraph cluster_%s {\n", g.ID)
+		label := g.Name
+		if g.Description != "" {
+			label += "\n" + g.Description
+		}
+		fmt.Fprintf(w, "    label=\"%s\";\n", dotEscaper.Replace(label))
+		for _, n := range g.Nodes {
+			name, targets := nodeLabel(n)
+			if targets != "" {
+				name += "\n" + targets
+			}
+			attrs := fmt.Sprintf(`label="%s"`, dotEscaper.Replace(name))
+			if n.Missing {
+				attrs += `, style="rounded,dashed", color=red, fontcolor=red`
+			}
+			fmt.Fprintf(w, "    %s [%s];\n", n.ID, attrs)
+		}
+		for _, e := range g.Edges {
+			fmt.Fprintf(w, "    %s -> %s;\n", e.From, e.To)
+		}
+		fmt.Fprintln(w, "  }")
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// mermaidEscaper escapes labels, which Mermaid reads as HTML
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+func writeMermaid(w io.Writer, groups []graphGroup) {
+	fmt.Fprintln(w, "flowchart LR")
+	for _, g := range groups {
+		fmt.Fprintf(w, "  subgraph %s [\"%s\"]\n", g.ID, mermaidEscaper.Replace(g.Name))
+		for _, n := range g.Nodes {
+			name, targets := nodeLabel(n)
+			label := mermaidEscaper.Replace(name)
+			if targets != "" {
+				label += "<br/><small>" + mermaidEscaper.Replace(targets) + "</small>"
+			}
+			fmt.Fprintf(w, "    %s[\"%s\"]\n", n.ID, label)
+		}
+		for _, e := range g.Edges {
+			fmt.Fprintf(w, "    %s --> %s\n", e.From, e.To)
+		}
+		fmt.Fprintln(w, "  end")
+		for _, n := range g.Nodes {
+			if n.Missing {
+				fmt.Fprintf(w, "  style %s stroke:#d00,stroke-dasharray:4 3,color:#d00\n", n.ID)
+			}
+		}
+	}
+}
+
+// SVG layout: tasks are placed in columns by how many dependencies deep
+// they are, and groups are stacked top to bottom
+const (
+	svgNodeWidth  = 200
+	svgNodeHeight = 48
+	svgColumnGap  = 60
+	svgRowGap     = 16
+	svgPadding    = 20
+	svgTitle      = 28
+	svgMaxLabel   = 26
+)
+
+// graphColumns returns the column of each node: 0 for nodes without
+// dependencies, else one more than their deepest dependency. Cycles are
+// cut where they are found.
+func graphColumns(g graphGroup) map[string]int {
+	deps := make(map[string][]string)
+	for _, e := range g.Edges {
+		deps[e.To] = append(deps[e.To], e.From)
+	}
+	columns := make(map[string]int, len(g.Nodes))
+	visiting := make(map[string]bool)
+	var column func(id string) int
+	column = func(id string) int {
+		if c, ok := columns[id]; ok {
+			return c
+		}
+		if visiting[id] {
+			return -1
+		}
+		visiting[id] = true
+		c := 0
+		for _, dep := range deps[id] {
+			if d := column(dep) + 1; d > c {
+				c = d
+			}
+		}
+		visiting[id] = false
+		columns[id] = c
+		return c
+	}
+	for _, n := range g.Nodes {
+		column(n.ID)
+	}
+	return columns
+}
+
+// truncate shortens s to max characters
+func truncate(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
+
+func writeSVG(w io.Writer, groups []graphGroup) {
+	type point struct{ x, y int }
+	type layout struct {
+		g      graphGroup
+		y      int
+		width  int
+		height int
+		nodes  map[string]point
+	}
+
+	var layouts []layout
+	y, width := svgPadding, 0
+	for _, g := range groups {
+		columns := graphColumns(g)
+		rows := make(map[int]int)
+		l := layout{g: g, y: y, nodes: make(map[string]point, len(g.Nodes))}
+		maxColumn, maxRows := 0, 0
+		for _, n := range g.Nodes {
+			c := columns[n.ID]
+			l.nodes[n.ID] = point{
+				x: 2*svgPadding + c*(svgNodeWidth+svgColumnGap),
+				y: y + svgTitle + rows[c]*(svgNodeHeight+svgRowGap),
+			}
+			rows[c]++
+			if c > maxColumn {
+				maxColumn = c
+			}
+			if rows[c] > maxRows {
+				maxRows = rows[c]
+			}
+		}
+		l.width = 2*svgPadding + (maxColumn+1)*svgNodeWidth + maxColumn*svgColumnGap
+		l.height = svgTitle + svgPadding
+		if maxRows > 0 {
+			l.height += maxRows*svgNodeHeight + (maxRows-1)*svgRowGap
+		}
+		if l.width > width {
+			width = l.width
+		}
+		layouts = append(layouts, l)
+		y += l.height + svgPadding
+	}
+	width += 2 * svgPadding
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="13">`+"\n", width, y, width, y)
+	fmt.Fprintln(w, `  <defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="#555"/></marker></defs>`)
+	for _, l := range layouts {
+		fmt.Fprintf(w, `  <g id="%s">`+"\n", l.g.ID)
+		fmt.Fprintf(w, `    <rect x="%d" y="%d" width="%d" height="%d" rx="8" fill="#f6f8fa" stroke="#d0d7de"/>`+"\n", svgPadding, l.y, l.width, l.height)
+		title := l.g.Name
+		if l.g.Description != "" {
+			title += " - " + l.g.Description
+		}
+		fmt.Fprintf(w, `    <text x="%d" y="%d" font-weight="bold">%s</text>`+"\n", 2*svgPadding, l.y+19, html.EscapeString(title))
+
+		for _, e := range l.g.Edges {
+			from, to := l.nodes[e.From], l.nodes[e.To]
+			x1, y1 := from.x+svgNodeWidth, from.y+svgNodeHeight/2
+			x2, y2 := to.x, to.y+svgNodeHeight/2
+			fmt.Fprintf(w, `    <path d="M%d,%d C%d,%d %d,%d %d,%d" fill="none" stroke="#555" marker-end="url(#arrow)"/>`+"\n",
+				x1, y1, x1+svgColumnGap/2, y1, x2-svgColumnGap/2, y2, x2, y2)
+		}
+
+		for _, n := range l.g.Nodes {
+			p := l.nodes[n.ID]
+			stroke, color, dash := "#57606a", "#24292f", ""
+			if n.Missing {
+				stroke, color, dash = "#cf222e", "#cf222e", ` stroke-dasharray="4 3"`
+			}
+			name, targets := nodeLabel(n)
+			fmt.Fprintf(w, `    <rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="#fff" stroke="%s"%s/>`+"\n", p.x, p.y, svgNodeWidth, svgNodeHeight, stroke, dash)
+			nameY := p.y + 29
+			if targets != "" {
+				nameY = p.y + 20
+				fmt.Fprintf(w, `    <text x="%d" y="%d" text-anchor="middle" font-size="11" fill="#57606a">%s</text>`+"\n", p.x+svgNodeWidth/2, p.y+37, html.EscapeString(truncate(targets, svgMaxLabel+4)))
+			}
+			fmt.Fprintf(w, `    <text x="%d" y="%d" text-anchor="middle" fill="%s">%s</text>`+"\n", p.x+svgNodeWidth/2, nameY, color, html.EscapeString(truncate(name, svgMaxLabel)))
+		}
+		fmt.Fprintln(w, "  </g>")
+	}
+	fmt.Fprintln(w, "</svg>")
+}
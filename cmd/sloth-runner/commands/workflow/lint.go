@@ -0,0 +1,95 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// NewLintCommand creates the lint command
+func NewLintCommand(ctx *commands.AppContext) *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "lint <workflow-file>",
+		Short: "Check a workflow for deprecated module usage",
+		Long: `Check a workflow file against the module API it declares with
+'module_api = N' and suggest migrations for deprecated calls.
+
+Workflows without a declaration use module API 1, where some modules keep
+legacy return conventions such as (ok, value). Module API 2 makes every
+function return (result, err).
+
+Legacy module names such as 'k8s' are reported with the canonical name to
+use instead; see 'sloth-runner modules aliases'.`,
+		Example: `  sloth-runner workflow lint deploy.sloth
+  sloth-runner workflow lint deploy.sloth -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read workflow: %w", err)
+			}
+
+			findings := luainterface.LintModuleAPI(string(source))
+
+			switch outputFormat {
+			case "json":
+				data, err := json.MarshalIndent(findings, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal findings: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			case "text":
+				printLintFindings(cmd.OutOrStdout(), args[0], findings)
+			default:
+				return fmt.Errorf("unknown format: %s", outputFormat)
+			}
+
+			for _, f := range findings {
+				if f.Severity == luainterface.LintError {
+					return fmt.Errorf("workflow has module API errors")
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+func printLintFindings(w io.Writer, file string, findings []luainterface.LintFinding) {
+	if len(findings) == 0 {
+		fmt.Fprintf(w, "%s %s: no issues found\n", pterm.Green("✓"), file)
+		return
+	}
+
+	for _, f := range findings {
+		var marker string
+		switch f.Severity {
+		case luainterface.LintError:
+			marker = pterm.Red("✗ error")
+		case luainterface.LintWarning:
+			marker = pterm.Yellow("⚠ warning")
+		default:
+			marker = pterm.Blue("ℹ info")
+		}
+
+		location := file
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", file, f.Line)
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", location, marker, f.Message)
+		if f.Suggestion != "" {
+			fmt.Fprintf(w, "    %s %s\n", pterm.Gray("→"), f.Suggestion)
+		}
+	}
+}
@@ -1,22 +1,139 @@
 package workflow
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
 
+// workflowInfo is a named workflow of a sloth file
+type workflowInfo struct {
+	File        string   `json:"file"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tasks       []string `json:"tasks"`
+}
+
 // NewListCommand creates the list command
-// TODO: Extract logic from main.go listCmd (lines ~574-641)
 func NewListCommand(ctx *commands.AppContext) *cobra.Command {
-	return &cobra.Command{
+	var filePath, outputFormat string
+
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List available workflows and tasks",
-		Long:  `List all available workflows and tasks from sloth files in the current directory.`,
+		Long: `List the named workflows and their tasks of a sloth file, or of every
+.sloth file in the current directory without --file.
+
+A file may define several workflows; run one of them with
+'sloth-runner run <stack> --file <file> --workflow <name>'.`,
+		Example: `  sloth-runner workflow list --file ops.sloth
+  sloth-runner workflow list -o json`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// TODO: Implement
-			// - Scan directory for .sloth files
-			// - Parse and display available workflows
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown format: %s", outputFormat)
+			}
+
+			files := []string{filePath}
+			if filePath == "" {
+				var err error
+				if files, err = filepath.Glob("*.sloth"); err != nil {
+					return fmt.Errorf("failed to find sloth files: %w", err)
+				}
+				if len(files) == 0 {
+					return fmt.Errorf("no .sloth files in the current directory (use --file)")
+				}
+			}
+
+			var workflows []workflowInfo
+			for _, file := range files {
+				found, err := listWorkflows(cmd.Context(), file)
+				if err != nil {
+					// One broken file doesn't hide the others of the directory
+					if filePath == "" {
+						pterm.Warning.Printfln("Skipping %s: %v", file, err)
+						continue
+					}
+					return err
+				}
+				workflows = append(workflows, found...)
+			}
+
+			if outputFormat == "json" {
+				if workflows == nil {
+					workflows = []workflowInfo{}
+				}
+				data, err := json.MarshalIndent(workflows, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal workflows: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+			printWorkflows(cmd.OutOrStdout(), workflows)
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&filePath, "file", "f", "", "Sloth file to list (default: every .sloth file in the current directory)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// listWorkflows parses a sloth file and returns its workflows by name
+func listWorkflows(ctx context.Context, file string) ([]workflowInfo, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	taskGroups, err := luainterface.ParseLuaScript(ctx, file, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	workflows := make([]workflowInfo, 0, len(taskGroups))
+	for name, group := range taskGroups {
+		info := workflowInfo{File: file, Name: name, Description: group.Description, Tasks: []string{}}
+		for _, task := range group.Tasks {
+			info.Tasks = append(info.Tasks, task.Name)
+		}
+		workflows = append(workflows, info)
+	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].Name < workflows[j].Name })
+	return workflows, nil
+}
+
+func printWorkflows(w io.Writer, workflows []workflowInfo) {
+	if len(workflows) == 0 {
+		fmt.Fprintln(w, "No workflows found")
+		return
+	}
+
+	file := ""
+	for _, wf := range workflows {
+		if wf.File != file {
+			if file != "" {
+				fmt.Fprintln(w)
+			}
+			file = wf.File
+			fmt.Fprintln(w, pterm.Bold.Sprint(file))
+		}
+		line := "  " + pterm.Cyan(wf.Name)
+		if wf.Description != "" {
+			line += " - " + wf.Description
+		}
+		fmt.Fprintln(w, line)
+		if len(wf.Tasks) > 0 {
+			fmt.Fprintf(w, "    %s %s\n", pterm.Gray("tasks:"), strings.Join(wf.Tasks, ", "))
+		}
+	}
 }
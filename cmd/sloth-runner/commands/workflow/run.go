@@ -43,6 +43,7 @@ If --sloth is specified, --file will be ignored.`,
 			sshProfile, _ := cmd.Flags().GetString("ssh")
 			sshPasswordStdin, _ := cmd.Flags().GetBool("ssh-password-stdin")
 			passwordStdin, _ := cmd.Flags().GetBool("password-stdin")
+			workflowName, _ := cmd.Flags().GetString("workflow")
 
 			// Configure log level based on debug flag
 			if debug {
@@ -114,6 +115,7 @@ If --sloth is specified, --file will be ignored.`,
 				Context:          cmd.Context(),
 				Writer:           writer,
 				AgentRegistry:    ctx.AgentRegistry,
+				Workflow:         workflowName,
 			}
 
 			// Create and execute handler
@@ -125,6 +127,7 @@ If --sloth is specified, --file will be ignored.`,
 	// Add flags
 	cmd.Flags().StringP("file", "f", "", "Path to the Lua task file")
 	cmd.Flags().String("sloth", "", "Name of saved sloth file to use (takes precedence over --file)")
+	cmd.Flags().StringP("workflow", "w", "", "Run only this named workflow of the file (default: all of them)")
 	cmd.Flags().StringP("values", "v", "", "Path to the values file")
 	cmd.Flags().Bool("yes", false, "Skip confirmation prompts")
 	cmd.Flags().Bool("interactive", false, "Run in interactive mode")
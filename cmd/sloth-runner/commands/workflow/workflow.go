@@ -13,13 +13,15 @@ func NewWorkflowCommand(ctx *commands.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "workflow",
 		Short: "Manage workflows",
-		Long:  `Manage workflows including running, listing, and previewing workflow files.`,
+		Long:  `Manage workflows including running, listing, previewing, linting and graphing workflow files.`,
 	}
 
 	// Add subcommands
 	cmd.AddCommand(NewRunCommand(ctx))
 	cmd.AddCommand(NewListCommand(ctx))
 	cmd.AddCommand(NewPreviewCommand(ctx))
+	cmd.AddCommand(NewLintCommand(ctx))
+	cmd.AddCommand(NewGraphCommand(ctx))
 
 	return cmd
 }
@@ -21,6 +21,8 @@ func NewWorkflowCommand(ctx *commands.AppContext) *cobra.Command {
 	// Add subcommands that don't require CGO
 	cmd.AddCommand(NewListCommand(ctx))
 	cmd.AddCommand(NewPreviewCommand(ctx))
+	cmd.AddCommand(NewLintCommand(ctx))
+	cmd.AddCommand(NewGraphCommand(ctx))
 
 	// Add a stub run command that returns an error
 	cmd.AddCommand(&cobra.Command{
@@ -4,6 +4,13 @@
 package workflow
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
@@ -36,8 +43,8 @@ func TestNewWorkflowCommand_HasSubcommands(t *testing.T) {
 	cmd := NewWorkflowCommand(ctx)
 
 	subcommands := cmd.Commands()
-	if len(subcommands) != 3 {
-		t.Errorf("Expected 3 subcommands, got %d", len(subcommands))
+	if len(subcommands) != 5 {
+		t.Errorf("Expected 5 subcommands, got %d", len(subcommands))
 	}
 }
 
@@ -45,7 +52,7 @@ func TestNewWorkflowCommand_SubcommandNames(t *testing.T) {
 	ctx := &commands.AppContext{}
 	cmd := NewWorkflowCommand(ctx)
 
-	expectedCommands := []string{"run", "list", "preview"}
+	expectedCommands := []string{"run", "list", "preview", "lint", "graph"}
 	subcommands := cmd.Commands()
 
 	for _, expected := range expectedCommands {
@@ -604,3 +611,145 @@ func TestPreviewCommand_SupportedFormats(t *testing.T) {
 		t.Error("Expected default format to be tree")
 	}
 }
+
+func TestLintCommand_ReportsDeprecations(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deploy.sloth")
+	os.WriteFile(file, []byte("local ok, out = pkg.install({packages = {\"nginx\"}})\n"), 0644)
+
+	cmd := NewLintCommand(&commands.AppContext{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{file})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "deploy.sloth:1") || !strings.Contains(out.String(), "local out, err = pkg.install(...)") {
+		t.Errorf("Expected migration suggestion, got:\n%s", out.String())
+	}
+
+	os.WriteFile(file, []byte("module_api = 9\n"), 0644)
+	cmd.SetArgs([]string{file})
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected error for unsupported module_api")
+	}
+}
+
+func TestListCommand_ListsNamedWorkflows(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ops.sloth")
+	os.WriteFile(file, []byte(`
+local restart = task("restart"):command("systemctl restart nginx"):build()
+workflow.define("restart-web")
+	:description("Restart the web tier")
+	:tasks({ restart })
+	:on_complete(function() end)
+
+workflow.define("rotate-logs", {
+	tasks = {
+		{ name = "rotate", command = "logrotate -f /etc/logrotate.conf" }
+	}
+})
+`), 0644)
+
+	cmd := NewListCommand(&commands.AppContext{})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--file", file, "-o", "json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	var workflows []workflowInfo
+	if err := json.Unmarshal(out.Bytes(), &workflows); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(workflows) != 2 || workflows[0].Name != "restart-web" || workflows[1].Name != "rotate-logs" {
+		t.Fatalf("Expected restart-web and rotate-logs, got %+v", workflows)
+	}
+	if workflows[0].Description != "Restart the web tier" || workflows[1].Description != "" {
+		t.Errorf("Unexpected descriptions: %+v", workflows)
+	}
+	if len(workflows[0].Tasks) != 1 || workflows[0].Tasks[0] != "restart" {
+		t.Errorf("Expected restart-web to run restart, got %v", workflows[0].Tasks)
+	}
+}
+
+func TestGraphCommand_DrawsDependenciesAndDelegation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pipeline.sloth")
+	os.WriteFile(file, []byte(`
+workflow.define("deploy", {
+	description = "Ship the \"web\" tier",
+	delegate_to = "build-01",
+	tasks = {
+		{ name = "build", command = "make" },
+		{ name = "test", command = "make test", depends_on = "build" },
+		{ name = "release", command = "./release.sh", depends_on = { "build", "test", "sign" }, delegate_to = { "web-01", "web-02" } },
+		{ name = "notify", command = "echo done", depends_on = "release", delegate_to = "local" },
+	}
+})
+`), 0644)
+
+	run := func(args ...string) (string, error) {
+		cmd := NewGraphCommand(&commands.AppContext{})
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(append([]string{file}, args...))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	dot, err := run()
+	if err != nil {
+		t.Fatalf("Graph failed: %v", err)
+	}
+	for _, want := range []string{
+		`label="deploy\nShip the \"web\" tier";`,
+		`g0_t0 [label="build\n→ build-01"];`,
+		`g0_t2 [label="release\n→ web-01, web-02"];`,
+		`g0_t3 [label="notify"];`,
+		`[label="sign (missing)", style="rounded,dashed", color=red, fontcolor=red];`,
+		"g0_t0 -> g0_t1;", "g0_t0 -> g0_t2;", "g0_t1 -> g0_t2;", "g0_t2 -> g0_t3;",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected dot output to contain %s, got:\n%s", want, dot)
+		}
+	}
+
+	mermaid, err := run("--format", "mermaid", "--workflow", "deploy")
+	if err != nil {
+		t.Fatalf("Graph failed: %v", err)
+	}
+	if !strings.HasPrefix(mermaid, "flowchart LR\n") || !strings.Contains(mermaid, `g0_t2["release<br/><small>→ web-01, web-02</small>"]`) || !strings.Contains(mermaid, "g0_t1 --> g0_t2") {
+		t.Errorf("Unexpected mermaid output:\n%s", mermaid)
+	}
+
+	svg, err := run("--format", "svg")
+	if err != nil {
+		t.Fatalf("Graph failed: %v", err)
+	}
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	texts := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("Invalid SVG: %v\n%s", err, svg)
+			}
+			break
+		}
+		if el, ok := token.(xml.StartElement); ok && el.Name.Local == "text" {
+			texts++
+		}
+	}
+	// The title, 5 names and 3 delegation lines
+	if texts != 9 {
+		t.Errorf("Expected 9 texts in the SVG, got %d:\n%s", texts, svg)
+	}
+
+	if _, err := run("--format", "png"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, err := run("--workflow", "rollback"); err == nil || !strings.Contains(err.Error(), "available: deploy") {
+		t.Errorf("Expected an error listing the workflows, got %v", err)
+	}
+}
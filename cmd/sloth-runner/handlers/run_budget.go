@@ -0,0 +1,97 @@
+//go:build cgo
+// +build cgo
+
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/pterm/pterm"
+)
+
+// checkBudget refuses a run that exceeds the execution budget of the
+// stack, unless --override-budget gives a reason
+func (h *RunHandler) checkBudget(taskGroups map[string]types.TaskGroup, now time.Time) error {
+	h.agents = len(touchedAgents(taskGroups))
+
+	budget, err := h.stackService.GetBudget(h.config.StackName)
+	if err != nil {
+		return err
+	}
+	if budget.IsZero() {
+		return nil
+	}
+	usage, err := h.stackService.GetBudgetUsage(h.config.StackName, stack.BudgetDay(now))
+	if err != nil {
+		return err
+	}
+
+	violations := budget.Violations(usage, h.agents)
+	if len(violations) == 0 {
+		return nil
+	}
+	if h.config.OverrideBudget == "" {
+		return fmt.Errorf("stack %q is over its execution budget:\n  - %s\nuse --override-budget \"<reason>\" to run anyway",
+			h.config.StackName, strings.Join(violations, "\n  - "))
+	}
+
+	pterm.Warning.Printfln("Running over the execution budget: %s", h.config.OverrideBudget)
+	h.budgetOverride = strings.Join(violations, "; ")
+	return nil
+}
+
+// touchedAgents returns the agents the tasks of a run are delegated to
+func touchedAgents(taskGroups map[string]types.TaskGroup) []string {
+	seen := make(map[string]bool)
+	for _, group := range taskGroups {
+		for i := range group.Tasks {
+			for _, agent := range taskrunner.TaskAgents(&group.Tasks[i], group) {
+				if agent != "local" {
+					seen[agent] = true
+				}
+			}
+		}
+	}
+	agents := make([]string, 0, len(seen))
+	for agent := range seen {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	return agents
+}
+
+// recordBudgetOverride adds an --override-budget run and its reason to the
+// stack's activity log
+func (h *RunHandler) recordBudgetOverride(stackID string) {
+	if h.budgetOverride == "" {
+		return
+	}
+	who := "cli-user"
+	if u, err := user.Current(); err == nil {
+		who = u.Username
+	}
+	details := fmt.Sprintf("Run %s over the execution budget (%s): %s", h.config.RunID, h.budgetOverride, h.config.OverrideBudget)
+	if err := h.stackService.RecordActivity(stackID, "budget_override", details, who); err != nil {
+		slog.Warn("Failed to record budget override", "stack", h.config.StackName, "error", err)
+	}
+}
+
+// recordUsage counts the run and the minutes its tasks took against the
+// stack's budget
+func (h *RunHandler) recordUsage(stackID string, runner *taskrunner.TaskRunner, startTime time.Time) {
+	usage := stack.RunUsage{RunID: h.config.RunID, StartedAt: startTime, Agents: h.agents}
+	for _, result := range runner.Results {
+		usage.TaskMinutes += result.Duration.Minutes()
+	}
+	if err := h.stackService.RecordUsage(stackID, usage); err != nil {
+		slog.Warn("Failed to record stack usage", "stack", h.config.StackName, "error", err)
+	}
+}
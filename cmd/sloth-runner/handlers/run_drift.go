@@ -0,0 +1,218 @@
+//go:build cgo
+// +build cgo
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+)
+
+// driftedResource is a resource a drift check found diverging from the
+// workflow, with the state it was found in
+type driftedResource struct {
+	Task    string `json:"task"`
+	Host    string `json:"host,omitempty"`
+	Module  string `json:"module"`
+	Action  string `json:"action"`
+	Target  string `json:"target,omitempty"`
+	Current string `json:"current,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+}
+
+// name is the resource's name in the stack
+func (d driftedResource) name() string {
+	if d.Host == "" {
+		return d.Target
+	}
+	return d.Host + ":" + d.Target
+}
+
+// driftReport is the outcome of a drift check
+type driftReport struct {
+	Stack   string            `json:"stack"`
+	Drifted []driftedResource `json:"drifted"`
+	// Unchecked are calls whose module can't tell the state it would
+	// change, or delegated to agents too old to check tasks
+	Unchecked []driftedResource `json:"unchecked"`
+	// Failed are tasks whose check failed, by task name
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// newDriftReport sorts the changes of a drift check into the resources
+// that drifted and the calls that couldn't be checked
+func newDriftReport(stackName string, results []types.TaskResult) *driftReport {
+	report := &driftReport{Stack: stackName, Drifted: []driftedResource{}, Unchecked: []driftedResource{}}
+	for _, r := range results {
+		if r.Error != nil {
+			if report.Failed == nil {
+				report.Failed = make(map[string]string)
+			}
+			report.Failed[r.Name] = r.Error.Error()
+		}
+		for _, c := range r.Changes {
+			d := driftedResource{Task: r.Name, Host: c.Host, Module: c.Module, Action: c.Action, Target: c.Target, Current: c.Current, Diff: c.Diff}
+			if c.Unknown {
+				report.Unchecked = append(report.Unchecked, d)
+			} else {
+				report.Drifted = append(report.Drifted, d)
+			}
+		}
+	}
+	return report
+}
+
+// recordDrift records the drifted resources in the stack, marked with the
+// drift state, and resolves the drift of resources back in line
+func (h *RunHandler) recordDrift(stackID string, report *driftReport) error {
+	manager := h.stackService.GetManager()
+	drifted := make(map[string]bool, len(report.Drifted))
+	for _, d := range report.Drifted {
+		expected := map[string]interface{}{d.Module + "." + d.Action: "applied"}
+		actual := map[string]interface{}{d.Module + "." + d.Action: "differs"}
+		if d.Current != "" {
+			actual[d.Module+"."+d.Action] = d.Current
+		}
+
+		res, err := manager.GetResourceByStackAndName(stackID, d.Module, d.name())
+		if err != nil {
+			return err
+		}
+		properties := map[string]interface{}{"task": d.Task, "host": d.Host, "target": d.Target, "action": d.Action, "current": d.Current}
+		if res == nil {
+			res = &stack.Resource{
+				ID:           uuid.NewString(),
+				StackID:      stackID,
+				Type:         d.Module,
+				Name:         d.name(),
+				Module:       d.Module,
+				Properties:   properties,
+				Dependencies: []string{},
+				State:        "drift",
+				Metadata:     map[string]interface{}{},
+			}
+			if err := manager.CreateResource(res); err != nil {
+				return err
+			}
+		} else {
+			res.Properties = properties
+			res.State = "drift"
+			if err := manager.UpdateResource(res); err != nil {
+				return err
+			}
+		}
+		if err := h.stackService.DetectDrift(stackID, res.ID, expected, actual); err != nil {
+			return err
+		}
+		drifted[res.ID] = true
+	}
+
+	// Tasks that failed to check say nothing about their resources, so
+	// only a clean check resolves earlier drift
+	if len(report.Failed) > 0 {
+		return nil
+	}
+	resources, err := manager.ListResources(stackID)
+	if err != nil {
+		return err
+	}
+	for _, res := range resources {
+		if res.State != "drift" || drifted[res.ID] {
+			continue
+		}
+		res.State = "applied"
+		if err := manager.UpdateResource(res); err != nil {
+			return err
+		}
+		if err := h.stackService.ResolveDrift(stackID, res.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportDrift records and shows the outcome of a drift check (stack drift
+// detect). The stack must have run before: its workflow is what was applied.
+func (h *RunHandler) reportDrift(results []types.TaskResult, runErr error) error {
+	st, err := h.stackService.GetStackByName(h.config.StackName)
+	if err != nil || st == nil {
+		return fmt.Errorf("stack '%s' not found: run it before checking it for drift", h.config.StackName)
+	}
+
+	report := newDriftReport(h.config.StackName, results)
+	if err := h.recordDrift(st.ID, report); err != nil {
+		return fmt.Errorf("failed to record drift: %w", err)
+	}
+
+	if h.config.OutputStyle == "json" {
+		enc := json.NewEncoder(h.config.Writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		writeDriftReport(h.config.Writer, report)
+	}
+	if runErr != nil {
+		return fmt.Errorf("drift check failed: %w", runErr)
+	}
+	return nil
+}
+
+// writeDriftReport lists the drifted resources by task, with the state they
+// were found in and the diffs of drifted files
+func writeDriftReport(w io.Writer, report *driftReport) {
+	fmt.Fprintf(w, "\n%s\n", pterm.Bold.Sprintf("Drift: %d resource(s) drifted, %d call(s) not checked", len(report.Drifted), len(report.Unchecked)))
+	if len(report.Drifted) == 0 && len(report.Failed) == 0 {
+		fmt.Fprintln(w, pterm.Green("No drift. The systems match the stack's workflow."))
+	}
+
+	task := ""
+	for _, d := range report.Drifted {
+		if d.Task != task {
+			task = d.Task
+			fmt.Fprintf(w, "\n  %s\n", pterm.Cyan(task))
+		}
+		found := ""
+		if d.Current != "" {
+			found = pterm.Gray(fmt.Sprintf("(found %s)", d.Current))
+		}
+		fmt.Fprintf(w, "    %s %s %s.%s %s %s\n", pterm.Yellow("~"), hostLabel(d.Host), d.Module, d.Action, d.Target, found)
+		writeDiff(w, d.Diff)
+	}
+
+	if len(report.Unchecked) > 0 {
+		fmt.Fprintf(w, "\n  %s\n", pterm.Gray("Not checked:"))
+		for _, d := range report.Unchecked {
+			reason := "can't tell its state"
+			if d.Current != "" {
+				reason = d.Current
+			}
+			fmt.Fprintf(w, "    %s %s %s.%s %s %s\n", pterm.Yellow("?"), hostLabel(d.Host), d.Module, d.Action, d.Target, pterm.Gray("("+reason+")"))
+		}
+	}
+	failed := make([]string, 0, len(report.Failed))
+	for name := range report.Failed {
+		failed = append(failed, name)
+	}
+	sort.Strings(failed)
+	for _, name := range failed {
+		fmt.Fprintf(w, "\n  %s %s: %s\n", pterm.Red("✗"), name, report.Failed[name])
+	}
+}
+
+// hostLabel names the agent a resource is on, "local" when it's local
+func hostLabel(host string) string {
+	if host == "" {
+		return "[local]"
+	}
+	return "[" + host + "]"
+}
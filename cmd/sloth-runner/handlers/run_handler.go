@@ -6,28 +6,41 @@ package handlers
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"os/user"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	lua "github.com/yuin/gopher-lua"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"gopkg.in/yaml.v3"
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
 	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	"github.com/chalkan3-sloth/sloth-runner/internal/output"
+	"github.com/chalkan3-sloth/sloth-runner/internal/policy"
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
+	"github.com/chalkan3-sloth/sloth-runner/internal/runlog"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
 	sshpkg "github.com/chalkan3-sloth/sloth-runner/internal/ssh"
 	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sudo"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
+	"golang.org/x/term"
 )
 
 // RunConfig holds configuration for running tasks
@@ -45,8 +58,24 @@ type RunConfig struct {
 	YesFlag          bool
 	Context          context.Context
 	Writer           io.Writer
-	AgentRegistry    interface{} // Will be properly typed later
-	RunID            string       // Unique run identifier for event tracking
+	AgentRegistry    interface{}   // Will be properly typed later
+	RunID            string        // Unique run identifier for event tracking
+	FlakyPolicy      string        // off, warn (default) or quarantine
+	RunnerVersion    string        // Checked against the file's min_runner_version
+	PolicyFile       string        // Admission policies; defaults to <data-dir>/policies.yaml
+	Approve          []string      // Policies requiring approval that are approved up front
+	Local            bool          // Run every task in-process, ignoring delegate_to
+	OverrideWindow   string        // Reason for running outside the maintenance windows
+	OverrideBudget   string        // Reason for running over the stack's execution budget
+	AskSudoPass      bool          // Prompt for a sudo password for every agent, not only those in <data-dir>/sudo.yaml
+	Workflow         string        // Named workflow of the file to run; every workflow when empty
+	Tasks            []string      // Tasks to run with the tasks they depend on; every task when empty
+	DryRun           bool          // Plan the run in check mode without changing the system or the stack
+	Sloth            string        // Saved sloth file FilePath was written from, if any
+	Schedule         string        // Workflow schedule that started the run, if any
+	Resume           bool          // RunID is an interrupted run to resume
+	LockTimeout      time.Duration // How long to wait for another run to release the stack; 0 fails at once
+	Drift            bool          // With DryRun, check the stack's workflow on its agents and record the drift found
 }
 
 // RunHandler handles the run command logic
@@ -54,6 +83,21 @@ type RunConfig struct {
 type RunHandler struct {
 	stackService *services.StackService
 	config       *RunConfig
+	flakyTasks   []*execution.FlakyTask
+	metadata     *sloth.Metadata
+	// values are the run's values and params, recorded with its history
+	values map[string]interface{}
+	// windowOverride describes the maintenance windows this run overrides,
+	// recorded in the stack's activity log once the stack exists
+	windowOverride string
+	// budgetOverride describes the budget limits this run exceeds, recorded
+	// like windowOverride
+	budgetOverride string
+	// agents is the number of agents the run's tasks are delegated to
+	agents int
+	// sudoPassword gives delegated tasks the sudo password of their agent
+	// (nil when no agent needs one)
+	sudoPassword taskctx.SudoPasswordFunc
 }
 
 // NewRunHandler creates a new run handler
@@ -71,6 +115,45 @@ func (h *RunHandler) Execute() error {
 		return err
 	}
 
+	// Check the file's front-matter before running any Lua
+	if err := h.checkFrontMatter(); err != nil {
+		return err
+	}
+
+	// Refuse runs outside the maintenance windows unless overridden. Drift
+	// checks change nothing, so they run at any time.
+	if !h.config.Drift {
+		if err := h.checkMaintenanceWindows(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// With --debug, debug.breakpoint() pauses in an inspector. It writes
+	// to stderr to stay out of run logs and JSON output.
+	if h.config.Debug {
+		luainterface.EnableDebugger(luainterface.NewDebugger(os.Stdin, os.Stderr))
+		defer luainterface.DisableDebugger()
+	}
+
+	// Load admission policies before any Lua state is created so
+	// privileged module calls are checked
+	gate, err := h.loadPolicyGate()
+	if err != nil {
+		return err
+	}
+	if gate != nil {
+		policy.SetActive(gate)
+		defer policy.SetActive(nil)
+	}
+
+	// Agents whose sudo asks for a password get it from the run
+	sudoPassword, forgetSudoPassword, err := h.loadSudoPasswords()
+	if err != nil {
+		return err
+	}
+	defer forgetSudoPassword()
+	h.sudoPassword = sudoPassword
+
 	// Initialize SSH executor if needed
 	sshExecutor, sshPassword, err := h.initializeSSH()
 	if err != nil {
@@ -93,8 +176,28 @@ func (h *RunHandler) Execute() error {
 		return err
 	}
 
-	// Apply delegate-to hosts
-	h.applyDelegateToHosts(taskGroups)
+	// Run only the named workflow of a file defining several
+	if h.config.Workflow != "" {
+		if taskGroups, err = selectWorkflow(taskGroups, h.config.Workflow, h.config.FilePath); err != nil {
+			return err
+		}
+	}
+
+	// Run only the named tasks, in the workflow that defines them
+	if len(h.config.Tasks) > 0 {
+		if taskGroups, err = selectTasks(taskGroups, h.config.Tasks, h.config.FilePath); err != nil {
+			return err
+		}
+	}
+
+	// Apply delegate-to hosts, or drop delegation entirely in local mode
+	if h.config.Local {
+		runAllLocally(taskGroups)
+	} else if len(h.config.DelegateToHosts) > 0 {
+		h.applyDelegateToHosts(taskGroups)
+	} else if err := h.applyDefaultTarget(taskGroups); err != nil {
+		return err
+	}
 
 	if len(taskGroups) == 0 {
 		h.handleEmptyTaskGroups(enhancedOutput)
@@ -104,6 +207,23 @@ func (h *RunHandler) Execute() error {
 	// Get workflow name
 	workflowName := h.getWorkflowName(taskGroups)
 
+	// Check run policies for every task and the agents it runs on
+	if gate != nil {
+		if err := checkRunPolicies(gate, taskGroups); err != nil {
+			return err
+		}
+	}
+
+	// A dry run only shows what the tasks would change
+	if h.config.DryRun {
+		return h.planTasks(taskGroups, sshExecutor, sshPassword)
+	}
+
+	// Refuse runs over the stack's execution budget unless overridden
+	if err := h.checkBudget(taskGroups, time.Now()); err != nil {
+		return err
+	}
+
 	// Show preview and confirm if needed
 	if err := h.showPreviewAndConfirm(workflowName, taskGroups); err != nil {
 		return err
@@ -115,6 +235,19 @@ func (h *RunHandler) Execute() error {
 		return err
 	}
 
+	// Only one run at a time changes the stack
+	if !h.config.Local {
+		unlock, err := h.lockStack(stackID, workflowName)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	// Audit runs that override the maintenance windows
+	h.recordWindowOverride(stackID)
+	h.recordBudgetOverride(stackID)
+
 	// Load secrets if password is provided
 	secrets, err := h.loadSecrets(stackID)
 	if err != nil {
@@ -133,6 +266,246 @@ func (h *RunHandler) validateInputs() error {
 	if h.config.FilePath == "" {
 		return fmt.Errorf("workflow file is required (use --file flag)")
 	}
+	if h.config.FlakyPolicy != "" {
+		if err := execution.ValidateFlakyPolicy(h.config.FlakyPolicy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFrontMatter reads the front-matter of a v2 sloth file and checks
+// that this runner can run it
+func (h *RunHandler) checkFrontMatter() error {
+	meta, _, err := sloth.ReadFile(h.config.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read workflow: %w", err)
+	}
+	h.metadata = meta
+
+	if meta.Format != sloth.FormatV2 {
+		return nil
+	}
+
+	var moduleAvailable func(string) bool
+	if len(meta.Modules) > 0 {
+		L := lua.NewState()
+		defer L.Close()
+		luainterface.RegisterAllModules(L)
+		moduleAvailable = func(name string) bool {
+			return luainterface.ModuleAvailable(L, name)
+		}
+	}
+
+	return meta.CheckCompatibility(h.config.RunnerVersion, moduleAvailable)
+}
+
+// checkMaintenanceWindows refuses a run outside the maintenance windows of
+// the stack or the workflow, unless --override-window gives a reason
+func (h *RunHandler) checkMaintenanceWindows(now time.Time) error {
+	specs, err := h.stackService.GetMaintenanceWindows(h.config.StackName)
+	if err != nil {
+		return err
+	}
+	stackWindows, err := sloth.ParseWindows(specs)
+	if err != nil {
+		return fmt.Errorf("stack %q: %w", h.config.StackName, err)
+	}
+	var workflowWindows sloth.Windows
+	if h.metadata != nil {
+		workflowWindows = h.metadata.MaintenanceWindows()
+	}
+
+	violations := windowViolations(now, stackWindows, workflowWindows)
+	if len(violations) == 0 {
+		return nil
+	}
+	if h.config.OverrideWindow == "" {
+		return fmt.Errorf("outside the maintenance window:\n  - %s\nuse --override-window \"<reason>\" to run anyway",
+			strings.Join(violations, "\n  - "))
+	}
+
+	pterm.Warning.Printfln("Running outside the maintenance window: %s", h.config.OverrideWindow)
+	h.windowOverride = strings.Join(violations, "; ")
+	return nil
+}
+
+// windowViolations describes the window sets now is outside of
+func windowViolations(now time.Time, stackWindows, workflowWindows sloth.Windows) []string {
+	var violations []string
+	check := func(owner string, windows sloth.Windows) {
+		if windows.Allows(now) {
+			return
+		}
+		violations = append(violations, fmt.Sprintf("%s allows runs %s; the next window opens %s",
+			owner, windows, windows.NextOpen(now).Format(time.RFC1123)))
+	}
+	check("the stack", stackWindows)
+	check("the workflow", workflowWindows)
+	return violations
+}
+
+// recordWindowOverride adds an --override-window run and its reason to the
+// stack's activity log
+func (h *RunHandler) recordWindowOverride(stackID string) {
+	if h.windowOverride == "" {
+		return
+	}
+	who := "cli-user"
+	if u, err := user.Current(); err == nil {
+		who = u.Username
+	}
+	details := fmt.Sprintf("Run %s outside the maintenance window (%s): %s", h.config.RunID, h.windowOverride, h.config.OverrideWindow)
+	if err := h.stackService.RecordActivity(stackID, "window_override", details, who); err != nil {
+		slog.Warn("Failed to record maintenance window override", "stack", h.config.StackName, "error", err)
+	}
+}
+
+// loadPolicyGate loads the admission policies for this run, or returns nil
+// when none are configured
+func (h *RunHandler) loadPolicyGate() (*policy.Gate, error) {
+	engine, err := policy.LoadDefault(h.config.PolicyFile, config.GetPolicyPath())
+	if err != nil || engine == nil {
+		return nil, err
+	}
+
+	base := policy.Input{Stack: h.config.StackName, Agent: "local"}
+	if u, err := user.Current(); err == nil {
+		base.User = u.Username
+	}
+
+	var prompt policy.PromptFunc
+	if !taskrunner.IsNonInteractive() {
+		prompt = approvePolicy
+	}
+	return policy.NewGate(engine, base, h.config.Approve, prompt), nil
+}
+
+// loadSudoPasswords returns the function delegated tasks get the sudo
+// password of their agent from: with --ask-sudo-pass every agent gets it,
+// otherwise the agents and groups listed in <data-dir>/sudo.yaml. The
+// password is prompted for once, before the run starts when a task is
+// delegated to such an agent (see resolveSudoPasswords), and forget wipes
+// it.
+func (h *RunHandler) loadSudoPasswords() (f taskctx.SudoPasswordFunc, forget func(), err error) {
+	var sudoConfig *sudo.Config
+	if !h.config.AskSudoPass {
+		sudoConfig, err = sudo.LoadConfig(config.GetSudoConfigPath())
+		if err != nil || sudoConfig == nil {
+			return nil, func() {}, err
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		password []byte
+		members  = make(map[string][]string)
+	)
+	groupAgents := func(group string) ([]string, error) {
+		if agents, ok := members[group]; ok {
+			return agents, nil
+		}
+		agents, err := taskrunner.AgentGroupAgents(group)
+		if err != nil {
+			return nil, err
+		}
+		members[group] = agents
+		return agents, nil
+	}
+
+	f = func(agent string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if sudoConfig != nil {
+			needed, err := sudoConfig.Matches(agent, groupAgents)
+			if err != nil || !needed {
+				return nil, err
+			}
+		}
+		if password == nil {
+			p, err := readPassword("sudo password", "SUDO password:", func() (string, error) {
+				p, err := sudo.ReadPassword(os.Stdin)
+				return string(p), err
+			})
+			if err != nil {
+				return nil, err
+			}
+			password = []byte(p)
+		}
+		return password, nil
+	}
+	forget = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		sudo.Wipe(password)
+	}
+	return f, forget, nil
+}
+
+// resolveSudoPasswords asks for the sudo password up front when a task of
+// the run is delegated to an agent that needs it, so the run never stops
+// halfway through to prompt, and fails before any change when it can't
+// ask. Agents only known once a task runs still get it asked for then.
+func (h *RunHandler) resolveSudoPasswords(taskGroups map[string]types.TaskGroup) error {
+	agents, err := taskrunner.DelegatedAgents(taskGroups)
+	if err != nil {
+		return err
+	}
+	for _, agent := range agents {
+		password, err := h.sudoPassword(agent)
+		if err != nil {
+			return fmt.Errorf("failed to get sudo password for agent %s: %w", agent, err)
+		}
+		if password != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// approvePolicy asks the user to approve an action a policy flagged
+func approvePolicy(d policy.Decision, in policy.Input) bool {
+	pterm.Warning.Printf("Policy %q requires approval: %s\n", d.Policy, d.Message)
+	target := fmt.Sprintf("task %s on %s", in.Task, in.Agent)
+	if in.Stage == policy.StageModule {
+		target = fmt.Sprintf("%s.%s on %s", in.Module, in.Function, in.Agent)
+	}
+	return confirmAction(fmt.Sprintf("Approve %s?", target))
+}
+
+// confirmAction asks the user to confirm an action a module is about to
+// take. When it can't ask, the action is declined and the error names the
+// approval flag.
+func confirmAction(message string) bool {
+	confirmed := false
+	if err := taskrunner.Ask(nil, taskrunner.Prompt{Name: "confirmation", Flag: "--approve", Prompt: &survey.Confirm{Message: message}}, &confirmed); err != nil {
+		pterm.Warning.Println(err)
+		return false
+	}
+	return confirmed
+}
+
+// checkRunPolicies evaluates run policies for every task and each agent it
+// is delegated to
+func checkRunPolicies(gate *policy.Gate, taskGroups map[string]types.TaskGroup) error {
+	groupNames := make([]string, 0, len(taskGroups))
+	for name := range taskGroups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+
+	for _, groupName := range groupNames {
+		group := taskGroups[groupName]
+		for i := range group.Tasks {
+			t := &group.Tasks[i]
+			for _, agent := range taskrunner.TaskAgents(t, group) {
+				in := policy.Input{Stage: policy.StageRun, Workflow: groupName, Task: t.Name, Agent: agent}
+				if err := gate.Check(in); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -155,7 +528,7 @@ func (h *RunHandler) initializeSSH() (*sshpkg.Executor, *string, error) {
 	// Handle password if requested
 	var password *string
 	if h.config.SSHPasswordStdin {
-		pwd, err := sshpkg.ReadPasswordFromStdin()
+		pwd, err := readPassword("SSH password", "SSH Password:", sshpkg.ReadPasswordFromStdin)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to read password: %w", err)
 		}
@@ -172,6 +545,17 @@ func (h *RunHandler) initializeSSH() (*sshpkg.Executor, *string, error) {
 	return executor, password, nil
 }
 
+// readPassword asks for a password without echoing it when stdin is a
+// terminal, and otherwise takes the one piped in with readPiped
+func readPassword(name, message string, readPiped func() (string, error)) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return readPiped()
+	}
+	var password string
+	err := taskrunner.Ask(nil, taskrunner.Prompt{Name: name, Flag: "a password piped on stdin", Prompt: &survey.Password{Message: message}}, &password)
+	return password, err
+}
+
 // cleanupSSH cleans up SSH password from memory
 func (h *RunHandler) cleanupSSH(password *string) {
 	if password != nil {
@@ -186,13 +570,16 @@ func (h *RunHandler) loadSecrets(stackID string) (map[string]string, error) {
 		return nil, nil
 	}
 
-	// Read password from stdin
-	reader := bufio.NewReader(os.Stdin)
-	password, err := reader.ReadString('\n')
-	if err != nil && err != io.EOF {
-		return nil, fmt.Errorf("failed to read password from stdin: %w", err)
+	password, err := readPassword("secrets password", "Password to decrypt the stack's secrets:", func() (string, error) {
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		return strings.TrimSpace(password), nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	password = strings.TrimSpace(password)
 
 	if password == "" {
 		return nil, fmt.Errorf("password cannot be empty")
@@ -255,26 +642,40 @@ func (h *RunHandler) initializeOutput() *output.PulumiStyleOutput {
 
 // loadValues loads values.yaml if specified
 func (h *RunHandler) loadValues(enhancedOutput *output.PulumiStyleOutput) (*lua.LTable, error) {
-	if h.config.Values == "" {
+	hasParams := h.metadata != nil && len(h.metadata.Params) > 0
+	if h.config.Values == "" && !hasParams {
 		return nil, nil
 	}
 
-	if enhancedOutput != nil {
-		enhancedOutput.Info(fmt.Sprintf("Loading values from: %s", h.config.Values))
-	} else {
-		fmt.Fprintf(h.config.Writer, "Loading values from: %s\n", h.config.Values)
-	}
+	var valuesMap map[string]interface{}
+	if h.config.Values != "" {
+		if enhancedOutput != nil {
+			enhancedOutput.Info(fmt.Sprintf("Loading values from: %s", h.config.Values))
+		} else {
+			fmt.Fprintf(h.config.Writer, "Loading values from: %s\n", h.config.Values)
+		}
 
-	valuesData, err := os.ReadFile(h.config.Values)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read values file: %w", err)
+		valuesData, err := os.ReadFile(h.config.Values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %w", err)
+		}
+
+		if err := yaml.Unmarshal(valuesData, &valuesMap); err != nil {
+			return nil, fmt.Errorf("failed to parse values file: %w", err)
+		}
 	}
 
-	var valuesMap map[string]interface{}
-	if err := yaml.Unmarshal(valuesData, &valuesMap); err != nil {
-		return nil, fmt.Errorf("failed to parse values file: %w", err)
+	// Values are the workflow's params: check them against the
+	// front-matter schema and fill in defaults
+	if hasParams {
+		var err error
+		if valuesMap, err = h.metadata.ApplyParams(valuesMap); err != nil {
+			return nil, err
+		}
 	}
 
+	h.values = valuesMap
+
 	tempL := lua.NewState()
 	defer tempL.Close()
 	return mapToLuaTable(tempL, valuesMap), nil
@@ -287,6 +688,9 @@ func (h *RunHandler) parseLuaScript(valuesTable *lua.LTable, enhancedOutput *out
 		if enhancedOutput != nil {
 			enhancedOutput.Error(fmt.Sprintf("Failed to parse Lua script: %v", err))
 		}
+		if h.config.OutputStyle != "json" {
+			h.reportScriptErrors(err, nil)
+		}
 		return nil, fmt.Errorf("failed to parse Lua script: %w", err)
 	}
 	return taskGroups, nil
@@ -315,6 +719,97 @@ func (h *RunHandler) applyDelegateToHosts(taskGroups map[string]types.TaskGroup)
 	}
 }
 
+// applyDefaultTarget sends the tasks that don't set delegate_to to the
+// stack's default target agent
+func (h *RunHandler) applyDefaultTarget(taskGroups map[string]types.TaskGroup) error {
+	agent, err := h.stackService.GetDefaultTarget(h.config.StackName)
+	if err != nil {
+		return err
+	}
+	if agent == "" {
+		return nil
+	}
+
+	if groups := defaultTargetGroups(taskGroups, agent); len(groups) > 0 {
+		pterm.Info.Printfln("Tasks without delegate_to run on '%s', the default target of stack '%s'", agent, h.config.StackName)
+		if h.config.Debug {
+			slog.Debug("Applied the stack's default target", "agent", agent, "groups", groups)
+		}
+	}
+	return nil
+}
+
+// defaultTargetGroups delegates the groups that set no delegate_to to
+// agent, so their tasks run there unless they set their own (delegate_to =
+// "local" keeps a task on this machine). It returns the groups changed,
+// sorted.
+func defaultTargetGroups(taskGroups map[string]types.TaskGroup, agent string) []string {
+	var changed []string
+	for groupName, group := range taskGroups {
+		if group.DelegateTo != nil {
+			continue
+		}
+		group.DelegateTo = agent
+		taskGroups[groupName] = group
+		changed = append(changed, groupName)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// selectWorkflow keeps only the named workflow of the file's task groups
+func selectWorkflow(taskGroups map[string]types.TaskGroup, name, filePath string) (map[string]types.TaskGroup, error) {
+	group, ok := taskGroups[name]
+	if !ok {
+		names := make([]string, 0, len(taskGroups))
+		for n := range taskGroups {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("workflow %q not found in %s (available: %s)", name, filePath, strings.Join(names, ", "))
+	}
+	return map[string]types.TaskGroup{name: group}, nil
+}
+
+// selectTasks keeps only the workflow of the file's task groups that
+// defines every one of tasks
+func selectTasks(taskGroups map[string]types.TaskGroup, tasks []string, filePath string) (map[string]types.TaskGroup, error) {
+	var found []string
+	for name, group := range taskGroups {
+		defined := make(map[string]bool, len(group.Tasks))
+		for _, t := range group.Tasks {
+			defined[t.Name] = true
+		}
+		all := true
+		for _, task := range tasks {
+			all = all && defined[task]
+		}
+		if all {
+			found = append(found, name)
+		}
+	}
+	sort.Strings(found)
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no workflow in %s defines task %s", filePath, strings.Join(tasks, ", "))
+	case 1:
+		return map[string]types.TaskGroup{found[0]: taskGroups[found[0]]}, nil
+	}
+	return nil, fmt.Errorf("workflows %s of %s all define task %s; pick one with --workflow", strings.Join(found, ", "), filePath, strings.Join(tasks, ", "))
+}
+
+// runAllLocally clears delegate_to on every group and task so they run in
+// this process
+func runAllLocally(taskGroups map[string]types.TaskGroup) {
+	for groupName, group := range taskGroups {
+		group.DelegateTo = nil
+		for i := range group.Tasks {
+			group.Tasks[i].DelegateTo = nil
+		}
+		taskGroups[groupName] = group
+	}
+}
+
 // handleEmptyTaskGroups handles the case when no task groups are found
 func (h *RunHandler) handleEmptyTaskGroups(enhancedOutput *output.PulumiStyleOutput) {
 	if enhancedOutput != nil {
@@ -341,7 +836,9 @@ func (h *RunHandler) getWorkflowName(taskGroups map[string]types.TaskGroup) stri
 
 // showPreviewAndConfirm shows execution plan preview and asks for confirmation
 func (h *RunHandler) showPreviewAndConfirm(workflowName string, taskGroups map[string]types.TaskGroup) error {
-	if h.config.YesFlag || h.config.StackName == "" {
+	// Local runs start from an empty throwaway stack, so there is no plan
+	// to compare against
+	if h.config.YesFlag || h.config.Local || h.config.StackName == "" {
 		return nil
 	}
 
@@ -354,7 +851,7 @@ func (h *RunHandler) showPreviewAndConfirm(workflowName string, taskGroups map[s
 		Message: "Do you want to proceed with this execution plan?",
 		Default: true,
 	}
-	if err := survey.AskOne(prompt, &confirm); err != nil {
+	if err := taskrunner.Ask(nil, taskrunner.Prompt{Name: "confirmation", Flag: "--yes", Prompt: prompt}, &confirm); err != nil {
 		return fmt.Errorf("confirmation cancelled: %w", err)
 	}
 
@@ -376,8 +873,9 @@ func (h *RunHandler) executeTasks(
 	sshPassword *string,
 	secrets map[string]string,
 ) error {
-	// Read Lua script content
-	luaScriptContent, err := os.ReadFile(h.config.FilePath)
+	// Read Lua script content, without front-matter and rendered like
+	// when it was parsed, so agents run the same script
+	_, luaScriptContent, err := sloth.RenderFile(h.config.FilePath, h.values)
 	if err != nil {
 		return fmt.Errorf("failed to read Lua script file: %w", err)
 	}
@@ -388,6 +886,9 @@ func (h *RunHandler) executeTasks(
 
 	luainterface.RegisterAllModules(L)
 	luainterface.OpenImport(L, h.config.FilePath)
+	if h.values != nil {
+		L.SetGlobal("values", mapToLuaTable(L, h.values))
+	}
 
 	if sshExecutor != nil {
 		luainterface.SetSSHExecutor(sshExecutor, h.config.SSHProfile, sshPassword)
@@ -412,22 +913,54 @@ func (h *RunHandler) executeTasks(
 		}
 	}
 
-	runner := taskrunner.NewTaskRunner(L, taskGroups, "", nil, false, h.config.Interactive, &taskrunner.DefaultSurveyAsker{}, string(luaScriptContent))
+	runner := taskrunner.NewTaskRunner(L, taskGroups, "", h.config.Tasks, false, h.config.Interactive, taskrunner.NewSurveyAsker(), string(luaScriptContent))
 
 	// Set execution context for event tracking
 	runner.Stack = h.config.StackName
 	runner.RunID = h.config.RunID
+	if h.metadata != nil {
+		runner.Assets = h.metadata.Assets
+	}
 
-	// Configure agent resolver
-	h.configureAgentResolver(runner)
+	// Let modules see the run's approvals and ask for confirmation
+	runner.BaseContext = taskctx.WithApprovals(context.Background(), h.config.Approve)
+	if !taskrunner.IsNonInteractive() {
+		runner.BaseContext = taskctx.WithConfirm(runner.BaseContext, confirmAction)
+	}
+
+	// Configure agent resolver; local runs never contact a master
+	if !h.config.Local {
+		h.configureAgentResolver(runner)
+	}
+
+	// Delegated tasks get their agent's sudo password, asked for before
+	// the first task runs
+	if h.sudoPassword != nil {
+		if err := h.resolveSudoPasswords(taskGroups); err != nil {
+			return err
+		}
+		runner.BaseContext = taskctx.WithSudoPassword(runner.BaseContext, h.sudoPassword)
+	}
 
 	runner.Outputs = make(map[string]interface{})
 
+	stopJournal, err := h.startJournal(workflowName, runner)
+	if err != nil {
+		return err
+	}
+	defer stopJournal()
+
 	if enhancedOutput != nil {
 		runner.SetPulumiOutput(enhancedOutput)
 		enhancedOutput.WorkflowStart(workflowName, "Executing workflow")
 	}
 
+	// Report the run to change-management systems before it changes anything
+	notifier, err := h.startIntegrations(workflowName, secrets)
+	if err != nil {
+		return err
+	}
+
 	// Create pre-execution snapshot
 	preExecutionVersion, snapshotErr := h.stackService.CreateSnapshot(
 		stackID,
@@ -456,12 +989,23 @@ func (h *RunHandler) executeTasks(
 		fmt.Fprintf(h.config.Writer, "Executing tasks from: %s\n", h.config.FilePath)
 	}
 
+	h.applyQuarantine(runner)
+
+	luainterface.ResetDeprecations()
+	stopRunLog := h.startRunLog(workflowName)
 	startTime := time.Now()
 	err = runner.Run()
 	duration := time.Since(startTime)
+	stopRunLog()
+
+	h.recordTaskRuns(runner, startTime)
+	h.recordUsage(stackID, runner, startTime)
+	h.recordHistory(runner, workflowName, startTime, duration, err)
+	h.finishIntegrations(notifier, runner, duration, err)
 
-	// Re-execute script to capture outputs
-	if reExecErr := runner.L.DoFile(h.config.FilePath); reExecErr != nil {
+	// Re-execute script to capture outputs, without stopping at breakpoints again
+	luainterface.DisableDebugger()
+	if reExecErr := luainterface.DoSlothFile(runner.L, h.config.FilePath); reExecErr != nil {
 		slog.Warn("Failed to re-execute script for outputs", "error", reExecErr)
 	}
 
@@ -499,6 +1043,156 @@ func (h *RunHandler) executeTasks(
 	return h.handleResults(err, duration, workflowName, stackID, runner, exportedOutputs, enhancedOutput)
 }
 
+// startRunLog captures the run output into the run log store.
+// Failures are only logged; they never affect the run itself.
+func (h *RunHandler) startRunLog(workflowName string) func() {
+	noop := func() {}
+	if h.config.Interactive {
+		// Prompts need the terminal attached directly
+		return noop
+	}
+
+	store, err := runlog.NewStore(config.GetRunLogDBPath())
+	if err != nil {
+		slog.Warn("Failed to open run log store", "error", err)
+		return noop
+	}
+	w, err := store.Create(h.config.StackName, h.config.RunID, workflowName)
+	if err != nil {
+		slog.Warn("Failed to create run log", "error", err)
+		store.Close()
+		return noop
+	}
+	stop, stdout, err := runlog.Capture(w)
+	if err != nil {
+		slog.Debug("Run output capture unavailable", "error", err)
+		w.Close()
+		store.Close()
+		return noop
+	}
+	// Confirmations during the run still talk to the terminal
+	restorePrompts := taskrunner.SetPromptTerminal(stdout)
+
+	return func() {
+		restorePrompts()
+		if err := stop(); err != nil {
+			slog.Warn("Failed to restore output after capture", "error", err)
+		}
+		if err := w.Close(); err != nil {
+			slog.Warn("Failed to store run log", "error", err)
+		}
+		store.Close()
+	}
+}
+
+// applyQuarantine marks the stack's flaky tasks as quarantined when the
+// quarantine policy is selected
+func (h *RunHandler) applyQuarantine(runner *taskrunner.TaskRunner) {
+	if h.config.FlakyPolicy != execution.FlakyPolicyQuarantine {
+		return
+	}
+
+	db, err := execution.NewHistoryDB(config.GetHistoryDBPath())
+	if err != nil {
+		slog.Warn("Failed to open history database", "error", err)
+		return
+	}
+	defer db.Close()
+
+	flaky, err := db.FlakyTasks(h.config.StackName)
+	if err != nil {
+		slog.Warn("Failed to load flaky tasks", "error", err)
+		return
+	}
+	if len(flaky) == 0 {
+		return
+	}
+
+	runner.Quarantine = make(map[string]bool, len(flaky))
+	for _, f := range flaky {
+		runner.Quarantine[f.TaskName] = true
+	}
+	slog.Info("quarantining flaky tasks", "stack", h.config.StackName, "count", len(flaky))
+}
+
+// recordTaskRuns stores each task's outcome for flaky task detection and
+// keeps the flaky tasks of this run for the summary
+func (h *RunHandler) recordTaskRuns(runner *taskrunner.TaskRunner, startTime time.Time) {
+	runs := taskRunsFromResults(h.config.StackName, h.config.RunID, startTime, runner)
+	if len(runs) == 0 {
+		return
+	}
+
+	db, err := execution.NewHistoryDB(config.GetHistoryDBPath())
+	if err != nil {
+		slog.Warn("Failed to open history database", "error", err)
+		return
+	}
+	defer db.Close()
+
+	if err := db.RecordTaskRuns(runs); err != nil {
+		slog.Warn("Failed to record task runs", "error", err)
+		return
+	}
+
+	if h.config.FlakyPolicy == execution.FlakyPolicyOff {
+		return
+	}
+	flaky, err := db.FlakyTasks(h.config.StackName)
+	if err != nil {
+		slog.Warn("Failed to load flaky tasks", "error", err)
+		return
+	}
+	ran := make(map[string]bool, len(runs))
+	for _, r := range runs {
+		ran[r.TaskName] = true
+	}
+	for _, f := range flaky {
+		if ran[f.TaskName] {
+			h.flakyTasks = append(h.flakyTasks, f)
+		}
+	}
+}
+
+// taskRunsFromResults folds the runner results, which hold one entry per
+// attempt, into one outcome per task
+func taskRunsFromResults(stackName, runID string, startTime time.Time, runner *taskrunner.TaskRunner) []*execution.TaskRun {
+	quarantined := make(map[string]bool, len(runner.QuarantinedFailures))
+	for _, name := range runner.QuarantinedFailures {
+		quarantined[name] = true
+	}
+
+	var runs []*execution.TaskRun
+	byName := make(map[string]*execution.TaskRun)
+	for _, result := range runner.Results {
+		if result.Status != "Success" && result.Status != "Failed" {
+			continue
+		}
+
+		run, ok := byName[result.Name]
+		if !ok {
+			run = &execution.TaskRun{
+				Stack:       stackName,
+				RunID:       runID,
+				TaskName:    result.Name,
+				Quarantined: quarantined[result.Name],
+				StartTime:   startTime.Unix(),
+			}
+			byName[result.Name] = run
+			runs = append(runs, run)
+		}
+
+		run.Attempts++
+		if result.Status == "Failed" {
+			run.Status = execution.StatusFailed
+		} else {
+			run.Recovered = run.Status == execution.StatusFailed
+			run.Status = execution.StatusCompleted
+		}
+	}
+	return runs
+}
+
 // configureAgentResolver configures the agent resolver
 func (h *RunHandler) configureAgentResolver(runner *taskrunner.TaskRunner) {
 	if h.config.AgentRegistry != nil {
@@ -535,7 +1229,24 @@ func (h *RunHandler) getExportedOutputs(runner *taskrunner.TaskRunner) map[strin
 		})
 	}
 
-	return exportedOutputs
+	return sealSensitiveOutputs(exportedOutputs, runner.Sensitive)
+}
+
+// sealSensitiveOutputs encrypts the outputs marked sensitive so they never
+// reach the run record in the clear
+func sealSensitiveOutputs(outputs map[string]interface{}, sensitive map[string]bool) map[string]interface{} {
+	if len(sensitive) == 0 {
+		return outputs
+	}
+	key, err := redact.LoadOrCreateKey(config.GetOutputKeyPath())
+	if err != nil {
+		slog.Warn("Failed to load output key, sensitive outputs are not stored", "error", err)
+	}
+	sealed, err := redact.SealOutputs(key, outputs, sensitive)
+	if err != nil {
+		slog.Warn("Failed to seal sensitive outputs, they are not stored", "error", err)
+	}
+	return sealed
 }
 
 // recordExecution records the execution in the stack
@@ -596,9 +1307,17 @@ func (h *RunHandler) handleResults(
 	enhancedOutput *output.PulumiStyleOutput,
 ) error {
 	useJSONOutput := h.config.OutputStyle == "json"
+	exportedOutputs = redact.MaskOutputs(exportedOutputs)
+	if !useJSONOutput {
+		defer h.reportDeprecations()
+		defer h.reportFlakyTasks()
+	}
 
 	if err != nil {
 		h.handleFailure(err, duration, workflowName, stackID, runner, exportedOutputs, enhancedOutput, useJSONOutput)
+		if !useJSONOutput {
+			h.reportScriptErrors(err, runner)
+		}
 		if strings.Contains(err.Error(), "✗") {
 			return err
 		}
@@ -609,6 +1328,61 @@ func (h *RunHandler) handleResults(
 	return nil
 }
 
+// reportScriptErrors shows where in the workflow the run failed, with the
+// task and a snippet of the code, for errors raised by Lua code
+func (h *RunHandler) reportScriptErrors(err error, runner *taskrunner.TaskRunner) {
+	errs := []error{err}
+	if runner != nil {
+		for _, result := range runner.Results {
+			errs = append(errs, result.Error)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, e := range errs {
+		var scriptErr *luainterface.ScriptError
+		if !errors.As(e, &scriptErr) {
+			continue
+		}
+		if seen[scriptErr.Error()] {
+			continue
+		}
+		seen[scriptErr.Error()] = true
+		fmt.Fprintf(h.config.Writer, "\n%s\n%s\n", pterm.Red("✗ Error in workflow:"), scriptErr.Details())
+	}
+}
+
+// reportDeprecations lists deprecated module calls made during the run
+func (h *RunHandler) reportDeprecations() {
+	warnings := luainterface.DeprecationWarnings()
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Fprintf(h.config.Writer, "\n%s\n", pterm.Yellow(fmt.Sprintf("⚠ %d deprecated module call(s):", len(warnings))))
+	for _, w := range warnings {
+		fmt.Fprintf(h.config.Writer, "  • %s (%dx, first at %s)\n", w.Message, w.Count, w.Location)
+		fmt.Fprintf(h.config.Writer, "    %s\n", pterm.Gray(w.Migration))
+	}
+	fmt.Fprintf(h.config.Writer, "  Run 'sloth-runner workflow lint %s' for migration suggestions\n", h.config.FilePath)
+}
+
+// reportFlakyTasks lists the tasks of this run that look flaky
+func (h *RunHandler) reportFlakyTasks() {
+	if len(h.flakyTasks) == 0 {
+		return
+	}
+
+	fmt.Fprintf(h.config.Writer, "\n%s\n", pterm.Yellow(fmt.Sprintf("⚠ %d flaky task(s) in stack %s:", len(h.flakyTasks), h.config.StackName)))
+	for _, f := range h.flakyTasks {
+		fmt.Fprintf(h.config.Writer, "  • %s: %d/%d runs failed, %d passed on retry\n", f.TaskName, f.Failures, f.Runs, f.Recovered)
+	}
+	if h.config.FlakyPolicy != execution.FlakyPolicyQuarantine {
+		fmt.Fprintln(h.config.Writer, "  Use --flaky-policy quarantine to keep their failures from failing the run")
+	}
+	fmt.Fprintf(h.config.Writer, "  Run 'sloth-runner runs stats --stack %s' for details\n", h.config.StackName)
+}
+
 // handleFailure handles execution failure
 func (h *RunHandler) handleFailure(
 	err error,
@@ -785,6 +1559,30 @@ func (r *remoteAgentResolver) GetAgentAddress(agentName string) (string, error)
 	return resp.AgentInfo.AgentAddress, nil
 }
 
+// AgentInfos implements the taskrunner.AgentLister interface
+func (r *remoteAgentResolver) AgentInfos() ([]*pb.AgentInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := r.client.ListAgents(ctx, &pb.ListAgentsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agents from master: %w", err)
+	}
+	return resp.GetAgents(), nil
+}
+
+// AgentGroups implements the taskrunner.AgentGroupLister interface
+func (r *remoteAgentResolver) AgentGroups() ([]*pb.AgentGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := r.client.ListAgentGroups(ctx, &pb.ListGroupsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent groups from master: %w", err)
+	}
+	return resp.GetGroups(), nil
+}
+
 // Close closes the gRPC connection
 func (r *remoteAgentResolver) Close() error {
 	if r.conn != nil {
@@ -804,7 +1602,7 @@ func createRemoteAgentResolver(masterAddr string) (interface{}, error) {
 	}
 
 	// Create gRPC connection to master
-	conn, err := grpc.Dial(masterAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(masterAddr, mtls.DialOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to master at %s: %w", masterAddr, err)
 	}
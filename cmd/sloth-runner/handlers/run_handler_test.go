@@ -5,10 +5,25 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
-
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/services"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -684,3 +699,336 @@ func TestRunConfig_LongRunID(t *testing.T) {
 		t.Errorf("Expected RunID length 100, got %d", len(config.RunID))
 	}
 }
+
+func TestRunAllLocally(t *testing.T) {
+	taskGroups := map[string]types.TaskGroup{
+		"deploy": {
+			DelegateTo: "web-1",
+			Tasks: []types.Task{
+				{Name: "build", DelegateTo: []interface{}{"web-1", "web-2"}},
+				{Name: "notify"},
+			},
+		},
+	}
+
+	runAllLocally(taskGroups)
+
+	group := taskGroups["deploy"]
+	if group.DelegateTo != nil {
+		t.Errorf("Expected group delegate_to to be cleared, got %v", group.DelegateTo)
+	}
+	for _, task := range group.Tasks {
+		if task.DelegateTo != nil {
+			t.Errorf("Expected delegate_to of %s to be cleared, got %v", task.Name, task.DelegateTo)
+		}
+	}
+}
+
+func TestDefaultTargetGroups(t *testing.T) {
+	taskGroups := map[string]types.TaskGroup{
+		"install": {
+			Tasks: []types.Task{
+				{Name: "packages"},
+				{Name: "report", DelegateTo: "local"},
+				{Name: "db-user", DelegateTo: "db-1"},
+			},
+		},
+		"deploy": {DelegateTo: "web-1", Tasks: []types.Task{{Name: "release"}}},
+	}
+
+	changed := defaultTargetGroups(taskGroups, "web-2")
+	if len(changed) != 1 || changed[0] != "install" {
+		t.Errorf("Expected only install to be changed, got %v", changed)
+	}
+
+	install := taskGroups["install"]
+	want := map[string][]string{"packages": {"web-2"}, "report": {"local"}, "db-user": {"db-1"}}
+	for i := range install.Tasks {
+		task := &install.Tasks[i]
+		if got := taskrunner.TaskAgents(task, install); !reflect.DeepEqual(got, want[task.Name]) {
+			t.Errorf("Expected %s to run on %v, got %v", task.Name, want[task.Name], got)
+		}
+	}
+	if got := taskGroups["deploy"].DelegateTo; got != "web-1" {
+		t.Errorf("Expected a delegated group to keep its agent, got %v", got)
+	}
+}
+
+func TestSelectWorkflow(t *testing.T) {
+	taskGroups := map[string]types.TaskGroup{
+		"restart-web": {Tasks: []types.Task{{Name: "restart"}}},
+		"rotate-logs": {Tasks: []types.Task{{Name: "rotate"}}},
+	}
+
+	selected, err := selectWorkflow(taskGroups, "restart-web", "ops.sloth")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(selected) != 1 || selected["restart-web"].Tasks[0].Name != "restart" {
+		t.Errorf("Expected only restart-web, got %v", selected)
+	}
+
+	_, err = selectWorkflow(taskGroups, "deploy", "ops.sloth")
+	if err == nil || !strings.Contains(err.Error(), "available: restart-web, rotate-logs") {
+		t.Errorf("Expected the available workflows in the error, got %v", err)
+	}
+}
+
+func TestSelectTasks(t *testing.T) {
+	taskGroups := map[string]types.TaskGroup{
+		"deploy":      {Tasks: []types.Task{{Name: "build"}, {Name: "restart"}}},
+		"restart-web": {Tasks: []types.Task{{Name: "restart"}}},
+	}
+
+	selected, err := selectTasks(taskGroups, []string{"build"}, "ops.sloth")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := selected["deploy"]; !ok || len(selected) != 1 {
+		t.Errorf("Expected only deploy, got %v", selected)
+	}
+
+	_, err = selectTasks(taskGroups, []string{"restart"}, "ops.sloth")
+	if err == nil || !strings.Contains(err.Error(), "workflows deploy, restart-web of ops.sloth all define task restart") {
+		t.Errorf("Expected the workflows defining restart in the error, got %v", err)
+	}
+
+	_, err = selectTasks(taskGroups, []string{"migrate"}, "ops.sloth")
+	if err == nil || !strings.Contains(err.Error(), "no workflow in ops.sloth defines task migrate") {
+		t.Errorf("Expected a missing task error, got %v", err)
+	}
+}
+
+func TestWindowViolations(t *testing.T) {
+	stackWindows, err := sloth.ParseWindows([]string{"weekdays 22:00-06:00 UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workflowWindows, err := sloth.ParseWindows([]string{"daily 20:00-23:00 UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Friday 22:30 is inside both
+	inside := time.Date(2026, 10, 16, 22, 30, 0, 0, time.UTC)
+	if v := windowViolations(inside, stackWindows, workflowWindows); len(v) != 0 {
+		t.Errorf("Expected no violations, got %v", v)
+	}
+
+	// Friday 21:00 is only inside the workflow's window
+	outside := time.Date(2026, 10, 16, 21, 0, 0, 0, time.UTC)
+	v := windowViolations(outside, stackWindows, workflowWindows)
+	if len(v) != 1 || !strings.Contains(v[0], "the stack allows runs weekdays 22:00-06:00 UTC") {
+		t.Errorf("Unexpected violations: %v", v)
+	}
+
+	if v := windowViolations(outside, nil, nil); len(v) != 0 {
+		t.Errorf("Expected no windows to allow any time, got %v", v)
+	}
+}
+
+func TestHistoryFromRun(t *testing.T) {
+	runner := &taskrunner.TaskRunner{
+		Results: []types.TaskResult{
+			{Name: "build", Status: "Success", Duration: time.Second,
+				WorkspaceChanges: []types.WorkspaceChange{{Path: "dist/app", Change: "added"}}},
+			{Name: "deploy", Status: "Failed", Duration: 2 * time.Second, Error: errors.New("timeout"),
+				Usage:    &taskusage.Usage{CPUTime: time.Second, PeakRSS: 300 << 20, WriteBytes: 4096},
+				Progress: []taskctx.Progress{{Time: time.UnixMilli(1500), Percent: 40, Message: "uploading artifacts"}}},
+			{Name: "deploy", Status: "Success", Duration: 3 * time.Second,
+				Usage:    &taskusage.Usage{CPUTime: 500 * time.Millisecond, PeakRSS: 200 << 20, WriteBytes: 1024},
+				Progress: []taskctx.Progress{{Time: time.UnixMilli(4000), Percent: 100}}},
+			{Name: "notify", Status: "Skipped"},
+		},
+		Outputs: map[string]interface{}{
+			"build": map[string]interface{}{"version": "1.2", "token": "s3cret"},
+		},
+		Sensitive: map[string]bool{"token": true},
+	}
+
+	exec, tasks := historyFromRun("run-1", "deploy", time.Unix(1000, 0), 6*time.Second, nil, runner)
+
+	if exec.Status != execution.StatusCompleted || exec.TasksTotal != 3 || exec.TasksSuccess != 2 || exec.Duration != 6000 {
+		t.Errorf("Unexpected execution record: %+v", exec)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("Expected one record per task, got %d", len(tasks))
+	}
+	if deploy := tasks[1]; deploy.Status != execution.StatusCompleted || deploy.Duration != 5000 || deploy.Error != "" {
+		t.Errorf("Expected deploy's retry to be folded in, got %+v", deploy)
+	}
+	if deploy := tasks[1]; deploy.CPUTimeMs != 1500 || deploy.PeakRSSBytes != 300<<20 || deploy.WriteBytes != 5120 {
+		t.Errorf("Expected the usage of both attempts, got %+v", deploy)
+	}
+	if a := tasks[1].Attempts; len(a) != 2 || a[0].Status != execution.StatusFailed || a[0].Error != "timeout" ||
+		a[1].Attempt != 2 || a[1].Duration != 3000 {
+		t.Errorf("Expected both attempts of deploy, got %+v", a)
+	}
+	if a := tasks[0].Attempts; a != nil {
+		t.Errorf("Expected no attempts for a task run once, got %+v", a)
+	}
+	if p := tasks[1].Progress; len(p) != 2 || p[0].Time != 1500 || p[0].Message != "uploading artifacts" || p[1].Percent != 100 {
+		t.Errorf("Expected the progress of both attempts, got %+v", p)
+	}
+	if c := tasks[0].WorkspaceChanges; len(c) != 1 || c[0].Path != "dist/app" || c[0].Change != "added" {
+		t.Errorf("Expected build's workspace changes, got %+v", c)
+	}
+	if tasks[2].Status != "skipped" {
+		t.Errorf("Expected notify to be skipped, got %s", tasks[2].Status)
+	}
+	if !strings.Contains(tasks[0].Output, `"version": "1.2"`) || strings.Contains(tasks[0].Output, "s3cret") {
+		t.Errorf("Expected build's outputs with the sensitive ones masked, got %s", tasks[0].Output)
+	}
+}
+
+func TestIntegrations_ReportRun(t *testing.T) {
+	var bodies []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if r.Header.Get("Authorization") != "Bearer from-stack" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	dataDir := t.TempDir()
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", dataDir)
+	config := "integrations:\n" +
+		"  - name: catalog\n    type: backstage\n    url: " + server.URL + "\n" +
+		"    auth: {token_secret: CATALOG_TOKEN}\n" +
+		"    on:\n      failure: {summary: '{{.Workflow}}: {{.TasksFailed}}/{{.TasksTotal}} failed'}\n"
+	if err := os.WriteFile(filepath.Join(dataDir, "integrations.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	h := &RunHandler{config: &RunConfig{StackName: "prod", RunID: "run-1", Writer: &out}}
+	notifier, err := h.startIntegrations("deploy", map[string]string{"CATALOG_TOKEN": "from-stack"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &taskrunner.TaskRunner{Results: []types.TaskResult{
+		{Name: "build", Status: "Success"},
+		{Name: "deploy", Status: "Failed"},
+	}}
+	h.finishIntegrations(notifier, runner, time.Second, errors.New("deploy failed"))
+
+	if out.Len() > 0 {
+		t.Errorf("Expected no warnings, got %q", out.String())
+	}
+	if len(bodies) != 2 || bodies[0]["event"] != "run.start" || bodies[1]["summary"] != "deploy: 1/2 failed" {
+		t.Errorf("Unexpected events %+v", bodies)
+	}
+}
+
+func TestLockStack(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DB_PATH", filepath.Join(t.TempDir(), "stacks.db"))
+	stackService, err := services.NewStackService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stackService.Close()
+	stackID, err := stackService.GetOrCreateStack("prod", "deploy", "deploy.sloth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := NewRunHandler(stackService, &RunConfig{StackName: "prod", RunID: "run-1"})
+	unlock, err := first.lockStack(stackID, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A concurrent run fails at once
+	second := NewRunHandler(stackService, &RunConfig{StackName: "prod", RunID: "run-2"})
+	if _, err := second.lockStack(stackID, "deploy"); err == nil || !strings.Contains(err.Error(), "stack lock release prod") {
+		t.Fatalf("Expected the stack to be locked, got %v", err)
+	}
+
+	// or waits for the lock with --lock-timeout
+	runLockRetryInterval = 10 * time.Millisecond
+	second.config.LockTimeout = 5 * time.Second
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unlock()
+	}()
+	unlock, err = second.lockStack(stackID, "deploy")
+	if err != nil {
+		t.Fatalf("Expected the lock once released, got %v", err)
+	}
+	lock, _ := stackService.GetLockInfo(stackID)
+	if lock == nil || lock.LockID != "run-2" || lock.Operation != "run deploy" {
+		t.Errorf("Unexpected lock %+v", lock)
+	}
+	unlock()
+	if lock, _ := stackService.GetLockInfo(stackID); lock != nil {
+		t.Errorf("Expected the lock to be released, got %+v", lock)
+	}
+}
+
+// TestRecordDrift validates that drifted resources are recorded in the
+// stack and resolved once a check finds them back in line
+func TestRecordDrift(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DB_PATH", filepath.Join(t.TempDir(), "stacks.db"))
+	stackService, err := services.NewStackService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stackService.Close()
+	stackID, err := stackService.GetOrCreateStack("prod", "deploy", "deploy.sloth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRunHandler(stackService, &RunConfig{StackName: "prod"})
+
+	report := newDriftReport("prod", []types.TaskResult{{
+		Name:   "web",
+		Status: "DryRun",
+		Changes: []taskctx.Change{
+			{Module: "systemd", Action: "start", Target: "nginx", Current: "inactive", Host: "web-1"},
+			{Module: "exec", Action: "run", Target: "make", Unknown: true},
+		},
+	}})
+	if len(report.Drifted) != 1 || len(report.Unchecked) != 1 {
+		t.Fatalf("Expected one drifted and one unchecked call, got %+v", report)
+	}
+	if err := h.recordDrift(stackID, report); err != nil {
+		t.Fatal(err)
+	}
+	// Checking again keeps a single resource for the service
+	if err := h.recordDrift(stackID, report); err != nil {
+		t.Fatal(err)
+	}
+
+	resources, err := stackService.ListStackResources(stackID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 || resources[0].Name != "web-1:nginx" || resources[0].State != "drift" {
+		t.Fatalf("Expected nginx on web-1 to be drifted, got %+v", resources)
+	}
+	drifts, err := stackService.GetDriftInfo(stackID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 2 || !drifts[0].IsDrifted || drifts[0].ActualState["systemd.start"] != "inactive" {
+		t.Fatalf("Expected the drift to be recorded, got %+v", drifts)
+	}
+
+	// A clean check resolves it
+	if err := h.recordDrift(stackID, newDriftReport("prod", nil)); err != nil {
+		t.Fatal(err)
+	}
+	resources, _ = stackService.ListStackResources(stackID)
+	if resources[0].State != "applied" {
+		t.Errorf("Expected the resource back in line, got %s", resources[0].State)
+	}
+	drifts, _ = stackService.GetDriftInfo(stackID)
+	for _, d := range drifts {
+		if d.ResolutionStatus != "resolved" {
+			t.Errorf("Expected drift %d to be resolved, got %s", d.ID, d.ResolutionStatus)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+//go:build cgo
+// +build cgo
+
+package handlers
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+)
+
+// recordHistory stores the run and the outcome and outputs of each of its
+// tasks in the execution history, so runs can be compared with runs diff
+func (h *RunHandler) recordHistory(runner *taskrunner.TaskRunner, workflowName string, startTime time.Time, duration time.Duration, runErr error) {
+	if h.config.RunID == "" {
+		return
+	}
+	exec, tasks := historyFromRun(h.config.RunID, workflowName, startTime, duration, runErr, runner)
+	exec.WorkflowFile = h.config.FilePath
+	exec.Metadata = map[string]interface{}{"stack": h.config.StackName}
+	if len(h.values) > 0 {
+		exec.Metadata["variables"] = h.values
+	}
+
+	db, err := execution.NewHistoryDB(config.GetHistoryDBPath())
+	if err != nil {
+		slog.Warn("Failed to open history database", "error", err)
+		return
+	}
+	defer db.Close()
+
+	if err := db.RecordRun(exec, tasks); err != nil {
+		slog.Warn("Failed to record run history", "error", err)
+	}
+}
+
+// historyFromRun builds the history records of a run. The runner results
+// hold one entry per attempt: a task's record has its last status, the
+// time and resources spent on all attempts and the progress they reported,
+// and lists the attempts of retried tasks.
+func historyFromRun(runID, workflowName string, startTime time.Time, duration time.Duration, runErr error, runner *taskrunner.TaskRunner) (*execution.Execution, []*execution.TaskExecution) {
+	exec := &execution.Execution{
+		ID:           runID,
+		WorkflowName: workflowName,
+		Status:       execution.StatusCompleted,
+		StartTime:    startTime.Unix(),
+		EndTime:      startTime.Add(duration).Unix(),
+		Duration:     duration.Milliseconds(),
+	}
+	if runErr != nil {
+		exec.Status = execution.StatusFailed
+		exec.ExitCode = 1
+		exec.ErrorMessage = runErr.Error()
+	}
+
+	var tasks []*execution.TaskExecution
+	byName := make(map[string]*execution.TaskExecution)
+	for _, result := range runner.Results {
+		task, ok := byName[result.Name]
+		if !ok {
+			task = &execution.TaskExecution{
+				ID:          runID + "/" + result.Name,
+				ExecutionID: runID,
+				TaskName:    result.Name,
+				StartTime:   startTime.Unix(),
+			}
+			byName[result.Name] = task
+			tasks = append(tasks, task)
+		}
+
+		task.Status = taskStatus(result.Status)
+		task.Duration += result.Duration.Milliseconds()
+		task.Error = ""
+		if result.Error != nil {
+			task.Error = result.Error.Error()
+		}
+		task.Attempts = append(task.Attempts, execution.TaskAttempt{
+			Attempt:  len(task.Attempts) + 1,
+			Status:   task.Status,
+			Duration: result.Duration.Milliseconds(),
+			Error:    task.Error,
+		})
+		if u := result.Usage; u != nil {
+			task.CPUTimeMs += u.CPUTime.Milliseconds()
+			task.PeakRSSBytes = max(task.PeakRSSBytes, int64(u.PeakRSS))
+			task.ReadBytes += int64(u.ReadBytes)
+			task.WriteBytes += int64(u.WriteBytes)
+		}
+		for _, p := range result.Progress {
+			task.Progress = append(task.Progress, execution.ProgressEvent{
+				Time:    p.Time.UnixMilli(),
+				Percent: p.Percent,
+				Message: p.Message,
+			})
+		}
+		// Attempts send the workspace afresh, so the last one tells what
+		// the task changed
+		task.WorkspaceChanges = nil
+		for _, c := range result.WorkspaceChanges {
+			task.WorkspaceChanges = append(task.WorkspaceChanges, execution.WorkspaceChange{Path: c.Path, Change: c.Change})
+		}
+	}
+
+	for _, task := range tasks {
+		if len(task.Attempts) == 1 {
+			task.Attempts = nil
+		}
+		task.EndTime = task.StartTime + task.Duration/1000
+		if outputs, ok := runner.Outputs[task.TaskName].(map[string]interface{}); ok {
+			task.Output = execution.FormatOutput(maskTaskOutputs(outputs, runner.Sensitive))
+		}
+		switch task.Status {
+		case execution.StatusFailed:
+			exec.TasksFailed++
+		case execution.StatusCompleted:
+			exec.TasksSuccess++
+		}
+	}
+	exec.TasksTotal = len(tasks)
+	return exec, tasks
+}
+
+// taskStatus converts a runner result status to a history status
+func taskStatus(status string) execution.ExecutionStatus {
+	switch status {
+	case "Success":
+		return execution.StatusCompleted
+	case "Failed":
+		return execution.StatusFailed
+	}
+	return execution.ExecutionStatus(strings.ToLower(status))
+}
+
+// maskTaskOutputs hides the outputs marked sensitive with task.set_output
+func maskTaskOutputs(outputs map[string]interface{}, sensitive map[string]bool) map[string]interface{} {
+	masked := make(map[string]interface{}, len(outputs))
+	for name, value := range outputs {
+		if sensitive[name] {
+			value = redact.Mask
+		}
+		masked[name] = value
+	}
+	return masked
+}
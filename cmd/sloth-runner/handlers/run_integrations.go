@@ -0,0 +1,74 @@
+//go:build cgo
+// +build cgo
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/changemgmt"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/pterm/pterm"
+)
+
+// startIntegrations reports the start of the run to the change-management
+// systems of <data-dir>/integrations.yaml that match it. Their secrets
+// come from the stack's secrets, or else the environment. An error means
+// a required integration failed and the run must not go ahead.
+func (h *RunHandler) startIntegrations(workflowName string, secrets map[string]string) (*changemgmt.Notifier, error) {
+	cfg, err := changemgmt.LoadConfig(config.GetIntegrationsPath())
+	if err != nil {
+		return nil, err
+	}
+
+	run := &changemgmt.Run{
+		ID:       h.config.RunID,
+		Workflow: workflowName,
+		Stack:    h.config.StackName,
+		File:     h.config.FilePath,
+		Started:  time.Now(),
+	}
+	if u, err := user.Current(); err == nil {
+		run.User = u.Username
+	}
+	run.Host, _ = os.Hostname()
+
+	lookup := func(name string) (string, bool) {
+		value, ok := secrets[name]
+		return value, ok
+	}
+	notifier := changemgmt.NewNotifier(cfg, run, lookup)
+	notifier.Warnf = func(format string, args ...interface{}) {
+		fmt.Fprintf(h.config.Writer, "%s\n", pterm.Yellow("⚠ "+fmt.Sprintf(format, args...)))
+	}
+	if err := notifier.Start(h.integrationContext()); err != nil {
+		return nil, fmt.Errorf("run not started: %w", err)
+	}
+	return notifier, nil
+}
+
+// finishIntegrations reports how the run ended, with its task counts
+func (h *RunHandler) finishIntegrations(notifier *changemgmt.Notifier, runner *taskrunner.TaskRunner, duration time.Duration, runErr error) {
+	runs := taskRunsFromResults(h.config.StackName, h.config.RunID, time.Now(), runner)
+	failed := 0
+	for _, r := range runs {
+		if r.Status == execution.StatusFailed {
+			failed++
+		}
+	}
+	notifier.SetTasks(len(runs), failed)
+	notifier.Finish(h.integrationContext(), duration, runErr)
+}
+
+func (h *RunHandler) integrationContext() context.Context {
+	if h.config.Context != nil {
+		return h.config.Context
+	}
+	return context.Background()
+}
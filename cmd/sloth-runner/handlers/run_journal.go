@@ -0,0 +1,94 @@
+//go:build cgo
+// +build cgo
+
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+)
+
+// runJournal records the tasks of a run in its write-ahead journal
+type runJournal struct {
+	*execution.RunJournal
+}
+
+func (j runJournal) TaskFinished(task, status string, err error, outputs map[string]interface{}) error {
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	return j.RunJournal.TaskFinished(task, taskStatus(status), errMsg, outputs)
+}
+
+// startJournal opens the journal of the run, which the master reads to
+// recover the run if its process stops before the end. When the run
+// resumes an interrupted one, the tasks that finished in it aren't run
+// again. The returned function removes the journal, once the run is
+// recorded.
+func (h *RunHandler) startJournal(workflowName string, runner *taskrunner.TaskRunner) (func(), error) {
+	noop := func() {}
+	if h.config.Local || h.config.DryRun || h.config.RunID == "" {
+		return noop, nil
+	}
+
+	dir := config.GetRunJournalDir()
+	if h.config.Resume {
+		run, err := execution.LoadInterruptedRun(dir, h.config.RunID)
+		if err != nil {
+			return nil, err
+		}
+		if run.Running() {
+			return nil, fmt.Errorf("run %s is still going (pid %d)", run.RunID, run.PID)
+		}
+		resumeTasks(run, runner)
+	}
+
+	journal, err := execution.OpenRunJournal(dir, execution.JournalRun{
+		RunID:     h.config.RunID,
+		Stack:     h.config.StackName,
+		File:      h.config.FilePath,
+		Sloth:     h.config.Sloth,
+		Workflow:  workflowName,
+		Schedule:  h.config.Schedule,
+		PID:       os.Getpid(),
+		StartTime: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		slog.Warn("Run journal unavailable, the run can't be recovered if it is interrupted", "error", err)
+		return noop, nil
+	}
+	runner.Journal = runJournal{journal}
+
+	return func() {
+		if err := journal.Close(); err != nil {
+			slog.Warn("Failed to remove run journal", "error", err)
+		}
+	}, nil
+}
+
+// resumeTasks marks the tasks that completed in an interrupted run so the
+// runner doesn't run them again, giving their outputs to the tasks that
+// depend on them. Failed and interrupted tasks run again.
+func resumeTasks(run *execution.InterruptedRun, runner *taskrunner.TaskRunner) {
+	runner.Resumed = make(map[string]bool)
+	if runner.Inputs == nil {
+		runner.Inputs = make(map[string]interface{})
+	}
+	for _, t := range run.Tasks {
+		if t.Status != execution.StatusCompleted {
+			continue
+		}
+		runner.Resumed[t.Name] = true
+		if t.Outputs != nil {
+			runner.Inputs[t.Name] = t.Outputs
+		}
+	}
+	slog.Info("Resuming interrupted run", "run_id", run.RunID, "attempt", run.Attempts+1, "completed_tasks", len(runner.Resumed))
+}
@@ -0,0 +1,128 @@
+//go:build cgo
+// +build cgo
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+)
+
+var (
+	// runLockTTL is how long the lock of a run's stack outlives the run if
+	// it crashes; the run renews it well before then
+	runLockTTL = 2 * time.Minute
+	// runLockRetryInterval is how often a run waiting with --lock-timeout
+	// tries to lock the stack again
+	runLockRetryInterval = 2 * time.Second
+)
+
+// lockStack locks the state of the stack for the run, so concurrent runs
+// against it fail, or wait up to --lock-timeout. The lock is renewed while
+// the run goes on; the returned function releases it. The lock ID is the
+// run ID, so resuming an interrupted run takes over the lock it left.
+func (h *RunHandler) lockStack(stackID, workflowName string) (func(), error) {
+	lockID := h.config.RunID
+	if lockID == "" {
+		lockID = uuid.NewString()
+	}
+	operation := fmt.Sprintf("run %s", workflowName)
+	if err := h.acquireStackLock(stackID, lockID, operation, runLockOwner(h.config.RunID)); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(runLockTTL / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := h.stackService.RenewLock(stackID, lockID, runLockTTL); err != nil {
+					slog.Warn("Failed to renew the lock of the stack", "stack", h.config.StackName, "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+		if err := h.stackService.UnlockState(stackID, lockID); err != nil {
+			slog.Warn("Failed to release the lock of the stack", "stack", h.config.StackName, "error", err)
+		}
+	}, nil
+}
+
+// acquireStackLock locks the stack, retrying until --lock-timeout while
+// another run holds it
+func (h *RunHandler) acquireStackLock(stackID, lockID, operation, who string) error {
+	ctx := h.config.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	deadline := time.Now().Add(h.config.LockTimeout)
+	waiting := false
+	for {
+		err := h.stackService.LockState(stackID, lockID, operation, who, runLockTTL)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, stack.ErrStateLocked) {
+			return err
+		}
+		if !time.Now().Before(deadline) {
+			return h.stackLockedError(stackID, err)
+		}
+		if !waiting {
+			pterm.Info.Printfln("Stack '%s' is locked, waiting up to %s for it to be released", h.config.StackName, h.config.LockTimeout)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(runLockRetryInterval, time.Until(deadline))):
+		}
+	}
+}
+
+// stackLockedError explains who holds the lock of the stack and how to get
+// past it
+func (h *RunHandler) stackLockedError(stackID string, err error) error {
+	held := "locked"
+	if lock, lockErr := h.stackService.GetLockInfo(stackID); lockErr == nil && lock != nil {
+		held = fmt.Sprintf("locked by %s for %q since %s", lock.Who, lock.Operation, lock.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Errorf("stack '%s' is %s: wait for it with --lock-timeout, or release it with 'sloth-runner stack lock release %s'",
+		h.config.StackName, held, h.config.StackName)
+}
+
+// runLockOwner describes the run holding a stack lock
+func runLockOwner(runID string) string {
+	who := "cli-user"
+	if u, err := user.Current(); err == nil {
+		who = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		who += "@" + host
+	}
+	if runID != "" {
+		who += fmt.Sprintf(" (run %s)", runID)
+	}
+	return who
+}
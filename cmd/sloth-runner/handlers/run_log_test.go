@@ -0,0 +1,107 @@
+//go:build cgo && unix
+// +build cgo,unix
+
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/sys/unix"
+)
+
+// attachTerminal points stdin and stdout at a new pseudo terminal and
+// returns its controlling side, so tests can answer prompts
+func attachTerminal(t *testing.T) *os.File {
+	t.Helper()
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("no pseudo terminal available: %v", err)
+	}
+	for _, fd := range []int{0, 1} {
+		saved, err := unix.Dup(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := unix.Dup2(int(tty.Fd()), fd); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			unix.Dup2(saved, fd)
+			unix.Close(saved)
+		})
+	}
+	t.Cleanup(func() {
+		tty.Close()
+		ptmx.Close()
+	})
+	return ptmx
+}
+
+// answerPrompt types answer into the terminal once prompt shows up on it.
+// It also answers cursor position queries the way a terminal would.
+// answerPrompt types answer into the terminal once prompt shows up on it
+// and the prompt has stopped drawing. It also answers cursor position
+// queries the way a terminal would.
+func answerPrompt(ptmx *os.File, prompt, answer string) {
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 1024)
+		for {
+			n, err := ptmx.Read(buf)
+			if err != nil {
+				return
+			}
+			chunks <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+	go func() {
+		var seen bytes.Buffer
+		answered := false
+		for {
+			select {
+			case chunk, ok := <-chunks:
+				if !ok {
+					return
+				}
+				if bytes.Contains(chunk, []byte("\x1b[6n")) {
+					ptmx.Write([]byte("\x1b[1;1R"))
+				}
+				seen.Write(chunk)
+			case <-time.After(200 * time.Millisecond):
+				if !answered && bytes.Contains(seen.Bytes(), []byte(prompt)) {
+					ptmx.Write([]byte(answer))
+					answered = true
+				}
+			}
+		}
+	}()
+}
+
+func TestConfirmActionWhileCapturingRunLog(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	t.Setenv("CI", "")
+	t.Setenv("SLOTH_RUNNER_NON_INTERACTIVE", "")
+	ptmx := attachTerminal(t)
+
+	h := &RunHandler{config: &RunConfig{StackName: "web", RunID: "run-1"}}
+	stop := h.startRunLog("deploy")
+	answerPrompt(ptmx, "Approve the rebuild?", "y\r")
+
+	confirmed := make(chan bool, 1)
+	go func() { confirmed <- confirmAction("Approve the rebuild?") }()
+	select {
+	case ok := <-confirmed:
+		stop()
+		if !ok {
+			t.Error("Expected the confirmation typed in the terminal to be accepted while the run log captures output")
+		}
+	case <-time.After(10 * time.Second):
+		stop()
+		t.Fatal("Timed out waiting for the confirmation")
+	}
+}
@@ -0,0 +1,179 @@
+//go:build cgo
+// +build cgo
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pterm/pterm"
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	sshpkg "github.com/chalkan3-sloth/sloth-runner/internal/ssh"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+)
+
+// planTasks runs the workflow in check mode (run --dry-run) and shows
+// what it would change. The stack is left alone: it isn't created,
+// nothing is recorded and no snapshot is taken.
+func (h *RunHandler) planTasks(taskGroups map[string]types.TaskGroup, sshExecutor *sshpkg.Executor, sshPassword *string) error {
+	_, luaScriptContent, err := sloth.RenderFile(h.config.FilePath, h.values)
+	if err != nil {
+		return fmt.Errorf("failed to read Lua script file: %w", err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	luainterface.RegisterAllModules(L)
+	luainterface.OpenImport(L, h.config.FilePath)
+	if h.values != nil {
+		L.SetGlobal("values", mapToLuaTable(L, h.values))
+	}
+	if sshExecutor != nil {
+		luainterface.SetSSHExecutor(sshExecutor, h.config.SSHProfile, sshPassword)
+	}
+
+	// Secrets only exist for stacks that ran before
+	if st, err := h.stackService.GetStackByName(h.config.StackName); err == nil && st != nil {
+		secrets, err := h.loadSecrets(st.ID)
+		if err != nil {
+			return err
+		}
+		if len(secrets) > 0 {
+			secretsTable := L.NewTable()
+			for key, value := range secrets {
+				secretsTable.RawSetString(key, lua.LString(value))
+			}
+			L.SetGlobal("secrets", secretsTable)
+		}
+	}
+
+	runner := taskrunner.NewTaskRunner(L, taskGroups, "", h.config.Tasks, true, false, taskrunner.NewSurveyAsker(), string(luaScriptContent))
+	runner.Stack = h.config.StackName
+	runner.RunID = h.config.RunID
+	if h.metadata != nil {
+		runner.Assets = h.metadata.Assets
+	}
+	runner.BaseContext = taskctx.WithApprovals(context.Background(), h.config.Approve)
+
+	// A drift check asks the agents what their tasks would change
+	if h.config.Drift {
+		runner.CheckDelegated = true
+		if h.sudoPassword != nil {
+			runner.BaseContext = taskctx.WithSudoPassword(runner.BaseContext, h.sudoPassword)
+		}
+	}
+
+	runErr := runner.Run()
+	if h.config.Drift {
+		return h.reportDrift(runner.Results, runErr)
+	}
+
+	if h.config.OutputStyle == "json" {
+		if err := writePlanJSON(h.config.Writer, runner.Results); err != nil {
+			return err
+		}
+	} else {
+		writePlan(h.config.Writer, runner.Results)
+	}
+	if runErr != nil {
+		return fmt.Errorf("dry run failed: %w", runErr)
+	}
+	return nil
+}
+
+// plannedTask is a task of a dry run with what it would change
+type plannedTask struct {
+	Task    string           `json:"task"`
+	Status  string           `json:"status"`
+	Error   string           `json:"error,omitempty"`
+	Changes []taskctx.Change `json:"changes"`
+}
+
+func writePlanJSON(w io.Writer, results []types.TaskResult) error {
+	tasks := make([]plannedTask, 0, len(results))
+	for _, r := range results {
+		t := plannedTask{Task: r.Name, Status: r.Status, Changes: r.Changes}
+		if r.Error != nil {
+			t.Error = r.Error.Error()
+		}
+		if t.Changes == nil {
+			t.Changes = []taskctx.Change{}
+		}
+		tasks = append(tasks, t)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{"dry_run": true, "tasks": tasks})
+}
+
+// writePlan lists the changes of every task, with the diffs of the files
+// they would write. Calls whose changes can't be told are listed apart.
+func writePlan(w io.Writer, results []types.TaskResult) {
+	var changes, unknown, tasks int
+	for _, r := range results {
+		if len(r.Changes) > 0 {
+			tasks++
+		}
+		for _, c := range r.Changes {
+			if c.Unknown {
+				unknown++
+			} else {
+				changes++
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s\n", pterm.Bold.Sprintf("Plan: %d change(s) in %d task(s), %d call(s) skipped", changes, tasks, unknown))
+	if tasks == 0 {
+		fmt.Fprintln(w, pterm.Green("No changes. The system matches the workflow."))
+		return
+	}
+
+	for _, r := range results {
+		if len(r.Changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n  %s\n", pterm.Cyan(r.Name))
+		for _, c := range r.Changes {
+			call := c.Module + "." + c.Action
+			if c.Unknown {
+				fmt.Fprintf(w, "    %s %s %s %s\n", pterm.Yellow("?"), call, c.Target, pterm.Gray("(skipped: can't tell what it would change)"))
+				continue
+			}
+			fmt.Fprintf(w, "    %s %s %s\n", pterm.Yellow("~"), call, c.Target)
+			writeDiff(w, c.Diff)
+		}
+	}
+	if unknown > 0 {
+		fmt.Fprintln(w, pterm.Gray("\nSkipped calls don't support check mode and may change the system when the workflow runs."))
+	}
+}
+
+func writeDiff(w io.Writer, diff string) {
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			line = pterm.Bold.Sprint(line)
+		case strings.HasPrefix(line, "+"):
+			line = pterm.Green(line)
+		case strings.HasPrefix(line, "-"):
+			line = pterm.Red(line)
+		case strings.HasPrefix(line, "@@"):
+			line = pterm.Cyan(line)
+		}
+		fmt.Fprintf(w, "        %s\n", line)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+)
+
+const (
+	// defaultLockTTL is the lease length when a request doesn't set one
+	defaultLockTTL = 30 * time.Second
+	// maxLockTTL bounds leases, so a lock held by a dead run frees up
+	maxLockTTL = time.Hour
+)
+
+// lockLease is a lock held by a run until it expires or is released
+type lockLease struct {
+	id      string
+	key     string
+	holder  string
+	expires time.Time
+}
+
+// lockTable tracks the named locks held by runs. It is kept in memory:
+// leases are short and renewed by their holders, so a restarted master
+// starts with every lock free.
+type lockTable struct {
+	mu     sync.Mutex
+	byKey  map[string]*lockLease
+	byID   map[string]*lockLease
+	now    func() time.Time
+	nextID func() string
+}
+
+func newLockTable() *lockTable {
+	return &lockTable{
+		byKey:  make(map[string]*lockLease),
+		byID:   make(map[string]*lockLease),
+		now:    time.Now,
+		nextID: newLeaseID,
+	}
+}
+
+func newLeaseID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// lockKey identifies a lock; scoped locks of different hosts are distinct
+func lockKey(scope, name string) string {
+	return scope + "/" + name
+}
+
+func lockTTL(seconds int64) time.Duration {
+	ttl := time.Duration(seconds) * time.Second
+	if ttl <= 0 {
+		return defaultLockTTL
+	}
+	if ttl > maxLockTTL {
+		return maxLockTTL
+	}
+	return ttl
+}
+
+// acquire takes a lock if it is free or its lease expired, returning the
+// new lease, or the current one when the lock is held
+func (t *lockTable) acquire(key, holder string, ttl time.Duration) (*lockLease, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if current, ok := t.byKey[key]; ok {
+		if current.expires.After(now) {
+			return current, false
+		}
+		delete(t.byID, current.id)
+	}
+
+	lease := &lockLease{id: t.nextID(), key: key, holder: holder, expires: now.Add(ttl)}
+	t.byKey[key] = lease
+	t.byID[lease.id] = lease
+	return lease, true
+}
+
+// renew extends a lease that is still held
+func (t *lockTable) renew(id string, ttl time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lease, ok := t.byID[id]
+	if !ok || !lease.expires.After(t.now()) {
+		return false
+	}
+	lease.expires = t.now().Add(ttl)
+	return true
+}
+
+// release frees the lock a lease holds
+func (t *lockTable) release(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lease, ok := t.byID[id]
+	if !ok {
+		return false
+	}
+	delete(t.byID, id)
+	if t.byKey[lease.key] == lease {
+		delete(t.byKey, lease.key)
+	}
+	return true
+}
+
+// AcquireLock takes a named lock for a run, without waiting.
+func (s *agentRegistryServer) AcquireLock(ctx context.Context, req *pb.AcquireLockRequest) (*pb.AcquireLockResponse, error) {
+	if req.Name == "" {
+		return &pb.AcquireLockResponse{Acquired: false, Message: "name is required"}, nil
+	}
+	if s.locks == nil {
+		return &pb.AcquireLockResponse{Acquired: false, Message: "Lock table not available"}, nil
+	}
+
+	lease, acquired := s.locks.acquire(lockKey(req.Scope, req.Name), req.Holder, lockTTL(req.TtlSeconds))
+	if !acquired {
+		return &pb.AcquireLockResponse{Acquired: false, Holder: lease.holder, ExpiresAt: lease.expires.Unix()}, nil
+	}
+	pterm.Debug.Printf("Lock %s acquired by %s\n", lease.key, req.Holder)
+	return &pb.AcquireLockResponse{Acquired: true, LeaseId: lease.id, Holder: lease.holder, ExpiresAt: lease.expires.Unix()}, nil
+}
+
+// RenewLock extends the lease of a held lock.
+func (s *agentRegistryServer) RenewLock(ctx context.Context, req *pb.RenewLockRequest) (*pb.RenewLockResponse, error) {
+	if s.locks == nil || !s.locks.renew(req.LeaseId, lockTTL(req.TtlSeconds)) {
+		return &pb.RenewLockResponse{Success: false, Message: "lease not held"}, nil
+	}
+	return &pb.RenewLockResponse{Success: true, Message: "Lease renewed"}, nil
+}
+
+// ReleaseLock frees a held lock.
+func (s *agentRegistryServer) ReleaseLock(ctx context.Context, req *pb.ReleaseLockRequest) (*pb.ReleaseLockResponse, error) {
+	if s.locks == nil || !s.locks.release(req.LeaseId) {
+		return &pb.ReleaseLockResponse{Success: false, Message: "lease not held"}, nil
+	}
+	return &pb.ReleaseLockResponse{Success: true, Message: "Lock released"}, nil
+}
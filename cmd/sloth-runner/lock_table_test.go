@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+func TestAgentRegistryLocks(t *testing.T) {
+	locks := newLockTable()
+	now := time.Now()
+	locks.now = func() time.Time { return now }
+	server := &agentRegistryServer{locks: locks}
+	ctx := context.Background()
+
+	acquire := func(scope, holder string) *pb.AcquireLockResponse {
+		t.Helper()
+		resp, err := server.AcquireLock(ctx, &pb.AcquireLockRequest{Name: "apt-db", Scope: scope, Holder: holder, TtlSeconds: 30})
+		if err != nil {
+			t.Fatalf("AcquireLock failed: %v", err)
+		}
+		return resp
+	}
+
+	first := acquire("web-01", "run 1")
+	if !first.Acquired || first.LeaseId == "" {
+		t.Fatalf("Expected the lock to be acquired, got %v", first)
+	}
+	if resp := acquire("web-01", "run 2"); resp.Acquired || resp.Holder != "run 1" {
+		t.Errorf("Expected the lock to be held by run 1, got %v", resp)
+	}
+	if resp := acquire("web-02", "run 3"); !resp.Acquired {
+		t.Errorf("Expected locks of other hosts to be independent, got %v", resp)
+	}
+
+	// Renewed leases outlive their TTL, expired ones are taken over
+	now = now.Add(20 * time.Second)
+	if resp, _ := server.RenewLock(ctx, &pb.RenewLockRequest{LeaseId: first.LeaseId, TtlSeconds: 30}); !resp.Success {
+		t.Errorf("RenewLock failed: %v", resp)
+	}
+	now = now.Add(20 * time.Second)
+	if resp := acquire("web-01", "run 2"); resp.Acquired {
+		t.Error("Expected the renewed lease to still hold the lock")
+	}
+	now = now.Add(20 * time.Second)
+	second := acquire("web-01", "run 2")
+	if !second.Acquired {
+		t.Fatal("Expected the expired lease to be taken over")
+	}
+	if resp, _ := server.RenewLock(ctx, &pb.RenewLockRequest{LeaseId: first.LeaseId}); resp.Success {
+		t.Error("Expected the expired lease not to be renewed")
+	}
+
+	// Releasing a lost lease doesn't free the lock taken over
+	if resp, _ := server.ReleaseLock(ctx, &pb.ReleaseLockRequest{LeaseId: first.LeaseId}); resp.Success {
+		t.Error("Expected releasing the expired lease to fail")
+	}
+	if resp, _ := server.ReleaseLock(ctx, &pb.ReleaseLockRequest{LeaseId: second.LeaseId}); !resp.Success {
+		t.Errorf("ReleaseLock failed: %v", resp)
+	}
+	if resp := acquire("web-01", "run 4"); !resp.Acquired {
+		t.Error("Expected the released lock to be free")
+	}
+}
@@ -8,8 +8,10 @@ import (
 
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/agent"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/cert"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/db"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/events"
+	gitopscmd "github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/gitops"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/group"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/history"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/hook"
@@ -19,9 +21,13 @@ import (
 	slothcmd "github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/sloth"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/stack"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/state"
+	telemetrycmd "github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/telemetry"
+	testcmd "github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/test"
+	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/token"
 	"github.com/chalkan3-sloth/sloth-runner/cmd/sloth-runner/commands/workflow"
 	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
 	coremodules "github.com/chalkan3-sloth/sloth-runner/internal/modules/core"
+	"github.com/chalkan3-sloth/sloth-runner/internal/telemetry"
 	"github.com/pterm/pterm"
 )
 
@@ -71,8 +77,25 @@ func Execute() error {
 	// Initialize master server starter function
 	commands.MasterServerStarter = func(port int) error {
 		server := newAgentRegistryServer()
-		return server.Start(port)
+		stopGitOps := startGitOps(&server.drain)
+		defer stopGitOps()
+		stopScheduler, resumeScheduled := startScheduler(&server.drain)
+		defer stopScheduler()
+		stopRunRecovery := startRunRecovery(resumeScheduled)
+		defer stopRunRecovery()
+		stopWatches := startWatches(&server.drain)
+		defer stopWatches()
+		stopDiskMonitor := startDiskMonitor(server)
+		defer stopDiskMonitor()
+		if err := server.Start(port); err != nil {
+			return err
+		}
+		if server.restartRequested() {
+			return reexecMaster()
+		}
+		return nil
 	}
+	commands.MasterBinaryFetcher = downloadReleaseBinary
 
 	// Create application context with build info
 	ctx := commands.NewAppContext(version, commit, date)
@@ -118,6 +141,10 @@ func Execute() error {
 	workflowCmd := workflow.NewWorkflowCommand(ctx)
 	rootCmd.AddCommand(workflowCmd)
 
+	// Add fmt command
+	fmtCmd := commands.NewFmtCommand(ctx)
+	rootCmd.AddCommand(fmtCmd)
+
 	// Add secrets command and subcommands
 	secretsCmd := secrets.NewSecretsCommand(ctx)
 	rootCmd.AddCommand(secretsCmd)
@@ -130,6 +157,17 @@ func Execute() error {
 	eventsCmd := events.NewEventsCommand(ctx)
 	rootCmd.AddCommand(eventsCmd)
 
+	// Add gitops command and subcommands
+	gitopsCmd := gitopscmd.NewGitOpsCommand(ctx)
+	rootCmd.AddCommand(gitopsCmd)
+
+	// Add token command and subcommands
+	tokenCmd := token.NewTokenCommand(ctx)
+	rootCmd.AddCommand(tokenCmd)
+
+	// Add cert command and subcommands (mTLS between masters and agents)
+	rootCmd.AddCommand(cert.NewCertCommand(ctx))
+
 	// Add db command and subcommands
 	dbCmd := db.NewDBCommand(ctx)
 	rootCmd.AddCommand(dbCmd)
@@ -155,6 +193,19 @@ func Execute() error {
 	// Add modules command (list available Lua modules)
 	rootCmd.AddCommand(modulesCmd)
 
+	// Add docs command (offline documentation site)
+	rootCmd.AddCommand(commands.NewDocsCommand(ctx))
+
+	// Add telemetry command (opt-in usage stats)
+	rootCmd.AddCommand(telemetrycmd.NewTelemetryCommand(ctx))
+
+	// Add test command (module conformance suites against containers)
+	rootCmd.AddCommand(testcmd.NewTestCommand(ctx))
+
 	// Execute root command
-	return rootCmd.Execute()
+	executed, err := rootCmd.ExecuteC()
+	if executed != nil {
+		telemetry.FinishCommand(version, strings.TrimPrefix(executed.CommandPath(), rootCmd.Name()+" "), err)
+	}
+	return err
 }
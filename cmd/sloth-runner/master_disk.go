@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/diskquota"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/runlog"
+	"github.com/pterm/pterm"
+)
+
+// startDiskMonitor checks the data directory against the quotas of the
+// disk section of config.yaml in the background, dispatching
+// system.warning and system.disk_full events and pruning as configured.
+// It returns a function that stops it.
+func startDiskMonitor(server *agentRegistryServer) func() {
+	cfg, err := diskquota.LoadConfig(config.GetConfigPath())
+	if err != nil {
+		pterm.Warning.Printf("Disk quotas are disabled: %v\n", err)
+		return func() {}
+	}
+	if cfg == nil {
+		return func() {}
+	}
+
+	monitor, err := diskquota.NewMonitor(config.GetDataDir(), cfg, diskPruners(server))
+	if err != nil {
+		pterm.Warning.Printf("Disk quotas are disabled: %v\n", err)
+		return func() {}
+	}
+	monitor.Notify = func(alert diskquota.Alert) {
+		reportDiskAlert(server.dispatcher, alert)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		monitor.Run(ctx)
+	}()
+	pterm.Success.Printf("Disk quota monitor started (interval: %s)\n", cfg.CheckInterval())
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// diskPruners are the kinds of data the prune section of the disk
+// quotas may name. Databases are vacuumed once pruned, so the space of
+// the deleted rows is freed.
+func diskPruners(server *agentRegistryServer) map[string]diskquota.Pruner {
+	pruners := map[string]diskquota.Pruner{
+		"history": func(ctx context.Context, olderThan time.Duration) error {
+			db, err := execution.NewHistoryDB(config.GetHistoryDBPath())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			// History is kept by the day
+			days := max(int(olderThan/(24*time.Hour)), 1)
+			if _, err := db.DeleteOldExecutions(days); err != nil {
+				return err
+			}
+			return diskquota.Vacuum(config.GetHistoryDBPath())
+		},
+		"run_logs": func(ctx context.Context, olderThan time.Duration) error {
+			store, err := runlog.NewStore(config.GetRunLogDBPath())
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			if _, err := store.Prune(time.Now().Add(-olderThan)); err != nil {
+				return err
+			}
+			return diskquota.Vacuum(config.GetRunLogDBPath())
+		},
+		"events": func(ctx context.Context, olderThan time.Duration) error {
+			repo, err := hooks.NewRepository()
+			if err != nil {
+				return err
+			}
+			defer repo.Close()
+			if _, err := repo.EventQueue.CleanupOldEvents(olderThan); err != nil {
+				return err
+			}
+			return diskquota.Vacuum(config.GetHookDBPath())
+		},
+		"logs": diskquota.PruneFiles(config.GetLogDir()),
+	}
+	if server.metricsDB != nil {
+		pruners["metrics"] = func(ctx context.Context, olderThan time.Duration) error {
+			if err := server.metricsDB.CleanupOldMetrics(ctx, olderThan); err != nil {
+				return err
+			}
+			return diskquota.Vacuum(config.GetMetricsDBPath())
+		}
+	}
+	return pruners
+}
+
+// reportDiskAlert prints a quota that changed level and dispatches it as
+// an event when it rose
+func reportDiskAlert(dispatcher *hooks.Dispatcher, alert diskquota.Alert) {
+	message := fmt.Sprintf("Data directory quota %s at %.0f%%: %s of %s",
+		alert.Name, alert.Percent(), formatDiskSize(alert.Used), formatDiskSize(alert.Quota))
+	for _, p := range alert.Pruned {
+		if p.Err != nil {
+			pterm.Warning.Printf("Failed to prune %s: %v\n", p.Kind, p.Err)
+		} else {
+			pterm.Info.Printf("Pruned %s, freeing %s\n", p.Kind, formatDiskSize(p.Freed))
+		}
+	}
+	if alert.After != nil {
+		message += fmt.Sprintf(", %s after pruning", formatDiskSize(alert.After.Used))
+	}
+
+	eventType, severity := hooks.EventSystemWarning, "warning"
+	switch alert.Level {
+	case diskquota.LevelOK:
+		pterm.Info.Printf("Data directory quota %s is back under its warning level\n", alert.Name)
+		return
+	case diskquota.LevelExceeded:
+		eventType, severity = hooks.EventSystemDiskFull, "critical"
+		pterm.Error.Println(message)
+	default:
+		pterm.Warning.Println(message)
+	}
+	if dispatcher == nil {
+		return
+	}
+
+	metrics := map[string]interface{}{
+		"quota_name":  alert.Name,
+		"used_bytes":  alert.Used,
+		"quota_bytes": alert.Quota,
+		"percent":     alert.Percent(),
+		"level":       alert.Level,
+	}
+	if alert.After != nil {
+		metrics["used_bytes_after_prune"] = alert.After.Used
+		pruned := make([]interface{}, len(alert.Pruned))
+		for i, p := range alert.Pruned {
+			pruned[i] = map[string]interface{}{"kind": p.Kind, "freed_bytes": p.Freed}
+		}
+		metrics["pruned"] = pruned
+	}
+	event := &hooks.SystemEvent{
+		Type:      "disk_quota",
+		Message:   message,
+		Component: "disk",
+		Severity:  severity,
+		Metrics:   metrics,
+	}
+	if err := dispatcher.DispatchSystemEvent(eventType, event); err != nil {
+		pterm.Debug.Printf("Failed to dispatch disk quota event: %v\n", err)
+	}
+}
+
+// formatDiskSize formats bytes to human-readable format
+func formatDiskSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
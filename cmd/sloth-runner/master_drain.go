@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errMasterDraining is returned to new runs and agent events while the
+// master is drained for an upgrade. Agents keep such events buffered and
+// send them once the master is back.
+var errMasterDraining = status.Error(codes.Unavailable, "master is draining for an upgrade")
+
+// drainPollInterval is how often a drain checks for finished runs
+const drainPollInterval = 100 * time.Millisecond
+
+// drainGate tracks the runs in flight on the master and stops new ones
+// from starting while it is drained.
+type drainGate struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+}
+
+// enter registers a new run. It returns false when the master is draining.
+func (g *drainGate) enter() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.draining {
+		return false
+	}
+	g.inFlight++
+	return true
+}
+
+// admit is enter for the runs the master starts on its own, such as
+// scheduled ones: it returns the function that leaves the gate.
+func (g *drainGate) admit() (func(), bool) {
+	if !g.enter() {
+		return nil, false
+	}
+	return g.leave, true
+}
+
+// gateWorkflow wraps the function running scheduled gitops workflows so
+// their runs are refused while the master is draining.
+func (g *drainGate) gateWorkflow(run func(stack, name string) error) func(stack, name string) error {
+	return func(stack, name string) error {
+		if !g.enter() {
+			return errMasterDraining
+		}
+		defer g.leave()
+		return run(stack, name)
+	}
+}
+
+// gateTrigger does the same for the runs of event triggers and watches.
+func (g *drainGate) gateTrigger(run hooks.TriggerRunFunc) hooks.TriggerRunFunc {
+	return func(ctx context.Context, trigger hooks.Trigger, events []*hooks.Event) error {
+		if !g.enter() {
+			return errMasterDraining
+		}
+		defer g.leave()
+		return run(ctx, trigger, events)
+	}
+}
+
+// leave marks a run started with enter as finished.
+func (g *drainGate) leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+}
+
+// Draining reports whether new runs are refused.
+func (g *drainGate) Draining() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.draining
+}
+
+// drain refuses new runs and waits until the running ones finish or ctx
+// is done. It returns the number of runs still in flight.
+func (g *drainGate) drain(ctx context.Context) int {
+	g.mu.Lock()
+	g.draining = true
+	g.mu.Unlock()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		g.mu.Lock()
+		inFlight := g.inFlight
+		g.mu.Unlock()
+		if inFlight == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return inFlight
+		case <-ticker.C:
+		}
+	}
+}
+
+// resume accepts new runs again.
+func (g *drainGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.draining = false
+}
+
+// DrainMaster stops the master from starting new runs and waits for the
+// running ones, so its binary can be swapped without losing state.
+func (s *agentRegistryServer) DrainMaster(ctx context.Context, req *pb.DrainMasterRequest) (*pb.DrainMasterResponse, error) {
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pterm.Info.Println("Draining master for upgrade: new runs are refused")
+	inFlight := s.drain.drain(drainCtx)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to find master executable: %v", err)
+	}
+
+	return &pb.DrainMasterResponse{
+		Drained:    inFlight == 0,
+		InFlight:   int32(inFlight),
+		Version:    version,
+		Executable: exe,
+		DataDir:    GetSlothRunnerDataDir(),
+	}, nil
+}
+
+// ResumeMaster ends a drain, either in place or by restarting the master
+// binary, which picks up a swapped binary and migrates its databases.
+func (s *agentRegistryServer) ResumeMaster(ctx context.Context, req *pb.ResumeMasterRequest) (*pb.ResumeMasterResponse, error) {
+	// Don't report the old version while shutting down for the restart
+	if s.restartRequested() {
+		return nil, errMasterDraining
+	}
+
+	if !req.Restart {
+		if s.drain.Draining() {
+			pterm.Info.Println("Master resumed")
+		}
+		s.drain.resume()
+		return &pb.ResumeMasterResponse{Version: version}, nil
+	}
+
+	s.mu.Lock()
+	s.restart = true
+	s.mu.Unlock()
+
+	pterm.Info.Println("Restarting master to complete the upgrade")
+	// Stop after this response is sent; Start returns and the caller
+	// re-executes the binary
+	go s.Stop()
+
+	return &pb.ResumeMasterResponse{Version: version, Restarting: true}, nil
+}
+
+// restartRequested reports whether the master stopped to be re-executed.
+func (s *agentRegistryServer) restartRequested() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.restart
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDrainGate(t *testing.T) {
+	var g drainGate
+	if !g.enter() {
+		t.Fatal("Expected a run to start before draining")
+	}
+
+	drained := make(chan int)
+	go func() {
+		drained <- g.drain(context.Background())
+	}()
+
+	// Wait for the drain to start refusing runs
+	for !g.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+	if g.enter() {
+		t.Fatal("Expected new runs to be refused while draining")
+	}
+
+	g.leave()
+	if inFlight := <-drained; inFlight != 0 {
+		t.Errorf("Expected no runs in flight, got %d", inFlight)
+	}
+
+	g.resume()
+	if !g.enter() {
+		t.Error("Expected runs to start after resuming")
+	}
+}
+
+func TestDrainGate_Timeout(t *testing.T) {
+	var g drainGate
+	g.enter()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if inFlight := g.drain(ctx); inFlight != 1 {
+		t.Errorf("Expected 1 run in flight after the timeout, got %d", inFlight)
+	}
+}
+
+func TestDrainGate_WaitsForScheduledRuns(t *testing.T) {
+	var g drainGate
+	engine := scheduler.NewEngine(nil, nil)
+	engine.Admit = g.admit
+	started, release := make(chan struct{}), make(chan struct{})
+	engine.RunWorkflow = func(ctx context.Context, s *scheduler.WorkflowSchedule, out io.Writer) error {
+		close(started)
+		<-release
+		return nil
+	}
+	go engine.Trigger(&scheduler.WorkflowSchedule{Name: "nightly", Stack: "prod", Sloth: "backup"})
+	<-started
+
+	drained := make(chan int)
+	go func() {
+		drained <- g.drain(context.Background())
+	}()
+	for !g.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+	if engine.Trigger(&scheduler.WorkflowSchedule{Name: "hourly", Stack: "prod", Sloth: "sync"}) {
+		t.Error("Expected scheduled runs to be skipped while draining")
+	}
+	select {
+	case <-drained:
+		t.Fatal("Expected the drain to wait for the scheduled run")
+	case <-time.After(2 * drainPollInterval):
+	}
+
+	close(release)
+	if inFlight := <-drained; inFlight != 0 {
+		t.Errorf("Expected no runs in flight, got %d", inFlight)
+	}
+}
+
+func TestDrainGate_GatesGitOpsAndTriggerRuns(t *testing.T) {
+	var g drainGate
+	workflows, triggers := 0, 0
+	runWorkflow := g.gateWorkflow(func(stack, name string) error {
+		workflows++
+		if g.inFlight != 1 {
+			t.Errorf("Expected the gitops run to be in flight, got %d", g.inFlight)
+		}
+		return nil
+	})
+	runTrigger := g.gateTrigger(func(ctx context.Context, trigger hooks.Trigger, events []*hooks.Event) error {
+		triggers++
+		return nil
+	})
+
+	if err := runWorkflow("prod", "deploy"); err != nil {
+		t.Fatalf("Expected the gitops run to start, got %v", err)
+	}
+	if err := runTrigger(context.Background(), hooks.Trigger{Name: "on-change"}, nil); err != nil {
+		t.Fatalf("Expected the trigger run to start, got %v", err)
+	}
+	if g.inFlight != 0 {
+		t.Errorf("Expected finished runs to leave the gate, got %d in flight", g.inFlight)
+	}
+
+	g.drain(context.Background())
+	if err := runWorkflow("prod", "deploy"); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected gitops runs to be refused while draining, got %v", err)
+	}
+	if err := runTrigger(context.Background(), hooks.Trigger{Name: "on-change"}, nil); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected trigger runs to be refused while draining, got %v", err)
+	}
+	if workflows != 1 || triggers != 1 {
+		t.Errorf("Expected one run of each while not draining, got %d and %d", workflows, triggers)
+	}
+}
+
+func TestAgentRegistry_RefusesEventsWhileDraining(t *testing.T) {
+	server := &agentRegistryServer{}
+	server.drain.drain(context.Background())
+
+	_, err := server.SendEventBatch(context.Background(), &pb.SendEventBatchRequest{
+		Events: []*pb.EventData{{EventId: "event-1", EventType: "task.completed"}},
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected events to be refused as unavailable, got %v", err)
+	}
+
+	resp, err := server.ResumeMaster(context.Background(), &pb.ResumeMasterRequest{})
+	if err != nil || resp.Version != version {
+		t.Fatalf("Expected resume to report the master version, got %v %v", resp, err)
+	}
+	if server.drain.Draining() {
+		t.Error("Expected the master to accept runs after resuming")
+	}
+}
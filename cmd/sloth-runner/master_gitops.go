@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/gitops"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/pterm/pterm"
+)
+
+// startGitOps runs the gitops controller in the background, syncing and
+// scheduling workflows once 'gitops enable' was run. Scheduled runs are
+// skipped while gate is drained. It returns a function that stops it.
+func startGitOps(gate *drainGate) func() {
+	store, err := gitops.DefaultStore()
+	if err != nil {
+		pterm.Warning.Printf("GitOps sync is unavailable: %v\n", err)
+		return func() {}
+	}
+	// The repository run --sloth reads
+	sloths, err := sloth.NewSQLiteRepository("")
+	if err != nil {
+		store.Close()
+		pterm.Warning.Printf("GitOps sync is unavailable: %v\n", err)
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	controller := gitops.NewController(gitops.NewReconciler(store, sloths, config.GetGitOpsCheckoutDir()))
+	controller.RunWorkflow = gate.gateWorkflow(controller.RunWorkflow)
+	go func() {
+		defer close(done)
+		controller.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		sloths.Close()
+		store.Close()
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxRunAttempts is how many times a scheduled run starts, resumptions
+// included, before an interruption fails it instead of resuming it again
+const maxRunAttempts = 3
+
+// journalPollInterval is how often an agent still running a task of an
+// interrupted run is asked about it again
+var journalPollInterval = 5 * time.Second
+
+// taskOutcomeFunc asks the agent a task was sent to what became of it
+type taskOutcomeFunc func(ctx context.Context, dispatch execution.TaskDispatch) (*pb.TaskOutcomeResponse, error)
+
+// runRecovery recovers the runs whose journals were left behind by their
+// process stopping, e.g. with the master that started them
+type runRecovery struct {
+	dir     string
+	outcome taskOutcomeFunc
+	// resume runs a scheduled run again, returning false when it can't
+	resume func(schedule, runID string) bool
+	// record stores a failed run in the execution history
+	record func(exec *execution.Execution, tasks []*execution.TaskExecution) error
+}
+
+// startRunRecovery reconciles in the background the runs interrupted when
+// the master stopped, resuming those of workflow schedules. It returns a
+// function that stops it; resumed runs go on with the scheduler.
+func startRunRecovery(resume func(schedule, runID string) bool) func() {
+	r := &runRecovery{
+		dir:     config.GetRunJournalDir(),
+		outcome: agentTaskOutcome,
+		resume:  resume,
+		record:  recordRunHistory,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// run reconciles every interrupted run. The tasks they sent to agents get
+// the outcome the agents tell, those that ran in the stopped process are
+// interrupted. Scheduled runs are then resumed, the others recorded as
+// failed.
+func (r *runRecovery) run(ctx context.Context) {
+	runs, err := execution.InterruptedRuns(r.dir)
+	if err != nil {
+		slog.Error("Failed to read run journals", "error", err)
+		return
+	}
+
+	for _, run := range runs {
+		if run.Running() {
+			slog.Info("Run started before the master restarted is still going", "run_id", run.RunID, "pid", run.PID)
+			continue
+		}
+		slog.Warn("Recovering interrupted run", "run_id", run.RunID, "stack", run.Stack, "schedule", run.Schedule)
+		r.reconcile(ctx, run)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if run.Schedule == "" {
+			r.fail(run, "interrupted: the process running it stopped")
+			continue
+		}
+		if run.Attempts >= maxRunAttempts {
+			r.fail(run, fmt.Sprintf("interrupted %d times, not resumed again", run.Attempts))
+			continue
+		}
+		go func(run *execution.InterruptedRun) {
+			if !r.resume(run.Schedule, run.RunID) {
+				r.fail(run, "interrupted: the master stopped and its schedule couldn't resume it")
+				return
+			}
+			// A resumption that failed before journaling leaves the
+			// journal as it was
+			if left, err := execution.LoadInterruptedRun(r.dir, run.RunID); err == nil && !left.Running() && left.Attempts == run.Attempts {
+				r.fail(left, "interrupted: the master stopped and resuming it failed")
+			}
+		}(run)
+	}
+}
+
+// reconcile sets the outcome of the tasks the run didn't see finish
+func (r *runRecovery) reconcile(ctx context.Context, run *execution.InterruptedRun) {
+	for _, task := range run.Tasks {
+		if task.Finished() {
+			continue
+		}
+		status, errMsg, outputs := r.reconcileTask(ctx, task)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Info("Reconciled task of interrupted run", "run_id", run.RunID, "task", task.Name, "status", status)
+		if err := run.Reconcile(task.Name, status, errMsg, outputs); err != nil {
+			slog.Warn("Failed to journal reconciled task", "run_id", run.RunID, "task", task.Name, "error", err)
+		}
+	}
+}
+
+// reconcileTask asks the agents a task was sent to what became of it. The
+// last request to each agent tells, earlier ones were retried. The task
+// failed if it failed on an agent, and was interrupted if an agent didn't
+// finish it or it ran in the process that stopped.
+func (r *runRecovery) reconcileTask(ctx context.Context, task *execution.JournaledTask) (execution.ExecutionStatus, string, map[string]interface{}) {
+	var agents []string
+	latest := make(map[string]execution.TaskDispatch)
+	for _, d := range task.Dispatches {
+		if _, ok := latest[d.Agent]; !ok {
+			agents = append(agents, d.Agent)
+		}
+		latest[d.Agent] = d
+	}
+
+	result := execution.StatusCompleted
+	var errs []string
+	var outputs map[string]interface{}
+	for _, agent := range agents {
+		d := latest[agent]
+		status, errMsg := execution.StatusInterrupted, "interrupted: its run stopped while it ran"
+		if d.IdempotencyKey != "" {
+			status, errMsg, outputs = r.agentOutcome(ctx, task.Name, d)
+		}
+		if statusRank(status) > statusRank(result) {
+			result = status
+		}
+		if errMsg != "" {
+			if d.Agent != "" {
+				errMsg = fmt.Sprintf("agent %s: %s", d.Agent, errMsg)
+			}
+			errs = append(errs, errMsg)
+		}
+	}
+	if len(agents) == 0 {
+		result = execution.StatusInterrupted
+		errs = append(errs, "interrupted: its run stopped before it started")
+	}
+	return result, strings.Join(errs, "; "), outputs
+}
+
+// statusRank orders the statuses a task has on several agents, the worst
+// one being the task's
+func statusRank(status execution.ExecutionStatus) int {
+	switch status {
+	case execution.StatusFailed:
+		return 3
+	case execution.StatusInterrupted:
+		return 2
+	case execution.StatusSkipped:
+		return 1
+	}
+	return 0
+}
+
+// agentOutcome waits for the agent to finish the task if it is still
+// running it, and returns its outcome
+func (r *runRecovery) agentOutcome(ctx context.Context, task string, d execution.TaskDispatch) (execution.ExecutionStatus, string, map[string]interface{}) {
+	for {
+		resp, err := r.outcome(ctx, d)
+		if err != nil {
+			return execution.StatusInterrupted, fmt.Sprintf("interrupted, the agent couldn't tell its outcome: %v", err), nil
+		}
+		switch resp.GetState() {
+		case "running":
+			slog.Info("Waiting for an agent to finish a task of an interrupted run", "task", task, "agent", d.Agent)
+			select {
+			case <-ctx.Done():
+				return execution.StatusInterrupted, ctx.Err().Error(), nil
+			case <-time.After(journalPollInterval):
+			}
+			continue
+		case "finished":
+			return finishedOutcome(task, resp)
+		default:
+			return execution.StatusInterrupted, "interrupted: the agent didn't finish it", nil
+		}
+	}
+}
+
+// finishedOutcome converts the outcome of a task an agent finished
+func finishedOutcome(task string, resp *pb.TaskOutcomeResponse) (execution.ExecutionStatus, string, map[string]interface{}) {
+	// Tasks sent together only have the outcome of the batch
+	if len(resp.GetResults()) > 0 {
+		for _, result := range resp.GetResults() {
+			if result.GetTaskName() != task {
+				continue
+			}
+			switch result.GetStatus() {
+			case "Success":
+				return execution.StatusCompleted, "", nil
+			case "Failed":
+				return execution.StatusFailed, result.GetError(), nil
+			}
+			return execution.StatusSkipped, result.GetError(), nil
+		}
+		return execution.StatusSkipped, "", nil
+	}
+
+	if !resp.GetSuccess() {
+		return execution.StatusFailed, resp.GetError(), nil
+	}
+	var outputs map[string]interface{}
+	if resp.GetOutputsJson() != "" {
+		if err := json.Unmarshal([]byte(resp.GetOutputsJson()), &outputs); err != nil {
+			slog.Warn("Failed to decode outputs of reconciled task", "task", task, "error", err)
+		}
+	}
+	// Sensitive outputs are recorded masked, as the run would have
+	if data := resp.GetSetOutputsJson(); data != "" {
+		var sensitive map[string]bool
+		if err := json.Unmarshal([]byte(data), &sensitive); err != nil {
+			slog.Warn("Failed to decode set outputs of reconciled task, not keeping its outputs", "task", task, "error", err)
+			return execution.StatusCompleted, "", nil
+		}
+		outputs, _ = redact.SealOutputs(nil, outputs, sensitive)
+	}
+	return execution.StatusCompleted, "", outputs
+}
+
+// fail records the run as failed with reason, and removes its journal
+func (r *runRecovery) fail(run *execution.InterruptedRun, reason string) {
+	exec, tasks := run.Record(reason, time.Now())
+	if err := r.record(exec, tasks); err != nil {
+		slog.Error("Failed to record interrupted run, keeping its journal", "run_id", run.RunID, "error", err)
+		return
+	}
+	slog.Warn("Interrupted run recorded as failed", "run_id", run.RunID, "reason", reason,
+		"tasks_completed", exec.TasksSuccess, "tasks_failed", exec.TasksFailed)
+	if err := run.Remove(); err != nil {
+		slog.Warn("Failed to remove run journal", "run_id", run.RunID, "error", err)
+	}
+}
+
+// agentTaskOutcome asks the agent a task was sent to what became of it
+func agentTaskOutcome(ctx context.Context, d execution.TaskDispatch) (*pb.TaskOutcomeResponse, error) {
+	conn, err := grpc.Dial(d.AgentAddress, reliability.AgentDialOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	resp, err := pb.NewAgentClient(conn).GetTaskOutcome(ctx, &pb.TaskOutcomeRequest{IdempotencyKey: d.IdempotencyKey})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, fmt.Errorf("agent is too old to report task outcomes")
+	}
+	return resp, err
+}
+
+// recordRunHistory stores a run in the execution history
+func recordRunHistory(exec *execution.Execution, tasks []*execution.TaskExecution) error {
+	db, err := execution.NewHistoryDB(config.GetHistoryDBPath())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.RecordRun(exec, tasks)
+}
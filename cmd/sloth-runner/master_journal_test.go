@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/execution"
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// testRecovery is a runRecovery whose agents answer from outcomes, keyed
+// by idempotency key, and that keeps the runs it records
+type testRecovery struct {
+	runRecovery
+	mu       sync.Mutex
+	asked    map[string]int
+	recorded []*execution.Execution
+	resumed  chan string
+}
+
+func newTestRecovery(t *testing.T, outcomes map[string][]*pb.TaskOutcomeResponse, resume bool) *testRecovery {
+	r := &testRecovery{asked: make(map[string]int), resumed: make(chan string, 1)}
+	r.dir = t.TempDir()
+	r.outcome = func(ctx context.Context, d execution.TaskDispatch) (*pb.TaskOutcomeResponse, error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		answers := outcomes[d.IdempotencyKey]
+		n := r.asked[d.IdempotencyKey]
+		r.asked[d.IdempotencyKey]++
+		if n >= len(answers) {
+			return &pb.TaskOutcomeResponse{State: "unknown"}, nil
+		}
+		return answers[n], nil
+	}
+	r.resume = func(schedule, runID string) bool {
+		r.resumed <- runID
+		return resume
+	}
+	r.record = func(exec *execution.Execution, tasks []*execution.TaskExecution) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.recorded = append(r.recorded, exec)
+		return nil
+	}
+	return r
+}
+
+func TestRunRecovery_ReconcilesAndFailsUnscheduledRun(t *testing.T) {
+	journalPollInterval = time.Millisecond
+	r := newTestRecovery(t, map[string][]*pb.TaskOutcomeResponse{
+		"key-deploy": {
+			{State: "running"},
+			{State: "finished", Success: true, OutputsJson: `{"version":"1.2.0","token":"s3cr3t"}`, SetOutputsJson: `{"token":true}`},
+		},
+		"key-migrate": {{State: "finished", Error: "migration 42 failed"}},
+	}, true)
+
+	j, err := execution.OpenRunJournal(r.dir, execution.JournalRun{RunID: "run-1", Stack: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.TaskStarted("build", "", "", "")
+	j.TaskStarted("deploy", "web-01", "10.0.0.21:50051", "key-deploy")
+	j.TaskStarted("migrate", "db-01", "10.0.0.31:50051", "key-migrate")
+	j.TaskStarted("notify", "web-01", "10.0.0.21:50051", "key-notify")
+
+	run, _ := execution.LoadInterruptedRun(r.dir, "run-1")
+	r.reconcile(context.Background(), run)
+	want := map[string]execution.ExecutionStatus{
+		"build":   execution.StatusInterrupted,
+		"deploy":  execution.StatusCompleted,
+		"migrate": execution.StatusFailed,
+		"notify":  execution.StatusInterrupted,
+	}
+	for name, status := range want {
+		if got := run.Task(name).Status; got != status {
+			t.Errorf("Expected %s to be %s, got %s", name, status, got)
+		}
+	}
+	if run.Task("deploy").Outputs["version"] != "1.2.0" {
+		t.Errorf("Expected the outputs of deploy, got %v", run.Task("deploy").Outputs)
+	}
+	if token := run.Task("deploy").Outputs["token"]; token != redact.Mask {
+		t.Errorf("Expected the sensitive output of deploy masked, got %v", token)
+	}
+	if r.asked["key-deploy"] != 2 {
+		t.Errorf("Expected the agent to be asked again while it ran deploy, asked %d times", r.asked["key-deploy"])
+	}
+
+	r.run(context.Background())
+	if len(r.recorded) != 1 || r.recorded[0].ID != "run-1" || r.recorded[0].Status != execution.StatusFailed {
+		t.Fatalf("Expected the unscheduled run to be recorded as failed, got %v", r.recorded)
+	}
+	if _, err := execution.LoadInterruptedRun(r.dir, "run-1"); err == nil {
+		t.Error("Expected the journal of the recorded run to be removed")
+	}
+}
+
+func TestRunRecovery_ResumesScheduledRun(t *testing.T) {
+	r := newTestRecovery(t, nil, false)
+	if _, err := execution.OpenRunJournal(r.dir, execution.JournalRun{RunID: "run-1", Schedule: "nightly"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.run(context.Background())
+	select {
+	case runID := <-r.resumed:
+		if runID != "run-1" {
+			t.Errorf("Expected run-1 to be resumed, got %s", runID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the scheduled run to be resumed")
+	}
+
+	// The schedule couldn't resume it, so it fails
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		n := len(r.recorded)
+		r.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the run its schedule couldn't resume to be recorded as failed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunRecovery_StopsResumingAfterMaxAttempts(t *testing.T) {
+	r := newTestRecovery(t, nil, true)
+	for i := 0; i < maxRunAttempts; i++ {
+		if _, err := execution.OpenRunJournal(r.dir, execution.JournalRun{RunID: "run-1", Schedule: "nightly"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r.run(context.Background())
+	if len(r.recorded) != 1 {
+		t.Fatalf("Expected the run to be recorded as failed, got %d records", len(r.recorded))
+	}
+	select {
+	case <-r.resumed:
+		t.Error("Expected the run not to be resumed again")
+	default:
+	}
+}
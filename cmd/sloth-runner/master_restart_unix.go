@@ -0,0 +1,22 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// reexecMaster replaces the master process with the binary now at its
+// path, keeping the PID so service managers don't notice the restart.
+func reexecMaster() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find master executable: %w", err)
+	}
+	if err := syscall.Exec(exe, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("failed to restart master: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// reexecMaster starts the binary now at the master's path with the same
+// arguments and exits, as Windows has no exec.
+func reexecMaster() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find master executable: %w", err)
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to restart master: %w", err)
+	}
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/scheduler"
+	"github.com/pterm/pterm"
+)
+
+// startScheduler runs the workflow schedules added with 'scheduler add' in
+// the background, skipping their runs while gate is drained. It returns a
+// function that stops it, and one that resumes an interrupted run of a
+// schedule, returning false when it can't.
+func startScheduler(gate *drainGate) (stop func(), resume func(schedule, runID string) bool) {
+	store, err := scheduler.DefaultScheduleStore()
+	if err != nil {
+		pterm.Warning.Printf("Workflow schedules are unavailable: %v\n", err)
+		return func() {}, func(string, string) bool { return false }
+	}
+	stats, err := scheduler.DefaultStatsStore()
+	if err != nil {
+		slog.Warn("Scheduled runs won't be recorded", "error", err)
+		stats = nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	engine := scheduler.NewEngine(store, stats)
+	engine.Admit = gate.admit
+	go func() {
+		defer close(done)
+		engine.Run(ctx)
+	}()
+
+	stop = func() {
+		cancel()
+		<-done
+		if stats != nil {
+			stats.Close()
+		}
+		store.Close()
+	}
+	return stop, engine.Resume
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/pterm/pterm"
+)
+
+// startWatches checks the watch.file, watch.process and watch.port
+// declarations of the workflow files listed in the watch file in the
+// background, running their tasks when a condition changes unless gate is
+// drained. It returns a function that stops it.
+func startWatches(gate *drainGate) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	engine, err := hooks.LoadWatches(ctx, config.GetWatchesPath(), readSloth, gate.gateTrigger(hooks.RunTriggerWorkflow))
+	if err != nil {
+		cancel()
+		pterm.Error.Printf("Failed to load master watches: %v\n", err)
+		return func() {}
+	}
+	if engine == nil {
+		cancel()
+		return func() {}
+	}
+	pterm.Success.Printf("Watching %d conditions from %s\n", engine.Len(), config.GetWatchesPath())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// readSloth returns the content of an active saved sloth file, the one run
+// --sloth runs
+func readSloth(name string) (string, error) {
+	sloths, err := sloth.NewSQLiteRepository("")
+	if err != nil {
+		return "", err
+	}
+	defer sloths.Close()
+
+	sl, err := sloths.GetByName(context.Background(), name)
+	if err != nil {
+		return "", err
+	}
+	if !sl.IsActive {
+		return "", sloth.ErrSlothInactive
+	}
+	return sl.Content, nil
+}
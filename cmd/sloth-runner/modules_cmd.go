@@ -1,10 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
 	"github.com/chalkan3-sloth/sloth-runner/internal/modules"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -43,6 +45,38 @@ var modulesListCmd = &cobra.Command{
 	},
 }
 
+var modulesAliasesCmd = &cobra.Command{
+	Use:   "aliases",
+	Short: "List legacy module names and their canonical names",
+	Long: `List the legacy global names modules can still be used under.
+
+Workflows using an alias keep working, but each alias used is listed in the
+run summary as deprecated. 'sloth-runner workflow lint' reports the lines
+using them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		aliases := luainterface.ModuleAliases()
+
+		switch outputFormat {
+		case "json":
+			data, err := json.MarshalIndent(aliases, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal aliases: %w", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		case "text":
+			tableData := pterm.TableData{{"Alias", "Canonical name"}}
+			for _, a := range aliases {
+				tableData = append(tableData, []string{pterm.FgYellow.Sprint(a.Alias), pterm.FgCyan.Sprint(a.Canonical)})
+			}
+			pterm.DefaultTable.WithHasHeader().WithData(tableData).WithWriter(cmd.OutOrStdout()).Render()
+		default:
+			return fmt.Errorf("unknown format: %s", outputFormat)
+		}
+		return nil
+	},
+}
+
 func listModules(docs map[string]modules.ModuleDoc, categoryFilter string) error {
 	pterm.DefaultHeader.WithFullWidth().Println("Sloth Runner - Available Modules")
 	fmt.Println()
@@ -148,4 +182,7 @@ func init() {
 	modulesCmd.AddCommand(modulesListCmd)
 	modulesListCmd.Flags().StringP("module", "m", "", "Show detailed information for a specific module")
 	modulesListCmd.Flags().StringP("category", "c", "", "Filter modules by category")
+
+	modulesCmd.AddCommand(modulesAliasesCmd)
+	modulesAliasesCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetRelease answers an agent's version check from the master's cached
+// release index, so a fleet shares one GitHub rate limit.
+func (s *agentRegistryServer) GetRelease(ctx context.Context, req *pb.GetReleaseRequest) (*pb.GetReleaseResponse, error) {
+	if s.releases == nil {
+		return nil, status.Error(codes.Unavailable, "release index not available")
+	}
+
+	release, err := s.releases.Release(ctx, req.Tag)
+	if err != nil {
+		if errors.Is(err, releases.ErrRateLimited) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	resp := &pb.GetReleaseResponse{
+		TagName:   release.TagName,
+		Name:      release.Name,
+		FetchedAt: release.FetchedAt.Unix(),
+	}
+	for _, asset := range release.Assets {
+		resp.Assets = append(resp.Assets, &pb.ReleaseAsset{Name: asset.Name, Url: asset.URL})
+	}
+	return resp, nil
+}
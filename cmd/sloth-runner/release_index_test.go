@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/releases"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAgentRegistryGetRelease(t *testing.T) {
+	requests := 0
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/repos/chalkan3-sloth/sloth-runner/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"tag_name": "v1.2.0", "name": "v1.2.0", "assets": [
+			{"name": "sloth-runner_v1.2.0_linux_amd64.tar.gz", "browser_download_url": "https://example.com/linux.tar.gz"}
+		]}`)
+	}))
+	defer github.Close()
+
+	server := &agentRegistryServer{releases: releases.NewIndex(releases.Options{APIURL: github.URL})}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		resp, err := server.GetRelease(ctx, &pb.GetReleaseRequest{})
+		if err != nil {
+			t.Fatalf("GetRelease failed: %v", err)
+		}
+		if resp.TagName != "v1.2.0" || len(resp.Assets) != 1 || resp.Assets[0].Url != "https://example.com/linux.tar.gz" {
+			t.Errorf("Unexpected release: %v", resp)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected agents to be answered from cache, GitHub got %d requests", requests)
+	}
+
+	if _, err := server.GetRelease(ctx, &pb.GetReleaseRequest{Tag: "v0.0.1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected an unknown tag to fail, got %v", err)
+	}
+	if _, err := (&agentRegistryServer{}).GetRelease(ctx, &pb.GetReleaseRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected no index to be unavailable, got %v", err)
+	}
+}
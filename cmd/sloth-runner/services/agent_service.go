@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // AgentService handles agent operations via gRPC
@@ -134,7 +134,7 @@ func (s *AgentService) GetAgent(agentName string) (*pb.AgentInfo, error) {
 // connect establishes a gRPC connection to the master
 func (s *AgentService) connect() (*grpc.ClientConn, error) {
 	conn, err := grpc.Dial(s.masterAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to master at %s: %w", s.masterAddr, err)
@@ -13,7 +13,7 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/crypto"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // SecretsService manages encrypted secrets for stacks
@@ -42,7 +42,7 @@ func NewSecretsService() (*SecretsService, error) {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -50,7 +50,7 @@ func NewSecretsService() (*SecretsService, error) {
 	service := &SecretsService{db: db}
 
 	if err := service.initialize(); err != nil {
-		db.Close()
+		sqlitedb.Close(db)
 		return nil, err
 	}
 
@@ -82,7 +82,7 @@ func (s *SecretsService) initialize() error {
 
 // Close closes the database connection
 func (s *SecretsService) Close() error {
-	return s.db.Close()
+	return sqlitedb.Close(s.db)
 }
 
 // AddSecret adds or updates an encrypted secret for a stack
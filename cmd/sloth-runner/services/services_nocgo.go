@@ -77,14 +77,27 @@ func (s *StackService) GetSnapshot(stackID string, version int) (*stack.StateSna
 func (s *StackService) ListSnapshots(stackID string) ([]stack.StateSnapshot, error) { return nil, errNoCGO }
 func (s *StackService) RollbackToSnapshot(stackID string, version int, performedBy string) error { return errNoCGO }
 func (s *StackService) DetectDrift(stackID, resourceID string, expectedState, actualState map[string]interface{}) error { return errNoCGO }
+func (s *StackService) ResolveDrift(stackID, resourceID string) error { return errNoCGO }
 func (s *StackService) GetDriftInfo(stackID string) ([]*stack.DriftInfo, error) { return nil, errNoCGO }
 func (s *StackService) LockState(stackID, lockID, operation, who string, duration time.Duration) error { return errNoCGO }
 func (s *StackService) UnlockState(stackID, lockID string) error { return errNoCGO }
+func (s *StackService) RenewLock(stackID, lockID string, duration time.Duration) error { return errNoCGO }
+func (s *StackService) ListLocks() ([]*stack.StateLock, error) { return nil, errNoCGO }
 func (s *StackService) AddTag(stackID, tag string) error { return errNoCGO }
 func (s *StackService) GetTags(stackID string) ([]string, error) { return nil, errNoCGO }
 func (s *StackService) AddResourceDependency(resourceID, dependsOnID, depType string) error { return errNoCGO }
 func (s *StackService) GetResourceDependencies(resourceID string) ([]string, error) { return nil, errNoCGO }
 func (s *StackService) GetActivity(stackID string, limit int) ([]map[string]interface{}, error) { return nil, errNoCGO }
+func (s *StackService) RecordActivity(stackID, activityType, details, user string) error { return errNoCGO }
+func (s *StackService) ListActivityAfter(after int64, limit int) ([]stack.ActivityEntry, error) { return nil, errNoCGO }
+func (s *StackService) GetMaintenanceWindows(stackName string) ([]string, error) { return nil, errNoCGO }
+func (s *StackService) SetMaintenanceWindows(stackName string, specs []string) error { return errNoCGO }
+func (s *StackService) GetDefaultTarget(stackName string) (string, error) { return "", errNoCGO }
+func (s *StackService) SetDefaultTarget(stackName, agent string) error { return errNoCGO }
+func (s *StackService) GetBudget(stackName string) (stack.Budget, error) { return stack.Budget{}, errNoCGO }
+func (s *StackService) SetBudget(stackName string, budget stack.Budget) error { return errNoCGO }
+func (s *StackService) GetBudgetUsage(stackName string, since time.Time) (stack.Usage, error) { return stack.Usage{}, errNoCGO }
+func (s *StackService) RecordUsage(stackID string, usage stack.RunUsage) error { return errNoCGO }
 func (s *StackService) GetStackResourceDependencies(stackID string) ([]ResourceDependency, error) { return nil, errNoCGO }
 func (s *StackService) ListStackResources(stackID string) ([]*stack.Resource, error) { return nil, errNoCGO }
 func (s *StackService) ListStacks() ([]*stack.StackState, error) { return nil, errNoCGO }
@@ -41,6 +41,15 @@ func (s *SlothService) AddSloth(ctx context.Context, name, filePath, description
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	// Front-matter of v2 files fills in what wasn't given
+	meta, _, err := sloth.ParseFrontMatter(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if description == "" {
+		description = meta.Description
+	}
+
 	// Calculate file hash
 	hash := sha256.Sum256(content)
 	fileHash := fmt.Sprintf("%x", hash)
@@ -50,6 +59,7 @@ func (s *SlothService) AddSloth(ctx context.Context, name, filePath, description
 		ID:          uuid.New().String(),
 		Name:        name,
 		Description: description,
+		Version:     meta.Version,
 		FilePath:    filePath,
 		Content:     string(content),
 		IsActive:    active,
@@ -143,6 +153,11 @@ func (s *SlothService) UpdateSloth(ctx context.Context, name, filePath, descript
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	meta, _, err := sloth.ParseFrontMatter(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+
 	// Calculate new file hash
 	hash := sha256.Sum256(content)
 	fileHash := fmt.Sprintf("%x", hash)
@@ -151,9 +166,12 @@ func (s *SlothService) UpdateSloth(ctx context.Context, name, filePath, descript
 	existing.FilePath = filePath
 	existing.Content = string(content)
 	existing.FileHash = fileHash
+	existing.Version = meta.Version
 
 	if description != "" {
 		existing.Description = description
+	} else if meta.Description != "" {
+		existing.Description = meta.Description
 	}
 
 	// Save changes
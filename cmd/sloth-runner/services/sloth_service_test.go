@@ -250,6 +250,33 @@ func TestSlothService_AddSloth(t *testing.T) {
 	assert.NotEmpty(t, createdSloth.FileHash)
 }
 
+func TestSlothService_AddSloth_FrontMatter(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-sloth-*.sloth")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.WriteString("---\ndescription: From front-matter\nversion: 1.2.0\n---\nlocal x = 1\n")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	var createdSloth *sloth.Sloth
+	mockRepo := &sloth.MockRepository{
+		CreateFunc: func(ctx context.Context, s *sloth.Sloth) error {
+			createdSloth = s
+			return nil
+		},
+	}
+
+	service := NewSlothServiceWithRepository(mockRepo)
+	defer service.Close()
+
+	err = service.AddSloth(context.Background(), "test-sloth", tmpFile.Name(), "", true)
+	require.NoError(t, err)
+	require.NotNil(t, createdSloth)
+	assert.Equal(t, "From front-matter", createdSloth.Description)
+	assert.Equal(t, "1.2.0", createdSloth.Version)
+}
+
 func TestSlothService_AddSloth_FileNotFound(t *testing.T) {
 	mockRepo := &sloth.MockRepository{}
 
@@ -4,6 +4,7 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -154,6 +155,11 @@ func (s *StackService) DetectDrift(stackID, resourceID string, expectedState, ac
 	return s.backend.DetectDrift(stackID, resourceID, expectedState, actualState)
 }
 
+// ResolveDrift marks the pending drift of a resource resolved
+func (s *StackService) ResolveDrift(stackID, resourceID string) error {
+	return s.backend.ResolveDrift(stackID, resourceID)
+}
+
 // GetDriftInfo retrieves drift information for a stack
 func (s *StackService) GetDriftInfo(stackID string) ([]*stack.DriftInfo, error) {
 	return s.backend.GetDriftInfo(stackID)
@@ -169,6 +175,16 @@ func (s *StackService) UnlockState(stackID, lockID string) error {
 	return s.backend.UnlockState(stackID, lockID)
 }
 
+// RenewLock extends a state lock its holder still holds
+func (s *StackService) RenewLock(stackID, lockID string, duration time.Duration) error {
+	return s.backend.RenewLock(stackID, lockID, duration)
+}
+
+// ListLocks returns the state locks that haven't expired
+func (s *StackService) ListLocks() ([]*stack.StateLock, error) {
+	return s.backend.ListLocks()
+}
+
 // AddTag adds a tag to a stack
 func (s *StackService) AddTag(stackID, tag string) error {
 	return s.backend.AddTag(stackID, tag)
@@ -194,6 +210,154 @@ func (s *StackService) GetActivity(stackID string, limit int) ([]map[string]inte
 	return s.backend.GetActivity(stackID, limit)
 }
 
+// RecordActivity adds an entry to a stack's activity log
+func (s *StackService) RecordActivity(stackID, activityType, details, user string) error {
+	return s.backend.RecordActivity(stackID, activityType, "", details, user)
+}
+
+// ListActivityAfter returns the activity of every stack recorded after the
+// entry with ID after, oldest first
+func (s *StackService) ListActivityAfter(after int64, limit int) ([]stack.ActivityEntry, error) {
+	return s.backend.ListActivityAfter(after, limit)
+}
+
+// MaintenanceWindowsKey is the stack configuration key holding the specs
+// of the stack's maintenance windows
+const MaintenanceWindowsKey = "maintenance_windows"
+
+// GetMaintenanceWindows returns the maintenance window specs of a stack,
+// none for stacks that don't exist yet
+func (s *StackService) GetMaintenanceWindows(stackName string) ([]string, error) {
+	st, err := s.manager.GetStackByName(stackName)
+	if err != nil {
+		return nil, nil
+	}
+
+	raw, _ := st.Configuration[MaintenanceWindowsKey].([]interface{})
+	specs := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if spec, ok := v.(string); ok {
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// SetMaintenanceWindows replaces the maintenance window specs of a stack;
+// no specs allow runs anytime
+func (s *StackService) SetMaintenanceWindows(stackName string, specs []string) error {
+	st, err := s.manager.GetStackByName(stackName)
+	if err != nil {
+		return err
+	}
+
+	if st.Configuration == nil {
+		st.Configuration = make(map[string]interface{})
+	}
+	if len(specs) == 0 {
+		delete(st.Configuration, MaintenanceWindowsKey)
+	} else {
+		st.Configuration[MaintenanceWindowsKey] = specs
+	}
+	return s.manager.UpdateStack(st)
+}
+
+// DefaultTargetKey is the stack configuration key holding the agent tasks
+// of the stack run on when they don't set delegate_to
+const DefaultTargetKey = "default_target"
+
+// GetDefaultTarget returns the default target agent of a stack, none for
+// stacks that don't exist yet
+func (s *StackService) GetDefaultTarget(stackName string) (string, error) {
+	st, err := s.manager.GetStackByName(stackName)
+	if err != nil {
+		return "", nil
+	}
+
+	agent, _ := st.Configuration[DefaultTargetKey].(string)
+	return agent, nil
+}
+
+// SetDefaultTarget sets the default target agent of a stack; an empty
+// agent makes tasks without delegate_to run locally again
+func (s *StackService) SetDefaultTarget(stackName, agent string) error {
+	st, err := s.manager.GetStackByName(stackName)
+	if err != nil {
+		return err
+	}
+
+	if st.Configuration == nil {
+		st.Configuration = make(map[string]interface{})
+	}
+	if agent == "" {
+		delete(st.Configuration, DefaultTargetKey)
+	} else {
+		st.Configuration[DefaultTargetKey] = agent
+	}
+	return s.manager.UpdateStack(st)
+}
+
+// BudgetKey is the stack configuration key holding the stack's execution
+// budget
+const BudgetKey = "budget"
+
+// GetBudget returns the execution budget of a stack, none for stacks that
+// don't exist yet
+func (s *StackService) GetBudget(stackName string) (stack.Budget, error) {
+	var budget stack.Budget
+	st, err := s.manager.GetStackByName(stackName)
+	if err != nil {
+		return budget, nil
+	}
+
+	raw, ok := st.Configuration[BudgetKey]
+	if !ok {
+		return budget, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return budget, err
+	}
+	if err := json.Unmarshal(data, &budget); err != nil {
+		return budget, fmt.Errorf("invalid budget of stack '%s': %w", stackName, err)
+	}
+	return budget, nil
+}
+
+// SetBudget replaces the execution budget of a stack; a zero budget lets
+// it run without limits
+func (s *StackService) SetBudget(stackName string, budget stack.Budget) error {
+	st, err := s.manager.GetStackByName(stackName)
+	if err != nil {
+		return err
+	}
+
+	if st.Configuration == nil {
+		st.Configuration = make(map[string]interface{})
+	}
+	if budget.IsZero() {
+		delete(st.Configuration, BudgetKey)
+	} else {
+		st.Configuration[BudgetKey] = budget
+	}
+	return s.manager.UpdateStack(st)
+}
+
+// GetBudgetUsage returns what the runs of a stack consumed since since,
+// nothing for stacks that don't exist yet
+func (s *StackService) GetBudgetUsage(stackName string, since time.Time) (stack.Usage, error) {
+	st, err := s.manager.GetStackByName(stackName)
+	if err != nil {
+		return stack.Usage{Since: since}, nil
+	}
+	return s.backend.GetUsage(st.ID, since)
+}
+
+// RecordUsage records what a run of a stack consumed
+func (s *StackService) RecordUsage(stackID string, usage stack.RunUsage) error {
+	return s.backend.RecordUsage(stackID, usage)
+}
+
 // ResourceDependency represents a dependency between resources
 type ResourceDependency struct {
 	ResourceID  string `json:"resource_id"`
@@ -0,0 +1,544 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/download"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// Artifact sources reported by ArtifactCache.Fetch
+const (
+	ArtifactSourceLocal  = "local"
+	ArtifactSourcePeer   = "peer"
+	ArtifactSourceOrigin = "origin"
+)
+
+// artifactSHAHeader tells clients the checksum of a served artifact. Agents
+// check peer downloads against the master's index, not this header.
+const artifactSHAHeader = "X-Artifact-SHA256"
+
+// PeerDiscovery finds other agents that already hold an artifact and
+// advertises artifacts downloaded by this agent.
+type PeerDiscovery interface {
+	// Lookup returns the base addresses (host:port) of peers holding key
+	// and the SHA-256 checksum the artifact was indexed with. Peers are
+	// only used when the checksum is known, as the bytes they serve are
+	// checked against it.
+	Lookup(ctx context.Context, key string) ([]string, string, error)
+	// Announce tells other agents that this agent now holds the artifact
+	Announce(ctx context.Context, entry ArtifactEntry) error
+}
+
+// ArtifactEntry describes a cached artifact
+type ArtifactEntry struct {
+	Key       string    `json:"key"`
+	URL       string    `json:"url"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// ArtifactFetchResult is returned by Fetch
+type ArtifactFetchResult struct {
+	ArtifactEntry
+	Path string `json:"path"`
+	Peer string `json:"peer,omitempty"`
+	Hit  string `json:"hit"` // local, peer or origin
+}
+
+// ArtifactCacheStats holds cache counters
+type ArtifactCacheStats struct {
+	LocalHits     int64 `json:"local_hits"`
+	PeerHits      int64 `json:"peer_hits"`
+	OriginFetches int64 `json:"origin_fetches"`
+	BytesFromPeer int64 `json:"bytes_from_peer"`
+	BytesOrigin   int64 `json:"bytes_from_origin"`
+	Entries       int   `json:"entries"`
+}
+
+// ArtifactCacheConfig holds configuration for the artifact cache
+type ArtifactCacheConfig struct {
+	Dir         string
+	Discovery   PeerDiscovery // nil disables peer lookups
+	HTTPTimeout time.Duration
+	// PeerTLS, when set, is used to fetch from peers over https; their
+	// Handler is then served with the matching server configuration
+	PeerTLS *tls.Config
+}
+
+// ArtifactCache is an agent-local read-through cache for remote files.
+// A miss is first resolved against peers known to hold the artifact and
+// only then against the origin URL, so a fleet downloads each URL once.
+type ArtifactCache struct {
+	dir        string
+	discovery  PeerDiscovery
+	client     *http.Client
+	peerClient *http.Client
+	peerScheme string
+
+	mu       sync.Mutex
+	inflight map[string]*sync.Mutex
+
+	localHits     int64
+	peerHits      int64
+	originFetches int64
+	bytesFromPeer int64
+	bytesOrigin   int64
+}
+
+// NewArtifactCache creates the cache directory and returns a cache rooted at it
+func NewArtifactCache(config ArtifactCacheConfig) (*ArtifactCache, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("artifact cache directory is required")
+	}
+	if config.HTTPTimeout == 0 {
+		config.HTTPTimeout = 10 * time.Minute
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact cache directory: %w", err)
+	}
+
+	peerClient, peerScheme := &http.Client{Timeout: config.HTTPTimeout}, "http"
+	if config.PeerTLS != nil {
+		peerClient.Transport = &http.Transport{TLSClientConfig: config.PeerTLS}
+		peerScheme = "https"
+	}
+
+	return &ArtifactCache{
+		dir:        config.Dir,
+		discovery:  config.Discovery,
+		client:     &http.Client{Timeout: config.HTTPTimeout},
+		peerClient: peerClient,
+		peerScheme: peerScheme,
+		inflight:   make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// ArtifactKey returns the cache key for a URL
+func ArtifactKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// isValidArtifactKey guards the HTTP handler against path traversal
+func isValidArtifactKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// Dir returns the cache directory
+func (c *ArtifactCache) Dir() string {
+	return c.dir
+}
+
+func (c *ArtifactCache) dataPath(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+func (c *ArtifactCache) metaPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// keyLock serializes concurrent fetches of the same artifact
+func (c *ArtifactCache) keyLock(key string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.inflight[key]
+	if !ok {
+		l = &sync.Mutex{}
+		c.inflight[key] = l
+	}
+	return l
+}
+
+// Lookup returns the cached entry for a key, if present
+func (c *ArtifactCache) Lookup(key string) (*ArtifactEntry, bool) {
+	if _, err := os.Stat(c.dataPath(key)); err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.metaPath(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry ArtifactEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Fetch returns a local path for url, downloading it from a peer or the
+// origin on a cache miss
+func (c *ArtifactCache) Fetch(ctx context.Context, url string) (*ArtifactFetchResult, error) {
+	key := ArtifactKey(url)
+	lock := c.keyLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if entry, ok := c.Lookup(key); ok {
+		atomic.AddInt64(&c.localHits, 1)
+		return &ArtifactFetchResult{ArtifactEntry: *entry, Path: c.dataPath(key), Hit: ArtifactSourceLocal}, nil
+	}
+
+	if c.discovery != nil {
+		peers, sum, err := c.discovery.Lookup(ctx, key)
+		if err != nil {
+			slog.Debug("Artifact peer lookup failed", "key", key, "error", err)
+		}
+		if sum == "" {
+			// Nothing to check what peers serve against
+			peers = nil
+		}
+		for _, peer := range peers {
+			entry, err := c.download(ctx, peerArtifactURL(c.peerScheme, peer, key), key, url, ArtifactSourcePeer, sum)
+			if err != nil {
+				slog.Debug("Failed to fetch artifact from peer", "peer", peer, "key", key, "error", err)
+				continue
+			}
+			atomic.AddInt64(&c.peerHits, 1)
+			atomic.AddInt64(&c.bytesFromPeer, entry.Size)
+			c.announce(ctx, *entry)
+			return &ArtifactFetchResult{ArtifactEntry: *entry, Path: c.dataPath(key), Peer: peer, Hit: ArtifactSourcePeer}, nil
+		}
+	}
+
+	entry, err := c.download(ctx, url, key, url, ArtifactSourceOrigin, "")
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&c.originFetches, 1)
+	atomic.AddInt64(&c.bytesOrigin, entry.Size)
+	c.announce(ctx, *entry)
+	return &ArtifactFetchResult{ArtifactEntry: *entry, Path: c.dataPath(key), Hit: ArtifactSourceOrigin}, nil
+}
+
+// CopyTo fetches url through the cache and copies it to dest
+func (c *ArtifactCache) CopyTo(ctx context.Context, url, dest string, mode os.FileMode) (*ArtifactFetchResult, error) {
+	result, err := c.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := os.Open(result.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cached artifact: %w", err)
+	}
+	defer src.Close()
+
+	if dir := filepath.Dir(dest); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create destination directory: %w", err)
+		}
+	}
+	dst, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return nil, fmt.Errorf("failed to copy artifact: %w", err)
+	}
+	return result, nil
+}
+
+func (c *ArtifactCache) announce(ctx context.Context, entry ArtifactEntry) {
+	if c.discovery == nil {
+		return
+	}
+	if err := c.discovery.Announce(ctx, entry); err != nil {
+		slog.Debug("Failed to announce artifact", "key", entry.Key, "error", err)
+	}
+}
+
+// download streams src into the cache. The content must match expected,
+// when set; peer downloads always set it.
+func (c *ArtifactCache) download(ctx context.Context, src, key, url, source, expected string) (*ArtifactEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Peers are on the site's network; only origin fetches take uplink
+	// bandwidth and download slots
+	var resp *http.Response
+	if source == ArtifactSourceOrigin {
+		resp, err = download.Default().Do(c.client, req)
+	} else {
+		resp, err = c.peerClient.Do(req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", src, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: HTTP %d", src, resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write artifact: %w", err)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if expected != "" && !strings.EqualFold(expected, sum) {
+		return nil, fmt.Errorf("checksum mismatch from %s: expected %s, got %s", src, expected, sum)
+	}
+
+	entry := &ArtifactEntry{
+		Key:       key,
+		URL:       url,
+		Size:      size,
+		SHA256:    sum,
+		Source:    source,
+		FetchedAt: time.Now(),
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(c.metaPath(key), meta, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write artifact metadata: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.dataPath(key)); err != nil {
+		os.Remove(c.metaPath(key))
+		return nil, fmt.Errorf("failed to store artifact: %w", err)
+	}
+
+	return entry, nil
+}
+
+// Entries lists all cached artifacts
+func (c *ArtifactCache) Entries() ([]ArtifactEntry, error) {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ArtifactEntry
+	for _, f := range files {
+		key := f.Name()
+		if f.IsDir() || !isValidArtifactKey(key) {
+			continue
+		}
+		if entry, ok := c.Lookup(key); ok {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries, nil
+}
+
+// AnnounceAll re-announces every cached artifact, e.g. after the agent
+// reconnects to a master that lost its in-memory index
+func (c *ArtifactCache) AnnounceAll(ctx context.Context) {
+	entries, err := c.Entries()
+	if err != nil {
+		slog.Warn("Failed to list artifact cache", "error", err)
+		return
+	}
+	for _, entry := range entries {
+		c.announce(ctx, entry)
+	}
+}
+
+// Remove deletes a cached artifact
+func (c *ArtifactCache) Remove(key string) error {
+	if !isValidArtifactKey(key) {
+		return fmt.Errorf("invalid artifact key: %s", key)
+	}
+	os.Remove(c.metaPath(key))
+	if err := os.Remove(c.dataPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Stats returns the cache counters
+func (c *ArtifactCache) Stats() ArtifactCacheStats {
+	entries, _ := c.Entries()
+	return ArtifactCacheStats{
+		LocalHits:     atomic.LoadInt64(&c.localHits),
+		PeerHits:      atomic.LoadInt64(&c.peerHits),
+		OriginFetches: atomic.LoadInt64(&c.originFetches),
+		BytesFromPeer: atomic.LoadInt64(&c.bytesFromPeer),
+		BytesOrigin:   atomic.LoadInt64(&c.bytesOrigin),
+		Entries:       len(entries),
+	}
+}
+
+// Handler serves cached artifacts to peers at /artifacts/<key>.
+// Only artifacts already present are served; peers never trigger downloads.
+func (c *ArtifactCache) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/artifacts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/artifacts/")
+		if !isValidArtifactKey(key) {
+			http.Error(w, "invalid artifact key", http.StatusBadRequest)
+			return
+		}
+		entry, ok := c.Lookup(key)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(artifactSHAHeader, entry.SHA256)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeFile(w, r, c.dataPath(key))
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.Stats())
+	})
+	return mux
+}
+
+func peerArtifactURL(scheme, peer, key string) string {
+	if !strings.HasPrefix(peer, "http://") && !strings.HasPrefix(peer, "https://") {
+		peer = scheme + "://" + peer
+	}
+	return strings.TrimSuffix(peer, "/") + "/artifacts/" + key
+}
+
+// StaticPeerDiscovery queries a fixed list of peers and never announces.
+// Peers are only asked for artifacts with a pinned checksum; the others
+// come from the origin.
+type StaticPeerDiscovery struct {
+	Peers []string
+	// Checksums are the pinned SHA-256 checksums by artifact key
+	Checksums map[string]string
+}
+
+// NewStaticPeerDiscovery returns a discovery asking peers for the URLs of
+// checksums, by their SHA-256 checksum
+func NewStaticPeerDiscovery(peers []string, checksums map[string]string) *StaticPeerDiscovery {
+	d := &StaticPeerDiscovery{Peers: peers, Checksums: make(map[string]string, len(checksums))}
+	for url, sum := range checksums {
+		d.Checksums[ArtifactKey(url)] = strings.ToLower(sum)
+	}
+	return d
+}
+
+// Lookup returns the configured peers and the pinned checksum of key,
+// no peers for artifacts without one
+func (d *StaticPeerDiscovery) Lookup(ctx context.Context, key string) ([]string, string, error) {
+	sum, ok := d.Checksums[key]
+	if !ok {
+		return nil, "", nil
+	}
+	return d.Peers, sum, nil
+}
+
+// Announce is a no-op for static discovery
+func (d *StaticPeerDiscovery) Announce(ctx context.Context, entry ArtifactEntry) error {
+	return nil
+}
+
+// MasterPeerDiscovery uses the master's artifact index to find peers
+type MasterPeerDiscovery struct {
+	Client       pb.AgentRegistryClient
+	AgentName    string
+	CacheAddress string
+}
+
+// Lookup asks the master which agents hold key, and the checksum they
+// announced. Peers announcing different checksums return none.
+func (d *MasterPeerDiscovery) Lookup(ctx context.Context, key string) ([]string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := d.Client.LookupArtifact(ctx, &pb.LookupArtifactRequest{
+		Key:             key,
+		RequestingAgent: d.AgentName,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	peers := make([]string, 0, len(resp.Peers))
+	sum := ""
+	for _, p := range resp.Peers {
+		if p.CacheAddress == "" {
+			continue
+		}
+		if p.Sha256 == "" || (sum != "" && !strings.EqualFold(sum, p.Sha256)) {
+			return nil, "", nil
+		}
+		sum = p.Sha256
+		peers = append(peers, p.CacheAddress)
+	}
+	return peers, sum, nil
+}
+
+// Announce registers this agent as a holder of the artifact
+func (d *MasterPeerDiscovery) Announce(ctx context.Context, entry ArtifactEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := d.Client.AnnounceArtifact(ctx, &pb.AnnounceArtifactRequest{
+		AgentName:    d.AgentName,
+		CacheAddress: d.CacheAddress,
+		Key:          entry.Key,
+		Url:          entry.URL,
+		Size:         entry.Size,
+		Sha256:       entry.SHA256,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("master rejected announcement: %s", resp.Message)
+	}
+	return nil
+}
+
+var (
+	globalArtifactCache   *ArtifactCache
+	globalArtifactCacheMu sync.RWMutex
+)
+
+// SetGlobalArtifactCache sets the cache used by Lua modules running on this agent
+func SetGlobalArtifactCache(cache *ArtifactCache) {
+	globalArtifactCacheMu.Lock()
+	defer globalArtifactCacheMu.Unlock()
+	globalArtifactCache = cache
+}
+
+// GetGlobalArtifactCache returns the agent's artifact cache, or nil if disabled
+func GetGlobalArtifactCache() *ArtifactCache {
+	globalArtifactCacheMu.RLock()
+	defer globalArtifactCacheMu.RUnlock()
+	return globalArtifactCache
+}
@@ -0,0 +1,261 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestOrigin(t *testing.T, body string) (*httptest.Server, *int64) {
+	var hits int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// indexedDiscovery returns peers with the checksum an index holds
+type indexedDiscovery struct {
+	peers  []string
+	sha256 string
+}
+
+func (d *indexedDiscovery) Lookup(ctx context.Context, key string) ([]string, string, error) {
+	return d.peers, d.sha256, nil
+}
+
+func (d *indexedDiscovery) Announce(ctx context.Context, entry ArtifactEntry) error {
+	return nil
+}
+
+func TestArtifactCache_FetchFromOriginThenLocal(t *testing.T) {
+	origin, hits := newTestOrigin(t, "binary-content")
+
+	cache, err := NewArtifactCache(ArtifactCacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+
+	url := origin.URL + "/tool.tar.gz"
+	result, err := cache.Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if result.Hit != ArtifactSourceOrigin {
+		t.Errorf("Expected origin hit, got %s", result.Hit)
+	}
+	if result.Size != int64(len("binary-content")) {
+		t.Errorf("Expected size %d, got %d", len("binary-content"), result.Size)
+	}
+
+	result, err = cache.Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Second fetch failed: %v", err)
+	}
+	if result.Hit != ArtifactSourceLocal {
+		t.Errorf("Expected local hit, got %s", result.Hit)
+	}
+	if atomic.LoadInt64(hits) != 1 {
+		t.Errorf("Expected 1 origin request, got %d", atomic.LoadInt64(hits))
+	}
+
+	stats := cache.Stats()
+	if stats.LocalHits != 1 || stats.OriginFetches != 1 || stats.Entries != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestArtifactCache_FetchFromPeer(t *testing.T) {
+	origin, hits := newTestOrigin(t, "shared-artifact")
+	url := origin.URL + "/pkg.deb"
+
+	seed, err := NewArtifactCache(ArtifactCacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+	seeded, err := seed.Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Seed fetch failed: %v", err)
+	}
+
+	peerServer := httptest.NewServer(seed.Handler())
+	defer peerServer.Close()
+
+	cache, err := NewArtifactCache(ArtifactCacheConfig{
+		Dir:       t.TempDir(),
+		Discovery: &indexedDiscovery{peers: []string{strings.TrimPrefix(peerServer.URL, "http://")}, sha256: seeded.SHA256},
+	})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "out", "pkg.deb")
+	result, err := cache.CopyTo(context.Background(), url, dest, 0644)
+	if err != nil {
+		t.Fatalf("CopyTo failed: %v", err)
+	}
+	if result.Hit != ArtifactSourcePeer {
+		t.Errorf("Expected peer hit, got %s", result.Hit)
+	}
+	if atomic.LoadInt64(hits) != 1 {
+		t.Errorf("Expected origin to be hit once, got %d", atomic.LoadInt64(hits))
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("Failed to read destination: %v", err)
+	}
+	if string(data) != "shared-artifact" {
+		t.Errorf("Unexpected content: %q", string(data))
+	}
+}
+
+func TestArtifactCache_UnverifiedPeersFallBackToOrigin(t *testing.T) {
+	origin, hits := newTestOrigin(t, "genuine")
+	genuine, err := NewArtifactCache(ArtifactCacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+	indexed, err := genuine.Fetch(context.Background(), origin.URL+"/tool")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	// The peer sends a checksum matching the content it tampered with
+	evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte("tampered"))
+		w.Header().Set(artifactSHAHeader, hex.EncodeToString(sum[:]))
+		w.Write([]byte("tampered"))
+	}))
+	defer evil.Close()
+	peers := []string{strings.TrimPrefix(evil.URL, "http://")}
+
+	for name, discovery := range map[string]PeerDiscovery{
+		"indexed checksum":    &indexedDiscovery{peers: peers, sha256: indexed.SHA256},
+		"no index":            &StaticPeerDiscovery{Peers: peers},
+		"no indexed checksum": &indexedDiscovery{peers: peers},
+	} {
+		cache, err := NewArtifactCache(ArtifactCacheConfig{Dir: t.TempDir(), Discovery: discovery})
+		if err != nil {
+			t.Fatalf("NewArtifactCache failed: %v", err)
+		}
+		result, err := cache.Fetch(context.Background(), origin.URL+"/tool")
+		if err != nil {
+			t.Fatalf("%s: Fetch failed: %v", name, err)
+		}
+		data, _ := os.ReadFile(result.Path)
+		if result.Hit != ArtifactSourceOrigin || string(data) != "genuine" {
+			t.Errorf("%s: expected the origin's content, got %q from %s", name, data, result.Hit)
+		}
+	}
+	if n := atomic.LoadInt64(hits); n != 4 {
+		t.Errorf("Expected 4 origin requests, got %d", n)
+	}
+}
+
+func TestArtifactCache_StaticPeersServePinnedArtifactsOverTLS(t *testing.T) {
+	origin, hits := newTestOrigin(t, "pinned-artifact")
+	url := origin.URL + "/tool"
+
+	seed, err := NewArtifactCache(ArtifactCacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+	seeded, err := seed.Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Seed fetch failed: %v", err)
+	}
+	peer := httptest.NewTLSServer(seed.Handler())
+	defer peer.Close()
+	peers := []string{strings.TrimPrefix(peer.URL, "https://")}
+
+	cache, err := NewArtifactCache(ArtifactCacheConfig{
+		Dir:       t.TempDir(),
+		Discovery: NewStaticPeerDiscovery(peers, map[string]string{url: strings.ToUpper(seeded.SHA256)}),
+		PeerTLS:   peer.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+	result, err := cache.Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if result.Hit != ArtifactSourcePeer {
+		t.Errorf("Expected the pinned artifact from the peer, got %s", result.Hit)
+	}
+	if n := atomic.LoadInt64(hits); n != 1 {
+		t.Errorf("Expected only the seed to hit the origin, got %d requests", n)
+	}
+
+	// A wrong pin makes the peer's bytes fail the check
+	cache, err = NewArtifactCache(ArtifactCacheConfig{
+		Dir:       t.TempDir(),
+		Discovery: NewStaticPeerDiscovery(peers, map[string]string{url: strings.Repeat("0", 64)}),
+		PeerTLS:   peer.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+	if result, err = cache.Fetch(context.Background(), url); err != nil || result.Hit != ArtifactSourceOrigin {
+		t.Errorf("Expected a mismatching peer to fall back to the origin, got %+v, %v", result, err)
+	}
+}
+
+func TestArtifactCache_PeerFailureFallsBackToOrigin(t *testing.T) {
+	origin, hits := newTestOrigin(t, "content")
+
+	cache, err := NewArtifactCache(ArtifactCacheConfig{
+		Dir:       t.TempDir(),
+		Discovery: &StaticPeerDiscovery{Peers: []string{"127.0.0.1:1"}},
+	})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+
+	result, err := cache.Fetch(context.Background(), origin.URL+"/file")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if result.Hit != ArtifactSourceOrigin {
+		t.Errorf("Expected origin hit, got %s", result.Hit)
+	}
+	if atomic.LoadInt64(hits) != 1 {
+		t.Errorf("Expected 1 origin request, got %d", atomic.LoadInt64(hits))
+	}
+}
+
+func TestArtifactCache_HandlerRejectsInvalidKeys(t *testing.T) {
+	cache, err := NewArtifactCache(ArtifactCacheConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewArtifactCache failed: %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/artifacts/etc/passwd", http.StatusBadRequest},
+		{"/artifacts/not-a-key", http.StatusBadRequest},
+		{"/artifacts/" + ArtifactKey("http://example.com/missing"), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = tt.path
+		cache.Handler().ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCustomFactsDir is where agents look for custom fact sources
+const DefaultCustomFactsDir = "/etc/sloth-runner/facts.d"
+
+// Default custom fact collection settings
+const (
+	DefaultCustomFactsTimeout = 10 * time.Second
+	DefaultCustomFactsTTL     = 5 * time.Minute
+)
+
+// CustomFactsCollector gathers user-defined facts from a directory. Each
+// executable file is a script whose JSON output becomes the fact named
+// after the file without its extension; *.json files are read as they
+// are. Outputs are cached for a TTL, or until the file changes, so slow
+// scripts don't run on every refresh.
+type CustomFactsCollector struct {
+	dir     string
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*customFact
+}
+
+type customFact struct {
+	modTime   time.Time
+	size      int64
+	collected time.Time
+	value     interface{}
+}
+
+// NewCustomFactsCollector creates a collector for dir. Zero timeout or ttl
+// use the defaults.
+func NewCustomFactsCollector(dir string, timeout, ttl time.Duration) *CustomFactsCollector {
+	if timeout <= 0 {
+		timeout = DefaultCustomFactsTimeout
+	}
+	if ttl <= 0 {
+		ttl = DefaultCustomFactsTTL
+	}
+	return &CustomFactsCollector{
+		dir:     dir,
+		timeout: timeout,
+		ttl:     ttl,
+		cache:   make(map[string]*customFact),
+	}
+}
+
+// Dir returns the directory the collector reads
+func (c *CustomFactsCollector) Dir() string {
+	return c.dir
+}
+
+// Collect returns the custom facts keyed by name. A source that fails keeps
+// its last value until it succeeds again; one that never succeeded is
+// left out.
+func (c *CustomFactsCollector) Collect() map[string]interface{} {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to read custom facts directory", "dir", c.dir, "error", err)
+		}
+		return nil
+	}
+
+	type source struct {
+		name string
+		path string
+		info os.FileInfo
+		exec bool
+	}
+	var sources []source
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(c.dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		isJSON := filepath.Ext(entry.Name()) == ".json"
+		isExec := info.Mode().Perm()&0111 != 0
+		if !isJSON && !isExec {
+			continue
+		}
+		sources = append(sources, source{
+			name: CustomFactName(entry.Name()),
+			path: path,
+			info: info,
+			exec: isExec,
+		})
+	}
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		c.mu.Lock()
+		cached := c.cache[src.path]
+		c.mu.Unlock()
+		if cached != nil && cached.modTime.Equal(src.info.ModTime()) && cached.size == src.info.Size() && now.Sub(cached.collected) < c.ttl {
+			continue
+		}
+
+		wg.Add(1)
+		go func(path string, info os.FileInfo, isExec bool) {
+			defer wg.Done()
+			value, err := c.read(path, isExec)
+			if err != nil {
+				slog.Warn("Failed to collect custom fact", "source", path, "error", err)
+				return
+			}
+			c.mu.Lock()
+			c.cache[path] = &customFact{modTime: info.ModTime(), size: info.Size(), collected: now, value: value}
+			c.mu.Unlock()
+		}(src.path, src.info, src.exec)
+	}
+	wg.Wait()
+
+	// Sources are sorted by file name, so on a name clash the later file wins
+	sort.Slice(sources, func(i, j int) bool { return sources[i].path < sources[j].path })
+	facts := make(map[string]interface{}, len(sources))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	present := make(map[string]bool, len(sources))
+	for _, src := range sources {
+		present[src.path] = true
+		if cached := c.cache[src.path]; cached != nil {
+			facts[src.name] = cached.value
+		}
+	}
+	for path := range c.cache {
+		if !present[path] {
+			delete(c.cache, path)
+		}
+	}
+	return facts
+}
+
+// Invalidate drops cached outputs so the next Collect runs every source
+func (c *CustomFactsCollector) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = make(map[string]*customFact)
+}
+
+func (c *CustomFactsCollector) read(path string, isExec bool) (interface{}, error) {
+	var output []byte
+	if isExec {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Dir = c.dir
+		cmd.Stderr = &stderr
+		// Don't wait for children of a killed script still holding stdout
+		cmd.WaitDelay = time.Second
+		out, err := cmd.Output()
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", c.timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		output = out
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		output = data
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(output, &value); err != nil {
+		return nil, fmt.Errorf("output is not valid JSON: %w", err)
+	}
+	return value, nil
+}
+
+// CustomFactName returns the fact name of a source file: its name without
+// the extension
+func CustomFactName(fileName string) string {
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName))
+}
+
+var (
+	globalCustomFacts   *CustomFactsCollector
+	globalCustomFactsMu sync.RWMutex
+)
+
+// SetGlobalCustomFacts sets the collector used by CollectSystemInfo
+func SetGlobalCustomFacts(c *CustomFactsCollector) {
+	globalCustomFactsMu.Lock()
+	defer globalCustomFactsMu.Unlock()
+	globalCustomFacts = c
+}
+
+// GetGlobalCustomFacts returns the agent's custom facts collector, or nil if
+// custom facts are disabled
+func GetGlobalCustomFacts() *CustomFactsCollector {
+	globalCustomFactsMu.RLock()
+	defer globalCustomFactsMu.RUnlock()
+	return globalCustomFacts
+}
+
+// flattenCustomFact adds value to facts under key, with nested objects
+// flattened into dotted keys. Strings are kept as they are; lists and
+// other values are stored as JSON.
+func flattenCustomFact(facts Facts, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, nested := range v {
+			flattenCustomFact(facts, key+"."+k, nested)
+		}
+	case string:
+		facts[key] = v
+	case nil:
+		facts[key] = "null"
+	default:
+		data, err := json.Marshal(v)
+		if err == nil {
+			facts[key] = string(data)
+		}
+	}
+}
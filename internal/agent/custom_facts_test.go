@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFactSource(t *testing.T, dir, name, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCustomFactsCollector_Collect(t *testing.T) {
+	dir := t.TempDir()
+	writeFactSource(t, dir, "app.sh", "#!/bin/sh\necho '{\"version\": \"1.4.2\", \"workers\": 4}'\n", 0755)
+	writeFactSource(t, dir, "role.json", `{"tier": "web"}`, 0644)
+	writeFactSource(t, dir, "README", "not a fact", 0644)
+	writeFactSource(t, dir, "broken", "#!/bin/sh\necho not json\n", 0755)
+
+	facts := NewCustomFactsCollector(dir, time.Second, time.Minute).Collect()
+	if len(facts) != 2 {
+		t.Fatalf("Expected 2 facts, got %v", facts)
+	}
+	app, ok := facts["app"].(map[string]interface{})
+	if !ok || app["version"] != "1.4.2" || app["workers"] != float64(4) {
+		t.Errorf("Unexpected app fact: %v", facts["app"])
+	}
+	if role, ok := facts["role"].(map[string]interface{}); !ok || role["tier"] != "web" {
+		t.Errorf("Unexpected role fact: %v", facts["role"])
+	}
+}
+
+func TestCustomFactsCollector_Cache(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, ".runs")
+	writeFactSource(t, dir, "count", "#!/bin/sh\necho x >> .runs\necho '\"ok\"'\n", 0755)
+
+	c := NewCustomFactsCollector(dir, time.Second, time.Hour)
+	c.Collect()
+	c.Collect()
+	if data, _ := os.ReadFile(counter); string(data) != "x\n" {
+		t.Errorf("Expected the script to run once within the TTL, got %q", data)
+	}
+
+	c.Invalidate()
+	c.Collect()
+	if data, _ := os.ReadFile(counter); string(data) != "x\nx\n" {
+		t.Errorf("Expected the script to run again after invalidation, got %q", data)
+	}
+}
+
+func TestCustomFactsCollector_TimeoutKeepsLastValue(t *testing.T) {
+	dir := t.TempDir()
+	writeFactSource(t, dir, "slow", "#!/bin/sh\necho '\"fast\"'\n", 0755)
+
+	c := NewCustomFactsCollector(dir, 200*time.Millisecond, time.Nanosecond)
+	if facts := c.Collect(); facts["slow"] != "fast" {
+		t.Fatalf("Unexpected facts: %v", facts)
+	}
+
+	writeFactSource(t, dir, "slow", "#!/bin/sh\nsleep 5 ; echo 1\n", 0755)
+	start := time.Now()
+	facts := c.Collect()
+	if time.Since(start) > 3*time.Second {
+		t.Error("Expected the script to be killed after the timeout")
+	}
+	if facts["slow"] != "fast" {
+		t.Errorf("Expected the last value to be kept, got %v", facts["slow"])
+	}
+}
+
+func TestStableFacts_Custom(t *testing.T) {
+	info := testSystemInfo()
+	info.Custom = map[string]interface{}{
+		"app":  map[string]interface{}{"version": "1.4.2", "ports": []interface{}{80.0, 443.0}},
+		"rack": "r12",
+	}
+
+	facts := StableFacts(info)
+	if facts["custom.app.version"] != "1.4.2" {
+		t.Errorf("Unexpected version fact: %q", facts["custom.app.version"])
+	}
+	if facts["custom.app.ports"] != "[80,443]" {
+		t.Errorf("Unexpected ports fact: %q", facts["custom.app.ports"])
+	}
+	if facts["custom.rack"] != "r12" {
+		t.Errorf("Unexpected rack fact: %q", facts["custom.rack"])
+	}
+}
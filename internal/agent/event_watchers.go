@@ -16,7 +16,7 @@ import (
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // WatcherType defines the type of watcher
@@ -110,6 +110,7 @@ type WatcherConfig struct {
 
 	// General settings
 	Interval time.Duration // Check interval
+	Group    string        // Watcher group, set on events as watcher_group
 	Stack    string        // Stack name context
 	RunID    string        // Run ID context
 }
@@ -124,6 +125,13 @@ type WatcherState struct {
 	CustomState  map[string]interface{}
 }
 
+// EventSender sends the events watchers raise: the agent's EventWorker
+// forwards them to the master, while the master handles the events of its
+// own watchers itself
+type EventSender interface {
+	SendEvent(eventType, stack, runID string, data map[string]interface{}) error
+}
+
 // EventWatcherManager manages all registered watchers
 type EventWatcherManager struct {
 	eventWorker *EventWorker
@@ -191,7 +199,7 @@ func (m *EventWatcherManager) initDB() error {
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", m.dbPath)
+	db, err := sqlitedb.Open(m.dbPath, sqlitedb.Options{})
 	if err != nil {
 		return fmt.Errorf("failed to open watcher database: %w", err)
 	}
@@ -334,12 +342,36 @@ func (m *EventWatcherManager) RegisterWatcher(config WatcherConfig) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	watcher := NewWatcher(config)
+	config = watcher.config
+	m.watchers[config.ID] = watcher
+
+	// Save to database
+	if err := m.saveWatcher(&config); err != nil {
+		slog.Error("Failed to save watcher to database", "id", config.ID, "error", err)
+		// Continue anyway - watcher is still in memory
+	}
+
+	// Start watcher goroutine
+	m.wg.Add(1)
+	go m.runWatcher(watcher)
+
+	slog.Info("Watcher registered",
+		"id", config.ID,
+		"type", config.Type,
+		"interval", config.Interval)
+
+	return nil
+}
+
+// NewWatcher creates a watcher, checking every 5 seconds unless config sets
+// an interval. File watchers start from the current state of their file.
+func NewWatcher(config WatcherConfig) *Watcher {
 	// Set default interval if not specified
 	if config.Interval == 0 {
 		config.Interval = 5 * time.Second
 	}
 
-	// Create watcher
 	watcher := &Watcher{
 		config: config,
 		state: WatcherState{
@@ -355,25 +387,18 @@ func (m *EventWatcherManager) RegisterWatcher(config WatcherConfig) error {
 			slog.Debug("Failed to initialize file watcher state", "path", config.FilePath, "error", err)
 		}
 	}
+	return watcher
+}
 
-	m.watchers[config.ID] = watcher
-
-	// Save to database
-	if err := m.saveWatcher(&config); err != nil {
-		slog.Error("Failed to save watcher to database", "id", config.ID, "error", err)
-		// Continue anyway - watcher is still in memory
-	}
-
-	// Start watcher goroutine
-	m.wg.Add(1)
-	go m.runWatcher(watcher)
-
-	slog.Info("Watcher registered",
-		"id", config.ID,
-		"type", config.Type,
-		"interval", config.Interval)
+// Config returns the configuration of the watcher
+func (w *Watcher) Config() WatcherConfig {
+	return w.config
+}
 
-	return nil
+// Check checks what the watcher watches, sending an event to sender for
+// every change of its conditions since the last check
+func (w *Watcher) Check(sender EventSender) {
+	w.check(sender)
 }
 
 // UnregisterWatcher removes a watcher
@@ -426,7 +451,7 @@ func (m *EventWatcherManager) Stop() {
 
 	// Close database
 	if m.db != nil {
-		if err := m.db.Close(); err != nil {
+		if err := sqlitedb.Close(m.db); err != nil {
 			slog.Error("Failed to close watcher database", "error", err)
 		}
 	}
@@ -446,20 +471,30 @@ func (m *EventWatcherManager) runWatcher(w *Watcher) {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			if !m.registered(w) {
+				// Removed, or replaced by a watcher with the same ID
+				return
+			}
+			if m.eventWorker == nil {
+				slog.Error("❌ EVENT WORKER IS NIL!", "watcher_id", w.config.ID)
+				continue
+			}
 			w.check(m.eventWorker)
 		}
 	}
 }
 
+// registered reports whether w is still the watcher registered under its ID
+func (m *EventWatcherManager) registered(w *Watcher) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.watchers[w.config.ID] == w
+}
+
 // check performs the watcher check
-func (w *Watcher) check(eventWorker *EventWorker) {
+func (w *Watcher) check(eventWorker EventSender) {
 	slog.Debug("🔍 Watcher check starting", "id", w.config.ID, "type", w.config.Type, "path", w.config.FilePath)
 
-	if eventWorker == nil {
-		slog.Error("❌ EVENT WORKER IS NIL!", "watcher_id", w.config.ID)
-		return
-	}
-
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -499,6 +534,15 @@ func (w *Watcher) check(eventWorker *EventWorker) {
 	w.state.LastCheck = time.Now()
 }
 
+// sendEvent sends an event of the watcher, tagged with its group so event
+// triggers can target every watcher of a group
+func (w *Watcher) sendEvent(eventWorker EventSender, eventType, stack, runID string, data map[string]interface{}) {
+	if w.config.Group != "" {
+		data["watcher_group"] = w.config.Group
+	}
+	eventWorker.SendEvent(eventType, stack, runID, data)
+}
+
 // initFileState initializes file watcher state
 func (w *Watcher) initFileState() error {
 	stat, err := os.Stat(w.config.FilePath)
@@ -522,7 +566,7 @@ func (w *Watcher) initFileState() error {
 }
 
 // checkFile checks for file changes
-func (w *Watcher) checkFile(eventWorker *EventWorker) {
+func (w *Watcher) checkFile(eventWorker EventSender) {
 	slog.Debug("📂 Checking file", "path", w.config.FilePath, "watcher_id", w.config.ID)
 
 	stat, err := os.Stat(w.config.FilePath)
@@ -534,7 +578,7 @@ func (w *Watcher) checkFile(eventWorker *EventWorker) {
 	if w.state.LastExists && !currentExists {
 		if w.hasCondition(ConditionDeleted) {
 			slog.Info("🗑️ File deleted - sending event", "path", w.config.FilePath)
-			eventWorker.SendEvent("file.deleted", w.config.Stack, w.config.RunID, map[string]interface{}{
+			w.sendEvent(eventWorker, "file.deleted", w.config.Stack, w.config.RunID, map[string]interface{}{
 				"path":       w.config.FilePath,
 				"watcher_id": w.config.ID,
 			})
@@ -546,7 +590,7 @@ func (w *Watcher) checkFile(eventWorker *EventWorker) {
 	// Check for creation
 	if !w.state.LastExists && currentExists {
 		if w.hasCondition(ConditionCreated) {
-			eventWorker.SendEvent("file.created", w.config.Stack, w.config.RunID, map[string]interface{}{
+			w.sendEvent(eventWorker, "file.created", w.config.Stack, w.config.RunID, map[string]interface{}{
 				"path":       w.config.FilePath,
 				"size":       stat.Size(),
 				"watcher_id": w.config.ID,
@@ -600,7 +644,7 @@ func (w *Watcher) checkFile(eventWorker *EventWorker) {
 
 		if changed {
 			slog.Info("📤 FILE CHANGED - Sending event", "path", w.config.FilePath, "details", changeDetails)
-			eventWorker.SendEvent("file.modified", w.config.Stack, w.config.RunID, changeDetails)
+			w.sendEvent(eventWorker, "file.modified", w.config.Stack, w.config.RunID, changeDetails)
 			slog.Info("✅ Event sent to worker", "event_type", "file.modified")
 		} else {
 			slog.Debug("⏭️ No changes detected", "path", w.config.FilePath)
@@ -611,21 +655,21 @@ func (w *Watcher) checkFile(eventWorker *EventWorker) {
 }
 
 // checkProcess checks for process events
-func (w *Watcher) checkProcess(eventWorker *EventWorker) {
+func (w *Watcher) checkProcess(eventWorker EventSender) {
 	// Check if process is running
 	running := w.isProcessRunning(w.config.ProcessName)
 
 	wasRunning := w.state.CustomState["running"] == true
 
 	if !wasRunning && running && w.hasCondition(ConditionCreated) {
-		eventWorker.SendEvent("process.started", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "process.started", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"process":    w.config.ProcessName,
 			"watcher_id": w.config.ID,
 		})
 	}
 
 	if wasRunning && !running && w.hasCondition(ConditionDeleted) {
-		eventWorker.SendEvent("process.stopped", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "process.stopped", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"process":    w.config.ProcessName,
 			"watcher_id": w.config.ID,
 		})
@@ -635,20 +679,20 @@ func (w *Watcher) checkProcess(eventWorker *EventWorker) {
 }
 
 // checkPort checks for port events
-func (w *Watcher) checkPort(eventWorker *EventWorker) {
+func (w *Watcher) checkPort(eventWorker EventSender) {
 	listening := w.isPortListening(w.config.Port)
 
 	wasListening := w.state.CustomState["listening"] == true
 
 	if !wasListening && listening && w.hasCondition(ConditionCreated) {
-		eventWorker.SendEvent("port.opened", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "port.opened", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"port":       w.config.Port,
 			"watcher_id": w.config.ID,
 		})
 	}
 
 	if wasListening && !listening && w.hasCondition(ConditionDeleted) {
-		eventWorker.SendEvent("port.closed", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "port.closed", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"port":       w.config.Port,
 			"watcher_id": w.config.ID,
 		})
@@ -658,13 +702,13 @@ func (w *Watcher) checkPort(eventWorker *EventWorker) {
 }
 
 // checkService checks for service events
-func (w *Watcher) checkService(eventWorker *EventWorker) {
+func (w *Watcher) checkService(eventWorker EventSender) {
 	status := w.getServiceStatus(w.config.ServiceName)
 
 	lastStatus, _ := w.state.CustomState["status"].(string)
 
 	if lastStatus != status && w.hasCondition(ConditionChanged) {
-		eventWorker.SendEvent("service.status_changed", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "service.status_changed", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"service":     w.config.ServiceName,
 			"old_status":  lastStatus,
 			"new_status":  status,
@@ -676,7 +720,7 @@ func (w *Watcher) checkService(eventWorker *EventWorker) {
 }
 
 // checkCustom executes custom check function
-func (w *Watcher) checkCustom(eventWorker *EventWorker) {
+func (w *Watcher) checkCustom(eventWorker EventSender) {
 	if w.config.CheckFunc == nil {
 		return
 	}
@@ -688,7 +732,7 @@ func (w *Watcher) checkCustom(eventWorker *EventWorker) {
 		}
 		data["watcher_id"] = w.config.ID
 
-		eventWorker.SendEvent("custom.triggered", w.config.Stack, w.config.RunID, data)
+		w.sendEvent(eventWorker, "custom.triggered", w.config.Stack, w.config.RunID, data)
 	}
 }
 
@@ -773,14 +817,14 @@ func (w *Watcher) getServiceStatus(name string) string {
 }
 
 // checkDirectory checks for directory changes
-func (w *Watcher) checkDirectory(eventWorker *EventWorker) {
+func (w *Watcher) checkDirectory(eventWorker EventSender) {
 	stat, err := os.Stat(w.config.FilePath)
 	currentExists := err == nil && stat.IsDir()
 
 	// Check for deletion
 	if w.state.LastExists && !currentExists {
 		if w.hasCondition(ConditionDeleted) {
-			eventWorker.SendEvent("dir.deleted", w.config.Stack, w.config.RunID, map[string]interface{}{
+			w.sendEvent(eventWorker, "dir.deleted", w.config.Stack, w.config.RunID, map[string]interface{}{
 				"path":       w.config.FilePath,
 				"watcher_id": w.config.ID,
 			})
@@ -792,7 +836,7 @@ func (w *Watcher) checkDirectory(eventWorker *EventWorker) {
 	// Check for creation
 	if !w.state.LastExists && currentExists {
 		if w.hasCondition(ConditionCreated) {
-			eventWorker.SendEvent("dir.created", w.config.Stack, w.config.RunID, map[string]interface{}{
+			w.sendEvent(eventWorker, "dir.created", w.config.Stack, w.config.RunID, map[string]interface{}{
 				"path":       w.config.FilePath,
 				"watcher_id": w.config.ID,
 			})
@@ -809,7 +853,7 @@ func (w *Watcher) checkDirectory(eventWorker *EventWorker) {
 			lastCount, _ := w.state.CustomState["file_count"].(int)
 
 			if lastCount != 0 && currentCount != lastCount {
-				eventWorker.SendEvent("dir.changed", w.config.Stack, w.config.RunID, map[string]interface{}{
+				w.sendEvent(eventWorker, "dir.changed", w.config.Stack, w.config.RunID, map[string]interface{}{
 					"path":           w.config.FilePath,
 					"old_file_count": lastCount,
 					"new_file_count": currentCount,
@@ -825,7 +869,7 @@ func (w *Watcher) checkDirectory(eventWorker *EventWorker) {
 }
 
 // checkLog checks for log file patterns
-func (w *Watcher) checkLog(eventWorker *EventWorker) {
+func (w *Watcher) checkLog(eventWorker EventSender) {
 	// Read log file
 	data, err := os.ReadFile(w.config.FilePath)
 	if err != nil {
@@ -854,7 +898,7 @@ func (w *Watcher) checkLog(eventWorker *EventWorker) {
 			}
 
 			if matched {
-				eventWorker.SendEvent("log.pattern_matched", w.config.Stack, w.config.RunID, map[string]interface{}{
+				w.sendEvent(eventWorker, "log.pattern_matched", w.config.Stack, w.config.RunID, map[string]interface{}{
 					"path":       w.config.FilePath,
 					"pattern":    w.config.Pattern,
 					"line":       line,
@@ -868,7 +912,7 @@ func (w *Watcher) checkLog(eventWorker *EventWorker) {
 }
 
 // checkCommand checks for command output changes
-func (w *Watcher) checkCommand(eventWorker *EventWorker) {
+func (w *Watcher) checkCommand(eventWorker EventSender) {
 	// Execute command
 	parts := strings.Fields(w.config.Command)
 	if len(parts) == 0 {
@@ -894,7 +938,7 @@ func (w *Watcher) checkCommand(eventWorker *EventWorker) {
 	lastOutput, _ := w.state.CustomState["last_output"].(string)
 
 	if lastOutput != "" && currentOutput != lastOutput && w.hasCondition(ConditionChanged) {
-		eventWorker.SendEvent("command.output_changed", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "command.output_changed", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"command":     w.config.Command,
 			"old_output":  lastOutput,
 			"new_output":  currentOutput,
@@ -906,7 +950,7 @@ func (w *Watcher) checkCommand(eventWorker *EventWorker) {
 }
 
 // checkCPU checks for CPU threshold events
-func (w *Watcher) checkCPU(eventWorker *EventWorker) {
+func (w *Watcher) checkCPU(eventWorker EventSender) {
 	// Read CPU stats from /proc/stat
 	data, err := os.ReadFile("/proc/stat")
 	if err != nil {
@@ -929,7 +973,7 @@ func (w *Watcher) checkCPU(eventWorker *EventWorker) {
 	cpuPercent := 50.0 // Placeholder
 
 	if w.hasCondition(ConditionAbove) && cpuPercent > w.config.Threshold {
-		eventWorker.SendEvent("cpu.high_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "cpu.high_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"cpu_percent": cpuPercent,
 			"threshold":   w.config.Threshold,
 			"watcher_id":  w.config.ID,
@@ -937,7 +981,7 @@ func (w *Watcher) checkCPU(eventWorker *EventWorker) {
 	}
 
 	if w.hasCondition(ConditionBelow) && cpuPercent < w.config.Threshold {
-		eventWorker.SendEvent("cpu.low_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "cpu.low_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"cpu_percent": cpuPercent,
 			"threshold":   w.config.Threshold,
 			"watcher_id":  w.config.ID,
@@ -946,7 +990,7 @@ func (w *Watcher) checkCPU(eventWorker *EventWorker) {
 }
 
 // checkMemory checks for memory threshold events
-func (w *Watcher) checkMemory(eventWorker *EventWorker) {
+func (w *Watcher) checkMemory(eventWorker EventSender) {
 	// Read memory info from /proc/meminfo
 	data, err := os.ReadFile("/proc/meminfo")
 	if err != nil {
@@ -977,7 +1021,7 @@ func (w *Watcher) checkMemory(eventWorker *EventWorker) {
 	memUsedPercent := float64(memTotal-memAvailable) / float64(memTotal) * 100.0
 
 	if w.hasCondition(ConditionAbove) && memUsedPercent > w.config.Threshold {
-		eventWorker.SendEvent("memory.high_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "memory.high_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"memory_percent": memUsedPercent,
 			"threshold":      w.config.Threshold,
 			"watcher_id":     w.config.ID,
@@ -986,7 +1030,7 @@ func (w *Watcher) checkMemory(eventWorker *EventWorker) {
 }
 
 // checkNetwork checks for network interface events
-func (w *Watcher) checkNetwork(eventWorker *EventWorker) {
+func (w *Watcher) checkNetwork(eventWorker EventSender) {
 	// Check network interface status
 	// Placeholder implementation
 	if w.hasCondition(ConditionChanged) {
@@ -995,7 +1039,7 @@ func (w *Watcher) checkNetwork(eventWorker *EventWorker) {
 }
 
 // checkConnection checks for network connection events
-func (w *Watcher) checkConnection(eventWorker *EventWorker) {
+func (w *Watcher) checkConnection(eventWorker EventSender) {
 	// Check for specific network connections
 	// Placeholder implementation
 	if w.hasCondition(ConditionChanged) {
@@ -1004,7 +1048,7 @@ func (w *Watcher) checkConnection(eventWorker *EventWorker) {
 }
 
 // checkUser checks for user session events
-func (w *Watcher) checkUser(eventWorker *EventWorker) {
+func (w *Watcher) checkUser(eventWorker EventSender) {
 	// Check user sessions (who, w command)
 	// Placeholder implementation
 	if w.hasCondition(ConditionChanged) {
@@ -1013,7 +1057,7 @@ func (w *Watcher) checkUser(eventWorker *EventWorker) {
 }
 
 // checkPackage checks for package installation events
-func (w *Watcher) checkPackage(eventWorker *EventWorker) {
+func (w *Watcher) checkPackage(eventWorker EventSender) {
 	// Check installed packages
 	// Placeholder implementation
 	if w.hasCondition(ConditionChanged) {
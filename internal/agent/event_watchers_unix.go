@@ -7,7 +7,7 @@ import (
 )
 
 // checkDisk checks for disk space threshold events (Unix/Linux/macOS implementation)
-func (w *Watcher) checkDisk(eventWorker *EventWorker) {
+func (w *Watcher) checkDisk(eventWorker EventSender) {
 	// Use syscall to get disk stats
 	var stat syscall.Statfs_t
 	err := syscall.Statfs(w.config.FilePath, &stat)
@@ -20,7 +20,7 @@ func (w *Watcher) checkDisk(eventWorker *EventWorker) {
 	usedPercent := float64(totalSpace-freeSpace) / float64(totalSpace) * 100.0
 
 	if w.hasCondition(ConditionAbove) && usedPercent > w.config.Threshold {
-		eventWorker.SendEvent("disk.high_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
+		w.sendEvent(eventWorker, "disk.high_usage", w.config.Stack, w.config.RunID, map[string]interface{}{
 			"path":         w.config.FilePath,
 			"used_percent": usedPercent,
 			"threshold":    w.config.Threshold,
@@ -8,10 +8,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // EventWorker monitors local events and sends them to master
@@ -20,9 +20,11 @@ type EventWorker struct {
 	masterAddr    string
 	batchSize     int
 	flushInterval time.Duration
+	maxBuffered   int
 
 	mu            sync.Mutex
 	events        []*pb.EventData
+	dropped       int
 	client        pb.AgentRegistryClient
 	conn          *grpc.ClientConn
 
@@ -37,6 +39,7 @@ type EventWorkerConfig struct {
 	MasterAddr    string
 	BatchSize     int           // Max events to buffer before sending
 	FlushInterval time.Duration // Max time to wait before sending buffered events
+	MaxBuffered   int           // Max events kept while the master is unreachable
 }
 
 // NewEventWorker creates a new event worker
@@ -50,12 +53,16 @@ func NewEventWorker(config EventWorkerConfig) *EventWorker {
 	if config.FlushInterval == 0 {
 		config.FlushInterval = 10 * time.Second // Default flush interval
 	}
+	if config.MaxBuffered == 0 {
+		config.MaxBuffered = 10000 // Enough for a master upgrade window
+	}
 
 	return &EventWorker{
 		agentName:     config.AgentName,
 		masterAddr:    config.MasterAddr,
 		batchSize:     config.BatchSize,
 		flushInterval: config.FlushInterval,
+		maxBuffered:   config.MaxBuffered,
 		events:        make([]*pb.EventData, 0, config.BatchSize),
 		ctx:           ctx,
 		cancel:        cancel,
@@ -65,7 +72,7 @@ func NewEventWorker(config EventWorkerConfig) *EventWorker {
 // Start begins the event worker (connects to master and starts monitoring)
 func (w *EventWorker) Start() error {
 	// Connect to master
-	conn, err := grpc.Dial(w.masterAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(w.masterAddr, mtls.DialOption())
 	if err != nil {
 		return fmt.Errorf("failed to connect to master: %w", err)
 	}
@@ -104,6 +111,28 @@ func (w *EventWorker) Stop() error {
 	return nil
 }
 
+// SetMasterAddr makes the worker send events to the master at addr from
+// now on. Buffered events go to the new master.
+func (w *EventWorker) SetMasterAddr(addr string) error {
+	conn, err := grpc.Dial(addr, mtls.DialOption())
+	if err != nil {
+		return fmt.Errorf("failed to connect to master: %w", err)
+	}
+
+	w.mu.Lock()
+	old := w.conn
+	w.masterAddr = addr
+	w.conn = conn
+	w.client = pb.NewAgentRegistryClient(conn)
+	w.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	slog.Info("Event worker switched master", "master_addr", addr, "agent_name", w.agentName)
+	return nil
+}
+
 // SendEvent sends a single event to the master (adds to buffer)
 func (w *EventWorker) SendEvent(eventType, stack, runID string, data map[string]interface{}) error {
 	slog.Info("📨 EventWorker.SendEvent CALLED", "event_type", eventType, "agent", w.agentName, "data", data)
@@ -129,7 +158,7 @@ func (w *EventWorker) SendEvent(eventType, stack, runID string, data map[string]
 
 	// Add to buffer
 	w.mu.Lock()
-	w.events = append(w.events, event)
+	w.bufferLocked(event)
 	bufferSize := len(w.events)
 	shouldFlush := bufferSize >= w.batchSize
 	w.mu.Unlock()
@@ -167,7 +196,7 @@ func (w *EventWorker) SendEventWithSeverity(eventType, stack, runID string, data
 
 	// Add to buffer
 	w.mu.Lock()
-	w.events = append(w.events, event)
+	w.bufferLocked(event)
 	shouldFlush := len(w.events) >= w.batchSize
 	w.mu.Unlock()
 
@@ -203,15 +232,20 @@ func (w *EventWorker) flush() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	resp, err := w.client.SendEventBatch(ctx, &pb.SendEventBatchRequest{
+	w.mu.Lock()
+	client := w.client
+	w.mu.Unlock()
+	resp, err := client.SendEventBatch(ctx, &pb.SendEventBatchRequest{
 		Events:    events,
 		BatchSize: int32(len(events)),
 	})
 
 	if err != nil {
-		// Put events back in buffer on failure
+		// Put events back in buffer on failure; they are sent once the
+		// master is reachable again, e.g. after it was upgraded
 		w.mu.Lock()
 		w.events = append(events, w.events...)
+		w.trimLocked()
 		w.mu.Unlock()
 
 		slog.Error("Failed to send event batch to master",
@@ -231,9 +265,42 @@ func (w *EventWorker) flush() error {
 			"processed", resp.EventsProcessed)
 	}
 
+	w.mu.Lock()
+	if w.dropped > 0 {
+		slog.Warn("Events were dropped while the master was unreachable",
+			"dropped", w.dropped,
+			"max_buffered", w.maxBuffered)
+		w.dropped = 0
+	}
+	w.mu.Unlock()
+
 	return nil
 }
 
+// Pending returns how many events wait to be sent to the master, and how
+// many were dropped since the last successful send
+func (w *EventWorker) Pending() (queued, dropped int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.events), w.dropped
+}
+
+// bufferLocked adds an event to the buffer. w.mu must be held.
+func (w *EventWorker) bufferLocked(event *pb.EventData) {
+	w.events = append(w.events, event)
+	w.trimLocked()
+}
+
+// trimLocked drops the oldest events once more than maxBuffered are kept,
+// so a long master outage can't exhaust the agent's memory. w.mu must be
+// held.
+func (w *EventWorker) trimLocked() {
+	if over := len(w.events) - w.maxBuffered; w.maxBuffered > 0 && over > 0 {
+		w.events = append(w.events[:0], w.events[over:]...)
+		w.dropped += over
+	}
+}
+
 // flushLoop periodically flushes buffered events
 func (w *EventWorker) flushLoop() {
 	defer w.wg.Done()
@@ -9,6 +9,8 @@ import (
 
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Mock gRPC client for testing
@@ -1758,3 +1760,51 @@ func TestEventWorker_LongRunID(t *testing.T) {
 		t.Error("Long run ID not preserved")
 	}
 }
+
+// TestEventWorker_BuffersWhileMasterDraining tests events are kept while the
+// master is upgrading and sent once it is back, dropping the oldest past the cap
+func TestEventWorker_BuffersWhileMasterDraining(t *testing.T) {
+	worker := NewEventWorker(EventWorkerConfig{
+		AgentName:   "test-agent",
+		MasterAddr:  "localhost:50051",
+		BatchSize:   100,
+		MaxBuffered: 5,
+	})
+
+	draining := true
+	var received []*pb.EventData
+	worker.client = &mockAgentRegistryClient{
+		sendEventBatchFunc: func(ctx context.Context, in *pb.SendEventBatchRequest, opts ...grpc.CallOption) (*pb.SendEventBatchResponse, error) {
+			if draining {
+				return nil, status.Error(codes.Unavailable, "master is draining for an upgrade")
+			}
+			received = append(received, in.Events...)
+			return &pb.SendEventBatchResponse{Success: true, EventsProcessed: int32(len(in.Events))}, nil
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		worker.SendEvent("test.upgrade", "stack", "run-1", map[string]interface{}{"index": i})
+	}
+	if err := worker.flush(); err == nil {
+		t.Fatal("Expected flush to fail while the master is draining")
+	}
+	for i := 3; i < 7; i++ {
+		worker.SendEvent("test.upgrade", "stack", "run-1", map[string]interface{}{"index": i})
+	}
+
+	worker.mu.Lock()
+	buffered, dropped := len(worker.events), worker.dropped
+	worker.mu.Unlock()
+	if buffered != 5 || dropped != 2 {
+		t.Fatalf("Expected 5 buffered and 2 dropped events, got %d and %d", buffered, dropped)
+	}
+
+	draining = false
+	if err := worker.flush(); err != nil {
+		t.Fatalf("Expected flush to succeed after the upgrade, got: %v", err)
+	}
+	if len(received) != 5 || received[0].DataJson != `{"index":2}` {
+		t.Errorf("Expected the 5 newest events in order, got %d starting with %v", len(received), received)
+	}
+}
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fact change kinds
+const (
+	FactAdded   = "added"
+	FactRemoved = "removed"
+	FactChanged = "changed"
+)
+
+// FactChange describes a single fact that differs between two snapshots
+type FactChange struct {
+	Fact     string `json:"fact"`
+	Change   string `json:"change"`
+	OldValue string `json:"old_value,omitempty"`
+	NewValue string `json:"new_value,omitempty"`
+}
+
+// Facts is a flat view of the stable parts of SystemInfo, keyed by dotted
+// fact name (e.g. "kernel_version", "network.eth0.addresses"). Volatile
+// values such as uptime, load and memory/disk usage are left out so that
+// only real inventory changes show up as differences.
+type Facts map[string]string
+
+// StableFacts extracts the facts tracked for change history from info
+func StableFacts(info *SystemInfo) Facts {
+	facts := Facts{}
+	if info == nil {
+		return facts
+	}
+
+	set := func(key, value string) {
+		if value != "" {
+			facts[key] = value
+		}
+	}
+
+	set("hostname", info.Hostname)
+	set("platform", info.Platform)
+	set("platform_family", info.PlatformFamily)
+	set("platform_version", info.PlatformVersion)
+	set("service_manager", info.ServiceManager)
+	set("nix", info.Nix)
+	set("architecture", info.Architecture)
+	set("kernel", info.Kernel)
+	set("kernel_version", info.KernelVersion)
+	set("virtualization", info.Virtualization)
+	set("timezone", info.Timezone)
+	if info.CPUs > 0 {
+		set("cpus", strconv.Itoa(info.CPUs))
+	}
+	if info.Memory != nil && info.Memory.Total > 0 {
+		set("memory.total", strconv.FormatUint(info.Memory.Total, 10))
+	}
+
+	for _, d := range info.Disk {
+		if d == nil || d.Mountpoint == "" {
+			continue
+		}
+		prefix := "disk." + d.Mountpoint
+		set(prefix+".device", d.Device)
+		set(prefix+".fstype", d.Fstype)
+		if d.Total > 0 {
+			set(prefix+".total", strconv.FormatUint(d.Total, 10))
+		}
+	}
+
+	for _, n := range info.Network {
+		if n == nil || n.Name == "" {
+			continue
+		}
+		prefix := "network." + n.Name
+		addrs := append([]string(nil), n.Addresses...)
+		sort.Strings(addrs)
+		set(prefix+".addresses", strings.Join(addrs, ","))
+		set(prefix+".mac", n.MAC)
+		if n.MTU > 0 {
+			set(prefix+".mtu", strconv.Itoa(n.MTU))
+		}
+		set(prefix+".is_up", strconv.FormatBool(n.IsUp))
+	}
+
+	for _, m := range info.Mounts {
+		if m.Mountpoint == "" {
+			continue
+		}
+		set("mount."+m.Mountpoint, m.Device+" "+m.FSType)
+	}
+
+	if info.Packages != nil {
+		set("packages.manager", info.Packages.Manager)
+		set("packages.installed_count", strconv.Itoa(info.Packages.InstalledCount))
+		for _, pkg := range info.Packages.Packages {
+			if pkg.Name != "" {
+				set("package."+pkg.Name, pkg.Version)
+			}
+		}
+	}
+
+	for _, s := range info.Services {
+		if s.Name != "" {
+			set("service."+s.Name, s.State)
+		}
+	}
+
+	for _, u := range info.Users {
+		if u.Username != "" {
+			set("user."+u.Username, u.UID+":"+u.GID+" "+u.Home+" "+u.Shell)
+		}
+	}
+
+	for name, value := range info.Custom {
+		flattenCustomFact(facts, "custom."+name, value)
+	}
+
+	return facts
+}
+
+// Fingerprint returns a stable hash of the facts, used by agents to skip
+// sending facts that did not change since the last update
+func (f Facts) Fingerprint() string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(f[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ToJSON serializes the facts
+func (f Facts) ToJSON() (string, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FactsFromJSON parses facts serialized with ToJSON
+func FactsFromJSON(jsonStr string) (Facts, error) {
+	facts := Facts{}
+	if jsonStr == "" {
+		return facts, nil
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &facts); err != nil {
+		return nil, err
+	}
+	return facts, nil
+}
+
+// DiffFacts returns the changes from old to new, sorted by fact name
+func DiffFacts(old, new Facts) []FactChange {
+	var changes []FactChange
+
+	for k, newValue := range new {
+		oldValue, ok := old[k]
+		switch {
+		case !ok:
+			changes = append(changes, FactChange{Fact: k, Change: FactAdded, NewValue: newValue})
+		case oldValue != newValue:
+			changes = append(changes, FactChange{Fact: k, Change: FactChanged, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for k, oldValue := range old {
+		if _, ok := new[k]; !ok {
+			changes = append(changes, FactChange{Fact: k, Change: FactRemoved, OldValue: oldValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Fact < changes[j].Fact
+	})
+	return changes
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"testing"
+)
+
+func testSystemInfo() *SystemInfo {
+	return &SystemInfo{
+		Hostname:      "web-01",
+		KernelVersion: "6.1.0",
+		CPUs:          4,
+		Uptime:        100,
+		LoadAverage:   []float64{0.5, 0.4, 0.3},
+		Memory:        &MemoryInfo{Total: 8 << 30, Used: 1 << 30},
+		Disk:          []*DiskInfo{{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4", Total: 100 << 30, Used: 10 << 30}},
+		Network:       []*NetworkInfo{{Name: "eth0", Addresses: []string{"10.0.0.5/24", "fe80::1/64"}, MAC: "aa:bb", MTU: 1500, IsUp: true}},
+		Services:      []ServiceInfo{{Name: "nginx", Status: "loaded", State: "active"}},
+	}
+}
+
+func TestStableFacts_IgnoresVolatileValues(t *testing.T) {
+	a := testSystemInfo()
+	b := testSystemInfo()
+	b.Uptime = 5000
+	b.LoadAverage = []float64{3, 2, 1}
+	b.Memory.Used = 6 << 30
+	b.Disk[0].Used = 90 << 30
+	b.Network[0].Addresses = []string{"fe80::1/64", "10.0.0.5/24"}
+
+	fa, fb := StableFacts(a), StableFacts(b)
+	if changes := DiffFacts(fa, fb); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+	if fa.Fingerprint() != fb.Fingerprint() {
+		t.Error("Expected equal fingerprints")
+	}
+
+	if fa["network.eth0.addresses"] != "10.0.0.5/24,fe80::1/64" {
+		t.Errorf("Unexpected addresses fact: %q", fa["network.eth0.addresses"])
+	}
+	if fa["service.nginx"] != "active" {
+		t.Errorf("Unexpected service fact: %q", fa["service.nginx"])
+	}
+}
+
+func TestDiffFacts(t *testing.T) {
+	old := testSystemInfo()
+	new := testSystemInfo()
+	new.KernelVersion = "6.8.0"
+	new.Network[0].Addresses = []string{"10.0.0.9/24"}
+	new.Disk = append(new.Disk, &DiskInfo{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "xfs", Total: 500 << 30})
+	new.Services = nil
+
+	fOld, fNew := StableFacts(old), StableFacts(new)
+	if fOld.Fingerprint() == fNew.Fingerprint() {
+		t.Error("Expected different fingerprints")
+	}
+
+	changes := DiffFacts(fOld, fNew)
+	byFact := make(map[string]FactChange)
+	for i, c := range changes {
+		if i > 0 && changes[i-1].Fact > c.Fact {
+			t.Errorf("Changes not sorted: %s before %s", changes[i-1].Fact, c.Fact)
+		}
+		byFact[c.Fact] = c
+	}
+
+	if c := byFact["kernel_version"]; c.Change != FactChanged || c.OldValue != "6.1.0" || c.NewValue != "6.8.0" {
+		t.Errorf("Unexpected kernel change: %+v", c)
+	}
+	if c := byFact["network.eth0.addresses"]; c.Change != FactChanged || c.NewValue != "10.0.0.9/24" {
+		t.Errorf("Unexpected address change: %+v", c)
+	}
+	if c := byFact["disk./data.device"]; c.Change != FactAdded || c.NewValue != "/dev/sdb1" {
+		t.Errorf("Unexpected disk change: %+v", c)
+	}
+	if c := byFact["service.nginx"]; c.Change != FactRemoved || c.OldValue != "active" {
+		t.Errorf("Unexpected service change: %+v", c)
+	}
+}
+
+func TestFactsJSONRoundTrip(t *testing.T) {
+	facts := StableFacts(testSystemInfo())
+	data, err := facts.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+
+	parsed, err := FactsFromJSON(data)
+	if err != nil {
+		t.Fatalf("FactsFromJSON failed: %v", err)
+	}
+	if len(DiffFacts(facts, parsed)) != 0 {
+		t.Error("Expected facts to survive a JSON round trip")
+	}
+}
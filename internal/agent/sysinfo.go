@@ -9,6 +9,10 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/nix"
+	"github.com/chalkan3-sloth/sloth-runner/internal/rcd"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scm"
 )
 
 // SystemInfo holds comprehensive system information
@@ -17,6 +21,8 @@ type SystemInfo struct {
 	Platform        string            `json:"platform"`
 	PlatformFamily  string            `json:"platform_family"`
 	PlatformVersion string            `json:"platform_version"`
+	ServiceManager  string            `json:"service_manager,omitempty"`
+	Nix             string            `json:"nix,omitempty"` // Version of the installed Nix
 	Architecture    string            `json:"architecture"`
 	CPUs            int               `json:"cpus"`
 	Memory          *MemoryInfo       `json:"memory"`
@@ -36,6 +42,9 @@ type SystemInfo struct {
 	Mounts          []MountInfo       `json:"mounts"`
 	Timezone        string            `json:"timezone"`
 	BootTime        int64             `json:"boot_time"`
+
+	// Custom holds user-defined facts, keyed by source name
+	Custom map[string]interface{} `json:"custom,omitempty"`
 }
 
 // ServiceInfo holds service information
@@ -137,6 +146,8 @@ func CollectSystemInfo() (*SystemInfo, error) {
 	// Platform detection
 	info.Platform = runtime.GOOS
 	detectPlatformDetails(info)
+	info.ServiceManager = detectServiceManager()
+	info.Nix = nix.Version()
 
 	// Memory information
 	info.Memory = collectMemoryInfo()
@@ -189,6 +200,11 @@ func CollectSystemInfo() (*SystemInfo, error) {
 		}
 	}
 
+	// User-defined facts
+	if custom := GetGlobalCustomFacts(); custom != nil {
+		info.Custom = custom.Collect()
+	}
+
 	return info, nil
 }
 
@@ -218,7 +234,34 @@ func detectPlatformDetails(info *SystemInfo) {
 		}
 	case "windows":
 		info.PlatformFamily = "windows"
+	case "freebsd", "openbsd", "netbsd", "dragonfly":
+		info.PlatformFamily = "bsd"
+		version := exec.Command("uname", "-r")
+		if runtime.GOOS == "freebsd" {
+			version = exec.Command("freebsd-version")
+		}
+		if output, err := version.Output(); err == nil {
+			info.PlatformVersion = strings.TrimSpace(string(output))
+		}
+	}
+}
+
+// detectServiceManager returns how the host manages services: systemd,
+// openrc, rc.d or scm. The systemd module picks its backend the same way.
+func detectServiceManager() string {
+	if _, ok := rcd.Detect(); ok {
+		return "rc.d"
+	}
+	if _, ok := scm.Detect(); ok {
+		return "scm"
+	}
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		return "systemd"
+	}
+	if _, err := exec.LookPath("rc-service"); err == nil {
+		return "openrc"
 	}
+	return ""
 }
 
 // collectMemoryInfo collects memory information
@@ -781,6 +824,36 @@ func collectPackageInfo() *PackageInfo {
 func collectServices() []ServiceInfo {
 	var services []ServiceInfo
 	
+	// rc.d and the SCM list services and report their state the same way
+	var m interface {
+		List() ([]string, error)
+		Enabled(service string) (bool, error)
+		Status(service string) (bool, string, error)
+	}
+	if r, ok := rcd.Detect(); ok {
+		m = r
+	} else if w, ok := scm.Detect(); ok {
+		m = w
+	}
+	if m != nil {
+		names, err := m.List()
+		if err != nil {
+			return services
+		}
+		for _, name := range names {
+			enabled, _ := m.Enabled(name)
+			if !enabled {
+				continue
+			}
+			state := "inactive"
+			if running, _, _ := m.Status(name); running {
+				state = "active"
+			}
+			services = append(services, ServiceInfo{Name: name, Status: "enabled", State: state})
+		}
+		return services
+	}
+
 	if _, err := exec.LookPath("systemctl"); err == nil {
 		output, err := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-pager", "--no-legend").Output()
 		if err == nil {
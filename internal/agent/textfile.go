@@ -0,0 +1,252 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TextfileName is the file written into the node_exporter textfile collector directory
+const TextfileName = "sloth_runner.prom"
+
+// TextfileExporter keeps task execution metrics and writes them in the
+// Prometheus text format for node_exporter's textfile collector, so
+// existing node_exporter scrapes pick up sloth-runner health
+type TextfileExporter struct {
+	mu        sync.Mutex
+	dir       string
+	agentName string
+	version   string
+	startTime time.Time
+
+	executed map[taskCounterKey]uint64
+	tasks    map[taskKey]*taskLastRun
+	groups   map[string]*taskLastRun
+}
+
+type taskKey struct {
+	group string
+	task  string
+}
+
+type taskCounterKey struct {
+	group  string
+	status string
+}
+
+type taskLastRun struct {
+	success  bool
+	duration time.Duration
+	at       time.Time
+}
+
+// NewTextfileExporter creates an exporter writing to dir
+func NewTextfileExporter(dir, agentName, version string) (*TextfileExporter, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("textfile collector directory is required")
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("textfile collector directory: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("textfile collector path %s is not a directory", dir)
+	}
+
+	return &TextfileExporter{
+		dir:       dir,
+		agentName: agentName,
+		version:   version,
+		startTime: time.Now(),
+		executed:  make(map[taskCounterKey]uint64),
+		tasks:     make(map[taskKey]*taskLastRun),
+		groups:    make(map[string]*taskLastRun),
+	}, nil
+}
+
+// Path returns the full path of the metrics file
+func (e *TextfileExporter) Path() string {
+	return filepath.Join(e.dir, TextfileName)
+}
+
+// RecordTask records the outcome of a single task
+func (e *TextfileExporter) RecordTask(group, task, status string, duration time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.executed[taskCounterKey{group: group, status: strings.ToLower(status)}]++
+	e.tasks[taskKey{group: group, task: task}] = &taskLastRun{
+		success:  isSuccessStatus(status),
+		duration: duration,
+		at:       time.Now(),
+	}
+}
+
+// RecordGroup records the outcome of a whole task group run
+func (e *TextfileExporter) RecordGroup(group string, success bool, duration time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.groups[group] = &taskLastRun{success: success, duration: duration, at: time.Now()}
+}
+
+func isSuccessStatus(status string) bool {
+	switch strings.ToLower(status) {
+	case "success", "succeeded", "skipped":
+		return true
+	}
+	return false
+}
+
+// Write renders the metrics and atomically replaces the metrics file.
+// node_exporter may read the file at any time, so it is written to a
+// temporary file in the same directory and renamed into place.
+func (e *TextfileExporter) Write() error {
+	content := e.Render()
+
+	tmp, err := os.CreateTemp(e.dir, "."+TextfileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary metrics file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, e.Path()); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to replace metrics file: %w", err)
+	}
+	return nil
+}
+
+// Render returns the metrics in the Prometheus text exposition format
+func (e *TextfileExporter) Render() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var b strings.Builder
+	agent := labelValue(e.agentName)
+
+	writeMetricHeader(&b, "sloth_runner_agent_info", "gauge", "sloth-runner agent information")
+	fmt.Fprintf(&b, "sloth_runner_agent_info{agent=\"%s\",version=\"%s\"} 1\n", agent, labelValue(e.version))
+
+	writeMetricHeader(&b, "sloth_runner_agent_start_time_seconds", "gauge", "Unix time the agent started")
+	fmt.Fprintf(&b, "sloth_runner_agent_start_time_seconds{agent=\"%s\"} %d\n", agent, e.startTime.Unix())
+
+	writeMetricHeader(&b, "sloth_runner_textfile_write_timestamp_seconds", "gauge", "Unix time this file was written")
+	fmt.Fprintf(&b, "sloth_runner_textfile_write_timestamp_seconds{agent=\"%s\"} %d\n", agent, time.Now().Unix())
+
+	writeMetricHeader(&b, "sloth_runner_tasks_executed_total", "counter", "Tasks executed by the agent by group and status")
+	counterKeys := make([]taskCounterKey, 0, len(e.executed))
+	for k := range e.executed {
+		counterKeys = append(counterKeys, k)
+	}
+	sort.Slice(counterKeys, func(i, j int) bool {
+		if counterKeys[i].group != counterKeys[j].group {
+			return counterKeys[i].group < counterKeys[j].group
+		}
+		return counterKeys[i].status < counterKeys[j].status
+	})
+	for _, k := range counterKeys {
+		fmt.Fprintf(&b, "sloth_runner_tasks_executed_total{agent=\"%s\",group=\"%s\",status=\"%s\"} %d\n",
+			agent, labelValue(k.group), labelValue(k.status), e.executed[k])
+	}
+
+	taskKeys := make([]taskKey, 0, len(e.tasks))
+	for k := range e.tasks {
+		taskKeys = append(taskKeys, k)
+	}
+	sort.Slice(taskKeys, func(i, j int) bool {
+		if taskKeys[i].group != taskKeys[j].group {
+			return taskKeys[i].group < taskKeys[j].group
+		}
+		return taskKeys[i].task < taskKeys[j].task
+	})
+
+	writeMetricHeader(&b, "sloth_runner_task_last_success", "gauge", "Whether the last run of the task succeeded (1) or failed (0)")
+	for _, k := range taskKeys {
+		fmt.Fprintf(&b, "sloth_runner_task_last_success{agent=\"%s\",group=\"%s\",task=\"%s\"} %d\n",
+			agent, labelValue(k.group), labelValue(k.task), boolValue(e.tasks[k].success))
+	}
+	writeMetricHeader(&b, "sloth_runner_task_last_duration_seconds", "gauge", "Duration of the last run of the task")
+	for _, k := range taskKeys {
+		fmt.Fprintf(&b, "sloth_runner_task_last_duration_seconds{agent=\"%s\",group=\"%s\",task=\"%s\"} %g\n",
+			agent, labelValue(k.group), labelValue(k.task), e.tasks[k].duration.Seconds())
+	}
+	writeMetricHeader(&b, "sloth_runner_task_last_run_timestamp_seconds", "gauge", "Unix time of the last run of the task")
+	for _, k := range taskKeys {
+		fmt.Fprintf(&b, "sloth_runner_task_last_run_timestamp_seconds{agent=\"%s\",group=\"%s\",task=\"%s\"} %d\n",
+			agent, labelValue(k.group), labelValue(k.task), e.tasks[k].at.Unix())
+	}
+
+	groupNames := make([]string, 0, len(e.groups))
+	for g := range e.groups {
+		groupNames = append(groupNames, g)
+	}
+	sort.Strings(groupNames)
+
+	writeMetricHeader(&b, "sloth_runner_group_last_success", "gauge", "Whether the last run of the task group succeeded (1) or failed (0)")
+	for _, g := range groupNames {
+		fmt.Fprintf(&b, "sloth_runner_group_last_success{agent=\"%s\",group=\"%s\"} %d\n", agent, labelValue(g), boolValue(e.groups[g].success))
+	}
+	writeMetricHeader(&b, "sloth_runner_group_last_duration_seconds", "gauge", "Duration of the last run of the task group")
+	for _, g := range groupNames {
+		fmt.Fprintf(&b, "sloth_runner_group_last_duration_seconds{agent=\"%s\",group=\"%s\"} %g\n", agent, labelValue(g), e.groups[g].duration.Seconds())
+	}
+	writeMetricHeader(&b, "sloth_runner_group_last_run_timestamp_seconds", "gauge", "Unix time of the last run of the task group")
+	for _, g := range groupNames {
+		fmt.Fprintf(&b, "sloth_runner_group_last_run_timestamp_seconds{agent=\"%s\",group=\"%s\"} %d\n", agent, labelValue(g), e.groups[g].at.Unix())
+	}
+
+	return b.String()
+}
+
+func writeMetricHeader(b *strings.Builder, name, metricType, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelValue(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func boolValue(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+var (
+	globalTextfileExporter   *TextfileExporter
+	globalTextfileExporterMu sync.RWMutex
+)
+
+// SetGlobalTextfileExporter sets the exporter updated after every task execution
+func SetGlobalTextfileExporter(e *TextfileExporter) {
+	globalTextfileExporterMu.Lock()
+	defer globalTextfileExporterMu.Unlock()
+	globalTextfileExporter = e
+}
+
+// GetGlobalTextfileExporter returns the agent's textfile exporter, or nil if disabled
+func GetGlobalTextfileExporter() *TextfileExporter {
+	globalTextfileExporterMu.RLock()
+	defer globalTextfileExporterMu.RUnlock()
+	return globalTextfileExporter
+}
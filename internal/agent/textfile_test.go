@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextfileExporter_Write(t *testing.T) {
+	dir := t.TempDir()
+	e, err := NewTextfileExporter(dir, "web-01", "1.2.3")
+	if err != nil {
+		t.Fatalf("NewTextfileExporter failed: %v", err)
+	}
+
+	e.RecordTask("deploy", "build", "Success", 1500*time.Millisecond)
+	e.RecordTask("deploy", "migrate", "Failed", 2*time.Second)
+	e.RecordTask("deploy", "build", "Success", time.Second)
+	e.RecordGroup("deploy", false, 3*time.Second)
+
+	if err := e.Write(); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, TextfileName))
+	if err != nil {
+		t.Fatalf("metrics file not written: %v", err)
+	}
+	content := string(data)
+
+	expected := []string{
+		`sloth_runner_agent_info{agent="web-01",version="1.2.3"} 1`,
+		`sloth_runner_tasks_executed_total{agent="web-01",group="deploy",status="success"} 2`,
+		`sloth_runner_tasks_executed_total{agent="web-01",group="deploy",status="failed"} 1`,
+		`sloth_runner_task_last_success{agent="web-01",group="deploy",task="build"} 1`,
+		`sloth_runner_task_last_success{agent="web-01",group="deploy",task="migrate"} 0`,
+		`sloth_runner_task_last_duration_seconds{agent="web-01",group="deploy",task="build"} 1`,
+		`sloth_runner_group_last_success{agent="web-01",group="deploy"} 0`,
+		`# TYPE sloth_runner_tasks_executed_total counter`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(content, line) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", line, content)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the metrics file in %s, found %d entries", dir, len(entries))
+	}
+}
+
+func TestTextfileExporter_EscapesLabels(t *testing.T) {
+	e, err := NewTextfileExporter(t.TempDir(), `agent"1`, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.RecordTask(`grp\a`, "task\nname", "Success", 0)
+
+	content := e.Render()
+	if !strings.Contains(content, `agent="agent\"1"`) {
+		t.Errorf("agent label not escaped:\n%s", content)
+	}
+	if !strings.Contains(content, `group="grp\\a",task="task\nname"`) {
+		t.Errorf("task labels not escaped:\n%s", content)
+	}
+}
+
+func TestNewTextfileExporter_MissingDir(t *testing.T) {
+	if _, err := NewTextfileExporter(filepath.Join(t.TempDir(), "missing"), "a", "v"); err == nil {
+		t.Error("expected error for missing directory")
+	}
+	if _, err := NewTextfileExporter("", "a", "v"); err == nil {
+		t.Error("expected error for empty directory")
+	}
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DefaultStatusInterval is how often the watchdog updates the status line
+// when systemd's watchdog is off
+const DefaultStatusInterval = 30 * time.Second
+
+// Watchdog keeps systemd informed about the agent: it reports READY=1 once
+// the agent serves, pings the watchdog (WatchdogSec=) only while the health
+// check passes, so systemd restarts an agent that hangs, and keeps the
+// STATUS= line of 'systemctl status' up to date.
+type Watchdog struct {
+	// Check reports why the agent can't serve requests
+	Check func(ctx context.Context) error
+	// Status describes the agent's health in one line
+	Status func() string
+	// Interval is how often the agent is checked: half the systemd
+	// watchdog timeout, so one slow check doesn't get the agent killed
+	Interval time.Duration
+
+	notify func(state string) (bool, error)
+}
+
+// NewWatchdog creates a watchdog from the environment systemd set
+func NewWatchdog(check func(ctx context.Context) error, status func() string) *Watchdog {
+	interval := SdWatchdogInterval() / 2
+	if interval <= 0 {
+		interval = DefaultStatusInterval
+	}
+	return &Watchdog{Check: check, Status: status, Interval: interval, notify: SdNotify}
+}
+
+// Run notifies systemd until ctx is done. It returns right away when the
+// agent isn't run by systemd with Type=notify.
+func (w *Watchdog) Run(ctx context.Context) {
+	if sent, err := w.notify("STATUS=starting"); !sent {
+		if err != nil {
+			slog.Warn("Failed to notify systemd", "error", err)
+		}
+		return
+	}
+	slog.Info("systemd notification enabled", "check_interval", w.Interval, "watchdog", SdWatchdogInterval() > 0)
+
+	ready := false
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		ready = w.step(ctx, ready)
+		select {
+		case <-ctx.Done():
+			w.send("STOPPING=1\nSTATUS=stopping")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// step checks the agent once and reports the result, returning whether
+// the agent was reported ready
+func (w *Watchdog) step(ctx context.Context, ready bool) bool {
+	checkCtx, cancel := context.WithTimeout(ctx, w.Interval)
+	err := w.Check(checkCtx)
+	cancel()
+
+	if err != nil {
+		// No WATCHDOG=1: systemd restarts the agent if this lasts
+		slog.Warn("Agent health check failed", "error", err)
+		w.send("STATUS=unhealthy: " + err.Error())
+		return ready
+	}
+
+	state := "WATCHDOG=1\nSTATUS=" + w.Status()
+	if !ready {
+		state = "READY=1\n" + state
+	}
+	w.send(state)
+	return true
+}
+
+func (w *Watchdog) send(state string) {
+	if _, err := w.notify(state); err != nil {
+		slog.Warn("Failed to notify systemd", "error", err)
+	}
+}
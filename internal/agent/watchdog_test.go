@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := SdNotify("READY=1"); sent || err != nil {
+		t.Fatalf("without NOTIFY_SOCKET nothing is sent: %v, %v", sent, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	if sent, err := SdNotify("READY=1\nSTATUS=ok"); !sent || err != nil {
+		t.Fatalf("SdNotify = %v, %v", sent, err)
+	}
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1\nSTATUS=ok" {
+		t.Errorf("received %q, %v", buf[:n], err)
+	}
+}
+
+func TestSdWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(0))
+	if d := SdWatchdogInterval(); d != 0 {
+		t.Errorf("the watchdog of another process is ignored, got %v", d)
+	}
+	t.Setenv("WATCHDOG_PID", "")
+	if d := SdWatchdogInterval(); d != 30*time.Second {
+		t.Errorf("SdWatchdogInterval = %v", d)
+	}
+	t.Setenv("WATCHDOG_USEC", "")
+	if d := SdWatchdogInterval(); d != 0 {
+		t.Errorf("SdWatchdogInterval = %v, want 0 when off", d)
+	}
+}
+
+func TestWatchdog_Step(t *testing.T) {
+	var sent []string
+	checkErr := errors.New("grpc server not serving")
+	w := &Watchdog{
+		Check:    func(ctx context.Context) error { return checkErr },
+		Status:   func() string { return "serving, event queue 3" },
+		Interval: time.Second,
+		notify: func(state string) (bool, error) {
+			sent = append(sent, state)
+			return true, nil
+		},
+	}
+	ctx := context.Background()
+
+	// Unhealthy: not ready, and no watchdog ping so systemd restarts it
+	if ready := w.step(ctx, false); ready {
+		t.Error("an unhealthy agent isn't ready")
+	}
+	checkErr = nil
+	if ready := w.step(ctx, false); !ready {
+		t.Error("a healthy agent is ready")
+	}
+	w.step(ctx, true)
+
+	want := []string{
+		"STATUS=unhealthy: grpc server not serving",
+		"READY=1\nWATCHDOG=1\nSTATUS=serving, event queue 3",
+		"WATCHDOG=1\nSTATUS=serving, event queue 3",
+	}
+	if len(sent) != len(want) {
+		t.Fatalf("sent %q", sent)
+	}
+	for i := range want {
+		if sent[i] != want[i] {
+			t.Errorf("notification %d = %q, want %q", i, sent[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,481 @@
+// Package apitoken manages scoped API tokens that let CI pipelines trigger
+// workflows on specific stacks. Tokens are stored on the master with only
+// a hash of their secret; revoked and expired tokens stay listed so the
+// revocation list can be audited.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+)
+
+// Role is what a token may do within its scope
+type Role string
+
+const (
+	// RoleViewer reads executions and their logs
+	RoleViewer Role = "viewer"
+	// RoleRunner also triggers workflows
+	RoleRunner Role = "runner"
+)
+
+// Actions checked against a token's role
+const (
+	ActionRead = "read"
+	ActionRun  = "run"
+)
+
+var roleActions = map[Role][]string{
+	RoleViewer: {ActionRead},
+	RoleRunner: {ActionRead, ActionRun},
+}
+
+// ParseRole validates a role name
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(s))
+	if _, ok := roleActions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (use viewer or runner)", s)
+	}
+	return role, nil
+}
+
+// Prefix starts every token, so leaked tokens are easy to spot
+const Prefix = "slr_"
+
+var (
+	// ErrInvalid is returned for unknown tokens and wrong secrets
+	ErrInvalid = errors.New("invalid token")
+	ErrExpired = errors.New("token expired")
+	ErrRevoked = errors.New("token revoked")
+	// ErrForbidden is returned when a token's scope doesn't allow a request
+	ErrForbidden = errors.New("token not allowed")
+)
+
+// Token is an API token. Stacks, Workflows and Agents are names or shell
+// globs; an empty list allows nothing, "*" allows everything. Agents are
+// what runs may be delegated to, agent groups written as "group:<name>".
+type Token struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Role        Role       `json:"role"`
+	Stacks      []string   `json:"stacks"`
+	Workflows   []string   `json:"workflows"`
+	Agents      []string   `json:"agents"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RevokeNote  string     `json:"revoke_note,omitempty"`
+	RotatedFrom string     `json:"rotated_from,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
+
+// State is whether a token is active, expired or revoked at a time
+func (t *Token) State(now time.Time) string {
+	switch {
+	case t.RevokedAt != nil && !t.RevokedAt.After(now):
+		return "revoked"
+	case t.ExpiresAt != nil && !t.ExpiresAt.After(now):
+		return "expired"
+	}
+	return "active"
+}
+
+// Can reports whether the token's role permits an action
+func (t *Token) Can(action string) bool {
+	for _, a := range roleActions[t.Role] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows checks that the token's role permits action on a workflow of a
+// stack. workflow may be empty for actions on a stack as a whole.
+func (t *Token) Allows(action, stack, workflow string) error {
+	if !t.Can(action) {
+		return fmt.Errorf("%w: role %s cannot %s", ErrForbidden, t.Role, action)
+	}
+	if !matchAny(t.Stacks, stack) {
+		return fmt.Errorf("%w: stack %q is out of scope", ErrForbidden, stack)
+	}
+	if workflow != "" && !matchAny(t.Workflows, workflow) {
+		return fmt.Errorf("%w: workflow %q is out of scope", ErrForbidden, workflow)
+	}
+	return nil
+}
+
+// AllowsDelegation checks that a run may be delegated to delegateTo, an
+// agent or a "group:<name>" agent group
+func (t *Token) AllowsDelegation(delegateTo string) error {
+	if !matchAny(t.Agents, delegateTo) {
+		return fmt.Errorf("%w: delegating to %q is out of scope", ErrForbidden, delegateTo)
+	}
+	return nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Spec describes a token to create
+type Spec struct {
+	Name      string
+	Role      Role
+	Stacks    []string
+	Workflows []string
+	// Agents runs may be delegated to; none means runs go where their
+	// workflow sends them
+	Agents []string
+	// TTL is how long the token is valid; zero means it never expires
+	TTL time.Duration
+}
+
+// Store keeps tokens in SQLite
+type Store struct {
+	db  *sql.DB
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewStore opens (creating if needed) the token store at dbPath
+func NewStore(dbPath string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token store: %w", err)
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		role TEXT NOT NULL,
+		stacks TEXT NOT NULL,
+		workflows TEXT NOT NULL,
+		secret_hash TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER,
+		revoked_at INTEGER,
+		revoke_note TEXT DEFAULT '',
+		rotated_from TEXT DEFAULT '',
+		last_used_at INTEGER,
+		agents TEXT NOT NULL DEFAULT '[]'
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		sqlitedb.Close(db)
+		return nil, fmt.Errorf("failed to initialize token store: %w", err)
+	}
+	// Stores created before tokens had an agent scope; the error means the
+	// column already exists
+	db.Exec(`ALTER TABLE api_tokens ADD COLUMN agents TEXT NOT NULL DEFAULT '[]'`)
+	os.Chmod(dbPath, 0600)
+
+	return &Store{db: db, now: time.Now}, nil
+}
+
+// DefaultStore opens the token store in the data directory
+func DefaultStore() (*Store, error) {
+	return NewStore(config.GetTokensDBPath())
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return sqlitedb.Close(s.db)
+}
+
+// Create stores a new token and returns it with its secret, which is
+// shown once and can't be recovered
+func (s *Store) Create(spec Spec) (string, *Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create(spec, "")
+}
+
+func (s *Store) create(spec Spec, rotatedFrom string) (string, *Token, error) {
+	if spec.Name == "" {
+		return "", nil, errors.New("token name is required")
+	}
+	if _, ok := roleActions[spec.Role]; !ok {
+		return "", nil, fmt.Errorf("unknown role %q", spec.Role)
+	}
+	if len(spec.Stacks) == 0 {
+		return "", nil, errors.New("at least one stack is required")
+	}
+	patterns := append(append(append([]string(nil), spec.Stacks...), spec.Workflows...), spec.Agents...)
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return "", nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	workflows := spec.Workflows
+	if len(workflows) == 0 {
+		workflows = []string{"*"}
+	}
+
+	id, err := randomHex(6)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	t := &Token{
+		ID:          id,
+		Name:        spec.Name,
+		Role:        spec.Role,
+		Stacks:      spec.Stacks,
+		Workflows:   workflows,
+		Agents:      spec.Agents,
+		CreatedAt:   now,
+		RotatedFrom: rotatedFrom,
+	}
+	if spec.TTL > 0 {
+		expires := now.Add(spec.TTL)
+		t.ExpiresAt = &expires
+	}
+
+	stacks, _ := json.Marshal(t.Stacks)
+	wfs, _ := json.Marshal(t.Workflows)
+	agents, _ := json.Marshal(t.Agents)
+	_, err = s.db.Exec(
+		`INSERT INTO api_tokens (id, name, role, stacks, workflows, agents, secret_hash, created_at, expires_at, rotated_from)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.Name, string(t.Role), string(stacks), string(wfs), string(agents), hashSecret(secret),
+		t.CreatedAt.Unix(), unixOrNil(t.ExpiresAt), rotatedFrom,
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store token: %w", err)
+	}
+	return Prefix + id + "_" + secret, t, nil
+}
+
+// Get returns a token by ID
+func (s *Store) Get(id string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, _, err := s.get(id)
+	return t, err
+}
+
+func (s *Store) get(id string) (*Token, string, error) {
+	row := s.db.QueryRow(`SELECT `+tokenColumns+`, secret_hash FROM api_tokens WHERE id = ?`, id)
+	var hash string
+	t, err := scanToken(row, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", fmt.Errorf("token %s not found", id)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return t, hash, nil
+}
+
+// List returns all tokens, newest first, including expired and revoked
+// ones
+func (s *Store) List() ([]*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT ` + tokenColumns + ` FROM api_tokens ORDER BY created_at DESC, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*Token
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke revokes a token at once, noting why
+func (s *Store) Revoke(id, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revokeAt(id, s.now(), note)
+}
+
+func (s *Store) revokeAt(id string, at time.Time, note string) error {
+	t, _, err := s.get(id)
+	if err != nil {
+		return err
+	}
+	if t.RevokedAt != nil && !t.RevokedAt.After(at) {
+		return fmt.Errorf("token %s is already revoked", id)
+	}
+	_, err = s.db.Exec(`UPDATE api_tokens SET revoked_at = ?, revoke_note = ? WHERE id = ?`,
+		at.UTC().Unix(), note, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// Rotate creates a token with the same name, role and scope, valid as long
+// as the original was, and revokes the original once grace has passed so
+// pipelines can switch over
+func (s *Store) Rotate(id string, grace time.Duration) (string, *Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, _, err := s.get(id)
+	if err != nil {
+		return "", nil, err
+	}
+	if state := old.State(s.now()); state != "active" {
+		return "", nil, fmt.Errorf("token %s is %s", id, state)
+	}
+
+	spec := Spec{Name: old.Name, Role: old.Role, Stacks: old.Stacks, Workflows: old.Workflows, Agents: old.Agents}
+	if old.ExpiresAt != nil {
+		spec.TTL = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	secret, t, err := s.create(spec, old.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.revokeAt(id, s.now().Add(grace), "rotated to "+t.ID); err != nil {
+		return "", nil, err
+	}
+	return secret, t, nil
+}
+
+// Authenticate returns the active token a secret belongs to and records
+// its use
+func (s *Store) Authenticate(raw string) (*Token, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, Prefix), "_")
+	if !strings.HasPrefix(raw, Prefix) || !ok {
+		return nil, ErrInvalid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, hash, err := s.get(id)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalid
+	}
+	now := s.now()
+	switch t.State(now) {
+	case "revoked":
+		return nil, ErrRevoked
+	case "expired":
+		return nil, ErrExpired
+	}
+
+	used := now.UTC().Truncate(time.Second)
+	t.LastUsedAt = &used
+	s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, used.Unix(), id)
+	return t, nil
+}
+
+const tokenColumns = `id, name, role, stacks, workflows, agents, created_at, expires_at, revoked_at, revoke_note, rotated_from, last_used_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanToken(row scanner, extra ...interface{}) (*Token, error) {
+	var t Token
+	var role, stacks, workflows, agents string
+	var created int64
+	var expires, revoked, used sql.NullInt64
+	dest := append([]interface{}{&t.ID, &t.Name, &role, &stacks, &workflows, &agents, &created,
+		&expires, &revoked, &t.RevokeNote, &t.RotatedFrom, &used}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	t.Role = Role(role)
+	json.Unmarshal([]byte(stacks), &t.Stacks)
+	json.Unmarshal([]byte(workflows), &t.Workflows)
+	json.Unmarshal([]byte(agents), &t.Agents)
+	t.CreatedAt = time.Unix(created, 0).UTC()
+	t.ExpiresAt = timeOrNil(expires)
+	t.RevokedAt = timeOrNil(revoked)
+	t.LastUsedAt = timeOrNil(used)
+	return &t, nil
+}
+
+func timeOrNil(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0).UTC()
+	return &t
+}
+
+func unixOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Unix()
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ParseTTL parses a token lifetime such as 90d, 12h or 0 (never expires)
+func ParseTTL(s string) (time.Duration, error) {
+	if s == "" || s == "0" || s == "never" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,215 @@
+package apitoken
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "tokens.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestStore_CreateAndAuthenticate(t *testing.T) {
+	store, now := newTestStore(t)
+
+	secret, tok, err := store.Create(Spec{Name: "ci", Role: RoleRunner, Stacks: []string{"prod"}, TTL: 90 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, Prefix+tok.ID+"_") {
+		t.Errorf("secret %q doesn't carry the token ID", secret)
+	}
+	if len(tok.Workflows) != 1 || tok.Workflows[0] != "*" {
+		t.Errorf("workflows default to all, got %v", tok.Workflows)
+	}
+
+	got, err := store.Authenticate(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != tok.ID || got.LastUsedAt == nil {
+		t.Errorf("Authenticate = %+v", got)
+	}
+
+	for _, bad := range []string{"", "slr_", secret + "x", Prefix + tok.ID + "_" + strings.Repeat("0", 48), "Bearer " + secret} {
+		if _, err := store.Authenticate(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Authenticate(%q) = %v, want ErrInvalid", bad, err)
+		}
+	}
+
+	*now = now.Add(91 * 24 * time.Hour)
+	if _, err := store.Authenticate(secret); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired token: %v", err)
+	}
+}
+
+func TestStore_CreateValidation(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	specs := []Spec{
+		{Role: RoleRunner, Stacks: []string{"prod"}},
+		{Name: "ci", Role: "admin", Stacks: []string{"prod"}},
+		{Name: "ci", Role: RoleRunner},
+		{Name: "ci", Role: RoleRunner, Stacks: []string{"[prod"}},
+		{Name: "ci", Role: RoleRunner, Stacks: []string{"prod"}, Agents: []string{"[web"}},
+	}
+	for _, spec := range specs {
+		if _, _, err := store.Create(spec); err == nil {
+			t.Errorf("Create(%+v) should fail", spec)
+		}
+	}
+}
+
+func TestStore_RevokeAndRotate(t *testing.T) {
+	store, now := newTestStore(t)
+
+	secret, tok, err := store.Create(Spec{Name: "ci", Role: RoleRunner, Stacks: []string{"prod"}, Agents: []string{"web-*"}, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSecret, rotated, err := store.Rotate(tok.ID, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.RotatedFrom != tok.ID || rotated.Name != "ci" || rotated.ExpiresAt.Sub(rotated.CreatedAt) != time.Hour ||
+		len(rotated.Agents) != 1 || rotated.Agents[0] != "web-*" {
+		t.Errorf("rotated token = %+v", rotated)
+	}
+
+	// The old token keeps working during the grace period
+	if _, err := store.Authenticate(secret); err != nil {
+		t.Errorf("old token within grace: %v", err)
+	}
+	*now = now.Add(11 * time.Minute)
+	if _, err := store.Authenticate(secret); !errors.Is(err, ErrRevoked) {
+		t.Errorf("old token after grace: %v", err)
+	}
+	if _, err := store.Authenticate(newSecret); err != nil {
+		t.Errorf("new token: %v", err)
+	}
+
+	if err := store.Revoke(rotated.ID, "leaked"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Authenticate(newSecret); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked token: %v", err)
+	}
+	if err := store.Revoke(rotated.ID, ""); err == nil {
+		t.Error("revoking twice should fail")
+	}
+	if _, _, err := store.Rotate(rotated.ID, 0); err == nil {
+		t.Error("rotating a revoked token should fail")
+	}
+
+	tokens, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("List returned %d tokens, want 2", len(tokens))
+	}
+	for _, listed := range tokens {
+		if listed.State(*now) != "revoked" {
+			t.Errorf("token %s is %s, want revoked", listed.ID, listed.State(*now))
+		}
+		if listed.ID == rotated.ID && listed.RevokeNote != "leaked" {
+			t.Errorf("revoke note = %q", listed.RevokeNote)
+		}
+	}
+}
+
+func TestToken_Allows(t *testing.T) {
+	tok := &Token{Role: RoleRunner, Stacks: []string{"prod", "staging-*"}, Workflows: []string{"deploy*"}}
+	viewer := &Token{Role: RoleViewer, Stacks: []string{"*"}, Workflows: []string{"*"}}
+
+	tests := []struct {
+		token    *Token
+		action   string
+		stack    string
+		workflow string
+		allowed  bool
+	}{
+		{tok, ActionRun, "prod", "deploy", true},
+		{tok, ActionRun, "staging-eu", "deploy-api", true},
+		{tok, ActionRead, "prod", "", true},
+		{tok, ActionRun, "dev", "deploy", false},
+		{tok, ActionRun, "prod", "destroy", false},
+		{viewer, ActionRead, "prod", "deploy", true},
+		{viewer, ActionRun, "prod", "deploy", false},
+	}
+	for _, tt := range tests {
+		err := tt.token.Allows(tt.action, tt.stack, tt.workflow)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s Allows(%s, %s, %s) = %v, want allowed=%v", tt.token.Role, tt.action, tt.stack, tt.workflow, err, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrForbidden) {
+			t.Errorf("error %v should wrap ErrForbidden", err)
+		}
+	}
+}
+
+func TestToken_AllowsDelegation(t *testing.T) {
+	store, _ := newTestStore(t)
+	secret, _, err := store.Create(Spec{Name: "ci", Role: RoleRunner, Stacks: []string{"prod"}, Agents: []string{"web-*", "group:canary"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := store.Authenticate(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unscoped := &Token{Role: RoleRunner, Stacks: []string{"prod"}}
+
+	tests := []struct {
+		token      *Token
+		delegateTo string
+		allowed    bool
+	}{
+		{tok, "web-01", true},
+		{tok, "group:canary", true},
+		{tok, "db-01", false},
+		{tok, "group:webservers", false},
+		{unscoped, "web-01", false},
+	}
+	for _, tt := range tests {
+		err := tt.token.AllowsDelegation(tt.delegateTo)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%v AllowsDelegation(%s) = %v, want allowed=%v", tt.token.Agents, tt.delegateTo, err, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrForbidden) {
+			t.Errorf("error %v should wrap ErrForbidden", err)
+		}
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90d":   90 * 24 * time.Hour,
+		"12h":   12 * time.Hour,
+		"0":     0,
+		"never": 0,
+	}
+	for in, want := range tests {
+		got, err := ParseTTL(in)
+		if err != nil || got != want {
+			t.Errorf("ParseTTL(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"d", "-1d", "1w", "-5h"} {
+		if _, err := ParseTTL(in); err == nil {
+			t.Errorf("ParseTTL(%q) should fail", in)
+		}
+	}
+}
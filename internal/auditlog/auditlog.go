@@ -0,0 +1,363 @@
+// Package auditlog writes audit records to an append-only, hash-chained
+// log for compliance exports. Each record carries the hash of the record
+// before it, so changing, removing or reordering a record breaks the
+// chain. Anchors, records holding the head of the chain, are written
+// every few records and copied to a separate anchors file that can be
+// kept elsewhere, so rewriting the whole chain is detected too.
+package auditlog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// GenesisHash is the previous hash of the first record of a log
+var GenesisHash = strings.Repeat("0", 64)
+
+// DefaultAnchorEvery is how many records are written between anchors
+const DefaultAnchorEvery = 100
+
+// SourceAnchor is the source of anchor records
+const SourceAnchor = "anchor"
+
+// maxLine bounds the size of a record in a log
+const maxLine = 16 << 20
+
+// Record is an entry of an audit log
+type Record struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Source is where the record was exported from and SourceID its
+	// position there, which tells the next export where to resume
+	Source   string `json:"source"`
+	SourceID int64  `json:"source_id,omitempty"`
+	// ID identifies the entry in its source, such as an event's UUID
+	ID       string          `json:"id,omitempty"`
+	Type     string          `json:"type"`
+	Stack    string          `json:"stack,omitempty"`
+	Agent    string          `json:"agent,omitempty"`
+	RunID    string          `json:"run_id,omitempty"`
+	Actor    string          `json:"actor,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// ComputeHash returns the hash of the record: the SHA-256 of its JSON
+// encoding without the hash, previous hash included
+func (r Record) ComputeHash() string {
+	r.Hash = ""
+	body, _ := json.Marshal(r)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Anchor is a line of an anchors file: the hash of an anchor record
+type Anchor struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Hash string    `json:"hash"`
+}
+
+// AnchorsPath returns the path of the anchors file of a log
+func AnchorsPath(path string) string {
+	return path + ".anchors"
+}
+
+// Log is an audit log open for appending. Only one process may append to
+// a log at a time.
+type Log struct {
+	file        *os.File
+	anchors     *os.File
+	anchorEvery int
+	lastSeq     uint64
+	lastHash    string
+	sinceAnchor int
+	cursors     map[string]int64
+}
+
+// Open opens the log at path for appending, creating it when missing. The
+// existing records are verified first, against the anchors file when there
+// is one, as appending to a tampered log would vouch for it. anchorEvery of 0 uses DefaultAnchorEvery.
+func Open(path string, anchorEvery int) (*Log, error) {
+	if anchorEvery <= 0 {
+		anchorEvery = DefaultAnchorEvery
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("audit log %s is in use: %w", path, err)
+	}
+
+	l := &Log{
+		file:        file,
+		anchorEvery: anchorEvery,
+		lastHash:    GenesisHash,
+		cursors:     make(map[string]int64),
+	}
+	anchorsPath := AnchorsPath(path)
+	if _, err := os.Stat(anchorsPath); err != nil {
+		anchorsPath = ""
+	}
+	if _, err := l.replay(file, anchorsPath); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("refusing to append to %s: %w", path, err)
+	}
+
+	l.anchors, err = os.OpenFile(AnchorsPath(path), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open anchors file: %w", err)
+	}
+	return l, nil
+}
+
+// Cursor returns the source ID of the last record exported from source,
+// 0 when none was
+func (l *Log) Cursor(source string) int64 {
+	return l.cursors[source]
+}
+
+// LastSeq returns the sequence number of the last record of the log
+func (l *Log) LastSeq() uint64 {
+	return l.lastSeq
+}
+
+// Append chains a record to the log, setting its sequence number and
+// hashes, and writes an anchor when one is due
+func (l *Log) Append(r Record) error {
+	if err := l.write(r); err != nil {
+		return err
+	}
+	if l.sinceAnchor >= l.anchorEvery {
+		return l.Anchor()
+	}
+	return nil
+}
+
+// Anchor writes an anchor holding the head of the chain, unless the last
+// record already is one
+func (l *Log) Anchor() error {
+	if l.sinceAnchor == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(map[string]interface{}{"head": l.lastHash, "head_seq": l.lastSeq})
+	if err := l.write(Record{Source: SourceAnchor, Type: SourceAnchor, Data: data}); err != nil {
+		return err
+	}
+
+	line, _ := json.Marshal(Anchor{Seq: l.lastSeq, Time: time.Now().UTC(), Hash: l.lastHash})
+	if _, err := l.anchors.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write anchor: %w", err)
+	}
+	return nil
+}
+
+// Close anchors the head of the chain and closes the log, syncing it to
+// disk
+func (l *Log) Close() error {
+	err := l.Anchor()
+	for _, f := range []*os.File{l.file, l.anchors} {
+		if syncErr := f.Sync(); err == nil {
+			err = syncErr
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (l *Log) write(r Record) error {
+	r.Seq = l.lastSeq + 1
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	// UTC times encode the same after a round trip, which keeps hashes
+	// verifiable
+	r.Time = r.Time.UTC()
+	r.PrevHash = l.lastHash
+	r.Hash = r.ComputeHash()
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	l.advance(r)
+	return nil
+}
+
+// check validates that r follows the last record of the log
+func (l *Log) check(r Record) error {
+	if r.Seq != l.lastSeq+1 {
+		return &TamperError{Seq: r.Seq, Reason: fmt.Sprintf("expected record %d", l.lastSeq+1)}
+	}
+	if r.PrevHash != l.lastHash {
+		return &TamperError{Seq: r.Seq, Reason: "previous hash doesn't match the record before it"}
+	}
+	if r.ComputeHash() != r.Hash {
+		return &TamperError{Seq: r.Seq, Reason: "hash doesn't match the record's content"}
+	}
+	if r.Source == SourceAnchor {
+		var anchor struct {
+			Head    string `json:"head"`
+			HeadSeq uint64 `json:"head_seq"`
+		}
+		if err := json.Unmarshal(r.Data, &anchor); err != nil || anchor.Head != r.PrevHash || anchor.HeadSeq != r.Seq-1 {
+			return &TamperError{Seq: r.Seq, Reason: "anchor doesn't hold the head of the chain"}
+		}
+	}
+	return nil
+}
+
+func (l *Log) advance(r Record) {
+	l.lastSeq = r.Seq
+	l.lastHash = r.Hash
+	if r.Source == SourceAnchor {
+		l.sinceAnchor = 0
+		return
+	}
+	l.sinceAnchor++
+	if r.SourceID > l.cursors[r.Source] {
+		l.cursors[r.Source] = r.SourceID
+	}
+}
+
+// TamperError reports the first record of a log that breaks the chain
+type TamperError struct {
+	Seq    uint64
+	Reason string
+}
+
+func (e *TamperError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Seq, e.Reason)
+}
+
+// Result summarizes a verified log
+type Result struct {
+	Records int    `json:"records"`
+	Anchors int    `json:"anchors"`
+	LastSeq uint64 `json:"last_seq"`
+	Head    string `json:"head"`
+	// ExternalAnchors is how many anchors of the anchors file were matched
+	ExternalAnchors int `json:"external_anchors"`
+}
+
+// Verify checks the chain of the log at path and, when anchorsPath isn't
+// empty, that the log still holds every anchor of the anchors file. A
+// broken chain or missing anchor is reported as a *TamperError.
+func Verify(path, anchorsPath string) (Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	l := &Log{lastHash: GenesisHash, cursors: make(map[string]int64)}
+	return l.replay(file, anchorsPath)
+}
+
+// replay checks the records of a log from its start, then the anchors of
+// the anchors file when anchorsPath isn't empty, leaving l at the end of
+// the chain
+func (l *Log) replay(file io.ReadSeeker, anchorsPath string) (Result, error) {
+	var result Result
+	hashes := make(map[uint64]string)
+	err := scan(file, func(r Record) error {
+		if err := l.check(r); err != nil {
+			return err
+		}
+		l.advance(r)
+		result.Records++
+		if r.Source == SourceAnchor {
+			result.Anchors++
+			hashes[r.Seq] = r.Hash
+		}
+		return nil
+	})
+	result.LastSeq = l.lastSeq
+	result.Head = l.lastHash
+	if err != nil {
+		return result, err
+	}
+
+	if anchorsPath == "" {
+		return result, nil
+	}
+	anchors, err := ReadAnchors(anchorsPath)
+	if err != nil {
+		return result, err
+	}
+	for _, a := range anchors {
+		switch hash, ok := hashes[a.Seq]; {
+		case a.Seq > l.lastSeq:
+			return result, &TamperError{Seq: a.Seq, Reason: fmt.Sprintf("anchored record is missing, the log ends at record %d", l.lastSeq)}
+		case !ok || hash != a.Hash:
+			return result, &TamperError{Seq: a.Seq, Reason: "record doesn't match its anchor, the chain was rewritten"}
+		}
+		result.ExternalAnchors++
+	}
+	return result, nil
+}
+
+// ReadAnchors reads an anchors file
+func ReadAnchors(path string) ([]Anchor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open anchors file: %w", err)
+	}
+	defer file.Close()
+
+	var anchors []Anchor
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var a Anchor
+		if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+			return nil, fmt.Errorf("anchors file line %d: %w", n, err)
+		}
+		anchors = append(anchors, a)
+	}
+	return anchors, scanner.Err()
+}
+
+// scan decodes the records of a log from its start
+func scan(r io.ReadSeeker, fn func(Record) error) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	var seq uint64
+	for scanner.Scan() {
+		seq++
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return &TamperError{Seq: seq, Reason: "record isn't valid JSON"}
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+		seq = rec.Seq
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLog(t *testing.T, path string, anchorEvery int, records ...Record) {
+	t.Helper()
+	l, err := Open(path, anchorEvery)
+	require.NoError(t, err)
+	for _, r := range records {
+		require.NoError(t, l.Append(r))
+	}
+	require.NoError(t, l.Close())
+}
+
+func event(id int64, eventType string) Record {
+	data, _ := json.Marshal(map[string]string{"message": "<" + eventType + "> & more"})
+	return Record{
+		Time:     time.Date(2026, 10, 17, 12, 0, int(id), 0, time.FixedZone("BRT", -3*3600)),
+		Source:   "events",
+		SourceID: id,
+		Type:     eventType,
+		Stack:    "production",
+		Data:     data,
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func writeLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o640))
+}
+
+func TestLog_ChainsAndAnchors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeLog(t, path, 2, event(1, "task.started"), event(2, "task.completed"), event(3, "task.failed"))
+
+	lines := readLines(t, path)
+	// 3 records, an anchor after the second and one on close
+	require.Len(t, lines, 5)
+
+	var records []Record
+	for _, line := range lines {
+		var r Record
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	assert.Equal(t, GenesisHash, records[0].PrevHash)
+	for i, r := range records {
+		assert.Equal(t, uint64(i+1), r.Seq)
+		assert.Equal(t, r.ComputeHash(), r.Hash)
+		if i > 0 {
+			assert.Equal(t, records[i-1].Hash, r.PrevHash)
+		}
+	}
+	assert.Equal(t, SourceAnchor, records[2].Source)
+	assert.Equal(t, SourceAnchor, records[4].Source)
+
+	anchors, err := ReadAnchors(AnchorsPath(path))
+	require.NoError(t, err)
+	require.Len(t, anchors, 2)
+	assert.Equal(t, Anchor{Seq: 3, Hash: records[2].Hash}, Anchor{Seq: anchors[0].Seq, Hash: anchors[0].Hash})
+	assert.Equal(t, uint64(5), anchors[1].Seq)
+
+	result, err := Verify(path, AnchorsPath(path))
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Records)
+	assert.Equal(t, 2, result.Anchors)
+	assert.Equal(t, 2, result.ExternalAnchors)
+	assert.Equal(t, records[4].Hash, result.Head)
+}
+
+func TestLog_ResumesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeLog(t, path, 0, event(1, "task.started"), event(7, "task.completed"))
+
+	l, err := Open(path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), l.Cursor("events"))
+	assert.Equal(t, int64(0), l.Cursor("stack_activity"))
+	assert.Equal(t, uint64(3), l.LastSeq())
+	require.NoError(t, l.Close())
+
+	// Closing without new records doesn't add an anchor
+	assert.Len(t, readLines(t, path), 3)
+
+	writeLog(t, path, 0, event(8, "task.failed"))
+	result, err := Verify(path, AnchorsPath(path))
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Records)
+	assert.Equal(t, 2, result.ExternalAnchors)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func([]string) []string
+		seq    uint64
+		reason string
+	}{
+		{
+			name: "modified record",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], "task.completed", "task.skipped", 1)
+				return lines
+			},
+			seq:    2,
+			reason: "hash doesn't match",
+		},
+		{
+			name: "removed record",
+			tamper: func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			},
+			seq:    3,
+			reason: "expected record 2",
+		},
+		{
+			name: "reordered records",
+			tamper: func(lines []string) []string {
+				lines[0], lines[1] = lines[1], lines[0]
+				return lines
+			},
+			seq:    2,
+			reason: "expected record 1",
+		},
+		{
+			name: "truncated log",
+			tamper: func(lines []string) []string {
+				return lines[:2]
+			},
+			seq:    4,
+			reason: "anchored record is missing",
+		},
+		{
+			name: "rewritten chain",
+			tamper: func(lines []string) []string {
+				path := filepath.Join(t.TempDir(), "rewritten.log")
+				forged := event(2, "task.skipped")
+				writeLog(t, path, 0, event(1, "task.started"), forged, event(3, "task.failed"))
+				return readLines(t, path)
+			},
+			seq:    4,
+			reason: "doesn't match its anchor",
+		},
+		{
+			name: "invalid record",
+			tamper: func(lines []string) []string {
+				lines[2] = "{"
+				return lines
+			},
+			seq:    3,
+			reason: "isn't valid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			writeLog(t, path, 0, event(1, "task.started"), event(2, "task.completed"), event(3, "task.failed"))
+			writeLines(t, path, tt.tamper(readLines(t, path)))
+
+			_, err := Verify(path, AnchorsPath(path))
+			var tamperErr *TamperError
+			require.ErrorAs(t, err, &tamperErr)
+			assert.Equal(t, tt.seq, tamperErr.Seq)
+			assert.Contains(t, tamperErr.Reason, tt.reason)
+		})
+	}
+}
+
+func TestVerify_WithoutAnchorsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeLog(t, path, 0, event(1, "task.started"), event(2, "task.completed"))
+	writeLines(t, path, readLines(t, path)[:1])
+
+	// Truncation is only visible against the anchors
+	result, err := Verify(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Records)
+}
+
+func TestOpen_RefusesTamperedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeLog(t, path, 0, event(1, "task.started"), event(2, "task.completed"))
+	lines := readLines(t, path)
+	lines[0] = strings.Replace(lines[0], "production", "staging", 1)
+	writeLines(t, path, lines)
+
+	_, err := Open(path, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to append")
+}
+
+func TestOpen_RefusesTruncatedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeLog(t, path, 0, event(1, "task.started"), event(2, "task.completed"))
+	writeLines(t, path, readLines(t, path)[:1])
+
+	_, err := Open(path, 0)
+	var tamperErr *TamperError
+	require.ErrorAs(t, err, &tamperErr)
+	assert.Contains(t, tamperErr.Reason, "anchored record is missing")
+}
@@ -0,0 +1,15 @@
+//go:build unix
+// +build unix
+
+package auditlog
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on f, released when it's closed, so two
+// exports can't interleave records of the same log
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
@@ -0,0 +1,394 @@
+// Package backup creates and restores archives of the SQLite databases in
+// the sloth-runner data directory. An archive is a zstd-compressed tar
+// holding a manifest followed by a consistent snapshot of each database.
+package backup
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/klauspost/compress/zstd"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// FormatVersion is the archive layout written by Create. Restore accepts
+// archives up to this version.
+const FormatVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	databasesDir = "databases"
+)
+
+// Database describes one database in a backup
+type Database struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a backup archive
+type Manifest struct {
+	FormatVersion int        `json:"format_version"`
+	RunnerVersion string     `json:"runner_version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Host          string     `json:"host,omitempty"`
+	Databases     []Database `json:"databases"`
+}
+
+// HasDatabase reports whether the backup contains the database file
+func (m *Manifest) HasDatabase(file string) bool {
+	for _, db := range m.Databases {
+		if db.File == file {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalSize returns the uncompressed size of all databases
+func (m *Manifest) TotalSize() int64 {
+	var total int64
+	for _, db := range m.Databases {
+		total += db.Size
+	}
+	return total
+}
+
+// RestoreOptions controls Restore
+type RestoreOptions struct {
+	// RunnerVersion is the version of the restoring runner
+	RunnerVersion string
+	// IgnoreVersion restores backups made by a newer runner
+	IgnoreVersion bool
+}
+
+// RestoreResult reports what Restore did
+type RestoreResult struct {
+	Manifest *Manifest
+	// PreviousDir holds the databases that were replaced, empty when the
+	// data directory had none
+	PreviousDir string
+}
+
+// ListDatabases returns the database files in dataDir
+func ListDatabases(dataDir string) ([]string, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".db") {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Create writes a backup of every database in dataDir to w. Each database
+// is snapshotted with VACUUM INTO, so it is consistent even while the
+// master keeps writing to it.
+func Create(dataDir string, w io.Writer, runnerVersion string) (*Manifest, error) {
+	files, err := ListDatabases(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no databases found in %s", dataDir)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "sloth-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	host, _ := os.Hostname()
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		RunnerVersion: runnerVersion,
+		CreatedAt:     time.Now().UTC(),
+		Host:          host,
+	}
+
+	for _, file := range files {
+		snapshot := filepath.Join(tmpDir, file)
+		if err := snapshotDatabase(filepath.Join(dataDir, file), snapshot); err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s: %w", file, err)
+		}
+		size, sum, err := fileChecksum(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Databases = append(manifest.Databases, Database{
+			Name:   strings.TrimSuffix(file, ".db"),
+			File:   file,
+			Size:   size,
+			SHA256: sum,
+		})
+	}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+	}
+	tw := tar.NewWriter(zw)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeTarFile(tw, manifestName, int64(len(data)), strings.NewReader(string(data))); err != nil {
+		return nil, err
+	}
+
+	for _, db := range manifest.Databases {
+		f, err := os.Open(filepath.Join(tmpDir, db.File))
+		if err != nil {
+			return nil, err
+		}
+		err = writeTarFile(tw, path.Join(databasesDir, db.File), db.Size, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// ReadManifest reads the manifest of a backup without extracting it
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a sloth-runner backup: %w", err)
+	}
+	defer zr.Close()
+	return readManifest(tar.NewReader(zr))
+}
+
+// CheckCompatibility returns an error when this runner can't restore the
+// backup. Backups from a newer runner may use database schemas this one
+// doesn't know; ignoreVersion restores them anyway.
+func CheckCompatibility(m *Manifest, runnerVersion string, ignoreVersion bool) error {
+	if m.FormatVersion < 1 || m.FormatVersion > FormatVersion {
+		return fmt.Errorf("backup format version %d is not supported (this runner reads up to %d); upgrade sloth-runner to restore it",
+			m.FormatVersion, FormatVersion)
+	}
+	if ignoreVersion {
+		return nil
+	}
+	if cmp, ok := sloth.CompareVersions(runnerVersion, m.RunnerVersion); ok && cmp < 0 {
+		return fmt.Errorf("backup was created by sloth-runner %s, which is newer than this runner (%s); upgrade first or pass --ignore-version",
+			m.RunnerVersion, runnerVersion)
+	}
+	return nil
+}
+
+// Restore replaces the databases in dataDir with those in the backup. The
+// archive is extracted and verified before anything is replaced; the
+// databases it replaces are moved to a pre-restore directory.
+func Restore(r io.Reader, dataDir string, opts RestoreOptions) (*RestoreResult, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a sloth-runner backup: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckCompatibility(manifest, opts.RunnerVersion, opts.IgnoreVersion); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	// Stage inside the data directory so the final renames don't cross
+	// file systems
+	staging, err := os.MkdirTemp(dataDir, ".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging dir: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractDatabases(tr, manifest, staging); err != nil {
+		return nil, err
+	}
+	for _, db := range manifest.Databases {
+		staged := filepath.Join(staging, db.File)
+		size, sum, err := fileChecksum(staged)
+		if err != nil {
+			return nil, fmt.Errorf("backup is missing %s", db.File)
+		}
+		if size != db.Size || sum != db.SHA256 {
+			return nil, fmt.Errorf("checksum mismatch for %s: backup is corrupt", db.File)
+		}
+		if err := checkDatabase(staged); err != nil {
+			return nil, fmt.Errorf("%s failed integrity check: %w", db.File, err)
+		}
+	}
+
+	result := &RestoreResult{Manifest: manifest}
+	previous, err := ListDatabases(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(previous) > 0 {
+		result.PreviousDir = filepath.Join(dataDir, "pre-restore-"+time.Now().Format("20060102-150405"))
+		if err := os.MkdirAll(result.PreviousDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", result.PreviousDir, err)
+		}
+		for _, file := range previous {
+			if err := moveDatabase(dataDir, result.PreviousDir, file); err != nil {
+				return nil, fmt.Errorf("failed to move aside %s: %w", file, err)
+			}
+		}
+	}
+
+	for _, db := range manifest.Databases {
+		if err := os.Rename(filepath.Join(staging, db.File), filepath.Join(dataDir, db.File)); err != nil {
+			return nil, fmt.Errorf("failed to restore %s (previous databases are in %s): %w", db.File, result.PreviousDir, err)
+		}
+	}
+	return result, nil
+}
+
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("not a sloth-runner backup: %w", err)
+	}
+	if hdr.Name != manifestName {
+		return nil, fmt.Errorf("not a sloth-runner backup: missing %s", manifestName)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func extractDatabases(tr *tar.Reader, manifest *Manifest, dir string) error {
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		file := path.Base(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || path.Dir(hdr.Name) != databasesDir || !manifest.HasDatabase(file) {
+			return fmt.Errorf("unexpected entry in backup: %s", hdr.Name)
+		}
+
+		f, err := os.OpenFile(filepath.Join(dir, file), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", file, err)
+		}
+	}
+}
+
+// snapshotDatabase writes a consistent copy of the database at src to dst
+func snapshotDatabase(src, dst string) error {
+	db, err := sql.Open("sqlite3", "file:"+src+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("VACUUM INTO ?", dst)
+	return err
+}
+
+func checkDatabase(file string) error {
+	db, err := sql.Open("sqlite3", "file:"+file+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return errors.New(result)
+	}
+	return nil
+}
+
+// moveDatabase moves a database and its WAL files from one directory to
+// another
+func moveDatabase(from, to, file string) error {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		err := os.Rename(filepath.Join(from, file+suffix), filepath.Join(to, file+suffix))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func fileChecksum(file string) (int64, string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
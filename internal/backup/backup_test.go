@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func createDB(t *testing.T, path, value string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS kv (v TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM kv; INSERT INTO kv (v) VALUES (?)", value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readDB(t *testing.T, path string) string {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var v string
+	if err := db.QueryRow("SELECT v FROM kv").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestBackupRestore(t *testing.T) {
+	src := t.TempDir()
+	createDB(t, filepath.Join(src, "agents.db"), "agent-1")
+	createDB(t, filepath.Join(src, "stacks.db"), "stack-1")
+	if err := os.WriteFile(filepath.Join(src, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := Create(src, &archive, "1.2.0")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(manifest.Databases) != 2 || !manifest.HasDatabase("agents.db") || !manifest.HasDatabase("stacks.db") {
+		t.Fatalf("Unexpected databases in manifest: %+v", manifest.Databases)
+	}
+
+	read, err := ReadManifest(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+	if read.RunnerVersion != "1.2.0" || read.FormatVersion != FormatVersion {
+		t.Errorf("Unexpected manifest: %+v", read)
+	}
+
+	// Restore over a data dir with newer data and a database the backup
+	// doesn't have
+	dst := t.TempDir()
+	createDB(t, filepath.Join(dst, "agents.db"), "agent-2")
+	createDB(t, filepath.Join(dst, "runlogs.db"), "log")
+
+	result, err := Restore(bytes.NewReader(archive.Bytes()), dst, RestoreOptions{RunnerVersion: "1.3.0"})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got := readDB(t, filepath.Join(dst, "agents.db")); got != "agent-1" {
+		t.Errorf("Expected restored agents.db, got %q", got)
+	}
+	if got := readDB(t, filepath.Join(dst, "stacks.db")); got != "stack-1" {
+		t.Errorf("Expected restored stacks.db, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "runlogs.db")); !os.IsNotExist(err) {
+		t.Error("Expected runlogs.db to be moved aside")
+	}
+	if got := readDB(t, filepath.Join(result.PreviousDir, "agents.db")); got != "agent-2" {
+		t.Errorf("Expected previous agents.db to be kept, got %q", got)
+	}
+}
+
+func TestRestore_NewerRunner(t *testing.T) {
+	src := t.TempDir()
+	createDB(t, filepath.Join(src, "agents.db"), "agent-1")
+	var archive bytes.Buffer
+	if _, err := Create(src, &archive, "2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	createDB(t, filepath.Join(dst, "agents.db"), "current")
+	_, err := Restore(bytes.NewReader(archive.Bytes()), dst, RestoreOptions{RunnerVersion: "1.9.0"})
+	if err == nil || !strings.Contains(err.Error(), "newer than this runner") {
+		t.Fatalf("Expected version error, got %v", err)
+	}
+	if got := readDB(t, filepath.Join(dst, "agents.db")); got != "current" {
+		t.Errorf("Expected data dir untouched, got %q", got)
+	}
+
+	if _, err := Restore(bytes.NewReader(archive.Bytes()), dst, RestoreOptions{RunnerVersion: "1.9.0", IgnoreVersion: true}); err != nil {
+		t.Errorf("Expected restore with IgnoreVersion to succeed: %v", err)
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+		runner   string
+		wantErr  bool
+	}{
+		{"same version", Manifest{FormatVersion: 1, RunnerVersion: "1.0.0"}, "1.0.0", false},
+		{"older backup", Manifest{FormatVersion: 1, RunnerVersion: "0.9.0"}, "1.0.0", false},
+		{"dev runner", Manifest{FormatVersion: 1, RunnerVersion: "2.0.0"}, "dev", false},
+		{"newer backup", Manifest{FormatVersion: 1, RunnerVersion: "1.1.0"}, "1.0.0", true},
+		{"newer format", Manifest{FormatVersion: FormatVersion + 1, RunnerVersion: "1.0.0"}, "1.0.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCompatibility(&tt.manifest, tt.runner, false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckCompatibility() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestore_NotABackup(t *testing.T) {
+	if _, err := Restore(strings.NewReader("garbage"), t.TempDir(), RestoreOptions{}); err == nil {
+		t.Error("Expected error for invalid archive")
+	}
+}
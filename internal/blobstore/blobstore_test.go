@@ -0,0 +1,281 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testPKI struct {
+	dir    string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	p := &testPKI{dir: t.TempDir()}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ca, _ = x509.ParseCertificate(der)
+	p.caKey = key
+	p.serial = 1
+	p.write(t, "ca.pem", "CERTIFICATE", der)
+	return p
+}
+
+func (p *testPKI) write(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(p.dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// issue creates a certificate signed by the CA and returns its TLS files
+func (p *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage) TLSFiles {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(p.serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return TLSFiles{
+		CertFile: p.write(t, name+".pem", "CERTIFICATE", der),
+		KeyFile:  p.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER),
+		CAFile:   filepath.Join(p.dir, "ca.pem"),
+	}
+}
+
+func startMTLSServer(t *testing.T, pki *testPKI, store Store) string {
+	t.Helper()
+	serverConfig, err := ServerTLSConfig(pki.issue(t, "master", x509.ExtKeyUsageServerAuth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(NewServer(store, t.TempDir()).Handler())
+	srv.TLS = serverConfig
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestClientServer_MTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := startMTLSServer(t, pki, store)
+
+	clientConfig, err := ClientTLSConfig(pki.issue(t, "agent-1", x509.ExtKeyUsageClientAuth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(url, clientConfig)
+
+	src := filepath.Join(t.TempDir(), "build.tar")
+	content := []byte(strings.Repeat("artifact ", 1000))
+	if err := os.WriteFile(src, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	digest, size, err := client.PutFile(ctx, src)
+	if err != nil {
+		t.Fatalf("PutFile failed: %v", err)
+	}
+	if digest != Digest(content) || size != int64(len(content)) {
+		t.Errorf("Unexpected digest %s size %d", digest, size)
+	}
+
+	// Uploading again is a no-op
+	if again, _, err := client.PutFile(ctx, src); err != nil || again != digest {
+		t.Errorf("Expected re-upload to return the same digest, got %s, %v", again, err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "out", "build.tar")
+	if _, err := client.GetFile(ctx, digest, dest, 0600); err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	got, _ := os.ReadFile(dest)
+	if string(got) != string(content) {
+		t.Error("Downloaded content differs")
+	}
+
+	missing := Digest([]byte("missing"))
+	if _, err := client.GetFile(ctx, missing, dest, 0600); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if ok, err := client.Exists(ctx, missing); err != nil || ok {
+		t.Errorf("Expected missing blob, got %v, %v", ok, err)
+	}
+}
+
+func TestClientServer_RejectsClientWithoutCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	store, _ := NewFileStore(t.TempDir())
+	url := startMTLSServer(t, pki, store)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(pki.ca)
+	client := NewClient(url, &tls.Config{RootCAs: pool})
+
+	if _, err := client.Exists(context.Background(), Digest([]byte("x"))); err == nil {
+		t.Error("Expected connection without a client certificate to fail")
+	}
+}
+
+func TestServer_DigestMismatch(t *testing.T) {
+	store, _ := NewFileStore(t.TempDir())
+	srv := httptest.NewServer(NewServer(store, t.TempDir()).Handler())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/v1/blobs", strings.NewReader("content"))
+	req.Header.Set(digestHeader, Digest([]byte("other")))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", resp.StatusCode)
+	}
+	if _, err := store.Stat(context.Background(), Digest([]byte("content"))); !errors.Is(err, ErrNotFound) {
+		t.Error("Expected mismatched upload not to be stored")
+	}
+}
+
+func TestParseDigest(t *testing.T) {
+	valid := Digest([]byte("x"))
+	if _, err := ParseDigest(valid); err != nil {
+		t.Errorf("Expected %s to be valid: %v", valid, err)
+	}
+	if _, err := ParseDigest(strings.TrimPrefix(valid, "sha256:")); err != nil {
+		t.Errorf("Expected bare hex to be valid: %v", err)
+	}
+	for _, bad := range []string{"", "sha256:abc", "sha256:../../etc/passwd", "md5:" + strings.Repeat("0", 64)} {
+		if _, err := ParseDigest(bad); err == nil {
+			t.Errorf("Expected %q to be invalid", bad)
+		}
+	}
+}
+
+// fakeS3 stores objects in memory and checks requests are signed
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		http.Error(w, "unsigned", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "artifacts", Prefix: "sloth", AccessKey: "AKID", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	content := []byte("hello")
+	digest := Digest(content)
+	if _, err := store.Stat(ctx, digest); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound before upload, got %v", err)
+	}
+	if err := store.Put(ctx, digest, strings.NewReader("hello"), int64(len(content))); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	hexPart, _ := ParseDigest(digest)
+	if _, ok := fake.objects["/artifacts/sloth/sha256/"+hexPart]; !ok {
+		t.Errorf("Expected object under the prefix, got %v", fake.objects)
+	}
+
+	body, size, err := store.Open(ctx, digest)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if string(data) != "hello" || size != 5 {
+		t.Errorf("Unexpected content %q size %d", data, size)
+	}
+}
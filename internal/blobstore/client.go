@@ -0,0 +1,187 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client uploads and downloads blobs from a blob server
+type Client struct {
+	endpoint string
+	http     *http.Client
+}
+
+// NewClient creates a client for the server at endpoint, e.g.
+// https://master:50063. tlsConfig may be nil for plain HTTP endpoints.
+func NewClient(endpoint string, tlsConfig *tls.Config) *Client {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "https://" + endpoint
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &Client{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		http:     &http.Client{Transport: transport, Timeout: 30 * time.Minute},
+	}
+}
+
+// Endpoint returns the server address
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// PutFile uploads a file and returns its digest. Blobs the server already
+// holds are not uploaded again.
+func (c *Client) PutFile(ctx context.Context, path string) (string, int64, error) {
+	digest, size, err := FileDigest(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if exists, err := c.Exists(ctx, digest); err != nil {
+		return "", 0, err
+	} else if exists {
+		return digest, size, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.endpoint+blobsPath, f)
+	if err != nil {
+		return "", 0, err
+	}
+	req.ContentLength = size
+	req.Header.Set(digestHeader, digest)
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to upload blob: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", 0, responseError("upload blob", resp)
+	}
+
+	var result PutResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", 0, fmt.Errorf("invalid response from blob server: %w", err)
+	}
+	return result.Digest, result.Size, nil
+}
+
+// GetFile downloads a blob to dest, verifying its digest before dest is
+// written
+func (c *Client) GetFile(ctx context.Context, digest, dest string, mode os.FileMode) (int64, error) {
+	hexPart, err := ParseDigest(digest)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+blobsPath+"/"+digestPrefix+hexPart, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, digest)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, responseError("download blob", resp)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".blob-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create destination: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to download blob: %w", err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != hexPart {
+		return 0, fmt.Errorf("downloaded blob does not match digest %s", digest)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	return size, nil
+}
+
+// Exists reports whether the server holds a blob
+func (c *Client) Exists(ctx context.Context, digest string) (bool, error) {
+	hexPart, err := ParseDigest(digest)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.endpoint+blobsPath+"/"+digestPrefix+hexPart, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach blob server: %w", err)
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to check blob: HTTP %d", resp.StatusCode)
+	}
+}
+
+func responseError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("failed to %s: HTTP %d: %s", action, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+var (
+	globalClient   *Client
+	globalClientMu sync.RWMutex
+)
+
+// SetGlobalClient sets the client used by Lua modules running on this agent
+func SetGlobalClient(client *Client) {
+	globalClientMu.Lock()
+	defer globalClientMu.Unlock()
+	globalClient = client
+}
+
+// GetGlobalClient returns the agent's blob client, or nil if not configured
+func GetGlobalClient() *Client {
+	globalClientMu.RLock()
+	defer globalClientMu.RUnlock()
+	return globalClient
+}
@@ -0,0 +1,154 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/s3"
+)
+
+// S3Config configures an S3-compatible bucket
+type S3Config struct {
+	// Endpoint is the base URL, e.g. https://s3.us-east-1.amazonaws.com
+	// or the address of a MinIO server
+	Endpoint  string
+	Bucket    string
+	Region    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Store keeps blobs in an S3-compatible bucket using path-style requests
+// signed with AWS Signature Version 4
+type S3Store struct {
+	config S3Config
+	signer *s3.Client
+	client *http.Client
+}
+
+// NewS3Store creates a store for the bucket. Credentials not set in
+// config are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func NewS3Store(config S3Config) (*S3Store, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("S3 blob store requires an endpoint and a bucket")
+	}
+	signer, err := s3.NewClient(s3.Config{
+		Endpoint:  config.Endpoint,
+		Region:    config.Region,
+		AccessKey: config.AccessKey,
+		SecretKey: config.SecretKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("S3 blob store: %w", err)
+	}
+	config.Endpoint = signer.Endpoint()
+	return &S3Store{
+		config: config,
+		signer: signer,
+		client: &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+func (s *S3Store) objectURL(digest string) (string, error) {
+	hexPart, err := ParseDigest(digest)
+	if err != nil {
+		return "", err
+	}
+	key := path.Join(s.config.Prefix, "sha256", hexPart)
+	return fmt.Sprintf("%s/%s/%s", s.config.Endpoint, s.config.Bucket, key), nil
+}
+
+// Put uploads a blob. The digest doubles as the payload hash S3 checks.
+func (s *S3Store) Put(ctx context.Context, digest string, r io.Reader, size int64) error {
+	u, err := s.objectURL(digest)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	hexPart, _ := ParseDigest(digest)
+
+	resp, err := s.do(req, hexPart)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+// Open downloads a blob
+func (s *S3Store) Open(ctx context.Context, digest string) (io.ReadCloser, int64, error) {
+	u, err := s.objectURL(digest)
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := s.do(req, s3.EmptyPayloadHash)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, resp.ContentLength, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, 0, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, 0, s3Error(resp)
+	}
+}
+
+// Stat returns the size of a blob
+func (s *S3Store) Stat(ctx context.Context, digest string) (int64, error) {
+	u, err := s.objectURL(digest)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.do(req, s3.EmptyPayloadHash)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.ContentLength, nil
+	case http.StatusNotFound:
+		return 0, ErrNotFound
+	default:
+		return 0, fmt.Errorf("S3 request failed: HTTP %d", resp.StatusCode)
+	}
+}
+
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.signer.Sign(req, payloadHash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+	return resp, nil
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 request failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
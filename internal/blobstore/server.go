@@ -0,0 +1,152 @@
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// digestHeader carries a blob digest in requests and responses
+const digestHeader = "X-Blob-Digest"
+
+const blobsPath = "/v1/blobs"
+
+// PutResult is returned for uploaded blobs
+type PutResult struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// Server serves a Store over HTTP:
+//
+//	PUT  /v1/blobs           upload a blob, returns its digest
+//	GET  /v1/blobs/<digest>  download a blob
+//	HEAD /v1/blobs/<digest>  check whether a blob exists
+type Server struct {
+	store Store
+	// tmpDir holds uploads while their digest is computed
+	tmpDir string
+}
+
+// NewServer creates a server for store, buffering uploads in tmpDir
+func NewServer(store Store, tmpDir string) *Server {
+	return &Server{store: store, tmpDir: tmpDir}
+}
+
+// Handler returns the HTTP handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(blobsPath, s.handleUpload)
+	mux.HandleFunc(blobsPath+"/", s.handleBlob)
+	return mux
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tmp, err := os.CreateTemp(s.tmpDir, "blob-upload-")
+	if err != nil {
+		http.Error(w, "failed to buffer upload", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r.Body)
+	if err != nil {
+		http.Error(w, "failed to read upload", http.StatusBadRequest)
+		return
+	}
+	digest := digestPrefix + hex.EncodeToString(h.Sum(nil))
+
+	if expected := r.Header.Get(digestHeader); expected != "" {
+		hexPart, err := ParseDigest(expected)
+		if err != nil || digestPrefix+hexPart != digest {
+			http.Error(w, "content does not match "+digestHeader, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := s.store.Stat(r.Context(), digest); errors.Is(err, ErrNotFound) {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, "failed to store blob", http.StatusInternalServerError)
+			return
+		}
+		if err := s.store.Put(r.Context(), digest, tmp, size); err != nil {
+			slog.Error("Failed to store blob", "digest", digest, "error", err)
+			http.Error(w, "failed to store blob", http.StatusInternalServerError)
+			return
+		}
+		slog.Info("Stored blob", "digest", digest, "size", size, "client", clientName(r))
+	} else if err != nil {
+		http.Error(w, "failed to check blob", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(PutResult{Digest: digest, Size: size})
+}
+
+func (s *Server) handleBlob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hexPart, err := ParseDigest(strings.TrimPrefix(r.URL.Path, blobsPath+"/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	digest := digestPrefix + hexPart
+
+	if r.Method == http.MethodHead {
+		size, err := s.store.Stat(r.Context(), digest)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to check blob", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(digestHeader, digest)
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		return
+	}
+
+	body, size, err := s.store.Open(r.Context(), digest)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to read blob", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set(digestHeader, digest)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	io.Copy(w, body)
+}
+
+// clientName returns the common name of the client certificate, if any
+func clientName(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,162 @@
+// Package blobstore is a content-addressed store for artifacts that agents
+// exchange during a run. The master serves it over mutually authenticated
+// TLS; blobs are addressed by the SHA-256 digest of their content and kept
+// on local disk or in an S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// digestPrefix is the algorithm prefix of blob digests
+const digestPrefix = "sha256:"
+
+// ErrNotFound is returned for digests the store doesn't hold
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs by digest
+type Store interface {
+	// Put stores size bytes read from r under digest. Callers have
+	// already verified that the content matches the digest.
+	Put(ctx context.Context, digest string, r io.Reader, size int64) error
+	// Open returns the content of a blob and its size
+	Open(ctx context.Context, digest string) (io.ReadCloser, int64, error)
+	// Stat returns the size of a blob
+	Stat(ctx context.Context, digest string) (int64, error)
+}
+
+// Digest returns the digest of data
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// FileDigest returns the digest and size of a file
+func FileDigest(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return digestPrefix + hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// ParseDigest validates a digest and returns its hex part. A bare hex
+// SHA-256 is accepted too.
+func ParseDigest(digest string) (string, error) {
+	hexPart := strings.TrimPrefix(digest, digestPrefix)
+	if len(hexPart) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid digest %q: expected sha256:<64 hex characters>", digest)
+	}
+	if _, err := hex.DecodeString(hexPart); err != nil {
+		return "", fmt.Errorf("invalid digest %q: expected sha256:<64 hex characters>", digest)
+	}
+	return strings.ToLower(hexPart), nil
+}
+
+// FileStore keeps blobs in a local directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store in dir
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Dir returns the directory blobs are stored in
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+func (s *FileStore) path(digest string) (string, error) {
+	hexPart, err := ParseDigest(digest)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, "sha256", hexPart[:2], hexPart), nil
+}
+
+// Put stores a blob, writing it to a temporary file first so readers never
+// see a partial blob
+func (s *FileStore) Put(ctx context.Context, digest string, r io.Reader, size int64) error {
+	path, err := s.path(digest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	if n != size {
+		return fmt.Errorf("failed to store blob: got %d bytes, expected %d", n, size)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open returns the content of a blob
+func (s *FileStore) Open(ctx context.Context, digest string) (io.ReadCloser, int64, error) {
+	path, err := s.path(digest)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// Stat returns the size of a blob
+func (s *FileStore) Stat(ctx context.Context, digest string) (int64, error) {
+	path, err := s.path(digest)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
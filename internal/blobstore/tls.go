@@ -0,0 +1,80 @@
+package blobstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSFiles names the PEM files used for mutual TLS
+type TLSFiles struct {
+	CertFile string
+	KeyFile  string
+	// CAFile verifies the other side: client certificates on the server,
+	// the server certificate on clients
+	CAFile string
+}
+
+// Enabled reports whether any TLS file is set
+func (f TLSFiles) Enabled() bool {
+	return f.CertFile != "" || f.KeyFile != "" || f.CAFile != ""
+}
+
+// ServerTLSConfig returns a config that only accepts clients presenting a
+// certificate signed by the CA
+func ServerTLSConfig(files TLSFiles) (*tls.Config, error) {
+	if files.CertFile == "" || files.KeyFile == "" || files.CAFile == "" {
+		return nil, fmt.Errorf("mTLS requires a certificate, a key and a client CA")
+	}
+	cert, err := tls.LoadX509KeyPair(files.CertFile, files.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	pool, err := loadCAPool(files.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ClientTLSConfig returns a config presenting the client certificate and
+// trusting servers signed by the CA
+func ClientTLSConfig(files TLSFiles) (*tls.Config, error) {
+	if files.CertFile == "" || files.KeyFile == "" {
+		return nil, fmt.Errorf("mTLS requires a client certificate and key")
+	}
+	cert, err := tls.LoadX509KeyPair(files.CertFile, files.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if files.CAFile != "" {
+		pool, err := loadCAPool(files.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+func loadCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,232 @@
+// Package certs obtains certificates from ACME CAs such as Let's Encrypt
+// and installs them on the hosts that serve them.
+package certs
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Directories of the Let's Encrypt CA
+const (
+	LetsEncrypt        = "https://acme-v02.api.letsencrypt.org/directory"
+	LetsEncryptStaging = "https://acme-staging-v02.api.letsencrypt.org/directory"
+)
+
+// Solver answers the ACME challenges of one type, proving control of the
+// domains of a certificate
+type Solver interface {
+	// Type is the challenge type, http-01 or dns-01
+	Type() string
+	// Present makes the response to the challenge of domain visible to
+	// the CA. keyAuth is the key authorization of the challenge's token.
+	Present(ctx context.Context, domain, token, keyAuth string) error
+	// CleanUp removes what Present set up
+	CleanUp(ctx context.Context, domain, token, keyAuth string) error
+}
+
+// Request is a certificate to obtain
+type Request struct {
+	// Domains are the names of the certificate, the first one being its
+	// common name. Wildcards need a dns-01 solver.
+	Domains []string
+	// Email is the contact of the ACME account, optional
+	Email string
+	// Directory is the directory URL of the CA, Let's Encrypt by default
+	Directory string
+	// AccountKey is the key of the ACME account, registered on first use
+	AccountKey crypto.Signer
+	// KeyType is the type of the certificate's key: ec256 (default),
+	// ec384, rsa2048 or rsa4096
+	KeyType string
+	Solver  Solver
+}
+
+// Obtain orders a certificate for the domains of req, answering the
+// challenges of the CA with the request's solver
+func Obtain(ctx context.Context, req Request) (*Certificate, error) {
+	if len(req.Domains) == 0 {
+		return nil, errors.New("no domains to certify")
+	}
+	key, err := NewKey(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	directory := req.Directory
+	if directory == "" {
+		directory = LetsEncrypt
+	}
+	client := &acme.Client{Key: req.AccountKey, DirectoryURL: directory, UserAgent: "sloth-runner"}
+
+	account := &acme.Account{}
+	if req.Email != "" {
+		account.Contact = []string{"mailto:" + req.Email}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("failed to register the ACME account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(req.Domains...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to order the certificate: %w", err)
+	}
+	// Authorizations are answered one at a time: a domain and its
+	// wildcard share the TXT record of their dns-01 challenges
+	for _, url := range order.AuthzURLs {
+		if err := authorize(ctx, client, url, req.Solver); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, fmt.Errorf("order not ready: %w", err)
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: req.Domains[0]},
+		DNSNames: req.Domains,
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize the order: %w", err)
+	}
+
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(certPEM, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+// authorize answers the challenge of an authorization with solver, unless
+// the CA still holds the authorization as valid from an earlier order
+func authorize(ctx context.Context, client *acme.Client, url string, solver Solver) error {
+	authz, err := client.GetAuthorization(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to get authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	domain := authz.Identifier.Value
+	var challenge *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == solver.Type() {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("the CA offers no %s challenge for %s", solver.Type(), domain)
+	}
+
+	var keyAuth string
+	switch challenge.Type {
+	case "http-01":
+		keyAuth, err = client.HTTP01ChallengeResponse(challenge.Token)
+	case "dns-01":
+		keyAuth, err = client.DNS01ChallengeRecord(challenge.Token)
+	default:
+		err = fmt.Errorf("unsupported challenge type %s", challenge.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := solver.Present(ctx, domain, challenge.Token, keyAuth); err != nil {
+		return fmt.Errorf("failed to present the %s challenge of %s: %w", challenge.Type, domain, err)
+	}
+	defer solver.CleanUp(ctx, domain, challenge.Token, keyAuth)
+	if _, err := client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("failed to accept the %s challenge of %s: %w", challenge.Type, domain, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("%s not authorized: %w", domain, err)
+	}
+	return nil
+}
+
+// NewKey generates a certificate key of the given type: ec256 (default),
+// ec384, rsa2048 or rsa4096
+func NewKey(keyType string) (crypto.Signer, error) {
+	switch strings.ToLower(keyType) {
+	case "", "ec256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ec384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "rsa2048":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "rsa4096":
+		return rsa.GenerateKey(rand.Reader, 4096)
+	default:
+		return nil, fmt.Errorf("unknown key type %q: expected ec256, ec384, rsa2048 or rsa4096", keyType)
+	}
+}
+
+// LoadAccountKey reads the ACME account key at path, creating it the first
+// time. The key is the account: losing it means registering a new one.
+func LoadAccountKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+			return nil, fmt.Errorf("failed to save the ACME account key: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseKey(data)
+}
+
+// parseKey parses a PEM private key in PKCS #8, SEC 1 or PKCS #1 form
+func parseKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM private key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported private key format")
+}
@@ -0,0 +1,131 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Certificate is a certificate chain, leaf first, with its private key
+// when it's known
+type Certificate struct {
+	CertPEM []byte
+	KeyPEM  []byte
+	Leaf    *x509.Certificate
+}
+
+// Parse parses a PEM certificate chain and, when keyPEM isn't empty, the
+// private key it must belong to
+func Parse(certPEM, keyPEM []byte) (*Certificate, error) {
+	block, rest := pem.Decode(certPEM)
+	for block != nil && block.Type != "CERTIFICATE" {
+		block, rest = pem.Decode(rest)
+	}
+	if block == nil {
+		return nil, errors.New("no PEM certificate found")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	if len(keyPEM) > 0 {
+		if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+			return nil, fmt.Errorf("certificate and key don't match: %w", err)
+		}
+	}
+	return &Certificate{CertPEM: certPEM, KeyPEM: keyPEM, Leaf: leaf}, nil
+}
+
+// Load reads an installed certificate and its key. With keyPath empty the
+// key is looked for in the certificate file, as Install writes it.
+func Load(certPath, keyPath string) (*Certificate, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyData := data
+	if keyPath != "" {
+		if keyData, err = os.ReadFile(keyPath); err != nil {
+			return nil, err
+		}
+	}
+	var certPEM, keyPEM []byte
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			certPEM = append(certPEM, pem.EncodeToMemory(block)...)
+		}
+	}
+	for block, rest := pem.Decode(keyData); block != nil; block, rest = pem.Decode(rest) {
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			keyPEM = pem.EncodeToMemory(block)
+			break
+		}
+	}
+	return Parse(certPEM, keyPEM)
+}
+
+// Domains returns the DNS names of the certificate, or its common name
+// when it has none
+func (c *Certificate) Domains() []string {
+	if len(c.Leaf.DNSNames) > 0 {
+		return c.Leaf.DNSNames
+	}
+	return []string{c.Leaf.Subject.CommonName}
+}
+
+// Covers reports whether the certificate holds every one of domains
+func (c *Certificate) Covers(domains []string) bool {
+	names := make(map[string]bool)
+	for _, name := range c.Domains() {
+		names[strings.ToLower(name)] = true
+	}
+	for _, domain := range domains {
+		if !names[strings.ToLower(domain)] {
+			return false
+		}
+	}
+	return true
+}
+
+// DaysLeft returns the whole days left until the certificate expires,
+// negative once it has
+func (c *Certificate) DaysLeft(now time.Time) int {
+	return DaysLeft(c.Leaf, now)
+}
+
+// DaysLeft returns the whole days left until cert expires, negative once
+// it has
+func DaysLeft(cert *x509.Certificate, now time.Time) int {
+	left := cert.NotAfter.Sub(now)
+	if left < 0 {
+		return -int((-left).Hours()/24) - 1
+	}
+	return int(left.Hours() / 24)
+}
+
+// Fetch returns the certificate a TLS server at addr (host:port) serves.
+// It isn't verified: expired or self-signed certificates are returned too.
+func Fetch(ctx context.Context, addr string) (*x509.Certificate, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, addr = addr, net.JoinHostPort(addr, "443")
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: host, InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s served no certificate", addr)
+	}
+	return certs[0], nil
+}
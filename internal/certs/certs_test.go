@@ -0,0 +1,368 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCA is an ACME CA checking challenges with verify and issuing
+// certificates valid for a day. It doesn't check request signatures.
+type fakeCA struct {
+	srv    *httptest.Server
+	verify func(challengeType, domain, token string) error
+
+	mu     sync.Mutex
+	key    *ecdsa.PrivateKey
+	cert   *x509.Certificate
+	authzs []*fakeAuthz
+	orders []*fakeOrder
+}
+
+type fakeAuthz struct {
+	domain   string
+	wildcard bool
+	token    string
+	status   string
+}
+
+type fakeOrder struct {
+	identifiers []map[string]string
+	authzs      []int
+	chain       []byte
+}
+
+func newFakeCA(t *testing.T, verify func(challengeType, domain, token string) error) *fakeCA {
+	ca := &fakeCA{verify: verify}
+	ca.key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fake CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &ca.key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.cert, _ = x509.ParseCertificate(der)
+	ca.srv = httptest.NewServer(http.HandlerFunc(ca.handle))
+	t.Cleanup(ca.srv.Close)
+	return ca
+}
+
+func (ca *fakeCA) directory() string { return ca.srv.URL + "/directory" }
+
+func (ca *fakeCA) handle(w http.ResponseWriter, r *http.Request) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	w.Header().Set("Replay-Nonce", fmt.Sprint(time.Now().UnixNano()))
+	url := ca.srv.URL
+
+	var payload []byte
+	if r.Method == http.MethodPost {
+		var jws struct{ Payload string }
+		json.NewDecoder(r.Body).Decode(&jws)
+		payload, _ = base64.RawURLEncoding.DecodeString(jws.Payload)
+	}
+	reply := func(status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	var id int
+	var kind string
+	switch {
+	case r.URL.Path == "/directory":
+		reply(http.StatusOK, map[string]string{"newNonce": url + "/nonce", "newAccount": url + "/account", "newOrder": url + "/order"})
+	case r.URL.Path == "/nonce":
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == "/account":
+		w.Header().Set("Location", url+"/account/1")
+		reply(http.StatusCreated, map[string]string{"status": "valid"})
+	case r.URL.Path == "/order":
+		var req struct{ Identifiers []map[string]string }
+		json.Unmarshal(payload, &req)
+		order := &fakeOrder{identifiers: req.Identifiers}
+		for _, ident := range req.Identifiers {
+			domain := ident["value"]
+			order.authzs = append(order.authzs, len(ca.authzs))
+			ca.authzs = append(ca.authzs, &fakeAuthz{
+				domain:   strings.TrimPrefix(domain, "*."),
+				wildcard: strings.HasPrefix(domain, "*."),
+				token:    fmt.Sprintf("token%d", len(ca.authzs)),
+				status:   "pending",
+			})
+		}
+		ca.orders = append(ca.orders, order)
+		w.Header().Set("Location", fmt.Sprintf("%s/order/%d", url, len(ca.orders)-1))
+		reply(http.StatusCreated, ca.orderJSON(len(ca.orders)-1))
+	case scan(r.URL.Path, "/authz/%d", &id):
+		a := ca.authzs[id]
+		var challenges []map[string]string
+		for _, typ := range []string{"http-01", "dns-01"} {
+			challenges = append(challenges, map[string]string{"type": typ, "url": fmt.Sprintf("%s/challenge/%d/%s", url, id, typ), "token": a.token, "status": a.status})
+		}
+		reply(http.StatusOK, map[string]interface{}{
+			"identifier": map[string]string{"type": "dns", "value": a.domain},
+			"status":     a.status,
+			"wildcard":   a.wildcard,
+			"challenges": challenges,
+		})
+	case scan(r.URL.Path, "/challenge/%d/%s", &id, &kind):
+		a := ca.authzs[id]
+		a.status = "valid"
+		if err := ca.verify(kind, a.domain, a.token); err != nil {
+			a.status = "invalid"
+		}
+		reply(http.StatusOK, map[string]string{"type": kind, "url": url + r.URL.Path, "token": a.token, "status": a.status})
+	case scan(r.URL.Path, "/order/%d", &id):
+		w.Header().Set("Location", url+r.URL.Path)
+		reply(http.StatusOK, ca.orderJSON(id))
+	case scan(r.URL.Path, "/finalize/%d", &id):
+		var req struct{ CSR string }
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			reply(http.StatusBadRequest, map[string]string{"type": "urn:ietf:params:acme:error:badCSR", "detail": err.Error()})
+			return
+		}
+		leaf := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(id) + 2),
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(24 * time.Hour),
+		}
+		leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca.cert, csr.PublicKey, ca.key)
+		if err != nil {
+			reply(http.StatusInternalServerError, map[string]string{"detail": err.Error()})
+			return
+		}
+		ca.orders[id].chain = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+			pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})...)
+		w.Header().Set("Location", fmt.Sprintf("%s/order/%d", url, id))
+		reply(http.StatusOK, ca.orderJSON(id))
+	case scan(r.URL.Path, "/cert/%d", &id):
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(ca.orders[id].chain)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func scan(path, format string, args ...interface{}) bool {
+	n, err := fmt.Sscanf(path, format, args...)
+	return err == nil && n == len(args)
+}
+
+func (ca *fakeCA) orderJSON(id int) map[string]interface{} {
+	order := ca.orders[id]
+	status := "ready"
+	var authzURLs []string
+	for _, a := range order.authzs {
+		authzURLs = append(authzURLs, fmt.Sprintf("%s/authz/%d", ca.srv.URL, a))
+		switch ca.authzs[a].status {
+		case "invalid":
+			status = "invalid"
+		case "pending":
+			if status != "invalid" {
+				status = "pending"
+			}
+		}
+	}
+	v := map[string]interface{}{
+		"status":         status,
+		"identifiers":    order.identifiers,
+		"authorizations": authzURLs,
+		"finalize":       fmt.Sprintf("%s/finalize/%d", ca.srv.URL, id),
+	}
+	if order.chain != nil {
+		v["status"] = "valid"
+		v["certificate"] = fmt.Sprintf("%s/cert/%d", ca.srv.URL, id)
+	}
+	return v
+}
+
+func TestObtain_HTTP01(t *testing.T) {
+	webroot := t.TempDir()
+	var checked []string
+	ca := newFakeCA(t, func(challengeType, domain, token string) error {
+		data, err := os.ReadFile(filepath.Join(webroot, ".well-known", "acme-challenge", token))
+		if challengeType != "http-01" || err != nil || !strings.HasPrefix(string(data), token+".") {
+			return fmt.Errorf("challenge not answered")
+		}
+		checked = append(checked, domain)
+		return nil
+	})
+	accountKey, err := LoadAccountKey(filepath.Join(t.TempDir(), "acme", "account.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := Obtain(context.Background(), Request{
+		Domains:    []string{"example.com", "www.example.com"},
+		Email:      "ops@example.com",
+		Directory:  ca.directory(),
+		AccountKey: accountKey,
+		Solver:     &HTTPSolver{Webroot: webroot},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cert.Covers([]string{"www.example.com", "example.com"}) || cert.Covers([]string{"api.example.com"}) {
+		t.Errorf("Unexpected domains %v", cert.Domains())
+	}
+	if days := cert.DaysLeft(time.Now()); days != 0 {
+		t.Errorf("Expected less than a day left, got %d", days)
+	}
+	if len(checked) != 2 {
+		t.Errorf("Expected both domains to be checked, got %v", checked)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(webroot, ".well-known", "acme-challenge")); len(entries) != 0 {
+		t.Errorf("Expected the challenge files to be removed, got %d", len(entries))
+	}
+	if _, err := Parse(cert.CertPEM, cert.KeyPEM); err != nil {
+		t.Errorf("Expected the key to match the certificate: %v", err)
+	}
+}
+
+func TestObtain_DNS01Wildcard(t *testing.T) {
+	var mu sync.Mutex
+	records := make(map[string]string)
+	var names []string
+	ca := newFakeCA(t, func(challengeType, domain, token string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if challengeType != "dns-01" || records["_acme-challenge."+domain] == "" {
+			return fmt.Errorf("no TXT record for %s", domain)
+		}
+		return nil
+	})
+	accountKey, _ := LoadAccountKey(filepath.Join(t.TempDir(), "account.key"))
+	solver := &DNSSolver{
+		SetTXT: func(_ context.Context, fqdn, value string, present bool) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if present {
+				records[fqdn] = value
+				names = append(names, fqdn)
+			} else {
+				delete(records, fqdn)
+			}
+			return nil
+		},
+		LookupTXT: func(_ context.Context, name string) ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return []string{records[name]}, nil
+		},
+	}
+
+	cert, err := Obtain(context.Background(), Request{
+		Domains:    []string{"example.com", "*.example.com"},
+		Directory:  ca.directory(),
+		AccountKey: accountKey,
+		KeyType:    "rsa2048",
+		Solver:     solver,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cert.Covers([]string{"*.example.com"}) {
+		t.Errorf("Expected the wildcard, got %v", cert.Domains())
+	}
+	if len(names) != 2 || names[0] != "_acme-challenge.example.com" || names[1] != names[0] || len(records) != 0 {
+		t.Errorf("Expected both challenges on one record, removed after, got %v and %v", names, records)
+	}
+}
+
+func TestObtain_ChallengeFails(t *testing.T) {
+	ca := newFakeCA(t, func(string, string, string) error { return fmt.Errorf("unreachable") })
+	accountKey, _ := LoadAccountKey(filepath.Join(t.TempDir(), "account.key"))
+	_, err := Obtain(context.Background(), Request{
+		Domains:    []string{"example.com"},
+		Directory:  ca.directory(),
+		AccountKey: accountKey,
+		Solver:     &HTTPSolver{Webroot: t.TempDir()},
+	})
+	if err == nil || !strings.Contains(err.Error(), "example.com not authorized") {
+		t.Errorf("Expected the authorization to fail, got %v", err)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	ca := newFakeCA(t, func(string, string, string) error { return nil })
+	accountKey, _ := LoadAccountKey(filepath.Join(t.TempDir(), "account.key"))
+	cert, err := Obtain(context.Background(), Request{
+		Domains:    []string{"example.com"},
+		Directory:  ca.directory(),
+		AccountKey: accountKey,
+		Solver:     &HTTPSolver{Webroot: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	opts := InstallOptions{CertPath: filepath.Join(dir, "tls", "example.crt"), KeyPath: filepath.Join(dir, "tls", "example.key")}
+	if changed, _ := Install(cert, InstallOptions{CertPath: opts.CertPath, KeyPath: opts.KeyPath, DryRun: true}); len(changed) != 2 {
+		t.Errorf("Expected a dry run to report both files, got %v", changed)
+	}
+	if _, err := os.Stat(opts.CertPath); !os.IsNotExist(err) {
+		t.Error("Expected a dry run to write nothing")
+	}
+	if changed, err := Install(cert, opts); err != nil || len(changed) != 2 {
+		t.Fatalf("Expected both files to be written, got %v, %v", changed, err)
+	}
+	if info, _ := os.Stat(opts.KeyPath); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the key to be private, got %v", info.Mode())
+	}
+	if changed, _ := Install(cert, opts); len(changed) != 0 {
+		t.Errorf("Expected an installed certificate to change nothing, got %v", changed)
+	}
+	os.Chmod(opts.CertPath, 0666)
+	if changed, _ := Install(cert, opts); len(changed) != 1 || changed[0] != opts.CertPath {
+		t.Errorf("Expected the mode of the certificate to be fixed, got %v", changed)
+	}
+
+	combined := filepath.Join(dir, "haproxy.pem")
+	if _, err := Install(cert, InstallOptions{CertPath: combined}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(combined)
+	if !strings.Contains(string(data), "CERTIFICATE") || !strings.Contains(string(data), "PRIVATE KEY") {
+		t.Error("Expected the combined file to hold the chain and the key")
+	}
+
+	for _, paths := range [][2]string{{opts.CertPath, opts.KeyPath}, {combined, ""}} {
+		loaded, err := Load(paths[0], paths[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(loaded.CertPEM) != string(cert.CertPEM) || string(loaded.KeyPEM) != string(cert.KeyPEM) {
+			t.Errorf("Expected %s to load as installed", paths[0])
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package certs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// InstallOptions tell where and how to install a certificate
+type InstallOptions struct {
+	// CertPath receives the certificate chain, KeyPath the private key.
+	// The key goes along the chain in CertPath when KeyPath is empty.
+	CertPath string
+	KeyPath  string
+	// Owner and Group own both files when set
+	Owner string
+	Group string
+	// CertMode defaults to 0644, KeyMode to 0600
+	CertMode os.FileMode
+	KeyMode  os.FileMode
+	// DryRun only works out what would change
+	DryRun bool
+}
+
+// Install writes the certificate and its key to their paths with their
+// owner and modes. It returns the paths it changed, or would change in a
+// dry run; files already as wanted are left alone.
+func Install(cert *Certificate, opts InstallOptions) ([]string, error) {
+	if opts.CertPath == "" {
+		return nil, fmt.Errorf("cert_path is required")
+	}
+	if len(cert.KeyPEM) == 0 {
+		return nil, fmt.Errorf("the certificate has no private key")
+	}
+	if opts.CertMode == 0 {
+		opts.CertMode = 0644
+	}
+	if opts.KeyMode == 0 {
+		opts.KeyMode = 0600
+	}
+	uid, gid, err := lookupOwner(opts.Owner, opts.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		path    string
+		content []byte
+		mode    os.FileMode
+	}{{opts.KeyPath, cert.KeyPEM, opts.KeyMode}, {opts.CertPath, cert.CertPEM, opts.CertMode}}
+	if opts.KeyPath == "" {
+		// A combined file holds the key, so it gets its mode
+		files = files[1:]
+		files[0].content = append(append([]byte(nil), cert.CertPEM...), cert.KeyPEM...)
+		files[0].mode = opts.KeyMode
+	}
+
+	var changed []string
+	for _, f := range files {
+		if installed(f.path, f.content, f.mode, uid, gid) {
+			continue
+		}
+		changed = append(changed, f.path)
+		if opts.DryRun {
+			continue
+		}
+		if err := writeFile(f.path, f.content, f.mode, uid, gid); err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// installed reports whether the file at path has content, mode and owner
+func installed(path string, content []byte, mode os.FileMode, uid, gid int) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != mode {
+		return false
+	}
+	if fileUID, fileGID, ok := fileOwner(info); ok && (uid >= 0 && fileUID != uid || gid >= 0 && fileGID != gid) {
+		return false
+	}
+	current, err := os.ReadFile(path)
+	return err == nil && bytes.Equal(current, content)
+}
+
+// writeFile replaces the file at path through a rename, so services never
+// read half a certificate, with the mode and owner set before it's visible
+func writeFile(path string, content []byte, mode os.FileMode, uid, gid int) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if uid >= 0 || gid >= 0 {
+		if err := os.Chown(tmp.Name(), uid, gid); err != nil {
+			return fmt.Errorf("failed to set the owner of %s: %w", path, err)
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// lookupOwner returns the uid and gid of owner and group, -1 for the ones
+// not set
+func lookupOwner(owner, group string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+	if owner != "" {
+		u, err := user.Lookup(owner)
+		if err != nil {
+			return uid, gid, err
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return uid, gid, fmt.Errorf("user %s has no numeric uid", owner)
+		}
+	}
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			return uid, gid, err
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return uid, gid, fmt.Errorf("group %s has no numeric gid", group)
+		}
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package certs
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the uid and gid owning a file
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
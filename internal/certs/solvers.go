@@ -0,0 +1,159 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPSolver answers http-01 challenges, with files under the document
+// root of a web server already listening on port 80, or by listening
+// itself when there's none
+type HTTPSolver struct {
+	// Webroot is the document root the files go to, under
+	// .well-known/acme-challenge
+	Webroot string
+	// Listen is the address to serve the challenges on without a webroot,
+	// :80 by default
+	Listen string
+
+	mu        sync.Mutex
+	responses map[string]string
+	server    *http.Server
+}
+
+// Type implements Solver
+func (s *HTTPSolver) Type() string { return "http-01" }
+
+// Present implements Solver
+func (s *HTTPSolver) Present(ctx context.Context, domain, token, keyAuth string) error {
+	if s.Webroot != "" {
+		dir := filepath.Join(s.Webroot, ".well-known", "acme-challenge")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, token), []byte(keyAuth), 0644)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.responses == nil {
+		s.responses = make(map[string]string)
+	}
+	s.responses[token] = keyAuth
+	if s.server != nil {
+		return nil
+	}
+	addr := s.Listen
+	if addr == "" {
+		addr = ":80"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for http-01 challenges: %w", err)
+	}
+	s.server = &http.Server{Handler: http.HandlerFunc(s.serve), ReadHeaderTimeout: 10 * time.Second}
+	go s.server.Serve(listener)
+	return nil
+}
+
+func (s *HTTPSolver) serve(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")
+	s.mu.Lock()
+	keyAuth, ok := s.responses[token]
+	s.mu.Unlock()
+	if !ok || token == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
+
+// CleanUp implements Solver. The listener is closed with the last token.
+func (s *HTTPSolver) CleanUp(ctx context.Context, domain, token, keyAuth string) error {
+	if s.Webroot != "" {
+		return os.Remove(filepath.Join(s.Webroot, ".well-known", "acme-challenge", token))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.responses, token)
+	if len(s.responses) == 0 && s.server != nil {
+		err := s.server.Close()
+		s.server = nil
+		return err
+	}
+	return nil
+}
+
+// TXTFunc sets the TXT record at fqdn to value, or removes it when
+// present is false, at a DNS provider
+type TXTFunc func(ctx context.Context, fqdn, value string, present bool) error
+
+// DNSSolver answers dns-01 challenges with TXT records set through a DNS
+// provider
+type DNSSolver struct {
+	SetTXT TXTFunc
+	// Propagation is how long to wait for the record to be visible to
+	// resolvers before the CA is asked to check it, 2 minutes by default
+	Propagation time.Duration
+	// LookupTXT resolves TXT records, net.DefaultResolver by default
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+// Type implements Solver
+func (s *DNSSolver) Type() string { return "dns-01" }
+
+// ChallengeName returns the name of the TXT record of the dns-01
+// challenge of domain
+func ChallengeName(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(domain, "*.")
+}
+
+// Present implements Solver. It returns once resolvers see the record,
+// or after Propagation, leaving it to the CA to tell.
+func (s *DNSSolver) Present(ctx context.Context, domain, token, keyAuth string) error {
+	name := ChallengeName(domain)
+	if err := s.SetTXT(ctx, name, keyAuth, true); err != nil {
+		return err
+	}
+
+	lookup := s.LookupTXT
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupTXT
+	}
+	propagation := s.Propagation
+	if propagation == 0 {
+		propagation = 2 * time.Minute
+	}
+	deadline := time.Now().Add(propagation)
+	for {
+		values, _ := lookup(ctx, name)
+		for _, v := range values {
+			if v == keyAuth {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			slog.Warn("challenge record not visible yet, asking the CA anyway", "name", name, "waited", propagation)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// CleanUp implements Solver
+func (s *DNSSolver) CleanUp(ctx context.Context, domain, token, keyAuth string) error {
+	return s.SetTXT(ctx, ChallengeName(domain), keyAuth, false)
+}
@@ -0,0 +1,29 @@
+package changemgmt
+
+import (
+	"context"
+	"net/url"
+)
+
+// defaultTopic is the Backstage events topic runs are posted to
+const defaultTopic = "sloth-runner"
+
+// backstage posts an event for each phase of a run to the HTTP ingress of
+// the Backstage events backend, where entity providers and notification
+// modules subscribed to the topic pick it up
+type backstage struct {
+	client *client
+	topic  string
+}
+
+func (b *backstage) post(ctx context.Context, fields map[string]string) error {
+	return b.client.do(ctx, "POST", "/api/events/http/"+url.PathEscape(b.topic), fields, nil)
+}
+
+func (b *backstage) start(ctx context.Context, fields map[string]string) (string, error) {
+	return "", b.post(ctx, fields)
+}
+
+func (b *backstage) finish(ctx context.Context, ref string, fields map[string]string) error {
+	return b.post(ctx, fields)
+}
@@ -0,0 +1,316 @@
+// Package changemgmt reports runs to change-management systems: it creates
+// a ServiceNow record or posts a Backstage event when a run starts, and
+// updates or posts again when it finishes or fails, so these systems
+// reflect automated changes.
+//
+// Integrations are configured in <data-dir>/integrations.yaml. The fields
+// sent for each phase are Go templates of the Run.
+package changemgmt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Phases of a run that are reported
+const (
+	PhaseStart   = "start"
+	PhaseFinish  = "finish"
+	PhaseFailure = "failure"
+)
+
+func validPhase(phase string) bool {
+	return phase == PhaseStart || phase == PhaseFinish || phase == PhaseFailure
+}
+
+// Run is what templates see of a run
+type Run struct {
+	ID       string
+	Workflow string
+	Stack    string
+	File     string
+	User     string
+	Host     string
+	Started  time.Time
+	// Status is running, succeeded or failed
+	Status string
+	// Set once the run finished
+	Error       string
+	Duration    time.Duration
+	TasksTotal  int
+	TasksFailed int
+}
+
+// SecretFunc looks up a secret by name
+type SecretFunc func(name string) (string, bool)
+
+// requestTimeout bounds each call to an integration
+const requestTimeout = 30 * time.Second
+
+// adapter talks to one kind of system. start returns a reference to the
+// record it created, which finish gets back ("" when start wasn't
+// reported or failed).
+type adapter interface {
+	start(ctx context.Context, fields map[string]string) (string, error)
+	finish(ctx context.Context, ref string, fields map[string]string) error
+}
+
+// Notifier reports one run to the integrations that match it
+type Notifier struct {
+	run   *Run
+	sinks []*sink
+	// Warnf reports integrations that failed, other than a required
+	// integration failing to report the start
+	Warnf func(format string, args ...interface{})
+}
+
+type sink struct {
+	integration Integration
+	adapter     adapter
+	ref         string
+	// err is why the integration can't be used, e.g. a missing secret
+	err error
+}
+
+// NewNotifier prepares to report run to the integrations of config whose
+// stacks and workflows match it. config may be nil.
+func NewNotifier(config *Config, run *Run, secrets SecretFunc) *Notifier {
+	n := &Notifier{run: run, Warnf: func(format string, args ...interface{}) {
+		slog.Warn(fmt.Sprintf(format, args...))
+	}}
+	if config == nil {
+		return n
+	}
+	for _, in := range config.Integrations {
+		if !in.matches(run.Stack, run.Workflow) {
+			continue
+		}
+		s := &sink{integration: in}
+		client, err := newClient(in, secrets)
+		if err != nil {
+			s.err = err
+		} else if in.Type == TypeServiceNow {
+			s.adapter = &serviceNow{client: client, table: orDefault(in.Table, defaultTable)}
+		} else {
+			s.adapter = &backstage{client: client, topic: orDefault(in.Topic, defaultTopic)}
+		}
+		n.sinks = append(n.sinks, s)
+	}
+	return n
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// Integrations returns the names of the integrations the run is reported to
+func (n *Notifier) Integrations() []string {
+	names := make([]string, len(n.sinks))
+	for i, s := range n.sinks {
+		names[i] = s.integration.Name
+	}
+	return names
+}
+
+// Start reports that the run starts. It returns an error when a required
+// integration failed; the run shouldn't go ahead then.
+func (n *Notifier) Start(ctx context.Context) error {
+	n.run.Status = "running"
+	var failed []string
+	for _, s := range n.sinks {
+		err := s.err
+		if err == nil {
+			var fields map[string]string
+			if fields, err = s.fields(PhaseStart, n.run); err == nil {
+				s.ref, err = s.adapter.start(ctx, fields)
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if s.integration.Required {
+			failed = append(failed, fmt.Sprintf("%s: %v", s.integration.Name, err))
+		} else {
+			n.Warnf("Failed to report run start to %s: %v", s.integration.Name, err)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("required integrations failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// SetTasks sets how many tasks the run ran and how many of them failed
+func (n *Notifier) SetTasks(total, failed int) {
+	n.run.TasksTotal = total
+	n.run.TasksFailed = failed
+}
+
+// Finish reports how the run ended. runErr is the error it failed with.
+func (n *Notifier) Finish(ctx context.Context, duration time.Duration, runErr error) {
+	n.run.Duration = duration
+	n.run.Status = "succeeded"
+	phase := PhaseFinish
+	if runErr != nil {
+		n.run.Status = "failed"
+		n.run.Error = runErr.Error()
+		phase = PhaseFailure
+	}
+	for _, s := range n.sinks {
+		if s.err != nil {
+			continue
+		}
+		fields, err := s.fields(phase, n.run)
+		if err == nil {
+			err = s.adapter.finish(ctx, s.ref, fields)
+		}
+		if err != nil {
+			n.Warnf("Failed to report run %s to %s: %v", n.run.Status, s.integration.Name, err)
+		}
+	}
+}
+
+// fields renders the fields of a phase. Failures use the finish fields
+// when no failure fields are configured, and phases without fields send
+// the defaults of the integration type.
+func (s *sink) fields(phase string, run *Run) (map[string]string, error) {
+	templates, ok := s.integration.On[phase]
+	if !ok && phase == PhaseFailure {
+		templates, ok = s.integration.On[PhaseFinish]
+	}
+	if !ok {
+		templates = defaultFields(s.integration.Type, phase)
+	}
+
+	fields := make(map[string]string, len(templates))
+	for name, text := range templates {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, run); err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		fields[name] = buf.String()
+	}
+	return fields, nil
+}
+
+// defaultFields are sent for phases an integration configures no fields for
+func defaultFields(kind, phase string) map[string]string {
+	if kind == TypeBackstage {
+		return map[string]string{
+			"event":    "run." + phase,
+			"run_id":   "{{.ID}}",
+			"workflow": "{{.Workflow}}",
+			"stack":    "{{.Stack}}",
+			"status":   "{{.Status}}",
+			"user":     "{{.User}}",
+			"host":     "{{.Host}}",
+			"started":  "{{.Started.Format \"2006-01-02T15:04:05Z07:00\"}}",
+			"duration": "{{.Duration}}",
+			"error":    "{{.Error}}",
+		}
+	}
+	switch phase {
+	case PhaseStart:
+		return map[string]string{
+			"short_description": "sloth-runner: {{.Workflow}} on stack {{.Stack}}",
+			"description":       "Run {{.ID}} of {{.File}} started by {{.User}} on {{.Host}}",
+		}
+	case PhaseFailure:
+		return map[string]string{
+			"work_notes": "Run {{.ID}} failed after {{.Duration}} ({{.TasksFailed}} of {{.TasksTotal}} tasks failed): {{.Error}}",
+		}
+	}
+	return map[string]string{
+		"work_notes": "Run {{.ID}} succeeded in {{.Duration}} ({{.TasksTotal}} tasks)",
+	}
+}
+
+// client sends authenticated JSON requests to an integration
+type client struct {
+	baseURL  string
+	http     *http.Client
+	username string
+	password string
+	token    string
+}
+
+func newClient(in Integration, secrets SecretFunc) (*client, error) {
+	c := &client{
+		baseURL:  strings.TrimRight(in.URL, "/"),
+		http:     &http.Client{Timeout: requestTimeout},
+		username: in.Auth.Username,
+	}
+	var err error
+	if in.Auth.PasswordSecret != "" {
+		c.password, err = lookupSecret(secrets, in.Auth.PasswordSecret)
+	}
+	if in.Auth.TokenSecret != "" {
+		c.token, err = lookupSecret(secrets, in.Auth.TokenSecret)
+	}
+	return c, err
+}
+
+func lookupSecret(secrets SecretFunc, name string) (string, error) {
+	if secrets != nil {
+		if value, ok := secrets(name); ok {
+			return value, nil
+		}
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("secret %s not found in the stack's secrets or the environment", name)
+}
+
+// do sends body as JSON and decodes the JSON response into out, when out
+// isn't nil
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package changemgmt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// request is a call an integration received
+type request struct {
+	Method, Path, Auth string
+	Body               map[string]string
+}
+
+func recordRequests(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, func() []request) {
+	var mu sync.Mutex
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, request{r.Method, r.URL.Path, r.Header.Get("Authorization"), body})
+		mu.Unlock()
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request(nil), requests...)
+	}
+}
+
+func testRun() *Run {
+	return &Run{
+		ID:       "run-1",
+		Workflow: "deploy",
+		Stack:    "prod",
+		File:     "deploy.sloth",
+		User:     "alice",
+		Host:     "ci-01",
+		Started:  time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestNotifier_ServiceNow(t *testing.T) {
+	server, requests := recordRequests(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result": {"sys_id": "abc123", "number": "CHG0001"}}`)
+	})
+	config := &Config{Integrations: []Integration{{
+		Name: "snow",
+		Type: TypeServiceNow,
+		URL:  server.URL,
+		Auth: Auth{Username: "svc", PasswordSecret: "SNOW_PASSWORD"},
+		On: map[string]map[string]string{
+			"start":  {"short_description": "Deploy {{.Workflow}} to {{.Stack}}"},
+			"finish": {"state": "3", "close_notes": "{{.Status}}: {{.Error}}"},
+		},
+	}}}
+	secrets := func(name string) (string, bool) { return "s3cret", name == "SNOW_PASSWORD" }
+
+	n := NewNotifier(config, testRun(), secrets)
+	if err := n.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	n.Finish(context.Background(), time.Minute, errors.New("task web failed"))
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 requests, got %+v", got)
+	}
+	if got[0].Method != "POST" || got[0].Path != "/api/now/table/change_request" || got[0].Body["short_description"] != "Deploy deploy to prod" {
+		t.Errorf("Unexpected start request %+v", got[0])
+	}
+	if !strings.HasPrefix(got[0].Auth, "Basic ") {
+		t.Errorf("Expected basic auth, got %q", got[0].Auth)
+	}
+	// Failures use the finish fields when no failure fields are set
+	if got[1].Method != "PATCH" || got[1].Path != "/api/now/table/change_request/abc123" || got[1].Body["close_notes"] != "failed: task web failed" {
+		t.Errorf("Unexpected finish request %+v", got[1])
+	}
+}
+
+func TestNotifier_Backstage(t *testing.T) {
+	server, requests := recordRequests(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	t.Setenv("BACKSTAGE_TOKEN", "tok")
+	config := &Config{Integrations: []Integration{{
+		Name:  "catalog",
+		Type:  TypeBackstage,
+		URL:   server.URL + "/",
+		Topic: "changes",
+		Auth:  Auth{TokenSecret: "BACKSTAGE_TOKEN"},
+	}}}
+
+	n := NewNotifier(config, testRun(), nil)
+	if err := n.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	n.Finish(context.Background(), 2*time.Second, nil)
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 requests, got %+v", got)
+	}
+	for _, r := range got {
+		if r.Path != "/api/events/http/changes" || r.Auth != "Bearer tok" {
+			t.Errorf("Unexpected request %+v", r)
+		}
+	}
+	if got[0].Body["event"] != "run.start" || got[0].Body["status"] != "running" || got[0].Body["started"] != "2026-10-17T10:00:00Z" {
+		t.Errorf("Unexpected start event %+v", got[0].Body)
+	}
+	if got[1].Body["event"] != "run.finish" || got[1].Body["status"] != "succeeded" || got[1].Body["duration"] != "2s" {
+		t.Errorf("Unexpected finish event %+v", got[1].Body)
+	}
+}
+
+func TestNotifier_Required(t *testing.T) {
+	server, _ := recordRequests(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no access", http.StatusForbidden)
+	})
+	config := &Config{Integrations: []Integration{
+		{Name: "optional", Type: TypeBackstage, URL: server.URL},
+		{Name: "missing-secret", Type: TypeBackstage, URL: server.URL, Auth: Auth{TokenSecret: "NO_SUCH_SECRET_FOR_TEST"}},
+		{Name: "required", Type: TypeServiceNow, URL: server.URL, Required: true},
+	}}
+
+	n := NewNotifier(config, testRun(), nil)
+	var warnings []string
+	n.Warnf = func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	err := n.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "required: POST /api/now/table/change_request: 403") {
+		t.Errorf("Expected the required integration to fail the start, got %v", err)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[1], "secret NO_SUCH_SECRET_FOR_TEST not found") {
+		t.Errorf("Expected warnings for the other integrations, got %q", warnings)
+	}
+}
+
+func TestNotifier_Filters(t *testing.T) {
+	config := &Config{Integrations: []Integration{
+		{Name: "prod", Type: TypeBackstage, URL: "https://b", Stacks: []string{"prod*"}},
+		{Name: "staging", Type: TypeBackstage, URL: "https://b", Stacks: []string{"staging"}},
+		{Name: "deploys", Type: TypeBackstage, URL: "https://b", Workflows: []string{"deploy-*"}},
+	}}
+	n := NewNotifier(config, testRun(), nil)
+	if got := n.Integrations(); len(got) != 1 || got[0] != "prod" {
+		t.Errorf("Expected only the prod integration, got %v", got)
+	}
+}
+
+func TestDefaultFields_ServiceNow(t *testing.T) {
+	s := &sink{integration: Integration{Type: TypeServiceNow}}
+	run := testRun()
+	run.Error, run.Duration, run.TasksTotal, run.TasksFailed = "boom", time.Minute, 3, 1
+
+	fields, err := s.fields(PhaseFailure, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Run run-1 failed after 1m0s (1 of 3 tasks failed): boom"
+	if fields["work_notes"] != want {
+		t.Errorf("Expected %q, got %q", want, fields["work_notes"])
+	}
+}
@@ -0,0 +1,148 @@
+package changemgmt
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Integration types
+const (
+	TypeServiceNow = "servicenow"
+	TypeBackstage  = "backstage"
+)
+
+// Config lists the systems runs are reported to
+type Config struct {
+	Integrations []Integration `yaml:"integrations"`
+}
+
+// Integration is a system the start and end of runs are reported to
+type Integration struct {
+	Name string `yaml:"name"`
+	// Type is servicenow or backstage
+	Type string `yaml:"type"`
+	// URL is the instance, e.g. https://acme.service-now.com
+	URL string `yaml:"url"`
+	// Table is the ServiceNow table records are created in
+	// (default change_request)
+	Table string `yaml:"table"`
+	// Topic is the Backstage events topic posted to (default sloth-runner)
+	Topic string `yaml:"topic"`
+	Auth  Auth   `yaml:"auth"`
+	// Stacks and Workflows limit the runs reported; * and ? work like in
+	// shell globs. Every run is reported when empty.
+	Stacks    []string `yaml:"stacks"`
+	Workflows []string `yaml:"workflows"`
+	// Required integrations keep a run from starting when its start can't
+	// be reported
+	Required bool `yaml:"required"`
+	// On holds the fields sent for each phase (start, finish, failure) as
+	// Go templates of the run
+	On map[string]map[string]string `yaml:"on"`
+}
+
+// Auth names the secrets an integration authenticates with. Secrets are
+// looked up in the stack's secrets, then in the environment.
+type Auth struct {
+	// Username and PasswordSecret authenticate with HTTP basic auth
+	Username       string `yaml:"username"`
+	PasswordSecret string `yaml:"password_secret"`
+	// TokenSecret authenticates with a bearer token
+	TokenSecret string `yaml:"token_secret"`
+}
+
+// LoadConfig reads an integrations file. It returns nil without an error
+// when the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read integrations file: %w", err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid integrations file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates the YAML of an integrations file
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for i, in := range config.Integrations {
+		if in.Name == "" {
+			return nil, fmt.Errorf("integration %d has no name", i+1)
+		}
+		if names[in.Name] {
+			return nil, fmt.Errorf("duplicate integration %s", in.Name)
+		}
+		names[in.Name] = true
+		if err := in.validate(); err != nil {
+			return nil, fmt.Errorf("integration %s: %w", in.Name, err)
+		}
+	}
+	return &config, nil
+}
+
+func (in *Integration) validate() error {
+	switch in.Type {
+	case TypeServiceNow, TypeBackstage:
+	default:
+		return fmt.Errorf("unknown type %q, expected servicenow or backstage", in.Type)
+	}
+	u, err := url.Parse(in.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %q is not an http(s) URL", in.URL)
+	}
+	if in.Auth.PasswordSecret != "" && in.Auth.TokenSecret != "" {
+		return errors.New("auth takes either password_secret or token_secret")
+	}
+	if (in.Auth.Username == "") != (in.Auth.PasswordSecret == "") {
+		return errors.New("auth needs both username and password_secret")
+	}
+	for _, pattern := range append(append([]string{}, in.Stacks...), in.Workflows...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+	}
+	for phase, fields := range in.On {
+		if !validPhase(phase) {
+			return fmt.Errorf("unknown phase %q, expected start, finish or failure", phase)
+		}
+		for name, text := range fields {
+			if _, err := template.New(name).Option("missingkey=error").Parse(text); err != nil {
+				return fmt.Errorf("%s field %s: %w", phase, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the run of workflow in stack is reported
+func (in *Integration) matches(stack, workflow string) bool {
+	return matchAny(in.Stacks, stack) && matchAny(in.Workflows, workflow)
+}
+
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
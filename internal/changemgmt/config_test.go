@@ -0,0 +1,80 @@
+package changemgmt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+integrations:
+  - name: snow
+    type: servicenow
+    url: https://acme.service-now.com
+    auth:
+      username: svc-sloth
+      password_secret: SNOW_PASSWORD
+    stacks: ["prod-*"]
+    required: true
+    on:
+      start:
+        short_description: "Deploy {{.Workflow}}"
+      failure:
+        close_code: unsuccessful
+  - name: catalog
+    type: backstage
+    url: https://backstage.acme.com
+    auth:
+      token_secret: BACKSTAGE_TOKEN
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Integrations) != 2 {
+		t.Fatalf("Expected 2 integrations, got %d", len(config.Integrations))
+	}
+	snow := config.Integrations[0]
+	if !snow.Required || snow.On["start"]["short_description"] != "Deploy {{.Workflow}}" {
+		t.Errorf("Unexpected integration %+v", snow)
+	}
+	if !snow.matches("prod-eu", "deploy") || snow.matches("staging", "deploy") {
+		t.Error("Expected only prod stacks to match")
+	}
+	if !config.Integrations[1].matches("staging", "deploy") {
+		t.Error("Expected integrations without patterns to match every run")
+	}
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no name":       "integrations: [{type: backstage, url: https://b}]",
+		"duplicate":     "integrations: [{name: a, type: backstage, url: https://b}, {name: a, type: backstage, url: https://b}]",
+		"unknown type":  "integrations: [{name: a, type: jira, url: https://j}]",
+		"bad url":       "integrations: [{name: a, type: backstage, url: backstage.local}]",
+		"both auths":    "integrations: [{name: a, type: backstage, url: https://b, auth: {username: u, password_secret: P, token_secret: T}}]",
+		"no username":   "integrations: [{name: a, type: servicenow, url: https://s, auth: {password_secret: P}}]",
+		"unknown phase": "integrations: [{name: a, type: backstage, url: https://b, on: {done: {x: y}}}]",
+		"bad template":  "integrations: [{name: a, type: backstage, url: https://b, on: {start: {x: '{{.ID'}}}]",
+		"bad pattern":   "integrations: [{name: a, type: backstage, url: https://b, stacks: ['[']}]",
+	}
+	for name, data := range tests {
+		if _, err := ParseConfig([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(filepath.Join(t.TempDir(), "integrations.yaml"))
+	if config != nil || err != nil {
+		t.Errorf("Expected no config for a missing file, got %v, %v", config, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "integrations.yaml")
+	os.WriteFile(path, []byte("integrations: [{name: a, type: jira}]"), 0644)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+}
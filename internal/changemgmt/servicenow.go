@@ -0,0 +1,51 @@
+package changemgmt
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+)
+
+// defaultTable is the ServiceNow table records are created in
+const defaultTable = "change_request"
+
+// serviceNow creates a record through the Table API when a run starts and
+// updates it when the run ends
+type serviceNow struct {
+	client *client
+	table  string
+}
+
+type tableResponse struct {
+	Result struct {
+		SysID  string `json:"sys_id"`
+		Number string `json:"number"`
+	} `json:"result"`
+}
+
+func (s *serviceNow) path() string {
+	return "/api/now/table/" + url.PathEscape(s.table)
+}
+
+func (s *serviceNow) start(ctx context.Context, fields map[string]string) (string, error) {
+	var resp tableResponse
+	if err := s.client.do(ctx, "POST", s.path(), fields, &resp); err != nil {
+		return "", err
+	}
+	if resp.Result.SysID == "" {
+		return "", errors.New("no sys_id in the created record")
+	}
+	slog.Info("Created ServiceNow record", "table", s.table, "number", resp.Result.Number, "sys_id", resp.Result.SysID)
+	return resp.Result.SysID, nil
+}
+
+// finish updates the record of the run, or creates one when the start
+// wasn't recorded
+func (s *serviceNow) finish(ctx context.Context, ref string, fields map[string]string) error {
+	if ref == "" {
+		_, err := s.start(ctx, fields)
+		return err
+	}
+	return s.client.do(ctx, "PATCH", s.path()+"/"+url.PathEscape(ref), fields, nil)
+}
@@ -4,14 +4,14 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // RegisterWatcherOnAgent registers a watcher on a remote agent via gRPC
 func RegisterWatcherOnAgent(ctx context.Context, agentAddr string, config *pb.WatcherConfig) (*pb.RegisterWatcherResponse, error) {
-	conn, err := grpc.NewClient(agentAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(agentAddr, mtls.DialOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to agent: %w", err)
 	}
@@ -28,7 +28,7 @@ func RegisterWatcherOnAgent(ctx context.Context, agentAddr string, config *pb.Wa
 
 // ListWatchersOnAgent lists all watchers on a remote agent via gRPC
 func ListWatchersOnAgent(ctx context.Context, agentAddr string) (*pb.ListWatchersResponse, error) {
-	conn, err := grpc.NewClient(agentAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(agentAddr, mtls.DialOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to agent: %w", err)
 	}
@@ -42,7 +42,7 @@ func ListWatchersOnAgent(ctx context.Context, agentAddr string) (*pb.ListWatcher
 
 // RemoveWatcherFromAgent removes a watcher from a remote agent via gRPC
 func RemoveWatcherFromAgent(ctx context.Context, agentAddr string, watcherID string) (*pb.RemoveWatcherResponse, error) {
-	conn, err := grpc.NewClient(agentAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(agentAddr, mtls.DialOption())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to agent: %w", err)
 	}
@@ -100,6 +100,129 @@ func GetHistoryDBPath() string {
 	return filepath.Join(GetDataDir(), "history.db")
 }
 
+// GetOutputKeyPath returns the key sensitive run outputs are sealed with
+func GetOutputKeyPath() string {
+	return filepath.Join(GetDataDir(), "output.key")
+}
+
+// GetRunLogDBPath returns the full path to the run log store
+func GetRunLogDBPath() string {
+	return filepath.Join(GetDataDir(), "runlogs.db")
+}
+
+// GetScheduleStatsDBPath returns the full path to the scheduled run stats database
+func GetScheduleStatsDBPath() string {
+	return filepath.Join(GetDataDir(), "schedule_stats.db")
+}
+
+// GetSchedulesDBPath returns the full path to the workflow schedules database
+func GetSchedulesDBPath() string {
+	return filepath.Join(GetDataDir(), "schedules.db")
+}
+
+// GetGitOpsDBPath returns the full path to the gitops sync database
+func GetGitOpsDBPath() string {
+	return filepath.Join(GetDataDir(), "gitops.db")
+}
+
+// GetGitOpsCheckoutDir returns the directory the gitops repository is
+// checked out in
+func GetGitOpsCheckoutDir() string {
+	return filepath.Join(GetDataDir(), "gitops", "checkout")
+}
+
+// GetTokensDBPath returns the full path to the API token database
+func GetTokensDBPath() string {
+	return filepath.Join(GetDataDir(), "tokens.db")
+}
+
+// GetJoinTokensDBPath returns the full path to the agent join token database
+func GetJoinTokensDBPath() string {
+	return filepath.Join(GetDataDir(), "join_tokens.db")
+}
+
+// GetAgentCredentialPath returns the file an agent keeps the credential the
+// master issued for it in
+func GetAgentCredentialPath(agentName string) string {
+	return filepath.Join(GetDataDir(), "credentials", agentName)
+}
+
+// GetArtifactCacheDir returns the directory for the agent artifact cache
+func GetArtifactCacheDir() string {
+	return filepath.Join(GetDataDir(), "artifact-cache")
+}
+
+// GetBlobDir returns the directory for the master's blob store
+func GetBlobDir() string {
+	return filepath.Join(GetDataDir(), "blobs")
+}
+
+// GetACMEDir returns the directory of the ACME account keys the certs
+// module registers with CAs
+func GetACMEDir() string {
+	return filepath.Join(GetDataDir(), "acme")
+}
+
+// GetShellRecordingDir returns the directory agents record shell sessions to
+func GetShellRecordingDir() string {
+	return filepath.Join(GetDataDir(), "shell-recordings")
+}
+
+// GetRunJournalDir returns the directory of the journals of the runs in
+// progress, read by the master to recover runs interrupted by it stopping
+func GetRunJournalDir() string {
+	return filepath.Join(GetDataDir(), "run-journal")
+}
+
+// GetTLSDir returns the directory of the mTLS CA and certificates
+func GetTLSDir() string {
+	return filepath.Join(GetDataDir(), "tls")
+}
+
+// GetConfigPath returns the general settings file, e.g. download limits
+func GetConfigPath() string {
+	return filepath.Join(GetDataDir(), "config.yaml")
+}
+
+// GetPolicyPath returns the default policy file for runs and agents
+func GetPolicyPath() string {
+	return filepath.Join(GetDataDir(), "policies.yaml")
+}
+
+// GetAgentConfigPath returns the settings file an agent reads at start and
+// re-reads on reload
+func GetAgentConfigPath() string {
+	return filepath.Join(GetDataDir(), "agent.yaml")
+}
+
+// GetTriggersPath returns the event trigger file read by the master
+func GetTriggersPath() string {
+	return filepath.Join(GetDataDir(), "triggers.yaml")
+}
+
+// GetWatchesPath returns the file listing the workflow files whose watches
+// the master checks
+func GetWatchesPath() string {
+	return filepath.Join(GetDataDir(), "watches.yaml")
+}
+
+// GetSudoConfigPath returns the file naming the agents whose sudo needs a
+// password
+func GetSudoConfigPath() string {
+	return filepath.Join(GetDataDir(), "sudo.yaml")
+}
+
+// GetIntegrationsPath returns the file configuring the change-management
+// systems runs are reported to
+func GetIntegrationsPath() string {
+	return filepath.Join(GetDataDir(), "integrations.yaml")
+}
+
+// GetTelemetryDir returns the directory for usage stats settings and payloads
+func GetTelemetryDir() string {
+	return filepath.Join(GetDataDir(), "telemetry")
+}
+
 // GetLogDir returns the directory for log files
 func GetLogDir() string {
 	return filepath.Join(GetDataDir(), "logs")
@@ -0,0 +1,295 @@
+// Package conformance runs the conformance suites of modules against
+// disposable containers of several distributions, so changes to a module
+// can be checked on every distribution it supports before they ship.
+package conformance
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:embed suites/*.sloth
+var suitesFS embed.FS
+
+// suitesDir is where the suites are copied to in the containers
+const suitesDir = "/opt/sloth-conformance"
+
+// Suite is the conformance suite of a module, a workflow run with the
+// sloth-runner binary under test inside the container
+type Suite struct {
+	Name string
+	// Systemd is set for suites that need systemd running as PID 1
+	Systemd bool
+	Script  []byte
+}
+
+// Suites returns the embedded suites, by name
+func Suites() ([]Suite, error) {
+	entries, err := suitesFS.ReadDir("suites")
+	if err != nil {
+		return nil, err
+	}
+	var suites []Suite
+	for _, e := range entries {
+		script, err := suitesFS.ReadFile(path.Join("suites", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(e.Name(), ".sloth")
+		suites = append(suites, Suite{Name: name, Systemd: name == "systemd", Script: script})
+	}
+	return suites, nil
+}
+
+// Distro is a distribution suites run against
+type Distro struct {
+	Name  string
+	Image string
+	// Systemd is set for images that boot systemd as PID 1. They run
+	// privileged, with the host's cgroups, and their own command.
+	Systemd bool
+}
+
+// Distros are the distributions suites run against by default: one of
+// each package manager the pkg module drives
+var Distros = []Distro{
+	{Name: "debian-12", Image: "geerlingguy/docker-debian12-ansible", Systemd: true},
+	{Name: "ubuntu-24.04", Image: "geerlingguy/docker-ubuntu2404-ansible", Systemd: true},
+	{Name: "rockylinux-9", Image: "geerlingguy/docker-rockylinux9-ansible", Systemd: true},
+	{Name: "fedora-40", Image: "geerlingguy/docker-fedora40-ansible", Systemd: true},
+	{Name: "archlinux", Image: "archlinux:latest"},
+	{Name: "opensuse-leap-15.6", Image: "opensuse/leap:15.6"},
+}
+
+// FindDistro returns the distribution named name, or a custom one given
+// as name=image
+func FindDistro(name string) (Distro, error) {
+	if n, image, ok := strings.Cut(name, "="); ok {
+		return Distro{Name: n, Image: image}, nil
+	}
+	for _, d := range Distros {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	var names []string
+	for _, d := range Distros {
+		names = append(names, d.Name)
+	}
+	return Distro{}, fmt.Errorf("unknown distro %q: expected one of %s, or name=image", name, strings.Join(names, ", "))
+}
+
+// Status is the outcome of a suite on a distribution
+type Status string
+
+const (
+	Passed  Status = "passed"
+	Failed  Status = "failed"
+	Skipped Status = "skipped"
+)
+
+// Result is the outcome of a suite on a distribution
+type Result struct {
+	Distro   string
+	Suite    string
+	Status   Status
+	Duration time.Duration
+	// Output is what the run of the suite printed, or why it didn't run
+	Output string
+}
+
+// Runner runs a command of the container engine, returning its combined
+// output
+type Runner func(ctx context.Context, args ...string) ([]byte, error)
+
+// Options tell which suites run against which distributions, and how
+type Options struct {
+	// Engine is the container engine, docker (default) or podman
+	Engine string
+	// Binary is the sloth-runner binary copied into the containers. It
+	// must run there: build it with CGO_ENABLED=0 for older or non-glibc
+	// distributions.
+	Binary  string
+	Distros []Distro
+	Suites  []Suite
+	// Parallel is how many distributions are tested at once, 1 by default
+	Parallel int
+	// Keep leaves the containers and snapshot images behind for debugging
+	Keep bool
+	// Progress, when set, is called as each suite finishes
+	Progress func(Result)
+	// Run runs the engine's commands, exec by default
+	Run Runner
+}
+
+// RunSuites runs every suite against every distribution. Each
+// distribution is prepared once, with the binary copied in, and
+// snapshotted to an image each suite starts a fresh container from, so
+// suites can't affect each other. Results are ordered by distribution,
+// then suite.
+func RunSuites(ctx context.Context, opts Options) []Result {
+	if opts.Engine == "" {
+		opts.Engine = "docker"
+	}
+	if opts.Run == nil {
+		opts.Run = func(ctx context.Context, args ...string) ([]byte, error) {
+			return exec.CommandContext(ctx, opts.Engine, args...).CombinedOutput()
+		}
+	}
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	// The suites are copied into the containers from files
+	dir, err := os.MkdirTemp("", "sloth-conformance-")
+	if err != nil {
+		return failAll(opts, opts.Distros, err)
+	}
+	defer os.RemoveAll(dir)
+	for _, s := range opts.Suites {
+		if err := os.WriteFile(filepath.Join(dir, s.Name+".sloth"), s.Script, 0644); err != nil {
+			return failAll(opts, opts.Distros, err)
+		}
+	}
+
+	var mu sync.Mutex
+	var results []Result
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	for _, d := range opts.Distros {
+		wg.Add(1)
+		go func(d Distro) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			for _, r := range runDistro(ctx, opts, d, dir) {
+				if opts.Progress != nil {
+					opts.Progress(r)
+				}
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	order := make(map[string]int)
+	for i, d := range opts.Distros {
+		order["d:"+d.Name] = i
+	}
+	for i, s := range opts.Suites {
+		order["s:"+s.Name] = i
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Distro != b.Distro {
+			return order["d:"+a.Distro] < order["d:"+b.Distro]
+		}
+		return order["s:"+a.Suite] < order["s:"+b.Suite]
+	})
+	return results
+}
+
+// runDistro prepares the snapshot of a distribution, with the binary and
+// the suites of dir copied in, and runs the suites against it
+func runDistro(ctx context.Context, opts Options, d Distro, dir string) []Result {
+	id := uuid.New().String()[:8]
+	snapshot := "sloth-conformance/" + d.Name + ":" + id
+	fail := func(err error) []Result {
+		return failAll(opts, []Distro{d}, err)
+	}
+
+	base := "sloth-conformance-" + d.Name + "-" + id
+	if err := startContainer(ctx, opts, d, base, d.Image); err != nil {
+		return fail(err)
+	}
+	if !opts.Keep {
+		defer opts.Run(context.Background(), "rm", "-f", base)
+	}
+	if out, err := opts.Run(ctx, "cp", opts.Binary, base+":/usr/local/bin/sloth-runner"); err != nil {
+		return fail(fmt.Errorf("failed to copy the binary into %s: %v: %s", d.Image, err, strings.TrimSpace(string(out))))
+	}
+	if out, err := opts.Run(ctx, "cp", dir+"/.", base+":"+suitesDir); err != nil {
+		return fail(fmt.Errorf("failed to copy the suites into %s: %v: %s", d.Image, err, strings.TrimSpace(string(out))))
+	}
+	if out, err := opts.Run(ctx, "commit", base, snapshot); err != nil {
+		return fail(fmt.Errorf("failed to snapshot %s: %v: %s", d.Image, err, strings.TrimSpace(string(out))))
+	}
+	if !opts.Keep {
+		defer opts.Run(context.Background(), "rmi", "-f", snapshot)
+	}
+
+	var results []Result
+	for _, s := range opts.Suites {
+		results = append(results, runSuite(ctx, opts, d, s, snapshot, base+"-"+s.Name))
+	}
+	return results
+}
+
+// runSuite runs a suite in a fresh container of the snapshot
+func runSuite(ctx context.Context, opts Options, d Distro, s Suite, snapshot, name string) (result Result) {
+	result = Result{Distro: d.Name, Suite: s.Name}
+	if s.Systemd && !d.Systemd {
+		result.Status, result.Output = Skipped, d.Image+" doesn't run systemd"
+		return result
+	}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	if err := startContainer(ctx, opts, d, name, snapshot); err != nil {
+		result.Status, result.Output = Failed, err.Error()
+		return result
+	}
+	if !opts.Keep {
+		defer opts.Run(context.Background(), "rm", "-f", name)
+	}
+	if d.Systemd {
+		// Degraded systems exit non-zero but run units fine
+		opts.Run(ctx, "exec", name, "systemctl", "is-system-running", "--wait")
+	}
+
+	out, err := opts.Run(ctx, "exec", name, "sloth-runner", "run", "conformance", "--file", suitesDir+"/"+s.Name+".sloth", "--local", "--yes", "--no-ansi")
+	result.Status, result.Output = Passed, string(out)
+	if err != nil {
+		result.Status = Failed
+	}
+	return result
+}
+
+// failAll fails every suite on distros with err
+func failAll(opts Options, distros []Distro, err error) []Result {
+	var results []Result
+	for _, d := range distros {
+		for _, s := range opts.Suites {
+			results = append(results, Result{Distro: d.Name, Suite: s.Name, Status: Failed, Output: err.Error()})
+		}
+	}
+	return results
+}
+
+// startContainer starts a detached container of image
+func startContainer(ctx context.Context, opts Options, d Distro, name, image string) error {
+	args := []string{"run", "-d", "--name", name}
+	if d.Systemd {
+		args = append(args, "--privileged", "--cgroupns=host", "-v", "/sys/fs/cgroup:/sys/fs/cgroup:rw", image)
+	} else {
+		args = append(args, "--entrypoint", "sleep", image, "infinity")
+	}
+	if out, err := opts.Run(ctx, args...); err != nil {
+		return fmt.Errorf("failed to start %s: %v: %s", image, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSuites(t *testing.T) {
+	suites, err := Suites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range suites {
+		names = append(names, s.Name)
+		L := lua.NewState()
+		if _, err := L.LoadString(string(s.Script)); err != nil {
+			t.Errorf("Suite %s doesn't parse: %v", s.Name, err)
+		}
+		L.Close()
+		if s.Systemd != (s.Name == "systemd") {
+			t.Errorf("Expected only the systemd suite to need systemd, got %s", s.Name)
+		}
+	}
+	if got := strings.Join(names, ","); got != "file,pkg,systemd,user" {
+		t.Errorf("Expected the file, pkg, systemd and user suites, got %s", got)
+	}
+}
+
+func TestFindDistro(t *testing.T) {
+	if d, err := FindDistro("debian-12"); err != nil || !d.Systemd {
+		t.Errorf("Expected debian-12 to boot systemd, got %+v, %v", d, err)
+	}
+	if d, err := FindDistro("alpine=alpine:3.20"); err != nil || d.Image != "alpine:3.20" || d.Systemd {
+		t.Errorf("Expected a custom distro, got %+v, %v", d, err)
+	}
+	if _, err := FindDistro("windows"); err == nil {
+		t.Error("Expected an unknown distro to fail")
+	}
+}
+
+// fakeEngine records the commands run, failing those starting with any
+// of fail
+type fakeEngine struct {
+	mu       sync.Mutex
+	commands []string
+	fail     []string
+}
+
+func (e *fakeEngine) run(ctx context.Context, args ...string) ([]byte, error) {
+	command := strings.Join(args, " ")
+	e.mu.Lock()
+	e.commands = append(e.commands, command)
+	e.mu.Unlock()
+	for _, f := range e.fail {
+		if strings.HasPrefix(command, f) {
+			return []byte("boom"), errors.New("exit status 1")
+		}
+	}
+	return []byte("ok"), nil
+}
+
+func (e *fakeEngine) count(prefix string) int {
+	n := 0
+	for _, c := range e.commands {
+		if strings.HasPrefix(c, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRunSuites(t *testing.T) {
+	engine := &fakeEngine{fail: []string{"run -d --name sloth-conformance-broken-"}}
+	suites := []Suite{{Name: "pkg"}, {Name: "systemd", Systemd: true}}
+	distros := []Distro{
+		{Name: "debian", Image: "debian-systemd", Systemd: true},
+		{Name: "arch", Image: "archlinux"},
+		{Name: "broken", Image: "missing"},
+	}
+	var progress int
+	results := RunSuites(context.Background(), Options{
+		Binary:   "/bin/sloth-runner",
+		Distros:  distros,
+		Suites:   suites,
+		Parallel: 2,
+		Progress: func(Result) { progress++ },
+		Run:      engine.run,
+	})
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Distro+"/"+r.Suite+"="+string(r.Status))
+	}
+	want := "debian/pkg=passed debian/systemd=passed arch/pkg=passed arch/systemd=skipped broken/pkg=failed broken/systemd=failed"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, " "))
+	}
+	if progress != len(results) {
+		t.Errorf("Expected progress for each of the %d results, got %d", len(results), progress)
+	}
+
+	// Each working distro is snapshotted once, and each suite that runs
+	// gets its own container, removed afterwards with the snapshots
+	if n := engine.count("commit "); n != 2 {
+		t.Errorf("Expected 2 snapshots, got %d", n)
+	}
+	if n := engine.count("exec "); n != 5 {
+		t.Errorf("Expected 3 suite runs and 2 waits for systemd, got %d execs", n)
+	}
+	if n := engine.count("rm -f "); n != 5 {
+		t.Errorf("Expected 5 containers removed, got %d", n)
+	}
+	if n := engine.count("rmi -f "); n != 2 {
+		t.Errorf("Expected 2 snapshots removed, got %d", n)
+	}
+	for _, c := range engine.commands {
+		if strings.HasPrefix(c, "run -d --name sloth-conformance-arch-") && !strings.Contains(c, "--entrypoint sleep") {
+			t.Errorf("Expected containers without systemd to idle, got %s", c)
+		}
+		if strings.HasPrefix(c, "run -d --name sloth-conformance-debian-") && !strings.Contains(c, "--privileged") {
+			t.Errorf("Expected systemd containers to run privileged, got %s", c)
+		}
+	}
+}
+
+func TestRunSuites_FailedSuite(t *testing.T) {
+	engine := &fakeEngine{fail: []string{"exec sloth-conformance-arch-"}}
+	results := RunSuites(context.Background(), Options{
+		Binary:  "/bin/sloth-runner",
+		Distros: []Distro{{Name: "arch", Image: "archlinux"}},
+		Suites:  []Suite{{Name: "user"}},
+		Keep:    true,
+		Run:     engine.run,
+	})
+	if len(results) != 1 || results[0].Status != Failed || results[0].Output != "boom" {
+		t.Fatalf("Expected the suite to fail with its output, got %+v", results)
+	}
+	if engine.count("rm") != 0 {
+		t.Error("Expected containers and snapshots to be kept")
+	}
+}
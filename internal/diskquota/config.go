@@ -0,0 +1,188 @@
+package diskquota
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/download"
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults of the disk section
+const (
+	DefaultWarnPercent = 80
+	DefaultInterval    = 10 * time.Minute
+)
+
+// Config is the disk section of config.yaml:
+//
+//	disk:
+//	  quota: 20GB
+//	  warn_percent: 80
+//	  components:
+//	    logs: 2GB
+//	    runlogs: 5GB
+//	  prune:
+//	    history: 90d
+//	    run_logs: 30d
+type Config struct {
+	// Quota is the most the whole data directory may take, e.g. "20GB"
+	Quota string `yaml:"quota"`
+	// Components are quotas of parts of the data directory: databases,
+	// logs, artifacts, other, or a database by name, e.g. history
+	Components map[string]string `yaml:"components"`
+	// WarnPercent is the share of a quota at which a warning is raised
+	WarnPercent int `yaml:"warn_percent"`
+	// Interval is how often the master checks, e.g. "10m"
+	Interval string `yaml:"interval"`
+	// Prune is how long data is kept once a quota is exceeded, by kind:
+	// history, run_logs, events, metrics or logs
+	Prune map[string]string `yaml:"prune"`
+
+	limits    []limit
+	interval  time.Duration
+	retention map[string]time.Duration
+}
+
+// limit is a parsed quota
+type limit struct {
+	name  string
+	bytes int64
+}
+
+// fileConfig is the part of config.yaml read by this package
+type fileConfig struct {
+	Disk *Config `yaml:"disk"`
+}
+
+// LoadConfig reads the disk section of a config file. It returns nil when
+// the file or the section is missing.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid disk section in %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates the disk section of a config file
+func ParseConfig(data []byte) (*Config, error) {
+	var fc fileConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return nil, err
+	}
+	if fc.Disk == nil {
+		return nil, nil
+	}
+	if err := fc.Disk.validate(); err != nil {
+		return nil, err
+	}
+	return fc.Disk, nil
+}
+
+func (c *Config) validate() error {
+	if c.Quota != "" {
+		bytes, err := parseSize(c.Quota)
+		if err != nil {
+			return fmt.Errorf("quota: %w", err)
+		}
+		c.limits = append(c.limits, limit{name: Total, bytes: bytes})
+	}
+	names := make([]string, 0, len(c.Components))
+	for name := range c.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == Total {
+			return fmt.Errorf("components: use quota to limit the whole data directory")
+		}
+		bytes, err := parseSize(c.Components[name])
+		if err != nil {
+			return fmt.Errorf("components: %s: %w", name, err)
+		}
+		c.limits = append(c.limits, limit{name: name, bytes: bytes})
+	}
+	if len(c.limits) == 0 {
+		return fmt.Errorf("set quota or components")
+	}
+
+	if c.WarnPercent == 0 {
+		c.WarnPercent = DefaultWarnPercent
+	}
+	if c.WarnPercent < 1 || c.WarnPercent > 100 {
+		return fmt.Errorf("warn_percent must be between 1 and 100")
+	}
+
+	c.interval = DefaultInterval
+	if c.Interval != "" {
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid interval %q", c.Interval)
+		}
+		c.interval = interval
+	}
+
+	c.retention = make(map[string]time.Duration, len(c.Prune))
+	for kind, value := range c.Prune {
+		retention, err := parseRetention(value)
+		if err != nil {
+			return fmt.Errorf("prune: %s: %w", kind, err)
+		}
+		c.retention[kind] = retention
+	}
+	return nil
+}
+
+// CheckInterval is how often the data directory is measured
+func (c *Config) CheckInterval() time.Duration {
+	return c.interval
+}
+
+// parseSize parses a size like "20GB" or "512MiB"
+func parseSize(s string) (int64, error) {
+	if strings.HasSuffix(strings.TrimSpace(s), "/s") {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	bytes, err := download.ParseRate(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if bytes <= 0 {
+		return 0, fmt.Errorf("size must be positive")
+	}
+	return bytes, nil
+}
+
+// parseRetention parses a retention like "30d" or "12h"
+func parseRetention(s string) (time.Duration, error) {
+	var retention time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		retention = time.Duration(n * float64(24*time.Hour))
+	} else {
+		var err error
+		if retention, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+	}
+	if retention <= 0 {
+		return 0, fmt.Errorf("retention must be positive")
+	}
+	return retention, nil
+}
@@ -0,0 +1,83 @@
+package diskquota
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+downloads:
+  bandwidth: 10MB/s
+disk:
+  quota: 20GB
+  components:
+    logs: 512MiB
+    runlogs: 5GB
+  prune:
+    history: 90d
+    logs: 12h
+`))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if cfg.WarnPercent != DefaultWarnPercent || cfg.CheckInterval() != DefaultInterval {
+		t.Errorf("defaults not applied: warn %d, interval %s", cfg.WarnPercent, cfg.CheckInterval())
+	}
+	want := []limit{{Total, 20e9}, {"logs", 512 << 20}, {"runlogs", 5e9}}
+	if len(cfg.limits) != len(want) {
+		t.Fatalf("limits = %v, want %v", cfg.limits, want)
+	}
+	for i := range want {
+		if cfg.limits[i] != want[i] {
+			t.Errorf("limit %d = %v, want %v", i, cfg.limits[i], want[i])
+		}
+	}
+	if cfg.retention["history"] != 90*24*time.Hour || cfg.retention["logs"] != 12*time.Hour {
+		t.Errorf("retention = %v", cfg.retention)
+	}
+}
+
+func TestParseConfig_NoSection(t *testing.T) {
+	cfg, err := ParseConfig([]byte("downloads:\n  bandwidth: 10MB/s\n"))
+	if err != nil || cfg != nil {
+		t.Errorf("ParseConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no quota":        "disk:\n  warn_percent: 90\n",
+		"bad size":        "disk:\n  quota: lots\n",
+		"rate":            "disk:\n  quota: 10MB/s\n",
+		"total component": "disk:\n  components:\n    total: 1GB\n",
+		"warn percent":    "disk:\n  quota: 1GB\n  warn_percent: 150\n",
+		"interval":        "disk:\n  quota: 1GB\n  interval: often\n",
+		"retention":       "disk:\n  quota: 1GB\n  prune:\n    history: forever\n",
+		"zero retention":  "disk:\n  quota: 1GB\n  prune:\n    history: 0d\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseConfig([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil || cfg != nil {
+		t.Errorf("missing file: LoadConfig = %v, %v; want nil, nil", cfg, err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("disk:\n  quota: nope\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadConfig error = %v, want it to name %s", err, path)
+	}
+}
@@ -0,0 +1,321 @@
+// Package diskquota watches how much space the sloth-runner data directory
+// takes. Quotas of the whole directory or of its parts are configured in
+// the disk section of <data-dir>/config.yaml; when usage grows past them
+// the master raises events and, when configured to, prunes old history,
+// logs and events, so it never fills its disk silently.
+package diskquota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Total names the quota of the whole data directory
+const Total = "total"
+
+// Components of the data directory
+const (
+	ComponentDatabases = "databases"
+	ComponentLogs      = "logs"
+	ComponentArtifacts = "artifacts"
+	ComponentOther     = "other"
+)
+
+// Levels of a quota
+const (
+	LevelOK       = "ok"
+	LevelWarning  = "warning"
+	LevelExceeded = "exceeded"
+)
+
+// Usage is how much space the data directory takes
+type Usage struct {
+	Dir   string `json:"dir"`
+	Total int64  `json:"total"`
+	// Components maps each component to its size
+	Components map[string]int64 `json:"components"`
+	// Databases maps database names, e.g. history for history.db, to
+	// their size including WAL files
+	Databases map[string]int64 `json:"databases"`
+}
+
+// Measure walks the data directory and adds up the size of its files
+func Measure(dir string) (*Usage, error) {
+	u := &Usage{Dir: dir, Components: map[string]int64{}, Databases: map[string]int64{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may go away while the directory is walked
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		component, database := classify(rel)
+		u.Total += info.Size()
+		u.Components[component] += info.Size()
+		if database != "" {
+			u.Databases[database] += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %w", dir, err)
+	}
+	return u, nil
+}
+
+// classify returns the component of a file of the data directory and,
+// for databases, the database name
+func classify(rel string) (component, database string) {
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	if len(parts) == 1 {
+		name := parts[0]
+		for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+			name = strings.TrimSuffix(name, suffix)
+		}
+		if db, ok := strings.CutSuffix(name, ".db"); ok {
+			return ComponentDatabases, db
+		}
+		return ComponentOther, ""
+	}
+	switch parts[0] {
+	case "logs":
+		return ComponentLogs, ""
+	case "artifact-cache", "blobs":
+		return ComponentArtifacts, ""
+	}
+	return ComponentOther, ""
+}
+
+// Status is how full one quota is
+type Status struct {
+	Name  string `json:"name"`
+	Used  int64  `json:"used"`
+	Quota int64  `json:"quota"`
+	Level string `json:"level"`
+}
+
+// Percent is how much of the quota is used
+func (s Status) Percent() float64 {
+	return float64(s.Used) * 100 / float64(s.Quota)
+}
+
+// Evaluate compares usage against the quotas, the whole data directory
+// first
+func (c *Config) Evaluate(u *Usage) []Status {
+	statuses := make([]Status, len(c.limits))
+	for i, l := range c.limits {
+		used, ok := u.Components[l.name]
+		if l.name == Total {
+			used = u.Total
+		} else if !ok {
+			used = u.Databases[l.name]
+		}
+		s := Status{Name: l.name, Used: used, Quota: l.bytes, Level: LevelOK}
+		switch {
+		case used > l.bytes:
+			s.Level = LevelExceeded
+		case used*100 >= l.bytes*int64(c.WarnPercent):
+			s.Level = LevelWarning
+		}
+		statuses[i] = s
+	}
+	return statuses
+}
+
+// Pruner deletes data of one kind older than olderThan
+type Pruner func(ctx context.Context, olderThan time.Duration) error
+
+// Pruned is the outcome of one prune policy
+type Pruned struct {
+	Kind string `json:"kind"`
+	// Freed is how much smaller the data directory got
+	Freed int64 `json:"freed"`
+	Err   error `json:"-"`
+}
+
+// Alert reports a quota whose level changed since the last check
+type Alert struct {
+	Status
+	Previous string
+	// Pruned lists the pruning the quota being exceeded caused, and After
+	// is the quota once pruned
+	Pruned []Pruned
+	After  *Status
+}
+
+// Monitor checks the data directory against the quotas of a config
+type Monitor struct {
+	dir     string
+	config  *Config
+	pruners map[string]Pruner
+	levels  map[string]string
+	// Notify is called for each quota whose level changed
+	Notify func(Alert)
+}
+
+// NewMonitor checks dir against config. pruners are the kinds of data the
+// prune section may name.
+func NewMonitor(dir string, config *Config, pruners map[string]Pruner) (*Monitor, error) {
+	for kind := range config.retention {
+		if pruners[kind] == nil {
+			return nil, fmt.Errorf("prune: unknown kind %s", kind)
+		}
+	}
+	return &Monitor{
+		dir:     dir,
+		config:  config,
+		pruners: pruners,
+		levels:  make(map[string]string),
+		Notify:  func(Alert) {},
+	}, nil
+}
+
+// Run checks the data directory right away and then at the configured
+// interval, until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Check(ctx); err != nil {
+			slog.Error("Failed to check disk quotas", "dir", m.dir, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check measures the data directory, prunes when a quota is exceeded and
+// notifies the quotas whose level changed since the last check. It
+// returns the quotas as they are after pruning.
+//
+// A quota pruning brings back under its limit is notified again the next
+// time it is exceeded.
+func (m *Monitor) Check(ctx context.Context) ([]Status, error) {
+	usage, err := Measure(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	statuses := m.config.Evaluate(usage)
+
+	final := statuses
+	var pruned []Pruned
+	if exceeded(statuses) && len(m.config.retention) > 0 {
+		pruned = m.prune(ctx, usage.Total)
+		if usage, err = Measure(m.dir); err != nil {
+			return nil, err
+		}
+		final = m.config.Evaluate(usage)
+	}
+
+	for i, s := range statuses {
+		previous, ok := m.levels[s.Name]
+		if !ok {
+			previous = LevelOK
+		}
+		if s.Level != previous {
+			alert := Alert{Status: s, Previous: previous}
+			if pruned != nil {
+				alert.Pruned, alert.After = pruned, &final[i]
+			}
+			m.Notify(alert)
+		}
+		m.levels[s.Name] = final[i].Level
+	}
+	return final, nil
+}
+
+func exceeded(statuses []Status) bool {
+	for _, s := range statuses {
+		if s.Level == LevelExceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// prune applies the prune policies in order of kind, measuring how much
+// each one freed
+func (m *Monitor) prune(ctx context.Context, total int64) []Pruned {
+	kinds := make([]string, 0, len(m.config.retention))
+	for kind := range m.config.retention {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	pruned := make([]Pruned, 0, len(kinds))
+	for _, kind := range kinds {
+		p := Pruned{Kind: kind}
+		p.Err = m.pruners[kind](ctx, m.config.retention[kind])
+		if usage, err := Measure(m.dir); err == nil {
+			p.Freed = max(total-usage.Total, 0)
+			total = usage.Total
+		}
+		pruned = append(pruned, p)
+	}
+	return pruned
+}
+
+// PruneFiles returns a Pruner removing the files under dir last modified
+// before the retention
+func PruneFiles(dir string) Pruner {
+	return func(ctx context.Context, olderThan time.Duration) error {
+		cutoff := time.Now().Add(-olderThan)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+				if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+			}
+			return nil
+		})
+		return err
+	}
+}
+
+// Vacuum rebuilds the SQLite database at path, handing the space of the
+// rows deleted from it back to the file system
+func Vacuum(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum %s: %w", filepath.Base(path), err)
+	}
+	// Shrink the WAL file the rebuild went through as well
+	_, err = db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
@@ -0,0 +1,200 @@
+package diskquota
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "history.db"), 100)
+	writeFile(t, filepath.Join(dir, "history.db-wal"), 20)
+	writeFile(t, filepath.Join(dir, "agents.db"), 50)
+	writeFile(t, filepath.Join(dir, "logs", "master.log"), 30)
+	writeFile(t, filepath.Join(dir, "blobs", "sha256", "ab"), 40)
+	writeFile(t, filepath.Join(dir, "artifact-cache", "x"), 5)
+	writeFile(t, filepath.Join(dir, "config.yaml"), 7)
+	writeFile(t, filepath.Join(dir, "gitops", "checkout", "main.sloth"), 3)
+
+	u, err := Measure(dir)
+	if err != nil {
+		t.Fatalf("Measure: %v", err)
+	}
+	if u.Total != 255 {
+		t.Errorf("Total = %d, want 255", u.Total)
+	}
+	want := map[string]int64{ComponentDatabases: 170, ComponentLogs: 30, ComponentArtifacts: 45, ComponentOther: 10}
+	for component, size := range want {
+		if u.Components[component] != size {
+			t.Errorf("%s = %d, want %d", component, u.Components[component], size)
+		}
+	}
+	if u.Databases["history"] != 120 || u.Databases["agents"] != 50 {
+		t.Errorf("Databases = %v", u.Databases)
+	}
+}
+
+func TestMeasure_MissingDir(t *testing.T) {
+	u, err := Measure(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || u.Total != 0 {
+		t.Errorf("Measure = %v, %v; want an empty usage", u, err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	cfg, err := ParseConfig([]byte("disk:\n  quota: 1000B\n  components:\n    logs: 100B\n    history: 100B\n    runlogs: 100B\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &Usage{
+		Total:      850,
+		Components: map[string]int64{ComponentLogs: 101},
+		Databases:  map[string]int64{"history": 79},
+	}
+	got := map[string]string{}
+	for _, s := range cfg.Evaluate(u) {
+		got[s.Name] = s.Level
+	}
+	want := map[string]string{Total: LevelWarning, "logs": LevelExceeded, "history": LevelOK, "runlogs": LevelOK}
+	for name, level := range want {
+		if got[name] != level {
+			t.Errorf("%s level = %s, want %s", name, got[name], level)
+		}
+	}
+}
+
+func TestMonitor_AlertsOnLevelChanges(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := ParseConfig([]byte("disk:\n  quota: 1000B\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMonitor(dir, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var alerts []Alert
+	m.Notify = func(a Alert) { alerts = append(alerts, a) }
+
+	check := func(size int) {
+		t.Helper()
+		writeFile(t, filepath.Join(dir, "history.db"), size)
+		if _, err := m.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check(100)
+	check(900)
+	check(950)
+	check(1200)
+	check(1300)
+	check(10)
+
+	levels := []string{LevelWarning, LevelExceeded, LevelOK}
+	if len(alerts) != len(levels) {
+		t.Fatalf("got %d alerts, want %d: %+v", len(alerts), len(levels), alerts)
+	}
+	for i, level := range levels {
+		if alerts[i].Level != level {
+			t.Errorf("alert %d level = %s, want %s", i, alerts[i].Level, level)
+		}
+	}
+	if alerts[1].Previous != LevelWarning || alerts[1].Used != 1200 {
+		t.Errorf("exceeded alert = %+v", alerts[1])
+	}
+}
+
+func TestMonitor_PrunesWhenExceeded(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "logs", "old.log"), 800)
+	writeFile(t, filepath.Join(dir, "logs", "new.log"), 300)
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "logs", "old.log"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ParseConfig([]byte("disk:\n  quota: 1000B\n  prune:\n    logs: 1d\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMonitor(dir, cfg, map[string]Pruner{"logs": PruneFiles(filepath.Join(dir, "logs"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var alerts []Alert
+	m.Notify = func(a Alert) { alerts = append(alerts, a) }
+
+	statuses, err := m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].Level != LevelOK || statuses[0].Used != 300 {
+		t.Errorf("status after pruning = %+v", statuses[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs", "new.log")); err != nil {
+		t.Errorf("recent log was pruned: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Level != LevelExceeded {
+		t.Fatalf("alerts = %+v, want one exceeded alert", alerts)
+	}
+	a := alerts[0]
+	if len(a.Pruned) != 1 || a.Pruned[0].Kind != "logs" || a.Pruned[0].Freed != 800 || a.Pruned[0].Err != nil {
+		t.Errorf("pruned = %+v", a.Pruned)
+	}
+	if a.After == nil || a.After.Level != LevelOK {
+		t.Errorf("after = %+v", a.After)
+	}
+}
+
+func TestNewMonitor_UnknownPruneKind(t *testing.T) {
+	cfg, err := ParseConfig([]byte("disk:\n  quota: 1GB\n  prune:\n    backups: 7d\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMonitor(t.TempDir(), cfg, map[string]Pruner{}); err == nil {
+		t.Error("expected an error for an unknown prune kind")
+	}
+}
+
+func TestVacuum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE t (data BLOB)"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if _, err := db.Exec("INSERT INTO t VALUES (?)", make([]byte, 16<<10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("DELETE FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.Stat(path)
+
+	if err := Vacuum(path); err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("size %d after vacuum, %d before", after.Size(), before.Size())
+	}
+}
@@ -0,0 +1,287 @@
+package docs
+
+// DSLSection is a part of the workflow DSL reference
+type DSLSection struct {
+	Name        string
+	Description string
+	Entries     []DSLEntry
+}
+
+// DSLEntry documents a field, method or function of the DSL
+type DSLEntry struct {
+	Name        string
+	Description string
+	Example     string
+}
+
+// DSLReference returns the reference of the workflow DSL
+func DSLReference() []DSLSection {
+	return []DSLSection{
+		{
+			Name:        "Workflows",
+			Description: "A workflow is a named list of tasks, defined with workflow.define and run with `sloth-runner run <workflow> --file <file>`.",
+			Entries: []DSLEntry{
+				{
+					Name:        "workflow.define(name, opts)",
+					Description: "Defines a workflow from a table of options. Tasks may be task tables or tasks built with task(), listed or keyed by name.",
+					Example: `workflow.define("deploy", {
+    description = "Deploy the web tier",
+    delegate_to = "web-01",
+    tasks = { install, restart },
+})`,
+				},
+				{
+					Name:        "description",
+					Description: "Description of the workflow.",
+				},
+				{
+					Name:        "tasks",
+					Description: "Tasks of the workflow. Tasks run in order, after the tasks they depend on.",
+					Example:     `tasks = { build, test, { name = "notify", command = "./notify.sh" } }`,
+				},
+				{
+					Name:        "delegate_to",
+					Description: "Agent every task runs on unless it sets its own delegate_to. The --delegate-to flag of `sloth-runner run` replaces it.",
+					Example:     `delegate_to = { "web-01", "web-02" }`,
+				},
+				{
+					Name:        "workdir",
+					Description: "Directory the tasks run in.",
+				},
+				{
+					Name:        "create_workdir_before_run",
+					Description: "Creates the workdir before the first task runs.",
+					Example:     `create_workdir_before_run = true`,
+				},
+				{
+					Name:        "clean_workdir_after_run",
+					Description: "Function called with the result of the run to decide whether to clean the workdir. Automatic removal is disabled, so the workdir is kept either way.",
+					Example: `clean_workdir_after_run = function(result)
+    return result.success
+end`,
+				},
+				{
+					Name:        "services",
+					Description: "Services, declared with service(), started before the tasks needing them and stopped after the run.",
+					Example: `local db = service("test-db")
+    :command("docker run --rm -p 5432:5432 postgres:16")
+    :ready_port(5432)
+
+workflow.define("integration")
+    :services({ db })
+    :tasks({ migrate })`,
+				},
+				{
+					Name:        "workflow.define(name)",
+					Description: "Returns a builder with the methods :description, :version, :tasks, :config, :delegate_to, :services and :on_start, which return the builder, and :on_complete(fn), which ends the definition.",
+					Example: `workflow.define("deploy_web")
+    :delegate_to("web-01")
+    :tasks({ install, notify })
+    :on_complete(function(success, results) end)`,
+				},
+			},
+		},
+		{
+			Name:        "Task builder",
+			Description: "task(name) returns a builder whose methods return the builder, so calls can be chained; :build() returns the task.",
+			Entries: []DSLEntry{
+				{
+					Name:        "task(name)",
+					Description: "Starts the definition of a task.",
+					Example: `local install = task("install")
+    :description("Install nginx")
+    :command(function(this, params)
+        return pkg.install({ packages = { "nginx" } })
+    end)
+    :build()`,
+				},
+				{
+					Name:        ":description(text)",
+					Description: "Sets the description of the task.",
+				},
+				{
+					Name:        ":command(cmd)",
+					Description: "Sets what the task runs: a shell command string or a Lua function.",
+					Example:     `:command("make build")`,
+				},
+				{
+					Name:        ":run(fn)",
+					Description: "Same as :command with a function.",
+				},
+				{
+					Name:        ":timeout(duration)",
+					Description: "Fails the task when it runs longer than the duration.",
+					Example:     `:timeout("10m")`,
+				},
+				{
+					Name:        ":workdir(path)",
+					Description: "Directory the task runs in.",
+				},
+				{
+					Name:        ":workspace(paths)",
+					Description: "Paths of the workspace sent when the task is delegated; by default the whole workspace is sent.",
+					Example:     `:workspace({ "scripts/", "configs/app.yaml" })`,
+				},
+				{
+					Name:        ":user(name)",
+					Description: "User the task runs as.",
+				},
+				{
+					Name:        ":delegate_to(agent)",
+					Description: "Agent the task runs on, by name, or a function returning the name.",
+					Example:     `:delegate_to("web-01")`,
+				},
+				{
+					Name:        ":services(names)",
+					Description: "Services the task needs running.",
+				},
+				{
+					Name:        ":on_success(fn)",
+					Description: "Function called when the task succeeds.",
+				},
+				{
+					Name:        ":on_failure(fn)",
+					Description: "Function called when the task fails; :on_fail is an alias.",
+				},
+				{
+					Name:        ":build()",
+					Description: "Returns the task, to be listed in workflow.define.",
+				},
+			},
+		},
+		{
+			Name:        "Task fields",
+			Description: "Fields of the task tables listed in `tasks = {...}`, in the order `sloth-runner fmt` puts them.",
+			Entries: []DSLEntry{
+				{
+					Name:        "name",
+					Description: "Name of the task, unique in the workflow.",
+				},
+				{
+					Name:        "description",
+					Description: "Description of the task.",
+				},
+				{
+					Name:        "delegate_to",
+					Description: "Agent the task runs on: a name, a list of names, `\"local\"`, or a table picking an agent by its facts.",
+					Example:     `delegate_to = { facts = { architecture = "arm64" } }`,
+				},
+				{
+					Name:        "user",
+					Description: "User the task runs as.",
+				},
+				{
+					Name:        "workdir",
+					Description: "Directory the task runs in.",
+				},
+				{
+					Name:        "workspace",
+					Description: "Paths of the workspace sent when the task is delegated; a single path may be a string.",
+					Example:     `workspace = { "scripts/", "configs/app.yaml" }`,
+				},
+				{
+					Name:        "depends_on",
+					Description: "Tasks that must succeed first; their outputs are passed to the command as deps.",
+					Example:     `depends_on = { "build", "test" }`,
+				},
+				{
+					Name:        "consumes",
+					Description: "Artifacts copied into the workdir before the task runs.",
+					Example:     `consumes = { "app.tar.gz" }`,
+				},
+				{
+					Name:        "artifacts",
+					Description: "Glob patterns of the files of the workdir kept as artifacts when the task succeeds.",
+					Example:     `artifacts = { "dist/*.tar.gz" }`,
+				},
+				{
+					Name:        "next_if_fail",
+					Description: "Tasks to continue with when this task fails. The field is parsed but not acted on by the runner.",
+				},
+				{
+					Name:        "run_if",
+					Description: "Runs the task only when the shell command exits with 0, or the function returns true; otherwise the task is skipped.",
+					Example:     `run_if = "test -f deploy.lock"`,
+				},
+				{
+					Name:        "abort_if",
+					Description: "Fails the task before it runs when the shell command exits with 0, or the function returns true.",
+					Example: `abort_if = function(params, deps)
+    return params.env == "prod"
+end`,
+				},
+				{
+					Name:        "params",
+					Description: "Values passed to the command as params.",
+					Example:     `params = { env = "staging" }`,
+				},
+				{
+					Name:        "timeout",
+					Description: "Fails the task when it runs longer than the duration.",
+					Example:     `timeout = "10m"`,
+				},
+				{
+					Name:        "retries",
+					Description: "Number of times the task is retried when it fails.",
+					Example:     `retries = 3`,
+				},
+				{
+					Name:        "async",
+					Description: "Lets the task run concurrently with the async tasks next to it when they are sent to an agent in a batch.",
+					Example:     `async = true`,
+				},
+				{
+					Name:        "pre_exec",
+					Description: "Function called before the command.",
+				},
+				{
+					Name:        "command",
+					Description: "What the task runs: a shell command string or a Lua function.",
+					Example: `command = function(this, params, deps)
+    local out = exec.run("make build")
+    return true, "built", { version = "1.2.0" }
+end`,
+				},
+				{
+					Name:        "run",
+					Description: "Same as command.",
+				},
+				{
+					Name:        "post_exec",
+					Description: "Function called after the command.",
+				},
+				{
+					Name:        "on_success",
+					Description: "Function called when the task succeeds.",
+				},
+				{
+					Name:        "on_failure",
+					Description: "Function called when the task fails.",
+				},
+				{
+					Name:        "on_fail",
+					Description: "Alias of on_failure.",
+				},
+				{
+					Name:        "services",
+					Description: "Services the task needs running.",
+				},
+			},
+		},
+		{
+			Name:        "Task commands",
+			Description: "Lua functions given as command are called with the task, its params and the outputs of the tasks it depends on.",
+			Entries: []DSLEntry{
+				{
+					Name:        "function(this, params, deps)",
+					Description: "this is the task, params its params with params.workdir always set, deps the outputs of the tasks it depends on, by task name.",
+				},
+				{
+					Name:        "return success, message, outputs",
+					Description: "success fails the task when false, message is shown in the results, and outputs, a table, is passed to the tasks depending on this one.",
+					Example:     `return true, "deployed", { url = "https://app.example.com" }`,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,391 @@
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/modules"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scaffolding"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Site serves the documentation built into the binary as a website: the
+// module registry, the DSL reference, the example workflows and the
+// command docs, with a search over all of them. Nothing is fetched from the
+// network, so it works on air-gapped machines.
+type Site struct {
+	modules  []modules.ModuleDoc
+	dsl      []DSLSection
+	examples []example
+	commands []command
+	index    []SearchResult
+	pages    map[string]*template.Template
+}
+
+// example is a workflow template rendered as an example workflow
+type example struct {
+	scaffolding.WorkflowTemplate
+	Source string
+}
+
+// command is an embedded command doc rendered to HTML
+type command struct {
+	Name  string
+	Title string
+	HTML  template.HTML
+	text  string
+}
+
+// SearchResult is a page, or a part of a page, the search can find
+type SearchResult struct {
+	Kind    string
+	Title   string
+	URL     string
+	Summary string
+	text    string
+}
+
+// NewSite builds the site from the in-binary documentation
+func NewSite() (*Site, error) {
+	s := &Site{
+		modules: modules.GetAllModuleDocs(),
+		dsl:     DSLReference(),
+	}
+	sort.Slice(s.modules, func(i, j int) bool { return s.modules[i].Name < s.modules[j].Name })
+
+	for _, t := range scaffolding.NewWorkflowScaffolder().Templates() {
+		source, err := t.Render(scaffolding.TemplateData{
+			WorkflowName: t.Name,
+			Description:  t.Description,
+			Author:       t.Author,
+			Version:      t.Version,
+			Category:     t.Category,
+			Complexity:   t.Complexity,
+			ProjectName:  t.Name,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render example %s: %w", t.Name, err)
+		}
+		s.examples = append(s.examples, example{WorkflowTemplate: t, Source: source})
+	}
+
+	if err := s.loadCommands(); err != nil {
+		return nil, err
+	}
+	if err := s.parsePages(); err != nil {
+		return nil, err
+	}
+	s.buildIndex()
+	return s, nil
+}
+
+// loadCommands renders the embedded command docs
+func (s *Site) loadCommands() error {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	entries, err := fs.ReadDir(docsFS, "content")
+	if err != nil {
+		return fmt.Errorf("failed to read documentation: %w", err)
+	}
+	for _, entry := range entries {
+		content, err := docsFS.ReadFile(path.Join("content", entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read documentation: %w", err)
+		}
+		var html bytes.Buffer
+		if err := md.Convert(content, &html); err != nil {
+			return fmt.Errorf("failed to render %s: %w", entry.Name(), err)
+		}
+		name := strings.TrimSuffix(entry.Name(), ".md")
+		s.commands = append(s.commands, command{
+			Name:  name,
+			Title: markdownTitle(string(content), name),
+			HTML:  template.HTML(html.String()),
+			text:  string(content),
+		})
+	}
+	return nil
+}
+
+// markdownTitle returns the first heading of a markdown document
+func markdownTitle(content, fallback string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+	}
+	return fallback
+}
+
+// buildIndex lists everything the search can find
+func (s *Site) buildIndex() {
+	for _, m := range s.modules {
+		s.index = append(s.index, SearchResult{
+			Kind:    "module",
+			Title:   m.Name,
+			URL:     "/modules/" + m.Name,
+			Summary: m.Description,
+			text:    m.Name + " " + m.Description,
+		})
+		for _, f := range m.Functions {
+			s.index = append(s.index, SearchResult{
+				Kind:    "function",
+				Title:   f.Name,
+				URL:     "/modules/" + m.Name + "#" + f.Name,
+				Summary: f.Description,
+				text:    strings.Join([]string{f.Name, f.Description, f.Parameters, f.Returns, f.Example}, " "),
+			})
+		}
+	}
+	for _, section := range s.dsl {
+		for _, e := range section.Entries {
+			s.index = append(s.index, SearchResult{
+				Kind:    "dsl",
+				Title:   e.Name,
+				URL:     "/dsl#" + anchor(section.Name+"-"+e.Name),
+				Summary: section.Name + ": " + e.Description,
+				text:    strings.Join([]string{section.Name, e.Name, e.Description, e.Example}, " "),
+			})
+		}
+	}
+	for _, e := range s.examples {
+		s.index = append(s.index, SearchResult{
+			Kind:    "example",
+			Title:   e.Name,
+			URL:     "/examples/" + e.Name,
+			Summary: e.Description,
+			text:    strings.Join([]string{e.Name, e.Description, e.Category, e.Source}, " "),
+		})
+	}
+	for _, c := range s.commands {
+		s.index = append(s.index, SearchResult{
+			Kind:    "command",
+			Title:   c.Title,
+			URL:     "/commands/" + c.Name,
+			Summary: "sloth-runner " + c.Name,
+			text:    c.text,
+		})
+	}
+}
+
+// Search returns the entries containing every word of the query, those
+// whose title matches first
+func (s *Site) Search(query string) []SearchResult {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil
+	}
+
+	var titled, others []SearchResult
+	for _, e := range s.index {
+		title := strings.ToLower(e.Title)
+		text := title + " " + strings.ToLower(e.text)
+		matches, inTitle := true, false
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				matches = false
+				break
+			}
+			inTitle = inTitle || strings.Contains(title, w)
+		}
+		switch {
+		case !matches:
+		case inTitle:
+			titled = append(titled, e)
+		default:
+			others = append(others, e)
+		}
+	}
+	return append(titled, others...)
+}
+
+// anchor turns a name into an HTML id
+func anchor(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// Handler returns the HTTP handler of the site
+func (s *Site) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		s.render(w, "index", "Sloth Runner Documentation", map[string]interface{}{
+			"DSL":      s.dsl,
+			"Modules":  s.modules,
+			"Examples": s.examples,
+			"Commands": s.commands,
+		})
+	})
+	mux.HandleFunc("GET /modules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		for _, m := range s.modules {
+			if m.Name == r.PathValue("name") {
+				s.render(w, "module", m.Name, m)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /dsl", func(w http.ResponseWriter, r *http.Request) {
+		s.render(w, "dsl", "DSL Reference", s.dsl)
+	})
+	mux.HandleFunc("GET /examples/{name}", func(w http.ResponseWriter, r *http.Request) {
+		for _, e := range s.examples {
+			if e.Name == r.PathValue("name") {
+				s.render(w, "example", e.Name, e)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /commands/{name}", func(w http.ResponseWriter, r *http.Request) {
+		for _, c := range s.commands {
+			if c.Name == r.PathValue("name") {
+				s.render(w, "command", c.Title, c)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /search", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		s.render(w, "search", "Search", map[string]interface{}{
+			"Query":   query,
+			"Results": s.Search(query),
+		})
+	})
+	return mux
+}
+
+// render writes a page of the site
+func (s *Site) render(w http.ResponseWriter, page, title string, data interface{}) {
+	var out bytes.Buffer
+	err := s.pages[page].ExecuteTemplate(&out, "layout", map[string]interface{}{
+		"Title": title,
+		"Data":  data,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(out.Bytes())
+}
+
+// parsePages parses the template of each page along with the layout
+func (s *Site) parsePages() error {
+	layout, err := template.New("layout").Funcs(template.FuncMap{"anchor": anchor}).Parse(layoutTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse layout: %w", err)
+	}
+	s.pages = make(map[string]*template.Template)
+	for name, body := range pageTemplates {
+		page, err := template.Must(layout.Clone()).Parse(body)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s page: %w", name, err)
+		}
+		s.pages[name] = page
+	}
+	return nil
+}
+
+const layoutTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} - Sloth Runner</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 0; color: #1f2328; line-height: 1.5; }
+header { background: #2d4a3e; color: #fff; padding: 0.75rem 2rem; display: flex; gap: 2rem; align-items: center; }
+header a { color: #fff; text-decoration: none; }
+header form { margin-left: auto; }
+header input { padding: 0.35rem 0.6rem; width: 18rem; border: 0; border-radius: 4px; }
+main { max-width: 60rem; margin: 0 auto; padding: 1rem 2rem 3rem; }
+pre { background: #f6f8fa; padding: 0.75rem 1rem; overflow-x: auto; border-radius: 4px; }
+code { font-family: ui-monospace, Menlo, monospace; font-size: 0.9em; }
+.entry { border-top: 1px solid #d0d7de; padding-top: 0.5rem; margin-top: 1.5rem; }
+.meta { color: #59636e; font-size: 0.9em; }
+.kind { display: inline-block; min-width: 5rem; color: #59636e; font-size: 0.85em; }
+ul.cols { columns: 3 12rem; padding-left: 1.2rem; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #d0d7de; padding: 0.3rem 0.6rem; }
+</style>
+</head>
+<body>
+<header>
+<a href="/"><strong>🦥 Sloth Runner</strong></a>
+<a href="/dsl">DSL</a>
+<a href="/#modules">Modules</a>
+<a href="/#examples">Examples</a>
+<a href="/#commands">Commands</a>
+<form action="/search"><input type="search" name="q" placeholder="Search the docs"></form>
+</header>
+<main>
+{{template "content" .Data}}
+</main>
+</body>
+</html>`
+
+var pageTemplates = map[string]string{
+	"index": `{{define "content"}}
+<h1>Sloth Runner Documentation</h1>
+<p>The reference built into this sloth-runner binary.</p>
+<h2><a href="/dsl">DSL Reference</a></h2>
+<ul>{{range .DSL}}<li><a href="/dsl#{{anchor .Name}}">{{.Name}}</a></li>{{end}}</ul>
+<h2 id="modules">Modules</h2>
+<ul class="cols">{{range .Modules}}<li><a href="/modules/{{.Name}}">{{.Name}}</a></li>{{end}}</ul>
+<h2 id="examples">Example Workflows</h2>
+<ul>{{range .Examples}}<li><a href="/examples/{{.Name}}">{{.Name}}</a> - {{.Description}}</li>{{end}}</ul>
+<h2 id="commands">Commands</h2>
+<ul>{{range .Commands}}<li><a href="/commands/{{.Name}}">{{.Title}}</a></li>{{end}}</ul>
+{{end}}`,
+	"module": `{{define "content"}}
+<h1>{{.Name}}</h1>
+<p>{{.Description}}</p>
+{{range .Functions}}<div class="entry" id="{{.Name}}">
+<h3><code>{{.Name}}</code></h3>
+<p>{{.Description}}</p>
+{{if .Parameters}}<p class="meta">Parameters: <code>{{.Parameters}}</code></p>{{end}}
+{{if .Returns}}<p class="meta">Returns: <code>{{.Returns}}</code></p>{{end}}
+{{if .Example}}<pre><code>{{.Example}}</code></pre>{{end}}
+</div>{{end}}
+{{end}}`,
+	"dsl": `{{define "content"}}
+<h1>DSL Reference</h1>
+{{range .}}{{$section := .Name}}<h2 id="{{anchor .Name}}">{{.Name}}</h2>
+<p>{{.Description}}</p>
+{{range .Entries}}<div class="entry" id="{{anchor (printf "%s-%s" $section .Name)}}">
+<h3><code>{{.Name}}</code></h3>
+<p>{{.Description}}</p>
+{{if .Example}}<pre><code>{{.Example}}</code></pre>{{end}}
+</div>{{end}}{{end}}
+{{end}}`,
+	"example": `{{define "content"}}
+<h1>{{.Name}}</h1>
+<p>{{.Description}}</p>
+<p class="meta">Category: {{.Category}} | Complexity: {{.Complexity}} | Version: {{.Version}}</p>
+<p>Save it as <code>{{.Name}}.sloth</code> and run it with <code>sloth-runner run {{.Name}} --file {{.Name}}.sloth</code>.</p>
+<pre><code>{{.Source}}</code></pre>
+{{end}}`,
+	"command": `{{define "content"}}{{.HTML}}{{end}}`,
+	"search": `{{define "content"}}
+<h1>Search</h1>
+<form action="/search"><input type="search" name="q" value="{{.Query}}" autofocus> <button>Search</button></form>
+{{if .Query}}<p class="meta">{{len .Results}} results for "{{.Query}}"</p>{{end}}
+<ul>{{range .Results}}<li><span class="kind">{{.Kind}}</span> <a href="{{.URL}}">{{.Title}}</a> - {{.Summary}}</li>{{end}}</ul>
+{{end}}`,
+}
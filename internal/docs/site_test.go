@@ -0,0 +1,105 @@
+package docs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luafmt"
+)
+
+func get(t *testing.T, srv *httptest.Server, path string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestSite_Pages(t *testing.T) {
+	site, err := NewSite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(site.Handler())
+	defer srv.Close()
+
+	for path, want := range map[string]string{
+		"/":                 `href="/modules/pkg"`,
+		"/modules/pkg":      `id="pkg.install"`,
+		"/dsl":              `id="task-fields-depends_on"`,
+		"/examples/basic":   `workflow.define(&#34;basic&#34;`,
+		"/commands/hook":    "<h1",
+		"/search?q=install": `href="/modules/pkg#pkg.install"`,
+	} {
+		status, body := get(t, srv, path)
+		if status != http.StatusOK {
+			t.Errorf("GET %s = %d", path, status)
+			continue
+		}
+		if !strings.Contains(body, want) {
+			t.Errorf("GET %s: body should contain %q", path, want)
+		}
+	}
+
+	for _, path := range []string{"/modules/nope", "/examples/nope", "/commands/nope"} {
+		if status, _ := get(t, srv, path); status != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, status)
+		}
+	}
+}
+
+func TestSite_Search(t *testing.T) {
+	site, err := NewSite()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := site.Search("PKG Install")
+	if len(results) == 0 || results[0].Title != "pkg.install" {
+		t.Fatalf("search for 'pkg install' = %v", results)
+	}
+
+	results = site.Search("depends_on")
+	found := false
+	for _, r := range results {
+		found = found || (r.Kind == "dsl" && r.Title == "depends_on")
+	}
+	if !found {
+		t.Errorf("search for depends_on should find the DSL entry, got %v", results)
+	}
+
+	if results := site.Search("   "); results != nil {
+		t.Errorf("empty search = %v", results)
+	}
+	if results := site.Search("no-such-thing-anywhere"); len(results) != 0 {
+		t.Errorf("search should find nothing, got %v", results)
+	}
+}
+
+// TestDSLReference_TaskFields validates that every field of task tables
+// known to the formatter is documented
+func TestDSLReference_TaskFields(t *testing.T) {
+	documented := make(map[string]bool)
+	for _, section := range DSLReference() {
+		if section.Name != "Task fields" {
+			continue
+		}
+		for _, e := range section.Entries {
+			documented[e.Name] = true
+		}
+	}
+	for _, field := range luafmt.TaskFieldOrder {
+		if !documented[field] {
+			t.Errorf("task field %s is not documented", field)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package download throttles the downloads modules make, so that large
+// artifact pulls on many agents don't saturate a site's uplink. A Manager
+// caps how many downloads run at once and how fast they go: in total, per
+// task and per call.
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Config limits downloads. Bandwidths are rates like "10MB/s"; empty or
+// zero values mean no limit.
+type Config struct {
+	// MaxConcurrent is how many downloads may run at once
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// Bandwidth is shared by all downloads of the process
+	Bandwidth string `yaml:"bandwidth"`
+	// TaskBandwidth is shared by the downloads of each task
+	TaskBandwidth string `yaml:"task_bandwidth"`
+}
+
+// Options are the limits of a single download
+type Options struct {
+	// Bandwidth in bytes per second, 0 for no limit
+	Bandwidth int64
+}
+
+// Manager runs downloads within the limits of a Config
+type Manager struct {
+	slots    chan struct{}
+	global   *Limiter
+	taskRate int64
+}
+
+// NewManager creates a manager enforcing cfg
+func NewManager(cfg Config) (*Manager, error) {
+	if cfg.MaxConcurrent < 0 {
+		return nil, fmt.Errorf("max_concurrent must not be negative")
+	}
+	rate, err := ParseRate(cfg.Bandwidth)
+	if err != nil {
+		return nil, fmt.Errorf("bandwidth: %w", err)
+	}
+	taskRate, err := ParseRate(cfg.TaskBandwidth)
+	if err != nil {
+		return nil, fmt.Errorf("task_bandwidth: %w", err)
+	}
+
+	m := &Manager{global: NewLimiter(rate), taskRate: taskRate}
+	if cfg.MaxConcurrent > 0 {
+		m.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return m, nil
+}
+
+// fileConfig is the part of config.yaml read by this package
+type fileConfig struct {
+	Downloads Config `yaml:"downloads"`
+}
+
+// LoadConfig reads the downloads section of a config file. A missing file
+// means no limits.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var fc fileConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return fc.Downloads, nil
+}
+
+var (
+	defaultManager *Manager
+	defaultMu      sync.Mutex
+)
+
+// Default returns the manager modules download through. It is loaded from
+// the config file the first time it is needed; an invalid config is
+// logged and leaves downloads unlimited.
+func Default() *Manager {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultManager != nil {
+		return defaultManager
+	}
+
+	path := config.GetConfigPath()
+	cfg, err := LoadConfig(path)
+	if err == nil {
+		defaultManager, err = NewManager(cfg)
+	}
+	if err != nil {
+		slog.Warn("Ignoring download limits", "config", path, "error", err)
+		defaultManager, _ = NewManager(Config{})
+	}
+	return defaultManager
+}
+
+// SetDefault replaces the manager returned by Default
+func SetDefault(m *Manager) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultManager = m
+}
+
+type taskKey struct{}
+type optionsKey struct{}
+
+// taskState holds the bandwidth shared by the downloads of one task
+type taskState struct {
+	once    sync.Once
+	limiter *Limiter
+}
+
+// WithTask returns a context whose downloads share the per-task bandwidth.
+// Task runners call it once per task.
+func WithTask(ctx context.Context) context.Context {
+	return context.WithValue(ctx, taskKey{}, &taskState{})
+}
+
+// WithOptions returns a context whose downloads use opts
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// Do sends req once a download slot is free and returns the response with
+// its body throttled to the global, task and call bandwidths found in the
+// request context. Closing the body frees the slot.
+func (m *Manager) Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if m.slots != nil {
+		select {
+		case m.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if m.slots != nil {
+			<-m.slots
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	limiters := []*Limiter{m.global}
+	if task, ok := ctx.Value(taskKey{}).(*taskState); ok && m.taskRate > 0 {
+		task.once.Do(func() { task.limiter = NewLimiter(m.taskRate) })
+		limiters = append(limiters, task.limiter)
+	}
+	if opts, ok := ctx.Value(optionsKey{}).(Options); ok {
+		limiters = append(limiters, NewLimiter(opts.Bandwidth))
+	}
+	resp.Body = &body{
+		ReadCloser: resp.Body,
+		reader:     NewReader(ctx, resp.Body, limiters...),
+		release:    release,
+	}
+	return resp, nil
+}
+
+// body is a throttled response body holding a download slot
+type body struct {
+	io.ReadCloser
+	reader  io.Reader
+	release func()
+	once    sync.Once
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,178 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"2048", 2048},
+		{"10MB/s", 10 * 1000 * 1000},
+		{"512KiB", 512 * 1024},
+		{"1.5 GiB/s", 1536 * 1024 * 1024},
+		{"100Mbit/s", 12500000},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if err != nil {
+			t.Errorf("ParseRate(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"fast", "10XB/s", "MB"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("ParseRate(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestReader_Throttles(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 30*1024)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, NewReader(context.Background(), bytes.NewReader(data), NewLimiter(100*1024)))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("copy = %d, %v", n, err)
+	}
+	// 30KiB at 100KiB/s takes about 300ms
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("copy took %v, expected throttling", elapsed)
+	}
+}
+
+func TestReader_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	data := bytes.Repeat([]byte("x"), 8*1024)
+	_, err := io.Copy(io.Discard, NewReader(ctx, bytes.NewReader(data), NewLimiter(1024)))
+	if err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestManager_MaxConcurrent(t *testing.T) {
+	var running, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	m, err := NewManager(Config{MaxConcurrent: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			resp, err := m.Do(http.DefaultClient, req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("%d downloads ran at once, want at most 2", peak)
+	}
+}
+
+func TestManager_TaskBandwidth(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 10*1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	m, err := NewManager(Config{TaskBandwidth: "100KiB/s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two downloads of one task share 100KiB/s: 20KiB take about 200ms
+	ctx := WithTask(context.Background())
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		resp, err := m.Do(http.DefaultClient, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("downloads took %v, expected the task limit to apply", elapsed)
+	}
+
+	// Without a task, the task limit doesn't apply
+	start = time.Now()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := m.Do(http.DefaultClient, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed > 80*time.Millisecond {
+		t.Errorf("download took %v outside a task", elapsed)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	cfg, err := LoadConfig(path)
+	if err != nil || cfg != (Config{}) {
+		t.Fatalf("missing file: %+v, %v", cfg, err)
+	}
+
+	os.WriteFile(path, []byte("downloads:\n  max_concurrent: 4\n  bandwidth: 50MB/s\n  task_bandwidth: 10MB/s\n"), 0644)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{MaxConcurrent: 4, Bandwidth: "50MB/s", TaskBandwidth: "10MB/s"}
+	if cfg != want {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+
+	if _, err := NewManager(Config{Bandwidth: "lots"}); err == nil {
+		t.Error("expected an error for an invalid bandwidth")
+	}
+}
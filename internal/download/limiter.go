@@ -0,0 +1,137 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chunkSize bounds each throttled read, so waits stay short and readers
+// sharing a limiter take turns
+const chunkSize = 32 * 1024
+
+// Limiter is a token bucket handing out bytes at a steady rate, with up to
+// one second of burst. A nil Limiter doesn't limit.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter letting bytesPerSecond through, or nil when
+// it is zero
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &Limiter{rate: float64(bytesPerSecond), last: time.Now()}
+}
+
+// Wait blocks until n bytes may pass. Bytes taken beyond the tokens
+// available become a debt the next callers wait for too.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reader throttles reads to all of its limiters
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+// NewReader returns a reader of r going no faster than any of limiters
+func NewReader(ctx context.Context, r io.Reader, limiters ...*Limiter) io.Reader {
+	var active []*Limiter
+	for _, l := range limiters {
+		if l != nil {
+			active = append(active, l)
+		}
+	}
+	if len(active) == 0 {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiters: active}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+	n, err := r.r.Read(p)
+	for _, l := range r.limiters {
+		if waitErr := l.Wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// rateUnits are the multipliers of the units ParseRate accepts
+var rateUnits = map[string]float64{
+	"":     1,
+	"b":    1,
+	"kb":   1000,
+	"mb":   1000 * 1000,
+	"gb":   1000 * 1000 * 1000,
+	"kib":  1024,
+	"mib":  1024 * 1024,
+	"gib":  1024 * 1024 * 1024,
+	"kbit": 1000 / 8.0,
+	"mbit": 1000 * 1000 / 8.0,
+	"gbit": 1000 * 1000 * 1000 / 8.0,
+}
+
+// ParseRate parses a bandwidth like "10MB/s", "512KiB" or "100Mbit/s" into
+// bytes per second. A bare number is bytes per second; "" and "0" mean no
+// limit.
+func ParseRate(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	s = strings.TrimSuffix(s, "/s")
+	if s == "" {
+		return 0, nil
+	}
+
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	unit, ok := rateUnits[strings.TrimSpace(s[i:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid rate %q: unknown unit %q", s, s[i:])
+	}
+	return int64(value * unit), nil
+}
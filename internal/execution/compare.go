@@ -0,0 +1,202 @@
+package execution
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of change between two runs
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// RunDiff is what changed between two runs of a workflow
+type RunDiff struct {
+	From *Execution `json:"from"`
+	To   *Execution `json:"to"`
+	// Added are tasks that only ran in To, Removed those that only ran in From
+	Added   []*TaskExecution `json:"added,omitempty"`
+	Removed []*TaskExecution `json:"removed,omitempty"`
+	// Tasks compares the tasks that ran in both, in the order they ran in To
+	Tasks     []*TaskDiff     `json:"tasks,omitempty"`
+	Variables []*VariableDiff `json:"variables,omitempty"`
+}
+
+// TaskDiff compares one task between two runs
+type TaskDiff struct {
+	TaskName   string          `json:"task_name"`
+	FromStatus ExecutionStatus `json:"from_status"`
+	ToStatus   ExecutionStatus `json:"to_status"`
+	// FromDuration and ToDuration are in milliseconds
+	FromDuration int64 `json:"from_duration"`
+	ToDuration   int64 `json:"to_duration"`
+	// Output holds the lines of the task's output removed and added, empty
+	// when the output is the same
+	Output string `json:"output,omitempty"`
+	// FromError and ToError are set when the task's error message changed
+	FromError string `json:"from_error,omitempty"`
+	ToError   string `json:"to_error,omitempty"`
+}
+
+// StatusChanged reports whether the task's outcome differs
+func (d *TaskDiff) StatusChanged() bool {
+	return d.FromStatus != d.ToStatus
+}
+
+// Changed reports whether anything but the duration differs
+func (d *TaskDiff) Changed() bool {
+	return d.StatusChanged() || d.Output != "" || d.FromError != d.ToError
+}
+
+// VariableDiff is a variable of the run, from its values and params, that
+// differs between two runs
+type VariableDiff struct {
+	Name   string      `json:"name"`
+	Change string      `json:"change"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+}
+
+// Compare returns what changed from the run from to the run to
+func Compare(from *Execution, fromTasks []*TaskExecution, to *Execution, toTasks []*TaskExecution) *RunDiff {
+	diff := &RunDiff{From: from, To: to}
+
+	before := make(map[string]*TaskExecution, len(fromTasks))
+	for _, t := range fromTasks {
+		before[t.TaskName] = t
+	}
+	after := make(map[string]bool, len(toTasks))
+	for _, t := range toTasks {
+		after[t.TaskName] = true
+		old, ok := before[t.TaskName]
+		if !ok {
+			diff.Added = append(diff.Added, t)
+			continue
+		}
+		d := &TaskDiff{
+			TaskName:     t.TaskName,
+			FromStatus:   old.Status,
+			ToStatus:     t.Status,
+			FromDuration: old.Duration,
+			ToDuration:   t.Duration,
+			Output:       lineDiff(splitLines(old.Output), splitLines(t.Output)),
+		}
+		if old.Error != t.Error {
+			d.FromError, d.ToError = old.Error, t.Error
+		}
+		diff.Tasks = append(diff.Tasks, d)
+	}
+	for _, t := range fromTasks {
+		if !after[t.TaskName] {
+			diff.Removed = append(diff.Removed, t)
+		}
+	}
+
+	diff.Variables = compareVariables(runVariables(from), runVariables(to))
+	return diff
+}
+
+// runVariables returns the variables recorded with a run
+func runVariables(exec *Execution) map[string]interface{} {
+	if exec == nil {
+		return nil
+	}
+	vars, _ := exec.Metadata["variables"].(map[string]interface{})
+	return vars
+}
+
+func compareVariables(from, to map[string]interface{}) []*VariableDiff {
+	var changes []*VariableDiff
+	for name, value := range to {
+		old, ok := from[name]
+		switch {
+		case !ok:
+			changes = append(changes, &VariableDiff{Name: name, Change: ChangeAdded, To: value})
+		case !reflect.DeepEqual(old, value):
+			changes = append(changes, &VariableDiff{Name: name, Change: ChangeChanged, From: old, To: value})
+		}
+	}
+	for name, value := range from {
+		if _, ok := to[name]; !ok {
+			changes = append(changes, &VariableDiff{Name: name, Change: ChangeRemoved, From: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// FormatOutput renders a task's outputs for the history, one value per
+// line so runs can be compared line by line
+func FormatOutput(outputs map[string]interface{}) string {
+	if len(outputs) == 0 {
+		return ""
+	}
+	data, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineDiff returns the lines removed from a and added in b, prefixed with
+// - and +, in order; removals come before the additions replacing them
+func lineDiff(a, b []string) string {
+	// Only the lines between the common prefix and suffix can differ
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// Longest common subsequence of the rest
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,118 @@
+package execution
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	from := &Execution{ID: "run-1", WorkflowName: "deploy", Metadata: map[string]interface{}{
+		"variables": map[string]interface{}{"image_tag": "v1", "debug": true, "replicas": float64(2)},
+	}}
+	to := &Execution{ID: "run-2", WorkflowName: "deploy", Metadata: map[string]interface{}{
+		"variables": map[string]interface{}{"image_tag": "v2", "replicas": float64(2), "region": "eu"},
+	}}
+	fromTasks := []*TaskExecution{
+		{TaskName: "build", Status: StatusCompleted, Duration: 1000, Output: "{\n  \"version\": \"1.0\"\n}"},
+		{TaskName: "deploy", Status: StatusCompleted, Duration: 3000},
+		{TaskName: "warm-cache", Status: StatusCompleted},
+	}
+	toTasks := []*TaskExecution{
+		{TaskName: "build", Status: StatusCompleted, Duration: 1500, Output: "{\n  \"version\": \"1.1\"\n}"},
+		{TaskName: "migrate", Status: StatusCompleted},
+		{TaskName: "deploy", Status: StatusFailed, Duration: 200, Error: "rollout timed out"},
+	}
+
+	diff := Compare(from, fromTasks, to, toTasks)
+
+	if len(diff.Added) != 1 || diff.Added[0].TaskName != "migrate" {
+		t.Errorf("Expected migrate to be added, got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].TaskName != "warm-cache" {
+		t.Errorf("Expected warm-cache to be removed, got %v", diff.Removed)
+	}
+	if len(diff.Tasks) != 2 {
+		t.Fatalf("Expected 2 tasks in both runs, got %d", len(diff.Tasks))
+	}
+
+	build, deploy := diff.Tasks[0], diff.Tasks[1]
+	if build.StatusChanged() || build.ToDuration-build.FromDuration != 500 {
+		t.Errorf("Unexpected build diff: %+v", build)
+	}
+	if want := "-  \"version\": \"1.0\"\n+  \"version\": \"1.1\"\n"; build.Output != want {
+		t.Errorf("Expected output diff %q, got %q", want, build.Output)
+	}
+	if !deploy.StatusChanged() || deploy.ToError != "rollout timed out" || deploy.Output != "" {
+		t.Errorf("Unexpected deploy diff: %+v", deploy)
+	}
+
+	changes := map[string]string{}
+	for _, v := range diff.Variables {
+		changes[v.Name] = v.Change
+	}
+	want := map[string]string{"debug": ChangeRemoved, "image_tag": ChangeChanged, "region": ChangeAdded}
+	if len(changes) != len(want) {
+		t.Errorf("Expected variable changes %v, got %v", want, changes)
+	}
+	for name, change := range want {
+		if changes[name] != change {
+			t.Errorf("Expected %s to be %s, got %q", name, change, changes[name])
+		}
+	}
+}
+
+func TestHistoryDB_RecordRun(t *testing.T) {
+	db, err := NewHistoryDB(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"3f2a9c1e-0001", "3f2b0000-0002"} {
+		exec := &Execution{
+			ID: id, WorkflowName: "deploy", Status: StatusCompleted, StartTime: 1000,
+			Metadata: map[string]interface{}{"variables": map[string]interface{}{"image_tag": "v1"}},
+		}
+		tasks := []*TaskExecution{
+			{ID: id + "/build", TaskName: "build", Status: StatusCompleted, StartTime: 1000, Output: "ok",
+				CPUTimeMs: 1200, PeakRSSBytes: 64 << 20, ReadBytes: 10, WriteBytes: 20},
+			{ID: id + "/deploy", TaskName: "deploy", Status: StatusFailed, StartTime: 1000, Error: "boom",
+				Attempts: []TaskAttempt{{Attempt: 1, Status: StatusFailed, Duration: 800, Error: "connection refused"}, {Attempt: 2, Status: StatusFailed, Error: "boom"}}},
+		}
+		if err := db.RecordRun(exec, tasks); err != nil {
+			t.Fatalf("RecordRun failed: %v", err)
+		}
+	}
+
+	id, err := db.ResolveExecutionID("3f2a")
+	if err != nil || id != "3f2a9c1e-0001" {
+		t.Errorf("Expected the prefix to resolve to 3f2a9c1e-0001, got %q (%v)", id, err)
+	}
+	if _, err := db.ResolveExecutionID("3f2"); err == nil {
+		t.Error("Expected an ambiguous prefix to be rejected")
+	}
+	if _, err := db.ResolveExecutionID("ffff"); err == nil {
+		t.Error("Expected an unknown ID to be rejected")
+	}
+
+	exec, err := db.GetExecution(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runVariables(exec)["image_tag"] != "v1" {
+		t.Errorf("Expected the variables to be recorded, got %v", exec.Metadata)
+	}
+	tasks, err := db.GetTaskExecutions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].TaskName != "build" || tasks[0].Output != "ok" || tasks[1].Error != "boom" {
+		t.Errorf("Expected the tasks in run order, got %+v %+v", tasks[0], tasks[1])
+	}
+	if b := tasks[0]; b.CPUTimeMs != 1200 || b.PeakRSSBytes != 64<<20 || b.ReadBytes != 10 || b.WriteBytes != 20 {
+		t.Errorf("Expected the task's resource usage to be recorded, got %+v", b)
+	}
+	if a := tasks[1].Attempts; len(a) != 2 || a[0].Error != "connection refused" || a[0].Duration != 800 {
+		t.Errorf("Expected the attempts of deploy to be recorded, got %+v", a)
+	}
+}
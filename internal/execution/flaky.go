@@ -0,0 +1,178 @@
+package execution
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Flaky task policies, selected with `run --flaky-policy`
+const (
+	// FlakyPolicyOff disables flaky task reporting
+	FlakyPolicyOff = "off"
+	// FlakyPolicyWarn reports flaky tasks after a run
+	FlakyPolicyWarn = "warn"
+	// FlakyPolicyQuarantine also lets flaky tasks fail without failing the run
+	FlakyPolicyQuarantine = "quarantine"
+)
+
+// FlakyWindow is how many recent runs of a task are considered
+const FlakyWindow = 20
+
+// ValidateFlakyPolicy checks a flaky task policy name
+func ValidateFlakyPolicy(policy string) error {
+	switch policy {
+	case FlakyPolicyOff, FlakyPolicyWarn, FlakyPolicyQuarantine:
+		return nil
+	}
+	return fmt.Errorf("invalid flaky policy %q (use %s, %s or %s)",
+		policy, FlakyPolicyOff, FlakyPolicyWarn, FlakyPolicyQuarantine)
+}
+
+// TaskRun is the outcome of one task in one run of a stack
+type TaskRun struct {
+	Stack    string          `json:"stack"`
+	RunID    string          `json:"run_id"`
+	TaskName string          `json:"task_name"`
+	Status   ExecutionStatus `json:"status"`
+	Attempts int             `json:"attempts"`
+	// Recovered is set when the task failed and then passed on retry
+	Recovered bool `json:"recovered"`
+	// Quarantined is set when the task failed but did not fail the run
+	Quarantined bool  `json:"quarantined"`
+	StartTime   int64 `json:"start_time"`
+}
+
+// FlakyTask summarizes the recent history of a task that looks flaky
+type FlakyTask struct {
+	Stack     string `json:"stack"`
+	TaskName  string `json:"task_name"`
+	Runs      int    `json:"runs"`
+	Passes    int    `json:"passes"`
+	Failures  int    `json:"failures"`
+	Recovered int    `json:"recovered"`
+	// Flips counts changes between pass and fail in consecutive runs
+	Flips      int             `json:"flips"`
+	Score      float64         `json:"score"`
+	LastStatus ExecutionStatus `json:"last_status"`
+	LastSeen   int64           `json:"last_seen"`
+}
+
+// RecordTaskRuns stores the task outcomes of a run
+func (h *HistoryDB) RecordTaskRuns(runs []*TaskRun) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO task_runs (
+			stack, run_id, task_name, status, attempts, recovered, quarantined, start_time
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range runs {
+		if _, err := stmt.Exec(r.Stack, r.RunID, r.TaskName, r.Status, r.Attempts,
+			r.Recovered, r.Quarantined, r.StartTime); err != nil {
+			return fmt.Errorf("failed to record task run %s: %w", r.TaskName, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FlakyTasks returns the tasks of a stack whose recent runs look flaky,
+// most flaky first. An empty stack returns flaky tasks of all stacks.
+func (h *HistoryDB) FlakyTasks(stack string) ([]*FlakyTask, error) {
+	query := `
+		SELECT stack, run_id, task_name, status, attempts, recovered, quarantined, start_time
+		FROM task_runs
+	`
+	var args []interface{}
+	if stack != "" {
+		query += " WHERE stack = ?"
+		args = append(args, stack)
+	}
+	query += " ORDER BY stack, task_name, start_time DESC, id DESC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flaky []*FlakyTask
+	var current []*TaskRun
+	flush := func() {
+		if f := DetectFlaky(current); f != nil {
+			flaky = append(flaky, f)
+		}
+		current = nil
+	}
+
+	for rows.Next() {
+		r := &TaskRun{}
+		if err := rows.Scan(&r.Stack, &r.RunID, &r.TaskName, &r.Status, &r.Attempts,
+			&r.Recovered, &r.Quarantined, &r.StartTime); err != nil {
+			return nil, err
+		}
+		if len(current) > 0 && (current[0].Stack != r.Stack || current[0].TaskName != r.TaskName) {
+			flush()
+		}
+		if len(current) < FlakyWindow {
+			current = append(current, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	sort.SliceStable(flaky, func(i, j int) bool {
+		return flaky[i].Score > flaky[j].Score
+	})
+	return flaky, nil
+}
+
+// DetectFlaky summarizes the runs of one task, newest first, and returns
+// nil unless they look flaky: the task failed and then passed on retry,
+// or its outcome flipped between pass and fail at least twice.
+func DetectFlaky(runs []*TaskRun) *FlakyTask {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	f := &FlakyTask{
+		Stack:      runs[0].Stack,
+		TaskName:   runs[0].TaskName,
+		Runs:       len(runs),
+		LastStatus: runs[0].Status,
+		LastSeen:   runs[0].StartTime,
+	}
+	for i, r := range runs {
+		if r.Status == StatusFailed {
+			f.Failures++
+		} else {
+			f.Passes++
+		}
+		if r.Recovered {
+			f.Recovered++
+		}
+		if i > 0 && (r.Status == StatusFailed) != (runs[i-1].Status == StatusFailed) {
+			f.Flips++
+		}
+	}
+
+	if f.Recovered == 0 && f.Flips < 2 {
+		return nil
+	}
+	f.Score = float64(f.Recovered+f.Flips) / float64(f.Runs)
+	if f.Score > 1 {
+		f.Score = 1
+	}
+	return f
+}
@@ -0,0 +1,99 @@
+package execution
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func taskRuns(stack, task string, statuses ...string) []*TaskRun {
+	runs := make([]*TaskRun, 0, len(statuses))
+	for i, s := range statuses {
+		r := &TaskRun{Stack: stack, TaskName: task, RunID: task + "-" + string(rune('a'+i)), Attempts: 1, StartTime: int64(1000 - i)}
+		switch s {
+		case "pass":
+			r.Status = StatusCompleted
+		case "fail":
+			r.Status = StatusFailed
+		case "retry":
+			r.Status = StatusCompleted
+			r.Recovered = true
+			r.Attempts = 2
+		}
+		runs = append(runs, r)
+	}
+	return runs
+}
+
+func TestDetectFlaky(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		flaky    bool
+	}{
+		{"always passes", []string{"pass", "pass", "pass"}, false},
+		{"always fails", []string{"fail", "fail", "fail"}, false},
+		{"broke once", []string{"fail", "fail", "pass", "pass"}, false},
+		{"passed on retry", []string{"pass", "retry", "pass"}, true},
+		{"alternates", []string{"pass", "fail", "pass", "pass"}, true},
+		{"no runs", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := DetectFlaky(taskRuns("s", "t", tt.statuses...))
+			if (f != nil) != tt.flaky {
+				t.Errorf("Expected flaky=%v, got %+v", tt.flaky, f)
+			}
+		})
+	}
+}
+
+func TestHistoryDB_FlakyTasks(t *testing.T) {
+	db, err := NewHistoryDB(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var runs []*TaskRun
+	runs = append(runs, taskRuns("prod", "deploy", "pass", "retry", "pass")...)
+	runs = append(runs, taskRuns("prod", "migrate", "pass", "fail", "pass", "fail")...)
+	runs = append(runs, taskRuns("prod", "build", "pass", "pass")...)
+	runs = append(runs, taskRuns("dev", "deploy", "pass", "fail", "pass")...)
+	if err := db.RecordTaskRuns(runs); err != nil {
+		t.Fatalf("Failed to record task runs: %v", err)
+	}
+
+	flaky, err := db.FlakyTasks("prod")
+	if err != nil {
+		t.Fatalf("Failed to get flaky tasks: %v", err)
+	}
+	if len(flaky) != 2 {
+		t.Fatalf("Expected 2 flaky tasks, got %+v", flaky)
+	}
+	if flaky[0].TaskName != "migrate" || flaky[0].Flips != 3 {
+		t.Errorf("Expected migrate to be the most flaky, got %+v", flaky[0])
+	}
+	if flaky[1].TaskName != "deploy" || flaky[1].Recovered != 1 || flaky[1].Runs != 3 {
+		t.Errorf("Unexpected deploy summary: %+v", flaky[1])
+	}
+
+	all, err := db.FlakyTasks("")
+	if err != nil {
+		t.Fatalf("Failed to get flaky tasks: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("Expected 3 flaky tasks across stacks, got %d", len(all))
+	}
+}
+
+func TestValidateFlakyPolicy(t *testing.T) {
+	for _, p := range []string{FlakyPolicyOff, FlakyPolicyWarn, FlakyPolicyQuarantine} {
+		if err := ValidateFlakyPolicy(p); err != nil {
+			t.Errorf("Expected %s to be valid: %v", p, err)
+		}
+	}
+	if err := ValidateFlakyPolicy("ignore"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // ExecutionStatus represents the status of an execution
@@ -52,6 +52,40 @@ type TaskExecution struct {
 	Output      string          `json:"output,omitempty"`
 	Error       string          `json:"error,omitempty"`
 	Changed     bool            `json:"changed"`
+	// What the task's processes consumed, when it was measured: CPU
+	// time in milliseconds, peak memory and disk I/O in bytes
+	CPUTimeMs    int64 `json:"cpu_time_ms,omitempty"`
+	PeakRSSBytes int64 `json:"peak_rss_bytes,omitempty"`
+	ReadBytes    int64 `json:"read_bytes,omitempty"`
+	WriteBytes   int64 `json:"write_bytes,omitempty"`
+	// Progress is the timeline of the progress the task reported
+	Progress []ProgressEvent `json:"progress,omitempty"`
+	// WorkspaceChanges are the workspace files the task added, modified
+	// or deleted, when it was delegated with workspace_diff
+	WorkspaceChanges []WorkspaceChange `json:"workspace_changes,omitempty"`
+	// Attempts are the runs of a task that was retried, in order
+	Attempts []TaskAttempt `json:"attempts,omitempty"`
+}
+
+// TaskAttempt is a run of a retried task
+type TaskAttempt struct {
+	Attempt  int             `json:"attempt"` // Counted from 1
+	Status   ExecutionStatus `json:"status"`
+	Duration int64           `json:"duration"` // milliseconds
+	Error    string          `json:"error,omitempty"`
+}
+
+// WorkspaceChange is a workspace file a task changed
+type WorkspaceChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"` // added, modified or deleted
+}
+
+// ProgressEvent is a progress update a task reported with progress.update
+type ProgressEvent struct {
+	Time    int64   `json:"time"`    // Unix milliseconds
+	Percent float64 `json:"percent"` // -1 when the update had none
+	Message string  `json:"message,omitempty"`
 }
 
 // HistoryDB manages execution history in SQLite
@@ -61,7 +95,7 @@ type HistoryDB struct {
 
 // NewHistoryDB creates a new history database connection
 func NewHistoryDB(dbPath string) (*HistoryDB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -109,20 +143,57 @@ func (h *HistoryDB) initialize() error {
 		output TEXT,
 		error TEXT,
 		changed INTEGER DEFAULT 0,
+		cpu_time_ms INTEGER DEFAULT 0,
+		peak_rss_bytes INTEGER DEFAULT 0,
+		read_bytes INTEGER DEFAULT 0,
+		write_bytes INTEGER DEFAULT 0,
+		progress TEXT,
+		workspace_changes TEXT,
+		attempts TEXT,
 		created_at INTEGER DEFAULT (strftime('%s', 'now')),
 		FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
 	);
 
+	CREATE TABLE IF NOT EXISTS task_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		stack TEXT NOT NULL,
+		run_id TEXT NOT NULL,
+		task_name TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER DEFAULT 1,
+		recovered INTEGER DEFAULT 0,
+		quarantined INTEGER DEFAULT 0,
+		start_time INTEGER NOT NULL
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_executions_workflow ON executions(workflow_name);
 	CREATE INDEX IF NOT EXISTS idx_executions_status ON executions(status);
 	CREATE INDEX IF NOT EXISTS idx_executions_start_time ON executions(start_time DESC);
 	CREATE INDEX IF NOT EXISTS idx_executions_agent ON executions(agent_name);
 	CREATE INDEX IF NOT EXISTS idx_executions_group ON executions(group_name);
 	CREATE INDEX IF NOT EXISTS idx_task_executions_execution ON task_executions(execution_id);
+	CREATE INDEX IF NOT EXISTS idx_task_runs_stack_task ON task_runs(stack, task_name, start_time DESC);
 	`
 
-	_, err := h.db.Exec(schema)
-	return err
+	if _, err := h.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Add columns introduced after the initial schema; errors mean the
+	// column already exists
+	migrations := []string{
+		"ALTER TABLE task_executions ADD COLUMN cpu_time_ms INTEGER DEFAULT 0",
+		"ALTER TABLE task_executions ADD COLUMN peak_rss_bytes INTEGER DEFAULT 0",
+		"ALTER TABLE task_executions ADD COLUMN read_bytes INTEGER DEFAULT 0",
+		"ALTER TABLE task_executions ADD COLUMN write_bytes INTEGER DEFAULT 0",
+		"ALTER TABLE task_executions ADD COLUMN progress TEXT",
+		"ALTER TABLE task_executions ADD COLUMN workspace_changes TEXT",
+		"ALTER TABLE task_executions ADD COLUMN attempts TEXT",
+	}
+	for _, m := range migrations {
+		h.db.Exec(m)
+	}
+	return nil
 }
 
 // CreateExecution creates a new execution record
@@ -145,6 +216,100 @@ func (h *HistoryDB) CreateExecution(exec *Execution) error {
 	return err
 }
 
+// RecordRun stores a finished execution along with its tasks
+func (h *HistoryDB) RecordRun(exec *Execution, tasks []*TaskExecution) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	metadataJSON, _ := json.Marshal(exec.Metadata)
+	_, err = tx.Exec(`
+		INSERT OR REPLACE INTO executions (
+			id, workflow_name, workflow_file, group_name, status, start_time,
+			end_time, duration, agent_name, user, exit_code, output, error_message,
+			tasks_total, tasks_success, tasks_failed, metadata
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		exec.ID, exec.WorkflowName, exec.WorkflowFile, exec.GroupName,
+		exec.Status, exec.StartTime, exec.EndTime, exec.Duration, exec.AgentName,
+		exec.User, exec.ExitCode, exec.Output, exec.ErrorMessage,
+		exec.TasksTotal, exec.TasksSuccess, exec.TasksFailed, string(metadataJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record execution %s: %w", exec.ID, err)
+	}
+
+	for _, task := range tasks {
+		var progressJSON, workspaceJSON, attemptsJSON sql.NullString
+		if len(task.Progress) > 0 {
+			data, _ := json.Marshal(task.Progress)
+			progressJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		if len(task.WorkspaceChanges) > 0 {
+			data, _ := json.Marshal(task.WorkspaceChanges)
+			workspaceJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		if len(task.Attempts) > 0 {
+			data, _ := json.Marshal(task.Attempts)
+			attemptsJSON = sql.NullString{String: string(data), Valid: true}
+		}
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO task_executions (
+				id, execution_id, task_name, status, start_time, end_time,
+				duration, output, error, changed,
+				cpu_time_ms, peak_rss_bytes, read_bytes, write_bytes, progress,
+				workspace_changes, attempts
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			task.ID, exec.ID, task.TaskName, task.Status, task.StartTime,
+			task.EndTime, task.Duration, task.Output, task.Error, task.Changed,
+			task.CPUTimeMs, task.PeakRSSBytes, task.ReadBytes, task.WriteBytes,
+			progressJSON, workspaceJSON, attemptsJSON,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record task %s: %w", task.TaskName, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ResolveExecutionID returns the ID of the execution whose ID starts with
+// prefix, as shown by history list
+func (h *HistoryDB) ResolveExecutionID(prefix string) (string, error) {
+	if prefix == "" {
+		return "", fmt.Errorf("execution ID is required")
+	}
+
+	rows, err := h.db.Query(`SELECT id FROM executions WHERE substr(id, 1, ?) = ? LIMIT 2`, len(prefix), prefix)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("execution %s not found", prefix)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("execution ID %s is ambiguous, give more of it", prefix)
+}
+
 // UpdateExecution updates an existing execution record
 func (h *HistoryDB) UpdateExecution(exec *Execution) error {
 	query := `
@@ -351,10 +516,13 @@ func (h *HistoryDB) ListExecutions(filters map[string]interface{}, limit, offset
 func (h *HistoryDB) GetTaskExecutions(executionID string) ([]*TaskExecution, error) {
 	query := `
 		SELECT id, execution_id, task_name, status, start_time, end_time,
-			duration, output, error, changed
+			duration, output, error, changed,
+			COALESCE(cpu_time_ms, 0), COALESCE(peak_rss_bytes, 0),
+			COALESCE(read_bytes, 0), COALESCE(write_bytes, 0), progress,
+			workspace_changes, attempts
 		FROM task_executions
 		WHERE execution_id = ?
-		ORDER BY start_time ASC
+		ORDER BY start_time ASC, rowid ASC
 	`
 
 	rows, err := h.db.Query(query, executionID)
@@ -367,12 +535,14 @@ func (h *HistoryDB) GetTaskExecutions(executionID string) ([]*TaskExecution, err
 	for rows.Next() {
 		var task TaskExecution
 		var endTime, duration sql.NullInt64
-		var output, errorStr sql.NullString
+		var output, errorStr, progress, workspaceChanges, attempts sql.NullString
 		var changed int
 
 		err := rows.Scan(
 			&task.ID, &task.ExecutionID, &task.TaskName, &task.Status,
 			&task.StartTime, &endTime, &duration, &output, &errorStr, &changed,
+			&task.CPUTimeMs, &task.PeakRSSBytes, &task.ReadBytes, &task.WriteBytes,
+			&progress, &workspaceChanges, &attempts,
 		)
 		if err != nil {
 			continue
@@ -391,6 +561,15 @@ func (h *HistoryDB) GetTaskExecutions(executionID string) ([]*TaskExecution, err
 			task.Error = errorStr.String
 		}
 		task.Changed = changed == 1
+		if progress.Valid {
+			json.Unmarshal([]byte(progress.String), &task.Progress)
+		}
+		if workspaceChanges.Valid {
+			json.Unmarshal([]byte(workspaceChanges.String), &task.WorkspaceChanges)
+		}
+		if attempts.Valid {
+			json.Unmarshal([]byte(attempts.String), &task.Attempts)
+		}
 
 		tasks = append(tasks, &task)
 	}
@@ -462,10 +641,14 @@ func (h *HistoryDB) DeleteOldExecutions(days int) (int64, error) {
 		return 0, err
 	}
 
+	if _, err := h.db.Exec("DELETE FROM task_runs WHERE start_time < ?", cutoffTime); err != nil {
+		return 0, err
+	}
+
 	return result.RowsAffected()
 }
 
 // Close closes the database connection
 func (h *HistoryDB) Close() error {
-	return h.db.Close()
+	return sqlitedb.Close(h.db)
 }
@@ -0,0 +1,407 @@
+package execution
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusInterrupted is the status of a task that was running when its
+	// run stopped and that no agent finished
+	StatusInterrupted ExecutionStatus = "interrupted"
+	// StatusSkipped is the status of a task that didn't run, e.g. because
+	// a task it depends on failed
+	StatusSkipped ExecutionStatus = "skipped"
+)
+
+// Journal event types
+const (
+	journalStarted      = "started"
+	journalTaskStarted  = "task_started"
+	journalTaskFinished = "task_finished"
+)
+
+// JournalRun describes a run in its journal, enough to record it or to run
+// it again
+type JournalRun struct {
+	RunID    string `json:"run_id"`
+	Stack    string `json:"stack"`
+	File     string `json:"file,omitempty"`
+	Sloth    string `json:"sloth,omitempty"`
+	Workflow string `json:"workflow,omitempty"`
+	// Schedule is the workflow schedule that started the run, if any
+	Schedule  string `json:"schedule,omitempty"`
+	PID       int    `json:"pid"`
+	StartTime int64  `json:"start_time"`
+}
+
+// journalEvent is a line of a run journal
+type journalEvent struct {
+	Type string `json:"type"`
+	Time int64  `json:"time"` // Unix milliseconds
+
+	Run *JournalRun `json:"run,omitempty"`
+
+	Task           string                 `json:"task,omitempty"`
+	Agent          string                 `json:"agent,omitempty"`
+	AgentAddress   string                 `json:"agent_address,omitempty"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+	Status         ExecutionStatus        `json:"status,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+	Outputs        map[string]interface{} `json:"outputs,omitempty"`
+}
+
+// RunJournal is the write-ahead journal of a run: every task request is
+// written, and synced, before it is sent to an agent, and every outcome
+// once it is known. A run that stops without closing its journal leaves it
+// behind, for the master to find what it was doing when it restarts.
+type RunJournal struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// OpenRunJournal starts the journal of run in dir. A journal the run
+// already has, because it resumes an interrupted one, is appended to.
+func OpenRunJournal(dir string, run JournalRun) (*RunJournal, error) {
+	if run.RunID == "" {
+		return nil, errors.New("run journal needs a run ID")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create run journal directory: %w", err)
+	}
+	j, err := appendJournal(journalPath(dir, run.RunID), os.O_CREATE)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.write(journalEvent{Type: journalStarted, Run: &run}); err != nil {
+		j.file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// appendJournal opens a journal to append to it. A last line cut short by
+// a crash is ended, so the events appended after it can be read.
+func appendJournal(path string, flag int) (*RunJournal, error) {
+	file, err := os.OpenFile(path, flag|os.O_APPEND|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run journal: %w", err)
+	}
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := file.Write([]byte{'\n'}); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("failed to write run journal: %w", err)
+			}
+		}
+	}
+	return &RunJournal{file: file, path: path}, nil
+}
+
+func journalPath(dir, runID string) string {
+	return filepath.Join(dir, runID+".jsonl")
+}
+
+// TaskStarted records that task is about to run: locally when agent is
+// empty, otherwise on an agent that is sent it with idempotency key, which
+// the agent is asked about when the run is reconciled
+func (j *RunJournal) TaskStarted(task, agent, agentAddress, key string) error {
+	return j.write(journalEvent{
+		Type:           journalTaskStarted,
+		Task:           task,
+		Agent:          agent,
+		AgentAddress:   agentAddress,
+		IdempotencyKey: key,
+	})
+}
+
+// TaskFinished records the outcome of task, with the outputs a resumed run
+// gives to the tasks depending on it
+func (j *RunJournal) TaskFinished(task string, status ExecutionStatus, errMsg string, outputs map[string]interface{}) error {
+	return j.write(journalEvent{
+		Type:    journalTaskFinished,
+		Task:    task,
+		Status:  status,
+		Error:   errMsg,
+		Outputs: outputs,
+	})
+}
+
+// Close removes the journal of a run that ended and was recorded
+func (j *RunJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	j.file.Close()
+	j.file = nil
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// write appends an event and syncs it, so it survives the process
+func (j *RunJournal) write(event journalEvent) error {
+	if event.Time == 0 {
+		event.Time = time.Now().UnixMilli()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode run journal event: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return errors.New("run journal is closed")
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write run journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+// TaskDispatch is a start of a task: a request the run sent an agent, or a
+// local run when Agent is empty
+type TaskDispatch struct {
+	Agent          string `json:"agent"`
+	AgentAddress   string `json:"agent_address"`
+	IdempotencyKey string `json:"idempotency_key"`
+	Time           int64  `json:"time"`
+}
+
+// JournaledTask is what the journal of a run tells about one of its tasks
+type JournaledTask struct {
+	Name       string                 `json:"name"`
+	Dispatches []TaskDispatch         `json:"dispatches,omitempty"`
+	Status     ExecutionStatus        `json:"status"` // running until it finished
+	Error      string                 `json:"error,omitempty"`
+	Outputs    map[string]interface{} `json:"outputs,omitempty"`
+	StartTime  int64                  `json:"start_time"` // Unix milliseconds
+	EndTime    int64                  `json:"end_time,omitempty"`
+}
+
+// Finished reports whether the outcome of the task is known
+func (t *JournaledTask) Finished() bool {
+	return t.Status != StatusRunning
+}
+
+// InterruptedRun is a run whose journal was left behind
+type InterruptedRun struct {
+	JournalRun
+	// Attempts is how many times the run started: once, plus once per
+	// resumption
+	Attempts int              `json:"attempts"`
+	Tasks    []*JournaledTask `json:"tasks"`
+	path     string
+}
+
+// Task returns the task named name, nil if the run didn't start it
+func (r *InterruptedRun) Task(name string) *JournaledTask {
+	for _, t := range r.Tasks {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Reconcile sets the outcome of task name, found once the run stopped,
+// and appends it to the journal so a resumption of the run knows it
+func (r *InterruptedRun) Reconcile(name string, status ExecutionStatus, errMsg string, outputs map[string]interface{}) error {
+	task := r.Task(name)
+	if task == nil {
+		return fmt.Errorf("run %s has no task %s", r.RunID, name)
+	}
+	j, err := appendJournal(r.path, 0)
+	if err != nil {
+		return err
+	}
+	defer j.file.Close()
+
+	now := time.Now().UnixMilli()
+	if err := j.write(journalEvent{Type: journalTaskFinished, Time: now, Task: name, Status: status, Error: errMsg, Outputs: outputs}); err != nil {
+		return err
+	}
+	task.Status, task.Error, task.Outputs, task.EndTime = status, errMsg, outputs, now
+	return nil
+}
+
+// Remove deletes the journal of the run, once it is recorded
+func (r *InterruptedRun) Remove() error {
+	if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// InterruptedRuns returns the runs whose journals are in dir, oldest
+// first. A run still going has one too: callers check its PID.
+func InterruptedRuns(dir string) ([]*InterruptedRun, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var runs []*InterruptedRun
+	for _, path := range paths {
+		run, err := readJournal(path)
+		if err != nil {
+			return nil, err
+		}
+		if run != nil {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartTime < runs[j].StartTime })
+	return runs, nil
+}
+
+// LoadInterruptedRun returns the run runID from its journal in dir
+func LoadInterruptedRun(dir, runID string) (*InterruptedRun, error) {
+	run, err := readJournal(journalPath(dir, runID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("run %s has no journal: it ended or never started", runID)
+		}
+		return nil, err
+	}
+	if run == nil {
+		return nil, fmt.Errorf("run %s has an empty journal", runID)
+	}
+	return run, nil
+}
+
+// readJournal replays a journal. A line cut short by a crash is ignored;
+// it was never acted upon, as events are synced before.
+func readJournal(path string) (*InterruptedRun, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run journal: %w", err)
+	}
+	defer file.Close()
+
+	var run *InterruptedRun
+	tasks := make(map[string]*JournaledTask)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var event journalEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+
+		switch event.Type {
+		case journalStarted:
+			if event.Run == nil {
+				continue
+			}
+			if run == nil {
+				run = &InterruptedRun{JournalRun: *event.Run, path: path}
+			} else {
+				// A resumption keeps the start of the first attempt
+				startTime := run.StartTime
+				run.JournalRun = *event.Run
+				run.StartTime = startTime
+			}
+			run.Attempts++
+		case journalTaskStarted, journalTaskFinished:
+			if run == nil {
+				continue
+			}
+			task, ok := tasks[event.Task]
+			if !ok {
+				task = &JournaledTask{Name: event.Task, Status: StatusRunning, StartTime: event.Time}
+				tasks[event.Task] = task
+				run.Tasks = append(run.Tasks, task)
+			}
+			if event.Type == journalTaskStarted {
+				if task.Finished() {
+					// Run again by a resumption
+					*task = JournaledTask{Name: task.Name, Status: StatusRunning, StartTime: event.Time}
+				}
+				task.Dispatches = append(task.Dispatches, TaskDispatch{
+					Agent:          event.Agent,
+					AgentAddress:   event.AgentAddress,
+					IdempotencyKey: event.IdempotencyKey,
+					Time:           event.Time,
+				})
+			} else {
+				task.Status = event.Status
+				task.Error = event.Error
+				task.Outputs = event.Outputs
+				task.EndTime = event.Time
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run journal %s: %w", path, err)
+	}
+	return run, nil
+}
+
+// Record builds the history records of an interrupted run whose tasks were
+// reconciled, failing it with reason
+func (r *InterruptedRun) Record(reason string, endTime time.Time) (*Execution, []*TaskExecution) {
+	exec := &Execution{
+		ID:           r.RunID,
+		WorkflowName: r.Workflow,
+		WorkflowFile: r.File,
+		Status:       StatusFailed,
+		StartTime:    r.StartTime / 1000,
+		EndTime:      endTime.Unix(),
+		Duration:     endTime.UnixMilli() - r.StartTime,
+		ExitCode:     1,
+		ErrorMessage: reason,
+		Metadata:     map[string]interface{}{"stack": r.Stack, "interrupted": true},
+	}
+	if r.Workflow == "" {
+		exec.WorkflowName = r.Stack
+	}
+	if r.Schedule != "" {
+		exec.Metadata["schedule"] = r.Schedule
+	}
+
+	var tasks []*TaskExecution
+	for _, t := range r.Tasks {
+		task := &TaskExecution{
+			ID:          r.RunID + "/" + t.Name,
+			ExecutionID: r.RunID,
+			TaskName:    t.Name,
+			Status:      t.Status,
+			StartTime:   t.StartTime / 1000,
+			Error:       t.Error,
+		}
+		if t.EndTime > 0 {
+			task.EndTime = t.EndTime / 1000
+			task.Duration = t.EndTime - t.StartTime
+		}
+		if len(t.Outputs) > 0 {
+			task.Output = FormatOutput(t.Outputs)
+		}
+		switch t.Status {
+		case StatusCompleted:
+			exec.TasksSuccess++
+		case StatusFailed, StatusInterrupted:
+			exec.TasksFailed++
+		}
+		tasks = append(tasks, task)
+	}
+	exec.TasksTotal = len(tasks)
+	return exec, tasks
+}
@@ -0,0 +1,105 @@
+package execution
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunJournal(t *testing.T) {
+	dir := t.TempDir()
+	j, err := OpenRunJournal(dir, JournalRun{RunID: "run-1", Stack: "prod", Schedule: "nightly", PID: 4242, StartTime: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.TaskStarted("build", "", "", "")
+	j.TaskFinished("build", StatusCompleted, "", map[string]interface{}{"artifact": "app.tar.gz"})
+	j.TaskStarted("deploy", "web-01", "10.0.0.21:50051", "key-1")
+	j.TaskStarted("deploy", "web-01", "10.0.0.21:50051", "key-2")
+
+	// A line cut short by a crash is ignored
+	f, err := os.OpenFile(filepath.Join(dir, "run-1.jsonl"), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"task_fin`)
+	f.Close()
+
+	runs, err := InterruptedRuns(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("Expected 1 interrupted run, got %d", len(runs))
+	}
+	run := runs[0]
+	if run.RunID != "run-1" || run.Schedule != "nightly" || run.PID != 4242 || run.Attempts != 1 {
+		t.Errorf("Unexpected run: %+v", run.JournalRun)
+	}
+	if len(run.Tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(run.Tasks))
+	}
+	build, deploy := run.Task("build"), run.Task("deploy")
+	if !build.Finished() || build.Outputs["artifact"] != "app.tar.gz" {
+		t.Errorf("Expected build to be completed with its outputs, got %+v", build)
+	}
+	if deploy.Finished() || len(deploy.Dispatches) != 2 || deploy.Dispatches[1].IdempotencyKey != "key-2" {
+		t.Errorf("Expected deploy to be running with both requests, got %+v", deploy)
+	}
+
+	// Reconciled outcomes are journaled for the resumption
+	if err := run.Reconcile("deploy", StatusInterrupted, "the agent didn't finish it", nil); err != nil {
+		t.Fatal(err)
+	}
+	run, err = LoadInterruptedRun(dir, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Task("deploy").Status != StatusInterrupted {
+		t.Errorf("Expected the reconciled status to be journaled, got %s", run.Task("deploy").Status)
+	}
+
+	// The resumption runs deploy again and ends the run
+	j, err = OpenRunJournal(dir, JournalRun{RunID: "run-1", Stack: "prod", PID: 4343, StartTime: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.TaskStarted("deploy", "web-01", "10.0.0.21:50051", "key-3")
+	run, _ = LoadInterruptedRun(dir, "run-1")
+	if run.Attempts != 2 || run.PID != 4343 || run.StartTime != 1000 {
+		t.Errorf("Expected the second attempt of the run started at 1000, got %+v", run.JournalRun)
+	}
+	if deploy := run.Task("deploy"); deploy.Finished() || len(deploy.Dispatches) != 1 {
+		t.Errorf("Expected deploy to run again with a new request, got %+v", deploy)
+	}
+
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadInterruptedRun(dir, "run-1"); err == nil {
+		t.Error("Expected the journal of an ended run to be removed")
+	}
+}
+
+func TestInterruptedRunRecord(t *testing.T) {
+	run := &InterruptedRun{
+		JournalRun: JournalRun{RunID: "run-1", Stack: "prod", Workflow: "deploy", Schedule: "nightly", StartTime: 1000},
+		Tasks: []*JournaledTask{
+			{Name: "build", Status: StatusCompleted, StartTime: 1000, EndTime: 3000},
+			{Name: "deploy", Status: StatusInterrupted, Error: "agent web-01: the agent didn't finish it", StartTime: 3000, EndTime: 9000},
+			{Name: "smoke", Status: StatusSkipped, StartTime: 9000, EndTime: 9000},
+		},
+	}
+
+	exec, tasks := run.Record("interrupted: the process running it stopped", time.UnixMilli(10000))
+	if exec.ID != "run-1" || exec.Status != StatusFailed || exec.Duration != 9000 || exec.Metadata["schedule"] != "nightly" {
+		t.Errorf("Unexpected execution record: %+v", exec)
+	}
+	if exec.TasksTotal != 3 || exec.TasksSuccess != 1 || exec.TasksFailed != 1 {
+		t.Errorf("Expected 3 tasks, 1 completed and 1 failed, got %d, %d and %d", exec.TasksTotal, exec.TasksSuccess, exec.TasksFailed)
+	}
+	if tasks[1].Status != StatusInterrupted || tasks[1].Duration != 6000 || tasks[1].ID != "run-1/deploy" {
+		t.Errorf("Unexpected task record: %+v", tasks[1])
+	}
+}
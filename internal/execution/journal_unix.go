@@ -0,0 +1,21 @@
+//go:build !windows
+
+package execution
+
+import (
+	"os"
+	"syscall"
+)
+
+// Running reports whether the process of the run is still going, in which
+// case its journal isn't left behind
+func (r *InterruptedRun) Running() bool {
+	if r.PID <= 0 || r.PID == os.Getpid() {
+		return false
+	}
+	process, err := os.FindProcess(r.PID)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
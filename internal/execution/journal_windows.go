@@ -0,0 +1,20 @@
+//go:build windows
+
+package execution
+
+import "os"
+
+// Running reports whether the process of the run is still going, in which
+// case its journal isn't left behind
+func (r *InterruptedRun) Running() bool {
+	if r.PID <= 0 || r.PID == os.Getpid() {
+		return false
+	}
+	// FindProcess opens the process on Windows, failing when it exited
+	process, err := os.FindProcess(r.PID)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
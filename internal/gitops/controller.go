@@ -0,0 +1,155 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// DefaultCheckInterval is how often the controller looks for configuration
+// changes and due syncs
+const DefaultCheckInterval = 30 * time.Second
+
+// Controller runs on the master: it reconciles the repository every sync
+// interval and keeps a cron entry for each synced workflow that declares a
+// schedule in its front-matter. Schedules follow the store, so syncs run by
+// 'gitops sync' are picked up too.
+type Controller struct {
+	reconciler *Reconciler
+	cron       *cron.Cron
+	// RunWorkflow runs a scheduled workflow on a stack
+	RunWorkflow func(stack, name string) error
+	// CheckInterval is how often configuration and schedules are checked
+	CheckInterval time.Duration
+
+	mu      sync.Mutex
+	entries map[string]scheduledEntry
+}
+
+type scheduledEntry struct {
+	id       cron.EntryID
+	schedule string
+	stack    string
+}
+
+// NewController creates a controller for a reconciler
+func NewController(reconciler *Reconciler) *Controller {
+	return &Controller{
+		reconciler:    reconciler,
+		cron:          cron.New(),
+		RunWorkflow:   runWorkflow,
+		CheckInterval: DefaultCheckInterval,
+		entries:       make(map[string]scheduledEntry),
+	}
+}
+
+// runWorkflow runs a saved sloth with this executable
+func runWorkflow(stack, name string) error {
+	self, err := os.Executable()
+	if err != nil {
+		self = "sloth-runner"
+	}
+	cmd := exec.Command(self, "run", stack, "--sloth", name, "--yes")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Run reconciles and schedules until ctx is done
+func (c *Controller) Run(ctx context.Context) {
+	c.cron.Start()
+	defer c.cron.Stop()
+
+	ticker := time.NewTicker(c.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		c.Step(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step reconciles when a sync is due and updates the schedules
+func (c *Controller) Step(ctx context.Context) {
+	store := c.reconciler.store
+	cfg, err := store.Config()
+	if err != nil {
+		// Not configured yet
+		c.syncSchedules(nil)
+		return
+	}
+
+	if cfg.Enabled {
+		if state, err := store.State(); err == nil && c.reconciler.now().Sub(state.LastSync) >= cfg.Interval {
+			report, err := c.reconciler.Reconcile(ctx)
+			if err != nil {
+				slog.Error("GitOps sync failed", "repo", cfg.Repo, "error", err)
+			} else {
+				slog.Info("GitOps sync completed", "commit", report.Commit,
+					"added", len(report.Added), "updated", len(report.Updated),
+					"removed", len(report.Removed), "invalid", len(report.Invalid))
+			}
+		}
+	}
+
+	var files []*SyncedFile
+	if cfg.Enabled {
+		if files, err = store.Files(); err != nil {
+			slog.Error("Failed to read GitOps schedules", "error", err)
+			return
+		}
+	}
+	c.syncSchedules(files)
+}
+
+// syncSchedules makes the cron entries match the schedules of files
+func (c *Controller) syncSchedules(files []*SyncedFile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	desired := make(map[string]scheduledEntry)
+	for _, f := range files {
+		if f.Name != "" && f.Schedule != "" {
+			desired[f.Name] = scheduledEntry{schedule: f.Schedule, stack: f.Stack}
+		}
+	}
+
+	for name, entry := range c.entries {
+		if want, ok := desired[name]; !ok || want.schedule != entry.schedule || want.stack != entry.stack {
+			c.cron.Remove(entry.id)
+			delete(c.entries, name)
+			slog.Info("GitOps schedule removed", "workflow", name)
+		}
+	}
+
+	for name, want := range desired {
+		if _, ok := c.entries[name]; ok {
+			continue
+		}
+		name, stack := name, want.stack
+		id, err := c.cron.AddFunc(want.schedule, func() {
+			slog.Info("Running scheduled GitOps workflow", "workflow", name, "stack", stack)
+			if err := c.RunWorkflow(stack, name); err != nil {
+				slog.Error("Scheduled GitOps workflow failed", "workflow", name, "error", err)
+			}
+		})
+		if err != nil {
+			// The schedule was validated when the file was synced
+			slog.Error("Invalid GitOps schedule", "workflow", name, "error", fmt.Errorf("%q: %w", want.schedule, err))
+			continue
+		}
+		want.id = id
+		c.entries[name] = want
+		slog.Info("GitOps schedule registered", "workflow", name, "schedule", want.schedule, "stack", stack)
+	}
+}
@@ -0,0 +1,336 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/google/uuid"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Tag marks the saved sloths registered by gitops
+const Tag = "gitops"
+
+// SyncReport lists what a reconciliation changed, by file path
+type SyncReport struct {
+	Commit    string            `json:"commit"`
+	Added     []string          `json:"added,omitempty"`
+	Updated   []string          `json:"updated,omitempty"`
+	Removed   []string          `json:"removed,omitempty"`
+	Unchanged []string          `json:"unchanged,omitempty"`
+	Invalid   map[string]string `json:"invalid,omitempty"`
+}
+
+// Reconciler makes the saved sloths match the .sloth files of the gitops
+// repository: new and changed files that validate are registered, removed
+// files are deregistered, and files that don't validate keep their last
+// valid version. Running it again without new commits changes nothing.
+type Reconciler struct {
+	store       *Store
+	sloths      sloth.Repository
+	checkoutDir string
+	// git runs a git command in dir and returns its output
+	git func(ctx context.Context, dir string, args ...string) (string, error)
+	now func() time.Time
+}
+
+// NewReconciler creates a reconciler that checks the repository out in
+// checkoutDir and registers workflows in sloths
+func NewReconciler(store *Store, sloths sloth.Repository, checkoutDir string) *Reconciler {
+	return &Reconciler{
+		store:       store,
+		sloths:      sloths,
+		checkoutDir: checkoutDir,
+		git:         runGit,
+		now:         time.Now,
+	}
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Never wait for credentials on a terminal nobody is watching
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Reconcile fetches the repository and syncs its workflows
+func (r *Reconciler) Reconcile(ctx context.Context) (*SyncReport, error) {
+	cfg, err := r.store.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := r.reconcile(ctx, cfg)
+	state := &SyncState{LastSync: r.now()}
+	if err != nil {
+		state.LastError = err.Error()
+		if previous, stateErr := r.store.State(); stateErr == nil {
+			state.LastCommit = previous.LastCommit
+		}
+	} else {
+		state.LastCommit = report.Commit
+	}
+	if saveErr := r.store.saveState(state); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return report, err
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, cfg *SyncConfig) (*SyncReport, error) {
+	commit, err := r.checkout(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(r.checkoutDir, filepath.FromSlash(cfg.Path))
+	if rel, err := filepath.Rel(r.checkoutDir, root); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("path %q is outside the repository", cfg.Path)
+	}
+	paths, err := findSlothFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked, err := r.store.Files()
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]*SyncedFile, len(tracked))
+	owners := make(map[string]string, len(tracked))
+	for _, f := range tracked {
+		byPath[f.Path] = f
+		if f.Name != "" {
+			owners[f.Name] = f.Path
+		}
+	}
+
+	report := &SyncReport{Commit: commit, Invalid: map[string]string{}}
+	seen := make(map[string]bool, len(paths))
+	for _, rel := range paths {
+		seen[rel] = true
+	}
+
+	// Deregister removed files first, so a workflow moved to another file
+	// in the same commit keeps its name
+	for _, f := range tracked {
+		if seen[f.Path] {
+			continue
+		}
+		if f.Name != "" {
+			delete(owners, f.Name)
+			if err := r.sloths.Delete(ctx, f.Name); err != nil && !errors.Is(err, sloth.ErrSlothNotFound) {
+				return nil, fmt.Errorf("failed to deregister %s: %w", f.Name, err)
+			}
+		}
+		if err := r.store.deleteFile(f.Path); err != nil {
+			return nil, err
+		}
+		report.Removed = append(report.Removed, f.Path)
+	}
+
+	for _, rel := range paths {
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		previous := byPath[rel]
+		hash := fmt.Sprintf("%x", sha256.Sum256(content))
+		if previous != nil && previous.Hash == hash && previous.Error == "" && r.registered(ctx, previous) {
+			report.Unchanged = append(report.Unchanged, rel)
+			continue
+		}
+
+		file, err := r.register(ctx, rel, filepath.Join(root, filepath.FromSlash(rel)), content, hash, commit, previous, owners)
+		if err != nil {
+			report.Invalid[rel] = err.Error()
+			file = &SyncedFile{Path: rel, Error: err.Error()}
+			if previous != nil {
+				kept := *previous
+				kept.Error = err.Error()
+				file = &kept
+			}
+		} else if previous == nil || previous.Hash == "" {
+			report.Added = append(report.Added, rel)
+		} else {
+			report.Updated = append(report.Updated, rel)
+		}
+		if err := r.store.putFile(file); err != nil {
+			return nil, err
+		}
+		if file.Name != "" {
+			owners[file.Name] = rel
+		}
+	}
+
+	if len(report.Invalid) == 0 {
+		report.Invalid = nil
+	}
+	return report, nil
+}
+
+// registered reports whether the saved sloth of a file is still the synced
+// version, so sloths removed or edited by hand are restored
+func (r *Reconciler) registered(ctx context.Context, f *SyncedFile) bool {
+	saved, err := r.sloths.GetByName(ctx, f.Name)
+	return err == nil && saved.FileHash == f.Hash
+}
+
+// register validates a file and saves it as a sloth
+func (r *Reconciler) register(ctx context.Context, rel, path string, content []byte, hash, commit string, previous *SyncedFile, owners map[string]string) (*SyncedFile, error) {
+	meta, body, err := sloth.ParseFrontMatter(content)
+	if err != nil {
+		return nil, err
+	}
+	// Templates are only Lua once rendered with the run's values
+	if !meta.Template {
+		if _, err := parse.Parse(bytes.NewReader(body), rel); err != nil {
+			return nil, fmt.Errorf("invalid Lua: %w", err)
+		}
+	}
+
+	name := meta.Name
+	if name == "" {
+		name = strings.ReplaceAll(strings.TrimSuffix(rel, ".sloth"), "/", "-")
+	}
+	if owner, ok := owners[name]; ok && owner != rel {
+		return nil, fmt.Errorf("workflow name %q is already used by %s", name, owner)
+	}
+
+	existing, err := r.sloths.GetByName(ctx, name)
+	if err != nil && !errors.Is(err, sloth.ErrSlothNotFound) {
+		return nil, err
+	}
+	if existing != nil && owners[name] != rel {
+		return nil, fmt.Errorf("a saved sloth named %q exists and is not managed by gitops", name)
+	}
+
+	now := r.now()
+	s := &sloth.Sloth{
+		Name:        name,
+		Description: meta.Description,
+		Version:     meta.Version,
+		FilePath:    path,
+		Content:     string(content),
+		IsActive:    true,
+		UpdatedAt:   now,
+		Tags:        Tag,
+		FileHash:    hash,
+	}
+	if existing != nil {
+		s.IsActive = existing.IsActive
+		err = r.sloths.Update(ctx, s)
+	} else {
+		s.ID = uuid.New().String()
+		s.CreatedAt = now
+		err = r.sloths.Create(ctx, s)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A renamed workflow leaves its old registration behind
+	if previous != nil && previous.Name != "" && previous.Name != name {
+		if err := r.sloths.Delete(ctx, previous.Name); err != nil && !errors.Is(err, sloth.ErrSlothNotFound) {
+			return nil, err
+		}
+		delete(owners, previous.Name)
+	}
+
+	stack := meta.Stack
+	if stack == "" {
+		stack = name
+	}
+	return &SyncedFile{
+		Path:     rel,
+		Name:     name,
+		Hash:     hash,
+		Schedule: meta.Schedule,
+		Stack:    stack,
+		Commit:   commit,
+		SyncedAt: now,
+	}, nil
+}
+
+// checkout clones the repository or updates the existing checkout to the
+// tip of the branch, returning its commit
+func (r *Reconciler) checkout(ctx context.Context, cfg *SyncConfig) (string, error) {
+	if remote, err := r.git(ctx, r.checkoutDir, "remote", "get-url", "origin"); err != nil || remote != cfg.Repo {
+		if err := os.RemoveAll(r.checkoutDir); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(r.checkoutDir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(r.checkoutDir), 0755); err != nil {
+			return "", err
+		}
+		args := []string{"clone", "--depth", "1"}
+		if cfg.Branch != "" {
+			args = append(args, "--branch", cfg.Branch)
+		}
+		args = append(args, cfg.Repo, r.checkoutDir)
+		if _, err := r.git(ctx, filepath.Dir(r.checkoutDir), args...); err != nil {
+			return "", err
+		}
+	} else {
+		ref := cfg.Branch
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := r.git(ctx, r.checkoutDir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, r.checkoutDir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+		if _, err := r.git(ctx, r.checkoutDir, "clean", "-fdx"); err != nil {
+			return "", err
+		}
+	}
+
+	return r.git(ctx, r.checkoutDir, "rev-parse", "HEAD")
+}
+
+// findSlothFiles returns the .sloth files under root, relative to it with
+// forward slashes. A missing root has no files: git drops directories
+// whose workflows were all removed.
+func findSlothFiles(root string) ([]string, error) {
+	var paths []string
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() && strings.HasSuffix(d.Name(), ".sloth") {
+			rel, _ := filepath.Rel(root, path)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,272 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+)
+
+// testRepo is a git repository workflows are committed to
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	r.git("config", "user.email", "test@example.com")
+	r.git("config", "user.name", "test")
+	return r
+}
+
+func (r *testRepo) git(args ...string) {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func (r *testRepo) write(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) commit() {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", "update")
+}
+
+func newTestReconciler(t *testing.T, repo *testRepo) (*Reconciler, *sloth.SQLiteRepository) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewStore(filepath.Join(dir, "gitops.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	sloths, err := sloth.NewSQLiteRepository(filepath.Join(dir, "sloths.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sloths.Close() })
+
+	cfg := &SyncConfig{Repo: repo.dir, Branch: "main", Path: "workflows", Interval: 5 * time.Minute, Enabled: true}
+	if err := store.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return NewReconciler(store, sloths, filepath.Join(dir, "checkout")), sloths
+}
+
+const deployWorkflow = `---
+name: deploy
+schedule: "0 2 * * *"
+stack: prod
+---
+local t = task("deploy"):command(function() return true end):build()
+`
+
+func TestReconciler_Reconcile(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("workflows/deploy.sloth", deployWorkflow)
+	repo.write("workflows/db/backup.sloth", "print('backup')\n")
+	repo.write("README.md", "not a workflow")
+	repo.commit()
+
+	r, sloths := newTestReconciler(t, repo)
+	ctx := context.Background()
+
+	report, err := r.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"db/backup.sloth", "deploy.sloth"}; !reflect.DeepEqual(report.Added, want) {
+		t.Errorf("Added = %v, want %v", report.Added, want)
+	}
+	deploy, err := sloths.GetByName(ctx, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deploy.Tags != Tag || !deploy.IsActive {
+		t.Errorf("deploy = %+v", deploy)
+	}
+	if _, err := sloths.GetByName(ctx, "db-backup"); err != nil {
+		t.Errorf("files without a name are registered by path: %v", err)
+	}
+
+	files, _ := r.store.Files()
+	if len(files) != 2 || files[1].Schedule != "0 2 * * *" || files[1].Stack != "prod" || files[0].Stack != "db-backup" {
+		t.Errorf("files = %+v %+v", files[0], files[1])
+	}
+
+	// Nothing changed: nothing is rewritten
+	report, err = r.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added)+len(report.Updated)+len(report.Removed) != 0 || len(report.Unchanged) != 2 {
+		t.Errorf("second sync = %+v", report)
+	}
+
+	// Sloths removed by hand are restored
+	if err := sloths.Delete(ctx, "db-backup"); err != nil {
+		t.Fatal(err)
+	}
+	report, err = r.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"db/backup.sloth"}) {
+		t.Errorf("drift sync = %+v", report)
+	}
+
+	// Break deploy, remove backup
+	repo.write("workflows/deploy.sloth", "---\nname: deploy\nschedule: every day\n---\n")
+	repo.git("rm", "-q", "workflows/db/backup.sloth")
+	repo.commit()
+
+	report, err = r.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Removed, []string{"db/backup.sloth"}) || report.Invalid["deploy.sloth"] == "" {
+		t.Errorf("third sync = %+v", report)
+	}
+	if _, err := sloths.GetByName(ctx, "db-backup"); !errors.Is(err, sloth.ErrSlothNotFound) {
+		t.Errorf("removed files are deregistered: %v", err)
+	}
+	kept, err := sloths.GetByName(ctx, "deploy")
+	if err != nil || kept.FileHash != deploy.FileHash {
+		t.Errorf("an invalid file keeps its last valid version: %v", err)
+	}
+	files, _ = r.store.Files()
+	if len(files) != 1 || files[0].Schedule != "0 2 * * *" || files[0].Error == "" {
+		t.Errorf("files = %+v", files)
+	}
+
+	// Fixing it registers the new version
+	repo.write("workflows/deploy.sloth", "---\nname: deploy\n---\nprint('v2')\n")
+	repo.commit()
+	report, err = r.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Updated, []string{"deploy.sloth"}) {
+		t.Errorf("fourth sync = %+v", report)
+	}
+	files, _ = r.store.Files()
+	if files[0].Error != "" || files[0].Schedule != "" {
+		t.Errorf("files = %+v", files)
+	}
+
+	state, err := r.store.State()
+	if err != nil || state.LastError != "" || state.LastCommit != report.Commit {
+		t.Errorf("state = %+v, %v", state, err)
+	}
+}
+
+func TestReconciler_Conflicts(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("workflows/a.sloth", "---\nname: web\n---\n")
+	repo.write("workflows/b.sloth", "---\nname: web\n---\n")
+	repo.write("workflows/manual.sloth", "---\nname: manual\n---\n")
+	repo.write("workflows/syntax.sloth", "local x = \n")
+	repo.commit()
+
+	r, sloths := newTestReconciler(t, repo)
+	ctx := context.Background()
+	if err := sloths.Create(ctx, &sloth.Sloth{ID: "1", Name: "manual", Content: "", FileHash: "x", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := r.Reconcile(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Added, []string{"a.sloth"}) {
+		t.Errorf("Added = %v", report.Added)
+	}
+	for _, path := range []string{"b.sloth", "manual.sloth", "syntax.sloth"} {
+		if report.Invalid[path] == "" {
+			t.Errorf("%s should be invalid: %+v", path, report.Invalid)
+		}
+	}
+	if manual, _ := sloths.GetByName(ctx, "manual"); manual == nil || manual.Tags == Tag {
+		t.Error("sloths not managed by gitops must be left alone")
+	}
+}
+
+func TestReconciler_RepositoryError(t *testing.T) {
+	repo := newTestRepo(t)
+	r, _ := newTestReconciler(t, repo)
+	cfg, _ := r.store.Config()
+	cfg.Repo = filepath.Join(t.TempDir(), "missing")
+	r.store.SaveConfig(cfg)
+
+	if _, err := r.Reconcile(context.Background()); err == nil {
+		t.Fatal("syncing a missing repository should fail")
+	}
+	state, _ := r.store.State()
+	if state.LastError == "" || state.LastSync.IsZero() {
+		t.Errorf("state = %+v", state)
+	}
+}
+
+func TestController_Schedules(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("workflows/deploy.sloth", deployWorkflow)
+	repo.commit()
+
+	r, _ := newTestReconciler(t, repo)
+	c := NewController(r)
+	ctx := context.Background()
+
+	c.Step(ctx)
+	if entry, ok := c.entries["deploy"]; !ok || entry.stack != "prod" || entry.schedule != "0 2 * * *" {
+		t.Fatalf("entries = %+v", c.entries)
+	}
+
+	// Not due yet: the repository isn't fetched again
+	repo.git("rm", "-q", "workflows/deploy.sloth")
+	repo.commit()
+	c.Step(ctx)
+	if len(c.entries) != 1 {
+		t.Errorf("synced before the interval elapsed: %+v", c.entries)
+	}
+
+	r.now = func() time.Time { return time.Now().Add(time.Hour) }
+	c.Step(ctx)
+	if len(c.entries) != 0 {
+		t.Errorf("removed workflows are deregistered: %+v", c.entries)
+	}
+
+	if err := r.store.SetEnabled(false); err != nil {
+		t.Fatal(err)
+	}
+	repo.write("workflows/deploy.sloth", deployWorkflow)
+	repo.commit()
+	c.Step(ctx)
+	if len(c.entries) != 0 {
+		t.Errorf("disabled gitops schedules nothing: %+v", c.entries)
+	}
+}
@@ -0,0 +1,250 @@
+package gitops
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+)
+
+// SyncConfig is the repository of workflows the master keeps in sync
+type SyncConfig struct {
+	Repo     string        `json:"repo"`
+	Branch   string        `json:"branch,omitempty"`
+	Path     string        `json:"path"`
+	Interval time.Duration `json:"interval"`
+	Enabled  bool          `json:"enabled"`
+}
+
+// SyncState is the outcome of the last reconciliation
+type SyncState struct {
+	LastSync   time.Time `json:"last_sync"`
+	LastCommit string    `json:"last_commit,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// SyncedFile is a .sloth file of the repository. Name, Hash, Schedule and
+// Stack describe the version last registered; Error is set while the
+// current version of the file doesn't validate, and the last valid version
+// stays registered.
+type SyncedFile struct {
+	Path     string    `json:"path"`
+	Name     string    `json:"name,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+	Schedule string    `json:"schedule,omitempty"`
+	Stack    string    `json:"stack,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	SyncedAt time.Time `json:"synced_at"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Store keeps the gitops configuration and sync status in SQLite
+type Store struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// NewStore opens (creating if needed) the gitops store at dbPath
+func NewStore(dbPath string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create gitops directory: %w", err)
+	}
+
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gitops database: %w", err)
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS gitops_config (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		repo TEXT NOT NULL,
+		branch TEXT DEFAULT '',
+		path TEXT DEFAULT '',
+		interval_seconds INTEGER NOT NULL,
+		enabled INTEGER NOT NULL,
+		last_sync INTEGER DEFAULT 0,
+		last_commit TEXT DEFAULT '',
+		last_error TEXT DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS gitops_files (
+		path TEXT PRIMARY KEY,
+		name TEXT DEFAULT '',
+		hash TEXT DEFAULT '',
+		schedule TEXT DEFAULT '',
+		stack TEXT DEFAULT '',
+		commit_hash TEXT DEFAULT '',
+		synced_at INTEGER DEFAULT 0,
+		error TEXT DEFAULT ''
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		sqlitedb.Close(db)
+		return nil, fmt.Errorf("failed to initialize gitops database: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// DefaultStore opens the gitops store in the data directory
+func DefaultStore() (*Store, error) {
+	return NewStore(config.GetGitOpsDBPath())
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return sqlitedb.Close(s.db)
+}
+
+// ErrNotConfigured is returned before gitops was ever enabled
+var ErrNotConfigured = errors.New("gitops is not configured, run 'sloth-runner gitops enable'")
+
+// Config returns the sync configuration
+func (s *Store) Config() (*SyncConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cfg SyncConfig
+	var interval int64
+	err := s.db.QueryRow(`SELECT repo, branch, path, interval_seconds, enabled FROM gitops_config WHERE id = 1`).
+		Scan(&cfg.Repo, &cfg.Branch, &cfg.Path, &interval, &cfg.Enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotConfigured
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gitops config: %w", err)
+	}
+	cfg.Interval = time.Duration(interval) * time.Second
+	return &cfg, nil
+}
+
+// SaveConfig replaces the sync configuration. Changing the repository
+// resets the sync state so the next reconciliation runs right away.
+func (s *Store) SaveConfig(cfg *SyncConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO gitops_config (id, repo, branch, path, interval_seconds, enabled)
+		VALUES (1, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			last_sync = CASE WHEN repo = excluded.repo AND branch = excluded.branch AND path = excluded.path
+				THEN last_sync ELSE 0 END,
+			repo = excluded.repo, branch = excluded.branch, path = excluded.path,
+			interval_seconds = excluded.interval_seconds, enabled = excluded.enabled`,
+		cfg.Repo, cfg.Branch, cfg.Path, int64(cfg.Interval/time.Second), cfg.Enabled)
+	if err != nil {
+		return fmt.Errorf("failed to save gitops config: %w", err)
+	}
+	return nil
+}
+
+// SetEnabled turns syncing on or off, keeping the configuration
+func (s *Store) SetEnabled(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`UPDATE gitops_config SET enabled = ? WHERE id = 1`, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to update gitops config: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotConfigured
+	}
+	return nil
+}
+
+// State returns the outcome of the last reconciliation
+func (s *Store) State() (*SyncState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state SyncState
+	var lastSync int64
+	err := s.db.QueryRow(`SELECT last_sync, last_commit, last_error FROM gitops_config WHERE id = 1`).
+		Scan(&lastSync, &state.LastCommit, &state.LastError)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotConfigured
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gitops state: %w", err)
+	}
+	if lastSync > 0 {
+		state.LastSync = time.Unix(lastSync, 0)
+	}
+	return &state, nil
+}
+
+func (s *Store) saveState(state *SyncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`UPDATE gitops_config SET last_sync = ?, last_commit = ?, last_error = ? WHERE id = 1`,
+		state.LastSync.Unix(), state.LastCommit, state.LastError)
+	if err != nil {
+		return fmt.Errorf("failed to save gitops state: %w", err)
+	}
+	return nil
+}
+
+// Files returns the tracked files, sorted by path
+func (s *Store) Files() ([]*SyncedFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT path, name, hash, schedule, stack, commit_hash, synced_at, error
+		FROM gitops_files ORDER BY path`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gitops files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*SyncedFile
+	for rows.Next() {
+		var f SyncedFile
+		var syncedAt int64
+		if err := rows.Scan(&f.Path, &f.Name, &f.Hash, &f.Schedule, &f.Stack, &f.Commit, &syncedAt, &f.Error); err != nil {
+			return nil, err
+		}
+		if syncedAt > 0 {
+			f.SyncedAt = time.Unix(syncedAt, 0)
+		}
+		files = append(files, &f)
+	}
+	return files, rows.Err()
+}
+
+func (s *Store) putFile(f *SyncedFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var syncedAt int64
+	if !f.SyncedAt.IsZero() {
+		syncedAt = f.SyncedAt.Unix()
+	}
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO gitops_files
+		(path, name, hash, schedule, stack, commit_hash, synced_at, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		f.Path, f.Name, f.Hash, f.Schedule, f.Stack, f.Commit, syncedAt, f.Error)
+	if err != nil {
+		return fmt.Errorf("failed to save gitops file %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+func (s *Store) deleteFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec(`DELETE FROM gitops_files WHERE path = ?`, path); err != nil {
+		return fmt.Errorf("failed to remove gitops file %s: %w", path, err)
+	}
+	return nil
+}
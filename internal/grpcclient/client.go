@@ -6,7 +6,8 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 )
 
 // Connect establishes a gRPC connection to the specified address
@@ -15,7 +16,7 @@ func Connect(address string) (*grpc.ClientConn, error) {
 	defer cancel()
 
 	conn, err := grpc.DialContext(ctx, address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 		grpc.WithBlock(),
 	)
 	if err != nil {
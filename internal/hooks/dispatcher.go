@@ -18,6 +18,7 @@ type Dispatcher struct {
 	processing   bool
 	eventChannel chan *Event
 	maxWorkers   int
+	triggers     *TriggerEngine
 
 	// Execution context for events
 	currentStack  string
@@ -175,6 +176,31 @@ func (d *Dispatcher) DispatchAgentUpdated(agent *AgentEvent) error {
 	return d.Dispatch(event)
 }
 
+// DispatchAgentFactsChanged dispatches an agent.facts_changed event. Each
+// change is a map with the fact name, the change kind (added, removed or
+// changed) and the old and new values.
+func (d *Dispatcher) DispatchAgentFactsChanged(agent *AgentEvent, changes []map[string]interface{}) error {
+	items := make([]interface{}, len(changes))
+	for i, c := range changes {
+		items[i] = c
+	}
+
+	event := &Event{
+		Type:      EventAgentFactsChanged,
+		Timestamp: getCurrentTime(),
+		Data: map[string]interface{}{
+			"agent": map[string]interface{}{
+				"name":    agent.Name,
+				"address": agent.Address,
+			},
+			"changes": items,
+		},
+		Agent: agent.Name,
+	}
+
+	return d.Dispatch(event)
+}
+
 // DispatchTaskStarted dispatches a task.started event
 func (d *Dispatcher) DispatchTaskStarted(task *TaskEvent) error {
 	event := &Event{
@@ -240,6 +266,50 @@ func (d *Dispatcher) DispatchTaskFailed(task *TaskEvent) error {
 	return d.Dispatch(event)
 }
 
+// DispatchTaskRetrying dispatches a task.retrying event, raised before a
+// failed task runs again
+func (d *Dispatcher) DispatchTaskRetrying(task *TaskEvent) error {
+	event := &Event{
+		Type:      EventTaskRetrying,
+		Timestamp: getCurrentTime(),
+		Data: map[string]interface{}{
+			"task": map[string]interface{}{
+				"task_name":    task.TaskName,
+				"agent_name":   task.AgentName,
+				"status":       task.Status,
+				"error":        task.Error,
+				"attempt":      task.Attempt,
+				"max_attempts": task.MaxAttempts,
+				"delay":        task.Duration,
+			},
+		},
+		Stack:  task.Stack,
+		Agent:  task.AgentName,
+		RunID:  task.RunID,
+	}
+
+	return d.Dispatch(event)
+}
+
+// DispatchSystemEvent dispatches a system event, e.g. system.disk_full
+func (d *Dispatcher) DispatchSystemEvent(eventType EventType, system *SystemEvent) error {
+	event := &Event{
+		Type:      eventType,
+		Timestamp: getCurrentTime(),
+		Data: map[string]interface{}{
+			"system": map[string]interface{}{
+				"type":      system.Type,
+				"message":   system.Message,
+				"component": system.Component,
+				"severity":  system.Severity,
+				"metrics":   system.Metrics,
+			},
+		},
+	}
+
+	return d.Dispatch(event)
+}
+
 // Enable enables the dispatcher
 func (d *Dispatcher) Enable() {
 	d.mu.Lock()
@@ -256,6 +326,20 @@ func (d *Dispatcher) Disable() {
 	slog.Info("hook dispatcher disabled")
 }
 
+// SetTriggers sets the event triggers that processed events are fed to
+func (d *Dispatcher) SetTriggers(triggers *TriggerEngine) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.triggers = triggers
+}
+
+// Triggers returns the event triggers, or nil when there are none
+func (d *Dispatcher) Triggers() *TriggerEngine {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.triggers
+}
+
 // IsEnabled returns whether the dispatcher is enabled
 func (d *Dispatcher) IsEnabled() bool {
 	d.mu.RLock()
@@ -297,6 +381,10 @@ func (d *Dispatcher) StopEventProcessor() {
 	}
 	d.mu.Unlock()
 
+	if triggers := d.Triggers(); triggers != nil {
+		triggers.Stop()
+	}
+
 	// Close stop channel to signal all workers
 	close(d.stopChan)
 
@@ -409,6 +497,10 @@ func (d *Dispatcher) processEvent(event *Event) {
 		return
 	}
 
+	if triggers := d.Triggers(); triggers != nil {
+		triggers.Observe(event)
+	}
+
 	// Get all enabled hooks for this event type
 	hooks, err := d.repo.ListByEventType(event.Type)
 	if err != nil {
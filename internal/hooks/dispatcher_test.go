@@ -397,6 +397,34 @@ func TestDispatchTaskFailed(t *testing.T) {
 	}
 }
 
+// Test DispatchTaskRetrying
+func TestDispatchTaskRetrying(t *testing.T) {
+	repo, err := NewRepository()
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	dispatcher := NewDispatcher(repo)
+	defer dispatcher.StopEventProcessor()
+
+	task := &TaskEvent{
+		TaskName:    "test-task",
+		AgentName:   "local",
+		Status:      "retrying",
+		Error:       "connection refused",
+		Duration:    "10s",
+		Attempt:     2,
+		MaxAttempts: 4,
+		RunID:       "run-123",
+	}
+
+	err = dispatcher.DispatchTaskRetrying(task)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
 // Test StartEventProcessor
 func TestStartEventProcessor(t *testing.T) {
 	repo, err := NewRepository()
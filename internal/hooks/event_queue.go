@@ -1,11 +1,13 @@
 package hooks
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	"github.com/google/uuid"
 )
 
@@ -99,7 +101,7 @@ func (eq *EventQueue) EnqueueEvent(event *Event) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = eq.db.Exec(query,
+	_, err = sqlitedb.Exec(context.Background(), eq.db, query,
 		event.ID,
 		event.Type,
 		string(dataJSON),
@@ -212,6 +214,50 @@ func (eq *EventQueue) ListEvents(eventType EventType, status EventStatus, limit
 	return events, rows.Err()
 }
 
+// SequencedEvent is an event with its position in the queue
+type SequencedEvent struct {
+	Seq int64
+	*Event
+}
+
+// ListEventsAfter returns the events enqueued after position after, oldest
+// first, so exports can resume where they stopped
+func (eq *EventQueue) ListEventsAfter(after int64, limit int) ([]SequencedEvent, error) {
+	rows, err := eq.db.Query(`
+		SELECT rowid, id, type, data, status, error, timestamp, created_at, processed_at, stack, agent, run_id
+		FROM events
+		WHERE rowid > ?
+		ORDER BY rowid ASC LIMIT ?
+	`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []SequencedEvent
+	for rows.Next() {
+		var seq int64
+		event, err := eq.scanEvent(seqScanner{rows, &seq})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, SequencedEvent{Seq: seq, Event: event})
+	}
+
+	return events, rows.Err()
+}
+
+// seqScanner scans a leading rowid column before handing the rest of the
+// row to scanEvent
+type seqScanner struct {
+	rows *sql.Rows
+	seq  *int64
+}
+
+func (s seqScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append([]interface{}{s.seq}, dest...)...)
+}
+
 // ListEventsByAgent returns events for a specific agent with optional filters
 func (eq *EventQueue) ListEventsByAgent(agent string, eventType EventType, status EventStatus, limit int) ([]*Event, error) {
 	query := `
@@ -273,7 +319,7 @@ func (eq *EventQueue) UpdateEventStatus(id string, status EventStatus, errorMsg
 		WHERE id = ?
 	`
 
-	_, err := eq.db.Exec(query, status, errorMsg, now.Unix(), id)
+	_, err := sqlitedb.Exec(context.Background(), eq.db, query, status, errorMsg, now.Unix(), id)
 	return err
 }
 
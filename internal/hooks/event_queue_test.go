@@ -359,6 +359,64 @@ func TestListEvents(t *testing.T) {
 	}
 }
 
+func TestListEventsAfter(t *testing.T) {
+	repo, err := NewRepository()
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	eq := repo.EventQueue
+
+	existing, err := eq.ListEventsAfter(0, 1000000)
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	var cursor int64
+	if len(existing) > 0 {
+		cursor = existing[len(existing)-1].Seq
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		event := &Event{
+			Type:      EventTaskStarted,
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"n": i},
+			Stack:     "audit-stack",
+		}
+		if err := eq.EnqueueEvent(event); err != nil {
+			t.Fatalf("Failed to enqueue event: %v", err)
+		}
+		ids = append(ids, event.ID)
+	}
+
+	events, err := eq.ListEventsAfter(cursor, 2)
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].ID != ids[0] || events[1].ID != ids[1] {
+		t.Errorf("Expected the oldest events first, got %s and %s", events[0].ID, events[1].ID)
+	}
+	if events[0].Seq <= cursor || events[1].Seq <= events[0].Seq {
+		t.Errorf("Expected increasing positions after %d, got %d and %d", cursor, events[0].Seq, events[1].Seq)
+	}
+	if events[0].Stack != "audit-stack" {
+		t.Errorf("Expected stack audit-stack, got %s", events[0].Stack)
+	}
+
+	rest, err := eq.ListEventsAfter(events[1].Seq, 10)
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != ids[2] {
+		t.Errorf("Expected only the last event after the cursor, got %d events", len(rest))
+	}
+}
+
 func TestListEvents_FilterByType(t *testing.T) {
 	repo, err := NewRepository()
 	if err != nil {
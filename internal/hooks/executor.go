@@ -2,10 +2,12 @@ package hooks
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
@@ -13,6 +15,9 @@ import (
 	lua "github.com/yuin/gopher-lua"
 )
 
+// DefaultHookTimeout bounds a hook run when the hook sets no timeout
+const DefaultHookTimeout = 30 * time.Second
+
 // Executor executes hook scripts
 type Executor struct {
 	repo *Repository
@@ -25,7 +30,20 @@ func NewExecutor(repo *Repository) *Executor {
 	}
 }
 
-// Execute executes a hook with the given event data
+// hookOutcome is what a single isolated hook run reports back
+type hookOutcome struct {
+	success bool
+	message string
+	err     error
+}
+
+// Execute executes a hook with the given event data.
+//
+// Each run gets its own Lua state with every sloth-runner module loaded,
+// so hooks have the same API as workflows (http, exec, notifications, ...).
+// The run is bounded by the hook timeout (DefaultHookTimeout if unset) and
+// isolated in its own goroutine, so a hanging or panicking hook cannot
+// block or crash the dispatcher.
 func (e *Executor) Execute(hook *Hook, event *Event) (*HookResult, error) {
 	startTime := time.Now()
 
@@ -42,87 +60,142 @@ func (e *Executor) Execute(hook *Hook, event *Event) (*HookResult, error) {
 		return result, fmt.Errorf("%s", result.Error)
 	}
 
-	// Create Lua state
-	L := lua.NewState()
-	defer L.Close()
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
 	// Capture output
-	var outputBuf bytes.Buffer
-	var errorBuf bytes.Buffer
+	outputBuf := &syncBuffer{}
+	errorBuf := &syncBuffer{}
+
+	done := make(chan hookOutcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- hookOutcome{err: fmt.Errorf("hook panicked: %v", r)}
+			}
+		}()
+		done <- e.run(ctx, hook, event, outputBuf, errorBuf)
+	}()
+
+	var outcome hookOutcome
+	select {
+	case outcome = <-done:
+	case <-ctx.Done():
+		outcome = hookOutcome{err: fmt.Errorf("hook timed out after %s", timeout)}
+	}
 
-	// Register event data in Lua
-	if err := e.registerEvent(L, event); err != nil {
+	result.Output = outputBuf.String()
+	result.Duration = time.Since(startTime)
+
+	if outcome.err != nil {
 		result.Success = false
-		result.Error = fmt.Sprintf("failed to register event: %v", err)
-		result.Duration = time.Since(startTime)
-		return result, err
+		result.Error = outcome.err.Error()
+		return result, outcome.err
 	}
 
-	// Register custom functions
-	e.registerCustomFunctions(L, &outputBuf, &errorBuf)
+	result.Success = outcome.success
+	if !result.Success {
+		if outcome.message != "" {
+			result.Error = outcome.message
+		} else if errorBuf.Len() > 0 {
+			result.Error = errorBuf.String()
+		}
+	}
+
+	return result, nil
+}
+
+// run executes the hook file in a fresh Lua state. The hook either defines
+// a global on_event(event) function or returns a function from the file;
+// returning false (optionally with a message) marks the run as failed.
+func (e *Executor) run(ctx context.Context, hook *Hook, event *Event, out, errOut io.Writer) hookOutcome {
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(ctx)
 
 	// Load all sloth-runner modules
+	luainterface.RegisterAllModules(L)
 	registry := modules.GetGlobalRegistry()
 	if err := registry.LoadAllModules(L); err != nil {
-		result.Success = false
-		result.Error = fmt.Sprintf("failed to load modules: %v", err)
-		result.Output = outputBuf.String()
-		result.Duration = time.Since(startTime)
-		return result, err
+		return hookOutcome{err: fmt.Errorf("failed to load modules: %w", err)}
+	}
+
+	// Register event data in Lua
+	if err := e.registerEvent(L, event); err != nil {
+		return hookOutcome{err: fmt.Errorf("failed to register event: %w", err)}
 	}
 
-	// Load file_ops module globally (without require)
-	fileOpsModule := luainterface.NewFileOpsModule()
-	fileOpsModule.Loader(L)
-	L.SetGlobal("file_ops", L.Get(-1))
-	L.Pop(1)
+	// Register custom functions
+	e.registerCustomFunctions(L, out, errOut)
 
 	// Execute the hook file
+	top := L.GetTop()
 	if err := L.DoFile(hook.FilePath); err != nil {
-		result.Success = false
-		result.Error = fmt.Sprintf("execution error: %v", err)
-		result.Output = outputBuf.String()
-		result.Duration = time.Since(startTime)
-		return result, err
+		return hookOutcome{err: fmt.Errorf("execution error: %w", err)}
 	}
 
-	// Check if hook function exists and call it
 	hookFn := L.GetGlobal("on_event")
-	if hookFn.Type() == lua.LTFunction {
-		// Call the hook function
-		if err := L.CallByParam(lua.P{
-			Fn:      hookFn,
-			NRet:    1,
-			Protect: true,
-		}); err != nil {
-			result.Success = false
-			result.Error = fmt.Sprintf("hook function error: %v", err)
-			result.Output = outputBuf.String()
-			result.Duration = time.Since(startTime)
-			return result, err
+	if L.GetTop() > top {
+		if fn, ok := L.Get(top + 1).(*lua.LFunction); ok {
+			hookFn = fn
 		}
+		L.SetTop(top)
+	}
 
-		// Get return value (success boolean)
-		ret := L.Get(-1)
-		L.Pop(1)
+	// No handler found, consider it successful if no errors
+	if hookFn.Type() != lua.LTFunction {
+		return hookOutcome{success: true}
+	}
 
-		if ret.Type() == lua.LTBool {
-			result.Success = lua.LVAsBool(ret)
-		} else {
-			result.Success = true // Default to success if no boolean returned
-		}
-	} else {
-		// No on_event function found, consider it successful if no errors
-		result.Success = true
+	if err := L.CallByParam(lua.P{
+		Fn:      hookFn,
+		NRet:    2,
+		Protect: true,
+	}, L.GetGlobal("event")); err != nil {
+		return hookOutcome{err: fmt.Errorf("hook function error: %w", err)}
 	}
 
-	result.Output = outputBuf.String()
-	if errorBuf.Len() > 0 && !result.Success {
-		result.Error = errorBuf.String()
+	// Get return values (success boolean, optional message)
+	ret, msg := L.Get(-2), L.Get(-1)
+	L.Pop(2)
+
+	outcome := hookOutcome{success: true}
+	if ret.Type() == lua.LTBool {
+		outcome.success = lua.LVAsBool(ret)
 	}
-	result.Duration = time.Since(startTime)
+	if msg != lua.LNil {
+		outcome.message = msg.String()
+	}
+	return outcome
+}
 
-	return result, nil
+// syncBuffer is a bytes.Buffer safe for concurrent use, since a timed-out
+// hook may still be writing while its output is collected
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
 }
 
 // registerEvent registers the event data in Lua state
@@ -218,11 +291,14 @@ func (e *Executor) goValueToLua(L *lua.LState, val interface{}) (lua.LValue, err
 	}
 }
 
-// registerCustomFunctions registers custom Lua functions
+// registerCustomFunctions registers custom Lua functions. Log output is
+// captured into the hook result while the rest of the log module is kept.
 func (e *Executor) registerCustomFunctions(L *lua.LState, out, err io.Writer) {
-	// log.info function
-	L.SetGlobal("log", L.NewTable())
-	logTable := L.GetGlobal("log").(*lua.LTable)
+	logTable, ok := L.GetGlobal("log").(*lua.LTable)
+	if !ok {
+		logTable = L.NewTable()
+		L.SetGlobal("log", logTable)
+	}
 
 	logTable.RawSetString("info", L.NewFunction(func(L *lua.LState) int {
 		msg := L.CheckString(1)
@@ -248,18 +324,6 @@ func (e *Executor) registerCustomFunctions(L *lua.LState, out, err io.Writer) {
 		return 0
 	}))
 
-	// http module for sending webhooks (basic implementation)
-	L.SetGlobal("http", L.NewTable())
-	httpTable := L.GetGlobal("http").(*lua.LTable)
-
-	httpTable.RawSetString("post", L.NewFunction(func(L *lua.LState) int {
-		url := L.CheckString(1)
-		// Basic stub - in real implementation, would send HTTP POST
-		fmt.Fprintf(out, "[HTTP] POST to %s\n", url)
-		L.Push(lua.LTrue)
-		return 1
-	}))
-
 	// Helper function to check if list contains value
 	L.SetGlobal("contains", L.NewFunction(func(L *lua.LState) int {
 		list := L.CheckTable(1)
@@ -4,8 +4,12 @@
 package hooks
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -709,10 +713,10 @@ func TestRegisterCustomFunctions(t *testing.T) {
 		t.Error("Expected log to be a table")
 	}
 
-	// Verify http table exists
+	// Verify http is left to the real http module
 	httpTable := L.GetGlobal("http")
-	if httpTable.Type() != lua.LTTable {
-		t.Error("Expected http to be a table")
+	if httpTable.Type() != lua.LTNil {
+		t.Error("Expected http not to be stubbed")
 	}
 
 	// Verify contains function exists
@@ -783,14 +787,22 @@ func TestExecute_HTTPPost(t *testing.T) {
 
 	executor := NewExecutor(repo)
 
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r.Method + " " + string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
 	tmpDir := t.TempDir()
 	hookFile := filepath.Join(tmpDir, "http_test.lua")
 
 	script := `
 function on_event()
-	local success = http.post("https://example.com/webhook")
-	log.info("HTTP POST result: " .. tostring(success))
-	return true
+	local resp, err = http.post("` + server.URL + `", "task done")
+	log.info("HTTP POST status: " .. tostring(resp and resp.status_code))
+	return resp ~= nil, err
 end
 `
 
@@ -818,6 +830,10 @@ end
 	if !result.Success {
 		t.Errorf("Expected success, got: %s", result.Error)
 	}
+
+	if received != "POST task done" {
+		t.Errorf("Expected webhook to receive the POST, got %q", received)
+	}
 }
 
 // TestExecute_ContainsFunction tests using contains helper function
@@ -985,3 +1001,101 @@ end
 		t.Error("Duration should not be negative")
 	}
 }
+
+// TestExecute_Timeout tests that a hook running past its timeout is stopped
+func TestExecute_Timeout(t *testing.T) {
+	repo, _ := NewRepository()
+	defer repo.Close()
+
+	executor := NewExecutor(repo)
+
+	hookFile := filepath.Join(t.TempDir(), "slow.sloth")
+	os.WriteFile(hookFile, []byte(`
+function on_event(event)
+	while true do end
+end
+`), 0644)
+
+	hook := &Hook{
+		ID:        "slow-hook",
+		Name:      "slow",
+		FilePath:  hookFile,
+		EventType: EventTaskStarted,
+		Timeout:   100 * time.Millisecond,
+	}
+
+	event := &Event{
+		Type:      EventTaskStarted,
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{},
+	}
+
+	start := time.Now()
+	result, err := executor.Execute(hook, event)
+
+	if err == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if result.Success {
+		t.Error("Expected result.Success to be false")
+	}
+	if !strings.Contains(result.Error, "timed out") {
+		t.Errorf("Expected timeout error, got: %s", result.Error)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Hook was not stopped at its timeout, took %s", time.Since(start))
+	}
+}
+
+// TestExecute_ReturnedHandler tests a hook file that returns its handler,
+// receiving the event payload as an argument and failing with a message
+func TestExecute_ReturnedHandler(t *testing.T) {
+	repo, _ := NewRepository()
+	defer repo.Close()
+
+	executor := NewExecutor(repo)
+
+	hookFile := filepath.Join(t.TempDir(), "handler.sloth")
+	os.WriteFile(hookFile, []byte(`
+return function(evt)
+	if evt.data.agent_name ~= "web-01" then
+		return false, "unexpected agent " .. tostring(evt.data.agent_name)
+	end
+	return exec ~= nil and notifications ~= nil, "modules missing"
+end
+`), 0644)
+
+	hook := &Hook{
+		ID:        "handler-hook",
+		Name:      "handler",
+		FilePath:  hookFile,
+		EventType: EventAgentRegistered,
+	}
+
+	result, err := executor.Execute(hook, &Event{
+		Type:      EventAgentRegistered,
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"agent_name": "web-01"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.Success {
+		t.Errorf("Expected success, got: %s", result.Error)
+	}
+
+	result, err = executor.Execute(hook, &Event{
+		Type:      EventAgentRegistered,
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"agent_name": "db-01"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Success {
+		t.Error("Expected failure for unexpected agent")
+	}
+	if result.Error != "unexpected agent db-01" {
+		t.Errorf("Expected hook message as error, got: %q", result.Error)
+	}
+}
@@ -0,0 +1,260 @@
+package hooks
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// IngestPath is where external systems post events
+const IngestPath = "/events"
+
+// IngestEventPrefix prefixes the type of every ingested event, so posted
+// events can't pass for the ones sloth-runner raises itself
+const IngestEventPrefix = "custom."
+
+// maxIngestBody bounds the size of a posted event
+const maxIngestBody = 1 << 20
+
+var ingestNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// ErrUnauthenticated is returned by authenticators that don't recognize a
+// request
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator identifies the system posting an event. It returns the
+// name of the source, or ErrUnauthenticated.
+type Authenticator interface {
+	Authenticate(r *http.Request) (string, error)
+}
+
+// TokenAuthenticator accepts requests carrying one of its bearer tokens.
+// Tokens map a source name to its token.
+type TokenAuthenticator struct {
+	tokens map[string]string
+}
+
+// NewTokenAuthenticator creates an authenticator for tokens
+func NewTokenAuthenticator(tokens map[string]string) *TokenAuthenticator {
+	return &TokenAuthenticator{tokens: tokens}
+}
+
+// LoadTokenAuthenticator reads tokens from a file with one
+// "<source>:<token>" per line; blank lines and lines starting with # are
+// skipped
+func LoadTokenAuthenticator(path string) (*TokenAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token file: %w", err)
+	}
+	defer f.Close()
+
+	tokens := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source, token, ok := strings.Cut(line, ":")
+		source, token = strings.TrimSpace(source), strings.TrimSpace(token)
+		if !ok || source == "" || token == "" {
+			return nil, fmt.Errorf("%s:%d: expected <source>:<token>", path, n)
+		}
+		if _, dup := tokens[source]; dup {
+			return nil, fmt.Errorf("%s:%d: duplicate source %s", path, n, source)
+		}
+		tokens[source] = token
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no tokens in %s", path)
+	}
+	return NewTokenAuthenticator(tokens), nil
+}
+
+// Authenticate implements Authenticator
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (string, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", ErrUnauthenticated
+	}
+	// Compare with every token so timing doesn't tell which source matched
+	match := ""
+	for source, want := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			match = source
+		}
+	}
+	if match == "" {
+		return "", ErrUnauthenticated
+	}
+	return match, nil
+}
+
+// ClientCertAuthenticator accepts requests over TLS that present a client
+// certificate the server verified. The source is the certificate's common
+// name; when names are set, only those are accepted.
+type ClientCertAuthenticator struct {
+	names map[string]bool
+}
+
+// NewClientCertAuthenticator creates an authenticator for client
+// certificates, limited to names when any are given
+func NewClientCertAuthenticator(names ...string) *ClientCertAuthenticator {
+	a := &ClientCertAuthenticator{}
+	if len(names) > 0 {
+		a.names = map[string]bool{}
+		for _, name := range names {
+			a.names[name] = true
+		}
+	}
+	return a
+}
+
+// Authenticate implements Authenticator
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", ErrUnauthenticated
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if name == "" || (a.names != nil && !a.names[name]) {
+		return "", ErrUnauthenticated
+	}
+	return name, nil
+}
+
+// ClientCertTLSConfig returns the server side of ClientCertAuthenticator:
+// client certificates are verified against the CA when presented, so
+// clients using tokens can still connect
+func ClientCertTLSConfig(config *tls.Config) *tls.Config {
+	config = config.Clone()
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return config
+}
+
+// anyAuthenticator accepts requests any of its authenticators accepts
+type anyAuthenticator []Authenticator
+
+// AnyAuthenticator combines authenticators; the first that recognizes a
+// request names its source
+func AnyAuthenticator(auths ...Authenticator) Authenticator {
+	return anyAuthenticator(auths)
+}
+
+// Authenticate implements Authenticator
+func (a anyAuthenticator) Authenticate(r *http.Request) (string, error) {
+	for _, auth := range a {
+		source, err := auth.Authenticate(r)
+		if err == nil {
+			return source, nil
+		}
+		if !errors.Is(err, ErrUnauthenticated) {
+			return "", err
+		}
+	}
+	return "", ErrUnauthenticated
+}
+
+// IngestRequest is the body of POST /events
+type IngestRequest struct {
+	// Name becomes the event type custom.<name>
+	Name    string                 `json:"name"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+	// Agent and Stack are what triggers and hooks see as the event's
+	// agent and stack
+	Agent string `json:"agent,omitempty"`
+	Stack string `json:"stack,omitempty"`
+}
+
+// IngestResponse is returned for accepted events
+type IngestResponse struct {
+	ID   string    `json:"id"`
+	Type EventType `json:"type"`
+}
+
+// IngestServer lets external systems post events over HTTP:
+//
+//	POST /events  {"name": "disk_full", "payload": {...}, "agent": "web-1"}
+//
+// The event is dispatched as custom.<name> with the CustomEvent data, so
+// hooks and triggers bound to that type run for it.
+type IngestServer struct {
+	auth     Authenticator
+	dispatch func(*Event) error
+}
+
+// NewIngestServer creates a server that authenticates requests with auth
+// and hands events to dispatch
+func NewIngestServer(auth Authenticator, dispatch func(*Event) error) *IngestServer {
+	return &IngestServer{auth: auth, dispatch: dispatch}
+}
+
+// Handler returns the HTTP handler of the server
+func (s *IngestServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(IngestPath, s.handleEvent)
+	return mux
+}
+
+func (s *IngestServer) handleEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, err := s.auth.Authenticate(r)
+	if err != nil {
+		if !errors.Is(err, ErrUnauthenticated) {
+			slog.Error("event ingestion authentication failed", "error", err)
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req IngestRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "invalid event: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ingestNamePattern.MatchString(req.Name) {
+		http.Error(w, "invalid event: name must be letters, digits, '_', '-' and '.'", http.StatusBadRequest)
+		return
+	}
+
+	event := &Event{
+		Type:      EventType(IngestEventPrefix + req.Name),
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"name":    req.Name,
+			"payload": req.Payload,
+			"source":  source,
+		},
+		Agent: req.Agent,
+		Stack: req.Stack,
+	}
+	if err := s.dispatch(event); err != nil {
+		slog.Error("failed to dispatch ingested event", "type", event.Type, "source", source, "error", err)
+		http.Error(w, "failed to dispatch event", http.StatusServiceUnavailable)
+		return
+	}
+	slog.Info("event ingested", "event_id", event.ID, "type", event.Type, "source", source)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(IngestResponse{ID: event.ID, Type: event.Type})
+}
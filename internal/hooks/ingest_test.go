@@ -0,0 +1,145 @@
+package hooks
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func postEvent(t *testing.T, handler http.Handler, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, IngestPath, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIngestServer(t *testing.T) {
+	var got []*Event
+	auth := NewTokenAuthenticator(map[string]string{"prometheus": "s3cret"})
+	handler := NewIngestServer(auth, func(e *Event) error {
+		e.ID = "evt-1"
+		got = append(got, e)
+		return nil
+	}).Handler()
+
+	rec := postEvent(t, handler, "s3cret", `{"name": "disk_full", "payload": {"host": "web-1"}, "agent": "web-1"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"type":"custom.disk_full"`) {
+		t.Errorf("response = %s", rec.Body)
+	}
+	if len(got) != 1 {
+		t.Fatalf("dispatched %d events", len(got))
+	}
+	e := got[0]
+	if e.Type != "custom.disk_full" || e.Agent != "web-1" || e.Data["source"] != "prometheus" {
+		t.Errorf("event = %+v", e)
+	}
+	if payload, _ := e.Data["payload"].(map[string]interface{}); payload["host"] != "web-1" {
+		t.Errorf("payload = %v", e.Data["payload"])
+	}
+
+	for _, tc := range []struct {
+		token, body string
+		status      int
+	}{
+		{"", `{"name": "x"}`, http.StatusUnauthorized},
+		{"wrong", `{"name": "x"}`, http.StatusUnauthorized},
+		{"s3cret", `{"name": "../x"}`, http.StatusBadRequest},
+		{"s3cret", `{"name": ""}`, http.StatusBadRequest},
+		{"s3cret", `{"name": "x", "type": "agent.registered"}`, http.StatusBadRequest},
+		{"s3cret", `not json`, http.StatusBadRequest},
+	} {
+		if rec := postEvent(t, handler, tc.token, tc.body); rec.Code != tc.status {
+			t.Errorf("token %q body %s: status = %d, want %d", tc.token, tc.body, rec.Code, tc.status)
+		}
+	}
+	if len(got) != 1 {
+		t.Errorf("rejected requests dispatched events: %d", len(got))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, IngestPath, nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d", rec.Code)
+	}
+}
+
+func TestIngestServer_DispatchFailure(t *testing.T) {
+	auth := NewTokenAuthenticator(map[string]string{"prometheus": "s3cret"})
+	handler := NewIngestServer(auth, func(e *Event) error {
+		return errors.New("hook dispatcher is disabled")
+	}).Handler()
+
+	rec := postEvent(t, handler, "s3cret", `{"name": "disk_full"}`)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	withCert := func(cn string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, IngestPath, nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return req
+	}
+
+	source, err := NewClientCertAuthenticator().Authenticate(withCert("alertmanager"))
+	if err != nil || source != "alertmanager" {
+		t.Errorf("source = %q, %v", source, err)
+	}
+	if _, err := NewClientCertAuthenticator("grafana").Authenticate(withCert("alertmanager")); err != ErrUnauthenticated {
+		t.Errorf("unlisted name: err = %v", err)
+	}
+	if _, err := NewClientCertAuthenticator().Authenticate(httptest.NewRequest(http.MethodPost, IngestPath, nil)); err != ErrUnauthenticated {
+		t.Errorf("no certificate: err = %v", err)
+	}
+
+	// Either authenticator lets a request in
+	auth := AnyAuthenticator(NewTokenAuthenticator(map[string]string{"ci": "t"}), NewClientCertAuthenticator())
+	if source, _ := auth.Authenticate(withCert("alertmanager")); source != "alertmanager" {
+		t.Errorf("certificate source = %q", source)
+	}
+	req := httptest.NewRequest(http.MethodPost, IngestPath, nil)
+	req.Header.Set("Authorization", "Bearer t")
+	if source, _ := auth.Authenticate(req); source != "ci" {
+		t.Errorf("token source = %q", source)
+	}
+}
+
+func TestLoadTokenAuthenticator(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens")
+	os.WriteFile(path, []byte("# monitoring\nprometheus: abc\n\nci:def\n"), 0600)
+
+	auth, err := LoadTokenAuthenticator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(auth.tokens) != 2 || auth.tokens["prometheus"] != "abc" || auth.tokens["ci"] != "def" {
+		t.Errorf("tokens = %v", auth.tokens)
+	}
+
+	os.WriteFile(path, []byte("no-separator\n"), 0600)
+	if _, err := LoadTokenAuthenticator(path); err == nil {
+		t.Error("expected an error for a malformed line")
+	}
+	os.WriteFile(path, []byte("a:1\na:2\n"), 0600)
+	if _, err := LoadTokenAuthenticator(path); err == nil {
+		t.Error("expected an error for a duplicate source")
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	"github.com/google/uuid"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // Repository manages hook persistence
@@ -25,7 +25,7 @@ func NewRepository() (*Repository, error) {
 	}
 
 	dbPath := config.GetHookDBPath()
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -60,7 +60,8 @@ func (r *Repository) initialize() error {
 		created_at INTEGER NOT NULL,
 		updated_at INTEGER NOT NULL,
 		last_run INTEGER,
-		run_count INTEGER DEFAULT 0
+		run_count INTEGER DEFAULT 0,
+		timeout_ms INTEGER DEFAULT 0
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_hooks_event_type ON hooks(event_type);
@@ -82,8 +83,21 @@ func (r *Repository) initialize() error {
 	CREATE INDEX IF NOT EXISTS idx_executions_executed_at ON hook_executions(executed_at);
 	`
 
-	_, err := r.db.Exec(schema)
-	return err
+	if _, err := r.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Migration: Add new columns if they don't exist (for existing databases)
+	migrations := []string{
+		`ALTER TABLE hooks ADD COLUMN timeout_ms INTEGER DEFAULT 0`,
+	}
+
+	for _, migration := range migrations {
+		// Ignore errors if column already exists
+		r.db.Exec(migration)
+	}
+
+	return nil
 }
 
 // Add adds a new hook
@@ -93,8 +107,8 @@ func (r *Repository) Add(hook *Hook) error {
 	hook.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO hooks (id, name, description, event_type, file_path, stack, enabled, created_at, updated_at, run_count)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO hooks (id, name, description, event_type, file_path, stack, enabled, created_at, updated_at, run_count, timeout_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := r.db.Exec(query,
@@ -108,6 +122,7 @@ func (r *Repository) Add(hook *Hook) error {
 		hook.CreatedAt.Unix(),
 		hook.UpdatedAt.Unix(),
 		hook.RunCount,
+		hook.Timeout.Milliseconds(),
 	)
 
 	if err != nil {
@@ -121,7 +136,7 @@ func (r *Repository) Add(hook *Hook) error {
 func (r *Repository) Get(id string) (*Hook, error) {
 	query := `
 		SELECT id, name, description, event_type, file_path, stack, enabled,
-		       created_at, updated_at, last_run, run_count
+		       created_at, updated_at, last_run, run_count, timeout_ms
 		FROM hooks
 		WHERE id = ?
 	`
@@ -129,7 +144,7 @@ func (r *Repository) Get(id string) (*Hook, error) {
 	var hook Hook
 	var enabled int
 	var createdAt, updatedAt int64
-	var lastRun sql.NullInt64
+	var lastRun, timeoutMS sql.NullInt64
 	var stack sql.NullString
 
 	err := r.db.QueryRow(query, id).Scan(
@@ -144,6 +159,7 @@ func (r *Repository) Get(id string) (*Hook, error) {
 		&updatedAt,
 		&lastRun,
 		&hook.RunCount,
+		&timeoutMS,
 	)
 
 	if err != nil {
@@ -163,6 +179,7 @@ func (r *Repository) Get(id string) (*Hook, error) {
 		t := time.Unix(lastRun.Int64, 0)
 		hook.LastRun = &t
 	}
+	hook.Timeout = time.Duration(timeoutMS.Int64) * time.Millisecond
 
 	return &hook, nil
 }
@@ -171,7 +188,7 @@ func (r *Repository) Get(id string) (*Hook, error) {
 func (r *Repository) GetByName(name string) (*Hook, error) {
 	query := `
 		SELECT id, name, description, event_type, file_path, stack, enabled,
-		       created_at, updated_at, last_run, run_count
+		       created_at, updated_at, last_run, run_count, timeout_ms
 		FROM hooks
 		WHERE name = ?
 	`
@@ -179,7 +196,7 @@ func (r *Repository) GetByName(name string) (*Hook, error) {
 	var hook Hook
 	var enabled int
 	var createdAt, updatedAt int64
-	var lastRun sql.NullInt64
+	var lastRun, timeoutMS sql.NullInt64
 	var stack sql.NullString
 
 	err := r.db.QueryRow(query, name).Scan(
@@ -194,6 +211,7 @@ func (r *Repository) GetByName(name string) (*Hook, error) {
 		&updatedAt,
 		&lastRun,
 		&hook.RunCount,
+		&timeoutMS,
 	)
 
 	if err != nil {
@@ -213,6 +231,7 @@ func (r *Repository) GetByName(name string) (*Hook, error) {
 		t := time.Unix(lastRun.Int64, 0)
 		hook.LastRun = &t
 	}
+	hook.Timeout = time.Duration(timeoutMS.Int64) * time.Millisecond
 
 	return &hook, nil
 }
@@ -221,7 +240,7 @@ func (r *Repository) GetByName(name string) (*Hook, error) {
 func (r *Repository) List() ([]*Hook, error) {
 	query := `
 		SELECT id, name, description, event_type, file_path, stack, enabled,
-		       created_at, updated_at, last_run, run_count
+		       created_at, updated_at, last_run, run_count, timeout_ms
 		FROM hooks
 		ORDER BY name
 	`
@@ -237,7 +256,7 @@ func (r *Repository) List() ([]*Hook, error) {
 		var hook Hook
 		var enabled int
 		var createdAt, updatedAt int64
-		var lastRun sql.NullInt64
+		var lastRun, timeoutMS sql.NullInt64
 		var stack sql.NullString
 
 		err := rows.Scan(
@@ -252,6 +271,7 @@ func (r *Repository) List() ([]*Hook, error) {
 			&updatedAt,
 			&lastRun,
 			&hook.RunCount,
+			&timeoutMS,
 		)
 
 		if err != nil {
@@ -268,6 +288,7 @@ func (r *Repository) List() ([]*Hook, error) {
 			t := time.Unix(lastRun.Int64, 0)
 			hook.LastRun = &t
 		}
+		hook.Timeout = time.Duration(timeoutMS.Int64) * time.Millisecond
 
 		hooks = append(hooks, &hook)
 	}
@@ -279,7 +300,7 @@ func (r *Repository) List() ([]*Hook, error) {
 func (r *Repository) ListByEventType(eventType EventType) ([]*Hook, error) {
 	query := `
 		SELECT id, name, description, event_type, file_path, stack, enabled,
-		       created_at, updated_at, last_run, run_count
+		       created_at, updated_at, last_run, run_count, timeout_ms
 		FROM hooks
 		WHERE event_type = ? AND enabled = 1
 		ORDER BY name
@@ -296,7 +317,7 @@ func (r *Repository) ListByEventType(eventType EventType) ([]*Hook, error) {
 		var hook Hook
 		var enabled int
 		var createdAt, updatedAt int64
-		var lastRun sql.NullInt64
+		var lastRun, timeoutMS sql.NullInt64
 		var stack sql.NullString
 
 		err := rows.Scan(
@@ -311,6 +332,7 @@ func (r *Repository) ListByEventType(eventType EventType) ([]*Hook, error) {
 			&updatedAt,
 			&lastRun,
 			&hook.RunCount,
+			&timeoutMS,
 		)
 
 		if err != nil {
@@ -327,6 +349,7 @@ func (r *Repository) ListByEventType(eventType EventType) ([]*Hook, error) {
 			t := time.Unix(lastRun.Int64, 0)
 			hook.LastRun = &t
 		}
+		hook.Timeout = time.Duration(timeoutMS.Int64) * time.Millisecond
 
 		hooks = append(hooks, &hook)
 	}
@@ -338,7 +361,7 @@ func (r *Repository) ListByEventType(eventType EventType) ([]*Hook, error) {
 func (r *Repository) ListByStack(stack string) ([]*Hook, error) {
 	query := `
 		SELECT id, name, description, event_type, file_path, stack, enabled,
-		       created_at, updated_at, last_run, run_count
+		       created_at, updated_at, last_run, run_count, timeout_ms
 		FROM hooks
 		WHERE stack = ?
 		ORDER BY name
@@ -355,7 +378,7 @@ func (r *Repository) ListByStack(stack string) ([]*Hook, error) {
 		var hook Hook
 		var enabled int
 		var createdAt, updatedAt int64
-		var lastRun sql.NullInt64
+		var lastRun, timeoutMS sql.NullInt64
 		var stackVal sql.NullString
 
 		err := rows.Scan(
@@ -370,6 +393,7 @@ func (r *Repository) ListByStack(stack string) ([]*Hook, error) {
 			&updatedAt,
 			&lastRun,
 			&hook.RunCount,
+			&timeoutMS,
 		)
 
 		if err != nil {
@@ -386,6 +410,7 @@ func (r *Repository) ListByStack(stack string) ([]*Hook, error) {
 			t := time.Unix(lastRun.Int64, 0)
 			hook.LastRun = &t
 		}
+		hook.Timeout = time.Duration(timeoutMS.Int64) * time.Millisecond
 
 		hooks = append(hooks, &hook)
 	}
@@ -400,7 +425,7 @@ func (r *Repository) Update(hook *Hook) error {
 	query := `
 		UPDATE hooks
 		SET name = ?, description = ?, event_type = ?, file_path = ?,
-		    stack = ?, enabled = ?, updated_at = ?
+		    stack = ?, enabled = ?, timeout_ms = ?, updated_at = ?
 		WHERE id = ?
 	`
 
@@ -411,6 +436,7 @@ func (r *Repository) Update(hook *Hook) error {
 		hook.FilePath,
 		hook.Stack,
 		boolToInt(hook.Enabled),
+		hook.Timeout.Milliseconds(),
 		hook.UpdatedAt.Unix(),
 		hook.ID,
 	)
@@ -583,7 +609,7 @@ func (r *Repository) GetExecutionHistory(hookID string, limit int) ([]*HookResul
 
 // Close closes the database connection
 func (r *Repository) Close() error {
-	return r.db.Close()
+	return sqlitedb.Close(r.db)
 }
 
 // Helper functions
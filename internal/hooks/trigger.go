@@ -0,0 +1,350 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Trigger runs a workflow when matching events arrive. Bursts of events are
+// folded into a single run: Debounce waits until events stop arriving for
+// that long, Throttle starts at most one run per period, and events that
+// arrive while a run is going on lead to one more run after it.
+type Trigger struct {
+	Name string `yaml:"name"`
+	// Events are the event types to react to; "file.*" matches every type
+	// starting with "file." and "*" matches any
+	Events []string `yaml:"events"`
+	// WatcherGroup only lets through events from watchers of the group
+	WatcherGroup string `yaml:"watcher_group,omitempty"`
+	// Agent only lets through events from the agent
+	Agent string `yaml:"agent,omitempty"`
+
+	// Stack is the stack the workflow runs with
+	Stack string `yaml:"stack"`
+	// File is the workflow file, Sloth the name of a saved sloth file
+	File   string `yaml:"file,omitempty"`
+	Sloth  string `yaml:"sloth,omitempty"`
+	Values string `yaml:"values,omitempty"`
+	// Workflow and Task narrow the run to a workflow of the file and to a
+	// task of it with the tasks it depends on
+	Workflow string `yaml:"workflow,omitempty"`
+	Task     string `yaml:"task,omitempty"`
+
+	Debounce time.Duration `yaml:"debounce,omitempty"`
+	Throttle time.Duration `yaml:"throttle,omitempty"`
+}
+
+// TriggerFile is the YAML trigger file
+type TriggerFile struct {
+	Triggers []Trigger `yaml:"triggers"`
+}
+
+// Validate checks that the trigger can run
+func (t *Trigger) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("trigger name is required")
+	}
+	if len(t.Events) == 0 {
+		return fmt.Errorf("trigger %s: at least one event type is required", t.Name)
+	}
+	if t.Stack == "" {
+		return fmt.Errorf("trigger %s: stack is required", t.Name)
+	}
+	if (t.File == "") == (t.Sloth == "") {
+		return fmt.Errorf("trigger %s: exactly one of file or sloth is required", t.Name)
+	}
+	if t.Debounce < 0 || t.Throttle < 0 {
+		return fmt.Errorf("trigger %s: debounce and throttle can't be negative", t.Name)
+	}
+	return nil
+}
+
+// Matches reports whether an event should lead to a run of the trigger
+func (t *Trigger) Matches(event *Event) bool {
+	if t.Agent != "" && t.Agent != event.Agent {
+		return false
+	}
+	if t.WatcherGroup != "" {
+		if group, _ := event.Data["watcher_group"].(string); group != t.WatcherGroup {
+			return false
+		}
+	}
+	for _, pattern := range t.Events {
+		if pattern == "*" || pattern == string(event.Type) ||
+			(strings.HasSuffix(pattern, ".*") && strings.HasPrefix(string(event.Type), strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// TriggerRunFunc runs the workflow of a trigger for the events folded into
+// the run
+type TriggerRunFunc func(ctx context.Context, trigger Trigger, events []*Event) error
+
+// TriggerStatus is a snapshot of a trigger's state
+type TriggerStatus struct {
+	Trigger
+	Pending   int       // events waiting for the next run
+	Running   bool      // a run is going on
+	Runs      int64     // runs started
+	Events    int64     // matching events received
+	LastRun   time.Time // start of the last run
+	LastError string    // error of the last run
+}
+
+// maxPendingEvents bounds the events kept for a run; older ones are only
+// counted
+const maxPendingEvents = 100
+
+type triggerState struct {
+	Trigger
+	pending   []*Event
+	dropped   int
+	lastEvent time.Time
+	lastRun   time.Time
+	lastError string
+	running   bool
+	timer     *time.Timer
+	runs      int64
+	events    int64
+}
+
+// TriggerEngine folds matching events into workflow runs
+type TriggerEngine struct {
+	mu       sync.Mutex
+	triggers []*triggerState
+	run      TriggerRunFunc
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewTriggerEngine creates an engine that starts runs with run
+func NewTriggerEngine(triggers []Trigger, run TriggerRunFunc) (*TriggerEngine, error) {
+	if err := validateTriggers(triggers); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &TriggerEngine{run: run, ctx: ctx, cancel: cancel}
+	for _, t := range triggers {
+		e.triggers = append(e.triggers, &triggerState{Trigger: t})
+	}
+	return e, nil
+}
+
+func validateTriggers(triggers []Trigger) error {
+	names := make(map[string]bool)
+	for i := range triggers {
+		if err := triggers[i].Validate(); err != nil {
+			return err
+		}
+		if names[triggers[i].Name] {
+			return fmt.Errorf("duplicate trigger name %s", triggers[i].Name)
+		}
+		names[triggers[i].Name] = true
+	}
+	return nil
+}
+
+// LoadTriggers reads a trigger file and returns an engine that runs
+// workflows with run, such as RunTriggerWorkflow. It returns nil without an
+// error when the file does not exist.
+func LoadTriggers(path string, run TriggerRunFunc) (*TriggerEngine, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trigger file: %w", err)
+	}
+	file, err := ParseTriggerFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trigger file %s: %w", path, err)
+	}
+	return NewTriggerEngine(file.Triggers, run)
+}
+
+// ParseTriggerFile parses and validates the YAML of a trigger file
+func ParseTriggerFile(data []byte) (*TriggerFile, error) {
+	var file TriggerFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if err := validateTriggers(file.Triggers); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Observe feeds an event to the triggers it matches
+func (e *TriggerEngine) Observe(event *Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.ctx.Err() != nil {
+		return
+	}
+	for _, t := range e.triggers {
+		if !t.Matches(event) {
+			continue
+		}
+		t.events++
+		t.lastEvent = time.Now()
+		if len(t.pending) < maxPendingEvents {
+			t.pending = append(t.pending, event)
+		} else {
+			t.dropped++
+		}
+		e.schedule(t)
+	}
+}
+
+// schedule starts a run of t when it is due, or sets a timer for when it
+// will be. Callers hold e.mu.
+func (e *TriggerEngine) schedule(t *triggerState) {
+	if t.running || len(t.pending) == 0 || e.ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	due := t.lastEvent.Add(t.Debounce)
+	if !t.lastRun.IsZero() {
+		if next := t.lastRun.Add(t.Throttle); next.After(due) {
+			due = next
+		}
+	}
+
+	if due.After(now) {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+		t.timer = time.AfterFunc(due.Sub(now), func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.schedule(t)
+		})
+		return
+	}
+
+	events, dropped := t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.running = true
+	t.lastRun = now
+	t.runs++
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		slog.Info("event trigger firing", "trigger", t.Name, "events", len(events)+dropped, "stack", t.Stack)
+		err := e.run(e.ctx, t.Trigger, events)
+		if err != nil {
+			slog.Error("event trigger run failed", "trigger", t.Name, "error", err)
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		t.running = false
+		t.lastError = ""
+		if err != nil {
+			t.lastError = err.Error()
+		}
+		e.schedule(t)
+	}()
+}
+
+// Status returns the state of every trigger
+func (e *TriggerEngine) Status() []TriggerStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	statuses := make([]TriggerStatus, 0, len(e.triggers))
+	for _, t := range e.triggers {
+		statuses = append(statuses, TriggerStatus{
+			Trigger:   t.Trigger,
+			Pending:   len(t.pending) + t.dropped,
+			Running:   t.running,
+			Runs:      t.runs,
+			Events:    t.events,
+			LastRun:   t.lastRun,
+			LastError: t.lastError,
+		})
+	}
+	return statuses
+}
+
+// Stop cancels pending runs and waits for the ones going on
+func (e *TriggerEngine) Stop() {
+	e.mu.Lock()
+	e.cancel()
+	for _, t := range e.triggers {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+	}
+	e.mu.Unlock()
+	e.wg.Wait()
+}
+
+// triggerCommand is the command runs are started with, mockable for tests
+var triggerCommand = exec.CommandContext
+
+// RunTriggerWorkflow runs the workflow of a trigger with sloth-runner run.
+// The run gets the trigger name, the number of events folded into it (up
+// to 100) and the type of the last one in SLOTH_RUNNER_TRIGGER,
+// SLOTH_RUNNER_TRIGGER_EVENTS and SLOTH_RUNNER_TRIGGER_EVENT_TYPE.
+func RunTriggerWorkflow(ctx context.Context, trigger Trigger, events []*Event) error {
+	args := []string{"run", trigger.Stack, "--yes"}
+	if trigger.Sloth != "" {
+		args = append(args, "--sloth", trigger.Sloth)
+	} else {
+		args = append(args, "--file", trigger.File)
+	}
+	if trigger.Values != "" {
+		args = append(args, "--values", trigger.Values)
+	}
+	if trigger.Workflow != "" {
+		args = append(args, "--workflow", trigger.Workflow)
+	}
+	if trigger.Task != "" {
+		args = append(args, "--task", trigger.Task)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "sloth-runner"
+	}
+	cmd := triggerCommand(ctx, executable, args...)
+	cmd.Env = append(os.Environ(),
+		"SLOTH_RUNNER_TRIGGER="+trigger.Name,
+		"SLOTH_RUNNER_TRIGGER_EVENTS="+strconv.Itoa(len(events)),
+	)
+	if len(events) > 0 {
+		cmd.Env = append(cmd.Env, "SLOTH_RUNNER_TRIGGER_EVENT_TYPE="+string(events[len(events)-1].Type))
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, lastLines(string(output), 5))
+	}
+	return nil
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
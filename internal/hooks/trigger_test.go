@@ -0,0 +1,216 @@
+package hooks
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingRunner records the runs of a trigger engine
+type recordingRunner struct {
+	mu    sync.Mutex
+	runs  [][]*Event
+	delay time.Duration
+}
+
+func (r *recordingRunner) run(ctx context.Context, trigger Trigger, events []*Event) error {
+	r.mu.Lock()
+	r.runs = append(r.runs, events)
+	r.mu.Unlock()
+	time.Sleep(r.delay)
+	return nil
+}
+
+func (r *recordingRunner) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.runs)
+}
+
+func fileEvent(group string) *Event {
+	return &Event{Type: EventFileModified, Agent: "web-01", Data: map[string]interface{}{"watcher_group": group}}
+}
+
+func TestTrigger_Matches(t *testing.T) {
+	trigger := Trigger{Events: []string{"file.*", "custom"}, WatcherGroup: "site", Agent: "web-01"}
+
+	tests := []struct {
+		event *Event
+		want  bool
+	}{
+		{fileEvent("site"), true},
+		{&Event{Type: EventFileCreated, Agent: "web-01", Data: map[string]interface{}{"watcher_group": "site"}}, true},
+		{fileEvent("logs"), false},
+		{&Event{Type: EventFileModified, Agent: "web-01"}, false},
+		{&Event{Type: EventFileModified, Agent: "web-02", Data: map[string]interface{}{"watcher_group": "site"}}, false},
+		{&Event{Type: EventDirCreated, Agent: "web-01", Data: map[string]interface{}{"watcher_group": "site"}}, false},
+	}
+	for i, tt := range tests {
+		if got := trigger.Matches(tt.event); got != tt.want {
+			t.Errorf("case %d: Matches = %v, want %v", i, got, tt.want)
+		}
+	}
+
+	if !(&Trigger{Events: []string{"*"}}).Matches(&Event{Type: EventTaskFailed}) {
+		t.Error("Expected * to match any event")
+	}
+}
+
+func TestTriggerEngine_Debounce(t *testing.T) {
+	runner := &recordingRunner{}
+	engine, err := NewTriggerEngine([]Trigger{{
+		Name: "site", Events: []string{"file.*"}, WatcherGroup: "site", Stack: "site", File: "site.sloth",
+		Debounce: 50 * time.Millisecond,
+	}}, runner.run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	for i := 0; i < 5; i++ {
+		engine.Observe(fileEvent("site"))
+		engine.Observe(fileEvent("other"))
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runner.count() != 0 {
+		t.Fatal("Expected no run while events keep arriving")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if runner.count() != 1 {
+		t.Fatalf("Expected one run after the events stopped, got %d", runner.count())
+	}
+	if len(runner.runs[0]) != 5 {
+		t.Errorf("Expected the run to get the 5 matching events, got %d", len(runner.runs[0]))
+	}
+}
+
+func TestTriggerEngine_Throttle(t *testing.T) {
+	runner := &recordingRunner{}
+	engine, err := NewTriggerEngine([]Trigger{{
+		Name: "site", Events: []string{"file.modified"}, Stack: "site", File: "site.sloth",
+		Throttle: 100 * time.Millisecond,
+	}}, runner.run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	engine.Observe(fileEvent(""))
+	time.Sleep(20 * time.Millisecond)
+	if runner.count() != 1 {
+		t.Fatalf("Expected the first event to run at once, got %d runs", runner.count())
+	}
+
+	for i := 0; i < 10; i++ {
+		engine.Observe(fileEvent(""))
+	}
+	time.Sleep(20 * time.Millisecond)
+	if runner.count() != 1 {
+		t.Fatalf("Expected events within the period to wait, got %d runs", runner.count())
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	if runner.count() != 2 {
+		t.Fatalf("Expected one more run at the end of the period, got %d", runner.count())
+	}
+	if status := engine.Status()[0]; status.Runs != 2 || status.Events != 11 || status.Pending != 0 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestTriggerEngine_FoldsEventsDuringRun(t *testing.T) {
+	runner := &recordingRunner{delay: 50 * time.Millisecond}
+	engine, err := NewTriggerEngine([]Trigger{{
+		Name: "site", Events: []string{"*"}, Stack: "site", Sloth: "site",
+	}}, runner.run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	engine.Observe(fileEvent(""))
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		engine.Observe(fileEvent(""))
+	}
+	if !engine.Status()[0].Running {
+		t.Error("Expected the trigger to be running")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if runner.count() != 2 {
+		t.Fatalf("Expected the events of a run to lead to one more run, got %d", runner.count())
+	}
+	if len(runner.runs[1]) != 3 {
+		t.Errorf("Expected the second run to get 3 events, got %d", len(runner.runs[1]))
+	}
+}
+
+func TestParseTriggerFile(t *testing.T) {
+	file, err := ParseTriggerFile([]byte(`
+triggers:
+  - name: rebuild-site
+    events: [file.modified, file.created]
+    watcher_group: site-content
+    stack: site
+    file: /srv/workflows/site.sloth
+    debounce: 30s
+    throttle: 10m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger := file.Triggers[0]
+	if trigger.Debounce != 30*time.Second || trigger.Throttle != 10*time.Minute || trigger.WatcherGroup != "site-content" {
+		t.Errorf("Unexpected trigger: %+v", trigger)
+	}
+
+	invalid := []string{
+		"triggers: [{name: a, stack: s, file: f}]",
+		"triggers: [{name: a, events: [x], file: f}]",
+		"triggers: [{name: a, events: [x], stack: s}]",
+		"triggers: [{name: a, events: [x], stack: s, file: f, sloth: g}]",
+		"triggers: [{name: a, events: [x], stack: s, file: f}, {name: a, events: [y], stack: s, file: f}]",
+		"triggers: [{name: a, events: [x], stack: s, file: f, debounce: soon}]",
+	}
+	for _, data := range invalid {
+		if _, err := ParseTriggerFile([]byte(data)); err == nil {
+			t.Errorf("Expected %q to be invalid", data)
+		}
+	}
+}
+
+func TestRunTriggerWorkflow(t *testing.T) {
+	var cmd *exec.Cmd
+	triggerCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		cmd = exec.CommandContext(ctx, "true", args...)
+		return cmd
+	}
+	defer func() { triggerCommand = exec.CommandContext }()
+
+	trigger := Trigger{Name: "rebuild-site", Stack: "site", File: "site.sloth", Values: "values.yaml"}
+	if err := RunTriggerWorkflow(context.Background(), trigger, []*Event{fileEvent("site")}); err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(cmd.Args[1:], " "); args != "run site --yes --file site.sloth --values values.yaml" {
+		t.Errorf("Unexpected arguments: %s", args)
+	}
+	env := strings.Join(cmd.Env, "\n")
+	for _, v := range []string{"SLOTH_RUNNER_TRIGGER=rebuild-site", "SLOTH_RUNNER_TRIGGER_EVENTS=1", "SLOTH_RUNNER_TRIGGER_EVENT_TYPE=file.modified"} {
+		if !strings.Contains(env, v) {
+			t.Errorf("Expected %s in the environment", v)
+		}
+	}
+
+	trigger = Trigger{Name: "reload-nginx", Stack: "web", Sloth: "nginx", Workflow: "nginx", Task: "reload"}
+	if err := RunTriggerWorkflow(context.Background(), trigger, nil); err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(cmd.Args[1:], " "); args != "run web --yes --sloth nginx --workflow nginx --task reload" {
+		t.Errorf("Unexpected arguments: %s", args)
+	}
+}
@@ -16,6 +16,7 @@ const (
 	EventAgentConnected       EventType = "agent.connected"
 	EventAgentVersionMismatch EventType = "agent.version_mismatch"
 	EventAgentResourceHigh    EventType = "agent.resource_high" // CPU/Memory alta
+	EventAgentFactsChanged    EventType = "agent.facts_changed"
 
 	// Task events
 	EventTaskStarted   EventType = "task.started"
@@ -130,6 +131,18 @@ const (
 	EventDeployFailed    EventType = "deploy.failed"
 	EventDeployRollback  EventType = "deploy.rollback"
 
+	// Canary events, emitted by the canary DSL helper
+	EventCanaryStarted    EventType = "canary.started"
+	EventCanaryDeployed   EventType = "canary.deployed"
+	EventCanaryPassed     EventType = "canary.passed"
+	EventCanaryPromoted   EventType = "canary.promoted"
+	EventCanaryFailed     EventType = "canary.failed"
+	EventCanaryRolledBack EventType = "canary.rolled_back"
+
+	// Certificate events, emitted by the certs module
+	EventCertExpiring EventType = "cert.expiring"
+	EventCertRenewed  EventType = "cert.renewed"
+
 	// Health check events
 	EventHealthCheckPassed EventType = "health.check_passed"
 	EventHealthCheckFailed EventType = "health.check_failed"
@@ -142,17 +155,18 @@ const (
 
 // Hook represents a registered hook
 type Hook struct {
-	ID          string     `json:"id"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	EventType   EventType  `json:"event_type"`
-	FilePath    string     `json:"file_path"`
-	Stack       string     `json:"stack,omitempty"`        // Stack name for hook isolation
-	Enabled     bool       `json:"enabled"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	LastRun     *time.Time `json:"last_run,omitempty"`
-	RunCount    int64      `json:"run_count"`
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	EventType   EventType     `json:"event_type"`
+	FilePath    string        `json:"file_path"`
+	Stack       string        `json:"stack,omitempty"`   // Stack name for hook isolation
+	Timeout     time.Duration `json:"timeout,omitempty"` // Maximum run time (DefaultHookTimeout if zero)
+	Enabled     bool          `json:"enabled"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	LastRun     *time.Time    `json:"last_run,omitempty"`
+	RunCount    int64         `json:"run_count"`
 }
 
 // EventStatus represents the processing status of an event
@@ -200,6 +214,11 @@ type TaskEvent struct {
 	Error      string `json:"error,omitempty"`
 	Duration   string `json:"duration"`
 
+	// Attempt is the attempt of a task.retrying event, counted from 1,
+	// out of MaxAttempts; Duration is then the wait before it starts
+	Attempt     int `json:"attempt,omitempty"`
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
 	// Execution context
 	Stack  string `json:"stack,omitempty"`   // Stack name being executed
 	RunID  string `json:"run_id,omitempty"`  // Unique run identifier
@@ -0,0 +1,275 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	lua "github.com/yuin/gopher-lua"
+	"gopkg.in/yaml.v3"
+)
+
+// WatchSource is a workflow file whose watch.file, watch.process and
+// watch.port declarations the master watches. The tasks of the watches run
+// with the stack, as with triggers.
+type WatchSource struct {
+	Stack string `yaml:"stack"`
+	// File is the workflow file, Sloth the name of a saved sloth file
+	File   string `yaml:"file,omitempty"`
+	Sloth  string `yaml:"sloth,omitempty"`
+	Values string `yaml:"values,omitempty"`
+}
+
+// WatchFile is the YAML file listing the workflow files the master watches
+// for
+type WatchFile struct {
+	Sources []WatchSource `yaml:"watches"`
+}
+
+// Validate checks that the watches of the source can be read
+func (s *WatchSource) Validate() error {
+	if s.Stack == "" {
+		return fmt.Errorf("stack is required")
+	}
+	if (s.File == "") == (s.Sloth == "") {
+		return fmt.Errorf("stack %s: exactly one of file or sloth is required", s.Stack)
+	}
+	return nil
+}
+
+// name is the file or sloth of the source, for messages
+func (s *WatchSource) name() string {
+	if s.Sloth != "" {
+		return "sloth " + s.Sloth
+	}
+	return s.File
+}
+
+// ParseWatchFile parses and validates the YAML of a watch file
+func ParseWatchFile(data []byte) (*WatchFile, error) {
+	var file WatchFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Sources {
+		if err := file.Sources[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &file, nil
+}
+
+// SlothReader returns the content of a saved sloth file
+type SlothReader func(name string) (string, error)
+
+// SourceWatches are the watches a workflow file declares
+type SourceWatches struct {
+	Source  WatchSource
+	Watches []luainterface.Watch
+}
+
+// LoadWatches reads a watch file and the watches of its workflow files, and
+// returns an engine that runs their tasks with run, such as
+// RunTriggerWorkflow. It returns nil without an error when the file does
+// not exist.
+func LoadWatches(ctx context.Context, path string, readSloth SlothReader, run TriggerRunFunc) (*WatchEngine, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch file: %w", err)
+	}
+	file, err := ParseWatchFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid watch file %s: %w", path, err)
+	}
+
+	var sources []SourceWatches
+	for _, source := range file.Sources {
+		watches, err := ParseSourceWatches(ctx, source, readSloth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.name(), err)
+		}
+		sources = append(sources, SourceWatches{Source: source, Watches: watches})
+	}
+	return NewWatchEngine(sources, run)
+}
+
+// ParseSourceWatches returns the watches the workflow file of source
+// declares
+func ParseSourceWatches(ctx context.Context, source WatchSource, readSloth SlothReader) ([]luainterface.Watch, error) {
+	L := lua.NewState()
+	defer L.Close()
+	var values *lua.LTable
+	if source.Values != "" {
+		data, err := os.ReadFile(source.Values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %w", err)
+		}
+		var valuesMap map[string]interface{}
+		if err := yaml.Unmarshal(data, &valuesMap); err != nil {
+			return nil, fmt.Errorf("failed to parse values file: %w", err)
+		}
+		values, _ = luainterface.GoValueToLua(L, valuesMap).(*lua.LTable)
+	}
+
+	path := source.File
+	if source.Sloth != "" {
+		if readSloth == nil {
+			return nil, fmt.Errorf("saved sloth files can't be read")
+		}
+		content, err := readSloth(source.Sloth)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.CreateTemp("", "sloth-watch-*.sloth")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+		path = f.Name()
+	}
+	return luainterface.ParseLuaWatches(ctx, path, values)
+}
+
+// watchConditions are the watcher conditions raising each event type of
+// watches
+var watchConditions = map[string]agent.EventCondition{
+	"file.modified":   agent.ConditionChanged,
+	"file.created":    agent.ConditionCreated,
+	"file.deleted":    agent.ConditionDeleted,
+	"process.started": agent.ConditionCreated,
+	"process.stopped": agent.ConditionDeleted,
+	"port.opened":     agent.ConditionCreated,
+	"port.closed":     agent.ConditionDeleted,
+}
+
+// WatchEngine checks the watches of workflow files on the master's host and
+// runs the task of a watch when its condition changes. Runs go through a
+// trigger engine, so events arriving during a run lead to one more run
+// after it.
+type WatchEngine struct {
+	watchers []*agent.Watcher
+	triggers *TriggerEngine
+}
+
+// NewWatchEngine creates an engine that runs the tasks of watches with run
+func NewWatchEngine(sources []SourceWatches, run TriggerRunFunc) (*WatchEngine, error) {
+	e := &WatchEngine{}
+	var triggers []Trigger
+	for i, source := range sources {
+		for j, w := range source.Watches {
+			group := fmt.Sprintf("watch-%d-%d", i+1, j+1)
+			config := agent.WatcherConfig{
+				ID:        group,
+				Type:      agent.WatcherType(w.Kind),
+				Group:     group,
+				Stack:     source.Source.Stack,
+				Interval:  w.Interval,
+				CheckHash: w.CheckHash,
+			}
+			switch w.Kind {
+			case "file":
+				config.FilePath = w.Target
+			case "process":
+				config.ProcessName = w.Target
+			case "port":
+				config.Port, _ = strconv.Atoi(w.Target)
+			default:
+				return nil, fmt.Errorf("unknown watch kind %s", w.Kind)
+			}
+
+			for _, h := range w.Handlers {
+				config.Conditions = append(config.Conditions, watchConditions[h.Event])
+				triggers = append(triggers, Trigger{
+					Name:         fmt.Sprintf("%s/%s: watch.%s(%s) %s", source.Source.Stack, h.Task, w.Kind, w.Target, h.Event),
+					Events:       []string{h.Event},
+					WatcherGroup: group,
+					Stack:        source.Source.Stack,
+					File:         source.Source.File,
+					Sloth:        source.Source.Sloth,
+					Values:       source.Source.Values,
+					Workflow:     h.Workflow,
+					Task:         h.Task,
+				})
+			}
+			e.watchers = append(e.watchers, agent.NewWatcher(config))
+		}
+	}
+
+	var err error
+	if e.triggers, err = NewTriggerEngine(triggers, run); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Len returns the number of watches checked
+func (e *WatchEngine) Len() int {
+	return len(e.watchers)
+}
+
+// Run checks the watches until ctx is done, then waits for the runs going
+// on. Processes and ports are first checked without running anything, so
+// the ones already up when the master starts don't count as started.
+func (e *WatchEngine) Run(ctx context.Context) {
+	for _, w := range e.watchers {
+		w.Check(discardEvents{})
+	}
+
+	sender := observeEvents{e.triggers}
+	var wg sync.WaitGroup
+	for _, w := range e.watchers {
+		wg.Add(1)
+		go func(w *agent.Watcher) {
+			defer wg.Done()
+			ticker := time.NewTicker(w.Config().Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					w.Check(sender)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	e.triggers.Stop()
+}
+
+// observeEvents feeds the events of watchers to a trigger engine
+type observeEvents struct {
+	triggers *TriggerEngine
+}
+
+func (s observeEvents) SendEvent(eventType, stack, runID string, data map[string]interface{}) error {
+	s.triggers.Observe(&Event{
+		Type:      EventType(eventType),
+		Timestamp: time.Now(),
+		Data:      data,
+		Stack:     stack,
+		RunID:     runID,
+	})
+	return nil
+}
+
+// discardEvents drops the events of watchers
+type discardEvents struct{}
+
+func (discardEvents) SendEvent(string, string, string, map[string]interface{}) error { return nil }
@@ -0,0 +1,118 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+)
+
+func TestParseWatchFile(t *testing.T) {
+	file, err := ParseWatchFile([]byte(`
+watches:
+  - stack: web
+    file: nginx.sloth
+  - stack: api
+    sloth: api
+    values: api.yaml
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Sources) != 2 || file.Sources[1].Sloth != "api" || file.Sources[1].Values != "api.yaml" {
+		t.Errorf("Unexpected sources: %+v", file.Sources)
+	}
+
+	_, err = ParseWatchFile([]byte("watches:\n  - stack: web\n    file: a.sloth\n    sloth: a\n"))
+	if err == nil || !strings.Contains(err.Error(), "exactly one of file or sloth") {
+		t.Errorf("Expected an error for a source with a file and a sloth, got %v", err)
+	}
+}
+
+func TestParseSourceWatchesFromSloth(t *testing.T) {
+	readSloth := func(name string) (string, error) {
+		return `
+workflow.define("nginx", {tasks = {{ name = "reload", command = "true" }}})
+watch.file("/etc/nginx/nginx.conf", {on_change = "reload"})
+`, nil
+	}
+	watches, err := ParseSourceWatches(context.Background(), WatchSource{Stack: "web", Sloth: "nginx"}, readSloth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(watches) != 1 || watches[0].Handlers[0].Workflow != "nginx" {
+		t.Errorf("Unexpected watches: %+v", watches)
+	}
+}
+
+func TestWatchEngineRunsTaskOnFileChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nginx.conf")
+	if err := os.WriteFile(path, []byte("worker_processes 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var runs []Trigger
+	run := func(ctx context.Context, trigger Trigger, events []*Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		runs = append(runs, trigger)
+		return nil
+	}
+	engine, err := NewWatchEngine([]SourceWatches{{
+		Source: WatchSource{Stack: "web", File: "nginx.sloth"},
+		Watches: []luainterface.Watch{{
+			Kind:     "file",
+			Target:   path,
+			Interval: 10 * time.Millisecond,
+			Handlers: []luainterface.WatchHandler{{Event: "file.modified", Task: "reload", Workflow: "nginx"}},
+		}},
+	}}, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	if len(runs) != 0 {
+		t.Errorf("Expected no run before the file changed, got %d", len(runs))
+	}
+	mu.Unlock()
+
+	if err := os.WriteFile(path, []byte("worker_processes 4;\nevents {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(runs)
+		mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(runs) != 1 {
+		t.Fatalf("Expected one run, got %d", len(runs))
+	}
+	if runs[0].Stack != "web" || runs[0].File != "nginx.sloth" || runs[0].Workflow != "nginx" || runs[0].Task != "reload" {
+		t.Errorf("Unexpected run: %+v", runs[0])
+	}
+}
@@ -0,0 +1,267 @@
+// Package jointoken manages one-time tokens that let a new host register
+// as an agent. A token is bound to the agent name it was created for and
+// can be redeemed once, before it expires. Redeeming it issues a
+// credential the agent re-registers with after restarts, so the token
+// itself is useless once used. Only hashes of secrets are stored.
+package jointoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+)
+
+// Prefix starts every join token, so leaked tokens are easy to spot
+const Prefix = "slj_"
+
+// CredentialPrefix starts every agent credential
+const CredentialPrefix = "slc_"
+
+var (
+	// ErrInvalid is returned for unknown tokens and wrong secrets
+	ErrInvalid = errors.New("invalid join token")
+	ErrExpired = errors.New("join token expired")
+	// ErrUsed is returned when a token was already redeemed
+	ErrUsed = errors.New("join token already used")
+	// ErrWrongAgent is returned when a token was created for another agent
+	ErrWrongAgent = errors.New("join token belongs to another agent")
+)
+
+var (
+	required   bool
+	requiredMu sync.Mutex
+)
+
+// SetRequired sets whether every agent must present a join token to
+// register; the master sets it from --require-join-token
+func SetRequired(r bool) {
+	requiredMu.Lock()
+	defer requiredMu.Unlock()
+	required = r
+}
+
+// Required reports whether agents must present a join token to register
+func Required() bool {
+	requiredMu.Lock()
+	defer requiredMu.Unlock()
+	return required
+}
+
+// Token is a join token for one agent
+type Token struct {
+	ID        string     `json:"id"`
+	Agent     string     `json:"agent"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// Store keeps join tokens in SQLite
+type Store struct {
+	db  *sql.DB
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewStore opens (creating if needed) the join token store at dbPath
+func NewStore(dbPath string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open join token store: %w", err)
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS join_tokens (
+		id TEXT PRIMARY KEY,
+		agent TEXT NOT NULL,
+		secret_hash TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		used_at INTEGER
+	);
+	CREATE TABLE IF NOT EXISTS agent_credentials (
+		agent TEXT PRIMARY KEY,
+		secret_hash TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		sqlitedb.Close(db)
+		return nil, fmt.Errorf("failed to initialize join token store: %w", err)
+	}
+	os.Chmod(dbPath, 0600)
+
+	return &Store{db: db, now: time.Now}, nil
+}
+
+// DefaultStore opens the join token store in the data directory
+func DefaultStore() (*Store, error) {
+	return NewStore(config.GetJoinTokensDBPath())
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return sqlitedb.Close(s.db)
+}
+
+// Create stores a token for agent, valid for ttl, and returns it with its
+// secret, which is shown once and can't be recovered
+func (s *Store) Create(agent string, ttl time.Duration) (string, *Token, error) {
+	if agent == "" {
+		return "", nil, errors.New("agent name is required")
+	}
+	if ttl <= 0 {
+		return "", nil, errors.New("join tokens must expire")
+	}
+
+	id, err := randomHex(6)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC().Truncate(time.Second)
+	t := &Token{ID: id, Agent: agent, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	_, err = s.db.Exec(
+		`INSERT INTO join_tokens (id, agent, secret_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		t.ID, t.Agent, hashSecret(secret), t.CreatedAt.Unix(), t.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store join token: %w", err)
+	}
+	return Prefix + id + "_" + secret, t, nil
+}
+
+// Redeem checks a token presented by agent, before it expires, and marks
+// it used. It returns the credential agent registers with from then on,
+// replacing the one an earlier token issued.
+func (s *Store) Redeem(raw, agent string) (*Token, string, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, Prefix), "_")
+	if !strings.HasPrefix(raw, Prefix) || !ok {
+		return nil, "", ErrInvalid
+	}
+	credential, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var t Token
+	var hash string
+	var created, expires int64
+	var used sql.NullInt64
+	err = s.db.QueryRow(
+		`SELECT id, agent, secret_hash, created_at, expires_at, used_at FROM join_tokens WHERE id = ?`, id,
+	).Scan(&t.ID, &t.Agent, &hash, &created, &expires, &used)
+	if err != nil {
+		return nil, "", ErrInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(secret))) != 1 {
+		return nil, "", ErrInvalid
+	}
+	t.CreatedAt = time.Unix(created, 0).UTC()
+	t.ExpiresAt = time.Unix(expires, 0).UTC()
+
+	if used.Valid {
+		return nil, "", ErrUsed
+	}
+	if t.Agent != agent {
+		return nil, "", ErrWrongAgent
+	}
+	now := s.now().UTC().Truncate(time.Second)
+	if !t.ExpiresAt.After(now) {
+		return nil, "", ErrExpired
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to redeem join token: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE join_tokens SET used_at = ? WHERE id = ?`, now.Unix(), id); err != nil {
+		return nil, "", fmt.Errorf("failed to redeem join token: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO agent_credentials (agent, secret_hash, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(agent) DO UPDATE SET secret_hash = excluded.secret_hash, created_at = excluded.created_at`,
+		agent, hashSecret(credential), now.Unix(),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to store agent credential: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("failed to redeem join token: %w", err)
+	}
+	t.UsedAt = &now
+	return &t, CredentialPrefix + credential, nil
+}
+
+// Authenticate checks the credential agent got redeeming its join token
+func (s *Store) Authenticate(raw, agent string) error {
+	secret, ok := strings.CutPrefix(raw, CredentialPrefix)
+	if !ok {
+		return ErrInvalid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hash string
+	if err := s.db.QueryRow(`SELECT secret_hash FROM agent_credentials WHERE agent = ?`, agent).Scan(&hash); err != nil {
+		return ErrInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(secret))) != 1 {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// Prune deletes expired tokens, returning how many were removed. Agents
+// that redeemed theirs keep their credential.
+func (s *Store) Prune() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec(`DELETE FROM join_tokens WHERE expires_at <= ?`, s.now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune join tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate join token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
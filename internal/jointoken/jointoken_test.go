@@ -0,0 +1,147 @@
+package jointoken
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "join_tokens.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestStore_Redeem(t *testing.T) {
+	store, now := newTestStore(t)
+
+	secret, tok, err := store.Create("web-01", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, Prefix+tok.ID+"_") {
+		t.Errorf("secret %q doesn't carry the token ID", secret)
+	}
+
+	if _, _, err := store.Redeem(secret, "web-02"); !errors.Is(err, ErrWrongAgent) {
+		t.Errorf("other agent before use: %v, want ErrWrongAgent", err)
+	}
+
+	got, credential, err := store.Redeem(secret, "web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UsedAt == nil {
+		t.Error("redeemed token isn't marked used")
+	}
+	if !strings.HasPrefix(credential, CredentialPrefix) {
+		t.Errorf("credential %q doesn't start with %s", credential, CredentialPrefix)
+	}
+
+	// The agent re-registers after restarts with its credential, long
+	// after the token expired; the token itself can't be used again
+	*now = now.Add(30 * 24 * time.Hour)
+	if err := store.Authenticate(credential, "web-01"); err != nil {
+		t.Errorf("credential after restart: %v", err)
+	}
+	if err := store.Authenticate(credential, "web-02"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("credential of another agent: %v, want ErrInvalid", err)
+	}
+	for _, agent := range []string{"web-01", "web-02"} {
+		if _, _, err := store.Redeem(secret, agent); !errors.Is(err, ErrUsed) {
+			t.Errorf("%s after use: %v, want ErrUsed", agent, err)
+		}
+	}
+
+	for _, bad := range []string{"", "slj_", secret + "x", Prefix + tok.ID + "_" + strings.Repeat("0", 48)} {
+		if _, _, err := store.Redeem(bad, "web-01"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Redeem(%q) = %v, want ErrInvalid", bad, err)
+		}
+	}
+	for _, bad := range []string{"", credential + "x", secret} {
+		if err := store.Authenticate(bad, "web-01"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Authenticate(%q) = %v, want ErrInvalid", bad, err)
+		}
+	}
+}
+
+func TestStore_RedeemReplacesCredential(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	first, _, err := store.Create("web-01", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, old, err := store.Redeem(first, "web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bootstrapping the host again issues a new credential
+	second, _, err := store.Create("web-01", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, current, err := store.Redeem(second, "web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Authenticate(old, "web-01"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("replaced credential: %v, want ErrInvalid", err)
+	}
+	if err := store.Authenticate(current, "web-01"); err != nil {
+		t.Errorf("current credential: %v", err)
+	}
+}
+
+func TestStore_ExpiredAndPrune(t *testing.T) {
+	store, now := newTestStore(t)
+
+	expired, _, err := store.Create("web-01", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used, _, err := store.Create("web-02", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, credential, err := store.Redeem(used, "web-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if _, _, err := store.Redeem(expired, "web-01"); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired token: %v", err)
+	}
+
+	removed, err := store.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("Prune removed %d tokens, want 2", removed)
+	}
+	if err := store.Authenticate(credential, "web-02"); err != nil {
+		t.Errorf("credential was pruned with its token: %v", err)
+	}
+}
+
+func TestStore_CreateValidation(t *testing.T) {
+	store, _ := newTestStore(t)
+
+	if _, _, err := store.Create("", time.Hour); err == nil {
+		t.Error("expected an error without agent name")
+	}
+	if _, _, err := store.Create("web-01", 0); err == nil {
+		t.Error("expected an error for a token that never expires")
+	}
+}
@@ -0,0 +1,209 @@
+// Package locks takes named locks held on the master, so concurrent runs
+// can serialize work that must not overlap, such as package installs.
+// A lock is a lease the holder renews while it runs; if the holder dies
+// the lease expires and the lock frees up.
+package locks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+)
+
+// DefaultTTL is the lease length; leases are renewed at a third of it
+const DefaultTTL = 30 * time.Second
+
+// ErrTimeout is returned when a lock is still held once the wait is over
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// Options configure how a lock is taken
+type Options struct {
+	// Timeout is how long to wait for the lock; zero tries once
+	Timeout time.Duration
+	// TTL is the lease length, DefaultTTL if zero
+	TTL time.Duration
+	// Global shares the lock between all hosts instead of the client's
+	Global bool
+	// Holder describes the run taking the lock, shown to runs waiting for
+	// it; the client's Holder if empty
+	Holder string
+}
+
+// Client takes locks on the master
+type Client struct {
+	Registry pb.AgentRegistryClient
+	// Scope is the host locks are scoped to unless they are global
+	Scope string
+	// Holder describes this process to runs waiting for its locks
+	Holder string
+	// PollInterval is the longest wait between attempts, 5s if zero
+	PollInterval time.Duration
+}
+
+// Lease is a held lock
+type Lease struct {
+	ID   string
+	Name string
+
+	client *Client
+	ttl    time.Duration
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Acquire takes the lock name, waiting up to opts.Timeout while another
+// run holds it. The lease is renewed until it is released.
+func (c *Client) Acquire(ctx context.Context, name string, opts Options) (*Lease, error) {
+	if name == "" {
+		return nil, errors.New("lock name is required")
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	holder := opts.Holder
+	if holder == "" {
+		holder = c.Holder
+	}
+	scope := c.Scope
+	if opts.Global {
+		scope = ""
+	}
+	maxPoll := c.PollInterval
+	if maxPoll <= 0 {
+		maxPoll = 5 * time.Second
+	}
+
+	deadline := time.Now().Add(opts.Timeout)
+	poll := maxPoll / 10
+	for {
+		resp, err := c.Registry.AcquireLock(ctx, &pb.AcquireLockRequest{
+			Name:       name,
+			Scope:      scope,
+			Holder:     holder,
+			TtlSeconds: int64((ttl + time.Second - 1) / time.Second),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+		}
+		if resp.Acquired {
+			lease := &Lease{
+				ID:     resp.LeaseId,
+				Name:   name,
+				client: c,
+				ttl:    ttl,
+				stop:   make(chan struct{}),
+				done:   make(chan struct{}),
+			}
+			go lease.renew()
+			return lease, nil
+		}
+		if resp.Message != "" {
+			return nil, fmt.Errorf("failed to acquire lock %s: %s", name, resp.Message)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w %s, held by %s", ErrTimeout, name, resp.Holder)
+		}
+		wait := poll
+		if wait > remaining {
+			wait = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		if poll *= 2; poll > maxPoll {
+			poll = maxPoll
+		}
+	}
+}
+
+// renew keeps the lease alive until it is released
+func (l *Lease) renew() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+			resp, err := l.client.Registry.RenewLock(ctx, &pb.RenewLockRequest{
+				LeaseId:    l.ID,
+				TtlSeconds: int64((l.ttl + time.Second - 1) / time.Second),
+			})
+			cancel()
+			if err == nil && !resp.Success {
+				// The lease expired or the master restarted: the lock may
+				// already be someone else's, so stop claiming it
+				return
+			}
+		}
+	}
+}
+
+// Release frees the lock. Releasing twice is a no-op.
+func (l *Lease) Release() error {
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.done
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var resp *pb.ReleaseLockResponse
+		resp, err = l.client.Registry.ReleaseLock(ctx, &pb.ReleaseLockRequest{LeaseId: l.ID})
+		if err == nil && !resp.Success {
+			err = fmt.Errorf("lock %s was lost before it was released", l.Name)
+		}
+	})
+	return err
+}
+
+var (
+	defaultClient   *Client
+	defaultClientMu sync.Mutex
+)
+
+// SetDefault sets the client Lua modules running in this process use;
+// agents set one scoped to their name
+func SetDefault(c *Client) {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	defaultClient = c
+}
+
+// Default returns the client set with SetDefault, or one connected to the
+// configured master and scoped to this host
+func Default() (*Client, error) {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	if defaultClient != nil {
+		return defaultClient, nil
+	}
+
+	conn, err := grpc.Dial(config.GetMasterAddress(), mtls.DialOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to master for locks: %w", err)
+	}
+	host, _ := os.Hostname()
+	defaultClient = &Client{
+		Registry: pb.NewAgentRegistryClient(conn),
+		Scope:    host,
+		Holder:   fmt.Sprintf("%s (pid %d)", host, os.Getpid()),
+	}
+	return defaultClient, nil
+}
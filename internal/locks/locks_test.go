@@ -0,0 +1,122 @@
+package locks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+)
+
+// fakeRegistry holds locks like the master, without expiry
+type fakeRegistry struct {
+	pb.AgentRegistryClient
+	mu      sync.Mutex
+	holders map[string]string // key -> lease ID
+	names   map[string]string // lease ID -> holder
+	renewed int
+	nextID  int
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{holders: map[string]string{}, names: map[string]string{}}
+}
+
+func (f *fakeRegistry) AcquireLock(ctx context.Context, in *pb.AcquireLockRequest, opts ...grpc.CallOption) (*pb.AcquireLockResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := in.Scope + "/" + in.Name
+	if id, ok := f.holders[key]; ok {
+		return &pb.AcquireLockResponse{Holder: f.names[id]}, nil
+	}
+	f.nextID++
+	id := fmt.Sprint(f.nextID)
+	f.holders[key] = id
+	f.names[id] = in.Holder
+	return &pb.AcquireLockResponse{Acquired: true, LeaseId: id, Holder: in.Holder}, nil
+}
+
+func (f *fakeRegistry) RenewLock(ctx context.Context, in *pb.RenewLockRequest, opts ...grpc.CallOption) (*pb.RenewLockResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.renewed++
+	_, ok := f.names[in.LeaseId]
+	return &pb.RenewLockResponse{Success: ok}, nil
+}
+
+func (f *fakeRegistry) ReleaseLock(ctx context.Context, in *pb.ReleaseLockRequest, opts ...grpc.CallOption) (*pb.ReleaseLockResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, id := range f.holders {
+		if id == in.LeaseId {
+			delete(f.holders, key)
+			delete(f.names, id)
+			return &pb.ReleaseLockResponse{Success: true}, nil
+		}
+	}
+	return &pb.ReleaseLockResponse{Success: false}, nil
+}
+
+func TestClient_AcquireWaitsForRelease(t *testing.T) {
+	registry := newFakeRegistry()
+	client := &Client{Registry: registry, Scope: "web-01", Holder: "web-01", PollInterval: 20 * time.Millisecond}
+	ctx := context.Background()
+
+	first, err := client.Acquire(ctx, "apt-db", Options{Holder: "install-nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Acquire(ctx, "apt-db", Options{Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) || !strings.Contains(err.Error(), "install-nginx") {
+		t.Errorf("Expected a timeout naming the holder, got %v", err)
+	}
+
+	// Other hosts and global locks are independent
+	other := &Client{Registry: registry, Scope: "web-02"}
+	if _, err := other.Acquire(ctx, "apt-db", Options{}); err != nil {
+		t.Errorf("Expected web-02's lock to be free: %v", err)
+	}
+	if _, err := client.Acquire(ctx, "apt-db", Options{Global: true}); err != nil {
+		t.Errorf("Expected the global lock to be free: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		first.Release()
+	}()
+	second, err := client.Acquire(ctx, "apt-db", Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Expected the lock after its release: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("Release failed: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("Second release should be a no-op: %v", err)
+	}
+}
+
+func TestLease_Renews(t *testing.T) {
+	registry := newFakeRegistry()
+	client := &Client{Registry: registry, Scope: "web-01"}
+
+	lease, err := client.Acquire(context.Background(), "apt-db", Options{TTL: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	lease.Release()
+
+	registry.mu.Lock()
+	renewed := registry.renewed
+	registry.mu.Unlock()
+	if renewed == 0 {
+		t.Error("Expected the lease to be renewed while held")
+	}
+}
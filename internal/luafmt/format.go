@@ -0,0 +1,462 @@
+// Package luafmt formats sloth files canonically, so workflow diffs only
+// show what changed. It works on tokens rather than a syntax tree to keep
+// comments and the layout of each line, and changes only:
+//
+//   - indentation, four spaces per block, table or call, and one more for
+//     method chains and other continuation lines
+//   - trailing whitespace and runs of blank lines
+//   - trailing commas after the last field of multi-line tables
+//   - the order of the fields of task definition tables, see TaskFieldOrder
+//
+// The front-matter of v2 sloth files is kept as is, and so are templated
+// files, which are only Lua once rendered.
+package luafmt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Indent is one level of indentation
+const Indent = "    "
+
+// TaskFieldOrder is the order of the fields of task definition tables,
+// the tables listed in a `tasks = {...}` field. Other fields follow in
+// their original order.
+var TaskFieldOrder = []string{
+	"name", "description",
+	"delegate_to", "user", "workdir", "workspace",
+	"depends_on", "consumes", "artifacts", "next_if_fail",
+	"run_if", "abort_if",
+	"params", "timeout", "retries", "async",
+	"pre_exec", "command", "run", "post_exec",
+	"on_success", "on_failure", "on_fail",
+}
+
+// Format returns the canonical form of a sloth file. Files that aren't
+// valid Lua are returned with an error, unchanged.
+func Format(src []byte) ([]byte, error) {
+	src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	prefix, body, err := splitPrefix(src)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != nil && isTemplate(src) {
+		return src, nil
+	}
+	if err := checkSyntax(body); err != nil {
+		return nil, err
+	}
+
+	tokens, err := lex(string(body))
+	if err != nil {
+		return nil, err
+	}
+	formatted := []byte(render(formatTables(tokens, false)))
+
+	if err := checkSyntax(formatted); err != nil {
+		return nil, fmt.Errorf("formatting produced invalid Lua, leaving the file unchanged: %w", err)
+	}
+	return append(prefix, formatted...), nil
+}
+
+// splitPrefix separates the parts Format keeps as they are, a #! line and
+// the front-matter, from the Lua body
+func splitPrefix(src []byte) (prefix, body []byte, err error) {
+	if bytes.HasPrefix(src, []byte("#!")) {
+		end := bytes.IndexByte(src, '\n')
+		if end < 0 {
+			return src, nil, nil
+		}
+		return src[:end+1], src[end+1:], nil
+	}
+
+	meta, _, err := sloth.ParseFrontMatter(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta.Format != sloth.FormatV2 {
+		return nil, src, nil
+	}
+	// The body starts after the second delimiter line
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	for i := 1; i < len(lines); i++ {
+		if string(bytes.TrimRight(lines[i], " \t\r\n")) == "---" {
+			n := len(bytes.Join(lines[:i+1], nil))
+			return src[:n], src[n:], nil
+		}
+	}
+	return nil, src, nil
+}
+
+func isTemplate(src []byte) bool {
+	meta, _, err := sloth.ParseFrontMatter(src)
+	return err == nil && meta.Template
+}
+
+func checkSyntax(body []byte) error {
+	if _, err := parse.Parse(bytes.NewReader(body), ""); err != nil {
+		return fmt.Errorf("syntax error: %s", strings.Join(strings.Fields(err.Error()), " "))
+	}
+	return nil
+}
+
+// formatTables adds trailing commas to multi-line tables and orders the
+// fields of task definitions, innermost tables first. taskList tells
+// whether tokens are the body of a `tasks = {...}` table.
+func formatTables(tokens []token, taskList bool) []token {
+	out := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].is(tokOp, "{") {
+			out = append(out, tokens[i])
+			continue
+		}
+		end := matchingBrace(tokens, i)
+		if end < 0 {
+			return append(out, tokens[i:]...)
+		}
+
+		prev := previousCode(tokens, i, 1)
+		isTaskList := prev.is(tokOp, "=") && previousCode(tokens, i, 2).is(tokName, "tasks")
+		isTask := taskList && (prev.kind == tokNewline || prev.is(tokOp, ",") || prev.is(tokOp, ";"))
+
+		body := formatTables(tokens[i+1:end], isTaskList)
+		if len(body) > 0 && body[len(body)-1].kind == tokNewline {
+			body = addTrailingComma(body)
+			if isTask {
+				body = orderTaskFields(body)
+			}
+		}
+		out = append(out, tokens[i])
+		out = append(out, body...)
+		out = append(out, tokens[end])
+		i = end
+	}
+	return out
+}
+
+// previousCode returns the nth code token before index i, or a line break
+// token when there is none
+func previousCode(tokens []token, i, n int) token {
+	for i--; i >= 0; i-- {
+		if tokens[i].code() {
+			if n--; n == 0 {
+				return tokens[i]
+			}
+		}
+	}
+	return token{kind: tokNewline}
+}
+
+func matchingBrace(tokens []token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].is(tokOp, "{"):
+			depth++
+		case tokens[i].is(tokOp, "}"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func addTrailingComma(body []token) []token {
+	last := -1
+	for i := len(body) - 1; i >= 0; i-- {
+		if body[i].code() {
+			last = i
+			break
+		}
+	}
+	if last < 0 || body[last].is(tokOp, ",") || body[last].is(tokOp, ";") {
+		return body
+	}
+	out := make([]token, 0, len(body)+1)
+	out = append(out, body[:last+1]...)
+	out = append(out, token{kind: tokOp, text: ","})
+	return append(out, body[last+1:]...)
+}
+
+// field is one `key = value,` entry of a table, with the comment lines
+// above it
+type field struct {
+	key    string
+	tokens []token
+}
+
+// orderTaskFields sorts the fields of a task definition table written one
+// `key = value,` field per line. Tables laid out any other way, or with
+// positional entries, are left alone.
+func orderTaskFields(body []token) []token {
+	lines := splitLines(body)
+	// The line of the opening brace holds nothing but comments
+	for _, t := range lines[0] {
+		if t.code() {
+			return body
+		}
+	}
+
+	var fields []field
+	var cur *field
+	var pending []token
+	depth := 0
+	for _, line := range lines[1:] {
+		code := codeTokens(line)
+		if cur == nil {
+			if len(code) == 0 {
+				pending = append(pending, line...)
+				continue
+			}
+			if code[0].kind != tokName || len(code) < 2 || !code[1].is(tokOp, "=") {
+				return body
+			}
+			cur = &field{key: code[0].text, tokens: pending}
+			pending = nil
+		}
+		cur.tokens = append(cur.tokens, line...)
+
+		for i, t := range code {
+			if depth == 0 && (t.is(tokOp, ",") || t.is(tokOp, ";")) && i != len(code)-1 {
+				return body // several fields on one line
+			}
+			depth += nesting(t)
+		}
+		if depth < 0 {
+			return body
+		}
+		if depth == 0 && len(code) > 0 && (code[len(code)-1].is(tokOp, ",") || code[len(code)-1].is(tokOp, ";")) {
+			fields = append(fields, *cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		return body
+	}
+
+	rank := func(key string) int {
+		for i, k := range TaskFieldOrder {
+			if k == key {
+				return i
+			}
+		}
+		return len(TaskFieldOrder)
+	}
+	if sort.SliceIsSorted(fields, func(i, j int) bool { return rank(fields[i].key) < rank(fields[j].key) }) {
+		return body
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return rank(fields[i].key) < rank(fields[j].key) })
+
+	out := append([]token{}, lines[0]...)
+	for _, f := range fields {
+		out = append(out, f.tokens...)
+	}
+	return append(out, pending...)
+}
+
+// splitLines splits tokens after each line break
+func splitLines(tokens []token) [][]token {
+	var lines [][]token
+	start := 0
+	for i, t := range tokens {
+		if t.kind == tokNewline {
+			lines = append(lines, tokens[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		lines = append(lines, tokens[start:])
+	}
+	return lines
+}
+
+func codeTokens(line []token) []token {
+	var code []token
+	for _, t := range line {
+		if t.code() {
+			code = append(code, t)
+		}
+	}
+	return code
+}
+
+// nesting is how a token changes the block and bracket depth
+func nesting(t token) int {
+	switch t.kind {
+	case tokOp:
+		switch t.text {
+		case "{", "(", "[":
+			return 1
+		case "}", ")", "]":
+			return -1
+		}
+	case tokName:
+		switch t.text {
+		case "function", "do", "then", "repeat":
+			return 1
+		case "end", "until", "elseif":
+			return -1
+		}
+	}
+	return 0
+}
+
+// closes reports whether a token at the start of a line belongs to the
+// enclosing level, like end, } or else
+func closes(t token) bool {
+	return nesting(t) < 0 || t.is(tokName, "else")
+}
+
+// continues reports whether a line starting with t continues the
+// expression of the line above, like a method chain. A leading - may be
+// a unary minus, so it doesn't count.
+func continues(t token) bool {
+	switch t.kind {
+	case tokOp:
+		switch t.text {
+		case ":", ".", "..", "+", "*", "/", "//", "%", "^", "==", "~=", "<", ">", "<=", ">=":
+			return true
+		}
+	case tokName:
+		return t.text == "and" || t.text == "or"
+	}
+	return false
+}
+
+// frame is an open block, bracket or run of continuation lines, with the
+// indentation of the line that opened it. Its contents are indented one
+// level more, however many frames that line opened.
+type frame struct {
+	indent       int
+	continuation bool
+}
+
+type frames []frame
+
+// body is the indentation of lines inside the innermost frame
+func (s frames) body() int {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1].indent + 1
+}
+
+// pop closes the innermost block or bracket, and the continuation lines
+// inside it
+func (s frames) pop() (frames, frame) {
+	for len(s) > 0 && s[len(s)-1].continuation {
+		s = s[:len(s)-1]
+	}
+	if len(s) == 0 {
+		return s, frame{}
+	}
+	return s[:len(s)-1], s[len(s)-1]
+}
+
+// render re-indents the tokens and writes them out
+func render(tokens []token) string {
+	var out strings.Builder
+	var stack frames
+	blank := false
+
+	lines := splitLines(tokens)
+	for n, line := range lines {
+		code := codeTokens(line)
+		if len(code) == 0 && hasComment(line) {
+			// Comment lines go with the statement below them
+			if next, ok := nextCode(lines[n+1:]); ok && !continues(next) && !closes(next) {
+				for len(stack) > 0 && stack[len(stack)-1].continuation {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+		if len(code) == 0 && !hasComment(line) {
+			blank = out.Len() > 0
+			continue
+		}
+		if blank {
+			out.WriteString("\n")
+			blank = false
+		}
+
+		indent := stack.body()
+		if len(code) > 0 {
+			switch {
+			case continues(code[0]) && line[0] == code[0]:
+				// The first continuation line opens a frame, so blocks
+				// opened in a method chain nest inside it
+				if len(stack) == 0 || !stack[len(stack)-1].continuation {
+					stack = append(stack, frame{indent: indent, continuation: true})
+				}
+				indent = stack.body()
+			case closes(code[0]):
+				// Closing tokens at the start of the line put it back at
+				// the indentation of the line that opened them
+				shown := stack
+				for _, t := range code {
+					if !closes(t) {
+						break
+					}
+					var closed frame
+					shown, closed = shown.pop()
+					indent = closed.indent
+				}
+			default:
+				// A new statement ends the continuation lines above it
+				for len(stack) > 0 && stack[len(stack)-1].continuation {
+					stack = stack[:len(stack)-1]
+				}
+				indent = stack.body()
+			}
+		}
+
+		var b strings.Builder
+		b.WriteString(strings.Repeat(Indent, indent))
+		for i, t := range line {
+			if i > 0 {
+				b.WriteString(t.lead)
+			}
+			b.WriteString(t.text)
+			switch {
+			case t.is(tokName, "else"):
+				stack, _ = stack.pop()
+				stack = append(stack, frame{indent: indent})
+			case nesting(t) > 0:
+				stack = append(stack, frame{indent: indent})
+			case nesting(t) < 0:
+				stack, _ = stack.pop()
+			}
+		}
+		// Nothing but whitespace may follow the last token of a line
+		out.WriteString(strings.TrimRight(b.String(), " \t\n"))
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// nextCode returns the first code token of lines
+func nextCode(lines [][]token) (token, bool) {
+	for _, line := range lines {
+		if code := codeTokens(line); len(code) > 0 {
+			return code[0], true
+		}
+	}
+	return token{}, false
+}
+
+func hasComment(line []token) bool {
+	for _, t := range line {
+		if t.kind == tokComment {
+			return true
+		}
+	}
+	return false
+}
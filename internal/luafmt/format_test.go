@@ -0,0 +1,179 @@
+package luafmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func format(t *testing.T, src string) string {
+	t.Helper()
+	out, err := Format([]byte(src))
+	require.NoError(t, err)
+
+	again, err := Format(out)
+	require.NoError(t, err)
+	require.Equal(t, string(out), string(again), "formatting is not idempotent")
+	return string(out)
+}
+
+func TestFormat_Indentation(t *testing.T) {
+	src := `
+local deploy = task("deploy")
+  :description("Deploy")
+  :command(function(this, params)
+  if params.env == "prod" then
+  log.info("prod")
+  elseif params.env == "staging" then
+     log.info("staging")
+  else
+  for _, host in ipairs({"a", "b"}) do
+  run(host)
+  end
+  end
+
+
+
+  return true, "ok"
+  end)
+  :build()
+-- both callbacks stay at the level of the call
+pcall(function()
+    step()
+  end, function()
+  rollback()
+end)
+`
+	want := `local deploy = task("deploy")
+    :description("Deploy")
+    :command(function(this, params)
+        if params.env == "prod" then
+            log.info("prod")
+        elseif params.env == "staging" then
+            log.info("staging")
+        else
+            for _, host in ipairs({"a", "b"}) do
+                run(host)
+            end
+        end
+
+        return true, "ok"
+    end)
+    :build()
+-- both callbacks stay at the level of the call
+pcall(function()
+    step()
+end, function()
+    rollback()
+end)
+`
+	assert.Equal(t, want, format(t, src))
+}
+
+func TestFormat_TrailingCommas(t *testing.T) {
+	src := `local cfg = {
+    name = "web",
+    ports = {80, 443},
+    env = {
+        MODE = "prod" -- keep comments
+    }
+}
+`
+	want := `local cfg = {
+    name = "web",
+    ports = {80, 443},
+    env = {
+        MODE = "prod", -- keep comments
+    },
+}
+`
+	assert.Equal(t, want, format(t, src))
+}
+
+func TestFormat_TaskFieldOrder(t *testing.T) {
+	src := `workflow.define("deploy", {
+    tasks = {
+        {
+            command = function()
+                return true
+            end,
+            -- runs after build
+            depends_on = {"build"},
+            name = "deploy",
+            custom = 1,
+        },
+        { command = "make", name = "build" },
+    },
+})
+cron.add({
+    command = "backup.sh",
+    name = "backup",
+})
+`
+	want := `workflow.define("deploy", {
+    tasks = {
+        {
+            name = "deploy",
+            -- runs after build
+            depends_on = {"build"},
+            command = function()
+                return true
+            end,
+            custom = 1,
+        },
+        { command = "make", name = "build" },
+    },
+})
+cron.add({
+    command = "backup.sh",
+    name = "backup",
+})
+`
+	assert.Equal(t, want, format(t, src))
+}
+
+func TestFormat_KeepsFrontMatterAndStrings(t *testing.T) {
+	src := `---
+name: deploy
+params:
+  env:
+    type: string
+---
+local script = [[
+  #!/bin/sh
+    echo "kept as is"
+]]
+   local msg = "a   b" --[[ long
+      comment ]]
+`
+	want := `---
+name: deploy
+params:
+  env:
+    type: string
+---
+local script = [[
+  #!/bin/sh
+    echo "kept as is"
+]]
+local msg = "a   b" --[[ long
+      comment ]]
+`
+	assert.Equal(t, want, format(t, src))
+
+	shebang := "#!/usr/bin/env sloth-runner\n  print(1)\n"
+	assert.Equal(t, "#!/usr/bin/env sloth-runner\nprint(1)\n", format(t, shebang))
+}
+
+func TestFormat_KeepsTemplates(t *testing.T) {
+	src := "---\ntemplate: true\n---\n{{ range .vars.hosts }}\n  print({{ lua . }})\n{{ end }}\n"
+	assert.Equal(t, src, format(t, src))
+}
+
+func TestFormat_InvalidLua(t *testing.T) {
+	_, err := Format([]byte("local x = {\n"))
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "syntax error"), err.Error())
+}
@@ -0,0 +1,177 @@
+package luafmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokName tokenKind = iota // names and keywords
+	tokNumber
+	tokString
+	tokComment
+	tokOp
+	tokNewline
+)
+
+// token is a lexeme with the whitespace before it, kept so the formatter
+// only changes what it means to
+type token struct {
+	kind tokenKind
+	text string
+	lead string
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// code reports whether the token is part of the program, rather than a
+// comment or line break
+func (t token) code() bool {
+	return t.kind != tokComment && t.kind != tokNewline
+}
+
+// multi-character operators, longest first
+var operators = []string{"...", "..", "==", "~=", "<=", ">=", "::", "//", "<<", ">>"}
+
+// lex splits Lua source into tokens. Strings and comments, long ones
+// included, are single tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line := 1
+	lead := ""
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			tokens = append(tokens, token{kind: tokNewline, text: "\n", lead: lead})
+			lead = ""
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			lead += string(c)
+			i++
+			continue
+		}
+
+		start := i
+		var kind tokenKind
+		switch {
+		case strings.HasPrefix(src[i:], "--"):
+			kind = tokComment
+			if n := longBracket(src[i+2:]); n > 0 {
+				end, err := longBracketEnd(src, i+2, n)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: unfinished long comment", line)
+				}
+				i = end
+			} else {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+			}
+		case c == '"' || c == '\'':
+			kind = tokString
+			i++
+			for ; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' {
+					i++
+				} else if src[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unfinished string", line)
+				}
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unfinished string", line)
+			}
+			i++
+		case c == '[' && longBracket(src[i:]) > 0:
+			kind = tokString
+			end, err := longBracketEnd(src, i, longBracket(src[i:]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: unfinished long string", line)
+			}
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			kind = tokNumber
+			for i < len(src) && (isNameChar(src[i]) || src[i] == '.' ||
+				((src[i] == '+' || src[i] == '-') && exponentSign(src[start:i]))) {
+				i++
+			}
+		case isNameStart(c):
+			kind = tokName
+			for i < len(src) && isNameChar(src[i]) {
+				i++
+			}
+		default:
+			kind = tokOp
+			i++
+			for _, op := range operators {
+				if strings.HasPrefix(src[start:], op) {
+					i = start + len(op)
+					break
+				}
+			}
+		}
+
+		text := src[start:i]
+		tokens = append(tokens, token{kind: kind, text: text, lead: lead})
+		lead = ""
+		line += strings.Count(text, "\n")
+	}
+	if lead != "" {
+		tokens = append(tokens, token{kind: tokNewline, text: "", lead: lead})
+	}
+	return tokens, nil
+}
+
+// longBracket returns the length of the opening long bracket [[ or [==[
+// at the start of s, or 0
+func longBracket(s string) int {
+	if !strings.HasPrefix(s, "[") {
+		return 0
+	}
+	n := 1
+	for n < len(s) && s[n] == '=' {
+		n++
+	}
+	if n < len(s) && s[n] == '[' {
+		return n + 1
+	}
+	return 0
+}
+
+// longBracketEnd returns the index after the long bracket opened at start
+func longBracketEnd(src string, start, open int) (int, error) {
+	closing := "]" + strings.Repeat("=", open-2) + "]"
+	end := strings.Index(src[start+open:], closing)
+	if end < 0 {
+		return 0, fmt.Errorf("unfinished long bracket")
+	}
+	return start + open + end + len(closing), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+// exponentSign reports whether a sign after number is its exponent's:
+// 1e-3 and 0x1p-3, but not 0x1e-3, which is a subtraction
+func exponentSign(number string) bool {
+	last := number[len(number)-1]
+	if strings.HasPrefix(number, "0x") || strings.HasPrefix(number, "0X") {
+		return last == 'p' || last == 'P'
+	}
+	return last == 'e' || last == 'E'
+}
@@ -0,0 +1,174 @@
+package luainterface
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+)
+
+// agent.wait_for defaults
+const (
+	defaultAgentWaitTimeout  = 10 * time.Minute
+	defaultAgentWaitInterval = 5 * time.Second
+	// agentHeartbeatWindow is how recent a heartbeat must be for the agent
+	// to count as healthy, as in 'sloth-runner agent list'
+	agentHeartbeatWindow = 60 * time.Second
+)
+
+// agentRegistry returns the master client agents are looked up with,
+// replaced in tests
+var agentRegistry = masterAgentRegistry
+
+var (
+	masterRegistryMu sync.Mutex
+	masterRegistry   pb.AgentRegistryClient
+)
+
+// SetMasterRegistry sets the master agent.wait_for and host.reboot ask
+// about agents.
+// Agents set it to their master; other runs reach the master at
+// SLOTH_RUNNER_MASTER_ADDR or the configured default master.
+func SetMasterRegistry(client pb.AgentRegistryClient) {
+	masterRegistryMu.Lock()
+	defer masterRegistryMu.Unlock()
+	masterRegistry = client
+}
+
+func masterAgentRegistry() (pb.AgentRegistryClient, error) {
+	masterRegistryMu.Lock()
+	defer masterRegistryMu.Unlock()
+	if masterRegistry != nil {
+		return masterRegistry, nil
+	}
+
+	addr := config.GetMasterAddress()
+	if addr == "" {
+		return nil, fmt.Errorf("no master configured (set SLOTH_RUNNER_MASTER_ADDR)")
+	}
+	conn, err := grpc.Dial(addr, mtls.DialOption())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to master: %w", err)
+	}
+	masterRegistry = pb.NewAgentRegistryClient(conn)
+	return masterRegistry, nil
+}
+
+// RegisterAgentModule registers the agent module, which waits for agents
+// started earlier in the run, such as on a VM just provisioned:
+//
+//	local vm = assert(agent.wait_for{name = "new-vm", timeout = "10m"})
+//	log.info("new-vm is up at " .. vm.address)
+//
+// An agent is healthy once it is registered with the master, its last
+// heartbeat is recent and the master's circuit breaker for it is not open.
+func RegisterAgentModule(L *lua.LState) {
+	agentTable := L.NewTable()
+	L.SetField(agentTable, "wait_for", L.NewFunction(luaAgentWaitFor))
+	L.SetGlobal("agent", agentTable)
+}
+
+// luaAgentWaitFor blocks until an agent is healthy:
+// agent.wait_for{name = "new-vm", timeout = "10m", interval = "5s"}
+func luaAgentWaitFor(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	name := optString(opts, "name")
+	if name == "" {
+		L.ArgError(1, "name is required")
+		return 0
+	}
+	timeout, err := optDuration(opts, "timeout", defaultAgentWaitTimeout)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	interval, err := optDuration(opts, "interval", defaultAgentWaitInterval)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	info, err := waitForAgent(ctx, name, timeout, interval, nil)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(info.AgentName))
+	t.RawSetString("address", lua.LString(info.AgentAddress))
+	t.RawSetString("version", lua.LString(info.Version))
+	t.RawSetString("last_heartbeat", lua.LNumber(info.LastHeartbeat))
+	L.Push(t)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// waitForAgent polls the master until the agent is healthy, and ready
+// when given returns no reason to keep waiting, or timeout elapsed. The
+// error says why the agent last wasn't healthy.
+func waitForAgent(ctx context.Context, name string, timeout, interval time.Duration, ready func(context.Context, *pb.AgentInfo) string) (*pb.AgentInfo, error) {
+	registry, err := agentRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		info, reason := checkAgentHealth(ctx, registry, name)
+		if reason == "" && ready != nil {
+			reason = ready(ctx, info)
+		}
+		if reason == "" {
+			return info, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, fmt.Errorf("agent %s not healthy after %s: %s", name, timeout, reason)
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// checkAgentHealth asks the master about an agent and returns why it isn't
+// healthy, or an empty reason
+func checkAgentHealth(ctx context.Context, registry pb.AgentRegistryClient, name string) (*pb.AgentInfo, string) {
+	resp, err := registry.GetAgentInfo(ctx, &pb.GetAgentInfoRequest{AgentName: name})
+	switch {
+	case err != nil:
+		return nil, fmt.Sprintf("master unreachable: %v", err)
+	case !resp.Success || resp.AgentInfo == nil:
+		return nil, "not registered with the master"
+	}
+
+	info := resp.AgentInfo
+	if info.LastHeartbeat == 0 {
+		return info, "no heartbeat yet"
+	}
+	if since := time.Since(time.Unix(info.LastHeartbeat, 0)); since >= agentHeartbeatWindow {
+		return info, fmt.Sprintf("last heartbeat %s ago", since.Round(time.Second))
+	}
+	if info.CircuitState == "open" {
+		return info, "the master can't reach it (circuit open)"
+	}
+	return info, ""
+}
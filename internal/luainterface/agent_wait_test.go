@@ -0,0 +1,94 @@
+package luainterface
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+)
+
+// fakeAgentRegistry registers its agent after a number of lookups, like a
+// VM booting while the run waits for it
+type fakeAgentRegistry struct {
+	pb.AgentRegistryClient
+	mu            sync.Mutex
+	lookups       int
+	registerAfter int
+	heartbeat     time.Time
+	circuit       string
+}
+
+func (f *fakeAgentRegistry) GetAgentInfo(ctx context.Context, in *pb.GetAgentInfoRequest, opts ...grpc.CallOption) (*pb.GetAgentInfoResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	if f.lookups <= f.registerAfter {
+		return &pb.GetAgentInfoResponse{Message: "Agent not found: " + in.AgentName}, nil
+	}
+	return &pb.GetAgentInfoResponse{Success: true, AgentInfo: &pb.AgentInfo{
+		AgentName:     in.AgentName,
+		AgentAddress:  "10.0.0.7:50051",
+		LastHeartbeat: f.heartbeat.Unix(),
+		Version:       "v1.2.0",
+		CircuitState:  f.circuit,
+	}}, nil
+}
+
+func runAgentWait(t *testing.T, registry *fakeAgentRegistry, script string) *lua.LState {
+	t.Helper()
+	orig := agentRegistry
+	agentRegistry = func() (pb.AgentRegistryClient, error) { return registry, nil }
+	t.Cleanup(func() { agentRegistry = orig })
+
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	RegisterAgentModule(L)
+	require.NoError(t, L.DoString(script))
+	return L
+}
+
+func TestAgentWaitFor_WaitsForRegistration(t *testing.T) {
+	registry := &fakeAgentRegistry{registerAfter: 2, heartbeat: time.Now(), circuit: "closed"}
+	L := runAgentWait(t, registry, `vm, err = agent.wait_for{name = "new-vm", timeout = "5s", interval = 0.01}`)
+
+	assert.Equal(t, lua.LNil, L.GetGlobal("err"))
+	vm := L.GetGlobal("vm").(*lua.LTable)
+	assert.Equal(t, "new-vm", vm.RawGetString("name").String())
+	assert.Equal(t, "10.0.0.7:50051", vm.RawGetString("address").String())
+	assert.Equal(t, 3, registry.lookups)
+}
+
+func TestAgentWaitFor_TimesOut(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry *fakeAgentRegistry
+		reason   string
+	}{
+		{"never registers", &fakeAgentRegistry{registerAfter: 1 << 30}, "not registered with the master"},
+		{"stale heartbeat", &fakeAgentRegistry{heartbeat: time.Now().Add(-5 * time.Minute)}, "last heartbeat 5m"},
+		{"unreachable", &fakeAgentRegistry{heartbeat: time.Now(), circuit: "open"}, "circuit open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			L := runAgentWait(t, tt.registry, `vm, err = agent.wait_for{name = "new-vm", timeout = 0.05, interval = 0.01}`)
+
+			assert.Equal(t, lua.LNil, L.GetGlobal("vm"))
+			err := L.GetGlobal("err").String()
+			assert.Contains(t, err, "agent new-vm not healthy after 50ms")
+			assert.Contains(t, err, tt.reason)
+		})
+	}
+}
+
+func TestAgentWaitFor_RequiresName(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	RegisterAgentModule(L)
+	assert.Error(t, L.DoString(`agent.wait_for{timeout = "1m"}`))
+}
@@ -0,0 +1,298 @@
+package luainterface
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/blobstore"
+	"github.com/chalkan3-sloth/sloth-runner/internal/download"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ArtifactModule downloads files through the agent's artifact cache when
+// one is configured, falling back to a direct download otherwise
+type ArtifactModule struct {
+	client *http.Client
+}
+
+// NewArtifactModule creates a new artifact module
+func NewArtifactModule() *ArtifactModule {
+	return &ArtifactModule{
+		client: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// RegisterArtifactModule registers the artifact module with the Lua state
+func RegisterArtifactModule(L *lua.LState) {
+	module := NewArtifactModule()
+
+	artifactTable := L.NewTable()
+	L.SetField(artifactTable, "fetch", L.NewFunction(module.luaFetch))
+	L.SetField(artifactTable, "path", L.NewFunction(module.luaPath))
+	L.SetField(artifactTable, "cached", L.NewFunction(module.luaCached))
+	L.SetField(artifactTable, "stats", L.NewFunction(module.luaStats))
+	L.SetField(artifactTable, "enabled", L.NewFunction(module.luaEnabled))
+	L.SetField(artifactTable, "put", L.NewFunction(module.luaPut))
+	L.SetField(artifactTable, "get", L.NewFunction(module.luaGet))
+	L.SetField(artifactTable, "exists", L.NewFunction(module.luaExists))
+
+	L.SetGlobal("artifact", artifactTable)
+}
+
+// luaFetch downloads url to dest:
+// artifact.fetch(url, dest, {mode = "0755", bandwidth = "10MB/s"})
+func (m *ArtifactModule) luaFetch(L *lua.LState) int {
+	url := L.CheckString(1)
+	dest := L.CheckString(2)
+	opts := L.OptTable(3, nil)
+
+	ctx, err := downloadContext(L, opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	mode, err := optFileMode(L, opts, "mode", 0644)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	if cache := agent.GetGlobalArtifactCache(); cache != nil {
+		fetched, err := cache.CopyTo(ctx, url, dest, mode)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.SetField(result, "path", lua.LString(dest))
+		L.SetField(result, "source", lua.LString(fetched.Hit))
+		L.SetField(result, "peer", lua.LString(fetched.Peer))
+		L.SetField(result, "size", lua.LNumber(fetched.Size))
+		L.SetField(result, "sha256", lua.LString(fetched.SHA256))
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	}
+
+	size, err := m.download(ctx, url, dest, mode)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.SetField(result, "path", lua.LString(dest))
+	L.SetField(result, "source", lua.LString(agent.ArtifactSourceOrigin))
+	L.SetField(result, "size", lua.LNumber(size))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+func (m *ArtifactModule) download(ctx context.Context, url, dest string, mode os.FileMode) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := download.Default().Do(m.client, req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to download %s: HTTP %d", url, resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create destination: %w", err)
+	}
+	defer f.Close()
+
+	return io.Copy(f, resp.Body)
+}
+
+// downloadContext returns the context for a download of the running task,
+// with the bandwidth option of opts applied
+func downloadContext(L *lua.LState, opts *lua.LTable) (context.Context, error) {
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts == nil {
+		return ctx, nil
+	}
+	v := opts.RawGetString("bandwidth")
+	if v == lua.LNil {
+		return ctx, nil
+	}
+	rate, err := download.ParseRate(v.String())
+	if err != nil {
+		return nil, err
+	}
+	return download.WithOptions(ctx, download.Options{Bandwidth: rate}), nil
+}
+
+// luaPath returns the cache path for url, fetching it if needed:
+// artifact.path(url, {bandwidth = "10MB/s"})
+func (m *ArtifactModule) luaPath(L *lua.LState) int {
+	url := L.CheckString(1)
+
+	ctx, err := downloadContext(L, L.OptTable(2, nil))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	cache := agent.GetGlobalArtifactCache()
+	if cache == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("artifact cache is not enabled on this agent"))
+		return 2
+	}
+
+	fetched, err := cache.Fetch(ctx, url)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LString(fetched.Path))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaCached reports whether url is already in the local cache
+func (m *ArtifactModule) luaCached(L *lua.LState) int {
+	url := L.CheckString(1)
+
+	cache := agent.GetGlobalArtifactCache()
+	if cache == nil {
+		L.Push(lua.LFalse)
+		return 1
+	}
+	_, ok := cache.Lookup(agent.ArtifactKey(url))
+	L.Push(lua.LBool(ok))
+	return 1
+}
+
+// luaStats returns the cache counters
+func (m *ArtifactModule) luaStats(L *lua.LState) int {
+	cache := agent.GetGlobalArtifactCache()
+	if cache == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	stats := cache.Stats()
+	t := L.NewTable()
+	L.SetField(t, "local_hits", lua.LNumber(stats.LocalHits))
+	L.SetField(t, "peer_hits", lua.LNumber(stats.PeerHits))
+	L.SetField(t, "origin_fetches", lua.LNumber(stats.OriginFetches))
+	L.SetField(t, "bytes_from_peer", lua.LNumber(stats.BytesFromPeer))
+	L.SetField(t, "bytes_from_origin", lua.LNumber(stats.BytesOrigin))
+	L.SetField(t, "entries", lua.LNumber(stats.Entries))
+	L.Push(t)
+	return 1
+}
+
+// luaEnabled reports whether the agent artifact cache is active
+func (m *ArtifactModule) luaEnabled(L *lua.LState) int {
+	L.Push(lua.LBool(agent.GetGlobalArtifactCache() != nil))
+	return 1
+}
+
+// blobClient returns the agent's blob client or raises a Lua error
+func blobClient(L *lua.LState) *blobstore.Client {
+	client := blobstore.GetGlobalClient()
+	if client == nil {
+		L.RaiseError("blob store is not configured on this agent (start it with --blob-endpoint)")
+	}
+	return client
+}
+
+// luaPut uploads a file to the master's blob store: artifact.put(path)
+// returns the digest other agents use to fetch it
+func (m *ArtifactModule) luaPut(L *lua.LState) int {
+	path := L.CheckString(1)
+	client := blobClient(L)
+
+	digest, _, err := client.PutFile(context.Background(), path)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LString(digest))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaGet downloads a blob by digest: artifact.get(digest, dest, {mode = "0755"}).
+// Without dest the blob is written to a temporary file.
+func (m *ArtifactModule) luaGet(L *lua.LState) int {
+	digest := L.CheckString(1)
+	dest := L.OptString(2, "")
+	opts := L.OptTable(3, nil)
+	client := blobClient(L)
+
+	mode, err := optFileMode(L, opts, "mode", 0644)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	if dest == "" {
+		hexPart, err := blobstore.ParseDigest(digest)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		dest = filepath.Join(os.TempDir(), "sloth-blobs", hexPart)
+	}
+
+	size, err := client.GetFile(context.Background(), digest, dest, mode)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	result := L.NewTable()
+	L.SetField(result, "path", lua.LString(dest))
+	L.SetField(result, "digest", lua.LString(digest))
+	L.SetField(result, "size", lua.LNumber(size))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaExists reports whether the blob store holds a digest
+func (m *ArtifactModule) luaExists(L *lua.LState) int {
+	digest := L.CheckString(1)
+	client := blobClient(L)
+
+	ok, err := client.Exists(context.Background(), digest)
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LBool(ok))
+	return 1
+}
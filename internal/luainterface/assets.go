@@ -0,0 +1,46 @@
+package luainterface
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// OpenAssets registers asset(name), which returns the path of a file
+// embedded in the assets of the sloth file's front-matter. Assets are
+// extracted into the workspace before a task group runs and travel with
+// it to agents, so the same path works wherever the task runs.
+func OpenAssets(L *lua.LState) {
+	L.SetGlobal("asset", L.NewFunction(luaAsset))
+}
+
+func luaAsset(L *lua.LState) int {
+	name := L.CheckString(1)
+	if err := sloth.ValidAssetName(name); err != nil {
+		L.RaiseError("%s", err.Error())
+		return 0
+	}
+
+	var dir lua.LString
+	if taskContext, ok := L.GetGlobal("__task_context").(*lua.LTable); ok {
+		dir, _ = taskContext.RawGetString("assets_dir").(lua.LString)
+	}
+	if dir == "" {
+		L.RaiseError("asset(%q): assets are only available while a task runs", name)
+		return 0
+	}
+
+	// Absolute, so the path still works for commands run in another directory
+	path, err := filepath.Abs(filepath.Join(string(dir), filepath.FromSlash(name)))
+	if err == nil {
+		_, err = os.Stat(path)
+	}
+	if err != nil {
+		L.RaiseError("asset %q is not embedded in this workflow", name)
+		return 0
+	}
+	L.Push(lua.LString(path))
+	return 1
+}
@@ -0,0 +1,42 @@
+package luainterface
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestAsset(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "conf"), 0755)
+	os.WriteFile(filepath.Join(dir, "conf", "app.conf"), []byte("port = 8080"), 0644)
+
+	L := lua.NewState()
+	defer L.Close()
+	OpenAssets(L)
+
+	// Outside a task there is no workspace to find assets in
+	if err := L.DoString(`asset("conf/app.conf")`); err == nil || !strings.Contains(err.Error(), "while a task runs") {
+		t.Errorf("Expected an error outside a task, got %v", err)
+	}
+
+	taskContext := L.NewTable()
+	taskContext.RawSetString("assets_dir", lua.LString(dir))
+	L.SetGlobal("__task_context", taskContext)
+
+	if err := L.DoString(`path = asset("conf/app.conf")`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path := L.GetGlobal("path").String(); path != filepath.Join(dir, "conf", "app.conf") {
+		t.Errorf("Unexpected path %q", path)
+	}
+
+	for _, name := range []string{"missing.conf", "../conf/app.conf"} {
+		if err := L.DoString(`asset("` + name + `")`); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package luainterface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/core"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// asyncStates are the Lua states functions run by the async module run in
+var asyncStates = NewStatePool(runtime.NumCPU())
+
+// errFailFast cancels the other functions of async.parallel once one failed
+var errFailFast = errors.New("another function failed")
+
+// RegisterAsyncModule registers the async module, which runs Lua functions
+// concurrently on the core worker pool:
+//
+//	local results, err = async.parallel({
+//	    function() return exec.run("make -C api") end,
+//	    function() return exec.run("make -C web") end,
+//	}, {workers = 2, timeout = "10m"})
+//
+// Each function runs in a Lua state of its own. It gets copies of its
+// upvalues and of the workflow's globals, so functions can't change each
+// other's variables or the caller's; results are returned instead.
+func RegisterAsyncModule(L *lua.LState) {
+	asyncTable := L.NewTable()
+	L.SetField(asyncTable, "parallel", L.NewFunction(luaAsyncParallel))
+	L.SetField(asyncTable, "sequence", L.NewFunction(luaAsyncSequence))
+	L.SetField(asyncTable, "timeout", L.NewFunction(luaAsyncTimeout))
+	L.SetGlobal("async", asyncTable)
+}
+
+// luaAsyncParallel runs functions concurrently:
+// async.parallel(fns, {workers = n, timeout = "5m", fail_fast = true}).
+// It returns the results keyed like fns and an error listing the
+// functions that failed.
+func luaAsyncParallel(L *lua.LState) int {
+	fns := L.CheckTable(1)
+	opts := L.OptTable(2, L.NewTable())
+
+	jobs, err := asyncJobs(fns)
+	if err != nil {
+		L.ArgError(1, err.Error())
+	}
+	timeout, err := optDuration(opts, "timeout", 0)
+	if err != nil {
+		L.ArgError(2, err.Error())
+	}
+	workers := defaultAsyncWorkers()
+	if n, ok := opts.RawGetString("workers").(lua.LNumber); ok {
+		if n < 1 {
+			L.ArgError(2, "workers must be at least 1")
+		}
+		workers = int(n)
+	}
+	failFast := opts.RawGetString("fail_fast") != lua.LFalse
+
+	g := newAsyncGroup(L, jobs, timeout, failFast)
+	g.run(workers)
+
+	L.Push(g.results(L))
+	if err := g.err(); err != nil {
+		L.Push(lua.LString(err.Error()))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 2
+}
+
+// luaAsyncSequence calls functions one after the other in the caller's
+// state, stopping at the first that fails: async.sequence(fns). It
+// returns the results keyed like fns and the error that stopped it.
+func luaAsyncSequence(L *lua.LState) int {
+	jobs, err := asyncJobs(L.CheckTable(1))
+	if err != nil {
+		L.ArgError(1, err.Error())
+	}
+
+	results := L.NewTable()
+	for _, job := range jobs {
+		top := L.GetTop()
+		L.Push(job.fn)
+		err := L.PCall(0, lua.MultRet, nil)
+		if err == nil {
+			values := make([]lua.LValue, 0, L.GetTop()-top)
+			for i := top + 1; i <= L.GetTop(); i++ {
+				values = append(values, L.Get(i))
+			}
+			var value lua.LValue
+			value, err = asyncResult(values)
+			results.RawSet(job.key, value)
+		}
+		L.SetTop(top)
+		if err != nil {
+			L.Push(results)
+			L.Push(lua.LString(fmt.Sprintf("%s failed: %s", asyncKey(job.key), MapError(err))))
+			return 2
+		}
+	}
+	L.Push(results)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaAsyncTimeout runs a function in a state of its own and gives up on it
+// after a while: async.timeout(duration, fn). It returns the function's
+// result and an error when it failed or took too long.
+func luaAsyncTimeout(L *lua.LState) int {
+	opts := L.NewTable()
+	opts.RawSetString("timeout", L.CheckAny(1))
+	timeout, err := optDuration(opts, "timeout", 0)
+	if err != nil {
+		L.ArgError(1, err.Error())
+	}
+	fn := L.CheckFunction(2)
+
+	g := newAsyncGroup(L, []*asyncJob{{key: lua.LNumber(1), fn: fn}}, timeout, false)
+	g.run(1)
+
+	L.Push(g.results(L).RawGetInt(1))
+	switch job := g.jobs[0]; {
+	case job.err == nil:
+		L.Push(lua.LNil)
+	case g.timedOut():
+		L.Push(lua.LString(fmt.Sprintf("timed out after %s", timeout)))
+	default:
+		L.Push(lua.LString(job.err.Error()))
+	}
+	return 2
+}
+
+// defaultAsyncWorkers is how many functions async.parallel runs at once
+// unless told otherwise: as many as the core worker pool has workers
+func defaultAsyncWorkers() int {
+	if gc := core.GetGlobalCore(); gc != nil && gc.WorkerPool != nil {
+		return gc.WorkerPool.Stats().Workers
+	}
+	return runtime.NumCPU() * 2
+}
+
+// asyncJob is one function of an async call
+type asyncJob struct {
+	key lua.LValue
+	fn  *lua.LFunction
+	// Set once the function returned
+	state  *lua.LState
+	env    *lua.LTable
+	values []lua.LValue
+	err    error
+	// stopped is set when the function failed after the group was
+	// cancelled, most likely because of it
+	stopped bool
+}
+
+// asyncJobs lists the functions of fns: those of the array part in order,
+// then the others by key
+func asyncJobs(fns *lua.LTable) ([]*asyncJob, error) {
+	var jobs, named []*asyncJob
+	var bad lua.LValue
+	n := fns.Len()
+	fns.ForEach(func(key, value lua.LValue) {
+		fn, ok := value.(*lua.LFunction)
+		if !ok {
+			if bad == nil {
+				bad = key
+			}
+			return
+		}
+		job := &asyncJob{key: key, fn: fn}
+		if i, ok := key.(lua.LNumber); ok && float64(i) == float64(int(i)) && int(i) >= 1 && int(i) <= n {
+			jobs = append(jobs, job)
+		} else {
+			named = append(named, job)
+		}
+	})
+	if bad != nil {
+		return nil, fmt.Errorf("%s is not a function", asyncKey(bad))
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].key.(lua.LNumber) < jobs[j].key.(lua.LNumber) })
+	sort.Slice(named, func(i, j int) bool { return named[i].key.String() < named[j].key.String() })
+	return append(jobs, named...), nil
+}
+
+// asyncGroup runs the functions of one async call
+type asyncGroup struct {
+	jobs     []*asyncJob
+	timeout  time.Duration
+	failFast bool
+
+	ctx           context.Context
+	cancel        context.CancelCauseFunc
+	cancelTimeout context.CancelFunc
+	next          atomic.Int64
+	failed        atomic.Bool
+	wg            sync.WaitGroup
+}
+
+func newAsyncGroup(L *lua.LState, jobs []*asyncJob, timeout time.Duration, failFast bool) *asyncGroup {
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	g := &asyncGroup{jobs: jobs, timeout: timeout, failFast: failFast, cancelTimeout: func() {}}
+	if timeout > 0 {
+		ctx, g.cancelTimeout = context.WithTimeout(ctx, timeout)
+	}
+	g.ctx, g.cancel = context.WithCancelCause(ctx)
+	return g
+}
+
+// run runs the functions, up to workers at once, and waits for them. The
+// caller runs functions as well: it never waits on a function that hasn't
+// started, so nested calls can't deadlock a busy pool.
+func (g *asyncGroup) run(workers int) {
+	defer g.cancelTimeout()
+	defer g.cancel(nil)
+	g.wg.Add(len(g.jobs))
+	for i := 1; i < workers && i < len(g.jobs); i++ {
+		if !submitAsync(g.work) {
+			break
+		}
+	}
+	g.work()
+	g.wg.Wait()
+}
+
+// submitAsync runs work on the core worker pool, or on a goroutine when
+// there is no pool. It returns false when the pool's queue is full.
+func submitAsync(work func()) bool {
+	if gc := core.GetGlobalCore(); gc != nil && gc.WorkerPool != nil {
+		return gc.SubmitTask(work, "async")
+	}
+	go work()
+	return true
+}
+
+// work runs the functions nobody has started yet
+func (g *asyncGroup) work() {
+	for {
+		i := int(g.next.Add(1)) - 1
+		if i >= len(g.jobs) {
+			return
+		}
+		g.runJob(g.jobs[i])
+		g.wg.Done()
+	}
+}
+
+func (g *asyncGroup) runJob(job *asyncJob) {
+	if err := g.ctx.Err(); err != nil {
+		job.err, job.stopped = context.Cause(g.ctx), true
+		return
+	}
+
+	job.state = asyncStates.Get()
+	job.state.SetContext(g.ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			job.err = fmt.Errorf("panic: %v", r)
+		}
+		if job.err != nil {
+			job.stopped = g.ctx.Err() != nil
+			if g.failFast && !job.stopped && g.failed.CompareAndSwap(false, true) {
+				g.cancel(errFailFast)
+			}
+		}
+	}()
+
+	c := newChildCopier(job.state, job.fn.Env)
+	job.env = c.env
+	fn := c.copy(job.fn)
+	job.state.Push(fn)
+	if err := job.state.PCall(0, lua.MultRet, nil); err != nil {
+		job.err = MapError(err)
+		return
+	}
+	for i := 1; i <= job.state.GetTop(); i++ {
+		job.values = append(job.values, job.state.Get(i))
+	}
+}
+
+// results copies the results of the functions into L, keyed like the
+// functions, and releases their states
+func (g *asyncGroup) results(L *lua.LState) *lua.LTable {
+	results := L.NewTable()
+	for _, job := range g.jobs {
+		if job.err == nil {
+			value, err := asyncResult(job.values)
+			if err != nil {
+				job.err = err
+			} else {
+				results.RawSet(job.key, newLuaCopier(L, L.G.Global).copy(value))
+			}
+		}
+		if job.state != nil {
+			asyncEnvs.Delete(job.env)
+			asyncStates.Put(job.state)
+			job.state, job.env, job.values = nil, nil, nil
+		}
+	}
+	return results
+}
+
+// err describes the functions that failed, or returns nil when all of
+// them returned
+func (g *asyncGroup) err() error {
+	var failures []string
+	stopped := 0
+	for _, job := range g.jobs {
+		switch {
+		case job.err == nil:
+		case job.stopped:
+			stopped++
+		default:
+			failures = append(failures, fmt.Sprintf("%s %s", asyncKey(job.key), job.err))
+		}
+	}
+
+	if len(failures) > 0 {
+		msg := fmt.Sprintf("%d of %d functions failed: %s", len(failures), len(g.jobs), strings.Join(failures, "; "))
+		if stopped > 0 {
+			msg += fmt.Sprintf(" (%d cancelled)", stopped)
+		}
+		return errors.New(msg)
+	}
+	if stopped == 0 {
+		return nil
+	}
+	if g.timedOut() {
+		return fmt.Errorf("timed out after %s, %d of %d functions did not finish", g.timeout, stopped, len(g.jobs))
+	}
+	return fmt.Errorf("cancelled, %d of %d functions did not finish: %v", stopped, len(g.jobs), context.Cause(g.ctx))
+}
+
+// timedOut reports whether the functions were stopped by the timeout
+func (g *asyncGroup) timedOut() bool {
+	return g.timeout > 0 && errors.Is(context.Cause(g.ctx), context.DeadlineExceeded)
+}
+
+// asyncResult is the result of a function from the values it returned:
+// the first, unless it returned nil and an error message, as modules do
+// when they fail
+func asyncResult(values []lua.LValue) (lua.LValue, error) {
+	if len(values) == 0 {
+		return lua.LNil, nil
+	}
+	if len(values) >= 2 && values[0] == lua.LNil {
+		if msg, ok := values[1].(lua.LString); ok {
+			return lua.LNil, errors.New(string(msg))
+		}
+	}
+	return values[0], nil
+}
+
+func asyncKey(key lua.LValue) string {
+	return "[" + key.String() + "]"
+}
+
+// luaCopier copies values into another Lua state. Tables are copied
+// deeply, keeping shared and cyclic references, and Lua functions are
+// rebuilt from their prototype with copies of their upvalues. Userdata,
+// channels and Go functions are shared; coroutines become nil.
+type luaCopier struct {
+	dst  *lua.LState
+	env  *lua.LTable
+	seen map[lua.LValue]lua.LValue
+}
+
+// newLuaCopier copies into dst; rebuilt Lua functions use env as their
+// globals
+func newLuaCopier(dst *lua.LState, env *lua.LTable) *luaCopier {
+	return &luaCopier{dst: dst, env: env, seen: make(map[lua.LValue]lua.LValue)}
+}
+
+// asyncEnv is where the globals of functions run by the async module
+// come from: the state they run in, then the globals of the function they
+// were copied from
+type asyncEnv struct {
+	globals *lua.LTable
+	source  *lua.LTable
+}
+
+// asyncEnvs maps the globals table of each running function to its
+// asyncEnv, so functions started from such a function find the globals
+// it sees
+var asyncEnvs sync.Map
+
+// newChildCopier copies functions into the state child they run in. Their
+// globals are those of child, falling back to copies of the globals in
+// source, made the first time they are read.
+func newChildCopier(child *lua.LState, source *lua.LTable) *luaCopier {
+	env := child.NewTable()
+	c := newLuaCopier(child, env)
+	asyncEnvs.Store(env, &asyncEnv{globals: child.G.Global, source: source})
+
+	mt := child.NewTable()
+	mt.RawSetString("__index", child.NewFunction(func(L *lua.LState) int {
+		key := L.Get(2)
+		value := child.G.Global.RawGet(key)
+		if value == lua.LNil {
+			value = c.copy(lookupGlobal(source, key))
+			env.RawSet(key, value)
+		}
+		L.Push(value)
+		return 1
+	}))
+	env.Metatable = mt
+	return c
+}
+
+// lookupGlobal reads a global from a globals table without running Lua
+// code, following the environments of functions run by the async module
+func lookupGlobal(globals *lua.LTable, key lua.LValue) lua.LValue {
+	for globals != nil {
+		if value := globals.RawGet(key); value != lua.LNil {
+			return value
+		}
+		e, ok := asyncEnvs.Load(globals)
+		if !ok {
+			break
+		}
+		env := e.(*asyncEnv)
+		if value := env.globals.RawGet(key); value != lua.LNil {
+			return value
+		}
+		globals = env.source
+	}
+	return lua.LNil
+}
+
+func (c *luaCopier) copy(value lua.LValue) lua.LValue {
+	switch v := value.(type) {
+	case *lua.LTable:
+		if copied, ok := c.seen[v]; ok {
+			return copied
+		}
+		t := c.dst.NewTable()
+		c.seen[v] = t
+		v.ForEach(func(key, value lua.LValue) {
+			t.RawSet(c.copy(key), c.copy(value))
+		})
+		if mt, ok := v.Metatable.(*lua.LTable); ok {
+			t.Metatable = c.copy(mt)
+		}
+		return t
+	case *lua.LFunction:
+		if v.IsG {
+			return v
+		}
+		if copied, ok := c.seen[v]; ok {
+			return copied
+		}
+		fn := c.dst.NewFunctionFromProto(v.Proto)
+		fn.Env = c.env
+		c.seen[v] = fn
+		for i, uv := range v.Upvalues {
+			copied := &lua.Upvalue{}
+			if uv != nil {
+				copied.SetValue(c.copy(uv.Value()))
+			}
+			fn.Upvalues[i] = copied
+		}
+		return fn
+	case *lua.LState:
+		return lua.LNil
+	default:
+		return value
+	}
+}
@@ -0,0 +1,186 @@
+package luainterface
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func runAsync(t *testing.T, script string) *lua.LState {
+	t.Helper()
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	OpenAll(L)
+	require.NoError(t, L.DoString(script))
+	return L
+}
+
+func asyncErr(L *lua.LState) string {
+	err, _ := L.GetGlobal("err").(lua.LString)
+	return string(err)
+}
+
+func TestAsyncParallel_OrdersResults(t *testing.T) {
+	L := runAsync(t, `
+		results, err = async.parallel({
+			function() time.sleep(0.05) return "first" end,
+			function() return "second" end,
+			function() time.sleep(0.02) return "third" end,
+			web = function() return "w" end,
+			api = function() return "a" end,
+		})
+	`)
+	require.Empty(t, asyncErr(L))
+	results := L.GetGlobal("results").(*lua.LTable)
+	assert.Equal(t, 3, results.Len())
+	assert.Equal(t, "first", results.RawGetInt(1).String())
+	assert.Equal(t, "second", results.RawGetInt(2).String())
+	assert.Equal(t, "third", results.RawGetInt(3).String())
+	assert.Equal(t, "w", results.RawGetString("web").String())
+	assert.Equal(t, "a", results.RawGetString("api").String())
+}
+
+func TestAsyncParallel_RunsConcurrently(t *testing.T) {
+	start := time.Now()
+	L := runAsync(t, `
+		local fns = {}
+		for i = 1, 4 do
+			fns[i] = function() time.sleep(0.2) return i end
+		end
+		results, err = async.parallel(fns, {workers = 4})
+	`)
+	require.Empty(t, asyncErr(L))
+	assert.Less(t, time.Since(start), 700*time.Millisecond)
+	results := L.GetGlobal("results").(*lua.LTable)
+	for i := 1; i <= 4; i++ {
+		assert.Equal(t, lua.LNumber(i), results.RawGetInt(i))
+	}
+}
+
+func TestAsyncParallel_IsolatesFunctions(t *testing.T) {
+	L := runAsync(t, `
+		count = 0
+		local shared = {n = 0}
+		local fns = {}
+		for i = 1, 8 do
+			fns[i] = function()
+				count = count + 1
+				shared.n = shared.n + 1
+				return {count = count, n = shared.n}
+			end
+		end
+		results, err = async.parallel(fns)
+		shared_n = shared.n
+	`)
+	require.Empty(t, asyncErr(L))
+	results := L.GetGlobal("results").(*lua.LTable)
+	for i := 1; i <= 8; i++ {
+		result := results.RawGetInt(i).(*lua.LTable)
+		assert.Equal(t, lua.LNumber(1), result.RawGetString("count"))
+		assert.Equal(t, lua.LNumber(1), result.RawGetString("n"))
+	}
+	assert.Equal(t, lua.LNumber(0), L.GetGlobal("count"))
+	assert.Equal(t, lua.LNumber(0), L.GetGlobal("shared_n"))
+}
+
+func TestAsyncParallel_AggregatesErrors(t *testing.T) {
+	L := runAsync(t, `
+		results, err = async.parallel({
+			function() return "ok" end,
+			function() error("boom") end,
+			function() return nil, "disk full" end,
+		}, {fail_fast = false})
+	`)
+	errMsg := asyncErr(L)
+	assert.Contains(t, errMsg, "2 of 3 functions failed")
+	assert.Contains(t, errMsg, "[2]")
+	assert.Contains(t, errMsg, "boom")
+	assert.Contains(t, errMsg, "[3] disk full")
+	results := L.GetGlobal("results").(*lua.LTable)
+	assert.Equal(t, "ok", results.RawGetInt(1).String())
+	assert.Equal(t, lua.LNil, results.RawGetInt(2))
+}
+
+func TestAsyncParallel_FailFastCancelsOthers(t *testing.T) {
+	start := time.Now()
+	L := runAsync(t, `
+		results, err = async.parallel({
+			function() error("boom") end,
+			function() while true do end end,
+		}, {workers = 2})
+	`)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	errMsg := asyncErr(L)
+	assert.Contains(t, errMsg, "1 of 2 functions failed")
+	assert.Contains(t, errMsg, "boom")
+	assert.Contains(t, errMsg, "1 cancelled")
+}
+
+func TestAsyncParallel_Timeout(t *testing.T) {
+	L := runAsync(t, `
+		results, err = async.parallel({
+			function() return "quick" end,
+			function() while true do end end,
+		}, {workers = 2, timeout = "100ms"})
+	`)
+	assert.Contains(t, asyncErr(L), "timed out after 100ms")
+	results := L.GetGlobal("results").(*lua.LTable)
+	assert.Equal(t, "quick", results.RawGetInt(1).String())
+}
+
+func TestAsyncParallel_Nested(t *testing.T) {
+	L := runAsync(t, `
+		results, err = async.parallel({
+			function()
+				local inner, err = async.parallel({
+					function() return 1 end,
+					function() return 2 end,
+				})
+				if err then return nil, err end
+				return inner[1] + inner[2]
+			end,
+			function() return 10 end,
+		}, {workers = 1})
+	`)
+	require.Empty(t, asyncErr(L))
+	results := L.GetGlobal("results").(*lua.LTable)
+	assert.Equal(t, lua.LNumber(3), results.RawGetInt(1))
+	assert.Equal(t, lua.LNumber(10), results.RawGetInt(2))
+}
+
+func TestAsyncParallel_RejectsNonFunctions(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	OpenAll(L)
+	err := L.DoString(`async.parallel({function() end, "not a function"})`)
+	require.Error(t, err)
+}
+
+func TestAsyncSequence_StopsAtFirstFailure(t *testing.T) {
+	L := runAsync(t, `
+		ran = 0
+		results, err = async.sequence({
+			function() ran = ran + 1 return "a" end,
+			function() ran = ran + 1 return nil, "broken" end,
+			function() ran = ran + 1 return "c" end,
+		})
+	`)
+	assert.Equal(t, "[2] failed: broken", asyncErr(L))
+	assert.Equal(t, lua.LNumber(2), L.GetGlobal("ran"))
+	results := L.GetGlobal("results").(*lua.LTable)
+	assert.Equal(t, "a", results.RawGetInt(1).String())
+}
+
+func TestAsyncTimeout(t *testing.T) {
+	L := runAsync(t, `
+		value, err = async.timeout("1s", function() return 42 end)
+		slow, slow_err = async.timeout(0.1, function() while true do end end)
+	`)
+	require.Empty(t, asyncErr(L))
+	assert.Equal(t, lua.LNumber(42), L.GetGlobal("value"))
+	assert.Equal(t, lua.LNil, L.GetGlobal("slow"))
+	assert.Equal(t, "timed out after 100ms", L.GetGlobal("slow_err").String())
+}
@@ -115,7 +115,10 @@ func (s3 *AWSS3) sync(L *lua.LState) int {
 	opts := L.CheckTable(2)
 	from := opts.RawGetString("from").String()
 	to := opts.RawGetString("to").String()
-	del := lua.LVAsBool(opts.RawGetString("delete"))
+	del, err := optBool(L, opts, "delete", false)
+	if err != nil {
+		L.RaiseError("s3:sync: %v", err)
+	}
 
 	if from == "" || to == "" {
 		L.RaiseError("from and to are required for s3:sync")
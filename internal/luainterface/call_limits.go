@@ -0,0 +1,120 @@
+package luainterface
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/modulecalls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// OpenCallLimits wraps the functions of the modules in a category of
+// modulecalls, so each call fails once it runs past its category's timeout
+// and is logged, then recorded in the task's slow calls, once it runs past
+// the slow threshold
+func OpenCallLimits(L *lua.LState) {
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		module := key.String()
+		category, ok := modulecalls.CategoryOf(module)
+		table, isTable := value.(*lua.LTable)
+		if !ok || !isTable {
+			return
+		}
+
+		var names []string
+		table.ForEach(func(k, v lua.LValue) {
+			if _, ok := v.(*lua.LFunction); ok {
+				names = append(names, k.String())
+			}
+		})
+		for _, name := range names {
+			fn := table.RawGetString(name).(*lua.LFunction)
+			table.RawSetString(name, L.NewFunction(callLimitsWrapper(module, name, category, fn)))
+		}
+	})
+}
+
+// callContext returns the context the commands and requests of a module call
+// run with, so they stop with the task or at the call's timeout
+func callContext(L *lua.LState) context.Context {
+	if ctx := L.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+func callLimitsWrapper(module, function string, category modulecalls.Category, fn *lua.LFunction) lua.LGFunction {
+	call := module + "." + function
+	return func(L *lua.LState) int {
+		bounds := modulecalls.Default()
+		timeout, slow := bounds.Timeout(category), bounds.Slow(category)
+		taskCtx := L.Context()
+
+		// Warn while the call still runs, so hung calls show up before
+		// they time out
+		start := time.Now()
+		if slow > 0 {
+			timer := time.AfterFunc(slow, func() {
+				slog.Warn("Module call is slow", "call", call, "running_for", slow.String())
+			})
+			defer timer.Stop()
+		}
+		record := func(timedOut bool) {
+			elapsed := time.Since(start)
+			if timedOut || (slow > 0 && elapsed >= slow) {
+				taskctx.RecordSlowCall(taskCtx, taskctx.SlowCall{
+					Module: module, Function: function, Duration: elapsed, Threshold: slow, TimedOut: timedOut,
+				})
+			}
+		}
+
+		top := L.GetTop()
+		L.Push(fn)
+		for i := 1; i <= top; i++ {
+			L.Push(L.Get(i))
+		}
+		if timeout <= 0 {
+			L.Call(top, lua.MultRet)
+			record(false)
+			return L.GetTop() - top
+		}
+
+		// The call runs with a context that ends at its timeout, which
+		// stops its Lua code and the commands and requests of modules
+		// honoring it
+		parent := taskCtx
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		L.SetContext(ctx)
+		err := L.PCall(top, lua.MultRet, nil)
+		if taskCtx == nil {
+			L.RemoveContext()
+		} else {
+			L.SetContext(taskCtx)
+		}
+
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil
+		record(timedOut)
+		if timedOut {
+			slog.Warn("Module call timed out", "call", call, "timeout", timeout.String())
+			L.RaiseError("%s timed out after %s (module_calls.%s.timeout in config.yaml)", call, timeout, category)
+			return 0
+		}
+		if err != nil {
+			var apiErr *lua.ApiError
+			if errors.As(err, &apiErr) {
+				L.Error(apiErr.Object, 0)
+			} else {
+				L.RaiseError("%s", err.Error())
+			}
+			return 0
+		}
+		return L.GetTop() - top
+	}
+}
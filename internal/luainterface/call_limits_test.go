@@ -0,0 +1,84 @@
+package luainterface
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/modulecalls"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestOpenCallLimits_TimesOutAndRecordsSlowCalls(t *testing.T) {
+	bounds, err := modulecalls.NewBounds(modulecalls.Config{
+		Network: modulecalls.Limits{Timeout: "100ms", Slow: "20ms"},
+		Package: modulecalls.Limits{Slow: "1h"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	modulecalls.SetDefault(bounds)
+	defer modulecalls.SetDefault(nil)
+
+	L := lua.NewState()
+	defer L.Close()
+	slow := &taskctx.SlowCalls{}
+	L.SetContext(taskctx.WithSlowCalls(context.Background(), slow))
+
+	// http.get waits for its call to end, as requests honoring the
+	// context do; pkg.info returns at once
+	http := L.NewTable()
+	L.SetField(http, "get", L.NewFunction(func(L *lua.LState) int {
+		select {
+		case <-time.After(time.Duration(L.CheckInt(1)) * time.Millisecond):
+			L.Push(lua.LString("ok"))
+			return 1
+		case <-L.Context().Done():
+			L.Push(lua.LNil)
+			L.Push(lua.LString("canceled"))
+			return 2
+		}
+	}))
+	L.SetGlobal("http", http)
+	pkg := L.NewTable()
+	L.SetField(pkg, "info", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString("info " + L.CheckString(1)))
+		return 1
+	}))
+	L.SetGlobal("pkg", pkg)
+	OpenCallLimits(L)
+
+	if err := L.DoString(`out = pkg.info("nginx")`); err != nil {
+		t.Fatalf("Expected a quick call to succeed: %v", err)
+	}
+	if out := L.GetGlobal("out").String(); out != "info nginx" {
+		t.Errorf("Expected the wrapped function's results, got %q", out)
+	}
+	if err := L.DoString(`out = http.get(40)`); err != nil {
+		t.Fatalf("Expected a slow call to succeed: %v", err)
+	}
+	if out := L.GetGlobal("out").String(); out != "ok" {
+		t.Errorf("Expected the wrapped function's results, got %q", out)
+	}
+
+	err = L.DoString(`http.get(10000)`)
+	if err == nil || !strings.Contains(err.Error(), "http.get timed out after 100ms") {
+		t.Errorf("Expected the call to time out, got %v", err)
+	}
+	if L.Context().Err() != nil {
+		t.Error("Expected the task's context back after the call")
+	}
+
+	calls := slow.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 slow calls, got %+v", calls)
+	}
+	if calls[0].Function != "get" || calls[0].TimedOut || calls[0].Threshold != 20*time.Millisecond {
+		t.Errorf("Unexpected slow call %+v", calls[0])
+	}
+	if !calls[1].TimedOut {
+		t.Errorf("Expected the second call to have timed out, got %+v", calls[1])
+	}
+}
@@ -0,0 +1,273 @@
+package luainterface
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	coremodules "github.com/chalkan3-sloth/sloth-runner/internal/modules/core"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Canary defaults
+const (
+	defaultCanaryBake     = 5 * time.Minute
+	defaultCanaryInterval = 30 * time.Second
+)
+
+// dispatchCanaryEvent emits the events of each canary phase, replaced in
+// tests
+var dispatchCanaryEvent = coremodules.DispatchEvent
+
+// canarySpec is a canary deployment to a group of hosts
+type canarySpec struct {
+	name     string
+	canary   []string
+	rest     []string
+	bake     time.Duration
+	interval time.Duration
+	deploy   *lua.LFunction
+	check    *lua.LFunction
+	rollback *lua.LFunction
+}
+
+// RegisterCanaryModule registers canary(), which deploys to a few hosts of
+// a group, checks them for a bake time and then deploys to the rest, or
+// rolls the canary hosts back when a check fails:
+//
+//	assert(canary{
+//	    name = "api",
+//	    hosts = {"web-01", "web-02", "web-03", "web-04"},
+//	    size = "25%",
+//	    bake = "10m",
+//	    deploy = function(hosts) ... end,
+//	    check = function(hosts) return probe.http{url = "http://web-01/health"} end,
+//	    rollback = function(hosts) ... end,
+//	})
+//
+// Each phase emits a canary.* event hooks can react to.
+func RegisterCanaryModule(L *lua.LState) {
+	L.SetGlobal("canary", L.NewFunction(luaCanary))
+}
+
+func luaCanary(L *lua.LState) int {
+	spec, err := newCanarySpec(L.CheckTable(1))
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+
+	result, err := spec.run(L)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+func newCanarySpec(opts *lua.LTable) (*canarySpec, error) {
+	spec := &canarySpec{name: optString(opts, "name")}
+	if spec.name == "" {
+		spec.name = "canary"
+	}
+
+	hostsTable, ok := opts.RawGetString("hosts").(*lua.LTable)
+	if !ok || hostsTable.Len() == 0 {
+		return nil, fmt.Errorf("hosts is required")
+	}
+	var hosts []string
+	for i := 1; i <= hostsTable.Len(); i++ {
+		host, ok := hostsTable.RawGetInt(i).(lua.LString)
+		if !ok || host == "" {
+			return nil, fmt.Errorf("hosts must hold host names")
+		}
+		hosts = append(hosts, string(host))
+	}
+	size, err := canarySize(opts.RawGetString("size"), len(hosts))
+	if err != nil {
+		return nil, err
+	}
+	spec.canary, spec.rest = hosts[:size], hosts[size:]
+
+	if spec.bake, err = optDuration(opts, "bake", defaultCanaryBake); err != nil {
+		return nil, err
+	}
+	if spec.interval, err = optDuration(opts, "interval", defaultCanaryInterval); err != nil {
+		return nil, err
+	}
+
+	if spec.deploy, ok = opts.RawGetString("deploy").(*lua.LFunction); !ok {
+		return nil, fmt.Errorf("deploy function is required")
+	}
+	if spec.check, ok = opts.RawGetString("check").(*lua.LFunction); !ok {
+		return nil, fmt.Errorf("check function is required")
+	}
+	switch fn := opts.RawGetString("rollback").(type) {
+	case *lua.LFunction:
+		spec.rollback = fn
+	case *lua.LNilType:
+	default:
+		return nil, fmt.Errorf("rollback must be a function")
+	}
+	return spec, nil
+}
+
+// canarySize reads size, a number of hosts or a percentage such as "10%"
+// of the group rounded up. The canary has at least one host.
+func canarySize(v lua.LValue, hosts int) (int, error) {
+	size := 1
+	switch v := v.(type) {
+	case *lua.LNilType:
+	case lua.LNumber:
+		if v < 1 {
+			return 0, fmt.Errorf("size must be at least 1")
+		}
+		size = int(v)
+	case lua.LString:
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(string(v), "%"), 64)
+		if !strings.HasSuffix(string(v), "%") || err != nil || percent <= 0 || percent > 100 {
+			return 0, fmt.Errorf("invalid size %q, expected a number of hosts or a percentage such as \"10%%\"", string(v))
+		}
+		size = int(math.Ceil(float64(hosts) * percent / 100))
+	default:
+		return 0, fmt.Errorf("size must be a number of hosts or a percentage")
+	}
+	if size > hosts {
+		size = hosts
+	}
+	return size, nil
+}
+
+// run deploys to the canary hosts, bakes them and then deploys to the rest,
+// or rolls the canary hosts back
+func (c *canarySpec) run(L *lua.LState) (*lua.LTable, error) {
+	start := time.Now()
+	c.emit("canary.started", nil)
+	slog.Info("canary started", "name", c.name, "canary", c.canary, "rest", len(c.rest))
+
+	if err := c.call(L, c.deploy, c.canary); err != nil {
+		return nil, c.fail(L, "deploy", fmt.Errorf("deploying to %s failed: %w", strings.Join(c.canary, ", "), err))
+	}
+	c.emit("canary.deployed", nil)
+
+	checks, err := c.bakeCanary(L)
+	if err != nil {
+		return nil, c.fail(L, "bake", err)
+	}
+	c.emit("canary.passed", map[string]interface{}{"checks": checks})
+	slog.Info("canary passed", "name", c.name, "checks", checks)
+
+	if len(c.rest) > 0 {
+		// The canary hosts are healthy, so a failure here leaves them
+		// on the new version
+		if err := c.call(L, c.deploy, c.rest); err != nil {
+			err = fmt.Errorf("canary %s passed, but deploying to the rest failed: %w", c.name, err)
+			c.emit("canary.failed", map[string]interface{}{"phase": "promote", "error": err.Error()})
+			return nil, err
+		}
+	}
+	c.emit("canary.promoted", nil)
+
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(c.name))
+	t.RawSetString("canary", stringListToLua(L, c.canary))
+	t.RawSetString("rest", stringListToLua(L, c.rest))
+	t.RawSetString("checks", lua.LNumber(checks))
+	t.RawSetString("promoted", lua.LTrue)
+	t.RawSetString("duration", lua.LNumber(time.Since(start).Seconds()))
+	return t, nil
+}
+
+// bakeCanary runs the check every interval until the bake time elapsed,
+// stopping at the first failed check
+func (c *canarySpec) bakeCanary(L *lua.LState) (int, error) {
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := time.Now()
+	deadline := start.Add(c.bake)
+
+	checks := 0
+	for {
+		checks++
+		if err := c.call(L, c.check, c.canary); err != nil {
+			return checks, fmt.Errorf("check %d failed after %s: %w", checks, time.Since(start).Round(time.Second), err)
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return checks, nil
+		}
+		if wait > c.interval {
+			wait = c.interval
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return checks, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// fail rolls the canary hosts back after a failed phase
+func (c *canarySpec) fail(L *lua.LState, phase string, err error) error {
+	c.emit("canary.failed", map[string]interface{}{"phase": phase, "error": err.Error()})
+	slog.Warn("canary failed", "name", c.name, "phase", phase, "error", err)
+
+	if c.rollback == nil {
+		return fmt.Errorf("canary %s failed: %w (no rollback given)", c.name, err)
+	}
+	if ctx := L.Context(); ctx != nil && ctx.Err() != nil {
+		return fmt.Errorf("canary %s failed: %w (not rolled back, the run was cancelled)", c.name, err)
+	}
+	if rollbackErr := c.call(L, c.rollback, c.canary); rollbackErr != nil {
+		c.emit("canary.failed", map[string]interface{}{"phase": "rollback", "error": rollbackErr.Error()})
+		return fmt.Errorf("canary %s failed: %w; rolling back %s failed: %v", c.name, err, strings.Join(c.canary, ", "), rollbackErr)
+	}
+	c.emit("canary.rolled_back", map[string]interface{}{"error": err.Error()})
+	return fmt.Errorf("canary %s failed and %s were rolled back: %w", c.name, strings.Join(c.canary, ", "), err)
+}
+
+// call runs a callback with a list of hosts. It fails when the callback
+// raises an error, returns false or returns nil and an error message.
+func (c *canarySpec) call(L *lua.LState, fn *lua.LFunction, hosts []string) error {
+	top := L.GetTop()
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 2, Protect: true}, stringListToLua(L, hosts)); err != nil {
+		return err
+	}
+	ok, msg := L.Get(top+1), L.Get(top+2)
+	L.SetTop(top)
+
+	switch {
+	case ok == lua.LFalse:
+		if msg != lua.LNil {
+			return fmt.Errorf("%s", msg.String())
+		}
+		return fmt.Errorf("returned false")
+	case ok == lua.LNil && msg != lua.LNil:
+		return fmt.Errorf("%s", msg.String())
+	}
+	return nil
+}
+
+func (c *canarySpec) emit(eventType string, data map[string]interface{}) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["name"] = c.name
+	data["canary"] = c.canary
+	data["rest"] = c.rest
+	if err := dispatchCanaryEvent(eventType, data); err != nil {
+		slog.Warn("failed to dispatch canary event", "event", eventType, "error", err)
+	}
+}
@@ -0,0 +1,114 @@
+package luainterface
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runCanary runs a canary with deploy, check and rollback callbacks that
+// record the hosts they were called with in the global calls
+func runCanary(t *testing.T, opts, check string) (lua.LValue, string, []string, []string) {
+	t.Helper()
+	var events []string
+	orig := dispatchCanaryEvent
+	dispatchCanaryEvent = func(eventType string, data map[string]interface{}) error {
+		events = append(events, eventType)
+		return nil
+	}
+	defer func() { dispatchCanaryEvent = orig }()
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterCanaryModule(L)
+	require.NoError(t, L.DoString(`
+		calls = {}
+		local function record(action)
+			return function(hosts)
+				table.insert(calls, action .. " " .. table.concat(hosts, ","))
+			end
+		end
+		result, err = canary{
+			name = "api",
+			hosts = {"web-01", "web-02", "web-03", "web-04"},
+			bake = 0.05,
+			interval = 0.01,
+			deploy = record("deploy"),
+			rollback = record("rollback"),
+			check = `+check+`,
+			`+opts+`
+		}
+	`))
+
+	var calls []string
+	L.GetGlobal("calls").(*lua.LTable).ForEach(func(_, v lua.LValue) {
+		calls = append(calls, v.String())
+	})
+	err, _ := L.GetGlobal("err").(lua.LString)
+	return L.GetGlobal("result"), string(err), calls, events
+}
+
+func TestCanary_Promotes(t *testing.T) {
+	result, errMsg, calls, events := runCanary(t, `size = "25%"`, `function(hosts) return {healthy = true} end`)
+	require.Empty(t, errMsg)
+
+	assert.Equal(t, []string{"deploy web-01", "deploy web-02,web-03,web-04"}, calls)
+	assert.Equal(t, []string{"canary.started", "canary.deployed", "canary.passed", "canary.promoted"}, events)
+
+	table := result.(*lua.LTable)
+	assert.Equal(t, lua.LTrue, table.RawGetString("promoted"))
+	assert.Equal(t, 3, table.RawGetString("rest").(*lua.LTable).Len())
+	assert.Greater(t, int(table.RawGetString("checks").(lua.LNumber)), 1, "checks should repeat during the bake")
+}
+
+func TestCanary_RollsBackFailedCheck(t *testing.T) {
+	result, errMsg, calls, events := runCanary(t, `size = 2`, `
+		function(hosts)
+			checks = (checks or 0) + 1
+			if checks == 3 then
+				return nil, "error rate 12%"
+			end
+			return true
+		end`)
+
+	assert.Equal(t, lua.LNil, result)
+	assert.Contains(t, errMsg, "web-01, web-02 were rolled back")
+	assert.Contains(t, errMsg, "check 3 failed")
+	assert.Contains(t, errMsg, "error rate 12%")
+	assert.Equal(t, []string{"deploy web-01,web-02", "rollback web-01,web-02"}, calls)
+	assert.Equal(t, []string{"canary.started", "canary.deployed", "canary.failed", "canary.rolled_back"}, events)
+}
+
+func TestCanary_RollsBackFailedDeploy(t *testing.T) {
+	_, errMsg, calls, _ := runCanary(t, `deploy = function(hosts) table.insert(calls, "deploy"); error("disk full") end`, `function() return true end`)
+
+	assert.Contains(t, errMsg, "deploying to web-01 failed")
+	assert.Contains(t, errMsg, "disk full")
+	assert.Equal(t, []string{"deploy", "rollback web-01"}, calls)
+}
+
+func TestCanarySize(t *testing.T) {
+	tests := []struct {
+		size lua.LValue
+		want int
+	}{
+		{lua.LNil, 1},
+		{lua.LNumber(3), 3},
+		{lua.LNumber(20), 10},
+		{lua.LString("10%"), 1},
+		{lua.LString("25%"), 3},
+		{lua.LString("100%"), 10},
+	}
+	for _, tt := range tests {
+		got, err := canarySize(tt.size, 10)
+		require.NoError(t, err, tt.size)
+		assert.Equal(t, tt.want, got, tt.size)
+	}
+
+	for _, invalid := range []lua.LValue{lua.LNumber(0), lua.LString("10"), lua.LString("0%"), lua.LString("150%"), lua.LTrue} {
+		_, err := canarySize(invalid, 10)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,452 @@
+package luainterface
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/certs"
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	coremodules "github.com/chalkan3-sloth/sloth-runner/internal/modules/core"
+	"github.com/chalkan3-sloth/sloth-runner/internal/modules/infra"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Defaults of the certs module, in days
+const (
+	defaultCertRenewBefore = 30
+	defaultCertAlertDays   = 14
+)
+
+// Replaced in tests
+var (
+	// obtainCertificate orders a certificate from the CA
+	obtainCertificate = certs.Obtain
+	// dispatchCertEvent emits the cert.* events
+	dispatchCertEvent = coremodules.DispatchEvent
+	// reloadCertService reloads a service that serves a certificate
+	reloadCertService = func(unit string) error {
+		if output, err := exec.Command("systemctl", "reload", unit).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to reload %s: %v: %s", unit, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+)
+
+// RegisterCertsModule registers the certs module, which obtains and renews
+// certificates from ACME CAs such as Let's Encrypt and installs them:
+//
+//	certs.ensure({
+//	    domains = {"example.com", "www.example.com"},
+//	    email = "ops@example.com",
+//	    webroot = "/var/www/html",
+//	    cert_path = "/etc/nginx/tls/example.crt",
+//	    key_path = "/etc/nginx/tls/example.key",
+//	    reload = {"nginx"},
+//	})
+//
+// Certificates close to expiry emit cert.expiring, renewed ones
+// cert.renewed.
+func RegisterCertsModule(L *lua.LState) {
+	certsTable := L.NewTable()
+	L.SetField(certsTable, "ensure", L.NewFunction(luaCertsEnsure))
+	L.SetField(certsTable, "obtain", L.NewFunction(luaCertsObtain))
+	L.SetField(certsTable, "install", L.NewFunction(luaCertsInstall))
+	L.SetField(certsTable, "check", L.NewFunction(luaCertsCheck))
+	L.SetGlobal("certs", certsTable)
+}
+
+// certRequest is a certificate request read from the options of a call,
+// and where to find the key of its ACME account
+type certRequest struct {
+	certs.Request
+	accountKeyPath string
+}
+
+// newCertRequest reads the request options of opts. The challenge is
+// dns-01 when a dns provider is given, http-01 otherwise.
+func newCertRequest(opts *lua.LTable) (*certRequest, error) {
+	req := &certRequest{Request: certs.Request{
+		Domains:   tableStrings(opts.RawGetString("domains")),
+		Email:     getTableString(opts, "email", ""),
+		Directory: getTableString(opts, "directory", certs.LetsEncrypt),
+		KeyType:   getTableString(opts, "key_type", ""),
+	}}
+	if domain := getTableString(opts, "domain", ""); domain != "" {
+		req.Domains = append([]string{domain}, req.Domains...)
+	}
+	if len(req.Domains) == 0 {
+		return nil, fmt.Errorf("domains is required")
+	}
+	if lua.LVAsBool(opts.RawGetString("staging")) {
+		req.Directory = certs.LetsEncryptStaging
+	}
+	if _, err := certs.NewKey(req.KeyType); err != nil {
+		return nil, err
+	}
+
+	req.accountKeyPath = getTableString(opts, "account_key", "")
+	if req.accountKeyPath == "" {
+		u, err := url.Parse(req.Directory)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid directory %q", req.Directory)
+		}
+		// One account per CA
+		req.accountKeyPath = filepath.Join(config.GetACMEDir(), strings.ReplaceAll(u.Host, ":", "_")+".key")
+	}
+
+	dnsOpts, _ := opts.RawGetString("dns").(*lua.LTable)
+	challenge := getTableString(opts, "challenge", "http-01")
+	if dnsOpts != nil {
+		challenge = getTableString(opts, "challenge", "dns-01")
+	}
+	switch challenge {
+	case "http-01":
+		req.Solver = &certs.HTTPSolver{Webroot: getTableString(opts, "webroot", ""), Listen: getTableString(opts, "listen", "")}
+	case "dns-01":
+		if dnsOpts == nil {
+			return nil, fmt.Errorf("dns-01 needs a dns table naming the provider, e.g. dns = {provider = \"cloudflare\", zone = \"example.com\"}")
+		}
+		solver, err := newCertDNSSolver(dnsOpts)
+		if err != nil {
+			return nil, err
+		}
+		req.Solver = solver
+	default:
+		return nil, fmt.Errorf("unknown challenge %q: expected http-01 or dns-01", challenge)
+	}
+	for _, domain := range req.Domains {
+		if strings.HasPrefix(domain, "*.") && challenge != "dns-01" {
+			return nil, fmt.Errorf("%s: wildcard certificates need the dns-01 challenge", domain)
+		}
+	}
+	return req, nil
+}
+
+// newCertDNSSolver answers dns-01 challenges with TXT records set through
+// a provider of the dns module, configured as for dns.record
+func newCertDNSSolver(dnsOpts *lua.LTable) (*certs.DNSSolver, error) {
+	provider, name, err := infra.DNSProviderFromTable(dnsOpts)
+	if err != nil {
+		return nil, err
+	}
+	if name == "hosts" {
+		return nil, fmt.Errorf("dns-01 needs a DNS provider the CA can query: route53 or cloudflare")
+	}
+	propagation, err := optDuration(dnsOpts, "propagation", 0)
+	if err != nil {
+		return nil, err
+	}
+	zone := getTableString(dnsOpts, "zone", "")
+	return &certs.DNSSolver{
+		Propagation: propagation,
+		SetTXT: func(ctx context.Context, fqdn, value string, present bool) error {
+			record := infra.DNSRecord{Zone: zone, Name: fqdn, Type: "TXT", Values: []string{value}, TTL: 60, State: "present"}
+			if !present {
+				record.State = "absent"
+			}
+			if _, err := provider.Apply(record, false); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// obtain orders the certificate, registering the ACME account on first
+// use
+func (r *certRequest) obtain(ctx context.Context) (*certs.Certificate, error) {
+	key, err := certs.LoadAccountKey(r.accountKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	req := r.Request
+	req.AccountKey = key
+	cert, err := obtainCertificate(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain a certificate for %s: %w", strings.Join(r.Domains, ", "), err)
+	}
+	return cert, nil
+}
+
+// certInstallOptions reads where and how a call installs the certificate
+func certInstallOptions(L *lua.LState, opts *lua.LTable) (certs.InstallOptions, error) {
+	install := certs.InstallOptions{
+		CertPath: getTableString(opts, "cert_path", ""),
+		KeyPath:  getTableString(opts, "key_path", ""),
+		Owner:    getTableString(opts, "owner", ""),
+		Group:    getTableString(opts, "group", ""),
+		DryRun:   taskctx.CheckMode(L.Context()),
+	}
+	if install.CertPath == "" {
+		return install, fmt.Errorf("cert_path is required")
+	}
+	var err error
+	if install.CertMode, err = optFileMode(L, opts, "cert_mode", 0644); err != nil {
+		return install, err
+	}
+	if install.KeyMode, err = optFileMode(L, opts, "key_mode", 0600); err != nil {
+		return install, err
+	}
+	return install, nil
+}
+
+// installCert installs cert where opts say, over current, the certificate
+// installed before when there's one, and reloads the services of the
+// reload option when the certificate itself changed: fixing the owner or
+// mode of the files reloads nothing.
+func installCert(L *lua.LState, opts *lua.LTable, install certs.InstallOptions, cert, current *certs.Certificate) (*lua.LTable, error) {
+	changed, err := certs.Install(cert, install)
+	for _, path := range changed {
+		change := taskctx.Change{Module: "certs", Action: "install", Target: path}
+		if install.DryRun {
+			taskctx.RecordChange(L.Context(), change)
+		} else {
+			taskctx.ReportChange(L.Context(), change)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reloaded := L.NewTable()
+	replaced := current == nil || !bytes.Equal(current.Leaf.Raw, cert.Leaf.Raw) || !bytes.Equal(current.KeyPEM, cert.KeyPEM)
+	if replaced && len(changed) > 0 {
+		for _, unit := range tableStrings(opts.RawGetString("reload")) {
+			change := taskctx.Change{Module: "certs", Action: "reload", Target: unit}
+			if taskctx.RecordChange(L.Context(), change) {
+				continue
+			}
+			if err := reloadCertService(unit); err != nil {
+				return nil, err
+			}
+			taskctx.ReportChange(L.Context(), change)
+			reloaded.Append(lua.LString(unit))
+		}
+	}
+
+	paths := L.NewTable()
+	for _, path := range changed {
+		paths.Append(lua.LString(path))
+	}
+	result := L.NewTable()
+	L.SetField(result, "changed", lua.LBool(len(changed) > 0))
+	L.SetField(result, "changed_paths", paths)
+	L.SetField(result, "reloaded", reloaded)
+	L.SetField(result, "cert_path", lua.LString(install.CertPath))
+	if install.KeyPath != "" {
+		L.SetField(result, "key_path", lua.LString(install.KeyPath))
+	}
+	if install.DryRun {
+		L.SetField(result, "check_mode", lua.LTrue)
+	}
+	return result, nil
+}
+
+// checkCertExpiry emits cert.expiring when leaf has alertDays or fewer
+// days left. target names the certificate in the event: a path or an
+// address.
+func checkCertExpiry(L *lua.LState, leaf *certs.Certificate, target string, alertDays int, now time.Time) bool {
+	days := leaf.DaysLeft(now)
+	if days > alertDays {
+		return false
+	}
+	data := map[string]interface{}{
+		"target":    target,
+		"domains":   leaf.Domains(),
+		"days_left": days,
+		"not_after": leaf.Leaf.NotAfter.Format(time.RFC3339),
+		"agent":     taskctx.Agent(L.Context()),
+	}
+	if err := dispatchCertEvent("cert.expiring", data); err != nil {
+		slog.Warn("failed to dispatch certificate event", "event", "cert.expiring", "error", err)
+	}
+	return true
+}
+
+// setCertFields sets the fields describing cert in result
+func setCertFields(L *lua.LState, result *lua.LTable, cert *certs.Certificate, now time.Time) {
+	domains := L.NewTable()
+	for _, domain := range cert.Domains() {
+		domains.Append(lua.LString(domain))
+	}
+	L.SetField(result, "domains", domains)
+	L.SetField(result, "issuer", lua.LString(cert.Leaf.Issuer.CommonName))
+	L.SetField(result, "not_after", lua.LString(cert.Leaf.NotAfter.Format(time.RFC3339)))
+	L.SetField(result, "days_left", lua.LNumber(cert.DaysLeft(now)))
+}
+
+// pushCertsError pushes nil and err, the result of a failed call
+func pushCertsError(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// luaCertsEnsure keeps a certificate for the domains installed, obtaining
+// it when it's missing, lacks a domain or has renew_before days or fewer
+// left. When renewing fails, the installed certificate is checked against
+// alert_days.
+func luaCertsEnsure(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	req, err := newCertRequest(opts)
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+	install, err := certInstallOptions(L, opts)
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+	renewBefore := getTableInt(opts, "renew_before", defaultCertRenewBefore)
+	alertDays := getTableInt(opts, "alert_days", defaultCertAlertDays)
+	now := time.Now()
+
+	current, _ := certs.Load(install.CertPath, install.KeyPath)
+	cert, reason := current, ""
+	switch {
+	case current == nil || len(current.KeyPEM) == 0:
+		reason = "absent"
+	case !current.Covers(req.Domains):
+		reason = "missing domains"
+	case current.DaysLeft(now) <= renewBefore:
+		reason = fmt.Sprintf("%d days left", current.DaysLeft(now))
+	}
+
+	renewed := false
+	if reason != "" {
+		change := taskctx.Change{Module: "certs", Action: "obtain", Target: install.CertPath, Current: reason}
+		if taskctx.RecordChange(L.Context(), change) {
+			result := L.NewTable()
+			L.SetField(result, "changed", lua.LTrue)
+			L.SetField(result, "check_mode", lua.LTrue)
+			L.SetField(result, "message", lua.LString(fmt.Sprintf("Would obtain a certificate for %s (%s)", strings.Join(req.Domains, ", "), reason)))
+			L.Push(result)
+			L.Push(lua.LNil)
+			return 2
+		}
+		if cert, err = req.obtain(callContext(L)); err != nil {
+			if current != nil && len(current.KeyPEM) > 0 {
+				checkCertExpiry(L, current, install.CertPath, alertDays, now)
+			}
+			return pushCertsError(L, err)
+		}
+		renewed = true
+	}
+
+	result, err := installCert(L, opts, install, cert, current)
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+	if renewed {
+		data := map[string]interface{}{
+			"target":    install.CertPath,
+			"domains":   cert.Domains(),
+			"not_after": cert.Leaf.NotAfter.Format(time.RFC3339),
+			"agent":     taskctx.Agent(L.Context()),
+		}
+		if err := dispatchCertEvent("cert.renewed", data); err != nil {
+			slog.Warn("failed to dispatch certificate event", "event", "cert.renewed", "error", err)
+		}
+	}
+	L.SetField(result, "renewed", lua.LBool(renewed))
+	L.SetField(result, "expiring", lua.LBool(checkCertExpiry(L, cert, install.CertPath, alertDays, now)))
+	setCertFields(L, result, cert, now)
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaCertsObtain obtains a certificate without installing it, returning
+// its chain and key as PEM: certs.obtain({domains = {...}, dns = {...}})
+func luaCertsObtain(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	req, err := newCertRequest(opts)
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+	cert, err := req.obtain(callContext(L))
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+
+	result := L.NewTable()
+	L.SetField(result, "cert", lua.LString(cert.CertPEM))
+	L.SetField(result, "key", lua.LString(cert.KeyPEM))
+	setCertFields(L, result, cert, time.Now())
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaCertsInstall installs a certificate given as PEM, e.g. one obtained
+// on another host: certs.install({cert = pem, key = pem, cert_path = ...})
+func luaCertsInstall(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	install, err := certInstallOptions(L, opts)
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+	cert, err := certs.Parse([]byte(getTableString(opts, "cert", "")), []byte(getTableString(opts, "key", "")))
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+	now := time.Now()
+
+	current, _ := certs.Load(install.CertPath, install.KeyPath)
+	result, err := installCert(L, opts, install, cert, current)
+	if err != nil {
+		return pushCertsError(L, err)
+	}
+	L.SetField(result, "expiring", lua.LBool(checkCertExpiry(L, cert, install.CertPath, getTableInt(opts, "alert_days", defaultCertAlertDays), now)))
+	setCertFields(L, result, cert, now)
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaCertsCheck reports the days a certificate file, or the certificate a
+// TLS server serves, has left, emitting cert.expiring within alert_days:
+// certs.check({path = "/etc/nginx/tls/example.crt"}) or
+// certs.check({host = "example.com:443", alert_days = 21})
+func luaCertsCheck(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	path, host := getTableString(opts, "path", ""), getTableString(opts, "host", "")
+
+	var cert *certs.Certificate
+	target := path
+	switch {
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return pushCertsError(L, err)
+		}
+		if cert, err = certs.Parse(data, nil); err != nil {
+			return pushCertsError(L, fmt.Errorf("%s: %w", path, err))
+		}
+	case host != "":
+		leaf, err := certs.Fetch(callContext(L), host)
+		if err != nil {
+			return pushCertsError(L, fmt.Errorf("failed to fetch the certificate of %s: %w", host, err))
+		}
+		cert, target = &certs.Certificate{Leaf: leaf}, host
+	default:
+		return pushCertsError(L, fmt.Errorf("path or host is required"))
+	}
+	now := time.Now()
+
+	result := L.NewTable()
+	L.SetField(result, "expiring", lua.LBool(checkCertExpiry(L, cert, target, getTableInt(opts, "alert_days", defaultCertAlertDays), now)))
+	L.SetField(result, "expired", lua.LBool(now.After(cert.Leaf.NotAfter)))
+	setCertFields(L, result, cert, now)
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
@@ -0,0 +1,192 @@
+package luainterface
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/certs"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// selfSignedCert returns a certificate for domains valid for the given
+// days
+func selfSignedCert(t *testing.T, domains []string, days int) *certs.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domains[0]},
+		DNSNames:     domains,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Duration(days)*24*time.Hour + time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	cert, err := certs.Parse(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// fakeCertsModule replaces the CA, the service reloads and the event
+// dispatcher of the certs module. Obtained certificates are valid for
+// *days.
+func fakeCertsModule(t *testing.T, days *int) (obtained, reloads, events *[]string) {
+	obtained, reloads, events = &[]string{}, &[]string{}, &[]string{}
+	origObtain, origReload, origDispatch := obtainCertificate, reloadCertService, dispatchCertEvent
+	obtainCertificate = func(ctx context.Context, req certs.Request) (*certs.Certificate, error) {
+		*obtained = append(*obtained, fmt.Sprint(req.Domains))
+		return selfSignedCert(t, req.Domains, *days), nil
+	}
+	reloadCertService = func(unit string) error {
+		*reloads = append(*reloads, unit)
+		return nil
+	}
+	dispatchCertEvent = func(eventType string, data map[string]interface{}) error {
+		*events = append(*events, fmt.Sprintf("%s %v", eventType, data["days_left"]))
+		return nil
+	}
+	t.Cleanup(func() { obtainCertificate, reloadCertService, dispatchCertEvent = origObtain, origReload, origDispatch })
+	return obtained, reloads, events
+}
+
+func TestCertsEnsure(t *testing.T) {
+	days := 90
+	obtained, reloads, events := fakeCertsModule(t, &days)
+	dir := t.TempDir()
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterCertsModule(L)
+	L.SetGlobal("dir", lua.LString(dir))
+	run := func() *lua.LTable {
+		t.Helper()
+		if err := L.DoString(`
+			result, err = certs.ensure({
+			    domains = {"example.com", "www.example.com"},
+			    account_key = dir .. "/account.key",
+			    webroot = dir .. "/www",
+			    cert_path = dir .. "/tls/example.crt",
+			    key_path = dir .. "/tls/example.key",
+			    reload = {"nginx"},
+			})
+			assert(err == nil, err)
+		`); err != nil {
+			t.Fatal(err)
+		}
+		return L.GetGlobal("result").(*lua.LTable)
+	}
+
+	first := run()
+	if first.RawGetString("renewed") != lua.LTrue || first.RawGetString("changed") != lua.LTrue {
+		t.Error("Expected the first run to obtain and install the certificate")
+	}
+	if info, err := os.Stat(filepath.Join(dir, "tls", "example.key")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private key file, got %v", err)
+	}
+
+	second := run()
+	if second.RawGetString("renewed") != lua.LFalse || second.RawGetString("changed") != lua.LFalse {
+		t.Error("Expected a valid certificate to be kept")
+	}
+	os.Chmod(filepath.Join(dir, "tls", "example.crt"), 0666)
+	if fixed := run(); fixed.RawGetString("changed") != lua.LTrue || fixed.RawGetString("reloaded").(*lua.LTable).Len() != 0 {
+		t.Error("Expected fixing the mode to reload nothing")
+	}
+
+	// A certificate within renew_before is renewed, and the new one is
+	// close enough to expiry to alert
+	days = 10
+	if err := L.DoString(`certs.ensure({domains = {"example.com", "www.example.com"}, account_key = dir .. "/account.key",
+		webroot = dir .. "/www", cert_path = dir .. "/tls/example.crt", key_path = dir .. "/tls/example.key", renew_before = 95})`); err != nil {
+		t.Fatal(err)
+	}
+	if len(*obtained) != 2 {
+		t.Errorf("Expected two orders, got %v", *obtained)
+	}
+	if fmt.Sprint(*reloads) != "[nginx]" {
+		t.Errorf("Expected one reload, for the first certificate, got %v", *reloads)
+	}
+	if want := "[cert.renewed <nil> cert.renewed <nil> cert.expiring 10]"; fmt.Sprint(*events) != want {
+		t.Errorf("Expected events %s, got %v", want, *events)
+	}
+}
+
+func TestCertsEnsure_CheckMode(t *testing.T) {
+	days := 90
+	obtained, _, _ := fakeCertsModule(t, &days)
+	dir := t.TempDir()
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterCertsModule(L)
+	plan := &taskctx.Plan{}
+	L.SetContext(taskctx.WithPlan(context.Background(), plan))
+	L.SetGlobal("dir", lua.LString(dir))
+	if err := L.DoString(`result = certs.ensure({domain = "example.com", account_key = dir .. "/account.key", cert_path = dir .. "/example.pem"})`); err != nil {
+		t.Fatal(err)
+	}
+	if len(*obtained) != 0 {
+		t.Error("Expected check mode to order nothing")
+	}
+	want := []taskctx.Change{{Module: "certs", Action: "obtain", Target: filepath.Join(dir, "example.pem"), Current: "absent"}}
+	if got := plan.Changes(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected plan %v, got %v", want, got)
+	}
+}
+
+func TestCertsInstallAndCheck(t *testing.T) {
+	days := 0
+	_, reloads, events := fakeCertsModule(t, &days)
+	dir := t.TempDir()
+	cert := selfSignedCert(t, []string{"db.internal"}, 5)
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterCertsModule(L)
+	L.SetGlobal("dir", lua.LString(dir))
+	L.SetGlobal("cert", lua.LString(cert.CertPEM))
+	L.SetGlobal("key", lua.LString(cert.KeyPEM))
+	if err := L.DoString(`
+		installed, err = certs.install({cert = cert, key = key, cert_path = dir .. "/db.pem", reload = {"postgresql"}, alert_days = 1})
+		assert(err == nil, err)
+		again = certs.install({cert = cert, key = key, cert_path = dir .. "/db.pem", reload = {"postgresql"}, alert_days = 1})
+		checked, err = certs.check({path = dir .. "/db.pem", alert_days = 7})
+		assert(err == nil, err)
+		_, mismatch = certs.install({cert = cert, key = "", cert_path = dir .. "/other.pem"})
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	if L.GetGlobal("installed").(*lua.LTable).RawGetString("changed") != lua.LTrue || L.GetGlobal("again").(*lua.LTable).RawGetString("changed") != lua.LFalse {
+		t.Error("Expected the certificate to be installed once")
+	}
+	if fmt.Sprint(*reloads) != "[postgresql]" {
+		t.Errorf("Expected one reload, got %v", *reloads)
+	}
+	checked := L.GetGlobal("checked").(*lua.LTable)
+	if checked.RawGetString("expiring") != lua.LTrue || checked.RawGetString("days_left") != lua.LNumber(5) {
+		t.Errorf("Expected the certificate to expire in 5 days, got %v", checked.RawGetString("days_left"))
+	}
+	if fmt.Sprint(*events) != "[cert.expiring 5]" {
+		t.Errorf("Expected one expiry alert, got %v", *events)
+	}
+	if L.GetGlobal("mismatch") == lua.LNil {
+		t.Error("Expected installing without a key to fail")
+	}
+}
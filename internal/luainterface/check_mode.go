@@ -0,0 +1,209 @@
+package luainterface
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// checkModeSafeModules only read or compute, so their calls run as usual
+// in check mode
+var checkModeSafeModules = map[string]bool{
+	"agent": true, "async": true, "circuit": true, "core": true, "crypto": true,
+	"data": true, "facts": true, "goroutine": true, "infra_test": true, "lock": true,
+	"log": true, "metrics": true, "multiarch": true, "network": true, "observability": true,
+	"perf": true, "probe": true, "progress": true, "reliability": true, "strings": true,
+	"task": true, "template": true, "time": true, "utils": true, "validate": true,
+	"workdir": true, "workflow": true,
+}
+
+// checkModeAware lists the functions that record what they would change
+// in the plan themselves. "*" stands for every function of the module.
+var checkModeAware = map[string][]string{
+	"certs":    {"check", "ensure", "install"},
+	"config":   {"edit_ini", "edit_json", "edit_toml", "edit_yaml"},
+	"exec":     {"run"},
+	"file_ops": {"blockinfile", "copy", "lineinfile", "replace", "template"},
+	"fs":       {"append", "copy", "mkdir", "rm", "rmr", "write"},
+	"incus":    {"file_push", "launch", "snapshot"},
+	"nixos":    {"*"},
+	"pkg":      {"install", "remove"},
+	"s3":       {"configure", "delete", "presign", "put", "sync_dir"},
+	"ssh":      {"authorized_keys"},
+	"systemd":  {"create_service", "daemon_reload", "disable", "enable", "reload", "remove_service", "restart", "start", "stop"},
+	"tofu":     {"*"},
+}
+
+// checkModeStdlib lists the functions of the Lua standard library that
+// change the system. The rest of it runs as usual.
+var checkModeStdlib = map[string][]string{
+	"io": {"popen"},
+	"os": {"execute", "remove", "rename"},
+}
+
+// checkModeFileWriters are the io functions that write the file named by
+// their first argument, depending on the other arguments: io.open with a
+// write, append or update mode, and io.output with a path. They return the
+// path when the call would write it.
+var checkModeFileWriters = map[string]func(L *lua.LState) (string, bool){
+	"open": func(L *lua.LState) (string, bool) {
+		path, ok := L.Get(1).(lua.LString)
+		return string(path), ok && strings.ContainsAny(L.OptString(2, "r"), "wa+")
+	},
+	"output": func(L *lua.LState) (string, bool) {
+		path, ok := L.Get(1).(lua.LString)
+		return string(path), ok
+	},
+}
+
+// checkModeReadOnlyNames are functions that only read, whatever their
+// module
+var checkModeReadOnlyNames = map[string]bool{
+	"basename": true, "deps": true, "describe": true, "detail": true, "detect": true,
+	"dirname": true, "events": true, "exists": true, "get": true, "history": true,
+	"info": true, "keys": true, "lint": true, "list": true, "loaded": true, "logs": true,
+	"ls": true, "read": true, "search": true, "show": true, "size": true,
+	"stat": true, "stats": true, "status": true, "tmpname": true, "top": true, "version": true,
+	"which": true,
+}
+
+// checkModeReadOnly reports whether function only reads, by its name
+func checkModeReadOnly(function string) bool {
+	return checkModeReadOnlyNames[function] ||
+		strings.HasPrefix(function, "get_") || strings.HasPrefix(function, "list_") ||
+		strings.HasPrefix(function, "is_") || strings.HasPrefix(function, "validate") ||
+		strings.HasSuffix(function, "_exists") || strings.HasSuffix(function, "_info") ||
+		strings.HasSuffix(function, "_list") || strings.HasSuffix(function, "_status")
+}
+
+// OpenCheckMode wraps the module functions that may change the system
+// and don't support check mode themselves. When the calling task runs in
+// check mode (run --dry-run) such calls are skipped and recorded in the
+// plan as changes it can't tell; otherwise they run as usual.
+func OpenCheckMode(L *lua.LState) {
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		module := key.String()
+		table, ok := value.(*lua.LTable)
+		if !ok || checkModeSafeModules[module] {
+			return
+		}
+
+		var names []string
+		if luaStdlibTables[module] {
+			names = checkModeStdlib[module]
+		} else {
+			aware := make(map[string]bool)
+			for _, name := range checkModeAware[module] {
+				aware[name] = true
+			}
+			if aware["*"] {
+				return
+			}
+			table.ForEach(func(k, v lua.LValue) {
+				name := k.String()
+				if _, ok := v.(*lua.LFunction); ok && !aware[name] && !checkModeReadOnly(name) {
+					names = append(names, name)
+				}
+			})
+		}
+		for _, name := range names {
+			if fn, ok := table.RawGetString(name).(*lua.LFunction); ok {
+				table.RawSetString(name, L.NewFunction(checkModeWrapper(module, name, fn)))
+			}
+		}
+		if module == "io" {
+			for name, writes := range checkModeFileWriters {
+				if fn, ok := table.RawGetString(name).(*lua.LFunction); ok {
+					table.RawSetString(name, L.NewFunction(checkModeFileWrapper(name, fn, writes)))
+				}
+			}
+		}
+	})
+}
+
+// checkModeFileWrapper records the file an io call would write as a change
+// in check mode, and opens the null device in its place, so the script
+// goes on writing without touching the file
+func checkModeFileWrapper(function string, fn *lua.LFunction, writes func(L *lua.LState) (string, bool)) lua.LGFunction {
+	return func(L *lua.LState) int {
+		top := L.GetTop()
+		path, ok := writes(L)
+		redirect := ok && taskctx.RecordChange(L.Context(), taskctx.Change{Module: "io", Action: function, Target: path, Unknown: true})
+		L.Push(fn)
+		for i := 1; i <= top; i++ {
+			L.Push(L.Get(i))
+		}
+		if redirect {
+			L.Replace(top+2, lua.LString(os.DevNull))
+		}
+		L.Call(top, lua.MultRet)
+		return L.GetTop() - top
+	}
+}
+
+func checkModeWrapper(module, function string, fn *lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		top := L.GetTop()
+		change := taskctx.Change{Module: module, Action: function, Target: checkModeTarget(L), Unknown: true}
+		if taskctx.RecordChange(L.Context(), change) {
+			result := L.NewTable()
+			result.RawSetString("changed", lua.LFalse)
+			result.RawSetString("skipped", lua.LTrue)
+			result.RawSetString("success", lua.LTrue)
+			result.RawSetString("check_mode", lua.LTrue)
+			result.RawSetString("message", lua.LString(fmt.Sprintf("%s.%s is skipped in check mode", module, function)))
+			L.Push(result)
+			L.Push(lua.LNil)
+			return 2
+		}
+
+		L.Push(fn)
+		for i := 1; i <= top; i++ {
+			L.Push(L.Get(i))
+		}
+		L.Call(top, lua.MultRet)
+		return L.GetTop() - top
+	}
+}
+
+// checkModeTarget names what a skipped call would act on: its first
+// string argument or the usual option of its first table argument
+func checkModeTarget(L *lua.LState) string {
+	switch arg := L.Get(1).(type) {
+	case lua.LString:
+		return string(arg)
+	case *lua.LTable:
+		for _, field := range []string{"name", "path", "dest", "command", "cmd", "packages", "image", "url", "src"} {
+			switch v := arg.RawGetString(field).(type) {
+			case lua.LString:
+				return string(v)
+			case *lua.LTable:
+				var items []string
+				for i := 1; i <= v.Len(); i++ {
+					items = append(items, v.RawGetInt(i).String())
+				}
+				return strings.Join(items, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+// pushPlanned pushes the result of a call whose change was recorded in
+// the plan instead of made: true and a table with changed, check_mode,
+// message and fields
+func pushPlanned(L *lua.LState, message string, fields map[string]lua.LValue) int {
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LTrue)
+	result.RawSetString("check_mode", lua.LTrue)
+	result.RawSetString("message", lua.LString(message))
+	for k, v := range fields {
+		result.RawSetString(k, v)
+	}
+	L.Push(lua.LTrue)
+	L.Push(result)
+	return 2
+}
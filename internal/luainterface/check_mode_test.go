@@ -0,0 +1,182 @@
+package luainterface
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func newCheckModeState(t *testing.T) (*lua.LState, *taskctx.Plan, *int) {
+	t.Helper()
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+
+	calls := 0
+	fake := L.NewTable()
+	L.SetField(fake, "apply", L.NewFunction(func(L *lua.LState) int {
+		calls++
+		L.Push(lua.LString("applied"))
+		return 1
+	}))
+	L.SetField(fake, "get_state", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString("state"))
+		return 1
+	}))
+	L.SetGlobal("fake", fake)
+	OpenCheckMode(L)
+
+	plan := &taskctx.Plan{}
+	L.SetContext(taskctx.WithPlan(context.Background(), plan))
+	return L, plan, &calls
+}
+
+func TestOpenCheckMode_SkipsCallsThatMayChangeTheSystem(t *testing.T) {
+	L, plan, calls := newCheckModeState(t)
+
+	if err := L.DoString(`result = fake.apply({name = "nginx"}); state = fake.get_state()`); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 0 {
+		t.Errorf("Expected fake.apply to be skipped, it ran %d time(s)", *calls)
+	}
+	result := L.GetGlobal("result").(*lua.LTable)
+	if result.RawGetString("skipped") != lua.LTrue || result.RawGetString("check_mode") != lua.LTrue {
+		t.Errorf("Expected a skipped check mode result, got skipped=%v check_mode=%v",
+			result.RawGetString("skipped"), result.RawGetString("check_mode"))
+	}
+	if state := L.GetGlobal("state").String(); state != "state" {
+		t.Errorf("Expected read-only calls to run, got %q", state)
+	}
+
+	changes := plan.Changes()
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %+v", changes)
+	}
+	want := taskctx.Change{Module: "fake", Action: "apply", Target: "nginx", Unknown: true}
+	if changes[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, changes[0])
+	}
+}
+
+func TestOpenCheckMode_KeepsFilesFromIOWrites(t *testing.T) {
+	L, plan, _ := newCheckModeState(t)
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing")
+	if err := os.WriteFile(existing, []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	L.SetGlobal("dir", lua.LString(dir))
+
+	err := L.DoString(`
+		local f = assert(io.open(dir .. "/written", "w"))
+		f:write("data")
+		f:close()
+		f = assert(io.open(dir .. "/existing", "a+"))
+		f:write("more")
+		f:close()
+		io.output(dir .. "/output")
+		io.write("data")
+		io.output(io.stdout)
+		f = assert(io.open(dir .. "/existing"))
+		content = f:read("*a")
+		f:close()
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "written")); !os.IsNotExist(err) {
+		t.Errorf("Expected io.open with mode w not to create the file, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "output")); !os.IsNotExist(err) {
+		t.Errorf("Expected io.output not to create the file, got %v", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "kept" {
+		t.Errorf("Expected the existing file untouched, got %q", data)
+	}
+	if content := L.GetGlobal("content").String(); content != "kept" {
+		t.Errorf("Expected reads to run, got %q", content)
+	}
+
+	var targets []string
+	for _, c := range plan.Changes() {
+		targets = append(targets, c.Module+"."+c.Action+" "+filepath.Base(c.Target))
+	}
+	want := []string{"io.open written", "io.open existing", "io.output output"}
+	if strings.Join(targets, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Expected changes %v, got %v", want, targets)
+	}
+}
+
+func TestOpenCheckMode_RunsCallsOutsideCheckMode(t *testing.T) {
+	L, _, calls := newCheckModeState(t)
+	L.SetContext(context.Background())
+
+	if err := L.DoString(`result = fake.apply("x")`); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 1 || L.GetGlobal("result").String() != "applied" {
+		t.Errorf("Expected fake.apply to run, calls=%d result=%v", *calls, L.GetGlobal("result"))
+	}
+}
+
+func TestCheckMode_LineinfilePlansDiff(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("file_ops", NewFileOpsModule().Loader)
+	plan := &taskctx.Plan{}
+	L.SetContext(taskctx.WithPlan(context.Background(), plan))
+
+	path := filepath.Join(t.TempDir(), "sshd_config")
+	if err := os.WriteFile(path, []byte("Port 22\nPermitRootLogin yes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := L.DoString(`
+		local file_ops = require('file_ops')
+		ok, result = file_ops.lineinfile({path = "` + path + `", regexp = "^PermitRootLogin", line = "PermitRootLogin no"})
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "Port 22\nPermitRootLogin yes\n" {
+		t.Errorf("Expected the file to be left alone, got %q", content)
+	}
+	if changed := L.GetGlobal("result").(*lua.LTable).RawGetString("changed"); changed != lua.LTrue {
+		t.Errorf("Expected changed = true, got %v", changed)
+	}
+
+	changes := plan.Changes()
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %+v", changes)
+	}
+	if changes[0].Unknown || changes[0].Target != path {
+		t.Errorf("Expected a known change of %s, got %+v", path, changes[0])
+	}
+	for _, line := range []string{"-PermitRootLogin yes", "+PermitRootLogin no"} {
+		if !strings.Contains(changes[0].Diff, line) {
+			t.Errorf("Expected diff to contain %q, got:\n%s", line, changes[0].Diff)
+		}
+	}
+}
+
+func TestFileDiff(t *testing.T) {
+	if diff := taskctx.FileDiff("a", []byte("x\n"), []byte("x\n")); diff != "" {
+		t.Errorf("Expected no diff for equal content, got %q", diff)
+	}
+	if diff := taskctx.FileDiff("a", nil, []byte("x\n")); !strings.Contains(diff, "+x") {
+		t.Errorf("Expected new file diff, got %q", diff)
+	}
+	if diff := taskctx.FileDiff("a", []byte("x\n"), []byte("x")); !strings.Contains(diff, "No newline at end of file") {
+		t.Errorf("Expected missing newline note, got %q", diff)
+	}
+	if diff := taskctx.FileDiff("a", []byte{0, 1}, []byte{0, 2}); !strings.HasPrefix(diff, "Binary") {
+		t.Errorf("Expected binary file note, got %q", diff)
+	}
+}
@@ -0,0 +1,125 @@
+package luainterface
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Modules read the options of their calls with the helpers below, so that
+// a value means the same in every module:
+//
+//   - Booleans are true or false. The strings "true", "false", "yes" and
+//     "no" and the numbers 1 and 0 are coerced.
+//   - Integers are whole numbers. Decimal strings like "30" are coerced.
+//   - File modes are octal strings like "0644", "644" or "0o644". Lua has
+//     no octal literals, so mode = 0644 is the number 644: numbers made of
+//     octal digits are read as octal too, while numbers with an 8 or a 9,
+//     like 420, are rejected.
+//
+// Workflows with strict_types = true coerce nothing: only booleans, whole
+// numbers and mode strings are accepted, so a mode can't be mistaken for
+// another one.
+
+// optBool returns the boolean option key of opts, def when it isn't set
+func optBool(L *lua.LState, opts *lua.LTable, key string, def bool) (bool, error) {
+	v := optValue(opts, key)
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return def, nil
+	case lua.LBool:
+		return bool(v), nil
+	}
+	if !strictTypes(L) {
+		switch v.String() {
+		case "true", "yes", "1":
+			return true, nil
+		case "false", "no", "0":
+			return false, nil
+		}
+	}
+	return false, coercionError(L, key, "a boolean", v)
+}
+
+// optInt returns the integer option key of opts, def when it isn't set
+func optInt(L *lua.LState, opts *lua.LTable, key string, def int) (int, error) {
+	v := optValue(opts, key)
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return def, nil
+	case lua.LNumber:
+		if f := float64(v); f == math.Trunc(f) {
+			return int(f), nil
+		}
+	case lua.LString:
+		if !strictTypes(L) {
+			if n, err := strconv.Atoi(strings.TrimSpace(string(v))); err == nil {
+				return n, nil
+			}
+		}
+	}
+	return 0, coercionError(L, key, "a whole number", v)
+}
+
+// optFileMode returns the file mode option key of opts, def when it isn't
+// set
+func optFileMode(L *lua.LState, opts *lua.LTable, key string, def os.FileMode) (os.FileMode, error) {
+	v := optValue(opts, key)
+	if v == lua.LNil {
+		return def, nil
+	}
+	return toFileMode(L, key, v)
+}
+
+// toFileMode converts the value of the mode named name to a file mode
+func toFileMode(L *lua.LState, name string, v lua.LValue) (os.FileMode, error) {
+	var digits string
+	switch v := v.(type) {
+	case lua.LString:
+		digits = strings.TrimPrefix(strings.TrimSpace(string(v)), "0o")
+	case lua.LNumber:
+		f := float64(v)
+		if strictTypes(L) || f != math.Trunc(f) || f < 0 {
+			return 0, coercionError(L, name, `a mode string like "0644"`, v)
+		}
+		digits = strconv.FormatInt(int64(f), 10)
+	default:
+		return 0, coercionError(L, name, `a mode string like "0644"`, v)
+	}
+	mode, err := strconv.ParseUint(digits, 8, 32)
+	if err != nil || mode > 07777 {
+		return 0, fmt.Errorf("%s: %s isn't an octal file mode, use a string like \"0644\"", name, v.String())
+	}
+	return os.FileMode(mode), nil
+}
+
+// optValue returns the option key of opts, nil when opts is nil
+func optValue(opts *lua.LTable, key string) lua.LValue {
+	if opts == nil {
+		return lua.LNil
+	}
+	return opts.RawGetString(key)
+}
+
+// strictTypes reports whether the task running L coerces no option values
+func strictTypes(L *lua.LState) bool {
+	return taskctx.StrictTypes(L.Context())
+}
+
+// coercionError is the error of an option that isn't what it should be
+func coercionError(L *lua.LState, key, want string, v lua.LValue) error {
+	got := v.String()
+	if _, ok := v.(lua.LString); ok {
+		got = strconv.Quote(got)
+	}
+	err := fmt.Errorf("%s: expected %s, got %s %s", key, want, v.Type(), got)
+	if strictTypes(L) {
+		return fmt.Errorf("%w (strict_types)", err)
+	}
+	return err
+}
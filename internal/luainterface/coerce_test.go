@@ -0,0 +1,104 @@
+package luainterface
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// optsTable evaluates the Lua table expression src
+func optsTable(t *testing.T, L *lua.LState, src string) *lua.LTable {
+	require.NoError(t, L.DoString("return "+src))
+	opts := L.CheckTable(-1)
+	L.Pop(1)
+	return opts
+}
+
+func TestOptFileMode(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	tests := []struct {
+		opts string
+		want os.FileMode
+		err  string
+	}{
+		{opts: `{}`, want: 0600},
+		{opts: `{mode = "0644"}`, want: 0644},
+		{opts: `{mode = "755"}`, want: 0755},
+		{opts: `{mode = "0o640"}`, want: 0640},
+		{opts: `{mode = 0644}`, want: 0644},
+		{opts: `{mode = 644}`, want: 0644},
+		{opts: `{mode = 420}`, want: 0420},
+		{opts: `{mode = 493}`, err: "493 isn't an octal file mode"},
+		{opts: `{mode = "rw-r--r--"}`, err: "isn't an octal file mode"},
+		{opts: `{mode = "77777"}`, err: "isn't an octal file mode"},
+		{opts: `{mode = 6.44}`, err: `mode: expected a mode string like "0644", got number 6.44`},
+		{opts: `{mode = true}`, err: "got boolean true"},
+	}
+	for _, tt := range tests {
+		mode, err := optFileMode(L, optsTable(t, L, tt.opts), "mode", 0600)
+		if tt.err != "" {
+			assert.ErrorContains(t, err, tt.err, tt.opts)
+			continue
+		}
+		require.NoError(t, err, tt.opts)
+		assert.Equal(t, tt.want, mode, tt.opts)
+	}
+
+	mode, err := optFileMode(L, nil, "mode", 0600)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), mode)
+}
+
+func TestOptBoolAndInt(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	opts := optsTable(t, L, `{on = true, off = "false", yes = "yes", one = 1, maybe = "sure", port = 2222, timeout = "30", half = 1.5}`)
+
+	for key, want := range map[string]bool{"on": true, "off": false, "yes": true, "one": true, "unset": true} {
+		got, err := optBool(L, opts, key, true)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, got, key)
+	}
+	_, err := optBool(L, opts, "maybe", false)
+	assert.EqualError(t, err, `maybe: expected a boolean, got string "sure"`)
+
+	for key, want := range map[string]int{"port": 2222, "timeout": 30, "unset": 22} {
+		got, err := optInt(L, opts, key, 22)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, got, key)
+	}
+	_, err = optInt(L, opts, "half", 0)
+	assert.EqualError(t, err, "half: expected a whole number, got number 1.5")
+}
+
+func TestStrictTypes(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	L.SetContext(taskctx.WithStrictTypes(context.Background()))
+	opts := optsTable(t, L, `{mode = 644, perm = "0644", wait = "true", ok = true, port = "22", count = 3}`)
+
+	_, err := optFileMode(L, opts, "mode", 0)
+	assert.EqualError(t, err, `mode: expected a mode string like "0644", got number 644 (strict_types)`)
+	mode, err := optFileMode(L, opts, "perm", 0)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), mode)
+
+	_, err = optBool(L, opts, "wait", false)
+	assert.EqualError(t, err, `wait: expected a boolean, got string "true" (strict_types)`)
+	ok, err := optBool(L, opts, "ok", false)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = optInt(L, opts, "port", 0)
+	assert.EqualError(t, err, `port: expected a whole number, got string "22" (strict_types)`)
+	count, err := optInt(L, opts, "count", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
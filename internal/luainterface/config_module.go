@@ -0,0 +1,12 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/modules/infra"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RegisterConfigModule registers the config file editing module into the Lua state
+func RegisterConfigModule(L *lua.LState) {
+	configModule := infra.NewConfigModule(L)
+	configModule.Register(L)
+}
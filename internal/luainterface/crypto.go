@@ -56,6 +56,11 @@ func RegisterCryptoModule(L *lua.LState) {
 	// AES encryption
 	L.SetField(cryptoTable, "aes_encrypt", L.NewFunction(module.luaAESEncrypt))
 	L.SetField(cryptoTable, "aes_decrypt", L.NewFunction(module.luaAESDecrypt))
+
+	// age encryption and OpenPGP signature verification
+	L.SetField(cryptoTable, "age_encrypt", L.NewFunction(module.luaAgeEncrypt))
+	L.SetField(cryptoTable, "age_decrypt", L.NewFunction(module.luaAgeDecrypt))
+	L.SetField(cryptoTable, "gpg_verify", L.NewFunction(module.luaGPGVerify))
 	
 	// Random functions
 	L.SetField(cryptoTable, "random_string", L.NewFunction(module.luaRandomString))
@@ -0,0 +1,269 @@
+package luainterface
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// luaAgeEncrypt encrypts data or a file to age recipients:
+//
+//	crypto.age_encrypt({recipients = {"age1...", "ssh-ed25519 ..."}, input = "/backup.tar", output = "/backup.tar.age"})
+//	crypto.age_encrypt({recipients_file = "/etc/backup/recipients", data = "secret", armor = true})
+//	crypto.age_encrypt({passphrase = "...", data = "secret"})
+//
+// It returns {output = path} when output is set, otherwise {data = ciphertext}.
+func (c *CryptoModule) luaAgeEncrypt(L *lua.LState) int {
+	opts := L.CheckTable(1)
+
+	recipients, err := ageRecipients(opts)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+	in, closeIn, err := cryptoInput(opts)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+	defer closeIn()
+
+	out, finish, err := cryptoOutput(L, opts)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+
+	dst := out
+	var armored io.WriteCloser
+	if lua.LVAsBool(opts.RawGetString("armor")) {
+		armored = armor.NewWriter(out)
+		dst = armored
+	}
+	w, err := age.Encrypt(dst, recipients...)
+	if err == nil {
+		_, err = io.Copy(w, in)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil && armored != nil {
+		err = armored.Close()
+	}
+	return finish(err)
+}
+
+// luaAgeDecrypt decrypts age data or a file, armored or not:
+//
+//	crypto.age_decrypt({identity_file = "/etc/backup/key.txt", input = "/backup.tar.age", output = "/backup.tar"})
+//	crypto.age_decrypt({identity = "AGE-SECRET-KEY-1...", data = ciphertext})
+//	crypto.age_decrypt({passphrase = "...", data = ciphertext})
+//
+// It returns {output = path} when output is set, otherwise {data = plaintext}.
+func (c *CryptoModule) luaAgeDecrypt(L *lua.LState) int {
+	opts := L.CheckTable(1)
+
+	identities, err := ageIdentities(opts)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+	in, closeIn, err := cryptoInput(opts)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+	defer closeIn()
+
+	src := bufio.NewReader(in)
+	var r io.Reader = src
+	if start, _ := src.Peek(len(armor.Header)); string(start) == armor.Header {
+		r = armor.NewReader(src)
+	}
+	plain, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return cryptoError(L, fmt.Errorf("age decryption failed: %w", err))
+	}
+
+	out, finish, err := cryptoOutput(L, opts)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+	_, err = io.Copy(out, plain)
+	return finish(err)
+}
+
+// ageRecipients parses the recipients, recipients_file and passphrase
+// options
+func ageRecipients(opts *lua.LTable) ([]age.Recipient, error) {
+	if passphrase := lua.LVAsString(opts.RawGetString("passphrase")); passphrase != "" {
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{r}, nil
+	}
+
+	lines := stringList(opts.RawGetString("recipients"))
+	if path := lua.LVAsString(opts.RawGetString("recipients_file")); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipients file: %w", err)
+		}
+		lines = append(lines, strings.Split(string(content), "\n")...)
+	}
+
+	var recipients []age.Recipient
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var r age.Recipient
+		var err error
+		if strings.HasPrefix(line, "ssh-") {
+			r, err = agessh.ParseRecipient(line)
+		} else {
+			r, err = age.ParseX25519Recipient(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", line, err)
+		}
+		recipients = append(recipients, r)
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("recipients, recipients_file or passphrase is required")
+	}
+	return recipients, nil
+}
+
+// ageIdentities parses the identity, identity_file and passphrase options.
+// Identities are age secret keys or unencrypted SSH private keys.
+func ageIdentities(opts *lua.LTable) ([]age.Identity, error) {
+	if passphrase := lua.LVAsString(opts.RawGetString("passphrase")); passphrase != "" {
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{id}, nil
+	}
+
+	var sources [][]byte
+	for _, id := range stringList(opts.RawGetString("identity")) {
+		sources = append(sources, []byte(id))
+	}
+	if path := lua.LVAsString(opts.RawGetString("identity_file")); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file: %w", err)
+		}
+		sources = append(sources, content)
+	}
+
+	var identities []age.Identity
+	for _, src := range sources {
+		if bytes.Contains(src, []byte("PRIVATE KEY-----")) {
+			id, err := agessh.ParseIdentity(src)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SSH identity: %w", err)
+			}
+			identities = append(identities, id)
+			continue
+		}
+		ids, err := age.ParseIdentities(bytes.NewReader(src))
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity: %w", err)
+		}
+		identities = append(identities, ids...)
+	}
+	if len(identities) == 0 {
+		return nil, errors.New("identity, identity_file or passphrase is required")
+	}
+	return identities, nil
+}
+
+// cryptoInput opens the input file of opts, or reads its data field
+func cryptoInput(opts *lua.LTable) (io.Reader, func(), error) {
+	if path := lua.LVAsString(opts.RawGetString("input")); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open input: %w", err)
+		}
+		return f, func() { f.Close() }, nil
+	}
+	data, ok := opts.RawGetString("data").(lua.LString)
+	if !ok {
+		return nil, nil, errors.New("data or input is required")
+	}
+	return strings.NewReader(string(data)), func() {}, nil
+}
+
+// cryptoOutput returns where to write the result: the output file of
+// opts, written to a temporary file and renamed when complete, or a
+// buffer returned as data. finish pushes the result, or the error.
+func cryptoOutput(L *lua.LState, opts *lua.LTable) (io.Writer, func(error) int, error) {
+	path := lua.LVAsString(opts.RawGetString("output"))
+	if path == "" {
+		var buf bytes.Buffer
+		return &buf, func(err error) int {
+			if err != nil {
+				return cryptoError(L, err)
+			}
+			result := L.NewTable()
+			result.RawSetString("data", lua.LString(buf.String()))
+			L.Push(result)
+			L.Push(lua.LNil)
+			return 2
+		}, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create output: %w", err)
+	}
+	return tmp, func(err error) int {
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			return cryptoError(L, err)
+		}
+		result := L.NewTable()
+		result.RawSetString("output", lua.LString(path))
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	}, nil
+}
+
+// stringList returns a string or the strings of a list
+func stringList(v lua.LValue) []string {
+	switch v := v.(type) {
+	case lua.LString:
+		return []string{string(v)}
+	case *lua.LTable:
+		var list []string
+		v.ForEach(func(_, item lua.LValue) {
+			if s, ok := item.(lua.LString); ok {
+				list = append(list, string(s))
+			}
+		})
+		return list
+	}
+	return nil
+}
+
+func cryptoError(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
@@ -0,0 +1,120 @@
+package luainterface
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestCryptoAge(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(keyFile, []byte("# test key\n"+id.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	plainFile := filepath.Join(dir, "plain.txt")
+	if err := os.WriteFile(plainFile, []byte("file contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterCryptoModule(L)
+	L.SetGlobal("recipient", lua.LString(id.Recipient().String()))
+	L.SetGlobal("key_file", lua.LString(keyFile))
+	L.SetGlobal("dir", lua.LString(dir))
+
+	script := `
+		local enc, err = crypto.age_encrypt({recipients = {recipient}, data = "secret", armor = true})
+		assert(enc, err)
+		assert(enc.data:find("BEGIN AGE ENCRYPTED FILE", 1, true), "armored output expected")
+		local dec, err = crypto.age_decrypt({identity_file = key_file, data = enc.data})
+		assert(dec, err)
+		assert(dec.data == "secret", "round trip failed: " .. tostring(dec.data))
+
+		enc, err = crypto.age_encrypt({recipients = recipient, input = dir .. "/plain.txt", output = dir .. "/plain.txt.age"})
+		assert(enc, err)
+		assert(enc.output == dir .. "/plain.txt.age")
+		dec, err = crypto.age_decrypt({identity_file = key_file, input = enc.output, output = dir .. "/out.txt"})
+		assert(dec, err)
+
+		enc, err = crypto.age_encrypt({passphrase = "hunter2", data = "secret"})
+		assert(enc, err)
+		dec, err = crypto.age_decrypt({passphrase = "wrong", data = enc.data})
+		assert(dec == nil and err:find("age decryption failed"), "wrong passphrase must fail")
+		dec, err = crypto.age_decrypt({passphrase = "hunter2", data = enc.data})
+		assert(dec and dec.data == "secret", err)
+
+		local _, err = crypto.age_encrypt({data = "secret"})
+		assert(err:find("required"), err)
+		_, err = crypto.age_encrypt({recipients = {"not-a-key"}, data = "secret"})
+		assert(err:find("invalid recipient"), err)
+	`
+	if err := L.DoString(script); err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+
+	out, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "file contents" {
+		t.Errorf("decrypted file = %q", out)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestCryptoGPGVerify(t *testing.T) {
+	entity, err := openpgp.NewEntity("Release Signing", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyring, signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, entity, strings.NewReader("release"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(&keyring); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyringFile := filepath.Join(dir, "vendor.gpg")
+	if err := os.WriteFile(keyringFile, keyring.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterCryptoModule(L)
+	L.SetGlobal("signature", lua.LString(signature.String()))
+	L.SetGlobal("keyring", lua.LString(keyringFile))
+
+	script := `
+		local result, err = crypto.gpg_verify({data = "release", signature = signature, keyring = keyring})
+		assert(result, err)
+		assert(result.valid == true)
+		assert(result.signer == "Release Signing <release@example.com>", result.signer)
+		assert(#result.key_id == 16, result.key_id)
+		assert(#result.fingerprint == 40, result.fingerprint)
+
+		result, err = crypto.gpg_verify({data = "tampered", signature = signature, keyring = keyring})
+		assert(result == nil and err:find("signature verification failed"), "tampered data must fail")
+	`
+	if err := L.DoString(script); err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+}
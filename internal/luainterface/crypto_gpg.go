@@ -0,0 +1,134 @@
+package luainterface
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// luaGPGVerify checks a detached OpenPGP signature of a file or data
+// against a keyring, without a gpg binary or home directory:
+//
+//	crypto.gpg_verify({file = "/tmp/app.tar.gz", signature = "/tmp/app.tar.gz.asc", keyring = "/etc/vendor.gpg"})
+//
+// signature and keyring are paths, or the armored signature and keys
+// themselves. It returns {valid = true, signer, key_id, fingerprint}, or
+// nil and why the signature doesn't verify.
+func (c *CryptoModule) luaGPGVerify(L *lua.LState) int {
+	opts := L.CheckTable(1)
+
+	keyringArg := lua.LVAsString(opts.RawGetString("keyring"))
+	signatureArg := lua.LVAsString(opts.RawGetString("signature"))
+	if keyringArg == "" || signatureArg == "" {
+		return cryptoError(L, errors.New("signature and keyring are required"))
+	}
+
+	keyring, err := readKeyring(keyringArg)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+	signature, err := pgpSource(signatureArg, "signature")
+	if err != nil {
+		return cryptoError(L, err)
+	}
+
+	var signed io.Reader
+	if path := lua.LVAsString(opts.RawGetString("file")); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return cryptoError(L, fmt.Errorf("failed to open file: %w", err))
+		}
+		defer f.Close()
+		signed = f
+	} else if data, ok := opts.RawGetString("data").(lua.LString); ok {
+		signed = strings.NewReader(string(data))
+	} else {
+		return cryptoError(L, errors.New("file or data is required"))
+	}
+
+	sig, err := dearmor(signature, openpgp.SignatureType)
+	if err != nil {
+		return cryptoError(L, err)
+	}
+	signer, err := openpgp.CheckDetachedSignature(keyring, signed, sig)
+	if err != nil {
+		return cryptoError(L, fmt.Errorf("signature verification failed: %w", err))
+	}
+
+	result := L.NewTable()
+	result.RawSetString("valid", lua.LTrue)
+	result.RawSetString("key_id", lua.LString(fmt.Sprintf("%016X", signer.PrimaryKey.KeyId)))
+	result.RawSetString("fingerprint", lua.LString(fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint)))
+	names := make([]string, 0, len(signer.Identities))
+	for name := range signer.Identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		result.RawSetString("signer", lua.LString(names[0]))
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// readKeyring reads an armored or binary public keyring
+func readKeyring(arg string) (openpgp.EntityList, error) {
+	src, err := pgpSource(arg, "keyring")
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(src)
+	var keyring openpgp.EntityList
+	if isArmored(r) {
+		keyring, err = openpgp.ReadArmoredKeyRing(r)
+	} else {
+		keyring, err = openpgp.ReadKeyRing(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+	return keyring, nil
+}
+
+// dearmor returns the packets of r, decoding them when they are armored
+func dearmor(r io.Reader, blockType string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if !isArmored(br) {
+		return br, nil
+	}
+	block, err := armor.Decode(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode armored signature: %w", err)
+	}
+	if block.Type != blockType {
+		return nil, fmt.Errorf("expected %s, got %s", blockType, block.Type)
+	}
+	return block.Body, nil
+}
+
+func isArmored(r *bufio.Reader) bool {
+	start, _ := r.Peek(len("-----BEGIN PGP"))
+	return string(start) == "-----BEGIN PGP"
+}
+
+// pgpSource returns the armored content arg, or the file it names
+func pgpSource(arg, what string) (io.Reader, error) {
+	if strings.HasPrefix(strings.TrimSpace(arg), "-----BEGIN PGP") {
+		return strings.NewReader(strings.TrimSpace(arg)), nil
+	}
+	content, err := os.ReadFile(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
+	}
+	return bytes.NewReader(content), nil
+}
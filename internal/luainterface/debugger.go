@@ -0,0 +1,435 @@
+package luainterface
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Debugger pauses a workflow at debug.breakpoint() calls and opens an
+// inspector on the paused Lua state. It is enabled by `run --debug`;
+// without it breakpoints do nothing.
+type Debugger struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// mu serializes pauses of tasks running in parallel
+	mu sync.Mutex
+	// stepping pauses again before the next task starts
+	stepping bool
+	// detached is set when input ends; later breakpoints are ignored
+	detached bool
+
+	callsMu sync.Mutex
+	calls   []ModuleCall
+}
+
+// ModuleCall is a module function call recorded while debugging
+type ModuleCall struct {
+	Function string
+	Location string
+	Results  []string
+}
+
+// maxModuleCalls is how many recent module calls the inspector keeps
+const maxModuleCalls = 20
+
+// debuggerSkipGlobals are Lua standard libraries, not sloth-runner modules
+var debuggerSkipGlobals = map[string]bool{
+	"_G": true, "string": true, "table": true, "math": true, "os": true,
+	"io": true, "coroutine": true, "debug": true, "package": true, "channel": true,
+}
+
+var (
+	debuggerMu     sync.RWMutex
+	activeDebugger *Debugger
+)
+
+// NewDebugger creates a debugger reading commands from in
+func NewDebugger(in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{in: bufio.NewReader(in), out: out}
+}
+
+// EnableDebugger makes breakpoints pause with d. Lua states opened
+// afterwards also record module call results for the inspector.
+func EnableDebugger(d *Debugger) {
+	debuggerMu.Lock()
+	defer debuggerMu.Unlock()
+	activeDebugger = d
+}
+
+// DisableDebugger makes breakpoints do nothing again
+func DisableDebugger() {
+	EnableDebugger(nil)
+}
+
+// ActiveDebugger returns the enabled debugger, or nil
+func ActiveDebugger() *Debugger {
+	debuggerMu.RLock()
+	defer debuggerMu.RUnlock()
+	return activeDebugger
+}
+
+// OpenDebugger adds debug.breakpoint([label]) to L
+func OpenDebugger(L *lua.LState) {
+	debugTable, ok := L.GetGlobal("debug").(*lua.LTable)
+	if !ok {
+		debugTable = L.NewTable()
+		L.SetGlobal("debug", debugTable)
+	}
+	debugTable.RawSetString("breakpoint", L.NewFunction(luaBreakpoint))
+
+	if d := ActiveDebugger(); d != nil {
+		d.recordModuleCalls(L)
+	}
+}
+
+func luaBreakpoint(L *lua.LState) int {
+	d := ActiveDebugger()
+	if d == nil {
+		return 0
+	}
+
+	label := L.OptString(1, "")
+	location := strings.TrimSuffix(L.Where(1), ":")
+	title := "breakpoint at " + location
+	if label != "" {
+		title = fmt.Sprintf("breakpoint %q at %s", label, location)
+	}
+	if d.pause(L, title, pausedFrame(L, 1)) {
+		L.RaiseError("workflow aborted from debugger at %s", location)
+	}
+	return 0
+}
+
+// BeforeTask pauses before a task starts when the user stepped from the
+// previous pause. L is the state the task runs in. It returns an error
+// when the user aborts the workflow.
+func (d *Debugger) BeforeTask(L *lua.LState, taskName string, params map[string]string) error {
+	d.mu.Lock()
+	stepping := d.stepping
+	d.mu.Unlock()
+	if !stepping {
+		return nil
+	}
+
+	paramsTable := L.NewTable()
+	for k, v := range params {
+		paramsTable.RawSetString(k, lua.LString(v))
+	}
+	frame := debugFrame{env: L.G.Global, vars: []debugVar{
+		{name: "task", value: lua.LString(taskName)},
+		{name: "params", value: paramsTable},
+	}}
+	if d.pause(L, fmt.Sprintf("step: before task %q", taskName), frame) {
+		return fmt.Errorf("workflow aborted from debugger before task %s", taskName)
+	}
+	return nil
+}
+
+// ModuleCalls returns the recorded module calls, oldest first
+func (d *Debugger) ModuleCalls() []ModuleCall {
+	d.callsMu.Lock()
+	defer d.callsMu.Unlock()
+	return append([]ModuleCall(nil), d.calls...)
+}
+
+type debugVar struct {
+	name  string
+	value lua.LValue
+}
+
+// debugFrame is what the inspector sees of the paused code: its globals
+// and local variables
+type debugFrame struct {
+	env  *lua.LTable
+	vars []debugVar
+}
+
+// pausedFrame returns the frame of the function at level
+func pausedFrame(L *lua.LState, level int) debugFrame {
+	frame := debugFrame{env: L.G.Global}
+	dbg, ok := L.GetStack(level)
+	if !ok {
+		return frame
+	}
+	// Task functions keep the globals of the state that defined them
+	if fn, err := L.GetInfo("f", dbg, lua.LNil); err == nil {
+		if f, ok := fn.(*lua.LFunction); ok && f.Env != nil {
+			frame.env = f.Env
+		}
+	}
+	for i := 1; ; i++ {
+		name, value := L.GetLocal(dbg, i)
+		if name == "" {
+			break
+		}
+		// Internal locals of for loops start with "("
+		if strings.HasPrefix(name, "(") {
+			continue
+		}
+		frame.vars = append(frame.vars, debugVar{name: name, value: value})
+	}
+	return frame
+}
+
+const debuggerHelp = `  c, continue    resume the workflow
+  s, step        resume and pause again before the next task starts
+  v, vars        show local variables
+  p <lua>        evaluate a Lua expression or statement; locals are visible
+  r, results     show the latest module call results
+  bt, where      show the Lua call stack
+  q, abort       abort the workflow
+  h, help        show this help`
+
+// pause runs the inspector until the user continues, steps or aborts.
+// It returns true when the user aborts.
+func (d *Debugger) pause(L *lua.LState, title string, frame debugFrame) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.detached {
+		return false
+	}
+	d.stepping = false
+
+	scope := evalScope(L, frame)
+
+	fmt.Fprintf(d.out, "\n⏸  Paused: %s\n", title)
+	fmt.Fprintln(d.out, "   Type 'help' for commands, 'c' to continue.")
+
+	for {
+		fmt.Fprint(d.out, "(sloth-debug) ")
+		line, err := d.in.ReadString('\n')
+		if err != nil && line == "" {
+			// No more input, e.g. stdin is not a terminal: run to the end
+			fmt.Fprintln(d.out, "\n   Input closed, continuing without breakpoints.")
+			d.detached = true
+			return false
+		}
+
+		command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+		switch command {
+		case "":
+			continue
+		case "c", "continue":
+			return false
+		case "s", "step", "n", "next":
+			d.stepping = true
+			return false
+		case "v", "vars":
+			d.printVars(frame.vars)
+		case "p", "print", "eval":
+			if arg == "" {
+				fmt.Fprintln(d.out, "   usage: p <lua>")
+				continue
+			}
+			d.eval(L, scope, arg)
+		case "r", "results":
+			d.printModuleCalls()
+		case "bt", "where":
+			d.printStack(L)
+		case "q", "abort":
+			return true
+		case "h", "help":
+			fmt.Fprintln(d.out, debuggerHelp)
+		default:
+			// Anything else is evaluated as Lua
+			d.eval(L, scope, strings.TrimSpace(line))
+		}
+	}
+}
+
+func (d *Debugger) printVars(vars []debugVar) {
+	if len(vars) == 0 {
+		fmt.Fprintln(d.out, "   no local variables")
+		return
+	}
+	// The latest local with a name shadows earlier ones
+	last := make(map[string]int)
+	for i, v := range vars {
+		last[v.name] = i
+	}
+	for i, v := range vars {
+		if last[v.name] == i {
+			fmt.Fprintf(d.out, "   %s = %s\n", v.name, formatDebugValue(v.value, 2))
+		}
+	}
+}
+
+// evalScope returns the scope code typed in the inspector runs in: the
+// locals of the paused frame over its globals. New variables and
+// assignments to locals stay in this scope until the workflow resumes.
+func evalScope(L *lua.LState, frame debugFrame) *lua.LTable {
+	scope := L.NewTable()
+	meta := L.NewTable()
+	meta.RawSetString("__index", frame.env)
+	L.SetMetatable(scope, meta)
+	for _, v := range frame.vars {
+		scope.RawSetString(v.name, v.value)
+	}
+	return scope
+}
+
+// eval runs code in the inspector scope and prints what it returns
+func (d *Debugger) eval(L *lua.LState, scope *lua.LTable, code string) {
+	fn, err := L.LoadString("return " + code)
+	if err != nil {
+		fn, err = L.LoadString(code)
+	}
+	if err != nil {
+		fmt.Fprintf(d.out, "   error: %v\n", err)
+		return
+	}
+	fn.Env = scope
+
+	top := L.GetTop()
+	L.Push(fn)
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		fmt.Fprintf(d.out, "   error: %v\n", err)
+		return
+	}
+
+	var results []string
+	for i := top + 1; i <= L.GetTop(); i++ {
+		results = append(results, formatDebugValue(L.Get(i), 3))
+	}
+	L.SetTop(top)
+	if len(results) > 0 {
+		fmt.Fprintf(d.out, "   %s\n", strings.Join(results, ", "))
+	}
+}
+
+func (d *Debugger) printModuleCalls() {
+	calls := d.ModuleCalls()
+	if len(calls) == 0 {
+		fmt.Fprintln(d.out, "   no module calls recorded yet")
+		return
+	}
+	for _, c := range calls {
+		fmt.Fprintf(d.out, "   %s  %s\n      → %s\n", c.Location, c.Function, strings.Join(c.Results, ", "))
+	}
+}
+
+func (d *Debugger) printStack(L *lua.LState) {
+	for level := 1; ; level++ {
+		dbg, ok := L.GetStack(level)
+		if !ok {
+			return
+		}
+		if _, err := L.GetInfo("Sln", dbg, lua.LNil); err != nil {
+			return
+		}
+		name := dbg.Name
+		if name == "" {
+			name = "?"
+		}
+		if dbg.CurrentLine > 0 {
+			fmt.Fprintf(d.out, "   #%d %s:%d in %s\n", level, dbg.Source, dbg.CurrentLine, name)
+		} else {
+			fmt.Fprintf(d.out, "   #%d [%s] in %s\n", level, dbg.What, name)
+		}
+	}
+}
+
+// recordModuleCalls wraps the Go functions of the module tables in L so
+// their results can be shown by the inspector
+func (d *Debugger) recordModuleCalls(L *lua.LState) {
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		module := key.String()
+		table, ok := value.(*lua.LTable)
+		if !ok || debuggerSkipGlobals[module] {
+			return
+		}
+
+		var names []string
+		table.ForEach(func(k, v lua.LValue) {
+			if fn, ok := v.(*lua.LFunction); ok && fn.IsG && len(fn.Upvalues) == 0 {
+				names = append(names, k.String())
+			}
+		})
+		for _, name := range names {
+			fn := table.RawGetString(name).(*lua.LFunction)
+			table.RawSetString(name, L.NewFunction(d.recordingWrapper(module+"."+name, fn.GFunction)))
+		}
+	})
+}
+
+func (d *Debugger) recordingWrapper(name string, fn lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		n := fn(L)
+
+		results := make([]string, 0, n)
+		for i := L.GetTop() - n + 1; i <= L.GetTop(); i++ {
+			results = append(results, formatDebugValue(L.Get(i), 2))
+		}
+		call := ModuleCall{
+			Function: name,
+			Location: strings.TrimSuffix(L.Where(1), ":"),
+			Results:  results,
+		}
+
+		d.callsMu.Lock()
+		d.calls = append(d.calls, call)
+		if len(d.calls) > maxModuleCalls {
+			d.calls = d.calls[len(d.calls)-maxModuleCalls:]
+		}
+		d.callsMu.Unlock()
+		return n
+	}
+}
+
+// formatDebugValue renders a Lua value, expanding tables up to depth
+func formatDebugValue(v lua.LValue, depth int) string {
+	switch value := v.(type) {
+	case lua.LString:
+		return fmt.Sprintf("%q", string(value))
+	case *lua.LTable:
+		if depth <= 0 {
+			return "{...}"
+		}
+		return formatDebugTable(value, depth)
+	default:
+		return v.String()
+	}
+}
+
+func formatDebugTable(t *lua.LTable, depth int) string {
+	const maxEntries = 20
+
+	var parts []string
+	n := t.Len()
+	for i := 1; i <= n && len(parts) < maxEntries; i++ {
+		parts = append(parts, formatDebugValue(t.RawGetInt(i), depth-1))
+	}
+
+	var keys []string
+	fields := make(map[string]lua.LValue)
+	t.ForEach(func(k, v lua.LValue) {
+		if num, ok := k.(lua.LNumber); ok && int(num) >= 1 && int(num) <= n && float64(int(num)) == float64(num) {
+			return
+		}
+		key := k.String()
+		if _, ok := k.(lua.LString); !ok {
+			key = "[" + key + "]"
+		}
+		keys = append(keys, key)
+		fields[key] = v
+	})
+	sort.Strings(keys)
+	for _, k := range keys {
+		if len(parts) >= maxEntries {
+			parts = append(parts, "...")
+			break
+		}
+		parts = append(parts, k+" = "+formatDebugValue(fields[k], depth-1))
+	}
+
+	return "{" + strings.Join(parts, ", ") + "}"
+}
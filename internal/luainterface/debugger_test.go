@@ -0,0 +1,118 @@
+package luainterface
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func runWithDebugger(t *testing.T, input, code string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	d := NewDebugger(strings.NewReader(input), &out)
+	EnableDebugger(d)
+	defer DisableDebugger()
+
+	L := lua.NewState()
+	defer L.Close()
+	OpenDebugger(L)
+
+	err := L.DoString(code)
+	return out.String(), err
+}
+
+func TestDebugger_Breakpoint(t *testing.T) {
+	code := `
+local answer = 42
+local cfg = { name = "web" }
+debug.breakpoint("setup")
+resumed = true
+`
+	out, err := runWithDebugger(t, "vars\np answer * 2\nx = cfg.name\nx\nc\n", code)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		`Paused: breakpoint "setup" at <string>:4`,
+		"answer = 42",
+		`cfg = {name = "web"}`,
+		"84",
+		`"web"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDebugger_Abort(t *testing.T) {
+	_, err := runWithDebugger(t, "q\n", "debug.breakpoint()\nreached = true")
+	if err == nil || !strings.Contains(err.Error(), "aborted from debugger") {
+		t.Errorf("Expected abort error, got %v", err)
+	}
+}
+
+func TestDebugger_InputClosed(t *testing.T) {
+	out, err := runWithDebugger(t, "", "debug.breakpoint()\ndebug.breakpoint()")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Count(out, "Paused") != 1 {
+		t.Errorf("Expected breakpoints to be ignored once input closed, got:\n%s", out)
+	}
+}
+
+func TestDebugger_Disabled(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	OpenDebugger(L)
+
+	if err := L.DoString("debug.breakpoint('ignored')"); err != nil {
+		t.Errorf("Expected breakpoint to be a no-op without a debugger: %v", err)
+	}
+}
+
+func TestDebugger_ModuleResultsAndStep(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDebugger(strings.NewReader("r\nstep\nv\nc\n"), &out)
+	EnableDebugger(d)
+	defer DisableDebugger()
+
+	L := lua.NewState()
+	defer L.Close()
+	mod := L.NewTable()
+	L.SetField(mod, "check", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString("ready"))
+		return 1
+	}))
+	L.SetGlobal("svc", mod)
+	OpenDebugger(L)
+
+	if err := L.DoString("local s = svc.check()\ndebug.breakpoint()"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := d.BeforeTask(L, "deploy", map[string]string{"env": "prod"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Stepping only pauses before the next task
+	if err := d.BeforeTask(L, "verify", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"svc.check",
+		`→ "ready"`,
+		`Paused: step: before task "deploy"`,
+		`params = {env = "prod"}`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "verify") {
+		t.Errorf("Expected no pause before verify, got:\n%s", out.String())
+	}
+}
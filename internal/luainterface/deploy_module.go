@@ -0,0 +1,12 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/modules/infra"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RegisterDeployModule registers the Deploy module into the Lua state
+func RegisterDeployModule(L *lua.LState) {
+	deployModule := infra.NewDeployModule(L)
+	deployModule.Register(L)
+}
@@ -120,7 +120,12 @@ func (mod *DigitalOceanModule) dropletsList(L *lua.LState) int {
 func (mod *DigitalOceanModule) dropletsDelete(L *lua.LState) int {
 	tbl := L.CheckTable(1)
 	dropletID := tbl.RawGetString("id").String()
-	force := lua.LVAsBool(tbl.RawGetString("force"))
+	force, err := optBool(L, tbl, "force", false)
+	if err != nil {
+		L.Push(lua.LBool(false))
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
 
 	if dropletID == "" {
 		L.Push(lua.LBool(false))
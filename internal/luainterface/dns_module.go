@@ -0,0 +1,12 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/modules/infra"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RegisterDNSModule registers the DNS module into the Lua state
+func RegisterDNSModule(L *lua.LState) {
+	dnsModule := infra.NewDNSModule(L)
+	dnsModule.Register(L)
+}
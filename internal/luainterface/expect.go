@@ -0,0 +1,287 @@
+package luainterface
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// expectationTypeName is the metatable of the objects expect() returns
+const expectationTypeName = "Expectation"
+
+// maxDescribedLength bounds how much of a value a failed expectation shows
+const maxDescribedLength = 400
+
+// expectation holds the values passed to expect(): usually everything a
+// module call returned, e.g. ok and a result table
+type expectation struct {
+	values []lua.LValue
+	// call is the source of the expression the values came from
+	call string
+}
+
+// RegisterExpectModule registers the global expect() function. It wraps
+// what a module call returned so tasks can state what they expect of it:
+//
+//	expect(pkg.install({packages = {"nginx"}})):to_be_ok():to_change()
+//	expect(exec.run("nginx -v")):to_contain("nginx/1.24")
+//
+// An unmet expectation raises an error naming the call, what it returned
+// and the agent, which fails the task; the task runner adds the task and
+// the workflow line.
+func RegisterExpectModule(L *lua.LState) {
+	mt := L.NewTypeMetatable(expectationTypeName)
+	L.SetField(mt, "__index", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"to_be_ok":   expectToBeOk,
+		"to_change":  expectToChange,
+		"to_contain": expectToContain,
+	}))
+	L.SetGlobal("expect", L.NewFunction(luaExpect))
+}
+
+func luaExpect(L *lua.LState) int {
+	e := &expectation{call: expectedCall(L)}
+	for i := 1; i <= L.GetTop(); i++ {
+		e.values = append(e.values, L.Get(i))
+	}
+	ud := L.NewUserData()
+	ud.Value = e
+	L.SetMetatable(ud, L.GetTypeMetatable(expectationTypeName))
+	L.Push(ud)
+	return 1
+}
+
+func checkExpectation(L *lua.LState) *expectation {
+	ud := L.CheckUserData(1)
+	e, ok := ud.Value.(*expectation)
+	if !ok {
+		L.ArgError(1, "expectation expected; call it with ':'")
+	}
+	return e
+}
+
+// expectToBeOk checks that the call succeeded: its first value is truthy
+// and, when it is a result table, it reports neither success = false nor
+// a non-zero exit code
+func expectToBeOk(L *lua.LState) int {
+	e := checkExpectation(L)
+	if ok, reason := e.ok(); !ok {
+		e.fail(L, "to be ok", reason)
+	}
+	L.Push(L.Get(1))
+	return 1
+}
+
+// expectToChange checks that the result reports changed = true
+func expectToChange(L *lua.LState) int {
+	e := checkExpectation(L)
+	if ok, reason := e.ok(); !ok {
+		e.fail(L, "to change something", reason)
+	}
+	result := e.result()
+	if result == nil {
+		e.fail(L, "to change something", "it returned no result table")
+	}
+	if !lua.LVAsBool(result.RawGetString("changed")) {
+		reason := "it reported no change"
+		if msg, ok := result.RawGetString("message").(lua.LString); ok && msg != "" {
+			reason += ": " + firstLine(string(msg))
+		}
+		e.fail(L, "to change something", reason)
+	}
+	L.Push(L.Get(1))
+	return 1
+}
+
+// expectToContain checks that the output contains text. The output is the
+// first value when it is a string, or the stdout, output, body or message
+// of the result table.
+func expectToContain(L *lua.LState) int {
+	e := checkExpectation(L)
+	text := L.CheckString(2)
+	what := fmt.Sprintf("to contain %q", text)
+
+	output, ok := e.output()
+	if !ok {
+		e.fail(L, what, "it returned no output")
+	}
+	if !strings.Contains(output, text) {
+		e.fail(L, what, "output was "+describeString(output))
+	}
+	L.Push(L.Get(1))
+	return 1
+}
+
+// ok reports whether the values are a successful result, and why not
+func (e *expectation) ok() (bool, string) {
+	if len(e.values) == 0 || !lua.LVAsBool(e.values[0]) {
+		if len(e.values) > 1 && e.values[1] != lua.LNil {
+			return false, "it failed: " + describeValue(e.values[1])
+		}
+		return false, "it returned " + e.describe()
+	}
+	if t, ok := e.values[0].(*lua.LTable); ok {
+		if s := t.RawGetString("success"); s != lua.LNil && !lua.LVAsBool(s) {
+			return false, "it returned " + describeValue(t)
+		}
+		if code, ok := t.RawGetString("exit_code").(lua.LNumber); ok && code != 0 {
+			return false, fmt.Sprintf("it exited with code %d: %s", int(code), describeValue(t))
+		}
+	}
+	return true, ""
+}
+
+// result returns the first table among the values
+func (e *expectation) result() *lua.LTable {
+	for _, v := range e.values {
+		if t, ok := v.(*lua.LTable); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// output returns the text to_contain looks in
+func (e *expectation) output() (string, bool) {
+	if len(e.values) > 0 {
+		if s, ok := e.values[0].(lua.LString); ok {
+			return string(s), true
+		}
+	}
+	if t := e.result(); t != nil {
+		for _, field := range []string{"stdout", "output", "body", "message"} {
+			if s, ok := t.RawGetString(field).(lua.LString); ok {
+				return string(s), true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e *expectation) describe() string {
+	if len(e.values) == 0 {
+		return "nothing"
+	}
+	parts := make([]string, len(e.values))
+	for i, v := range e.values {
+		parts[i] = describeValue(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fail raises the error for an unmet expectation at the line of the
+// expectation
+func (e *expectation) fail(L *lua.LState, what, reason string) {
+	subject := "value"
+	if e.call != "" {
+		subject = e.call
+	}
+	// One line: the runner keeps the first line of Lua errors
+	message := fmt.Sprintf("expected %s %s, but %s", subject, what, reason)
+	if agent := taskctx.Agent(L.Context()); agent != "" {
+		message += fmt.Sprintf(" (on agent '%s')", agent)
+	}
+	L.RaiseError("%s", message)
+}
+
+// expectedCall returns the source of the argument of the expect() call
+// being made, e.g. `pkg.install({packages = {"nginx"}})`, or "" when the
+// source can't be read
+func expectedCall(L *lua.LState) string {
+	dbg, ok := L.GetStack(1)
+	if !ok {
+		return ""
+	}
+	if _, err := L.GetInfo("Sl", dbg, lua.LNil); err != nil || dbg.CurrentLine < 1 {
+		return ""
+	}
+	content, err := os.ReadFile(dbg.Source)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(content), "\n")
+	if dbg.CurrentLine > len(lines) {
+		return ""
+	}
+	return expectArgument(lines[dbg.CurrentLine-1])
+}
+
+// expectArgument returns what is between the parentheses of expect(...)
+// in line, or "" when the call doesn't fit on it
+func expectArgument(line string) string {
+	i := strings.Index(line, "expect(")
+	if i < 0 {
+		return ""
+	}
+	start := i + len("expect(")
+	depth := 1
+	var quote byte
+	for j := start; j < len(line); j++ {
+		c := line[j]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				j++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '{' || c == '[':
+			depth++
+		case c == ')' || c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(line[start:j])
+			}
+		}
+	}
+	return ""
+}
+
+// describeValue renders a Lua value for a failure message
+func describeValue(v lua.LValue) string {
+	switch v := v.(type) {
+	case lua.LString:
+		return describeString(string(v))
+	case *lua.LTable:
+		var keys []string
+		fields := map[string]string{}
+		v.ForEach(func(k, val lua.LValue) {
+			key, ok := k.(lua.LString)
+			if !ok {
+				return
+			}
+			if _, nested := val.(*lua.LTable); nested {
+				return
+			}
+			keys = append(keys, string(key))
+			fields[string(key)] = describeValue(val)
+		})
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + " = " + fields[k]
+		}
+		return truncate("{" + strings.Join(parts, ", ") + "}")
+	default:
+		return v.String()
+	}
+}
+
+// describeString quotes s, which also keeps newlines of outputs out of
+// the one-line message
+func describeString(s string) string {
+	return truncate(fmt.Sprintf("%q", s))
+}
+
+func truncate(s string) string {
+	if len(s) <= maxDescribedLength {
+		return s
+	}
+	return s[:maxDescribedLength] + "..."
+}
@@ -0,0 +1,80 @@
+package luainterface
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func runExpectWorkflow(t *testing.T, ctx context.Context, content string) error {
+	t.Helper()
+	path := writeWorkflow(t, t.TempDir(), "main.sloth", content)
+
+	L := lua.NewState()
+	defer L.Close()
+	if ctx != nil {
+		L.SetContext(ctx)
+	}
+	RegisterExpectModule(L)
+	L.SetGlobal("install", L.NewFunction(func(L *lua.LState) int {
+		result := L.NewTable()
+		result.RawSetString("changed", lua.LBool(L.CheckString(1) == "new"))
+		result.RawSetString("message", lua.LString("already installed"))
+		L.Push(lua.LTrue)
+		L.Push(result)
+		return 2
+	}))
+	L.SetGlobal("broken", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("package nginx not found"))
+		return 2
+	}))
+	return MapError(DoSlothFile(L, path))
+}
+
+func TestExpect_Passes(t *testing.T) {
+	err := runExpectWorkflow(t, nil, `
+expect(install("new")):to_be_ok():to_change()
+expect("nginx version: nginx/1.24.0"):to_contain("1.24")
+expect({success = true, exit_code = 0, stdout = "ok\n"}):to_be_ok():to_contain("ok")
+`)
+	assert.NoError(t, err)
+}
+
+func TestExpect_ToBeOk(t *testing.T) {
+	err := runExpectWorkflow(t, context.Background(), `local x = 1
+expect(broken("nginx")):to_be_ok()
+`)
+	var scriptErr *ScriptError
+	require.True(t, errors.As(err, &scriptErr), "got %v", err)
+	assert.Equal(t, 2, scriptErr.Line)
+	assert.Contains(t, scriptErr.Message, `expected broken("nginx") to be ok, but it failed: "package nginx not found"`)
+
+	err = runExpectWorkflow(t, nil, `expect({success = false, exit_code = 2, stderr = "boom"}):to_be_ok()`)
+	assert.ErrorContains(t, err, "it returned {exit_code = 2, stderr = \"boom\", success = false}")
+}
+
+func TestExpect_ToChange(t *testing.T) {
+	ctx := taskctx.WithAgent(context.Background(), "web-1")
+	err := runExpectWorkflow(t, ctx, `expect(install("old")):to_change()`)
+	assert.ErrorContains(t, err, `expected install("old") to change something, but it reported no change: already installed`)
+	assert.ErrorContains(t, err, "(on agent 'web-1')")
+}
+
+func TestExpect_ToContain(t *testing.T) {
+	err := runExpectWorkflow(t, nil, `local out = "nginx/1.22"
+expect(out):to_contain("1.24")
+`)
+	assert.ErrorContains(t, err, `expected out to contain "1.24", but output was "nginx/1.22"`)
+}
+
+func TestExpectArgument(t *testing.T) {
+	assert.Equal(t, `pkg.install({packages = {"a)"}})`, expectArgument(`  expect(pkg.install({packages = {"a)"}})):to_be_ok()`))
+	assert.Equal(t, "", expectArgument(`expect(pkg.install({`))
+	assert.Equal(t, "", expectArgument(`local x = 1`))
+}
@@ -15,6 +15,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -73,6 +74,14 @@ func (f *FileOpsModule) copy(L *lua.LState) int {
 		return 2
 	}
 
+	// Without a mode the copy keeps the permissions of the source
+	perm, err := optFileMode(L, opts, "mode", srcInfo.Mode())
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
 	// IDEMPOTENCY: Check if destination exists and is identical
 	if dstInfo, err := os.Stat(dst); err == nil {
 		// Check if files are identical by comparing checksums
@@ -93,6 +102,21 @@ func (f *FileOpsModule) copy(L *lua.LState) int {
 		}
 	}
 
+	if taskctx.CheckMode(L.Context()) {
+		content, err := os.ReadFile(src)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("failed to open source: %v", err)))
+			return 2
+		}
+		planFile(L, "copy", dst, content)
+		return pushPlanned(L, fmt.Sprintf("Would copy %s to %s", src, dst), map[string]lua.LValue{
+			"src":  lua.LString(src),
+			"dest": lua.LString(dst),
+			"size": lua.LNumber(srcInfo.Size()),
+		})
+	}
+
 	// Create destination directory if needed
 	dstDir := filepath.Dir(dst)
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
@@ -124,17 +148,7 @@ func (f *FileOpsModule) copy(L *lua.LState) int {
 		return 2
 	}
 
-	// Set permissions if specified
-	if mode := opts.RawGetString("mode"); mode != lua.LNil {
-		if modeStr := mode.String(); modeStr != "" {
-			var perm os.FileMode
-			fmt.Sscanf(modeStr, "%o", &perm)
-			os.Chmod(dst, perm)
-		}
-	} else {
-		// Copy original permissions
-		os.Chmod(dst, srcInfo.Mode())
-	}
+	os.Chmod(dst, perm)
 
 	result := L.NewTable()
 	L.SetField(result, "changed", lua.LBool(true))
@@ -147,6 +161,20 @@ func (f *FileOpsModule) copy(L *lua.LState) int {
 	return 2
 }
 
+// planFile records writing content to path in the plan when the task
+// runs in check mode, where files aren't written, and reports whether it
+// does
+func planFile(L *lua.LState, action, path string, content []byte) bool {
+	if !taskctx.CheckMode(L.Context()) {
+		return false
+	}
+	before, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(before, content) {
+		taskctx.RecordChange(L.Context(), taskctx.FileChange("file_ops", action, path, before, content))
+	}
+	return true
+}
+
 // fetch downloads a file from remote to local
 // Usage: file_ops.fetch({src="/path/to/source", dest="/path/to/dest"})
 func (f *FileOpsModule) fetch(L *lua.LState) int {
@@ -263,6 +291,13 @@ func (f *FileOpsModule) templateRender(L *lua.LState) int {
 		return 2
 	}
 
+	if planFile(L, "template", dst, buf.Bytes()) {
+		return pushPlanned(L, "Would render "+dst, map[string]lua.LValue{
+			"src":  lua.LString(src),
+			"dest": lua.LString(dst),
+		})
+	}
+
 	// Create destination directory
 	dstDir := filepath.Dir(dst)
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
@@ -375,7 +410,8 @@ func (f *FileOpsModule) lineinfile(L *lua.LState) int {
 		}
 	}
 
-	if changed {
+	output := strings.Join(newLines, "\n")
+	if changed && !planFile(L, "lineinfile", path, []byte(output)) {
 		// Create directory if needed
 		dir := filepath.Dir(path)
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -385,7 +421,6 @@ func (f *FileOpsModule) lineinfile(L *lua.LState) int {
 		}
 
 		// Write file
-		output := strings.Join(newLines, "\n")
 		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
 			L.Push(lua.LNil)
 			L.Push(lua.LString(fmt.Sprintf("failed to write file: %v", err)))
@@ -500,7 +535,8 @@ func (f *FileOpsModule) blockinfile(L *lua.LState) int {
 		}
 	}
 
-	if changed {
+	output := strings.Join(newLines, "\n")
+	if changed && !planFile(L, "blockinfile", path, []byte(output)) {
 		// Create directory if needed
 		dir := filepath.Dir(path)
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -510,7 +546,6 @@ func (f *FileOpsModule) blockinfile(L *lua.LState) int {
 		}
 
 		// Write file
-		output := strings.Join(newLines, "\n")
 		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
 			L.Push(lua.LNil)
 			L.Push(lua.LString(fmt.Sprintf("failed to write file: %v", err)))
@@ -572,7 +607,7 @@ func (f *FileOpsModule) replace(L *lua.LState) int {
 	newContent := re.ReplaceAllString(string(content), replacement)
 	changed := string(content) != newContent
 
-	if changed {
+	if changed && !planFile(L, "replace", path, []byte(newContent)) {
 		if err := os.WriteFile(path, []byte(newContent), 0644); err != nil {
 			L.Push(lua.LNil)
 			L.Push(lua.LString(fmt.Sprintf("failed to write file: %v", err)))
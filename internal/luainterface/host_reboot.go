@@ -0,0 +1,160 @@
+package luainterface
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// host.reboot defaults
+const (
+	defaultRebootTimeout      = 15 * time.Minute
+	defaultRebootDrainTimeout = 5 * time.Minute
+	// rebootUptimeSlack allows for the time the agent took to report its
+	// uptime when telling a rebooted host from one still up
+	rebootUptimeSlack = 5 * time.Second
+)
+
+// agentUptime asks an agent how long its host has been up, replaced in
+// tests
+var agentUptime = func(ctx context.Context, address string) (time.Duration, error) {
+	conn, err := grpc.Dial(address, mtls.DialOption())
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	usage, err := pb.NewAgentClient(conn).GetResourceUsage(ctx, &pb.ResourceUsageRequest{})
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(usage.UptimeSeconds) * time.Second, nil
+}
+
+// RegisterHostModule registers the host module, which reboots agents'
+// hosts and waits for them to come back:
+//
+//	assert(host.reboot{agent = "web-01", timeout = "15m"})
+//
+// The master marks the agent offline until it is back, and the reboot
+// only counts as done once the host's uptime was reset.
+func RegisterHostModule(L *lua.LState) {
+	hostTable := L.NewTable()
+	L.SetField(hostTable, "reboot", L.NewFunction(luaHostReboot))
+	L.SetGlobal("host", hostTable)
+}
+
+// luaHostReboot reboots an agent's host:
+// host.reboot{agent = "web-01", wait = true, timeout = "15m", pre_drain = true}
+func luaHostReboot(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	name := optString(opts, "agent")
+	if name == "" {
+		L.ArgError(1, "agent is required")
+		return 0
+	}
+	wait := opts.RawGetString("wait") != lua.LFalse
+	drain := opts.RawGetString("pre_drain") != lua.LFalse
+	timeout, err := optDuration(opts, "timeout", defaultRebootTimeout)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	drainTimeout, err := optDuration(opts, "drain_timeout", defaultRebootDrainTimeout)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	interval, err := optDuration(opts, "interval", defaultAgentWaitInterval)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	result, err := rebootHost(ctx, name, drain, drainTimeout, wait, timeout, interval)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	t := L.NewTable()
+	t.RawSetString("agent", lua.LString(name))
+	t.RawSetString("address", lua.LString(result.address))
+	t.RawSetString("back", lua.LBool(result.back))
+	if result.back {
+		t.RawSetString("downtime", lua.LNumber(result.downtime.Seconds()))
+		t.RawSetString("version", lua.LString(result.version))
+	}
+	L.Push(t)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// hostReboot is the outcome of a reboot
+type hostReboot struct {
+	address  string
+	back     bool
+	downtime time.Duration
+	version  string
+}
+
+// rebootHost asks the master to reboot the agent's host and, with wait,
+// waits until the agent is healthy again on a freshly booted host
+func rebootHost(ctx context.Context, name string, drain bool, drainTimeout time.Duration, wait bool, timeout, interval time.Duration) (*hostReboot, error) {
+	registry, err := agentRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	requested := time.Now()
+	// The master answers once the agent drained, so give it the time
+	rebootCtx, cancel := context.WithTimeout(ctx, drainTimeout+time.Minute)
+	resp, err := registry.RebootAgent(rebootCtx, &pb.RebootAgentRequest{
+		AgentName:           name,
+		Drain:               drain,
+		DrainTimeoutSeconds: int64(drainTimeout / time.Second),
+	})
+	cancel()
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			return nil, fmt.Errorf("rebooting %s failed: %s", name, st.Message())
+		}
+		return nil, fmt.Errorf("rebooting %s failed: %w", name, err)
+	}
+
+	result := &hostReboot{address: resp.AgentAddress}
+	if !wait {
+		return result, nil
+	}
+
+	// A heartbeat sent before the host went down makes the agent look
+	// healthy, so it is only back once its host's uptime was reset
+	info, err := waitForAgent(ctx, name, timeout, interval, func(ctx context.Context, info *pb.AgentInfo) string {
+		uptime, err := agentUptime(ctx, info.AgentAddress)
+		if err != nil {
+			return fmt.Sprintf("agent unreachable: %v", err)
+		}
+		if uptime > time.Since(requested)+rebootUptimeSlack {
+			return fmt.Sprintf("host up for %s, not rebooted yet", uptime.Round(time.Second))
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.back = true
+	result.address = info.AgentAddress
+	result.version = info.Version
+	result.downtime = time.Since(requested)
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package luainterface
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeRebootRegistry reboots agents like the master, answering with the
+// agent's info from fakeAgentRegistry
+type fakeRebootRegistry struct {
+	*fakeAgentRegistry
+	refuse   string
+	requests []*pb.RebootAgentRequest
+}
+
+func (f *fakeRebootRegistry) RebootAgent(ctx context.Context, in *pb.RebootAgentRequest, opts ...grpc.CallOption) (*pb.RebootAgentResponse, error) {
+	f.requests = append(f.requests, in)
+	if f.refuse != "" {
+		return nil, status.Error(codes.FailedPrecondition, f.refuse)
+	}
+	return &pb.RebootAgentResponse{AgentAddress: "10.0.0.7:50051"}, nil
+}
+
+// stubUptime reports the host up for hours for the first lookups, then
+// freshly booted
+func stubUptime(t *testing.T, staleLookups int) *int {
+	t.Helper()
+	lookups := 0
+	orig := agentUptime
+	agentUptime = func(ctx context.Context, address string) (time.Duration, error) {
+		lookups++
+		if lookups <= staleLookups {
+			return 3 * time.Hour, nil
+		}
+		return time.Millisecond, nil
+	}
+	t.Cleanup(func() { agentUptime = orig })
+	return &lookups
+}
+
+func runHostReboot(t *testing.T, registry *fakeRebootRegistry, script string) *lua.LState {
+	t.Helper()
+	orig := agentRegistry
+	agentRegistry = func() (pb.AgentRegistryClient, error) { return registry, nil }
+	t.Cleanup(func() { agentRegistry = orig })
+
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	RegisterHostModule(L)
+	require.NoError(t, L.DoString(script))
+	return L
+}
+
+func TestHostReboot_WaitsForUptimeReset(t *testing.T) {
+	// The agent still heartbeats while the host goes down
+	registry := &fakeRebootRegistry{fakeAgentRegistry: &fakeAgentRegistry{heartbeat: time.Now(), circuit: "closed"}}
+	lookups := stubUptime(t, 2)
+
+	L := runHostReboot(t, registry, `result, err = host.reboot{agent = "web-01", timeout = "5s", interval = 0.01}`)
+
+	assert.Equal(t, lua.LNil, L.GetGlobal("err"))
+	result := L.GetGlobal("result").(*lua.LTable)
+	assert.Equal(t, lua.LTrue, result.RawGetString("back"))
+	assert.Equal(t, "v1.2.0", result.RawGetString("version").String())
+	assert.Equal(t, 3, *lookups, "uptime should be checked until it was reset")
+
+	require.Len(t, registry.requests, 1)
+	assert.True(t, registry.requests[0].Drain, "drains by default")
+	assert.Equal(t, int64(300), registry.requests[0].DrainTimeoutSeconds)
+}
+
+func TestHostReboot_NotRebooted(t *testing.T) {
+	registry := &fakeRebootRegistry{fakeAgentRegistry: &fakeAgentRegistry{heartbeat: time.Now()}}
+	stubUptime(t, 1<<30)
+
+	L := runHostReboot(t, registry, `result, err = host.reboot{agent = "web-01", timeout = 0.05, interval = 0.01}`)
+
+	assert.Equal(t, lua.LNil, L.GetGlobal("result"))
+	assert.Contains(t, L.GetGlobal("err").String(), "host up for 3h0m0s, not rebooted yet")
+}
+
+func TestHostReboot_NoWait(t *testing.T) {
+	registry := &fakeRebootRegistry{fakeAgentRegistry: &fakeAgentRegistry{}}
+	L := runHostReboot(t, registry, `result, err = host.reboot{agent = "web-01", wait = false, pre_drain = false}`)
+
+	result := L.GetGlobal("result").(*lua.LTable)
+	assert.Equal(t, lua.LFalse, result.RawGetString("back"))
+	assert.Equal(t, "10.0.0.7:50051", result.RawGetString("address").String())
+	assert.False(t, registry.requests[0].Drain)
+	assert.Zero(t, registry.fakeAgentRegistry.lookups, "should not wait for the agent")
+}
+
+func TestHostReboot_Refused(t *testing.T) {
+	registry := &fakeRebootRegistry{
+		fakeAgentRegistry: &fakeAgentRegistry{},
+		refuse:            "1 task requests still running after 5m0s, not rebooting",
+	}
+	L := runHostReboot(t, registry, `result, err = host.reboot{agent = "web-01"}`)
+
+	assert.Equal(t, lua.LNil, L.GetGlobal("result"))
+	assert.Equal(t, "rebooting web-01 failed: 1 task requests still running after 5m0s, not rebooting", L.GetGlobal("err").String())
+}
@@ -2,13 +2,17 @@ package luainterface
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/download"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -40,6 +44,7 @@ func RegisterHTTPModule(L *lua.LState) {
 	L.SetField(httpTable, "delete", L.NewFunction(module.luaHTTPDelete))
 	L.SetField(httpTable, "patch", L.NewFunction(module.luaHTTPPatch))
 	L.SetField(httpTable, "request", L.NewFunction(module.luaHTTPRequest))
+	L.SetField(httpTable, "download", L.NewFunction(module.luaHTTPDownload))
 	
 	// Set the http module in global scope
 	L.SetGlobal("http", httpTable)
@@ -139,6 +144,99 @@ func (h *HTTPModule) luaHTTPRequest(L *lua.LState) int {
 	return h.performRequest(L, method, url, body, headers)
 }
 
+// luaHTTPDownload streams url to dest within the download limits:
+// http.download(url, dest, {headers = {...}, timeout = "30m", bandwidth = "10MB/s", mode = "0644"})
+func (h *HTTPModule) luaHTTPDownload(L *lua.LState) int {
+	url := L.CheckString(1)
+	dest := L.CheckString(2)
+	opts := L.OptTable(3, nil)
+
+	ctx, err := downloadContext(L, opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	mode, err := optFileMode(L, opts, "mode", 0644)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	timeout := 30 * time.Minute
+	var headers *lua.LTable
+	if opts != nil {
+		if v := opts.RawGetString("timeout"); v != lua.LNil {
+			if t, err := time.ParseDuration(v.String()); err == nil {
+				timeout = t
+			}
+		}
+		if v, ok := opts.RawGetString("headers").(*lua.LTable); ok {
+			headers = v
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("Failed to create request: " + err.Error()))
+		return 2
+	}
+	if headers != nil {
+		headers.ForEach(func(key, value lua.LValue) {
+			req.Header.Set(key.String(), value.String())
+		})
+	}
+
+	// The timeout comes from ctx; the module's client would cut large
+	// downloads after 30 seconds
+	resp, err := download.Default().Do(&http.Client{}, req)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("Download failed: " + err.Error()))
+		return 2
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("Download failed: HTTP %d", resp.StatusCode)))
+		return 2
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("Failed to create destination directory: " + err.Error()))
+		return 2
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("Failed to create destination: " + err.Error()))
+		return 2
+	}
+	size, err := io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("Failed to write " + dest + ": " + err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	L.SetField(result, "path", lua.LString(dest))
+	L.SetField(result, "size", lua.LNumber(size))
+	L.SetField(result, "status_code", lua.LNumber(resp.StatusCode))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
 // performRequest executes the HTTP request and returns the response
 func (h *HTTPModule) performRequest(L *lua.LState, method, url string, body []byte, headers *lua.LTable) int {
 	// Create request
@@ -146,9 +244,9 @@ func (h *HTTPModule) performRequest(L *lua.LState, method, url string, body []by
 	var err error
 	
 	if body != nil {
-		req, err = http.NewRequest(method, url, bytes.NewReader(body))
+		req, err = http.NewRequestWithContext(callContext(L), method, url, bytes.NewReader(body))
 	} else {
-		req, err = http.NewRequest(method, url, nil)
+		req, err = http.NewRequestWithContext(callContext(L), method, url, nil)
 	}
 	
 	if err != nil {
@@ -0,0 +1,12 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/modules/infra"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RegisterKmodModule registers the kernel module management module into the Lua state
+func RegisterKmodModule(L *lua.LState) {
+	kmodModule := infra.NewKmodModule(L)
+	kmodModule.Register(L)
+}
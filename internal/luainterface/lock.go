@@ -0,0 +1,210 @@
+package luainterface
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/locks"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// lockClient returns the client locks are taken with, replaced in tests
+var lockClient = locks.Default
+
+// heldLocks are the leases a task holds, released when it returns
+type heldLocks struct {
+	mu     sync.Mutex
+	leases []*locks.Lease
+}
+
+// RegisterLockModule registers the lock module, which takes named locks
+// held on the master so concurrent runs don't overlap:
+//
+//	local l = assert(lock.acquire("apt-db", {timeout = "5m"}))
+//	exec.run("apt-get install -y nginx")
+//	l:release()
+//
+//	lock.with("apt-db", {timeout = "5m"}, function()
+//	    exec.run("apt-get install -y nginx")
+//	end)
+//
+// Locks are scoped to the agent the task runs on, or to the host for
+// local runs; global = true shares a lock between all hosts. Locks a task
+// still holds are released when it returns.
+func RegisterLockModule(L *lua.LState) {
+	lockTable := L.NewTable()
+	L.SetField(lockTable, "acquire", L.NewFunction(luaLockAcquire))
+	L.SetField(lockTable, "release", L.NewFunction(luaLockRelease))
+	L.SetField(lockTable, "with", L.NewFunction(luaLockWith))
+	L.SetGlobal("lock", lockTable)
+}
+
+// luaLockAcquire takes a lock: lock.acquire(name, {timeout = "5m", ttl = "30s", global = false})
+func luaLockAcquire(L *lua.LState) int {
+	name := L.CheckString(1)
+	lease, err := acquireLock(L, name, L.OptTable(2, L.NewTable()))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(leaseValue(L, lease))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaLockRelease releases a lock taken with lock.acquire
+func luaLockRelease(L *lua.LState) int {
+	lease, ok := L.CheckTable(1).RawGetString("__lease").(*lua.LUserData)
+	if !ok {
+		L.ArgError(1, "lock expected")
+		return 0
+	}
+	if err := releaseLock(L, lease.Value.(*locks.Lease)); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaLockWith runs fn holding a lock and returns its results:
+// lock.with(name, [opts], fn)
+func luaLockWith(L *lua.LState) int {
+	name := L.CheckString(1)
+	opts := L.NewTable()
+	fnIndex := 2
+	if t, ok := L.Get(2).(*lua.LTable); ok {
+		opts = t
+		fnIndex = 3
+	}
+	fn := L.CheckFunction(fnIndex)
+
+	lease, err := acquireLock(L, name, opts)
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+		return 0
+	}
+
+	top := L.GetTop()
+	L.Push(fn)
+	callErr := L.PCall(0, lua.MultRet, nil)
+	releaseErr := releaseLock(L, lease)
+	if callErr != nil {
+		L.RaiseError("%s", callErr.Error())
+		return 0
+	}
+	if releaseErr != nil {
+		L.RaiseError("%s", releaseErr.Error())
+		return 0
+	}
+	return L.GetTop() - top
+}
+
+func acquireLock(L *lua.LState, name string, opts *lua.LTable) (*locks.Lease, error) {
+	timeout, err := optDuration(opts, "timeout", 0)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := optDuration(opts, "ttl", locks.DefaultTTL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := lockClient()
+	if err != nil {
+		return nil, err
+	}
+
+	holder := client.Holder
+	if task := lockTaskName(L); task != "" {
+		holder = fmt.Sprintf("%s task %s", client.Holder, task)
+	}
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	lease, err := client.Acquire(ctx, name, locks.Options{
+		Timeout: timeout,
+		TTL:     ttl,
+		Global:  lua.LVAsBool(opts.RawGetString("global")),
+		Holder:  holder,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if held := taskLocks(L); held != nil {
+		held.mu.Lock()
+		held.leases = append(held.leases, lease)
+		held.mu.Unlock()
+	}
+	return lease, nil
+}
+
+func releaseLock(L *lua.LState, lease *locks.Lease) error {
+	if held := taskLocks(L); held != nil {
+		held.mu.Lock()
+		for i, l := range held.leases {
+			if l == lease {
+				held.leases = append(held.leases[:i], held.leases[i+1:]...)
+				break
+			}
+		}
+		held.mu.Unlock()
+	}
+	return lease.Release()
+}
+
+// leaseValue wraps a lease for Lua, with lease:release()
+func leaseValue(L *lua.LState, lease *locks.Lease) *lua.LTable {
+	ud := L.NewUserData()
+	ud.Value = lease
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(lease.Name))
+	t.RawSetString("id", lua.LString(lease.ID))
+	t.RawSetString("__lease", ud)
+	t.RawSetString("release", L.NewFunction(luaLockRelease))
+	return t
+}
+
+func lockTaskName(L *lua.LState) string {
+	if ctx, ok := L.GetGlobal("__task_context").(*lua.LTable); ok {
+		return lua.LVAsString(ctx.RawGetString("task_name"))
+	}
+	return ""
+}
+
+// taskLocks returns the locks held by the running task, nil outside tasks
+func taskLocks(L *lua.LState) *heldLocks {
+	ctx, ok := L.GetGlobal("__task_context").(*lua.LTable)
+	if !ok {
+		return nil
+	}
+	if ud, ok := ctx.RawGetString("__locks").(*lua.LUserData); ok {
+		return ud.Value.(*heldLocks)
+	}
+	ud := L.NewUserData()
+	held := &heldLocks{}
+	ud.Value = held
+	ctx.RawSetString("__locks", ud)
+	return held
+}
+
+// releaseTaskLocks releases the locks a task didn't release itself
+func releaseTaskLocks(taskContext *lua.LTable) {
+	ud, ok := taskContext.RawGetString("__locks").(*lua.LUserData)
+	if !ok {
+		return
+	}
+	held := ud.Value.(*heldLocks)
+	held.mu.Lock()
+	leases := held.leases
+	held.leases = nil
+	held.mu.Unlock()
+	for _, lease := range leases {
+		lease.Release()
+	}
+}
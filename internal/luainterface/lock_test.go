@@ -0,0 +1,141 @@
+package luainterface
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/locks"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+)
+
+// fakeLockRegistry holds locks like the master, without expiry
+type fakeLockRegistry struct {
+	pb.AgentRegistryClient
+	mu      sync.Mutex
+	held    map[string]string // key -> holder
+	leases  map[string]string // lease ID -> key
+	counter int
+}
+
+func (f *fakeLockRegistry) AcquireLock(ctx context.Context, in *pb.AcquireLockRequest, opts ...grpc.CallOption) (*pb.AcquireLockResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := in.Scope + "/" + in.Name
+	if holder, ok := f.held[key]; ok {
+		return &pb.AcquireLockResponse{Holder: holder}, nil
+	}
+	f.counter++
+	id := fmt.Sprint(f.counter)
+	f.held[key] = in.Holder
+	f.leases[id] = key
+	return &pb.AcquireLockResponse{Acquired: true, LeaseId: id, Holder: in.Holder}, nil
+}
+
+func (f *fakeLockRegistry) RenewLock(ctx context.Context, in *pb.RenewLockRequest, opts ...grpc.CallOption) (*pb.RenewLockResponse, error) {
+	return &pb.RenewLockResponse{Success: true}, nil
+}
+
+func (f *fakeLockRegistry) ReleaseLock(ctx context.Context, in *pb.ReleaseLockRequest, opts ...grpc.CallOption) (*pb.ReleaseLockResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key, ok := f.leases[in.LeaseId]
+	if ok {
+		delete(f.leases, in.LeaseId)
+		delete(f.held, key)
+	}
+	return &pb.ReleaseLockResponse{Success: ok}, nil
+}
+
+func (f *fakeLockRegistry) holders() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := map[string]string{}
+	for k, v := range f.held {
+		out[k] = v
+	}
+	return out
+}
+
+func newLockState(t *testing.T) (*lua.LState, *fakeLockRegistry) {
+	t.Helper()
+	registry := &fakeLockRegistry{held: map[string]string{}, leases: map[string]string{}}
+	client := &locks.Client{Registry: registry, Scope: "web-01", Holder: "web-01"}
+	previous := lockClient
+	lockClient = func() (*locks.Client, error) { return client, nil }
+	t.Cleanup(func() { lockClient = previous })
+
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	RegisterLockModule(L)
+	return L, registry
+}
+
+func TestLock_AcquireAndRelease(t *testing.T) {
+	L, registry := newLockState(t)
+
+	require.NoError(t, L.DoString(`
+		l = assert(lock.acquire("apt-db", {timeout = "1s"}))
+		again, err = lock.acquire("apt-db", {timeout = 0.05})
+		global = assert(lock.acquire("apt-db", {global = true}))
+	`))
+	assert.Equal(t, lua.LNil, L.GetGlobal("again"))
+	assert.Contains(t, L.GetGlobal("err").String(), "held by web-01")
+	assert.Equal(t, map[string]string{"web-01/apt-db": "web-01", "/apt-db": "web-01"}, registry.holders())
+
+	require.NoError(t, L.DoString(`
+		assert(l:release())
+		assert(lock.release(global))
+		l = assert(lock.acquire("apt-db"))
+		l:release()
+	`))
+	assert.Empty(t, registry.holders())
+}
+
+func TestLock_With(t *testing.T) {
+	L, registry := newLockState(t)
+
+	require.NoError(t, L.DoString(`
+		a, b = lock.with("apt-db", {timeout = "1s"}, function()
+			held = lock.acquire("apt-db")
+			return 1, 2
+		end)
+		ok, err = pcall(lock.with, "apt-db", function() error("boom") end)
+	`))
+	assert.Equal(t, lua.LNumber(1), L.GetGlobal("a"))
+	assert.Equal(t, lua.LNumber(2), L.GetGlobal("b"))
+	assert.Equal(t, lua.LNil, L.GetGlobal("held"), "lock taken twice")
+	assert.Equal(t, lua.LFalse, L.GetGlobal("ok"))
+	assert.Contains(t, L.GetGlobal("err").String(), "boom")
+	assert.Empty(t, registry.holders(), "locks are released after errors")
+}
+
+func TestLock_ReleasedWhenTaskReturns(t *testing.T) {
+	L, registry := newLockState(t)
+
+	require.NoError(t, L.DoString(`
+		function fn()
+			assert(lock.acquire("apt-db"))
+			return true, "done"
+		end
+	`))
+	success, _, _, err := ExecuteLuaFunction(L, L.GetGlobal("fn").(*lua.LFunction), map[string]string{"task_name": "install"}, nil, 3, context.Background())
+	require.NoError(t, err)
+	assert.True(t, success)
+	assert.Empty(t, registry.holders())
+
+	require.NoError(t, L.DoString(`
+		function fn()
+			assert(lock.acquire("apt-db"))
+			error("failed")
+		end
+	`))
+	_, _, _, err = ExecuteLuaFunction(L, L.GetGlobal("fn").(*lua.LFunction), map[string]string{"task_name": "install"}, nil, 3, context.Background())
+	require.Error(t, err)
+	assert.Empty(t, registry.holders())
+}
@@ -1,12 +1,14 @@
 package luainterface
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
 	"log/slog"
 	"os/exec"
 	"path/filepath"
+	"sync"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/ai"
 	"github.com/chalkan3-sloth/sloth-runner/internal/core"
@@ -36,6 +38,13 @@ var ExecCommand = exec.Command
 
 // ParseLuaScript parses a Lua script using Modern DSL only
 func ParseLuaScript(ctx context.Context, filePath string, valuesTable *lua.LTable) (map[string]types.TaskGroup, error) {
+	taskGroups, _, err := parseLuaScript(ctx, filePath, valuesTable)
+	return taskGroups, err
+}
+
+// parseLuaScript also returns the globals of the state the script ran in,
+// which the workflow's functions keep using after it is closed
+func parseLuaScript(ctx context.Context, filePath string, valuesTable *lua.LTable) (map[string]types.TaskGroup, *lua.LTable, error) {
 	L := lua.NewState()
 	defer L.Close()
 
@@ -47,20 +56,19 @@ func ParseLuaScript(ctx context.Context, filePath string, valuesTable *lua.LTabl
 
 	// Load values if provided
 	if valuesTable != nil {
-		L.SetGlobal("Values", valuesTable)
-		L.SetGlobal("values", valuesTable) // Also set lowercase for compatibility
+		L.SetGlobal("values", valuesTable)
 	}
 
 	// Execute the Lua script
-	if err := L.DoFile(filePath); err != nil {
-		return nil, fmt.Errorf("failed to execute Lua script: %w", err)
+	if err := DoSlothFile(L, filePath); err != nil {
+		return nil, nil, fmt.Errorf("failed to execute Lua script: %w", MapError(err))
 	}
 
 	// Modern DSL: Extract workflows from __workflows__ global table
 	globalWorkflows := L.GetGlobal("__workflows__")
 
 	if globalWorkflows.Type() != lua.LTTable {
-		return nil, fmt.Errorf("no workflows found. Please define workflows using workflow() or workflow.define()")
+		return nil, nil, fmt.Errorf("no workflows found. Please define workflows using workflow() or workflow.define()")
 	}
 
 	loadedTaskGroups := make(map[string]types.TaskGroup)
@@ -74,7 +82,7 @@ func ParseLuaScript(ctx context.Context, filePath string, valuesTable *lua.LTabl
 		}
 
 		groupTable := groupValue.(*lua.LTable)
-		description := groupTable.RawGetString("description").String()
+		description := lua.LVAsString(groupTable.RawGetString("description"))
 		workdir := groupTable.RawGetString("workdir").String()
 
 		// Parse workdir lifecycle fields
@@ -106,6 +114,9 @@ func ParseLuaScript(ctx context.Context, filePath string, valuesTable *lua.LTabl
 					if localOverrides.CommandStr != "" {
 						finalTask.CommandStr = localOverrides.CommandStr
 					}
+					if len(localOverrides.Services) > 0 {
+						finalTask.Services = localOverrides.Services
+					}
 					finalTask.Name = taskKey.String()
 				} else {
 					finalTask = parseLuaTask(L, taskTable)
@@ -120,7 +131,7 @@ func ParseLuaScript(ctx context.Context, filePath string, valuesTable *lua.LTabl
 		if luaDelegateTo.Type() == lua.LTString {
 			delegateTo = luaDelegateTo.String()
 		} else if luaDelegateTo.Type() == lua.LTTable {
-			delegateTo = LuaTableToGoMap(L, luaDelegateTo.(*lua.LTable))
+			delegateTo = parseDelegateTable(L, luaDelegateTo.(*lua.LTable))
 		}
 
 		loadedTaskGroups[groupName] = types.TaskGroup{
@@ -131,15 +142,17 @@ func ParseLuaScript(ctx context.Context, filePath string, valuesTable *lua.LTabl
 			CreateWorkdirBeforeRun:   createWorkdir,
 			CleanWorkdirAfterRunFunc: cleanWorkdirFunc,
 			DelegateTo:               delegateTo,
+			Services:                 parseLuaServices(groupTable.RawGetString("services")),
+			StrictTypes:              lua.LVAsBool(groupTable.RawGetString("strict_types")),
 		}
 	})
 
 	// Check if any workflows were found
 	if workflowCount == 0 {
-		return nil, fmt.Errorf("no workflows found. Please define workflows using workflow() or workflow.define()")
+		return nil, nil, fmt.Errorf("no workflows found. Please define workflows using workflow() or workflow.define()")
 	}
 
-	return loadedTaskGroups, nil
+	return loadedTaskGroups, L.G.Global, nil
 }
 
 func parseLuaTask(L *lua.LState, taskTable *lua.LTable) types.Task {
@@ -198,6 +211,9 @@ func parseLuaTask(L *lua.LState, taskTable *lua.LTable) types.Task {
 		})
 	}
 
+	// Parse workspace: the paths sent along when the task is delegated
+	workspace := workspacePaths(taskTable.RawGetString("workspace"))
+
 	// Parse next_if_fail
 	var nextIfFail []string
 	luaNextIfFail := taskTable.RawGetString("next_if_fail")
@@ -216,6 +232,17 @@ func parseLuaTask(L *lua.LState, taskTable *lua.LTable) types.Task {
 		retries = int(luaRetries.(lua.LNumber))
 	}
 
+	// Parse retry_delay, retry_backoff and retry_on: when and after which
+	// failures the task runs again
+	retryDelay, retryBackoff := "", ""
+	if luaRetryDelay := taskTable.RawGetString("retry_delay"); luaRetryDelay.Type() == lua.LTString {
+		retryDelay = luaRetryDelay.String()
+	}
+	if luaRetryBackoff := taskTable.RawGetString("retry_backoff"); luaRetryBackoff.Type() == lua.LTString {
+		retryBackoff = luaRetryBackoff.String()
+	}
+	retryOn := stringList(taskTable.RawGetString("retry_on"))
+
 	// Parse timeout
 	timeout := ""
 	luaTimeout := taskTable.RawGetString("timeout")
@@ -230,6 +257,24 @@ func parseLuaTask(L *lua.LState, taskTable *lua.LTable) types.Task {
 		async = lua.LVAsBool(luaAsync)
 	}
 
+	// Parse max_concurrency and fail_fast (tasks delegated to several hosts)
+	maxConcurrency := 0
+	luaMaxConcurrency := taskTable.RawGetString("max_concurrency")
+	if luaMaxConcurrency.Type() == lua.LTNumber {
+		maxConcurrency = int(luaMaxConcurrency.(lua.LNumber))
+	}
+	failFast := false
+	luaFailFast := taskTable.RawGetString("fail_fast")
+	if luaFailFast.Type() == lua.LTBool {
+		failFast = lua.LVAsBool(luaFailFast)
+	}
+
+	// Parse workspace_diff: record what a delegated task changed in the workspace
+	workspaceDiff := lua.LVAsBool(taskTable.RawGetString("workspace_diff"))
+
+	// Parse runtime: the Nix shell the task's commands run in
+	nixShell := nixShellFromLua(taskTable.RawGetString("runtime"))
+
 	// Parse pre_exec and post_exec
 	var preExec, postExec, onSuccess, onFailure *lua.LFunction
 	luaPreExec := taskTable.RawGetString("pre_exec")
@@ -290,7 +335,7 @@ func parseLuaTask(L *lua.LState, taskTable *lua.LTable) types.Task {
 		delegateTo = luaDelegateTo.String()
 		slog.Debug("delegate_to parsed as string", "task_name", name, "value", delegateTo)
 	} else if luaDelegateTo.Type() == lua.LTTable {
-		delegateTo = LuaTableToGoMap(L, luaDelegateTo.(*lua.LTable))
+		delegateTo = parseDelegateTable(L, luaDelegateTo.(*lua.LTable))
 		slog.Debug("delegate_to parsed as table", "task_name", name, "value", delegateTo)
 	} else {
 		slog.Debug("delegate_to not found or invalid type", "task_name", name, "type", luaDelegateTo.Type().String())
@@ -308,8 +353,12 @@ func parseLuaTask(L *lua.LState, taskTable *lua.LTable) types.Task {
 		DependsOn:   dependsOn,
 		Artifacts:   artifacts,
 		Consumes:    consumes,
+		Workspace:   workspace,
 		NextIfFail:  nextIfFail,
 		Retries:     retries,
+		RetryDelay:   retryDelay,
+		RetryBackoff: retryBackoff,
+		RetryOn:      retryOn,
 		Timeout:     timeout,
 		Async:       async,
 		PreExec:     preExec,
@@ -321,9 +370,34 @@ func parseLuaTask(L *lua.LState, taskTable *lua.LTable) types.Task {
 		AbortIf:     abortIf,     // ✅ Include abort_if string condition
 		AbortIfFunc: abortIfFunc, // ✅ Include abort_if function condition
 		DelegateTo:  delegateTo,
+		Services:    serviceNames(taskTable.RawGetString("services")),
+
+		MaxConcurrency: maxConcurrency,
+		FailFast:       failFast,
+		WorkspaceDiff:  workspaceDiff,
+		NixShell:       nixShell,
 	}
 }
 
+// workspacePaths converts a task's workspace field, a path or a list of
+// paths, skipping empty ones
+func workspacePaths(value lua.LValue) []string {
+	var paths []string
+	switch v := value.(type) {
+	case lua.LString:
+		if v != "" {
+			paths = append(paths, string(v))
+		}
+	case *lua.LTable:
+		v.ForEach(func(_, item lua.LValue) {
+			if s := lua.LVAsString(item); s != "" {
+				paths = append(paths, s)
+			}
+		})
+	}
+	return paths
+}
+
 func newLuaImportFunction(baseDir string) lua.LGFunction {
 	return func(L *lua.LState) int {
 		relPath := L.CheckString(1)
@@ -333,8 +407,14 @@ func newLuaImportFunction(baseDir string) lua.LGFunction {
 			L.RaiseError("cannot read imported file: %s", err.Error())
 			return 0
 		}
-		if err := L.DoString(string(content)); err != nil {
-			L.RaiseError("error executing imported file: %s", err.Error())
+		// Name the chunk after the file so errors point at it
+		fn, err := L.Load(bytes.NewReader(content), absPath)
+		if err == nil {
+			L.Push(fn)
+			err = L.PCall(0, lua.MultRet, nil)
+		}
+		if err != nil {
+			L.RaiseError("%s%s", importErrorPrefix, err.Error())
 			return 0
 		}
 		return 1
@@ -426,12 +506,18 @@ func RegisterAllModules(L *lua.LState, agentClient ...interface{}) {
 	registerAllModulesInternal(L, client)
 }
 
+// configureExecModule sets the SSH helpers of the exec module once, since
+// states are registered concurrently
+var configureExecModule sync.Once
+
 // registerAllModulesInternal is the actual implementation
 func registerAllModulesInternal(L *lua.LState, agentClient interface{}) {
 	// Configure SSH helpers for exec module
-	execmodule.IsSSHExecutionEnabled = IsSSHExecutionEnabled
-	execmodule.GetSSHProfile = GetSSHProfile
-	execmodule.ExecuteCommandWithSSH = ExecuteCommandWithSSH
+	configureExecModule.Do(func() {
+		execmodule.IsSSHExecutionEnabled = IsSSHExecutionEnabled
+		execmodule.GetSSHProfile = GetSSHProfile
+		execmodule.ExecuteCommandWithSSH = ExecuteCommandWithSSH
+	})
 
 	// Register core modules using new modular structure
 	data.Open(L)
@@ -440,6 +526,7 @@ func registerAllModulesInternal(L *lua.LState, agentClient interface{}) {
 	execmodule.Open(L)
 	log.Open(L)
 	workdir.Open(L)
+	OpenAssets(L)
 
 	// Register event module for dispatching events
 	eventModule := coremodules.NewEventModule()
@@ -448,6 +535,12 @@ func registerAllModulesInternal(L *lua.LState, agentClient interface{}) {
 	// Register watcher module for event watchers
 	RegisterWatcherModule(L)
 
+	// Register watch module for what the master watches
+	OpenWatch(L)
+
+	// Register runtime module for the environments tasks run in
+	OpenRuntime(L)
+
 	// Register extended modules from other files
 	RegisterGitModule(L)  // Use new git module with table-based API
 	OpenPython(L)
@@ -505,6 +598,17 @@ func registerAllModulesInternal(L *lua.LState, agentClient interface{}) {
 
 	// Register new enhanced modules
 	RegisterHTTPModule(L)
+	RegisterArtifactModule(L)
+	RegisterS3Module(L)
+	RegisterCertsModule(L)
+	RegisterExpectModule(L)
+	RegisterProbeModule(L)
+	RegisterLockModule(L)
+	RegisterProgressModule(L)
+	RegisterMultiarchModule(L)
+	RegisterCanaryModule(L)
+	RegisterAgentModule(L)
+	RegisterHostModule(L)
 	RegisterStringModule(L)
 	RegisterMathModule(L)
 	
@@ -531,14 +635,27 @@ func registerAllModulesInternal(L *lua.LState, agentClient interface{}) {
 	// Register Firewall module for firewall management
 	RegisterFirewallModule(L)
 
-	// Register infrastructure modules (LVM, RAID, Sysctl, Cron, NFS/SMB, NixOS)
+	// Register infrastructure modules (LVM, RAID, Sysctl, Kmod, Cron, NFS/SMB, NixOS)
 	RegisterLVMModule(L)
 	RegisterRAIDModule(L)
 	RegisterSysctlModule(L)
+	RegisterKmodModule(L)
 	RegisterCronModule(L)
 	RegisterNFSSMBModule(L)
 	RegisterNixOSModule(L)
 
+	// Register Deploy module for releases/current style application deployments
+	RegisterDeployModule(L)
+
+	// Register DNS module for /etc/hosts entries and provider records
+	RegisterDNSModule(L)
+
+	// Register config module for structured JSON/YAML/TOML/INI edits
+	RegisterConfigModule(L)
+
+	// Register tofu module for OpenTofu/Terraform plans parsed into tables
+	RegisterTofuModule(L)
+
 	// Register Stow module for dotfiles management (as PreloadModule for require compatibility)
 	stowModule := NewStowModule(nil)
 	L.PreloadModule("stow", stowModule.Loader)
@@ -573,6 +690,28 @@ func registerAllModulesInternal(L *lua.LState, agentClient interface{}) {
 	
 	// ✅ AUTO-LOAD ALL MODULES GLOBALLY (No require() needed)
 	RegisterModulesGlobally(L, agentClient)
+
+	// Resolve legacy module names such as k8s to their canonical module
+	OpenModuleAliases(L)
+
+	// Skip calls that would change the system in check mode (run --dry-run)
+	OpenCheckMode(L)
+
+	// Fail calls that would work on the runner in tasks delegated over SSH
+	OpenRemoteHost(L)
+
+	// Bound network, package and command module calls by their timeouts
+	// and record the slow ones
+	OpenCallLimits(L)
+
+	// Count built-in module calls for opt-in usage stats
+	OpenUsageStats(L)
+
+	// Check privileged module calls against the active policy gate
+	OpenPolicy(L)
+
+	// Register debug.breakpoint() last so module calls can be recorded
+	OpenDebugger(L)
 }
 
 // RegisterModulesGlobally loads all modules automatically as global variables
@@ -690,6 +829,19 @@ func LuaTableToGoMap(L *lua.LState, table *lua.LTable) map[string]interface{} {
 	return result
 }
 
+// parseDelegateTable converts a delegate_to table: a list of agent names
+// becomes []interface{}, an inline agent definition a map
+func parseDelegateTable(L *lua.LState, table *lua.LTable) interface{} {
+	if n := table.Len(); n > 0 {
+		hosts := make([]interface{}, 0, n)
+		for i := 1; i <= n; i++ {
+			hosts = append(hosts, table.RawGetInt(i).String())
+		}
+		return hosts
+	}
+	return LuaTableToGoMap(L, table)
+}
+
 // ExecuteLuaFunction executes a Lua function with parameters
 func ExecuteLuaFunction(L *lua.LState, fn *lua.LFunction, params map[string]string, secondArg lua.LValue, nRet int, ctx context.Context, args ...lua.LValue) (bool, string, *lua.LTable, error) {
 	if ctx != nil {
@@ -708,8 +860,12 @@ func ExecuteLuaFunction(L *lua.LState, fn *lua.LFunction, params map[string]stri
 		if groupName, exists := params["group_name"]; exists {
 			taskContext.RawSetString("group_name", lua.LString(groupName))
 		}
+		if assetsDir, exists := params["assets_dir"]; exists {
+			taskContext.RawSetString("assets_dir", lua.LString(assetsDir))
+		}
 	}
 	L.SetGlobal("__task_context", taskContext)
+	defer releaseTaskLocks(taskContext)
 	
 	// ✅ Create 'this' object for Modern DSL tasks
 	var thisObj lua.LValue = lua.LNil
@@ -792,7 +948,7 @@ func ExecuteLuaFunction(L *lua.LState, fn *lua.LFunction, params map[string]stri
 	}
 
 	if err := L.PCall(numArgs, lua.MultRet, nil); err != nil {
-		return false, "", nil, fmt.Errorf("error executing Lua function: %w", err)
+		return false, "", nil, fmt.Errorf("error executing Lua function: %w", MapError(err))
 	}
 	top := L.GetTop()
 	var success bool
@@ -817,6 +973,9 @@ func ExecuteLuaFunction(L *lua.LState, fn *lua.LFunction, params map[string]stri
 		}
 	}
 	L.SetTop(0)
+	if success {
+		outputTable = mergeSetOutputs(L, taskContext, outputTable)
+	}
 	return success, message, outputTable, nil
 }
 
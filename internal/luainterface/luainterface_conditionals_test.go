@@ -950,3 +950,91 @@ end
 	require.NoError(t, err)
 	assert.True(t, result, "Should handle many parameters")
 }
+
+// TestParseLuaScript_GroupDelegateTo tests delegate_to set once for a whole workflow
+func TestParseLuaScript_GroupDelegateTo(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "group_delegate.sloth")
+	script := `
+workflow.define("table_form", {
+	delegate_to = { "web-01", "web-02" },
+	tasks = {
+		{ name = "deploy", command = "echo deploy" },
+		{ name = "notify", command = "echo notify", delegate_to = "local" }
+	}
+})
+
+local build = task("build"):command("echo build"):build()
+workflow.define("fluent_form")
+	:delegate_to("build-01")
+	:tasks({ build })
+	:on_complete(function() end)
+`
+	require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0644))
+
+	taskGroups, err := ParseLuaScript(context.Background(), scriptPath, nil)
+	require.NoError(t, err)
+
+	tableForm := taskGroups["table_form"]
+	assert.Equal(t, []interface{}{"web-01", "web-02"}, tableForm.DelegateTo)
+	require.Len(t, tableForm.Tasks, 2)
+	for _, task := range tableForm.Tasks {
+		if task.Name == "notify" {
+			assert.Equal(t, "local", task.DelegateTo)
+		} else {
+			assert.Nil(t, task.DelegateTo)
+		}
+	}
+
+	assert.Equal(t, "build-01", taskGroups["fluent_form"].DelegateTo)
+}
+
+// TestParseLuaScript_Services tests services declared with service() and
+// the services tasks use
+func TestParseLuaScript_Services(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "services.sloth")
+	script := `
+local db = service("test-db")
+	:command("docker run --rm -p 5432:5432 postgres:16")
+	:env({ POSTGRES_PASSWORD = "test" })
+	:ready_port(5432)
+	:ready_timeout("1m")
+
+local migrate = task("migrate"):services({ db }):command(function() return true end):build()
+workflow.define("fluent_form")
+	:services({ db })
+	:tasks({ migrate })
+	:on_complete(function() end)
+
+workflow.define("table_form", {
+	services = {
+		forward = { command = "kubectl port-forward svc/api 8080:80", ready_command = "curl -sf localhost:8080" }
+	},
+	tasks = {
+		{ name = "smoke", command = "echo smoke", services = "forward" }
+	}
+})
+`
+	require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0644))
+
+	taskGroups, err := ParseLuaScript(context.Background(), scriptPath, nil)
+	require.NoError(t, err)
+
+	fluent := taskGroups["fluent_form"]
+	require.Len(t, fluent.Services, 1)
+	assert.Equal(t, types.Service{
+		Name:         "test-db",
+		Command:      "docker run --rm -p 5432:5432 postgres:16",
+		Env:          map[string]string{"POSTGRES_PASSWORD": "test"},
+		ReadyPort:    5432,
+		ReadyTimeout: "1m",
+	}, fluent.Services[0])
+	require.Len(t, fluent.Tasks, 1)
+	assert.Equal(t, []string{"test-db"}, fluent.Tasks[0].Services)
+
+	tableForm := taskGroups["table_form"]
+	require.Len(t, tableForm.Services, 1)
+	assert.Equal(t, "forward", tableForm.Services[0].Name)
+	assert.Equal(t, "curl -sf localhost:8080", tableForm.Services[0].ReadyCommand)
+	require.Len(t, tableForm.Tasks, 1)
+	assert.Equal(t, []string{"forward"}, tableForm.Tasks[0].Services)
+}
@@ -39,6 +39,9 @@ type WorkflowBuilder struct {
 	metadata    map[string]interface{}
 	onComplete  *lua.LFunction
 	onStart     *lua.LFunction
+	delegateTo  lua.LValue // Default delegate_to for tasks that set none
+	services    lua.LValue // Services kept running while the tasks run
+	strictTypes bool       // Modules reject option values they would coerce
 }
 
 // TaskBuilder provides fluent API for task construction
@@ -57,12 +60,16 @@ type TaskDefinition struct {
 	Category        string                 `json:"category"`
 	Workdir         string                 `json:"workdir"` // ✅ Added workdir field
 	User            string                 `json:"user"`    // ✅ User to run the task as (default: root)
+	Workspace       []string               `json:"workspace"` // Paths sent along when delegated
+	WorkspaceDiff   bool                   `json:"workspace_diff"` // Record what a delegated run changed in the workspace
+	Runtime         *lua.LTable            `json:"-"`              // Environment the commands run in, from runtime.nix_shell
 
 	// Execution properties
 	Command         interface{}            `json:"command"`
 	Dependencies    []Dependency           `json:"dependencies"`
 	Parameters      map[string]Parameter   `json:"parameters"`
 	Environment     map[string]string      `json:"environment"`
+	Services        []string               `json:"services"`
 
 	// Behavior
 	Async           bool                   `json:"async"`
@@ -211,6 +218,7 @@ func (m *ModernDSL) RegisterModernDSL(L *lua.LState) {
 	// Core DSL functions
 	m.registerTaskDefinition(L)
 	m.registerWorkflowDefinition(L)
+	m.registerServiceDefinition(L)
 	m.registerBuilders(L)
 	m.registerUtilities(L)
 	m.registerValidators(L)
@@ -285,6 +293,24 @@ func (m *ModernDSL) taskBuilderIndex(L *lua.LState) int {
 			L.Push(ud) // Return self for chaining
 			return 1
 		}))
+	case "workspace":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			builder.definition.Workspace = workspacePaths(L.CheckAny(2))
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	case "workspace_diff":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			builder.definition.WorkspaceDiff = L.OptBool(2, true)
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	case "runtime":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			builder.definition.Runtime = L.CheckTable(2)
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
 	case "user":
 		L.Push(L.NewFunction(func(L *lua.LState) int {
 			userName := L.CheckString(2) // Argument position 2 (1 is self)
@@ -324,6 +350,12 @@ func (m *ModernDSL) taskBuilderIndex(L *lua.LState) int {
 				Command: hook,
 			})
 			
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	case "services":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			builder.definition.Services = serviceNames(L.CheckAny(2))
 			L.Push(ud) // Return self for chaining
 			return 1
 		}))
@@ -341,8 +373,24 @@ func (m *ModernDSL) taskBuilderIndex(L *lua.LState) int {
 		}))
 	case "retries":
 		L.Push(L.NewFunction(func(L *lua.LState) int {
-			_ = L.CheckAny(2) // retries config - simplified for now
-			_ = L.CheckAny(3) // strategy - simplified for now
+			builder.definition.Retries.MaxAttempts = L.CheckInt(2) + 1
+			builder.definition.Retries.Backoff = L.OptString(3, "")
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	case "retry_delay":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			delay, err := time.ParseDuration(L.CheckString(2))
+			if err != nil {
+				L.ArgError(2, err.Error())
+			}
+			builder.definition.Retries.Delay = delay
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	case "retry_on":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			builder.definition.Retries.On = stringList(L.CheckAny(2))
 			L.Push(ud) // Return self for chaining
 			return 1
 		}))
@@ -450,6 +498,21 @@ func (m *ModernDSL) taskBuilderIndex(L *lua.LState) int {
 			if builder.definition.Delegation.Agent != "" {
 				taskTable.RawSetString("delegate_to", lua.LString(builder.definition.Delegation.Agent))
 			}
+
+			if len(builder.definition.Services) > 0 {
+				taskTable.RawSetString("services", stringListToLua(L, builder.definition.Services))
+			}
+
+			if len(builder.definition.Workspace) > 0 {
+				taskTable.RawSetString("workspace", stringListToLua(L, builder.definition.Workspace))
+			}
+			if builder.definition.WorkspaceDiff {
+				taskTable.RawSetString("workspace_diff", lua.LTrue)
+			}
+			if builder.definition.Runtime != nil {
+				taskTable.RawSetString("runtime", builder.definition.Runtime)
+			}
+			builder.definition.Retries.setFields(L, taskTable)
 			
 			// NEW BEHAVIOR: Tasks are only registered globally for workflows
 			// They are NOT added to any group automatically
@@ -551,6 +614,29 @@ func (m *ModernDSL) workflowBuilderIndex(L *lua.LState) int {
 			
 			return 0 // Don't return anything to end the chain
 		}))
+	case "delegate_to":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			target := L.CheckAny(2) // Argument position 2 (1 is self)
+			if target.Type() != lua.LTString && target.Type() != lua.LTTable {
+				L.ArgError(2, "delegate_to expects an agent name or a list of agents")
+				return 0
+			}
+			builder.delegateTo = target
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	case "services":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			builder.services = L.CheckTable(2) // Argument position 2 (1 is self)
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	case "strict_types":
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			builder.strictTypes = L.OptBool(2, true) // Argument position 2 (1 is self)
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
 	case "on_start":
 		L.Push(L.NewFunction(func(L *lua.LState) int {
 			onStartFunc := L.CheckFunction(2) // Argument position 2 (1 is self)
@@ -586,6 +672,20 @@ func (m *ModernDSL) buildAndRegisterWorkflow(L *lua.LState, builder *WorkflowBui
 		workflowTable.RawSetString("version", lua.LString(builder.version))
 	}
 
+	// Set default delegate_to for all tasks
+	if builder.delegateTo != nil {
+		workflowTable.RawSetString("delegate_to", builder.delegateTo)
+	}
+
+	// Set services
+	if builder.services != nil {
+		workflowTable.RawSetString("services", builder.services)
+	}
+
+	if builder.strictTypes {
+		workflowTable.RawSetString("strict_types", lua.LTrue)
+	}
+
 	// Convert Modern DSL tasks to workflow format
 	if len(builder.tasks) > 0 {
 		tasksTable := L.NewTable()
@@ -623,6 +723,23 @@ func (m *ModernDSL) buildAndRegisterWorkflow(L *lua.LState, builder *WorkflowBui
 				taskTable.RawSetString("delegate_to", lua.LString(taskDef.Delegation.Agent))
 			}
 
+			// Convert services
+			if len(taskDef.Services) > 0 {
+				taskTable.RawSetString("services", stringListToLua(L, taskDef.Services))
+			}
+
+			// Convert workspace
+			if len(taskDef.Workspace) > 0 {
+				taskTable.RawSetString("workspace", stringListToLua(L, taskDef.Workspace))
+			}
+			if taskDef.WorkspaceDiff {
+				taskTable.RawSetString("workspace_diff", lua.LTrue)
+			}
+			if taskDef.Runtime != nil {
+				taskTable.RawSetString("runtime", taskDef.Runtime)
+			}
+			taskDef.Retries.setFields(L, taskTable)
+
 			// Convert hooks
 			if len(taskDef.OnSuccess) > 0 {
 				if hook := taskDef.OnSuccess[0]; hook.Command != nil {
@@ -677,8 +794,17 @@ func (m *ModernDSL) buildAndRegisterWorkflow(L *lua.LState, builder *WorkflowBui
 }
 
 func (m *ModernDSL) registerTaskDefinition(L *lua.LState) {
-	// task() - main task builder function
-	L.SetGlobal("task", L.NewFunction(m.taskBuilderFunc))
+	// task() - main task builder function, also the namespace of the
+	// functions tasks call while they run
+	taskMt := L.NewTable()
+	L.SetField(taskMt, "set_output", L.NewFunction(taskSetOutput))
+	taskMetaTable := L.NewTable()
+	L.SetField(taskMetaTable, "__call", L.NewFunction(func(L *lua.LState) int {
+		L.Remove(1) // the task table itself
+		return m.taskBuilderFunc(L)
+	}))
+	L.SetMetatable(taskMt, taskMetaTable)
+	L.SetGlobal("task", taskMt)
 	
 	// chain() - sequential task chain
 	L.SetGlobal("chain", L.NewFunction(m.chainBuilderFunc))
@@ -711,11 +837,7 @@ func (m *ModernDSL) registerWorkflowDefinition(L *lua.LState) {
 
 func (m *ModernDSL) registerBuilders(L *lua.LState) {
 	// async namespace
-	asyncMt := L.NewTable()
-	L.SetField(asyncMt, "parallel", L.NewFunction(m.asyncParallelFunc))
-	L.SetField(asyncMt, "sequence", L.NewFunction(m.asyncSequenceFunc))
-	L.SetField(asyncMt, "timeout", L.NewFunction(m.asyncTimeoutFunc))
-	L.SetGlobal("async", asyncMt)
+	RegisterAsyncModule(L)
 	
 	// perf namespace for performance monitoring
 	perfMt := L.NewTable()
@@ -886,6 +1008,23 @@ type RetryConfig struct {
 	Delay       time.Duration `json:"delay"`
 	Backoff     string        `json:"backoff"`
 	Jitter      bool          `json:"jitter"`
+	On          []string      `json:"on"` // Error kinds retried, all when empty
+}
+
+// setFields sets the retry fields of a task table
+func (r RetryConfig) setFields(L *lua.LState, taskTable *lua.LTable) {
+	if r.MaxAttempts > 1 {
+		taskTable.RawSetString("retries", lua.LNumber(r.MaxAttempts-1))
+	}
+	if r.Delay > 0 {
+		taskTable.RawSetString("retry_delay", lua.LString(r.Delay.String()))
+	}
+	if r.Backoff != "" {
+		taskTable.RawSetString("retry_backoff", lua.LString(r.Backoff))
+	}
+	if len(r.On) > 0 {
+		taskTable.RawSetString("retry_on", stringListToLua(L, r.On))
+	}
 }
 
 type ResourceRequirements struct {
@@ -1014,9 +1153,6 @@ func (m *ModernDSL) workflowDefineFunc(L *lua.LState) int {
 func (m *ModernDSL) workflowParallelFunc(L *lua.LState) int    { return 0 }
 func (m *ModernDSL) workflowSequenceFunc(L *lua.LState) int    { return 0 }
 func (m *ModernDSL) workflowConditionalFunc(L *lua.LState) int { return 0 }
-func (m *ModernDSL) asyncParallelFunc(L *lua.LState) int       { return 0 }
-func (m *ModernDSL) asyncSequenceFunc(L *lua.LState) int       { return 0 }
-func (m *ModernDSL) asyncTimeoutFunc(L *lua.LState) int        { return 0 }
 func (m *ModernDSL) perfMeasureFunc(L *lua.LState) int         { return 0 }
 func (m *ModernDSL) perfStatsFunc(L *lua.LState) int           { return 0 }
 func (m *ModernDSL) coreStatsFunc(L *lua.LState) int           { return 0 }
@@ -456,3 +456,63 @@ func TestMultipleTaskGroups(t *testing.T) {
 		t.Fatalf("Failed to parse multiple task groups: %v", err)
 	}
 }
+
+func TestTaskBuilderWithWorkspace(t *testing.T) {
+	L, _ := setupTestDSL(t)
+	defer L.Close()
+
+	err := L.DoString(`
+		task("deploy")
+			:workspace({"scripts/", "configs/app.yaml"})
+			:workspace_diff()
+			:build()
+		table_task = {name = "check", workspace = "scripts/"}
+	`)
+	if err != nil {
+		t.Fatalf("Error executing Lua code: %v", err)
+	}
+
+	built := parseLuaTask(L, L.GetGlobal("__task_deploy").(*lua.LTable))
+	if len(built.Workspace) != 2 || built.Workspace[0] != "scripts/" || built.Workspace[1] != "configs/app.yaml" {
+		t.Errorf("Expected the builder's workspace paths, got %v", built.Workspace)
+	}
+	if !built.WorkspaceDiff {
+		t.Error("Expected workspace_diff to be set by the builder")
+	}
+	table := parseLuaTask(L, L.GetGlobal("table_task").(*lua.LTable))
+	if len(table.Workspace) != 1 || table.Workspace[0] != "scripts/" {
+		t.Errorf("Expected a single workspace path, got %v", table.Workspace)
+	}
+	if table.WorkspaceDiff {
+		t.Error("Expected workspace_diff to be off by default")
+	}
+}
+
+func TestTaskBuilderWithRuntime(t *testing.T) {
+	L, _ := setupTestDSL(t)
+	defer L.Close()
+	OpenRuntime(L)
+
+	err := L.DoString(`
+		task("test")
+			:runtime(runtime.nix_shell{packages = {"nodejs_20", "python312"}})
+			:build()
+		table_task = {name = "lint", runtime = runtime.nix_shell{flake = ".#ci"}}
+	`)
+	if err != nil {
+		t.Fatalf("Error executing Lua code: %v", err)
+	}
+
+	built := parseLuaTask(L, L.GetGlobal("__task_test").(*lua.LTable))
+	if built.NixShell == nil || len(built.NixShell.Packages) != 2 || built.NixShell.Packages[1] != "python312" {
+		t.Errorf("Expected the builder's nix_shell packages, got %+v", built.NixShell)
+	}
+	table := parseLuaTask(L, L.GetGlobal("table_task").(*lua.LTable))
+	if table.NixShell == nil || table.NixShell.Flake != ".#ci" {
+		t.Errorf("Expected the flake devShell, got %+v", table.NixShell)
+	}
+
+	if err := L.DoString(`runtime.nix_shell{packages = {"go"}, flake = "."}`); err == nil {
+		t.Error("Expected an error for a nix_shell with packages and a flake")
+	}
+}
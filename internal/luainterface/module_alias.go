@@ -0,0 +1,119 @@
+package luainterface
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ModuleAlias is a legacy global name that still resolves to a module
+// registered under its canonical name
+type ModuleAlias struct {
+	Alias     string `json:"alias"`
+	Canonical string `json:"canonical"`
+}
+
+// moduleAliases maps legacy global names to canonical module names. Only
+// the canonical name is set as a global; an alias is resolved when a
+// workflow reads it, and the use is recorded as a deprecation so it shows
+// up in the run summary.
+var moduleAliases = map[string]string{
+	"Values":   "values",
+	"k8s":      "kubernetes",
+	"database": "db",
+}
+
+// ModuleAliases returns the legacy aliases sorted by name
+func ModuleAliases() []ModuleAlias {
+	aliases := make([]ModuleAlias, 0, len(moduleAliases))
+	for alias, canonical := range moduleAliases {
+		aliases = append(aliases, ModuleAlias{Alias: alias, Canonical: canonical})
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Alias < aliases[j].Alias
+	})
+	return aliases
+}
+
+// CanonicalModuleName returns the canonical name of a module, resolving
+// legacy aliases, and whether name was an alias
+func CanonicalModuleName(name string) (string, bool) {
+	if canonical, ok := moduleAliases[name]; ok {
+		return canonical, true
+	}
+	return name, false
+}
+
+func aliasDeprecation(alias, canonical string) Deprecation {
+	return Deprecation{
+		Function:  alias,
+		Message:   fmt.Sprintf("%s is a legacy name for the %s module", alias, canonical),
+		Migration: fmt.Sprintf("use %s instead of %s", canonical, alias),
+	}
+}
+
+// OpenModuleAliases makes the legacy aliases resolve through the globals
+// table's __index, so globals set under those names by the workflow itself
+// take precedence
+func OpenModuleAliases(L *lua.LState) {
+	globals := L.G.Global
+	mt, ok := L.GetMetatable(globals).(*lua.LTable)
+	if !ok {
+		mt = L.NewTable()
+		L.SetMetatable(globals, mt)
+	}
+	mt.RawSetString("__index", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(2)
+		canonical, ok := moduleAliases[name]
+		if !ok {
+			L.Push(lua.LNil)
+			return 1
+		}
+		module := globals.RawGetString(canonical)
+		if module != lua.LNil {
+			RecordDeprecation(L, aliasDeprecation(name, canonical))
+		}
+		L.Push(module)
+		return 1
+	}))
+}
+
+var (
+	globalRefRe  = regexp.MustCompile(`(?:^|[^.\w])([A-Za-z_]\w*)\s*[.\[:]`)
+	globalDeclRe = regexp.MustCompile(`^\s*(?:local\s+)?([A-Za-z_]\w*)\s*=[^=]`)
+)
+
+// lintModuleAliases finds uses of legacy module names. Names the workflow
+// declares itself are not reported.
+func lintModuleAliases(lines []string) []LintFinding {
+	declared := make(map[string]bool)
+	for _, line := range lines {
+		if m := globalDeclRe.FindStringSubmatch(stripLuaComment(line)); m != nil {
+			declared[m[1]] = true
+		}
+	}
+
+	var findings []LintFinding
+	for i, line := range lines {
+		seen := make(map[string]bool)
+		for _, m := range globalRefRe.FindAllStringSubmatch(stripLuaComment(line), -1) {
+			alias := m[1]
+			canonical, ok := moduleAliases[alias]
+			if !ok || declared[alias] || seen[alias] {
+				continue
+			}
+			seen[alias] = true
+			dep := aliasDeprecation(alias, canonical)
+			findings = append(findings, LintFinding{
+				Line:       i + 1,
+				Severity:   LintWarning,
+				Function:   alias,
+				Message:    dep.Message,
+				Suggestion: dep.Migration,
+			})
+		}
+	}
+	return findings
+}
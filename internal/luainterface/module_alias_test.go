@@ -0,0 +1,108 @@
+package luainterface
+
+import (
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestModuleAliases_ResolveAndWarn(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	ResetDeprecations()
+	t.Cleanup(ResetDeprecations)
+
+	db := L.NewTable()
+	db.RawSetString("ping", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LTrue)
+		return 1
+	}))
+	L.SetGlobal("db", db)
+	OpenModuleAliases(L)
+
+	err := L.DoString(`
+		assert(database == db, "database should resolve to db")
+		assert(database.ping())
+		assert(k8s == nil, "aliases of unregistered modules are nil")
+		assert(undefined_global == nil)
+	`)
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+
+	warnings := DeprecationWarnings()
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 deprecation, got %+v", warnings)
+	}
+	w := warnings[0]
+	if w.Function != "database" || w.Count != 2 || w.Location == "" {
+		t.Errorf("Unexpected warning: %+v", w)
+	}
+	if w.Migration != "use db instead of database" {
+		t.Errorf("Unexpected migration: %q", w.Migration)
+	}
+}
+
+func TestModuleAliases_WorkflowGlobalTakesPrecedence(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	ResetDeprecations()
+	t.Cleanup(ResetDeprecations)
+
+	L.SetGlobal("db", L.NewTable())
+	OpenModuleAliases(L)
+
+	if err := L.DoString(`
+		database = {name = "mine"}
+		assert(database.name == "mine")
+	`); err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if warnings := DeprecationWarnings(); len(warnings) != 0 {
+		t.Errorf("Expected no deprecations, got %+v", warnings)
+	}
+}
+
+func TestCanonicalModuleName(t *testing.T) {
+	if name, alias := CanonicalModuleName("k8s"); name != "kubernetes" || !alias {
+		t.Errorf("CanonicalModuleName(k8s) = %q, %v", name, alias)
+	}
+	if name, alias := CanonicalModuleName("pkg"); name != "pkg" || alias {
+		t.Errorf("CanonicalModuleName(pkg) = %q, %v", name, alias)
+	}
+	for _, a := range ModuleAliases() {
+		if _, isAlias := moduleAliases[a.Canonical]; isAlias {
+			t.Errorf("alias %s points to another alias %s", a.Alias, a.Canonical)
+		}
+	}
+}
+
+func TestLintModuleAPI_LegacyAliases(t *testing.T) {
+	source := `module_api = 2
+local result = k8s.apply({file = "app.yaml"})
+local port = Values["port"]
+local conn = mydatabase.connect()
+`
+	var got []LintFinding
+	for _, f := range LintModuleAPI(source) {
+		if f.Severity == LintWarning {
+			got = append(got, f)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", got)
+	}
+	if got[0].Line != 2 || got[0].Function != "k8s" || got[0].Suggestion != "use kubernetes instead of k8s" {
+		t.Errorf("Unexpected finding: %+v", got[0])
+	}
+	if got[1].Line != 3 || got[1].Function != "Values" {
+		t.Errorf("Unexpected finding: %+v", got[1])
+	}
+
+	declared := "local k8s = require('my_k8s')\nk8s.apply()\n"
+	for _, f := range LintModuleAPI(declared) {
+		if f.Function == "k8s" {
+			t.Errorf("Declared name reported: %+v", f)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package luainterface
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Module API versions. A workflow selects one with a top-level
+// `module_api = 2`; workflows that don't declare it get version 1.
+//
+//   - v1: legacy conventions, e.g. pkg functions return (ok, value)
+//   - v2: every function returns (result, err), err is nil on success
+const (
+	ModuleAPIV1      = 1
+	ModuleAPIV2      = 2
+	DefaultModuleAPI = ModuleAPIV1
+	LatestModuleAPI  = ModuleAPIV2
+)
+
+// ModuleAPIGlobal is the Lua global a workflow uses to declare its version
+const ModuleAPIGlobal = "module_api"
+
+// ModuleAPIVersion returns the module API version declared by the workflow
+func ModuleAPIVersion(L *lua.LState) int {
+	if n, ok := L.GetGlobal(ModuleAPIGlobal).(lua.LNumber); ok {
+		return int(n)
+	}
+	return DefaultModuleAPI
+}
+
+// Deprecation describes a module function whose convention changed in a
+// later module API version
+type Deprecation struct {
+	// Function is the qualified name, e.g. "pkg.install"
+	Function string
+	// Since is the module API version that introduced the new convention
+	Since     int
+	Message   string
+	Migration string
+}
+
+// legacyOkValue lists functions that return (ok, value) in API v1 and
+// (result, err) from v2 on. Predicates such as pkg.is_installed keep
+// returning a boolean and are not listed.
+var legacyOkValue = map[string][]string{
+	"pkg": {
+		"install", "remove", "update", "upgrade", "search", "info",
+		"list", "clean", "autoremove", "deps", "install_local",
+	},
+}
+
+// ModuleDeprecations returns all known deprecations keyed by function
+func ModuleDeprecations() map[string]Deprecation {
+	deps := make(map[string]Deprecation)
+	for module, funcs := range legacyOkValue {
+		for _, fn := range funcs {
+			name := module + "." + fn
+			deps[name] = okValueDeprecation(name)
+		}
+	}
+	return deps
+}
+
+func okValueDeprecation(name string) Deprecation {
+	return Deprecation{
+		Function: name,
+		Since:    ModuleAPIV2,
+		Message:  fmt.Sprintf("%s returns (ok, value); this convention is deprecated", name),
+		Migration: fmt.Sprintf("declare module_api = %d and use `local result, err = %s(...)`, checking `err ~= nil` instead of `not ok`",
+			ModuleAPIV2, name),
+	}
+}
+
+// withModuleAPIShims wraps a module's exports so legacy functions follow
+// the convention of the module API version the workflow declared
+func withModuleAPIShims(module string, exports map[string]lua.LGFunction) map[string]lua.LGFunction {
+	for _, name := range legacyOkValue[module] {
+		if fn, ok := exports[name]; ok {
+			exports[name] = okValueShim(okValueDeprecation(module+"."+name), fn)
+		}
+	}
+	return exports
+}
+
+// okValueShim adapts a function returning (ok, value). Under API v1 the
+// result is passed through and a deprecation is recorded; from v2 on it
+// is converted to (value, nil) or (nil, value).
+func okValueShim(dep Deprecation, fn lua.LGFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		version := ModuleAPIVersion(L)
+		if version > LatestModuleAPI {
+			L.RaiseError("module_api %d is not supported (latest is %d)", version, LatestModuleAPI)
+			return 0
+		}
+
+		n := fn(L)
+		if version < dep.Since {
+			RecordDeprecation(L, dep)
+			return n
+		}
+		if n != 2 {
+			return n
+		}
+
+		ok := L.Get(-2)
+		value := L.Get(-1)
+		L.Pop(2)
+		if lua.LVAsBool(ok) {
+			L.Push(value)
+			L.Push(lua.LNil)
+		} else {
+			L.Push(lua.LNil)
+			L.Push(value)
+		}
+		return 2
+	}
+}
+
+// DeprecationWarning is a deprecated call made during a run
+type DeprecationWarning struct {
+	Function  string `json:"function"`
+	Message   string `json:"message"`
+	Migration string `json:"migration"`
+	// Location is where the function was first called from
+	Location string `json:"location,omitempty"`
+	Count    int    `json:"count"`
+}
+
+var (
+	deprecationsMu    sync.Mutex
+	deprecationsOrder []string
+	deprecations      = make(map[string]*DeprecationWarning)
+)
+
+// RecordDeprecation notes a call to a deprecated function
+func RecordDeprecation(L *lua.LState, dep Deprecation) {
+	deprecationsMu.Lock()
+	defer deprecationsMu.Unlock()
+
+	if w, ok := deprecations[dep.Function]; ok {
+		w.Count++
+		return
+	}
+	deprecations[dep.Function] = &DeprecationWarning{
+		Function:  dep.Function,
+		Message:   dep.Message,
+		Migration: dep.Migration,
+		Location:  strings.TrimSuffix(L.Where(1), ":"),
+		Count:     1,
+	}
+	deprecationsOrder = append(deprecationsOrder, dep.Function)
+}
+
+// DeprecationWarnings returns the deprecations collected since the last reset
+func DeprecationWarnings() []DeprecationWarning {
+	deprecationsMu.Lock()
+	defer deprecationsMu.Unlock()
+
+	warnings := make([]DeprecationWarning, 0, len(deprecationsOrder))
+	for _, name := range deprecationsOrder {
+		warnings = append(warnings, *deprecations[name])
+	}
+	return warnings
+}
+
+// ResetDeprecations clears collected deprecations, at the start of a run
+func ResetDeprecations() {
+	deprecationsMu.Lock()
+	defer deprecationsMu.Unlock()
+
+	deprecationsOrder = nil
+	deprecations = make(map[string]*DeprecationWarning)
+}
@@ -0,0 +1,155 @@
+package luainterface
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lint finding severities
+const (
+	LintInfo    = "info"
+	LintWarning = "warning"
+	LintError   = "error"
+)
+
+// LintFinding is a module API issue found in a workflow
+type LintFinding struct {
+	Line       int    `json:"line,omitempty"`
+	Severity   string `json:"severity"`
+	Function   string `json:"function,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+var (
+	moduleAPIDeclRe = regexp.MustCompile(`^\s*` + ModuleAPIGlobal + `\s*=\s*(\S+)`)
+	moduleCallRe    = regexp.MustCompile(`\b([a-z_][a-z0-9_]*)\.([a-z_][a-z0-9_]*)\s*\(`)
+	okValueAssignRe = regexp.MustCompile(`^\s*(?:local\s+)?([A-Za-z_]\w*)\s*,\s*([A-Za-z_]\w*)\s*=\s*$`)
+)
+
+// LintModuleAPI checks a workflow's source for its module API declaration,
+// for calls whose convention depends on it and for legacy module names,
+// suggesting migrations
+func LintModuleAPI(source string) []LintFinding {
+	var findings []LintFinding
+	lines := strings.Split(source, "\n")
+
+	version, declLine := DefaultModuleAPI, 0
+	for i, line := range lines {
+		m := moduleAPIDeclRe.FindStringSubmatch(stripLuaComment(line))
+		if m == nil {
+			continue
+		}
+		declLine = i + 1
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < ModuleAPIV1 {
+			findings = append(findings, LintFinding{
+				Line:     declLine,
+				Severity: LintError,
+				Message:  fmt.Sprintf("invalid module_api value %q", m[1]),
+			})
+			continue
+		}
+		if n > LatestModuleAPI {
+			findings = append(findings, LintFinding{
+				Line:     declLine,
+				Severity: LintError,
+				Message:  fmt.Sprintf("module_api %d is not supported (latest is %d)", n, LatestModuleAPI),
+			})
+			continue
+		}
+		version = n
+		break
+	}
+
+	deprecated := ModuleDeprecations()
+	usesDeprecated := false
+
+	for i, line := range lines {
+		code := stripLuaComment(line)
+		for _, m := range moduleCallRe.FindAllStringSubmatchIndex(code, -1) {
+			name := code[m[2]:m[3]] + "." + code[m[4]:m[5]]
+			dep, ok := deprecated[name]
+			if !ok {
+				continue
+			}
+
+			assign := okValueAssignRe.FindStringSubmatch(code[:m[0]])
+			if version < dep.Since {
+				usesDeprecated = true
+				f := LintFinding{
+					Line:       i + 1,
+					Severity:   LintWarning,
+					Function:   name,
+					Message:    dep.Message,
+					Suggestion: dep.Migration,
+				}
+				if assign != nil {
+					f.Suggestion = fmt.Sprintf("with module_api = %d write `local %s, err = %s(...)` and replace checks of `%s` with `err == nil`",
+						dep.Since, assign[2], name, assign[1])
+				}
+				findings = append(findings, f)
+			} else if assign != nil && looksLikeOkName(assign[1]) {
+				findings = append(findings, LintFinding{
+					Line:       i + 1,
+					Severity:   LintWarning,
+					Function:   name,
+					Message:    fmt.Sprintf("under module_api %d %s returns (result, err), but the first value is named %q", version, name, assign[1]),
+					Suggestion: fmt.Sprintf("write `local %s, err = %s(...)`", assign[2], name),
+				})
+			}
+		}
+	}
+
+	findings = append(findings, lintModuleAliases(lines)...)
+
+	if declLine == 0 {
+		f := LintFinding{
+			Severity: LintInfo,
+			Message:  fmt.Sprintf("no module_api declared, module API %d is used", DefaultModuleAPI),
+		}
+		if usesDeprecated {
+			f.Suggestion = fmt.Sprintf("migrate the calls below and add `module_api = %d` at the top of the workflow", LatestModuleAPI)
+		} else {
+			f.Suggestion = fmt.Sprintf("add `module_api = %d` at the top of the workflow", LatestModuleAPI)
+		}
+		findings = append([]LintFinding{f}, findings...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+func looksLikeOkName(name string) bool {
+	switch strings.ToLower(name) {
+	case "ok", "success", "succeeded", "status":
+		return true
+	}
+	return false
+}
+
+// stripLuaComment drops a trailing `--` comment, ignoring string contents
+func stripLuaComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '-' && i+1 < len(line) && line[i+1] == '-':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,138 @@
+package luainterface
+
+import (
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// newShimmedState registers a fake pkg module whose install succeeds for
+// "nginx" and fails otherwise, using the v1 (ok, value) convention
+func newShimmedState(t *testing.T) *lua.LState {
+	t.Helper()
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	ResetDeprecations()
+	t.Cleanup(ResetDeprecations)
+
+	exports := withModuleAPIShims("pkg", map[string]lua.LGFunction{
+		"install": func(L *lua.LState) int {
+			if L.CheckString(1) == "nginx" {
+				L.Push(lua.LTrue)
+				L.Push(lua.LString("installed"))
+			} else {
+				L.Push(lua.LFalse)
+				L.Push(lua.LString("no such package"))
+			}
+			return 2
+		},
+		"is_installed": func(L *lua.LState) int {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString("Package not installed"))
+			return 2
+		},
+	})
+	L.SetGlobal("pkg", L.SetFuncs(L.NewTable(), exports))
+	return L
+}
+
+func TestModuleAPI_V1KeepsLegacyConventionAndWarns(t *testing.T) {
+	L := newShimmedState(t)
+
+	err := L.DoString(`
+		local ok, out = pkg.install("nginx")
+		assert(ok == true and out == "installed")
+		local ok2, msg = pkg.install("missing")
+		assert(ok2 == false and msg == "no such package")
+		pkg.is_installed("nginx")
+	`)
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+
+	warnings := DeprecationWarnings()
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 deprecation, got %+v", warnings)
+	}
+	if warnings[0].Function != "pkg.install" || warnings[0].Count != 2 || warnings[0].Location == "" {
+		t.Errorf("Unexpected warning: %+v", warnings[0])
+	}
+}
+
+func TestModuleAPI_V2ReturnsResultErr(t *testing.T) {
+	L := newShimmedState(t)
+
+	err := L.DoString(`
+		module_api = 2
+		local out, err = pkg.install("nginx")
+		assert(out == "installed" and err == nil)
+		local res, err2 = pkg.install("missing")
+		assert(res == nil and err2 == "no such package")
+	`)
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if len(DeprecationWarnings()) != 0 {
+		t.Error("Expected no deprecations under module_api 2")
+	}
+}
+
+func TestModuleAPI_UnsupportedVersion(t *testing.T) {
+	L := newShimmedState(t)
+
+	err := L.DoString(`
+		module_api = 99
+		pkg.install("nginx")
+	`)
+	if err == nil || !strings.Contains(err.Error(), "module_api 99 is not supported") {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+}
+
+func TestLintModuleAPI(t *testing.T) {
+	t.Run("legacy workflow", func(t *testing.T) {
+		findings := LintModuleAPI(`
+local ok, out = pkg.install({packages = {"nginx"}})
+-- pkg.remove({packages = {"x"}})
+pkg.update({})
+`)
+		if len(findings) != 3 {
+			t.Fatalf("Expected 3 findings, got %+v", findings)
+		}
+		if findings[0].Severity != LintInfo || !strings.Contains(findings[0].Suggestion, "module_api = 2") {
+			t.Errorf("Expected missing declaration info first, got %+v", findings[0])
+		}
+		if findings[1].Line != 2 || !strings.Contains(findings[1].Suggestion, "local out, err = pkg.install(...)") {
+			t.Errorf("Unexpected install finding: %+v", findings[1])
+		}
+		if findings[2].Function != "pkg.update" {
+			t.Errorf("Unexpected update finding: %+v", findings[2])
+		}
+	})
+
+	t.Run("migrated workflow", func(t *testing.T) {
+		findings := LintModuleAPI(`module_api = 2
+local out, err = pkg.install({packages = {"nginx"}})
+`)
+		if len(findings) != 0 {
+			t.Errorf("Expected no findings, got %+v", findings)
+		}
+	})
+
+	t.Run("half migrated workflow", func(t *testing.T) {
+		findings := LintModuleAPI(`module_api = 2
+local ok, out = pkg.install({packages = {"nginx"}})
+`)
+		if len(findings) != 1 || findings[0].Line != 2 || !strings.Contains(findings[0].Message, `named "ok"`) {
+			t.Errorf("Expected misleading ok finding, got %+v", findings)
+		}
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		findings := LintModuleAPI("module_api = 7\n")
+		if len(findings) == 0 || findings[0].Severity != LintError {
+			t.Errorf("Expected error finding, got %+v", findings)
+		}
+	})
+}
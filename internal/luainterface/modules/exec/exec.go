@@ -5,10 +5,13 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
 	"os/exec"
+	"sort"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/nix"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -32,6 +35,27 @@ func Run(L *lua.LState) int {
 		ctx = context.Background()
 	}
 
+	// In check mode commands aren't run, unless the workflow marks them
+	// as only reading with run_in_check_mode
+	if !lua.LVAsBool(opts.RawGetString("run_in_check_mode")) &&
+		taskctx.RecordChange(ctx, taskctx.Change{Module: "exec", Action: "run", Target: commandStr, Unknown: true}) {
+		result := L.NewTable()
+		L.SetField(result, "stdout", lua.LString(""))
+		L.SetField(result, "stderr", lua.LString(""))
+		L.SetField(result, "exit_code", lua.LNumber(0))
+		L.SetField(result, "success", lua.LTrue)
+		L.SetField(result, "skipped", lua.LTrue)
+		L.SetField(result, "check_mode", lua.LTrue)
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	}
+
+	// Tasks delegated to a host without an agent run their commands there
+	if remote := taskctx.RemoteHost(ctx); remote != nil {
+		return runRemote(L, ctx, remote, commandStr, opts)
+	}
+
 	// Check if SSH execution is enabled
 	if IsSSHExecutionEnabled != nil && IsSSHExecutionEnabled() {
 		slog.Debug("executing command via SSH", "source", "lua", "command", commandStr, "profile", GetSSHProfile())
@@ -87,13 +111,27 @@ func Run(L *lua.LState) int {
 	// Local execution
 	slog.Debug("executing command locally", "source", "lua", "command", commandStr)
 
-	cmd := ExecCommand("bash", "-c", commandStr)
-
-	// Start with a minimal, controlled environment
-	cmd.Env = []string{
-		"PATH=/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin", // Set a default PATH
-		"HOME=" + os.Getenv("HOME"),                         // Keep HOME if it exists
+	shell := shellArgs(commandStr)
+	if nixShell := taskctx.NixShell(ctx); nixShell != nil {
+		// Like a missing command, a missing nix fails with exit code 127
+		nixPath, err := nix.Find()
+		if err != nil {
+			result := L.NewTable()
+			L.SetField(result, "stdout", lua.LString(""))
+			L.SetField(result, "stderr", lua.LString("task runs in a nix_shell: "+err.Error()))
+			L.SetField(result, "exit_code", lua.LNumber(127))
+			L.SetField(result, "success", lua.LFalse)
+			L.Push(result)
+			L.Push(lua.LNil)
+			return 2
+		}
+		shell = nixShell.Command(nixPath, shell)
 	}
+	cmd := ExecCommand(shell[0], shell[1:]...)
+
+	// Start with a minimal, controlled environment, plus what the run adds
+	// to it, like the askpass helper of a sudo password
+	cmd.Env = taskctx.Environ(ctx, baseEnv())
 
 	// Set workdir from options
 	if workdir := opts.RawGetString("workdir"); workdir.Type() == lua.LTString {
@@ -122,7 +160,9 @@ func Run(L *lua.LState) int {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	done := taskusage.Attach(ctx, cmd)
 	err := cmd.Run()
+	done()
 
 	stdoutStr := stdout.String()
 	stderrStr := stderr.String()
@@ -153,6 +193,53 @@ func Run(L *lua.LState) int {
 	return 2
 }
 
+// runRemote runs a command on the remote host of the task, in its workdir
+// and with its env exported, and pushes the same result as a local run
+func runRemote(L *lua.LState, ctx context.Context, remote taskctx.Remote, commandStr string, opts *lua.LTable) int {
+	if workdir := opts.RawGetString("workdir"); workdir.Type() == lua.LTString {
+		commandStr = "cd " + shellQuote(workdir.String()) + " && " + commandStr
+	}
+	if envTbl, ok := opts.RawGetString("env").(*lua.LTable); ok {
+		var exports []string
+		envTbl.ForEach(func(key, value lua.LValue) {
+			exports = append(exports, "export "+key.String()+"="+shellQuote(value.String()))
+		})
+		sort.Strings(exports)
+		if len(exports) > 0 {
+			commandStr = strings.Join(exports, "; ") + "; " + commandStr
+		}
+	}
+	slog.Debug("executing command over SSH", "source", "lua", "command", commandStr, "host", remote.Host())
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := remote.Run(ctx, commandStr, &stdout, &stderr)
+	stdoutStr := stdout.String()
+	stderrStr := stderr.String()
+	if err != nil {
+		stderrStr += err.Error()
+	}
+	if stdoutStr != "" {
+		slog.Info(stdoutStr, "source", "lua", "stream", "stdout", "host", remote.Host())
+	}
+	if stderrStr != "" {
+		slog.Warn(stderrStr, "source", "lua", "stream", "stderr", "host", remote.Host())
+	}
+
+	result := L.NewTable()
+	L.SetField(result, "stdout", lua.LString(stdoutStr))
+	L.SetField(result, "stderr", lua.LString(stderrStr))
+	L.SetField(result, "exit_code", lua.LNumber(exitCode))
+	L.SetField(result, "success", lua.LBool(err == nil && exitCode == 0))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// shellQuote quotes s as a single word for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // Loader returns the exec module loader
 func Loader(L *lua.LState) int {
 	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
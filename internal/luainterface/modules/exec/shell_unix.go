@@ -0,0 +1,18 @@
+//go:build !windows
+
+package exec
+
+import "os"
+
+// shellArgs runs a command with bash
+func shellArgs(command string) []string {
+	return []string{"bash", "-c", command}
+}
+
+// baseEnv is the environment commands start with
+func baseEnv() []string {
+	return []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin:/usr/sbin:/sbin", // Set a default PATH
+		"HOME=" + os.Getenv("HOME"),                         // Keep HOME if it exists
+	}
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package exec
+
+import "os"
+
+// shellArgs runs a command with PowerShell
+func shellArgs(command string) []string {
+	return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", command}
+}
+
+// windowsEnv are the variables PowerShell and most programs need to start
+var windowsEnv = []string{
+	"PATH", "PATHEXT", "SystemRoot", "SystemDrive", "windir", "ComSpec",
+	"TEMP", "TMP", "USERPROFILE", "APPDATA", "LOCALAPPDATA", "ProgramData",
+	"ProgramFiles", "ProgramFiles(x86)", "PSModulePath", "COMPUTERNAME",
+}
+
+// baseEnv is the environment commands start with
+func baseEnv() []string {
+	var env []string
+	for _, name := range windowsEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
@@ -1,18 +1,111 @@
 package fs
 
 import (
-	"io"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"os"
 	"path/filepath"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
+// fileSystem is where the module works with files: the local file system,
+// or the remote host of a task delegated over SSH
+type fileSystem interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	AppendFile(path string, data []byte) error
+	Stat(path string) (os.FileInfo, error)
+	ReadDir(path string) ([]os.FileInfo, error)
+	MkdirAll(path string) error
+	Remove(path string) error
+	RemoveAll(path string) error
+}
+
+// files returns the file system the task calling the module works with
+func files(L *lua.LState) fileSystem {
+	if remote := taskctx.RemoteHost(L.Context()); remote != nil {
+		return remote
+	}
+	return localFiles{}
+}
+
+// localFiles is the local file system
+type localFiles struct{}
+
+func (localFiles) ReadFile(path string) ([]byte, error) { return os.ReadFile(path) }
+
+func (localFiles) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+func (localFiles) AppendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (localFiles) Stat(path string) (os.FileInfo, error) { return os.Stat(path) }
+
+func (localFiles) ReadDir(path string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (localFiles) MkdirAll(path string) error  { return os.MkdirAll(path, 0755) }
+func (localFiles) Remove(path string) error    { return os.Remove(path) }
+func (localFiles) RemoveAll(path string) error { return os.RemoveAll(path) }
+
+// planned records the change in the plan when the task runs in check
+// mode, where the file system isn't touched, and pushes true. It reports
+// whether the task runs in check mode.
+func planned(L *lua.LState, change taskctx.Change) bool {
+	if !taskctx.RecordChange(L.Context(), change) {
+		return false
+	}
+	L.Push(lua.LTrue)
+	return true
+}
+
+// planWrite records writing content over the file at path in the plan
+// when the task runs in check mode and pushes true. It reports whether
+// the task runs in check mode. Writes that change nothing aren't recorded.
+func planWrite(L *lua.LState, action, path string, content []byte) bool {
+	if !taskctx.CheckMode(L.Context()) {
+		return false
+	}
+	before, err := files(L).ReadFile(path)
+	if err != nil || !bytes.Equal(before, content) {
+		taskctx.RecordChange(L.Context(), taskctx.FileChange("fs", action, path, before, content))
+	}
+	L.Push(lua.LTrue)
+	return true
+}
+
 // Read reads a file and returns its content
 func Read(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	data, err := os.ReadFile(path)
+	data, err := files(L).ReadFile(path)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
@@ -28,7 +121,10 @@ func Write(L *lua.LState) int {
 	path := L.CheckString(1)
 	content := L.CheckString(2)
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+	if planWrite(L, "write", path, []byte(content)) {
+		return 1
+	}
+	if err := files(L).WriteFile(path, []byte(content), 0644); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(err.Error()))
 		return 2
@@ -43,15 +139,12 @@ func Append(L *lua.LState) int {
 	path := L.CheckString(1)
 	content := L.CheckString(2)
 
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		L.Push(lua.LBool(false))
-		L.Push(lua.LString(err.Error()))
-		return 2
+	if taskctx.CheckMode(L.Context()) {
+		before, _ := files(L).ReadFile(path)
+		planWrite(L, "append", path, append(before, content...))
+		return 1
 	}
-	defer f.Close()
-
-	if _, err := f.WriteString(content); err != nil {
+	if err := files(L).AppendFile(path, []byte(content)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(err.Error()))
 		return 2
@@ -65,7 +158,7 @@ func Append(L *lua.LState) int {
 func Exists(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	_, err := os.Stat(path)
+	_, err := files(L).Stat(path)
 	if os.IsNotExist(err) {
 		L.Push(lua.LBool(false))
 	} else if err != nil {
@@ -82,7 +175,10 @@ func Exists(L *lua.LState) int {
 func Mkdir(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	if err := os.MkdirAll(path, 0755); err != nil {
+	if _, err := files(L).Stat(path); os.IsNotExist(err) && planned(L, taskctx.Change{Module: "fs", Action: "mkdir", Target: path}) {
+		return 1
+	}
+	if err := files(L).MkdirAll(path); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(err.Error()))
 		return 2
@@ -96,7 +192,10 @@ func Mkdir(L *lua.LState) int {
 func Rm(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	if err := os.Remove(path); err != nil {
+	if planned(L, taskctx.Change{Module: "fs", Action: "rm", Target: path}) {
+		return 1
+	}
+	if err := files(L).Remove(path); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(err.Error()))
 		return 2
@@ -110,7 +209,10 @@ func Rm(L *lua.LState) int {
 func RmR(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	if err := os.RemoveAll(path); err != nil {
+	if planned(L, taskctx.Change{Module: "fs", Action: "rmr", Target: path}) {
+		return 1
+	}
+	if err := files(L).RemoveAll(path); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(err.Error()))
 		return 2
@@ -124,7 +226,7 @@ func RmR(L *lua.LState) int {
 func Ls(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	entries, err := os.ReadDir(path)
+	entries, err := files(L).ReadDir(path)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
@@ -147,6 +249,14 @@ func TmpName(L *lua.LState) int {
 		prefix = L.CheckString(1)
 	}
 
+	// Remote hosts get a name in their /tmp, which isn't created either
+	if taskctx.RemoteHost(L.Context()) != nil {
+		suffix := make([]byte, 6)
+		rand.Read(suffix)
+		L.Push(lua.LString("/tmp/" + prefix + hex.EncodeToString(suffix)))
+		return 1
+	}
+
 	f, err := os.CreateTemp("", prefix+"*")
 	if err != nil {
 		L.Push(lua.LNil)
@@ -165,7 +275,7 @@ func TmpName(L *lua.LState) int {
 func Size(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	info, err := os.Stat(path)
+	info, err := files(L).Stat(path)
 	if err != nil {
 		L.Push(lua.LNumber(0))
 		L.Push(lua.LString(err.Error()))
@@ -181,23 +291,16 @@ func Copy(L *lua.LState) int {
 	src := L.CheckString(1)
 	dst := L.CheckString(2)
 
-	source, err := os.Open(src)
+	content, err := files(L).ReadFile(src)
 	if err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(err.Error()))
 		return 2
 	}
-	defer source.Close()
-
-	destination, err := os.Create(dst)
-	if err != nil {
-		L.Push(lua.LBool(false))
-		L.Push(lua.LString(err.Error()))
-		return 2
+	if planWrite(L, "copy", dst, content) {
+		return 1
 	}
-	defer destination.Close()
-
-	if _, err := io.Copy(destination, source); err != nil {
+	if err := files(L).WriteFile(dst, content, 0644); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(err.Error()))
 		return 2
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -36,10 +37,10 @@ func Debug(L *lua.LState) int {
 	return 0
 }
 
-// Print prints directly to stdout without formatting
+// Print prints to the task's live output without formatting
 func Print(L *lua.LState) int {
 	message := L.CheckString(1)
-	fmt.Println(message)
+	fmt.Fprintln(taskctx.Output(L.Context()), message)
 	return 0
 }
 
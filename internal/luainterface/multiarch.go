@@ -0,0 +1,355 @@
+package luainterface
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/blobstore"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// defaultMultiarchArches are the architectures built when none are given
+var defaultMultiarchArches = []string{"amd64", "arm64"}
+
+// multiarchTaskFields are the fields of multiarch.tasks passed on to the
+// build tasks as they are
+var multiarchTaskFields = []string{"workdir", "workspace", "user", "timeout", "retries"}
+
+// putMultiarchBlob uploads a file to the master's blob store, replaced in
+// tests
+var putMultiarchBlob = func(ctx context.Context, path string) (string, int64, error) {
+	client := blobstore.GetGlobalClient()
+	if client == nil {
+		return "", 0, fmt.Errorf("blob store is not configured on this agent (start it with --blob-endpoint)")
+	}
+	return client.PutFile(ctx, path)
+}
+
+// multiarchSpec is a build fanned out to agents of several architectures
+type multiarchSpec struct {
+	name     string
+	arches   []string
+	artifact string
+	agents   []string
+	build    *lua.LFunction
+	fields   map[string]lua.LValue
+}
+
+// multiarchManifest lists the artifacts of a multi-arch build. It is kept
+// in the blob store next to them.
+type multiarchManifest struct {
+	Name      string              `json:"name"`
+	Artifacts []multiarchArtifact `json:"artifacts"`
+}
+
+// multiarchArtifact is the artifact built for one architecture
+type multiarchArtifact struct {
+	Arch   string `json:"arch"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	Agent  string `json:"agent,omitempty"`
+}
+
+// RegisterMultiarchModule registers the multiarch module, which fans a
+// build out to agents of several architectures:
+//
+//	local tasks = multiarch.tasks{
+//	    name = "app",
+//	    arches = {"amd64", "arm64"},
+//	    artifact = "dist/app-{arch}.tar",
+//	    build = function(this, params)
+//	        exec.run("make dist ARCH=" .. params.arch)
+//	        return true
+//	    end,
+//	}
+//
+// It returns the tasks of the build for workflow.define: app-amd64 and
+// app-arm64 run on agents picked by their architecture fact and upload
+// their artifact to the blob store, and app-manifest stores a manifest of
+// the artifacts and outputs their digests for later tasks.
+func RegisterMultiarchModule(L *lua.LState) {
+	multiarchTable := L.NewTable()
+	L.SetField(multiarchTable, "tasks", L.NewFunction(luaMultiarchTasks))
+	L.SetGlobal("multiarch", multiarchTable)
+}
+
+func luaMultiarchTasks(L *lua.LState) int {
+	spec, err := newMultiarchSpec(L.CheckTable(1))
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	L.Push(spec.tasks(L))
+	return 1
+}
+
+func newMultiarchSpec(opts *lua.LTable) (*multiarchSpec, error) {
+	spec := &multiarchSpec{
+		name:     optString(opts, "name"),
+		artifact: optString(opts, "artifact"),
+		fields:   make(map[string]lua.LValue),
+	}
+	if spec.name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if spec.artifact == "" {
+		return nil, fmt.Errorf("artifact is required")
+	}
+
+	var err error
+	if spec.arches, err = multiarchNames(opts, "arches"); err != nil {
+		return nil, err
+	}
+	if len(spec.arches) == 0 {
+		spec.arches = defaultMultiarchArches
+	}
+	if len(spec.arches) > 1 && !strings.Contains(spec.artifact, "{arch}") {
+		return nil, fmt.Errorf("artifact must contain {arch} when building for several architectures")
+	}
+	if spec.agents, err = multiarchNames(opts, "agents"); err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	if spec.build, ok = opts.RawGetString("build").(*lua.LFunction); !ok {
+		return nil, fmt.Errorf("build function is required")
+	}
+	for _, field := range multiarchTaskFields {
+		if v := opts.RawGetString(field); v != lua.LNil {
+			spec.fields[field] = v
+		}
+	}
+	return spec, nil
+}
+
+// multiarchNames reads a list of names, nil when it is not set
+func multiarchNames(opts *lua.LTable, field string) ([]string, error) {
+	switch v := opts.RawGetString(field).(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case *lua.LTable:
+		var names []string
+		for i := 1; i <= v.Len(); i++ {
+			name, ok := v.RawGetInt(i).(lua.LString)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("%s must hold names", field)
+			}
+			names = append(names, string(name))
+		}
+		return names, nil
+	default:
+		return nil, fmt.Errorf("%s must be a list of names", field)
+	}
+}
+
+// taskName returns the name of the build task for arch
+func (s *multiarchSpec) taskName(arch string) string {
+	return s.name + "-" + arch
+}
+
+// manifestTaskName returns the name of the task storing the manifest
+func (s *multiarchSpec) manifestTaskName() string {
+	return s.name + "-manifest"
+}
+
+// tasks returns the build task of each architecture followed by the
+// manifest task
+func (s *multiarchSpec) tasks(L *lua.LState) *lua.LTable {
+	tasks := L.NewTable()
+	dependsOn := L.NewTable()
+	for _, arch := range s.arches {
+		task := L.NewTable()
+		task.RawSetString("name", lua.LString(s.taskName(arch)))
+		task.RawSetString("description", lua.LString(fmt.Sprintf("Build %s for %s", s.name, arch)))
+		for field, v := range s.fields {
+			task.RawSetString(field, v)
+		}
+		params := L.NewTable()
+		params.RawSetString("arch", lua.LString(arch))
+		task.RawSetString("params", params)
+		task.RawSetString("delegate_to", s.delegateTo(L, arch))
+		task.RawSetString("command", L.NewFunction(s.buildCommand(arch)))
+		tasks.Append(task)
+		dependsOn.Append(lua.LString(s.taskName(arch)))
+	}
+
+	// The manifest goes to the blob store, so it is stored from an agent
+	manifest := L.NewTable()
+	manifest.RawSetString("name", lua.LString(s.manifestTaskName()))
+	manifest.RawSetString("description", lua.LString(fmt.Sprintf("Store the manifest of %s", s.name)))
+	manifest.RawSetString("depends_on", dependsOn)
+	manifest.RawSetString("delegate_to", s.delegateTo(L, s.arches[0]))
+	manifest.RawSetString("command", L.NewFunction(s.manifestCommand))
+	tasks.Append(manifest)
+	return tasks
+}
+
+// delegateTo returns the delegate_to of a task picking an agent of arch
+func (s *multiarchSpec) delegateTo(L *lua.LState, arch string) *lua.LTable {
+	facts := L.NewTable()
+	facts.RawSetString("architecture", lua.LString(arch))
+	delegateTo := L.NewTable()
+	delegateTo.RawSetString("facts", facts)
+	if len(s.agents) > 0 {
+		agents := L.NewTable()
+		for _, agent := range s.agents {
+			agents.Append(lua.LString(agent))
+		}
+		delegateTo.RawSetString("agents", agents)
+	}
+	return delegateTo
+}
+
+// buildCommand returns the command of the build task for arch: it runs
+// the build function and uploads the artifact it produced
+func (s *multiarchSpec) buildCommand(arch string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		args := make([]lua.LValue, L.GetTop())
+		for i := range args {
+			args[i] = L.Get(i + 1)
+		}
+		L.CallByParam(lua.P{Fn: s.build, NRet: 3}, args...)
+		success, msg, outputs := L.Get(-3), L.Get(-2), L.Get(-1)
+		L.Pop(3)
+		if !lua.LVAsBool(success) {
+			if msg == lua.LNil {
+				msg = lua.LString(fmt.Sprintf("build of %s for %s failed", s.name, arch))
+			}
+			L.Push(lua.LFalse)
+			L.Push(msg)
+			return 2
+		}
+
+		path := strings.ReplaceAll(s.artifact, "{arch}", arch)
+		if params, ok := L.Get(2).(*lua.LTable); ok && !filepath.IsAbs(path) {
+			if workdir := optString(params, "workdir"); workdir != "" {
+				path = filepath.Join(workdir, path)
+			}
+		}
+		ctx := multiarchContext(L)
+		digest, size, err := putMultiarchBlob(ctx, path)
+		if err != nil {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(fmt.Sprintf("failed to store artifact %s: %v", path, err)))
+			return 2
+		}
+
+		result, ok := outputs.(*lua.LTable)
+		if !ok {
+			result = L.NewTable()
+		}
+		result.RawSetString("arch", lua.LString(arch))
+		result.RawSetString("digest", lua.LString(digest))
+		result.RawSetString("size", lua.LNumber(size))
+		if agent := taskctx.Agent(ctx); agent != "" {
+			result.RawSetString("agent", lua.LString(agent))
+		}
+		L.Push(lua.LTrue)
+		L.Push(lua.LString(fmt.Sprintf("built %s for %s: %s", s.name, arch, digest)))
+		L.Push(result)
+		return 3
+	}
+}
+
+// manifestCommand is the command of the manifest task: it stores the
+// manifest of the artifacts its dependencies built
+func (s *multiarchSpec) manifestCommand(L *lua.LState) int {
+	deps, _ := L.Get(3).(*lua.LTable)
+	manifest, err := s.manifest(deps)
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	f, err := os.CreateTemp("", "sloth-manifest-*.json")
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	digest, _, err := putMultiarchBlob(multiarchContext(L), f.Name())
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(fmt.Sprintf("failed to store the manifest: %v", err)))
+		return 2
+	}
+
+	digests := L.NewTable()
+	artifacts := L.NewTable()
+	for _, a := range manifest.Artifacts {
+		digests.RawSetString(a.Arch, lua.LString(a.Digest))
+		artifact := L.NewTable()
+		artifact.RawSetString("digest", lua.LString(a.Digest))
+		artifact.RawSetString("size", lua.LNumber(a.Size))
+		if a.Agent != "" {
+			artifact.RawSetString("agent", lua.LString(a.Agent))
+		}
+		artifacts.RawSetString(a.Arch, artifact)
+	}
+	result := L.NewTable()
+	result.RawSetString("manifest", lua.LString(digest))
+	result.RawSetString("digests", digests)
+	result.RawSetString("artifacts", artifacts)
+	L.Push(lua.LTrue)
+	L.Push(lua.LString(fmt.Sprintf("stored the manifest of %s: %s", s.name, digest)))
+	L.Push(result)
+	return 3
+}
+
+// manifest collects the artifacts from the outputs of the build tasks
+func (s *multiarchSpec) manifest(deps *lua.LTable) (*multiarchManifest, error) {
+	manifest := &multiarchManifest{Name: s.name}
+	for _, arch := range s.arches {
+		var output *lua.LTable
+		if deps != nil {
+			output, _ = deps.RawGetString(s.taskName(arch)).(*lua.LTable)
+		}
+		if output == nil {
+			return nil, fmt.Errorf("no artifact from %s", s.taskName(arch))
+		}
+		digest, ok := output.RawGetString("digest").(lua.LString)
+		if !ok || digest == "" {
+			return nil, fmt.Errorf("no artifact from %s", s.taskName(arch))
+		}
+		size, _ := output.RawGetString("size").(lua.LNumber)
+		agent, _ := output.RawGetString("agent").(lua.LString)
+		manifest.Artifacts = append(manifest.Artifacts, multiarchArtifact{
+			Arch:   arch,
+			Digest: string(digest),
+			Size:   int64(size),
+			Agent:  string(agent),
+		})
+	}
+	return manifest, nil
+}
+
+// multiarchContext returns the context of the running task
+func multiarchContext(L *lua.LState) context.Context {
+	if ctx := L.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,197 @@
+package luainterface
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeMultiarchBlobs replaces the blob store for the test, recording the
+// content of each file stored under its digest
+func fakeMultiarchBlobs(t *testing.T) map[string]string {
+	t.Helper()
+	blobs := make(map[string]string)
+	orig := putMultiarchBlob
+	putMultiarchBlob = func(ctx context.Context, path string) (string, int64, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", 0, err
+		}
+		digest := "sha256:" + filepath.Base(path)
+		if strings.HasPrefix(filepath.Base(path), "sloth-manifest-") {
+			digest = "sha256:manifest"
+		}
+		blobs[digest] = string(data)
+		return digest, int64(len(data)), nil
+	}
+	t.Cleanup(func() { putMultiarchBlob = orig })
+	return blobs
+}
+
+func TestMultiarchTasks(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	RegisterMultiarchModule(L)
+
+	err := L.DoString(`
+		tasks = multiarch.tasks{
+			name = "app",
+			artifact = "dist/app-{arch}.tar",
+			agents = {"build-01", "build-02"},
+			timeout = "30m",
+			build = function(this, params) return true end,
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := L.GetGlobal("tasks").(*lua.LTable)
+	if tasks.Len() != 3 {
+		t.Fatalf("got %d tasks, want 3", tasks.Len())
+	}
+
+	for i, arch := range []string{"amd64", "arm64"} {
+		task := parseLuaTask(L, tasks.RawGetInt(i+1).(*lua.LTable))
+		if task.Name != "app-"+arch || task.Params["arch"] != arch || task.CommandFunc == nil {
+			t.Errorf("task %d = %s, params %v", i+1, task.Name, task.Params)
+		}
+		if task.Timeout != "30m" {
+			t.Errorf("%s timeout = %q, want 30m", task.Name, task.Timeout)
+		}
+		delegateTo, _ := task.DelegateTo.(map[string]interface{})
+		facts, _ := delegateTo["facts"].(map[string]interface{})
+		if facts["architecture"] != arch || delegateTo["agents"] == nil {
+			t.Errorf("%s delegate_to = %v", task.Name, task.DelegateTo)
+		}
+	}
+
+	manifest := parseLuaTask(L, tasks.RawGetInt(3).(*lua.LTable))
+	if manifest.Name != "app-manifest" || strings.Join(manifest.DependsOn, ",") != "app-amd64,app-arm64" {
+		t.Errorf("manifest task = %s, depends on %v", manifest.Name, manifest.DependsOn)
+	}
+
+	for script, want := range map[string]string{
+		`multiarch.tasks{artifact = "a-{arch}", build = function() end}`:                        "name is required",
+		`multiarch.tasks{name = "app", build = function() end}`:                                 "artifact is required",
+		`multiarch.tasks{name = "app", artifact = "app.tar", build = function() end}`:            "must contain {arch}",
+		`multiarch.tasks{name = "app", artifact = "a-{arch}"}`:                                  "build function is required",
+		`multiarch.tasks{name = "app", artifact = "a-{arch}", arches = "arm64", build = print}`: "arches must be a list",
+	} {
+		if err := L.DoString(script); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", script, err, want)
+		}
+	}
+}
+
+func TestMultiarchBuild(t *testing.T) {
+	blobs := fakeMultiarchBlobs(t)
+	workdir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workdir, "dist"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterMultiarchModule(L)
+	L.SetContext(taskctx.WithAgent(context.Background(), "build-arm"))
+	L.SetGlobal("workdir", lua.LString(workdir))
+
+	err := L.DoString(`
+		local tasks = multiarch.tasks{
+			name = "app",
+			artifact = "dist/app-{arch}.tar",
+			build = function(this, params)
+				local f = io.open(workdir .. "/dist/app-" .. params.arch .. ".tar", "w")
+				f:write("binary for " .. params.arch)
+				f:close()
+				return true, "built", {version = "1.2.0"}
+			end,
+		}
+		ok, msg, out = tasks[2].command(nil, {arch = "arm64", workdir = workdir})
+		failed, reason = multiarch.tasks{
+			name = "app",
+			artifact = "dist/app-{arch}.tar",
+			build = function() return false, "compiler crashed" end,
+		}[1].command(nil, {arch = "amd64", workdir = workdir})
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("build failed: %s", L.GetGlobal("msg"))
+	}
+	out := L.GetGlobal("out").(*lua.LTable)
+	if got := out.RawGetString("digest").String(); got != "sha256:app-arm64.tar" {
+		t.Errorf("digest = %s", got)
+	}
+	if blobs["sha256:app-arm64.tar"] != "binary for arm64" {
+		t.Errorf("stored %q", blobs["sha256:app-arm64.tar"])
+	}
+	for field, want := range map[string]string{"arch": "arm64", "agent": "build-arm", "size": "16", "version": "1.2.0"} {
+		if got := out.RawGetString(field).String(); got != want {
+			t.Errorf("%s = %s, want %s", field, got, want)
+		}
+	}
+
+	if L.GetGlobal("failed") != lua.LFalse || L.GetGlobal("reason").String() != "compiler crashed" {
+		t.Errorf("failed build = %v, %v", L.GetGlobal("failed"), L.GetGlobal("reason"))
+	}
+}
+
+func TestMultiarchManifest(t *testing.T) {
+	blobs := fakeMultiarchBlobs(t)
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterMultiarchModule(L)
+
+	err := L.DoString(`
+		local tasks = multiarch.tasks{
+			name = "app",
+			artifact = "dist/app-{arch}.tar",
+			build = function() return true end,
+		}
+		local manifest = tasks[3].command
+		ok, msg, out = manifest(nil, {}, {
+			["app-amd64"] = {arch = "amd64", digest = "sha256:aaa", size = 10, agent = "build-01"},
+			["app-arm64"] = {arch = "arm64", digest = "sha256:bbb", size = 20, agent = "build-02"},
+		})
+		missing, reason = manifest(nil, {}, {["app-amd64"] = {digest = "sha256:aaa"}})
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("manifest failed: %s", L.GetGlobal("msg"))
+	}
+	out := L.GetGlobal("out").(*lua.LTable)
+	if got := out.RawGetString("manifest").String(); got != "sha256:manifest" {
+		t.Errorf("manifest = %s", got)
+	}
+	digests := out.RawGetString("digests").(*lua.LTable)
+	if digests.RawGetString("amd64").String() != "sha256:aaa" || digests.RawGetString("arm64").String() != "sha256:bbb" {
+		t.Errorf("digests = %v", LuaTableToGoMap(L, digests))
+	}
+
+	var stored multiarchManifest
+	if err := json.Unmarshal([]byte(blobs["sha256:manifest"]), &stored); err != nil {
+		t.Fatal(err)
+	}
+	want := multiarchManifest{Name: "app", Artifacts: []multiarchArtifact{
+		{Arch: "amd64", Digest: "sha256:aaa", Size: 10, Agent: "build-01"},
+		{Arch: "arm64", Digest: "sha256:bbb", Size: 20, Agent: "build-02"},
+	}}
+	if stored.Name != want.Name || len(stored.Artifacts) != 2 || stored.Artifacts[0] != want.Artifacts[0] || stored.Artifacts[1] != want.Artifacts[1] {
+		t.Errorf("stored manifest = %+v", stored)
+	}
+
+	if L.GetGlobal("missing") != lua.LFalse || L.GetGlobal("reason").String() != "no artifact from app-arm64" {
+		t.Errorf("manifest without arm64 = %v, %v", L.GetGlobal("missing"), L.GetGlobal("reason"))
+	}
+}
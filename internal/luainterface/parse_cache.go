@@ -0,0 +1,234 @@
+package luainterface
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxIdleParses is how many parsed copies of one workflow are kept for
+// concurrent runs
+const maxIdleParses = 4
+
+// parseTimeFileAccess matches scripts that read other files while being
+// parsed; what they load can change without the script changing
+var parseTimeFileAccess = regexp.MustCompile(`\b(import|dofile|loadfile)\b\s*[("']`)
+
+// ParseCache keeps the task groups parsed from workflow scripts, keyed by
+// a hash of the script, so an agent running many delegated tasks of the
+// same workflow parses it once.
+//
+// A parsed workflow is leased to one run at a time: its functions share
+// the globals and upvalues of the state they were defined in. Globals are
+// restored when the lease ends; upvalues keep their values.
+type ParseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	lru        *list.List
+	hits       int64
+	misses     int64
+}
+
+type parseCacheEntry struct {
+	hash     string
+	idle     []*ParsedWorkflow
+	lastUsed time.Time
+}
+
+// ParsedWorkflow is a workflow leased from a ParseCache
+type ParsedWorkflow struct {
+	// TaskGroups is a copy the caller may modify
+	TaskGroups map[string]types.TaskGroup
+	// Cached is set when the script was not parsed again
+	Cached bool
+
+	hash      string
+	cacheable bool
+	groups    map[string]types.TaskGroup
+	globals   *lua.LTable
+	snapshot  map[lua.LValue]lua.LValue
+}
+
+// ParseCacheStats reports how well the cache works
+type ParseCacheStats struct {
+	Workflows int   `json:"workflows"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+}
+
+// NewParseCache creates a cache for up to maxEntries workflows. Workflows
+// not used for ttl are parsed again.
+func NewParseCache(maxEntries int, ttl time.Duration) *ParseCache {
+	return &ParseCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// ScriptHash returns the cache key of a script
+func ScriptHash(script []byte) string {
+	sum := sha256.Sum256(script)
+	return hex.EncodeToString(sum[:])
+}
+
+// Acquire returns the task groups of script, written at scriptPath,
+// parsing it unless an idle parsed copy is cached. Release the result
+// when the run is over.
+func (c *ParseCache) Acquire(ctx context.Context, scriptPath string, script []byte) (*ParsedWorkflow, error) {
+	hash := ScriptHash(script)
+	cacheable := !parseTimeFileAccess.Match(script)
+
+	if cacheable {
+		if p := c.takeIdle(hash); p != nil {
+			p.TaskGroups = CloneTaskGroups(p.groups)
+			p.Cached = true
+			return p, nil
+		}
+	}
+
+	c.mu.Lock()
+	c.misses++
+	c.mu.Unlock()
+
+	groups, globals, err := parseLuaScript(ctx, scriptPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ParsedWorkflow{
+		TaskGroups: CloneTaskGroups(groups),
+		hash:       hash,
+		cacheable:  cacheable,
+		groups:     groups,
+		globals:    globals,
+		snapshot:   snapshotTable(globals),
+	}, nil
+}
+
+// Release ends the lease of a parsed workflow and keeps it for the next
+// run of the same script
+func (c *ParseCache) Release(p *ParsedWorkflow) {
+	if p == nil || !p.cacheable {
+		return
+	}
+	restoreTable(p.globals, p.snapshot)
+	p.TaskGroups = nil
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[p.hash]
+	if !ok {
+		elem = c.lru.PushFront(&parseCacheEntry{hash: p.hash})
+		c.entries[p.hash] = elem
+	}
+	entry := elem.Value.(*parseCacheEntry)
+	entry.lastUsed = time.Now()
+	c.lru.MoveToFront(elem)
+	if len(entry.idle) < maxIdleParses {
+		entry.idle = append(entry.idle, p)
+	}
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Stats returns the cache counters
+func (c *ParseCache) Stats() ParseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ParseCacheStats{Workflows: c.lru.Len(), Hits: c.hits, Misses: c.misses}
+}
+
+func (c *ParseCache) takeIdle(hash string) *ParsedWorkflow {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[hash]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*parseCacheEntry)
+	if c.ttl > 0 && time.Since(entry.lastUsed) > c.ttl {
+		c.remove(elem)
+		return nil
+	}
+	if len(entry.idle) == 0 {
+		return nil
+	}
+
+	p := entry.idle[len(entry.idle)-1]
+	entry.idle = entry.idle[:len(entry.idle)-1]
+	entry.lastUsed = time.Now()
+	c.lru.MoveToFront(elem)
+	c.hits++
+	return p
+}
+
+func (c *ParseCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*parseCacheEntry).hash)
+}
+
+// CloneTaskGroups copies task groups so a run can change them, e.g. to
+// filter tasks, without affecting other runs. Lua values are shared.
+func CloneTaskGroups(groups map[string]types.TaskGroup) map[string]types.TaskGroup {
+	clone := make(map[string]types.TaskGroup, len(groups))
+	for name, group := range groups {
+		tasks := make([]types.Task, len(group.Tasks))
+		for i, t := range group.Tasks {
+			if t.Params != nil {
+				params := make(map[string]string, len(t.Params))
+				for k, v := range t.Params {
+					params[k] = v
+				}
+				t.Params = params
+			}
+			t.Output = nil
+			tasks[i] = t
+		}
+		group.Tasks = tasks
+		clone[name] = group
+	}
+	return clone
+}
+
+// snapshotTable records the top-level fields of t
+func snapshotTable(t *lua.LTable) map[lua.LValue]lua.LValue {
+	snapshot := make(map[lua.LValue]lua.LValue)
+	t.ForEach(func(k, v lua.LValue) {
+		snapshot[k] = v
+	})
+	return snapshot
+}
+
+// restoreTable puts back the top-level fields of t recorded by
+// snapshotTable, dropping fields added since
+func restoreTable(t *lua.LTable, snapshot map[lua.LValue]lua.LValue) {
+	var added []lua.LValue
+	t.ForEach(func(k, v lua.LValue) {
+		if _, ok := snapshot[k]; !ok {
+			added = append(added, k)
+		}
+	})
+	for _, k := range added {
+		t.RawSet(k, lua.LNil)
+	}
+	for k, v := range snapshot {
+		if t.RawGet(k) != v {
+			t.RawSet(k, v)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package luainterface
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const cachedWorkflowScript = `
+workflow.define("deploy", {
+	tasks = {
+		{ name = "build", command = "echo build" },
+		{ name = "ship", command = "echo ship" }
+	}
+})
+`
+
+func writeCachedScript(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "task.lua")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0644))
+	return path
+}
+
+func TestParseCache_ReusesParse(t *testing.T) {
+	cache := NewParseCache(4, time.Minute)
+	path := writeCachedScript(t, cachedWorkflowScript)
+
+	first, err := cache.Acquire(context.Background(), path, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	assert.False(t, first.Cached)
+	require.Len(t, first.TaskGroups["deploy"].Tasks, 2)
+
+	// Filtering the leased copy must not change the cached groups
+	group := first.TaskGroups["deploy"]
+	group.Tasks = group.Tasks[:1]
+	first.TaskGroups["deploy"] = group
+	first.globals.RawSetString("leaked", lua.LTrue)
+	cache.Release(first)
+
+	second, err := cache.Acquire(context.Background(), path, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Len(t, second.TaskGroups["deploy"].Tasks, 2)
+	assert.Equal(t, lua.LNil, second.globals.RawGetString("leaked"))
+
+	// A concurrent run of the same script gets its own parse
+	third, err := cache.Acquire(context.Background(), path, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	assert.False(t, third.Cached)
+
+	cache.Release(second)
+	cache.Release(third)
+	stats := cache.Stats()
+	assert.Equal(t, ParseCacheStats{Workflows: 1, Hits: 1, Misses: 2}, stats)
+}
+
+func TestParseCache_ChangedScript(t *testing.T) {
+	cache := NewParseCache(4, time.Minute)
+	path := writeCachedScript(t, cachedWorkflowScript)
+
+	p, err := cache.Acquire(context.Background(), path, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	cache.Release(p)
+
+	changed := cachedWorkflowScript + "\n-- changed\n"
+	require.NoError(t, os.WriteFile(path, []byte(changed), 0644))
+	p, err = cache.Acquire(context.Background(), path, []byte(changed))
+	require.NoError(t, err)
+	assert.False(t, p.Cached)
+	cache.Release(p)
+}
+
+func TestParseCache_Eviction(t *testing.T) {
+	cache := NewParseCache(1, time.Minute)
+	a := writeCachedScript(t, cachedWorkflowScript)
+	scriptB := cachedWorkflowScript + "\n-- b\n"
+	b := writeCachedScript(t, scriptB)
+
+	p, err := cache.Acquire(context.Background(), a, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	cache.Release(p)
+	p, err = cache.Acquire(context.Background(), b, []byte(scriptB))
+	require.NoError(t, err)
+	cache.Release(p)
+
+	p, err = cache.Acquire(context.Background(), a, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	assert.False(t, p.Cached, "least recently used workflow should have been evicted")
+	cache.Release(p)
+	assert.Equal(t, 1, cache.Stats().Workflows)
+}
+
+func TestParseCache_Expiry(t *testing.T) {
+	cache := NewParseCache(4, time.Nanosecond)
+	path := writeCachedScript(t, cachedWorkflowScript)
+
+	p, err := cache.Acquire(context.Background(), path, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	cache.Release(p)
+	time.Sleep(time.Millisecond)
+
+	p, err = cache.Acquire(context.Background(), path, []byte(cachedWorkflowScript))
+	require.NoError(t, err)
+	assert.False(t, p.Cached)
+	cache.Release(p)
+}
+
+func TestParseCache_ImportsNotCached(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.lua"), []byte("return {}"), 0644))
+	script := "local lib = import(\"lib.lua\")\n" + cachedWorkflowScript
+	path := filepath.Join(dir, "task.lua")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0644))
+
+	cache := NewParseCache(4, time.Minute)
+	for i := 0; i < 2; i++ {
+		p, err := cache.Acquire(context.Background(), path, []byte(script))
+		require.NoError(t, err)
+		assert.False(t, p.Cached)
+		cache.Release(p)
+	}
+	assert.Equal(t, 0, cache.Stats().Workflows)
+}
+
+func TestStatePool_ResetsGlobals(t *testing.T) {
+	pool := NewStatePool(1)
+
+	L := pool.Get()
+	require.NoError(t, L.DoString(`leaked = true; package.loaded.mine = {}`))
+	pool.Put(L)
+
+	again := pool.Get()
+	assert.Same(t, L, again, "expected the idle state to be reused")
+	assert.Equal(t, lua.LNil, again.GetGlobal("leaked"))
+	assert.Equal(t, lua.LNil, packageLoaded(again).RawGetString("mine"))
+	assert.NotEqual(t, lua.LNil, again.GetGlobal("exec"), "modules should stay registered")
+
+	other := pool.Get()
+	pool.Put(again)
+	pool.Put(other) // pool is full, closed
+	assert.Len(t, pool.idle, 1)
+}
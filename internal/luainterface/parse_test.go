@@ -191,6 +191,53 @@ workflow({
 	assert.Equal(t, "30s", task.Timeout)
 }
 
+func TestParseLuaScript_WithRetryPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "test.sloth")
+
+	script := `
+workflow.define("table_form", {
+	tasks = {
+		{
+			name = "task1",
+			command = "echo test",
+			retries = 3,
+			retry_delay = "10s",
+			retry_backoff = "exponential",
+			retry_on = {"timeout", "connection"}
+		}
+	}
+})
+
+local fetch = task("fetch")
+	:command(function() return true end)
+	:retries(2, "fixed")
+	:retry_delay("5s")
+	:retry_on("connection")
+	:build()
+workflow.define("fluent_form")
+	:tasks({ fetch })
+	:on_complete(function() end)
+`
+	require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0644))
+
+	taskGroups, err := ParseLuaScript(context.Background(), scriptPath, nil)
+	require.NoError(t, err)
+
+	task := taskGroups["table_form"].Tasks[0]
+	assert.Equal(t, 3, task.Retries)
+	assert.Equal(t, "10s", task.RetryDelay)
+	assert.Equal(t, "exponential", task.RetryBackoff)
+	assert.Equal(t, []string{"timeout", "connection"}, task.RetryOn)
+
+	require.Len(t, taskGroups["fluent_form"].Tasks, 1)
+	built := taskGroups["fluent_form"].Tasks[0]
+	assert.Equal(t, 2, built.Retries)
+	assert.Equal(t, "5s", built.RetryDelay)
+	assert.Equal(t, "fixed", built.RetryBackoff)
+	assert.Equal(t, []string{"connection"}, built.RetryOn)
+}
+
 func TestParseLuaScript_WithAsync(t *testing.T) {
 	tmpDir := t.TempDir()
 	scriptPath := filepath.Join(tmpDir, "test.sloth")
@@ -307,6 +354,61 @@ workflow({
 	assert.Equal(t, true, delegateMap["parallel"])
 }
 
+func TestParseLuaScript_WithAgentGroupFanOut(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "test.sloth")
+
+	script := `
+workflow.define("test_group", {
+	tasks = {
+		{
+			name = "task1",
+			command = "echo test",
+			delegate_to = "group:webservers",
+			max_concurrency = 2,
+			fail_fast = true
+		}
+	}
+})
+`
+	require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0644))
+
+	taskGroups, err := ParseLuaScript(context.Background(), scriptPath, nil)
+	require.NoError(t, err)
+
+	task := taskGroups["test_group"].Tasks[0]
+	assert.Equal(t, "group:webservers", task.DelegateTo)
+	assert.Equal(t, 2, task.MaxConcurrency)
+	assert.True(t, task.FailFast)
+}
+
+func TestParseLuaScript_WithStrictTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "test.sloth")
+
+	script := `
+workflow.define("strict", {
+	strict_types = true,
+	tasks = {{ name = "task1", command = "echo test" }}
+})
+workflow.define("lenient", {
+	tasks = {{ name = "task1", command = "echo test" }}
+})
+workflow.define("fluent")
+	:tasks({ task("task1"):command("echo test"):build() })
+	:strict_types()
+	:on_complete(function() end)
+`
+	require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0644))
+
+	taskGroups, err := ParseLuaScript(context.Background(), scriptPath, nil)
+	require.NoError(t, err)
+
+	assert.True(t, taskGroups["strict"].StrictTypes)
+	assert.False(t, taskGroups["lenient"].StrictTypes)
+	assert.True(t, taskGroups["fluent"].StrictTypes)
+}
+
 func TestParseLuaScript_WithValues(t *testing.T) {
 	tmpDir := t.TempDir()
 	scriptPath := filepath.Join(tmpDir, "test.sloth")
@@ -3,9 +3,12 @@ package luainterface
 import (
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -25,7 +28,7 @@ func (p *PkgModule) Loader(L *lua.LState) int {
 }
 
 func (p *PkgModule) exports() map[string]lua.LGFunction {
-	return map[string]lua.LGFunction{
+	return withModuleAPIShims("pkg", map[string]lua.LGFunction{
 		"install":        p.install,
 		"remove":         p.remove,
 		"update":         p.update,
@@ -41,7 +44,7 @@ func (p *PkgModule) exports() map[string]lua.LGFunction {
 		"version":        p.version,
 		"deps":           p.deps,
 		"install_local":  p.installLocal,
-	}
+	})
 }
 
 // detectPackageManager detects the available package manager
@@ -59,6 +62,7 @@ func (p *PkgModule) detectPackageManager() (string, error) {
 		"eopkg",                    // Solus
 		"pkg",                      // FreeBSD
 		"brew",                     // macOS
+		"choco", "winget",          // Windows
 	}
 
 	for _, manager := range managers {
@@ -93,7 +97,7 @@ func (p *PkgModule) parsePackages(val lua.LValue) []string {
 // needsSudo checks if the command needs sudo
 func (p *PkgModule) needsSudo(manager string) bool {
 	// Package managers that don't need sudo
-	noSudoManagers := []string{"brew", "nix-env"}
+	noSudoManagers := []string{"brew", "nix-env", "choco", "winget"}
 
 	for _, m := range noSudoManagers {
 		if manager == m {
@@ -101,8 +105,9 @@ func (p *PkgModule) needsSudo(manager string) bool {
 		}
 	}
 
-	// On macOS with other package managers (like MacPorts), may not need sudo
-	if runtime.GOOS == "darwin" {
+	// On macOS with other package managers (like MacPorts), may not need sudo,
+	// and Windows has no sudo: the agent runs with the rights it needs
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
 		return false
 	}
 
@@ -154,6 +159,12 @@ func (p *PkgModule) buildInstallCommand(manager string, packages []string) []str
 	case "brew":
 		args = append(args, manager, "install")
 		args = append(args, packages...)
+	case "choco":
+		args = append(args, manager, "install", "-y")
+		args = append(args, packages...)
+	case "winget":
+		args = append(args, manager, "install", "--exact", "--silent", "--accept-package-agreements", "--accept-source-agreements")
+		args = append(args, packages...)
 	default:
 		args = append(args, manager, "install")
 		args = append(args, packages...)
@@ -208,6 +219,12 @@ func (p *PkgModule) buildRemoveCommand(manager string, packages []string) []stri
 	case "brew":
 		args = append(args, manager, "uninstall")
 		args = append(args, packages...)
+	case "choco":
+		args = append(args, manager, "uninstall", "-y")
+		args = append(args, packages...)
+	case "winget":
+		args = append(args, manager, "uninstall", "--exact", "--silent")
+		args = append(args, packages...)
 	default:
 		args = append(args, manager, "remove")
 		args = append(args, packages...)
@@ -249,6 +266,12 @@ func (p *PkgModule) buildUpdateCommand(manager string) []string {
 		args = append(args, manager, "update")
 	case "brew":
 		args = append(args, manager, "update")
+	case "choco":
+		// Chocolatey reads its sources on each command, outdated shows
+		// what an upgrade would do
+		args = append(args, manager, "outdated")
+	case "winget":
+		args = append(args, manager, "source", "update")
 	default:
 		args = append(args, manager, "update")
 	}
@@ -289,6 +312,10 @@ func (p *PkgModule) buildUpgradeCommand(manager string) []string {
 		args = append(args, manager, "upgrade", "-y")
 	case "brew":
 		args = append(args, manager, "upgrade")
+	case "choco":
+		args = append(args, manager, "upgrade", "all", "-y")
+	case "winget":
+		args = append(args, manager, "upgrade", "--all", "--silent", "--accept-package-agreements", "--accept-source-agreements")
 	default:
 		args = append(args, manager, "upgrade")
 	}
@@ -323,17 +350,18 @@ func (p *PkgModule) install(L *lua.LState) int {
 	}
 	
 	// IDEMPOTENCY: Check which packages need to be installed
-	var packagesToInstall []string
+	var packagesToInstall, alreadyPresent []string
 	for _, pkg := range packages {
-		if !p.isPackageInstalled(manager, pkg) {
+		if p.isPackageInstalled(manager, pkg) {
+			alreadyPresent = append(alreadyPresent, pkg)
+		} else {
 			packagesToInstall = append(packagesToInstall, pkg)
 		}
 	}
 	
 	// If all packages are already installed, return changed=false
 	if len(packagesToInstall) == 0 {
-		result := L.NewTable()
-		result.RawSetString("changed", lua.LFalse)
+		result := pkgResult(L, "installed", nil, "already_present", alreadyPresent)
 		result.RawSetString("message", lua.LString("All packages already installed"))
 		L.Push(lua.LTrue)
 		L.Push(result)
@@ -341,7 +369,15 @@ func (p *PkgModule) install(L *lua.LState) int {
 	}
 	
 	args := p.buildInstallCommand(manager, packagesToInstall)
-	cmd := exec.Command(args[0], args[1:]...)
+	change := taskctx.Change{Module: "pkg", Action: "install", Target: strings.Join(packagesToInstall, ", "), Current: "not installed"}
+	if taskctx.RecordChange(L.Context(), change) {
+		return pushPlanned(L, "Would install "+change.Target, map[string]lua.LValue{
+			"installed":       stringsToLuaTable(L, packagesToInstall),
+			"already_present": stringsToLuaTable(L, alreadyPresent),
+		})
+	}
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -349,16 +385,25 @@ func (p *PkgModule) install(L *lua.LState) int {
 		L.Push(lua.LString(fmt.Sprintf("Failed to install packages: %s\n%s", err, string(output))))
 		return 2
 	}
+	taskctx.ReportChange(L.Context(), change)
 	
-	result := L.NewTable()
-	result.RawSetString("changed", lua.LTrue)
-	result.RawSetString("installed", lua.LString(strings.Join(packagesToInstall, ", ")))
+	result := pkgResult(L, "installed", packagesToInstall, "already_present", alreadyPresent)
 	result.RawSetString("output", lua.LString(string(output)))
 	L.Push(lua.LTrue)
 	L.Push(result)
 	return 2
 }
 
+// pkgResult is the result of a pkg call that changed the packages in
+// changedKey, if any, and left the ones in unchangedKey as they were
+func pkgResult(L *lua.LState, changedKey string, changed []string, unchangedKey string, unchanged []string) *lua.LTable {
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(len(changed) > 0))
+	result.RawSetString(changedKey, stringsToLuaTable(L, changed))
+	result.RawSetString(unchangedKey, stringsToLuaTable(L, unchanged))
+	return result
+}
+
 // remove removes packages (with idempotency)
 // pkg.remove({packages = "vim"}) or pkg.remove({packages = {"vim", "git"}})
 func (p *PkgModule) remove(L *lua.LState) int {
@@ -386,17 +431,18 @@ func (p *PkgModule) remove(L *lua.LState) int {
 	}
 	
 	// IDEMPOTENCY: Check which packages are actually installed
-	var packagesToRemove []string
+	var packagesToRemove, alreadyAbsent []string
 	for _, pkg := range packages {
 		if p.isPackageInstalled(manager, pkg) {
 			packagesToRemove = append(packagesToRemove, pkg)
+		} else {
+			alreadyAbsent = append(alreadyAbsent, pkg)
 		}
 	}
 	
 	// If no packages are installed, return changed=false
 	if len(packagesToRemove) == 0 {
-		result := L.NewTable()
-		result.RawSetString("changed", lua.LFalse)
+		result := pkgResult(L, "removed", nil, "already_absent", alreadyAbsent)
 		result.RawSetString("message", lua.LString("Packages already not installed"))
 		L.Push(lua.LTrue)
 		L.Push(result)
@@ -404,7 +450,15 @@ func (p *PkgModule) remove(L *lua.LState) int {
 	}
 	
 	args := p.buildRemoveCommand(manager, packagesToRemove)
-	cmd := exec.Command(args[0], args[1:]...)
+	change := taskctx.Change{Module: "pkg", Action: "remove", Target: strings.Join(packagesToRemove, ", "), Current: "installed"}
+	if taskctx.RecordChange(L.Context(), change) {
+		return pushPlanned(L, "Would remove "+change.Target, map[string]lua.LValue{
+			"removed":        stringsToLuaTable(L, packagesToRemove),
+			"already_absent": stringsToLuaTable(L, alreadyAbsent),
+		})
+	}
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -412,10 +466,9 @@ func (p *PkgModule) remove(L *lua.LState) int {
 		L.Push(lua.LString(fmt.Sprintf("Failed to remove packages: %s\n%s", err, string(output))))
 		return 2
 	}
+	taskctx.ReportChange(L.Context(), change)
 	
-	result := L.NewTable()
-	result.RawSetString("changed", lua.LTrue)
-	result.RawSetString("removed", lua.LString(strings.Join(packagesToRemove, ", ")))
+	result := pkgResult(L, "removed", packagesToRemove, "already_absent", alreadyAbsent)
 	result.RawSetString("output", lua.LString(string(output)))
 	L.Push(lua.LTrue)
 	L.Push(result)
@@ -435,7 +488,8 @@ func (p *PkgModule) update(L *lua.LState) int {
 	}
 	
 	args := p.buildUpdateCommand(manager)
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	
 	output, err := cmd.CombinedOutput()
 	// yum check-update returns 100 if there are updates available
@@ -463,7 +517,8 @@ func (p *PkgModule) upgrade(L *lua.LState) int {
 	}
 	
 	args := p.buildUpgradeCommand(manager)
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -522,11 +577,14 @@ func (p *PkgModule) search(L *lua.LState) int {
 		args = []string{manager, "search", query}
 	case "brew":
 		args = []string{manager, "search", query}
+	case "choco", "winget":
+		args = []string{manager, "search", query}
 	default:
 		args = []string{manager, "search", query}
 	}
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -584,11 +642,16 @@ func (p *PkgModule) info(L *lua.LState) int {
 		args = []string{manager, "info", pkgName}
 	case "brew":
 		args = []string{manager, "info", pkgName}
+	case "choco":
+		args = []string{manager, "info", pkgName}
+	case "winget":
+		args = []string{manager, "show", "--exact", pkgName}
 	default:
 		args = []string{manager, "info", pkgName}
 	}
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -639,11 +702,16 @@ func (p *PkgModule) list(L *lua.LState) int {
 		args = []string{manager, "info"}
 	case "brew":
 		args = []string{manager, "list"}
+	case "choco":
+		args = []string{manager, "list", "--limit-output"}
+	case "winget":
+		args = []string{manager, "list"}
 	default:
 		args = []string{manager, "list"}
 	}
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -695,6 +763,10 @@ func (p *PkgModule) isPackageInstalled(manager, pkgName string) bool {
 		cmd = exec.Command(manager, "info", pkgName)
 	case "brew":
 		cmd = exec.Command(manager, "list", pkgName)
+	case "choco":
+		cmd = exec.Command(manager, "list", "--exact", "--limit-output", pkgName)
+	case "winget":
+		cmd = exec.Command(manager, "list", "--exact", "--id", pkgName)
 	default:
 		cmd = exec.Command(manager, "list", pkgName)
 	}
@@ -796,13 +868,16 @@ func (p *PkgModule) clean(L *lua.LState) int {
 		args = append(args, manager, "clean")
 	case "brew":
 		args = append(args, manager, "cleanup")
+	case "choco":
+		args = append(args, manager, "cache", "remove", "-y")
 	default:
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("Clean command not supported for " + manager))
 		return 2
 	}
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -866,7 +941,8 @@ func (p *PkgModule) autoremove(L *lua.LState) int {
 		return 2
 	}
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	output, err := cmd.CombinedOutput()
 	// Some package managers return non-zero if nothing to remove
 	if err != nil && !strings.Contains(string(output), "Nothing to do") {
@@ -926,29 +1002,33 @@ func (p *PkgModule) version(L *lua.LState) int {
 	var cmd *exec.Cmd
 	switch manager {
 	case "apt", "apt-get":
-		cmd = exec.Command("dpkg", "-s", pkgName)
+		cmd = exec.CommandContext(callContext(L), "dpkg", "-s", pkgName)
 	case "yum", "dnf":
-		cmd = exec.Command(manager, "info", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", pkgName)
 	case "pacman":
-		cmd = exec.Command(manager, "-Q", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "-Q", pkgName)
 	case "zypper":
-		cmd = exec.Command(manager, "info", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", pkgName)
 	case "apk":
-		cmd = exec.Command(manager, "info", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", pkgName)
 	case "slackpkg":
-		cmd = exec.Command("ls", "-l", "/var/log/packages/"+pkgName+"*")
+		cmd = exec.CommandContext(callContext(L), "ls", "-l", "/var/log/packages/"+pkgName+"*")
 	case "emerge":
-		cmd = exec.Command("qlist", "-Iv", pkgName)
+		cmd = exec.CommandContext(callContext(L), "qlist", "-Iv", pkgName)
 	case "xbps-install":
-		cmd = exec.Command("xbps-query", "-l", pkgName)
+		cmd = exec.CommandContext(callContext(L), "xbps-query", "-l", pkgName)
 	case "nix-env":
-		cmd = exec.Command(manager, "-q", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "-q", pkgName)
 	case "eopkg":
-		cmd = exec.Command(manager, "info", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", pkgName)
 	case "pkg":
-		cmd = exec.Command(manager, "info", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", pkgName)
 	case "brew":
-		cmd = exec.Command(manager, "info", pkgName, "--json")
+		cmd = exec.CommandContext(callContext(L), manager, "info", pkgName, "--json")
+	case "choco":
+		cmd = exec.CommandContext(callContext(L), manager, "list", "--exact", "--limit-output", pkgName)
+	case "winget":
+		cmd = exec.CommandContext(callContext(L), manager, "list", "--exact", "--id", pkgName)
 	default:
 		L.Push(lua.LNil)
 		L.Push(lua.LString("Version check not supported for " + manager))
@@ -999,31 +1079,33 @@ func (p *PkgModule) deps(L *lua.LState) int {
 	var cmd *exec.Cmd
 	switch manager {
 	case "apt", "apt-get":
-		cmd = exec.Command("apt-cache", "depends", pkgName)
+		cmd = exec.CommandContext(callContext(L), "apt-cache", "depends", pkgName)
 	case "yum", "dnf":
-		cmd = exec.Command(manager, "deplist", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "deplist", pkgName)
 	case "pacman":
-		cmd = exec.Command(manager, "-Si", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "-Si", pkgName)
 	case "zypper":
-		cmd = exec.Command(manager, "info", "--requires", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", "--requires", pkgName)
 	case "apk":
-		cmd = exec.Command(manager, "info", "-R", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", "-R", pkgName)
 	case "slackpkg":
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("Dependency listing not directly supported for slackpkg"))
 		return 2
 	case "emerge":
-		cmd = exec.Command(manager, "--pretend", "--verbose", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "--pretend", "--verbose", pkgName)
 	case "xbps-install":
-		cmd = exec.Command("xbps-query", "-x", pkgName)
+		cmd = exec.CommandContext(callContext(L), "xbps-query", "-x", pkgName)
 	case "nix-env":
-		cmd = exec.Command("nix-store", "-q", "--references", pkgName)
+		cmd = exec.CommandContext(callContext(L), "nix-store", "-q", "--references", pkgName)
 	case "eopkg":
-		cmd = exec.Command(manager, "info", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", pkgName)
 	case "pkg":
-		cmd = exec.Command(manager, "info", "-d", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "info", "-d", pkgName)
 	case "brew":
-		cmd = exec.Command(manager, "deps", pkgName)
+		cmd = exec.CommandContext(callContext(L), manager, "deps", pkgName)
+	case "winget":
+		cmd = exec.CommandContext(callContext(L), manager, "show", "--exact", pkgName)
 	default:
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("Dependency listing not supported for " + manager))
@@ -1094,13 +1176,19 @@ func (p *PkgModule) installLocal(L *lua.LState) int {
 		args = append(args, manager, "add", filePath)
 	case "brew":
 		args = append(args, manager, "install", filePath)
+	case "choco":
+		// A local .nupkg installs from the directory holding it
+		args = append(args, manager, "install", "-y", strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)), "--source", filepath.Dir(filePath))
+	case "winget":
+		args = append(args, manager, "install", "--manifest", filePath, "--silent", "--accept-package-agreements", "--accept-source-agreements")
 	default:
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("Local install not supported for " + manager))
 		return 2
 	}
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
+	defer taskusage.Attach(L.Context(), cmd)()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1,10 +1,14 @@
 package luainterface
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -83,6 +87,8 @@ func TestPkgNeedsSudo(t *testing.T) {
 		expected bool
 	}{
 		{"brew", false},
+		{"choco", false},
+		{"winget", false},
 		{"apt-get", runtime.GOOS != "darwin"},
 		{"yum", runtime.GOOS != "darwin"},
 		{"dnf", runtime.GOOS != "darwin"},
@@ -153,6 +159,8 @@ func TestPkgBuildInstallCommand(t *testing.T) {
 		{"dnf", []string{"git"}, []string{"install", "git"}},
 		{"pacman", []string{"vim"}, []string{"-S", "vim"}},
 		{"brew", []string{"node"}, []string{"install", "node"}},
+		{"choco", []string{"git"}, []string{"choco install -y git"}},
+		{"winget", []string{"Git.Git"}, []string{"winget install", "--accept-package-agreements", "Git.Git"}},
 	}
 
 	for _, tt := range tests {
@@ -280,3 +288,63 @@ func TestPkgModuleIntegration(t *testing.T) {
 		t.Fatalf("Integration test failed: %v", err)
 	}
 }
+
+// fakeBrew puts a brew on PATH, the only package manager found, that has
+// git installed and installs or removes anything else
+func fakeBrew(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script on PATH")
+	}
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$1" in
+list) [ "$2" = git ] && echo git && exit 0; exit 1 ;;
+*) echo "$@" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "brew"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestPkgInstallReportsChanges(t *testing.T) {
+	fakeBrew(t)
+	L := lua.NewState()
+	defer L.Close()
+	log := &taskctx.ChangeLog{}
+	L.SetContext(taskctx.WithChangeLog(context.Background(), log))
+	L.PreloadModule("pkg", NewPkgModule().Loader)
+
+	err := L.DoString(`
+		local pkg = require("pkg")
+
+		local ok, result = pkg.install({packages = {"git", "htop"}})
+		assert(ok, tostring(result))
+		assert(result.changed == true, "installing htop is a change")
+		assert(#result.installed == 1 and result.installed[1] == "htop", "htop installed")
+		assert(#result.already_present == 1 and result.already_present[1] == "git", "git already present")
+
+		ok, result = pkg.install({packages = "git"})
+		assert(ok, tostring(result))
+		assert(result.changed == false, "git is already installed")
+		assert(#result.installed == 0, "nothing installed")
+
+		ok, result = pkg.remove({packages = {"git", "htop"}})
+		assert(ok, tostring(result))
+		assert(result.changed == true, "removing git is a change")
+		assert(result.removed[1] == "git" and result.already_absent[1] == "htop", "git removed, htop absent")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := log.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %v", changes)
+	}
+	if changes[0] != (taskctx.Change{Module: "pkg", Action: "install", Target: "htop", Current: "not installed"}) ||
+		changes[1] != (taskctx.Change{Module: "pkg", Action: "remove", Target: "git", Current: "installed"}) {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
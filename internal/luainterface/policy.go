@@ -0,0 +1,59 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/policy"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// OpenPolicy wraps the functions of privileged modules so each call is
+// checked against the active policy gate. Nothing is wrapped when no gate
+// is active.
+func OpenPolicy(L *lua.LState) {
+	gate := policy.Active()
+	if gate == nil {
+		return
+	}
+
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		module := key.String()
+		table, ok := value.(*lua.LTable)
+		if !ok || !gate.Engine().Privileged(module) {
+			return
+		}
+
+		var names []string
+		table.ForEach(func(k, v lua.LValue) {
+			if _, ok := v.(*lua.LFunction); ok {
+				names = append(names, k.String())
+			}
+		})
+		for _, name := range names {
+			fn := table.RawGetString(name).(*lua.LFunction)
+			table.RawSetString(name, L.NewFunction(policyWrapper(module, name, fn)))
+		}
+	})
+}
+
+func policyWrapper(module, function string, fn *lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		top := L.GetTop()
+		if gate := policy.Active(); gate != nil {
+			args := make([]interface{}, 0, top)
+			for i := 1; i <= top; i++ {
+				args = append(args, LuaToGoValue(L, L.Get(i)))
+			}
+			in := policy.Input{Stage: policy.StageModule, Module: module, Function: function, Args: args}
+			if err := gate.Check(in); err != nil {
+				L.RaiseError("%s", err.Error())
+				return 0
+			}
+		}
+
+		L.Push(fn)
+		for i := 1; i <= top; i++ {
+			L.Push(L.Get(i))
+		}
+		L.Call(top, lua.MultRet)
+		return L.GetTop() - top
+	}
+}
@@ -0,0 +1,49 @@
+package luainterface
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/policy"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestOpenPolicy_ChecksPrivilegedCalls(t *testing.T) {
+	engine, err := policy.New(policy.File{
+		PrivilegedModules: []string{"fake"},
+		Policies: []policy.Policy{{
+			Name:   "no-danger",
+			Stage:  policy.StageModule,
+			When:   `fn == "run" && args[0] == "danger"`,
+			Action: policy.ActionDeny,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.SetActive(policy.NewGate(engine, policy.Input{Agent: "local"}, nil, nil))
+	defer policy.SetActive(nil)
+
+	L := lua.NewState()
+	defer L.Close()
+	fake := L.NewTable()
+	L.SetField(fake, "run", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString("ran " + L.CheckString(1)))
+		L.Push(lua.LNil)
+		return 2
+	}))
+	L.SetGlobal("fake", fake)
+	OpenPolicy(L)
+
+	if err := L.DoString(`out, err = fake.run("safe")`); err != nil {
+		t.Fatalf("Expected allowed call to succeed: %v", err)
+	}
+	if out := L.GetGlobal("out").String(); out != "ran safe" {
+		t.Errorf("Expected wrapped function results, got %q", out)
+	}
+
+	err = L.DoString(`fake.run("danger")`)
+	if err == nil || !strings.Contains(err.Error(), `denied by policy "no-danger"`) {
+		t.Errorf("Expected call to be denied, got %v", err)
+	}
+}
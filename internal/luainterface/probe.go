@@ -0,0 +1,383 @@
+package luainterface
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Probe defaults
+const (
+	defaultProbeInterval       = 5 * time.Second
+	defaultProbeTimeout        = 2 * time.Minute
+	defaultProbeAttemptTimeout = 5 * time.Second
+	// probeHistory is how many attempts a timed out probe reports
+	probeHistory = 5
+)
+
+// probeSpec is what a probe checks and how patiently
+type probeSpec struct {
+	target   string
+	interval time.Duration
+	timeout  time.Duration
+	// attemptTimeout bounds each check
+	attemptTimeout time.Duration
+	// successes is how many checks in a row must pass
+	successes int
+	// check runs one attempt and describes its outcome, e.g. "503" or
+	// "connection refused"
+	check func(ctx context.Context) (outcome string, err error)
+}
+
+// probeResult is the outcome of waiting on a probe
+type probeResult struct {
+	attempts int
+	duration time.Duration
+	// last is the outcome of the last attempt
+	last string
+	// history holds the outcomes of the last attempts, oldest first
+	history []string
+	lastErr error
+}
+
+// RegisterProbeModule registers the probe module, which blocks until a
+// deployed service is healthy:
+//
+//	assert(probe.http{url = "http://localhost:8080/health", expect_status = 200, timeout = "2m"})
+//	assert(probe.tcp{host = "db.internal", port = 5432})
+//
+// A probe returns a result table once the target passed, or nil and a
+// message with the last status codes and connection errors when it timed
+// out.
+func RegisterProbeModule(L *lua.LState) {
+	probeTable := L.NewTable()
+	L.SetField(probeTable, "http", L.NewFunction(luaProbeHTTP))
+	L.SetField(probeTable, "tcp", L.NewFunction(luaProbeTCP))
+	L.SetGlobal("probe", probeTable)
+}
+
+func luaProbeHTTP(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	url := optString(opts, "url")
+	if url == "" {
+		L.ArgError(1, "url is required")
+		return 0
+	}
+	spec, err := newProbeSpec(opts, url)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+
+	method := strings.ToUpper(optString(opts, "method"))
+	if method == "" {
+		method = http.MethodGet
+	}
+	expectStatus, expected, err := probeStatuses(opts.RawGetString("expect_status"))
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+	expectBody := optString(opts, "expect_body")
+	headers := map[string]string{}
+	if t, ok := opts.RawGetString("headers").(*lua.LTable); ok {
+		t.ForEach(func(k, v lua.LValue) { headers[k.String()] = v.String() })
+	}
+
+	client := &http.Client{
+		// Redirects are followed, the final status is checked
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: lua.LVAsBool(opts.RawGetString("insecure"))},
+			DisableKeepAlives: true,
+		},
+	}
+	spec.check = func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		outcome := strconv.Itoa(resp.StatusCode)
+		if !expectStatus[resp.StatusCode] {
+			return outcome, fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if expectBody != "" {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			if err != nil {
+				return outcome, err
+			}
+			if !strings.Contains(string(body), expectBody) {
+				return outcome + " (body mismatch)", fmt.Errorf("body doesn't contain %q", expectBody)
+			}
+		}
+		return outcome, nil
+	}
+
+	result := spec.wait(L.Context())
+	if result.lastErr != nil {
+		return pushProbeFailure(L, spec, result, "expected status "+expected)
+	}
+
+	t := probeResultTable(L, result)
+	if status, err := strconv.Atoi(result.last); err == nil {
+		t.RawSetString("status", lua.LNumber(status))
+	}
+	L.Push(t)
+	L.Push(lua.LNil)
+	return 2
+}
+
+func luaProbeTCP(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	address := optString(opts, "address")
+	if address == "" {
+		host := optString(opts, "host")
+		if host == "" {
+			host = "localhost"
+		}
+		port, ok := opts.RawGetString("port").(lua.LNumber)
+		if !ok {
+			L.ArgError(1, "address or port is required")
+			return 0
+		}
+		address = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	spec, err := newProbeSpec(opts, address)
+	if err != nil {
+		L.ArgError(1, err.Error())
+		return 0
+	}
+
+	spec.check = func(ctx context.Context) (string, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return "", err
+		}
+		conn.Close()
+		return "connected", nil
+	}
+
+	result := spec.wait(L.Context())
+	if result.lastErr != nil {
+		return pushProbeFailure(L, spec, result, "")
+	}
+	L.Push(probeResultTable(L, result))
+	L.Push(lua.LNil)
+	return 2
+}
+
+func newProbeSpec(opts *lua.LTable, target string) (*probeSpec, error) {
+	spec := &probeSpec{target: target, successes: 1}
+	var err error
+	if spec.interval, err = optDuration(opts, "interval", defaultProbeInterval); err != nil {
+		return nil, err
+	}
+	if spec.timeout, err = optDuration(opts, "timeout", defaultProbeTimeout); err != nil {
+		return nil, err
+	}
+	if spec.attemptTimeout, err = optDuration(opts, "attempt_timeout", defaultProbeAttemptTimeout); err != nil {
+		return nil, err
+	}
+	if n, ok := opts.RawGetString("successes").(lua.LNumber); ok {
+		if n < 1 {
+			return nil, fmt.Errorf("successes must be at least 1")
+		}
+		spec.successes = int(n)
+	}
+	return spec, nil
+}
+
+// wait checks the target every interval until it passed successes times
+// in a row, the timeout elapsed or ctx was cancelled
+func (p *probeSpec) wait(ctx context.Context) *probeResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := time.Now()
+	deadline := start.Add(p.timeout)
+	result := &probeResult{}
+	passed := 0
+
+	for {
+		attemptCtx, cancel := context.WithTimeout(ctx, p.attemptTimeout)
+		outcome, err := p.check(attemptCtx)
+		cancel()
+
+		result.attempts++
+		if err != nil && outcome == "" {
+			outcome = probeErrorText(err)
+		}
+		result.last = outcome
+		result.lastErr = err
+		result.history = append(result.history, outcome)
+		if len(result.history) > probeHistory {
+			result.history = result.history[1:]
+		}
+
+		if err == nil {
+			passed++
+			if passed >= p.successes {
+				result.duration = time.Since(start)
+				slog.Info("probe passed", "target", p.target, "attempts", result.attempts, "duration", result.duration.Round(time.Millisecond))
+				return result
+			}
+		} else {
+			passed = 0
+			slog.Debug("probe attempt failed", "target", p.target, "attempt", result.attempts, "outcome", outcome)
+		}
+
+		wait := p.interval
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			break
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			result.lastErr = ctx.Err()
+			result.duration = time.Since(start)
+			return result
+		case <-timer.C:
+		}
+	}
+
+	if result.lastErr == nil {
+		// The last check passed, but not enough times in a row
+		result.lastErr = fmt.Errorf("passed %d of %d checks in a row", passed, p.successes)
+	}
+	result.duration = time.Since(start)
+	return result
+}
+
+// pushProbeFailure returns nil and a message describing why the probe
+// never passed
+func pushProbeFailure(L *lua.LState, p *probeSpec, result *probeResult, expected string) int {
+	var b strings.Builder
+	if result.lastErr == context.Canceled || result.lastErr == context.DeadlineExceeded {
+		fmt.Fprintf(&b, "probe %s cancelled after %s (%d attempts)", p.target, result.duration.Round(time.Second), result.attempts)
+	} else {
+		fmt.Fprintf(&b, "probe %s not healthy after %s (%d attempts)", p.target, result.duration.Round(time.Second), result.attempts)
+	}
+	if expected != "" {
+		fmt.Fprintf(&b, ": %s", expected)
+	}
+	fmt.Fprintf(&b, "\n  last attempts: %s", strings.Join(result.history, ", "))
+	if result.lastErr != nil {
+		fmt.Fprintf(&b, "\n  last error: %s", result.lastErr)
+	}
+
+	L.Push(lua.LNil)
+	L.Push(lua.LString(b.String()))
+	return 2
+}
+
+func probeResultTable(L *lua.LState, result *probeResult) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("healthy", lua.LTrue)
+	t.RawSetString("attempts", lua.LNumber(result.attempts))
+	t.RawSetString("duration", lua.LNumber(result.duration.Seconds()))
+	return t
+}
+
+// probeStatuses reads expect_status, a code or a list of codes, and
+// describes it. Without it any 2xx status passes.
+func probeStatuses(v lua.LValue) (map[int]bool, string, error) {
+	codes := map[int]bool{}
+	var want []string
+	switch v := v.(type) {
+	case *lua.LNilType:
+		for code := 200; code < 300; code++ {
+			codes[code] = true
+		}
+		return codes, "2xx", nil
+	case lua.LNumber:
+		codes[int(v)] = true
+		want = append(want, v.String())
+	case *lua.LTable:
+		for i := 1; i <= v.Len(); i++ {
+			n, ok := v.RawGetInt(i).(lua.LNumber)
+			if !ok {
+				return nil, "", fmt.Errorf("expect_status must hold numbers, got %s", v.RawGetInt(i).Type())
+			}
+			codes[int(n)] = true
+			want = append(want, n.String())
+		}
+	default:
+		return nil, "", fmt.Errorf("expect_status must be a number or a list of numbers, got %s", v.Type())
+	}
+	if len(codes) == 0 {
+		return nil, "", fmt.Errorf("expect_status is empty")
+	}
+	return codes, strings.Join(want, " or "), nil
+}
+
+// probeErrorCauses shortens the errors of failed attempts for the list of
+// last attempts; the last error is reported in full
+var probeErrorCauses = []struct{ match, text string }{
+	{"connection refused", "connection refused"},
+	{"connection reset by peer", "connection reset"},
+	{"no such host", "no such host"},
+	{"network is unreachable", "network unreachable"},
+	{"i/o timeout", "timeout"},
+	{"context deadline exceeded", "timeout"},
+	{"certificate", "TLS error"},
+}
+
+func probeErrorText(err error) string {
+	text := err.Error()
+	for _, cause := range probeErrorCauses {
+		if strings.Contains(text, cause.match) {
+			return cause.text
+		}
+	}
+	return text
+}
+
+// optDuration reads a duration option given as "30s" or a number of seconds
+func optDuration(opts *lua.LTable, name string, def time.Duration) (time.Duration, error) {
+	switch v := opts.RawGetString(name).(type) {
+	case *lua.LNilType:
+		return def, nil
+	case lua.LNumber:
+		if v <= 0 {
+			return 0, fmt.Errorf("%s must be positive", name)
+		}
+		return time.Duration(float64(v) * float64(time.Second)), nil
+	case lua.LString:
+		d, err := time.ParseDuration(string(v))
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid %s %q", name, string(v))
+		}
+		return d, nil
+	default:
+		return 0, fmt.Errorf("%s must be a duration such as \"5s\"", name)
+	}
+}
+
+func optString(opts *lua.LTable, name string) string {
+	if s, ok := opts.RawGetString(name).(lua.LString); ok {
+		return string(s)
+	}
+	return ""
+}
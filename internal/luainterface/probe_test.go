@@ -0,0 +1,92 @@
+package luainterface
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func runProbe(t *testing.T, script string) (lua.LValue, string) {
+	t.Helper()
+	L := lua.NewState()
+	defer L.Close()
+	RegisterProbeModule(L)
+	require.NoError(t, L.DoString("result, err = "+script))
+	err, _ := L.GetGlobal("err").(lua.LString)
+	return L.GetGlobal("result"), string(err)
+}
+
+func TestProbeHTTP_WaitsUntilHealthy(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status": "up"}`))
+	}))
+	defer server.Close()
+
+	result, errMsg := runProbe(t, `probe.http{url = "`+server.URL+`", expect_status = 200, expect_body = "up", interval = "10ms", timeout = "5s"}`)
+	require.Empty(t, errMsg)
+	table := result.(*lua.LTable)
+	assert.Equal(t, lua.LTrue, table.RawGetString("healthy"))
+	assert.Equal(t, lua.LNumber(200), table.RawGetString("status"))
+	assert.Equal(t, lua.LNumber(3), table.RawGetString("attempts"))
+}
+
+func TestProbeHTTP_TimesOutWithDiagnostics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	result, errMsg := runProbe(t, `probe.http{url = "`+server.URL+`", expect_status = {200, 204}, interval = "10ms", timeout = "100ms"}`)
+	assert.Equal(t, lua.LNil, result)
+	assert.Contains(t, errMsg, "not healthy after")
+	assert.Contains(t, errMsg, "expected status 200 or 204")
+	assert.Contains(t, errMsg, "last attempts: 502, 502")
+	assert.Contains(t, errMsg, "last error: status 502")
+}
+
+func TestProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	result, errMsg := runProbe(t, `probe.tcp{host = "127.0.0.1", port = `+strconv.Itoa(port)+`, interval = "10ms", timeout = "1s"}`)
+	require.Empty(t, errMsg)
+	assert.Equal(t, lua.LTrue, result.(*lua.LTable).RawGetString("healthy"))
+
+	// Nothing listens once it is closed
+	listener.Close()
+	result, errMsg = runProbe(t, `probe.tcp{address = "127.0.0.1:`+strconv.Itoa(port)+`", interval = "10ms", timeout = "50ms"}`)
+	assert.Equal(t, lua.LNil, result)
+	assert.Contains(t, errMsg, "connection refused")
+}
+
+func TestProbe_InvalidOptions(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	RegisterProbeModule(L)
+
+	for _, script := range []string{
+		`probe.http{}`,
+		`probe.http{url = "http://localhost", interval = "soon"}`,
+		`probe.http{url = "http://localhost", expect_status = "ok"}`,
+		`probe.tcp{host = "localhost"}`,
+	} {
+		err := L.DoString(script)
+		if assert.Error(t, err, script) {
+			assert.False(t, strings.Contains(err.Error(), "not healthy"), script)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RegisterProgressModule registers the progress module, with which long
+// tasks report how far along they are:
+//
+//	progress.update{percent = 40, message = "uploading artifacts"}
+//
+// Updates are shown live in the run output and the web UI, and kept in the
+// run's history as the task's timeline. Either field may be left out.
+// Outside of a task, updates are ignored.
+func RegisterProgressModule(L *lua.LState) {
+	progressTable := L.NewTable()
+	L.SetField(progressTable, "update", L.NewFunction(luaProgressUpdate))
+	L.SetGlobal("progress", progressTable)
+}
+
+func luaProgressUpdate(L *lua.LState) int {
+	opts := L.CheckTable(1)
+
+	p := taskctx.Progress{Percent: taskctx.NoPercent}
+	switch percent := opts.RawGetString("percent").(type) {
+	case lua.LNumber:
+		if percent < 0 || percent > 100 {
+			L.ArgError(1, "percent must be between 0 and 100")
+		}
+		p.Percent = float64(percent)
+	case *lua.LNilType:
+	default:
+		L.ArgError(1, "percent must be a number")
+	}
+	if message := opts.RawGetString("message"); message != lua.LNil {
+		p.Message = message.String()
+	}
+	if p.Percent == taskctx.NoPercent && p.Message == "" {
+		L.ArgError(1, "percent or message is required")
+	}
+
+	taskctx.ReportProgress(L.Context(), p)
+	return 0
+}
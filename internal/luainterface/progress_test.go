@@ -0,0 +1,59 @@
+package luainterface
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestProgressUpdate(t *testing.T) {
+	var got []taskctx.Progress
+	ctx := taskctx.WithProgress(context.Background(), func(p taskctx.Progress) {
+		got = append(got, p)
+	})
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterProgressModule(L)
+	L.SetContext(ctx)
+
+	err := L.DoString(`
+		progress.update{percent = 40, message = "uploading artifacts"}
+		progress.update{message = "waiting for the load balancer"}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d updates, want 2", len(got))
+	}
+	if got[0].Percent != 40 || got[0].Message != "uploading artifacts" || got[0].Time.IsZero() {
+		t.Errorf("first update = %+v", got[0])
+	}
+	if got[1].Percent != taskctx.NoPercent || got[1].Message != "waiting for the load balancer" {
+		t.Errorf("second update = %+v", got[1])
+	}
+
+	for script, want := range map[string]string{
+		`progress.update{percent = 140}`:   "between 0 and 100",
+		`progress.update{percent = "40%"}`: "must be a number",
+		`progress.update{}`:                "percent or message is required",
+	} {
+		if err := L.DoString(script); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", script, err, want)
+		}
+	}
+}
+
+func TestProgressUpdate_OutsideTask(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	RegisterProgressModule(L)
+
+	if err := L.DoString(`progress.update{percent = 50}`); err != nil {
+		t.Errorf("updates outside of a task should be ignored, got %v", err)
+	}
+}
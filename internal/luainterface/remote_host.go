@@ -0,0 +1,135 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// remoteHostAware lists the modules that work on the host of a task
+// delegated over SSH (delegate_to = {ssh = ...}). "*" stands for every
+// function of the module.
+var remoteHostAware = map[string][]string{
+	"exec": {"*"},
+	"fs":   {"*"},
+	// Requests and utilities; download writes to the runner's disk
+	"http": {"build_url", "client", "delete", "get", "is_error", "is_success", "new_client",
+		"parse_json", "patch", "post", "put", "request", "to_json", "url_decode", "url_encode"},
+}
+
+// remoteHostIndependentModules neither change nor read the machine they
+// run on, so their calls work the same in tasks delegated over SSH
+var remoteHostIndependentModules = map[string]bool{
+	"_G": true, "__workflows__": true, "async": true, "channel": true, "circuit": true,
+	"coroutine": true, "crypto": true, "data": true, "debug": true, "goroutine": true,
+	"lock": true, "log": true, "math": true, "metrics": true, "notifications": true,
+	"progress": true, "reliability": true, "runtime": true, "saga": true, "stack": true,
+	"state": true, "string": true, "strings": true, "table": true, "task": true,
+	"template": true, "time": true, "utils": true, "validate": true, "workflow": true,
+}
+
+// remoteHostStdlib lists the functions of the Lua standard library that
+// work on the runner's files and processes. io.input and io.output only do
+// when given a file name.
+var remoteHostStdlib = map[string][]string{
+	"io": {"input", "lines", "open", "output", "popen"},
+	"os": {"execute", "remove", "rename", "tmpname"},
+}
+
+// OpenRemoteHost wraps the module functions that only work on the machine
+// running the task, so that in tasks delegated over SSH they fail instead
+// of changing the runner in place of the host. Modules set as globals or
+// loaded with require later on are wrapped when they show up.
+func OpenRemoteHost(L *lua.LState) {
+	wrapped := make(map[*lua.LTable]bool)
+	wrap := func(module string, table *lua.LTable, goOnly bool) {
+		if wrapped[table] || remoteHostIndependentModules[module] {
+			return
+		}
+		wrapped[table] = true
+		wrapRemoteHostModule(L, module, table, goOnly)
+	}
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		if table, ok := value.(*lua.LTable); ok {
+			wrap(key.String(), table, false)
+		}
+	})
+
+	globals := L.NewTable()
+	globals.RawSetString("__newindex", L.NewFunction(func(L *lua.LState) int {
+		key, value := L.CheckAny(2), L.CheckAny(3)
+		if table, ok := value.(*lua.LTable); ok {
+			wrap(key.String(), table, true)
+		}
+		L.G.Global.RawSet(key, value)
+		return 0
+	}))
+	L.SetMetatable(L.G.Global, globals)
+
+	if require, ok := L.GetGlobal("require").(*lua.LFunction); ok {
+		L.G.Global.RawSetString("require", L.NewFunction(func(L *lua.LState) int {
+			module := L.CheckString(1)
+			L.Push(require)
+			L.Push(lua.LString(module))
+			L.Call(1, 1)
+			if table, ok := L.Get(-1).(*lua.LTable); ok {
+				wrap(module, table, true)
+			}
+			return 1
+		}))
+	}
+}
+
+// wrapRemoteHostModule wraps the functions of a module that do not work on
+// the host of a task delegated over SSH. With goOnly, used for modules that
+// show up once the state is set up, functions written in Lua are left
+// alone, so tables the script builds keep working.
+func wrapRemoteHostModule(L *lua.LState, module string, table *lua.LTable, goOnly bool) {
+	var names []string
+	if luaStdlibTables[module] {
+		names = remoteHostStdlib[module]
+	} else {
+		aware := make(map[string]bool)
+		for _, name := range remoteHostAware[module] {
+			aware[name] = true
+		}
+		if aware["*"] {
+			return
+		}
+		table.ForEach(func(k, v lua.LValue) {
+			if fn, ok := v.(*lua.LFunction); ok && (fn.IsG || !goOnly) && !aware[k.String()] {
+				names = append(names, k.String())
+			}
+		})
+	}
+	for _, name := range names {
+		if fn, ok := table.RawGetString(name).(*lua.LFunction); ok {
+			table.RawSetString(name, L.NewFunction(remoteHostWrapper(module, name, fn)))
+		}
+	}
+}
+
+func remoteHostWrapper(module, function string, fn *lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		if remote := taskctx.RemoteHost(L.Context()); remote != nil && remoteHostNamesFile(L, module, function) {
+			L.RaiseError("%s.%s would run on the runner, not on %s: tasks delegated over SSH only run exec and fs calls on the host", module, function, remote.Host())
+			return 0
+		}
+		top := L.GetTop()
+		L.Push(fn)
+		for i := 1; i <= top; i++ {
+			L.Push(L.Get(i))
+		}
+		L.Call(top, lua.MultRet)
+		return L.GetTop() - top
+	}
+}
+
+// remoteHostNamesFile tells whether a call works on the runner: io.input
+// and io.output without a file name only switch between open files
+func remoteHostNamesFile(L *lua.LState, module, function string) bool {
+	if module == "io" && (function == "input" || function == "output") {
+		_, ok := L.Get(1).(lua.LString)
+		return ok
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package luainterface
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeRemote is a host reached over SSH; only its name is used
+type fakeRemote struct {
+	taskctx.Remote
+}
+
+func (fakeRemote) Host() string { return "admin@switch-01" }
+
+func TestOpenRemoteHost_FailsModulesWorkingOnTheRunner(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	calls := map[string]int{}
+	module := func(name string, functions ...string) {
+		table := L.NewTable()
+		for _, function := range functions {
+			call := name + "." + function
+			L.SetField(table, function, L.NewFunction(func(L *lua.LState) int {
+				calls[call]++
+				return 0
+			}))
+		}
+		L.SetGlobal(name, table)
+	}
+	module("pkg", "install")
+	module("fs", "write")
+	module("strings", "split")
+	module("http", "get", "download")
+	OpenRemoteHost(L)
+
+	// Locally every call runs
+	if err := L.DoString(`pkg.install({"nginx"}); http.download("https://example.com/x", "/tmp/x")`); err != nil {
+		t.Fatalf("Expected local calls to run, got %v", err)
+	}
+
+	L.SetContext(taskctx.WithRemote(context.Background(), fakeRemote{}))
+	for _, code := range []string{`pkg.install({"nginx"})`, `http.download("https://example.com/x", "/tmp/x")`, `os.execute("true")`} {
+		err := L.DoString(code)
+		if err == nil || !strings.Contains(err.Error(), "would run on the runner, not on admin@switch-01") {
+			t.Errorf("Expected %s to fail on an SSH delegate, got %v", code, err)
+		}
+	}
+	if err := L.DoString(`fs.write("/etc/motd", "hi"); strings.split("a,b", ","); http.get("https://example.com")`); err != nil {
+		t.Fatalf("Expected remote-capable and host-independent calls to run, got %v", err)
+	}
+
+	want := map[string]int{"pkg.install": 1, "http.download": 1, "fs.write": 1, "strings.split": 1, "http.get": 1}
+	for call, n := range want {
+		if calls[call] != n {
+			t.Errorf("Expected %s to run %d time(s), got %d", call, n, calls[call])
+		}
+	}
+}
+
+func TestOpenRemoteHost_FailsFilesAndLateModules(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	OpenRemoteHost(L)
+
+	calls := 0
+	module := func() *lua.LTable {
+		return L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+			"install": func(L *lua.LState) int { calls++; return 0 },
+		})
+	}
+	L.SetGlobal("pkg", module())
+	L.PreloadModule("apt", func(L *lua.LState) int {
+		L.Push(module())
+		return 1
+	})
+
+	L.SetContext(taskctx.WithRemote(context.Background(), fakeRemote{}))
+	for _, code := range []string{
+		`io.open("/etc/hostname")`,
+		`io.lines("/etc/hostname")`,
+		`io.output("/tmp/out")`,
+		`pkg.install({"nginx"})`,
+		`require("apt").install({"nginx"})`,
+	} {
+		err := L.DoString(code)
+		if err == nil || !strings.Contains(err.Error(), "would run on the runner, not on admin@switch-01") {
+			t.Errorf("Expected %s to fail on an SSH delegate, got %v", code, err)
+		}
+	}
+	if err := L.DoString(`
+		io.output(io.stdout)
+		helpers = {}
+		function helpers.name() return "web" end
+		assert(helpers.name() == "web")
+	`); err != nil {
+		t.Fatalf("Expected calls that leave the runner's files alone to run, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no module call to run, got %d", calls)
+	}
+}
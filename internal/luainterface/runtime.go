@@ -0,0 +1,72 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/nix"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// OpenRuntime registers the runtime module, whose functions describe the
+// environment the commands of a task run in:
+//
+//	runtime = runtime.nix_shell{packages = {"nodejs_20", "python312"}}
+//	runtime = runtime.nix_shell{flake = ".#ci"}
+//
+// Tasks with a Nix runtime run their exec.run commands with nix shell or
+// nix develop --command, and when delegated by facts go to agents
+// reporting the nix fact.
+func OpenRuntime(L *lua.LState) {
+	mod := L.NewTable()
+	L.SetField(mod, "nix_shell", L.NewFunction(runtimeNixShell))
+	L.SetGlobal("runtime", mod)
+}
+
+// runtimeNixShell checks the options of a Nix shell and returns them as
+// the table tasks take as runtime
+func runtimeNixShell(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	shell := nixShellFromLua(opts)
+	if shell == nil {
+		shell = &nix.Shell{}
+	}
+	if err := shell.Validate(); err != nil {
+		L.ArgError(1, err.Error())
+	}
+
+	rt := L.NewTable()
+	rt.RawSetString("kind", lua.LString("nix_shell"))
+	if len(shell.Packages) > 0 {
+		rt.RawSetString("packages", stringListToLua(L, shell.Packages))
+	}
+	if shell.Flake != "" {
+		rt.RawSetString("flake", lua.LString(shell.Flake))
+	}
+	L.Push(rt)
+	return 1
+}
+
+// nixShellFromLua reads a task's runtime field, returning nil unless it
+// describes a Nix shell
+func nixShellFromLua(value lua.LValue) *nix.Shell {
+	tbl, ok := value.(*lua.LTable)
+	if !ok {
+		return nil
+	}
+	if kind := tbl.RawGetString("kind"); kind != lua.LNil && kind.String() != "nix_shell" {
+		return nil
+	}
+	shell := &nix.Shell{}
+	if packages, ok := tbl.RawGetString("packages").(*lua.LTable); ok {
+		packages.ForEach(func(_, p lua.LValue) {
+			if s, ok := p.(lua.LString); ok && s != "" {
+				shell.Packages = append(shell.Packages, string(s))
+			}
+		})
+	}
+	if flake, ok := tbl.RawGetString("flake").(lua.LString); ok {
+		shell.Flake = string(flake)
+	}
+	if len(shell.Packages) == 0 && shell.Flake == "" {
+		return nil
+	}
+	return shell
+}
@@ -0,0 +1,383 @@
+package luainterface
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/s3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// S3Module works with objects of S3-compatible storage, AWS S3 or MinIO
+type S3Module struct {
+	// defaults are the connection options set with s3.configure
+	defaults map[string]string
+}
+
+// NewS3Module creates a new S3Module
+func NewS3Module() *S3Module {
+	return &S3Module{defaults: make(map[string]string)}
+}
+
+// RegisterS3Module registers the s3 module with the Lua state
+func RegisterS3Module(L *lua.LState) {
+	module := NewS3Module()
+
+	s3Table := L.NewTable()
+	L.SetField(s3Table, "configure", L.NewFunction(module.luaConfigure))
+	L.SetField(s3Table, "put", L.NewFunction(module.luaPut))
+	L.SetField(s3Table, "get", L.NewFunction(module.luaGet))
+	L.SetField(s3Table, "list", L.NewFunction(module.luaList))
+	L.SetField(s3Table, "delete", L.NewFunction(module.luaDelete))
+	L.SetField(s3Table, "presign", L.NewFunction(module.luaPresign))
+	L.SetField(s3Table, "sync_dir", L.NewFunction(module.luaSyncDir))
+
+	L.SetGlobal("s3", s3Table)
+}
+
+// s3ConnectionOptions are the options that pick the endpoint and the
+// credentials. The _secret ones name entries of the secrets table.
+var s3ConnectionOptions = []string{
+	"endpoint", "region", "access_key", "secret_key", "session_token",
+	"access_key_secret", "secret_key_secret", "session_token_secret",
+}
+
+// luaConfigure sets connection options for the later calls of the run:
+// s3.configure({endpoint = "https://minio:9000", access_key_secret = "minio_key", ...})
+func (m *S3Module) luaConfigure(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	for _, name := range s3ConnectionOptions {
+		if v := opts.RawGetString(name); v != lua.LNil {
+			m.defaults[name] = v.String()
+		}
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// client creates a client from the connection options of opts over the
+// configured ones. Credentials come from the options, from the secrets
+// table, or from the AWS_* environment variables.
+func (m *S3Module) client(L *lua.LState, opts *lua.LTable) (*s3.Client, error) {
+	option := func(name string) string {
+		if opts != nil {
+			if v := opts.RawGetString(name); v != lua.LNil {
+				return v.String()
+			}
+		}
+		return m.defaults[name]
+	}
+	credential := func(name string) (string, error) {
+		if v := option(name); v != "" {
+			return v, nil
+		}
+		secret := option(name + "_secret")
+		if secret == "" {
+			return "", nil
+		}
+		secrets, ok := L.GetGlobal("secrets").(*lua.LTable)
+		if !ok {
+			return "", fmt.Errorf("secret %q not found: the run has no secrets (run it with --stack and the secrets password)", secret)
+		}
+		v, ok := secrets.RawGetString(secret).(lua.LString)
+		if !ok {
+			return "", fmt.Errorf("secret %q not found in the stack's secrets", secret)
+		}
+		return string(v), nil
+	}
+
+	config := s3.Config{Endpoint: option("endpoint"), Region: option("region")}
+	var err error
+	if config.AccessKey, err = credential("access_key"); err != nil {
+		return nil, err
+	}
+	if config.SecretKey, err = credential("secret_key"); err != nil {
+		return nil, err
+	}
+	if config.SessionToken, err = credential("session_token"); err != nil {
+		return nil, err
+	}
+	return s3.NewClient(config)
+}
+
+// s3PutOptions reads the upload options of opts
+func s3PutOptions(opts *lua.LTable) s3.PutOptions {
+	var put s3.PutOptions
+	if opts == nil {
+		return put
+	}
+	if v, ok := opts.RawGetString("part_size_mb").(lua.LNumber); ok {
+		put.PartSize = int64(v) << 20
+	}
+	if v, ok := opts.RawGetString("content_type").(lua.LString); ok {
+		put.ContentType = string(v)
+	}
+	return put
+}
+
+// s3URL names an object in changes and messages
+func s3URL(bucket, key string) string {
+	return "s3://" + bucket + "/" + key
+}
+
+// pushS3Error pushes nil and err, the result of a failed call
+func pushS3Error(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// pushS3Planned pushes the result of a call whose change was recorded in
+// the plan instead of made
+func pushS3Planned(L *lua.LState, message string) int {
+	result := L.NewTable()
+	L.SetField(result, "changed", lua.LTrue)
+	L.SetField(result, "check_mode", lua.LTrue)
+	L.SetField(result, "message", lua.LString(message))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaPut uploads a file, in parts when it's large, unless the object
+// already has its content: s3.put(bucket, key, path, {part_size_mb = 64})
+func (m *S3Module) luaPut(L *lua.LState) int {
+	bucket, key, path := L.CheckString(1), L.CheckString(2), L.CheckString(3)
+	opts := L.OptTable(4, nil)
+	client, err := m.client(L, opts)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+	ctx := callContext(L)
+	putOpts := s3PutOptions(opts)
+
+	etag, err := s3.FileETag(path, putOpts.PartSize)
+	if err != nil {
+		return pushS3Error(L, fmt.Errorf("failed to read %s: %w", path, err))
+	}
+	current := "absent"
+	existing, err := client.Stat(ctx, bucket, key)
+	switch {
+	case err == nil && existing.ETag == etag:
+		result := L.NewTable()
+		L.SetField(result, "changed", lua.LFalse)
+		L.SetField(result, "bucket", lua.LString(bucket))
+		L.SetField(result, "key", lua.LString(key))
+		L.SetField(result, "size", lua.LNumber(existing.Size))
+		L.SetField(result, "etag", lua.LString(existing.ETag))
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	case err == nil:
+		current = "differs"
+	case !errors.Is(err, s3.ErrNotFound):
+		return pushS3Error(L, err)
+	}
+
+	change := taskctx.Change{Module: "s3", Action: "put", Target: s3URL(bucket, key), Current: current}
+	if taskctx.RecordChange(L.Context(), change) {
+		return pushS3Planned(L, "Would upload "+path+" to "+s3URL(bucket, key))
+	}
+	obj, err := client.PutFile(ctx, bucket, key, path, putOpts)
+	if err != nil {
+		return pushS3Error(L, fmt.Errorf("failed to upload %s to %s: %w", path, s3URL(bucket, key), err))
+	}
+	taskctx.ReportChange(L.Context(), change)
+
+	result := L.NewTable()
+	L.SetField(result, "changed", lua.LTrue)
+	L.SetField(result, "bucket", lua.LString(bucket))
+	L.SetField(result, "key", lua.LString(key))
+	L.SetField(result, "size", lua.LNumber(obj.Size))
+	L.SetField(result, "etag", lua.LString(obj.ETag))
+	L.SetField(result, "sha256", lua.LString(obj.SHA256))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaGet downloads an object to dest and verifies its checksum:
+// s3.get(bucket, key, dest, {mode = "0600"})
+func (m *S3Module) luaGet(L *lua.LState) int {
+	bucket, key, dest := L.CheckString(1), L.CheckString(2), L.CheckString(3)
+	opts := L.OptTable(4, nil)
+	mode, err := optFileMode(L, opts, "mode", 0644)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+	client, err := m.client(L, opts)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+
+	obj, err := client.GetFile(callContext(L), bucket, key, dest, mode)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+	result := L.NewTable()
+	L.SetField(result, "path", lua.LString(dest))
+	L.SetField(result, "size", lua.LNumber(obj.Size))
+	L.SetField(result, "etag", lua.LString(obj.ETag))
+	L.SetField(result, "sha256", lua.LString(obj.SHA256))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaList lists the objects under a prefix: s3.list(bucket, "backups/")
+func (m *S3Module) luaList(L *lua.LState) int {
+	bucket := L.CheckString(1)
+	prefix := L.OptString(2, "")
+	client, err := m.client(L, L.OptTable(3, nil))
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+
+	objects, err := client.List(callContext(L), bucket, prefix)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+	list := L.NewTable()
+	for _, o := range objects {
+		item := L.NewTable()
+		L.SetField(item, "key", lua.LString(o.Key))
+		L.SetField(item, "size", lua.LNumber(o.Size))
+		L.SetField(item, "etag", lua.LString(o.ETag))
+		L.SetField(item, "last_modified", lua.LString(o.LastModified.Format(time.RFC3339)))
+		list.Append(item)
+	}
+	L.Push(list)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaDelete deletes an object: s3.delete(bucket, key)
+func (m *S3Module) luaDelete(L *lua.LState) int {
+	bucket, key := L.CheckString(1), L.CheckString(2)
+	client, err := m.client(L, L.OptTable(3, nil))
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+	ctx := callContext(L)
+
+	result := L.NewTable()
+	L.SetField(result, "bucket", lua.LString(bucket))
+	L.SetField(result, "key", lua.LString(key))
+	if _, err := client.Stat(ctx, bucket, key); errors.Is(err, s3.ErrNotFound) {
+		L.SetField(result, "changed", lua.LFalse)
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	} else if err != nil {
+		return pushS3Error(L, err)
+	}
+
+	change := taskctx.Change{Module: "s3", Action: "delete", Target: s3URL(bucket, key), Current: "present"}
+	if taskctx.RecordChange(L.Context(), change) {
+		return pushS3Planned(L, "Would delete "+s3URL(bucket, key))
+	}
+	if err := client.Delete(ctx, bucket, key); err != nil {
+		return pushS3Error(L, err)
+	}
+	taskctx.ReportChange(L.Context(), change)
+	L.SetField(result, "changed", lua.LTrue)
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaPresign returns a URL that grants access to an object without
+// credentials: s3.presign(bucket, key, {method = "GET", expires = "1h"})
+func (m *S3Module) luaPresign(L *lua.LState) int {
+	bucket, key := L.CheckString(1), L.CheckString(2)
+	opts := L.OptTable(3, nil)
+	method, expires := http.MethodGet, time.Hour
+	if opts != nil {
+		if v, ok := opts.RawGetString("method").(lua.LString); ok {
+			method = strings.ToUpper(string(v))
+		}
+		if v, ok := opts.RawGetString("expires").(lua.LString); ok {
+			d, err := time.ParseDuration(string(v))
+			if err != nil {
+				return pushS3Error(L, fmt.Errorf("invalid expires %q: %w", v, err))
+			}
+			expires = d
+		}
+	}
+	client, err := m.client(L, opts)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+
+	u, err := client.Presign(method, bucket, key, expires)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+	L.Push(lua.LString(u))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// luaSyncDir mirrors a directory to the keys under a prefix, uploading the
+// files that changed and, with delete set, deleting the objects whose file
+// is gone: s3.sync_dir(dir, bucket, prefix, {delete = true})
+func (m *S3Module) luaSyncDir(L *lua.LState) int {
+	dir, bucket := L.CheckString(1), L.CheckString(2)
+	prefix := L.OptString(3, "")
+	opts := L.OptTable(4, nil)
+	client, err := m.client(L, opts)
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+	syncOpts := s3.SyncOptions{PutOptions: s3PutOptions(opts), DryRun: taskctx.CheckMode(L.Context())}
+	if opts != nil {
+		syncOpts.Delete = lua.LVAsBool(opts.RawGetString("delete"))
+	}
+
+	synced, err := client.SyncDir(callContext(L), dir, bucket, prefix, syncOpts)
+	if synced != nil {
+		for _, key := range synced.Uploaded {
+			m.recordSync(L, syncOpts.DryRun, "put", bucket, key)
+		}
+		for _, key := range synced.Deleted {
+			m.recordSync(L, syncOpts.DryRun, "delete", bucket, key)
+		}
+	}
+	if err != nil {
+		return pushS3Error(L, err)
+	}
+
+	keys := func(list []string) *lua.LTable {
+		t := L.NewTable()
+		for _, key := range list {
+			t.Append(lua.LString(key))
+		}
+		return t
+	}
+	result := L.NewTable()
+	L.SetField(result, "changed", lua.LBool(synced.Changed()))
+	L.SetField(result, "uploaded", keys(synced.Uploaded))
+	L.SetField(result, "deleted", keys(synced.Deleted))
+	L.SetField(result, "unchanged", keys(synced.Unchanged))
+	if syncOpts.DryRun {
+		L.SetField(result, "check_mode", lua.LTrue)
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// recordSync records an object a sync changed, or would change in check
+// mode
+func (m *S3Module) recordSync(L *lua.LState, dryRun bool, action, bucket, key string) {
+	change := taskctx.Change{Module: "s3", Action: action, Target: s3URL(bucket, key)}
+	if dryRun {
+		taskctx.RecordChange(L.Context(), change)
+	} else {
+		taskctx.ReportChange(L.Context(), change)
+	}
+}
@@ -0,0 +1,171 @@
+package luainterface
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeObjectStore answers the single-request calls of the s3 module from
+// memory, for requests signed with the access key AKID
+func fakeObjectStore(t *testing.T) (*httptest.Server, map[string][]byte) {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") {
+			http.Error(w, "<Error><Code>AccessDenied</Code><Message>no credentials</Message></Error>", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[path] = data
+		case http.MethodDelete:
+			delete(objects, path)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet, http.MethodHead:
+			if !strings.Contains(path, "/") {
+				var keys []string
+				for name := range objects {
+					if bucket, key, _ := strings.Cut(name, "/"); bucket == path && strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+						keys = append(keys, key)
+					}
+				}
+				sort.Strings(keys)
+				fmt.Fprint(w, "<ListBucketResult>")
+				for _, key := range keys {
+					fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size></Contents>", key, len(objects[path+"/"+key]))
+				}
+				fmt.Fprint(w, "</ListBucketResult>")
+				return
+			}
+			data, ok := objects[path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			sum := md5.Sum(data)
+			w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			if r.Method == http.MethodGet {
+				w.Write(data)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, objects
+}
+
+func TestS3Module(t *testing.T) {
+	srv, objects := fakeObjectStore(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "backup.tar")
+	if err := os.WriteFile(src, []byte("backup"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterS3Module(L)
+	secrets := L.NewTable()
+	secrets.RawSetString("minio_access", lua.LString("AKID"))
+	secrets.RawSetString("minio_secret", lua.LString("secret"))
+	L.SetGlobal("secrets", secrets)
+	L.SetGlobal("endpoint", lua.LString(srv.URL))
+	L.SetGlobal("src", lua.LString(src))
+	L.SetGlobal("dest", lua.LString(filepath.Join(dir, "restored.tar")))
+
+	script := `
+		s3.configure({endpoint = endpoint, access_key_secret = "minio_access", secret_key_secret = "minio_secret"})
+		first, err = s3.put("backups", "db/backup.tar", src)
+		assert(err == nil, err)
+		second = s3.put("backups", "db/backup.tar", src)
+		got, err = s3.get("backups", "db/backup.tar", dest)
+		assert(err == nil, err)
+		list = s3.list("backups", "db/")
+		url = s3.presign("backups", "db/backup.tar", {expires = "10m"})
+		deleted = s3.delete("backups", "db/backup.tar")
+		again = s3.delete("backups", "db/backup.tar")
+		_, missing = s3.list("backups", "", {access_key_secret = "nope"})
+	`
+	if err := L.DoString(script); err != nil {
+		t.Fatal(err)
+	}
+
+	field := func(global, name string) lua.LValue {
+		return L.GetGlobal(global).(*lua.LTable).RawGetString(name)
+	}
+	if field("first", "changed") != lua.LTrue || field("second", "changed") != lua.LFalse {
+		t.Errorf("Expected the second put of the same file to change nothing")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "restored.tar")); string(data) != "backup" {
+		t.Errorf("Unexpected download %q", data)
+	}
+	if list := L.GetGlobal("list").(*lua.LTable); list.Len() != 1 || list.RawGetInt(1).(*lua.LTable).RawGetString("key").String() != "db/backup.tar" {
+		t.Errorf("Unexpected list")
+	}
+	if url := L.GetGlobal("url").String(); !strings.HasPrefix(url, srv.URL+"/backups/db/backup.tar?") || !strings.Contains(url, "X-Amz-Expires=600") {
+		t.Errorf("Unexpected presigned URL %s", url)
+	}
+	if field("deleted", "changed") != lua.LTrue || field("again", "changed") != lua.LFalse || len(objects) != 0 {
+		t.Errorf("Expected the object to be deleted once, left %v", objects)
+	}
+	if missing := L.GetGlobal("missing").String(); !strings.Contains(missing, `secret "nope" not found`) {
+		t.Errorf("Expected a missing secret error, got %q", missing)
+	}
+}
+
+func TestS3Module_CheckMode(t *testing.T) {
+	srv, objects := fakeObjectStore(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterS3Module(L)
+	plan := &taskctx.Plan{}
+	L.SetContext(taskctx.WithPlan(context.Background(), plan))
+	L.SetGlobal("endpoint", lua.LString(srv.URL))
+	L.SetGlobal("dir", lua.LString(dir))
+
+	if err := L.DoString(`
+		put = s3.put("site", "index.html", dir .. "/index.html", {endpoint = endpoint})
+		sync = s3.sync_dir(dir, "site", "www", {endpoint = endpoint})
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("Expected check mode to upload nothing, got %v", objects)
+	}
+	want := []taskctx.Change{
+		{Module: "s3", Action: "put", Target: "s3://site/index.html", Current: "absent"},
+		{Module: "s3", Action: "put", Target: "s3://site/www/index.html"},
+	}
+	if got := plan.Changes(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected plan %v, got %v", want, got)
+	}
+	if L.GetGlobal("sync").(*lua.LTable).RawGetString("check_mode") != lua.LTrue {
+		t.Error("Expected the sync to report check mode")
+	}
+}
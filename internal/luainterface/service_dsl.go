@@ -0,0 +1,183 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Services are declared with service() and attached to a workflow; tasks
+// list the services they need:
+//
+//	local db = service("test-db")
+//		:command("docker run --rm -p 5432:5432 postgres:16")
+//		:ready_port(5432)
+//
+//	workflow.define("integration")
+//		:services({ db })
+//		:tasks({ task("migrate"):services({ "test-db" }):command(...):build() })
+
+// ServiceBuilder provides fluent API for service construction
+type ServiceBuilder struct {
+	service *types.Service
+}
+
+// registerServiceDefinition registers service() and the ServiceBuilder metatable
+func (m *ModernDSL) registerServiceDefinition(L *lua.LState) {
+	serviceBuilderMt := L.NewTypeMetatable("ServiceBuilder")
+	L.SetField(serviceBuilderMt, "__index", L.NewFunction(m.serviceBuilderIndex))
+
+	L.SetGlobal("service", L.NewFunction(m.serviceBuilderFunc))
+}
+
+// serviceBuilderFunc implements service("name") and service("name", {opts})
+func (m *ModernDSL) serviceBuilderFunc(L *lua.LState) int {
+	builder := &ServiceBuilder{service: &types.Service{Name: L.CheckString(1)}}
+	if opts := L.OptTable(2, nil); opts != nil {
+		applyServiceTable(builder.service, opts)
+	}
+
+	ud := L.NewUserData()
+	ud.Value = builder
+	L.SetMetatable(ud, L.GetTypeMetatable("ServiceBuilder"))
+	L.Push(ud)
+	return 1
+}
+
+// serviceBuilderIndex handles method calls on ServiceBuilder objects
+func (m *ModernDSL) serviceBuilderIndex(L *lua.LState) int {
+	ud := L.CheckUserData(1)
+	key := L.CheckString(2)
+
+	builder, ok := ud.Value.(*ServiceBuilder)
+	if !ok {
+		L.ArgError(1, "ServiceBuilder expected")
+		return 0
+	}
+	svc := builder.service
+
+	chain := func(set func(L *lua.LState)) {
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			set(L)
+			L.Push(ud) // Return self for chaining
+			return 1
+		}))
+	}
+
+	switch key {
+	case "command":
+		chain(func(L *lua.LState) { svc.Command = L.CheckString(2) })
+	case "workdir":
+		chain(func(L *lua.LState) { svc.Workdir = L.CheckString(2) })
+	case "env":
+		chain(func(L *lua.LState) { svc.Env = luaStringMap(L.CheckTable(2)) })
+	case "ready_port":
+		chain(func(L *lua.LState) { svc.ReadyPort = L.CheckInt(2) })
+	case "ready_command":
+		chain(func(L *lua.LState) { svc.ReadyCommand = L.CheckString(2) })
+	case "ready_timeout":
+		chain(func(L *lua.LState) { svc.ReadyTimeout = L.CheckString(2) })
+	case "build":
+		// Services need no build step; it is accepted for symmetry with task()
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			L.Push(ud)
+			return 1
+		}))
+	case "name":
+		L.Push(lua.LString(svc.Name))
+	default:
+		L.Push(lua.LNil)
+	}
+	return 1
+}
+
+// applyServiceTable sets the fields of a service from a Lua table
+func applyServiceTable(svc *types.Service, table *lua.LTable) {
+	if v := table.RawGetString("name"); v.Type() == lua.LTString {
+		svc.Name = v.String()
+	}
+	if v := table.RawGetString("command"); v.Type() == lua.LTString {
+		svc.Command = v.String()
+	}
+	if v := table.RawGetString("workdir"); v.Type() == lua.LTString {
+		svc.Workdir = v.String()
+	}
+	if v, ok := table.RawGetString("env").(*lua.LTable); ok {
+		svc.Env = luaStringMap(v)
+	}
+	if v, ok := table.RawGetString("ready_port").(lua.LNumber); ok {
+		svc.ReadyPort = int(v)
+	}
+	if v := table.RawGetString("ready_command"); v.Type() == lua.LTString {
+		svc.ReadyCommand = v.String()
+	}
+	if v := table.RawGetString("ready_timeout"); v.Type() == lua.LTString {
+		svc.ReadyTimeout = v.String()
+	}
+}
+
+// parseLuaServices converts a workflow's services: a list of service()
+// values or tables, or a table of tables keyed by service name
+func parseLuaServices(value lua.LValue) []types.Service {
+	table, ok := value.(*lua.LTable)
+	if !ok {
+		return nil
+	}
+
+	var services []types.Service
+	table.ForEach(func(key, v lua.LValue) {
+		var svc types.Service
+		switch entry := v.(type) {
+		case *lua.LUserData:
+			builder, ok := entry.Value.(*ServiceBuilder)
+			if !ok {
+				return
+			}
+			svc = *builder.service
+		case *lua.LTable:
+			if key.Type() == lua.LTString {
+				svc.Name = key.String()
+			}
+			applyServiceTable(&svc, entry)
+		default:
+			return
+		}
+		services = append(services, svc)
+	})
+	return services
+}
+
+// serviceNames converts the services a task needs: a name, a service()
+// value or a list of either
+func serviceNames(value lua.LValue) []string {
+	switch v := value.(type) {
+	case lua.LString:
+		return []string{string(v)}
+	case *lua.LUserData:
+		if builder, ok := v.Value.(*ServiceBuilder); ok {
+			return []string{builder.service.Name}
+		}
+	case *lua.LTable:
+		var names []string
+		v.ForEach(func(_, item lua.LValue) {
+			names = append(names, serviceNames(item)...)
+		})
+		return names
+	}
+	return nil
+}
+
+func stringListToLua(L *lua.LState, list []string) *lua.LTable {
+	table := L.NewTable()
+	for _, item := range list {
+		table.Append(lua.LString(item))
+	}
+	return table
+}
+
+func luaStringMap(table *lua.LTable) map[string]string {
+	m := make(map[string]string)
+	table.ForEach(func(k, v lua.LValue) {
+		m[k.String()] = v.String()
+	})
+	return m
+}
@@ -0,0 +1,45 @@
+package luainterface
+
+import (
+	"bytes"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// DoSlothFile runs a sloth file like L.DoFile, skipping the front-matter
+// of v2 files. Templated files are rendered with the values global first.
+func DoSlothFile(L *lua.LState, path string) error {
+	var values map[string]interface{}
+	if tbl, ok := L.GetGlobal("values").(*lua.LTable); ok {
+		values, _ = LuaToGoValue(L, tbl).(map[string]interface{})
+	}
+	meta, body, err := sloth.RenderFile(path, values)
+	if err != nil {
+		return err
+	}
+	if meta.Format == sloth.FormatV1 {
+		return L.DoFile(path)
+	}
+
+	fn, err := L.Load(bytes.NewReader(body), path)
+	if err != nil {
+		return err
+	}
+	L.Push(fn)
+	return L.PCall(0, lua.MultRet, nil)
+}
+
+// ModuleAvailable reports whether a module is registered in L, either as
+// a global or as a module that can be required
+func ModuleAvailable(L *lua.LState, name string) bool {
+	if L.GetGlobal(name) != lua.LNil {
+		return true
+	}
+	if pkg, ok := L.GetGlobal("package").(*lua.LTable); ok {
+		if preload, ok := pkg.RawGetString("preload").(*lua.LTable); ok {
+			return preload.RawGetString(name) != lua.LNil
+		}
+	}
+	return false
+}
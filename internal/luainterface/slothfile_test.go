@@ -0,0 +1,68 @@
+package luainterface
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestDoSlothFile_FrontMatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy.sloth")
+	content := "---\nname: deploy\nversion: 1.0.0\n---\nresult = 'ran'\nerror('boom')\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	err := DoSlothFile(L, path)
+	if L.GetGlobal("result").String() != "ran" {
+		t.Errorf("Expected the Lua body to run")
+	}
+	// Line numbers count the front-matter lines
+	if err == nil || !strings.Contains(err.Error(), "deploy.sloth:6") {
+		t.Errorf("Expected error at line 6, got %v", err)
+	}
+}
+
+func TestDoSlothFile_Template(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "matrix.sloth")
+	content := "---\ntemplate: true\nvars:\n  env: staging\n  hosts: [a, b]\n---\n" +
+		"hosts = {}\n{{ range .vars.hosts }}table.insert(hosts, \"{{ $.vars.env }}-{{ . }}\")\n{{ end }}"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	values := L.NewTable()
+	values.RawSetString("env", lua.LString("production"))
+	L.SetGlobal("values", values)
+
+	if err := DoSlothFile(L, path); err != nil {
+		t.Fatalf("DoSlothFile failed: %v", err)
+	}
+	hosts := L.GetGlobal("hosts").(*lua.LTable)
+	if hosts.Len() != 2 || hosts.RawGetInt(2).String() != "production-b" {
+		t.Errorf("Expected the template rendered with values, got %d hosts", hosts.Len())
+	}
+}
+
+func TestModuleAvailable(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	RegisterAllModules(L)
+
+	for _, name := range []string{"pkg", "user", "exec"} {
+		if !ModuleAvailable(L, name) {
+			t.Errorf("Expected module %s to be available", name)
+		}
+	}
+	if ModuleAvailable(L, "no_such_module") {
+		t.Error("Expected no_such_module to be unavailable")
+	}
+}
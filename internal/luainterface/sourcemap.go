@@ -0,0 +1,275 @@
+package luainterface
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// snippetContext is how many lines are shown around a failing line
+const snippetContext = 2
+
+// importErrorPrefix is how import() wraps errors of imported files
+const importErrorPrefix = "error executing imported file: "
+
+var (
+	// sourceLocationPattern matches "file.sloth:12: " in Lua errors
+	sourceLocationPattern = regexp.MustCompile(`((?:[A-Za-z]:)?[^\s:<>"'()]+\.(?:sloth|lua)):(\d+): `)
+	// syntaxErrorPattern matches gopher-lua parse errors:
+	// "file.sloth line:3(column:5) near 'x':   syntax error"
+	syntaxErrorPattern = regexp.MustCompile(`((?:[A-Za-z]:)?[^\s:<>"'()]+\.(?:sloth|lua)) line:(\d+)\(column:\d+\) near '(.*)':\s*(.+)`)
+	// syntaxErrorAtEOFPattern matches parse errors at the end of a file
+	syntaxErrorAtEOFPattern = regexp.MustCompile(`((?:[A-Za-z]:)?[^\s:<>"'()]+\.(?:sloth|lua)) at EOF:\s*(.+)`)
+	// traceLocationPattern matches the workflow frames of a stack traceback
+	traceLocationPattern = regexp.MustCompile(`^\s*((?:[A-Za-z]:)?[^\s:<>"'()]+\.(?:sloth|lua)):(\d+): in `)
+)
+
+// SourceLocation is a line in a workflow file
+type SourceLocation struct {
+	File string
+	Line int
+}
+
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", displayPath(l.File), l.Line)
+}
+
+// ScriptError is a Lua error mapped back to the workflow source, instead
+// of gopher-lua's message and stack traceback
+type ScriptError struct {
+	SourceLocation
+	Message string
+	// Task is the task the error happened in, if any
+	Task string
+	// Imports lists the import() calls that led to the file, outermost first
+	Imports []SourceLocation
+	// Calls lists the workflow lines that called the failing function,
+	// innermost first
+	Calls []SourceLocation
+	// Err is the original error
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s: %s", e.SourceLocation, e.Message)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// Snippet returns the lines around the failing line, which is marked with
+// ">". It is empty when the file can't be read.
+func (e *ScriptError) Snippet() string {
+	content, err := os.ReadFile(e.File)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if e.Line < 1 || e.Line > len(lines) {
+		return ""
+	}
+
+	first := e.Line - snippetContext
+	if first < 1 {
+		first = 1
+	}
+	last := e.Line + snippetContext
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == e.Line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, n, strings.ReplaceAll(lines[n-1], "\t", "    "))
+	}
+	return b.String()
+}
+
+// Details describes the error for the user: the location, the task, a
+// snippet of the code and how the failing line was reached
+func (e *ScriptError) Details() string {
+	var b strings.Builder
+	b.WriteString(e.Error())
+	if e.Task != "" {
+		fmt.Fprintf(&b, "\n  in task '%s'", e.Task)
+	}
+	if snippet := e.Snippet(); snippet != "" {
+		b.WriteString("\n\n")
+		b.WriteString(snippet)
+	}
+	if len(e.Calls) > 0 || len(e.Imports) > 0 {
+		b.WriteString("\n")
+	}
+	for _, call := range e.Calls {
+		fmt.Fprintf(&b, "  called from %s\n", call)
+	}
+	for i := len(e.Imports) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "  imported from %s\n", e.Imports[i])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Relocate moves the error's locations from one copy of a workflow to
+// another, e.g. from the script an agent ran to the file it came from.
+// Files next to from, such as imports, are moved next to to.
+func (e *ScriptError) Relocate(from, to string) {
+	move := func(l *SourceLocation) {
+		if l.File == from {
+			l.File = to
+			return
+		}
+		if rel, err := filepath.Rel(filepath.Dir(from), l.File); err == nil && !strings.HasPrefix(rel, "..") {
+			l.File = filepath.Join(filepath.Dir(to), rel)
+		}
+	}
+	move(&e.SourceLocation)
+	for i := range e.Imports {
+		move(&e.Imports[i])
+	}
+	for i := range e.Calls {
+		move(&e.Calls[i])
+	}
+}
+
+// MapError maps a Lua error to a *ScriptError pointing at the workflow
+// file and line it was raised at. Errors without a workflow location are
+// returned unchanged.
+func MapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var scriptErr *ScriptError
+	if errors.As(err, &scriptErr) {
+		return err
+	}
+
+	msg := err.Error()
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) && apiErr.Object != nil {
+		msg = apiErr.Object.String() + "\n" + apiErr.StackTrace
+	}
+	if mapped := ParseScriptError(msg); mapped != nil {
+		mapped.Err = err
+		return mapped
+	}
+	return err
+}
+
+// ParseScriptError finds the workflow location in a Lua error message and
+// its stack traceback, or returns nil if there is none
+func ParseScriptError(msg string) *ScriptError {
+	head := msg
+	if i := strings.Index(msg, "stack traceback:"); i >= 0 {
+		head = msg[:i]
+	}
+
+	e := &ScriptError{}
+	if m := sourceLocationPattern.FindStringSubmatchIndex(head); m != nil {
+		e.File = head[m[2]:m[3]]
+		e.Line, _ = strconv.Atoi(head[m[4]:m[5]])
+		rest := head[m[1]:]
+
+		// Follow import() into the imported file
+		for strings.HasPrefix(rest, importErrorPrefix) {
+			inner := rest[len(importErrorPrefix):]
+			loc := sourceLocationPattern.FindStringSubmatchIndex(inner)
+			if loc == nil || loc[0] != 0 {
+				if nested := parseSyntaxError(inner); nested != nil {
+					e.Imports = append(e.Imports, e.SourceLocation)
+					e.SourceLocation, rest = nested.SourceLocation, nested.Message
+				}
+				break
+			}
+			e.Imports = append(e.Imports, e.SourceLocation)
+			e.File = inner[loc[2]:loc[3]]
+			e.Line, _ = strconv.Atoi(inner[loc[4]:loc[5]])
+			rest = inner[loc[1]:]
+		}
+		e.Message = firstLine(rest)
+	} else if syntax := parseSyntaxError(head); syntax != nil {
+		e = syntax
+	} else {
+		return nil
+	}
+
+	// The traceback of the outermost error is last; import() embeds the
+	// inner tracebacks in the message
+	for _, line := range strings.Split(msg, "\n") {
+		m := traceLocationPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		loc := SourceLocation{File: m[1], Line: n}
+		if loc == e.SourceLocation || containsLocation(e.Imports, loc) || containsLocation(e.Calls, loc) {
+			continue
+		}
+		e.Calls = append(e.Calls, loc)
+	}
+	return e
+}
+
+func parseSyntaxError(msg string) *ScriptError {
+	if m := syntaxErrorPattern.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		return &ScriptError{
+			SourceLocation: SourceLocation{File: m[1], Line: line},
+			Message:        fmt.Sprintf("%s near '%s'", strings.TrimSpace(m[4]), m[3]),
+		}
+	}
+	if m := syntaxErrorAtEOFPattern.FindStringSubmatch(msg); m != nil {
+		return &ScriptError{
+			SourceLocation: SourceLocation{File: m[1], Line: countLines(m[1])},
+			Message:        fmt.Sprintf("%s at end of file", strings.TrimSpace(firstLine(m[2]))),
+		}
+	}
+	return nil
+}
+
+func containsLocation(list []SourceLocation, loc SourceLocation) bool {
+	for _, l := range list {
+		if l == loc {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func countLines(path string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	return len(strings.Split(strings.TrimRight(string(content), "\n"), "\n"))
+}
+
+// displayPath shows paths under the working directory relative to it
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil || !filepath.IsAbs(path) {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
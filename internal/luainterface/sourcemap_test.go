@@ -0,0 +1,99 @@
+package luainterface
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func writeWorkflow(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestMapError_RuntimeError(t *testing.T) {
+	dir := t.TempDir()
+	mainFile := writeWorkflow(t, dir, "main.sloth", `local x = 1
+
+local function deploy()
+	local cfg = nil
+	return cfg.name
+end
+
+deploy()
+`)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	err := MapError(DoSlothFile(L, mainFile))
+	var scriptErr *ScriptError
+	require.True(t, errors.As(err, &scriptErr), "got %v", err)
+
+	assert.Equal(t, mainFile, scriptErr.File)
+	assert.Equal(t, 5, scriptErr.Line)
+	assert.Contains(t, scriptErr.Message, "attempt to index")
+	assert.Contains(t, scriptErr.Calls, SourceLocation{File: mainFile, Line: 8})
+
+	scriptErr.Task = "deploy"
+	details := scriptErr.Details()
+	assert.Contains(t, details, "in task 'deploy'")
+	assert.Contains(t, details, "> 5 |     return cfg.name")
+	assert.Contains(t, details, "  3 | local function deploy()")
+	assert.NotContains(t, details, "stack traceback")
+}
+
+func TestMapError_SyntaxError(t *testing.T) {
+	dir := t.TempDir()
+	mainFile := writeWorkflow(t, dir, "main.sloth", `local a = 1
+local b = = 2
+`)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	err := MapError(DoSlothFile(L, mainFile))
+	var scriptErr *ScriptError
+	require.True(t, errors.As(err, &scriptErr), "got %v", err)
+	assert.Equal(t, mainFile, scriptErr.File)
+	assert.Equal(t, 2, scriptErr.Line)
+	assert.Contains(t, scriptErr.Message, "near '='")
+}
+
+func TestMapError_ThroughImport(t *testing.T) {
+	dir := t.TempDir()
+	libFile := writeWorkflow(t, dir, "lib.sloth", `local lib = {}
+error("bad config")
+return lib
+`)
+	mainFile := writeWorkflow(t, dir, "main.sloth", `-- main
+local lib = import("lib.sloth")
+`)
+
+	L := lua.NewState()
+	defer L.Close()
+	OpenImport(L, mainFile)
+
+	err := MapError(DoSlothFile(L, mainFile))
+	var scriptErr *ScriptError
+	require.True(t, errors.As(err, &scriptErr), "got %v", err)
+
+	assert.Equal(t, libFile, scriptErr.File)
+	assert.Equal(t, 2, scriptErr.Line)
+	assert.Equal(t, "bad config", scriptErr.Message)
+	assert.Equal(t, []SourceLocation{{File: mainFile, Line: 2}}, scriptErr.Imports)
+	assert.Contains(t, scriptErr.Details(), "imported from")
+}
+
+func TestMapError_WithoutLocation(t *testing.T) {
+	err := errors.New("connection refused")
+	assert.Equal(t, err, MapError(err))
+	assert.Nil(t, MapError(nil))
+}
@@ -57,6 +57,11 @@ func RegisterSSHModule(L *lua.LState) {
 	L.SetField(mod, "remove_authorized_key", L.NewFunction(sshRemoveAuthorizedKey))
 	L.SetField(mod, "list_authorized_keys", L.NewFunction(sshListAuthorizedKeys))
 	L.SetField(mod, "key_exists", L.NewFunction(sshKeyExists))
+	L.SetField(mod, "authorized_keys", L.NewFunction(sshAuthorizedKeys))
+
+	// Certificate authority
+	L.SetField(mod, "ca_sign", L.NewFunction(sshCASign))
+	L.SetField(mod, "ca_trust", L.NewFunction(sshCATrust))
 
 	L.SetGlobal("ssh", mod)
 }
@@ -68,10 +73,20 @@ func sshConnect(L *lua.LState) int {
 	user := L.CheckString(2)
 	options := L.OptTable(3, L.NewTable())
 
-	port := getTableInt(options, "port", 22)
+	port, err := optInt(L, options, "port", 22)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	timeout, err := optInt(L, options, "timeout", 30)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
 	password := getTableString(options, "password", "")
 	keyPath := getTableString(options, "key_path", "")
-	timeout := getTableInt(options, "timeout", 30)
 
 	var authMethods []ssh.AuthMethod
 
@@ -582,7 +597,11 @@ func sshRename(L *lua.LState) int {
 func sshChmod(L *lua.LState) int {
 	ud := L.CheckUserData(1)
 	path := L.CheckString(2)
-	mode := L.CheckInt(3)
+	mode, err := toFileMode(L, "mode", L.CheckAny(3))
+	if err != nil {
+		L.ArgError(3, err.Error())
+		return 0
+	}
 
 	client, ok := ud.Value.(*ssh.Client)
 	if !ok {
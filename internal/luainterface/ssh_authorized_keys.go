@@ -0,0 +1,240 @@
+package luainterface
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshAuthorizedKeys reconciles the authorized_keys of a user to a set of
+// keys. With exclusive, keys that aren't in the set are removed.
+// Usage: local result, err = ssh.authorized_keys({user = "deploy", keys = {...}, exclusive = true})
+func sshAuthorizedKeys(L *lua.LState) int {
+	params := L.CheckTable(1)
+
+	opts := authorizedKeysOptions{
+		user:      getTableString(params, "user", ""),
+		path:      getTableString(params, "path", ""),
+		exclusive: getBoolField(L, params, "exclusive", false),
+		backup:    getBoolField(L, params, "backup", true),
+	}
+	keys, ok := params.RawGetString("keys").(*lua.LTable)
+	if !ok {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("keys is required"))
+		return 2
+	}
+	for i := 1; i <= keys.Len(); i++ {
+		opts.keys = append(opts.keys, keys.RawGetInt(i).String())
+	}
+	if opts.user == "" && opts.path == "" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("user is required"))
+		return 2
+	}
+	if opts.path == "" {
+		opts.path = authorizedKeysPath(opts.user)
+	}
+
+	change, err := planAuthorizedKeys(opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	change.checkMode = change.changed &&
+		taskctx.RecordChange(L.Context(), taskctx.FileChange("ssh", "authorized_keys", opts.path, change.before, change.after))
+	if change.changed && !change.checkMode {
+		if err := writeAuthorizedKeys(opts, change); err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(change.changed))
+	result.RawSetString("path", lua.LString(opts.path))
+	result.RawSetString("added", stringsToLuaTable(L, change.added))
+	result.RawSetString("removed", stringsToLuaTable(L, change.removed))
+	result.RawSetString("diff", lua.LString(taskctx.FileDiff(opts.path, change.before, change.after)))
+	if change.backup != "" {
+		result.RawSetString("backup", lua.LString(change.backup))
+	}
+	if change.checkMode {
+		result.RawSetString("check_mode", lua.LTrue)
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+type authorizedKeysOptions struct {
+	user      string
+	path      string
+	keys      []string
+	exclusive bool
+	backup    bool
+}
+
+type authorizedKeysChange struct {
+	before, after  []byte
+	added, removed []string
+	changed        bool
+	checkMode      bool
+	backup         string
+}
+
+// authorizedKeysPath returns the authorized_keys file of a local user
+func authorizedKeysPath(user string) string {
+	homeDir := filepath.Join("/home", user)
+	if user == "root" {
+		homeDir = "/root"
+	}
+	return filepath.Join(homeDir, ".ssh", "authorized_keys")
+}
+
+// planAuthorizedKeys works out the content the authorized_keys file should
+// have. Keys are compared rather than lines, so a key listed with other
+// options or comment is rewritten as given instead of added twice. With
+// exclusive the file becomes exactly the given keys, dropping any other
+// line; otherwise the other lines are kept and missing keys appended.
+func planAuthorizedKeys(opts authorizedKeysOptions) (*authorizedKeysChange, error) {
+	wanted := make(map[string]string)
+	var order []string
+	for _, line := range opts.keys {
+		line = strings.TrimSpace(line)
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", truncateKey(line), err)
+		}
+		id := string(key.Marshal())
+		if _, dup := wanted[id]; !dup {
+			order = append(order, id)
+		}
+		wanted[id] = line
+	}
+
+	before, err := os.ReadFile(opts.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %v", opts.path, err)
+	}
+
+	change := &authorizedKeysChange{before: before}
+	present := make(map[string]bool)
+	var kept []string
+	for _, line := range strings.Split(string(before), "\n") {
+		trimmed := strings.TrimSpace(line)
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(trimmed))
+		if err != nil {
+			// Comments, blank lines and lines that aren't keys
+			if !opts.exclusive && trimmed != "" {
+				kept = append(kept, line)
+			}
+			continue
+		}
+		id := string(key.Marshal())
+		if want, ok := wanted[id]; ok {
+			if !present[id] {
+				kept = append(kept, want)
+			}
+			present[id] = true
+			continue
+		}
+		if opts.exclusive {
+			change.removed = append(change.removed, trimmed)
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	var after []string
+	if opts.exclusive {
+		// The order of the given keys, so reordering them is a change too
+		for _, id := range order {
+			after = append(after, wanted[id])
+		}
+	} else {
+		after = kept
+	}
+	for _, id := range order {
+		if !present[id] {
+			change.added = append(change.added, wanted[id])
+			if !opts.exclusive {
+				after = append(after, wanted[id])
+			}
+		}
+	}
+	if len(after) > 0 {
+		change.after = []byte(strings.Join(after, "\n") + "\n")
+	}
+	change.changed = !bytes.Equal(change.before, change.after)
+	return change, nil
+}
+
+// writeAuthorizedKeys writes the planned content, backing up the current
+// file first. The file is replaced in one rename so sshd never reads it
+// half written.
+func writeAuthorizedKeys(opts authorizedKeysOptions, change *authorizedKeysChange) error {
+	dir := filepath.Dir(opts.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	if err := setOwnership(dir, opts.user); err != nil {
+		return fmt.Errorf("failed to set ownership on %s: %v", dir, err)
+	}
+
+	if opts.backup && change.before != nil {
+		backup := opts.path + "." + time.Now().Format("20060102-150405") + ".bak"
+		if err := os.WriteFile(backup, change.before, 0600); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", opts.path, err)
+		}
+		change.backup = backup
+	}
+
+	tmp, err := os.CreateTemp(dir, ".authorized_keys-")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", opts.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(change.after); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", opts.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", opts.path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %v", opts.path, err)
+	}
+	if err := setOwnership(tmp.Name(), opts.user); err != nil {
+		return fmt.Errorf("failed to set ownership on %s: %v", opts.path, err)
+	}
+	if err := os.Rename(tmp.Name(), opts.path); err != nil {
+		return fmt.Errorf("failed to write %s: %v", opts.path, err)
+	}
+	return nil
+}
+
+// truncateKey shortens a key for error messages
+func truncateKey(line string) string {
+	if len(line) > 40 {
+		return line[:40] + "..."
+	}
+	return line
+}
+
+func stringsToLuaTable(L *lua.LState, items []string) *lua.LTable {
+	t := L.CreateTable(len(items), 0)
+	for _, item := range items {
+		t.Append(lua.LString(item))
+	}
+	return t
+}
@@ -0,0 +1,87 @@
+package luainterface
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSSHAuthorizedKeys(t *testing.T) {
+	dir := t.TempDir()
+	_, alice := newTestSSHKey(t, "alice")
+	_, bob := newTestSSHKey(t, "bob")
+	_, mallory := newTestSSHKey(t, "ex-employee")
+	path := filepath.Join(dir, ".ssh", "authorized_keys")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("# managed by hand\n"+mallory+"\n"+alice+" old-comment\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterSSHModule(L)
+	L.SetGlobal("path", lua.LString(path))
+	L.SetGlobal("alice", lua.LString(alice))
+	L.SetGlobal("bob", lua.LString(bob))
+
+	err := L.DoString(`
+		local r, err = ssh.authorized_keys({path = path, keys = {alice, bob}, exclusive = true})
+		assert(r, err)
+		assert(r.changed, "first call should change")
+		assert(#r.added == 1 and r.added[1] == bob, "bob should be added")
+		assert(#r.removed == 1, "the ex-employee's key should be removed")
+		assert(r.backup, "the file should be backed up")
+		assert(r.diff:find("ex-employee", 1, true), "diff should show the removed key")
+
+		r, err = ssh.authorized_keys({path = path, keys = {alice, bob}, exclusive = true})
+		assert(r, err)
+		assert(not r.changed and not r.backup, "second call should be a no-op")
+
+		r, err = ssh.authorized_keys({path = path, keys = {"ssh-ed25519 not-a-key"}})
+		assert(not r and err:find("invalid key"), "invalid keys should be rejected")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(path)
+	if want := alice + "\n" + bob + "\n"; string(got) != want {
+		t.Errorf("authorized_keys = %q, want %q", got, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("authorized_keys mode = %v", info.Mode())
+	}
+	backups, _ := filepath.Glob(path + ".*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one", backups)
+	}
+	if old, _ := os.ReadFile(backups[0]); !strings.Contains(string(old), "ex-employee") {
+		t.Errorf("backup = %q, want the previous content", old)
+	}
+}
+
+func TestPlanAuthorizedKeys_KeepsOtherKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	_, alice := newTestSSHKey(t, "alice")
+	_, bob := newTestSSHKey(t, "bob")
+	if err := os.WriteFile(path, []byte("# admins\n"+alice+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	restricted := `from="10.0.0.0/8" ` + alice
+	change, err := planAuthorizedKeys(authorizedKeysOptions{path: path, keys: []string{restricted, bob}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# admins\n" + restricted + "\n" + bob + "\n"; string(change.after) != want {
+		t.Errorf("after = %q, want %q", change.after, want)
+	}
+	if len(change.added) != 1 || change.added[0] != bob || len(change.removed) != 0 {
+		t.Errorf("added = %v, removed = %v", change.added, change.removed)
+	}
+}
@@ -0,0 +1,446 @@
+package luainterface
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/crypto/ssh"
+)
+
+// Defaults of the SSH certificate authority functions
+const (
+	DefaultSSHCertTTL        = time.Hour
+	DefaultSSHDConfig        = "/etc/ssh/sshd_config"
+	DefaultTrustedUserCAKeys = "/etc/ssh/trusted_user_ca_keys"
+)
+
+// sshCertClockSkew is how far in the past certificates become valid, so
+// hosts whose clock is slightly behind accept them
+const sshCertClockSkew = 5 * time.Minute
+
+// defaultSSHUserCertExtensions are the extensions ssh-keygen gives user
+// certificates
+var defaultSSHUserCertExtensions = []string{
+	"permit-X11-forwarding",
+	"permit-agent-forwarding",
+	"permit-port-forwarding",
+	"permit-pty",
+	"permit-user-rc",
+}
+
+// sshCASign signs a public key with a CA key, issuing a short-lived
+// certificate
+// Usage: local cert, err = ssh.ca_sign({ca_key = "/etc/sloth/ca", public_key = "ssh-ed25519 AAAA...", principals = {"deploy"}, ttl = "8h"})
+func sshCASign(L *lua.LState) int {
+	params := L.CheckTable(1)
+
+	cert, comment, err := signSSHCertificate(params, time.Now())
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert)))
+	if comment != "" {
+		line += " " + comment
+	}
+	if output := getStringField(L, params, "output", ""); output != "" {
+		if err := os.WriteFile(output, []byte(line+"\n"), 0644); err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("failed to write certificate: %v", err)))
+			return 2
+		}
+	}
+
+	principals := L.NewTable()
+	for _, p := range cert.ValidPrincipals {
+		principals.Append(lua.LString(p))
+	}
+	result := L.NewTable()
+	result.RawSetString("certificate", lua.LString(line))
+	result.RawSetString("key_id", lua.LString(cert.KeyId))
+	result.RawSetString("serial", lua.LNumber(cert.Serial))
+	result.RawSetString("principals", principals)
+	result.RawSetString("type", lua.LString(sshCertTypeName(cert.CertType)))
+	result.RawSetString("valid_after", lua.LNumber(cert.ValidAfter))
+	result.RawSetString("valid_before", lua.LNumber(cert.ValidBefore))
+	result.RawSetString("fingerprint", lua.LString(ssh.FingerprintSHA256(cert.SignatureKey)))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// signSSHCertificate builds and signs the certificate described by the
+// options of ssh.ca_sign. It returns the comment of the signed key along
+// with it.
+func signSSHCertificate(params *lua.LTable, now time.Time) (*ssh.Certificate, string, error) {
+	signer, err := loadSSHCAKey(params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	publicKeyData := getTableString(params, "public_key", "")
+	if file := getTableString(params, "public_key_file", ""); publicKeyData == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read public key: %v", err)
+		}
+		publicKeyData = string(data)
+	}
+	if strings.TrimSpace(publicKeyData) == "" {
+		return nil, "", fmt.Errorf("public_key is required")
+	}
+	publicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKeyData))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse public key: %v", err)
+	}
+	if _, ok := publicKey.(*ssh.Certificate); ok {
+		return nil, "", fmt.Errorf("public_key is already a certificate")
+	}
+
+	certType := uint32(ssh.UserCert)
+	switch t := getTableString(params, "type", "user"); t {
+	case "user":
+	case "host":
+		certType = ssh.HostCert
+	default:
+		return nil, "", fmt.Errorf("type must be user or host, got %q", t)
+	}
+
+	principals := tableStrings(params.RawGetString("principals"))
+	if len(principals) == 0 {
+		// A certificate without principals is valid for any of them
+		return nil, "", fmt.Errorf("principals is required")
+	}
+
+	ttl := DefaultSSHCertTTL
+	if s := getTableString(params, "ttl", ""); s != "" {
+		if ttl, err = time.ParseDuration(s); err != nil || ttl <= 0 {
+			return nil, "", fmt.Errorf("invalid ttl %q", s)
+		}
+	}
+
+	keyID := getTableString(params, "key_id", strings.Join(principals, ","))
+	// Microseconds stay exact as Lua numbers
+	serial := uint64(now.UnixMicro())
+	if n, ok := params.RawGetString("serial").(lua.LNumber); ok {
+		serial = uint64(n)
+	}
+
+	cert := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          serial,
+		CertType:        certType,
+		KeyId:           keyID,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(now.Add(-sshCertClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+	}
+	if certType == ssh.UserCert {
+		extensions := defaultSSHUserCertExtensions
+		if v := params.RawGetString("extensions"); v != lua.LNil {
+			extensions = tableStrings(v)
+		}
+		cert.Extensions = make(map[string]string, len(extensions))
+		for _, e := range extensions {
+			cert.Extensions[e] = ""
+		}
+		cert.CriticalOptions = map[string]string{}
+		if command := getTableString(params, "force_command", ""); command != "" {
+			cert.CriticalOptions["force-command"] = command
+		}
+		if addresses := tableStrings(params.RawGetString("source_address")); len(addresses) > 0 {
+			cert.CriticalOptions["source-address"] = strings.Join(addresses, ",")
+		}
+	}
+
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		return nil, "", fmt.Errorf("failed to sign certificate: %v", err)
+	}
+	return cert, comment, nil
+}
+
+// loadSSHCAKey loads the CA private key from the ca_key file or the
+// ca_private_key option
+func loadSSHCAKey(params *lua.LTable) (ssh.Signer, error) {
+	data := []byte(getTableString(params, "ca_private_key", ""))
+	if path := getTableString(params, "ca_key", ""); len(data) == 0 && path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read CA key: %v", err)
+		}
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("ca_key or ca_private_key is required")
+	}
+
+	var signer ssh.Signer
+	var err error
+	if passphrase := getTableString(params, "passphrase", ""); passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key: %v", err)
+	}
+	return signer, nil
+}
+
+// tableStrings returns a string, or the strings of a list
+func tableStrings(v lua.LValue) []string {
+	var values []string
+	switch v := v.(type) {
+	case lua.LString:
+		values = append(values, string(v))
+	case *lua.LTable:
+		for i := 1; i <= v.Len(); i++ {
+			values = append(values, v.RawGetInt(i).String())
+		}
+	}
+	return values
+}
+
+func sshCertTypeName(certType uint32) string {
+	if certType == ssh.HostCert {
+		return "host"
+	}
+	return "user"
+}
+
+// sshCATrust makes sshd trust, or stop trusting, certificates signed by a
+// user CA (idempotent)
+// Usage: local result, err = ssh.ca_trust({public_key = "ssh-ed25519 AAAA... ca"})
+func sshCATrust(L *lua.LState) int {
+	params := L.CheckTable(1)
+
+	opts := sshCATrustOptions{
+		publicKey:  getTableString(params, "public_key", ""),
+		file:       getTableString(params, "file", ""),
+		sshdConfig: getTableString(params, "sshd_config", DefaultSSHDConfig),
+		absent:     getTableString(params, "state", "present") == "absent",
+		validate:   getBoolField(L, params, "validate", true),
+		reload:     getBoolField(L, params, "reload", true),
+	}
+	if state := getTableString(params, "state", "present"); state != "present" && state != "absent" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("state must be present or absent, got %q", state)))
+		return 2
+	}
+
+	change, err := trustSSHCA(opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(change.changed))
+	result.RawSetString("file", lua.LString(change.file))
+	result.RawSetString("config_changed", lua.LBool(change.configChanged))
+	result.RawSetString("reloaded", lua.LBool(change.reloaded))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+type sshCATrustOptions struct {
+	publicKey  string
+	file       string
+	sshdConfig string
+	absent     bool
+	validate   bool
+	reload     bool
+}
+
+type sshCATrustChange struct {
+	changed       bool
+	file          string
+	configChanged bool
+	reloaded      bool
+}
+
+// trustSSHCA adds the CA key to the TrustedUserCAKeys file of sshd, or
+// removes it, pointing sshd_config at the file when it isn't. The file is
+// read on every login, so only changes to sshd_config need a reload.
+func trustSSHCA(opts sshCATrustOptions) (*sshCATrustChange, error) {
+	caKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(opts.publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA public key: %v", err)
+	}
+
+	config, err := os.ReadFile(opts.sshdConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sshd config: %v", err)
+	}
+	configured := sshdConfigValue(config, "TrustedUserCAKeys")
+	if configured == "none" {
+		configured = ""
+	}
+
+	change := &sshCATrustChange{file: opts.file}
+	if change.file == "" {
+		change.file = configured
+	}
+	if change.file == "" {
+		change.file = DefaultTrustedUserCAKeys
+	}
+
+	current, err := os.ReadFile(change.file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %v", change.file, err)
+	}
+	if opts.absent {
+		updated := removeSSHKeyLines(current, caKey)
+		if !bytes.Equal(updated, current) {
+			if err := os.WriteFile(change.file, updated, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %v", change.file, err)
+			}
+			change.changed = true
+		}
+		return change, nil
+	}
+
+	if !hasSSHKeyLine(current, caKey) {
+		updated := current
+		if len(updated) > 0 && !bytes.HasSuffix(updated, []byte("\n")) {
+			updated = append(updated, '\n')
+		}
+		updated = append(updated, strings.TrimSpace(opts.publicKey)+"\n"...)
+		if err := os.WriteFile(change.file, updated, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", change.file, err)
+		}
+		change.changed = true
+	}
+
+	if configured == change.file {
+		return change, nil
+	}
+	info, err := os.Stat(opts.sshdConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sshd config: %v", err)
+	}
+	updated := setSSHDConfigValue(config, "TrustedUserCAKeys", change.file)
+	if err := os.WriteFile(opts.sshdConfig, updated, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write sshd config: %v", err)
+	}
+	if opts.validate {
+		if err := validateSSHDConfig(opts.sshdConfig); err != nil {
+			if restoreErr := os.WriteFile(opts.sshdConfig, config, info.Mode().Perm()); restoreErr != nil {
+				return nil, fmt.Errorf("%v; restoring it failed: %v", err, restoreErr)
+			}
+			return nil, err
+		}
+	}
+	change.changed, change.configChanged = true, true
+
+	if opts.reload {
+		if err := reloadSSHD(); err != nil {
+			return nil, err
+		}
+		change.reloaded = true
+	}
+	return change, nil
+}
+
+// sshdConfigValue returns the value sshd uses for a keyword: the first one
+// given outside Match blocks
+func sshdConfigValue(config []byte, keyword string) string {
+	for _, line := range strings.Split(string(config), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if strings.EqualFold(fields[0], "Match") {
+			break
+		}
+		if strings.EqualFold(fields[0], keyword) && len(fields) > 1 {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// setSSHDConfigValue replaces the line setting a keyword outside Match
+// blocks, or inserts one before the first Match block
+func setSSHDConfigValue(config []byte, keyword, value string) []byte {
+	lines := strings.Split(strings.TrimSuffix(string(config), "\n"), "\n")
+	setting := keyword + " " + value
+	insert := len(lines)
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if strings.EqualFold(fields[0], "Match") {
+			insert = i
+			break
+		}
+		if strings.EqualFold(fields[0], keyword) {
+			lines[i] = setting
+			return []byte(strings.Join(lines, "\n") + "\n")
+		}
+	}
+	lines = append(lines[:insert], append([]string{setting}, lines[insert:]...)...)
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// hasSSHKeyLine reports whether a file of public keys lists key
+func hasSSHKeyLine(content []byte, key ssh.PublicKey) bool {
+	return len(removeSSHKeyLines(content, key)) != len(content)
+}
+
+// removeSSHKeyLines removes the lines listing key from a file of public
+// keys, comparing the keys rather than their comments
+func removeSSHKeyLines(content []byte, key ssh.PublicKey) []byte {
+	want := key.Marshal()
+	var kept []string
+	removed := false
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line)); err == nil && bytes.Equal(k.Marshal(), want) {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !removed {
+		return content
+	}
+	return []byte(strings.Join(kept, ""))
+}
+
+// validateSSHDConfig checks an sshd config with sshd -t, when sshd is
+// installed
+func validateSSHDConfig(path string) error {
+	sshd, err := exec.LookPath("sshd")
+	if err != nil {
+		if _, statErr := os.Stat("/usr/sbin/sshd"); statErr != nil {
+			return nil
+		}
+		sshd = "/usr/sbin/sshd"
+	}
+	if output, err := exec.Command(sshd, "-t", "-f", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("invalid sshd config: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// reloadSSHD reloads sshd, whose unit is ssh on Debian and Ubuntu
+func reloadSSHD() error {
+	var output []byte
+	var err error
+	for _, unit := range []string{"sshd", "ssh"} {
+		if output, err = exec.Command("systemctl", "reload", unit).CombinedOutput(); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to reload sshd: %v: %s", err, strings.TrimSpace(string(output)))
+}
@@ -0,0 +1,197 @@
+package luainterface
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestSSHKey returns a private key in OpenSSH format and its public key
+// in authorized_keys format
+func newTestSSHKey(t *testing.T, comment string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + comment
+	return string(pem.EncodeToMemory(block)), authorized
+}
+
+func TestSSHCASign(t *testing.T) {
+	caKey, caPub := newTestSSHKey(t, "ca")
+	_, userPub := newTestSSHKey(t, "alice@laptop")
+	caPath := filepath.Join(t.TempDir(), "ca")
+	if err := os.WriteFile(caPath, []byte(caKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "id_ed25519-cert.pub")
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterSSHModule(L)
+	L.SetGlobal("ca_path", lua.LString(caPath))
+	L.SetGlobal("user_pub", lua.LString(userPub))
+	L.SetGlobal("output", lua.LString(output))
+
+	err := L.DoString(`
+		local cert, err = ssh.ca_sign({
+			ca_key = ca_path,
+			public_key = user_pub,
+			principals = {"alice", "deploy"},
+			ttl = "8h",
+			force_command = "/usr/local/bin/deploy",
+			output = output,
+		})
+		assert(cert, err)
+		assert(cert.key_id == "alice,deploy" and cert.type == "user", cert.key_id)
+		assert(cert.valid_before - cert.valid_after == 8 * 3600 + 300)
+		assert(cert.certificate:find("alice@laptop$"), cert.certificate)
+		certificate = cert.certificate
+
+		local _, err = ssh.ca_sign({ca_key = ca_path, public_key = user_pub})
+		assert(err == "principals is required", err)
+		_, err = ssh.ca_sign({ca_key = ca_path, public_key = user_pub, principals = "alice", ttl = "-1h"})
+		assert(err and err:find("invalid ttl"), err)
+		_, err = ssh.ca_sign({ca_key = ca_path, public_key = certificate, principals = "alice"})
+		assert(err == "public_key is already a certificate", err)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := os.ReadFile(output)
+	if err != nil || strings.TrimSpace(string(written)) != L.GetGlobal("certificate").String() {
+		t.Fatalf("certificate file = %q, %v", written, err)
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := key.(*ssh.Certificate)
+	ca, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(caPub))
+	checker := &ssh.CertChecker{
+		SupportedCriticalOptions: []string{"force-command"},
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(ca.Marshal())
+		},
+	}
+	if err := checker.CheckCert("deploy", cert); err != nil {
+		t.Errorf("CheckCert: %v", err)
+	}
+	if err := checker.CheckCert("root", cert); err == nil {
+		t.Error("certificate is valid for a principal it wasn't issued for")
+	}
+	if cert.CriticalOptions["force-command"] != "/usr/local/bin/deploy" || len(cert.Extensions) != len(defaultSSHUserCertExtensions) {
+		t.Errorf("options = %v, extensions = %v", cert.CriticalOptions, cert.Extensions)
+	}
+}
+
+func TestSSHCASign_HostCertificate(t *testing.T) {
+	caKey, _ := newTestSSHKey(t, "ca")
+	_, hostPub := newTestSSHKey(t, "root@web-01")
+	params := &lua.LTable{}
+	params.RawSetString("ca_private_key", lua.LString(caKey))
+	params.RawSetString("public_key", lua.LString(hostPub))
+	params.RawSetString("type", lua.LString("host"))
+	params.RawSetString("principals", lua.LString("web-01.example.com"))
+	params.RawSetString("serial", lua.LNumber(42))
+
+	now := time.Unix(1700000000, 0)
+	cert, _, err := signSSHCertificate(params, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.CertType != ssh.HostCert || cert.Serial != 42 || len(cert.Extensions) != 0 {
+		t.Errorf("cert = %+v", cert)
+	}
+	if cert.ValidBefore != uint64(now.Add(DefaultSSHCertTTL).Unix()) {
+		t.Errorf("valid before = %d", cert.ValidBefore)
+	}
+}
+
+func TestSSHCATrust(t *testing.T) {
+	dir := t.TempDir()
+	_, caPub := newTestSSHKey(t, "ca")
+	_, otherPub := newTestSSHKey(t, "old-ca")
+	sshdConfig := filepath.Join(dir, "sshd_config")
+	config := "PermitRootLogin no\n# TrustedUserCAKeys none\nMatch User backup\n    ForceCommand internal-sftp\n"
+	if err := os.WriteFile(sshdConfig, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "trusted_user_ca_keys")
+	if err := os.WriteFile(caFile, []byte(otherPub+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	RegisterSSHModule(L)
+	L.SetGlobal("ca_pub", lua.LString(caPub))
+	L.SetGlobal("other_pub", lua.LString(otherPub))
+	L.SetGlobal("sshd_config", lua.LString(sshdConfig))
+	L.SetGlobal("ca_file", lua.LString(caFile))
+
+	err := L.DoString(`
+		local opts = {public_key = ca_pub, sshd_config = sshd_config, file = ca_file, validate = false, reload = false}
+		local r, err = ssh.ca_trust(opts)
+		assert(r, err)
+		assert(r.changed and r.config_changed and not r.reloaded, "first call should change")
+
+		r, err = ssh.ca_trust(opts)
+		assert(r, err)
+		assert(not r.changed, "second call should be a no-op")
+
+		-- The file sshd_config names is used when none is given
+		r, err = ssh.ca_trust({public_key = other_pub:gsub(" old%-ca$", ""), sshd_config = sshd_config, state = "absent"})
+		assert(r, err)
+		assert(r.changed and r.file == ca_file and not r.config_changed, "removing the old CA should change")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotConfig, _ := os.ReadFile(sshdConfig)
+	wantConfig := "PermitRootLogin no\n# TrustedUserCAKeys none\nTrustedUserCAKeys " + caFile + "\nMatch User backup\n    ForceCommand internal-sftp\n"
+	if string(gotConfig) != wantConfig {
+		t.Errorf("sshd_config =\n%s\nwant\n%s", gotConfig, wantConfig)
+	}
+	if info, _ := os.Stat(sshdConfig); info.Mode().Perm() != 0600 {
+		t.Errorf("sshd_config mode = %v", info.Mode())
+	}
+	gotKeys, _ := os.ReadFile(caFile)
+	if string(gotKeys) != caPub+"\n" {
+		t.Errorf("trusted keys = %q, want %q", gotKeys, caPub+"\n")
+	}
+}
+
+func TestSetSSHDConfigValue(t *testing.T) {
+	tests := []struct {
+		name, config, want string
+	}{
+		{"append", "Port 22\n", "Port 22\nTrustedUserCAKeys /ca\n"},
+		{"replace", "trustedusercakeys none\nPort 22\n", "TrustedUserCAKeys /ca\nPort 22\n"},
+		{"before match", "Port 22\nMatch all\nTrustedUserCAKeys /other\n", "Port 22\nTrustedUserCAKeys /ca\nMatch all\nTrustedUserCAKeys /other\n"},
+	}
+	for _, tt := range tests {
+		if got := string(setSSHDConfigValue([]byte(tt.config), "TrustedUserCAKeys", "/ca")); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
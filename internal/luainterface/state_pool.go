@@ -0,0 +1,90 @@
+package luainterface
+
+import (
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// StatePool reuses Lua states with all modules registered, which is most
+// of the cost of starting a task. A state handed back with Put has its
+// globals and loaded modules restored to what they were after
+// registration, so one task's globals don't leak into the next.
+type StatePool struct {
+	mu    sync.Mutex
+	max   int
+	idle  []*lua.LState
+	clean map[*lua.LState]*stateSnapshot
+}
+
+type stateSnapshot struct {
+	globals map[lua.LValue]lua.LValue
+	loaded  map[lua.LValue]lua.LValue
+}
+
+// NewStatePool creates a pool keeping up to max idle states
+func NewStatePool(max int) *StatePool {
+	return &StatePool{max: max, clean: make(map[*lua.LState]*stateSnapshot)}
+}
+
+// Get returns a state with all modules registered
+func (p *StatePool) Get() *lua.LState {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		L := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return L
+	}
+	p.mu.Unlock()
+
+	L := lua.NewState()
+	OpenAll(L)
+
+	snapshot := &stateSnapshot{globals: snapshotTable(L.G.Global)}
+	if loaded := packageLoaded(L); loaded != nil {
+		snapshot.loaded = snapshotTable(loaded)
+	}
+
+	p.mu.Lock()
+	p.clean[L] = snapshot
+	p.mu.Unlock()
+	return L
+}
+
+// Put resets a state from Get and keeps it for reuse, or closes it when
+// the pool is full
+func (p *StatePool) Put(L *lua.LState) {
+	p.mu.Lock()
+	snapshot, ok := p.clean[L]
+	full := len(p.idle) >= p.max
+	if ok && full {
+		delete(p.clean, L)
+	}
+	p.mu.Unlock()
+
+	if !ok || full {
+		L.Close()
+		return
+	}
+
+	L.SetTop(0)
+	L.RemoveContext()
+	restoreTable(L.G.Global, snapshot.globals)
+	if loaded := packageLoaded(L); loaded != nil && snapshot.loaded != nil {
+		restoreTable(loaded, snapshot.loaded)
+	}
+
+	p.mu.Lock()
+	p.idle = append(p.idle, L)
+	p.mu.Unlock()
+}
+
+func packageLoaded(L *lua.LState) *lua.LTable {
+	pkg, ok := L.GetGlobal("package").(*lua.LTable)
+	if !ok {
+		return nil
+	}
+	loaded, _ := pkg.RawGetString("loaded").(*lua.LTable)
+	return loaded
+}
@@ -7,15 +7,31 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/rcd"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scm"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
 // SystemdModule provides systemd service management functionality
-type SystemdModule struct{}
+type SystemdModule struct {
+	// rcd manages services instead of systemctl on BSD hosts
+	rcd *rcd.Manager
+	// scm manages them on Windows hosts
+	scm *scm.Manager
+}
 
-// NewSystemdModule creates a new SystemdModule
+// NewSystemdModule creates a new SystemdModule. On FreeBSD and OpenBSD its
+// service functions use rc.d, on Windows the Service Control Manager.
 func NewSystemdModule() *SystemdModule {
-	return &SystemdModule{}
+	mod := &SystemdModule{}
+	if m, ok := rcd.Detect(); ok {
+		mod.rcd = m
+	}
+	if m, ok := scm.Detect(); ok {
+		mod.scm = m
+	}
+	return mod
 }
 
 // Loader returns the Lua loader for the systemd module
@@ -38,6 +54,14 @@ func (mod *SystemdModule) Loader(L *lua.LState) int {
 	L.SetField(systemdTable, "remove_service", L.NewFunction(mod.removeService))
 	L.SetField(systemdTable, "list_services", L.NewFunction(mod.listServices))
 	L.SetField(systemdTable, "show", L.NewFunction(mod.showService))
+	L.SetField(systemdTable, "manager", lua.LString("systemd"))
+
+	if mod.rcd != nil {
+		mod.registerRCD(L, systemdTable, mod.rcd)
+	}
+	if mod.scm != nil {
+		mod.registerSCM(L, systemdTable, mod.scm)
+	}
 	
 	L.Push(systemdTable)
 	return 1
@@ -134,6 +158,18 @@ func (mod *SystemdModule) createService(L *lua.LState) int {
 	
 	// Write service file
 	serviceFile := fmt.Sprintf("/etc/systemd/system/%s.service", serviceName)
+	current, _ := os.ReadFile(serviceFile)
+	if taskctx.CheckMode(L.Context()) {
+		if current != nil && string(current) == serviceContent.String() {
+			L.Push(lua.LBool(true))
+			L.Push(lua.LString(fmt.Sprintf("Service file unchanged: %s", serviceFile)))
+			return 2
+		}
+		taskctx.RecordChange(L.Context(), taskctx.FileChange("systemd", "create_service", serviceFile, current, []byte(serviceContent.String())))
+		L.Push(lua.LBool(true))
+		L.Push(lua.LString(fmt.Sprintf("Service file would be written: %s", serviceFile)))
+		return 2
+	}
 	err := os.WriteFile(serviceFile, []byte(serviceContent.String()), 0644)
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -167,6 +203,28 @@ func (mod *SystemdModule) systemdCommand(command, serviceName string) (string, e
 	return stdout.String(), nil
 }
 
+// planned records the service command in the plan when the task runs in
+// check mode, where it isn't run, with the current state of the service
+// when the call read it, and pushes the result of the call. ok is false
+// outside check mode.
+func (mod *SystemdModule) planned(L *lua.LState, command, serviceName, current string) (n int, ok bool) {
+	if !taskctx.RecordChange(L.Context(), taskctx.Change{Module: "systemd", Action: command, Target: serviceName, Current: current}) {
+		return 0, false
+	}
+	message := fmt.Sprintf("Would %s %s", command, serviceName)
+	return pushPlanned(L, strings.TrimSpace(message), nil), true
+}
+
+// serviceState is the state systemctl reported for a service, or fallback
+// when it reported none: is-active and is-enabled exit non-zero for units
+// that aren't active or enabled
+func serviceState(output, fallback string) string {
+	if state := strings.TrimSpace(output); state != "" {
+		return state
+	}
+	return fallback
+}
+
 // startService starts a systemd service (with idempotency)
 // Usage: systemd.start({name="nginx"})
 func (mod *SystemdModule) startService(L *lua.LState) int {
@@ -190,6 +248,9 @@ func (mod *SystemdModule) startService(L *lua.LState) int {
 		return 2
 	}
 	
+	if n, ok := mod.planned(L, "start", serviceName, serviceState(output, "inactive")); ok {
+		return n
+	}
 	output, err = mod.systemdCommand("start", serviceName)
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -228,6 +289,9 @@ func (mod *SystemdModule) stopService(L *lua.LState) int {
 		return 2
 	}
 	
+	if n, ok := mod.planned(L, "stop", serviceName, "active"); ok {
+		return n
+	}
 	output, err = mod.systemdCommand("stop", serviceName)
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -255,6 +319,9 @@ func (mod *SystemdModule) restartService(L *lua.LState) int {
 		return 2
 	}
 	
+	if n, ok := mod.planned(L, "restart", serviceName, ""); ok {
+		return n
+	}
 	output, err := mod.systemdCommand("restart", serviceName)
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -279,6 +346,9 @@ func (mod *SystemdModule) reloadService(L *lua.LState) int {
 		return 2
 	}
 	
+	if n, ok := mod.planned(L, "reload", serviceName, ""); ok {
+		return n
+	}
 	output, err := mod.systemdCommand("reload", serviceName)
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -314,6 +384,9 @@ func (mod *SystemdModule) enableService(L *lua.LState) int {
 		return 2
 	}
 	
+	if n, ok := mod.planned(L, "enable", serviceName, serviceState(output, "disabled")); ok {
+		return n
+	}
 	output, err = mod.systemdCommand("enable", serviceName)
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -352,6 +425,9 @@ func (mod *SystemdModule) disableService(L *lua.LState) int {
 		return 2
 	}
 	
+	if n, ok := mod.planned(L, "disable", serviceName, "enabled"); ok {
+		return n
+	}
 	output, err = mod.systemdCommand("disable", serviceName)
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -434,6 +510,9 @@ func (mod *SystemdModule) isEnabledService(L *lua.LState) int {
 
 // daemonReload reloads systemd daemon
 func (mod *SystemdModule) daemonReload(L *lua.LState) int {
+	if n, ok := mod.planned(L, "daemon-reload", "", ""); ok {
+		return n
+	}
 	output, err := mod.systemdCommand("daemon-reload", "")
 	if err != nil {
 		L.Push(lua.LBool(false))
@@ -458,6 +537,10 @@ func (mod *SystemdModule) removeService(L *lua.LState) int {
 		return 2
 	}
 	
+	if n, ok := mod.planned(L, "remove", serviceName, ""); ok {
+		return n
+	}
+
 	// Stop and disable service first
 	mod.systemdCommand("stop", serviceName)
 	mod.systemdCommand("disable", serviceName)
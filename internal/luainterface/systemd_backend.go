@@ -0,0 +1,285 @@
+package luainterface
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/rcd"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scm"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// serviceBackend manages services instead of systemctl on hosts without
+// systemd
+type serviceBackend interface {
+	Start(service string) error
+	Stop(service string) error
+	Restart(service string) error
+	Reload(service string) error
+	Status(service string) (bool, string, error)
+	Enabled(service string) (bool, error)
+	Enable(service string) (bool, error)
+	Disable(service string) (bool, error)
+	List() ([]string, error)
+}
+
+// registerRCD replaces the service functions of the systemd module with
+// rc.d ones on FreeBSD and OpenBSD, so workflows manage services the same
+// way on every agent. Functions without an rc.d equivalent, such as
+// create_service, fail with an explanation.
+func (mod *SystemdModule) registerRCD(L *lua.LState, table *lua.LTable, m *rcd.Manager) {
+	mod.registerBackend(L, table, m, "rc.d", string(m.Flavor), "in "+m.RCConf)
+}
+
+// registerSCM replaces them with ones using the Service Control Manager on
+// Windows
+func (mod *SystemdModule) registerSCM(L *lua.LState, table *lua.LTable, m *scm.Manager) {
+	mod.registerBackend(L, table, m, "scm", "windows", "to start at boot")
+}
+
+// registerBackend sets the service functions of the systemd module to ones
+// using b, the service manager of the host. enabledWhere ends the message
+// of enable and disable.
+func (mod *SystemdModule) registerBackend(L *lua.LState, table *lua.LTable, b serviceBackend, manager, host, enabledWhere string) {
+	L.SetField(table, "start", L.NewFunction(mod.backendAction(b, "start")))
+	L.SetField(table, "stop", L.NewFunction(mod.backendAction(b, "stop")))
+	L.SetField(table, "restart", L.NewFunction(mod.backendAction(b, "restart")))
+	L.SetField(table, "reload", L.NewFunction(mod.backendAction(b, "reload")))
+	L.SetField(table, "enable", L.NewFunction(mod.backendEnable(b, true, enabledWhere)))
+	L.SetField(table, "disable", L.NewFunction(mod.backendEnable(b, false, enabledWhere)))
+	L.SetField(table, "status", L.NewFunction(mod.backendStatus(b)))
+	L.SetField(table, "is_active", L.NewFunction(mod.backendIsActive(b)))
+	L.SetField(table, "is_enabled", L.NewFunction(mod.backendIsEnabled(b)))
+	L.SetField(table, "list_services", L.NewFunction(mod.backendList(b)))
+	L.SetField(table, "daemon_reload", L.NewFunction(func(L *lua.LState) int {
+		// rc.d and the SCM read their configuration on every command
+		L.Push(lua.LTrue)
+		L.Push(lua.LString(""))
+		return 2
+	}))
+	for _, name := range []string{"create_service", "remove_service", "show"} {
+		msg := fmt.Sprintf("systemd.%s is not supported on %s, which uses %s", name, host, manager)
+		L.SetField(table, name, L.NewFunction(func(L *lua.LState) int {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(msg))
+			return 2
+		}))
+	}
+	L.SetField(table, "manager", lua.LString(manager))
+}
+
+func backendServiceName(L *lua.LState) (string, bool) {
+	name, ok := L.CheckTable(1).RawGetString("name").(lua.LString)
+	if !ok || name == "" {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("name parameter is required"))
+		return "", false
+	}
+	return string(name), true
+}
+
+// backendAction runs start, stop, restart or reload. Like with systemd,
+// start and stop do nothing when the service already runs or is stopped.
+func (mod *SystemdModule) backendAction(m serviceBackend, action string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		name, ok := backendServiceName(L)
+		if !ok {
+			return 2
+		}
+
+		current := ""
+		if action == "start" || action == "stop" {
+			running, _, err := m.Status(name)
+			if err == nil && running == (action == "start") {
+				state := "active"
+				if action == "stop" {
+					state = "inactive"
+				}
+				result := L.NewTable()
+				result.RawSetString("changed", lua.LFalse)
+				result.RawSetString("message", lua.LString(fmt.Sprintf("Service %s is already %s", name, state)))
+				L.Push(lua.LTrue)
+				L.Push(result)
+				return 2
+			}
+			if err == nil {
+				current = "inactive"
+				if running {
+					current = "active"
+				}
+			}
+		}
+
+		if n, ok := mod.planned(L, action, name, current); ok {
+			return n
+		}
+
+		var err error
+		switch action {
+		case "start":
+			err = m.Start(name)
+		case "stop":
+			err = m.Stop(name)
+		case "restart":
+			err = m.Restart(name)
+		case "reload":
+			err = m.Reload(name)
+		}
+		if err != nil {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+
+		result := L.NewTable()
+		result.RawSetString("changed", lua.LTrue)
+		result.RawSetString("message", lua.LString(fmt.Sprintf("Service %s %s", name, pastTense(action))))
+		L.Push(lua.LTrue)
+		L.Push(result)
+		return 2
+	}
+}
+
+func pastTense(action string) string {
+	switch action {
+	case "stop":
+		return "stopped"
+	case "reload":
+		return "reloaded"
+	}
+	return action + "ed"
+}
+
+// backendEnable enables or disables a service at boot
+func (mod *SystemdModule) backendEnable(m serviceBackend, enable bool, where string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		name, ok := backendServiceName(L)
+		if !ok {
+			return 2
+		}
+
+		action := "enable"
+		if !enable {
+			action = "disable"
+		}
+		if n, ok := mod.planned(L, action, name, ""); ok {
+			return n
+		}
+
+		var changed bool
+		var err error
+		state := "enabled"
+		if enable {
+			changed, err = m.Enable(name)
+		} else {
+			changed, err = m.Disable(name)
+			state = "disabled"
+		}
+		if err != nil {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+
+		message := fmt.Sprintf("Service %s %s %s", name, state, where)
+		if !changed {
+			message = fmt.Sprintf("Service %s is already %s", name, state)
+		}
+		result := L.NewTable()
+		result.RawSetString("changed", lua.LBool(changed))
+		result.RawSetString("message", lua.LString(message))
+		L.Push(lua.LTrue)
+		L.Push(result)
+		return 2
+	}
+}
+
+func (mod *SystemdModule) backendStatus(m serviceBackend) lua.LGFunction {
+	return func(L *lua.LState) int {
+		name, ok := backendServiceName(L)
+		if !ok {
+			return 2
+		}
+		running, output, err := m.Status(name)
+		L.Push(lua.LString(output))
+		switch {
+		case err != nil:
+			L.Push(lua.LString(err.Error()))
+		case !running:
+			L.Push(lua.LString(fmt.Sprintf("service %s is not running", name)))
+		default:
+			L.Push(lua.LNil)
+		}
+		return 2
+	}
+}
+
+func (mod *SystemdModule) backendIsActive(m serviceBackend) lua.LGFunction {
+	return func(L *lua.LState) int {
+		name, ok := backendServiceName(L)
+		if !ok {
+			return 2
+		}
+		running, _, err := m.Status(name)
+		state := "inactive"
+		if running {
+			state = "active"
+		}
+		if err != nil {
+			state = err.Error()
+		}
+		L.Push(lua.LBool(running))
+		L.Push(lua.LString(state))
+		return 2
+	}
+}
+
+func (mod *SystemdModule) backendIsEnabled(m serviceBackend) lua.LGFunction {
+	return func(L *lua.LState) int {
+		name, ok := backendServiceName(L)
+		if !ok {
+			return 2
+		}
+		enabled, err := m.Enabled(name)
+		state := "disabled"
+		if enabled {
+			state = "enabled"
+		}
+		if err != nil {
+			state = err.Error()
+		}
+		L.Push(lua.LBool(enabled))
+		L.Push(lua.LString(state))
+		return 2
+	}
+}
+
+// backendList lists services, one per line, optionally only the running
+// ("active") or stopped ("inactive") ones
+func (mod *SystemdModule) backendList(m serviceBackend) lua.LGFunction {
+	return func(L *lua.LState) int {
+		opts := L.OptTable(1, L.NewTable())
+		state := lua.LVAsString(opts.RawGetString("state"))
+
+		services, err := m.List()
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("Failed to list services: %v", err)))
+			return 2
+		}
+
+		var lines []string
+		for _, name := range services {
+			if state == "active" || state == "running" || state == "inactive" {
+				running, _, _ := m.Status(name)
+				if running != (state != "inactive") {
+					continue
+				}
+			}
+			lines = append(lines, name)
+		}
+		L.Push(lua.LString(strings.Join(lines, "\n")))
+		L.Push(lua.LNil)
+		return 2
+	}
+}
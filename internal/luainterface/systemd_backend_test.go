@@ -0,0 +1,172 @@
+package luainterface
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/rcd"
+	"github.com/chalkan3-sloth/sloth-runner/internal/scm"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSystemdModule_RCD(t *testing.T) {
+	dir := t.TempDir()
+	m := rcd.New(rcd.FreeBSD)
+	m.RCConf = filepath.Join(dir, "rc.conf")
+	m.Defaults = ""
+	m.ScriptDirs = nil
+
+	running := map[string]bool{"sshd": true}
+	var calls []string
+	m.Run = func(name string, args ...string) (string, error) {
+		calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+		service, command := args[0], args[1]
+		switch command {
+		case "onestatus":
+			if !running[service] {
+				return service + " is not running.\n", &rcd.ExitError{Code: 1}
+			}
+			return service + " is running as pid 42.\n", nil
+		case "onestart":
+			running[service] = true
+		}
+		return "", nil
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	L.Push(L.NewFunction((&SystemdModule{rcd: m}).Loader))
+	L.Call(0, 1)
+	L.SetGlobal("systemd", L.Get(-1))
+	L.Pop(1)
+
+	err := L.DoString(`
+		assert(systemd.manager == "rc.d")
+
+		local ok, result = systemd.start({name = "sshd"})
+		assert(ok and result.changed == false, "sshd already runs")
+		ok, result = systemd.start({name = "nginx"})
+		assert(ok and result.changed == true, tostring(result))
+		assert(systemd.is_active({name = "nginx"}))
+
+		ok, result = systemd.enable({name = "nginx"})
+		assert(ok and result.changed == true, tostring(result))
+		ok, result = systemd.enable({name = "nginx"})
+		assert(ok and result.changed == false)
+		assert(systemd.is_enabled({name = "nginx"}))
+
+		local out, err = systemd.status({name = "redis"})
+		assert(err ~= nil and out:find("not running"), tostring(err))
+
+		ok, err = systemd.create_service({name = "app"})
+		assert(not ok and err:find("rc.d"), err)
+		ok, err = systemd.start({})
+		assert(not ok and err == "name parameter is required")
+	`)
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+
+	data, err := os.ReadFile(m.RCConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "nginx_enable=\"YES\"\n" {
+		t.Errorf("rc.conf = %q", data)
+	}
+	if calls[1] != "service nginx onestatus" || calls[2] != "service nginx onestart" {
+		t.Errorf("unexpected commands: %v", calls)
+	}
+}
+
+// memorySCM is a Service Control Manager whose services change state at
+// once
+type memorySCM struct {
+	states     map[string]scm.State
+	startTypes map[string]scm.StartType
+}
+
+func (s *memorySCM) State(service string) (scm.State, error) {
+	state, ok := s.states[service]
+	if !ok {
+		return "", errors.New("The specified service does not exist as an installed service.")
+	}
+	return state, nil
+}
+
+func (s *memorySCM) StartType(service string) (scm.StartType, error) {
+	return s.startTypes[service], nil
+}
+
+func (s *memorySCM) SetStartType(service string, t scm.StartType) error {
+	s.startTypes[service] = t
+	return nil
+}
+
+func (s *memorySCM) Start(service string) error {
+	s.states[service] = scm.Running
+	return nil
+}
+
+func (s *memorySCM) Stop(service string) error {
+	s.states[service] = scm.Stopped
+	return nil
+}
+
+func (s *memorySCM) ParamChange(service string) error {
+	return errors.New("The requested control is not valid for this service.")
+}
+
+func (s *memorySCM) List() ([]string, error) {
+	var services []string
+	for name := range s.states {
+		services = append(services, name)
+	}
+	return services, nil
+}
+
+func TestSystemdModule_SCM(t *testing.T) {
+	controller := &memorySCM{
+		states:     map[string]scm.State{"W3SVC": scm.Running, "Spooler": scm.Stopped},
+		startTypes: map[string]scm.StartType{"W3SVC": scm.Automatic, "Spooler": scm.Manual},
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	L.Push(L.NewFunction((&SystemdModule{scm: scm.New(controller)}).Loader))
+	L.Call(0, 1)
+	L.SetGlobal("systemd", L.Get(-1))
+	L.Pop(1)
+
+	err := L.DoString(`
+		assert(systemd.manager == "scm")
+
+		local ok, result = systemd.start({name = "W3SVC"})
+		assert(ok and result.changed == false, "W3SVC already runs")
+		ok, result = systemd.start({name = "Spooler"})
+		assert(ok and result.changed == true, tostring(result))
+		assert(systemd.is_active({name = "Spooler"}))
+
+		ok, result = systemd.enable({name = "Spooler"})
+		assert(ok and result.changed == true, tostring(result))
+		assert(result.message == "Service Spooler enabled to start at boot", result.message)
+		assert(systemd.is_enabled({name = "Spooler"}))
+
+		ok, err = systemd.reload({name = "W3SVC"})
+		assert(not ok and err:find("restart it instead"), tostring(err))
+
+		assert(systemd.list_services({state = "active"}) == "Spooler\nW3SVC")
+
+		ok, err = systemd.create_service({name = "app"})
+		assert(not ok and err:find("windows, which uses scm"), err)
+	`)
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	if controller.startTypes["Spooler"] != scm.Automatic {
+		t.Errorf("Spooler start type = %s", controller.startTypes["Spooler"])
+	}
+}
@@ -0,0 +1,77 @@
+package luainterface
+
+import (
+	lua "github.com/yuin/gopher-lua"
+)
+
+// taskSetOutput implements task.set_output(name, value, {sensitive = true}).
+// The value becomes part of the task's output, as if returned from the
+// command, and is recorded with the run; sensitive values are stored
+// sealed and shown masked.
+func taskSetOutput(L *lua.LState) int {
+	name := L.CheckString(1)
+	value := L.CheckAny(2)
+	opts := L.OptTable(3, nil)
+
+	taskContext, ok := L.GetGlobal("__task_context").(*lua.LTable)
+	if !ok {
+		L.RaiseError("task.set_output can only be called while a task runs")
+		return 0
+	}
+
+	outputs := contextTable(L, taskContext, "outputs")
+	outputs.RawSetString(name, value)
+
+	sensitive := false
+	if opts != nil {
+		sensitive = lua.LVAsBool(opts.RawGetString("sensitive"))
+	}
+	contextTable(L, taskContext, "output_sensitive").RawSetString(name, lua.LBool(sensitive))
+	return 0
+}
+
+// SetOutputs returns the outputs the last task function set with
+// task.set_output, mapped to whether they are sensitive
+func SetOutputs(L *lua.LState) map[string]bool {
+	taskContext, ok := L.GetGlobal("__task_context").(*lua.LTable)
+	if !ok {
+		return nil
+	}
+	sensitive, ok := taskContext.RawGetString("output_sensitive").(*lua.LTable)
+	if !ok {
+		return nil
+	}
+
+	result := make(map[string]bool)
+	sensitive.ForEach(func(key, value lua.LValue) {
+		result[key.String()] = lua.LVAsBool(value)
+	})
+	return result
+}
+
+// mergeSetOutputs adds the values set with task.set_output to the output
+// table a task function returned. Returned values take precedence.
+func mergeSetOutputs(L *lua.LState, taskContext, outputTable *lua.LTable) *lua.LTable {
+	outputs, ok := taskContext.RawGetString("outputs").(*lua.LTable)
+	if !ok {
+		return outputTable
+	}
+	if outputTable == nil {
+		outputTable = L.NewTable()
+	}
+	outputs.ForEach(func(key, value lua.LValue) {
+		if outputTable.RawGet(key) == lua.LNil {
+			outputTable.RawSet(key, value)
+		}
+	})
+	return outputTable
+}
+
+func contextTable(L *lua.LState, taskContext *lua.LTable, name string) *lua.LTable {
+	if table, ok := taskContext.RawGetString(name).(*lua.LTable); ok {
+		return table
+	}
+	table := L.NewTable()
+	taskContext.RawSetString(name, table)
+	return table
+}
@@ -0,0 +1,48 @@
+package luainterface
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestTaskSetOutput(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	OpenModernDSL(L)
+
+	// task() still builds tasks
+	require.NoError(t, L.DoString(`builder = task("deploy")`))
+	assert.NotEqual(t, lua.LNil, L.GetGlobal("builder"))
+
+	// Outside a task there is nothing to set outputs on
+	assert.ErrorContains(t, L.DoString(`task.set_output("x", 1)`), "while a task runs")
+
+	require.NoError(t, L.DoString(`
+		fn = function()
+			task.set_output("db_host", "db.internal")
+			task.set_output("db_password", "s3cret", {sensitive = true})
+			return true, "ok", {db_host = "returned"}
+		end
+	`))
+	fn := L.GetGlobal("fn").(*lua.LFunction)
+
+	success, _, output, err := ExecuteLuaFunction(L, fn, map[string]string{"task_name": "db"}, nil, 3, context.Background())
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// Returned values take precedence over set_output
+	assert.Equal(t, lua.LString("returned"), output.RawGetString("db_host"))
+	assert.Equal(t, lua.LString("s3cret"), output.RawGetString("db_password"))
+	assert.Equal(t, map[string]bool{"db_host": false, "db_password": true}, SetOutputs(L))
+
+	// Each call starts without outputs
+	require.NoError(t, L.DoString(`fn = function() return true, "ok" end`))
+	_, _, output, err = ExecuteLuaFunction(L, L.GetGlobal("fn").(*lua.LFunction), nil, nil, 3, context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, output)
+	assert.Empty(t, SetOutputs(L))
+}
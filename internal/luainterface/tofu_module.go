@@ -0,0 +1,12 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/modules/infra"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RegisterTofuModule registers the OpenTofu/Terraform module into the Lua state
+func RegisterTofuModule(L *lua.LState) {
+	tofuModule := infra.NewTofuModule()
+	tofuModule.Register(L)
+}
@@ -0,0 +1,55 @@
+package luainterface
+
+import (
+	"github.com/chalkan3-sloth/sloth-runner/internal/telemetry"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// luaStdlibTables are not counted in usage stats
+var luaStdlibTables = map[string]bool{
+	"_G": true, "string": true, "table": true, "math": true, "os": true,
+	"io": true, "coroutine": true, "debug": true, "package": true, "channel": true,
+}
+
+// OpenUsageStats wraps the functions of the built-in modules so each call
+// is counted in the opt-in usage stats. It must run before workflow code,
+// so tables defined by workflows are never counted. Nothing is wrapped
+// when usage stats are disabled.
+func OpenUsageStats(L *lua.LState) {
+	if !telemetry.UsageEnabled() {
+		return
+	}
+
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		module := key.String()
+		table, ok := value.(*lua.LTable)
+		if !ok || luaStdlibTables[module] {
+			return
+		}
+
+		var names []string
+		table.ForEach(func(k, v lua.LValue) {
+			if _, ok := v.(*lua.LFunction); ok {
+				names = append(names, k.String())
+			}
+		})
+		for _, name := range names {
+			fn := table.RawGetString(name).(*lua.LFunction)
+			table.RawSetString(name, L.NewFunction(usageWrapper(module, name, fn)))
+		}
+	})
+}
+
+func usageWrapper(module, function string, fn *lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		telemetry.RecordModuleCall(module, function)
+
+		top := L.GetTop()
+		L.Push(fn)
+		for i := 1; i <= top; i++ {
+			L.Push(L.Get(i))
+		}
+		L.Call(top, lua.MultRet)
+		return L.GetTop() - top
+	}
+}
@@ -0,0 +1,33 @@
+package luainterface
+
+import (
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/telemetry"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestUsageWrapper(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	fn := L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString("installed " + L.CheckString(1)))
+		L.Push(lua.LTrue)
+		return 2
+	})
+	mod := L.NewTable()
+	mod.RawSetString("install", L.NewFunction(usageWrapper("usagetest", "install", fn)))
+	L.SetGlobal("usagetest", mod)
+
+	before := telemetry.ModuleCalls()["usagetest.install"]
+	if err := L.DoString(`msg, ok = usagetest.install("nginx")`); err != nil {
+		t.Fatal(err)
+	}
+	if msg := L.GetGlobal("msg").String(); msg != "installed nginx" || L.GetGlobal("ok") != lua.LTrue {
+		t.Errorf("Expected the wrapped function's results, got %s %v", msg, L.GetGlobal("ok"))
+	}
+	if calls := telemetry.ModuleCalls()["usagetest.install"]; calls != before+1 {
+		t.Errorf("Expected the call to be counted, got %d", calls-before)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -228,7 +229,7 @@ func (u *UserModule) createUser(L *lua.LState) int {
 
 	args = append(args, username)
 
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err = cmd.CombinedOutput()
 	if err != nil {
 		// Update resource state to failed
@@ -248,7 +249,7 @@ func (u *UserModule) createUser(L *lua.LState) int {
 			chownArgs = append(chownArgs, "sudo")
 		}
 		chownArgs = append(chownArgs, "chown", "-R", fmt.Sprintf("%s:%s", username, username), home)
-		chownCmd := exec.Command(chownArgs[0], chownArgs[1:]...)
+		chownCmd := taskctx.Command(callContext(L), chownArgs[0], chownArgs[1:]...)
 		chownOutput, chownErr := chownCmd.CombinedOutput()
 		if chownErr != nil {
 			// Log the error but don't fail the user creation, as the user itself was created
@@ -273,7 +274,7 @@ func (u *UserModule) createUser(L *lua.LState) int {
 
 		passArgs = append(passArgs, "chpasswd")
 
-		passCmd := exec.Command(passArgs[0], passArgs[1:]...)
+		passCmd := taskctx.Command(callContext(L), passArgs[0], passArgs[1:]...)
 		passCmd.Stdin = strings.NewReader(fmt.Sprintf("%s:%s\n", username, password))
 
 		passOutput, passErr := passCmd.CombinedOutput()
@@ -323,7 +324,7 @@ func (u *UserModule) deleteUser(L *lua.LState) int {
 	}
 	args = append(args, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -392,7 +393,7 @@ func (u *UserModule) modifyUser(L *lua.LState) int {
 	
 	args = append(args, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -533,7 +534,7 @@ func (u *UserModule) lockUser(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-L", username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -562,7 +563,7 @@ func (u *UserModule) unlockUser(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-U", username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -591,7 +592,7 @@ func (u *UserModule) isUserLocked(L *lua.LState) int {
 	
 	args = append(args, "passwd", "-S", username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LNil)
@@ -636,7 +637,7 @@ func (u *UserModule) setPassword(L *lua.LState) int {
 	
 	args = append(args, "chpasswd")
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	cmd.Stdin = strings.NewReader(fmt.Sprintf("%s:%s\n", username, password))
 	
 	output, err := cmd.CombinedOutput()
@@ -667,7 +668,7 @@ func (u *UserModule) expirePassword(L *lua.LState) int {
 	
 	args = append(args, "passwd", "-e", username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -704,7 +705,7 @@ func (u *UserModule) setShell(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-s", shell, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -746,7 +747,7 @@ func (u *UserModule) setHomeDir(L *lua.LState) int {
 	}
 	args = append(args, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -855,7 +856,7 @@ func (u *UserModule) addUserToGroup(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-aG", group, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -892,7 +893,7 @@ func (u *UserModule) removeUserFromGroup(L *lua.LState) int {
 	
 	args = append(args, "gpasswd", "-d", username, group)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -929,7 +930,7 @@ func (u *UserModule) setPrimaryGroup(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-g", group, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1031,7 +1032,7 @@ func (u *UserModule) setComment(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-c", comment, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1116,7 +1117,7 @@ func (u *UserModule) createGroup(L *lua.LState) int {
 	
 	args = append(args, groupname)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1145,7 +1146,7 @@ func (u *UserModule) deleteGroup(L *lua.LState) int {
 	
 	args = append(args, "groupdel", groupname)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1329,7 +1330,7 @@ func (u *UserModule) addGroupMember(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-aG", groupname, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1366,7 +1367,7 @@ func (u *UserModule) removeGroupMember(L *lua.LState) int {
 	
 	args = append(args, "gpasswd", "-d", username, groupname)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1405,7 +1406,7 @@ func (u *UserModule) setAccountExpiry(L *lua.LState) int {
 	
 	args = append(args, "usermod", "-e", expiry, username)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -1546,7 +1547,7 @@ func (u *UserModule) runAs(L *lua.LState) int {
 	
 	args = append(args, "sh", "-c", command)
 	
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(callContext(L), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		L.Push(lua.LFalse)
@@ -0,0 +1,194 @@
+package luainterface
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Watch is a condition the master watches on its own host, declared in a
+// workflow file with watch.file, watch.process or watch.port, and the
+// tasks it runs when the condition changes
+type Watch struct {
+	Kind   string // file, process or port
+	Target string // the path, process name or port watched
+	// Handlers are the tasks run on each event, in the order of their
+	// event types
+	Handlers []WatchHandler
+	// Interval is how often the condition is checked; 5s when zero
+	Interval time.Duration
+	// CheckHash has file watches also compare the file's checksum
+	CheckHash bool
+}
+
+// WatchHandler is the task a watch runs on an event
+type WatchHandler struct {
+	Event    string // event type, as in file.modified
+	Task     string
+	Workflow string // workflow of the file that defines Task
+}
+
+// watchEvents are the handler options of each kind of watch and the event
+// types they handle, the ones the agent's watchers raise
+var watchEvents = map[string]map[string]string{
+	"file":    {"on_change": "file.modified", "on_create": "file.created", "on_delete": "file.deleted"},
+	"process": {"on_start": "process.started", "on_stop": "process.stopped"},
+	"port":    {"on_open": "port.opened", "on_close": "port.closed"},
+}
+
+// watchesGlobal holds the watches a workflow file declares
+const watchesGlobal = "__watches__"
+
+// OpenWatch registers the watch module, which declares what the master
+// watches for a workflow file:
+//
+//	watch.file("/etc/nginx/nginx.conf", {on_change = "reload_nginx"})
+//	watch.process("nginx", {on_stop = "start_nginx", interval = "10s"})
+//	watch.port(8080, {on_close = "restart_api"})
+//
+// Watches only take effect on a master the file is added to; runs of the
+// file ignore them.
+func OpenWatch(L *lua.LState) {
+	mod := L.NewTable()
+	for kind := range watchEvents {
+		L.SetField(mod, kind, L.NewFunction(watchDeclare(kind)))
+	}
+	L.SetGlobal("watch", mod)
+}
+
+// watchDeclare returns the function declaring a watch of kind
+func watchDeclare(kind string) lua.LGFunction {
+	return func(L *lua.LState) int {
+		var target string
+		if kind == "port" {
+			port := L.CheckInt(1)
+			if port < 1 || port > 65535 {
+				L.ArgError(1, fmt.Sprintf("invalid port %d", port))
+			}
+			target = strconv.Itoa(port)
+		} else {
+			target = L.CheckString(1)
+			if target == "" {
+				L.ArgError(1, "must not be empty")
+			}
+		}
+		opts := L.CheckTable(2)
+
+		w := &Watch{Kind: kind, Target: target}
+		var err error
+		if w.CheckHash, err = optBool(L, opts, "check_hash", false); err != nil {
+			L.ArgError(2, err.Error())
+		}
+		if kind != "file" && opts.RawGetString("check_hash") != lua.LNil {
+			L.ArgError(2, "check_hash only applies to watch.file")
+		}
+		if v := opts.RawGetString("interval"); v != lua.LNil {
+			if w.Interval, err = time.ParseDuration(v.String()); err != nil || w.Interval <= 0 {
+				L.ArgError(2, fmt.Sprintf("invalid interval %q", v.String()))
+			}
+		}
+		workflow := lua.LVAsString(opts.RawGetString("workflow"))
+
+		for option, event := range watchEvents[kind] {
+			v := opts.RawGetString(option)
+			if v == lua.LNil {
+				continue
+			}
+			task, ok := v.(lua.LString)
+			if !ok || task == "" {
+				L.ArgError(2, fmt.Sprintf("%s must name a task", option))
+			}
+			w.Handlers = append(w.Handlers, WatchHandler{Event: event, Task: string(task), Workflow: workflow})
+		}
+		if len(w.Handlers) == 0 {
+			L.ArgError(2, fmt.Sprintf("watch.%s needs a task to run, set %s", kind, watchOptions(kind)))
+		}
+		sort.Slice(w.Handlers, func(i, j int) bool { return w.Handlers[i].Event < w.Handlers[j].Event })
+
+		watches, ok := L.GetGlobal(watchesGlobal).(*lua.LTable)
+		if !ok {
+			watches = L.NewTable()
+			L.SetGlobal(watchesGlobal, watches)
+		}
+		ud := L.NewUserData()
+		ud.Value = w
+		watches.Append(ud)
+		return 0
+	}
+}
+
+// watchOptions lists the handler options of kind, as in "on_change, on_create or on_delete"
+func watchOptions(kind string) string {
+	var options []string
+	for option := range watchEvents[kind] {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	last := len(options) - 1
+	return strings.Join(options[:last], ", ") + " or " + options[last]
+}
+
+// ParseLuaWatches returns the watches a workflow file declares, with the
+// workflow of each handler's task
+func ParseLuaWatches(ctx context.Context, filePath string, valuesTable *lua.LTable) ([]Watch, error) {
+	groups, globals, err := parseLuaScript(ctx, filePath, valuesTable)
+	if err != nil {
+		return nil, err
+	}
+	table, ok := globals.RawGetString(watchesGlobal).(*lua.LTable)
+	if !ok {
+		return nil, nil
+	}
+
+	var watches []Watch
+	for i := 1; i <= table.Len(); i++ {
+		ud, ok := table.RawGetInt(i).(*lua.LUserData)
+		if !ok {
+			continue
+		}
+		w, ok := ud.Value.(*Watch)
+		if !ok {
+			continue
+		}
+		for j := range w.Handlers {
+			if w.Handlers[j].Workflow, err = taskWorkflow(groups, w.Handlers[j].Task, w.Handlers[j].Workflow); err != nil {
+				return nil, fmt.Errorf("watch.%s(%s): %w", w.Kind, w.Target, err)
+			}
+		}
+		watches = append(watches, *w)
+	}
+	return watches, nil
+}
+
+// taskWorkflow returns the workflow of groups that defines task: workflow
+// itself when set, otherwise the only one that does
+func taskWorkflow(groups map[string]types.TaskGroup, task, workflow string) (string, error) {
+	var found []string
+	for name, group := range groups {
+		if workflow != "" && name != workflow {
+			continue
+		}
+		for _, t := range group.Tasks {
+			if t.Name == task {
+				found = append(found, name)
+				break
+			}
+		}
+	}
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case workflow != "":
+		return "", fmt.Errorf("task %s not found in workflow %s", task, workflow)
+	case len(found) == 0:
+		return "", fmt.Errorf("task %s not found in any workflow", task)
+	}
+	sort.Strings(found)
+	return "", fmt.Errorf("task %s is defined by several workflows (%v), set workflow", task, found)
+}
@@ -0,0 +1,86 @@
+package luainterface
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes a workflow file to a temporary directory
+func writeScript(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "test.sloth")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0644))
+	return path
+}
+
+func TestParseLuaWatches(t *testing.T) {
+	path := writeScript(t, `
+workflow.define("nginx", {
+	tasks = {
+		{ name = "reload_nginx", command = "nginx -s reload" },
+		{ name = "start_nginx", command = "systemctl start nginx" },
+	}
+})
+workflow.define("api", {
+	tasks = {{ name = "restart", command = "systemctl restart api" }}
+})
+workflow.define("worker", {
+	tasks = {{ name = "restart", command = "systemctl restart worker" }}
+})
+
+watch.file("/etc/nginx/nginx.conf", {on_change = "reload_nginx", on_delete = "start_nginx", check_hash = true})
+watch.process("nginx", {on_stop = "start_nginx", interval = "10s"})
+watch.port(8080, {on_close = "restart", workflow = "api"})
+`)
+
+	watches, err := ParseLuaWatches(context.Background(), path, nil)
+	require.NoError(t, err)
+	require.Len(t, watches, 3)
+
+	assert.Equal(t, Watch{
+		Kind:   "file",
+		Target: "/etc/nginx/nginx.conf",
+		Handlers: []WatchHandler{
+			{Event: "file.deleted", Task: "start_nginx", Workflow: "nginx"},
+			{Event: "file.modified", Task: "reload_nginx", Workflow: "nginx"},
+		},
+		CheckHash: true,
+	}, watches[0])
+	assert.Equal(t, "process", watches[1].Kind)
+	assert.Equal(t, 10*time.Second, watches[1].Interval)
+	assert.Equal(t, []WatchHandler{{Event: "port.closed", Task: "restart", Workflow: "api"}}, watches[2].Handlers)
+	assert.Equal(t, "8080", watches[2].Target)
+
+	path = writeScript(t, `workflow.define("nginx", {tasks = {{ name = "reload", command = "true" }}})`)
+	watches, err = ParseLuaWatches(context.Background(), path, nil)
+	require.NoError(t, err)
+	assert.Empty(t, watches)
+}
+
+func TestParseLuaWatchesErrors(t *testing.T) {
+	tasks := `
+workflow.define("api", {tasks = {{ name = "restart", command = "true" }}})
+workflow.define("worker", {tasks = {{ name = "restart", command = "true" }}})
+`
+	tests := []struct {
+		watch string
+		err   string
+	}{
+		{`watch.port(8080, {on_close = "restart"})`, "task restart is defined by several workflows ([api worker]), set workflow"},
+		{`watch.port(8080, {on_close = "restart", workflow = "deploy"})`, "task restart not found in workflow deploy"},
+		{`watch.file("/tmp/x", {on_change = "reload"})`, "task reload not found in any workflow"},
+		{`watch.file("/tmp/x", {})`, "watch.file needs a task to run, set on_change, on_create or on_delete"},
+		{`watch.port(70000, {on_open = "restart"})`, "invalid port 70000"},
+		{`watch.process("api", {on_start = "restart", check_hash = true})`, "check_hash only applies to watch.file"},
+		{`watch.process("api", {on_start = "restart", interval = "soon"})`, `invalid interval "soon"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseLuaWatches(context.Background(), writeScript(t, tasks+tt.watch), nil)
+		assert.ErrorContains(t, err, tt.err, tt.watch)
+	}
+}
@@ -88,10 +88,7 @@ func (m *EventRegisterModule) registerFileWatcher(L *lua.LState) int {
 		watcherConfig["recursive"] = lua.LVAsBool(recursive)
 	}
 
-	// Optional: interval
-	if interval := L.GetField(config, "interval"); interval != lua.LNil {
-		watcherConfig["interval"] = interval.String()
-	}
+	m.extractCommonFields(L, config, watcherConfig)
 
 	// Store in global registry
 	// Watchers are automatically registered on the agent where this code executes
@@ -137,10 +134,7 @@ func (m *EventRegisterModule) registerProcessWatcher(L *lua.LState) int {
 	}
 	watcherConfig["conditions"] = conditions
 
-	// Optional: interval
-	if interval := L.GetField(config, "interval"); interval != lua.LNil {
-		watcherConfig["interval"] = interval.String()
-	}
+	m.extractCommonFields(L, config, watcherConfig)
 
 	m.storeWatcher(L, watcherConfig)
 
@@ -183,10 +177,7 @@ func (m *EventRegisterModule) registerPortWatcher(L *lua.LState) int {
 	}
 	watcherConfig["conditions"] = conditions
 
-	// Optional: interval
-	if interval := L.GetField(config, "interval"); interval != lua.LNil {
-		watcherConfig["interval"] = interval.String()
-	}
+	m.extractCommonFields(L, config, watcherConfig)
 
 	m.storeWatcher(L, watcherConfig)
 
@@ -229,10 +220,7 @@ func (m *EventRegisterModule) registerServiceWatcher(L *lua.LState) int {
 	}
 	watcherConfig["conditions"] = conditions
 
-	// Optional: interval
-	if interval := L.GetField(config, "interval"); interval != lua.LNil {
-		watcherConfig["interval"] = interval.String()
-	}
+	m.extractCommonFields(L, config, watcherConfig)
 
 	m.storeWatcher(L, watcherConfig)
 
@@ -365,10 +353,7 @@ func (m *EventRegisterModule) registerCustomWatcher(L *lua.LState) int {
 	}
 	watcherConfig["conditions"] = conditions
 
-	// Optional: interval
-	if interval := L.GetField(config, "interval"); interval != lua.LNil {
-		watcherConfig["interval"] = interval.String()
-	}
+	m.extractCommonFields(L, config, watcherConfig)
 
 	m.storeWatcher(L, watcherConfig)
 
@@ -387,6 +372,11 @@ func (m *EventRegisterModule) extractCommonFields(L *lua.LState, config *lua.LTa
 	if agent := L.GetField(config, "agent"); agent != lua.LNil {
 		watcherConfig["agent"] = agent.String()
 	}
+
+	// Optional: group (targeted by event triggers)
+	if group := L.GetField(config, "group"); group != lua.LNil {
+		watcherConfig["group"] = group.String()
+	}
 }
 
 // storeWatcher stores watcher config in Lua global registry AND registers with EventWatcherManager
@@ -420,6 +410,10 @@ func (m *EventRegisterModule) storeWatcher(L *lua.LState, config map[string]inte
 				Type: agent.WatcherType(config["type"].(string)),
 			}
 
+			if group, ok := config["group"].(string); ok {
+				watcherConfig.Group = group
+			}
+
 			// Parse interval
 			if interval, ok := config["interval"].(string); ok {
 				duration, err := time.ParseDuration(interval)
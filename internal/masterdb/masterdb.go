@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // Master represents a master server configuration
@@ -25,14 +25,14 @@ type MasterDB struct {
 
 // NewMasterDB creates a new MasterDB instance
 func NewMasterDB(dbPath string) (*MasterDB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	mdb := &MasterDB{db: db}
 	if err := mdb.initSchema(); err != nil {
-		db.Close()
+		sqlitedb.Close(db)
 		return nil, err
 	}
 
@@ -272,5 +272,5 @@ func (m *MasterDB) Count() (int, error) {
 
 // Close closes the database connection
 func (m *MasterDB) Close() error {
-	return m.db.Close()
+	return sqlitedb.Close(m.db)
 }
@@ -7,7 +7,7 @@ import (
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // MetricPoint represents a single metric data point
@@ -53,7 +53,7 @@ func NewMetricsDB(dbPath string) (*MetricsDB, error) {
 	// Log database path for debugging
 	fmt.Printf("📊 Opening metrics database at: %s\n", dbPath)
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open metrics database: %w", err)
 	}
@@ -357,5 +357,5 @@ func (m *MetricsDB) Close() error {
 		m.insertStmt.Close()
 	}
 
-	return m.db.Close()
+	return sqlitedb.Close(m.db)
 }
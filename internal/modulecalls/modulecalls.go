@@ -0,0 +1,183 @@
+// Package modulecalls bounds how long the module calls of tasks may take.
+// Modules fall in categories (network, package, command) with a timeout
+// after which their calls fail, and a slow threshold after which they are
+// reported as slow, so hung external dependencies show up quickly.
+package modulecalls
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Category groups modules that depend on the same kind of external system
+type Category string
+
+const (
+	// Network modules talk to remote services
+	Network Category = "network"
+	// Package modules run package managers
+	Package Category = "package"
+	// Command modules run commands and the tools they wrap
+	Command Category = "command"
+)
+
+// categories lists the modules of each category
+var categories = map[string]Category{
+	"artifact": Network, "aws": Network, "certs": Network, "azure": Network, "digitalocean": Network,
+	"gcp": Network, "git": Network, "http": Network, "net": Network,
+	"notifications": Network, "probe": Network, "s3": Network,
+
+	"pkg": Package,
+
+	"docker": Command, "exec": Command, "helm": Command, "incus": Command, "kubernetes": Command,
+	"pulumi": Command, "systemd": Command, "terraform": Command, "tofu": Command,
+}
+
+// CategoryOf returns the category of module, false for modules in none
+func CategoryOf(module string) (Category, bool) {
+	c, ok := categories[module]
+	return c, ok
+}
+
+// Limits bound the calls of a category. Durations are like "5m"; empty or
+// "0" means no limit.
+type Limits struct {
+	// Timeout is how long a call may run before it fails
+	Timeout string `yaml:"timeout"`
+	// Slow is how long a call may run before it's reported as slow
+	Slow string `yaml:"slow"`
+}
+
+// Config holds the limits of each category
+type Config struct {
+	Network Limits `yaml:"network"`
+	Package Limits `yaml:"package"`
+	Command Limits `yaml:"command"`
+}
+
+// DefaultConfig returns the limits used for what config.yaml leaves out.
+// Network calls may run as long as http.download by default, and commands
+// have no timeout: they run as long as the task lets them.
+func DefaultConfig() Config {
+	return Config{
+		Network: Limits{Timeout: "30m", Slow: "30s"},
+		Package: Limits{Timeout: "30m", Slow: "2m"},
+		Command: Limits{Slow: "2m"},
+	}
+}
+
+// limit is a parsed Limits
+type limit struct {
+	timeout time.Duration
+	slow    time.Duration
+}
+
+// Bounds holds the parsed limits of a Config
+type Bounds struct {
+	limits map[Category]limit
+}
+
+// NewBounds parses cfg
+func NewBounds(cfg Config) (*Bounds, error) {
+	b := &Bounds{limits: make(map[Category]limit)}
+	for category, limits := range map[Category]Limits{Network: cfg.Network, Package: cfg.Package, Command: cfg.Command} {
+		timeout, err := parseDuration(limits.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%s timeout: %w", category, err)
+		}
+		slow, err := parseDuration(limits.Slow)
+		if err != nil {
+			return nil, fmt.Errorf("%s slow: %w", category, err)
+		}
+		b.limits[category] = limit{timeout: timeout, slow: slow}
+	}
+	return b, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+// Timeout returns how long the calls of category may run, 0 for no limit
+func (b *Bounds) Timeout(category Category) time.Duration {
+	return b.limits[category].timeout
+}
+
+// Slow returns how long the calls of category may run before they're
+// reported as slow, 0 to never report them
+func (b *Bounds) Slow(category Category) time.Duration {
+	return b.limits[category].slow
+}
+
+// fileConfig is the part of config.yaml read by this package
+type fileConfig struct {
+	ModuleCalls Config `yaml:"module_calls"`
+}
+
+// LoadConfig reads the module_calls section of a config file over the
+// defaults. A missing file means the defaults.
+func LoadConfig(path string) (Config, error) {
+	fc := fileConfig{ModuleCalls: DefaultConfig()}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fc.ModuleCalls, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return fc.ModuleCalls, nil
+}
+
+var (
+	defaultBounds *Bounds
+	defaultMu     sync.Mutex
+)
+
+// Default returns the bounds module calls run with. They are loaded from
+// the config file the first time they are needed; an invalid config is
+// logged and leaves the defaults.
+func Default() *Bounds {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultBounds != nil {
+		return defaultBounds
+	}
+
+	path := config.GetConfigPath()
+	cfg, err := LoadConfig(path)
+	if err == nil {
+		defaultBounds, err = NewBounds(cfg)
+	}
+	if err != nil {
+		slog.Warn("Ignoring module call limits", "config", path, "error", err)
+		defaultBounds, _ = NewBounds(DefaultConfig())
+	}
+	return defaultBounds
+}
+
+// SetDefault replaces the bounds returned by Default
+func SetDefault(b *Bounds) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultBounds = b
+}
@@ -0,0 +1,73 @@
+package modulecalls
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	// A missing file means the defaults
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != DefaultConfig() {
+		t.Errorf("Expected the defaults, got %+v", cfg)
+	}
+
+	data := "downloads:\n  bandwidth: 10MB/s\nmodule_calls:\n  package:\n    timeout: 1h\n  command:\n    timeout: 10m\n    slow: \"0\"\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBounds(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		category      Category
+		timeout, slow time.Duration
+	}{
+		{Network, 30 * time.Minute, 30 * time.Second},
+		{Package, time.Hour, 2 * time.Minute},
+		{Command, 10 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		if got := b.Timeout(tt.category); got != tt.timeout {
+			t.Errorf("%s timeout = %v, want %v", tt.category, got, tt.timeout)
+		}
+		if got := b.Slow(tt.category); got != tt.slow {
+			t.Errorf("%s slow = %v, want %v", tt.category, got, tt.slow)
+		}
+	}
+}
+
+func TestNewBounds_Invalid(t *testing.T) {
+	for _, cfg := range []Config{
+		{Network: Limits{Timeout: "soon"}},
+		{Package: Limits{Slow: "-1m"}},
+	} {
+		if _, err := NewBounds(cfg); err == nil {
+			t.Errorf("NewBounds(%+v): expected an error", cfg)
+		}
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	for module, want := range map[string]Category{"http": Network, "pkg": Package, "exec": Command} {
+		if got, ok := CategoryOf(module); !ok || got != want {
+			t.Errorf("CategoryOf(%q) = %v, %v, want %v", module, got, ok, want)
+		}
+	}
+	if _, ok := CategoryOf("log"); ok {
+		t.Error("Expected log to be in no category")
+	}
+}
@@ -26,6 +26,15 @@ func SetGlobalEventDispatcher(dispatcher EventDispatcher) {
 	globalEventDispatcher = dispatcher
 }
 
+// DispatchEvent dispatches an event from Go code run by workflows, such as
+// the helpers of the DSL. Without a dispatcher the event is dropped.
+func DispatchEvent(eventType string, data map[string]interface{}) error {
+	if globalEventDispatcher == nil {
+		return nil
+	}
+	return globalEventDispatcher(eventType, data)
+}
+
 // SetExecutionContext sets the global execution context for events
 func SetExecutionContext(stack, agent, runID string) {
 	globalStack = stack
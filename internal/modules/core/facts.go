@@ -1,15 +1,19 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -31,7 +35,7 @@ func (m *FactsModule) getAgentFacts(agentName string) (*agent.SystemInfo, error)
 	defer cancel()
 
 	conn, err := grpc.Dial(m.masterAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to master: %w", err)
@@ -84,6 +88,8 @@ func (m *FactsModule) Register(L *lua.LState) {
 		"get_load":      m.luaGetLoad,
 		"get_kernel":    m.luaGetKernel,
 		"query":         m.luaQuery,
+		"get_custom":    m.luaGetCustom,
+		"define":        m.luaDefine,
 	})
 
 	L.SetGlobal("facts", mod)
@@ -160,6 +166,7 @@ func (m *FactsModule) luaGetPlatform(L *lua.LState) int {
 	platformTable.RawSetString("os", lua.LString(facts.Platform))
 	platformTable.RawSetString("family", lua.LString(facts.PlatformFamily))
 	platformTable.RawSetString("version", lua.LString(facts.PlatformVersion))
+	platformTable.RawSetString("service_manager", lua.LString(facts.ServiceManager))
 	platformTable.RawSetString("architecture", lua.LString(facts.Architecture))
 	platformTable.RawSetString("kernel", lua.LString(facts.Kernel))
 	platformTable.RawSetString("kernel_version", lua.LString(facts.KernelVersion))
@@ -768,6 +775,132 @@ func (m *FactsModule) luaQuery(L *lua.LState) int {
 	return 1
 }
 
+// luaGetCustom gets user-defined facts. Without an agent they are collected
+// on the machine running the task.
+// Usage: facts.get_custom({ agent = "agent-name", name = "app" })
+func (m *FactsModule) luaGetCustom(L *lua.LState) int {
+	opts := L.OptTable(1, L.NewTable())
+	agentName := getStringField(L, opts, "agent", "")
+	name := getStringField(L, opts, "name", "")
+
+	var custom map[string]interface{}
+	if agentName == "" {
+		custom = localCustomFacts().Collect()
+	} else {
+		facts, err := m.getAgentFacts(agentName)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		custom = facts.Custom
+	}
+
+	if name != "" {
+		value, ok := custom[name]
+		if !ok {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("custom fact not found: %s", name)))
+			return 2
+		}
+		L.Push(factValueToLua(L, value))
+		return 1
+	}
+
+	result := L.NewTable()
+	for key, value := range custom {
+		result.RawSetString(key, factValueToLua(L, value))
+	}
+	L.Push(result)
+	return 1
+}
+
+// luaDefine installs a custom fact source in the facts directory of the
+// machine running the task, picked up by the agent on its next refresh
+// Usage: facts.define({ name = "app", command = "cat /opt/app/version.json" })
+//        facts.define({ name = "app", script = "#!/usr/bin/env python3\n..." })
+//        facts.define({ name = "role", value = { tier = "web" } })
+func (m *FactsModule) luaDefine(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	name := getStringField(L, opts, "name", "")
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("a valid fact name is required"))
+		return 2
+	}
+	dir := getStringField(L, opts, "dir", localCustomFacts().Dir())
+
+	var fileName string
+	var content []byte
+	var mode os.FileMode
+	switch {
+	case opts.RawGetString("value") != lua.LNil:
+		data, err := json.MarshalIndent(factLuaToGo(opts.RawGetString("value")), "", "  ")
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("failed to encode value: %v", err)))
+			return 2
+		}
+		fileName, content, mode = name+".json", append(data, '\n'), 0644
+	case getStringField(L, opts, "script", "") != "":
+		fileName, content, mode = name, []byte(getStringField(L, opts, "script", "")), 0755
+	case getStringField(L, opts, "command", "") != "":
+		fileName, content, mode = name, []byte("#!/bin/sh\n"+getStringField(L, opts, "command", "")+"\n"), 0755
+	default:
+		L.Push(lua.LNil)
+		L.Push(lua.LString("one of value, script or command is required"))
+		return 2
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("failed to create %s: %v", dir, err)))
+		return 2
+	}
+
+	path := filepath.Join(dir, fileName)
+	changed := false
+	// Another source with the same fact name would shadow this one
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if entry.Name() != fileName && agent.CustomFactName(entry.Name()) == name {
+				os.Remove(filepath.Join(dir, entry.Name()))
+				changed = true
+			}
+		}
+	}
+	info, err := os.Stat(path)
+	existing, _ := os.ReadFile(path)
+	if err != nil || !bytes.Equal(existing, content) || info.Mode().Perm() != mode {
+		if err := os.WriteFile(path, content, mode); err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("failed to write %s: %v", path, err)))
+			return 2
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("failed to chmod %s: %v", path, err)))
+			return 2
+		}
+		changed = true
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(changed))
+	result.RawSetString("path", lua.LString(path))
+	L.Push(result)
+	return 1
+}
+
+// localCustomFacts returns the agent's custom facts collector, or one for
+// the default directory outside of agents
+func localCustomFacts() *agent.CustomFactsCollector {
+	if c := agent.GetGlobalCustomFacts(); c != nil {
+		return c
+	}
+	return agent.NewCustomFactsCollector(agent.DefaultCustomFactsDir, 0, 0)
+}
+
 // Helper functions
 
 func (m *FactsModule) factsToLuaTable(L *lua.LState, facts *agent.SystemInfo) *lua.LTable {
@@ -777,6 +910,7 @@ func (m *FactsModule) factsToLuaTable(L *lua.LState, facts *agent.SystemInfo) *l
 	table.RawSetString("platform", lua.LString(facts.Platform))
 	table.RawSetString("platform_family", lua.LString(facts.PlatformFamily))
 	table.RawSetString("platform_version", lua.LString(facts.PlatformVersion))
+	table.RawSetString("service_manager", lua.LString(facts.ServiceManager))
 	table.RawSetString("architecture", lua.LString(facts.Architecture))
 	table.RawSetString("cpus", lua.LNumber(facts.CPUs))
 	table.RawSetString("kernel", lua.LString(facts.Kernel))
@@ -806,6 +940,13 @@ func (m *FactsModule) factsToLuaTable(L *lua.LState, facts *agent.SystemInfo) *l
 		loadArray.Append(lua.LNumber(load))
 	}
 	table.RawSetString("load_average", loadArray)
+
+	// Custom facts
+	custom := L.NewTable()
+	for name, value := range facts.Custom {
+		custom.RawSetString(name, factValueToLua(L, value))
+	}
+	table.RawSetString("custom", custom)
 	
 	return table
 }
@@ -846,3 +987,57 @@ func getStringField(L *lua.LState, table *lua.LTable, key, defaultValue string)
 	}
 	return defaultValue
 }
+
+// factValueToLua converts a decoded JSON value to Lua
+func factValueToLua(L *lua.LState, value interface{}) lua.LValue {
+	switch v := value.(type) {
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case map[string]interface{}:
+		table := L.NewTable()
+		for key, val := range v {
+			table.RawSetString(key, factValueToLua(L, val))
+		}
+		return table
+	case []interface{}:
+		table := L.NewTable()
+		for i, val := range v {
+			table.RawSetInt(i+1, factValueToLua(L, val))
+		}
+		return table
+	default:
+		return lua.LNil
+	}
+}
+
+// factLuaToGo converts a Lua value to one encodable as JSON. Tables with
+// only consecutive integer keys become lists.
+func factLuaToGo(value lua.LValue) interface{} {
+	switch v := value.(type) {
+	case lua.LBool:
+		return bool(v)
+	case lua.LNumber:
+		return float64(v)
+	case lua.LString:
+		return string(v)
+	case *lua.LTable:
+		if n := v.Len(); n > 0 {
+			list := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				list = append(list, factLuaToGo(v.RawGetInt(i)))
+			}
+			return list
+		}
+		obj := make(map[string]interface{})
+		v.ForEach(func(key, val lua.LValue) {
+			obj[key.String()] = factLuaToGo(val)
+		})
+		return obj
+	default:
+		return nil
+	}
+}
@@ -28,7 +28,7 @@ func TestFactsModule_Register(t *testing.T) {
 		"get_disk", "get_network", "get_packages", "get_package",
 		"get_services", "get_service", "get_users", "get_user",
 		"get_processes", "get_mounts", "get_uptime", "get_load",
-		"get_kernel", "query",
+		"get_kernel", "query", "get_custom", "define",
 	}
 
 	for _, fn := range functions {
@@ -601,3 +601,41 @@ func TestFactsModule_Integration_Placeholder(t *testing.T) {
 	
 	t.Skip("Integration tests require mock gRPC client setup")
 }
+
+func TestFactsModule_DefineAndGetCustom(t *testing.T) {
+	dir := t.TempDir()
+	agent.SetGlobalCustomFacts(agent.NewCustomFactsCollector(dir, 0, 0))
+	defer agent.SetGlobalCustomFacts(nil)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	module := NewFactsModule("localhost:50053")
+	module.Register(L)
+
+	err := L.DoString(`
+		local r, err = facts.define({ name = "role", value = { tier = "web", zones = { "a", "b" } } })
+		assert(r, err)
+		assert(r.changed, "expected the fact to be written")
+
+		r, err = facts.define({ name = "role", value = { tier = "web", zones = { "a", "b" } } })
+		assert(r, err)
+		assert(not r.changed, "expected no change")
+
+		r, err = facts.define({ name = "app", command = [[echo '{"version": "2.0"}']] })
+		assert(r, err)
+
+		local role = facts.get_custom({ name = "role" })
+		assert(role.tier == "web" and role.zones[2] == "b", "unexpected role fact")
+
+		local all = facts.get_custom()
+		assert(all.app.version == "2.0", "unexpected app fact")
+
+		local missing, err = facts.get_custom({ name = "missing" })
+		assert(missing == nil and string.find(err, "not found"))
+	`)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os/exec"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -13,7 +14,7 @@ func execAsTaskUserGit(L *lua.LState, args []string) *exec.Cmd {
 	if taskUser.Type() == lua.LTString && taskUser.String() != "" && taskUser.String() != "root" {
 		// Run as specific user using sudo
 		allArgs := append([]string{"-u", taskUser.String(), "git"}, args...)
-		return exec.Command("sudo", allArgs...)
+		return taskctx.Command(L.Context(), "sudo", allArgs...)
 	}
 	// Run as current user (root)
 	return exec.Command("git", args...)
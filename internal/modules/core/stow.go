@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -16,7 +17,7 @@ func execAsTaskUser(L *lua.LState, command string, args []string) *exec.Cmd {
 	if taskUser.Type() == lua.LTString && taskUser.String() != "" && taskUser.String() != "root" {
 		// Run as specific user using sudo
 		allArgs := append([]string{"-u", taskUser.String(), command}, args...)
-		return exec.Command("sudo", allArgs...)
+		return taskctx.Command(L.Context(), "sudo", allArgs...)
 	}
 	// Run as current user (root)
 	return exec.Command(command, args...)
@@ -0,0 +1,677 @@
+package infra
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+	"gopkg.in/yaml.v3"
+)
+
+// Config file formats handled by the config module
+const (
+	ConfigJSON = "json"
+	ConfigYAML = "yaml"
+	ConfigTOML = "toml"
+	ConfigINI  = "ini"
+)
+
+// ConfigEdit is one structural change to a config file. Path elements are
+// keys, or 1-based indexes into lists; an index one past the end appends.
+type ConfigEdit struct {
+	Path   []string
+	Value  interface{}
+	Delete bool
+}
+
+// ConfigChange is the outcome of editing a config file
+type ConfigChange struct {
+	Changed bool
+	Created bool
+	Diff    string
+	Content []byte
+}
+
+// ConfigModule edits JSON, YAML, TOML and INI files structurally, keeping
+// comments, key order and indentation where the format allows
+type ConfigModule struct {
+	L *lua.LState
+}
+
+// NewConfigModule creates a new Config module instance
+func NewConfigModule(L *lua.LState) *ConfigModule {
+	return &ConfigModule{L: L}
+}
+
+// Register registers the Config module with the Lua state
+func (m *ConfigModule) Register(L *lua.LState) {
+	configTable := L.NewTable()
+
+	for _, format := range []string{ConfigJSON, ConfigYAML, ConfigTOML, ConfigINI} {
+		format := format
+		L.SetField(configTable, "edit_"+format, L.NewFunction(func(L *lua.LState) int {
+			return m.edit(L, format)
+		}))
+	}
+
+	L.SetGlobal("config", configTable)
+}
+
+// edit loads a file, applies the changes and writes it back:
+//
+//	config.edit_json({
+//	    path = "/etc/app/config.json",
+//	    changes = {server = {port = 8080}},  -- deep merged
+//	    set = {["log.level"] = "debug"},     -- dotted paths, "\." escapes a dot
+//	    delete = {"server.legacy"},
+//	    create = true,                       -- create the file if missing
+//	    dry_run = false,
+//	})
+//
+// changes are merged first, then set and delete are applied. Returns a
+// table with changed, created and diff.
+func (m *ConfigModule) edit(L *lua.LState, format string) int {
+	opts := L.CheckTable(1)
+
+	path := opts.RawGetString("path").String()
+	if opts.RawGetString("path") == lua.LNil || path == "" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("path is required"))
+		return 2
+	}
+
+	edits, err := configEditsFromTable(opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	// In check mode the change is only planned, like with dry_run
+	dryRun := lua.LVAsBool(opts.RawGetString("dry_run"))
+	checkMode := taskctx.CheckMode(L.Context())
+	change, err := EditConfigFile(path, format, edits, lua.LVAsBool(opts.RawGetString("create")), dryRun || checkMode)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if checkMode && !dryRun && change.Changed {
+		before, _ := os.ReadFile(path)
+		taskctx.RecordChange(L.Context(), taskctx.FileChange("config", "edit_"+format, path, before, change.Content))
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(change.Changed))
+	result.RawSetString("created", lua.LBool(change.Created))
+	result.RawSetString("diff", lua.LString(change.Diff))
+	result.RawSetString("path", lua.LString(path))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// configEditsFromTable reads the changes, set and delete options
+func configEditsFromTable(opts *lua.LTable) ([]ConfigEdit, error) {
+	var edits []ConfigEdit
+
+	if changes := opts.RawGetString("changes"); changes != lua.LNil {
+		merged, ok := configValue(changes).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("changes must be a table of keys")
+		}
+		edits = append(edits, mergeEdits(nil, merged)...)
+	}
+
+	if set, ok := opts.RawGetString("set").(*lua.LTable); ok {
+		var sets []ConfigEdit
+		set.ForEach(func(k, v lua.LValue) {
+			sets = append(sets, ConfigEdit{Path: SplitConfigPath(k.String()), Value: configValue(v)})
+		})
+		sort.Slice(sets, func(i, j int) bool {
+			return strings.Join(sets[i].Path, ".") < strings.Join(sets[j].Path, ".")
+		})
+		edits = append(edits, sets...)
+	}
+
+	for _, path := range optStrings(opts, "delete") {
+		edits = append(edits, ConfigEdit{Path: SplitConfigPath(path), Delete: true})
+	}
+
+	for _, e := range edits {
+		for _, key := range e.Path {
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q", strings.Join(e.Path, "."))
+			}
+		}
+	}
+	return edits, nil
+}
+
+// mergeEdits turns a deep merge of changes into sets of its leaves: tables
+// are merged key by key, anything else replaces the current value
+func mergeEdits(prefix []string, changes map[string]interface{}) []ConfigEdit {
+	keys := make([]string, 0, len(changes))
+	for k := range changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var edits []ConfigEdit
+	for _, k := range keys {
+		path := append(append([]string{}, prefix...), k)
+		if nested, ok := changes[k].(map[string]interface{}); ok && len(nested) > 0 {
+			edits = append(edits, mergeEdits(path, nested)...)
+			continue
+		}
+		edits = append(edits, ConfigEdit{Path: path, Value: changes[k]})
+	}
+	return edits
+}
+
+// SplitConfigPath splits a dotted path; "\." is a literal dot in a key
+func SplitConfigPath(path string) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			current.WriteByte('.')
+			i++
+		case path[i] == '.':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	return append(parts, current.String())
+}
+
+// configValue converts a Lua value, keeping whole numbers integers so they
+// aren't written as floats
+func configValue(lv lua.LValue) interface{} {
+	return integralNumbers(luaValueToGo(lv))
+}
+
+func integralNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return int64(val)
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = integralNumbers(val[i])
+		}
+	case map[string]interface{}:
+		for k := range val {
+			val[k] = integralNumbers(val[k])
+		}
+	}
+	return v
+}
+
+// configEditor applies edits to a parsed config file
+type configEditor interface {
+	Set(path []string, value interface{}) error
+	Delete(path []string) error
+	Bytes() ([]byte, error)
+}
+
+func newConfigEditor(format string, content []byte) (configEditor, error) {
+	switch format {
+	case ConfigJSON, ConfigYAML:
+		return newNodeEditor(format, content)
+	case ConfigTOML:
+		return newTOMLEditor(content)
+	case ConfigINI:
+		return newSectionEditor(content, false), nil
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+}
+
+// EditConfig applies edits to the content of a config file and returns the
+// new content
+func EditConfig(format string, content []byte, edits []ConfigEdit) ([]byte, error) {
+	editor, err := newConfigEditor(format, content)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range edits {
+		if e.Delete {
+			err = editor.Delete(e.Path)
+		} else {
+			err = editor.Set(e.Path, e.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(e.Path, "."), err)
+		}
+	}
+	return editor.Bytes()
+}
+
+// EditConfigFile applies edits to a config file. A missing file is an
+// error unless create is set. With dryRun the file is left untouched.
+func EditConfigFile(path, format string, edits []ConfigEdit, create, dryRun bool) (*ConfigChange, error) {
+	change := &ConfigChange{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) || !create {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("%s does not exist (set create = true to create it)", path)
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		change.Created = true
+	}
+
+	updated, err := EditConfig(format, content, edits)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !change.Created && sameConfig(format, content, updated) {
+		change.Content = content
+		return change, nil
+	}
+
+	change.Changed = true
+	change.Content = updated
+	change.Diff = lineDiff(configLines(content), configLines(updated))
+	if dryRun {
+		return change, nil
+	}
+	if err := writeFileAtomic(path, updated); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// sameConfig reports whether two versions of a file hold the same data, so
+// rewriting a file in its own format isn't reported as a change
+func sameConfig(format string, a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var da, db interface{}
+	var errA, errB error
+	switch format {
+	case ConfigJSON, ConfigYAML:
+		errA, errB = yaml.Unmarshal(a, &da), yaml.Unmarshal(b, &db)
+	case ConfigTOML:
+		da, errA = decodeTOML(a)
+		db, errB = decodeTOML(b)
+	default:
+		return false
+	}
+	return errA == nil && errB == nil && reflect.DeepEqual(da, db)
+}
+
+func configLines(content []byte) []string {
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// nodeEditor edits JSON and YAML as a node tree, which keeps key order and
+// YAML comments
+type nodeEditor struct {
+	format  string
+	doc     *yaml.Node
+	indent  string
+	compact bool
+}
+
+func newNodeEditor(format string, content []byte) (*nodeEditor, error) {
+	e := &nodeEditor{format: format}
+
+	var err error
+	if format == ConfigJSON {
+		var root *yaml.Node
+		root, err = parseJSONNode(content)
+		e.doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+		e.indent, e.compact = jsonIndent(content)
+	} else {
+		e.doc, err = parseYAMLDocument(content)
+		e.indent = yamlIndent(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.doc == nil {
+		e.doc = &yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(e.doc.Content) == 0 || e.doc.Content[0] == nil || e.doc.Content[0].ShortTag() == "!!null" {
+		e.doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if kind := e.root().Kind; kind != yaml.MappingNode && kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("the document is not a mapping or list")
+	}
+	return e, nil
+}
+
+func (e *nodeEditor) root() *yaml.Node {
+	return e.doc.Content[0]
+}
+
+// Set implements configEditor. Missing parents are created as mappings,
+// and scalars in the way are replaced by them.
+func (e *nodeEditor) Set(path []string, value interface{}) error {
+	newNode := &yaml.Node{}
+	if err := newNode.Encode(value); err != nil {
+		return err
+	}
+
+	parent := e.root()
+	for i, key := range path {
+		last := i == len(path)-1
+		var child **yaml.Node
+		switch parent.Kind {
+		case yaml.MappingNode:
+			idx := mappingIndex(parent, key)
+			if idx < 0 {
+				parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, nil)
+				idx = len(parent.Content) - 2
+			}
+			child = &parent.Content[idx+1]
+		case yaml.SequenceNode:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 1 || n > len(parent.Content)+1 {
+				return fmt.Errorf("%q is not an index of a list of %d items", key, len(parent.Content))
+			}
+			if n == len(parent.Content)+1 {
+				parent.Content = append(parent.Content, nil)
+			}
+			child = &parent.Content[n-1]
+		default:
+			return fmt.Errorf("%q is not inside a mapping or list", key)
+		}
+
+		if last {
+			if *child == nil {
+				*child = newNode
+			} else {
+				replaceNode(*child, newNode)
+			}
+			return nil
+		}
+		if *child == nil || ((*child).Kind != yaml.MappingNode && (*child).Kind != yaml.SequenceNode) {
+			*child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		parent = *child
+	}
+	return nil
+}
+
+// Delete implements configEditor. Missing paths are left alone.
+func (e *nodeEditor) Delete(path []string) error {
+	parent := e.root()
+	for i, key := range path {
+		last := i == len(path)-1
+		switch parent.Kind {
+		case yaml.MappingNode:
+			idx := mappingIndex(parent, key)
+			if idx < 0 {
+				return nil
+			}
+			if last {
+				parent.Content = append(parent.Content[:idx], parent.Content[idx+2:]...)
+				return nil
+			}
+			parent = parent.Content[idx+1]
+		case yaml.SequenceNode:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 1 || n > len(parent.Content) {
+				return nil
+			}
+			if last {
+				parent.Content = append(parent.Content[:n-1], parent.Content[n:]...)
+				return nil
+			}
+			parent = parent.Content[n-1]
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// Bytes implements configEditor
+func (e *nodeEditor) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if e.format == ConfigJSON {
+		if err := writeJSONNode(&buf, e.root(), e.indent, "", e.compact); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	}
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(len(e.indent))
+	if err := enc.Encode(e.doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// replaceNode puts new in the place of old, keeping old's comments and,
+// for values of the same type, its quoting or flow style
+func replaceNode(old, new *yaml.Node) {
+	head, line, foot := old.HeadComment, old.LineComment, old.FootComment
+	style := old.Style
+	sameType := old.Kind == new.Kind && old.ShortTag() == new.ShortTag()
+
+	*old = *new
+	old.HeadComment, old.LineComment, old.FootComment = head, line, foot
+	if sameType {
+		old.Style = style
+	}
+}
+
+func parseYAMLDocument(content []byte) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	var next yaml.Node
+	if err := dec.Decode(&next); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("files with several YAML documents are not supported")
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return &doc, nil
+}
+
+// yamlIndent returns the indentation of the first nested line, or two
+// spaces
+func yamlIndent(content []byte) string {
+	for _, line := range configLines(content) {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n >= 2 {
+			return strings.Repeat(" ", n)
+		}
+	}
+	return "  "
+}
+
+// parseJSONNode reads JSON into a node tree, which unlike a map keeps the
+// order of keys
+func parseJSONNode(content []byte) (*yaml.Node, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	root, err := readJSONNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after the top-level value")
+	}
+	if root.Kind != yaml.MappingNode && root.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("the document is not an object or array")
+	}
+	return root, nil
+}
+
+func readJSONNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if t == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		for dec.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			child, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// jsonIndent returns the indentation of the first indented line, and
+// whether the file is written on a single line
+func jsonIndent(content []byte) (string, bool) {
+	lines := configLines(bytes.TrimSpace(content))
+	if len(lines) == 1 {
+		return "", true
+	}
+	for _, line := range lines[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)], false
+		}
+	}
+	return "  ", false
+}
+
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node, indent, prefix string, compact bool) error {
+	newline := func(p string) {
+		if !compact {
+			buf.WriteString("\n" + p)
+		}
+	}
+	colon := ": "
+	if compact {
+		colon = ":"
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "[", "]", 1
+		if node.Kind == yaml.MappingNode {
+			open, close, step = "{", "}", 2
+		}
+		buf.WriteString(open)
+		for i := 0; i < len(node.Content); i += step {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(prefix + indent)
+			value := node.Content[i]
+			if step == 2 {
+				writeJSONString(buf, node.Content[i].Value)
+				buf.WriteString(colon)
+				value = node.Content[i+1]
+			}
+			if err := writeJSONNode(buf, value, indent, prefix+indent, compact); err != nil {
+				return err
+			}
+		}
+		if len(node.Content) > 0 {
+			newline(prefix)
+		}
+		buf.WriteString(close)
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!str":
+			writeJSONString(buf, node.Value)
+		case "!!int", "!!bool":
+			buf.WriteString(node.Value)
+		case "!!float":
+			f, err := strconv.ParseFloat(node.Value, 64)
+			if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+				return fmt.Errorf("%s can't be written as JSON", node.Value)
+			}
+			buf.WriteString(node.Value)
+		default:
+			buf.WriteString("null")
+		}
+	case yaml.AliasNode:
+		return writeJSONNode(buf, node.Alias, indent, prefix, compact)
+	default:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Write(bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+}
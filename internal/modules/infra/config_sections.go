@@ -0,0 +1,494 @@
+package infra
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// sectionEditor edits TOML and INI files line by line, so everything it
+// doesn't touch, comments included, is left as it was. Keys are addressed
+// by their section and key names split on dots for TOML, and by the
+// section name followed by the key for INI.
+type sectionEditor struct {
+	lines []string
+	toml  bool
+	sep   string
+}
+
+// sectionLine describes a header or key line of a file
+type sectionLine struct {
+	header  bool
+	array   bool     // [[array of tables]] header
+	section []string // the header's name, or the section a key is in
+	key     []string
+	prefix  string // the key line up to its value
+	value   string // the value and any trailing comment
+}
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func newSectionEditor(content []byte, isTOML bool) *sectionEditor {
+	e := &sectionEditor{lines: configLines(content), toml: isTOML, sep: " = "}
+	if !isTOML {
+		for _, line := range e.lines {
+			if info, ok := e.parse(line, nil); ok && !info.header {
+				e.sep = iniSeparator(info.prefix)
+				break
+			}
+		}
+	}
+	return e
+}
+
+// iniSeparator returns the separator of a key line prefix with the key
+// removed, such as "=" or " = "
+func iniSeparator(prefix string) string {
+	i := strings.IndexAny(prefix, "=:")
+	start := strings.LastIndexFunc(prefix[:i], func(r rune) bool { return r != ' ' && r != '\t' }) + 1
+	return prefix[start:]
+}
+
+// parse classifies a line; section is the section the line is in
+func (e *sectionEditor) parse(line string, section []string) (sectionLine, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || trimmed[0] == '#' || (!e.toml && trimmed[0] == ';') {
+		return sectionLine{}, false
+	}
+
+	if trimmed[0] == '[' {
+		array := strings.HasPrefix(trimmed, "[[")
+		name := strings.TrimLeft(trimmed, "[")
+		end := strings.Index(name, "]")
+		if end < 0 {
+			return sectionLine{}, false
+		}
+		name = name[:end]
+		if !e.toml {
+			return sectionLine{header: true, section: []string{strings.TrimSpace(name)}}, true
+		}
+		return sectionLine{header: true, array: array, section: splitTOMLKey(name)}, true
+	}
+
+	seps := "="
+	if !e.toml {
+		seps = "=:"
+	}
+	i := indexOutsideQuotes(line, seps)
+	if i < 0 {
+		return sectionLine{}, false
+	}
+	valueAt := i + 1
+	for valueAt < len(line) && (line[valueAt] == ' ' || line[valueAt] == '\t') {
+		valueAt++
+	}
+	info := sectionLine{section: section, prefix: line[:valueAt], value: line[valueAt:]}
+	if e.toml {
+		info.key = splitTOMLKey(line[:i])
+	} else {
+		info.key = []string{strings.TrimSpace(line[:i])}
+	}
+	return info, true
+}
+
+// scan parses every line, returning nil entries for blank, comment and
+// continuation lines
+func (e *sectionEditor) scan() []*sectionLine {
+	infos := make([]*sectionLine, len(e.lines))
+	var section []string
+	for i, line := range e.lines {
+		info, ok := e.parse(line, section)
+		if !ok {
+			continue
+		}
+		if info.header {
+			section = info.section
+			if info.array {
+				// Keys of arrays of tables can't be addressed by name
+				section = append([]string{"[]"}, section...)
+			}
+		}
+		infos[i] = &info
+	}
+	return infos
+}
+
+// split turns a path into the section and key it addresses
+func (e *sectionEditor) split(path []string) ([]string, []string) {
+	if len(path) == 1 {
+		return nil, path
+	}
+	if !e.toml {
+		return []string{strings.Join(path[:len(path)-1], ".")}, path[len(path)-1:]
+	}
+	return path[:len(path)-1], path[len(path)-1:]
+}
+
+// Set implements configEditor
+func (e *sectionEditor) Set(path []string, value interface{}) error {
+	if fields, ok := value.(map[string]interface{}); ok {
+		if !e.toml {
+			return fmt.Errorf("INI values must be strings, numbers or booleans")
+		}
+		if err := e.Delete(path); err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			return fmt.Errorf("empty tables aren't supported")
+		}
+		for _, edit := range mergeEdits(path, fields) {
+			if err := e.Set(edit.Path, edit.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	formatted, err := e.format(value)
+	if err != nil {
+		return err
+	}
+	section, key := e.split(path)
+	if !e.toml {
+		path = append(append([]string{}, section...), key...)
+	}
+
+	infos := e.scan()
+	for i, info := range infos {
+		if info != nil && !info.header && reflect.DeepEqual(append(append([]string{}, info.section...), info.key...), path) {
+			e.lines[i] = info.prefix + formatted + e.trailingComment(info.value)
+			return nil
+		}
+	}
+
+	line := e.formatKey(key) + e.sep + formatted
+	headerAt, insertAt := -1, -1
+	for i, info := range infos {
+		if info == nil {
+			// Continuation lines of multi-line values belong to the key above
+			trimmed := strings.TrimSpace(e.lines[i])
+			if trimmed != "" && trimmed[0] != '#' && trimmed[0] != ';' && (headerAt >= 0 || len(section) == 0) {
+				insertAt = i + 1
+			}
+			continue
+		}
+		if info.header {
+			if headerAt >= 0 || len(section) == 0 {
+				break
+			}
+			if !info.array && reflect.DeepEqual(info.section, section) {
+				headerAt, insertAt = i, i+1
+			}
+			continue
+		}
+		if headerAt >= 0 || len(section) == 0 {
+			insertAt = i + 1
+		}
+	}
+
+	switch {
+	case insertAt >= 0:
+		e.insert(insertAt, line)
+	case len(section) == 0:
+		if len(e.lines) > 0 {
+			e.insert(0, line, "")
+		} else {
+			e.insert(0, line)
+		}
+	default:
+		if n := len(e.lines); n > 0 && strings.TrimSpace(e.lines[n-1]) != "" {
+			e.lines = append(e.lines, "")
+		}
+		e.lines = append(e.lines, "["+e.formatSection(section)+"]", line)
+	}
+	return nil
+}
+
+// Delete implements configEditor. Deleting a section removes it and, for
+// TOML, its subtables.
+func (e *sectionEditor) Delete(path []string) error {
+	section := path
+	if !e.toml {
+		section = []string{strings.Join(path, ".")}
+	}
+	infos := e.scan()
+	for i := 0; i < len(e.lines); i++ {
+		info := infos[i]
+		if info == nil || !info.header || info.array || !reflect.DeepEqual(info.section, section) {
+			continue
+		}
+		end := i + 1
+		for ; end < len(e.lines); end++ {
+			next := infos[end]
+			if next != nil && next.header && !(e.toml && hasPathPrefix(next.section, section)) {
+				break
+			}
+		}
+		e.lines = append(e.lines[:i], e.lines[end:]...)
+		return nil
+	}
+
+	s, key := e.split(path)
+	path = append(append([]string{}, s...), key...)
+	for i, info := range infos {
+		if info != nil && !info.header && reflect.DeepEqual(append(append([]string{}, info.section...), info.key...), path) {
+			e.lines = append(e.lines[:i], e.lines[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Bytes implements configEditor
+func (e *sectionEditor) Bytes() ([]byte, error) {
+	if len(e.lines) == 0 {
+		return nil, nil
+	}
+	return []byte(strings.Join(e.lines, "\n") + "\n"), nil
+}
+
+func (e *sectionEditor) insert(at int, lines ...string) {
+	e.lines = append(e.lines[:at], append(lines, e.lines[at:]...)...)
+}
+
+func (e *sectionEditor) format(value interface{}) (string, error) {
+	if !e.toml {
+		switch v := value.(type) {
+		case string, bool, int64, float64:
+			return fmt.Sprint(v), nil
+		default:
+			return "", fmt.Errorf("INI values must be strings, numbers or booleans")
+		}
+	}
+	if value == nil {
+		return "", fmt.Errorf("TOML has no null value")
+	}
+	out, err := toml.Marshal(map[string]interface{}{"v": value})
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(line, "v = ") || strings.Contains(line, "\n") {
+		return "", fmt.Errorf("value can't be written inline")
+	}
+	return strings.TrimPrefix(line, "v = "), nil
+}
+
+func (e *sectionEditor) formatKey(key []string) string {
+	if !e.toml {
+		return key[0]
+	}
+	return e.formatSection(key)
+}
+
+func (e *sectionEditor) formatSection(section []string) string {
+	if !e.toml {
+		return section[0]
+	}
+	parts := make([]string, len(section))
+	for i, part := range section {
+		if bareTOMLKey.MatchString(part) {
+			parts[i] = part
+		} else {
+			parts[i] = strconv.Quote(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// trailingComment returns the comment after a TOML value, with the space
+// before it; INI has no trailing comments
+func (e *sectionEditor) trailingComment(value string) string {
+	if !e.toml {
+		return ""
+	}
+	for i := strings.IndexByte(value, '#'); i >= 0; {
+		var v map[string]interface{}
+		if toml.Unmarshal([]byte("v = "+value[:i]), &v) == nil {
+			return value[len(strings.TrimRight(value[:i], " \t")):]
+		}
+		next := strings.IndexByte(value[i+1:], '#')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return ""
+}
+
+// splitTOMLKey splits a dotted TOML key, unquoting its parts
+func splitTOMLKey(key string) []string {
+	var parts []string
+	for {
+		i := indexOutsideQuotes(key, ".")
+		part := key
+		if i >= 0 {
+			part = key[:i]
+		}
+		part = strings.TrimSpace(part)
+		if unquoted, err := strconv.Unquote(part); err == nil && strings.HasPrefix(part, `"`) {
+			part = unquoted
+		} else if len(part) >= 2 && part[0] == '\'' && part[len(part)-1] == '\'' {
+			part = part[1 : len(part)-1]
+		}
+		parts = append(parts, part)
+		if i < 0 {
+			return parts
+		}
+		key = key[i+1:]
+	}
+}
+
+// indexOutsideQuotes returns the index of the first of chars that isn't in
+// a quoted string
+func indexOutsideQuotes(s, chars string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case strings.IndexByte(chars, s[i]) >= 0:
+			return i
+		}
+	}
+	return -1
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	return len(path) >= len(prefix) && reflect.DeepEqual(path[:len(prefix)], prefix)
+}
+
+// tomlEditor edits TOML line by line and checks the result against the
+// same edits made to the decoded document. Edits the lines can't express,
+// such as keys of arrays of tables or multi-line values, fall back to
+// encoding the document, which loses comments.
+type tomlEditor struct {
+	lines   *sectionEditor
+	data    map[string]interface{}
+	lineErr error
+}
+
+func newTOMLEditor(content []byte) (*tomlEditor, error) {
+	data, err := decodeTOML(content)
+	if err != nil {
+		return nil, err
+	}
+	return &tomlEditor{lines: newSectionEditor(content, true), data: data}, nil
+}
+
+func decodeTOML(content []byte) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if err := toml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+	return data, nil
+}
+
+// Set implements configEditor
+func (e *tomlEditor) Set(path []string, value interface{}) error {
+	updated, err := setConfigPath(e.data, path, value)
+	if err != nil {
+		return err
+	}
+	e.data = updated.(map[string]interface{})
+	if e.lineErr == nil {
+		e.lineErr = e.lines.Set(path, value)
+	}
+	return nil
+}
+
+// Delete implements configEditor
+func (e *tomlEditor) Delete(path []string) error {
+	deleteConfigPath(e.data, path)
+	if e.lineErr == nil {
+		e.lineErr = e.lines.Delete(path)
+	}
+	return nil
+}
+
+// Bytes implements configEditor
+func (e *tomlEditor) Bytes() ([]byte, error) {
+	encoded, err := toml.Marshal(e.data)
+	if err != nil {
+		return nil, err
+	}
+	if e.lineErr != nil {
+		return encoded, nil
+	}
+
+	edited, _ := e.lines.Bytes()
+	got, err := decodeTOML(edited)
+	if err != nil {
+		return encoded, nil
+	}
+	want, err := decodeTOML(encoded)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		return encoded, nil
+	}
+	return edited, nil
+}
+
+// setConfigPath sets the value at path in decoded data, creating tables
+// on the way, and returns the updated data
+func setConfigPath(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, err := setConfigPath(n[path[0]], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = child
+		return n, nil
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 1 || i > len(n)+1 {
+			return nil, fmt.Errorf("%q is not an index of a list of %d items", path[0], len(n))
+		}
+		if i == len(n)+1 {
+			n = append(n, nil)
+		}
+		child, err := setConfigPath(n[i-1], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[i-1] = child
+		return n, nil
+	default:
+		return setConfigPath(map[string]interface{}{}, path, value)
+	}
+}
+
+// deleteConfigPath removes the value at path from decoded data
+func deleteConfigPath(node interface{}, path []string) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(n, path[0])
+		} else if child, ok := n[path[0]]; ok {
+			n[path[0]] = deleteConfigPath(child, path[1:])
+		}
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 1 || i > len(n) {
+			return n
+		}
+		if len(path) == 1 {
+			return append(n[:i-1], n[i:]...)
+		}
+		n[i-1] = deleteConfigPath(n[i-1], path[1:])
+	}
+	return node
+}
@@ -0,0 +1,190 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestEditConfig_JSON(t *testing.T) {
+	content := "{\n    \"name\": \"app\",\n    \"server\": {\n        \"port\": 80,\n        \"hosts\": [\"a\", \"b\"]\n    },\n    \"legacy\": true\n}\n"
+	got, err := EditConfig(ConfigJSON, []byte(content), []ConfigEdit{
+		{Path: []string{"server", "port"}, Value: int64(8080)},
+		{Path: []string{"server", "hosts", "3"}, Value: "c"},
+		{Path: []string{"log", "level"}, Value: "debug"},
+		{Path: []string{"legacy"}, Delete: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n    \"name\": \"app\",\n    \"server\": {\n        \"port\": 8080,\n        \"hosts\": [\n            \"a\",\n            \"b\",\n            \"c\"\n        ]\n    },\n    \"log\": {\n        \"level\": \"debug\"\n    }\n}\n"
+	if string(got) != want {
+		t.Errorf("Unexpected JSON:\n%s", got)
+	}
+
+	compact, err := EditConfig(ConfigJSON, []byte(`{"a":1,"b":{"c":"x"}}`), []ConfigEdit{{Path: []string{"b", "c"}, Value: "y<z"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(compact) != "{\"a\":1,\"b\":{\"c\":\"y<z\"}}\n" {
+		t.Errorf("Unexpected compact JSON: %s", compact)
+	}
+}
+
+func TestEditConfig_YAMLKeepsComments(t *testing.T) {
+	content := "# app settings\nname: app\nserver:\n  port: 80 # public port\n  host: \"0.0.0.0\"\n"
+	got, err := EditConfig(ConfigYAML, []byte(content), []ConfigEdit{
+		{Path: []string{"server", "port"}, Value: int64(8080)},
+		{Path: []string{"server", "host"}, Value: "127.0.0.1"},
+		{Path: []string{"features"}, Value: []interface{}{"a"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# app settings\nname: app\nserver:\n  port: 8080 # public port\n  host: \"127.0.0.1\"\nfeatures:\n  - a\n"
+	if string(got) != want {
+		t.Errorf("Unexpected YAML:\n%s", got)
+	}
+
+	if _, err := EditConfig(ConfigYAML, []byte("a: 1\n---\nb: 2\n"), nil); err == nil {
+		t.Error("Expected several documents to be rejected")
+	}
+}
+
+func TestEditConfig_TOML(t *testing.T) {
+	content := `# global
+title = "app"
+
+[server]
+port = 80 # public port
+"tls.cert" = "/etc/a.pem"
+
+[server.limits]
+rps = 10
+
+[database]
+url = "postgres://db"
+`
+	got, err := EditConfig(ConfigTOML, []byte(content), []ConfigEdit{
+		{Path: []string{"server", "port"}, Value: int64(8080)},
+		{Path: []string{"server", "tls.cert"}, Value: "/etc/b.pem"},
+		{Path: []string{"server", "workers"}, Value: int64(4)},
+		{Path: []string{"debug"}, Value: true},
+		{Path: []string{"log", "level"}, Value: "info"},
+		{Path: []string{"server", "limits"}, Delete: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# global
+title = "app"
+debug = true
+
+[server]
+port = 8080 # public port
+"tls.cert" = '/etc/b.pem'
+workers = 4
+
+[database]
+url = "postgres://db"
+
+[log]
+level = 'info'
+`
+	if string(got) != want {
+		t.Errorf("Unexpected TOML:\n%s", got)
+	}
+}
+
+func TestEditConfig_TOMLFallsBackToEncoding(t *testing.T) {
+	content := "[[servers]]\nname = \"a\"\n"
+	got, err := EditConfig(ConfigTOML, []byte(content), []ConfigEdit{{Path: []string{"servers", "1", "name"}, Value: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := decodeTOML(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := data["servers"].([]interface{})[0].(map[string]interface{})["name"]; name != "b" {
+		t.Errorf("Unexpected TOML:\n%s", got)
+	}
+}
+
+func TestEditConfig_INI(t *testing.T) {
+	content := "; main config\nuser=app\n\n[server]\nport=80\n\n[log]\nlevel=warn\n"
+	got, err := EditConfig(ConfigINI, []byte(content), []ConfigEdit{
+		{Path: []string{"server", "port"}, Value: int64(8080)},
+		{Path: []string{"server", "host"}, Value: "0.0.0.0"},
+		{Path: []string{"cache", "size"}, Value: "64m"},
+		{Path: []string{"log"}, Delete: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "; main config\nuser=app\n\n[server]\nport=8080\nhost=0.0.0.0\n\n[cache]\nsize=64m\n"
+	if string(got) != want {
+		t.Errorf("Unexpected INI:\n%s", got)
+	}
+
+	if _, err := EditConfig(ConfigINI, nil, []ConfigEdit{{Path: []string{"a"}, Value: []interface{}{"x"}}}); err == nil {
+		t.Error("Expected a list value to be rejected")
+	}
+}
+
+func TestConfigModule_Edit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.json")
+	if err := os.WriteFile(path, []byte("{\n  \"server\": {\n    \"port\": 80\n  }\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	NewConfigModule(L).Register(L)
+	L.SetGlobal("path", lua.LString(path))
+	L.SetGlobal("missing", lua.LString(filepath.Join(dir, "new.yaml")))
+
+	err := L.DoString(`
+		local r, err = config.edit_json({path = path, changes = {server = {port = 8080, tls = true}}, dry_run = true})
+		assert(r, err)
+		assert(r.changed, "dry run should report a change")
+
+		r, err = config.edit_json({path = path, changes = {server = {port = 8080, tls = true}}, set = {["name"] = "app"}})
+		assert(r, err)
+		assert(r.changed, "first edit should change")
+		assert(r.diff:find('-    "port": 80', 1, true), r.diff)
+		assert(r.diff:find('+    "port": 8080,', 1, true), r.diff)
+
+		r, err = config.edit_json({path = path, changes = {server = {port = 8080, tls = true}}})
+		assert(r, err)
+		assert(not r.changed, "second edit should be a no-op")
+
+		r, err = config.edit_yaml({path = missing, changes = {a = 1}})
+		assert(not r and err:find("create = true", 1, true), "missing file should need create")
+
+		r, err = config.edit_yaml({path = missing, changes = {a = 1}, create = true})
+		assert(r and r.created and r.changed, err)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(path)
+	want := "{\n  \"server\": {\n    \"port\": 8080,\n    \"tls\": true\n  },\n  \"name\": \"app\"\n}\n"
+	if string(content) != want {
+		t.Errorf("Unexpected file:\n%s", content)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestSplitConfigPath(t *testing.T) {
+	parts := SplitConfigPath(`server.tls\.cert.1`)
+	if len(parts) != 3 || parts[1] != "tls.cert" || parts[2] != "1" {
+		t.Errorf("Unexpected parts: %q", parts)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -37,7 +38,7 @@ func (m *CronModule) Register(L *lua.LState) {
 }
 
 func (m *CronModule) execCommand(args ...string) (string, error) {
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(m.L.Context(), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	return strings.TrimSpace(string(output)), err
 }
@@ -106,10 +107,10 @@ func (m *CronModule) getCrontab(username string) (string, error) {
 	var cmd *exec.Cmd
 	if username == "root" || username == "" {
 		args := m.prependSudo([]string{"crontab", "-l"})
-		cmd = exec.Command(args[0], args[1:]...)
+		cmd = taskctx.Command(m.L.Context(), args[0], args[1:]...)
 	} else {
 		args := m.prependSudo([]string{"crontab", "-u", username, "-l"})
-		cmd = exec.Command(args[0], args[1:]...)
+		cmd = taskctx.Command(m.L.Context(), args[0], args[1:]...)
 	}
 
 	output, err := cmd.CombinedOutput()
@@ -146,7 +147,7 @@ func (m *CronModule) setCrontab(username, content string) error {
 		args = m.prependSudo([]string{"crontab", "-u", username, tmpFile.Name()})
 	}
 
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(m.L.Context(), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to install crontab: %s\n%s", err, string(output))
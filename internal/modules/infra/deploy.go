@@ -0,0 +1,849 @@
+package infra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	deployReleasesDir = "releases"
+	deployCurrentLink = "current"
+	deploySharedDir   = "shared"
+	deployDefaultKeep = 5
+)
+
+// DeployModule implements the releases/current symlink deployment pattern:
+//
+//	<base_dir>/releases/<timestamp>/  unpacked artifacts
+//	<base_dir>/shared/                files kept across releases
+//	<base_dir>/current -> releases/<timestamp>
+type DeployModule struct {
+	L *lua.LState
+}
+
+// NewDeployModule creates a new Deploy module instance
+func NewDeployModule(L *lua.LState) *DeployModule {
+	return &DeployModule{L: L}
+}
+
+// Register registers the Deploy module with the Lua state
+func (m *DeployModule) Register(L *lua.LState) {
+	deployTable := L.NewTable()
+
+	L.SetField(deployTable, "release", L.NewFunction(m.release))
+	L.SetField(deployTable, "rollback", L.NewFunction(m.rollback))
+	L.SetField(deployTable, "list", L.NewFunction(m.list))
+	L.SetField(deployTable, "current", L.NewFunction(m.current))
+	L.SetField(deployTable, "cleanup", L.NewFunction(m.cleanup))
+
+	L.SetGlobal("deploy", deployTable)
+}
+
+// ReleaseManager manages the releases of a single application directory
+type ReleaseManager struct {
+	BaseDir string
+}
+
+// ReleasesDir returns the directory holding all releases
+func (r *ReleaseManager) ReleasesDir() string {
+	return filepath.Join(r.BaseDir, deployReleasesDir)
+}
+
+// CurrentLink returns the path of the current symlink
+func (r *ReleaseManager) CurrentLink() string {
+	return filepath.Join(r.BaseDir, deployCurrentLink)
+}
+
+// Releases returns release names sorted oldest first
+func (r *ReleaseManager) Releases() ([]string, error) {
+	entries, err := os.ReadDir(r.ReleasesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var releases []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			releases = append(releases, e.Name())
+		}
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releaseLess(releases[i], releases[j])
+	})
+	return releases, nil
+}
+
+// releaseLess orders release names by their timestamp, then by the -N
+// suffix NewReleaseDir adds to releases made within the same second,
+// compared as a number so -10 comes after -9
+func releaseLess(a, b string) bool {
+	baseA, nA := releaseSuffix(a)
+	baseB, nB := releaseSuffix(b)
+	if baseA != baseB {
+		return baseA < baseB
+	}
+	return nA < nB
+}
+
+// releaseSuffix splits a release name into its base and -N suffix, 0 when
+// it has none
+func releaseSuffix(name string) (string, int) {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return name, 0
+	}
+	n, err := strconv.Atoi(name[i+1:])
+	if err != nil || n < 0 {
+		return name, 0
+	}
+	return name[:i], n
+}
+
+// Current returns the name of the active release, or "" if none
+func (r *ReleaseManager) Current() (string, error) {
+	target, err := os.Readlink(r.CurrentLink())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return filepath.Base(target), nil
+}
+
+// NewReleaseDir creates an empty, uniquely named release directory
+func (r *ReleaseManager) NewReleaseDir() (string, error) {
+	if err := os.MkdirAll(r.ReleasesDir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create releases directory: %w", err)
+	}
+
+	base := time.Now().UTC().Format("20060102150405")
+	name := base
+	for i := 1; ; i++ {
+		err := os.Mkdir(filepath.Join(r.ReleasesDir(), name), 0755)
+		if err == nil {
+			return name, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create release directory: %w", err)
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// Switch atomically points current at release by renaming a temporary symlink over it
+func (r *ReleaseManager) Switch(release string) error {
+	if _, err := os.Stat(filepath.Join(r.ReleasesDir(), release)); err != nil {
+		return fmt.Errorf("release %s not found: %w", release, err)
+	}
+
+	tmpLink := fmt.Sprintf("%s.tmp-%d", r.CurrentLink(), time.Now().UnixNano())
+	if err := os.Symlink(filepath.Join(deployReleasesDir, release), tmpLink); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+	if err := os.Rename(tmpLink, r.CurrentLink()); err != nil {
+		os.Remove(tmpLink)
+		return fmt.Errorf("failed to switch current release: %w", err)
+	}
+	return nil
+}
+
+// Previous returns the release deployed before the current one
+func (r *ReleaseManager) Previous() (string, error) {
+	releases, err := r.Releases()
+	if err != nil {
+		return "", err
+	}
+	current, err := r.Current()
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(releases) - 1; i >= 0; i-- {
+		if releases[i] == current {
+			if i == 0 {
+				return "", fmt.Errorf("no release before %s", current)
+			}
+			return releases[i-1], nil
+		}
+	}
+	if len(releases) == 0 {
+		return "", fmt.Errorf("no releases found in %s", r.ReleasesDir())
+	}
+	return releases[len(releases)-1], nil
+}
+
+// Cleanup removes the oldest releases beyond keep, never removing the current one
+func (r *ReleaseManager) Cleanup(keep int) ([]string, error) {
+	if keep < 1 {
+		keep = 1
+	}
+	releases, err := r.Releases()
+	if err != nil {
+		return nil, err
+	}
+	current, _ := r.Current()
+
+	var removed []string
+	excess := len(releases) - keep
+	for _, release := range releases {
+		if excess <= 0 {
+			break
+		}
+		if release == current {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(r.ReleasesDir(), release)); err != nil {
+			return removed, fmt.Errorf("failed to remove release %s: %w", release, err)
+		}
+		removed = append(removed, release)
+		excess--
+	}
+	return removed, nil
+}
+
+// LinkShared replaces each shared path inside release with a symlink into shared/
+func (r *ReleaseManager) LinkShared(release string, paths []string) error {
+	releaseDir := filepath.Join(r.ReleasesDir(), release)
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if filepath.IsAbs(p) || strings.HasPrefix(p, "..") {
+			return fmt.Errorf("shared path must be relative to the release: %s", p)
+		}
+
+		sharedPath := filepath.Join(r.BaseDir, deploySharedDir, p)
+		if _, err := os.Stat(sharedPath); os.IsNotExist(err) {
+			// Treat paths with an extension as files, everything else as directories
+			if filepath.Ext(p) != "" {
+				if err := os.MkdirAll(filepath.Dir(sharedPath), 0755); err != nil {
+					return err
+				}
+				if f, err := os.OpenFile(sharedPath, os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+					f.Close()
+				} else {
+					return err
+				}
+			} else if err := os.MkdirAll(sharedPath, 0755); err != nil {
+				return err
+			}
+		}
+
+		target := filepath.Join(releaseDir, p)
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", p, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(sharedPath, target); err != nil {
+			return fmt.Errorf("failed to link shared path %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// UnpackArtifact extracts a .tar, .tar.gz/.tgz or .zip archive into dest,
+// dropping the first strip path components. Any other file is copied as is.
+func UnpackArtifact(artifact, dest string, strip int) error {
+	switch {
+	case strings.HasSuffix(artifact, ".tar.gz"), strings.HasSuffix(artifact, ".tgz"):
+		f, err := os.Open(artifact)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to read gzip: %w", err)
+		}
+		defer gz.Close()
+		return untar(gz, dest, strip)
+	case strings.HasSuffix(artifact, ".tar"):
+		f, err := os.Open(artifact)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return untar(f, dest, strip)
+	case strings.HasSuffix(artifact, ".zip"):
+		return unzip(artifact, dest, strip)
+	default:
+		return copyArtifactFile(artifact, filepath.Join(dest, filepath.Base(artifact)), 0755)
+	}
+}
+
+// safeJoin strips path components and rejects entries escaping dest
+func safeJoin(dest, name string, strip int) (string, bool, error) {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(name)), "/")
+	if len(parts) <= strip {
+		return "", false, nil
+	}
+	rel := filepath.Join(parts[strip:]...)
+	if rel == "." || rel == "" {
+		return "", false, nil
+	}
+	target := filepath.Join(dest, rel)
+	if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", false, fmt.Errorf("archive entry escapes destination: %s", name)
+	}
+	return target, true, nil
+}
+
+// checkNoSymlinks refuses to write target when it or any directory
+// between dest and it is a symlink, so an archive can't write through a
+// link it created earlier
+func checkNoSymlinks(dest, target string) error {
+	rel, err := filepath.Rel(dest, target)
+	if err != nil {
+		return err
+	}
+	path := dest
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("archive entry writes through a symlink: %s", path)
+		}
+	}
+	return nil
+}
+
+// checkLinkTarget rejects symlinks pointing outside dest: absolute ones,
+// and relative ones escaping it from the link's directory
+func checkLinkTarget(dest, target, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("archive symlink points to an absolute path: %s -> %s", target, linkname)
+	}
+	resolved := filepath.Join(filepath.Dir(target), linkname)
+	if resolved != filepath.Clean(dest) && !strings.HasPrefix(resolved, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return fmt.Errorf("archive symlink escapes destination: %s -> %s", target, linkname)
+	}
+	return nil
+}
+
+func untar(r io.Reader, dest string, strip int) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		target, ok, err := safeJoin(dest, hdr.Name, strip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := checkNoSymlinks(dest, target); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArtifactFile(target, tr, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkLinkTarget(dest, target, hdr.Linkname); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := linkArtifactFile(dest, target, hdr.Linkname, strip); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// linkArtifactFile creates target as a hard link to the file an archive
+// names by linkname, which must be a regular file already extracted into
+// dest
+func linkArtifactFile(dest, target, linkname string, strip int) error {
+	source, ok, err := safeJoin(dest, linkname, strip)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("archive hard link points outside the extracted files: %s -> %s", target, linkname)
+	}
+	if err := checkNoSymlinks(dest, source); err != nil {
+		return err
+	}
+	info, err := os.Lstat(source)
+	if err != nil {
+		return fmt.Errorf("archive hard link target not found: %s -> %s", target, linkname)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("archive hard link target is not a regular file: %s -> %s", target, linkname)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Link(source, target)
+}
+
+func unzip(artifact, dest string, strip int) error {
+	zr, err := zip.OpenReader(artifact)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, ok, err := safeJoin(dest, f.Name, strip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := checkNoSymlinks(dest, target); err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			if err := unzipSymlink(f, dest, target); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArtifactFile(target, rc, f.Mode())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unzipSymlink creates the symlink a zip entry holds, whose content is the
+// link's target
+func unzipSymlink(f *zip.File, dest, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	linkname, err := io.ReadAll(io.LimitReader(rc, 4096))
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	if err := checkLinkTarget(dest, target, string(linkname)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(string(linkname), target)
+}
+
+func writeArtifactFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, r)
+	return err
+}
+
+func copyArtifactFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeArtifactFile(dst, in, mode)
+}
+
+// resolveArtifact returns a local path for artifact, downloading URLs
+// through the agent artifact cache when available
+func resolveArtifact(artifact string) (string, func(), error) {
+	noop := func() {}
+	if !strings.HasPrefix(artifact, "http://") && !strings.HasPrefix(artifact, "https://") {
+		if _, err := os.Stat(artifact); err != nil {
+			return "", noop, fmt.Errorf("artifact not found: %w", err)
+		}
+		return artifact, noop, nil
+	}
+
+	// Keep the URL's file name so the archive type can be detected
+	name := filepath.Base(strings.SplitN(artifact, "?", 2)[0])
+	tmpDir, err := os.MkdirTemp("", "sloth-deploy-")
+	if err != nil {
+		return "", noop, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+	dest := filepath.Join(tmpDir, name)
+
+	if cache := agent.GetGlobalArtifactCache(); cache != nil {
+		if _, err := cache.CopyTo(context.Background(), artifact, dest, 0644); err != nil {
+			cleanup()
+			return "", noop, err
+		}
+		return dest, cleanup, nil
+	}
+
+	resp, err := http.Get(artifact)
+	if err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to download artifact: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to download artifact: HTTP %d", resp.StatusCode)
+	}
+	if err := writeArtifactFile(dest, resp.Body, 0644); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+	return dest, cleanup, nil
+}
+
+// callHook runs an optional Lua hook with the release path. A hook fails
+// by raising an error or returning false (optionally with a message).
+func (m *DeployModule) callHook(L *lua.LState, opts *lua.LTable, name, releasePath string) error {
+	fn, ok := opts.RawGetString(name).(*lua.LFunction)
+	if !ok {
+		return nil
+	}
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 2, Protect: true}, lua.LString(releasePath)); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	ret, msg := L.Get(-2), L.Get(-1)
+	L.Pop(2)
+	if ret == lua.LFalse {
+		if msg != lua.LNil {
+			return fmt.Errorf("%s hook failed: %s", name, msg.String())
+		}
+		return fmt.Errorf("%s hook returned false", name)
+	}
+	return nil
+}
+
+func deployBaseDir(L *lua.LState, opts *lua.LTable) (string, bool) {
+	baseDir := opts.RawGetString("base_dir")
+	if baseDir == lua.LNil || baseDir.String() == "" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("base_dir is required"))
+		return "", false
+	}
+	return baseDir.String(), true
+}
+
+func optInt(opts *lua.LTable, key string, def int) int {
+	if v, ok := opts.RawGetString(key).(lua.LNumber); ok {
+		return int(v)
+	}
+	return def
+}
+
+func optStrings(opts *lua.LTable, key string) []string {
+	var values []string
+	if t, ok := opts.RawGetString(key).(*lua.LTable); ok {
+		t.ForEach(func(_, v lua.LValue) {
+			values = append(values, v.String())
+		})
+	}
+	return values
+}
+
+// release deploys a new release:
+//
+//	deploy.release({
+//	    base_dir = "/opt/myapp",
+//	    artifact = "https://example.com/myapp-1.2.0.tar.gz", -- or a local path
+//	    strip_components = 1,
+//	    shared = {"log", "config/app.env"},
+//	    keep = 5,
+//	    before_switch = function(path) ... end,
+//	    after_switch = function(path) ... end,
+//	})
+func (m *DeployModule) release(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	baseDir, ok := deployBaseDir(L, opts)
+	if !ok {
+		return 2
+	}
+	artifactOpt := opts.RawGetString("artifact")
+	if artifactOpt == lua.LNil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("artifact is required"))
+		return 2
+	}
+
+	rm := &ReleaseManager{BaseDir: baseDir}
+	previous, _ := rm.Current()
+
+	artifactPath, cleanupArtifact, err := resolveArtifact(artifactOpt.String())
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	defer cleanupArtifact()
+
+	release, err := rm.NewReleaseDir()
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	releasePath := filepath.Join(rm.ReleasesDir(), release)
+
+	fail := func(err error) int {
+		os.RemoveAll(releasePath)
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	if err := UnpackArtifact(artifactPath, releasePath, optInt(opts, "strip_components", 0)); err != nil {
+		return fail(fmt.Errorf("failed to unpack artifact: %w", err))
+	}
+	if err := rm.LinkShared(release, optStrings(opts, "shared")); err != nil {
+		return fail(err)
+	}
+	if err := m.callHook(L, opts, "before_switch", releasePath); err != nil {
+		return fail(err)
+	}
+	if err := rm.Switch(release); err != nil {
+		return fail(err)
+	}
+
+	rolledBack := false
+	if err := m.callHook(L, opts, "after_switch", releasePath); err != nil {
+		if previous != "" && opts.RawGetString("rollback_on_failure") != lua.LFalse {
+			if switchErr := rm.Switch(previous); switchErr == nil {
+				rolledBack = true
+			}
+		}
+		if rolledBack {
+			err = fmt.Errorf("%w (rolled back to %s)", err, previous)
+		}
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	removed, err := rm.Cleanup(optInt(opts, "keep", deployDefaultKeep))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LTrue)
+	result.RawSetString("release", lua.LString(release))
+	result.RawSetString("path", lua.LString(releasePath))
+	result.RawSetString("previous", lua.LString(previous))
+	removedTable := L.NewTable()
+	for _, r := range removed {
+		removedTable.Append(lua.LString(r))
+	}
+	result.RawSetString("removed", removedTable)
+	result.RawSetString("message", lua.LString(fmt.Sprintf("Release %s deployed", release)))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// rollback switches current back to the previous release, or to opts.to
+func (m *DeployModule) rollback(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	baseDir, ok := deployBaseDir(L, opts)
+	if !ok {
+		return 2
+	}
+
+	rm := &ReleaseManager{BaseDir: baseDir}
+	current, _ := rm.Current()
+
+	target := ""
+	if to := opts.RawGetString("to"); to != lua.LNil {
+		target = to.String()
+	} else {
+		prev, err := rm.Previous()
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("Failed to find previous release: %v", err)))
+			return 2
+		}
+		target = prev
+	}
+
+	result := L.NewTable()
+	result.RawSetString("release", lua.LString(target))
+	result.RawSetString("previous", lua.LString(current))
+
+	if target == current {
+		result.RawSetString("changed", lua.LFalse)
+		result.RawSetString("message", lua.LString(fmt.Sprintf("Release %s is already current", target)))
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	}
+
+	targetPath := filepath.Join(rm.ReleasesDir(), target)
+	if err := m.callHook(L, opts, "before_switch", targetPath); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if err := rm.Switch(target); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if err := m.callHook(L, opts, "after_switch", targetPath); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result.RawSetString("changed", lua.LTrue)
+	result.RawSetString("path", lua.LString(targetPath))
+	result.RawSetString("message", lua.LString(fmt.Sprintf("Rolled back from %s to %s", current, target)))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// list returns all releases, oldest first, marking the current one
+func (m *DeployModule) list(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	baseDir, ok := deployBaseDir(L, opts)
+	if !ok {
+		return 2
+	}
+
+	rm := &ReleaseManager{BaseDir: baseDir}
+	releases, err := rm.Releases()
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("Failed to list releases: %v", err)))
+		return 2
+	}
+	current, _ := rm.Current()
+
+	result := L.NewTable()
+	for _, r := range releases {
+		entry := L.NewTable()
+		entry.RawSetString("name", lua.LString(r))
+		entry.RawSetString("path", lua.LString(filepath.Join(rm.ReleasesDir(), r)))
+		entry.RawSetString("current", lua.LBool(r == current))
+		result.Append(entry)
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// current returns the active release name and path
+func (m *DeployModule) current(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	baseDir, ok := deployBaseDir(L, opts)
+	if !ok {
+		return 2
+	}
+
+	rm := &ReleaseManager{BaseDir: baseDir}
+	current, err := rm.Current()
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("Failed to read current release: %v", err)))
+		return 2
+	}
+	if current == "" {
+		L.Push(lua.LNil)
+		L.Push(lua.LNil)
+		return 2
+	}
+
+	result := L.NewTable()
+	result.RawSetString("name", lua.LString(current))
+	result.RawSetString("path", lua.LString(filepath.Join(rm.ReleasesDir(), current)))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// cleanup removes old releases, keeping opts.keep (default 5)
+func (m *DeployModule) cleanup(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	baseDir, ok := deployBaseDir(L, opts)
+	if !ok {
+		return 2
+	}
+
+	rm := &ReleaseManager{BaseDir: baseDir}
+	removed, err := rm.Cleanup(optInt(opts, "keep", deployDefaultKeep))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(len(removed) > 0))
+	removedTable := L.NewTable()
+	for _, r := range removed {
+		removedTable.Append(lua.LString(r))
+	}
+	result.RawSetString("removed", removedTable)
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
@@ -0,0 +1,281 @@
+package infra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func writeTestTarball(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDeployModule_ReleaseAndRollback(t *testing.T) {
+	dir := t.TempDir()
+	baseDir := filepath.Join(dir, "app")
+	v1 := filepath.Join(dir, "app-1.tar.gz")
+	v2 := filepath.Join(dir, "app-2.tar.gz")
+	writeTestTarball(t, v1, map[string]string{"app-1/VERSION": "1"})
+	writeTestTarball(t, v2, map[string]string{"app-2/VERSION": "2"})
+
+	L := lua.NewState()
+	defer L.Close()
+	NewDeployModule(L).Register(L)
+	L.SetGlobal("base_dir", lua.LString(baseDir))
+	L.SetGlobal("v1", lua.LString(v1))
+	L.SetGlobal("v2", lua.LString(v2))
+
+	err := L.DoString(`
+		local r1, err = deploy.release({base_dir = base_dir, artifact = v1, strip_components = 1, shared = {"log"}})
+		assert(r1, err)
+		local r2, err = deploy.release({base_dir = base_dir, artifact = v2, strip_components = 1, shared = {"log"}})
+		assert(r2, err)
+		assert(r2.previous == r1.release, "previous should be first release")
+		second = r2.release
+
+		local rb, err = deploy.rollback({base_dir = base_dir})
+		assert(rb, err)
+		assert(rb.changed, "rollback should change current")
+		assert(rb.release == r1.release, "rollback should target first release")
+
+		local releases = deploy.list({base_dir = base_dir})
+		release_count = #releases
+	`)
+	if err != nil {
+		t.Fatalf("deploy script failed: %v", err)
+	}
+
+	if n := L.GetGlobal("release_count"); n.(lua.LNumber) != 2 {
+		t.Errorf("expected 2 releases, got %v", n)
+	}
+	data, err := os.ReadFile(filepath.Join(baseDir, "current", "VERSION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "1" {
+		t.Errorf("expected current to be version 1 after rollback, got %q", data)
+	}
+	if fi, err := os.Lstat(filepath.Join(baseDir, "current", "log")); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected shared log directory to be a symlink")
+	}
+}
+
+func TestDeployModule_FailedHookKeepsCurrent(t *testing.T) {
+	dir := t.TempDir()
+	baseDir := filepath.Join(dir, "app")
+	artifact := filepath.Join(dir, "app.tar.gz")
+	writeTestTarball(t, artifact, map[string]string{"VERSION": "1"})
+
+	L := lua.NewState()
+	defer L.Close()
+	NewDeployModule(L).Register(L)
+	L.SetGlobal("base_dir", lua.LString(baseDir))
+	L.SetGlobal("artifact", lua.LString(artifact))
+
+	err := L.DoString(`
+		local r, err = deploy.release({base_dir = base_dir, artifact = artifact})
+		assert(r, err)
+		first = r.release
+
+		local r2, err2 = deploy.release({
+			base_dir = base_dir,
+			artifact = artifact,
+			before_switch = function(path) return false, "migration failed" end,
+		})
+		assert(r2 == nil, "release should fail")
+		hook_err = err2
+		current = deploy.current({base_dir = base_dir}).name
+	`)
+	if err != nil {
+		t.Fatalf("deploy script failed: %v", err)
+	}
+
+	if L.GetGlobal("current").String() != L.GetGlobal("first").String() {
+		t.Errorf("current release should be unchanged after failed hook")
+	}
+	rm := &ReleaseManager{BaseDir: baseDir}
+	if releases, _ := rm.Releases(); len(releases) != 1 {
+		t.Errorf("failed release directory should be removed, got %v", releases)
+	}
+}
+
+func TestReleaseManager_CleanupKeepsCurrent(t *testing.T) {
+	rm := &ReleaseManager{BaseDir: t.TempDir()}
+	for _, r := range []string{"20240101000000", "20240102000000", "20240103000000"} {
+		if err := os.MkdirAll(filepath.Join(rm.ReleasesDir(), r), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rm.Switch("20240101000000"); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := rm.Cleanup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected 2 removed releases, got %v", removed)
+	}
+	current, _ := rm.Current()
+	if current != "20240101000000" {
+		t.Errorf("current release must never be removed, got %q", current)
+	}
+}
+
+func TestReleaseManager_ReleasesOrderSuffixes(t *testing.T) {
+	rm := &ReleaseManager{BaseDir: t.TempDir()}
+	for _, r := range []string{"20240101000000-10", "20240101000000-9", "20240102000000", "20240101000000"} {
+		if err := os.MkdirAll(filepath.Join(rm.ReleasesDir(), r), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	releases, err := rm.Releases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20240101000000", "20240101000000-9", "20240101000000-10", "20240102000000"}
+	if strings.Join(releases, " ") != strings.Join(want, " ") {
+		t.Errorf("releases = %v, want %v", releases, want)
+	}
+}
+
+func TestSafeJoin_RejectsTraversal(t *testing.T) {
+	if _, _, err := safeJoin("/tmp/dest", "../../etc/passwd", 0); err == nil {
+		t.Error("expected traversal to be rejected")
+	}
+	target, ok, err := safeJoin("/tmp/dest", "pkg/bin/app", 1)
+	if err != nil || !ok || target != "/tmp/dest/bin/app" {
+		t.Errorf("unexpected result %q %v %v", target, ok, err)
+	}
+}
+
+func TestUntar_RejectsSymlinkEscapes(t *testing.T) {
+	// tarball writes a symlink to link, then a file through it
+	tarball := func(link string) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "x", Linkname: link, Typeflag: tar.TypeSymlink})
+		tw.WriteHeader(&tar.Header{Name: "x/passwd", Mode: 0644, Size: 5, Typeflag: tar.TypeReg})
+		tw.Write([]byte("owned"))
+		tw.Close()
+		return &buf
+	}
+
+	for _, link := range []string{"/etc", "../../outside", "sub"} {
+		dir := t.TempDir()
+		dest := filepath.Join(dir, "releases", "1")
+		os.MkdirAll(filepath.Join(dest, "sub"), 0755)
+		if err := untar(tarball(link), dest, 0); err == nil {
+			t.Errorf("expected archive with x -> %s then x/passwd to be rejected", link)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "outside", "passwd")); err == nil {
+			t.Errorf("x -> %s wrote outside the release", link)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "sub", "passwd")); err == nil {
+			t.Errorf("x -> %s wrote through the symlink", link)
+		}
+	}
+
+	// Links within the release are kept
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "bin/app", Mode: 0755, Size: 2, Typeflag: tar.TypeReg})
+	tw.Write([]byte("ok"))
+	tw.WriteHeader(&tar.Header{Name: "bin/current", Linkname: "app", Typeflag: tar.TypeSymlink})
+	tw.Close()
+	dest := t.TempDir()
+	if err := untar(&buf, dest, 0); err != nil {
+		t.Fatalf("expected a link within the release to be kept: %v", err)
+	}
+	if link, err := os.Readlink(filepath.Join(dest, "bin", "current")); err != nil || link != "app" {
+		t.Errorf("unexpected link %q %v", link, err)
+	}
+}
+
+func TestUntar_HardLinks(t *testing.T) {
+	tarball := func(link string) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "pkg/bin/app", Mode: 0755, Size: 2, Typeflag: tar.TypeReg})
+		tw.Write([]byte("ok"))
+		tw.WriteHeader(&tar.Header{Name: "pkg/bin/app-link", Linkname: link, Typeflag: tar.TypeLink})
+		tw.Close()
+		return &buf
+	}
+
+	dest := t.TempDir()
+	if err := untar(tarball("pkg/bin/app"), dest, 1); err != nil {
+		t.Fatalf("expected a hard link within the release to be kept: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "bin", "app-link")); err != nil || string(data) != "ok" {
+		t.Errorf("unexpected hard link content %q %v", data, err)
+	}
+
+	for _, link := range []string{"/etc/passwd", "../../etc/passwd", "pkg", "pkg/bin/missing"} {
+		if err := untar(tarball(link), t.TempDir(), 1); err == nil {
+			t.Errorf("expected hard link to %s to be rejected", link)
+		}
+	}
+}
+
+func TestUnzip_Symlinks(t *testing.T) {
+	zipball := func(link string) string {
+		path := filepath.Join(t.TempDir(), "app.zip")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw := zip.NewWriter(f)
+		w, _ := zw.Create("bin/app")
+		w.Write([]byte("ok"))
+		h := &zip.FileHeader{Name: "bin/current"}
+		h.SetMode(os.ModeSymlink | 0777)
+		w, _ = zw.CreateHeader(h)
+		w.Write([]byte(link))
+		zw.Close()
+		f.Close()
+		return path
+	}
+
+	dest := t.TempDir()
+	if err := unzip(zipball("app"), dest, 0); err != nil {
+		t.Fatalf("expected a link within the release to be kept: %v", err)
+	}
+	if link, err := os.Readlink(filepath.Join(dest, "bin", "current")); err != nil || link != "app" {
+		t.Errorf("unexpected link %q %v", link, err)
+	}
+
+	for _, link := range []string{"/etc", "../../outside"} {
+		if err := unzip(zipball(link), t.TempDir(), 0); err == nil {
+			t.Errorf("expected zip symlink to %s to be rejected", link)
+		}
+	}
+}
@@ -0,0 +1,599 @@
+package infra
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	defaultHostsPath = "/etc/hosts"
+	defaultDNSTTL    = 300
+)
+
+// DNSRecord is a record set managed by dns.record: every value of a name
+// and type, so applying it makes the provider hold exactly these values
+type DNSRecord struct {
+	Zone   string
+	Name   string
+	Type   string
+	Values []string
+	TTL    int
+	// State is "present" or "absent"
+	State string
+}
+
+// FQDN returns the record name qualified with its zone. "@" names the
+// zone itself.
+func (r DNSRecord) FQDN() string {
+	name := strings.TrimSuffix(r.Name, ".")
+	zone := strings.TrimSuffix(r.Zone, ".")
+	switch {
+	case zone == "":
+		return name
+	case name == "" || name == "@":
+		return zone
+	case name == zone || strings.HasSuffix(name, "."+zone):
+		return name
+	default:
+		return name + "." + zone
+	}
+}
+
+// DNSChange is the outcome of applying a record
+type DNSChange struct {
+	Changed bool
+	// Action is created, updated, deleted or unchanged
+	Action string
+	Before []string
+	After  []string
+	Diff   string
+}
+
+// DNSProvider applies records to a DNS backend. With dryRun set it only
+// reports the change it would make.
+type DNSProvider interface {
+	Apply(record DNSRecord, dryRun bool) (*DNSChange, error)
+}
+
+// DNSModule manages /etc/hosts entries and records of external DNS providers
+type DNSModule struct {
+	L *lua.LState
+}
+
+// NewDNSModule creates a new DNS module instance
+func NewDNSModule(L *lua.LState) *DNSModule {
+	return &DNSModule{L: L}
+}
+
+// Register registers the DNS module with the Lua state
+func (m *DNSModule) Register(L *lua.LState) {
+	dnsTable := L.NewTable()
+
+	L.SetField(dnsTable, "record", L.NewFunction(m.record))
+	L.SetField(dnsTable, "hosts_entry", L.NewFunction(m.hostsEntry))
+	L.SetField(dnsTable, "hosts_list", L.NewFunction(m.hostsList))
+
+	L.SetGlobal("dns", dnsTable)
+}
+
+// record applies a record with the chosen provider:
+//
+//	dns.record({
+//	    provider = "cloudflare", -- hosts (default), route53 or cloudflare
+//	    zone = "example.com",
+//	    name = "api",
+//	    type = "A",
+//	    value = {"203.0.113.10", "203.0.113.11"}, -- or a single string
+//	    ttl = 300,
+//	    state = "present",
+//	})
+func (m *DNSModule) record(L *lua.LState) int {
+	opts := L.CheckTable(1)
+
+	record, err := dnsRecordFromTable(opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	provider, name, err := DNSProviderFromTable(opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	change, err := provider.Apply(record, lua.LVAsBool(opts.RawGetString("dry_run")))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("%s: %v", name, err)))
+		return 2
+	}
+
+	result := dnsChangeTable(L, change)
+	result.RawSetString("provider", lua.LString(name))
+	result.RawSetString("name", lua.LString(record.FQDN()))
+	result.RawSetString("type", lua.LString(record.Type))
+	result.RawSetString("message", lua.LString(fmt.Sprintf("%s %s record %s %s", name, record.Type, record.FQDN(), change.Action)))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// hostsEntry points hostnames at an IP in a hosts file:
+//
+//	dns.hosts_entry({ip = "10.0.0.5", hostnames = {"db", "db.internal"}, state = "present"})
+//
+// With state = "absent" the hostnames are removed, along with lines left
+// without any.
+func (m *DNSModule) hostsEntry(L *lua.LState) int {
+	opts := L.CheckTable(1)
+
+	ip := opts.RawGetString("ip")
+	hostnames := optStrings(opts, "hostnames")
+	if h := opts.RawGetString("hostname"); h.Type() == lua.LTString {
+		hostnames = append(hostnames, h.String())
+	}
+	state := "present"
+	if s := opts.RawGetString("state"); s.Type() == lua.LTString {
+		state = s.String()
+	}
+	if len(hostnames) == 0 {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("hostnames is required"))
+		return 2
+	}
+	if state != "present" && state != "absent" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("invalid state %q: expected present or absent", state)))
+		return 2
+	}
+	var values []string
+	if ip.Type() == lua.LTString {
+		values = []string{ip.String()}
+	} else if state == "present" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("ip is required"))
+		return 2
+	}
+
+	hosts := &HostsFile{Path: hostsPath(opts)}
+	change, err := hosts.ApplyNames(hostnames, values, state, lua.LVAsBool(opts.RawGetString("dry_run")))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := dnsChangeTable(L, change)
+	result.RawSetString("message", lua.LString(fmt.Sprintf("%s %s in %s", strings.Join(hostnames, ", "), change.Action, hosts.Path)))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// hostsList returns the entries of a hosts file, each with ip and hostnames
+func (m *DNSModule) hostsList(L *lua.LState) int {
+	opts := L.OptTable(1, L.NewTable())
+
+	hosts := &HostsFile{Path: hostsPath(opts)}
+	lines, err := hosts.read()
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	for _, line := range lines {
+		ip, names, _ := parseHostsLine(line)
+		if ip == "" {
+			continue
+		}
+		entry := L.NewTable()
+		entry.RawSetString("ip", lua.LString(ip))
+		namesTable := L.NewTable()
+		for _, name := range names {
+			namesTable.Append(lua.LString(name))
+		}
+		entry.RawSetString("hostnames", namesTable)
+		result.Append(entry)
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+func hostsPath(opts *lua.LTable) string {
+	if p := opts.RawGetString("path"); p.Type() == lua.LTString && p.String() != "" {
+		return p.String()
+	}
+	return defaultHostsPath
+}
+
+func dnsRecordFromTable(opts *lua.LTable) (DNSRecord, error) {
+	record := DNSRecord{TTL: optInt(opts, "ttl", defaultDNSTTL), State: "present"}
+	for key, dst := range map[string]*string{"zone": &record.Zone, "name": &record.Name, "type": &record.Type, "state": &record.State} {
+		if v := opts.RawGetString(key); v.Type() == lua.LTString {
+			*dst = v.String()
+		}
+	}
+	switch v := opts.RawGetString("value").(type) {
+	case lua.LString:
+		record.Values = []string{string(v)}
+	case *lua.LTable:
+		v.ForEach(func(_, item lua.LValue) {
+			record.Values = append(record.Values, item.String())
+		})
+	}
+	record.Type = strings.ToUpper(record.Type)
+	if record.Type == "" {
+		record.Type = "A"
+	}
+
+	if record.FQDN() == "" {
+		return record, fmt.Errorf("name or zone is required")
+	}
+	if record.State != "present" && record.State != "absent" {
+		return record, fmt.Errorf("invalid state %q: expected present or absent", record.State)
+	}
+	if record.State == "present" && len(record.Values) == 0 {
+		return record, fmt.Errorf("value is required")
+	}
+	return record, nil
+}
+
+// DNSProviderFromTable returns the provider named by the provider option
+// of a dns.record call, and its name, configured with the other options
+func DNSProviderFromTable(opts *lua.LTable) (DNSProvider, string, error) {
+	name := "hosts"
+	if p := opts.RawGetString("provider"); p.Type() == lua.LTString {
+		name = p.String()
+	}
+	str := func(key string) string {
+		if v := opts.RawGetString(key); v.Type() == lua.LTString {
+			return v.String()
+		}
+		return ""
+	}
+
+	switch name {
+	case "hosts":
+		return &HostsFile{Path: hostsPath(opts)}, name, nil
+	case "route53":
+		return &Route53Provider{ZoneID: str("zone_id"), Profile: str("profile")}, name, nil
+	case "cloudflare":
+		token := str("api_token")
+		if token == "" {
+			token = os.Getenv("CLOUDFLARE_API_TOKEN")
+		}
+		if token == "" {
+			return nil, name, fmt.Errorf("cloudflare: api_token or CLOUDFLARE_API_TOKEN is required")
+		}
+		return &CloudflareProvider{Token: token, ZoneID: str("zone_id"), Proxied: lua.LVAsBool(opts.RawGetString("proxied"))}, name, nil
+	default:
+		return nil, name, fmt.Errorf("unknown provider %q: expected hosts, route53 or cloudflare", name)
+	}
+}
+
+func dnsChangeTable(L *lua.LState, change *DNSChange) *lua.LTable {
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(change.Changed))
+	result.RawSetString("action", lua.LString(change.Action))
+	result.RawSetString("diff", lua.LString(change.Diff))
+	for key, values := range map[string][]string{"before": change.Before, "after": change.After} {
+		t := L.NewTable()
+		for _, v := range values {
+			t.Append(lua.LString(v))
+		}
+		result.RawSetString(key, t)
+	}
+	return result
+}
+
+// dnsAction names the change from before to after
+func dnsAction(before, after []string) string {
+	switch {
+	case len(before) == 0 && len(after) > 0:
+		return "created"
+	case len(before) > 0 && len(after) == 0:
+		return "deleted"
+	default:
+		return "updated"
+	}
+}
+
+// HostsFile manages entries of a hosts file, keeping comments, blank
+// lines and unrelated entries as they are
+type HostsFile struct {
+	Path string
+}
+
+// Apply implements DNSProvider: the record's name resolves to exactly its
+// values, or to nothing when absent
+func (h *HostsFile) Apply(record DNSRecord, dryRun bool) (*DNSChange, error) {
+	if record.Type != "A" && record.Type != "AAAA" {
+		return nil, fmt.Errorf("hosts files only hold A and AAAA records, not %s", record.Type)
+	}
+	for _, value := range record.Values {
+		ip := net.ParseIP(value)
+		if ip == nil || (record.Type == "A") != (ip.To4() != nil) {
+			return nil, fmt.Errorf("invalid %s record value %q", record.Type, value)
+		}
+	}
+	return h.ApplyNames([]string{record.FQDN()}, record.Values, record.State, dryRun)
+}
+
+// ApplyNames points each name at exactly ips when state is present, or
+// removes the names (from the lines of ips only, when given) when absent
+func (h *HostsFile) ApplyNames(names, ips []string, state string, dryRun bool) (*DNSChange, error) {
+	lines, err := h.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid IP address %q", ip)
+		}
+	}
+
+	before := hostsAddresses(lines, names)
+	var updated []string
+	if state == "absent" {
+		updated = hostsRemove(lines, names, ips)
+	} else {
+		updated = hostsSet(lines, names, ips)
+	}
+	after := hostsAddresses(updated, names)
+
+	change := &DNSChange{Action: "unchanged", Before: before, After: after}
+	if strings.Join(lines, "\n") == strings.Join(updated, "\n") {
+		return change, nil
+	}
+	change.Changed = true
+	change.Action = dnsAction(before, after)
+	change.Diff = lineDiff(lines, updated)
+	if dryRun {
+		return change, nil
+	}
+	if err := h.write(updated); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (h *HostsFile) read() ([]string, error) {
+	content, err := os.ReadFile(h.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", h.Path, err)
+	}
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+func (h *HostsFile) write(lines []string) error {
+	return writeFileAtomic(h.Path, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// writeFileAtomic replaces a file through a rename, keeping its mode,
+// falling back to rewriting it in place where it can't be replaced, e.g.
+// when bind-mounted in a container
+func writeFileAtomic(path string, content []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err == nil {
+		_, err = tmp.Write(content)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), mode)
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err == nil {
+			return nil
+		}
+		os.Remove(tmp.Name())
+	}
+
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseHostsLine splits an entry into its IP, hostnames and trailing
+// comment. Comments and blank lines have no IP.
+func parseHostsLine(line string) (ip string, names []string, comment string) {
+	entry := line
+	if i := strings.Index(line, "#"); i >= 0 {
+		entry, comment = line[:i], line[i:]
+	}
+	fields := strings.Fields(entry)
+	if len(fields) == 0 {
+		return "", nil, comment
+	}
+	return fields[0], fields[1:], comment
+}
+
+func formatHostsLine(ip string, names []string, comment string) string {
+	line := ip + "\t" + strings.Join(names, " ")
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+// hostsAddresses returns the sorted IPs any of names resolve to
+func hostsAddresses(lines []string, names []string) []string {
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[n] = true
+	}
+	seen := make(map[string]bool)
+	var ips []string
+	for _, line := range lines {
+		ip, lineNames, _ := parseHostsLine(line)
+		for _, n := range lineNames {
+			if wanted[n] && !seen[ip] {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// hostsSet makes names resolve to exactly ips: they are dropped from the
+// lines of other IPs and added to the first line of each IP, or to a new
+// line at the end
+func hostsSet(lines, names, ips []string) []string {
+	keep := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		keep[ip] = true
+	}
+	updated := lines
+	if others := otherIPs(lines, keep); len(others) > 0 {
+		updated = hostsRemove(lines, names, others)
+	}
+	updated = append([]string(nil), updated...)
+	for _, ip := range ips {
+		found := false
+		for i, line := range updated {
+			lineIP, lineNames, comment := parseHostsLine(line)
+			if lineIP != ip {
+				continue
+			}
+			found = true
+			missing := false
+			for _, n := range names {
+				if !containsString(lineNames, n) {
+					lineNames = append(lineNames, n)
+					missing = true
+				}
+			}
+			if missing {
+				updated[i] = formatHostsLine(ip, lineNames, comment)
+			}
+			break
+		}
+		if !found {
+			updated = append(updated, formatHostsLine(ip, names, ""))
+		}
+	}
+	return updated
+}
+
+// hostsRemove drops names from the lines of ips (of every IP when ips is
+// empty) and drops lines left without hostnames
+func hostsRemove(lines, names, ips []string) []string {
+	var updated []string
+	for _, line := range lines {
+		ip, lineNames, comment := parseHostsLine(line)
+		if ip == "" || (len(ips) > 0 && !containsString(ips, ip)) {
+			updated = append(updated, line)
+			continue
+		}
+		var kept []string
+		for _, n := range lineNames {
+			if !containsString(names, n) {
+				kept = append(kept, n)
+			}
+		}
+		switch {
+		case len(kept) == len(lineNames):
+			updated = append(updated, line)
+		case len(kept) > 0:
+			updated = append(updated, formatHostsLine(ip, kept, comment))
+		}
+	}
+	return updated
+}
+
+// otherIPs returns the IPs of entries not in keep
+func otherIPs(lines []string, keep map[string]bool) []string {
+	var ips []string
+	for _, line := range lines {
+		if ip, _, _ := parseHostsLine(line); ip != "" && !keep[ip] && !containsString(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lineDiff returns the lines removed from a and added in b, prefixed with
+// - and +, in file order
+func lineDiff(a, b []string) string {
+	// Only the lines between the common prefix and suffix can differ
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// Longest common subsequence of the rest
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			diff.WriteString("+" + b[j] + "\n")
+			j++
+		default:
+			diff.WriteString("-" + a[i] + "\n")
+			i++
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,360 @@
+package infra
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dnsCommand runs a CLI used by a DNS provider and returns its stdout.
+// Tests replace it to fake the aws CLI.
+var dnsCommand = func(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// recordLines formats record values the way the diff of a provider shows them
+func recordLines(fqdn, recordType string, ttl int, values []string) []string {
+	lines := make([]string, len(values))
+	for i, v := range values {
+		lines[i] = fmt.Sprintf("%s %d IN %s %s", fqdn, ttl, recordType, v)
+	}
+	return lines
+}
+
+// recordDiff returns the diff between two versions of a record set
+func recordDiff(fqdn, recordType string, beforeTTL int, before []string, afterTTL int, after []string) string {
+	return lineDiff(recordLines(fqdn, recordType, beforeTTL, before), recordLines(fqdn, recordType, afterTTL, after))
+}
+
+func sortedCopy(values []string) []string {
+	out := append([]string(nil), values...)
+	sort.Strings(out)
+	return out
+}
+
+func sameValues(a, b []string) bool {
+	return strings.Join(sortedCopy(a), "\x00") == strings.Join(sortedCopy(b), "\x00")
+}
+
+// Route53Provider manages records in AWS Route 53 through the aws CLI, so
+// it uses the credentials the CLI is configured with
+type Route53Provider struct {
+	// ZoneID is looked up from the record's zone when empty
+	ZoneID  string
+	Profile string
+}
+
+type route53RecordSet struct {
+	Name            string `json:"Name"`
+	Type            string `json:"Type"`
+	TTL             int    `json:"TTL"`
+	ResourceRecords []struct {
+		Value string `json:"Value"`
+	} `json:"ResourceRecords"`
+}
+
+func (p *Route53Provider) aws(args ...string) ([]byte, error) {
+	if p.Profile != "" {
+		args = append(args, "--profile", p.Profile)
+	}
+	return dnsCommand("aws", append(args, "--output", "json")...)
+}
+
+func (p *Route53Provider) zoneID(zone string) (string, error) {
+	if p.ZoneID != "" {
+		return strings.TrimPrefix(p.ZoneID, "/hostedzone/"), nil
+	}
+	if zone == "" {
+		return "", fmt.Errorf("zone or zone_id is required")
+	}
+	out, err := p.aws("route53", "list-hosted-zones-by-name", "--dns-name", zone, "--max-items", "1")
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		HostedZones []struct {
+			ID   string `json:"Id"`
+			Name string `json:"Name"`
+		} `json:"HostedZones"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse hosted zones: %w", err)
+	}
+	if len(resp.HostedZones) == 0 || resp.HostedZones[0].Name != strings.TrimSuffix(zone, ".")+"." {
+		return "", fmt.Errorf("hosted zone %s not found", zone)
+	}
+	return strings.TrimPrefix(resp.HostedZones[0].ID, "/hostedzone/"), nil
+}
+
+func (p *Route53Provider) current(zoneID, fqdn, recordType string) (*route53RecordSet, error) {
+	out, err := p.aws("route53", "list-resource-record-sets", "--hosted-zone-id", zoneID,
+		"--start-record-name", fqdn, "--start-record-type", recordType, "--max-items", "1")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		ResourceRecordSets []route53RecordSet `json:"ResourceRecordSets"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse record sets: %w", err)
+	}
+	if len(resp.ResourceRecordSets) == 0 {
+		return nil, nil
+	}
+	set := resp.ResourceRecordSets[0]
+	if set.Name != fqdn+"." || set.Type != recordType {
+		return nil, nil
+	}
+	return &set, nil
+}
+
+// route53Value quotes TXT values, which Route 53 stores quoted
+func route53Value(recordType, value string) string {
+	if recordType == "TXT" && !strings.HasPrefix(value, "\"") {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
+
+// Apply implements DNSProvider with an UPSERT or DELETE change batch
+func (p *Route53Provider) Apply(record DNSRecord, dryRun bool) (*DNSChange, error) {
+	zoneID, err := p.zoneID(record.Zone)
+	if err != nil {
+		return nil, err
+	}
+	fqdn := record.FQDN()
+	existing, err := p.current(zoneID, fqdn, record.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	var before []string
+	beforeTTL := 0
+	if existing != nil {
+		beforeTTL = existing.TTL
+		for _, rr := range existing.ResourceRecords {
+			before = append(before, rr.Value)
+		}
+	}
+	var after []string
+	if record.State == "present" {
+		for _, v := range record.Values {
+			after = append(after, route53Value(record.Type, v))
+		}
+	}
+
+	change := &DNSChange{Action: "unchanged", Before: before, After: after}
+	if record.State == "absent" {
+		change.After = nil
+		if existing == nil {
+			return change, nil
+		}
+	} else if existing != nil && beforeTTL == record.TTL && sameValues(before, after) {
+		return change, nil
+	}
+	change.Changed = true
+	change.Action = dnsAction(before, change.After)
+	change.Diff = recordDiff(fqdn, record.Type, beforeTTL, before, record.TTL, change.After)
+	if dryRun {
+		return change, nil
+	}
+
+	action, set := "UPSERT", route53RecordSet{Name: fqdn + ".", Type: record.Type, TTL: record.TTL}
+	values := after
+	if record.State == "absent" {
+		// Route 53 only deletes a record set given exactly as it is
+		action, set.TTL, values = "DELETE", existing.TTL, before
+	}
+	for _, v := range values {
+		set.ResourceRecords = append(set.ResourceRecords, struct {
+			Value string `json:"Value"`
+		}{Value: v})
+	}
+	batch, err := json.Marshal(map[string]interface{}{
+		"Changes": []map[string]interface{}{{"Action": action, "ResourceRecordSet": set}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.aws("route53", "change-resource-record-sets", "--hosted-zone-id", zoneID, "--change-batch", string(batch)); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// cloudflareAPI is the base URL of the Cloudflare API; tests point it at a
+// local server
+var cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// CloudflareProvider manages records through the Cloudflare API. Each value
+// of a record set is a separate Cloudflare record.
+type CloudflareProvider struct {
+	Token string
+	// ZoneID is looked up from the record's zone when empty
+	ZoneID  string
+	Proxied bool
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+func (p *CloudflareProvider) request(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, cloudflareAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("%s %s: unexpected response (HTTP %d): %w", method, path, resp.StatusCode, err)
+	}
+	if !envelope.Success {
+		var msgs []string
+		for _, e := range envelope.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return fmt.Errorf("%s %s: HTTP %d: %s", method, path, resp.StatusCode, strings.Join(msgs, "; "))
+	}
+	if out != nil {
+		return json.Unmarshal(envelope.Result, out)
+	}
+	return nil
+}
+
+func (p *CloudflareProvider) zoneID(zone string) (string, error) {
+	if p.ZoneID != "" {
+		return p.ZoneID, nil
+	}
+	if zone == "" {
+		return "", fmt.Errorf("zone or zone_id is required")
+	}
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	if err := p.request(http.MethodGet, "/zones?name="+url.QueryEscape(strings.TrimSuffix(zone, ".")), nil, &zones); err != nil {
+		return "", err
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone %s not found", zone)
+	}
+	return zones[0].ID, nil
+}
+
+// Apply implements DNSProvider, creating, updating and deleting the
+// Cloudflare records of the set so they hold exactly the wanted values
+func (p *CloudflareProvider) Apply(record DNSRecord, dryRun bool) (*DNSChange, error) {
+	zoneID, err := p.zoneID(record.Zone)
+	if err != nil {
+		return nil, err
+	}
+	fqdn := record.FQDN()
+
+	var existing []cloudflareRecord
+	query := url.Values{"type": {record.Type}, "name": {fqdn}}
+	if err := p.request(http.MethodGet, "/zones/"+zoneID+"/dns_records?"+query.Encode(), nil, &existing); err != nil {
+		return nil, err
+	}
+
+	var before []string
+	beforeTTL := 0
+	byContent := make(map[string]cloudflareRecord, len(existing))
+	for _, r := range existing {
+		before = append(before, r.Content)
+		byContent[r.Content] = r
+		beforeTTL = r.TTL
+	}
+
+	var after []string
+	var create, update, remove []cloudflareRecord
+	if record.State == "present" {
+		after = record.Values
+		for _, v := range record.Values {
+			wanted := cloudflareRecord{Type: record.Type, Name: fqdn, Content: v, TTL: record.TTL, Proxied: p.Proxied}
+			r, ok := byContent[v]
+			switch {
+			case !ok:
+				create = append(create, wanted)
+			case r.TTL != record.TTL || r.Proxied != p.Proxied:
+				wanted.ID = r.ID
+				update = append(update, wanted)
+			}
+		}
+	}
+	for _, r := range existing {
+		if !containsString(after, r.Content) {
+			remove = append(remove, r)
+		}
+	}
+
+	change := &DNSChange{Action: "unchanged", Before: before, After: after}
+	if len(create)+len(update)+len(remove) == 0 {
+		return change, nil
+	}
+	change.Changed = true
+	change.Action = dnsAction(before, after)
+	change.Diff = recordDiff(fqdn, record.Type, beforeTTL, before, record.TTL, after)
+	if dryRun {
+		return change, nil
+	}
+
+	base := "/zones/" + zoneID + "/dns_records"
+	for _, r := range create {
+		if err := p.request(http.MethodPost, base, r, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range update {
+		if err := p.request(http.MethodPut, base+"/"+r.ID, r, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range remove {
+		if err := p.request(http.MethodDelete, base+"/"+r.ID, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	return change, nil
+}
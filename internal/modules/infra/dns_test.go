@@ -0,0 +1,215 @@
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const testHosts = `127.0.0.1	localhost
+# cluster
+10.0.0.4	old-db # keep me
+10.0.0.9	db cache
+`
+
+func TestDNSModule_HostsEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(testHosts), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	NewDNSModule(L).Register(L)
+	L.SetGlobal("path", lua.LString(path))
+
+	err := L.DoString(`
+		local r, err = dns.hosts_entry({path = path, ip = "10.0.0.5", hostnames = {"db", "db.internal"}, dry_run = true})
+		assert(r, err)
+		assert(r.changed and r.action == "updated", "dry run should report an update")
+
+		r, err = dns.hosts_entry({path = path, ip = "10.0.0.5", hostnames = {"db", "db.internal"}})
+		assert(r, err)
+		assert(r.changed, "first apply should change")
+		assert(r.before[1] == "10.0.0.9" and r.after[1] == "10.0.0.5")
+		assert(r.diff:find("-10.0.0.9\tdb cache", 1, true), r.diff)
+		assert(r.diff:find("+10.0.0.5\tdb db.internal", 1, true), r.diff)
+
+		r, err = dns.hosts_entry({path = path, ip = "10.0.0.5", hostnames = {"db", "db.internal"}})
+		assert(r, err)
+		assert(not r.changed and r.action == "unchanged", "second apply should be a no-op")
+
+		r, err = dns.hosts_entry({path = path, hostname = "old-db", state = "absent"})
+		assert(r, err)
+		assert(r.changed and r.action == "deleted")
+
+		local entries = dns.hosts_list({path = path})
+		assert(#entries == 3, "expected 3 entries, got " .. #entries)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(path)
+	want := "127.0.0.1\tlocalhost\n# cluster\n10.0.0.9\tcache\n10.0.0.5\tdb db.internal\n"
+	if string(content) != want {
+		t.Fatalf("unexpected hosts file:\n%s", content)
+	}
+}
+
+func TestDNSModule_RecordHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+
+	L := lua.NewState()
+	defer L.Close()
+	NewDNSModule(L).Register(L)
+	L.SetGlobal("path", lua.LString(path))
+
+	err := L.DoString(`
+		local r, err = dns.record({path = path, zone = "lab", name = "web", value = "192.168.1.10"})
+		assert(r, err)
+		assert(r.changed and r.action == "created" and r.name == "web.lab")
+
+		r, err = dns.record({path = path, zone = "lab", name = "web", value = "192.168.1.10"})
+		assert(r, err)
+		assert(not r.changed)
+
+		r, err = dns.record({path = path, zone = "lab", name = "web", type = "CNAME", value = "www"})
+		assert(r == nil and err:find("A and AAAA"), "hosts should reject CNAME records")
+
+		r, err = dns.record({path = path, zone = "lab", name = "web", state = "absent"})
+		assert(r, err)
+		assert(r.changed and r.action == "deleted")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDNSModule_Route53(t *testing.T) {
+	var calls [][]string
+	original := dnsCommand
+	t.Cleanup(func() { dnsCommand = original })
+	dnsCommand = func(name string, args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		switch args[1] {
+		case "list-hosted-zones-by-name":
+			return []byte(`{"HostedZones": [{"Id": "/hostedzone/Z123", "Name": "example.com."}]}`), nil
+		case "list-resource-record-sets":
+			return []byte(`{"ResourceRecordSets": [{"Name": "api.example.com.", "Type": "A", "TTL": 300, "ResourceRecords": [{"Value": "203.0.113.1"}]}]}`), nil
+		case "change-resource-record-sets":
+			return []byte(`{}`), nil
+		}
+		return nil, fmt.Errorf("unexpected command %v", args)
+	}
+
+	provider := &Route53Provider{}
+	record := DNSRecord{Zone: "example.com", Name: "api", Type: "A", Values: []string{"203.0.113.1"}, TTL: 300, State: "present"}
+	change, err := provider.Apply(record, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Changed || len(calls) != 2 {
+		t.Fatalf("expected no change, got %+v after %d calls", change, len(calls))
+	}
+
+	record.Values = []string{"203.0.113.2"}
+	change, err = provider.Apply(record, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !change.Changed || change.Action != "updated" {
+		t.Fatalf("expected an update, got %+v", change)
+	}
+	last := calls[len(calls)-1]
+	batch := last[len(last)-3]
+	if last[1] != "change-resource-record-sets" || !strings.Contains(batch, `"UPSERT"`) || !strings.Contains(batch, "203.0.113.2") {
+		t.Fatalf("unexpected change batch: %v", last)
+	}
+}
+
+// fakeCloudflare serves the parts of the Cloudflare API the provider uses
+type fakeCloudflare struct {
+	mu      sync.Mutex
+	records map[string]cloudflareRecord
+	nextID  int
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": result})
+	}
+	switch {
+	case r.URL.Path == "/zones":
+		reply([]map[string]string{{"id": "zone1"}})
+	case r.Method == http.MethodGet:
+		var matches []cloudflareRecord
+		for _, rec := range f.records {
+			if rec.Name == r.URL.Query().Get("name") && rec.Type == r.URL.Query().Get("type") {
+				matches = append(matches, rec)
+			}
+		}
+		reply(matches)
+	case r.Method == http.MethodPost:
+		var rec cloudflareRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		f.nextID++
+		rec.ID = fmt.Sprintf("rec%d", f.nextID)
+		f.records[rec.ID] = rec
+		reply(rec)
+	case r.Method == http.MethodDelete:
+		delete(f.records, filepath.Base(r.URL.Path))
+		reply(map[string]string{})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func TestDNSModule_Cloudflare(t *testing.T) {
+	api := &fakeCloudflare{records: map[string]cloudflareRecord{}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	original := cloudflareAPI
+	cloudflareAPI = server.URL
+	t.Cleanup(func() { cloudflareAPI = original })
+	t.Setenv("CLOUDFLARE_API_TOKEN", "test-token")
+
+	L := lua.NewState()
+	defer L.Close()
+	NewDNSModule(L).Register(L)
+
+	err := L.DoString(`
+		local opts = {provider = "cloudflare", zone = "example.com", name = "api", value = {"203.0.113.1", "203.0.113.2"}}
+		local r, err = dns.record(opts)
+		assert(r, err)
+		assert(r.changed and r.action == "created")
+		assert(r.diff:find("+api.example.com 300 IN A 203.0.113.2", 1, true), r.diff)
+
+		r, err = dns.record(opts)
+		assert(r, err)
+		assert(not r.changed, "second apply should be a no-op")
+
+		opts.value = "203.0.113.2"
+		r, err = dns.record(opts)
+		assert(r, err)
+		assert(r.changed and r.action == "updated")
+		assert(r.diff == "-api.example.com 300 IN A 203.0.113.1\n", r.diff)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.records) != 1 {
+		t.Fatalf("expected 1 record left, got %d", len(api.records))
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -87,16 +88,18 @@ type IncusSnapshot struct {
 // Register registra o módulo Incus no Lua
 func (m *IncusModule) Register(L *lua.LState) {
 	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
-		"instance": m.createInstance,
-		"image":    m.createImage,
-		"network":  m.createNetwork,
-		"profile":  m.createProfile,
-		"storage":  m.createStorage,
-		"snapshot": m.createSnapshot,
-		"exec":     m.exec,
-		"list":     m.list,
-		"info":     m.info,
-		"delete":   m.delete,
+		"instance":  m.createInstance,
+		"image":     m.createImage,
+		"network":   m.createNetwork,
+		"profile":   m.createProfile,
+		"storage":   m.createStorage,
+		"snapshot":  m.createSnapshot,
+		"launch":    m.launch,
+		"file_push": m.filePush,
+		"exec":      m.exec,
+		"list":      m.list,
+		"info":      m.info,
+		"delete":    m.delete,
 	})
 
 	L.SetGlobal("incus", mod)
@@ -202,8 +205,12 @@ func (m *IncusModule) createStorage(L *lua.LState) int {
 	return 1
 }
 
-// createSnapshot cria um builder de snapshot
+// createSnapshot cria um builder de snapshot, ou tira o snapshot quando
+// recebe uma tabela de opções
 func (m *IncusModule) createSnapshot(L *lua.LState) int {
+	if opts, ok := L.Get(1).(*lua.LTable); ok {
+		return m.snapshotTable(L, opts)
+	}
 	instance := L.CheckString(1)
 	name := L.CheckString(2)
 
@@ -454,6 +461,21 @@ func (m *IncusModule) delete(L *lua.LState) int {
 		return 2
 	}
 
+	if resourceType == "instance" {
+		// Deleting an instance that's gone already is a no-op
+		_, exists, err := incusInstanceStatus(name)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		if !exists {
+			L.Push(lua.LString(fmt.Sprintf("instance %s does not exist (idempotent)", name)))
+			L.Push(lua.LNil)
+			return 2
+		}
+	}
+
 	cmd := ""
 	switch resourceType {
 	case "instance":
@@ -482,6 +504,7 @@ func (m *IncusModule) delete(L *lua.LState) int {
 		L.Push(lua.LString(err.Error()))
 		return 2
 	}
+	taskctx.ReportChange(L.Context(), taskctx.Change{Module: "incus", Action: "delete", Target: resourceType + " " + name})
 
 	L.Push(lua.LString(result))
 	L.Push(lua.LNil) // Sempre retornar (result, nil) no sucesso
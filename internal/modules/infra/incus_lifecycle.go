@@ -0,0 +1,288 @@
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runIncus runs incus with args on this host. Delegated tasks run it on
+// their agent.
+var runIncus = func(args ...string) (string, error) {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return executeCommand(nil, "incus "+strings.Join(quoted, " "), "")
+}
+
+// incusNotFound tells an error of incus about a missing resource from
+// other failures, including incus itself missing
+func incusNotFound(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not found") && !strings.Contains(msg, "command not found")
+}
+
+// incusInstanceStatus returns the status of an instance, like "Running"
+// or "Stopped", and false if it doesn't exist
+func incusInstanceStatus(name string) (string, bool, error) {
+	out, err := runIncus("query", "/1.0/instances/"+name)
+	if err != nil {
+		if incusNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	var instance struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(out), &instance); err != nil {
+		return "", false, fmt.Errorf("failed to parse instance %s: %w", name, err)
+	}
+	return instance.Status, true, nil
+}
+
+// incusResult pushes the result of an idempotent call: a table with
+// changed and fields, and no error
+func incusResult(L *lua.LState, changed bool, fields map[string]string) int {
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(changed))
+	result.RawSetString("check_mode", lua.LBool(taskctx.CheckMode(L.Context())))
+	for k, v := range fields {
+		result.RawSetString(k, lua.LString(v))
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+func incusError(L *lua.LState, format string, args ...interface{}) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(fmt.Sprintf(format, args...)))
+	return 2
+}
+
+// incusStrings reads a list, or a single string, of opts
+func incusStrings(opts *lua.LTable, key string) []string {
+	switch v := opts.RawGetString(key).(type) {
+	case lua.LString:
+		return []string{string(v)}
+	case *lua.LTable:
+		var items []string
+		v.ForEach(func(_, item lua.LValue) {
+			items = append(items, item.String())
+		})
+		return items
+	}
+	return nil
+}
+
+// incusKeyValues reads a table of opts as sorted key=value pairs
+func incusKeyValues(tbl lua.LValue) []string {
+	t, ok := tbl.(*lua.LTable)
+	if !ok {
+		return nil
+	}
+	var pairs []string
+	t.ForEach(func(k, v lua.LValue) {
+		pairs = append(pairs, k.String()+"="+v.String())
+	})
+	sort.Strings(pairs)
+	return pairs
+}
+
+// launch creates and starts an instance unless it exists, starting
+// it if it's stopped
+// Usage: local result, err = incus.launch({name = "web01", image = "images:debian/12", profiles = {"default", "web"}})
+func (m *IncusModule) launch(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	name := getStringField(L, opts, "name", "")
+	image := getStringField(L, opts, "image", "")
+	start := getBoolField(L, opts, "start", true)
+	if name == "" || image == "" {
+		return incusError(L, "name and image are required")
+	}
+
+	status, exists, err := incusInstanceStatus(name)
+	if err != nil {
+		return incusError(L, "failed to check instance %s: %v", name, err)
+	}
+	if exists {
+		if !start || status != "Stopped" {
+			return incusResult(L, false, map[string]string{"name": name, "status": status})
+		}
+		change := taskctx.Change{Module: "incus", Action: "start", Target: name, Current: "stopped"}
+		if taskctx.RecordChange(L.Context(), change) {
+			return incusResult(L, true, map[string]string{"name": name, "status": status})
+		}
+		if _, err := runIncus("start", name); err != nil {
+			return incusError(L, "failed to start instance %s: %v", name, err)
+		}
+		taskctx.ReportChange(L.Context(), change)
+		return incusResult(L, true, map[string]string{"name": name, "status": "Running"})
+	}
+
+	change := taskctx.Change{Module: "incus", Action: "launch", Target: name, Current: "absent"}
+	if taskctx.RecordChange(L.Context(), change) {
+		return incusResult(L, true, map[string]string{"name": name, "status": "Absent"})
+	}
+
+	args := []string{"launch", image, name}
+	status = "Running"
+	if !start {
+		args[0], status = "init", "Stopped"
+	}
+	for _, profile := range incusStrings(opts, "profiles") {
+		args = append(args, "--profile", profile)
+	}
+	for _, pair := range incusKeyValues(opts.RawGetString("config")) {
+		args = append(args, "--config", pair)
+	}
+	if devices, ok := opts.RawGetString("devices").(*lua.LTable); ok {
+		var names []string
+		devices.ForEach(func(k, _ lua.LValue) {
+			names = append(names, k.String())
+		})
+		sort.Strings(names)
+		for _, device := range names {
+			for _, pair := range incusKeyValues(devices.RawGetString(device)) {
+				args = append(args, "--device", device+","+pair)
+			}
+		}
+	}
+	if storage := getStringField(L, opts, "storage", ""); storage != "" {
+		args = append(args, "--storage", storage)
+	}
+	if network := getStringField(L, opts, "network", ""); network != "" {
+		args = append(args, "--network", network)
+	}
+	if member := getStringField(L, opts, "member", ""); member != "" {
+		args = append(args, "--target", member)
+	}
+	if getBoolField(L, opts, "vm", false) {
+		args = append(args, "--vm")
+	}
+	if getBoolField(L, opts, "ephemeral", false) {
+		args = append(args, "--ephemeral")
+	}
+
+	if _, err := runIncus(args...); err != nil {
+		return incusError(L, "failed to launch instance %s: %v", name, err)
+	}
+	taskctx.ReportChange(L.Context(), change)
+	return incusResult(L, true, map[string]string{"name": name, "status": status})
+}
+
+// filePush writes a file into an instance unless it already has the
+// content
+// Usage: local result, err = incus.file_push({instance = "web01", path = "/etc/motd", content = "hello\n"})
+func (m *IncusModule) filePush(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	instance := getStringField(L, opts, "instance", "")
+	path := getStringField(L, opts, "path", "")
+	src := getStringField(L, opts, "src", "")
+	if instance == "" || !strings.HasPrefix(path, "/") {
+		return incusError(L, "instance and an absolute path are required")
+	}
+
+	var content []byte
+	if v, ok := opts.RawGetString("content").(lua.LString); ok {
+		content = []byte(v)
+	} else if src != "" {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return incusError(L, "failed to read %s: %v", src, err)
+		}
+		content = data
+	} else {
+		return incusError(L, "content or src is required")
+	}
+
+	// A missing file reads as an error, and is pushed like a different one
+	target := instance + path
+	current, err := runIncus("file", "pull", target, "-")
+	if err == nil && current == string(content) {
+		return incusResult(L, false, map[string]string{"instance": instance, "path": path})
+	}
+
+	var before []byte
+	if err == nil {
+		before = []byte(current)
+	}
+	change := taskctx.FileChange("incus", "file_push", target, before, content)
+	if taskctx.RecordChange(L.Context(), change) {
+		return incusResult(L, true, map[string]string{"instance": instance, "path": path})
+	}
+
+	tmp, err := os.CreateTemp("", "sloth-incus-")
+	if err != nil {
+		return incusError(L, "failed to stage %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return incusError(L, "failed to stage %s: %v", path, err)
+	}
+
+	args := []string{"file", "push", tmp.Name(), target, "--mode", getStringField(L, opts, "mode", "0644")}
+	if uid := getIntField(L, opts, "uid", -1); uid >= 0 {
+		args = append(args, "--uid", strconv.Itoa(uid))
+	}
+	if gid := getIntField(L, opts, "gid", -1); gid >= 0 {
+		args = append(args, "--gid", strconv.Itoa(gid))
+	}
+	if getBoolField(L, opts, "create_dirs", false) {
+		args = append(args, "--create-dirs")
+	}
+	if _, err := runIncus(args...); err != nil {
+		return incusError(L, "failed to push %s: %v", target, err)
+	}
+	taskctx.ReportChange(L.Context(), change)
+	return incusResult(L, true, map[string]string{"instance": instance, "path": path})
+}
+
+// snapshotTable takes a snapshot of an instance unless one of the same
+// name exists
+// Usage: local result, err = incus.snapshot({instance = "db01", name = "pre-upgrade", stateful = false})
+func (m *IncusModule) snapshotTable(L *lua.LState, opts *lua.LTable) int {
+	instance := getStringField(L, opts, "instance", "")
+	name := getStringField(L, opts, "name", "")
+	if instance == "" || name == "" {
+		return incusError(L, "instance and name are required")
+	}
+
+	target := instance + "/" + name
+	_, err := runIncus("query", "/1.0/instances/"+instance+"/snapshots/"+name)
+	if err == nil {
+		return incusResult(L, false, map[string]string{"instance": instance, "name": name})
+	}
+	if !incusNotFound(err) {
+		return incusError(L, "failed to check snapshot %s: %v", target, err)
+	}
+
+	change := taskctx.Change{Module: "incus", Action: "snapshot", Target: target, Current: "absent"}
+	if taskctx.RecordChange(L.Context(), change) {
+		return incusResult(L, true, map[string]string{"instance": instance, "name": name})
+	}
+	args := []string{"snapshot", "create", instance, name}
+	if getBoolField(L, opts, "stateful", false) {
+		args = append(args, "--stateful")
+	}
+	if expiry := getStringField(L, opts, "expiry", ""); expiry != "" {
+		args = append(args, "--expiry", expiry)
+	}
+	if _, err := runIncus(args...); err != nil {
+		return incusError(L, "failed to snapshot %s: %v", instance, err)
+	}
+	taskctx.ReportChange(L.Context(), change)
+	return incusResult(L, true, map[string]string{"instance": instance, "name": name})
+}
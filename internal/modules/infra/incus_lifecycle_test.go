@@ -0,0 +1,162 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeIncus replaces incus with a host whose instances have the given
+// statuses, and whose files and snapshots are in files and snapshots. It
+// returns the commands that changed something.
+func fakeIncus(t *testing.T, instances, files map[string]string, snapshots map[string]bool) *[]string {
+	var commands []string
+	orig := runIncus
+	runIncus = func(args ...string) (string, error) {
+		switch {
+		case args[0] == "query" && strings.Contains(args[1], "/snapshots/"):
+			if snapshots[strings.TrimPrefix(args[1], "/1.0/instances/")] {
+				return "{}", nil
+			}
+			return "", errors.New("command failed: exit status 1 - Error: Snapshot not found")
+		case args[0] == "query":
+			if status, ok := instances[strings.TrimPrefix(args[1], "/1.0/instances/")]; ok {
+				return fmt.Sprintf(`{"name": "x", "status": %q}`, status), nil
+			}
+			return "", errors.New("command failed: exit status 1 - Error: Instance not found")
+		case args[0] == "file" && args[1] == "pull":
+			if content, ok := files[args[2]]; ok {
+				return content, nil
+			}
+			return "", errors.New("command failed: exit status 1 - Error: not found")
+		}
+		commands = append(commands, strings.Join(args, " "))
+		return "", nil
+	}
+	t.Cleanup(func() { runIncus = orig })
+	return &commands
+}
+
+func newIncusState() *lua.LState {
+	L := lua.NewState()
+	NewIncusModule(nil).Register(L)
+	RegisterSnapshotMetatable(L)
+	return L
+}
+
+func TestIncusLaunch(t *testing.T) {
+	commands := fakeIncus(t, map[string]string{"web01": "Running", "db01": "Stopped"}, nil, nil)
+	L := newIncusState()
+	defer L.Close()
+
+	if err := L.DoString(`
+		local result, err = incus.launch({name = "web01", image = "images:debian/12"})
+		assert(err == nil and not result.changed and result.status == "Running", "existing instance changed")
+
+		result, err = incus.launch({name = "db01", image = "images:debian/12"})
+		assert(err == nil and result.changed and result.status == "Running", "stopped instance not started")
+
+		result, err = incus.launch({
+		    name = "app01",
+		    image = "images:debian/12",
+		    profiles = {"default", "web"},
+		    config = {["limits.cpu"] = "2", ["boot.autostart"] = "true"},
+		    devices = {root = {path = "/", pool = "fast"}},
+		    member = "node2",
+		    vm = true,
+		})
+		assert(err == nil and result.changed, "new instance not launched")
+
+		result, err = incus.launch({name = "app02"})
+		assert(result == nil and err ~= nil, "launch without image succeeded")
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"start db01",
+		"launch images:debian/12 app01 --profile default --profile web --config boot.autostart=true --config limits.cpu=2 --device root,path=/ --device root,pool=fast --target node2 --vm",
+	}
+	if fmt.Sprint(*commands) != fmt.Sprint(want) {
+		t.Errorf("Expected %q, got %q", want, *commands)
+	}
+}
+
+func TestIncusFilePush(t *testing.T) {
+	commands := fakeIncus(t, nil, map[string]string{"web01/etc/motd": "hello\n"}, nil)
+	L := newIncusState()
+	defer L.Close()
+
+	if err := L.DoString(`
+		local result, err = incus.file_push({instance = "web01", path = "/etc/motd", content = "hello\n"})
+		assert(err == nil and not result.changed, "same content pushed")
+
+		result, err = incus.file_push({instance = "web01", path = "/etc/app.conf", content = "port = 80\n", mode = "0600", uid = 0, create_dirs = true})
+		assert(err == nil and result.changed, "new file not pushed")
+
+		result, err = incus.file_push({instance = "web01", path = "etc/motd", content = ""})
+		assert(result == nil and err ~= nil, "relative path pushed")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if len(*commands) != 1 || !strings.HasPrefix((*commands)[0], "file push ") || !strings.HasSuffix((*commands)[0], " web01/etc/app.conf --mode 0600 --uid 0 --create-dirs") {
+		t.Errorf("Expected one push of app.conf, got %q", *commands)
+	}
+}
+
+func TestIncusSnapshotAndDelete(t *testing.T) {
+	commands := fakeIncus(t, map[string]string{"db01": "Running"}, nil, map[string]bool{"db01/snapshots/nightly": true})
+	L := newIncusState()
+	defer L.Close()
+
+	if err := L.DoString(`
+		local result, err = incus.snapshot({instance = "db01", name = "nightly"})
+		assert(err == nil and not result.changed, "existing snapshot taken again")
+
+		result, err = incus.snapshot({instance = "db01", name = "pre-upgrade", stateful = true})
+		assert(err == nil and result.changed, "snapshot not taken")
+
+		-- The builder form is kept
+		assert(incus.snapshot("db01", "manual"):stateful(true) ~= nil)
+
+		local out, err = incus.delete({type = "instance", name = "gone"})
+		assert(err == nil and out:find("does not exist"), "missing instance not skipped")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[snapshot create db01 pre-upgrade --stateful]"; fmt.Sprint(*commands) != want {
+		t.Errorf("Expected %s, got %q", want, *commands)
+	}
+}
+
+func TestIncusLaunch_CheckMode(t *testing.T) {
+	commands := fakeIncus(t, nil, map[string]string{"web01/etc/motd": "old\n"}, nil)
+	L := newIncusState()
+	defer L.Close()
+	plan := &taskctx.Plan{}
+	L.SetContext(taskctx.WithPlan(context.Background(), plan))
+
+	if err := L.DoString(`
+		local result = incus.launch({name = "web01", image = "images:debian/12"})
+		assert(result.changed and result.check_mode, "launch not planned")
+		incus.file_push({instance = "web01", path = "/etc/motd", content = "new\n"})
+		incus.snapshot({instance = "web01", name = "before"})
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if len(*commands) != 0 {
+		t.Errorf("Expected check mode to change nothing, got %q", *commands)
+	}
+	var got []string
+	for _, c := range plan.Changes() {
+		got = append(got, c.Action+" "+c.Target)
+	}
+	if want := "launch web01,file_push web01/etc/motd,snapshot web01/before"; strings.Join(got, ",") != want {
+		t.Errorf("Expected plan %s, got %s", want, strings.Join(got, ","))
+	}
+}
@@ -0,0 +1,305 @@
+package infra
+
+import (
+	"context"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Where kernel modules are configured to load at boot
+const (
+	ModulesLoadDir = "/etc/modules-load.d"
+	ModprobeDir    = "/etc/modprobe.d"
+)
+
+// KmodModule loads and unloads kernel modules and makes them load at boot
+type KmodModule struct {
+	L *lua.LState
+	// root is prefixed to /proc, /sys and /etc paths; set by tests
+	root string
+	// modprobe runs modprobe, through sudo when not root
+	modprobe func(ctx context.Context, args ...string) (string, error)
+	// writeFile and removeFile change system files, through sudo when
+	// not root
+	writeFile  func(ctx context.Context, path string, data []byte) error
+	removeFile func(ctx context.Context, path string) error
+}
+
+// NewKmodModule creates a new Kmod module instance
+func NewKmodModule(L *lua.LState) *KmodModule {
+	return &KmodModule{
+		L:          L,
+		modprobe:   runModprobe,
+		writeFile:  writeSystemFile,
+		removeFile: removeSystemFile,
+	}
+}
+
+// Register registers the Kmod module with the Lua state
+func (m *KmodModule) Register(L *lua.LState) {
+	kmodTable := L.NewTable()
+
+	L.SetField(kmodTable, "load", L.NewFunction(m.load))
+	L.SetField(kmodTable, "unload", L.NewFunction(m.unload))
+	L.SetField(kmodTable, "ensure_at_boot", L.NewFunction(m.ensureAtBoot))
+	L.SetField(kmodTable, "loaded", L.NewFunction(m.loaded))
+	L.SetField(kmodTable, "list", L.NewFunction(m.list))
+
+	L.SetGlobal("kmod", kmodTable)
+}
+
+func runModprobe(ctx context.Context, args ...string) (string, error) {
+	args = append([]string{"modprobe"}, args...)
+	if os.Geteuid() != 0 {
+		args = append([]string{"sudo"}, args...)
+	}
+	output, err := taskctx.Command(ctx, args[0], args[1:]...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// kmodOptions are the options of the kmod functions
+type kmodOptions struct {
+	name       string
+	params     []string
+	persistent bool
+}
+
+func kmodOptionsFromTable(opts *lua.LTable) (*kmodOptions, error) {
+	o := &kmodOptions{
+		name:       strings.TrimSpace(lua.LVAsString(opts.RawGetString("name"))),
+		persistent: lua.LVAsBool(opts.RawGetString("persistent")),
+	}
+	if o.name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	switch params := opts.RawGetString("params").(type) {
+	case lua.LString:
+		o.params = strings.Fields(string(params))
+	case *lua.LTable:
+		params.ForEach(func(key, value lua.LValue) {
+			o.params = append(o.params, key.String()+"="+kmodParamValue(value))
+		})
+		sort.Strings(o.params)
+	case *lua.LNilType:
+	default:
+		return nil, fmt.Errorf("params must be a table or a string")
+	}
+	return o, nil
+}
+
+func kmodParamValue(value lua.LValue) string {
+	if b, ok := value.(lua.LBool); ok {
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+	return value.String()
+}
+
+// KmodChange is the outcome of a kmod function
+type KmodChange struct {
+	Changed bool
+	Loaded  bool
+	// Files are the boot configuration files written or removed
+	Files []string
+}
+
+// load loads a kernel module unless it is loaded, and with persistent
+// makes it load at boot:
+//
+//	kmod.load{name = "br_netfilter", params = {debug = 1}, persistent = true}
+//
+// Parameters only apply when the module gets loaded. Returns a table with
+// changed, name and loaded.
+func (m *KmodModule) load(L *lua.LState) int {
+	return m.call(L, func(o *kmodOptions) (*KmodChange, error) {
+		change := &KmodChange{Loaded: true}
+		if !m.isLoaded(o.name) {
+			if out, err := m.modprobe(m.L.Context(), append([]string{o.name}, o.params...)...); err != nil {
+				return nil, fmt.Errorf("failed to load %s: %v: %s", o.name, err, out)
+			}
+			change.Changed = true
+		}
+		if o.persistent {
+			if err := m.writeBootConfig(o, change); err != nil {
+				return nil, err
+			}
+		}
+		return change, nil
+	})
+}
+
+// unload unloads a kernel module if it is loaded, and with persistent
+// removes the files kmod wrote to load it at boot:
+//
+//	kmod.unload{name = "floppy", persistent = true}
+func (m *KmodModule) unload(L *lua.LState) int {
+	return m.call(L, func(o *kmodOptions) (*KmodChange, error) {
+		change := &KmodChange{}
+		if m.isLoaded(o.name) {
+			if out, err := m.modprobe(m.L.Context(), "-r", o.name); err != nil {
+				return nil, fmt.Errorf("failed to unload %s: %v: %s", o.name, err, out)
+			}
+			change.Changed = true
+		}
+		if o.persistent {
+			for _, file := range m.bootFiles(o.name) {
+				path := filepath.Join(m.root, file)
+				content, err := os.ReadFile(path)
+				if err != nil || !isKmodBootFile(content, o.name) {
+					continue
+				}
+				if err := m.removeFile(m.L.Context(), path); err != nil {
+					return nil, fmt.Errorf("failed to remove %s: %w", file, err)
+				}
+				change.Changed = true
+				change.Files = append(change.Files, file)
+			}
+		}
+		return change, nil
+	})
+}
+
+// ensureAtBoot makes a kernel module load at boot without loading it now:
+//
+//	kmod.ensure_at_boot{name = "overlay"}
+func (m *KmodModule) ensureAtBoot(L *lua.LState) int {
+	return m.call(L, func(o *kmodOptions) (*KmodChange, error) {
+		change := &KmodChange{Loaded: m.isLoaded(o.name)}
+		if err := m.writeBootConfig(o, change); err != nil {
+			return nil, err
+		}
+		return change, nil
+	})
+}
+
+// call reads the options of a kmod function, runs it and returns its
+// change as a table
+func (m *KmodModule) call(L *lua.LState, fn func(*kmodOptions) (*KmodChange, error)) int {
+	o, err := kmodOptionsFromTable(L.CheckTable(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	change, err := fn(o)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(change.Changed))
+	result.RawSetString("name", lua.LString(o.name))
+	result.RawSetString("loaded", lua.LBool(change.Loaded))
+	files := L.NewTable()
+	for _, f := range change.Files {
+		files.Append(lua.LString(f))
+	}
+	result.RawSetString("files", files)
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// bootFiles are the files loading a module at boot and setting its
+// parameters
+func (m *KmodModule) bootFiles(name string) []string {
+	return []string{
+		filepath.Join(ModulesLoadDir, name+".conf"),
+		filepath.Join(ModprobeDir, name+".conf"),
+	}
+}
+
+// writeBootConfig writes the files loading a module at boot and, when
+// there are parameters, setting them, leaving those already right
+// untouched
+func (m *KmodModule) writeBootConfig(o *kmodOptions, change *KmodChange) error {
+	files := m.bootFiles(o.name)
+	contents := []string{o.name + "\n"}
+	if len(o.params) > 0 {
+		contents = append(contents, fmt.Sprintf("options %s %s\n", o.name, strings.Join(o.params, " ")))
+	}
+
+	for i, content := range contents {
+		path := filepath.Join(m.root, files[i])
+		current, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", files[i], err)
+		}
+		if bytes.Equal(current, []byte(content)) {
+			continue
+		}
+		if err := m.writeFile(m.L.Context(), path, []byte(content)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", files[i], err)
+		}
+		change.Changed = true
+		change.Files = append(change.Files, files[i])
+	}
+	return nil
+}
+
+// isKmodBootFile reports whether content is that of a boot file kmod
+// writes for module name, and not a file it shouldn't remove
+func isKmodBootFile(content []byte, name string) bool {
+	text := strings.TrimSpace(string(content))
+	return text == name || !strings.Contains(text, "\n") && strings.HasPrefix(text, "options "+name+" ")
+}
+
+// loaded reports whether a kernel module is loaded or built in:
+// kmod.loaded("br_netfilter")
+func (m *KmodModule) loaded(L *lua.LState) int {
+	L.Push(lua.LBool(m.isLoaded(L.CheckString(1))))
+	return 1
+}
+
+// isLoaded checks /sys/module, which lists loaded and built-in modules.
+// Module names use underscores there where modprobe accepts dashes.
+func (m *KmodModule) isLoaded(name string) bool {
+	_, err := os.Stat(filepath.Join(m.root, "/sys/module", strings.ReplaceAll(name, "-", "_")))
+	return err == nil
+}
+
+// list returns the loaded modules, from /proc/modules, as tables with
+// name, size and used_by
+func (m *KmodModule) list(L *lua.LState) int {
+	content, err := os.ReadFile(filepath.Join(m.root, "/proc/modules"))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("failed to read loaded modules: %v", err)))
+		return 2
+	}
+
+	modules := L.NewTable()
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		module := L.NewTable()
+		module.RawSetString("name", lua.LString(fields[0]))
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		module.RawSetString("size", lua.LNumber(size))
+		usedBy := L.NewTable()
+		for _, user := range strings.Split(strings.Trim(fields[3], ","), ",") {
+			if user != "" && user != "-" {
+				usedBy.Append(lua.LString(user))
+			}
+		}
+		module.RawSetString("used_by", usedBy)
+		modules.Append(module)
+	}
+	L.Push(modules)
+	L.Push(lua.LNil)
+	return 2
+}
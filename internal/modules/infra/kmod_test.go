@@ -0,0 +1,132 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// newTestKmodModule returns a kmod module under a temporary root whose
+// modprobe records its calls and loads and unloads modules in /sys/module
+func newTestKmodModule(t *testing.T, L *lua.LState) (*KmodModule, *[]string) {
+	root := t.TempDir()
+	var calls []string
+	m := NewKmodModule(L)
+	m.root = root
+	m.writeFile = writeTestFile
+	m.removeFile = func(_ context.Context, path string) error { return os.Remove(path) }
+	m.modprobe = func(_ context.Context, args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		if args[0] == "-r" {
+			return "", os.RemoveAll(filepath.Join(root, "sys/module", args[1]))
+		}
+		return "", os.MkdirAll(filepath.Join(root, "sys/module", args[0]), 0755)
+	}
+	return m, &calls
+}
+
+func TestKmodModule_LoadUnload(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	m, calls := newTestKmodModule(t, L)
+	m.Register(L)
+
+	err := L.DoString(`
+		local r, err = kmod.load{name = "br_netfilter", params = {debug = 1, enable = true}, persistent = true}
+		assert(r, err)
+		assert(r.changed and r.loaded, "first load should change")
+		assert(#r.files == 2, "both boot files should be written")
+		assert(kmod.loaded("br_netfilter"))
+
+		r, err = kmod.load{name = "br_netfilter", params = {debug = 1, enable = true}, persistent = true}
+		assert(r, err)
+		assert(not r.changed and #r.files == 0, "second load should be a no-op")
+
+		r, err = kmod.unload{name = "br_netfilter", persistent = true}
+		assert(r, err)
+		assert(r.changed and not r.loaded and #r.files == 2, "unload should remove the boot files")
+
+		r, err = kmod.unload{name = "br_netfilter", persistent = true}
+		assert(r, err)
+		assert(not r.changed, "second unload should be a no-op")
+
+		r, err = kmod.load{}
+		assert(r == nil and err == "name is required", err)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"br_netfilter debug=1 enable=1", "-r br_netfilter"}
+	if !reflect.DeepEqual(*calls, want) {
+		t.Errorf("modprobe calls = %q, want %q", *calls, want)
+	}
+	for _, file := range m.bootFiles("br_netfilter") {
+		if _, err := os.Stat(filepath.Join(m.root, file)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", file)
+		}
+	}
+}
+
+func TestKmodModule_EnsureAtBoot(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	m, calls := newTestKmodModule(t, L)
+	m.Register(L)
+
+	// A modprobe.d file kmod did not write is left alone
+	foreign := filepath.Join(m.root, ModprobeDir, "overlay.conf")
+	if err := writeTestFile(context.Background(), foreign, []byte("options overlay metacopy=on\nblacklist foo\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := L.DoString(`
+		local r, err = kmod.ensure_at_boot{name = "overlay"}
+		assert(r, err)
+		assert(r.changed and not r.loaded and #r.files == 1, "boot file should be written")
+
+		r, err = kmod.ensure_at_boot{name = "overlay"}
+		assert(r, err)
+		assert(not r.changed, "second call should be a no-op")
+
+		r, err = kmod.unload{name = "overlay", persistent = true}
+		assert(r, err)
+		assert(r.changed and #r.files == 1, "only the modules-load.d file should be removed")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("modprobe calls = %q, want none", *calls)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("foreign modprobe.d file was removed: %v", err)
+	}
+}
+
+func TestKmodModule_List(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	m, _ := newTestKmodModule(t, L)
+	m.Register(L)
+	modules := "overlay 151552 0 - Live 0x0000000000000000\nbridge 311296 1 br_netfilter, Live 0x0000000000000000\n"
+	if err := writeTestFile(context.Background(), filepath.Join(m.root, "proc/modules"), []byte(modules)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := L.DoString(`
+		local mods, err = kmod.list()
+		assert(mods, err)
+		assert(#mods == 2)
+		assert(mods[1].name == "overlay" and mods[1].size == 151552 and #mods[1].used_by == 0)
+		assert(mods[2].name == "bridge" and mods[2].used_by[1] == "br_netfilter")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
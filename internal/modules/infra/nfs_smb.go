@@ -3,10 +3,10 @@ package infra
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -44,7 +44,7 @@ func (m *NFSSMBModule) Register(L *lua.LState) {
 }
 
 func (m *NFSSMBModule) execCommand(args ...string) (string, error) {
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(m.L.Context(), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	return strings.TrimSpace(string(output)), err
 }
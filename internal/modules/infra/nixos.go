@@ -1,12 +1,14 @@
 package infra
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -234,6 +236,33 @@ func RegisterNixOSModule(L *lua.LState) {
 	L.SetGlobal("nixos", nixosModule)
 }
 
+// writeNixOSFile writes a NixOS configuration file. In check mode the
+// change is recorded in the plan, with its diff, and the file is left
+// untouched.
+func writeNixOSFile(L *lua.LState, path string, content []byte) error {
+	if !taskctx.CheckMode(L.Context()) {
+		return os.WriteFile(path, content, 0644)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(before, content) {
+		taskctx.RecordChange(L.Context(), taskctx.FileChange("nixos", "write", path, before, content))
+	}
+	return nil
+}
+
+// nixosPlanCommand records a command that changes the system in the plan
+// when the task runs in check mode, where it isn't run, and pushes the
+// result of the call. ok is false outside check mode.
+func nixosPlanCommand(L *lua.LState, action string, args []string) (n int, ok bool) {
+	command := strings.Join(args, " ")
+	if !taskctx.RecordChange(L.Context(), taskctx.Change{Module: "nixos", Action: action, Target: command}) {
+		return 0, false
+	}
+	L.Push(lua.LBool(true))
+	L.Push(lua.LString("check mode: would run " + command))
+	return 2, true
+}
+
 // nixosAddUser adds a user to NixOS configuration.nix (idempotent)
 // Usage: local success, msg = nixos.add_user({username = "user", groups = {"wheel"}, description = "User"})
 func nixosAddUser(L *lua.LState) int {
@@ -291,7 +320,7 @@ func nixosAddUser(L *lua.LState) int {
 	newContent := insertUserIntoConfig(string(content), userConfig)
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -341,7 +370,7 @@ func nixosRemoveUser(L *lua.LState) int {
 	newContent := removeUserFromConfig(string(content), username)
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -426,7 +455,7 @@ func nixosAddSSHKey(L *lua.LState) int {
 	newContent := addSSHKeyToUser(string(content), username, sshKey)
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -486,7 +515,7 @@ func nixosRemoveSSHKey(L *lua.LState) int {
 	newContent := removeSSHKeyFromUser(string(content), username, sshKey)
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -497,39 +526,6 @@ func nixosRemoveSSHKey(L *lua.LState) int {
 	return 2
 }
 
-// nixosRebuild executes nixos-rebuild switch
-func nixosRebuild(L *lua.LState) int {
-	params := L.OptTable(1, L.NewTable())
-
-	action := getStringField(L, params, "action", "switch")
-	upgrade := getBoolField(L, params, "upgrade", false)
-	useSudo := getBoolField(L, params, "use_sudo", true)
-
-	// Build command
-	var cmdArgs []string
-	if useSudo {
-		cmdArgs = append(cmdArgs, "sudo")
-	}
-	cmdArgs = append(cmdArgs, "nixos-rebuild", action)
-	if upgrade {
-		cmdArgs = append(cmdArgs, "--upgrade")
-	}
-
-	// Execute command
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	output, err := cmd.CombinedOutput()
-
-	if err != nil {
-		L.Push(lua.LBool(false))
-		L.Push(lua.LString(fmt.Sprintf("nixos-rebuild failed: %v\nOutput: %s", err, string(output))))
-		return 2
-	}
-
-	L.Push(lua.LBool(true))
-	L.Push(lua.LString(fmt.Sprintf("nixos-rebuild %s completed successfully", action)))
-	return 2
-}
-
 // nixosGetConfig reads the configuration.nix file
 func nixosGetConfig(L *lua.LState) int {
 	params := L.OptTable(1, L.NewTable())
@@ -564,7 +560,7 @@ func nixosBackupConfig(L *lua.LState) int {
 	}
 
 	// Write backup
-	if err := os.WriteFile(backupPath, content, 0644); err != nil {
+	if err := writeNixOSFile(L, backupPath, content); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write backup: %v", err)))
 		return 2
@@ -609,7 +605,7 @@ func nixosAddPackage(L *lua.LState) int {
 	newContent := addPackageToConfig(configStr, packageName)
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -654,7 +650,7 @@ func nixosRemovePackage(L *lua.LState) int {
 	newContent := removePackageFromConfig(configStr, packageName)
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -876,7 +872,7 @@ func nixosEnableService(L *lua.LState) int {
 	if re.MatchString(configStr) {
 		// Change false to true
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf("services.%s.enable = true", serviceName))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -890,7 +886,7 @@ func nixosEnableService(L *lua.LState) int {
 	serviceConfig := fmt.Sprintf("  services.%s.enable = true;\n", serviceName)
 	newContent := addLineToConfig(configStr, serviceConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -938,7 +934,7 @@ func nixosDisableService(L *lua.LState) int {
 	if re.MatchString(configStr) {
 		// Change true to false
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf("services.%s.enable = false", serviceName))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -994,7 +990,7 @@ func nixosSetHostname(L *lua.LState) int {
 	re := regexp.MustCompile(hostnamePattern)
 	if re.MatchString(configStr) {
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf(`networking.hostName = "%s"`, hostname))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -1008,7 +1004,7 @@ func nixosSetHostname(L *lua.LState) int {
 	hostnameConfig := fmt.Sprintf("  networking.hostName = \"%s\";\n", hostname)
 	newContent := addLineToConfig(configStr, hostnameConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1055,7 +1051,7 @@ func nixosSetTimezone(L *lua.LState) int {
 	re := regexp.MustCompile(timezonePattern)
 	if re.MatchString(configStr) {
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf(`time.timeZone = "%s"`, timezone))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -1069,7 +1065,7 @@ func nixosSetTimezone(L *lua.LState) int {
 	timezoneConfig := fmt.Sprintf("  time.timeZone = \"%s\";\n", timezone)
 	newContent := addLineToConfig(configStr, timezoneConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1116,7 +1112,7 @@ func nixosSetLocale(L *lua.LState) int {
 	re := regexp.MustCompile(localePattern)
 	if re.MatchString(configStr) {
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf(`i18n.defaultLocale = "%s"`, locale))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -1130,7 +1126,7 @@ func nixosSetLocale(L *lua.LState) int {
 	localeConfig := fmt.Sprintf("  i18n.defaultLocale = \"%s\";\n", locale)
 	newContent := addLineToConfig(configStr, localeConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1175,7 +1171,7 @@ func nixosEnableFirewall(L *lua.LState) int {
 	re := regexp.MustCompile(firewallPattern)
 	if re.MatchString(configStr) {
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf("networking.firewall.enable = %s", enableStr))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -1189,7 +1185,7 @@ func nixosEnableFirewall(L *lua.LState) int {
 	firewallConfig := fmt.Sprintf("  networking.firewall.enable = %s;\n", enableStr)
 	newContent := addLineToConfig(configStr, firewallConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1245,7 +1241,7 @@ func nixosAddFirewallPort(L *lua.LState) int {
 	if re.MatchString(configStr) {
 		// Add port to existing list
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf("${1}${2} %d${3}", port))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -1259,7 +1255,7 @@ func nixosAddFirewallPort(L *lua.LState) int {
 	portsConfig := fmt.Sprintf("  networking.firewall.%s = [ %d ];\n", portField, port)
 	newContent := addLineToConfig(configStr, portsConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1294,7 +1290,11 @@ func nixosCollectGarbage(L *lua.LState) int {
 	}
 
 	// Execute command
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	if n, ok := nixosPlanCommand(L, "collect_garbage", cmdArgs); ok {
+		return n
+	}
+
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -1322,7 +1322,11 @@ func nixosOptimizeStore(L *lua.LState) int {
 	cmdArgs = append(cmdArgs, "nix-store", "--optimize")
 
 	// Execute command
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	if n, ok := nixosPlanCommand(L, "optimize_store", cmdArgs); ok {
+		return n
+	}
+
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -1354,7 +1358,11 @@ func nixosUpdateChannels(L *lua.LState) int {
 	cmdArgs = append(cmdArgs, "nix-channel", "--update")
 
 	// Execute command
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	if n, ok := nixosPlanCommand(L, "update_channels", cmdArgs); ok {
+		return n
+	}
+
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -1382,7 +1390,7 @@ func nixosListChannels(L *lua.LState) int {
 	cmdArgs = append(cmdArgs, "nix-channel", "--list")
 
 	// Execute command
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -1464,7 +1472,7 @@ func nixosSetBootloader(L *lua.LState) int {
 	// Add new bootloader config
 	newContent := addLineToConfig(configStr, bootloaderConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1516,7 +1524,7 @@ func nixosAddImport(L *lua.LState) int {
 	if re.MatchString(configStr) {
 		// Add to existing imports list
 		newContent := re.ReplaceAllString(configStr, fmt.Sprintf("${1}${2}    %s\n  ${3}", importPath))
-		if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+		if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 			L.Push(lua.LBool(false))
 			L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 			return 2
@@ -1530,7 +1538,7 @@ func nixosAddImport(L *lua.LState) int {
 	importsConfig := fmt.Sprintf("  imports = [\n    %s\n  ];\n", importPath)
 	newContent := addLineToConfig(configStr, importsConfig)
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1577,7 +1585,7 @@ func nixosRemoveImport(L *lua.LState) int {
 	re := regexp.MustCompile(linePattern)
 	newContent := re.ReplaceAllString(configStr, "")
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1653,7 +1661,7 @@ func nixosConfigureService(L *lua.LState) int {
 	}
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1818,7 +1826,7 @@ func nixosConfigureUser(L *lua.LState) int {
 	}
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -1970,7 +1978,7 @@ func nixosConfigureNetworking(L *lua.LState) int {
 	}
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2135,7 +2143,7 @@ func nixosConfigureSystem(L *lua.LState) int {
 	}
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(configStr), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(configStr)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2249,7 +2257,7 @@ func nixosConfigureEnvironment(L *lua.LState) int {
 	}
 
 	// Write back to config
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2330,7 +2338,7 @@ func nixosCreateSystemdService(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, serviceConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2380,7 +2388,7 @@ func nixosCreateSystemdTimer(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, timerConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2429,7 +2437,7 @@ func nixosCreateSystemdMount(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, mountConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2474,7 +2482,7 @@ func nixosConfigureZFS(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, zfsConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2522,7 +2530,7 @@ func nixosConfigureFilesystem(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, fsConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2580,7 +2588,7 @@ func nixosConfigureContainer(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, containerConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2624,7 +2632,7 @@ func nixosConfigureDocker(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, dockerConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2671,7 +2679,7 @@ func nixosConfigureVLAN(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, vlanConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2729,7 +2737,7 @@ func nixosConfigureBridge(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, bridgeConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2775,7 +2783,7 @@ func nixosConfigureVPN(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, vpnConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2823,7 +2831,7 @@ func nixosConfigureSecurity(L *lua.LState) int {
 
 	newContent := addLineToConfig(configStr, securityConfig.String())
 
-	if err := os.WriteFile(configPath, []byte(newContent), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(newContent)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -2849,7 +2857,7 @@ func nixosListGenerations(L *lua.LState) int {
 	}
 	cmdArgs = append(cmdArgs, "nix-env", "--list-generations", "-p", "/nix/var/nix/profiles/system")
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -2874,7 +2882,11 @@ func nixosRollback(L *lua.LState) int {
 	}
 	cmdArgs = append(cmdArgs, "nixos-rebuild", "switch", "--rollback")
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	if n, ok := nixosPlanCommand(L, "rollback", cmdArgs); ok {
+		return n
+	}
+
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -2907,7 +2919,11 @@ func nixosSwitchGeneration(L *lua.LState) int {
 	}
 	cmdArgs = append(cmdArgs, "nix-env", "-p", "/nix/var/nix/profiles/system", "--switch-generation", fmt.Sprintf("%d", generation))
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	if n, ok := nixosPlanCommand(L, "switch_generation", cmdArgs); ok {
+		return n
+	}
+
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -2923,7 +2939,7 @@ func nixosSwitchGeneration(L *lua.LState) int {
 		cmdArgs = []string{"/nix/var/nix/profiles/system/bin/switch-to-configuration", "switch"}
 	}
 
-	cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd = taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err = cmd.CombinedOutput()
 
 	if err != nil {
@@ -2956,7 +2972,11 @@ func nixosDeleteGenerations(L *lua.LState) int {
 	}
 	cmdArgs = append(cmdArgs, "nix-env", "-p", "/nix/var/nix/profiles/system", "--delete-generations", olderThan)
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	if n, ok := nixosPlanCommand(L, "delete_generations", cmdArgs); ok {
+		return n
+	}
+
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -2985,7 +3005,11 @@ func nixosTestConfig(L *lua.LState) int {
 	}
 	cmdArgs = append(cmdArgs, "nixos-rebuild", "test", "--fast")
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	if n, ok := nixosPlanCommand(L, "test_config", cmdArgs); ok {
+		return n
+	}
+
+	cmd := taskctx.Command(L.Context(), cmdArgs[0], cmdArgs[1:]...)
 	output, err := cmd.CombinedOutput()
 
 	if err != nil {
@@ -3255,7 +3279,7 @@ func nixosConfigureBoot(L *lua.LState) int {
 	}
 
 	// Write back
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3354,7 +3378,7 @@ func nixosConfigureHardware(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + hwConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3435,7 +3459,7 @@ func nixosConfigureKernel(L *lua.LState) int {
 		}
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3507,7 +3531,7 @@ func nixosConfigureLibvirt(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + libvirtConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3586,7 +3610,7 @@ func nixosCreateVM(L *lua.LState) int {
 	// Add to configuration
 	config = strings.TrimSuffix(config, "}\n") + "\n" + vmConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3690,7 +3714,7 @@ func nixosConfigureBackup(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + backupConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3708,6 +3732,9 @@ func nixosCreateSnapshot(L *lua.LState) int {
 	description := getStringField(L, params, "description", "Manual snapshot")
 
 	// Create snapshot by running nixos-rebuild boot
+	if n, ok := nixosPlanCommand(L, "create_snapshot", []string{"nixos-rebuild", "boot"}); ok {
+		return n
+	}
 	cmd := exec.Command("nixos-rebuild", "boot")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -3793,7 +3820,7 @@ func nixosConfigureMonitoring(L *lua.LState) int {
 	// Add to configuration
 	config = strings.TrimSuffix(config, "}\n") + "\n" + monitoringConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3847,7 +3874,7 @@ func nixosConfigureJournald(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + journaldConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3900,7 +3927,7 @@ func nixosConfigureLogrotate(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + logrotateConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -3958,7 +3985,7 @@ func nixosConfigureXServer(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + xserverConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4002,7 +4029,7 @@ func nixosConfigureDesktop(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + desktopConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4050,7 +4077,7 @@ func nixosConfigureDisplayManager(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + dmConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4091,7 +4118,7 @@ func nixosConfigureAudio(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + audioConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4160,7 +4187,7 @@ func nixosConfigurePostgreSQL(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + pgConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4217,7 +4244,7 @@ func nixosConfigureMySQL(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + mysqlConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4267,7 +4294,7 @@ func nixosConfigureRedis(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + redisConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4318,7 +4345,7 @@ func nixosConfigureMongoDB(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + mongoConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4381,7 +4408,7 @@ func nixosConfigureApache(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + apacheConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4445,7 +4472,7 @@ func nixosConfigureCaddy(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + caddyConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4497,7 +4524,7 @@ func nixosConfigureMailServer(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + mailConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4545,7 +4572,7 @@ func nixosConfigureDNS(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + dnsConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4586,7 +4613,7 @@ func nixosConfigureDHCP(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + dhcpConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4634,7 +4661,7 @@ func nixosConfigureNFS(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + nfsConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4694,7 +4721,7 @@ func nixosConfigureSamba(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + sambaConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4748,7 +4775,7 @@ func nixosConfigureLDAP(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + ldapConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4817,7 +4844,7 @@ func nixosConfigureACME(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + acmeConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4854,7 +4881,7 @@ func nixosAddCertificate(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + certConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4899,7 +4926,7 @@ func nixosConfigureHAProxy(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + haproxyConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4948,7 +4975,7 @@ func nixosConfigureTraefik(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + traefikConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -4985,7 +5012,7 @@ func nixosConfigureSquid(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + squidConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -5033,7 +5060,7 @@ func nixosConfigureOpenVPN(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + ovpnConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -5078,7 +5105,7 @@ func nixosConfigureK3s(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + k3sConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -5129,7 +5156,7 @@ func nixosConfigureGitLabRunner(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + gitlabConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -5180,7 +5207,7 @@ func nixosImportConfiguration(L *lua.LState) int {
 	mergedConfig += "\n}\n"
 
 	// Write back
-	if err := os.WriteFile(configPath, []byte(mergedConfig), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(mergedConfig)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -5221,7 +5248,7 @@ func nixosExportModule(L *lua.LState) int {
 `, configPath, string(content))
 
 	// Write to output file
-	if err := os.WriteFile(outputFile, []byte(module), 0644); err != nil {
+	if err := writeNixOSFile(L, outputFile, []byte(module)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write module: %v", err)))
 		return 2
@@ -5299,7 +5326,7 @@ func nixosConfigurePerformance(L *lua.LState) int {
 
 	config = strings.TrimSuffix(config, "}\n") + "\n" + perfConfig.String() + "}\n"
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -5349,7 +5376,7 @@ func nixosConfigureSwap(L *lua.LState) int {
 		config = strings.TrimSuffix(config, "}\n") + "\n" + swapConfig.String() + "}\n"
 	}
 
-	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+	if err := writeNixOSFile(L, configPath, []byte(config)); err != nil {
 		L.Push(lua.LBool(false))
 		L.Push(lua.LString(fmt.Sprintf("failed to write config: %v", err)))
 		return 2
@@ -0,0 +1,211 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// nixosRebuildApproval confirms rebuilds in runs that cannot prompt, e.g.
+// on agents: run --approve nixos-rebuild
+const nixosRebuildApproval = "nixos-rebuild"
+
+// unitChangePattern matches the unit lists switch-to-configuration prints,
+// both when activating ("restarting the following units: ...") and in
+// dry-activate ("would restart the following units: ...")
+var unitChangePattern = regexp.MustCompile(`^(?:would )?(NOT )?(stop|restart|reload|start)(?:ping|ing)? the following (?:changed )?units: (.+)$`)
+
+// unitChanges lists the systemd units a generation switch affects
+type unitChanges struct {
+	Stop    []string
+	Restart []string
+	Reload  []string
+	Start   []string
+	// Skipped lists changed units that are not restarted
+	Skipped []string
+}
+
+// parseUnitChanges extracts the affected units from nixos-rebuild output
+func parseUnitChanges(output string) unitChanges {
+	var changes unitChanges
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "the following new units were started: ") {
+			changes.Start = append(changes.Start, splitUnits(strings.TrimPrefix(line, "the following new units were started: "))...)
+			continue
+		}
+		m := unitChangePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		units := splitUnits(m[3])
+		switch {
+		case m[1] != "":
+			changes.Skipped = append(changes.Skipped, units...)
+		case m[2] == "stop":
+			changes.Stop = append(changes.Stop, units...)
+		case m[2] == "restart":
+			changes.Restart = append(changes.Restart, units...)
+		case m[2] == "reload":
+			changes.Reload = append(changes.Reload, units...)
+		case m[2] == "start":
+			changes.Start = append(changes.Start, units...)
+		}
+	}
+	return changes
+}
+
+func splitUnits(list string) []string {
+	var units []string
+	for _, unit := range strings.Split(list, ",") {
+		if unit = strings.TrimSpace(unit); unit != "" {
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// Summary describes the changes for the user
+func (c unitChanges) Summary() string {
+	rows := []struct {
+		label string
+		units []string
+	}{
+		{"stop", c.Stop},
+		{"restart", c.Restart},
+		{"reload", c.Reload},
+		{"start", c.Start},
+		{"not restarted", c.Skipped},
+	}
+	var b strings.Builder
+	for _, row := range rows {
+		if len(row.units) > 0 {
+			fmt.Fprintf(&b, "  %-14s %s\n", row.label+":", strings.Join(row.units, ", "))
+		}
+	}
+	if b.Len() == 0 {
+		return "Switching generations affects no systemd units\n"
+	}
+	return "Switching generations affects these systemd units:\n" + b.String()
+}
+
+func (c unitChanges) toLua(L *lua.LState) *lua.LTable {
+	tbl := L.NewTable()
+	set := func(key string, units []string) {
+		list := L.NewTable()
+		for _, unit := range units {
+			list.Append(lua.LString(unit))
+		}
+		tbl.RawSetString(key, list)
+	}
+	set("stop", c.Stop)
+	set("restart", c.Restart)
+	set("reload", c.Reload)
+	set("start", c.Start)
+	set("skipped", c.Skipped)
+	return tbl
+}
+
+// nixosRebuild runs nixos-rebuild, streaming its output live. With
+// confirm = true, activating actions first run dry-activate to show which
+// units will change and only switch once confirmed.
+func nixosRebuild(L *lua.LState) int {
+	params := L.OptTable(1, L.NewTable())
+
+	action := getStringField(L, params, "action", "switch")
+	upgrade := getBoolField(L, params, "upgrade", false)
+	useSudo := getBoolField(L, params, "use_sudo", true)
+	confirm := getBoolField(L, params, "confirm", false)
+
+	if n, ok := nixosPlanCommand(L, "rebuild", nixosRebuildArgs(useSudo, action, upgrade)); ok {
+		return n
+	}
+
+	ctx := L.Context()
+	out := taskctx.Output(ctx)
+	result := L.NewTable()
+	result.RawSetString("action", lua.LString(action))
+
+	activates := action == "switch" || action == "test" || action == "boot"
+	if confirm && activates {
+		// boot only takes effect on the next boot, so no unit changes
+		if action != "boot" {
+			output, err := runNixosRebuild(ctx, out, nixosRebuildArgs(useSudo, "dry-activate", upgrade))
+			if err != nil {
+				L.Push(lua.LBool(false))
+				L.Push(lua.LString(fmt.Sprintf("nixos-rebuild dry-activate failed: %v\nOutput: %s", err, output)))
+				L.Push(result)
+				return 3
+			}
+			planned := parseUnitChanges(output)
+			result.RawSetString("planned", planned.toLua(L))
+			io.WriteString(out, planned.Summary())
+		}
+
+		if ok, reason := confirmNixosRebuild(ctx, action); !ok {
+			L.Push(lua.LBool(false))
+			L.Push(lua.LString(reason))
+			L.Push(result)
+			return 3
+		}
+	}
+
+	output, err := runNixosRebuild(ctx, out, nixosRebuildArgs(useSudo, action, upgrade))
+	if err != nil {
+		L.Push(lua.LBool(false))
+		L.Push(lua.LString(fmt.Sprintf("nixos-rebuild failed: %v\nOutput: %s", err, output)))
+		L.Push(result)
+		return 3
+	}
+	result.RawSetString("units", parseUnitChanges(output).toLua(L))
+
+	L.Push(lua.LBool(true))
+	L.Push(lua.LString(fmt.Sprintf("nixos-rebuild %s completed successfully", action)))
+	L.Push(result)
+	return 3
+}
+
+// confirmNixosRebuild asks the user to confirm, or checks the run's
+// approvals when nobody can be asked
+func confirmNixosRebuild(ctx context.Context, action string) (bool, string) {
+	if taskctx.Approved(ctx, nixosRebuildApproval) {
+		return true, ""
+	}
+	if ask := taskctx.Confirm(ctx); ask != nil {
+		if ask(fmt.Sprintf("Run nixos-rebuild %s?", action)) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("nixos-rebuild %s cancelled by user", action)
+	}
+	return false, fmt.Sprintf("nixos-rebuild %s requires confirmation; approve it with --approve %s", action, nixosRebuildApproval)
+}
+
+func nixosRebuildArgs(useSudo bool, action string, upgrade bool) []string {
+	var args []string
+	if useSudo {
+		args = append(args, "sudo")
+	}
+	args = append(args, "nixos-rebuild", action)
+	if upgrade {
+		args = append(args, "--upgrade")
+	}
+	return args
+}
+
+// runNixosRebuild runs the command, copying its output to out as it is
+// produced, and returns the combined output
+func runNixosRebuild(ctx context.Context, out io.Writer, args []string) (string, error) {
+	cmd := taskctx.Command(ctx, args[0], args[1:]...)
+	var buf bytes.Buffer
+	w := io.MultiWriter(&buf, out)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	return buf.String(), err
+}
@@ -0,0 +1,72 @@
+package infra
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+)
+
+func TestParseUnitChanges(t *testing.T) {
+	dryActivate := `building the system configuration...
+would stop the following units: old-app.service
+would NOT stop the following changed units: systemd-fsck@dev-disk.service
+would restart systemd
+would restart the following units: nginx.service, sshd.service
+would reload the following units: dbus.service
+would start the following units: new-app.service
+`
+	changes := parseUnitChanges(dryActivate)
+	want := unitChanges{
+		Stop:    []string{"old-app.service"},
+		Restart: []string{"nginx.service", "sshd.service"},
+		Reload:  []string{"dbus.service"},
+		Start:   []string{"new-app.service"},
+		Skipped: []string{"systemd-fsck@dev-disk.service"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Unexpected dry-activate changes: %+v", changes)
+	}
+
+	switchOutput := `activating the configuration...
+stopping the following units: old-app.service
+NOT restarting the following changed units: systemd-fsck@dev-disk.service
+restarting the following units: nginx.service
+reloading the following units: dbus.service
+the following new units were started: new-app.service
+`
+	changes = parseUnitChanges(switchOutput)
+	if !reflect.DeepEqual(changes.Restart, []string{"nginx.service"}) || !reflect.DeepEqual(changes.Start, []string{"new-app.service"}) {
+		t.Errorf("Unexpected switch changes: %+v", changes)
+	}
+
+	summary := want.Summary()
+	if !strings.Contains(summary, "restart:       nginx.service, sshd.service") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+	if s := (unitChanges{}).Summary(); !strings.Contains(s, "affects no systemd units") {
+		t.Errorf("Unexpected empty summary: %s", s)
+	}
+}
+
+func TestConfirmNixosRebuild(t *testing.T) {
+	ctx := context.Background()
+	if ok, reason := confirmNixosRebuild(ctx, "switch"); ok || !strings.Contains(reason, "--approve nixos-rebuild") {
+		t.Errorf("Expected rebuild without a prompt or approval to be refused, got %v %q", ok, reason)
+	}
+
+	if ok, _ := confirmNixosRebuild(taskctx.WithApprovals(ctx, []string{"nixos-rebuild"}), "switch"); !ok {
+		t.Error("Expected approved rebuild to be confirmed")
+	}
+
+	asked := ""
+	deny := taskctx.WithConfirm(ctx, func(message string) bool {
+		asked = message
+		return false
+	})
+	if ok, _ := confirmNixosRebuild(deny, "switch"); ok || asked != "Run nixos-rebuild switch?" {
+		t.Errorf("Expected the user to be asked and decline, got %v %q", ok, asked)
+	}
+}
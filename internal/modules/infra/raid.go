@@ -2,10 +2,10 @@ package infra
 
 import (
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -56,7 +56,7 @@ func (m *RAIDModule) Register(L *lua.LState) {
 
 // execCommand executes a command and returns output
 func (m *RAIDModule) execCommand(args ...string) (string, error) {
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(m.L.Context(), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	return strings.TrimSpace(string(output)), err
 }
@@ -1,23 +1,32 @@
 package infra
 
 import (
+	"context"
+	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	lua "github.com/yuin/gopher-lua"
 )
 
+// DefaultSysctlFile is where persistent kernel parameters are written
+const DefaultSysctlFile = "/etc/sysctl.d/99-sloth-runner.conf"
+
 // SysctlModule provides kernel parameter management
 type SysctlModule struct {
 	L *lua.LState
+	// root is prefixed to /proc and /etc paths; set by tests
+	root string
+	// writeFile writes a system file, through sudo when not root
+	writeFile func(ctx context.Context, path string, data []byte) error
 }
 
 // NewSysctlModule creates a new Sysctl module instance
 func NewSysctlModule(L *lua.LState) *SysctlModule {
-	return &SysctlModule{L: L}
+	return &SysctlModule{L: L, writeFile: writeSystemFile}
 }
 
 // Register registers the Sysctl module with the Lua state
@@ -36,7 +45,7 @@ func (m *SysctlModule) Register(L *lua.LState) {
 }
 
 func (m *SysctlModule) execCommand(args ...string) (string, error) {
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := taskctx.Command(m.L.Context(), args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	return strings.TrimSpace(string(output)), err
 }
@@ -73,8 +82,16 @@ func (m *SysctlModule) get(L *lua.LState) int {
 	return 2
 }
 
-// set sets a kernel parameter (runtime only)
+// set sets a kernel parameter. With a table it is idempotent and can
+// persist the parameter:
+//
+//	sysctl.set{key = "vm.swappiness", value = 10, persistent = true}
+//
+// Otherwise, sysctl.set(param, value), it sets the runtime value only.
 func (m *SysctlModule) set(L *lua.LState) int {
+	if opts, ok := L.Get(1).(*lua.LTable); ok {
+		return m.setTable(L, opts)
+	}
 	param := L.CheckString(1)
 	value := L.CheckAny(2)
 
@@ -119,11 +136,156 @@ func (m *SysctlModule) set(L *lua.LState) int {
 	return 2
 }
 
+// setTable sets the runtime value of a kernel parameter and, with
+// persistent, writes it to a file of /etc/sysctl.d so it is set at boot.
+// Nothing is written when the values already match. Returns a table with
+// changed, key, value and previous.
+func (m *SysctlModule) setTable(L *lua.LState, opts *lua.LTable) int {
+	key := strings.TrimSpace(lua.LVAsString(opts.RawGetString("key")))
+	if key == "" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("key is required"))
+		return 2
+	}
+	value, err := sysctlValue(opts.RawGetString("value"))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	change, err := m.setParameter(key, value, lua.LVAsBool(opts.RawGetString("persistent")), lua.LVAsString(opts.RawGetString("file")))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(change.Changed))
+	result.RawSetString("key", lua.LString(key))
+	result.RawSetString("value", lua.LString(value))
+	result.RawSetString("previous", lua.LString(change.Previous))
+	if change.File != "" {
+		result.RawSetString("file", lua.LString(change.File))
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// SysctlChange is the outcome of setting a kernel parameter
+type SysctlChange struct {
+	Changed  bool
+	Previous string
+	// File is where the parameter was persisted
+	File string
+}
+
+// setParameter sets the runtime value of key and, when persistent, its
+// line in file
+func (m *SysctlModule) setParameter(key, value string, persistent bool, file string) (*SysctlChange, error) {
+	procPath := filepath.Join(m.root, "/proc/sys", strings.ReplaceAll(key, ".", "/"))
+	current, err := os.ReadFile(procPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("unknown kernel parameter %s", key)
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	change := &SysctlChange{Previous: normalizeSysctl(string(current))}
+	if change.Previous != normalizeSysctl(value) {
+		if err := m.writeFile(m.L.Context(), procPath, []byte(value+"\n")); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", key, err)
+		}
+		change.Changed = true
+	}
+
+	if persistent {
+		if file == "" {
+			file = DefaultSysctlFile
+		}
+		change.File = file
+		path := filepath.Join(m.root, file)
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		updated := setSysctlLine(content, key, value)
+		if !bytes.Equal(updated, content) {
+			if err := m.writeFile(m.L.Context(), path, updated); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", file, err)
+			}
+			change.Changed = true
+		}
+	}
+	return change, nil
+}
+
+// sysctlValue converts a Lua value to a kernel parameter value
+func sysctlValue(value lua.LValue) (string, error) {
+	switch v := value.(type) {
+	case lua.LString:
+		return strings.TrimSpace(string(v)), nil
+	case lua.LNumber:
+		return v.String(), nil
+	case lua.LBool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case *lua.LTable:
+		// Multi-valued parameters, e.g. net.ipv4.tcp_rmem
+		var fields []string
+		for i := 1; i <= v.Len(); i++ {
+			fields = append(fields, v.RawGetInt(i).String())
+		}
+		return strings.Join(fields, " "), nil
+	case *lua.LNilType:
+		return "", fmt.Errorf("value is required")
+	}
+	return "", fmt.Errorf("unsupported value type %s", value.Type())
+}
+
+// normalizeSysctl collapses the whitespace between the fields of a value,
+// as the kernel separates them with tabs
+func normalizeSysctl(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// setSysctlLine sets key to value in the content of a sysctl.d file,
+// replacing its last assignment or appending one. Other lines are kept.
+func setSysctlLine(content []byte, key, value string) []byte {
+	line := key + " = " + value
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(content) == 0 {
+		lines = nil
+	}
+	last := -1
+	for i, l := range lines {
+		name, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(l), "-"), "=")
+		if ok && strings.TrimSpace(name) == key {
+			last = i
+		}
+	}
+	if last >= 0 {
+		_, current, _ := strings.Cut(lines[last], "=")
+		if normalizeSysctl(current) == normalizeSysctl(value) {
+			return content
+		}
+		lines[last] = line
+	} else {
+		lines = append(lines, line)
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
 // setPersistent sets a kernel parameter persistently in /etc/sysctl.conf or /etc/sysctl.d/
 func (m *SysctlModule) setPersistent(L *lua.LState) int {
 	param := L.CheckString(1)
 	value := L.CheckAny(2)
-	configFile := "/etc/sysctl.d/99-sloth-runner.conf"
+	configFile := DefaultSysctlFile
 	if L.GetTop() >= 3 {
 		configFile = L.CheckString(3)
 	}
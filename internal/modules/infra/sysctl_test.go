@@ -0,0 +1,89 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// writeTestFile stands in for writeSystemFile under a temporary root
+func writeTestFile(_ context.Context, path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func TestSysctlModule_SetTable(t *testing.T) {
+	root := t.TempDir()
+	if err := writeTestFile(context.Background(), filepath.Join(root, "proc/sys/vm/swappiness"), []byte("60\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTestFile(context.Background(), filepath.Join(root, "proc/sys/net/ipv4/tcp_rmem"), []byte("4096\t131072\t6291456\n")); err != nil {
+		t.Fatal(err)
+	}
+	confPath := filepath.Join(root, DefaultSysctlFile)
+	if err := writeTestFile(context.Background(), confPath, []byte("# managed\nvm.swappiness = 30\nnet.core.somaxconn = 1024\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	m := NewSysctlModule(L)
+	m.root = root
+	m.writeFile = writeTestFile
+	m.Register(L)
+
+	err := L.DoString(`
+		local r, err = sysctl.set{key = "vm.swappiness", value = 10, persistent = true}
+		assert(r, err)
+		assert(r.changed, "first set should change")
+		assert(r.previous == "60" and r.value == "10", r.previous .. " " .. r.value)
+
+		r, err = sysctl.set{key = "vm.swappiness", value = "10", persistent = true}
+		assert(r, err)
+		assert(not r.changed, "second set should be a no-op")
+
+		r, err = sysctl.set{key = "net.ipv4.tcp_rmem", value = {4096, 131072, 6291456}}
+		assert(r, err)
+		assert(not r.changed, "tab separated values should match")
+
+		r, err = sysctl.set{key = "vm.nope", value = 1}
+		assert(r == nil and err:find("unknown kernel parameter"), err)
+
+		r, err = sysctl.set{value = 1}
+		assert(r == nil and err == "key is required", err)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, _ := os.ReadFile(filepath.Join(root, "proc/sys/vm/swappiness"))
+	if strings.TrimSpace(string(value)) != "10" {
+		t.Errorf("runtime value = %q, want 10", value)
+	}
+	conf, _ := os.ReadFile(confPath)
+	if want := "# managed\nvm.swappiness = 10\nnet.core.somaxconn = 1024\n"; string(conf) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", DefaultSysctlFile, conf, want)
+	}
+}
+
+func TestSetSysctlLine(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"empty", "", "vm.swappiness = 10\n"},
+		{"append", "fs.file-max = 100\n", "fs.file-max = 100\nvm.swappiness = 10\n"},
+		{"replace last", "vm.swappiness=1\n# x\n-vm.swappiness = 5\n", "vm.swappiness=1\n# x\nvm.swappiness = 10\n"},
+		{"unchanged", "vm.swappiness=10", "vm.swappiness=10"},
+	}
+	for _, tt := range tests {
+		if got := string(setSysctlLine([]byte(tt.content), "vm.swappiness", "10")); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+)
+
+// writeSystemFile writes a file owned by root, such as one of /etc or
+// /proc/sys. Without root it goes through sudo.
+func writeSystemFile(ctx context.Context, path string, data []byte) error {
+	if os.Geteuid() == 0 {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	}
+
+	if out, err := taskctx.Command(ctx, "sudo", "mkdir", "-p", filepath.Dir(path)).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	cmd := taskctx.Command(ctx, "sudo", "tee", path)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// removeSystemFile removes a file owned by root, through sudo when not
+// root. A missing file is not an error.
+func removeSystemFile(ctx context.Context, path string) error {
+	if os.Geteuid() == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if out, err := taskctx.Command(ctx, "sudo", "rm", "-f", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
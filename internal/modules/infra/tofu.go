@@ -0,0 +1,545 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// DefaultTofuPlanFile is where tofu.plan saves its plan, in the working
+// directory, when no out is given
+const DefaultTofuPlanFile = "sloth.tfplan"
+
+// TofuModule drives the OpenTofu CLI, or Terraform's where tofu isn't
+// installed, and parses its JSON plans
+type TofuModule struct {
+	// run runs the CLI in dir and returns its stdout; set by tests
+	run func(ctx context.Context, dir string, env []string, binary string, args ...string) (string, error)
+	// lookPath finds a CLI in PATH; set by tests
+	lookPath func(file string) (string, error)
+}
+
+// NewTofuModule creates a new Tofu module instance
+func NewTofuModule() *TofuModule {
+	return &TofuModule{run: runTofu, lookPath: exec.LookPath}
+}
+
+// Register registers the Tofu module with the Lua state
+func (m *TofuModule) Register(L *lua.LState) {
+	tofuTable := L.NewTable()
+
+	L.SetField(tofuTable, "init", L.NewFunction(m.init))
+	L.SetField(tofuTable, "plan", L.NewFunction(m.plan))
+	L.SetField(tofuTable, "apply", L.NewFunction(m.apply))
+	L.SetField(tofuTable, "output", L.NewFunction(m.output))
+
+	L.SetGlobal("tofu", tofuTable)
+}
+
+func runTofu(ctx context.Context, dir string, env []string, binary string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("%s", msg)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// tofuOptions are the options shared by the tofu functions
+type tofuOptions struct {
+	dir      string
+	binary   string
+	env      []string
+	vars     []string
+	varFiles []string
+	targets  []string
+}
+
+func (m *TofuModule) optionsFromTable(L *lua.LState, opts *lua.LTable) (*tofuOptions, error) {
+	o := &tofuOptions{
+		dir:    getStringField(L, opts, "dir", "."),
+		binary: getStringField(L, opts, "binary", ""),
+		env:    []string{"TF_IN_AUTOMATION=1"},
+	}
+	if o.binary == "" {
+		o.binary = "terraform"
+		if _, err := m.lookPath("tofu"); err == nil {
+			o.binary = "tofu"
+		}
+	}
+	if _, err := os.Stat(o.dir); err != nil {
+		return nil, fmt.Errorf("working directory %s: %w", o.dir, err)
+	}
+
+	if env, ok := opts.RawGetString("env").(*lua.LTable); ok {
+		env.ForEach(func(key, value lua.LValue) {
+			o.env = append(o.env, key.String()+"="+value.String())
+		})
+	}
+	if vars, ok := opts.RawGetString("vars").(*lua.LTable); ok {
+		vars.ForEach(func(key, value lua.LValue) {
+			o.vars = append(o.vars, "-var="+key.String()+"="+tofuVarValue(value))
+		})
+		sort.Strings(o.vars)
+	}
+	o.varFiles = luaStringList(opts.RawGetString("var_files"))
+	o.targets = luaStringList(opts.RawGetString("targets"))
+	return o, nil
+}
+
+// tofuVarValue formats a variable for -var: strings as they are, lists and
+// maps as JSON, which the CLI parses as HCL
+func tofuVarValue(value lua.LValue) string {
+	switch v := value.(type) {
+	case lua.LString:
+		return string(v)
+	case *lua.LTable:
+		data, _ := json.Marshal(luaValueToGo(v))
+		return string(data)
+	default:
+		return v.String()
+	}
+}
+
+// luaStringList reads a list of strings, or a single string, from a field
+func luaStringList(value lua.LValue) []string {
+	var list []string
+	switch v := value.(type) {
+	case lua.LString:
+		list = append(list, string(v))
+	case *lua.LTable:
+		v.ForEach(func(_, item lua.LValue) {
+			list = append(list, item.String())
+		})
+	}
+	return list
+}
+
+// variableArgs are the arguments passing variables and targets to plan
+// and apply
+func (o *tofuOptions) variableArgs() []string {
+	args := append([]string{}, o.vars...)
+	for _, f := range o.varFiles {
+		args = append(args, "-var-file="+f)
+	}
+	for _, t := range o.targets {
+		args = append(args, "-target="+t)
+	}
+	return args
+}
+
+func (m *TofuModule) cli(L *lua.LState, o *tofuOptions, args ...string) (string, error) {
+	ctx := L.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out, err := m.run(ctx, o.dir, o.env, o.binary, args...)
+	if err != nil {
+		return out, fmt.Errorf("%s %s failed: %v", o.binary, args[0], err)
+	}
+	return out, nil
+}
+
+// pushTofuError pushes the nil, error result of a failed call
+func pushTofuError(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// init initializes a working directory, installing its providers and
+// modules and configuring its backend:
+//
+//	tofu.init{dir = "infra/prod", backend_config = {bucket = "state"}, upgrade = true}
+//
+// Returns a table with binary and output.
+func (m *TofuModule) init(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	o, err := m.optionsFromTable(L, opts)
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+
+	args := []string{"init", "-input=false", "-no-color"}
+	if getBoolField(L, opts, "upgrade", false) {
+		args = append(args, "-upgrade")
+	}
+	if getBoolField(L, opts, "reconfigure", false) {
+		args = append(args, "-reconfigure")
+	}
+	if !getBoolField(L, opts, "backend", true) {
+		args = append(args, "-backend=false")
+	}
+	switch backend := opts.RawGetString("backend_config").(type) {
+	case *lua.LTable:
+		var settings []string
+		backend.ForEach(func(key, value lua.LValue) {
+			if _, ok := key.(lua.LNumber); ok {
+				settings = append(settings, "-backend-config="+value.String())
+			} else {
+				settings = append(settings, "-backend-config="+key.String()+"="+value.String())
+			}
+		})
+		sort.Strings(settings)
+		args = append(args, settings...)
+	case lua.LString:
+		args = append(args, "-backend-config="+string(backend))
+	}
+
+	out, err := m.cli(L, o, args...)
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+
+	result := L.NewTable()
+	result.RawSetString("binary", lua.LString(o.binary))
+	result.RawSetString("output", lua.LString(out))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// tofuPlanJSON is the part of the JSON plan (show -json) parsed into
+// resource changes
+type tofuPlanJSON struct {
+	ResourceChanges []struct {
+		Address      string `json:"address"`
+		Mode         string `json:"mode"`
+		Type         string `json:"type"`
+		Name         string `json:"name"`
+		ProviderName string `json:"provider_name"`
+		Change       struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+	OutputChanges map[string]struct {
+		Actions []string `json:"actions"`
+	} `json:"output_changes"`
+}
+
+// TofuResourceChange is a change to a resource in a plan
+type TofuResourceChange struct {
+	Address  string
+	Mode     string
+	Type     string
+	Name     string
+	Provider string
+	// Action is create, update, delete, replace or read
+	Action string
+}
+
+// TofuPlan is a parsed plan
+type TofuPlan struct {
+	// Add, Change and Destroy count resources as the CLI's summary does:
+	// a replaced resource is both added and destroyed
+	Add, Change, Destroy, Replace int
+	Resources                     []TofuResourceChange
+	// Outputs maps the outputs that change to their action
+	Outputs map[string]string
+	// Raw is the whole JSON plan
+	Raw map[string]interface{}
+}
+
+// Changed reports whether applying the plan changes anything
+func (p *TofuPlan) Changed() bool {
+	return p.Add+p.Change+p.Destroy > 0 || len(p.Outputs) > 0
+}
+
+// Summary is the CLI's one-line summary of the plan
+func (p *TofuPlan) Summary() string {
+	if !p.Changed() {
+		return "No changes. Your infrastructure matches the configuration."
+	}
+	return fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy.", p.Add, p.Change, p.Destroy)
+}
+
+// ParseTofuPlan parses the output of show -json for a saved plan
+func ParseTofuPlan(data []byte) (*TofuPlan, error) {
+	var parsed tofuPlanJSON
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	plan := &TofuPlan{Outputs: make(map[string]string)}
+	if err := json.Unmarshal(data, &plan.Raw); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	for _, rc := range parsed.ResourceChanges {
+		action := tofuAction(rc.Change.Actions)
+		switch action {
+		case "no-op", "":
+			continue
+		case "create":
+			plan.Add++
+		case "update":
+			plan.Change++
+		case "delete":
+			plan.Destroy++
+		case "replace":
+			plan.Add++
+			plan.Destroy++
+			plan.Replace++
+		}
+		plan.Resources = append(plan.Resources, TofuResourceChange{
+			Address:  rc.Address,
+			Mode:     rc.Mode,
+			Type:     rc.Type,
+			Name:     rc.Name,
+			Provider: rc.ProviderName,
+			Action:   action,
+		})
+	}
+	for name, oc := range parsed.OutputChanges {
+		if action := tofuAction(oc.Actions); action != "no-op" && action != "" {
+			plan.Outputs[name] = action
+		}
+	}
+	return plan, nil
+}
+
+// tofuAction names the actions of a change: a delete and a create, in
+// either order, are a replacement
+func tofuAction(actions []string) string {
+	if len(actions) == 2 {
+		return "replace"
+	}
+	if len(actions) == 1 {
+		return actions[0]
+	}
+	return ""
+}
+
+// plan creates a plan, saves it and parses it:
+//
+//	local plan, err = tofu.plan{dir = "infra/prod", vars = {region = "us-east-1"}}
+//	if plan.changed then log.info(plan.summary) end
+//
+// Returns a table with changed, add, change, destroy, replace, summary,
+// resources (address, type, name, mode, provider and action of each
+// changed resource), outputs (output name to action), plan_file and plan,
+// the whole JSON plan.
+func (m *TofuModule) plan(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	o, err := m.optionsFromTable(L, opts)
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+
+	plan, planFile, err := m.makePlan(L, o, getStringField(L, opts, "out", DefaultTofuPlanFile), getBoolField(L, opts, "destroy", false))
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+	L.Push(tofuPlanTable(L, plan, planFile))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// makePlan runs plan, saving the plan to out, and parses it
+func (m *TofuModule) makePlan(L *lua.LState, o *tofuOptions, out string, destroy bool) (*TofuPlan, string, error) {
+	planFile := out
+	if !filepath.IsAbs(planFile) {
+		planFile = filepath.Join(o.dir, planFile)
+	}
+	planFile, err := filepath.Abs(planFile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	args := []string{"plan", "-input=false", "-no-color", "-out=" + planFile}
+	if destroy {
+		args = append(args, "-destroy")
+	}
+	if _, err := m.cli(L, o, append(args, o.variableArgs()...)...); err != nil {
+		return nil, "", err
+	}
+	plan, err := m.showPlan(L, o, planFile)
+	return plan, planFile, err
+}
+
+func (m *TofuModule) showPlan(L *lua.LState, o *tofuOptions, planFile string) (*TofuPlan, error) {
+	out, err := m.cli(L, o, "show", "-json", "-no-color", planFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTofuPlan([]byte(out))
+}
+
+func tofuPlanTable(L *lua.LState, plan *TofuPlan, planFile string) *lua.LTable {
+	result := L.NewTable()
+	result.RawSetString("changed", lua.LBool(plan.Changed()))
+	result.RawSetString("add", lua.LNumber(plan.Add))
+	result.RawSetString("change", lua.LNumber(plan.Change))
+	result.RawSetString("destroy", lua.LNumber(plan.Destroy))
+	result.RawSetString("replace", lua.LNumber(plan.Replace))
+	result.RawSetString("summary", lua.LString(plan.Summary()))
+	result.RawSetString("plan_file", lua.LString(planFile))
+
+	resources := L.NewTable()
+	for _, rc := range plan.Resources {
+		r := L.NewTable()
+		r.RawSetString("address", lua.LString(rc.Address))
+		r.RawSetString("mode", lua.LString(rc.Mode))
+		r.RawSetString("type", lua.LString(rc.Type))
+		r.RawSetString("name", lua.LString(rc.Name))
+		r.RawSetString("provider", lua.LString(rc.Provider))
+		r.RawSetString("action", lua.LString(rc.Action))
+		resources.Append(r)
+	}
+	result.RawSetString("resources", resources)
+
+	outputs := L.NewTable()
+	for name, action := range plan.Outputs {
+		outputs.RawSetString(name, lua.LString(action))
+	}
+	result.RawSetString("outputs", outputs)
+	result.RawSetString("plan", goValueToLua(L, plan.Raw))
+	return result
+}
+
+// applySummary matches the CLI's summary of an apply
+var applySummary = regexp.MustCompile(`Resources: (\d+) added, (\d+) changed, (\d+) destroyed`)
+
+// apply applies a saved plan, the plan_file of a tofu.plan result, or
+// plans and applies the configuration when plan isn't given:
+//
+//	local plan = tofu.plan{dir = "infra/prod"}
+//	if plan.changed then tofu.apply{dir = "infra/prod", plan = plan} end
+//
+// In check mode (run --dry-run) the changes of the plan are recorded
+// instead of applied. Returns a table with changed, add, change, destroy
+// and output.
+func (m *TofuModule) apply(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	o, err := m.optionsFromTable(L, opts)
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+
+	var planFile string
+	switch p := opts.RawGetString("plan").(type) {
+	case lua.LString:
+		planFile = string(p)
+	case *lua.LTable:
+		planFile = lua.LVAsString(p.RawGetString("plan_file"))
+	}
+	destroy := getBoolField(L, opts, "destroy", false)
+
+	if taskctx.CheckMode(L.Context()) {
+		var plan *TofuPlan
+		if planFile != "" {
+			plan, err = m.showPlan(L, o, planFile)
+		} else {
+			plan, planFile, err = m.makePlan(L, o, DefaultTofuPlanFile, destroy)
+		}
+		if err != nil {
+			return pushTofuError(L, err)
+		}
+		for _, rc := range plan.Resources {
+			taskctx.RecordChange(L.Context(), taskctx.Change{Module: "tofu", Action: rc.Action, Target: rc.Address})
+		}
+		result := L.NewTable()
+		result.RawSetString("changed", lua.LFalse)
+		result.RawSetString("add", lua.LNumber(plan.Add))
+		result.RawSetString("change", lua.LNumber(plan.Change))
+		result.RawSetString("destroy", lua.LNumber(plan.Destroy))
+		result.RawSetString("output", lua.LString("check mode: "+plan.Summary()))
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	}
+
+	args := []string{"apply", "-input=false", "-no-color", "-auto-approve"}
+	if planFile != "" {
+		// A saved plan already holds its variables and targets
+		args = append(args, planFile)
+	} else {
+		if destroy {
+			args = append(args, "-destroy")
+		}
+		args = append(args, o.variableArgs()...)
+	}
+	out, err := m.cli(L, o, args...)
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+
+	result := L.NewTable()
+	var total int
+	if match := applySummary.FindStringSubmatch(out); match != nil {
+		for i, key := range []string{"add", "change", "destroy"} {
+			n, _ := strconv.Atoi(match[i+1])
+			total += n
+			result.RawSetString(key, lua.LNumber(n))
+		}
+	}
+	result.RawSetString("changed", lua.LBool(total > 0))
+	result.RawSetString("output", lua.LString(out))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// output reads the outputs of the state, decoded from JSON:
+//
+//	local url = tofu.output{dir = "infra/prod", name = "lb_url"}
+//	local outputs = tofu.output{dir = "infra/prod"} -- outputs.lb_url
+//
+// Returns the value of the named output, or a table of every output's
+// value.
+func (m *TofuModule) output(L *lua.LState) int {
+	opts := L.CheckTable(1)
+	o, err := m.optionsFromTable(L, opts)
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+
+	args := []string{"output", "-json", "-no-color"}
+	name := getStringField(L, opts, "name", "")
+	if name != "" {
+		args = append(args, name)
+	}
+	out, err := m.cli(L, o, args...)
+	if err != nil {
+		return pushTofuError(L, err)
+	}
+
+	var value interface{}
+	if name != "" {
+		err = json.Unmarshal([]byte(out), &value)
+	} else {
+		var outputs map[string]struct {
+			Value interface{} `json:"value"`
+		}
+		err = json.Unmarshal([]byte(out), &outputs)
+		values := make(map[string]interface{}, len(outputs))
+		for k, v := range outputs {
+			values[k] = v.Value
+		}
+		value = values
+	}
+	if err != nil {
+		return pushTofuError(L, fmt.Errorf("failed to parse outputs: %w", err))
+	}
+
+	L.Push(goValueToLua(L, value))
+	L.Push(lua.LNil)
+	return 2
+}
@@ -0,0 +1,202 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const testTofuPlan = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+     "provider_name": "registry.opentofu.org/hashicorp/aws", "change": {"actions": ["create"]}},
+    {"address": "aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web",
+     "provider_name": "registry.opentofu.org/hashicorp/aws", "change": {"actions": ["update"]}},
+    {"address": "aws_eip.web", "mode": "managed", "type": "aws_eip", "name": "web",
+     "provider_name": "registry.opentofu.org/hashicorp/aws", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "name": "logs",
+     "provider_name": "registry.opentofu.org/hashicorp/aws", "change": {"actions": ["no-op"]}},
+    {"address": "aws_key_pair.old", "mode": "managed", "type": "aws_key_pair", "name": "old",
+     "provider_name": "registry.opentofu.org/hashicorp/aws", "change": {"actions": ["delete"]}}
+  ],
+  "output_changes": {
+    "ip": {"actions": ["update"]},
+    "bucket": {"actions": ["no-op"]}
+  }
+}`
+
+// newTestTofuModule returns a tofu module whose CLI records its calls and
+// answers them with outputs, keyed by subcommand
+func newTestTofuModule(L *lua.LState, outputs map[string]string) (*TofuModule, *[]string) {
+	var calls []string
+	m := NewTofuModule()
+	m.lookPath = func(file string) (string, error) {
+		if file == "tofu" {
+			return "/usr/bin/tofu", nil
+		}
+		return "", exec.ErrNotFound
+	}
+	m.run = func(ctx context.Context, dir string, env []string, binary string, args ...string) (string, error) {
+		calls = append(calls, binary+" "+strings.Join(args, " "))
+		if out, ok := outputs[args[0]]; ok {
+			return out, nil
+		}
+		return "", fmt.Errorf("unexpected %s", args[0])
+	}
+	m.Register(L)
+	return m, &calls
+}
+
+func TestParseTofuPlan(t *testing.T) {
+	plan, err := ParseTofuPlan([]byte(testTofuPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Add != 2 || plan.Change != 1 || plan.Destroy != 2 || plan.Replace != 1 {
+		t.Errorf("counts = %d/%d/%d/%d, want 2/1/2/1", plan.Add, plan.Change, plan.Destroy, plan.Replace)
+	}
+	if got, want := plan.Summary(), "Plan: 2 to add, 1 to change, 2 to destroy."; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	var actions []string
+	for _, rc := range plan.Resources {
+		actions = append(actions, rc.Address+" "+rc.Action)
+	}
+	want := []string{"aws_instance.web create", "aws_security_group.web update", "aws_eip.web replace", "aws_key_pair.old delete"}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("resources = %q, want %q", actions, want)
+	}
+	if !reflect.DeepEqual(plan.Outputs, map[string]string{"ip": "update"}) {
+		t.Errorf("outputs = %v", plan.Outputs)
+	}
+
+	empty, err := ParseTofuPlan([]byte(`{"resource_changes": [{"address": "a.b", "change": {"actions": ["no-op"]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Changed() || !strings.HasPrefix(empty.Summary(), "No changes.") {
+		t.Errorf("a no-op plan should not change anything")
+	}
+}
+
+func TestTofuModule_PlanAndApply(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	dir := t.TempDir()
+	_, calls := newTestTofuModule(L, map[string]string{
+		"init":  "OpenTofu has been successfully initialized!",
+		"plan":  "",
+		"show":  testTofuPlan,
+		"apply": "Apply complete! Resources: 2 added, 1 changed, 2 destroyed.",
+	})
+	L.SetGlobal("dir", lua.LString(dir))
+
+	err := L.DoString(`
+		local r, err = tofu.init{dir = dir, backend_config = {bucket = "state", region = "us-east-1"}}
+		assert(r, err)
+		assert(r.binary == "tofu", r.binary)
+
+		local plan, err = tofu.plan{dir = dir, vars = {region = "us-east-1", zones = {"a", "b"}}, targets = "aws_instance.web"}
+		assert(plan, err)
+		assert(plan.changed, "plan should change")
+		assert(plan.add == 2 and plan.change == 1 and plan.destroy == 2 and plan.replace == 1, "counts")
+		assert(#plan.resources == 4 and plan.resources[3].action == "replace", "resources")
+		assert(plan.outputs.ip == "update", "outputs")
+		assert(plan.plan.format_version == "1.2", "raw plan")
+		assert(plan.plan_file == dir .. "/sloth.tfplan", plan.plan_file)
+
+		local applied, err = tofu.apply{dir = dir, plan = plan}
+		assert(applied, err)
+		assert(applied.changed and applied.add == 2 and applied.destroy == 2, "apply counts")
+
+		r, err = tofu.plan{dir = dir .. "/missing"}
+		assert(r == nil and err:find("working directory"), err)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planFile := dir + "/sloth.tfplan"
+	want := []string{
+		"tofu init -input=false -no-color -backend-config=bucket=state -backend-config=region=us-east-1",
+		"tofu plan -input=false -no-color -out=" + planFile + ` -var=region=us-east-1 -var=zones=["a","b"] -target=aws_instance.web`,
+		"tofu show -json -no-color " + planFile,
+		"tofu apply -input=false -no-color -auto-approve " + planFile,
+	}
+	if !reflect.DeepEqual(*calls, want) {
+		t.Errorf("calls =\n%s\nwant\n%s", strings.Join(*calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTofuModule_ApplyCheckMode(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	plan := &taskctx.Plan{}
+	L.SetContext(taskctx.WithPlan(context.Background(), plan))
+	dir := t.TempDir()
+	_, calls := newTestTofuModule(L, map[string]string{"plan": "", "show": testTofuPlan})
+	L.SetGlobal("dir", lua.LString(dir))
+
+	err := L.DoString(`
+		local r, err = tofu.apply{dir = dir}
+		assert(r, err)
+		assert(not r.changed, "check mode should not apply")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, call := range *calls {
+		if strings.HasPrefix(call, "tofu apply") {
+			t.Errorf("apply ran in check mode: %s", call)
+		}
+	}
+	var changes []string
+	for _, c := range plan.Changes() {
+		changes = append(changes, c.Module+" "+c.Action+" "+c.Target)
+	}
+	want := []string{"tofu create aws_instance.web", "tofu update aws_security_group.web", "tofu replace aws_eip.web", "tofu delete aws_key_pair.old"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("recorded changes = %q, want %q", changes, want)
+	}
+}
+
+func TestTofuModule_Output(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	m, calls := newTestTofuModule(L, nil)
+	m.lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	m.run = func(ctx context.Context, dir string, env []string, binary string, args ...string) (string, error) {
+		*calls = append(*calls, binary+" "+strings.Join(args, " "))
+		if len(args) == 4 {
+			return `"203.0.113.10"`, nil
+		}
+		return `{"ip": {"sensitive": false, "type": "string", "value": "203.0.113.10"},
+			"zones": {"sensitive": false, "type": ["list", "string"], "value": ["a", "b"]}}`, nil
+	}
+
+	err := L.DoString(`
+		local ip, err = tofu.output{name = "ip"}
+		assert(ip == "203.0.113.10", err)
+
+		local outputs, err = tofu.output{}
+		assert(outputs, err)
+		assert(outputs.ip == "203.0.113.10" and outputs.zones[2] == "b", "outputs")
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"terraform output -json -no-color ip", "terraform output -json -no-color"}
+	if !reflect.DeepEqual(*calls, want) {
+		t.Errorf("calls = %q, want %q", *calls, want)
+	}
+}
@@ -0,0 +1,212 @@
+// Package mtls secures the gRPC channels between the CLI, masters and
+// agents with mutual TLS. Every node has a certificate signed by the same
+// CA, usable to serve and to connect, and only accepts peers presenting
+// one. Without certificates, channels stay in plaintext.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Environment variables setting the files when the flags aren't given
+const (
+	EnvCert = "SLOTH_RUNNER_TLS_CERT"
+	EnvKey  = "SLOTH_RUNNER_TLS_KEY"
+	EnvCA   = "SLOTH_RUNNER_TLS_CA"
+)
+
+// Files names the PEM files of a node
+type Files struct {
+	CertFile string
+	KeyFile  string
+	// CAFile verifies the other side: clients on servers and servers on
+	// clients
+	CAFile string
+}
+
+// Enabled reports whether any file is set
+func (f Files) Enabled() bool {
+	return f.CertFile != "" || f.KeyFile != "" || f.CAFile != ""
+}
+
+// FilesFromEnv returns the files named by the SLOTH_RUNNER_TLS_*
+// environment variables
+func FilesFromEnv() Files {
+	return Files{CertFile: os.Getenv(EnvCert), KeyFile: os.Getenv(EnvKey), CAFile: os.Getenv(EnvCA)}
+}
+
+var (
+	mu     sync.RWMutex
+	active Files
+	server *tls.Config
+	client *tls.Config
+)
+
+// Configure sets the files every gRPC server and connection of the
+// process uses. Files are checked now; the certificate and key are read
+// again when they change, so they can be rotated without a restart.
+func Configure(files Files) error {
+	var serverConfig, clientConfig *tls.Config
+	if files.Enabled() {
+		if files.CertFile == "" || files.KeyFile == "" || files.CAFile == "" {
+			return fmt.Errorf("mTLS requires a certificate, a key and a CA (--tls-cert, --tls-key and --tls-ca)")
+		}
+		pair := &keyPair{certFile: files.CertFile, keyFile: files.KeyFile}
+		if _, err := pair.get(); err != nil {
+			return err
+		}
+		pool, err := loadCAPool(files.CAFile)
+		if err != nil {
+			return err
+		}
+		serverConfig = &tls.Config{
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return pair.get() },
+			ClientCAs:      pool,
+			ClientAuth:     tls.RequireAndVerifyClientCert,
+			MinVersion:     tls.VersionTLS12,
+		}
+		clientConfig = &tls.Config{
+			GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return pair.get() },
+			RootCAs:              pool,
+			MinVersion:           tls.VersionTLS12,
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	active, server, client = files, serverConfig, clientConfig
+	return nil
+}
+
+// Active returns the configured files
+func Active() Files {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active
+}
+
+// Enabled reports whether channels use mTLS
+func Enabled() bool {
+	return Active().Enabled()
+}
+
+// DialOption returns the transport credentials for connections to masters
+// and agents
+func DialOption() grpc.DialOption {
+	mu.RLock()
+	defer mu.RUnlock()
+	if client == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(client))
+}
+
+// ServerOptions returns the options securing a gRPC server, none without
+// mTLS
+func ServerOptions() []grpc.ServerOption {
+	mu.RLock()
+	defer mu.RUnlock()
+	if server == nil {
+		return nil
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(server))}
+}
+
+// ServerConfig returns the TLS configuration for servers other than gRPC
+// ones, like the agents' artifact cache, nil without mTLS
+func ServerConfig() *tls.Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	if server == nil {
+		return nil
+	}
+	return server.Clone()
+}
+
+// ClientConfig returns the TLS configuration for connections to servers
+// using ServerConfig, nil without mTLS
+func ClientConfig() *tls.Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	if client == nil {
+		return nil
+	}
+	return client.Clone()
+}
+
+// DaemonArgs returns the flags passing the files on to a daemon process
+func DaemonArgs() []string {
+	files := Active()
+	if !files.Enabled() {
+		return nil
+	}
+	return []string{"--tls-cert", files.CertFile, "--tls-key", files.KeyFile, "--tls-ca", files.CAFile}
+}
+
+// keyPair loads a certificate and its key, again whenever either file
+// changes
+type keyPair struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (k *keyPair) get() (*tls.Certificate, error) {
+	modTime, err := latestModTime(k.certFile, k.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.cert != nil && modTime.Equal(k.modTime) {
+		return k.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		// Keep serving the previous pair while a rotation is half done
+		if k.cert != nil {
+			return k.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	k.cert, k.modTime = &cert, modTime
+	return k.cert, nil
+}
+
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func loadCAPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
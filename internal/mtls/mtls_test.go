@@ -0,0 +1,93 @@
+package mtls
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func newTestDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, NewCA(dir, "test CA", time.Hour))
+	require.NoError(t, Issue(dir, "node", []string{"localhost", "127.0.0.1"}, time.Hour))
+	t.Cleanup(func() { Configure(Files{}) })
+	return dir
+}
+
+func serveHealth(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(ServerOptions()...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func check(address string, opts ...grpc.DialOption) error {
+	conn, err := grpc.NewClient(address, opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := newTestDir(t)
+	require.NoError(t, Configure(Files{CertFile: CertFile(dir, "node"), KeyFile: KeyFile(dir, "node"), CAFile: dir + "/" + CACertFile}))
+	address := serveHealth(t)
+
+	assert.NoError(t, check(address, DialOption()))
+	assert.Error(t, check(address, grpc.WithTransportCredentials(insecure.NewCredentials())), "plaintext clients must be refused")
+
+	// A certificate of another CA isn't accepted
+	other := t.TempDir()
+	require.NoError(t, NewCA(other, "other CA", time.Hour))
+	require.NoError(t, Issue(other, "intruder", []string{"127.0.0.1"}, time.Hour))
+	require.NoError(t, Configure(Files{CertFile: CertFile(other, "intruder"), KeyFile: KeyFile(other, "intruder"), CAFile: dir + "/" + CACertFile}))
+	assert.Error(t, check(address, DialOption()), "certificates of another CA must be refused")
+}
+
+func TestConfigure(t *testing.T) {
+	dir := newTestDir(t)
+
+	assert.Error(t, Configure(Files{CertFile: CertFile(dir, "node")}), "partial configuration must fail")
+	assert.Error(t, Configure(Files{CertFile: CertFile(dir, "node"), KeyFile: KeyFile(dir, "missing"), CAFile: dir + "/" + CACertFile}))
+
+	require.NoError(t, Configure(Files{}))
+	assert.False(t, Enabled())
+	assert.Nil(t, ServerOptions())
+	assert.Nil(t, DaemonArgs())
+}
+
+func TestKeyPairReloadsRotatedCertificate(t *testing.T) {
+	dir := newTestDir(t)
+	pair := &keyPair{certFile: CertFile(dir, "node"), keyFile: KeyFile(dir, "node")}
+	first, err := pair.get()
+	require.NoError(t, err)
+
+	// Make sure the rotated files get a later modification time
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, Issue(dir, "node", []string{"localhost"}, time.Hour))
+	second, err := pair.get()
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	cert, err := ReadCertificate(CertFile(dir, "node"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, Hosts(cert))
+}
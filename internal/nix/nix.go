@@ -0,0 +1,99 @@
+// Package nix runs commands in Nix shells, which give tasks the exact tool
+// versions they ask for on hosts that have Nix installed.
+package nix
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Shell is the Nix environment the commands of a task run in: either
+// packages of nixpkgs or the devShell of a flake
+type Shell struct {
+	// Packages are installables like "nodejs_20", taken from nixpkgs, or
+	// "github:NixOS/nixpkgs/nixos-24.05#nodejs_20"
+	Packages []string
+	// Flake is the flake whose devShell commands run in, like "." or
+	// "github:org/repo#ci"
+	Flake string
+}
+
+// Validate reports whether the shell sets exactly one of packages and flake
+func (s *Shell) Validate() error {
+	if len(s.Packages) == 0 && s.Flake == "" {
+		return errors.New("nix_shell needs packages or a flake")
+	}
+	if len(s.Packages) > 0 && s.Flake != "" {
+		return errors.New("nix_shell takes packages or a flake, not both")
+	}
+	return nil
+}
+
+// experimentalFeatures are enabled for each command since nix shell and nix
+// develop need them and most installs don't enable them
+const experimentalFeatures = "nix-command flakes"
+
+// Command returns the command line running args, a command and its
+// arguments, in the shell with the nix command at nixPath
+func (s *Shell) Command(nixPath string, args []string) []string {
+	cmd := []string{nixPath, "--extra-experimental-features", experimentalFeatures}
+	if s.Flake != "" {
+		cmd = append(cmd, "develop", s.Flake)
+	} else {
+		cmd = append(cmd, "shell")
+		for _, p := range s.Packages {
+			if !strings.Contains(p, "#") {
+				p = "nixpkgs#" + p
+			}
+			cmd = append(cmd, p)
+		}
+	}
+	cmd = append(cmd, "--command")
+	return append(cmd, args...)
+}
+
+// profilePaths are where Nix installs put the nix command, which agents
+// running as services may not have on their PATH
+var profilePaths = []string{
+	"/nix/var/nix/profiles/default/bin/nix",
+	"/run/current-system/sw/bin/nix",
+}
+
+// Find returns the path of the nix command
+func Find() (string, error) {
+	if path, err := exec.LookPath("nix"); err == nil {
+		return path, nil
+	}
+	for _, path := range profilePaths {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("nix is not installed")
+}
+
+// Version returns the version of the installed Nix, or "" when there is
+// none
+func Version() string {
+	path, err := Find()
+	if err != nil {
+		return ""
+	}
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	return parseVersion(string(out))
+}
+
+// parseVersion returns the version nix --version prints, as in
+// "nix (Nix) 2.18.1"
+func parseVersion(out string) string {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
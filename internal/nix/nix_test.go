@@ -0,0 +1,48 @@
+package nix
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellCommand(t *testing.T) {
+	args := []string{"bash", "-c", "node --version"}
+
+	s := &Shell{Packages: []string{"nodejs_20", "github:NixOS/nixpkgs/nixos-24.05#python312"}}
+	want := []string{"/bin/nix", "--extra-experimental-features", "nix-command flakes", "shell",
+		"nixpkgs#nodejs_20", "github:NixOS/nixpkgs/nixos-24.05#python312", "--command", "bash", "-c", "node --version"}
+	if got := s.Command("/bin/nix", args); !reflect.DeepEqual(got, want) {
+		t.Errorf("Command = %q, want %q", got, want)
+	}
+
+	s = &Shell{Flake: ".#ci"}
+	want = []string{"/bin/nix", "--extra-experimental-features", "nix-command flakes", "develop", ".#ci",
+		"--command", "bash", "-c", "node --version"}
+	if got := s.Command("/bin/nix", args); !reflect.DeepEqual(got, want) {
+		t.Errorf("Command = %q, want %q", got, want)
+	}
+}
+
+func TestShellValidate(t *testing.T) {
+	if err := (&Shell{}).Validate(); err == nil {
+		t.Error("Expected an error for a shell without packages or flake")
+	}
+	if err := (&Shell{Packages: []string{"go"}, Flake: "."}).Validate(); err == nil {
+		t.Error("Expected an error for a shell with packages and a flake")
+	}
+	if err := (&Shell{Flake: "."}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for out, want := range map[string]string{
+		"nix (Nix) 2.18.1\n":         "2.18.1",
+		"nix (Lix, like Nix) 2.91.1": "2.91.1",
+		"":                           "",
+	} {
+		if got := parseVersion(out); got != want {
+			t.Errorf("parseVersion(%q) = %q, want %q", out, got, want)
+		}
+	}
+}
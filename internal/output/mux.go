@@ -0,0 +1,272 @@
+package output
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/pterm/pterm"
+)
+
+// ansiPattern matches ANSI escape sequences: colors, cursor movement and
+// line clearing
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|\x1b\][^\x07]*\x07`)
+
+// clearLine moves to the start of the line and clears it
+const clearLine = "\r\x1b[K"
+
+// prefixColors are the colors prefixes are shown in; a prefix always gets
+// the same one
+var prefixColors = []pterm.Color{
+	pterm.FgCyan, pterm.FgMagenta, pterm.FgYellow, pterm.FgBlue,
+	pterm.FgGreen, pterm.FgLightCyan, pterm.FgLightMagenta, pterm.FgLightBlue,
+}
+
+// StripANSI removes ANSI escape sequences from s
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// Multiplexer serializes the output of tasks and agents running at the
+// same time. Everything written through it, including pterm's spinners and
+// progress bars once Setup installs it, goes out one write at a time, and
+// a line written while a spinner is drawn clears the spinner first so the
+// spinner is redrawn below it instead of being spliced into the line.
+type Multiplexer struct {
+	mu     sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+	noANSI bool
+	// partial is the stream that left the terminal's last line
+	// unfinished, e.g. with a spinner frame on stderr
+	partial stream
+}
+
+type stream int
+
+const (
+	noStream stream = iota
+	stdoutStream
+	stderrStream
+)
+
+// NewMultiplexer creates a multiplexer writing to stdout and stderr. With
+// noANSI, escape sequences are stripped so the output is plain text for
+// CI log collectors.
+func NewMultiplexer(stdout, stderr io.Writer, noANSI bool) *Multiplexer {
+	return &Multiplexer{stdout: stdout, stderr: stderr, noANSI: noANSI}
+}
+
+// NoANSI reports whether the multiplexer writes plain text
+func (m *Multiplexer) NoANSI() bool {
+	return m.noANSI
+}
+
+// Stdout returns a writer for standard output
+func (m *Multiplexer) Stdout() io.Writer {
+	return streamWriter{m: m, s: stdoutStream}
+}
+
+// Stderr returns a writer for standard error
+func (m *Multiplexer) Stderr() io.Writer {
+	return streamWriter{m: m, s: stderrStream}
+}
+
+type streamWriter struct {
+	m *Multiplexer
+	s stream
+}
+
+func (s streamWriter) Write(p []byte) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.m.write(s.s, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// write writes p to stream s; the caller holds the lock
+func (m *Multiplexer) write(s stream, p []byte) error {
+	if m.noANSI {
+		p = []byte(StripANSI(string(p)))
+		// Without cursor control, redraws become new lines
+		p = bytes.ReplaceAll(p, []byte("\r"), nil)
+		if len(p) == 0 {
+			return nil
+		}
+	}
+	w := m.stdout
+	if s == stderrStream {
+		w = m.stderr
+	}
+
+	// Another stream left a line unfinished: a spinner or progress bar
+	// on stderr is cleared and redraws itself below on its next frame,
+	// other text is ended
+	if m.partial != noStream && m.partial != s {
+		end := "\n"
+		if m.partial == stderrStream && !m.noANSI {
+			end = clearLine
+		}
+		if _, err := io.WriteString(w, end); err != nil {
+			return err
+		}
+		m.partial = noStream
+	}
+
+	if _, err := w.Write(p); err != nil {
+		return err
+	}
+	if p[len(p)-1] == '\n' {
+		m.partial = noStream
+	} else {
+		m.partial = s
+	}
+	return nil
+}
+
+// Prefixed returns a writer that writes whole lines to standard output,
+// each starting with prefix, e.g. the task or agent the output comes from.
+// Flush writes a last line that doesn't end in a newline.
+func (m *Multiplexer) Prefixed(prefix string) *PrefixWriter {
+	label := "[" + prefix + "] "
+	if !m.noANSI {
+		h := fnv.New32a()
+		h.Write([]byte(prefix))
+		color := prefixColors[h.Sum32()%uint32(len(prefixColors))]
+		label = color.Sprint(prefix) + pterm.Gray(" │ ")
+	}
+	return &PrefixWriter{m: m, label: label}
+}
+
+// PrefixWriter writes prefixed lines through a Multiplexer
+type PrefixWriter struct {
+	m     *Multiplexer
+	label string
+	mu    sync.Mutex
+	buf   []byte
+}
+
+// Write implements io.Writer
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	lines := w.buf[:i+1]
+	err := w.writeLines(lines)
+	w.buf = append(w.buf[:0], w.buf[i+1:]...)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes what is left of an unfinished line
+func (w *PrefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLines(append(w.buf, '\n'))
+	w.buf = w.buf[:0]
+	return err
+}
+
+// writeLines writes newline-terminated lines in one go, so lines of other
+// writers don't end up between them
+func (w *PrefixWriter) writeLines(lines []byte) error {
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		// Progress output redraws a line with \r; keep its last state
+		if i := bytes.LastIndexByte(bytes.TrimSuffix(line, []byte("\n")), '\r'); i >= 0 {
+			line = line[i+1:]
+		}
+		out.WriteString(w.label)
+		out.Write(line)
+	}
+
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	// Lines start on a line of their own, even after unfinished text on
+	// stdout
+	if w.m.partial == stdoutStream {
+		if _, err := io.WriteString(w.m.stdout, "\n"); err != nil {
+			return err
+		}
+		w.m.partial = noStream
+	}
+	return w.m.write(stdoutStream, out.Bytes())
+}
+
+var (
+	defaultMux   *Multiplexer
+	defaultMuxMu sync.RWMutex
+)
+
+// Default returns the multiplexer installed by Setup, or one writing to
+// the process' stdout and stderr
+func Default() *Multiplexer {
+	defaultMuxMu.RLock()
+	m := defaultMux
+	defaultMuxMu.RUnlock()
+	if m != nil {
+		return m
+	}
+
+	defaultMuxMu.Lock()
+	defer defaultMuxMu.Unlock()
+	if defaultMux == nil {
+		defaultMux = newProcessMultiplexer(false)
+	}
+	return defaultMux
+}
+
+// Setup routes pterm's output, including spinners, progress bars and the
+// logger, through a new default multiplexer. With noANSI, styling is
+// turned off, spinners and progress bars print plain lines instead of
+// redrawing, and any remaining escape sequences are stripped.
+func Setup(noANSI bool) *Multiplexer {
+	m := newProcessMultiplexer(noANSI)
+
+	defaultMuxMu.Lock()
+	defaultMux = m
+	defaultMuxMu.Unlock()
+
+	if noANSI {
+		pterm.DisableStyling()
+	}
+	pterm.SetDefaultOutput(m.Stdout())
+	pterm.DefaultLogger.Writer = m.Stdout()
+	pterm.DefaultSpinner.Writer = m.Stderr()
+	pterm.DefaultProgressbar.Writer = m.Stderr()
+	return m
+}
+
+// newProcessMultiplexer writes to os.Stdout and os.Stderr as they are at
+// the time of each write, so tests redirecting them keep working
+func newProcessMultiplexer(noANSI bool) *Multiplexer {
+	return NewMultiplexer(processWriter{stderr: false}, processWriter{stderr: true}, noANSI)
+}
+
+type processWriter struct {
+	stderr bool
+}
+
+func (p processWriter) Write(b []byte) (int, error) {
+	if p.stderr {
+		return os.Stderr.Write(b)
+	}
+	return os.Stdout.Write(b)
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	in := "\x1b[1;32m✓\x1b[0m done\x1b[K"
+	if got := StripANSI(in); got != "✓ done" {
+		t.Errorf("StripANSI = %q", got)
+	}
+}
+
+func TestPrefixWriter_Lines(t *testing.T) {
+	var out bytes.Buffer
+	m := NewMultiplexer(&out, &out, true)
+
+	w := m.Prefixed("deploy@web-1")
+	fmt.Fprint(w, "pulling ")
+	fmt.Fprint(w, "image\nstarting\x1b[32m ok\x1b[0m\nhalf")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[deploy@web-1] pulling image\n[deploy@web-1] starting ok\n[deploy@web-1] half\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrefixWriter_KeepsLinesWhole(t *testing.T) {
+	var out bytes.Buffer
+	m := NewMultiplexer(&out, &out, true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := m.Prefixed(fmt.Sprintf("task-%d", i))
+			for j := 0; j < 50; j++ {
+				// Write each line in pieces
+				fmt.Fprintf(w, "line %d ", j)
+				fmt.Fprintf(w, "of task-%d\n", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 8*50 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	for _, line := range lines {
+		var prefix, owner string
+		var n int
+		if _, err := fmt.Sscanf(line, "[%s line %d of %s", &prefix, &n, &owner); err != nil {
+			t.Fatalf("malformed line %q: %v", line, err)
+		}
+		if strings.TrimSuffix(prefix, "]") != owner {
+			t.Errorf("line %q has another task's prefix", line)
+		}
+	}
+}
+
+func TestMultiplexer_ClearsSpinner(t *testing.T) {
+	var out bytes.Buffer
+	m := NewMultiplexer(&out, &out, false)
+
+	fmt.Fprint(m.Stderr(), "\r⠋ Running")
+	fmt.Fprint(m.Prefixed("build"), "compiled\n")
+	fmt.Fprint(m.Stderr(), "\r⠙ Running")
+
+	got := StripANSI(strings.ReplaceAll(out.String(), clearLine, "<clear>"))
+	want := "\r⠋ Running<clear>build │ compiled\n\r⠙ Running"
+	if got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestMultiplexer_NoANSI(t *testing.T) {
+	var out bytes.Buffer
+	m := NewMultiplexer(&out, &out, true)
+
+	fmt.Fprint(m.Stdout(), "\x1b[36mINFO\x1b[0m ready\n")
+	fmt.Fprint(m.Stderr(), "\r\x1b[K")
+	fmt.Fprint(m.Stdout(), "partial")
+	fmt.Fprint(m.Prefixed("t"), "line\n")
+
+	if want := "INFO ready\npartial\n[t] line\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestProgress_RoundTrip(t *testing.T) {
+	tests := []struct {
+		percent float64
+		message string
+		line    string
+	}{
+		{40, "uploading artifacts", "↻ [deploy] 40% uploading artifacts"},
+		{12.5, "", "↻ [deploy] 12.5%"},
+		{-1, "waiting for the lock", "↻ [deploy] waiting for the lock"},
+	}
+	for _, tt := range tests {
+		line := FormatProgress("deploy", tt.percent, tt.message)
+		if line != tt.line {
+			t.Errorf("FormatProgress = %q, want %q", line, tt.line)
+		}
+		task, percent, message, ok := ParseProgress("    \x1b[36m" + line + "\x1b[0m")
+		if !ok || task != "deploy" || percent != tt.percent || message != tt.message {
+			t.Errorf("ParseProgress(%q) = %q, %v, %q, %v", line, task, percent, message, ok)
+		}
+	}
+	if _, _, _, ok := ParseProgress("[deploy] 40% done"); ok {
+		t.Error("ParseProgress accepted a line without the marker")
+	}
+}
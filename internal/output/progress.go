@@ -0,0 +1,45 @@
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// progressMarker starts the lines reporting the progress of a task
+const progressMarker = "↻"
+
+// progressPattern matches a progress line once stripped of ANSI sequences:
+// the task, the percent when there is one and the message
+var progressPattern = regexp.MustCompile(`^\s*` + progressMarker + ` \[([^\]]+)\](?: (\d+(?:\.\d+)?)%)?(?: (.*))?$`)
+
+// FormatProgress formats the progress of a task as a line of the run
+// output, e.g. "↻ [deploy] 40% uploading artifacts". A negative percent is
+// left out.
+func FormatProgress(task string, percent float64, message string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s]", progressMarker, task)
+	if percent >= 0 {
+		fmt.Fprintf(&b, " %s%%", strconv.FormatFloat(percent, 'f', -1, 64))
+	}
+	if message != "" {
+		b.WriteString(" " + message)
+	}
+	return b.String()
+}
+
+// ParseProgress parses a line written with FormatProgress, as the web UI
+// reads it from the output of the runs it starts. The percent is -1 when
+// the line has none.
+func ParseProgress(line string) (task string, percent float64, message string, ok bool) {
+	m := progressPattern.FindStringSubmatch(StripANSI(line))
+	if m == nil {
+		return "", 0, "", false
+	}
+	percent = -1
+	if m[2] != "" {
+		percent, _ = strconv.ParseFloat(m[2], 64)
+	}
+	return m[1], percent, m[3], true
+}
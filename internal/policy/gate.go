@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DeniedError is returned when a policy denies an action or approval was
+// required but not given
+type DeniedError struct {
+	Decision Decision
+	Input    Input
+}
+
+func (e *DeniedError) Error() string {
+	target := e.Input.describe()
+	msg := e.Decision.Message
+	if msg == "" {
+		msg = "no message"
+	}
+	if e.Decision.Action == ActionRequireApproval {
+		return fmt.Sprintf("%s requires approval by policy %q: %s (approve with --approve %s)", target, e.Decision.Policy, msg, e.Decision.Policy)
+	}
+	return fmt.Sprintf("%s denied by policy %q: %s", target, e.Decision.Policy, msg)
+}
+
+func (in Input) describe() string {
+	if in.Stage == StageModule {
+		return fmt.Sprintf("call to %s.%s on %s", in.Module, in.Function, in.Agent)
+	}
+	return fmt.Sprintf("run of stack %q on %s", in.Stack, in.Agent)
+}
+
+// PromptFunc asks the user to approve a decision. It returns true when the
+// user approves.
+type PromptFunc func(d Decision, in Input) bool
+
+// Gate applies an engine's decisions. Approvals are given up front by
+// policy name, or interactively through Prompt, and last for the lifetime
+// of the gate.
+type Gate struct {
+	engine *Engine
+	// Base fills the fields of checked inputs that are left empty
+	base   Input
+	prompt PromptFunc
+
+	mu       sync.Mutex
+	approved map[string]bool
+}
+
+// NewGate creates a gate. approved lists pre-approved policy names; prompt
+// may be nil in non-interactive contexts, in which case policies requiring
+// approval deny unless pre-approved.
+func NewGate(engine *Engine, base Input, approved []string, prompt PromptFunc) *Gate {
+	g := &Gate{engine: engine, base: base, prompt: prompt, approved: make(map[string]bool)}
+	for _, name := range approved {
+		g.approved[name] = true
+	}
+	return g
+}
+
+// Engine returns the gate's engine
+func (g *Gate) Engine() *Engine {
+	return g.engine
+}
+
+// Check evaluates in and returns a *DeniedError when the action may not
+// proceed
+func (g *Gate) Check(in Input) error {
+	in = g.withBase(in)
+	decision := g.engine.Evaluate(in)
+	switch decision.Action {
+	case ActionAllow:
+		return nil
+	case ActionRequireApproval:
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.approved[decision.Policy] {
+			return nil
+		}
+		if g.prompt != nil && g.prompt(decision, in) {
+			g.approved[decision.Policy] = true
+			return nil
+		}
+	}
+	return &DeniedError{Decision: decision, Input: in}
+}
+
+func (g *Gate) withBase(in Input) Input {
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&in.User, g.base.User)
+	fill(&in.Stack, g.base.Stack)
+	fill(&in.Workflow, g.base.Workflow)
+	fill(&in.Agent, g.base.Agent)
+	return in
+}
+
+var (
+	activeGate   *Gate
+	activeGateMu sync.RWMutex
+)
+
+// SetActive sets the gate checked by Lua modules in this process. Pass nil
+// to disable checks.
+func SetActive(g *Gate) {
+	activeGateMu.Lock()
+	defer activeGateMu.Unlock()
+	activeGate = g
+}
+
+// Active returns the gate checked by Lua modules, or nil
+func Active() *Gate {
+	activeGateMu.RLock()
+	defer activeGateMu.RUnlock()
+	return activeGate
+}
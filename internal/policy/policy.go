@@ -0,0 +1,239 @@
+// Package policy evaluates admission policies written in CEL before a run
+// starts and before privileged module calls.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v3"
+)
+
+// Stage is the point at which a policy is evaluated
+type Stage string
+
+const (
+	// StageRun is evaluated for every task and target agent before a run
+	// starts
+	StageRun Stage = "run"
+	// StageModule is evaluated before each call into a privileged module
+	StageModule Stage = "module"
+)
+
+// Action is what happens when a policy matches
+type Action string
+
+const (
+	ActionAllow           Action = "allow"
+	ActionDeny            Action = "deny"
+	ActionRequireApproval Action = "require_approval"
+)
+
+// DefaultPrivilegedModules are the modules whose calls are checked when the
+// policy file does not list its own
+var DefaultPrivilegedModules = []string{
+	"exec", "pkg", "package", "user", "systemd", "file_ops", "docker", "incus",
+	"ssh", "sysctl", "kmod", "firewall", "lvm", "raid", "nfs", "smb", "kubernetes",
+	"helm", "terraform", "tofu", "pulumi", "dns", "config",
+}
+
+// File is the YAML policy file
+type File struct {
+	// PrivilegedModules overrides DefaultPrivilegedModules
+	PrivilegedModules []string `yaml:"privileged_modules"`
+	Policies          []Policy `yaml:"policies"`
+}
+
+// Policy is a single rule. When is a CEL expression over the Input fields
+// that must evaluate to a bool.
+type Policy struct {
+	Name    string `yaml:"name"`
+	Stage   Stage  `yaml:"stage"`
+	When    string `yaml:"when"`
+	Action  Action `yaml:"action"`
+	Message string `yaml:"message"`
+}
+
+// Input is what policies are evaluated against. Fields that don't apply to
+// a stage are empty.
+type Input struct {
+	Stage    Stage
+	User     string
+	Stack    string
+	Workflow string
+	Agent    string
+	Task     string
+	Module   string
+	Function string
+	Args     []interface{}
+}
+
+func (in Input) activation() map[string]interface{} {
+	args := in.Args
+	if args == nil {
+		args = []interface{}{}
+	}
+	return map[string]interface{}{
+		"stage":    string(in.Stage),
+		"user":     in.User,
+		"stack":    in.Stack,
+		"workflow": in.Workflow,
+		"agent":    in.Agent,
+		"task":     in.Task,
+		"module":   in.Module,
+		"fn":       in.Function,
+		"args":     args,
+	}
+}
+
+// Decision is the outcome of evaluating the policies for an input
+type Decision struct {
+	Action  Action
+	Policy  string
+	Message string
+}
+
+type compiledPolicy struct {
+	Policy
+	program cel.Program
+}
+
+// Engine holds compiled policies
+type Engine struct {
+	policies   []compiledPolicy
+	privileged map[string]bool
+}
+
+// Load reads and compiles a policy file
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	engine, err := New(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return engine, nil
+}
+
+// LoadDefault loads path, or the default policy file when path is empty.
+// It returns nil without an error when no path is given and the default
+// file does not exist.
+func LoadDefault(path, defaultPath string) (*Engine, error) {
+	if path == "" {
+		if _, err := os.Stat(defaultPath); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		path = defaultPath
+	}
+	return Load(path)
+}
+
+// New compiles the policies in file
+func New(file File) (*Engine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("stage", cel.StringType),
+		cel.Variable("user", cel.StringType),
+		cel.Variable("stack", cel.StringType),
+		cel.Variable("workflow", cel.StringType),
+		cel.Variable("agent", cel.StringType),
+		cel.Variable("task", cel.StringType),
+		cel.Variable("module", cel.StringType),
+		cel.Variable("fn", cel.StringType),
+		cel.Variable("args", cel.ListType(cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{privileged: make(map[string]bool)}
+	modules := file.PrivilegedModules
+	if len(modules) == 0 {
+		modules = DefaultPrivilegedModules
+	}
+	for _, m := range modules {
+		engine.privileged[m] = true
+	}
+
+	names := make(map[string]bool)
+	for i, p := range file.Policies {
+		if p.Name == "" {
+			return nil, fmt.Errorf("policy %d has no name", i+1)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("duplicate policy %q", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Stage == "" {
+			p.Stage = StageRun
+		}
+		if p.Stage != StageRun && p.Stage != StageModule {
+			return nil, fmt.Errorf("policy %q: unknown stage %q (expected run or module)", p.Name, p.Stage)
+		}
+		if p.Action != ActionDeny && p.Action != ActionRequireApproval {
+			return nil, fmt.Errorf("policy %q: unknown action %q (expected deny or require_approval)", p.Name, p.Action)
+		}
+		if strings.TrimSpace(p.When) == "" {
+			return nil, fmt.Errorf("policy %q: when is required", p.Name)
+		}
+
+		ast, issues := env.Compile(p.When)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("policy %q: %w", p.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("policy %q: when must evaluate to a bool, got %s", p.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+		engine.policies = append(engine.policies, compiledPolicy{Policy: p, program: program})
+	}
+	return engine, nil
+}
+
+// Privileged reports whether calls into module are checked
+func (e *Engine) Privileged(module string) bool {
+	return e.privileged[module]
+}
+
+// Evaluate returns the decision for in. A matching deny policy wins over
+// policies requiring approval; otherwise the first matching policy is
+// returned. A policy whose expression fails to evaluate denies.
+func (e *Engine) Evaluate(in Input) Decision {
+	activation := in.activation()
+	decision := Decision{Action: ActionAllow}
+	for _, p := range e.policies {
+		if p.Stage != in.Stage {
+			continue
+		}
+		out, _, err := p.program.Eval(activation)
+		if err != nil {
+			return Decision{
+				Action:  ActionDeny,
+				Policy:  p.Name,
+				Message: fmt.Sprintf("policy failed to evaluate: %v", err),
+			}
+		}
+		if matched, ok := out.Value().(bool); !ok || !matched {
+			continue
+		}
+		if p.Action == ActionDeny {
+			return Decision{Action: ActionDeny, Policy: p.Name, Message: p.Message}
+		}
+		if decision.Action == ActionAllow {
+			decision = Decision{Action: p.Action, Policy: p.Name, Message: p.Message}
+		}
+	}
+	return decision
+}
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicies = `
+policies:
+  - name: no-prod-from-laptops
+    stage: run
+    when: 'stack.startsWith("prod") && agent == "local"'
+    action: deny
+    message: production runs must be delegated
+  - name: prod-approval
+    stage: run
+    when: 'stack.startsWith("prod")'
+    action: require_approval
+    message: production change
+  - name: no-rm-rf
+    stage: module
+    when: 'module == "exec" && args.size() > 0 && string(args[0]).contains("rm -rf /")'
+    action: deny
+`
+
+func loadTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	if err := os.WriteFile(path, []byte(testPolicies), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return engine
+}
+
+func TestEvaluate(t *testing.T) {
+	engine := loadTestEngine(t)
+
+	tests := []struct {
+		name   string
+		in     Input
+		action Action
+		policy string
+	}{
+		{"dev run", Input{Stage: StageRun, Stack: "dev", Agent: "local"}, ActionAllow, ""},
+		{"deny wins over approval", Input{Stage: StageRun, Stack: "prod-eu", Agent: "local"}, ActionDeny, "no-prod-from-laptops"},
+		{"approval", Input{Stage: StageRun, Stack: "prod-eu", Agent: "web-1"}, ActionRequireApproval, "prod-approval"},
+		{"module deny", Input{Stage: StageModule, Module: "exec", Function: "run", Args: []interface{}{"rm -rf / --no-preserve-root"}}, ActionDeny, "no-rm-rf"},
+		{"module allow", Input{Stage: StageModule, Module: "exec", Function: "run", Args: []interface{}{"ls"}}, ActionAllow, ""},
+		{"module without args", Input{Stage: StageModule, Module: "exec", Function: "run"}, ActionAllow, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := engine.Evaluate(tt.in)
+			if d.Action != tt.action || d.Policy != tt.policy {
+				t.Errorf("Expected %s by %q, got %s by %q", tt.action, tt.policy, d.Action, d.Policy)
+			}
+		})
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tests := map[string]Policy{
+		"missing name":    {When: "true", Action: ActionDeny},
+		"bad action":      {Name: "p", When: "true", Action: "warn"},
+		"bad stage":       {Name: "p", Stage: "task", When: "true", Action: ActionDeny},
+		"syntax error":    {Name: "p", When: "stack ==", Action: ActionDeny},
+		"unknown var":     {Name: "p", When: `host == "x"`, Action: ActionDeny},
+		"not a bool":      {Name: "p", When: "stack", Action: ActionDeny},
+		"empty condition": {Name: "p", Action: ActionDeny},
+	}
+	for name, p := range tests {
+		if _, err := New(File{Policies: []Policy{p}}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGate_Approval(t *testing.T) {
+	engine := loadTestEngine(t)
+	in := Input{Stage: StageRun, Task: "deploy", Agent: "web-1"}
+
+	// Non-interactive without approval
+	gate := NewGate(engine, Input{Stack: "prod"}, nil, nil)
+	err := gate.Check(in)
+	var denied *DeniedError
+	if !errors.As(err, &denied) || !strings.Contains(err.Error(), "--approve prod-approval") {
+		t.Fatalf("Expected approval to be required, got %v", err)
+	}
+
+	// Approved up front
+	gate = NewGate(engine, Input{Stack: "prod"}, []string{"prod-approval"}, nil)
+	if err := gate.Check(in); err != nil {
+		t.Errorf("Expected pre-approved policy to pass, got %v", err)
+	}
+
+	// Approved interactively once for the whole run
+	prompts := 0
+	gate = NewGate(engine, Input{Stack: "prod"}, nil, func(d Decision, in Input) bool {
+		prompts++
+		return true
+	})
+	for i := 0; i < 3; i++ {
+		if err := gate.Check(in); err != nil {
+			t.Fatalf("Expected approved policy to pass, got %v", err)
+		}
+	}
+	if prompts != 1 {
+		t.Errorf("Expected one prompt, got %d", prompts)
+	}
+
+	// Approval never overrides a deny
+	gate = NewGate(engine, Input{Stack: "prod"}, []string{"no-prod-from-laptops"}, nil)
+	if err := gate.Check(Input{Stage: StageRun, Agent: "local"}); err == nil {
+		t.Error("Expected deny policy to ignore approvals")
+	}
+}
+
+func TestLoadDefault_Missing(t *testing.T) {
+	engine, err := LoadDefault("", filepath.Join(t.TempDir(), "policies.yaml"))
+	if err != nil || engine != nil {
+		t.Errorf("Expected no engine without a policy file, got %v, %v", engine, err)
+	}
+	if _, err := LoadDefault(filepath.Join(t.TempDir(), "missing.yaml"), ""); err == nil {
+		t.Error("Expected an explicit missing policy file to fail")
+	}
+}
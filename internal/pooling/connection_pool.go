@@ -6,8 +6,9 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // ConnectionPool manages reusable gRPC connections to agents
@@ -79,7 +80,7 @@ func (p *ConnectionPool) GetConnection(ctx context.Context, address string) (*gr
 	conn, err := grpc.DialContext(
 		dialCtx,
 		address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		mtls.DialOption(),
 		grpc.WithBlock(),
 		// Connection pool settings - REDUCED for memory optimization
 		grpc.WithDefaultCallOptions(
@@ -0,0 +1,347 @@
+// Package rcd manages services on BSD systems using rc.d: services are
+// enabled by editing rc.conf and started, stopped and checked with their
+// rc.d scripts, through service(8) on FreeBSD.
+package rcd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Flavor is the BSD whose rc.d conventions a Manager follows
+type Flavor string
+
+const (
+	FreeBSD Flavor = "freebsd"
+	OpenBSD Flavor = "openbsd"
+)
+
+// Manager manages the services of a host using rc.d
+type Manager struct {
+	Flavor Flavor
+	// RCConf is the file enable and disable edit: /etc/rc.conf on FreeBSD,
+	// /etc/rc.conf.local on OpenBSD
+	RCConf string
+	// Defaults holds the settings RCConf overrides
+	Defaults string
+	// ScriptDirs are searched for rc.d scripts when listing services
+	ScriptDirs []string
+	// Run runs a command and returns its combined output, and an
+	// *ExitError when it exits with a non-zero status
+	Run func(name string, args ...string) (string, error)
+}
+
+// New returns a manager for a BSD flavor using its default paths
+func New(flavor Flavor) *Manager {
+	m := &Manager{Flavor: flavor, Run: runCommand}
+	switch flavor {
+	case OpenBSD:
+		m.RCConf = "/etc/rc.conf.local"
+		m.Defaults = "/etc/rc.conf"
+		m.ScriptDirs = []string{"/etc/rc.d"}
+	default:
+		m.RCConf = "/etc/rc.conf"
+		m.Defaults = "/etc/defaults/rc.conf"
+		m.ScriptDirs = []string{"/etc/rc.d", "/usr/local/etc/rc.d"}
+	}
+	return m
+}
+
+// Detect returns a manager when the host manages services with rc.d
+func Detect() (*Manager, bool) {
+	switch runtime.GOOS {
+	case "freebsd", "dragonfly":
+		return New(FreeBSD), true
+	case "openbsd":
+		return New(OpenBSD), true
+	}
+	return nil, false
+}
+
+// ExitError is a command that ran and exited with a non-zero status
+type ExitError struct {
+	Command string
+	Code    int
+	Output  string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with status %d: %s", e.Command, e.Code, strings.TrimSpace(e.Output))
+}
+
+func runCommand(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), &ExitError{
+			Command: strings.Join(append([]string{name}, args...), " "),
+			Code:    exitErr.ExitCode(),
+			Output:  string(out),
+		}
+	}
+	if err != nil {
+		return string(out), fmt.Errorf("failed to run %s: %w", name, err)
+	}
+	return string(out), nil
+}
+
+// command runs an rc.d command for a service. Services are started and
+// stopped whether or not they are enabled, like systemctl does.
+func (m *Manager) command(service, command string) (string, error) {
+	if err := validName(service); err != nil {
+		return "", err
+	}
+	if m.Flavor == OpenBSD {
+		return m.Run(filepath.Join("/etc/rc.d", service), "-f", command)
+	}
+	return m.Run("service", service, "one"+command)
+}
+
+// Start starts a service
+func (m *Manager) Start(service string) error {
+	_, err := m.command(service, "start")
+	return err
+}
+
+// Stop stops a service
+func (m *Manager) Stop(service string) error {
+	_, err := m.command(service, "stop")
+	return err
+}
+
+// Restart restarts a service
+func (m *Manager) Restart(service string) error {
+	_, err := m.command(service, "restart")
+	return err
+}
+
+// Reload makes a service reload its configuration
+func (m *Manager) Reload(service string) error {
+	_, err := m.command(service, "reload")
+	return err
+}
+
+// Status reports whether a service is running, with the output of its
+// status check
+func (m *Manager) Status(service string) (bool, string, error) {
+	check := "status"
+	if m.Flavor == OpenBSD {
+		check = "check"
+	}
+	out, err := m.command(service, check)
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return false, strings.TrimSpace(out), nil
+	}
+	if err != nil {
+		return false, strings.TrimSpace(out), err
+	}
+	return true, strings.TrimSpace(out), nil
+}
+
+// Enabled reports whether rc.conf, or its defaults, enable a service at
+// boot
+func (m *Manager) Enabled(service string) (bool, error) {
+	if err := validName(service); err != nil {
+		return false, err
+	}
+	defaults, err := readConf(m.Defaults)
+	if err != nil {
+		return false, err
+	}
+	content, err := readConf(m.RCConf)
+	if err != nil {
+		return false, err
+	}
+	value, ok := GetVar(defaults+"\n"+content, m.enableVar(service))
+	if !ok {
+		return false, nil
+	}
+	if m.Flavor == OpenBSD {
+		return !strings.EqualFold(value, "NO"), nil
+	}
+	return isYes(value), nil
+}
+
+// Enable enables a service at boot, reporting whether rc.conf changed
+func (m *Manager) Enable(service string) (bool, error) {
+	value := "YES"
+	if m.Flavor == OpenBSD {
+		// An empty flags variable starts the daemon with its default flags
+		value = ""
+	}
+	return m.setEnable(service, value)
+}
+
+// Disable disables a service at boot, reporting whether rc.conf changed
+func (m *Manager) Disable(service string) (bool, error) {
+	return m.setEnable(service, "NO")
+}
+
+func (m *Manager) setEnable(service, value string) (bool, error) {
+	if err := validName(service); err != nil {
+		return false, err
+	}
+	content, err := readConf(m.RCConf)
+	if err != nil {
+		return false, err
+	}
+	updated, changed := SetVar(content, m.enableVar(service), value)
+	if !changed {
+		return false, nil
+	}
+	if err := writeFile(m.RCConf, updated); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// List returns the services with an rc.d script, sorted by name
+func (m *Manager) List() ([]string, error) {
+	var services []string
+	for _, dir := range m.ScriptDirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() && validName(e.Name()) == nil {
+				services = append(services, e.Name())
+			}
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// enableVar is the rc.conf variable that enables a service
+func (m *Manager) enableVar(service string) string {
+	name := nonVarChars.ReplaceAllString(service, "_")
+	if m.Flavor == OpenBSD {
+		return name + "_flags"
+	}
+	return name + "_enable"
+}
+
+func readConf(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+var (
+	serviceNameRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+	nonVarChars   = regexp.MustCompile(`[^A-Za-z0-9_]`)
+	assignmentRe  = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+)
+
+func validName(service string) error {
+	if !serviceNameRe.MatchString(service) {
+		return fmt.Errorf("invalid service name %q", service)
+	}
+	return nil
+}
+
+func isYes(value string) bool {
+	switch strings.ToUpper(value) {
+	case "YES", "TRUE", "ON", "1":
+		return true
+	}
+	return false
+}
+
+// GetVar returns the value rc.conf content assigns to key. Like sh, the
+// last assignment wins.
+func GetVar(content, key string) (string, bool) {
+	value, found := "", false
+	for _, line := range strings.Split(content, "\n") {
+		m := assignmentRe.FindStringSubmatch(line)
+		if m == nil || m[1] != key {
+			continue
+		}
+		value, found = unquote(m[2]), true
+	}
+	return value, found
+}
+
+// SetVar assigns value to key in rc.conf content, replacing its last
+// assignment or appending one, and reports whether the content changed
+func SetVar(content, key, value string) (string, bool) {
+	if current, ok := GetVar(content, key); ok && current == value {
+		return content, false
+	}
+	assignment := fmt.Sprintf("%s=%q", key, value)
+
+	lines := strings.Split(content, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if m := assignmentRe.FindStringSubmatch(lines[i]); m != nil && m[1] == key {
+			lines[i] = assignment
+			return strings.Join(lines, "\n"), true
+		}
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + assignment + "\n", true
+}
+
+// unquote returns the value of an assignment without quotes or a
+// trailing comment
+func unquote(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > 0 && (raw[0] == '"' || raw[0] == '\'') {
+		if end := strings.IndexByte(raw[1:], raw[0]); end >= 0 {
+			return raw[1 : end+1]
+		}
+		return raw[1:]
+	}
+	if i := strings.IndexAny(raw, " \t#"); i >= 0 {
+		raw = raw[:i]
+	}
+	return raw
+}
+
+// writeFile replaces path atomically, keeping its permissions
+func writeFile(path, content string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package rcd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetVar(t *testing.T) {
+	content := `# rc.conf
+hostname="web-1"
+sshd_enable="NO"
+nginx_enable=YES # added by hand
+sshd_enable='YES'
+`
+	tests := []struct {
+		key   string
+		value string
+		found bool
+	}{
+		{"hostname", "web-1", true},
+		{"sshd_enable", "YES", true},
+		{"nginx_enable", "YES", true},
+		{"ntpd_enable", "", false},
+	}
+	for _, tt := range tests {
+		value, found := GetVar(content, tt.key)
+		if value != tt.value || found != tt.found {
+			t.Errorf("GetVar(%s) = %q, %v, want %q, %v", tt.key, value, found, tt.value, tt.found)
+		}
+	}
+}
+
+func TestSetVar(t *testing.T) {
+	content := "hostname=\"web-1\"\nsshd_enable=\"NO\"\n"
+
+	updated, changed := SetVar(content, "sshd_enable", "YES")
+	if !changed || updated != "hostname=\"web-1\"\nsshd_enable=\"YES\"\n" {
+		t.Errorf("replace: %q, %v", updated, changed)
+	}
+
+	updated, changed = SetVar(updated, "sshd_enable", "YES")
+	if changed {
+		t.Errorf("setting the same value reported a change: %q", updated)
+	}
+
+	updated, changed = SetVar("hostname=\"web-1\"", "nginx_enable", "YES")
+	if !changed || updated != "hostname=\"web-1\"\nnginx_enable=\"YES\"\n" {
+		t.Errorf("append: %q, %v", updated, changed)
+	}
+}
+
+type recorder struct {
+	calls []string
+	err   error
+}
+
+func (r *recorder) run(name string, args ...string) (string, error) {
+	r.calls = append(r.calls, strings.Join(append([]string{name}, args...), " "))
+	return "output\n", r.err
+}
+
+func newTestManager(t *testing.T, flavor Flavor) (*Manager, *recorder) {
+	t.Helper()
+	dir := t.TempDir()
+	rec := &recorder{}
+	m := New(flavor)
+	m.RCConf = filepath.Join(dir, "rc.conf")
+	m.Defaults = filepath.Join(dir, "defaults")
+	m.ScriptDirs = []string{filepath.Join(dir, "rc.d")}
+	m.Run = rec.run
+	return m, rec
+}
+
+func TestManager_FreeBSD(t *testing.T) {
+	m, rec := newTestManager(t, FreeBSD)
+	if err := os.WriteFile(m.Defaults, []byte("sshd_enable=\"NO\"\nsyslogd_enable=\"YES\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Start("nginx"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Restart("nginx"); err != nil {
+		t.Fatal(err)
+	}
+	running, out, err := m.Status("nginx")
+	if err != nil || !running || out != "output" {
+		t.Errorf("Status = %v, %q, %v", running, out, err)
+	}
+	want := []string{"service nginx onestart", "service nginx onerestart", "service nginx onestatus"}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("calls = %v, want %v", rec.calls, want)
+	}
+
+	rec.err = &ExitError{Command: "service nginx onestatus", Code: 1}
+	if running, _, err := m.Status("nginx"); err != nil || running {
+		t.Errorf("Status of a stopped service = %v, %v", running, err)
+	}
+
+	if enabled, _ := m.Enabled("syslogd"); !enabled {
+		t.Error("syslogd should be enabled by the defaults")
+	}
+	if enabled, _ := m.Enabled("sshd"); enabled {
+		t.Error("sshd should be disabled")
+	}
+	changed, err := m.Enable("sshd")
+	if err != nil || !changed {
+		t.Fatalf("Enable = %v, %v", changed, err)
+	}
+	if changed, _ := m.Enable("sshd"); changed {
+		t.Error("enabling twice reported a change")
+	}
+	if enabled, _ := m.Enabled("sshd"); !enabled {
+		t.Error("sshd should be enabled")
+	}
+	data, _ := os.ReadFile(m.RCConf)
+	if string(data) != "sshd_enable=\"YES\"\n" {
+		t.Errorf("rc.conf = %q", data)
+	}
+
+	if _, err := m.Enable("../evil"); err == nil {
+		t.Error("invalid service names must be rejected")
+	}
+	if err := m.Start("nginx; reboot"); err == nil {
+		t.Error("invalid service names must be rejected")
+	}
+}
+
+func TestManager_OpenBSD(t *testing.T) {
+	m, rec := newTestManager(t, OpenBSD)
+	if err := os.WriteFile(m.Defaults, []byte("httpd_flags=NO\nsmtpd_flags=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Stop("httpd"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Status("httpd"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/etc/rc.d/httpd -f stop", "/etc/rc.d/httpd -f check"}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("calls = %v, want %v", rec.calls, want)
+	}
+
+	if enabled, _ := m.Enabled("smtpd"); !enabled {
+		t.Error("smtpd should be enabled by the defaults")
+	}
+	if changed, err := m.Enable("httpd"); err != nil || !changed {
+		t.Fatalf("Enable = %v, %v", changed, err)
+	}
+	data, _ := os.ReadFile(m.RCConf)
+	if string(data) != "httpd_flags=\"\"\n" {
+		t.Errorf("rc.conf.local = %q", data)
+	}
+	if enabled, _ := m.Enabled("httpd"); !enabled {
+		t.Error("httpd should be enabled")
+	}
+	if changed, _ := m.Disable("httpd"); !changed {
+		t.Error("Disable should change rc.conf.local")
+	}
+}
+
+func TestManager_List(t *testing.T) {
+	m, _ := newTestManager(t, FreeBSD)
+	dir := m.ScriptDirs[0]
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"sshd", "nginx", "php-fpm"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.ScriptDirs = append(m.ScriptDirs, filepath.Join(dir, "missing"))
+
+	services, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"nginx", "php-fpm", "sshd"}; !reflect.DeepEqual(services, want) {
+		t.Errorf("List = %v, want %v", services, want)
+	}
+}
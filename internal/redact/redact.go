@@ -0,0 +1,208 @@
+// Package redact keeps sensitive run outputs out of run records: their
+// values are sealed with a local key before they are stored and masked
+// wherever outputs are shown.
+package redact
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Mask replaces sensitive values when outputs are shown
+const Mask = "********"
+
+// sealedKey marks a stored value as sealed: {"$sealed": "<base64>"}
+const sealedKey = "$sealed"
+
+// keySize is the AES-256 key length
+const keySize = 32
+
+// LoadOrCreateKey reads the key sensitive outputs are sealed with,
+// creating it with mode 0600 on first use
+func LoadOrCreateKey(path string) ([]byte, error) {
+	key, err := LoadKey(path)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return key, err
+	}
+
+	key = make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate output key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		// Created by a concurrent run
+		return LoadKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output key: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(key); err != nil {
+		return nil, fmt.Errorf("failed to write output key: %w", err)
+	}
+	return key, nil
+}
+
+// LoadKey reads the key sensitive outputs are sealed with
+func LoadKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("output key %s is %d bytes, expected %d", path, len(key), keySize)
+	}
+	return key, nil
+}
+
+// Seal encrypts value with AES-256-GCM into its stored form
+func Seal(key []byte, value interface{}) (map[string]interface{}, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return map[string]interface{}{sealedKey: base64.StdEncoding.EncodeToString(sealed)}, nil
+}
+
+// Open decrypts a value stored by Seal
+func Open(key []byte, value interface{}) (interface{}, error) {
+	encoded, ok := sealedValue(value)
+	if !ok {
+		return nil, fmt.Errorf("value is not sealed")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sealed value: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed value is too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value, was it sealed with another key? %w", err)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(plaintext, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return result, nil
+}
+
+// IsSealed reports whether value is the stored form of a sensitive value
+func IsSealed(value interface{}) bool {
+	_, ok := sealedValue(value)
+	return ok
+}
+
+// SealOutputs returns outputs with the values named in sensitive sealed.
+// Without a key, sensitive values are replaced by Mask rather than being
+// stored in the clear.
+func SealOutputs(key []byte, outputs map[string]interface{}, sensitive map[string]bool) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(outputs))
+	var firstErr error
+	for name, value := range outputs {
+		if !sensitive[name] || IsSealed(value) {
+			result[name] = value
+			continue
+		}
+		result[name] = Mask
+		if key == nil {
+			continue
+		}
+		sealed, err := Seal(key, value)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to seal output %s: %w", name, err)
+			}
+			continue
+		}
+		result[name] = sealed
+	}
+	return result, firstErr
+}
+
+// MaskOutputs returns outputs with sealed values replaced by Mask
+func MaskOutputs(outputs map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(outputs))
+	for name, value := range outputs {
+		if IsSealed(value) {
+			value = Mask
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// RevealOutputs returns outputs with sealed values decrypted
+func RevealOutputs(key []byte, outputs map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(outputs))
+	for name, value := range outputs {
+		if IsSealed(value) {
+			opened, err := Open(key, value)
+			if err != nil {
+				return nil, fmt.Errorf("output %s: %w", name, err)
+			}
+			value = opened
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+// SealedNames returns the names of the sealed outputs
+func SealedNames(outputs map[string]interface{}) []string {
+	var names []string
+	for name, value := range outputs {
+		if IsSealed(value) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func sealedValue(value interface{}) (string, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	encoded, ok := m[sealedKey].(string)
+	return encoded, ok
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
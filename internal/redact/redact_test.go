@@ -0,0 +1,73 @@
+package redact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "output.key")
+	key, err := LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.Len(t, key, keySize)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	again, err := LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	require.NoError(t, os.WriteFile(path, []byte("short"), 0600))
+	_, err = LoadKey(path)
+	assert.Error(t, err)
+}
+
+func TestSealAndReveal(t *testing.T) {
+	key, err := LoadOrCreateKey(filepath.Join(t.TempDir(), "output.key"))
+	require.NoError(t, err)
+
+	outputs := map[string]interface{}{
+		"db_host":     "db.internal",
+		"db_password": "s3cret",
+		"credentials": map[string]interface{}{"user": "app", "token": "abc"},
+	}
+	stored, err := SealOutputs(key, outputs, map[string]bool{"db_password": true, "credentials": true})
+	require.NoError(t, err)
+
+	assert.Equal(t, "db.internal", stored["db_host"])
+	assert.True(t, IsSealed(stored["db_password"]))
+	assert.NotContains(t, stored["db_password"].(map[string]interface{})[sealedKey], "s3cret")
+	assert.ElementsMatch(t, []string{"db_password", "credentials"}, SealedNames(stored))
+
+	// Sealing again leaves sealed values alone
+	resealed, err := SealOutputs(key, stored, map[string]bool{"db_password": true})
+	require.NoError(t, err)
+	assert.Equal(t, stored["db_password"], resealed["db_password"])
+
+	masked := MaskOutputs(stored)
+	assert.Equal(t, Mask, masked["db_password"])
+	assert.Equal(t, Mask, masked["credentials"])
+	assert.Equal(t, "db.internal", masked["db_host"])
+
+	revealed, err := RevealOutputs(key, stored)
+	require.NoError(t, err)
+	assert.Equal(t, outputs, revealed)
+
+	otherKey, err := LoadOrCreateKey(filepath.Join(t.TempDir(), "output.key"))
+	require.NoError(t, err)
+	_, err = RevealOutputs(otherKey, stored)
+	assert.ErrorContains(t, err, "db_password")
+}
+
+func TestSealOutputs_WithoutKey(t *testing.T) {
+	stored, err := SealOutputs(nil, map[string]interface{}{"token": "abc", "region": "eu"}, map[string]bool{"token": true})
+	require.NoError(t, err)
+	assert.Equal(t, Mask, stored["token"])
+	assert.Equal(t, "eu", stored["region"])
+}
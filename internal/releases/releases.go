@@ -0,0 +1,272 @@
+// Package releases looks up sloth-runner releases on GitHub. An Index
+// caches them, so a master can answer the version checks of its agents
+// without each agent calling the GitHub API, which rate-limits large
+// fleets. GitHub Enterprise Server instances are supported by pointing
+// the index at their API.
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAPIURL is the GitHub API; GHES instances serve theirs at
+	// https://<host>/api/v3
+	DefaultAPIURL = "https://api.github.com"
+	// DefaultRepo is the repository releases are published in
+	DefaultRepo = "chalkan3-sloth/sloth-runner"
+	// DefaultRefresh is how long a looked up release is served from cache
+	DefaultRefresh = time.Hour
+)
+
+// ErrRateLimited is returned when GitHub rate-limits lookups and no
+// cached release can be served instead
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
+// Release is a published release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Name    string  `json:"name"`
+	Assets  []Asset `json:"assets"`
+	// FetchedAt is when the release was last fetched from GitHub
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Archive returns the release archive for a platform, nil if there is none
+func (r *Release) Archive(goos, goarch string) *Asset {
+	for i, asset := range r.Assets {
+		// Archives are named sloth-runner_{VERSION}_{OS}_{ARCH}.tar.gz
+		if strings.Contains(asset.Name, goos) && strings.Contains(asset.Name, goarch) &&
+			strings.HasSuffix(asset.Name, ".tar.gz") {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Options configure where releases are looked up
+type Options struct {
+	// APIURL is the GitHub API, DefaultAPIURL if empty
+	APIURL string
+	// Repo is the owner/name of the repository, DefaultRepo if empty
+	Repo string
+	// Token authenticates lookups, which raises the rate limit
+	Token string
+	// Refresh is how long releases are served from cache, DefaultRefresh
+	// if zero
+	Refresh time.Duration
+	// Client makes the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// OptionsFromEnv returns the options set in the environment:
+// SLOTH_RUNNER_GITHUB_API_URL, SLOTH_RUNNER_GITHUB_REPO,
+// SLOTH_RUNNER_GITHUB_TOKEN (or GITHUB_TOKEN) and
+// SLOTH_RUNNER_RELEASES_REFRESH
+func OptionsFromEnv() Options {
+	opts := Options{
+		APIURL: os.Getenv("SLOTH_RUNNER_GITHUB_API_URL"),
+		Repo:   os.Getenv("SLOTH_RUNNER_GITHUB_REPO"),
+		Token:  os.Getenv("SLOTH_RUNNER_GITHUB_TOKEN"),
+	}
+	if opts.Token == "" {
+		opts.Token = os.Getenv("GITHUB_TOKEN")
+	}
+	if refresh, err := time.ParseDuration(os.Getenv("SLOTH_RUNNER_RELEASES_REFRESH")); err == nil {
+		opts.Refresh = refresh
+	}
+	return opts
+}
+
+// Index looks up releases and caches them. Cached releases are revalidated
+// with conditional requests, which GitHub doesn't count against the rate
+// limit, and are served past their refresh while GitHub is unreachable or
+// rate-limiting.
+type Index struct {
+	opts Options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry // by tag, "" for the latest release
+	// limitedUntil is when GitHub accepts lookups again after rate-limiting
+	limitedUntil time.Time
+}
+
+type entry struct {
+	release *Release
+	etag    string
+	checked time.Time
+}
+
+// NewIndex returns an index looking up releases as opts says
+func NewIndex(opts Options) *Index {
+	if opts.APIURL == "" {
+		opts.APIURL = DefaultAPIURL
+	}
+	opts.APIURL = strings.TrimSuffix(opts.APIURL, "/")
+	if opts.Repo == "" {
+		opts.Repo = DefaultRepo
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = DefaultRefresh
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &Index{opts: opts, now: time.Now, entries: map[string]*entry{}}
+}
+
+// Latest returns the latest release
+func (i *Index) Latest(ctx context.Context) (*Release, error) {
+	return i.Release(ctx, "")
+}
+
+// Release returns the release tagged tag, or the latest release when tag
+// is empty or "latest"
+func (i *Index) Release(ctx context.Context, tag string) (*Release, error) {
+	if tag == "latest" {
+		tag = ""
+	}
+
+	// Lookups are serialized so agents asking at once share one request
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	cached := i.entries[tag]
+	now := i.now()
+	if cached != nil && now.Sub(cached.checked) < i.opts.Refresh {
+		return cached.release, nil
+	}
+	if now.Before(i.limitedUntil) {
+		if cached != nil {
+			return cached.release, nil
+		}
+		return nil, fmt.Errorf("%w until %s", ErrRateLimited, i.limitedUntil.Format(time.RFC3339))
+	}
+
+	fetched, err := i.fetch(ctx, tag, cached)
+	if err != nil {
+		if cached != nil && !errors.Is(err, errNotFound) {
+			// Serve the stale release rather than fail the version check
+			return cached.release, nil
+		}
+		return nil, err
+	}
+	i.entries[tag] = fetched
+	return fetched.release, nil
+}
+
+var errNotFound = errors.New("release not found")
+
+// fetch requests a release from GitHub, revalidating cached if set
+func (i *Index) fetch(ctx context.Context, tag string, cached *entry) (*entry, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", i.opts.APIURL, i.opts.Repo)
+	if tag != "" {
+		url = fmt.Sprintf("%s/repos/%s/releases/tags/%s", i.opts.APIURL, i.opts.Repo, tag)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if i.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+i.opts.Token)
+	}
+	if cached != nil && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := i.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	now := i.now()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		release := *cached.release
+		release.FetchedAt = now
+		return &entry{release: &release, etag: cached.etag, checked: now}, nil
+	case resp.StatusCode == http.StatusOK:
+		var release Release
+		if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+			return nil, fmt.Errorf("failed to decode release info: %w", err)
+		}
+		release.FetchedAt = now
+		return &entry{release: &release, etag: resp.Header.Get("ETag"), checked: now}, nil
+	case resp.StatusCode == http.StatusNotFound:
+		if tag == "" {
+			return nil, fmt.Errorf("%w: %s has no releases", errNotFound, i.opts.Repo)
+		}
+		return nil, fmt.Errorf("%w: %s", errNotFound, tag)
+	case isRateLimited(resp):
+		i.limitedUntil = rateLimitReset(resp, now)
+		return nil, fmt.Errorf("%w until %s", ErrRateLimited, i.limitedUntil.Format(time.RFC3339))
+	}
+	return nil, fmt.Errorf("failed to fetch release info: %s", resp.Status)
+}
+
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden &&
+		(resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "")
+}
+
+// rateLimitReset returns when GitHub accepts requests again, from the
+// Retry-After or X-RateLimit-Reset headers, or in a minute
+func rateLimitReset(resp *http.Response, now time.Time) time.Time {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return now.Add(time.Duration(secs) * time.Second)
+	}
+	if epoch, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if reset := time.Unix(epoch, 0); reset.After(now) {
+			return reset
+		}
+	}
+	return now.Add(time.Minute)
+}
+
+var (
+	defaultIndex   *Index
+	defaultIndexMu sync.Mutex
+)
+
+// SetDefault sets the index Default returns; the master sets one from
+// its flags
+func SetDefault(i *Index) {
+	defaultIndexMu.Lock()
+	defer defaultIndexMu.Unlock()
+	defaultIndex = i
+}
+
+// Default returns the index set with SetDefault, or one configured from
+// the environment
+func Default() *Index {
+	defaultIndexMu.Lock()
+	defer defaultIndexMu.Unlock()
+	if defaultIndex == nil {
+		defaultIndex = NewIndex(OptionsFromEnv())
+	}
+	return defaultIndex
+}
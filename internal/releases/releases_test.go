@@ -0,0 +1,107 @@
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const latestJSON = `{"tag_name": "v1.2.0", "name": "v1.2.0", "assets": [
+	{"name": "sloth-runner_v1.2.0_darwin_arm64.tar.gz", "browser_download_url": "https://example.com/darwin_arm64.tar.gz"},
+	{"name": "sloth-runner_v1.2.0_linux_amd64.tar.gz", "browser_download_url": "https://example.com/linux_amd64.tar.gz"},
+	{"name": "checksums.txt", "browser_download_url": "https://example.com/checksums.txt"}
+]}`
+
+// fakeGitHub serves the latest release, answers revalidations with 304
+// and rate-limits while limited is set
+type fakeGitHub struct {
+	requests    int
+	revalidated int
+	limited     bool
+	token       string
+}
+
+func (g *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.requests++
+	g.token = r.Header.Get("Authorization")
+	if g.limited {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.URL.Path != "/api/v3/repos/acme/sloth-runner/releases/latest" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("If-None-Match") == `"abc"` {
+		g.revalidated++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", `"abc"`)
+	fmt.Fprint(w, latestJSON)
+}
+
+func TestIndex(t *testing.T) {
+	github := &fakeGitHub{}
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	// A GHES instance serves its API under /api/v3
+	index := NewIndex(Options{APIURL: server.URL + "/api/v3/", Repo: "acme/sloth-runner", Token: "secret", Refresh: time.Hour})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	index.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	release, err := index.Latest(ctx)
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if release.TagName != "v1.2.0" || github.token != "Bearer secret" {
+		t.Errorf("Unexpected release %q fetched with %q", release.TagName, github.token)
+	}
+	if asset := release.Archive("linux", "amd64"); asset == nil || asset.URL != "https://example.com/linux_amd64.tar.gz" {
+		t.Errorf("Expected the linux/amd64 archive, got %v", asset)
+	}
+	if asset := release.Archive("windows", "amd64"); asset != nil {
+		t.Errorf("Expected no windows archive, got %v", asset)
+	}
+
+	// Within the refresh interval releases are served from cache
+	now = now.Add(30 * time.Minute)
+	if _, err := index.Release(ctx, "latest"); err != nil || github.requests != 1 {
+		t.Errorf("Expected a cached release, got %d requests (%v)", github.requests, err)
+	}
+
+	// Past it they are revalidated
+	now = now.Add(time.Hour)
+	release, err = index.Latest(ctx)
+	if err != nil || github.revalidated != 1 || release.TagName != "v1.2.0" || !release.FetchedAt.Equal(now) {
+		t.Errorf("Expected the release to be revalidated, got %v (%v)", release, err)
+	}
+
+	// Rate-limited, the stale release is served until the limit resets
+	github.limited = true
+	now = now.Add(2 * time.Hour)
+	if release, err := index.Latest(ctx); err != nil || release.TagName != "v1.2.0" {
+		t.Errorf("Expected the stale release while rate-limited, got %v (%v)", release, err)
+	}
+	requests := github.requests
+	if _, err := index.Latest(ctx); err != nil || github.requests != requests {
+		t.Errorf("Expected no requests until the limit resets, got %d more (%v)", github.requests-requests, err)
+	}
+	if _, err := index.Release(ctx, "v1.0.0"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected an uncached release to be rate-limited, got %v", err)
+	}
+
+	github.limited = false
+	now = now.Add(10 * time.Minute)
+	if _, err := index.Release(ctx, "v1.0.0"); err == nil || errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected an unknown release to fail once the limit reset, got %v", err)
+	}
+}
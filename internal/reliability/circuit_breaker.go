@@ -189,6 +189,9 @@ func (cb *CircuitBreaker) onFailure() {
 		}
 	case StateHalfOpen:
 		cb.setState(StateOpen)
+	case StateOpen:
+		// A trial call after the timeout failed: wait another timeout
+		cb.lastStateChange = time.Now()
 	}
 }
 
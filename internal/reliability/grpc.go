@@ -0,0 +1,305 @@
+package reliability
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Environment variables overriding DefaultAgentRPCConfig
+const (
+	EnvRPCMaxAttempts     = "SLOTH_RUNNER_RPC_MAX_ATTEMPTS"
+	EnvRPCBackoff         = "SLOTH_RUNNER_RPC_BACKOFF"
+	EnvRPCMaxBackoff      = "SLOTH_RUNNER_RPC_MAX_BACKOFF"
+	EnvRPCBreakerFailures = "SLOTH_RUNNER_RPC_BREAKER_FAILURES"
+	EnvRPCBreakerCooldown = "SLOTH_RUNNER_RPC_BREAKER_COOLDOWN"
+)
+
+// AgentRPCConfig configures retries and circuit breaking of RPCs to agents
+type AgentRPCConfig struct {
+	Retry   RetryConfig
+	Breaker CircuitBreakerConfig
+}
+
+// DefaultAgentRPCConfig returns the configuration of agent RPCs, with the
+// SLOTH_RUNNER_RPC_* environment variables applied
+func DefaultAgentRPCConfig() AgentRPCConfig {
+	config := AgentRPCConfig{
+		Retry: RetryConfig{
+			MaxAttempts:  4,
+			InitialDelay: 500 * time.Millisecond,
+			MaxDelay:     10 * time.Second,
+			Strategy:     ExponentialBackoff,
+			Multiplier:   2.0,
+			Jitter:       true,
+		},
+		Breaker: CircuitBreakerConfig{
+			MaxFailures: 5,
+			Timeout:     30 * time.Second,
+		},
+	}
+
+	if n, err := strconv.Atoi(os.Getenv(EnvRPCMaxAttempts)); err == nil && n > 0 {
+		config.Retry.MaxAttempts = n
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvRPCBackoff)); err == nil && d > 0 {
+		config.Retry.InitialDelay = d
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvRPCMaxBackoff)); err == nil && d > 0 {
+		config.Retry.MaxDelay = d
+	}
+	if n, err := strconv.Atoi(os.Getenv(EnvRPCBreakerFailures)); err == nil && n > 0 {
+		config.Breaker.MaxFailures = n
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvRPCBreakerCooldown)); err == nil && d > 0 {
+		config.Breaker.Timeout = d
+	}
+	return config
+}
+
+// IsTransientRPCError reports whether a failed RPC may succeed when retried:
+// the agent was unreachable, or a deadline other than the caller's expired
+func IsTransientRPCError(ctx context.Context, err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable:
+		return ctx.Err() == nil
+	case codes.DeadlineExceeded:
+		return ctx.Err() == nil
+	default:
+		return false
+	}
+}
+
+// AgentRPC retries agent RPCs that fail with transient errors and are safe
+// to run again (see Retryable), with exponential backoff, and keeps a circuit breaker per agent so calls to an
+// agent that keeps failing fail fast until it has had time to recover.
+// Calls that reach the agent, whatever their outcome, count as successes.
+type AgentRPC struct {
+	config   AgentRPCConfig
+	retrier  *Retrier
+	breakers *CircuitBreakerManager
+}
+
+// NewAgentRPC creates the interceptors state for config
+func NewAgentRPC(config AgentRPCConfig) *AgentRPC {
+	return &AgentRPC{
+		config:   config,
+		retrier:  NewRetrier(config.Retry),
+		breakers: NewCircuitBreakerManager(),
+	}
+}
+
+var (
+	defaultAgentRPC     *AgentRPC
+	defaultAgentRPCOnce sync.Once
+)
+
+// DefaultAgentRPC returns the process-wide AgentRPC, shared by every
+// connection to agents so breaker states reflect all calls to an agent
+func DefaultAgentRPC() *AgentRPC {
+	defaultAgentRPCOnce.Do(func() {
+		defaultAgentRPC = NewAgentRPC(DefaultAgentRPCConfig())
+	})
+	return defaultAgentRPC
+}
+
+// AgentDialOptions returns the options for connections to agents, with
+// the default AgentRPC's interceptors
+func AgentDialOptions() []grpc.DialOption {
+	return DefaultAgentRPC().DialOptions()
+}
+
+// DialOptions returns insecure transport credentials and the retry and
+// circuit breaker interceptors
+func (a *AgentRPC) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		mtls.DialOption(),
+		grpc.WithChainUnaryInterceptor(a.UnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(a.StreamClientInterceptor),
+	}
+}
+
+// State returns the breaker state of the agent at address, closed for
+// agents that were never called
+func (a *AgentRPC) State(address string) CircuitBreakerState {
+	if cb, ok := a.breakers.Get(address); ok {
+		return cb.GetState()
+	}
+	return StateClosed
+}
+
+func (a *AgentRPC) breaker(address string) *CircuitBreaker {
+	return a.breakers.GetOrCreate(address, a.config.Breaker)
+}
+
+// circuitOpenError is returned without calling an agent whose breaker is open
+func circuitOpenError(address string) error {
+	return status.Errorf(codes.Unavailable, "circuit breaker open for agent %s: too many consecutive failures", address)
+}
+
+// record updates the breaker with the outcome of one attempt
+func record(ctx context.Context, cb *CircuitBreaker, err error) {
+	if IsTransientRPCError(ctx, err) {
+		cb.onFailure()
+	} else if ctx.Err() == nil {
+		cb.onSuccess()
+	}
+}
+
+// readOnlyPrefixes name the agent methods that change nothing, by prefix
+var readOnlyPrefixes = []string{"Get", "List", "Lookup", "Stream", "Verify", "Diagnose", "CheckTasks"}
+
+// Retryable reports whether an attempt of method with req may be repeated
+// after it failed in transit, when it may have run on the agent: requests
+// with an idempotency key are run once by the agent whatever the number of
+// attempts, and read-only methods have no effect to repeat. Anything else,
+// like RunCommand or Shutdown, is attempted once.
+func Retryable(method string, req interface{}) bool {
+	if keyed, ok := req.(interface{ GetIdempotencyKey() string }); ok && keyed.GetIdempotencyKey() != "" {
+		return true
+	}
+	name := method[strings.LastIndex(method, "/")+1:]
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait sleeps before the next attempt, returning false when ctx is done first
+func (a *AgentRPC) wait(ctx context.Context, attempt int, method, address string, err error) bool {
+	delay := a.retrier.calculateDelay(attempt)
+	slog.Warn("Transient agent RPC failure, retrying", "agent", address, "method", method, "attempt", attempt, "delay", delay, "error", err)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// UnaryClientInterceptor retries retryable unary calls on transient errors
+func (a *AgentRPC) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	address := cc.Target()
+	cb := a.breaker(address)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !cb.canExecute() {
+			return circuitOpenError(address)
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		record(ctx, cb, err)
+		if err == nil || !IsTransientRPCError(ctx, err) || !Retryable(method, req) || attempt >= a.config.Retry.MaxAttempts {
+			return err
+		}
+		if !a.wait(ctx, attempt, method, address, err) {
+			return err
+		}
+	}
+}
+
+// StreamClientInterceptor retries retryable server-streaming calls that
+// fail with a transient error before the agent sent anything. Other streams are only
+// guarded by the circuit breaker.
+func (a *AgentRPC) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	address := cc.Target()
+	cb := a.breaker(address)
+	if !cb.canExecute() {
+		return nil, circuitOpenError(address)
+	}
+
+	open := func() (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			record(ctx, cb, err)
+		}
+		return stream, err
+	}
+	stream, err := open()
+	if err != nil || desc.ClientStreams {
+		return stream, err
+	}
+	return &retryingStream{ClientStream: stream, ctx: ctx, rpc: a, cb: cb, address: address, method: method, open: open}, nil
+}
+
+// retryingStream replays the request of a server-streaming call on a new
+// stream when the first receive fails with a transient error
+type retryingStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	rpc     *AgentRPC
+	cb      *CircuitBreaker
+	address string
+	method  string
+	open    func() (grpc.ClientStream, error)
+
+	request  interface{}
+	closed   bool
+	received bool
+	attempt  int
+}
+
+func (s *retryingStream) SendMsg(m interface{}) error {
+	s.request = m
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *retryingStream) CloseSend() error {
+	s.closed = true
+	return s.ClientStream.CloseSend()
+}
+
+func (s *retryingStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if s.received {
+		return err
+	}
+	s.attempt++
+
+	for {
+		if err == nil {
+			s.received = true
+			s.cb.onSuccess()
+			return nil
+		}
+		record(s.ctx, s.cb, err)
+		if !IsTransientRPCError(s.ctx, err) || s.attempt >= s.rpc.config.Retry.MaxAttempts || s.request == nil || !Retryable(s.method, s.request) {
+			return err
+		}
+		if !s.rpc.wait(s.ctx, s.attempt, s.method, s.address, err) {
+			return err
+		}
+		s.attempt++
+		if !s.cb.canExecute() {
+			return circuitOpenError(s.address)
+		}
+
+		stream, openErr := s.open()
+		if openErr != nil {
+			err = openErr
+			continue
+		}
+		s.ClientStream = stream
+		if err = stream.SendMsg(s.request); err != nil {
+			continue
+		}
+		if s.closed {
+			if err = stream.CloseSend(); err != nil {
+				continue
+			}
+		}
+		err = stream.RecvMsg(m)
+	}
+}
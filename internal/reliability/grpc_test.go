@@ -0,0 +1,192 @@
+package reliability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func testAgentRPC(maxAttempts, maxFailures int) *AgentRPC {
+	return NewAgentRPC(AgentRPCConfig{
+		Retry: RetryConfig{
+			MaxAttempts:  maxAttempts,
+			InitialDelay: time.Millisecond,
+			MaxDelay:     time.Millisecond,
+			Strategy:     FixedDelay,
+		},
+		Breaker: CircuitBreakerConfig{
+			MaxFailures: maxFailures,
+			Timeout:     time.Hour,
+		},
+	})
+}
+
+func testClientConn(t *testing.T, target string) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// failingInvoker fails with errs in order, then succeeds
+func failingInvoker(calls *int, errs ...error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestIsTransientRPCError(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, IsTransientRPCError(ctx, status.Error(codes.Unavailable, "down")))
+	assert.True(t, IsTransientRPCError(ctx, status.Error(codes.DeadlineExceeded, "slow")))
+	assert.False(t, IsTransientRPCError(ctx, status.Error(codes.InvalidArgument, "bad")))
+	assert.False(t, IsTransientRPCError(ctx, nil))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, IsTransientRPCError(cancelled, status.Error(codes.Unavailable, "down")))
+}
+
+func TestAgentRPC_RetriesTransientErrors(t *testing.T) {
+	rpc := testAgentRPC(3, 10)
+	conn := testClientConn(t, "agent-1:50051")
+
+	calls := 0
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	err := rpc.UnaryClientInterceptor(context.Background(), "/agent.Agent/GetResourceUsage", nil, nil, conn,
+		failingInvoker(&calls, unavailable, unavailable))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, StateClosed, rpc.State("agent-1:50051"))
+}
+
+// keyedRequest stands for requests carrying an idempotency key
+type keyedRequest struct{ key string }
+
+func (r keyedRequest) GetIdempotencyKey() string { return r.key }
+
+func TestAgentRPC_RetriesOnlySafeMethods(t *testing.T) {
+	conn := testClientConn(t, "agent-1:50051")
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+
+	tests := []struct {
+		method string
+		req    interface{}
+		calls  int
+	}{
+		{"/agent.Agent/RunCommand", nil, 1},
+		{"/agent.Agent/Shutdown", nil, 1},
+		{"/agent.Agent/ExecuteTask", keyedRequest{}, 1},
+		{"/agent.Agent/ExecuteTask", keyedRequest{key: "run-1/deploy"}, 3},
+		{"/agent.Agent/GetResourceUsage", nil, 3},
+		{"/agent.Agent/CheckTasks", nil, 3},
+	}
+	for _, tt := range tests {
+		calls := 0
+		err := testAgentRPC(3, 10).UnaryClientInterceptor(context.Background(), tt.method, tt.req, nil, conn,
+			failingInvoker(&calls, unavailable, unavailable, unavailable))
+		assert.Equal(t, codes.Unavailable, status.Code(err), tt.method)
+		assert.Equal(t, tt.calls, calls, "%s %+v", tt.method, tt.req)
+	}
+}
+
+func TestAgentRPC_DoesNotRetryOtherErrors(t *testing.T) {
+	rpc := testAgentRPC(3, 10)
+	conn := testClientConn(t, "agent-1:50051")
+
+	calls := 0
+	err := rpc.UnaryClientInterceptor(context.Background(), "/agent.Agent/ExecuteTask", nil, nil, conn,
+		failingInvoker(&calls, status.Error(codes.Internal, "task failed")))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestAgentRPC_BreakerOpensPerAgent(t *testing.T) {
+	rpc := testAgentRPC(2, 4)
+	down := testClientConn(t, "agent-down:50051")
+	up := testClientConn(t, "agent-up:50051")
+
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	calls := 0
+	for i := 0; i < 2; i++ {
+		err := rpc.UnaryClientInterceptor(context.Background(), "/agent.Agent/GetResourceUsage", nil, nil, down,
+			failingInvoker(&calls, unavailable, unavailable, unavailable, unavailable))
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, StateOpen, rpc.State("agent-down:50051"))
+
+	// Calls to the open agent fail without reaching it
+	err := rpc.UnaryClientInterceptor(context.Background(), "/agent.Agent/GetResourceUsage", nil, nil, down,
+		failingInvoker(&calls))
+	assert.ErrorContains(t, err, "circuit breaker open")
+	assert.Equal(t, 4, calls)
+
+	// Other agents are unaffected
+	upCalls := 0
+	err = rpc.UnaryClientInterceptor(context.Background(), "/agent.Agent/GetResourceUsage", nil, nil, up,
+		failingInvoker(&upCalls))
+	assert.NoError(t, err)
+	assert.Equal(t, StateClosed, rpc.State("agent-up:50051"))
+}
+
+func TestDefaultAgentRPCConfig_Env(t *testing.T) {
+	t.Setenv(EnvRPCMaxAttempts, "7")
+	t.Setenv(EnvRPCBackoff, "2s")
+	t.Setenv(EnvRPCBreakerCooldown, "1m")
+	t.Setenv(EnvRPCBreakerFailures, "invalid")
+
+	config := DefaultAgentRPCConfig()
+	assert.Equal(t, 7, config.Retry.MaxAttempts)
+	assert.Equal(t, 2*time.Second, config.Retry.InitialDelay)
+	assert.Equal(t, time.Minute, config.Breaker.Timeout)
+	assert.Equal(t, 5, config.Breaker.MaxFailures)
+}
+
+// fakeStream is a server-streaming call whose first receive returns err
+type fakeStream struct {
+	grpc.ClientStream
+	err  error
+	sent []interface{}
+}
+
+func (s *fakeStream) SendMsg(m interface{}) error { s.sent = append(s.sent, m); return nil }
+func (s *fakeStream) CloseSend() error            { return nil }
+func (s *fakeStream) RecvMsg(m interface{}) error { return s.err }
+
+func TestAgentRPC_StreamReplaysRequest(t *testing.T) {
+	rpc := testAgentRPC(3, 10)
+	conn := testClientConn(t, "agent-1:50051")
+
+	var streams []*fakeStream
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream := &fakeStream{}
+		if len(streams) == 0 {
+			stream.err = status.Error(codes.Unavailable, "connection reset")
+		}
+		streams = append(streams, stream)
+		return stream, nil
+	}
+
+	stream, err := rpc.StreamClientInterceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, conn, "/agent.Agent/ExecuteTaskStream", streamer)
+	require.NoError(t, err)
+	request := keyedRequest{key: "run-1/deploy"}
+	require.NoError(t, stream.SendMsg(request))
+	require.NoError(t, stream.CloseSend())
+	assert.NoError(t, stream.RecvMsg(nil))
+
+	require.Len(t, streams, 2)
+	assert.Equal(t, []interface{}{request}, streams[1].sent)
+}
@@ -0,0 +1,28 @@
+package runlog
+
+import (
+	"io"
+	"sync"
+)
+
+// StopFunc ends an output capture started with Capture
+type StopFunc func() error
+
+// errorIgnoringWriter keeps a failing log writer from interrupting the
+// terminal output it is teed from
+type errorIgnoringWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	failed bool
+}
+
+func (e *errorIgnoringWriter) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.failed {
+		if _, err := e.w.Write(p); err != nil {
+			e.failed = true
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+// +build !unix
+
+package runlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Capture is not supported on this platform
+func Capture(w io.Writer) (StopFunc, *os.File, error) {
+	return nil, nil, fmt.Errorf("output capture is not supported on this platform")
+}
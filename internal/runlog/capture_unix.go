@@ -0,0 +1,104 @@
+//go:build unix
+// +build unix
+
+package runlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// drainTimeout bounds how long StopFunc waits for output still held by
+// background processes that inherited the captured descriptors
+const drainTimeout = 2 * time.Second
+
+// Capture copies everything written to the process stdout and stderr into
+// w while still passing it through to the terminal. It works at the file
+// descriptor level, so output of child processes and of writers holding
+// the original *os.File values is captured too. The returned function
+// restores the descriptors and waits for pending output. The returned
+// file is the original stdout, for prompts that must reach the terminal
+// directly; it is valid until the capture stops.
+func Capture(w io.Writer) (StopFunc, *os.File, error) {
+	sink := &errorIgnoringWriter{w: w}
+
+	restoreOut, doneOut, stdout, err := captureFD(1, sink)
+	if err != nil {
+		return nil, nil, err
+	}
+	restoreErr, doneErr, _, err := captureFD(2, sink)
+	if err != nil {
+		restoreOut()
+		return nil, nil, err
+	}
+
+	return func() error {
+		errOut := restoreOut()
+		errErr := restoreErr()
+		waitDrained(drainTimeout, doneOut, doneErr)
+		if errOut != nil {
+			return errOut
+		}
+		return errErr
+	}, stdout, nil
+}
+
+// captureFD points fd at a pipe whose contents are copied to the original
+// fd and to sink. orig is the original fd.
+func captureFD(fd int, sink io.Writer) (restore func() error, done chan struct{}, orig *os.File, err error) {
+	origFD, err := unix.Dup(fd)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to duplicate fd %d: %w", fd, err)
+	}
+	orig = os.NewFile(uintptr(origFD), fmt.Sprintf("fd%d", fd))
+
+	r, pw, err := os.Pipe()
+	if err != nil {
+		orig.Close()
+		return nil, nil, nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+	if err := unix.Dup2(int(pw.Fd()), fd); err != nil {
+		orig.Close()
+		r.Close()
+		pw.Close()
+		return nil, nil, nil, fmt.Errorf("failed to redirect fd %d: %w", fd, err)
+	}
+
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(io.MultiWriter(orig, sink), r)
+	}()
+
+	var once sync.Once
+	restore = func() error {
+		var restoreErr error
+		once.Do(func() {
+			restoreErr = unix.Dup2(int(orig.Fd()), fd)
+			pw.Close()
+			go func() {
+				<-done
+				r.Close()
+				orig.Close()
+			}()
+		})
+		return restoreErr
+	}
+	return restore, done, orig, nil
+}
+
+func waitDrained(timeout time.Duration, done ...chan struct{}) {
+	deadline := time.After(timeout)
+	for _, d := range done {
+		select {
+		case <-d:
+		case <-deadline:
+			return
+		}
+	}
+}
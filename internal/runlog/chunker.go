@@ -0,0 +1,89 @@
+package runlog
+
+import (
+	"bytes"
+	"hash/fnv"
+)
+
+// Chunk size bounds. Boundaries are content-defined: a chunk is cut after a
+// line whose hash matches boundaryMask once the chunk holds at least
+// minChunkSize bytes, so identical runs of lines produce identical chunks
+// even when the surrounding output differs. maxChunkSize bounds chunks made
+// of very long lines or unlucky hashes.
+const (
+	minChunkSize = 8 * 1024
+	maxChunkSize = 256 * 1024
+	boundaryMask = 0x7f
+)
+
+// chunker splits a stream of log output into content-defined chunks
+type chunker struct {
+	buf  []byte
+	line []byte
+	emit func(chunk []byte) error
+}
+
+func newChunker(emit func(chunk []byte) error) *chunker {
+	return &chunker{emit: emit}
+}
+
+// Write buffers p and emits every completed chunk
+func (c *chunker) Write(p []byte) error {
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			c.line = append(c.line, p...)
+			if len(c.buf)+len(c.line) >= maxChunkSize {
+				if err := c.cut(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		c.line = append(c.line, p[:i+1]...)
+		p = p[i+1:]
+		if err := c.endLine(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *chunker) endLine() error {
+	boundary := isBoundary(c.line)
+	c.buf = append(c.buf, c.line...)
+	c.line = c.line[:0]
+
+	if len(c.buf) >= maxChunkSize || (len(c.buf) >= minChunkSize && boundary) {
+		return c.flush()
+	}
+	return nil
+}
+
+// cut emits the buffered data including a partial line
+func (c *chunker) cut() error {
+	c.buf = append(c.buf, c.line...)
+	c.line = c.line[:0]
+	return c.flush()
+}
+
+func (c *chunker) flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	chunk := c.buf
+	c.buf = nil
+	return c.emit(chunk)
+}
+
+// Close emits whatever is left
+func (c *chunker) Close() error {
+	return c.cut()
+}
+
+func isBoundary(line []byte) bool {
+	h := fnv.New32a()
+	h.Write(line)
+	return h.Sum32()&boundaryMask == 0
+}
@@ -0,0 +1,536 @@
+// Package runlog stores run logs in chunked, zstd-compressed and
+// content-deduplicated form. Logs are split into content-defined chunks;
+// each distinct chunk is compressed and stored once no matter how many runs
+// produced it, and chunks are only decompressed as a log is read.
+package runlog
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+)
+
+// LogInfo describes a stored run log
+type LogInfo struct {
+	ID          string    `json:"id"`
+	Stack       string    `json:"stack"`
+	RunID       string    `json:"run_id,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	Size        int64     `json:"size"`
+	Chunks      int       `json:"chunks"`
+}
+
+// StackUsage is the space used by the run logs of a stack
+type StackUsage struct {
+	Stack string `json:"stack"`
+	Logs  int    `json:"logs"`
+	// Size is the uncompressed size of all logs of the stack
+	Size int64 `json:"size"`
+	// StoredSize is the compressed size of the distinct chunks the stack
+	// references. Chunks shared with other stacks count for each of them.
+	StoredSize int64 `json:"stored_size"`
+}
+
+// Usage is the space used by the log store
+type Usage struct {
+	Stacks []StackUsage `json:"stacks"`
+	Logs   int          `json:"logs"`
+	// Size is the uncompressed size of all logs
+	Size int64 `json:"size"`
+	// StoredSize is the compressed size of all distinct chunks
+	StoredSize int64 `json:"stored_size"`
+	Chunks     int   `json:"chunks"`
+}
+
+// Store is a run log store backed by SQLite
+type Store struct {
+	db  *sql.DB
+	mu  sync.Mutex
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+// NewStore opens (creating if needed) the log store at dbPath
+func NewStore(dbPath string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log store directory: %w", err)
+	}
+
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log store: %w", err)
+	}
+
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		sqlitedb.Close(db)
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		enc.Close()
+		sqlitedb.Close(db)
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+
+	s := &Store{db: db, enc: enc, dec: dec}
+	if err := s.initialize(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Store) initialize() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS log_chunks (
+		hash TEXT PRIMARY KEY,
+		data BLOB NOT NULL,
+		size INTEGER NOT NULL,
+		stored_size INTEGER NOT NULL,
+		refs INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS run_logs (
+		id TEXT PRIMARY KEY,
+		stack TEXT NOT NULL DEFAULT '',
+		run_id TEXT DEFAULT '',
+		name TEXT DEFAULT '',
+		created_at INTEGER NOT NULL,
+		completed_at INTEGER DEFAULT 0,
+		size INTEGER DEFAULT 0,
+		chunks INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS run_log_chunks (
+		log_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		hash TEXT NOT NULL,
+		PRIMARY KEY (log_id, seq)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_run_logs_stack ON run_logs(stack, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_run_log_chunks_hash ON run_log_chunks(hash);
+	`
+
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to initialize log store: %w", err)
+	}
+	return nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	s.enc.Close()
+	s.dec.Close()
+	return sqlitedb.Close(s.db)
+}
+
+// Create starts a new log for a run of stack. The returned writer stores
+// chunks as they fill up, so the output of a run that never finishes is
+// still kept; Close stores the remainder and marks the log complete.
+func (s *Store) Create(stack, runID, name string) (*Writer, error) {
+	info := &LogInfo{
+		ID:        uuid.New().String(),
+		Stack:     stack,
+		RunID:     runID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := s.db.Exec(`INSERT INTO run_logs (id, stack, run_id, name, created_at) VALUES (?, ?, ?, ?, ?)`,
+		info.ID, info.Stack, info.RunID, info.Name, info.CreatedAt.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log: %w", err)
+	}
+
+	w := &Writer{store: s, info: info}
+	w.chunker = newChunker(w.storeChunk)
+	return w, nil
+}
+
+// Put stores a complete log in one call
+func (s *Store) Put(stack, runID, name string, data []byte) (*LogInfo, error) {
+	w, err := s.Create(stack, runID, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return w.Info(), nil
+}
+
+// putChunk stores chunk unless an identical chunk exists, adds a reference
+// to it and appends it to the log
+func (s *Store) putChunk(logID string, seq int, chunk []byte) error {
+	sum := sha256.Sum256(chunk)
+	hash := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE log_chunks SET refs = refs + 1 WHERE hash = ?`, hash)
+	if err != nil {
+		return fmt.Errorf("failed to reference chunk: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		compressed := s.enc.EncodeAll(chunk, nil)
+		_, err := tx.Exec(`INSERT INTO log_chunks (hash, data, size, stored_size, refs) VALUES (?, ?, ?, ?, 1)`,
+			hash, compressed, len(chunk), len(compressed))
+		if err != nil {
+			return fmt.Errorf("failed to store chunk: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO run_log_chunks (log_id, seq, hash) VALUES (?, ?, ?)`, logID, seq, hash)
+	if err != nil {
+		return fmt.Errorf("failed to append chunk: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE run_logs SET size = size + ?, chunks = chunks + 1 WHERE id = ?`, len(chunk), logID)
+	if err != nil {
+		return fmt.Errorf("failed to update log: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Get returns a log's metadata
+func (s *Store) Get(id string) (*LogInfo, error) {
+	row := s.db.QueryRow(`SELECT id, stack, run_id, name, created_at, completed_at, size, chunks
+		FROM run_logs WHERE id = ?`, id)
+	info, err := scanLogInfo(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("log not found: %s", id)
+	}
+	return info, err
+}
+
+// List returns the logs of a stack, newest first. An empty stack lists
+// all logs and limit <= 0 returns every match.
+func (s *Store) List(stack string, limit int) ([]*LogInfo, error) {
+	query := `SELECT id, stack, run_id, name, created_at, completed_at, size, chunks FROM run_logs`
+	var args []interface{}
+	if stack != "" {
+		query += ` WHERE stack = ?`
+		args = append(args, stack)
+	}
+	query += ` ORDER BY created_at DESC, rowid DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*LogInfo
+	for rows.Next() {
+		info, err := scanLogInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, info)
+	}
+	return logs, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLogInfo(row rowScanner) (*LogInfo, error) {
+	info := &LogInfo{}
+	var createdAt, completedAt int64
+	if err := row.Scan(&info.ID, &info.Stack, &info.RunID, &info.Name, &createdAt, &completedAt, &info.Size, &info.Chunks); err != nil {
+		return nil, err
+	}
+	info.CreatedAt = time.Unix(createdAt, 0)
+	if completedAt > 0 {
+		info.CompletedAt = time.Unix(completedAt, 0)
+	}
+	return info, nil
+}
+
+// Reader returns a reader over a log. Chunks are fetched and decompressed
+// one at a time as the reader advances.
+func (s *Store) Reader(id string) (io.ReadCloser, error) {
+	info, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT hash FROM run_log_chunks WHERE log_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log chunks: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make([]string, 0, info.Chunks)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &reader{store: s, hashes: hashes}, nil
+}
+
+func (s *Store) readChunk(hash string) ([]byte, error) {
+	var compressed []byte
+	if err := s.db.QueryRow(`SELECT data FROM log_chunks WHERE hash = ?`, hash).Scan(&compressed); err != nil {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", hash, err)
+	}
+	data, err := s.dec.DecodeAll(compressed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress chunk %s: %w", hash, err)
+	}
+	return data, nil
+}
+
+// Delete removes a log and the chunks no other log references
+func (s *Store) Delete(id string) error {
+	n, err := s.deleteWhere(`SELECT id FROM run_logs WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("log not found: %s", id)
+	}
+	return nil
+}
+
+// DeleteStack removes all logs of a stack and returns how many were removed
+func (s *Store) DeleteStack(stack string) (int, error) {
+	return s.deleteWhere(`SELECT id FROM run_logs WHERE stack = ?`, stack)
+}
+
+// Prune removes logs created before cutoff and returns how many were removed
+func (s *Store) Prune(cutoff time.Time) (int, error) {
+	return s.deleteWhere(`SELECT id FROM run_logs WHERE created_at < ?`, cutoff.Unix())
+}
+
+func (s *Store) deleteWhere(selectIDs string, args ...interface{}) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM (`+selectIDs+`)`, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count logs: %w", err)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	if err := deleteLogs(tx, selectIDs, args...); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// deleteLogs drops the references of the selected logs, the chunks left
+// without references and the logs themselves
+func deleteLogs(tx *sql.Tx, selectIDs string, args ...interface{}) error {
+	statements := []string{
+		`UPDATE log_chunks SET refs = refs - (
+			SELECT COUNT(*) FROM run_log_chunks c
+			WHERE c.hash = log_chunks.hash AND c.log_id IN (` + selectIDs + `))
+		WHERE hash IN (SELECT hash FROM run_log_chunks WHERE log_id IN (` + selectIDs + `))`,
+		`DELETE FROM run_log_chunks WHERE log_id IN (` + selectIDs + `)`,
+		`DELETE FROM run_logs WHERE id IN (` + selectIDs + `)`,
+	}
+	argCounts := []int{2, 1, 1}
+
+	for i, stmt := range statements {
+		var stmtArgs []interface{}
+		for j := 0; j < argCounts[i]; j++ {
+			stmtArgs = append(stmtArgs, args...)
+		}
+		if _, err := tx.Exec(stmt, stmtArgs...); err != nil {
+			return fmt.Errorf("failed to delete logs: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM log_chunks WHERE refs <= 0`); err != nil {
+		return fmt.Errorf("failed to delete unreferenced chunks: %w", err)
+	}
+	return nil
+}
+
+// Usage reports the space used by the store, per stack and in total
+func (s *Store) Usage() (*Usage, error) {
+	usage := &Usage{}
+
+	rows, err := s.db.Query(`
+		SELECT l.stack, COUNT(*), COALESCE(SUM(l.size), 0),
+			COALESCE((SELECT SUM(stored_size) FROM log_chunks WHERE hash IN (
+				SELECT c.hash FROM run_log_chunks c JOIN run_logs r ON r.id = c.log_id WHERE r.stack = l.stack)), 0)
+		FROM run_logs l
+		GROUP BY l.stack
+		ORDER BY 4 DESC, l.stack`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute log usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var su StackUsage
+		if err := rows.Scan(&su.Stack, &su.Logs, &su.Size, &su.StoredSize); err != nil {
+			return nil, err
+		}
+		usage.Stacks = append(usage.Stacks, su)
+		usage.Logs += su.Logs
+		usage.Size += su.Size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(stored_size), 0) FROM log_chunks`).Scan(&usage.Chunks, &usage.StoredSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute chunk usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// Writer writes a run log into the store
+type Writer struct {
+	store   *Store
+	info    *LogInfo
+	chunker *chunker
+	mu      sync.Mutex
+	seq     int
+	closed  bool
+	err     error
+}
+
+// ID returns the log ID
+func (w *Writer) ID() string {
+	return w.info.ID
+}
+
+// Info returns the log metadata as written so far
+func (w *Writer) Info() *LogInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info := *w.info
+	return &info
+}
+
+// Write appends p to the log. It is safe for concurrent use.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, fmt.Errorf("log %s is closed", w.info.ID)
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if err := w.chunker.Write(p); err != nil {
+		w.err = err
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) storeChunk(chunk []byte) error {
+	if err := w.store.putChunk(w.info.ID, w.seq, chunk); err != nil {
+		return err
+	}
+	w.seq++
+	w.info.Size += int64(len(chunk))
+	w.info.Chunks++
+	return nil
+}
+
+// Close stores the remaining output and marks the log complete
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.err == nil {
+		w.err = w.chunker.Close()
+	}
+
+	w.info.CompletedAt = time.Now()
+	if _, err := w.store.db.Exec(`UPDATE run_logs SET completed_at = ? WHERE id = ?`, w.info.CompletedAt.Unix(), w.info.ID); err != nil && w.err == nil {
+		w.err = fmt.Errorf("failed to complete log: %w", err)
+	}
+	return w.err
+}
+
+// reader lazily decompresses a log chunk by chunk
+type reader struct {
+	store  *Store
+	hashes []string
+	buf    []byte
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if len(r.hashes) == 0 {
+			return 0, io.EOF
+		}
+		data, err := r.store.readChunk(r.hashes[0])
+		if err != nil {
+			return 0, err
+		}
+		r.hashes = r.hashes[1:]
+		r.buf = data
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *reader) Close() error {
+	r.hashes = nil
+	r.buf = nil
+	return nil
+}
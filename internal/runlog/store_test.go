@@ -0,0 +1,174 @@
+package runlog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "runlogs.db"))
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func testLog(lines int, prefix string) []byte {
+	var b bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "%s line %d: task step output with some repeated text\n", prefix, i)
+	}
+	return b.Bytes()
+}
+
+func readAll(t *testing.T, s *Store, id string) []byte {
+	t.Helper()
+	r, err := s.Reader(id)
+	if err != nil {
+		t.Fatalf("Reader failed: %v", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	return data
+}
+
+func TestStore_RoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	data := testLog(20000, "run")
+
+	w, err := s.Create("web", "run-1", "deploy")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// Write in odd-sized pieces so lines are split across writes
+	for rest := data; len(rest) > 0; {
+		n := 777
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	info, err := s.Get(w.ID())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if info.Size != int64(len(data)) || info.Chunks < 2 || info.CompletedAt.IsZero() {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+
+	if got := readAll(t, s, w.ID()); !bytes.Equal(got, data) {
+		t.Fatalf("Read back %d bytes, want %d", len(got), len(data))
+	}
+
+	usage, err := s.Usage()
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if usage.StoredSize <= 0 || usage.StoredSize >= usage.Size/4 {
+		t.Errorf("Expected compressed size well below %d, got %d", usage.Size, usage.StoredSize)
+	}
+}
+
+func TestStore_Deduplicates(t *testing.T) {
+	s := newTestStore(t)
+	data := testLog(5000, "same")
+
+	first, err := s.Put("web", "run-1", "", data)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	before, _ := s.Usage()
+
+	// Same output with a different header still shares the following chunks
+	second, err := s.Put("web", "run-2", "", append([]byte("started at 12:00\n"), data...))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, err := s.Put("api", "run-3", "", data); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	after, err := s.Usage()
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if after.Chunks > before.Chunks+2 {
+		t.Errorf("Expected chunks to be shared, went from %d to %d", before.Chunks, after.Chunks)
+	}
+	if after.Logs != 3 || len(after.Stacks) != 2 {
+		t.Errorf("Unexpected usage: %+v", after)
+	}
+	for _, su := range after.Stacks {
+		if su.Stack == "web" && (su.Logs != 2 || su.Size != first.Size+second.Size) {
+			t.Errorf("Unexpected web usage: %+v", su)
+		}
+	}
+
+	if got := readAll(t, s, second.ID); !strings.HasPrefix(string(got), "started at 12:00\n") || len(got) != len(data)+17 {
+		t.Error("Second log did not read back intact")
+	}
+}
+
+func TestStore_DeleteKeepsSharedChunks(t *testing.T) {
+	s := newTestStore(t)
+	data := testLog(3000, "shared")
+
+	a, _ := s.Put("web", "run-1", "", data)
+	b, _ := s.Put("web", "run-2", "", data)
+
+	if err := s.Delete(a.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if got := readAll(t, s, b.ID); !bytes.Equal(got, data) {
+		t.Fatal("Remaining log lost shared chunks")
+	}
+
+	n, err := s.DeleteStack("web")
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteStack = %d, %v", n, err)
+	}
+	usage, _ := s.Usage()
+	if usage.Chunks != 0 || usage.StoredSize != 0 || usage.Logs != 0 {
+		t.Errorf("Expected empty store, got %+v", usage)
+	}
+	if err := s.Delete(a.ID); err == nil {
+		t.Error("Expected error deleting a missing log")
+	}
+}
+
+func TestStore_ListAndPrune(t *testing.T) {
+	s := newTestStore(t)
+	s.Put("web", "run-1", "", []byte("one\n"))
+	s.Put("web", "run-2", "", []byte("two\n"))
+	s.Put("api", "run-3", "", []byte("three\n"))
+
+	logs, err := s.List("web", 0)
+	if err != nil || len(logs) != 2 {
+		t.Fatalf("List = %d logs, %v", len(logs), err)
+	}
+	if logs[0].RunID != "run-2" {
+		t.Errorf("Expected newest first, got %s", logs[0].RunID)
+	}
+
+	n, err := s.Prune(time.Now().Add(time.Hour))
+	if err != nil || n != 3 {
+		t.Fatalf("Prune = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,286 @@
+// Package s3 is a client for S3-compatible object storage, AWS S3 and
+// MinIO among others. Requests are path-style and signed with AWS
+// Signature Version 4, so it needs no SDK.
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmptyPayloadHash is the SHA-256 of an empty request body
+const EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// maxPresignExpiry is the longest a presigned URL may stay valid
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// ErrNotFound is returned for objects that don't exist
+var ErrNotFound = errors.New("object not found")
+
+// Config configures the connection to an S3-compatible endpoint
+type Config struct {
+	// Endpoint is the base URL, e.g. https://s3.us-east-1.amazonaws.com
+	// or the address of a MinIO server. Empty means the AWS endpoint of
+	// Region.
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// SessionToken is set with temporary credentials
+	SessionToken string
+}
+
+// Client sends signed requests to an S3-compatible endpoint
+type Client struct {
+	config Config
+	http   *http.Client
+	now    func() time.Time
+}
+
+// NewClient creates a client for config. Credentials not set in config are
+// read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func NewClient(config Config) (*Client, error) {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	if !strings.Contains(config.Endpoint, "://") {
+		config.Endpoint = "https://" + config.Endpoint
+	}
+	if config.AccessKey == "" && config.SecretKey == "" {
+		config.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		config.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("S3 requires credentials (AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	return &Client{
+		config: config,
+		http:   &http.Client{Timeout: 30 * time.Minute},
+		now:    time.Now,
+	}, nil
+}
+
+// Endpoint returns the base URL requests go to
+func (c *Client) Endpoint() string {
+	return c.config.Endpoint
+}
+
+// objectURL returns the path-style URL of key in bucket, of the bucket
+// itself when key is empty
+func (c *Client) objectURL(bucket, key string, query url.Values) (*url.URL, error) {
+	u, err := url.Parse(c.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %q: %w", c.config.Endpoint, err)
+	}
+	u.Path += "/" + bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+	return u, nil
+}
+
+// newRequest creates a request for key in bucket, for the bucket itself
+// when key is empty
+func (c *Client) newRequest(ctx context.Context, method, bucket, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u, err := c.objectURL(bucket, key, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	return req, nil
+}
+
+// do signs and sends req, whose body hashes to payloadHash
+func (c *Client) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	c.Sign(req, payloadHash)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// Sign adds an AWS Signature Version 4 Authorization header to req, whose
+// body hashes to payloadHash. The x-amz- headers of req are signed too.
+func (c *Client) Sign(req *http.Request, payloadHash string) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.config.SessionToken)
+	}
+
+	signed := []string{"host"}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			signed = append(signed, lower)
+		}
+	}
+	sort.Strings(signed)
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+	scope, signature := c.signature(now, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.config.AccessKey, scope, strings.Join(signed, ";"), signature))
+}
+
+// Presign returns a URL that allows method on key in bucket without
+// credentials until expires has passed
+func (c *Client) Presign(method, bucket, key string, expires time.Duration) (string, error) {
+	if expires <= 0 || expires > maxPresignExpiry {
+		return "", fmt.Errorf("presigned URLs expire after 1s to %s, not %s", maxPresignExpiry, expires)
+	}
+	now := c.now().UTC()
+	day := now.Format("20060102")
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", fmt.Sprintf("%s/%s/%s/s3/aws4_request", c.config.AccessKey, day, c.config.Region))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if c.config.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.config.SessionToken)
+	}
+	u, err := c.objectURL(bucket, key, query)
+	if err != nil {
+		return "", err
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapePath(u.Path),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	_, signature := c.signature(now, canonicalRequest)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+// signature signs canonicalRequest made at now and returns the credential
+// scope it was signed for
+func (c *Client) signature(now time.Time, canonicalRequest string) (scope, signature string) {
+	day := now.Format("20060102")
+	scope = fmt.Sprintf("%s/%s/s3/aws4_request", day, c.config.Region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.config.SecretKey), day)
+	key = hmacSHA256(key, c.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// escapePath encodes path as Signature Version 4 wants it: every byte but
+// the unreserved characters and slashes
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		if ch == '/' || unreserved(ch) {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func unreserved(ch byte) bool {
+	return 'A' <= ch && ch <= 'Z' || 'a' <= ch && ch <= 'z' || '0' <= ch && ch <= '9' ||
+		ch == '-' || ch == '.' || ch == '_' || ch == '~'
+}
+
+// canonicalQuery encodes values sorted by key, with spaces as %20
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range values[k] {
+			parts = append(parts, queryEscape(k)+"="+queryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func queryEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseError returns the error of a failed response, with the code and
+// message S3 sent when it sent one
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &s3Err) == nil && s3Err.Code != "" {
+		return fmt.Errorf("S3 request failed: HTTP %d: %s: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+	}
+	return fmt.Errorf("S3 request failed: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
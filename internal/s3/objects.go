@@ -0,0 +1,451 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPartSize is the size of the parts larger files are uploaded
+	// in, and the largest file uploaded in a single request
+	DefaultPartSize = 64 << 20
+	// MinPartSize is the smallest part S3 takes, but for the last one
+	MinPartSize = 5 << 20
+	// maxParts is the most parts an upload may have
+	maxParts = 10000
+	// checksumHeader keeps the SHA-256 of objects this client uploaded,
+	// which downloads verify
+	checksumHeader = "X-Amz-Meta-Sha256"
+)
+
+// Object is an object of a bucket
+type Object struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	// SHA256 is the checksum the object was uploaded with, when it was
+	// uploaded by this client
+	SHA256 string
+}
+
+// PutOptions tune an upload
+type PutOptions struct {
+	// PartSize is the size of the parts of multipart uploads, 0 for
+	// DefaultPartSize. Files up to its size are uploaded in one request.
+	PartSize int64
+	// ContentType defaults to the type of the file's extension
+	ContentType string
+}
+
+func (o PutOptions) partSize() int64 {
+	if o.PartSize <= 0 {
+		return DefaultPartSize
+	}
+	return o.PartSize
+}
+
+// Stat returns the object at key, ErrNotFound when there's none
+func (c *Client) Stat(ctx context.Context, bucket, key string) (*Object, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, bucket, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req, EmptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return headerObject(key, resp), nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("S3 request failed: HTTP %d", resp.StatusCode)
+	}
+}
+
+func headerObject(key string, resp *http.Response) *Object {
+	obj := &Object{
+		Key:    key,
+		Size:   resp.ContentLength,
+		ETag:   strings.Trim(resp.Header.Get("ETag"), `"`),
+		SHA256: resp.Header.Get(checksumHeader),
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.LastModified = t
+	}
+	return obj
+}
+
+// PutFile uploads the file at path to key. Files larger than the part
+// size are uploaded in parts. The payload of every request is signed with
+// its SHA-256, which S3 checks, and the file's SHA-256 is kept with the
+// object for downloads to verify.
+func (c *Client) PutFile(ctx context.Context, bucket, key, path string, opts PutOptions) (*Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := sectionSHA256(f, 0, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	header := http.Header{}
+	header.Set(checksumHeader, checksum)
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	obj := &Object{Key: key, Size: info.Size(), SHA256: checksum}
+	if info.Size() <= opts.partSize() {
+		obj.ETag, err = c.putPart(ctx, bucket, key, nil, header, io.NewSectionReader(f, 0, info.Size()), checksum)
+	} else {
+		obj.ETag, err = c.putMultipart(ctx, bucket, key, header, f, info.Size(), opts.partSize())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// putPart uploads body, which hashes to checksum, and returns its ETag
+func (c *Client) putPart(ctx context.Context, bucket, key string, query url.Values, header http.Header, body *io.SectionReader, checksum string) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodPut, bucket, key, query, body)
+	if err != nil {
+		return "", err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.ContentLength = body.Size()
+	resp, err := c.do(req, checksum)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+	return strings.Trim(resp.Header.Get("ETag"), `"`), nil
+}
+
+// putMultipart uploads f in parts of partSize. A failed upload is aborted
+// so its parts don't linger in the bucket.
+func (c *Client) putMultipart(ctx context.Context, bucket, key string, header http.Header, f *os.File, size, partSize int64) (string, error) {
+	if partSize < MinPartSize {
+		return "", fmt.Errorf("parts must be at least %d bytes, not %d", MinPartSize, partSize)
+	}
+	if (size+partSize-1)/partSize > maxParts {
+		return "", fmt.Errorf("%d bytes take more than %d parts of %d bytes", size, maxParts, partSize)
+	}
+
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := c.postXML(ctx, bucket, key, url.Values{"uploads": {""}}, header, nil, &initiated); err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	type part struct {
+		Number int    `xml:"PartNumber"`
+		ETag   string `xml:"ETag"`
+	}
+	var complete struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}
+	var completed struct {
+		ETag string `xml:"ETag"`
+	}
+	upload := func() error {
+		for offset, number := int64(0), 1; offset < size; offset, number = offset+partSize, number+1 {
+			n := min(partSize, size-offset)
+			checksum, err := sectionSHA256(f, offset, n)
+			if err != nil {
+				return err
+			}
+			query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiated.UploadID}}
+			etag, err := c.putPart(ctx, bucket, key, query, nil, io.NewSectionReader(f, offset, n), checksum)
+			if err != nil {
+				return fmt.Errorf("failed to upload part %d: %w", number, err)
+			}
+			complete.Parts = append(complete.Parts, part{Number: number, ETag: `"` + etag + `"`})
+		}
+		body, err := xml.Marshal(complete)
+		if err != nil {
+			return err
+		}
+		if err := c.postXML(ctx, bucket, key, url.Values{"uploadId": {initiated.UploadID}}, nil, body, &completed); err != nil {
+			return fmt.Errorf("failed to complete multipart upload: %w", err)
+		}
+		return nil
+	}
+	if err := upload(); err != nil {
+		c.abortMultipart(bucket, key, initiated.UploadID)
+		return "", err
+	}
+	return strings.Trim(completed.ETag, `"`), nil
+}
+
+// abortMultipart drops the parts of an upload. It runs after the upload
+// failed, maybe because its context ended, so it has a context of its own.
+func (c *Client) abortMultipart(bucket, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := c.newRequest(ctx, http.MethodDelete, bucket, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return
+	}
+	if resp, err := c.do(req, EmptyPayloadHash); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// postXML posts body to key and decodes the XML response into out. S3 may
+// answer 200 with an error in the body, which is returned as such.
+func (c *Client) postXML(ctx context.Context, bucket, key string, query url.Values, header http.Header, body []byte, out interface{}) error {
+	req, err := c.newRequest(ctx, http.MethodPost, bucket, key, query, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := c.do(req, hexSHA256(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("S3 request failed: %w", err)
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		return responseError(&http.Response{StatusCode: resp.StatusCode, Body: io.NopCloser(bytes.NewReader(data))})
+	}
+	if err := xml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid S3 response: %w", err)
+	}
+	return nil
+}
+
+// GetFile downloads key to dest with mode and verifies it against the
+// SHA-256 it was uploaded with or, for objects uploaded in one request by
+// other clients, against its ETag. dest is only replaced by a verified
+// download.
+func (c *Client) GetFile(ctx context.Context, bucket, key, dest string, mode os.FileMode) (*Object, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, bucket, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req, EmptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, responseError(resp)
+	}
+	obj := headerObject(key, resp)
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	sha, md := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(tmp, sha, md), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download s3://%s/%s: %w", bucket, key, err)
+	}
+	if err := verify(obj, size, sha, md, resp.Header.Get("X-Amz-Server-Side-Encryption")); err != nil {
+		return nil, fmt.Errorf("s3://%s/%s: %w", bucket, key, err)
+	}
+	obj.Size = size
+	obj.SHA256 = hex.EncodeToString(sha.Sum(nil))
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	return obj, nil
+}
+
+// verify checks a download against what S3 said of the object. The ETag
+// of multipart uploads and of KMS-encrypted objects is no MD5, so such
+// objects are only checked when they carry their SHA-256.
+func verify(obj *Object, size int64, sha, md hash.Hash, encryption string) error {
+	if obj.Size >= 0 && size != obj.Size {
+		return fmt.Errorf("download is %d bytes, expected %d", size, obj.Size)
+	}
+	if obj.SHA256 != "" {
+		if got := hex.EncodeToString(sha.Sum(nil)); got != obj.SHA256 {
+			return fmt.Errorf("checksum mismatch: SHA-256 is %s, expected %s", got, obj.SHA256)
+		}
+		return nil
+	}
+	if len(obj.ETag) == 32 && encryption != "aws:kms" {
+		if got := hex.EncodeToString(md.Sum(nil)); got != obj.ETag {
+			return fmt.Errorf("checksum mismatch: MD5 is %s, expected ETag %s", got, obj.ETag)
+		}
+	}
+	return nil
+}
+
+// List returns the objects whose key starts with prefix, by key
+func (c *Client) List(ctx context.Context, bucket, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := c.newRequest(ctx, http.MethodGet, bucket, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.do(req, EmptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				ETag         string    `xml:"ETag"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = responseError(resp)
+		} else if decodeErr := xml.NewDecoder(resp.Body).Decode(&page); decodeErr != nil {
+			err = fmt.Errorf("invalid S3 response: %w", decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range page.Contents {
+			objects = append(objects, Object{Key: o.Key, Size: o.Size, ETag: strings.Trim(o.ETag, `"`), LastModified: o.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Delete deletes key. Deleting a key that doesn't exist succeeds.
+func (c *Client) Delete(ctx context.Context, bucket, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return responseError(resp)
+	}
+}
+
+// FileETag returns the ETag S3 gives the file at path once uploaded with
+// partSize by PutFile: the MD5 of files uploaded in one request, the MD5 of
+// the MD5s of the parts and their count otherwise. Uploads are skipped for
+// files matching their object's ETag.
+func FileETag(path string, partSize int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+
+	if info.Size() <= partSize {
+		md := md5.New()
+		if _, err := io.Copy(md, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(md.Sum(nil)), nil
+	}
+	all, parts := md5.New(), 0
+	for offset := int64(0); offset < info.Size(); offset += partSize {
+		md := md5.New()
+		if _, err := io.Copy(md, io.NewSectionReader(f, offset, min(partSize, info.Size()-offset))); err != nil {
+			return "", err
+		}
+		all.Write(md.Sum(nil))
+		parts++
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(all.Sum(nil)), parts), nil
+}
+
+// sectionSHA256 returns the hex SHA-256 of n bytes of f from offset
+func sectionSHA256(f *os.File, offset, n int64) (string, error) {
+	sum := sha256.New()
+	if _, err := io.Copy(sum, io.NewSectionReader(f, offset, n)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
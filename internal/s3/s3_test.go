@@ -0,0 +1,352 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 keeps objects in memory, checks the signature of every request
+// against the path as sent and supports multipart uploads and paged lists
+type fakeS3 struct {
+	signer  *Client
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	uploads map[string]map[int][]byte
+	aborted int
+	// failPart fails the upload of the part with this number
+	failPart int
+}
+
+type fakeObject struct {
+	data   []byte
+	etag   string
+	sha256 string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *Client) {
+	f := &fakeS3{objects: make(map[string]*fakeObject), uploads: make(map[string]map[int][]byte)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(Config{Endpoint: srv.URL, AccessKey: "AKID", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.signer = client
+	return f, client
+}
+
+// checkSignature signs a copy of r, as received, and compares signatures
+func (f *fakeS3) checkSignature(r *http.Request, body []byte) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") {
+		return false
+	}
+	if hexSHA256(body) != r.Header.Get("X-Amz-Content-Sha256") {
+		return false
+	}
+	rawPath, _, _ := strings.Cut(r.RequestURI, "?")
+	if rawPath != escapePath(r.URL.Path) {
+		return false
+	}
+
+	copied := r.Clone(context.Background())
+	copied.URL.Host = r.Host
+	date, _ := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	signer := *f.signer
+	signer.now = func() time.Time { return date }
+	signer.Sign(copied, r.Header.Get("X-Amz-Content-Sha256"))
+	return copied.Header.Get("Authorization") == auth
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !f.checkSignature(r, body) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error>", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	name := bucket + "/" + key
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[id] = map[int][]byte{0: []byte(r.Header.Get(checksumHeader))}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if number == f.failPart {
+			http.Error(w, "<Error><Code>InternalError</Code><Message>part lost</Message></Error>", http.StatusInternalServerError)
+			return
+		}
+		f.uploads[query.Get("uploadId")][number] = body
+		w.Header().Set("ETag", `"`+md5Hex(body)+`"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.uploads[query.Get("uploadId")]
+		var data []byte
+		all := md5.New()
+		for i := 1; i < len(parts); i++ {
+			data = append(data, parts[i]...)
+			sum := md5.Sum(parts[i])
+			all.Write(sum[:])
+		}
+		etag := fmt.Sprintf("%s-%d", hex.EncodeToString(all.Sum(nil)), len(parts)-1)
+		f.objects[name] = &fakeObject{data: data, etag: etag, sha256: string(parts[0])}
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><ETag>"%s"</ETag></CompleteMultipartUploadResult>`, etag)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		f.aborted++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[name] = &fakeObject{data: body, etag: md5Hex(body), sha256: r.Header.Get(checksumHeader)}
+		w.Header().Set("ETag", `"`+md5Hex(body)+`"`)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, bucket, query)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[name]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+		if obj.sha256 != "" {
+			w.Header().Set(checksumHeader, obj.sha256)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	default:
+		http.Error(w, "unsupported", http.StatusBadRequest)
+	}
+}
+
+// list answers ListObjectsV2 two keys a page
+func (f *fakeS3) list(w http.ResponseWriter, bucket string, query map[string][]string) {
+	var keys []string
+	for name := range f.objects {
+		b, key, _ := strings.Cut(name, "/")
+		if b == bucket && strings.HasPrefix(key, first(query["prefix"])) && key > first(query["continuation-token"]) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("<ListBucketResult>")
+	for i, key := range keys {
+		if i == 2 {
+			fmt.Fprintf(&b, "<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>", keys[1])
+			break
+		}
+		obj := f.objects[bucket+"/"+key]
+		fmt.Fprintf(&b, `<Contents><Key>%s</Key><Size>%d</Size><ETag>"%s"</ETag></Contents>`, key, len(obj.data), obj.etag)
+	}
+	b.WriteString("</ListBucketResult>")
+	w.Write([]byte(b.String()))
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPutFileGetFile(t *testing.T) {
+	fake, client := newFakeS3(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "report.txt")
+	writeFile(t, src, []byte("hello"))
+
+	// Keys are signed as sent, whatever their characters
+	key := "reports/2024 Q1+final=1.txt"
+	obj, err := client.PutFile(ctx, "backups", key, src, PutOptions{})
+	if err != nil {
+		t.Fatalf("PutFile failed: %v", err)
+	}
+	if obj.ETag != md5Hex([]byte("hello")) || obj.Size != 5 {
+		t.Errorf("Unexpected object %+v", obj)
+	}
+
+	dest := filepath.Join(dir, "out", "report.txt")
+	got, err := client.GetFile(ctx, "backups", key, dest, 0600)
+	if err != nil {
+		t.Fatalf("GetFile failed: %v", err)
+	}
+	if got.SHA256 != obj.SHA256 {
+		t.Errorf("Expected checksum %s, got %s", obj.SHA256, got.SHA256)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "hello" {
+		t.Errorf("Unexpected content %q", data)
+	}
+
+	// A corrupted object fails its checksum and leaves dest alone
+	fake.objects["backups/"+key].data = []byte("jello")
+	if _, err := client.GetFile(ctx, "backups", key, dest, 0600); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "hello" {
+		t.Errorf("Expected dest to be kept, got %q", data)
+	}
+
+	if _, err := client.GetFile(ctx, "backups", "missing", dest, 0600); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := client.Delete(ctx, "backups", key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Stat(ctx, "backups", key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestPutFile_Multipart(t *testing.T) {
+	fake, client := newFakeS3(t)
+	src := filepath.Join(t.TempDir(), "image.raw")
+	data := bytes.Repeat([]byte("0123456789"), MinPartSize/10+1)
+	writeFile(t, src, data)
+
+	obj, err := client.PutFile(context.Background(), "images", "image.raw", src, PutOptions{PartSize: MinPartSize})
+	if err != nil {
+		t.Fatalf("PutFile failed: %v", err)
+	}
+	stored := fake.objects["images/image.raw"]
+	if !bytes.Equal(stored.data, data) {
+		t.Fatalf("Expected the parts to add up to the file, got %d bytes", len(stored.data))
+	}
+	etag, err := FileETag(src, MinPartSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(obj.ETag, "-2") || obj.ETag != etag {
+		t.Errorf("Expected ETag %s of 2 parts, got %s", etag, obj.ETag)
+	}
+	if stored.sha256 != obj.SHA256 {
+		t.Errorf("Expected the file's checksum kept with the object")
+	}
+
+	// A failed part aborts the upload
+	fake.failPart = 2
+	if _, err := client.PutFile(context.Background(), "images", "image.raw", src, PutOptions{PartSize: MinPartSize}); err == nil || !strings.Contains(err.Error(), "part lost") {
+		t.Errorf("Expected the failed part's error, got %v", err)
+	}
+	if fake.aborted != 1 || len(fake.uploads) != 0 {
+		t.Errorf("Expected the upload to be aborted, %d aborted, %d left", fake.aborted, len(fake.uploads))
+	}
+
+	if _, err := client.PutFile(context.Background(), "images", "image.raw", src, PutOptions{PartSize: 1024}); err == nil {
+		t.Error("Expected parts smaller than the minimum to fail")
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	fake, client := newFakeS3(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "css/site.css", "js/app.js"} {
+		writeFile(t, filepath.Join(dir, name), []byte("content of "+name))
+	}
+	fake.objects["site/other/keep.txt"] = &fakeObject{data: []byte("x"), etag: md5Hex([]byte("x"))}
+
+	result, err := client.SyncDir(ctx, dir, "site", "www/", SyncOptions{})
+	if err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if len(result.Uploaded) != 3 || !result.Changed() {
+		t.Errorf("Expected 3 uploads, got %+v", result)
+	}
+
+	writeFile(t, filepath.Join(dir, "js/app.js"), []byte("changed"))
+	os.Remove(filepath.Join(dir, "css/site.css"))
+	result, err = client.SyncDir(ctx, dir, "site", "www", SyncOptions{Delete: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &SyncResult{Uploaded: []string{"www/js/app.js"}, Deleted: []string{"www/css/site.css"}, Unchanged: []string{"www/index.html"}}
+	if fmt.Sprint(result) != fmt.Sprint(want) {
+		t.Errorf("Expected dry run %+v, got %+v", want, result)
+	}
+	if _, ok := fake.objects["site/www/css/site.css"]; !ok {
+		t.Error("Expected a dry run to delete nothing")
+	}
+
+	if _, err := client.SyncDir(ctx, dir, "site", "www", SyncOptions{Delete: true}); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := client.List(ctx, "site", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+	}
+	if strings.Join(keys, ",") != "other/keep.txt,www/index.html,www/js/app.js" {
+		t.Errorf("Unexpected objects after sync %v", keys)
+	}
+}
+
+func TestPresign(t *testing.T) {
+	client, err := NewClient(Config{Endpoint: "https://minio.example.com:9000", Region: "eu-west-1", AccessKey: "AKID", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.now = func() time.Time { return time.Date(2024, 5, 24, 0, 0, 0, 0, time.UTC) }
+
+	u, err := client.Presign(http.MethodGet, "releases", "v1/app.tar.gz", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{
+		"https://minio.example.com:9000/releases/v1/app.tar.gz?",
+		"X-Amz-Credential=AKID%2F20240524%2Feu-west-1%2Fs3%2Faws4_request",
+		"X-Amz-Expires=3600",
+		"X-Amz-Signature=",
+	} {
+		if !strings.Contains(u, part) {
+			t.Errorf("Expected %q in %s", part, u)
+		}
+	}
+
+	if _, err := client.Presign(http.MethodGet, "releases", "app", 8*24*time.Hour); err == nil {
+		t.Error("Expected expiries over 7 days to fail")
+	}
+}
@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SyncOptions tune a directory sync
+type SyncOptions struct {
+	PutOptions
+	// Delete deletes the objects under the prefix whose file is gone
+	Delete bool
+	// DryRun only works out what the sync would do
+	DryRun bool
+}
+
+// SyncResult is what a directory sync did, or would do in a dry run, by key
+type SyncResult struct {
+	Uploaded  []string
+	Deleted   []string
+	Unchanged []string
+}
+
+// Changed reports whether the sync uploaded or deleted anything
+func (r *SyncResult) Changed() bool {
+	return len(r.Uploaded) > 0 || len(r.Deleted) > 0
+}
+
+// SyncDir mirrors the files under dir to the keys under prefix. Files whose
+// object has their size and ETag are left alone.
+func (c *Client) SyncDir(ctx context.Context, dir, bucket, prefix string, opts SyncOptions) (*SyncResult, error) {
+	prefix = strings.Trim(prefix, "/")
+	listPrefix := ""
+	if prefix != "" {
+		listPrefix = prefix + "/"
+	}
+	remote, err := c.List(ctx, bucket, listPrefix)
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[string]Object, len(remote))
+	for _, o := range remote {
+		objects[o.Key] = o
+	}
+
+	result := &SyncResult{}
+	local := make(map[string]bool)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		local[key] = true
+
+		if o, ok := objects[key]; ok {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if o.Size == info.Size() {
+				etag, err := FileETag(p, opts.partSize())
+				if err != nil {
+					return err
+				}
+				if etag == o.ETag {
+					result.Unchanged = append(result.Unchanged, key)
+					return nil
+				}
+			}
+		}
+		if !opts.DryRun {
+			if _, err := c.PutFile(ctx, bucket, key, p, opts.PutOptions); err != nil {
+				return fmt.Errorf("failed to upload %s: %w", p, err)
+			}
+		}
+		result.Uploaded = append(result.Uploaded, key)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if opts.Delete {
+		for _, o := range remote {
+			if local[o.Key] {
+				continue
+			}
+			if !opts.DryRun {
+				if err := c.Delete(ctx, bucket, o.Key); err != nil {
+					return result, fmt.Errorf("failed to delete %s: %w", o.Key, err)
+				}
+			}
+			result.Deleted = append(result.Deleted, o.Key)
+		}
+		sort.Strings(result.Deleted)
+	}
+	return result, nil
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
 	"github.com/pterm/pterm"
 )
 
@@ -121,27 +124,36 @@ func (ws *WorkflowScaffolder) InitWorkflow(workflowName string, templateName str
 	pterm.DefaultCenter.Printf("%s\n", pterm.LightCyan("🦥 Sloth Runner Workflow Scaffolder"))
 	pterm.Printf("\n")
 
-	var template WorkflowTemplate
-	var exists bool
+	// Ask for what wasn't given; without a terminal, every missing input
+	// is reported at once
+	inputs := taskrunner.NewInputCollector(nil)
+	templates := ws.Templates()
+	var selected int
+	if templateName == "" || interactive {
+		inputs.Ask(taskrunner.Prompt{Name: "template", Flag: "--template", Prompt: templatePrompt(templates)}, &selected)
+	}
+	if workflowName == "" || interactive {
+		inputs.Ask(taskrunner.Prompt{Name: "project name", Flag: "the project-name argument", Prompt: &survey.Input{
+			Message: "Enter workflow name:",
+			Default: "my-workflow",
+			Help:    "The name of your workflow (will be used as directory and file name)",
+		}}, &workflowName)
+	}
+	if err := inputs.Err(); err != nil {
+		return err
+	}
 
+	var template WorkflowTemplate
 	if templateName == "" || interactive {
-		// Interactive template selection
-		template, exists = ws.selectTemplateInteractively()
-		if !exists {
-			return fmt.Errorf("no template selected")
-		}
+		template = templates[selected]
 	} else {
+		var exists bool
 		template, exists = ws.templates[templateName]
 		if !exists {
 			return fmt.Errorf("template '%s' not found", templateName)
 		}
 	}
 
-	// Get workflow name if not provided
-	if workflowName == "" || interactive {
-		workflowName = ws.getWorkflowNameInteractively()
-	}
-
 	// Create project directory
 	projectDir := workflowName
 	if err := os.MkdirAll(projectDir, 0755); err != nil {
@@ -149,7 +161,10 @@ func (ws *WorkflowScaffolder) InitWorkflow(workflowName string, templateName str
 	}
 
 	// Gather template data
-	templateData := ws.gatherTemplateData(workflowName, template, interactive)
+	templateData, err := ws.gatherTemplateData(workflowName, template, interactive)
+	if err != nil {
+		return err
+	}
 
 	// Generate workflow file
 	workflowFile := filepath.Join(projectDir, fmt.Sprintf("%s.sloth", workflowName))
@@ -168,50 +183,25 @@ func (ws *WorkflowScaffolder) InitWorkflow(workflowName string, templateName str
 	return nil
 }
 
-// selectTemplateInteractively allows user to select a template interactively
-func (ws *WorkflowScaffolder) selectTemplateInteractively() (WorkflowTemplate, bool) {
-	var templateNames []string
-	var templateDescriptions []string
-
-	for name, template := range ws.templates {
-		templateNames = append(templateNames, name)
-		desc := fmt.Sprintf("%s - %s (%s, %s)", 
-			template.Description, 
-			template.Category, 
+// templatePrompt asks to pick one of templates
+func templatePrompt(templates []WorkflowTemplate) survey.Prompt {
+	var descriptions []string
+	for _, template := range templates {
+		descriptions = append(descriptions, fmt.Sprintf("%s - %s (%s, %s)",
+			template.Description,
+			template.Category,
 			template.Complexity,
-			template.Version)
-		templateDescriptions = append(templateDescriptions, desc)
+			template.Version))
 	}
-
-	prompt := &survey.Select{
+	return &survey.Select{
 		Message: "Select a workflow template:",
-		Options: templateDescriptions,
+		Options: descriptions,
 		Help:    "Choose the type of workflow you want to create",
 	}
-
-	var selectedIndex int
-	if err := survey.AskOne(prompt, &selectedIndex); err != nil {
-		return WorkflowTemplate{}, false
-	}
-
-	selectedName := templateNames[selectedIndex]
-	return ws.templates[selectedName], true
-}
-
-// getWorkflowNameInteractively gets workflow name from user
-func (ws *WorkflowScaffolder) getWorkflowNameInteractively() string {
-	var workflowName string
-	prompt := &survey.Input{
-		Message: "Enter workflow name:",
-		Default: "my-workflow",
-		Help:    "The name of your workflow (will be used as directory and file name)",
-	}
-	survey.AskOne(prompt, &workflowName)
-	return workflowName
 }
 
 // gatherTemplateData gathers data for template rendering
-func (ws *WorkflowScaffolder) gatherTemplateData(workflowName string, template WorkflowTemplate, interactive bool) TemplateData {
+func (ws *WorkflowScaffolder) gatherTemplateData(workflowName string, template WorkflowTemplate, interactive bool) (TemplateData, error) {
 	data := TemplateData{
 		WorkflowName: workflowName,
 		Description:  template.Description,
@@ -225,42 +215,60 @@ func (ws *WorkflowScaffolder) gatherTemplateData(workflowName string, template W
 
 	if interactive {
 		// Ask for additional details
-		var description string
-		survey.AskOne(&survey.Input{
+		inputs := taskrunner.NewInputCollector(nil)
+		inputs.Ask(taskrunner.Prompt{Name: "description", Prompt: &survey.Input{
 			Message: "Enter workflow description:",
 			Default: data.Description,
-		}, &description)
-		data.Description = description
-
-		var author string
-		survey.AskOne(&survey.Input{
+		}}, &data.Description)
+		inputs.Ask(taskrunner.Prompt{Name: "author", Prompt: &survey.Input{
 			Message: "Enter author name:",
 			Default: "Developer",
-		}, &author)
-		data.Author = author
+		}}, &data.Author)
+		if err := inputs.Err(); err != nil {
+			return data, err
+		}
 	}
 
-	return data
+	return data, nil
 }
 
 // generateWorkflowFile generates the main workflow file
 func (ws *WorkflowScaffolder) generateWorkflowFile(filename string, workflowTemplate WorkflowTemplate, data TemplateData) error {
-	tmpl, err := template.New("workflow").Parse(workflowTemplate.Template)
+	content, err := workflowTemplate.Render(data)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
 	}
 
-	file, err := os.Create(filename)
-	if err != nil {
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer file.Close()
 
-	if err := tmpl.Execute(file, data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	return nil
+}
+
+// Render renders the workflow file of the template
+func (t WorkflowTemplate) Render(data TemplateData) (string, error) {
+	tmpl, err := template.New("workflow").Parse(t.Template)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	return nil
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return out.String(), nil
+}
+
+// Templates returns the available templates sorted by name
+func (ws *WorkflowScaffolder) Templates() []WorkflowTemplate {
+	templates := make([]WorkflowTemplate, 0, len(ws.templates))
+	for _, t := range ws.templates {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
 }
 
 // generateAdditionalFiles generates additional project files
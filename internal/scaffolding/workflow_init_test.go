@@ -0,0 +1,31 @@
+package scaffolding
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskrunner"
+)
+
+func TestInitWorkflow_ReportsMissingInputs(t *testing.T) {
+	taskrunner.SetNonInteractive(true)
+	defer taskrunner.SetNonInteractive(false)
+
+	err := NewWorkflowScaffolder().InitWorkflow("", "", false)
+	var missing *taskrunner.MissingInputError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected missing inputs, got %v", err)
+	}
+	want := []taskrunner.MissingInput{
+		{Name: "template", Message: "Select a workflow template:", Flag: "--template"},
+		{Name: "project name", Message: "Enter workflow name:", Flag: "the project-name argument"},
+	}
+	if len(missing.Inputs) != len(want) {
+		t.Fatalf("Expected %d missing inputs, got %+v", len(want), missing.Inputs)
+	}
+	for i, in := range want {
+		if missing.Inputs[i] != in {
+			t.Errorf("Expected missing input %+v, got %+v", in, missing.Inputs[i])
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// DefaultCheckInterval is how often the engine picks up schedules added,
+// changed or removed with the scheduler commands
+const DefaultCheckInterval = 30 * time.Second
+
+// Engine runs on the master: it keeps a cron entry for each enabled
+// workflow schedule of the store, runs the workflow when it's due and
+// records the outcome of every run in the stats store. A run still going
+// when its schedule fires again isn't started twice.
+type Engine struct {
+	store *ScheduleStore
+	stats *StatsStore
+	cron  *cron.Cron
+	// RunWorkflow runs the workflow of a schedule, writing its output to out
+	RunWorkflow func(ctx context.Context, schedule *WorkflowSchedule, out io.Writer) error
+	// CheckInterval is how often schedules are reloaded from the store
+	CheckInterval time.Duration
+	// Admit, when set, is asked before each run. It returns false to skip
+	// the run, as the master does while drained for an upgrade, and
+	// otherwise a function called once the run finished.
+	Admit func() (done func(), ok bool)
+
+	mu      sync.Mutex
+	ctx     context.Context
+	entries map[string]engineEntry
+	running map[string]bool
+}
+
+type engineEntry struct {
+	id       cron.EntryID
+	schedule WorkflowSchedule
+}
+
+// NewEngine creates an engine for the schedules of store. stats may be nil,
+// in which case runs aren't recorded.
+func NewEngine(store *ScheduleStore, stats *StatsStore) *Engine {
+	return &Engine{
+		store:         store,
+		stats:         stats,
+		cron:          cron.New(),
+		RunWorkflow:   runScheduledWorkflow,
+		CheckInterval: DefaultCheckInterval,
+		ctx:           context.Background(),
+		entries:       make(map[string]engineEntry),
+		running:       make(map[string]bool),
+	}
+}
+
+// runScheduledWorkflow runs the workflow of a schedule with this executable
+func runScheduledWorkflow(ctx context.Context, schedule *WorkflowSchedule, out io.Writer) error {
+	self, err := os.Executable()
+	if err != nil {
+		self = "sloth-runner"
+	}
+	cmd := exec.CommandContext(ctx, self, scheduledRunArgs(schedule)...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+// scheduledRunArgs are the 'sloth-runner run' arguments of a schedule
+func scheduledRunArgs(schedule *WorkflowSchedule) []string {
+	args := []string{"run", schedule.Stack}
+	if schedule.Sloth != "" {
+		args = append(args, "--sloth", schedule.Sloth)
+	} else {
+		args = append(args, "--file", schedule.File)
+	}
+	if schedule.Workflow != "" {
+		args = append(args, "--workflow", schedule.Workflow)
+	}
+	if schedule.ResumeRunID != "" {
+		args = append(args, "--resume", schedule.ResumeRunID)
+	}
+	return append(args, "--schedule", schedule.Name, "--yes")
+}
+
+// Run schedules workflows until ctx is done. Runs in progress are cancelled
+// with ctx.
+func (e *Engine) Run(ctx context.Context) {
+	e.mu.Lock()
+	e.ctx = ctx
+	e.mu.Unlock()
+
+	e.cron.Start()
+	defer e.cron.Stop()
+
+	ticker := time.NewTicker(e.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		e.Step()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step makes the cron entries match the enabled schedules of the store
+func (e *Engine) Step() {
+	schedules, err := e.store.List()
+	if err != nil {
+		slog.Error("Failed to read workflow schedules", "error", err)
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	desired := make(map[string]*WorkflowSchedule)
+	for _, schedule := range schedules {
+		if schedule.Enabled {
+			desired[schedule.Name] = schedule
+		}
+	}
+
+	for name, entry := range e.entries {
+		if want, ok := desired[name]; !ok || !sameRun(want, &entry.schedule) {
+			e.cron.Remove(entry.id)
+			delete(e.entries, name)
+			slog.Info("Workflow schedule removed", "schedule", name)
+		}
+	}
+
+	for name, want := range desired {
+		if _, ok := e.entries[name]; ok {
+			continue
+		}
+		parsed, err := ParseCron(want.Cron)
+		if err != nil {
+			// Validated when added, so only a hand-edited database gets here
+			slog.Error("Invalid workflow schedule", "schedule", name, "error", err)
+			continue
+		}
+		schedule := *want
+		id := e.cron.Schedule(parsed, cron.FuncJob(func() {
+			e.Trigger(&schedule)
+		}))
+		e.entries[name] = engineEntry{id: id, schedule: schedule}
+		slog.Info("Workflow schedule registered", "schedule", name, "cron", want.Cron, "stack", want.Stack,
+			"next_run", parsed.Next(timeNow()).Format(time.RFC3339))
+	}
+}
+
+// sameRun reports whether two versions of a schedule run the same way
+func sameRun(a, b *WorkflowSchedule) bool {
+	return a.Cron == b.Cron && a.Stack == b.Stack && a.Sloth == b.Sloth && a.File == b.File && a.Workflow == b.Workflow
+}
+
+// Resume runs the schedule named name again to resume its interrupted run
+// runID, as Trigger does. It returns false without running it when the
+// schedule was removed or disabled since, or Trigger doesn't run it.
+func (e *Engine) Resume(name, runID string) bool {
+	schedule, err := e.store.Get(name)
+	if err != nil {
+		slog.Warn("Can't resume the run of a removed workflow schedule", "schedule", name, "run_id", runID, "error", err)
+		return false
+	}
+	if !schedule.Enabled {
+		slog.Warn("Not resuming the run of a disabled workflow schedule", "schedule", name, "run_id", runID)
+		return false
+	}
+	schedule.ResumeRunID = runID
+	return e.Trigger(schedule)
+}
+
+// Trigger runs the workflow of a schedule now and records the run. It
+// returns false without running it when the previous run is still going,
+// the workflow file's maintenance windows are closed or Admit refuses it.
+func (e *Engine) Trigger(schedule *WorkflowSchedule) bool {
+	e.mu.Lock()
+	if e.running[schedule.Name] {
+		e.mu.Unlock()
+		slog.Warn("Skipping scheduled workflow, its previous run is still going", "schedule", schedule.Name)
+		return false
+	}
+	e.running[schedule.Name] = true
+	ctx := e.ctx
+	e.mu.Unlock()
+
+	defer func() {
+		e.mu.Lock()
+		delete(e.running, schedule.Name)
+		e.mu.Unlock()
+	}()
+
+	if schedule.File != "" {
+		if windows := workflowWindows(schedule.File); !windows.Allows(timeNow()) {
+			slog.Info("Skipping scheduled workflow outside its maintenance windows", "schedule", schedule.Name,
+				"windows", windows.String(), "next_open", windows.NextOpen(timeNow()).Format(time.RFC3339))
+			return false
+		}
+	}
+
+	if e.Admit != nil {
+		done, ok := e.Admit()
+		if !ok {
+			slog.Warn("Skipping scheduled workflow, the master is draining for an upgrade", "schedule", schedule.Name)
+			return false
+		}
+		defer done()
+	}
+
+	if schedule.ResumeRunID != "" {
+		slog.Info("Resuming scheduled workflow", "schedule", schedule.Name, "stack", schedule.Stack, "workflow", schedule.Source(), "run_id", schedule.ResumeRunID)
+	} else {
+		slog.Info("Running scheduled workflow", "schedule", schedule.Name, "stack", schedule.Stack, "workflow", schedule.Source())
+	}
+	output := &tailBuffer{max: failureCauseBytes}
+	startedAt := timeNow()
+	err := e.RunWorkflow(ctx, schedule, io.MultiWriter(os.Stdout, output))
+	run := ScheduleRun{Schedule: schedule.Name, StartedAt: startedAt, Duration: timeNow().Sub(startedAt), Success: err == nil}
+	if err != nil {
+		run.Cause = failureCause(output.String(), err)
+		slog.Error("Scheduled workflow failed", "schedule", schedule.Name, "cause", run.Cause)
+	} else {
+		slog.Info("Scheduled workflow completed", "schedule", schedule.Name, "duration", run.Duration)
+	}
+
+	if e.stats != nil {
+		if err := e.stats.Record(run); err != nil {
+			slog.Warn("Failed to record scheduled run", "schedule", schedule.Name, "error", err)
+		}
+	}
+	return true
+}
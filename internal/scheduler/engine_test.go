@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Step(t *testing.T) {
+	store := newTestScheduleStore(t)
+	e := NewEngine(store, nil)
+
+	require.NoError(t, store.Add(&WorkflowSchedule{Name: "deploy-prod", Cron: "0 3 * * *", Stack: "prod", Sloth: "deploy", Enabled: true}))
+	require.NoError(t, store.Add(&WorkflowSchedule{Name: "backup", Cron: "@hourly", Stack: "db", Sloth: "backup"}))
+
+	e.Step()
+	require.Len(t, e.entries, 1, "only enabled schedules are registered")
+	first := e.entries["deploy-prod"].id
+
+	// Nothing changed: the entry is kept
+	e.Step()
+	assert.Equal(t, first, e.entries["deploy-prod"].id)
+
+	// A changed schedule replaces its entry
+	require.NoError(t, store.Delete("deploy-prod"))
+	require.NoError(t, store.Add(&WorkflowSchedule{Name: "deploy-prod", Cron: "0 4 * * *", Stack: "prod", Sloth: "deploy", Enabled: true}))
+	require.NoError(t, store.SetEnabled("backup", true))
+	e.Step()
+	require.Len(t, e.entries, 2)
+	assert.NotEqual(t, first, e.entries["deploy-prod"].id)
+	assert.Equal(t, "0 4 * * *", e.entries["deploy-prod"].schedule.Cron)
+	assert.Len(t, e.cron.Entries(), 2)
+
+	require.NoError(t, store.SetEnabled("deploy-prod", false))
+	require.NoError(t, store.Delete("backup"))
+	e.Step()
+	assert.Empty(t, e.entries)
+	assert.Empty(t, e.cron.Entries())
+}
+
+func TestEngine_Trigger(t *testing.T) {
+	stats := newTestStatsStore(t)
+	e := NewEngine(newTestScheduleStore(t), stats)
+
+	var calls []string
+	e.RunWorkflow = func(ctx context.Context, s *WorkflowSchedule, out io.Writer) error {
+		calls = append(calls, fmt.Sprint(scheduledRunArgs(s)))
+		if s.Sloth == "broken" {
+			fmt.Fprintln(out, "Error: task deploy failed: exit status 1")
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+
+	assert.True(t, e.Trigger(&WorkflowSchedule{Name: "deploy-prod", Stack: "prod", Sloth: "deploy"}))
+	assert.True(t, e.Trigger(&WorkflowSchedule{Name: "deploy-prod", Stack: "prod", Sloth: "broken", Workflow: "web"}))
+
+	assert.Equal(t, []string{
+		"[run prod --sloth deploy --schedule deploy-prod --yes]",
+		"[run prod --sloth broken --workflow web --schedule deploy-prod --yes]",
+	}, calls)
+
+	runs, err := stats.Runs("deploy-prod", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.False(t, runs[0].Success, "newest first")
+	assert.Equal(t, "Error: task deploy failed: exit status 1", runs[0].Cause)
+	assert.True(t, runs[1].Success)
+}
+
+func TestEngine_TriggerSkipsOverlappingRuns(t *testing.T) {
+	stats := newTestStatsStore(t)
+	e := NewEngine(newTestScheduleStore(t), stats)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e.RunWorkflow = func(ctx context.Context, s *WorkflowSchedule, out io.Writer) error {
+		close(started)
+		<-release
+		return nil
+	}
+
+	schedule := &WorkflowSchedule{Name: "slow", Stack: "prod", Sloth: "slow"}
+	done := make(chan bool)
+	go func() { done <- e.Trigger(schedule) }()
+	<-started
+
+	assert.False(t, e.Trigger(schedule), "a schedule doesn't run twice at once")
+	close(release)
+	assert.True(t, <-done)
+
+	runs, err := stats.Runs("slow", 10)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
+
+func TestEngine_TriggerMaintenanceWindow(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deploy.sloth")
+	require.NoError(t, os.WriteFile(file, []byte("---\nname: deploy\nwindows: [\"weekends 00:00-24:00 UTC\"]\n---\n"), 0644))
+
+	orig := timeNow
+	defer func() { timeNow = orig }()
+	// A Friday at noon
+	timeNow = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+
+	e := NewEngine(newTestScheduleStore(t), nil)
+	ran := false
+	e.RunWorkflow = func(ctx context.Context, s *WorkflowSchedule, out io.Writer) error {
+		ran = true
+		return nil
+	}
+
+	schedule := &WorkflowSchedule{Name: "deploy", Stack: "prod", File: file}
+	assert.False(t, e.Trigger(schedule))
+	assert.False(t, ran, "runs outside the window are skipped")
+
+	timeNow = func() time.Time { return time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC) }
+	assert.True(t, e.Trigger(schedule))
+	assert.True(t, ran)
+}
+
+func TestEngine_TriggerAdmit(t *testing.T) {
+	stats := newTestStatsStore(t)
+	e := NewEngine(newTestScheduleStore(t), stats)
+	ran := false
+	e.RunWorkflow = func(ctx context.Context, s *WorkflowSchedule, out io.Writer) error {
+		ran = true
+		return nil
+	}
+
+	draining, inFlight := true, 0
+	e.Admit = func() (func(), bool) {
+		if draining {
+			return nil, false
+		}
+		inFlight++
+		return func() { inFlight-- }, true
+	}
+
+	schedule := &WorkflowSchedule{Name: "deploy", Stack: "prod", Sloth: "deploy"}
+	assert.False(t, e.Trigger(schedule))
+	assert.False(t, ran, "runs refused by Admit are skipped")
+	runs, err := stats.Runs("deploy", 10)
+	require.NoError(t, err)
+	assert.Empty(t, runs, "skipped runs aren't recorded")
+
+	draining = false
+	assert.True(t, e.Trigger(schedule))
+	assert.True(t, ran)
+	assert.Equal(t, 0, inFlight, "the run leaves once it finished")
+}
+
+func TestEngine_Resume(t *testing.T) {
+	store := newTestScheduleStore(t)
+	require.NoError(t, store.Add(&WorkflowSchedule{Name: "nightly", Cron: "@daily", Stack: "prod", Sloth: "deploy", Enabled: true}))
+	require.NoError(t, store.Add(&WorkflowSchedule{Name: "paused", Cron: "@daily", Stack: "prod", Sloth: "deploy"}))
+
+	e := NewEngine(store, nil)
+	var calls []string
+	e.RunWorkflow = func(ctx context.Context, s *WorkflowSchedule, out io.Writer) error {
+		calls = append(calls, fmt.Sprint(scheduledRunArgs(s)))
+		return nil
+	}
+
+	assert.True(t, e.Resume("nightly", "run-1"))
+	assert.False(t, e.Resume("paused", "run-2"), "disabled schedules aren't resumed")
+	assert.False(t, e.Resume("removed", "run-3"))
+	assert.Equal(t, []string{"[run prod --sloth deploy --resume run-1 --schedule nightly --yes]"}, calls)
+}
@@ -0,0 +1,441 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FeedsPath prefixes the feed endpoints
+const FeedsPath = "/feeds"
+
+const (
+	// DefaultFeedDays is how far ahead the calendar lists runs
+	DefaultFeedDays = 14
+	// DefaultFeedRuns is how many recent results the feeds carry
+	DefaultFeedRuns = 50
+	// maxUpcomingPerSchedule bounds the calendar entries of schedules that
+	// run often, e.g. @every 5m
+	maxUpcomingPerSchedule = 200
+	// defaultRunLength is how long calendar entries of schedules that
+	// never ran last
+	defaultRunLength = 15 * time.Minute
+)
+
+// UpcomingRun is a future run of a schedule
+type UpcomingRun struct {
+	Schedule *WorkflowSchedule
+	At       time.Time
+}
+
+// Upcoming lists the runs of the enabled schedules after from and up to
+// to, in time order, with at most max runs per schedule
+func Upcoming(schedules []*WorkflowSchedule, from, to time.Time, max int) []UpcomingRun {
+	var runs []UpcomingRun
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		parsed, err := ParseCron(schedule.Cron)
+		if err != nil {
+			continue
+		}
+		for at, n := parsed.Next(from), 0; !at.IsZero() && !at.After(to) && n < max; at, n = parsed.Next(at), n+1 {
+			runs = append(runs, UpcomingRun{Schedule: schedule, At: at})
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].At.Before(runs[j].At) })
+	return runs
+}
+
+// FeedAuthorizer authenticates a feed request, returning which schedules
+// it may see
+type FeedAuthorizer func(r *http.Request) (func(*WorkflowSchedule) bool, error)
+
+// FeedServer serves the upcoming runs and recent results of the workflow
+// schedules as an iCal calendar and Atom and RSS feeds, so teams can
+// follow scheduled maintenance from their calendars and feed readers
+type FeedServer struct {
+	schedules *ScheduleStore
+	stats     *StatsStore
+	authorize FeedAuthorizer
+	// Days is how far ahead the calendar lists runs
+	Days int
+	// Runs is how many recent results the feeds carry
+	Runs int
+}
+
+// NewFeedServer creates a feed server for the schedules of store. stats
+// may be nil, in which case the feeds have no results.
+func NewFeedServer(schedules *ScheduleStore, stats *StatsStore, authorize FeedAuthorizer) *FeedServer {
+	return &FeedServer{
+		schedules: schedules,
+		stats:     stats,
+		authorize: authorize,
+		Days:      DefaultFeedDays,
+		Runs:      DefaultFeedRuns,
+	}
+}
+
+// Handler returns the HTTP handler of the feeds:
+//
+//	GET /feeds/calendar.ics  upcoming runs and recent results
+//	GET /feeds/runs.atom     recent results
+//	GET /feeds/runs.rss      recent results
+//
+// Each takes stack and schedule query parameters to narrow it down; the
+// calendar also takes days, how far ahead it lists runs.
+func (s *FeedServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+FeedsPath+"/calendar.ics", s.handleCalendar)
+	mux.HandleFunc("GET "+FeedsPath+"/runs.atom", s.handleAtom)
+	mux.HandleFunc("GET "+FeedsPath+"/runs.rss", s.handleRSS)
+	return mux
+}
+
+// load returns the schedules the request may see and asks for, and their
+// recent runs, newest first. It writes the error response when it fails.
+func (s *FeedServer) load(w http.ResponseWriter, r *http.Request) ([]*WorkflowSchedule, []ScheduleRun, bool) {
+	visible, err := s.authorize(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	all, err := s.schedules.List()
+	if err != nil {
+		slog.Error("Failed to read workflow schedules for feeds", "error", err)
+		http.Error(w, "failed to read schedules", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	query := r.URL.Query()
+	var schedules []*WorkflowSchedule
+	for _, schedule := range all {
+		if visible(schedule) && matchesFilter(query["stack"], schedule.Stack) && matchesFilter(query["schedule"], schedule.Name) {
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	var runs []ScheduleRun
+	if s.stats != nil {
+		for _, schedule := range schedules {
+			scheduleRuns, err := s.stats.Runs(schedule.Name, s.Runs)
+			if err != nil {
+				slog.Error("Failed to read scheduled runs for feeds", "schedule", schedule.Name, "error", err)
+				http.Error(w, "failed to read runs", http.StatusInternalServerError)
+				return nil, nil, false
+			}
+			runs = append(runs, scheduleRuns...)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if len(runs) > s.Runs {
+		runs = runs[:s.Runs]
+	}
+	return schedules, runs, true
+}
+
+// matchesFilter reports whether name is one of filter, an empty filter
+// matching everything. Values may also be comma separated.
+func matchesFilter(filter []string, name string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, values := range filter {
+		for _, value := range strings.Split(values, ",") {
+			if strings.TrimSpace(value) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// feedURL is the URL of the requested feed, without its query so tokens
+// don't end up in feed content
+func feedURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+func (s *FeedServer) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	schedules, runs, ok := s.load(w, r)
+	if !ok {
+		return
+	}
+	days := s.Days
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 && d <= 366 {
+		days = d
+	}
+	now := timeNow()
+	upcoming := Upcoming(schedules, now, now.AddDate(0, 0, days), maxUpcomingPerSchedule)
+
+	var buf bytes.Buffer
+	writeCalendar(&buf, schedules, upcoming, runs, now)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func (s *FeedServer) handleAtom(w http.ResponseWriter, r *http.Request) {
+	schedules, runs, ok := s.load(w, r)
+	if !ok {
+		return
+	}
+	writeXML(w, "application/atom+xml; charset=utf-8", atomFeedOf(feedURL(r), schedules, runs, timeNow()))
+}
+
+func (s *FeedServer) handleRSS(w http.ResponseWriter, r *http.Request) {
+	schedules, runs, ok := s.load(w, r)
+	if !ok {
+		return
+	}
+	writeXML(w, "application/rss+xml; charset=utf-8", rssFeedOf(feedURL(r), schedules, runs, timeNow()))
+}
+
+func writeXML(w http.ResponseWriter, contentType string, feed interface{}) {
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	io.WriteString(w, xml.Header)
+	w.Write(out)
+}
+
+// byName indexes schedules by name
+func byName(schedules []*WorkflowSchedule) map[string]*WorkflowSchedule {
+	index := make(map[string]*WorkflowSchedule, len(schedules))
+	for _, schedule := range schedules {
+		index[schedule.Name] = schedule
+	}
+	return index
+}
+
+// runTitle and runSummary describe a finished run in feeds
+func runTitle(run ScheduleRun, schedule *WorkflowSchedule) string {
+	outcome := "succeeded"
+	if !run.Success {
+		outcome = "failed"
+	}
+	return fmt.Sprintf("%s %s on %s", run.Schedule, outcome, schedule.Stack)
+}
+
+func runSummary(run ScheduleRun, schedule *WorkflowSchedule) string {
+	summary := fmt.Sprintf("Ran %s on stack %s at %s, for %s.", schedule.Source(), schedule.Stack,
+		run.StartedAt.UTC().Format(time.RFC3339), run.Duration.Round(time.Second))
+	if !run.Success && run.Cause != "" {
+		summary += "\nCause: " + run.Cause
+	}
+	return summary
+}
+
+// eventUID identifies the calendar event of a run of a schedule. An
+// upcoming run and its result share it, cron firing on the minute, so
+// calendars replace the one with the other.
+func eventUID(schedule string, at time.Time) string {
+	return schedule + "-" + at.UTC().Truncate(time.Minute).Format("20060102T1504") + "@sloth-runner"
+}
+
+// writeCalendar writes an iCalendar (RFC 5545) of the upcoming runs and
+// the finished runs of schedules
+func writeCalendar(w io.Writer, schedules []*WorkflowSchedule, upcoming []UpcomingRun, runs []ScheduleRun, now time.Time) {
+	index := byName(schedules)
+	// Upcoming runs last as long as the latest run of their schedule
+	length := make(map[string]time.Duration)
+	for _, run := range runs {
+		if _, ok := length[run.Schedule]; !ok {
+			length[run.Schedule] = run.Duration.Round(time.Minute)
+		}
+	}
+
+	cal := &icalWriter{w: w}
+	cal.line("BEGIN", "VCALENDAR")
+	cal.line("VERSION", "2.0")
+	cal.line("PRODID", "-//sloth-runner//Workflow schedules//EN")
+	cal.line("CALSCALE", "GREGORIAN")
+	cal.line("METHOD", "PUBLISH")
+	cal.line("X-WR-CALNAME", "sloth-runner schedules")
+	cal.line("REFRESH-INTERVAL;VALUE=DURATION", "PT15M")
+	cal.line("X-PUBLISHED-TTL", "PT15M")
+
+	for _, run := range upcoming {
+		end := length[run.Schedule.Name]
+		if end < time.Minute {
+			end = defaultRunLength
+		}
+		cal.event(eventUID(run.Schedule.Name, run.At), now, run.At, run.At.Add(end),
+			fmt.Sprintf("%s on %s", run.Schedule.Name, run.Schedule.Stack),
+			fmt.Sprintf("Runs %s on stack %s (cron: %s).", run.Schedule.Source(), run.Schedule.Stack, run.Schedule.Cron),
+			run.Schedule.Stack)
+	}
+	for _, run := range runs {
+		schedule := index[run.Schedule]
+		end := run.StartedAt.Add(run.Duration)
+		if run.Duration < time.Minute {
+			end = run.StartedAt.Add(time.Minute)
+		}
+		cal.event(eventUID(run.Schedule, run.StartedAt), now, run.StartedAt, end,
+			runTitle(run, schedule), runSummary(run, schedule), schedule.Stack)
+	}
+	cal.line("END", "VCALENDAR")
+}
+
+// icalWriter writes iCalendar content lines: escaped, folded at 75
+// octets and ended with CRLF
+type icalWriter struct {
+	w io.Writer
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func (c *icalWriter) event(uid string, stamp, start, end time.Time, summary, description, stack string) {
+	const layout = "20060102T150405Z"
+	c.line("BEGIN", "VEVENT")
+	c.line("UID", uid)
+	c.line("DTSTAMP", stamp.UTC().Format(layout))
+	c.line("DTSTART", start.UTC().Format(layout))
+	c.line("DTEND", end.UTC().Format(layout))
+	c.line("SUMMARY", icalEscaper.Replace(summary))
+	c.line("DESCRIPTION", icalEscaper.Replace(description))
+	c.line("CATEGORIES", icalEscaper.Replace(stack))
+	c.line("TRANSP", "TRANSPARENT")
+	c.line("END", "VEVENT")
+}
+
+func (c *icalWriter) line(name, value string) {
+	line := name + ":" + value
+	for len(line) > 75 {
+		// Fold without splitting a UTF-8 sequence
+		cut := 75
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		io.WriteString(c.w, line[:cut]+"\r\n ")
+		line = line[cut:]
+	}
+	io.WriteString(c.w, line+"\r\n")
+}
+
+// Atom (RFC 4287) feed of finished runs
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Category  atomCategory `xml:"category"`
+	Summary   string       `xml:"summary"`
+}
+
+func atomFeedOf(url string, schedules []*WorkflowSchedule, runs []ScheduleRun, now time.Time) *atomFeed {
+	index := byName(schedules)
+	feed := &atomFeed{
+		ID:      "urn:sloth-runner:schedules:runs",
+		Title:   "sloth-runner scheduled runs",
+		Updated: now.UTC().Format(time.RFC3339),
+		Link:    atomLink{Rel: "self", Href: url},
+		Author:  atomAuthor{Name: "sloth-runner"},
+	}
+	if len(runs) > 0 {
+		feed.Updated = runs[0].StartedAt.Add(runs[0].Duration).UTC().Format(time.RFC3339)
+	}
+	for _, run := range runs {
+		schedule := index[run.Schedule]
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        "urn:sloth-runner:run:" + strings.TrimSuffix(eventUID(run.Schedule, run.StartedAt), "@sloth-runner"),
+			Title:     runTitle(run, schedule),
+			Published: run.StartedAt.UTC().Format(time.RFC3339),
+			Updated:   run.StartedAt.Add(run.Duration).UTC().Format(time.RFC3339),
+			Category:  atomCategory{Term: schedule.Stack},
+			Summary:   runSummary(run, schedule),
+		})
+	}
+	return feed
+}
+
+// RSS 2.0 feed of finished runs
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	TTL           int       `xml:"ttl"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category"`
+	PubDate     string  `xml:"pubDate"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+func rssFeedOf(url string, schedules []*WorkflowSchedule, runs []ScheduleRun, now time.Time) *rssFeed {
+	index := byName(schedules)
+	feed := &rssFeed{Version: "2.0", Channel: rssChannel{
+		Title:         "sloth-runner scheduled runs",
+		Link:          url,
+		Description:   "Results of the workflow schedules of sloth-runner",
+		LastBuildDate: now.UTC().Format(time.RFC1123Z),
+		TTL:           15,
+	}}
+	for _, run := range runs {
+		schedule := index[run.Schedule]
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       runTitle(run, schedule),
+			Description: runSummary(run, schedule),
+			Category:    schedule.Stack,
+			PubDate:     run.StartedAt.Add(run.Duration).UTC().Format(time.RFC1123Z),
+			GUID:        rssGUID{IsPermaLink: "false", Value: eventUID(run.Schedule, run.StartedAt)},
+		})
+	}
+	return feed
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpcoming(t *testing.T) {
+	from := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	schedules := []*WorkflowSchedule{
+		{Name: "nightly", Cron: "0 3 * * *", Enabled: true},
+		{Name: "often", Cron: "@every 1h", Enabled: true},
+		{Name: "off", Cron: "0 4 * * *"},
+	}
+
+	runs := Upcoming(schedules, from, from.Add(24*time.Hour), 5)
+	var got []string
+	for _, run := range runs {
+		got = append(got, run.Schedule.Name+"@"+run.At.Format("15:04"))
+	}
+	assert.Equal(t, []string{"often@13:00", "often@14:00", "often@15:00", "often@16:00", "often@17:00", "nightly@03:00"}, got)
+}
+
+func newTestFeedServer(t *testing.T) *httptest.Server {
+	orig := timeNow
+	timeNow = func() time.Time { return time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { timeNow = orig })
+
+	schedules := newTestScheduleStore(t)
+	require.NoError(t, schedules.Add(&WorkflowSchedule{Name: "patch-web", Cron: "0 3 * * *", Stack: "web", File: "/srv/patch.sloth", Enabled: true}))
+	require.NoError(t, schedules.Add(&WorkflowSchedule{Name: "backup-db", Cron: "30 1 * * *", Stack: "db", Sloth: "backup", Enabled: true}))
+	stats := newTestStatsStore(t)
+	require.NoError(t, stats.Record(ScheduleRun{Schedule: "patch-web", StartedAt: time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC), Duration: 20 * time.Minute, Success: true}))
+	require.NoError(t, stats.Record(ScheduleRun{Schedule: "backup-db", StartedAt: time.Date(2026, 10, 17, 1, 30, 0, 0, time.UTC), Duration: time.Minute, Cause: "disk full; no space left"}))
+	require.NoError(t, stats.Record(ScheduleRun{Schedule: "removed", StartedAt: time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC), Success: true}))
+
+	// "web" tokens only see the web stack
+	authorize := func(r *http.Request) (func(*WorkflowSchedule) bool, error) {
+		switch r.URL.Query().Get("token") {
+		case "all":
+			return func(*WorkflowSchedule) bool { return true }, nil
+		case "web":
+			return func(s *WorkflowSchedule) bool { return s.Stack == "web" }, nil
+		}
+		return nil, errors.New("invalid token")
+	}
+	server := NewFeedServer(schedules, stats, authorize)
+	server.Days = 2
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getFeed(t *testing.T, url string) (int, string, string) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, resp.Header.Get("Content-Type"), string(body)
+}
+
+func TestFeedServer_Calendar(t *testing.T) {
+	ts := newTestFeedServer(t)
+
+	status, contentType, body := getFeed(t, ts.URL+"/feeds/calendar.ics?token=all")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "text/calendar; charset=utf-8", contentType)
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "line not folded: %q", line)
+	}
+	unfolded := strings.ReplaceAll(body, "\r\n ", "")
+
+	// Two days of upcoming runs, lasting as long as the last run
+	assert.Equal(t, 2, strings.Count(unfolded, "SUMMARY:patch-web on web\r\n"))
+	assert.Contains(t, unfolded, "UID:patch-web-20261018T0300@sloth-runner\r\nDTSTAMP:20261017T120000Z\r\nDTSTART:20261018T030000Z\r\nDTEND:20261018T032000Z\r\n")
+	assert.Equal(t, 2, strings.Count(unfolded, "SUMMARY:backup-db on db\r\n"))
+
+	// Recent results, escaped
+	assert.Contains(t, unfolded, "UID:patch-web-20261017T0300@sloth-runner\r\n")
+	assert.Contains(t, unfolded, "SUMMARY:backup-db failed on db\r\n")
+	assert.Contains(t, unfolded, `Cause: disk full\; no space left`)
+	assert.NotContains(t, unfolded, "removed")
+
+	_, _, body = getFeed(t, ts.URL+"/feeds/calendar.ics?token=all&stack=db&days=1")
+	assert.NotContains(t, body, "patch-web")
+	assert.Equal(t, 1, strings.Count(body, "SUMMARY:backup-db on db\r\n"))
+}
+
+func TestFeedServer_Feeds(t *testing.T) {
+	ts := newTestFeedServer(t)
+
+	status, contentType, body := getFeed(t, ts.URL+"/feeds/runs.atom?token=all")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", contentType)
+	var atom atomFeed
+	require.NoError(t, xml.Unmarshal([]byte(body), &atom))
+	require.Len(t, atom.Entries, 2)
+	assert.Equal(t, "patch-web succeeded on web", atom.Entries[0].Title)
+	assert.Equal(t, "backup-db failed on db", atom.Entries[1].Title)
+	assert.Equal(t, "2026-10-17T03:20:00Z", atom.Updated)
+	assert.Equal(t, ts.URL+"/feeds/runs.atom", atom.Link.Href, "the token shouldn't leak into the feed")
+
+	status, _, body = getFeed(t, ts.URL+"/feeds/runs.rss?token=web")
+	require.Equal(t, http.StatusOK, status)
+	var rss rssFeed
+	require.NoError(t, xml.Unmarshal([]byte(body), &rss))
+	require.Len(t, rss.Channel.Items, 1)
+	assert.Equal(t, "patch-web succeeded on web", rss.Channel.Items[0].Title)
+	assert.Equal(t, "Sat, 17 Oct 2026 03:20:00 +0000", rss.Channel.Items[0].PubDate)
+
+	status, _, _ = getFeed(t, ts.URL+"/feeds/runs.rss")
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultMetricsWindow is how far back the schedule metrics look
+const DefaultMetricsWindow = 7 * 24 * time.Hour
+
+var (
+	scheduleRunsDesc = prometheus.NewDesc(
+		"sloth_schedule_runs",
+		"Runs of the schedule in the metrics window",
+		[]string{"schedule"}, nil,
+	)
+	scheduleFailuresDesc = prometheus.NewDesc(
+		"sloth_schedule_failures",
+		"Failed runs of the schedule in the metrics window",
+		[]string{"schedule"}, nil,
+	)
+	scheduleSuccessRatioDesc = prometheus.NewDesc(
+		"sloth_schedule_success_ratio",
+		"Ratio of successful runs of the schedule in the metrics window",
+		[]string{"schedule"}, nil,
+	)
+	scheduleDurationDesc = prometheus.NewDesc(
+		"sloth_schedule_duration_seconds",
+		"Run duration percentiles of the schedule in the metrics window",
+		[]string{"schedule", "quantile"}, nil,
+	)
+	scheduleLastRunDesc = prometheus.NewDesc(
+		"sloth_schedule_last_run_timestamp_seconds",
+		"Unix time the schedule last ran",
+		[]string{"schedule"}, nil,
+	)
+	scheduleLastSuccessDesc = prometheus.NewDesc(
+		"sloth_schedule_last_success_timestamp_seconds",
+		"Unix time the schedule last succeeded",
+		[]string{"schedule"}, nil,
+	)
+	scheduleLastFailureDesc = prometheus.NewDesc(
+		"sloth_schedule_last_failure_timestamp_seconds",
+		"Unix time the schedule last failed",
+		[]string{"schedule"}, nil,
+	)
+)
+
+// StatsCollector exports the stats of a StatsStore as Prometheus metrics,
+// read from the store on every scrape
+type StatsCollector struct {
+	store  *StatsStore
+	window time.Duration
+}
+
+// NewStatsCollector creates a collector over the runs of the last window
+func NewStatsCollector(store *StatsStore, window time.Duration) *StatsCollector {
+	if window <= 0 {
+		window = DefaultMetricsWindow
+	}
+	return &StatsCollector{store: store, window: window}
+}
+
+// Describe implements prometheus.Collector
+func (c *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- scheduleRunsDesc
+	ch <- scheduleFailuresDesc
+	ch <- scheduleSuccessRatioDesc
+	ch <- scheduleDurationDesc
+	ch <- scheduleLastRunDesc
+	ch <- scheduleLastSuccessDesc
+	ch <- scheduleLastFailureDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.store.Stats(time.Now().Add(-c.window))
+	if err != nil {
+		slog.Warn("Failed to read schedule stats", "error", err)
+		return
+	}
+
+	for _, st := range stats {
+		gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append([]string{st.Schedule}, labels...)...)
+		}
+		timestamp := func(desc *prometheus.Desc, t time.Time) {
+			if !t.IsZero() {
+				gauge(desc, float64(t.Unix()))
+			}
+		}
+
+		gauge(scheduleRunsDesc, float64(st.Runs))
+		gauge(scheduleFailuresDesc, float64(st.Failures))
+		gauge(scheduleSuccessRatioDesc, st.SuccessRate)
+		gauge(scheduleDurationDesc, st.P50.Seconds(), "0.5")
+		gauge(scheduleDurationDesc, st.P90.Seconds(), "0.9")
+		gauge(scheduleDurationDesc, st.P99.Seconds(), "0.99")
+		timestamp(scheduleLastRunDesc, st.LastRun)
+		timestamp(scheduleLastSuccessDesc, st.LastSuccess)
+		timestamp(scheduleLastFailureDesc, st.LastFailure)
+	}
+}
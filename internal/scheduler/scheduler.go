@@ -2,11 +2,16 @@ package scheduler
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
 	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v2"
 )
@@ -18,6 +23,12 @@ type ScheduledTask struct {
 	TaskFile  string `yaml:"task_file"`
 	TaskGroup string `yaml:"task_group"`
 	TaskName  string `yaml:"task_name"`
+	// OverrideWindow, when set, runs the task outside the workflow's
+	// maintenance windows with this reason
+	OverrideWindow string `yaml:"override_window,omitempty"`
+	// OverrideBudget, when set, runs the task over the stack's execution
+	// budget with this reason
+	OverrideBudget string `yaml:"override_budget,omitempty"`
 }
 
 // SchedulerConfig holds the configuration for the scheduler
@@ -31,6 +42,8 @@ type Scheduler struct {
 	configPath string
 	config     *SchedulerConfig
 	mu         sync.Mutex
+	// stats, when set, records the outcome of every scheduled run
+	stats *StatsStore
 }
 
 // NewScheduler creates a new Scheduler instance
@@ -68,6 +81,15 @@ func (s *Scheduler) Start() error {
 		return fmt.Errorf("scheduler configuration not loaded")
 	}
 
+	if s.stats == nil {
+		stats, err := DefaultStatsStore()
+		if err != nil {
+			slog.Warn("Scheduled run stats are disabled", "error", err)
+		} else {
+			s.stats = stats
+		}
+	}
+
 	for _, task := range s.config.ScheduledTasks {
 		task := task // capture loop variable
 		_, err := s.cron.AddFunc(task.Schedule, func() {
@@ -92,24 +114,111 @@ func (s *Scheduler) Stop() {
 	fmt.Println("Scheduler stopped.")
 }
 
-// Mockable exec.Command for testing
-var execCommand = exec.Command
+// Mockable exec.Command and time.Now for testing
+var (
+	execCommand = exec.Command
+	timeNow     = time.Now
+)
 
 // RunTask executes a sloth-runner task
 func (s *Scheduler) RunTask(task ScheduledTask) {
 	fmt.Printf("Executing scheduled task '%s' (file: %s, group: %s, task: %s)...\n", task.Name, task.TaskFile, task.TaskGroup, task.TaskName)
 
+	args := []string{"run", "-f", task.TaskFile, "-g", task.TaskGroup, "-t", task.TaskName}
+	if task.OverrideWindow != "" {
+		args = append(args, "--override-window", task.OverrideWindow)
+	} else if windows := workflowWindows(task.TaskFile); !windows.Allows(timeNow()) {
+		fmt.Printf("Skipping scheduled task '%s': outside its maintenance windows (%s), the next one opens %s\n",
+			task.Name, windows, windows.NextOpen(timeNow()).Format(time.RFC1123))
+		return
+	}
+	if task.OverrideBudget != "" {
+		args = append(args, "--override-budget", task.OverrideBudget)
+	}
+
 	// Assuming sloth-runner executable is in the same directory or in PATH
-	cmd := execCommand("sloth-runner", "run", "-f", task.TaskFile, "-g", task.TaskGroup, "-t", task.TaskName)
+	cmd := execCommand("sloth-runner", args...)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	output := &tailBuffer{max: failureCauseBytes}
+	cmd.Stdout = io.MultiWriter(os.Stdout, output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, output)
 
-	if err := cmd.Run(); err != nil {
+	startedAt := time.Now()
+	err := cmd.Run()
+	if err != nil {
 		fmt.Printf("Error executing scheduled task '%s': %v\n", task.Name, err)
 	} else {
 		fmt.Printf("Scheduled task '%s' completed successfully.\n", task.Name)
 	}
+
+	s.mu.Lock()
+	stats := s.stats
+	s.mu.Unlock()
+	if stats != nil {
+		run := ScheduleRun{Schedule: task.Name, StartedAt: startedAt, Duration: time.Since(startedAt), Success: err == nil}
+		if err != nil {
+			run.Cause = failureCause(output.String(), err)
+		}
+		if err := stats.Record(run); err != nil {
+			slog.Warn("Failed to record scheduled run", "task", task.Name, "error", err)
+		}
+	}
+}
+
+// workflowWindows returns the maintenance windows declared in a workflow's
+// front-matter. Files that can't be read are left for the run to report.
+func workflowWindows(path string) sloth.Windows {
+	meta, _, err := sloth.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return meta.MaintenanceWindows()
+}
+
+// SetStatsStore sets where the outcome of scheduled runs is recorded
+func (s *Scheduler) SetStatsStore(stats *StatsStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = stats
+}
+
+// failureCauseBytes is how much of a failed run's output is kept to find
+// the cause of the failure
+const failureCauseBytes = 4096
+
+// failureCause is the last line of a failed run's output, which is usually
+// the error, or the exit error when there is no output
+func failureCause(output string, err error) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+	return err.Error()
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
 }
 
 // Config returns the current scheduler configuration
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -92,6 +93,7 @@ func TestRunTask(t *testing.T) {
 		return cmd
 	}
 
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
 	sched := NewScheduler("dummy.yaml")
 	task := ScheduledTask{
 		Name:      "mock_task",
@@ -104,6 +106,58 @@ func TestRunTask(t *testing.T) {
 	// In a real test, you'd check logs or mock stdout/stderr to verify output
 }
 
+func TestRunTask_MaintenanceWindow(t *testing.T) {
+	oldExecCommand, oldTimeNow := execCommand, timeNow
+	defer func() { execCommand, timeNow = oldExecCommand, oldTimeNow }()
+
+	var calls [][]string
+	execCommand = func(name string, arg ...string) *exec.Cmd {
+		calls = append(calls, arg)
+		return exec.Command("true")
+	}
+	// A Friday at noon
+	timeNow = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+
+	taskFile := filepath.Join(t.TempDir(), "deploy.sloth")
+	err := os.WriteFile(taskFile, []byte("---\nname: deploy\nwindows: [\"weekends 00:00-24:00 UTC\"]\n---\n"), 0644)
+	assert.NoError(t, err)
+
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	sched := NewScheduler("dummy.yaml")
+	task := ScheduledTask{Name: "deploy", TaskFile: taskFile, TaskGroup: "deploy", TaskName: "all"}
+
+	sched.RunTask(task)
+	assert.Empty(t, calls, "runs outside the window are skipped")
+
+	task.OverrideWindow = "hotfix"
+	sched.RunTask(task)
+	assert.Len(t, calls, 1)
+	assert.Contains(t, calls[0], "--override-window")
+
+	timeNow = func() time.Time { return time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC) }
+	task.OverrideWindow = ""
+	sched.RunTask(task)
+	assert.Len(t, calls, 2)
+}
+
+func TestRunTask_OverrideBudget(t *testing.T) {
+	oldExecCommand := execCommand
+	defer func() { execCommand = oldExecCommand }()
+
+	var calls [][]string
+	execCommand = func(name string, arg ...string) *exec.Cmd {
+		calls = append(calls, arg)
+		return exec.Command("true")
+	}
+
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	sched := NewScheduler("dummy.yaml")
+	sched.RunTask(ScheduledTask{Name: "sync", TaskFile: "sync.sloth", TaskGroup: "sync", TaskName: "all", OverrideBudget: "backfill"})
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, []string{"--override-budget", "backfill"}, calls[0][len(calls[0])-2:])
+	}
+}
+
 // TestHelperProcess is a helper for TestRunTask to mock exec.Command
 func TestHelperProcess(t *testing.T) {
 	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
@@ -140,6 +194,8 @@ func TestHelperProcess(t *testing.T) {
 // var execCommand = exec.Command // This is already defined in scheduler.go
 
 func TestStartStop(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+
 	// Create a temporary config file
 	tmpFile, err := ioutil.TempFile("", "scheduler_test_*.yaml")
 	assert.NoError(t, err)
@@ -0,0 +1,235 @@
+package scheduler
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+)
+
+// maxRunsPerSchedule bounds the run history kept for each schedule
+const maxRunsPerSchedule = 1000
+
+// ScheduleRun is the outcome of one run of a scheduled workflow
+type ScheduleRun struct {
+	Schedule  string        `json:"schedule"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Success   bool          `json:"success"`
+	// Cause is why the run failed, e.g. the last line of its output
+	Cause string `json:"cause,omitempty"`
+}
+
+// ScheduleStats summarizes the runs of a schedule over a window
+type ScheduleStats struct {
+	Schedule    string        `json:"schedule"`
+	Runs        int           `json:"runs"`
+	Failures    int           `json:"failures"`
+	SuccessRate float64       `json:"success_rate"`
+	P50         time.Duration `json:"p50"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+	LastRun     time.Time     `json:"last_run"`
+	LastSuccess time.Time     `json:"last_success,omitempty"`
+	LastFailure time.Time     `json:"last_failure,omitempty"`
+	// LastFailureCause is the cause of the most recent failure, which may
+	// be older than the window
+	LastFailureCause string `json:"last_failure_cause,omitempty"`
+}
+
+// StatsStore records scheduled runs in SQLite
+type StatsStore struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// NewStatsStore opens (creating if needed) the stats store at dbPath
+func NewStatsStore(dbPath string) (*StatsStore, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create stats directory: %w", err)
+	}
+
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schedule stats: %w", err)
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS schedule_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		schedule TEXT NOT NULL,
+		started_at INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		success INTEGER NOT NULL,
+		cause TEXT DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_schedule_runs_schedule ON schedule_runs(schedule, started_at);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		sqlitedb.Close(db)
+		return nil, fmt.Errorf("failed to initialize schedule stats: %w", err)
+	}
+
+	return &StatsStore{db: db}, nil
+}
+
+// DefaultStatsStore opens the stats store in the data directory
+func DefaultStatsStore() (*StatsStore, error) {
+	return NewStatsStore(config.GetScheduleStatsDBPath())
+}
+
+// Close closes the store
+func (s *StatsStore) Close() error {
+	return sqlitedb.Close(s.db)
+}
+
+// Record stores a run and drops the oldest runs of its schedule beyond
+// maxRunsPerSchedule
+func (s *StatsStore) Record(run ScheduleRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(
+		`INSERT INTO schedule_runs (schedule, started_at, duration_ms, success, cause) VALUES (?, ?, ?, ?, ?)`,
+		run.Schedule, run.StartedAt.Unix(), run.Duration.Milliseconds(), run.Success, run.Cause,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record scheduled run: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		DELETE FROM schedule_runs WHERE schedule = ? AND id NOT IN (
+			SELECT id FROM schedule_runs WHERE schedule = ? ORDER BY started_at DESC, id DESC LIMIT ?
+		)`, run.Schedule, run.Schedule, maxRunsPerSchedule)
+	if err != nil {
+		return fmt.Errorf("failed to prune scheduled runs: %w", err)
+	}
+	return nil
+}
+
+// Runs returns the most recent runs of a schedule, newest first
+func (s *StatsStore) Runs(schedule string, limit int) ([]ScheduleRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(
+		`SELECT started_at, duration_ms, success, cause FROM schedule_runs WHERE schedule = ? ORDER BY started_at DESC, id DESC LIMIT ?`,
+		schedule, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []ScheduleRun
+	for rows.Next() {
+		run := ScheduleRun{Schedule: schedule}
+		var startedAt, durationMs int64
+		if err := rows.Scan(&startedAt, &durationMs, &run.Success, &run.Cause); err != nil {
+			return nil, err
+		}
+		run.StartedAt = time.Unix(startedAt, 0)
+		run.Duration = time.Duration(durationMs) * time.Millisecond
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// Stats summarizes the runs of every schedule since the given time,
+// sorted by schedule name
+func (s *StatsStore) Stats(since time.Time) ([]ScheduleStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(
+		`SELECT schedule, started_at, duration_ms, success, cause FROM schedule_runs WHERE started_at >= ? ORDER BY started_at, id`,
+		since.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled runs: %w", err)
+	}
+	defer rows.Close()
+
+	bySchedule := make(map[string][]ScheduleRun)
+	for rows.Next() {
+		var run ScheduleRun
+		var startedAt, durationMs int64
+		if err := rows.Scan(&run.Schedule, &startedAt, &durationMs, &run.Success, &run.Cause); err != nil {
+			return nil, err
+		}
+		run.StartedAt = time.Unix(startedAt, 0)
+		run.Duration = time.Duration(durationMs) * time.Millisecond
+		bySchedule[run.Schedule] = append(bySchedule[run.Schedule], run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([]ScheduleStats, 0, len(bySchedule))
+	for schedule, runs := range bySchedule {
+		st := summarizeRuns(schedule, runs)
+		// The last failure may be older than the window
+		if st.LastFailure.IsZero() {
+			var startedAt int64
+			var cause string
+			err := s.db.QueryRow(
+				`SELECT started_at, cause FROM schedule_runs WHERE schedule = ? AND success = 0 ORDER BY started_at DESC, id DESC LIMIT 1`,
+				schedule,
+			).Scan(&startedAt, &cause)
+			if err == nil {
+				st.LastFailure = time.Unix(startedAt, 0)
+				st.LastFailureCause = cause
+			} else if err != sql.ErrNoRows {
+				return nil, err
+			}
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Schedule < stats[j].Schedule })
+	return stats, nil
+}
+
+// summarizeRuns computes the stats of a schedule's runs, oldest first
+func summarizeRuns(schedule string, runs []ScheduleRun) ScheduleStats {
+	st := ScheduleStats{Schedule: schedule, Runs: len(runs)}
+	durations := make([]time.Duration, 0, len(runs))
+	for _, run := range runs {
+		durations = append(durations, run.Duration)
+		st.LastRun = run.StartedAt
+		if run.Success {
+			st.LastSuccess = run.StartedAt
+		} else {
+			st.Failures++
+			st.LastFailure = run.StartedAt
+			st.LastFailureCause = run.Cause
+		}
+	}
+	if st.Runs > 0 {
+		st.SuccessRate = float64(st.Runs-st.Failures) / float64(st.Runs)
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	st.P50 = percentile(durations, 50)
+	st.P90 = percentile(durations, 90)
+	st.P99 = percentile(durations, 99)
+	return st
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
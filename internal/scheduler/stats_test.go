@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStatsStore(t *testing.T) *StatsStore {
+	t.Helper()
+	store, err := NewStatsStore(filepath.Join(t.TempDir(), "stats.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStatsStore_Stats(t *testing.T) {
+	store := newTestStatsStore(t)
+	start := time.Now().Add(-time.Hour)
+
+	for i := 1; i <= 10; i++ {
+		run := ScheduleRun{
+			Schedule:  "nightly",
+			StartedAt: start.Add(time.Duration(i) * time.Minute),
+			Duration:  time.Duration(i) * time.Second,
+			Success:   i != 4 && i != 7,
+		}
+		if !run.Success {
+			run.Cause = fmt.Sprintf("run %d failed", i)
+		}
+		require.NoError(t, store.Record(run))
+	}
+	require.NoError(t, store.Record(ScheduleRun{Schedule: "hourly", StartedAt: start, Duration: time.Second, Success: true}))
+
+	stats, err := store.Stats(start.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "hourly", stats[0].Schedule)
+	assert.Equal(t, 1.0, stats[0].SuccessRate)
+	assert.True(t, stats[0].LastFailure.IsZero())
+
+	nightly := stats[1]
+	assert.Equal(t, 10, nightly.Runs)
+	assert.Equal(t, 2, nightly.Failures)
+	assert.InDelta(t, 0.8, nightly.SuccessRate, 0.0001)
+	assert.Equal(t, 5*time.Second, nightly.P50)
+	assert.Equal(t, 9*time.Second, nightly.P90)
+	assert.Equal(t, 10*time.Second, nightly.P99)
+	assert.Equal(t, start.Add(10*time.Minute).Unix(), nightly.LastRun.Unix())
+	assert.Equal(t, start.Add(7*time.Minute).Unix(), nightly.LastFailure.Unix())
+	assert.Equal(t, "run 7 failed", nightly.LastFailureCause)
+}
+
+// TestStatsStore_LastFailureOutsideWindow validates that the last failure
+// is reported even when it is older than the window
+func TestStatsStore_LastFailureOutsideWindow(t *testing.T) {
+	store := newTestStatsStore(t)
+	now := time.Now()
+
+	require.NoError(t, store.Record(ScheduleRun{Schedule: "nightly", StartedAt: now.Add(-48 * time.Hour), Success: false, Cause: "disk full"}))
+	require.NoError(t, store.Record(ScheduleRun{Schedule: "nightly", StartedAt: now.Add(-time.Hour), Duration: time.Minute, Success: true}))
+
+	stats, err := store.Stats(now.Add(-24 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Runs)
+	assert.Equal(t, 0, stats[0].Failures)
+	assert.Equal(t, "disk full", stats[0].LastFailureCause)
+	assert.Equal(t, now.Add(-48*time.Hour).Unix(), stats[0].LastFailure.Unix())
+}
+
+func TestStatsCollector(t *testing.T) {
+	store := newTestStatsStore(t)
+	require.NoError(t, store.Record(ScheduleRun{Schedule: "nightly", StartedAt: time.Now(), Duration: 2 * time.Second, Success: false, Cause: "boom"}))
+
+	expected := `
+# HELP sloth_schedule_success_ratio Ratio of successful runs of the schedule in the metrics window
+# TYPE sloth_schedule_success_ratio gauge
+sloth_schedule_success_ratio{schedule="nightly"} 0
+# HELP sloth_schedule_failures Failed runs of the schedule in the metrics window
+# TYPE sloth_schedule_failures gauge
+sloth_schedule_failures{schedule="nightly"} 1
+`
+	collector := NewStatsCollector(store, 0)
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"sloth_schedule_success_ratio", "sloth_schedule_failures"))
+	// runs, failures, ratio, 3 quantiles, last run and last failure
+	assert.Equal(t, 8, testutil.CollectAndCount(collector))
+}
+
+func TestFailureCause(t *testing.T) {
+	err := errors.New("exit status 1")
+	assert.Equal(t, "Error: task 'backup' failed", failureCause("starting\nError: task 'backup' failed\n\n", err))
+	assert.Equal(t, "exit status 1", failureCause("  \n", err))
+}
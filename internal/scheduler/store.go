@@ -0,0 +1,239 @@
+package scheduler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
+	"github.com/robfig/cron/v3"
+)
+
+// WorkflowSchedule runs a workflow on a stack on a cron schedule. The
+// workflow is a saved sloth or a workflow file: exactly one of Sloth and
+// File is set.
+type WorkflowSchedule struct {
+	Name  string `json:"name"`
+	Cron  string `json:"cron"`
+	Stack string `json:"stack"`
+	Sloth string `json:"sloth,omitempty"`
+	File  string `json:"file,omitempty"`
+	// Workflow, when set, runs only this named workflow of the file
+	Workflow  string    `json:"workflow,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ResumeRunID, when set, makes the run resume the interrupted run with
+	// this ID instead of starting afresh. It isn't stored.
+	ResumeRunID string `json:"-"`
+}
+
+// Source describes what the schedule runs
+func (s *WorkflowSchedule) Source() string {
+	source := s.Sloth
+	if s.File != "" {
+		source = s.File
+	}
+	if s.Workflow != "" {
+		source += " (" + s.Workflow + ")"
+	}
+	return source
+}
+
+// Next returns the next time the schedule runs after t, or the zero time
+// when its expression doesn't parse
+func (s *WorkflowSchedule) Next(t time.Time) time.Time {
+	schedule, err := ParseCron(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return schedule.Next(t)
+}
+
+// ParseCron parses a standard 5-field cron expression. Descriptors such as
+// @daily or @every 1h and a CRON_TZ= prefix are accepted too.
+func ParseCron(expr string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(strings.TrimSpace(expr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	return schedule, nil
+}
+
+var (
+	// ErrScheduleNotFound is returned for a schedule that doesn't exist
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrScheduleExists is returned when adding a schedule under a taken name
+	ErrScheduleExists = errors.New("schedule already exists")
+)
+
+// ScheduleStore keeps the workflow schedules the master runs in SQLite
+type ScheduleStore struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// NewScheduleStore opens (creating if needed) the schedule store at dbPath
+func NewScheduleStore(dbPath string) (*ScheduleStore, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create schedules directory: %w", err)
+	}
+
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schedules database: %w", err)
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS workflow_schedules (
+		name TEXT PRIMARY KEY,
+		cron TEXT NOT NULL,
+		stack TEXT NOT NULL,
+		sloth TEXT DEFAULT '',
+		file TEXT DEFAULT '',
+		workflow TEXT DEFAULT '',
+		enabled INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		sqlitedb.Close(db)
+		return nil, fmt.Errorf("failed to initialize schedules database: %w", err)
+	}
+
+	return &ScheduleStore{db: db}, nil
+}
+
+// DefaultScheduleStore opens the schedule store in the data directory
+func DefaultScheduleStore() (*ScheduleStore, error) {
+	return NewScheduleStore(config.GetSchedulesDBPath())
+}
+
+// Close closes the store
+func (s *ScheduleStore) Close() error {
+	return sqlitedb.Close(s.db)
+}
+
+// Add stores a new schedule after validating it
+func (s *ScheduleStore) Add(schedule *WorkflowSchedule) error {
+	if schedule.Name == "" || schedule.Stack == "" {
+		return fmt.Errorf("a schedule needs a name and a stack")
+	}
+	if (schedule.Sloth == "") == (schedule.File == "") {
+		return fmt.Errorf("a schedule runs either a saved sloth or a workflow file")
+	}
+	if _, err := ParseCron(schedule.Cron); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if schedule.CreatedAt.IsZero() {
+		schedule.CreatedAt = now
+	}
+	schedule.UpdatedAt = now
+	_, err := s.db.Exec(`INSERT INTO workflow_schedules
+		(name, cron, stack, sloth, file, workflow, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		schedule.Name, schedule.Cron, schedule.Stack, schedule.Sloth, schedule.File, schedule.Workflow,
+		schedule.Enabled, schedule.CreatedAt.Unix(), schedule.UpdatedAt.Unix())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("%w: %s", ErrScheduleExists, schedule.Name)
+		}
+		return fmt.Errorf("failed to add schedule %s: %w", schedule.Name, err)
+	}
+	return nil
+}
+
+const scheduleColumns = `name, cron, stack, sloth, file, workflow, enabled, created_at, updated_at`
+
+func scanSchedule(row interface{ Scan(...interface{}) error }) (*WorkflowSchedule, error) {
+	var schedule WorkflowSchedule
+	var createdAt, updatedAt int64
+	err := row.Scan(&schedule.Name, &schedule.Cron, &schedule.Stack, &schedule.Sloth, &schedule.File,
+		&schedule.Workflow, &schedule.Enabled, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	schedule.CreatedAt = time.Unix(createdAt, 0)
+	schedule.UpdatedAt = time.Unix(updatedAt, 0)
+	return &schedule, nil
+}
+
+// Get returns the schedule with the given name
+func (s *ScheduleStore) Get(name string) (*WorkflowSchedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, err := scanSchedule(s.db.QueryRow(`SELECT `+scheduleColumns+` FROM workflow_schedules WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule %s: %w", name, err)
+	}
+	return schedule, nil
+}
+
+// List returns every schedule, sorted by name
+func (s *ScheduleStore) List() ([]*WorkflowSchedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT ` + scheduleColumns + ` FROM workflow_schedules ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*WorkflowSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// SetEnabled turns a schedule on or off
+func (s *ScheduleStore) SetEnabled(name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`UPDATE workflow_schedules SET enabled = ?, updated_at = ? WHERE name = ?`,
+		enabled, time.Now().Unix(), name)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule %s: %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	return nil
+}
+
+// Delete removes a schedule. Its run history is kept.
+func (s *ScheduleStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`DELETE FROM workflow_schedules WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule %s: %w", name, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	return nil
+}
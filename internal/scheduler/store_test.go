@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScheduleStore(t *testing.T) *ScheduleStore {
+	t.Helper()
+	store, err := NewScheduleStore(filepath.Join(t.TempDir(), "schedules.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestScheduleStore(t *testing.T) {
+	store := newTestScheduleStore(t)
+
+	deploy := &WorkflowSchedule{Name: "deploy-prod", Cron: "0 3 * * *", Stack: "prod", File: "/srv/deploy.sloth", Enabled: true}
+	require.NoError(t, store.Add(deploy))
+	require.NoError(t, store.Add(&WorkflowSchedule{Name: "backup", Cron: "@every 6h", Stack: "db", Sloth: "backup", Workflow: "full"}))
+
+	err := store.Add(&WorkflowSchedule{Name: "deploy-prod", Cron: "0 4 * * *", Stack: "prod", Sloth: "deploy"})
+	assert.ErrorIs(t, err, ErrScheduleExists)
+
+	got, err := store.Get("deploy-prod")
+	require.NoError(t, err)
+	assert.Equal(t, "0 3 * * *", got.Cron)
+	assert.Equal(t, "/srv/deploy.sloth", got.Source())
+	assert.True(t, got.Enabled)
+	assert.Equal(t, deploy.CreatedAt.Unix(), got.CreatedAt.Unix())
+
+	schedules, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	assert.Equal(t, "backup", schedules[0].Name)
+	assert.Equal(t, "backup (full)", schedules[0].Source())
+	assert.False(t, schedules[0].Enabled)
+
+	require.NoError(t, store.SetEnabled("backup", true))
+	got, err = store.Get("backup")
+	require.NoError(t, err)
+	assert.True(t, got.Enabled)
+
+	require.NoError(t, store.Delete("backup"))
+	_, err = store.Get("backup")
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	assert.ErrorIs(t, store.Delete("backup"), ErrScheduleNotFound)
+	assert.ErrorIs(t, store.SetEnabled("backup", false), ErrScheduleNotFound)
+}
+
+func TestScheduleStore_Validates(t *testing.T) {
+	store := newTestScheduleStore(t)
+
+	tests := []struct {
+		name     string
+		schedule WorkflowSchedule
+		err      string
+	}{
+		{"invalid cron", WorkflowSchedule{Name: "a", Cron: "0 3 * *", Stack: "prod", Sloth: "deploy"}, "invalid cron expression"},
+		{"no stack", WorkflowSchedule{Name: "a", Cron: "0 3 * * *", Sloth: "deploy"}, "needs a name and a stack"},
+		{"no workflow", WorkflowSchedule{Name: "a", Cron: "0 3 * * *", Stack: "prod"}, "either a saved sloth or a workflow file"},
+		{"two workflows", WorkflowSchedule{Name: "a", Cron: "0 3 * * *", Stack: "prod", Sloth: "deploy", File: "deploy.sloth"}, "either a saved sloth or a workflow file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.Add(&tt.schedule)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestWorkflowSchedule_Next(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)
+
+	s := &WorkflowSchedule{Cron: "CRON_TZ=UTC 0 3 * * *"}
+	assert.Equal(t, time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), s.Next(now).UTC())
+
+	s.Cron = "not a schedule"
+	assert.True(t, s.Next(now).IsZero())
+}
@@ -0,0 +1,168 @@
+// Package scm manages services on Windows through the Service Control
+// Manager: services are started, stopped and checked with service control
+// requests, and enabled by setting their start type.
+package scm
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// State is the state the SCM reports for a service
+type State string
+
+const (
+	Stopped  State = "STOPPED"
+	Starting State = "START_PENDING"
+	Stopping State = "STOP_PENDING"
+	Running  State = "RUNNING"
+	Paused   State = "PAUSED"
+)
+
+// StartType is when the SCM starts a service
+type StartType string
+
+const (
+	Automatic StartType = "AUTO_START"
+	Manual    StartType = "DEMAND_START"
+	Disabled  StartType = "DISABLED"
+)
+
+// Controller is the connection to the Service Control Manager
+type Controller interface {
+	State(service string) (State, error)
+	StartType(service string) (StartType, error)
+	SetStartType(service string, t StartType) error
+	Start(service string) error
+	Stop(service string) error
+	// ParamChange asks a service to reread its configuration
+	ParamChange(service string) error
+	List() ([]string, error)
+}
+
+// Manager manages the services of a host through the SCM
+type Manager struct {
+	SCM Controller
+	// Timeout bounds how long Stop and Restart wait for a service to stop
+	Timeout time.Duration
+	// Poll is how often the state of a stopping service is checked
+	Poll time.Duration
+}
+
+// New returns a manager using the controller
+func New(c Controller) *Manager {
+	return &Manager{SCM: c, Timeout: 30 * time.Second, Poll: 250 * time.Millisecond}
+}
+
+// Start starts a service
+func (m *Manager) Start(service string) error {
+	if err := m.SCM.Start(service); err != nil {
+		return fmt.Errorf("failed to start %s: %w", service, err)
+	}
+	return nil
+}
+
+// Stop stops a service and waits until the SCM reports it stopped
+func (m *Manager) Stop(service string) error {
+	state, err := m.SCM.State(service)
+	if err != nil {
+		return err
+	}
+	if state == Stopped {
+		return nil
+	}
+	if state != Stopping {
+		if err := m.SCM.Stop(service); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", service, err)
+		}
+	}
+
+	deadline := time.Now().Add(m.Timeout)
+	for {
+		state, err := m.SCM.State(service)
+		if err != nil {
+			return err
+		}
+		if state == Stopped {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s did not stop within %s, it is %s", service, m.Timeout, state)
+		}
+		time.Sleep(m.Poll)
+	}
+}
+
+// Restart stops a service, when it runs, and starts it
+func (m *Manager) Restart(service string) error {
+	if err := m.Stop(service); err != nil {
+		return err
+	}
+	return m.Start(service)
+}
+
+// Reload makes a service reread its configuration. Services that don't
+// accept the request have to be restarted.
+func (m *Manager) Reload(service string) error {
+	if err := m.SCM.ParamChange(service); err != nil {
+		return fmt.Errorf("%s can't reload its configuration, restart it instead: %w", service, err)
+	}
+	return nil
+}
+
+// Status reports whether a service is running, with its state as sc query
+// prints it
+func (m *Manager) Status(service string) (bool, string, error) {
+	state, err := m.SCM.State(service)
+	if err != nil {
+		return false, "", err
+	}
+	return state == Running, fmt.Sprintf("SERVICE_NAME: %s\nSTATE: %s", service, state), nil
+}
+
+// Enabled reports whether the SCM starts a service at boot
+func (m *Manager) Enabled(service string) (bool, error) {
+	t, err := m.SCM.StartType(service)
+	if err != nil {
+		return false, err
+	}
+	return t == Automatic, nil
+}
+
+// Enable makes the SCM start a service at boot, reporting whether its start
+// type changed
+func (m *Manager) Enable(service string) (bool, error) {
+	return m.setStartType(service, Automatic)
+}
+
+// Disable stops the SCM from starting a service at boot, reporting whether
+// its start type changed. Like systemctl disable, the service can still be
+// started by hand.
+func (m *Manager) Disable(service string) (bool, error) {
+	return m.setStartType(service, Manual)
+}
+
+func (m *Manager) setStartType(service string, t StartType) (bool, error) {
+	current, err := m.SCM.StartType(service)
+	if err != nil {
+		return false, err
+	}
+	if current == t {
+		return false, nil
+	}
+	if err := m.SCM.SetStartType(service, t); err != nil {
+		return false, fmt.Errorf("failed to set the start type of %s: %w", service, err)
+	}
+	return true, nil
+}
+
+// List returns the services the SCM knows, sorted by name
+func (m *Manager) List() ([]string, error) {
+	services, err := m.SCM.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	sort.Strings(services)
+	return services, nil
+}
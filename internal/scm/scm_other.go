@@ -0,0 +1,8 @@
+//go:build !windows
+
+package scm
+
+// Detect returns a manager when the host manages services with the SCM
+func Detect() (*Manager, bool) {
+	return nil, false
+}
@@ -0,0 +1,131 @@
+package scm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeSCM stops services after a few state checks, like services taking
+// time to shut down
+type fakeSCM struct {
+	states     map[string]State
+	startTypes map[string]StartType
+	stopChecks int
+	calls      []string
+}
+
+func (f *fakeSCM) State(service string) (State, error) {
+	state, ok := f.states[service]
+	if !ok {
+		return "", errors.New("The specified service does not exist as an installed service.")
+	}
+	if state == Stopping {
+		if f.stopChecks--; f.stopChecks <= 0 {
+			f.states[service] = Stopped
+		}
+	}
+	return state, nil
+}
+
+func (f *fakeSCM) StartType(service string) (StartType, error) {
+	return f.startTypes[service], nil
+}
+
+func (f *fakeSCM) SetStartType(service string, t StartType) error {
+	f.calls = append(f.calls, "config "+service+" "+string(t))
+	f.startTypes[service] = t
+	return nil
+}
+
+func (f *fakeSCM) Start(service string) error {
+	f.calls = append(f.calls, "start "+service)
+	f.states[service] = Running
+	return nil
+}
+
+func (f *fakeSCM) Stop(service string) error {
+	f.calls = append(f.calls, "stop "+service)
+	f.states[service] = Stopping
+	return nil
+}
+
+func (f *fakeSCM) ParamChange(service string) error {
+	return errors.New("The requested control is not valid for this service.")
+}
+
+func (f *fakeSCM) List() ([]string, error) {
+	var services []string
+	for name := range f.states {
+		services = append(services, name)
+	}
+	return services, nil
+}
+
+func newFake() (*Manager, *fakeSCM) {
+	f := &fakeSCM{
+		states:     map[string]State{"W3SVC": Running, "Spooler": Stopped},
+		startTypes: map[string]StartType{"W3SVC": Automatic, "Spooler": Manual},
+		stopChecks: 2,
+	}
+	m := New(f)
+	m.Poll = time.Millisecond
+	return m, f
+}
+
+func TestRestartWaitsForStop(t *testing.T) {
+	m, f := newFake()
+	if err := m.Restart("W3SVC"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"stop W3SVC", "start W3SVC"}; !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %v, want %v", f.calls, want)
+	}
+	running, output, err := m.Status("W3SVC")
+	if err != nil || !running || output != "SERVICE_NAME: W3SVC\nSTATE: RUNNING" {
+		t.Errorf("Status = %v, %q, %v", running, output, err)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	m, f := newFake()
+	f.stopChecks = 1000
+	m.Timeout = 10 * time.Millisecond
+	if err := m.Stop("W3SVC"); err == nil {
+		t.Error("Expected an error for a service that doesn't stop")
+	}
+	if err := m.Stop("Spooler"); err != nil {
+		t.Errorf("Stopping a stopped service: %v", err)
+	}
+}
+
+func TestEnableDisable(t *testing.T) {
+	m, f := newFake()
+	changed, err := m.Enable("W3SVC")
+	if err != nil || changed {
+		t.Errorf("Enable of an automatic service = %v, %v", changed, err)
+	}
+	changed, err = m.Enable("Spooler")
+	if err != nil || !changed {
+		t.Errorf("Enable = %v, %v", changed, err)
+	}
+	if enabled, _ := m.Enabled("Spooler"); !enabled {
+		t.Error("Spooler should be enabled")
+	}
+	changed, err = m.Disable("Spooler")
+	if err != nil || !changed || f.startTypes["Spooler"] != Manual {
+		t.Errorf("Disable = %v, %v, start type %s", changed, err, f.startTypes["Spooler"])
+	}
+}
+
+func TestReloadUnsupported(t *testing.T) {
+	m, _ := newFake()
+	if err := m.Reload("W3SVC"); err == nil {
+		t.Error("Expected an error for a service that can't reload")
+	}
+	services, _ := m.List()
+	if want := []string{"Spooler", "W3SVC"}; !reflect.DeepEqual(services, want) {
+		t.Errorf("List = %v, want %v", services, want)
+	}
+}
@@ -0,0 +1,122 @@
+//go:build windows
+
+package scm
+
+import (
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Detect returns a manager when the host manages services with the SCM
+func Detect() (*Manager, bool) {
+	return New(windowsSCM{}), true
+}
+
+// windowsSCM connects to the local SCM for each request, so a manager
+// doesn't hold a handle between tasks
+type windowsSCM struct{}
+
+func (windowsSCM) withService(name string, f func(s *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return f(s)
+}
+
+func (c windowsSCM) State(name string) (State, error) {
+	var state State
+	err := c.withService(name, func(s *mgr.Service) error {
+		status, err := s.Query()
+		if err != nil {
+			return err
+		}
+		switch status.State {
+		case svc.Stopped:
+			state = Stopped
+		case svc.StartPending, svc.ContinuePending:
+			state = Starting
+		case svc.StopPending:
+			state = Stopping
+		case svc.Paused, svc.PausePending:
+			state = Paused
+		default:
+			state = Running
+		}
+		return nil
+	})
+	return state, err
+}
+
+func (c windowsSCM) StartType(name string) (StartType, error) {
+	var t StartType
+	err := c.withService(name, func(s *mgr.Service) error {
+		config, err := s.Config()
+		if err != nil {
+			return err
+		}
+		switch config.StartType {
+		case mgr.StartAutomatic:
+			t = Automatic
+		case mgr.StartDisabled:
+			t = Disabled
+		default:
+			t = Manual
+		}
+		return nil
+	})
+	return t, err
+}
+
+func (c windowsSCM) SetStartType(name string, t StartType) error {
+	return c.withService(name, func(s *mgr.Service) error {
+		config, err := s.Config()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case Automatic:
+			config.StartType = mgr.StartAutomatic
+		case Disabled:
+			config.StartType = mgr.StartDisabled
+		default:
+			config.StartType = mgr.StartManual
+		}
+		return s.UpdateConfig(config)
+	})
+}
+
+func (c windowsSCM) Start(name string) error {
+	return c.withService(name, func(s *mgr.Service) error {
+		return s.Start()
+	})
+}
+
+func (c windowsSCM) Stop(name string) error {
+	return c.withService(name, func(s *mgr.Service) error {
+		_, err := s.Control(svc.Stop)
+		return err
+	})
+}
+
+func (c windowsSCM) ParamChange(name string) error {
+	return c.withService(name, func(s *mgr.Service) error {
+		_, err := s.Control(svc.ParamChange)
+		return err
+	})
+}
+
+func (windowsSCM) List() ([]string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.ListServices()
+}
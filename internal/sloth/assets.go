@@ -0,0 +1,155 @@
+package sloth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxAssetsSize is how much embedded asset data a sloth file may carry:
+// assets are meant for small configs and scripts, not artifacts
+const MaxAssetsSize = 1 << 20
+
+// Asset is a file embedded in the front-matter, extracted into the
+// workspace of every task group and found with asset("name"). It is
+// written either as the text of the file or as a mapping:
+//
+//	assets:
+//	  nginx.conf: |
+//	    server { listen 80; }
+//	  bin/install.sh:
+//	    mode: "0755"
+//	    content: |
+//	      #!/bin/sh
+//	  logo.png:
+//	    encoding: base64
+//	    content: iVBORw0KGgo...
+type Asset struct {
+	Content string `yaml:"content" json:"content"`
+	// Encoding is empty for text or base64
+	Encoding string `yaml:"encoding" json:"encoding,omitempty"`
+	// Mode is the octal file mode, 0644 by default
+	Mode string `yaml:"mode" json:"mode,omitempty"`
+}
+
+// Assets are the embedded files of a sloth file, by name
+type Assets map[string]*Asset
+
+// UnmarshalYAML accepts the text of the file as well as the mapping form
+func (a *Asset) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		a.Content = value.Value
+		return nil
+	}
+	type plain Asset
+	return value.Decode((*plain)(a))
+}
+
+// Data returns the content of the asset, decoded
+func (a *Asset) Data() ([]byte, error) {
+	switch a.Encoding {
+	case "":
+		return []byte(a.Content), nil
+	case "base64":
+		// Long base64 values are usually folded over several lines
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(a.Content), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64: %w", err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", a.Encoding)
+}
+
+// FileMode returns the mode the asset is extracted with
+func (a *Asset) FileMode() (os.FileMode, error) {
+	if a.Mode == "" {
+		return 0644, nil
+	}
+	mode, err := strconv.ParseUint(strings.TrimPrefix(a.Mode, "0o"), 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q", a.Mode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// ValidAssetName reports why name can't name an asset: names are relative
+// slash-separated paths that stay inside the workspace
+func ValidAssetName(name string) error {
+	if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) ||
+		path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("invalid asset name %q: use a relative path such as \"conf/app.yaml\"", name)
+	}
+	return nil
+}
+
+// Names returns the asset names, sorted
+func (as Assets) Names() []string {
+	names := make([]string, 0, len(as))
+	for name := range as {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (as Assets) validate() error {
+	total := 0
+	for _, name := range as.Names() {
+		if err := ValidAssetName(name); err != nil {
+			return err
+		}
+		a := as[name]
+		if a == nil {
+			as[name] = &Asset{}
+			continue
+		}
+		data, err := a.Data()
+		if err != nil {
+			return fmt.Errorf("asset %q: %w", name, err)
+		}
+		if _, err := a.FileMode(); err != nil {
+			return fmt.Errorf("asset %q: %w", name, err)
+		}
+		total += len(data)
+	}
+	if total > MaxAssetsSize {
+		return fmt.Errorf("assets take %d bytes, more than the %d allowed", total, MaxAssetsSize)
+	}
+	return nil
+}
+
+// Extract writes the assets under dir, replacing existing files
+func (as Assets) Extract(dir string) error {
+	for _, name := range as.Names() {
+		a := as[name]
+		data, err := a.Data()
+		if err != nil {
+			return fmt.Errorf("asset %q: %w", name, err)
+		}
+		mode, err := a.FileMode()
+		if err != nil {
+			return fmt.Errorf("asset %q: %w", name, err)
+		}
+
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to extract asset %q: %w", name, err)
+		}
+		if err := os.WriteFile(dest, data, mode); err != nil {
+			return fmt.Errorf("failed to extract asset %q: %w", name, err)
+		}
+		// WriteFile keeps the mode of a file that already exists
+		if err := os.Chmod(dest, mode); err != nil {
+			return fmt.Errorf("failed to extract asset %q: %w", name, err)
+		}
+	}
+	return nil
+}
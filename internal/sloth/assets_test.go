@@ -0,0 +1,85 @@
+package sloth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const assetsFile = `---
+name: web
+assets:
+  nginx.conf: |
+    server { listen 80; }
+  bin/install.sh:
+    mode: "0755"
+    content: |
+      #!/bin/sh
+      echo installed
+  logo.bin:
+    encoding: base64
+    content: |
+      aGVs
+      bG8=
+---
+`
+
+func TestAssets_Extract(t *testing.T) {
+	meta, _, err := ParseFrontMatter([]byte(assetsFile))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if names := meta.Assets.Names(); strings.Join(names, ",") != "bin/install.sh,logo.bin,nginx.conf" {
+		t.Fatalf("Unexpected assets: %v", names)
+	}
+
+	dir := t.TempDir()
+	// Existing files are replaced, mode included
+	os.MkdirAll(filepath.Join(dir, "bin"), 0755)
+	os.WriteFile(filepath.Join(dir, "bin", "install.sh"), []byte("old"), 0600)
+
+	if err := meta.Assets.Extract(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		content string
+		mode    os.FileMode
+	}{
+		"nginx.conf":     {"server { listen 80; }\n", 0644},
+		"bin/install.sh": {"#!/bin/sh\necho installed\n", 0755},
+		"logo.bin":       {"hello", 0644},
+	}
+	for name, want := range tests {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != want.content {
+			t.Errorf("%s: got %q, %v", name, data, err)
+			continue
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != want.mode {
+			t.Errorf("%s: mode %v, want %v", name, info.Mode().Perm(), want.mode)
+		}
+	}
+}
+
+func TestValidAssetName(t *testing.T) {
+	for _, name := range []string{"app.conf", "conf/app.yaml", ".env"} {
+		if err := ValidAssetName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "/etc/passwd", "../x", "..", "a/../../x", "a//b", "./a", `a\b`} {
+		if err := ValidAssetName(name); err == nil {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestAssets_TooLarge(t *testing.T) {
+	assets := Assets{"big": {Content: strings.Repeat("x", MaxAssetsSize+1)}}
+	if err := assets.validate(); err == nil {
+		t.Error("Expected assets over the limit to be rejected")
+	}
+}
@@ -0,0 +1,392 @@
+package sloth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Sloth file formats
+const (
+	// FormatV1 is a plain Lua file
+	FormatV1 = 1
+	// FormatV2 starts with a YAML front-matter block between "---" lines
+	FormatV2 = 2
+)
+
+const frontMatterDelimiter = "---"
+
+// Metadata is the front-matter of a v2 sloth file:
+//
+//	---
+//	name: deploy
+//	description: Deploy the web tier
+//	version: 1.2.0
+//	min_runner_version: 1.4.0
+//	modules: [pkg, systemd]
+//	windows: ["mon-fri 22:00-06:00 Europe/Lisbon"]
+//	schedule: "0 2 * * *"
+//	stack: production
+//	params:
+//	  env:
+//	    type: string
+//	    required: true
+//	    enum: [staging, production]
+//	  replicas:
+//	    type: integer
+//	    default: 2
+//	assets:
+//	  nginx.conf: |
+//	    server { listen 80; }
+//	template: true
+//	vars:
+//	  regions: [us-east-1, eu-west-1]
+//	---
+type Metadata struct {
+	Format           int                   `yaml:"-" json:"format"`
+	Name             string                `yaml:"name" json:"name,omitempty"`
+	Description      string                `yaml:"description" json:"description,omitempty"`
+	Version          string                `yaml:"version" json:"version,omitempty"`
+	Modules          []string              `yaml:"modules" json:"modules,omitempty"`
+	MinRunnerVersion string                `yaml:"min_runner_version" json:"min_runner_version,omitempty"`
+	Windows          []string              `yaml:"windows" json:"windows,omitempty"`
+	Schedule         string                `yaml:"schedule" json:"schedule,omitempty"`
+	Stack            string                `yaml:"stack" json:"stack,omitempty"`
+	Params           map[string]*ParamSpec `yaml:"params" json:"params,omitempty"`
+	Assets           Assets                `yaml:"assets" json:"assets,omitempty"`
+	// Template marks a body with template expressions, see Render
+	Template bool                   `yaml:"template" json:"template,omitempty"`
+	Vars     map[string]interface{} `yaml:"vars" json:"vars,omitempty"`
+}
+
+// ParamSpec describes one workflow parameter, passed through the values file
+type ParamSpec struct {
+	// Type is string, number, integer, boolean, list or map; empty accepts anything
+	Type        string        `yaml:"type" json:"type,omitempty"`
+	Description string        `yaml:"description" json:"description,omitempty"`
+	Required    bool          `yaml:"required" json:"required,omitempty"`
+	Default     interface{}   `yaml:"default" json:"default,omitempty"`
+	Enum        []interface{} `yaml:"enum" json:"enum,omitempty"`
+}
+
+var paramTypes = map[string]bool{
+	"": true, "string": true, "number": true, "integer": true,
+	"boolean": true, "list": true, "map": true,
+}
+
+// ParseFrontMatter splits a sloth file into its metadata and Lua body.
+// Files without front-matter are returned as FormatV1 with the body
+// unchanged. The front-matter lines are blanked rather than removed so
+// Lua error line numbers still match the file.
+func ParseFrontMatter(content []byte) (*Metadata, []byte, error) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) == 0 || lineText(lines[0]) != frontMatterDelimiter {
+		return &Metadata{Format: FormatV1}, content, nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if lineText(lines[i]) == frontMatterDelimiter {
+			end = i
+			break
+		}
+	}
+	// A bare "---" is also a Lua comment: without a closing line, or with
+	// only comments in between, this is plain Lua and not front-matter
+	if end < 0 || onlyLuaComments(lines[1:end]) {
+		return &Metadata{Format: FormatV1}, content, nil
+	}
+
+	meta := &Metadata{}
+	if err := yaml.Unmarshal(bytes.Join(lines[1:end], nil), meta); err != nil {
+		return nil, nil, fmt.Errorf("invalid front-matter: %w", err)
+	}
+	meta.Format = FormatV2
+	if err := meta.validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid front-matter: %w", err)
+	}
+
+	body := make([]byte, 0, len(content))
+	for i := 0; i <= end; i++ {
+		body = append(body, '\n')
+	}
+	body = append(body, bytes.Join(lines[end+1:], nil)...)
+	return meta, body, nil
+}
+
+// ReadFile reads a sloth file and parses its front-matter
+func ReadFile(path string) (*Metadata, []byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, body, err := ParseFrontMatter(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return meta, body, nil
+}
+
+// StripFrontMatter returns the Lua body of a sloth file. Content whose
+// front-matter can't be parsed is returned unchanged.
+func StripFrontMatter(content []byte) []byte {
+	_, body, err := ParseFrontMatter(content)
+	if err != nil {
+		return content
+	}
+	return body
+}
+
+func (m *Metadata) validate() error {
+	if _, err := ParseWindows(m.Windows); err != nil {
+		return err
+	}
+	if m.Schedule != "" {
+		if _, err := cron.ParseStandard(m.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule %q: %w", m.Schedule, err)
+		}
+	}
+	if err := m.Assets.validate(); err != nil {
+		return err
+	}
+	for name, p := range m.Params {
+		if p == nil {
+			m.Params[name] = &ParamSpec{}
+			continue
+		}
+		if !paramTypes[p.Type] {
+			return fmt.Errorf("param %q has unknown type %q", name, p.Type)
+		}
+		if p.Default != nil {
+			if err := p.check(name, p.Default); err != nil {
+				return fmt.Errorf("default of %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckCompatibility reports why the file can't run on this runner: a
+// runner older than min_runner_version or required modules that are not
+// available. Development builds skip the version check.
+func (m *Metadata) CheckCompatibility(runnerVersion string, moduleAvailable func(string) bool) error {
+	var problems []string
+
+	if m.MinRunnerVersion != "" {
+		if cmp, ok := CompareVersions(runnerVersion, m.MinRunnerVersion); ok && cmp < 0 {
+			problems = append(problems, fmt.Sprintf("requires sloth-runner >= %s, this is %s", m.MinRunnerVersion, runnerVersion))
+		}
+	}
+
+	if moduleAvailable != nil {
+		var missing []string
+		for _, name := range m.Modules {
+			if !moduleAvailable(name) {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("requires modules not available in this runner: %s", strings.Join(missing, ", ")))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s is not compatible with this runner:\n  - %s", m.displayName(), strings.Join(problems, "\n  - "))
+}
+
+// MaintenanceWindows returns the windows the workflow may run in, none
+// meaning anytime
+func (m *Metadata) MaintenanceWindows() Windows {
+	// Windows were checked when the front-matter was parsed
+	windows, _ := ParseWindows(m.Windows)
+	return windows
+}
+
+// ApplyParams validates values against the params schema and returns them
+// with defaults filled in. Values without a matching param are kept.
+func (m *Metadata) ApplyParams(values map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(values)+len(m.Params))
+	for k, v := range values {
+		result[k] = v
+	}
+
+	names := make([]string, 0, len(m.Params))
+	for name := range m.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		p := m.Params[name]
+		v, ok := result[name]
+		if !ok || v == nil {
+			if p.Default != nil {
+				result[name] = p.Default
+			} else if p.Required {
+				problems = append(problems, fmt.Sprintf("param %q is required", name))
+			}
+			continue
+		}
+		if err := p.check(name, v); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid params for %s:\n  - %s", m.displayName(), strings.Join(problems, "\n  - "))
+	}
+	return result, nil
+}
+
+func (m *Metadata) displayName() string {
+	if m.Name != "" {
+		return fmt.Sprintf("workflow %q", m.Name)
+	}
+	return "workflow"
+}
+
+func (p *ParamSpec) check(name string, v interface{}) error {
+	if !matchesType(p.Type, v) {
+		return fmt.Errorf("param %q must be %s, got %s", name, typeArticle(p.Type), valueTypeName(v))
+	}
+	if len(p.Enum) == 0 {
+		return nil
+	}
+	for _, allowed := range p.Enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(v) {
+			return nil
+		}
+	}
+	return fmt.Errorf("param %q must be one of %v, got %v", name, p.Enum, v)
+}
+
+func matchesType(typ string, v interface{}) bool {
+	switch typ {
+	case "":
+		return true
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "integer":
+		switch n := v.(type) {
+		case int, int64, uint64:
+			return true
+		case float64:
+			return n == float64(int64(n))
+		}
+		return false
+	case "number":
+		switch v.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	case "list":
+		_, ok := v.([]interface{})
+		return ok
+	case "map":
+		switch v.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+func valueTypeName(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		if n == float64(int64(n)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func typeArticle(typ string) string {
+	if typ == "integer" {
+		return "an integer"
+	}
+	return "a " + typ
+}
+
+// CompareVersions compares two dotted versions such as "1.4.0" or
+// "v1.4.0-rc1", ignoring pre-release suffixes. ok is false when either
+// version can't be parsed, e.g. "dev".
+func CompareVersions(a, b string) (int, bool) {
+	pa, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	pb, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+	for i := 0; i < 3; i++ {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if v == "" || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+func lineText(line []byte) string {
+	return strings.TrimRight(string(line), " \t\r\n")
+}
+
+func onlyLuaComments(lines [][]byte) bool {
+	for _, l := range lines {
+		text := strings.TrimSpace(string(l))
+		if text != "" && !strings.HasPrefix(text, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package sloth
+
+import (
+	"strings"
+	"testing"
+)
+
+const v2File = `---
+name: deploy
+description: Deploy the web tier
+version: 1.2.0
+min_runner_version: 1.4.0
+modules: [pkg, systemd]
+params:
+  env:
+    type: string
+    required: true
+    enum: [staging, production]
+  replicas:
+    type: integer
+    default: 2
+---
+local x = 1
+`
+
+func TestParseFrontMatter(t *testing.T) {
+	meta, body, err := ParseFrontMatter([]byte(v2File))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if meta.Format != FormatV2 || meta.Name != "deploy" || meta.Version != "1.2.0" {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+	if len(meta.Modules) != 2 || meta.MinRunnerVersion != "1.4.0" {
+		t.Errorf("Unexpected requirements: %+v", meta)
+	}
+	if p := meta.Params["env"]; p == nil || !p.Required || len(p.Enum) != 2 {
+		t.Errorf("Unexpected env param: %+v", p)
+	}
+
+	// Front-matter lines are blanked so Lua line numbers still match
+	lines := strings.Split(string(body), "\n")
+	if len(lines) != strings.Count(v2File, "\n")+1 || lines[15] != "local x = 1" {
+		t.Errorf("Unexpected body: %q", body)
+	}
+}
+
+func TestParseFrontMatter_PlainLua(t *testing.T) {
+	tests := []string{
+		"local x = 1\n",
+		"---\n-- a comment block\n---\nlocal x = 1\n",
+		"---\nlocal x = 1\n",
+		"",
+	}
+
+	for _, content := range tests {
+		meta, body, err := ParseFrontMatter([]byte(content))
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", content, err)
+			continue
+		}
+		if meta.Format != FormatV1 || string(body) != content {
+			t.Errorf("Expected %q to be plain Lua, got %+v", content, meta)
+		}
+	}
+}
+
+func TestParseFrontMatter_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"yaml":     "---\nname: [unclosed\n---\n",
+		"type":     "---\nparams:\n  env:\n    type: text\n---\n",
+		"default":  "---\nparams:\n  n:\n    type: integer\n    default: two\n---\n",
+		"schedule": "---\nschedule: every day\n---\n",
+		"asset":    "---\nassets:\n  ../etc/passwd: x\n---\n",
+		"base64":   "---\nassets:\n  a.bin:\n    encoding: base64\n    content: '%%%'\n---\n",
+	}
+
+	for name, content := range tests {
+		if _, _, err := ParseFrontMatter([]byte(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMetadata_CheckCompatibility(t *testing.T) {
+	meta, _, err := ParseFrontMatter([]byte(v2File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	available := func(name string) bool { return name == "pkg" }
+
+	err = meta.CheckCompatibility("1.3.9", available)
+	if err == nil {
+		t.Fatal("Expected compatibility error")
+	}
+	for _, want := range []string{">= 1.4.0", "systemd"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q: %v", want, err)
+		}
+	}
+
+	all := func(string) bool { return true }
+	for _, v := range []string{"1.4.0", "v2.0.0", "1.4.1-rc1", "dev"} {
+		if err := meta.CheckCompatibility(v, all); err != nil {
+			t.Errorf("Expected runner %s to be compatible: %v", v, err)
+		}
+	}
+}
+
+func TestMetadata_ApplyParams(t *testing.T) {
+	meta, _, err := ParseFrontMatter([]byte(v2File))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := meta.ApplyParams(map[string]interface{}{"env": "staging", "extra": true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if values["replicas"] != 2 || values["extra"] != true {
+		t.Errorf("Expected default and extra values, got %v", values)
+	}
+
+	tests := []struct {
+		values map[string]interface{}
+		want   string
+	}{
+		{nil, `param "env" is required`},
+		{map[string]interface{}{"env": "qa"}, "must be one of"},
+		{map[string]interface{}{"env": "staging", "replicas": 1.5}, "must be an integer, got number"},
+	}
+	for _, tt := range tests {
+		_, err := meta.ApplyParams(tt.values)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected error containing %q for %v, got %v", tt.want, tt.values, err)
+		}
+	}
+}
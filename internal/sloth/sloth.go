@@ -9,6 +9,7 @@ type Sloth struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
+	Version     string    `json:"version,omitempty"` // From the file's front-matter
 	FilePath    string    `json:"file_path"`
 	Content     string    `json:"content"`
 	IsActive    bool      `json:"is_active"`
@@ -24,6 +25,7 @@ type Sloth struct {
 type SlothListItem struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
+	Version     string    `json:"version,omitempty"`
 	IsActive    bool      `json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
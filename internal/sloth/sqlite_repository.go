@@ -8,7 +8,7 @@ import (
 	"path/filepath"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // SQLiteRepository implements the Repository interface using SQLite
@@ -29,7 +29,7 @@ func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
 		return nil, fmt.Errorf("failed to create sloth directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_foreign_keys=on")
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{ForeignKeys: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -53,6 +53,7 @@ func (r *SQLiteRepository) initSchema() error {
 		id TEXT PRIMARY KEY,
 		name TEXT NOT NULL UNIQUE,
 		description TEXT,
+		version TEXT DEFAULT '',
 		file_path TEXT NOT NULL,
 		content TEXT NOT NULL,
 		is_active BOOLEAN NOT NULL DEFAULT 1,
@@ -71,8 +72,15 @@ func (r *SQLiteRepository) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_sloths_hash ON sloths(file_hash);
 	`
 
-	_, err := r.db.Exec(schema)
-	return err
+	if _, err := r.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Migrations for databases created before these columns existed;
+	// errors mean the column is already there
+	r.db.Exec(`ALTER TABLE sloths ADD COLUMN version TEXT DEFAULT ''`)
+
+	return nil
 }
 
 // Create adds a new sloth to the repository
@@ -85,15 +93,16 @@ func (r *SQLiteRepository) Create(ctx context.Context, sloth *Sloth) error {
 
 	query := `
 		INSERT INTO sloths (
-			id, name, description, file_path, content, is_active,
+			id, name, description, version, file_path, content, is_active,
 			created_at, updated_at, tags, file_hash
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err = r.db.ExecContext(ctx, query,
 		sloth.ID,
 		sloth.Name,
 		sloth.Description,
+		sloth.Version,
 		sloth.FilePath,
 		sloth.Content,
 		sloth.IsActive,
@@ -113,7 +122,7 @@ func (r *SQLiteRepository) Create(ctx context.Context, sloth *Sloth) error {
 // GetByName retrieves a sloth by its name
 func (r *SQLiteRepository) GetByName(ctx context.Context, name string) (*Sloth, error) {
 	query := `
-		SELECT id, name, description, file_path, content, is_active,
+		SELECT id, name, description, COALESCE(version, ''), file_path, content, is_active,
 			   created_at, updated_at, last_used_at, usage_count, tags, file_hash
 		FROM sloths
 		WHERE name = ?
@@ -126,6 +135,7 @@ func (r *SQLiteRepository) GetByName(ctx context.Context, name string) (*Sloth,
 		&sloth.ID,
 		&sloth.Name,
 		&sloth.Description,
+		&sloth.Version,
 		&sloth.FilePath,
 		&sloth.Content,
 		&sloth.IsActive,
@@ -154,7 +164,7 @@ func (r *SQLiteRepository) GetByName(ctx context.Context, name string) (*Sloth,
 // GetByID retrieves a sloth by its ID
 func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*Sloth, error) {
 	query := `
-		SELECT id, name, description, file_path, content, is_active,
+		SELECT id, name, description, COALESCE(version, ''), file_path, content, is_active,
 			   created_at, updated_at, last_used_at, usage_count, tags, file_hash
 		FROM sloths
 		WHERE id = ?
@@ -167,6 +177,7 @@ func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*Sloth, erro
 		&sloth.ID,
 		&sloth.Name,
 		&sloth.Description,
+		&sloth.Version,
 		&sloth.FilePath,
 		&sloth.Content,
 		&sloth.IsActive,
@@ -195,7 +206,7 @@ func (r *SQLiteRepository) GetByID(ctx context.Context, id string) (*Sloth, erro
 // List returns all sloths, optionally filtered by active status
 func (r *SQLiteRepository) List(ctx context.Context, activeOnly bool) ([]*SlothListItem, error) {
 	query := `
-		SELECT name, description, is_active, created_at, last_used_at, usage_count
+		SELECT name, description, COALESCE(version, ''), is_active, created_at, last_used_at, usage_count
 		FROM sloths
 	`
 
@@ -220,6 +231,7 @@ func (r *SQLiteRepository) List(ctx context.Context, activeOnly bool) ([]*SlothL
 		err := rows.Scan(
 			&item.Name,
 			&item.Description,
+			&item.Version,
 			&item.IsActive,
 			&item.CreatedAt,
 			&lastUsedAt,
@@ -244,13 +256,14 @@ func (r *SQLiteRepository) List(ctx context.Context, activeOnly bool) ([]*SlothL
 func (r *SQLiteRepository) Update(ctx context.Context, sloth *Sloth) error {
 	query := `
 		UPDATE sloths
-		SET description = ?, file_path = ?, content = ?, is_active = ?,
+		SET description = ?, version = ?, file_path = ?, content = ?, is_active = ?,
 			updated_at = ?, tags = ?, file_hash = ?
 		WHERE name = ?
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
 		sloth.Description,
+		sloth.Version,
 		sloth.FilePath,
 		sloth.Content,
 		sloth.IsActive,
@@ -349,5 +362,5 @@ func (r *SQLiteRepository) IncrementUsage(ctx context.Context, name string) erro
 
 // Close closes the repository connection
 func (r *SQLiteRepository) Close() error {
-	return r.db.Close()
+	return sqlitedb.Close(r.db)
 }
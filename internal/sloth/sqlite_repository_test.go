@@ -20,6 +20,7 @@ func TestSQLiteRepository_Create(t *testing.T) {
 		ID:          "test-id-1",
 		Name:        "test-sloth",
 		Description: "Test description",
+		Version:     "1.2.0",
 		FilePath:    "/path/to/test.sloth",
 		Content:     "test content",
 		IsActive:    true,
@@ -42,6 +43,9 @@ func TestSQLiteRepository_Create(t *testing.T) {
 	if retrieved.Name != sloth.Name {
 		t.Errorf("Expected name %s, got %s", sloth.Name, retrieved.Name)
 	}
+	if retrieved.Version != "1.2.0" {
+		t.Errorf("Expected version 1.2.0, got %s", retrieved.Version)
+	}
 }
 
 // TestSQLiteRepository_CreateDuplicate tests creating a duplicate sloth
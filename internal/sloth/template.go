@@ -0,0 +1,207 @@
+package sloth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Render expands the template expressions of the body of a file with
+// `template: true` in its front-matter, before it is parsed as Lua. Other
+// files are returned unchanged. name, usually the file path, prefixes
+// errors.
+//
+// Templates use Go's text/template syntax with these data:
+//
+//	.vars   front-matter vars, overridden by the run's values
+//	.facts  hostname, os, arch and cpus of the host rendering the file
+//
+// Lua table constructors starting with "{{" must be written "{ {", or
+// the braces as {{"{{"}}. Keys that don't exist are errors rather than
+// empty strings.
+func (m *Metadata) Render(name string, body []byte, values map[string]interface{}) ([]byte, error) {
+	if !m.Template {
+		return body, nil
+	}
+
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(templateFuncs).
+		Parse(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	vars := make(map[string]interface{}, len(m.Vars)+len(values))
+	for k, v := range m.Vars {
+		vars[k] = v
+	}
+	for k, v := range values {
+		vars[k] = v
+	}
+	data := map[string]interface{}{
+		"vars":  vars,
+		"facts": templateFacts(),
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// RenderFile reads a sloth file and returns its metadata and Lua body,
+// rendered with values when it is a template
+func RenderFile(path string, values map[string]interface{}) (*Metadata, []byte, error) {
+	meta, body, err := ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body, err = meta.Render(path, body, values); err != nil {
+		return nil, nil, err
+	}
+	return meta, body, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"lua":     luaLiteral,
+	"join":    templateJoin,
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"replace": strings.ReplaceAll,
+	"seq":     templateSeq,
+}
+
+func templateFacts() map[string]interface{} {
+	hostname, _ := os.Hostname()
+	return map[string]interface{}{
+		"hostname": hostname,
+		"os":       runtime.GOOS,
+		"arch":     runtime.GOARCH,
+		"cpus":     runtime.NumCPU(),
+	}
+}
+
+// luaLiteral writes a value as a Lua literal: strings are quoted, lists
+// and maps become table constructors, so {{ lua .vars.regions }} gives
+// {"us-east-1", "eu-west-1"}
+func luaLiteral(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "nil", nil
+	case string:
+		return luaQuote(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case int:
+		return strconv.Itoa(val), nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case uint64:
+		return strconv.FormatUint(val, 10), nil
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			s, err := luaLiteral(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return "{" + strings.Join(items, ", ") + "}", nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = item
+		}
+		return luaLiteral(m)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fields := make([]string, 0, len(keys))
+		for _, k := range keys {
+			s, err := luaLiteral(val[k])
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, fmt.Sprintf("[%s] = %s", luaQuote(k), s))
+		}
+		return "{" + strings.Join(fields, ", ") + "}", nil
+	}
+	return "", fmt.Errorf("can't write %T as Lua", v)
+}
+
+// luaQuote quotes a string with the escapes Lua 5.1 understands
+func luaQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\%03d`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// templateJoin joins a list with sep: {{ join .vars.hosts "," }}
+func templateJoin(list interface{}, sep string) (string, error) {
+	switch val := list.(type) {
+	case []string:
+		return strings.Join(val, sep), nil
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, sep), nil
+	}
+	return "", fmt.Errorf("join expects a list, got %T", list)
+}
+
+// templateSeq returns 1..n, for ranges such as {{ range seq 3 }}
+func templateSeq(n interface{}) ([]int, error) {
+	var count int
+	switch val := n.(type) {
+	case int:
+		count = val
+	case int64:
+		count = int(val)
+	case uint64:
+		count = int(val)
+	case float64:
+		count = int(val)
+	default:
+		return nil, fmt.Errorf("seq expects a number, got %T", n)
+	}
+	seq := make([]int, 0, count)
+	for i := 1; i <= count; i++ {
+		seq = append(seq, i)
+	}
+	return seq, nil
+}
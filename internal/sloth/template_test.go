@@ -0,0 +1,101 @@
+package sloth
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/yuin/gopher-lua/parse"
+)
+
+const templatedFile = `---
+name: matrix
+template: true
+vars:
+  regions: [us-east-1, eu-west-1]
+  replicas: 2
+---
+local tasks = { {{"{{"}}name = "noop"}} }
+{{- range .vars.regions }}
+table.insert(tasks, task("deploy-{{ . }}"):command({{ lua (printf "deploy %s" .) }}):build())
+{{- end }}
+local replicas = {{ .vars.replicas }}
+local regions = {{ lua .vars.regions }}
+local os_name = {{ lua .facts.os }}
+`
+
+func TestRender(t *testing.T) {
+	meta, body, err := ParseFrontMatter([]byte(templatedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := meta.Render("matrix.sloth", body, map[string]interface{}{"replicas": 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lua := string(out)
+
+	for _, want := range []string{
+		`local tasks = { {{name = "noop"}} }`,
+		`task("deploy-us-east-1"):command("deploy us-east-1")`,
+		`task("deploy-eu-west-1"):command("deploy eu-west-1")`,
+		"local replicas = 5",
+		`local regions = {"us-east-1", "eu-west-1"}`,
+		`local os_name = "` + runtime.GOOS + `"`,
+	} {
+		if !strings.Contains(lua, want) {
+			t.Errorf("Expected %q in rendered body:\n%s", want, lua)
+		}
+	}
+	if _, err := parse.Parse(strings.NewReader(lua), "matrix.sloth"); err != nil {
+		t.Errorf("Expected valid Lua, got %v", err)
+	}
+}
+
+func TestRender_NotTemplate(t *testing.T) {
+	meta, body, err := ParseFrontMatter([]byte("---\nname: plain\n---\nlocal t = {{name = 'x'}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := meta.Render("plain.sloth", body, nil)
+	if err != nil || string(out) != string(body) {
+		t.Errorf("Expected the body unchanged, got %q, %v", out, err)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	meta := &Metadata{Template: true}
+
+	_, err := meta.Render("x.sloth", []byte("\nlocal r = {{ .vars.region }}\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), `x.sloth:2`) || !strings.Contains(err.Error(), "region") {
+		t.Errorf("Expected a missing var error at line 2, got %v", err)
+	}
+
+	// Unescaped Lua table constructors are template actions
+	_, err = meta.Render("x.sloth", []byte("local t = {{name = 'x'}}\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid template") {
+		t.Errorf("Expected a template syntax error, got %v", err)
+	}
+}
+
+func TestLuaLiteral(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{"say \"hi\"\n", `"say \"hi\"\n"`},
+		{"tab\there\x01", `"tab\there\001"`},
+		{3.5, "3.5"},
+		{float64(4), "4"},
+		{true, "true"},
+		{nil, "nil"},
+		{map[string]interface{}{"b": 1, "a": []interface{}{"x"}}, `{["a"] = {"x"}, ["b"] = 1}`},
+	}
+	for _, tt := range tests {
+		got, err := luaLiteral(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("luaLiteral(%v) = %s, %v; want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package sloth
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is a recurring maintenance window in which runs are allowed,
+// written as "[days] HH:MM-HH:MM [timezone]":
+//
+//	mon-fri 22:00-06:00 Europe/Lisbon
+//	weekends 00:00-24:00 UTC
+//	sat,sun 02:00-04:00
+//
+// Days are names (mon, tue, ...), ranges such as mon-fri or fri-mon, lists
+// of both, or one of daily (the default), weekdays and weekends. A window
+// ending at or before its start runs past midnight and belongs to the day
+// it starts on, so "fri 22:00-06:00" ends on Saturday morning. Without a
+// timezone the runner's local time is used.
+type Window struct {
+	spec     string
+	days     [7]bool
+	start    int // minutes since midnight
+	end      int // minutes since midnight, up to 24:00
+	location *time.Location
+}
+
+var windowTimesPattern = regexp.MustCompile(`^(\d{1,2}):(\d{2})-(\d{1,2}):(\d{2})$`)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseWindow parses a maintenance window spec
+func ParseWindow(spec string) (*Window, error) {
+	fields := strings.Fields(spec)
+	timesAt := -1
+	for i, f := range fields {
+		if windowTimesPattern.MatchString(f) {
+			timesAt = i
+			break
+		}
+	}
+	if timesAt < 0 || timesAt > 1 || len(fields)-timesAt > 2 {
+		return nil, fmt.Errorf("invalid window %q: expected \"[days] HH:MM-HH:MM [timezone]\"", spec)
+	}
+
+	w := &Window{spec: strings.Join(fields, " "), location: time.Local}
+
+	days := "daily"
+	if timesAt == 1 {
+		days = fields[0]
+	}
+	if err := w.parseDays(strings.ToLower(days)); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+
+	m := windowTimesPattern.FindStringSubmatch(fields[timesAt])
+	var err error
+	if w.start, err = windowMinutes(m[1], m[2]); err != nil || w.start == 24*60 {
+		return nil, fmt.Errorf("invalid window %q: invalid start time", spec)
+	}
+	if w.end, err = windowMinutes(m[3], m[4]); err != nil {
+		return nil, fmt.Errorf("invalid window %q: invalid end time", spec)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("invalid window %q: start and end are the same time", spec)
+	}
+
+	if timesAt+1 < len(fields) {
+		loc, err := time.LoadLocation(fields[timesAt+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: unknown timezone %q", spec, fields[timesAt+1])
+		}
+		w.location = loc
+	}
+	return w, nil
+}
+
+func (w *Window) parseDays(days string) error {
+	switch days {
+	case "daily":
+		days = "sun-sat"
+	case "weekdays":
+		days = "mon-fri"
+	case "weekends":
+		days = "sat,sun"
+	}
+
+	for _, part := range strings.Split(days, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdayNames[from]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdayNames[to]; !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func windowMinutes(hours, minutes string) (int, error) {
+	h, _ := strconv.Atoi(hours)
+	m, _ := strconv.Atoi(minutes)
+	if m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %s:%s", hours, minutes)
+	}
+	return h*60 + m, nil
+}
+
+// String returns the window spec
+func (w *Window) String() string {
+	return w.spec
+}
+
+// Contains reports whether t falls inside the window
+func (w *Window) Contains(t time.Time) bool {
+	local := t.In(w.location)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	return (w.days[day] && minute >= w.start) || (w.days[(day+6)%7] && minute < w.end)
+}
+
+// NextOpen returns when the window next opens after t, or t if it is open
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	local := t.In(w.location)
+	for i := 0; i <= 7; i++ {
+		open := time.Date(local.Year(), local.Month(), local.Day()+i, w.start/60, w.start%60, 0, 0, w.location)
+		if w.days[open.Weekday()] && open.After(t) {
+			return open
+		}
+	}
+	return t
+}
+
+// Windows is a set of maintenance windows; a run is allowed inside any of
+// them, and anytime when there are none
+type Windows []*Window
+
+// ParseWindows parses maintenance window specs
+func ParseWindows(specs []string) (Windows, error) {
+	windows := make(Windows, 0, len(specs))
+	for _, spec := range specs {
+		w, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// Allows reports whether t falls inside one of the windows
+func (ws Windows) Allows(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns when the earliest window opens after t, or t if one is
+// open
+func (ws Windows) NextOpen(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range ws {
+		if open := w.NextOpen(t); next.IsZero() || open.Before(next) {
+			next = open
+		}
+	}
+	if next.IsZero() {
+		return t
+	}
+	return next
+}
+
+// String returns the window specs separated by commas
+func (ws Windows) String() string {
+	specs := make([]string, len(ws))
+	for i, w := range ws {
+		specs[i] = w.spec
+	}
+	return strings.Join(specs, ", ")
+}
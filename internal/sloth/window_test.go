@@ -0,0 +1,102 @@
+package sloth
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParseWindow(t *testing.T, spec string) *Window {
+	t.Helper()
+	w, err := ParseWindow(spec)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return w
+}
+
+func TestWindow_Contains(t *testing.T) {
+	// 2026-10-16 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"mon-fri 09:00-17:00 UTC", at(16, 9, 0), true},
+		{"mon-fri 09:00-17:00 UTC", at(16, 17, 0), false},
+		{"mon-fri 09:00-17:00 UTC", at(17, 10, 0), false},
+		{"fri 22:00-06:00 UTC", at(16, 23, 30), true},
+		{"fri 22:00-06:00 UTC", at(17, 5, 59), true},
+		{"fri 22:00-06:00 UTC", at(16, 5, 0), false},
+		{"weekends 00:00-24:00 UTC", at(18, 23, 59), true},
+		{"fri-mon 10:00-11:00 UTC", at(19, 10, 30), true},
+		{"fri-mon 10:00-11:00 UTC", at(20, 10, 30), false},
+		{"sat,wed 10:00-11:00 UTC", at(17, 10, 30), true},
+		{"10:00-11:00 UTC", at(20, 10, 30), true},
+		// 23:30 UTC is 00:30 on Saturday in Lisbon
+		{"sat 00:00-01:00 Europe/Lisbon", at(16, 23, 30), true},
+	}
+	for _, tt := range tests {
+		if got := mustParseWindow(t, tt.spec).Contains(tt.at); got != tt.want {
+			t.Errorf("%q contains %s = %v, want %v", tt.spec, tt.at.Format(time.RFC1123), got, tt.want)
+		}
+	}
+}
+
+func TestParseWindow_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"mon-fri",
+		"funday 10:00-11:00",
+		"10:00-10:00",
+		"24:00-02:00",
+		"10:00-11:60",
+		"10:00-11:00 Mars/Olympus",
+		"mon tue 10:00-11:00",
+	} {
+		if _, err := ParseWindow(spec); err == nil {
+			t.Errorf("Expected %q to be invalid", spec)
+		}
+	}
+}
+
+func TestWindows_NextOpen(t *testing.T) {
+	windows, err := ParseWindows([]string{"mon-fri 22:00-06:00 UTC", "sat 12:00-13:00 UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if windows.Allows(friday) {
+		t.Error("Expected Friday noon to be outside the windows")
+	}
+	if next := windows.NextOpen(friday); !next.Equal(time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected next window: %s", next)
+	}
+
+	saturday := time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)
+	if next := windows.NextOpen(saturday); !next.Equal(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected next window: %s", next)
+	}
+
+	if !Windows(nil).Allows(friday) {
+		t.Error("Expected no windows to allow any time")
+	}
+}
+
+func TestParseFrontMatter_Windows(t *testing.T) {
+	meta, _, err := ParseFrontMatter([]byte("---\nname: deploy\nwindows: [\"weekdays 22:00-06:00 UTC\"]\n---\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if windows := meta.MaintenanceWindows(); len(windows) != 1 || windows.String() != "weekdays 22:00-06:00 UTC" {
+		t.Errorf("Unexpected windows: %v", windows)
+	}
+
+	if _, _, err := ParseFrontMatter([]byte("---\nname: deploy\nwindows: [\"never\"]\n---\n")); err == nil {
+		t.Error("Expected an invalid window to be rejected")
+	}
+}
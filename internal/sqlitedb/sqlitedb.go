@@ -0,0 +1,190 @@
+// Package sqlitedb opens the SQLite databases of sloth-runner with the same
+// settings, so concurrent CLI commands, the master and agents don't fail
+// with "database is locked":
+//
+//   - WAL journaling, so readers don't block the writer and the writer
+//     doesn't block readers
+//   - a busy timeout, so a connection waits for a lock instead of failing
+//   - immediate transactions, which take the write lock when they begin
+//     rather than when they first write, where SQLite can't wait for it
+//
+// Subsystems of one process opening the same file share a connection pool.
+// Writes that still hit a busy database can be retried with Retry.
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultBusyTimeout is how long a connection waits for a lock
+const DefaultBusyTimeout = 5 * time.Second
+
+// Options configure a database
+type Options struct {
+	// ForeignKeys enforces foreign key constraints
+	ForeignKeys bool
+	// BusyTimeout is how long to wait for a lock, DefaultBusyTimeout if zero
+	BusyTimeout time.Duration
+}
+
+// DSN returns the data source name opening path with opts
+func DSN(path string, opts Options) string {
+	timeout := opts.BusyTimeout
+	if timeout <= 0 {
+		timeout = DefaultBusyTimeout
+	}
+	params := []string{
+		"_busy_timeout=" + fmt.Sprint(timeout.Milliseconds()),
+		"_txlock=immediate",
+	}
+	if !isMemory(path) {
+		params = append(params, "_journal_mode=WAL", "_synchronous=NORMAL")
+	}
+	if opts.ForeignKeys {
+		params = append(params, "_foreign_keys=on")
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + strings.Join(params, "&")
+}
+
+// shared are the open databases by path
+var (
+	sharedMu sync.Mutex
+	shared   = map[string]*sharedDB{}
+	// owners maps a pool to its path, for Close
+	owners = map[*sql.DB]string{}
+)
+
+type sharedDB struct {
+	db   *sql.DB
+	refs int
+}
+
+// Open opens the database at path, or returns the pool already open for
+// it in this process. The first Open of a path sets its options. Close
+// the database with Close rather than its own Close method, so the pool
+// is only closed when its last user is done with it.
+func Open(path string, opts Options) (*sql.DB, error) {
+	if isMemory(path) {
+		// Every connection to :memory: is a database of its own
+		db, err := sql.Open("sqlite3", DSN(path, opts))
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(1)
+		return db, nil
+	}
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = filepath.Clean(path)
+	}
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if s, ok := shared[key]; ok {
+		s.refs++
+		return s.db, nil
+	}
+
+	db, err := sql.Open("sqlite3", DSN(path, opts))
+	if err != nil {
+		return nil, err
+	}
+	shared[key] = &sharedDB{db: db, refs: 1}
+	owners[db] = key
+	return db, nil
+}
+
+// Close releases a database returned by Open, closing its pool when no
+// one else uses it
+func Close(db *sql.DB) error {
+	if db == nil {
+		return nil
+	}
+
+	sharedMu.Lock()
+	key, ok := owners[db]
+	if ok {
+		s := shared[key]
+		s.refs--
+		if s.refs > 0 {
+			sharedMu.Unlock()
+			return nil
+		}
+		delete(shared, key)
+		delete(owners, db)
+	}
+	sharedMu.Unlock()
+	return db.Close()
+}
+
+// IsBusy reports whether err is SQLite failing to get a lock
+// (SQLITE_BUSY or SQLITE_LOCKED), which is worth retrying
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
+}
+
+// Retry backoff
+const (
+	retryAttempts = 5
+	retryDelay    = 50 * time.Millisecond
+)
+
+// Retry runs fn until it doesn't fail with a busy database, up to a few
+// times with growing delays
+func Retry(ctx context.Context, fn func() error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !IsBusy(err) || attempt == retryAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// Exec runs a statement, retrying it while the database is busy
+func Exec(ctx context.Context, db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var result sql.Result
+	err := Retry(ctx, func() error {
+		var err error
+		result, err = db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+func isMemory(path string) bool {
+	return path == ":memory:" || strings.HasPrefix(path, "file::memory:") || strings.Contains(path, "mode=memory")
+}
@@ -0,0 +1,151 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentWriters runs read-modify-write transactions from several
+// pools at once, as concurrent CLI commands and the master do. Opened with
+// a bare path most of them fail with "database is locked".
+func TestConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stress.db")
+	db, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	if _, err := db.Exec(`CREATE TABLE counter (id INTEGER PRIMARY KEY, n INTEGER NOT NULL);
+		INSERT INTO counter (id, n) VALUES (1, 0)`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Separate pools stand in for separate processes
+	const processes, workers, increments = 4, 8, 25
+	pools := []*sql.DB{db}
+	for i := 1; i < processes; i++ {
+		pool, err := sql.Open("sqlite3", DSN(path, Options{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+		pools = append(pools, pool)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, processes*workers)
+	for _, pool := range pools {
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(pool *sql.DB) {
+				defer wg.Done()
+				for i := 0; i < increments; i++ {
+					if err := increment(pool); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(pool)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Increment failed: %v", err)
+	}
+
+	var n int
+	if err := db.QueryRow(`SELECT n FROM counter WHERE id = 1`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if want := processes * workers * increments; n != want {
+		t.Errorf("Expected the counter at %d, got %d", want, n)
+	}
+
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("Expected WAL journaling, got %q (%v)", mode, err)
+	}
+}
+
+func increment(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRow(`SELECT n FROM counter WHERE id = 1`).Scan(&n); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE counter SET n = ? WHERE id = 1`, n+1); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func TestOpen_SharesPools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+	first, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Open(filepath.Join(filepath.Dir(path), ".", "shared.db"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("Expected subsystems opening the same file to share a pool")
+	}
+
+	// The pool stays open until its last user closes it
+	if err := Close(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Ping(); err != nil {
+		t.Errorf("Expected the pool to stay open for its other user: %v", err)
+	}
+	if err := Close(second); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Ping(); err == nil {
+		t.Error("Expected the pool to be closed after its last user closed it")
+	}
+
+	// Every :memory: database is private
+	a, _ := Open(":memory:", Options{})
+	b, _ := Open(":memory:", Options{})
+	defer Close(a)
+	defer Close(b)
+	if a == b {
+		t.Error("Expected :memory: databases not to be shared")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("failed to record: database is locked")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected busy errors to be retried, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), func() error {
+		calls++
+		return errors.New("no such table: agents")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected other errors not to be retried, got %v after %d calls", err, calls)
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // Profile represents an SSH connection profile
@@ -25,6 +25,45 @@ type Profile struct {
 	ConnectionTimeout  int       `json:"connection_timeout"`
 	KeepaliveInterval  int       `json:"keepalive_interval"`
 	StrictHostChecking bool      `json:"strict_host_checking"`
+
+	// Protocol is ProtocolSSH (default) or ProtocolWinRM for Windows targets
+	Protocol string `json:"protocol,omitempty"`
+	// AuthMethod forces an authentication method; empty picks password
+	// when one is supplied and the key otherwise
+	AuthMethod string `json:"auth_method,omitempty"`
+
+	// Kerberos settings, used when AuthMethod is AuthKerberos
+	KerberosPrincipal string `json:"kerberos_principal,omitempty"`
+	KerberosKeytab    string `json:"kerberos_keytab,omitempty"`
+	KerberosCCache    string `json:"kerberos_ccache,omitempty"`
+	KerberosDelegate  bool   `json:"kerberos_delegate,omitempty"`
+
+	// WinRM settings, used when Protocol is ProtocolWinRM
+	WinRMHTTPS    bool `json:"winrm_https,omitempty"`
+	WinRMInsecure bool `json:"winrm_insecure,omitempty"`
+}
+
+// Connection protocols
+const (
+	ProtocolSSH   = "ssh"
+	ProtocolWinRM = "winrm"
+)
+
+// Authentication methods
+const (
+	AuthPassword = "password"
+	AuthKey      = "key"
+	AuthKerberos = "kerberos"
+)
+
+// IsWinRM reports whether the profile targets a Windows host over WinRM
+func (p *Profile) IsWinRM() bool {
+	return p.Protocol == ProtocolWinRM
+}
+
+// IsKerberos reports whether the profile authenticates with Kerberos
+func (p *Profile) IsKerberos() bool {
+	return p.AuthMethod == AuthKerberos
 }
 
 // AuditLog represents an SSH operation audit entry
@@ -54,7 +93,7 @@ func NewDatabase(dbPath string) (*Database, error) {
 	}
 
 	// Open database
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -62,14 +101,14 @@ func NewDatabase(dbPath string) (*Database, error) {
 	// Set database file permissions (only if file exists)
 	if _, err := os.Stat(dbPath); err == nil {
 		if err := os.Chmod(dbPath, 0600); err != nil {
-			db.Close()
+			sqlitedb.Close(db)
 			return nil, fmt.Errorf("failed to set database permissions: %w", err)
 		}
 	}
 
 	// Create tables if they don't exist
 	if err := createTables(db); err != nil {
-		db.Close()
+		sqlitedb.Close(db)
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
@@ -149,6 +188,22 @@ func createTables(db *sql.DB) error {
 		}
 	}
 
+	// Add columns introduced after the initial schema; errors mean the
+	// column already exists
+	migrations := []string{
+		"ALTER TABLE ssh_profiles ADD COLUMN protocol TEXT NOT NULL DEFAULT 'ssh'",
+		"ALTER TABLE ssh_profiles ADD COLUMN auth_method TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE ssh_profiles ADD COLUMN kerberos_principal TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE ssh_profiles ADD COLUMN kerberos_keytab TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE ssh_profiles ADD COLUMN kerberos_ccache TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE ssh_profiles ADD COLUMN kerberos_delegate BOOLEAN NOT NULL DEFAULT 0",
+		"ALTER TABLE ssh_profiles ADD COLUMN winrm_https BOOLEAN NOT NULL DEFAULT 0",
+		"ALTER TABLE ssh_profiles ADD COLUMN winrm_insecure BOOLEAN NOT NULL DEFAULT 0",
+	}
+	for _, m := range migrations {
+		db.Exec(m)
+	}
+
 	// Create trigger for updating timestamp
 	triggerSQL := `
 	CREATE TRIGGER IF NOT EXISTS update_ssh_profile_timestamp
@@ -168,7 +223,7 @@ func createTables(db *sql.DB) error {
 
 // Close closes the database connection
 func (d *Database) Close() error {
-	return d.db.Close()
+	return sqlitedb.Close(d.db)
 }
 
 // AddProfile adds a new SSH profile
@@ -176,8 +231,15 @@ func (d *Database) AddProfile(profile *Profile) error {
 	query := `
 		INSERT INTO ssh_profiles (
 			name, host, user, port, key_path, description,
-			connection_timeout, keepalive_interval, strict_host_checking
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			connection_timeout, keepalive_interval, strict_host_checking,
+			protocol, auth_method, kerberos_principal, kerberos_keytab,
+			kerberos_ccache, kerberos_delegate, winrm_https, winrm_insecure
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	protocol := profile.Protocol
+	if protocol == "" {
+		protocol = ProtocolSSH
+	}
 
 	_, err := d.db.Exec(query,
 		profile.Name,
@@ -189,6 +251,14 @@ func (d *Database) AddProfile(profile *Profile) error {
 		profile.ConnectionTimeout,
 		profile.KeepaliveInterval,
 		profile.StrictHostChecking,
+		protocol,
+		profile.AuthMethod,
+		profile.KerberosPrincipal,
+		profile.KerberosKeytab,
+		profile.KerberosCCache,
+		profile.KerberosDelegate,
+		profile.WinRMHTTPS,
+		profile.WinRMInsecure,
 	)
 
 	if err != nil {
@@ -203,7 +273,9 @@ func (d *Database) GetProfile(name string) (*Profile, error) {
 	query := `
 		SELECT name, host, user, port, key_path, description,
 			   created_at, updated_at, last_used, use_count,
-			   connection_timeout, keepalive_interval, strict_host_checking
+			   connection_timeout, keepalive_interval, strict_host_checking,
+			   protocol, auth_method, kerberos_principal, kerberos_keytab,
+			   kerberos_ccache, kerberos_delegate, winrm_https, winrm_insecure
 		FROM ssh_profiles
 		WHERE name = ?`
 
@@ -224,6 +296,14 @@ func (d *Database) GetProfile(name string) (*Profile, error) {
 		&profile.ConnectionTimeout,
 		&profile.KeepaliveInterval,
 		&profile.StrictHostChecking,
+		&profile.Protocol,
+		&profile.AuthMethod,
+		&profile.KerberosPrincipal,
+		&profile.KerberosKeytab,
+		&profile.KerberosCCache,
+		&profile.KerberosDelegate,
+		&profile.WinRMHTTPS,
+		&profile.WinRMInsecure,
 	)
 
 	if err == sql.ErrNoRows {
@@ -245,7 +325,9 @@ func (d *Database) ListProfiles() ([]*Profile, error) {
 	query := `
 		SELECT name, host, user, port, key_path, description,
 			   created_at, updated_at, last_used, use_count,
-			   connection_timeout, keepalive_interval, strict_host_checking
+			   connection_timeout, keepalive_interval, strict_host_checking,
+			   protocol, auth_method, kerberos_principal, kerberos_keytab,
+			   kerberos_ccache, kerberos_delegate, winrm_https, winrm_insecure
 		FROM ssh_profiles
 		ORDER BY name`
 
@@ -274,6 +356,14 @@ func (d *Database) ListProfiles() ([]*Profile, error) {
 			&profile.ConnectionTimeout,
 			&profile.KeepaliveInterval,
 			&profile.StrictHostChecking,
+			&profile.Protocol,
+			&profile.AuthMethod,
+			&profile.KerberosPrincipal,
+			&profile.KerberosKeytab,
+			&profile.KerberosCCache,
+			&profile.KerberosDelegate,
+			&profile.WinRMHTTPS,
+			&profile.WinRMInsecure,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan profile: %w", err)
@@ -297,13 +387,14 @@ func (d *Database) UpdateProfile(name string, updates map[string]interface{}) er
 
 	for field, value := range updates {
 		switch field {
-		case "host", "user", "key_path", "description":
+		case "host", "user", "key_path", "description",
+			"protocol", "auth_method", "kerberos_principal", "kerberos_keytab", "kerberos_ccache":
 			setClause = append(setClause, fmt.Sprintf("%s = ?", field))
 			args = append(args, value)
 		case "port", "connection_timeout", "keepalive_interval":
 			setClause = append(setClause, fmt.Sprintf("%s = ?", field))
 			args = append(args, value)
-		case "strict_host_checking":
+		case "strict_host_checking", "kerberos_delegate", "winrm_https", "winrm_insecure":
 			setClause = append(setClause, fmt.Sprintf("%s = ?", field))
 			args = append(args, value)
 		}
@@ -111,6 +111,52 @@ func TestDatabase_AddProfile(t *testing.T) {
 	}
 }
 
+func TestDatabase_KerberosWinRMProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, _ := NewDatabase(dbPath)
+	defer db.Close()
+
+	profile := &Profile{
+		Name:              "win-dc",
+		Host:              "dc01.corp.example.com",
+		User:              "svc-deploy",
+		Port:              5986,
+		Protocol:          ProtocolWinRM,
+		AuthMethod:        AuthKerberos,
+		KerberosPrincipal: "svc-deploy@CORP.EXAMPLE.COM",
+		KerberosKeytab:    "/etc/sloth/svc-deploy.keytab",
+		WinRMHTTPS:        true,
+	}
+	if err := db.AddProfile(profile); err != nil {
+		t.Fatalf("Failed to add profile: %v", err)
+	}
+	db.AddProfile(&Profile{Name: "plain", Host: "localhost", User: "user", Port: 22})
+
+	retrieved, err := db.GetProfile("win-dc")
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if !retrieved.IsWinRM() || !retrieved.IsKerberos() || !retrieved.WinRMHTTPS ||
+		retrieved.KerberosPrincipal != "svc-deploy@CORP.EXAMPLE.COM" {
+		t.Errorf("Kerberos/WinRM settings not persisted: %+v", retrieved)
+	}
+
+	plain, _ := db.GetProfile("plain")
+	if plain.Protocol != ProtocolSSH || plain.IsKerberos() {
+		t.Errorf("Expected ssh defaults, got protocol=%q auth=%q", plain.Protocol, plain.AuthMethod)
+	}
+
+	if err := db.UpdateProfile("plain", map[string]interface{}{"auth_method": AuthKerberos, "kerberos_delegate": true}); err != nil {
+		t.Fatalf("Failed to update profile: %v", err)
+	}
+	plain, _ = db.GetProfile("plain")
+	if !plain.IsKerberos() || !plain.KerberosDelegate {
+		t.Error("Kerberos settings not updated")
+	}
+}
+
 // Test GetProfile
 func TestDatabase_GetProfile(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -49,6 +50,24 @@ func (e *Executor) ExecuteCommand(profileName string, command string, password *
 		Timestamp:   startTime,
 	}
 
+	// Kerberos and WinRM targets run through their own transports
+	if profile.IsWinRM() || profile.IsKerberos() {
+		result, err := e.runExternal(profile, command, password)
+		duration := time.Since(startTime)
+		auditLog.DurationMs = int(duration.Milliseconds())
+		if err != nil {
+			auditLog.Success = false
+			auditLog.ErrorMessage = err.Error()
+			e.db.AddAuditLog(auditLog)
+			return nil, fmt.Errorf("command execution failed: %w", err)
+		}
+		result.Duration = duration
+		auditLog.Success = true
+		auditLog.BytesTransferred = len(result.Output) + len(result.Error)
+		e.db.AddAuditLog(auditLog)
+		return result, nil
+	}
+
 	// Establish SSH connection
 	client, err := e.connect(profile, password)
 	if err != nil {
@@ -125,6 +144,18 @@ func (e *Executor) TestConnection(profileName string, password *string) error {
 		Timestamp:   time.Now(),
 	}
 
+	if profile.IsWinRM() || profile.IsKerberos() {
+		if _, err := e.runExternal(profile, "hostname", password); err != nil {
+			auditLog.Success = false
+			auditLog.ErrorMessage = err.Error()
+			e.db.AddAuditLog(auditLog)
+			return fmt.Errorf("connection test failed: %w", err)
+		}
+		auditLog.Success = true
+		e.db.AddAuditLog(auditLog)
+		return nil
+	}
+
 	// Try to connect
 	client, err := e.connect(profile, password)
 	if err != nil {
@@ -152,6 +183,15 @@ func (e *Executor) TestConnection(profileName string, password *string) error {
 	return nil
 }
 
+// runExternal executes a command on profiles that do not use the native
+// SSH client: WinRM targets and Kerberos (GSSAPI) SSH logins
+func (e *Executor) runExternal(profile *Profile, command string, password *string) (*ExecutionResult, error) {
+	if profile.IsWinRM() {
+		return runWinRM(profile, command, password)
+	}
+	return runKerberosSSH(profile, command)
+}
+
 // connect establishes an SSH connection
 func (e *Executor) connect(profile *Profile, password *string) (*ssh.Client, error) {
 	config := &ssh.ClientConfig{
@@ -215,22 +255,8 @@ func (e *Executor) connect(profile *Profile, password *string) (*ssh.Client, err
 	return client, nil
 }
 
-// ReadPasswordFromStdin reads a password from standard input
+// ReadPasswordFromStdin reads a password piped in on standard input
 func ReadPasswordFromStdin() (string, error) {
-	// Check if stdin is a terminal or pipe
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
-		// Interactive mode - terminal
-		fmt.Fprint(os.Stderr, "SSH Password: ")
-		password, err := term.ReadPassword(int(os.Stdin.Fd()))
-		fmt.Fprintln(os.Stderr) // New line after password
-		if err != nil {
-			return "", fmt.Errorf("failed to read password: %w", err)
-		}
-		return string(password), nil
-	}
-
-	// Pipe mode - read from stdin
 	reader := bufio.NewReader(os.Stdin)
 	password, err := reader.ReadString('\n')
 	if err != nil && err != io.EOF {
@@ -266,6 +292,22 @@ func (e *Executor) CreateInteractiveSession(profileName string, password *string
 		return fmt.Errorf("failed to get profile: %w", err)
 	}
 
+	if profile.IsWinRM() {
+		return fmt.Errorf("interactive sessions are not supported over WinRM")
+	}
+	if profile.IsKerberos() {
+		ccache, err := EnsureKerberosTicket(profile)
+		if err != nil {
+			return err
+		}
+		cmd := execCommand("ssh", kerberosSSHArgs(profile, true, "")...)
+		cmd.Env = kerberosEnv(ccache)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+
 	// Establish SSH connection
 	client, err := e.connect(profile, password)
 	if err != nil {
@@ -321,6 +363,24 @@ func (e *Executor) TransferFile(profileName string, localPath string, remotePath
 		return fmt.Errorf("failed to get profile: %w", err)
 	}
 
+	if profile.IsWinRM() {
+		return fmt.Errorf("file transfer is not supported over WinRM")
+	}
+	if profile.IsKerberos() {
+		ccache, err := EnsureKerberosTicket(profile)
+		if err != nil {
+			return err
+		}
+		args := append([]string{"-P", strconv.Itoa(profile.Port), "-o", "User=" + profile.User}, kerberosSSHOptions(profile)...)
+		args = append(args, localPath, fmt.Sprintf("%s:%s", profile.Host, remotePath))
+		cmd := execCommand("scp", args...)
+		cmd.Env = kerberosEnv(ccache)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("scp failed: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
 	// Establish SSH connection
 	client, err := e.connect(profile, password)
 	if err != nil {
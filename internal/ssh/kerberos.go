@@ -0,0 +1,163 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// execCommand is replaced in tests
+var execCommand = exec.Command
+
+// KerberosCCache returns the credential cache used for a profile. Profiles
+// with a keytab get a private cache so automated logins never touch the
+// user's own tickets; otherwise the default cache (KRB5CCNAME) is used.
+func KerberosCCache(profile *Profile) (string, error) {
+	if profile.KerberosCCache != "" {
+		return profile.KerberosCCache, nil
+	}
+	if profile.KerberosKeytab == "" {
+		return "", nil
+	}
+
+	// The cache lives in a directory only the user can enter, under a name
+	// that stays the same across runs, so a ticket is reused while it is
+	// valid instead of leaving a new cache behind on every run
+	dir := kerberosCCacheDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create Kerberos credential cache directory: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to secure Kerberos credential cache directory: %w", err)
+	}
+	return "FILE:" + filepath.Join(dir, "krb5cc_"+url.PathEscape(profile.Name)), nil
+}
+
+// kerberosCCacheDir returns the directory holding the caches of keytab profiles
+func kerberosCCacheDir() string {
+	return filepath.Join(filepath.Dir(GetDefaultDatabasePath()), "krb5cc")
+}
+
+// EnsureKerberosTicket makes sure the profile's credential cache holds a
+// valid ticket, obtaining a new one from the keytab when it is missing or
+// expired. It returns the cache to use.
+func EnsureKerberosTicket(profile *Profile) (string, error) {
+	ccache, err := KerberosCCache(profile)
+	if err != nil {
+		return "", err
+	}
+	if kerberosTicketValid(ccache) {
+		return ccache, nil
+	}
+
+	if profile.KerberosKeytab == "" {
+		principal := profile.KerberosPrincipal
+		if principal == "" {
+			principal = profile.User
+		}
+		return "", fmt.Errorf("no valid Kerberos ticket found; run 'kinit %s' or configure a keytab for profile '%s'",
+			principal, profile.Name)
+	}
+	if profile.KerberosPrincipal == "" {
+		return "", fmt.Errorf("profile '%s' has a keytab but no Kerberos principal", profile.Name)
+	}
+	if _, err := os.Stat(profile.KerberosKeytab); err != nil {
+		return "", fmt.Errorf("cannot access keytab: %w", err)
+	}
+
+	cmd := execCommand("kinit", "-k", "-t", profile.KerberosKeytab, "-c", ccache, profile.KerberosPrincipal)
+	cmd.Env = kerberosEnv(ccache)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("kinit failed for %s: %v: %s", profile.KerberosPrincipal, err, strings.TrimSpace(string(out)))
+	}
+
+	return ccache, nil
+}
+
+// kerberosTicketValid reports whether the cache holds a non-expired ticket
+func kerberosTicketValid(ccache string) bool {
+	cmd := execCommand("klist", "-s")
+	cmd.Env = kerberosEnv(ccache)
+	return cmd.Run() == nil
+}
+
+// kerberosEnv returns the process environment pointing at ccache
+func kerberosEnv(ccache string) []string {
+	env := os.Environ()
+	if ccache != "" {
+		env = append(env, "KRB5CCNAME="+ccache)
+	}
+	return env
+}
+
+// kerberosSSHOptions returns the OpenSSH options for GSSAPI authentication.
+// The system ssh client is used because it authenticates with the ticket
+// cache through the platform GSSAPI library.
+func kerberosSSHOptions(profile *Profile) []string {
+	opts := []string{
+		"-o", "BatchMode=yes",
+		"-o", "GSSAPIAuthentication=yes",
+		"-o", "PreferredAuthentications=gssapi-with-mic",
+		"-o", fmt.Sprintf("ConnectTimeout=%d", profile.ConnectionTimeout),
+	}
+	if profile.KeepaliveInterval > 0 {
+		opts = append(opts, "-o", fmt.Sprintf("ServerAliveInterval=%d", profile.KeepaliveInterval))
+	}
+	if profile.KerberosDelegate {
+		// Forward the TGT so the remote session can reach other AD services
+		opts = append(opts, "-o", "GSSAPIDelegateCredentials=yes")
+	}
+	if !profile.StrictHostChecking {
+		opts = append(opts, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null")
+	}
+	return opts
+}
+
+// kerberosSSHArgs builds the ssh command line for a profile
+func kerberosSSHArgs(profile *Profile, tty bool, command string) []string {
+	args := append([]string{"-p", strconv.Itoa(profile.Port), "-l", profile.User}, kerberosSSHOptions(profile)...)
+	if tty {
+		args = append(args, "-t")
+	}
+	// Hosts starting with "-" must not be taken for options
+	args = append(args, "--", profile.Host)
+	if command != "" {
+		args = append(args, command)
+	}
+	return args
+}
+
+// runKerberosSSH executes a command through the system ssh client
+func runKerberosSSH(profile *Profile, command string) (*ExecutionResult, error) {
+	ccache, err := EnsureKerberosTicket(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := execCommand("ssh", kerberosSSHArgs(profile, false, command)...)
+	cmd.Env = kerberosEnv(ccache)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	result := &ExecutionResult{
+		Output: stdout.String(),
+		Error:  stderr.String(),
+	}
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		// ssh exits with 255 when the connection or authentication failed
+		if !ok || exitErr.ExitCode() == 255 {
+			return nil, fmt.Errorf("ssh failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		result.ExitCode = exitErr.ExitCode()
+	}
+
+	return result, nil
+}
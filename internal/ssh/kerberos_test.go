@@ -0,0 +1,140 @@
+package ssh
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubCommands replaces execCommand, recording invocations and making the
+// named programs fail
+func stubCommands(t *testing.T, failing ...string) *[]string {
+	t.Helper()
+	var calls []string
+	orig := execCommand
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		for _, f := range failing {
+			if f == name {
+				return exec.Command("false")
+			}
+		}
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { execCommand = orig })
+	return &calls
+}
+
+func TestKerberosCCache(t *testing.T) {
+	if got, _ := KerberosCCache(&Profile{Name: "p", KerberosCCache: "FILE:/tmp/cc"}); got != "FILE:/tmp/cc" {
+		t.Errorf("Expected configured cache, got %s", got)
+	}
+	if got, _ := KerberosCCache(&Profile{Name: "p"}); got != "" {
+		t.Errorf("Expected default cache, got %s", got)
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	profile := &Profile{Name: "p", KerberosKeytab: "/etc/krb5.keytab"}
+	got, err := KerberosCCache(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "FILE:") {
+		t.Fatalf("Expected private cache for keytab profile, got %s", got)
+	}
+	dir := filepath.Dir(strings.TrimPrefix(got, "FILE:"))
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("Expected the cache directory to be private to the user, got mode %v", info.Mode().Perm())
+	}
+	if again, _ := KerberosCCache(profile); again != got {
+		t.Errorf("Expected the profile to keep its cache, got %s and %s", got, again)
+	}
+	other, _ := KerberosCCache(&Profile{Name: "../q", KerberosKeytab: "/etc/krb5.keytab"})
+	if other == got {
+		t.Error("Expected each profile to get its own cache")
+	}
+	if filepath.Dir(strings.TrimPrefix(other, "FILE:")) != dir {
+		t.Errorf("Expected the cache to stay in %s, got %s", dir, other)
+	}
+}
+
+func TestEnsureKerberosTicket(t *testing.T) {
+	t.Run("valid ticket", func(t *testing.T) {
+		calls := stubCommands(t)
+		if _, err := EnsureKerberosTicket(&Profile{Name: "p", User: "alice"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(*calls) != 1 || !strings.HasPrefix((*calls)[0], "klist") {
+			t.Errorf("Expected only klist, got %v", *calls)
+		}
+	})
+
+	t.Run("no ticket and no keytab", func(t *testing.T) {
+		stubCommands(t, "klist")
+		_, err := EnsureKerberosTicket(&Profile{Name: "p", User: "alice", KerberosPrincipal: "alice@CORP.EXAMPLE.COM"})
+		if err == nil || !strings.Contains(err.Error(), "kinit alice@CORP.EXAMPLE.COM") {
+			t.Errorf("Expected kinit hint, got %v", err)
+		}
+	})
+
+	t.Run("renews from keytab", func(t *testing.T) {
+		keytab := filepath.Join(t.TempDir(), "svc.keytab")
+		os.WriteFile(keytab, []byte("x"), 0600)
+		calls := stubCommands(t, "klist")
+
+		profile := &Profile{Name: "p", User: "svc", KerberosPrincipal: "svc@CORP.EXAMPLE.COM", KerberosKeytab: keytab}
+		ccache, err := EnsureKerberosTicket(profile)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := "kinit -k -t " + keytab + " -c " + ccache + " svc@CORP.EXAMPLE.COM"
+		if len(*calls) != 2 || (*calls)[1] != want {
+			t.Errorf("Expected %q, got %v", want, *calls)
+		}
+	})
+
+	t.Run("keytab without principal", func(t *testing.T) {
+		stubCommands(t, "klist")
+		if _, err := EnsureKerberosTicket(&Profile{Name: "p", KerberosKeytab: "/etc/krb5.keytab"}); err == nil {
+			t.Error("Expected error without principal")
+		}
+	})
+}
+
+func TestKerberosSSHArgs(t *testing.T) {
+	profile := &Profile{
+		Host:              "web01.corp.example.com",
+		User:              "alice",
+		Port:              2222,
+		ConnectionTimeout: 10,
+		KerberosDelegate:  true,
+	}
+
+	args := strings.Join(kerberosSSHArgs(profile, false, "uptime"), " ")
+	for _, want := range []string{
+		"-p 2222 -l alice",
+		"GSSAPIAuthentication=yes",
+		"PreferredAuthentications=gssapi-with-mic",
+		"GSSAPIDelegateCredentials=yes",
+		"StrictHostKeyChecking=no",
+		"ConnectTimeout=10",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Expected %q in %s", want, args)
+		}
+	}
+	if !strings.HasSuffix(args, "-- web01.corp.example.com uptime") {
+		t.Errorf("Expected host and command at the end: %s", args)
+	}
+
+	profile.StrictHostChecking = true
+	if strings.Contains(strings.Join(kerberosSSHArgs(profile, true, ""), " "), "StrictHostKeyChecking") {
+		t.Error("Strict profiles must keep host key checking")
+	}
+}
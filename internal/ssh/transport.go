@@ -0,0 +1,282 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Target is a host tasks run on over SSH, without an agent
+type Target struct {
+	User string
+	Host string
+	Port int
+	// KeyPath is the private key to log in with. Without it the keys of
+	// the SSH agent at SSH_AUTH_SOCK are used.
+	KeyPath string
+	// KnownHosts is the known_hosts file verifying the host's key,
+	// ~/.ssh/known_hosts by default
+	KnownHosts string
+	// InsecureIgnoreHostKey skips verifying the host's key
+	InsecureIgnoreHostKey bool
+	Timeout               time.Duration
+}
+
+// ParseTarget parses a destination written as user@host or user@host:port.
+// Without a user the current user logs in.
+func ParseTarget(dest string) (Target, error) {
+	t := Target{Port: 22}
+	user, host, ok := strings.Cut(dest, "@")
+	if !ok {
+		host, user = dest, os.Getenv("USER")
+	}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return t, fmt.Errorf("invalid port in %q", dest)
+		}
+		host, t.Port = h, p
+	}
+	if host == "" || user == "" {
+		return t, fmt.Errorf("invalid SSH destination %q: expected user@host", dest)
+	}
+	t.User, t.Host = user, host
+	return t, nil
+}
+
+// String returns the destination as user@host, with the port unless it's 22
+func (t Target) String() string {
+	if t.Port != 0 && t.Port != 22 {
+		return t.User + "@" + net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	}
+	return t.User + "@" + t.Host
+}
+
+// Conn runs commands on a target and works with its files over SFTP
+type Conn struct {
+	target Target
+	client *ssh.Client
+	sftp   *sftp.Client
+}
+
+// Dial connects to the target and opens an SFTP session on it
+func Dial(ctx context.Context, t Target) (*Conn, error) {
+	config, closeAgent, err := t.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	// The agent is only asked for keys while logging in
+	defer closeAgent()
+	address := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	dialer := net.Dialer{Timeout: config.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", address, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, address, config)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", t, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to start SFTP on %s: %w", t, err)
+	}
+	return &Conn{target: t, client: client, sftp: sftpClient}, nil
+}
+
+// clientConfig returns the client config logging in to the target and a
+// function closing the connection to the SSH agent it uses
+func (t Target) clientConfig() (*ssh.ClientConfig, func(), error) {
+	config := &ssh.ClientConfig{User: t.User, Timeout: t.Timeout}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	if t.InsecureIgnoreHostKey {
+		config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		path := t.KnownHosts
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, nil, err
+			}
+			path = filepath.Join(home, ".ssh", "known_hosts")
+		}
+		callback, err := knownhosts.New(expandHome(path))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load known hosts: %w", err)
+		}
+		config.HostKeyCallback = callback
+	}
+
+	if t.KeyPath != "" {
+		key, err := os.ReadFile(expandHome(t.KeyPath))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read private key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse private key: %w", err)
+		}
+		config.Auth = []ssh.AuthMethod{ssh.PublicKeys(signer)}
+		return config, func() {}, nil
+	}
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, errors.New("no authentication method available: set a key or start an SSH agent")
+	}
+	agentConn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reach the SSH agent: %w", err)
+	}
+	config.Auth = []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers)}
+	return config, func() { agentConn.Close() }, nil
+}
+
+// expandHome expands a leading ~ in path to the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// Host returns the target as user@host
+func (c *Conn) Host() string {
+	return c.target.String()
+}
+
+// Run runs command in the target's shell and returns its exit code.
+// Cancelling ctx closes the session.
+func (c *Conn) Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return -1, fmt.Errorf("failed to open a session on %s: %w", c.target, err)
+	}
+	defer session.Close()
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.Signal(ssh.SIGKILL)
+			session.Close()
+		case <-done:
+		}
+	}()
+
+	err = session.Run(command)
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		return exitErr.ExitStatus(), nil
+	case ctx.Err() != nil:
+		return -1, ctx.Err()
+	default:
+		return -1, err
+	}
+}
+
+// ReadFile reads the file at path on the target
+func (c *Conn) ReadFile(path string) ([]byte, error) {
+	f, err := c.sftp.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WriteFile writes data to the file at path on the target, creating it
+// with perm or truncating it
+func (c *Conn) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	f, err := c.sftp.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AppendFile appends data to the file at path on the target, creating it.
+// Not every SFTP server honours the append flag, so writes start at the
+// end of the file.
+func (c *Conn) AppendFile(path string, data []byte) error {
+	f, err := c.sftp.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Stat returns the file info of path on the target
+func (c *Conn) Stat(path string) (fs.FileInfo, error) {
+	return c.sftp.Stat(path)
+}
+
+// ReadDir returns the entries of the directory at path on the target
+func (c *Conn) ReadDir(path string) ([]fs.FileInfo, error) {
+	return c.sftp.ReadDir(path)
+}
+
+// MkdirAll creates the directory at path on the target with its parents
+func (c *Conn) MkdirAll(path string) error {
+	return c.sftp.MkdirAll(path)
+}
+
+// Remove removes the file or empty directory at path on the target
+func (c *Conn) Remove(path string) error {
+	return c.sftp.Remove(path)
+}
+
+// RemoveAll removes path on the target with everything it contains
+func (c *Conn) RemoveAll(path string) error {
+	return c.sftp.RemoveAll(path)
+}
+
+// Close closes the SFTP session and the connection
+func (c *Conn) Close() error {
+	c.sftp.Close()
+	return c.client.Close()
+}
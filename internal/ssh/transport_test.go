@@ -0,0 +1,209 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var _ taskctx.Remote = (*Conn)(nil)
+
+// testServer starts an SSH server running commands with sh and serving
+// SFTP, for the client key it returns the path of. It returns the target
+// logging in to it, trusting its host key.
+func testServer(t *testing.T) Target {
+	t.Helper()
+	dir := t.TempDir()
+	_, hostPriv, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPub, clientPriv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authorized, _ := ssh.NewPublicKey(clientPub)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{listener.Addr().String()}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHosts, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Target{User: "deploy", Host: "127.0.0.1", Port: addr.Port, KeyPath: keyPath, KnownHosts: knownHosts}
+}
+
+func serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		ch, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range requests {
+				var payload struct{ Value string }
+				ssh.Unmarshal(req.Payload, &payload)
+				switch req.Type {
+				case "exec":
+					req.Reply(true, nil)
+					cmd := exec.Command("sh", "-c", payload.Value)
+					cmd.Stdout, cmd.Stderr = ch, ch.Stderr()
+					code := 0
+					if err := cmd.Run(); err != nil {
+						code = 1
+						if exitErr, ok := err.(*exec.ExitError); ok {
+							code = exitErr.ExitCode()
+						}
+					}
+					status := make([]byte, 4)
+					binary.BigEndian.PutUint32(status, uint32(code))
+					ch.SendRequest("exit-status", false, status)
+					return
+				case "subsystem":
+					if payload.Value != "sftp" {
+						req.Reply(false, nil)
+						continue
+					}
+					req.Reply(true, nil)
+					server, err := sftp.NewServer(ch)
+					if err != nil {
+						return
+					}
+					server.Serve()
+					return
+				default:
+					req.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		dest string
+		want string
+		port int
+	}{
+		{"root@10.0.0.5", "root@10.0.0.5", 22},
+		{"admin@switch-01:2222", "admin@switch-01:2222", 2222},
+		{"admin@[fe80::1]:22", "admin@fe80::1", 22},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.dest)
+		if err != nil {
+			t.Fatalf("ParseTarget(%q): %v", tt.dest, err)
+		}
+		if got.String() != tt.want || got.Port != tt.port {
+			t.Errorf("ParseTarget(%q) = %s port %d, want %s port %d", tt.dest, got, got.Port, tt.want, tt.port)
+		}
+	}
+	if _, err := ParseTarget("root@host:ssh"); err == nil {
+		t.Error("Expected an invalid port to fail")
+	}
+}
+
+func TestConn(t *testing.T) {
+	target := testServer(t)
+	conn, err := Dial(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.Host() != "deploy@127.0.0.1:"+strconv.Itoa(target.Port) {
+		t.Errorf("Unexpected host %s", conn.Host())
+	}
+
+	var stdout, stderr bytes.Buffer
+	code, err := conn.Run(context.Background(), "echo out; echo err >&2; exit 3", &stdout, &stderr)
+	if err != nil || code != 3 || stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("Unexpected run: code %d, err %v, stdout %q, stderr %q", code, err, stdout.String(), stderr.String())
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "etc", "motd")
+	if err := conn.MkdirAll(filepath.Dir(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteFile(path, []byte("hello\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.AppendFile(path, []byte("world\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := conn.ReadFile(path); err != nil || string(data) != "hello\nworld\n" {
+		t.Errorf("Unexpected content %q, err %v", data, err)
+	}
+	if info, err := conn.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("Unexpected stat %v, err %v", info, err)
+	}
+	if err := conn.RemoveAll(filepath.Join(dir, "etc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be gone, got %v", err)
+	}
+}
+
+func TestDial_UnknownHostKey(t *testing.T) {
+	target := testServer(t)
+	target.KnownHosts = filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(target.KnownHosts, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Dial(context.Background(), target); err == nil || !strings.Contains(err.Error(), "key is unknown") {
+		t.Errorf("Expected an unknown host key to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/winrm"
+)
+
+// newWinRMClient creates a WinRM client for a Windows profile
+func newWinRMClient(profile *Profile, password *string) (*winrm.Client, error) {
+	cfg := winrm.Config{
+		Host:     profile.Host,
+		Port:     profile.Port,
+		HTTPS:    profile.WinRMHTTPS,
+		Insecure: profile.WinRMInsecure,
+		User:     profile.User,
+		Timeout:  time.Duration(profile.ConnectionTimeout) * time.Second,
+	}
+
+	switch profile.AuthMethod {
+	case AuthKerberos:
+		ccache, err := EnsureKerberosTicket(profile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Auth = winrm.AuthKerberos
+		cfg.CCache = ccache
+	case "", AuthPassword:
+		if password == nil || *password == "" {
+			return nil, fmt.Errorf("WinRM profile '%s' needs a password or kerberos authentication", profile.Name)
+		}
+		cfg.Auth = winrm.AuthBasic
+		cfg.Password = *password
+	default:
+		return nil, fmt.Errorf("authentication method '%s' is not supported over WinRM", profile.AuthMethod)
+	}
+
+	return winrm.NewClient(cfg)
+}
+
+// runWinRM executes a command on a Windows target. Commands run in cmd.exe;
+// use "powershell -Command ..." for PowerShell.
+func runWinRM(profile *Profile, command string, password *string) (*ExecutionResult, error) {
+	client, err := newWinRMClient(profile, password)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Run(context.Background(), command)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecutionResult{
+		Output:   res.Stdout,
+		Error:    res.Stderr,
+		ExitCode: res.ExitCode,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package stack
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Budget caps how much a stack may run, so runaway scheduled automation
+// stops instead of hammering agents. Zero limits are unlimited.
+type Budget struct {
+	MaxRunsPerDay   int     `json:"max_runs_per_day,omitempty"`
+	MaxTaskMinutes  float64 `json:"max_task_minutes,omitempty"`
+	MaxAgentsPerRun int     `json:"max_agents_per_run,omitempty"`
+}
+
+// IsZero reports whether the budget sets no limit
+func (b Budget) IsZero() bool {
+	return b.MaxRunsPerDay == 0 && b.MaxTaskMinutes == 0 && b.MaxAgentsPerRun == 0
+}
+
+// Usage is what a stack consumed since the start of a budget day
+type Usage struct {
+	Since       time.Time `json:"since"`
+	Runs        int       `json:"runs"`
+	TaskMinutes float64   `json:"task_minutes"`
+	// MaxAgents is the most agents a single run touched
+	MaxAgents int `json:"max_agents"`
+}
+
+// RunUsage is what one run of a stack consumed
+type RunUsage struct {
+	RunID       string
+	StartedAt   time.Time
+	TaskMinutes float64
+	Agents      int
+}
+
+// BudgetDay returns the start of the budget day containing t, local
+// midnight
+func BudgetDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Violations describes the limits a new run touching agents agents
+// exceeds, given the usage of the day so far
+func (b Budget) Violations(usage Usage, agents int) []string {
+	var violations []string
+	if b.MaxRunsPerDay > 0 && usage.Runs >= b.MaxRunsPerDay {
+		violations = append(violations, fmt.Sprintf("%d of %d runs per day used", usage.Runs, b.MaxRunsPerDay))
+	}
+	if b.MaxTaskMinutes > 0 && usage.TaskMinutes >= b.MaxTaskMinutes {
+		violations = append(violations, fmt.Sprintf("%s of %s task-minutes per day used",
+			FormatMinutes(usage.TaskMinutes), FormatMinutes(b.MaxTaskMinutes)))
+	}
+	if b.MaxAgentsPerRun > 0 && agents > b.MaxAgentsPerRun {
+		violations = append(violations, fmt.Sprintf("the run touches %d agents, more than the %d allowed per run", agents, b.MaxAgentsPerRun))
+	}
+	return violations
+}
+
+// FormatMinutes formats task-minutes with at most one decimal
+func FormatMinutes(minutes float64) string {
+	return strconv.FormatFloat(float64(int64(minutes*10+0.5))/10, 'f', -1, 64)
+}
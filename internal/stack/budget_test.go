@@ -0,0 +1,27 @@
+package stack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetViolations(t *testing.T) {
+	budget := Budget{MaxRunsPerDay: 3, MaxTaskMinutes: 60, MaxAgentsPerRun: 5}
+
+	assert.Empty(t, budget.Violations(Usage{Runs: 2, TaskMinutes: 59.9}, 5))
+	assert.Equal(t, []string{"3 of 3 runs per day used"}, budget.Violations(Usage{Runs: 3}, 1))
+	assert.Equal(t, []string{"61.3 of 60 task-minutes per day used"}, budget.Violations(Usage{TaskMinutes: 61.27}, 1))
+	assert.Equal(t, []string{"the run touches 6 agents, more than the 5 allowed per run"}, budget.Violations(Usage{}, 6))
+
+	// Zero limits are unlimited
+	assert.True(t, Budget{}.IsZero())
+	assert.Empty(t, Budget{}.Violations(Usage{Runs: 1000, TaskMinutes: 1e6}, 1000))
+}
+
+func TestBudgetDay(t *testing.T) {
+	loc := time.FixedZone("UTC+1", 3600)
+	day := BudgetDay(time.Date(2026, 10, 17, 23, 59, 0, 0, loc))
+	assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, loc), day)
+}
@@ -0,0 +1,278 @@
+package stack
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Cleanup action outcomes
+const (
+	CleanupPending = "pending"
+	CleanupDone    = "done"
+	CleanupSkipped = "skipped"
+	CleanupFailed  = "failed"
+)
+
+// CleanupAction is the reverse operation that removes one resource
+type CleanupAction struct {
+	Resource    *Resource `json:"resource"`
+	Description string    `json:"description"`
+	// Command is empty for resources that can only be dropped from state
+	Command []string `json:"command,omitempty"`
+	Status  string   `json:"status"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Key identifies the action's resource as type:name, the form used by
+// skip options
+func (a *CleanupAction) Key() string {
+	return a.Resource.Type + ":" + a.Resource.Name
+}
+
+// StateOnly reports whether the resource has no known reverse operation
+func (a *CleanupAction) StateOnly() bool {
+	return len(a.Command) == 0
+}
+
+// CleanupPlan lists the actions that destroy a stack's resources, in the
+// order they must run: dependents before what they depend on, newest first
+type CleanupPlan struct {
+	StackName string           `json:"stack_name"`
+	Actions   []*CleanupAction `json:"actions"`
+}
+
+// cleanupExecCommand is replaced in tests
+var cleanupExecCommand = exec.Command
+
+// lookPath is replaced in tests
+var lookPath = exec.LookPath
+
+// PlanCleanup computes the cleanup plan for a stack's resources.
+// Operation records kept by the state tracker are not resources and are
+// left out; they go away with the stack.
+func PlanCleanup(stackName string, resources []*Resource) *CleanupPlan {
+	var managed []*Resource
+	for _, r := range resources {
+		if r.Module != "state_tracker" {
+			managed = append(managed, r)
+		}
+	}
+
+	plan := &CleanupPlan{StackName: stackName}
+	for _, r := range orderForCleanup(managed) {
+		description, command := reverseOperation(r)
+		plan.Actions = append(plan.Actions, &CleanupAction{
+			Resource:    r,
+			Description: description,
+			Command:     command,
+			Status:      CleanupPending,
+		})
+	}
+	return plan
+}
+
+// Skip marks the actions matching any of the keys as skipped. A key is
+// either type:name for one resource or a bare type for all of that type.
+func (p *CleanupPlan) Skip(keys []string) {
+	for _, a := range p.Actions {
+		for _, key := range keys {
+			if key == a.Key() || key == a.Resource.Type {
+				a.Status = CleanupSkipped
+				break
+			}
+		}
+	}
+}
+
+// Execute runs the pending actions in order. Each resource whose reverse
+// operation succeeds is passed to onDone, e.g. to drop it from the state.
+// A failed action doesn't stop the others.
+func (p *CleanupPlan) Execute(onDone func(*Resource) error) {
+	for _, a := range p.Actions {
+		if a.Status != CleanupPending {
+			continue
+		}
+
+		if !a.StateOnly() {
+			cmd := cleanupExecCommand(a.Command[0], a.Command[1:]...)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				a.Status = CleanupFailed
+				a.Error = strings.TrimSpace(fmt.Sprintf("%v: %s", err, output))
+				continue
+			}
+		}
+
+		if onDone != nil {
+			if err := onDone(a.Resource); err != nil {
+				a.Status = CleanupFailed
+				a.Error = err.Error()
+				continue
+			}
+		}
+		a.Status = CleanupDone
+	}
+}
+
+// Failed returns the actions that failed
+func (p *CleanupPlan) Failed() []*CleanupAction {
+	var failed []*CleanupAction
+	for _, a := range p.Actions {
+		if a.Status == CleanupFailed {
+			failed = append(failed, a)
+		}
+	}
+	return failed
+}
+
+// orderForCleanup sorts resources newest first, then moves every resource
+// ahead of the resources it depends on
+func orderForCleanup(resources []*Resource) []*Resource {
+	ordered := make([]*Resource, len(resources))
+	copy(ordered, resources)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].CreatedAt.After(ordered[j].CreatedAt)
+	})
+
+	byRef := make(map[string]*Resource)
+	for _, r := range ordered {
+		byRef[r.ID] = r
+		byRef[r.Name] = r
+		byRef[r.Type+":"+r.Name] = r
+	}
+
+	// Depth-first over dependents: a resource is emitted only after every
+	// resource that depends on it
+	dependents := make(map[*Resource][]*Resource)
+	for _, r := range ordered {
+		for _, dep := range r.Dependencies {
+			if target, ok := byRef[dep]; ok && target != r {
+				dependents[target] = append(dependents[target], r)
+			}
+		}
+	}
+
+	var result []*Resource
+	visited := make(map[*Resource]bool)
+	var visit func(r *Resource)
+	visit = func(r *Resource) {
+		if visited[r] {
+			return
+		}
+		visited[r] = true
+		for _, d := range dependents[r] {
+			visit(d)
+		}
+		result = append(result, r)
+	}
+	for _, r := range ordered {
+		visit(r)
+	}
+	return result
+}
+
+// reverseOperation returns how to remove a resource. A resource can carry
+// its own command in the destroy_command property; otherwise it is derived
+// from the resource type.
+func reverseOperation(r *Resource) (string, []string) {
+	if custom := propertyString(r, "destroy_command"); custom != "" {
+		return "run destroy_command", []string{"sh", "-c", custom}
+	}
+
+	switch r.Type {
+	case "package":
+		manager := propertyString(r, "manager")
+		if manager == "" {
+			manager = detectPackageManager()
+		}
+		if args := packageRemoveArgs(manager, r.Name); args != nil {
+			if manager != "brew" {
+				args = withSudo(args)
+			}
+			return fmt.Sprintf("remove package %s", r.Name), args
+		}
+		return fmt.Sprintf("remove package %s (no supported package manager found)", r.Name), nil
+
+	case "file", "directory", "template", "symlink":
+		path := propertyString(r, "path")
+		if path == "" {
+			path = r.Name
+		}
+		if r.Type == "directory" {
+			return fmt.Sprintf("delete directory %s", path), withSudo([]string{"rm", "-rf", path})
+		}
+		return fmt.Sprintf("delete file %s", path), withSudo([]string{"rm", "-f", path})
+
+	case "user":
+		args := []string{"userdel"}
+		if propertyString(r, "create_home") != "" {
+			args = append(args, "-r")
+		}
+		return fmt.Sprintf("delete user %s", r.Name), withSudo(append(args, r.Name))
+
+	case "group":
+		return fmt.Sprintf("delete group %s", r.Name), withSudo([]string{"groupdel", r.Name})
+
+	case "container":
+		engine := propertyString(r, "runtime")
+		if engine == "" {
+			engine = r.Module
+		}
+		if engine != "podman" {
+			engine = "docker"
+		}
+		return fmt.Sprintf("remove container %s", r.Name), []string{engine, "rm", "-f", r.Name}
+
+	case "service":
+		return fmt.Sprintf("stop and disable service %s", r.Name), withSudo([]string{"systemctl", "disable", "--now", r.Name})
+	}
+
+	return fmt.Sprintf("forget %s %s (no reverse operation for this type)", r.Type, r.Name), nil
+}
+
+func packageRemoveArgs(manager, name string) []string {
+	switch manager {
+	case "apt", "apt-get":
+		return []string{"apt-get", "remove", "-y", name}
+	case "dnf", "yum", "zypper":
+		return []string{manager, "remove", "-y", name}
+	case "pacman":
+		return []string{"pacman", "-R", "--noconfirm", name}
+	case "apk":
+		return []string{"apk", "del", name}
+	case "brew":
+		return []string{"brew", "uninstall", name}
+	}
+	return nil
+}
+
+func detectPackageManager() string {
+	for _, m := range []string{"apt-get", "dnf", "yum", "zypper", "pacman", "apk", "brew"} {
+		if _, err := lookPath(m); err == nil {
+			return m
+		}
+	}
+	return ""
+}
+
+// withSudo prefixes sudo when not running as root, like the modules that
+// created the resources do
+func withSudo(args []string) []string {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		return args
+	}
+	return append([]string{"sudo"}, args...)
+}
+
+func propertyString(r *Resource, key string) string {
+	if r.Properties == nil {
+		return ""
+	}
+	if v, ok := r.Properties[key]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
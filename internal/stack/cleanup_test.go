@@ -0,0 +1,134 @@
+package stack
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cleanupResource(typ, name string, created time.Time, deps ...string) *Resource {
+	return &Resource{
+		ID:           "stack-1/mod/" + typ + "/" + name,
+		Type:         typ,
+		Name:         name,
+		CreatedAt:    created,
+		Dependencies: deps,
+		Properties:   map[string]interface{}{},
+	}
+}
+
+func TestPlanCleanup_Order(t *testing.T) {
+	base := time.Now()
+	pkg := cleanupResource("package", "nginx", base)
+	conf := cleanupResource("file", "/etc/nginx/nginx.conf", base.Add(time.Second), "package:nginx")
+	svc := cleanupResource("service", "nginx", base.Add(2*time.Second), "/etc/nginx/nginx.conf")
+	// Created last but depended on by the service
+	usr := cleanupResource("user", "www", base.Add(3*time.Second))
+	svc.Dependencies = append(svc.Dependencies, usr.ID)
+
+	op := cleanupResource("workflow_execution", "deploy", base.Add(4*time.Second))
+	op.Module = "state_tracker"
+
+	plan := PlanCleanup("web", []*Resource{pkg, conf, usr, svc, op})
+
+	var got []string
+	for _, a := range plan.Actions {
+		got = append(got, a.Key())
+	}
+	want := "service:nginx user:www file:/etc/nginx/nginx.conf package:nginx"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected order %q, got %q", want, strings.Join(got, " "))
+	}
+}
+
+func TestPlanCleanup_ReverseOperations(t *testing.T) {
+	origLookPath := lookPath
+	lookPath = func(name string) (string, error) {
+		if name == "dnf" {
+			return "/usr/bin/dnf", nil
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() { lookPath = origLookPath })
+
+	now := time.Now()
+	home := cleanupResource("user", "deploy", now)
+	home.Properties["create_home"] = "true"
+	podman := cleanupResource("container", "redis", now)
+	podman.Module = "podman"
+	custom := cleanupResource("custom", "dns-record", now)
+	custom.Properties["destroy_command"] = "dnsctl delete dns-record"
+
+	tests := []struct {
+		resource *Resource
+		command  string
+	}{
+		{cleanupResource("package", "nginx", now), "dnf remove -y nginx"},
+		{cleanupResource("directory", "/opt/app", now), "rm -rf /opt/app"},
+		{home, "userdel -r deploy"},
+		{podman, "podman rm -f redis"},
+		{cleanupResource("container", "web", now), "docker rm -f web"},
+		{custom, "sh -c dnsctl delete dns-record"},
+		{cleanupResource("vpc", "main", now), ""},
+	}
+
+	for _, tt := range tests {
+		_, command := reverseOperation(tt.resource)
+		got := strings.TrimPrefix(strings.Join(command, " "), "sudo ")
+		if got != tt.command {
+			t.Errorf("%s:%s: expected %q, got %q", tt.resource.Type, tt.resource.Name, tt.command, got)
+		}
+	}
+}
+
+func TestCleanupPlan_SkipAndExecute(t *testing.T) {
+	var ran []string
+	orig := cleanupExecCommand
+	cleanupExecCommand = func(name string, args ...string) *exec.Cmd {
+		cmd := strings.TrimPrefix(name+" "+strings.Join(args, " "), "sudo ")
+		ran = append(ran, cmd)
+		if strings.HasPrefix(cmd, "userdel") {
+			return exec.Command("false")
+		}
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { cleanupExecCommand = orig })
+
+	now := time.Now()
+	plan := PlanCleanup("web", []*Resource{
+		cleanupResource("file", "/etc/app.conf", now),
+		cleanupResource("file", "/etc/keep.conf", now),
+		cleanupResource("user", "app", now),
+		cleanupResource("container", "db", now),
+		cleanupResource("vpc", "main", now),
+	})
+	plan.Skip([]string{"file:/etc/keep.conf", "container"})
+
+	var removed []string
+	plan.Execute(func(r *Resource) error {
+		removed = append(removed, r.Type+":"+r.Name)
+		return nil
+	})
+
+	if len(ran) != 2 {
+		t.Errorf("Expected 2 commands, got %v", ran)
+	}
+	if strings.Join(removed, " ") != "file:/etc/app.conf vpc:main" {
+		t.Errorf("Unexpected removed resources: %v", removed)
+	}
+
+	failed := plan.Failed()
+	if len(failed) != 1 || failed[0].Key() != "user:app" || failed[0].Error == "" {
+		t.Errorf("Expected user:app to fail, got %+v", failed)
+	}
+
+	statuses := map[string]string{}
+	for _, a := range plan.Actions {
+		statuses[a.Key()] = a.Status
+	}
+	if statuses["file:/etc/keep.conf"] != CleanupSkipped || statuses["container:db"] != CleanupSkipped {
+		t.Errorf("Expected skipped actions, got %v", statuses)
+	}
+}
@@ -0,0 +1,301 @@
+package stack
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/agent"
+)
+
+// ImportSelection picks what stack import codifies from a host. Packages,
+// services and users are names where * and ? work like in shell globs;
+// files are paths.
+type ImportSelection struct {
+	Packages []string
+	Services []string
+	Users    []string
+	Files    []string
+}
+
+// ImportedHost is the current state of a host, as stack import found it
+type ImportedHost struct {
+	Agent    string
+	Packages []agent.PackageDetail
+	Services []agent.ServiceInfo
+	Users    []agent.UserInfo
+	Files    []ImportedFile
+}
+
+// ImportedFile is a text file read from a host
+type ImportedFile struct {
+	Path    string
+	Mode    string // octal, e.g. 0644
+	Owner   string
+	Group   string
+	Content string
+}
+
+// SelectFacts returns the packages, services and users in the facts of an
+// agent that match sel, sorted by name
+func SelectFacts(agentName string, info *agent.SystemInfo, sel ImportSelection) *ImportedHost {
+	host := &ImportedHost{Agent: agentName}
+	if info == nil {
+		return host
+	}
+	if info.Packages != nil {
+		for _, p := range info.Packages.Packages {
+			if matchesAny(sel.Packages, p.Name) {
+				host.Packages = append(host.Packages, p)
+			}
+		}
+	}
+	for _, s := range info.Services {
+		if matchesAny(sel.Services, s.Name) {
+			host.Services = append(host.Services, s)
+		}
+	}
+	for _, u := range info.Users {
+		if matchesAny(sel.Users, u.Username) {
+			host.Users = append(host.Users, u)
+		}
+	}
+
+	sort.Slice(host.Packages, func(i, j int) bool { return host.Packages[i].Name < host.Packages[j].Name })
+	sort.Slice(host.Services, func(i, j int) bool { return host.Services[i].Name < host.Services[j].Name })
+	sort.Slice(host.Users, func(i, j int) bool { return host.Users[i].Username < host.Users[j].Username })
+	return host
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateImportSelection checks the patterns of sel
+func ValidateImportSelection(sel ImportSelection) error {
+	for _, patterns := range [][]string{sel.Packages, sel.Services, sel.Users} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	for _, file := range sel.Files {
+		if !strings.HasPrefix(file, "/") {
+			return fmt.Errorf("file %q must be an absolute path", file)
+		}
+	}
+	return nil
+}
+
+// serviceRunning reports whether the imported state of s is running
+func serviceRunning(s agent.ServiceInfo) bool {
+	return s.State == "active"
+}
+
+// ImportedResources returns the stack resources recording the state of
+// host, as the workflow GenerateImportWorkflow writes for it would
+// register them
+func ImportedResources(stackID string, host *ImportedHost) []*Resource {
+	now := time.Now()
+	metadata := func() map[string]interface{} {
+		return map[string]interface{}{"imported_from": host.Agent, "imported_at": now.Format(time.RFC3339)}
+	}
+	newResource := func(module, resourceType, name string, properties map[string]interface{}) *Resource {
+		propsJSON, _ := json.Marshal(properties)
+		return &Resource{
+			ID:          fmt.Sprintf("%s/%s/%s", stackID, module, resourceType+"/"+name),
+			StackID:     stackID,
+			Type:        resourceType,
+			Name:        name,
+			Module:      module,
+			Properties:  properties,
+			State:       "applied",
+			Checksum:    fmt.Sprintf("%x", sha256.Sum256(propsJSON)),
+			LastApplied: &now,
+			Metadata:    metadata(),
+		}
+	}
+
+	var resources []*Resource
+	for _, p := range host.Packages {
+		resources = append(resources, newResource("pkg", "package", p.Name, map[string]interface{}{
+			"name":    p.Name,
+			"version": p.Version,
+		}))
+	}
+	for _, s := range host.Services {
+		state := "stopped"
+		if serviceRunning(s) {
+			state = "running"
+		}
+		resources = append(resources, newResource("systemd", "service", s.Name, map[string]interface{}{
+			"name":  s.Name,
+			"state": state,
+		}))
+	}
+	// Users match what the user module registers for user.create with
+	// the options the workflow passes
+	for _, u := range host.Users {
+		resources = append(resources, newResource("user", "user", u.Username, map[string]interface{}{
+			"username": u.Username,
+			"uid":      u.UID,
+			"home":     u.Home,
+			"shell":    u.Shell,
+		}))
+	}
+	for _, f := range host.Files {
+		resources = append(resources, newResource("fs", "file", f.Path, map[string]interface{}{
+			"path":   f.Path,
+			"mode":   f.Mode,
+			"owner":  f.Owner,
+			"group":  f.Group,
+			"sha256": fmt.Sprintf("%x", sha256.Sum256([]byte(f.Content))),
+		}))
+	}
+	return resources
+}
+
+// GenerateImportWorkflow writes a workflow that keeps host as it was found:
+// the packages installed, the services running or stopped, the users and
+// the files with their content, owner and mode. Every task is delegated to
+// the agent.
+func GenerateImportWorkflow(name string, host *ImportedHost) string {
+	var b strings.Builder
+	modules := []string{}
+	if len(host.Packages) > 0 {
+		modules = append(modules, "pkg")
+	}
+	if len(host.Services) > 0 {
+		modules = append(modules, "systemd")
+	}
+	if len(host.Users) > 0 {
+		modules = append(modules, "user")
+	}
+	if len(host.Files) > 0 {
+		modules = append(modules, "fs", "exec")
+	}
+
+	fmt.Fprintf(&b, "---\nname: %s\ndescription: %s\n", name, yamlString("State of "+host.Agent+" imported by sloth-runner stack import"))
+	if len(modules) > 0 {
+		fmt.Fprintf(&b, "modules: [%s]\n", strings.Join(modules, ", "))
+	}
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "-- Imported from agent %s. Review it before running it: it describes\n", host.Agent)
+	b.WriteString("-- the host as it was, not necessarily as it should be.\n")
+
+	var tasks []string
+	writeTask := func(taskName, description, body string) {
+		tasks = append(tasks, taskName)
+		fmt.Fprintf(&b, "\nlocal %s = task(%s)\n", taskName, luaString(taskName))
+		fmt.Fprintf(&b, "    :description(%s)\n", luaString(description))
+		fmt.Fprintf(&b, "    :delegate_to(%s)\n", luaString(host.Agent))
+		b.WriteString("    :command(function(this, params)\n")
+		b.WriteString(body)
+		b.WriteString("    end)\n    :build()\n")
+	}
+
+	if len(host.Packages) > 0 {
+		var body strings.Builder
+		body.WriteString("        local ok, out = pkg.install({packages = {\n")
+		for _, p := range host.Packages {
+			fmt.Fprintf(&body, "            %s, -- %s\n", luaString(p.Name), p.Version)
+		}
+		body.WriteString("        }})\n")
+		body.WriteString("        if not ok then\n            return false, \"failed to install packages: \" .. tostring(out)\n        end\n")
+		body.WriteString("        return true, \"packages installed\"\n")
+		writeTask("packages", "Install the imported packages", body.String())
+	}
+
+	if len(host.Users) > 0 {
+		var body strings.Builder
+		for _, u := range host.Users {
+			fmt.Fprintf(&body, "        local ok, msg = user.create({username = %s, uid = %s, home = %s, shell = %s})\n",
+				luaString(u.Username), luaString(u.UID), luaString(u.Home), luaString(u.Shell))
+			fmt.Fprintf(&body, "        if not ok then\n            return false, \"user %s: \" .. tostring(msg)\n        end\n", luaEscape(u.Username))
+		}
+		body.WriteString("        return true, \"users present\"\n")
+		writeTask("users", "Create the imported users", body.String())
+	}
+
+	if len(host.Files) > 0 {
+		var body strings.Builder
+		body.WriteString("        -- Writes path when its content differs, then sets its owner and mode\n")
+		body.WriteString("        local function put(path, content, fix)\n")
+		body.WriteString("            if not fs.exists(path) or fs.read(path) ~= content then\n")
+		body.WriteString("                local ok, err = fs.write(path, content)\n")
+		body.WriteString("                if not ok then\n                    return false, err\n                end\n            end\n")
+		body.WriteString("            local result = exec.run(fix)\n")
+		body.WriteString("            if not result.success then\n                return false, result.stderr\n            end\n")
+		body.WriteString("            return true\n        end\n")
+		for _, f := range host.Files {
+			fix := fmt.Sprintf("chown %s %s && chmod %s %s",
+				shellQuote(f.Owner+":"+f.Group), shellQuote(f.Path), f.Mode, shellQuote(f.Path))
+			fmt.Fprintf(&body, "\n        local ok, err = put(%s, %s, %s)\n", luaString(f.Path), luaLongString(f.Content), luaString(fix))
+			fmt.Fprintf(&body, "        if not ok then\n            return false, \"%s: \" .. tostring(err)\n        end\n", luaEscape(f.Path))
+		}
+		body.WriteString("        return true, \"files in place\"\n")
+		writeTask("files", "Write the imported files", body.String())
+	}
+
+	if len(host.Services) > 0 {
+		var body strings.Builder
+		for _, s := range host.Services {
+			fn := "stop"
+			if serviceRunning(s) {
+				fn = "start"
+			}
+			fmt.Fprintf(&body, "        local ok, msg = systemd.%s({name = %s})\n", fn, luaString(s.Name))
+			fmt.Fprintf(&body, "        if not ok then\n            return false, \"service %s: \" .. tostring(msg)\n        end\n", luaEscape(s.Name))
+		}
+		body.WriteString("        return true, \"services in their imported state\"\n")
+		// Last, as services usually need their packages, users and files
+		writeTask("services", "Start or stop the imported services", body.String())
+	}
+
+	fmt.Fprintf(&b, "\nworkflow.define(%s, {\n", luaString(name))
+	fmt.Fprintf(&b, "    description = %s,\n", luaString("State of "+host.Agent+" imported by sloth-runner stack import"))
+	fmt.Fprintf(&b, "    tasks = {%s},\n", strings.Join(tasks, ", "))
+	b.WriteString("})\n")
+	return b.String()
+}
+
+// luaString returns s as a Lua string literal
+func luaString(s string) string {
+	return `"` + luaEscape(s) + `"`
+}
+
+func luaEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return r.Replace(s)
+}
+
+// luaLongString returns s as a Lua long string literal. The newline after
+// the opening bracket is dropped by Lua, so s is kept byte for byte.
+func luaLongString(s string) string {
+	level := ""
+	for strings.Contains(s, "]"+level+"]") {
+		level += "="
+	}
+	return "[" + level + "[\n" + s + "]" + level + "]"
+}
+
+// yamlString returns s as a double-quoted YAML scalar
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,131 @@
+package stack
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+func importFacts() *agent.SystemInfo {
+	return &agent.SystemInfo{
+		Packages: &agent.PackageInfo{Manager: "apt", Packages: []agent.PackageDetail{
+			{Name: "nginx", Version: "1.24.0"},
+			{Name: "curl", Version: "8.5.0"},
+			{Name: "vim", Version: "9.1"},
+		}},
+		Services: []agent.ServiceInfo{
+			{Name: "nginx", State: "active"},
+			{Name: "cron", State: "inactive"},
+		},
+		Users: []agent.UserInfo{
+			{Username: "deploy", UID: "1001", Home: "/home/deploy", Shell: "/bin/bash"},
+			{Username: "root", UID: "0", Home: "/root", Shell: "/bin/bash"},
+		},
+	}
+}
+
+func TestSelectFacts(t *testing.T) {
+	host := SelectFacts("web-1", importFacts(), ImportSelection{
+		Packages: []string{"nginx", "c*"},
+		Services: []string{"*"},
+		Users:    []string{"deploy"},
+	})
+
+	var packages []string
+	for _, p := range host.Packages {
+		packages = append(packages, p.Name)
+	}
+	if got := strings.Join(packages, " "); got != "curl nginx" {
+		t.Errorf("packages = %q, want %q", got, "curl nginx")
+	}
+	if len(host.Services) != 2 || host.Services[0].Name != "cron" {
+		t.Errorf("services = %+v, want cron and nginx", host.Services)
+	}
+	if len(host.Users) != 1 || host.Users[0].Username != "deploy" {
+		t.Errorf("users = %+v, want deploy", host.Users)
+	}
+}
+
+func TestValidateImportSelection(t *testing.T) {
+	if err := ValidateImportSelection(ImportSelection{Packages: []string{"[a"}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if err := ValidateImportSelection(ImportSelection{Files: []string{"etc/hosts"}}); err == nil {
+		t.Error("expected an error for a relative file")
+	}
+	if err := ValidateImportSelection(ImportSelection{Packages: []string{"lib*"}, Files: []string{"/etc/hosts"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGenerateImportWorkflow(t *testing.T) {
+	host := SelectFacts("web-1", importFacts(), ImportSelection{
+		Packages: []string{"nginx"},
+		Services: []string{"*"},
+		Users:    []string{"deploy"},
+	})
+	host.Files = []ImportedFile{{
+		Path:    "/etc/motd",
+		Mode:    "0644",
+		Owner:   "root",
+		Group:   "root",
+		Content: "say \"hi\"\nlist[t[1]]\n]]\n",
+	}}
+
+	workflow := GenerateImportWorkflow("web-1-imported", host)
+
+	parts := strings.SplitN(workflow, "---\n", 3)
+	if len(parts) != 3 || !strings.Contains(parts[1], "modules: [pkg, systemd, user, fs, exec]") {
+		t.Fatalf("unexpected front matter:\n%s", workflow)
+	}
+	if _, err := parse.Parse(strings.NewReader(parts[2]), "import.sloth"); err != nil {
+		t.Fatalf("generated workflow is not valid Lua: %v\n%s", err, workflow)
+	}
+
+	for _, want := range []string{
+		`:delegate_to("web-1")`,
+		`"nginx", -- 1.24.0`,
+		`systemd.start({name = "nginx"})`,
+		`systemd.stop({name = "cron"})`,
+		`user.create({username = "deploy", uid = "1001", home = "/home/deploy", shell = "/bin/bash"})`,
+		"[=[\nsay \"hi\"\nlist[t[1]]\n]]\n]=]",
+		"tasks = {packages, users, files, services}",
+	} {
+		if !strings.Contains(workflow, want) {
+			t.Errorf("workflow is missing %q:\n%s", want, workflow)
+		}
+	}
+}
+
+func TestImportedResources(t *testing.T) {
+	host := SelectFacts("web-1", importFacts(), ImportSelection{
+		Services: []string{"nginx"},
+		Users:    []string{"deploy"},
+	})
+	resources := ImportedResources("stack-1", host)
+	if len(resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(resources))
+	}
+
+	svc := resources[0]
+	if svc.ID != "stack-1/systemd/service/nginx" || svc.State != "applied" || svc.Properties["state"] != "running" {
+		t.Errorf("unexpected service resource: %+v", svc)
+	}
+	if svc.Metadata["imported_from"] != "web-1" {
+		t.Errorf("imported_from = %v, want web-1", svc.Metadata["imported_from"])
+	}
+
+	// The user module registers the options of user.create plus the
+	// username, so a run of the workflow finds the user unchanged
+	usr := resources[1]
+	props := map[string]interface{}{"username": "deploy", "uid": "1001", "home": "/home/deploy", "shell": "/bin/bash"}
+	data, _ := json.Marshal(props)
+	if want := fmt.Sprintf("%x", sha256.Sum256(data)); usr.Checksum != want {
+		t.Errorf("user checksum = %s, want %s", usr.Checksum, want)
+	}
+}
@@ -3,15 +3,20 @@ package stack
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
+// ErrStateLocked is returned when a stack's state is locked by another
+// holder
+var ErrStateLocked = errors.New("state is already locked")
+
 // StackState represents the state of a workflow stack
 type StackState struct {
 	ID              string                 `json:"id"`
@@ -52,7 +57,7 @@ func NewStackManager(dbPath string) (*StackManager, error) {
 		return nil, fmt.Errorf("failed to create stack directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_foreign_keys=on")
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{ForeignKeys: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -797,5 +802,5 @@ func (sm *StackManager) Close() error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	return sm.db.Close()
+	return sqlitedb.Close(sm.db)
 }
\ No newline at end of file
@@ -139,6 +139,17 @@ func (sb *StateBackend) initExtendedSchema() error {
 		FOREIGN KEY (stack_id) REFERENCES stacks(id) ON DELETE CASCADE
 	);
 
+	-- Stack Usage Table (per-run consumption, counted against budgets)
+	CREATE TABLE IF NOT EXISTS stack_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		stack_id TEXT NOT NULL,
+		run_id TEXT,
+		started_at INTEGER NOT NULL, -- Unix seconds
+		task_minutes REAL NOT NULL DEFAULT 0,
+		agents INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (stack_id) REFERENCES stacks(id) ON DELETE CASCADE
+	);
+
 	-- Indexes for performance
 	CREATE INDEX IF NOT EXISTS idx_state_versions_stack ON state_versions(stack_id);
 	CREATE INDEX IF NOT EXISTS idx_state_versions_version ON state_versions(stack_id, version);
@@ -155,6 +166,7 @@ func (sb *StateBackend) initExtendedSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_state_events_type ON state_events(event_type);
 	CREATE INDEX IF NOT EXISTS idx_state_events_time ON state_events(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_state_events_severity ON state_events(severity);
+	CREATE INDEX IF NOT EXISTS idx_stack_usage_stack ON stack_usage(stack_id, started_at);
 	`
 
 	_, err := sb.sm.db.Exec(schema)
@@ -198,6 +210,7 @@ type StateLock struct {
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 	Info      string    `json:"info"`
+	StackName string    `json:"stack_name,omitempty"`
 }
 
 // CreateSnapshot creates a new state snapshot (version)
@@ -426,6 +439,22 @@ func (sb *StateBackend) DetectDrift(stackID, resourceID string, expectedState, a
 	return nil
 }
 
+// ResolveDrift marks the pending drift of a resource resolved
+func (sb *StateBackend) ResolveDrift(stackID, resourceID string) error {
+	sb.sm.mu.Lock()
+	defer sb.sm.mu.Unlock()
+
+	_, err := sb.sm.db.Exec(`
+		UPDATE drift_detections
+		SET resolution_status = 'resolved'
+		WHERE stack_id = ? AND resource_id = ? AND resolution_status = 'pending'
+	`, stackID, resourceID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve drift: %w", err)
+	}
+	return nil
+}
+
 // GetDriftInfo retrieves drift information for a stack
 func (sb *StateBackend) GetDriftInfo(stackID string) ([]*DriftInfo, error) {
 	sb.sm.mu.RLock()
@@ -467,36 +496,47 @@ func (sb *StateBackend) GetDriftInfo(stackID string) ([]*DriftInfo, error) {
 	return drifts, nil
 }
 
-// LockState acquires a lock on the state
+// LockState acquires a lock on the state. A lock whose holder didn't
+// renew it before it expired, e.g. because it crashed, is taken over, and
+// so is one with the same lockID. Other locks fail with ErrStateLocked.
 func (sb *StateBackend) LockState(stackID, lockID, operation, who string, duration time.Duration) error {
 	sb.sm.mu.Lock()
 	defer sb.sm.mu.Unlock()
 
-	// Check if already locked (use UTC time comparison)
-	var existingWho string
+	var existingID, existingWho string
 	var expiresAt time.Time
 	err := sb.sm.db.QueryRow(`
-		SELECT who, expires_at FROM state_locks
+		SELECT lock_id, who, expires_at FROM state_locks
 		WHERE stack_id = ?
-	`, stackID).Scan(&existingWho, &expiresAt)
+	`, stackID).Scan(&existingID, &existingWho, &expiresAt)
 
 	if err == nil {
 		// Lock exists, check if it's still valid
-		if time.Now().Before(expiresAt) {
-			return fmt.Errorf("state is already locked by %s", existingWho)
+		if existingID != lockID && time.Now().Before(expiresAt) {
+			return fmt.Errorf("%w by %s", ErrStateLocked, existingWho)
+		}
+		// Only remove the lock that was read: another process may have
+		// replaced it since
+		if _, err := sb.sm.db.Exec(`
+			DELETE FROM state_locks WHERE stack_id = ? AND lock_id = ?
+		`, stackID, existingID); err != nil {
+			return fmt.Errorf("failed to remove expired lock: %w", err)
 		}
-		// Lock is expired, we can replace it
 	}
 
 	expiresAtTime := time.Now().Add(duration)
 
-	// Use REPLACE to handle both insert and update
+	// The primary key makes the insert fail if another process locked the
+	// state in the meantime
 	_, err = sb.sm.db.Exec(`
-		REPLACE INTO state_locks (stack_id, lock_id, operation, who, created_at, expires_at, info)
+		INSERT INTO state_locks (stack_id, lock_id, operation, who, created_at, expires_at, info)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`, stackID, lockID, operation, who, time.Now(), expiresAtTime, "")
 
 	if err != nil {
+		if sb.sm.db.QueryRow(`SELECT who FROM state_locks WHERE stack_id = ?`, stackID).Scan(&existingWho) == nil {
+			return fmt.Errorf("%w by %s", ErrStateLocked, existingWho)
+		}
 		return fmt.Errorf("failed to acquire lock: %w", err)
 	}
 
@@ -529,6 +569,60 @@ func (sb *StateBackend) UnlockState(stackID, lockID string) error {
 	return nil
 }
 
+// RenewLock extends a lock its holder still holds by duration from now. It
+// fails if the lock expired and was taken over, or was released.
+func (sb *StateBackend) RenewLock(stackID, lockID string, duration time.Duration) error {
+	sb.sm.mu.Lock()
+	defer sb.sm.mu.Unlock()
+
+	result, err := sb.sm.db.Exec(`
+		UPDATE state_locks SET expires_at = ?
+		WHERE stack_id = ? AND lock_id = ?
+	`, time.Now().Add(duration), stackID, lockID)
+	if err != nil {
+		return fmt.Errorf("failed to renew lock: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return fmt.Errorf("lock not found or already released")
+	}
+	return nil
+}
+
+// ListLocks returns the locks that haven't expired, with the names of
+// their stacks
+func (sb *StateBackend) ListLocks() ([]*StateLock, error) {
+	sb.sm.mu.RLock()
+	defer sb.sm.mu.RUnlock()
+
+	rows, err := sb.sm.db.Query(`
+		SELECT l.stack_id, l.lock_id, l.operation, l.who, l.created_at, l.expires_at,
+			COALESCE(l.info, ''), COALESCE(s.name, '')
+		FROM state_locks l
+		LEFT JOIN stacks s ON s.id = l.stack_id
+		ORDER BY l.created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locks: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var locks []*StateLock
+	for rows.Next() {
+		var lock StateLock
+		if err := rows.Scan(&lock.StackID, &lock.LockID, &lock.Operation, &lock.Who, &lock.CreatedAt, &lock.ExpiresAt, &lock.Info, &lock.StackName); err != nil {
+			return nil, fmt.Errorf("failed to scan lock: %w", err)
+		}
+		if now.After(lock.ExpiresAt) {
+			continue
+		}
+		locks = append(locks, &lock)
+	}
+	return locks, rows.Err()
+}
+
 // GetLockInfo retrieves lock information for a stack
 func (sb *StateBackend) GetLockInfo(stackID string) (*StateLock, error) {
 	sb.sm.mu.RLock()
@@ -669,6 +763,20 @@ func (sb *StateBackend) logActivity(stackID, activityType, resourceID, details,
 	`, stackID, activityType, resourceID, string(detailsJSON), user)
 }
 
+// RecordActivity adds an entry to a stack's activity log
+func (sb *StateBackend) RecordActivity(stackID, activityType, resourceID, details, user string) error {
+	detailsJSON, _ := json.Marshal(map[string]string{"message": details})
+
+	_, err := sb.sm.db.Exec(`
+		INSERT INTO state_activity (stack_id, activity_type, resource_id, details, user)
+		VALUES (?, ?, ?, ?, ?)
+	`, stackID, activityType, resourceID, string(detailsJSON), user)
+	if err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
 // GetActivity retrieves activity log for a stack
 func (sb *StateBackend) GetActivity(stackID string, limit int) ([]map[string]interface{}, error) {
 	sb.sm.mu.RLock()
@@ -714,6 +822,78 @@ func (sb *StateBackend) GetActivity(stackID string, limit int) ([]map[string]int
 	return activities, nil
 }
 
+// ActivityEntry is an entry of the activity log of a stack
+type ActivityEntry struct {
+	ID         int64
+	StackID    string
+	StackName  string
+	Type       string
+	ResourceID string
+	Details    string
+	User       string
+	CreatedAt  time.Time
+}
+
+// ListActivityAfter returns the activity of every stack recorded after the
+// entry with ID after, oldest first, so exports can resume where they
+// stopped
+func (sb *StateBackend) ListActivityAfter(after int64, limit int) ([]ActivityEntry, error) {
+	sb.sm.mu.RLock()
+	defer sb.sm.mu.RUnlock()
+
+	rows, err := sb.sm.db.Query(`
+		SELECT a.id, a.stack_id, COALESCE(s.name, ''), a.activity_type, COALESCE(a.resource_id, ''),
+			COALESCE(a.details, ''), COALESCE(a.user, ''), a.created_at
+		FROM state_activity a
+		LEFT JOIN stacks s ON s.id = a.stack_id
+		WHERE a.id > ?
+		ORDER BY a.id ASC LIMIT ?
+	`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ActivityEntry
+	for rows.Next() {
+		var e ActivityEntry
+		if err := rows.Scan(&e.ID, &e.StackID, &e.StackName, &e.Type, &e.ResourceID, &e.Details, &e.User, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// RecordUsage records what a run of a stack consumed
+func (sb *StateBackend) RecordUsage(stackID string, usage RunUsage) error {
+	_, err := sb.sm.db.Exec(`
+		INSERT INTO stack_usage (stack_id, run_id, started_at, task_minutes, agents)
+		VALUES (?, ?, ?, ?, ?)
+	`, stackID, usage.RunID, usage.StartedAt.Unix(), usage.TaskMinutes, usage.Agents)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsage sums what the runs of a stack started since since consumed
+func (sb *StateBackend) GetUsage(stackID string, since time.Time) (Usage, error) {
+	sb.sm.mu.RLock()
+	defer sb.sm.mu.RUnlock()
+
+	usage := Usage{Since: since}
+	err := sb.sm.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(task_minutes), 0), COALESCE(MAX(agents), 0)
+		FROM stack_usage
+		WHERE stack_id = ? AND started_at >= ?
+	`, stackID, since.Unix()).Scan(&usage.Runs, &usage.TaskMinutes, &usage.MaxAgents)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return usage, nil
+}
+
 // GetStackManager returns the underlying stack manager
 func (sb *StateBackend) GetStackManager() *StackManager {
 	return sb.sm
@@ -41,6 +41,7 @@ type StateLock struct {
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 	Info      string    `json:"info"`
+	StackName string    `json:"stack_name,omitempty"`
 }
 
 // NewStateBackend returns an error for non-CGO builds
@@ -83,6 +84,11 @@ func (sb *StateBackend) DetectDrift(stackID, resourceID string, expectedState, a
 	return fmt.Errorf("state backend not available in non-CGO builds")
 }
 
+// ResolveDrift stub
+func (sb *StateBackend) ResolveDrift(stackID, resourceID string) error {
+	return fmt.Errorf("state backend not available in non-CGO builds")
+}
+
 // GetDriftInfo stub
 func (sb *StateBackend) GetDriftInfo(stackID string) ([]*DriftInfo, error) {
 	return nil, fmt.Errorf("state backend not available in non-CGO builds")
@@ -98,6 +104,16 @@ func (sb *StateBackend) UnlockState(stackID, lockID string) error {
 	return fmt.Errorf("state backend not available in non-CGO builds")
 }
 
+// RenewLock stub
+func (sb *StateBackend) RenewLock(stackID, lockID string, duration time.Duration) error {
+	return fmt.Errorf("state backend not available in non-CGO builds")
+}
+
+// ListLocks stub
+func (sb *StateBackend) ListLocks() ([]*StateLock, error) {
+	return nil, fmt.Errorf("state backend not available in non-CGO builds")
+}
+
 // GetLockInfo stub
 func (sb *StateBackend) GetLockInfo(stackID string) (*StateLock, error) {
 	return nil, fmt.Errorf("state backend not available in non-CGO builds")
@@ -128,7 +144,39 @@ func (sb *StateBackend) GetResourceDependencies(resourceID string) ([]string, er
 	return nil, fmt.Errorf("state backend not available in non-CGO builds")
 }
 
+// RecordActivity stub
+func (sb *StateBackend) RecordActivity(stackID, activityType, resourceID, details, user string) error {
+	return fmt.Errorf("state backend not available in non-CGO builds")
+}
+
 // GetActivity stub
 func (sb *StateBackend) GetActivity(stackID string, limit int) ([]map[string]interface{}, error) {
 	return nil, fmt.Errorf("state backend not available in non-CGO builds")
 }
+
+// ActivityEntry is an entry of the activity log of a stack
+type ActivityEntry struct {
+	ID         int64
+	StackID    string
+	StackName  string
+	Type       string
+	ResourceID string
+	Details    string
+	User       string
+	CreatedAt  time.Time
+}
+
+// ListActivityAfter stub
+func (sb *StateBackend) ListActivityAfter(after int64, limit int) ([]ActivityEntry, error) {
+	return nil, fmt.Errorf("state backend not available in non-CGO builds")
+}
+
+// RecordUsage stub
+func (sb *StateBackend) RecordUsage(stackID string, usage RunUsage) error {
+	return fmt.Errorf("state backend not available in non-CGO builds")
+}
+
+// GetUsage stub
+func (sb *StateBackend) GetUsage(stackID string, since time.Time) (Usage, error) {
+	return Usage{}, fmt.Errorf("state backend not available in non-CGO builds")
+}
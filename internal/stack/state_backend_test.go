@@ -4,6 +4,7 @@
 package stack
 
 import (
+	"errors"
 	"path/filepath"
 	"testing"
 	"time"
@@ -207,6 +208,67 @@ func TestStateBackend_StateLock(t *testing.T) {
 	}
 }
 
+func TestStateBackend_StateLockTTL(t *testing.T) {
+	tmpDir := t.TempDir()
+	backend, err := NewStateBackend(filepath.Join(tmpDir, "test_lock_ttl.db"))
+	if err != nil {
+		t.Fatalf("Failed to create state backend: %v", err)
+	}
+	defer backend.Close()
+
+	stackID := uuid.New().String()
+	stack := &StackState{
+		ID:            stackID,
+		Name:          "lock-ttl-stack",
+		Status:        "created",
+		TaskResults:   make(map[string]interface{}),
+		Outputs:       make(map[string]interface{}),
+		Configuration: make(map[string]interface{}),
+		Metadata:      make(map[string]interface{}),
+	}
+	if err := backend.GetStackManager().CreateStack(stack); err != nil {
+		t.Fatalf("Failed to create stack: %v", err)
+	}
+
+	// A crashed run leaves a lock that expires
+	if err := backend.LockState(stackID, "run-1", "run deploy", "alice", 50*time.Millisecond); err != nil {
+		t.Fatalf("Failed to lock state: %v", err)
+	}
+	err = backend.LockState(stackID, "run-2", "run deploy", "bob", time.Minute)
+	if !errors.Is(err, ErrStateLocked) {
+		t.Fatalf("Expected ErrStateLocked, got %v", err)
+	}
+
+	locks, err := backend.ListLocks()
+	if err != nil {
+		t.Fatalf("Failed to list locks: %v", err)
+	}
+	if len(locks) != 1 || locks[0].StackName != "lock-ttl-stack" || locks[0].Who != "alice" {
+		t.Fatalf("Expected the lock of alice on lock-ttl-stack, got %+v", locks)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if locks, _ := backend.ListLocks(); len(locks) != 0 {
+		t.Errorf("Expected expired locks not to be listed, got %d", len(locks))
+	}
+	if err := backend.LockState(stackID, "run-2", "run deploy", "bob", time.Minute); err != nil {
+		t.Fatalf("Expected the expired lock to be taken over: %v", err)
+	}
+
+	// The crashed run lost its lock, so it can't renew it
+	if err := backend.RenewLock(stackID, "run-1", time.Minute); err == nil {
+		t.Error("Expected renewing a lock taken over to fail")
+	}
+	if err := backend.RenewLock(stackID, "run-2", time.Minute); err != nil {
+		t.Errorf("Failed to renew lock: %v", err)
+	}
+
+	// The same lock ID takes over its own lock, e.g. to resume a run
+	if err := backend.LockState(stackID, "run-2", "run deploy", "bob", time.Minute); err != nil {
+		t.Errorf("Expected the holder to lock again: %v", err)
+	}
+}
+
 func TestStateBackend_Rollback(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test_rollback.db")
@@ -502,3 +564,103 @@ func BenchmarkStateBackend_CreateSnapshot(b *testing.B) {
 		backend.CreateSnapshot(stackID, "bench-user", "Benchmark snapshot")
 	}
 }
+
+func TestStateBackend_Usage(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_usage.db")
+
+	backend, err := NewStateBackend(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create state backend: %v", err)
+	}
+	defer backend.Close()
+
+	stackID := uuid.New().String()
+	stack := &StackState{
+		ID:            stackID,
+		Name:          "usage-test-stack",
+		Version:       "1.0.0",
+		Status:        "created",
+		TaskResults:   make(map[string]interface{}),
+		Outputs:       make(map[string]interface{}),
+		Configuration: make(map[string]interface{}),
+		Metadata:      make(map[string]interface{}),
+	}
+	if err := backend.GetStackManager().CreateStack(stack); err != nil {
+		t.Fatalf("Failed to create stack: %v", err)
+	}
+
+	day := BudgetDay(time.Now())
+	runs := []RunUsage{
+		{RunID: "yesterday", StartedAt: day.Add(-time.Hour), TaskMinutes: 50, Agents: 9},
+		{RunID: "a", StartedAt: day.Add(time.Minute), TaskMinutes: 1.5, Agents: 2},
+		{RunID: "b", StartedAt: day.Add(2 * time.Minute), TaskMinutes: 3, Agents: 4},
+	}
+	for _, run := range runs {
+		if err := backend.RecordUsage(stackID, run); err != nil {
+			t.Fatalf("Failed to record usage: %v", err)
+		}
+	}
+
+	usage, err := backend.GetUsage(stackID, day)
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.Runs != 2 || usage.TaskMinutes != 4.5 || usage.MaxAgents != 4 {
+		t.Errorf("Expected 2 runs, 4.5 task-minutes and 4 agents today, got %+v", usage)
+	}
+}
+
+func TestStateBackend_ListActivityAfter(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_activity_after.db")
+
+	backend, err := NewStateBackend(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create state backend: %v", err)
+	}
+	defer backend.Close()
+
+	stackID := uuid.New().String()
+	stack := &StackState{
+		ID:            stackID,
+		Name:          "activity-test-stack",
+		Version:       "1.0.0",
+		Status:        "created",
+		TaskResults:   make(map[string]interface{}),
+		Outputs:       make(map[string]interface{}),
+		Configuration: make(map[string]interface{}),
+		Metadata:      make(map[string]interface{}),
+	}
+	if err := backend.GetStackManager().CreateStack(stack); err != nil {
+		t.Fatalf("Failed to create stack: %v", err)
+	}
+
+	for _, activity := range []string{"lock", "budget_override", "unlock"} {
+		if err := backend.RecordActivity(stackID, activity, "", activity+" details", "alice"); err != nil {
+			t.Fatalf("Failed to record activity: %v", err)
+		}
+	}
+
+	entries, err := backend.ListActivityAfter(0, 2)
+	if err != nil {
+		t.Fatalf("Failed to list activity: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Type != "lock" || entries[1].Type != "budget_override" {
+		t.Errorf("Expected the oldest entries first, got %s and %s", entries[0].Type, entries[1].Type)
+	}
+	if entries[0].StackName != "activity-test-stack" || entries[0].User != "alice" {
+		t.Errorf("Expected the stack name and user, got %+v", entries[0])
+	}
+
+	rest, err := backend.ListActivityAfter(entries[1].ID, 10)
+	if err != nil {
+		t.Fatalf("Failed to list activity: %v", err)
+	}
+	if len(rest) != 1 || rest[0].Type != "unlock" {
+		t.Errorf("Expected only the last entry after the cursor, got %+v", rest)
+	}
+}
@@ -13,7 +13,7 @@ import (
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 )
 
 // StateManager manages persistent state
@@ -38,7 +38,7 @@ func NewStateManager(dbPath string) (*StateManager, error) {
 		return nil, fmt.Errorf("failed to create state directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dbPath+"?_journal_mode=WAL&_foreign_keys=on")
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{ForeignKeys: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -230,7 +230,7 @@ func (sm *StateManager) IsLocked(name string) (bool, string, error) {
 // Close closes the database connection
 func (sm *StateManager) Close() error {
 	if sm.db != nil {
-		return sm.db.Close()
+		return sqlitedb.Close(sm.db)
 	}
 	return nil
 }
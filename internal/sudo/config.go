@@ -0,0 +1,93 @@
+package sudo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config names the agents whose sudo needs a password. Runs delegating
+// tasks to one of them prompt for it.
+type Config struct {
+	// Agents are agent names or addresses; * and ? work like in shell globs
+	Agents []string `yaml:"agents"`
+	// Groups are agent groups whose members need a password
+	Groups []string `yaml:"groups"`
+}
+
+// GroupMembersFunc returns the agents of a group
+type GroupMembersFunc func(group string) ([]string, error)
+
+// LoadConfig reads a sudo config file. It returns nil without an error
+// when the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sudo config: %w", err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sudo config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates the YAML of a sudo config file
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	for _, pattern := range config.Agents {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("agent pattern %q: %w", pattern, err)
+		}
+	}
+	return &config, nil
+}
+
+// Matches reports whether agent needs a password. members is only asked
+// for the groups when no agent pattern matches.
+func (c *Config) Matches(agent string, members GroupMembersFunc) (bool, error) {
+	for _, pattern := range c.Agents {
+		if ok, _ := path.Match(pattern, agent); ok {
+			return true, nil
+		}
+	}
+	for _, group := range c.Groups {
+		agents, err := members(group)
+		if err != nil {
+			return false, fmt.Errorf("failed to get agents of group %s: %w", group, err)
+		}
+		for _, a := range agents {
+			if a == agent {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ReadPassword reads a password piped in on r, up to the end of its first
+// line
+func ReadPassword(r io.Reader) ([]byte, error) {
+	line, err := bufio.NewReader(r).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	if len(line) == 0 {
+		return nil, errors.New("no sudo password given on stdin")
+	}
+	return line, nil
+}
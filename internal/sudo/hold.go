@@ -0,0 +1,136 @@
+package sudo
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session serves the sudo password of a run to the run's commands
+// through an askpass helper. Each run has its own helper, so runs going on
+// at once don't see each other's password.
+type Session struct {
+	mu       sync.Mutex
+	password []byte
+	dir      string
+	listener net.Listener
+	once     sync.Once
+}
+
+// lookSudo finds the sudo binary the shim runs, mockable for tests
+var lookSudo = func() (string, error) {
+	return exec.LookPath("sudo")
+}
+
+// helperCommand is the command line of the askpass helper for socket
+var helperCommand = func(socket string) string {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "sloth-runner"
+	}
+	return shellQuote(executable) + " agent askpass " + shellQuote(socket)
+}
+
+// Hold makes sudo get password from an askpass helper until Release is
+// called. Commands running with Env find a sudo shim adding -A first in
+// their PATH, so modules and commands calling sudo use the helper instead
+// of needing a terminal.
+func Hold(password []byte) (*Session, error) {
+	sudoPath, err := lookSudo()
+	if err != nil {
+		return nil, fmt.Errorf("sudo not found: %w", err)
+	}
+
+	// MkdirTemp creates the directory with mode 0700, so only the agent's
+	// user can reach the socket
+	dir, err := os.MkdirTemp("", "sloth-runner-sudo-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create askpass directory: %w", err)
+	}
+	socket := filepath.Join(dir, "askpass.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen for askpass requests: %w", err)
+	}
+
+	scripts := map[string]string{
+		filepath.Join(dir, "askpass"): "#!/bin/sh\nexec " + helperCommand(socket) + "\n",
+		filepath.Join(dir, "sudo"):    "#!/bin/sh\nexec " + shellQuote(sudoPath) + " -A \"$@\"\n",
+	}
+	for path, script := range scripts {
+		if err := os.WriteFile(path, []byte(script), 0700); err != nil {
+			listener.Close()
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to write askpass helper: %w", err)
+		}
+	}
+
+	s := &Session{password: append([]byte(nil), password...), dir: dir, listener: listener}
+	go s.serve()
+	return s, nil
+}
+
+// Env returns the variables the run's commands add to their environment:
+// the directory of the shim, to come first in PATH, and SUDO_ASKPASS
+func (s *Session) Env() []string {
+	return []string{
+		"PATH=" + s.dir,
+		"SUDO_ASKPASS=" + filepath.Join(s.dir, "askpass"),
+	}
+}
+
+// serve answers each connection to the socket with the password
+func (s *Session) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		reply := append(append([]byte(nil), s.password...), '\n')
+		s.mu.Unlock()
+
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		conn.Write(reply)
+		conn.Close()
+		Wipe(reply)
+	}
+}
+
+// Release removes the helper and the shim and forgets the password
+func (s *Session) Release() {
+	s.once.Do(func() {
+		s.listener.Close()
+		os.RemoveAll(s.dir)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		Wipe(s.password)
+		s.password = nil
+	})
+}
+
+// Askpass writes the password held by the agent listening on socket to w.
+// The askpass helper runs it; sudo reads the password from its output.
+func Askpass(socket string, w io.Writer) error {
+	conn, err := net.DialTimeout("unix", socket, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("no sudo password available: %w", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.Copy(w, conn)
+	return err
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
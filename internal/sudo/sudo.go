@@ -0,0 +1,62 @@
+// Package sudo lets delegated tasks use sudo on agents where it asks for a
+// password. The runner prompts for the password and seals it to a key the
+// agent generates at startup; the agent opens it, keeps it in memory while
+// runs need it and hands it to sudo through an askpass helper, so it never
+// reaches a disk, a command line or a log.
+package sudo
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// KeyPair is the key passwords are sealed to. Agents create one when they
+// start and keep it in memory only.
+type KeyPair struct {
+	public  *[32]byte
+	private *[32]byte
+}
+
+// GenerateKeyPair creates a new key pair
+func GenerateKeyPair() (*KeyPair, error) {
+	public, private, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate sudo key: %w", err)
+	}
+	return &KeyPair{public: public, private: private}, nil
+}
+
+// PublicKey returns the key runners seal passwords to
+func (k *KeyPair) PublicKey() []byte {
+	return k.public[:]
+}
+
+// Open returns the password sealed to the key pair
+func (k *KeyPair) Open(sealed []byte) ([]byte, error) {
+	password, ok := box.OpenAnonymous(nil, sealed, k.public, k.private)
+	if !ok {
+		return nil, errors.New("failed to open sudo password: it was not sealed to this agent's key")
+	}
+	return password, nil
+}
+
+// Seal encrypts password so that only the holder of the private key of
+// publicKey can read it
+func Seal(password, publicKey []byte) ([]byte, error) {
+	if len(publicKey) != 32 {
+		return nil, fmt.Errorf("invalid sudo key: expected 32 bytes, got %d", len(publicKey))
+	}
+	var recipient [32]byte
+	copy(recipient[:], publicKey)
+	return box.SealAnonymous(nil, password, &recipient, rand.Reader)
+}
+
+// Wipe overwrites b so a password doesn't linger in memory
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,121 @@
+package sudo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Seal([]byte("s3cret"), key.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("s3cret")) {
+		t.Fatal("Expected the password to be encrypted")
+	}
+
+	password, err := key.Open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(password) != "s3cret" {
+		t.Errorf("Unexpected password %q", password)
+	}
+
+	other, _ := GenerateKeyPair()
+	if _, err := other.Open(sealed); err == nil {
+		t.Error("Expected another key to fail to open the password")
+	}
+	if _, err := Seal([]byte("x"), []byte("short")); err == nil {
+		t.Error("Expected an invalid key to be rejected")
+	}
+}
+
+func TestHold(t *testing.T) {
+	orig := lookSudo
+	lookSudo = func() (string, error) { return "/usr/bin/sudo", nil }
+	defer func() { lookSudo = orig }()
+	path := os.Getenv("PATH")
+
+	first, err := Hold([]byte("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Hold([]byte("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("PATH") != path {
+		t.Errorf("Expected PATH to stay untouched, got %q", os.Getenv("PATH"))
+	}
+	if _, ok := os.LookupEnv("SUDO_ASKPASS"); ok {
+		t.Error("Expected SUDO_ASKPASS to be left to the run's commands")
+	}
+
+	// Each run's commands get their own helper and password
+	for session, password := range map[*Session]string{first: "first", second: "second"} {
+		env := session.Env()
+		dir := strings.TrimPrefix(env[0], "PATH=")
+		shim, err := os.ReadFile(filepath.Join(dir, "sudo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(shim), `'/usr/bin/sudo' -A "$@"`) {
+			t.Errorf("Unexpected shim:\n%s", shim)
+		}
+		if env[1] != "SUDO_ASKPASS="+filepath.Join(dir, "askpass") {
+			t.Errorf("Unexpected SUDO_ASKPASS %q", env[1])
+		}
+
+		var out bytes.Buffer
+		if err := Askpass(filepath.Join(dir, "askpass.sock"), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != password+"\n" {
+			t.Errorf("Expected the run's password %q, got %q", password, out.String())
+		}
+	}
+
+	firstDir := strings.TrimPrefix(first.Env()[0], "PATH=")
+	first.Release()
+	first.Release()
+	if _, err := os.Stat(firstDir); !os.IsNotExist(err) {
+		t.Error("Expected the helper to be removed on release")
+	}
+	var out bytes.Buffer
+	if err := Askpass(filepath.Join(strings.TrimPrefix(second.Env()[0], "PATH="), "askpass.sock"), &out); err != nil || out.String() != "second\n" {
+		t.Errorf("Expected the other run to keep its password, got %q, %v", out.String(), err)
+	}
+	second.Release()
+}
+
+func TestConfig_Matches(t *testing.T) {
+	config, err := ParseConfig([]byte("agents: [web-*, db-01]\ngroups: [legacy]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := func(group string) ([]string, error) {
+		return map[string][]string{"legacy": {"old-01"}}[group], nil
+	}
+
+	for agent, want := range map[string]bool{"web-03": true, "db-01": true, "old-01": true, "db-02": false} {
+		got, err := config.Matches(agent, members)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Matches(%s) = %v, want %v", agent, got, want)
+		}
+	}
+
+	if _, err := ParseConfig([]byte("agents: ['web-[']\n")); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
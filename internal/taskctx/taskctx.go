@@ -0,0 +1,534 @@
+// Package taskctx carries per-task execution settings to Lua modules
+// through the context tasks run with.
+package taskctx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/nix"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type outputKey struct{}
+type approvalsKey struct{}
+type confirmKey struct{}
+type sudoPasswordKey struct{}
+type agentKey struct{}
+type progressKey struct{}
+type planKey struct{}
+type changeLogKey struct{}
+type strictTypesKey struct{}
+type nixShellKey struct{}
+type slowCallsKey struct{}
+type remoteKey struct{}
+type commandEnvKey struct{}
+
+// ConfirmFunc asks the user a yes/no question and returns the answer
+type ConfirmFunc func(message string) bool
+
+// WithOutput returns a context whose tasks stream module output to w
+// instead of stdout. Agents use it to send output back to the runner.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, &lockedWriter{w: w})
+}
+
+// Output returns where modules should write live output. It is stdout
+// unless the task runs with WithOutput. ctx may be nil.
+func Output(ctx context.Context) io.Writer {
+	if ctx != nil {
+		if w, ok := ctx.Value(outputKey{}).(io.Writer); ok {
+			return w
+		}
+	}
+	return os.Stdout
+}
+
+// HasOutput reports whether ctx carries an output set with WithOutput
+func HasOutput(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	_, ok := ctx.Value(outputKey{}).(io.Writer)
+	return ok
+}
+
+// WithApprovals returns a context carrying the approvals given for the run
+func WithApprovals(ctx context.Context, approvals []string) context.Context {
+	if len(approvals) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, approvalsKey{}, append([]string(nil), approvals...))
+}
+
+// Approvals returns the approvals given for the run
+func Approvals(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	approvals, _ := ctx.Value(approvalsKey{}).([]string)
+	return approvals
+}
+
+// Approved reports whether name was approved for the run
+func Approved(ctx context.Context, name string) bool {
+	for _, a := range Approvals(ctx) {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithConfirm returns a context in which modules may ask the user to
+// confirm an action. Contexts without one cannot prompt.
+func WithConfirm(ctx context.Context, confirm ConfirmFunc) context.Context {
+	return context.WithValue(ctx, confirmKey{}, confirm)
+}
+
+// Confirm returns the function to ask the user with, or nil when nobody
+// can be asked
+func Confirm(ctx context.Context) ConfirmFunc {
+	if ctx == nil {
+		return nil
+	}
+	confirm, _ := ctx.Value(confirmKey{}).(ConfirmFunc)
+	return confirm
+}
+
+// SudoPasswordFunc returns the sudo password for the agent a task is
+// delegated to, or nil when its sudo doesn't need one
+type SudoPasswordFunc func(agent string) ([]byte, error)
+
+// WithSudoPassword returns a context whose delegated tasks get the sudo
+// password for their agent from f
+func WithSudoPassword(ctx context.Context, f SudoPasswordFunc) context.Context {
+	return context.WithValue(ctx, sudoPasswordKey{}, f)
+}
+
+// SudoPassword returns the sudo password for agent, or nil when the run
+// has none for it
+func SudoPassword(ctx context.Context, agent string) ([]byte, error) {
+	if ctx == nil {
+		return nil, nil
+	}
+	f, _ := ctx.Value(sudoPasswordKey{}).(SudoPasswordFunc)
+	if f == nil {
+		return nil, nil
+	}
+	return f(agent)
+}
+
+// WithAgent returns a context for tasks running on the named agent
+func WithAgent(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, agentKey{}, name)
+}
+
+// Agent returns the name of the agent tasks run on, or "" when they run
+// where the workflow was started
+func Agent(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	name, _ := ctx.Value(agentKey{}).(string)
+	return name
+}
+
+// NoPercent is the percent of progress reported with a message only
+const NoPercent = -1
+
+// Progress is a progress update of a task, reported with progress.update
+type Progress struct {
+	Time time.Time `json:"time"`
+	// Percent is between 0 and 100, or NoPercent
+	Percent float64 `json:"percent"`
+	Message string  `json:"message,omitempty"`
+}
+
+// ProgressFunc receives the progress updates of a task
+type ProgressFunc func(Progress)
+
+// WithProgress returns a context whose tasks report their progress to f
+func WithProgress(ctx context.Context, f ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+// HasProgress reports whether ctx carries a function set with
+// WithProgress
+func HasProgress(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	_, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	return ok
+}
+
+// ReportProgress reports the progress of the task ctx belongs to. It
+// returns false when nothing receives it.
+func ReportProgress(ctx context.Context, p Progress) bool {
+	if ctx == nil {
+		return false
+	}
+	f, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	if f == nil {
+		return false
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	f(p)
+	return true
+}
+
+// Change is a change a module call makes, or would make when recorded in
+// check mode instead of making it
+type Change struct {
+	Module string `json:"module"`
+	Action string `json:"action"`
+	// Target is what changes: a file, a service, packages or a command
+	Target string `json:"target,omitempty"`
+	// Diff is the unified diff of a file the call would write
+	Diff string `json:"diff,omitempty"`
+	// Current is the state the call read from the system before deciding
+	// to change it, e.g. "inactive" for a service it would start. Drift
+	// detection reports it as what diverges from the workflow.
+	Current string `json:"current,omitempty"`
+	// Host is the agent the call ran on, set by the runner that delegated
+	// the task
+	Host string `json:"host,omitempty"`
+	// Unknown is set for calls skipped because their module can't tell
+	// what they would change
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Plan collects the changes of a task run in check mode
+type Plan struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// Record adds a change to the plan
+func (p *Plan) Record(c Change) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changes = append(p.changes, c)
+}
+
+// Changes returns the changes recorded so far, in order
+func (p *Plan) Changes() []Change {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Change(nil), p.changes...)
+}
+
+// WithPlan returns a context whose tasks run in check mode: modules
+// record what they would change in plan and leave the system untouched
+func WithPlan(ctx context.Context, plan *Plan) context.Context {
+	return context.WithValue(ctx, planKey{}, plan)
+}
+
+// CheckMode reports whether ctx runs in check mode
+func CheckMode(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	plan, _ := ctx.Value(planKey{}).(*Plan)
+	return plan != nil
+}
+
+// RecordChange records c in the plan of ctx. It returns false, recording
+// nothing, when ctx doesn't run in check mode and the module must make
+// the change itself.
+func RecordChange(ctx context.Context, c Change) bool {
+	if ctx == nil {
+		return false
+	}
+	plan, _ := ctx.Value(planKey{}).(*Plan)
+	if plan == nil {
+		return false
+	}
+	plan.Record(c)
+	return true
+}
+
+// ChangeLog collects the changes the module calls of a task made, telling
+// a task that changed the system from one that found it as wanted
+type ChangeLog struct {
+	Plan
+}
+
+// WithChangeLog returns a context whose modules report the changes they
+// make to log
+func WithChangeLog(ctx context.Context, log *ChangeLog) context.Context {
+	return context.WithValue(ctx, changeLogKey{}, log)
+}
+
+// ReportChange adds c to the change log of ctx, if any. Modules that can
+// tell whether a call changed anything call it once the change is made.
+func ReportChange(ctx context.Context, c Change) {
+	if ctx == nil {
+		return
+	}
+	if log, _ := ctx.Value(changeLogKey{}).(*ChangeLog); log != nil {
+		log.Record(c)
+	}
+}
+
+// SlowCall is a module call that ran longer than the slow threshold of
+// its module's category, or timed out
+type SlowCall struct {
+	Module   string        `json:"module"`
+	Function string        `json:"function"`
+	Duration time.Duration `json:"duration"`
+	// Threshold is how long the call could run before it was slow
+	Threshold time.Duration `json:"threshold"`
+	TimedOut  bool          `json:"timed_out,omitempty"`
+	// Host is the agent the call ran on, set by the runner that delegated
+	// the task
+	Host string `json:"host,omitempty"`
+}
+
+// SlowCalls collects the slow module calls of a task
+type SlowCalls struct {
+	mu    sync.Mutex
+	calls []SlowCall
+}
+
+// Calls returns the slow calls recorded so far, in order
+func (s *SlowCalls) Calls() []SlowCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SlowCall(nil), s.calls...)
+}
+
+// WithSlowCalls returns a context whose slow module calls are recorded in
+// calls
+func WithSlowCalls(ctx context.Context, calls *SlowCalls) context.Context {
+	return context.WithValue(ctx, slowCallsKey{}, calls)
+}
+
+// RecordSlowCall adds c to the slow calls of ctx, if any
+func RecordSlowCall(ctx context.Context, c SlowCall) {
+	if ctx == nil {
+		return
+	}
+	if calls, _ := ctx.Value(slowCallsKey{}).(*SlowCalls); calls != nil {
+		calls.mu.Lock()
+		calls.calls = append(calls.calls, c)
+		calls.mu.Unlock()
+	}
+}
+
+// FileChange describes writing after over the file at path, which holds
+// before or nil when it doesn't exist yet
+func FileChange(module, action, path string, before, after []byte) Change {
+	c := Change{Module: module, Action: action, Target: path, Diff: FileDiff(path, before, after)}
+	if before == nil {
+		c.Current = "absent"
+	}
+	return c
+}
+
+// maxDiffBytes is the size of files above which no diff is computed
+const maxDiffBytes = 1 << 20
+
+// FileDiff returns the unified diff between two versions of the file at
+// path. Binary and large files are only summarized.
+func FileDiff(path string, before, after []byte) string {
+	if bytes.Equal(before, after) {
+		return ""
+	}
+	if len(before)+len(after) > maxDiffBytes || !isText(before) || !isText(after) {
+		return "Binary or large file " + path + " differs\n"
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(before),
+		B:        diffLines(after),
+		FromFile: path,
+		ToFile:   path,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// diffLines splits content into lines keeping their newlines; unlike
+// difflib.SplitLines it adds no empty line after the last newline
+func diffLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+func isText(b []byte) bool {
+	return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0
+}
+
+// lockedWriter serializes writes from concurrent module calls
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// WithStrictTypes returns a context whose modules reject option values
+// they would otherwise coerce, like mode = 644, as workflows with
+// strict_types = true ask for
+func WithStrictTypes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictTypesKey{}, true)
+}
+
+// StrictTypes reports whether modules run with ctx reject ambiguous
+// option values
+func StrictTypes(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	strict, _ := ctx.Value(strictTypesKey{}).(bool)
+	return strict
+}
+
+// WithNixShell returns a context whose commands run in the Nix shell, as
+// tasks with runtime = runtime.nix_shell{...} ask for
+func WithNixShell(ctx context.Context, shell *nix.Shell) context.Context {
+	return context.WithValue(ctx, nixShellKey{}, shell)
+}
+
+// NixShell returns the Nix shell commands run with ctx run in, or nil
+func NixShell(ctx context.Context) *nix.Shell {
+	if ctx == nil {
+		return nil
+	}
+	shell, _ := ctx.Value(nixShellKey{}).(*nix.Shell)
+	return shell
+}
+
+// Remote is a host without an agent the commands and file operations of a
+// task run on, as tasks with delegate_to = {ssh = "user@host"} ask for
+type Remote interface {
+	// Host names the host, as user@host
+	Host() string
+	// Run runs command in the host's shell and returns its exit code
+	Run(ctx context.Context, command string, stdout, stderr io.Writer) (int, error)
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, perm fs.FileMode) error
+	AppendFile(path string, data []byte) error
+	Stat(path string) (fs.FileInfo, error)
+	ReadDir(path string) ([]fs.FileInfo, error)
+	MkdirAll(path string) error
+	Remove(path string) error
+	RemoveAll(path string) error
+}
+
+// WithRemote returns a context whose commands and file operations run on r
+func WithRemote(ctx context.Context, r Remote) context.Context {
+	return context.WithValue(ctx, remoteKey{}, r)
+}
+
+// RemoteHost returns the remote host the commands run with ctx run on, or
+// nil when they run locally
+func RemoteHost(ctx context.Context) Remote {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(remoteKey{}).(Remote)
+	return r
+}
+
+// WithCommandEnv returns a context whose commands run with env, a list of
+// KEY=value, on top of their own environment. The PATH of env comes
+// before theirs.
+func WithCommandEnv(ctx context.Context, env ...string) context.Context {
+	return context.WithValue(ctx, commandEnvKey{}, append(CommandEnv(ctx), env...))
+}
+
+// CommandEnv returns the variables commands run with ctx add to their
+// environment
+func CommandEnv(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	env, _ := ctx.Value(commandEnvKey{}).([]string)
+	return env
+}
+
+// Environ returns base with the variables of CommandEnv(ctx) set
+func Environ(ctx context.Context, base []string) []string {
+	env := append([]string(nil), base...)
+	for _, kv := range CommandEnv(ctx) {
+		key, value, _ := strings.Cut(kv, "=")
+		i := indexEnv(env, key)
+		switch {
+		case i < 0:
+			env = append(env, kv)
+		case key == "PATH":
+			env[i] = kv + string(os.PathListSeparator) + strings.TrimPrefix(env[i], "PATH=")
+		default:
+			env[i] = key + "=" + value
+		}
+	}
+	return env
+}
+
+func indexEnv(env []string, key string) int {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], key+"=") {
+			return i
+		}
+	}
+	return -1
+}
+
+// Command is exec.CommandContext for commands of the task ctx belongs to:
+// they run with its CommandEnv, and name is looked up in its PATH first
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	env := CommandEnv(ctx)
+	if len(env) == 0 {
+		return cmd
+	}
+	cmd.Env = Environ(ctx, os.Environ())
+	if strings.ContainsRune(name, os.PathSeparator) {
+		return cmd
+	}
+	for _, kv := range env {
+		dirs, ok := strings.CutPrefix(kv, "PATH=")
+		if !ok {
+			continue
+		}
+		for _, dir := range filepath.SplitList(dirs) {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+				cmd.Path, cmd.Err = path, nil
+				return cmd
+			}
+		}
+	}
+	return cmd
+}
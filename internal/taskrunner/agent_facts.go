@@ -0,0 +1,143 @@
+package taskrunner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/agent"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// AgentLister is implemented by agent resolvers that can list the agents
+// they know, which lets delegate_to pick an agent by its facts
+type AgentLister interface {
+	AgentInfos() ([]*pb.AgentInfo, error)
+}
+
+// agentHeartbeatWindow is how recent an agent's heartbeat must be for it to
+// be picked by its facts
+const agentHeartbeatWindow = 60 * time.Second
+
+// anyFactValue matches a fact an agent reports with any value
+const anyFactValue = "*"
+
+// factsSelector returns the facts a delegate_to table asks for, e.g.
+// delegate_to = {facts = {architecture = "arm64"}}, and the agents it
+// limits the choice to, if any. A fact set to "*" only has to be reported.
+func factsSelector(delegateTo map[string]interface{}) (map[string]string, []string, bool) {
+	raw, ok := delegateTo["facts"].(map[string]interface{})
+	if !ok {
+		return nil, nil, false
+	}
+	facts := make(map[string]string, len(raw))
+	for name, value := range raw {
+		facts[name] = fmt.Sprint(value)
+	}
+	return facts, candidateAgents(delegateTo["agents"]), true
+}
+
+// runtimeFacts adds to facts the ones the runtime of t needs: tasks
+// running in a Nix shell go to agents that have Nix
+func runtimeFacts(t *types.Task, facts map[string]string) map[string]string {
+	if t.NixShell == nil {
+		return facts
+	}
+	if _, ok := facts["nix"]; !ok {
+		facts["nix"] = anyFactValue
+	}
+	return facts
+}
+
+// candidateAgents returns the agent names of a delegate_to agents list,
+// which arrives as a map keyed by index when nested in a table
+func candidateAgents(agents interface{}) []string {
+	m, ok := agents.(map[string]interface{})
+	if !ok {
+		return getHostsList(agents)
+	}
+	names := make([]string, 0, len(m))
+	for _, name := range m {
+		names = append(names, fmt.Sprint(name))
+	}
+	return names
+}
+
+// selectAgentByFacts picks the first healthy agent, by name, whose facts
+// match all of the given ones. When candidates is not empty only those
+// agents are considered.
+func selectAgentByFacts(facts map[string]string, candidates []string) (name, address string, err error) {
+	lister, ok := globalAgentResolver.(AgentLister)
+	if !ok {
+		return "", "", fmt.Errorf("no agent registry available to select an agent by facts")
+	}
+	infos, err := lister.AgentInfos()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list agents: %w", err)
+	}
+	return matchAgentByFacts(infos, facts, candidates, time.Now())
+}
+
+// matchAgentByFacts is selectAgentByFacts over a known list of agents
+func matchAgentByFacts(infos []*pb.AgentInfo, facts map[string]string, candidates []string, now time.Time) (string, string, error) {
+	allowed := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		allowed[c] = true
+	}
+
+	sorted := append([]*pb.AgentInfo(nil), infos...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetAgentName() < sorted[j].GetAgentName() })
+
+	for _, info := range sorted {
+		if len(allowed) > 0 && !allowed[info.GetAgentName()] {
+			continue
+		}
+		if info.GetLastHeartbeat() == 0 || now.Sub(time.Unix(info.GetLastHeartbeat(), 0)) >= agentHeartbeatWindow {
+			continue
+		}
+		if info.GetCircuitState() == "open" {
+			continue
+		}
+		if agentHasFacts(info, facts) {
+			return info.GetAgentName(), info.GetAgentAddress(), nil
+		}
+	}
+	return "", "", fmt.Errorf("no healthy agent with %s", describeFacts(facts))
+}
+
+// agentHasFacts reports whether the facts an agent reported include all of
+// the given ones
+func agentHasFacts(info *pb.AgentInfo, facts map[string]string) bool {
+	if info.GetSystemInfoJson() == "" {
+		return false
+	}
+	var sysInfo agent.SystemInfo
+	if err := json.Unmarshal([]byte(info.GetSystemInfoJson()), &sysInfo); err != nil {
+		return false
+	}
+	have := agent.StableFacts(&sysInfo)
+	for name, value := range facts {
+		reported, ok := have[name]
+		if value == anyFactValue {
+			if !ok {
+				return false
+			}
+		} else if reported != value {
+			return false
+		}
+	}
+	return true
+}
+
+// describeFacts formats facts as name=value pairs for error messages
+func describeFacts(facts map[string]string) string {
+	pairs := make([]string, 0, len(facts))
+	for name, value := range facts {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
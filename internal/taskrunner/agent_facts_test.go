@@ -0,0 +1,189 @@
+package taskrunner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/nix"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgentLister resolves and lists a fixed set of agents
+type fakeAgentLister struct {
+	agents []*pb.AgentInfo
+}
+
+func (f *fakeAgentLister) GetAgentAddress(name string) (string, error) {
+	for _, info := range f.agents {
+		if info.GetAgentName() == name {
+			return info.GetAgentAddress(), nil
+		}
+	}
+	return "", fmt.Errorf("agent not found: %s", name)
+}
+
+func (f *fakeAgentLister) AgentInfos() ([]*pb.AgentInfo, error) {
+	return f.agents, nil
+}
+
+func factsAgent(name, address, arch string, heartbeat time.Time, custom map[string]interface{}) *pb.AgentInfo {
+	sysInfo, _ := json.Marshal(map[string]interface{}{"architecture": arch, "custom": custom})
+	return &pb.AgentInfo{
+		AgentName:      name,
+		AgentAddress:   address,
+		LastHeartbeat:  heartbeat.Unix(),
+		SystemInfoJson: string(sysInfo),
+	}
+}
+
+func TestMatchAgentByFacts(t *testing.T) {
+	now := time.Now()
+	stale := factsAgent("arm-00", "10.0.0.9:50051", "arm64", now.Add(-5*time.Minute), nil)
+	open := factsAgent("arm-01", "10.0.0.10:50051", "arm64", now, nil)
+	open.CircuitState = "open"
+	infos := []*pb.AgentInfo{
+		factsAgent("x86-01", "10.0.0.1:50051", "amd64", now, nil),
+		factsAgent("arm-03", "10.0.0.3:50051", "arm64", now, map[string]interface{}{"role": "builder"}),
+		factsAgent("arm-02", "10.0.0.2:50051", "arm64", now, nil),
+		stale,
+		open,
+	}
+
+	name, addr, err := matchAgentByFacts(infos, map[string]string{"architecture": "arm64"}, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, "arm-02", name, "the first healthy agent by name should be picked")
+	assert.Equal(t, "10.0.0.2:50051", addr)
+
+	name, _, err = matchAgentByFacts(infos, map[string]string{"architecture": "arm64", "custom.role": "builder"}, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, "arm-03", name)
+
+	name, _, err = matchAgentByFacts(infos, map[string]string{"architecture": "arm64"}, []string{"x86-01", "arm-03"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "arm-03", name, "only the candidates should be considered")
+
+	_, _, err = matchAgentByFacts(infos, map[string]string{"architecture": "riscv64"}, nil, now)
+	assert.EqualError(t, err, "no healthy agent with architecture=riscv64")
+
+	_, _, err = matchAgentByFacts(infos, map[string]string{"architecture": "arm64"}, []string{"arm-00", "arm-01"}, now)
+	assert.Error(t, err, "stale agents and agents with an open circuit should be skipped")
+}
+
+func TestFactsSelector(t *testing.T) {
+	facts, candidates, ok := factsSelector(map[string]interface{}{
+		"facts":  map[string]interface{}{"architecture": "arm64", "cpus": 4.0},
+		"agents": map[string]interface{}{"1": "build-01", "2": "build-02"},
+	})
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"architecture": "arm64", "cpus": "4"}, facts)
+	assert.ElementsMatch(t, []string{"build-01", "build-02"}, candidates)
+
+	_, _, ok = factsSelector(map[string]interface{}{"address": "10.0.0.1:50051"})
+	assert.False(t, ok)
+}
+
+func TestMatchAgentByFacts_NixRuntime(t *testing.T) {
+	now := time.Now()
+	withNix := factsAgent("build-02", "10.0.0.2:50051", "amd64", now, nil)
+	withNix.SystemInfoJson = `{"architecture": "amd64", "nix": "2.18.1"}`
+	infos := []*pb.AgentInfo{factsAgent("build-01", "10.0.0.1:50051", "amd64", now, nil), withNix}
+
+	task := &types.Task{Name: "test", NixShell: &nix.Shell{Packages: []string{"nodejs_20"}}}
+	facts := runtimeFacts(task, map[string]string{"architecture": "amd64"})
+	assert.Equal(t, map[string]string{"architecture": "amd64", "nix": "*"}, facts)
+
+	name, _, err := matchAgentByFacts(infos, facts, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, "build-02", name, "only agents with Nix should run tasks in a nix_shell")
+
+	facts = runtimeFacts(task, map[string]string{"nix": "2.24.0"})
+	_, _, err = matchAgentByFacts(infos, facts, nil, now)
+	assert.EqualError(t, err, "no healthy agent with nix=2.24.0", "a version asked for should be kept")
+
+	facts = runtimeFacts(&types.Task{Name: "build"}, map[string]string{"architecture": "amd64"})
+	name, _, err = matchAgentByFacts(infos, facts, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, "build-01", name)
+}
+
+// fakeOutputsAgent returns outputs for the tasks it runs and records the
+// inputs it is given
+type fakeOutputsAgent struct {
+	pb.UnimplementedAgentServer
+
+	mu     sync.Mutex
+	inputs map[string]string
+}
+
+func (f *fakeOutputsAgent) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest) (*pb.ExecuteTaskResponse, error) {
+	f.mu.Lock()
+	f.inputs[in.GetTaskName()] = in.GetInputsJson()
+	f.mu.Unlock()
+	outputs, _ := json.Marshal(map[string]interface{}{"digest": "sha256:" + in.GetTaskName()})
+	return &pb.ExecuteTaskResponse{Success: true, Workspace: in.GetWorkspace(), OutputsJson: string(outputs)}, nil
+}
+
+// TestRun_AgentByFactsOutputs validates that tasks are delegated to agents
+// picked by their facts and that the outputs of delegated tasks reach the
+// delegated tasks depending on them
+func TestRun_AgentByFactsOutputs(t *testing.T) {
+	agent := &fakeOutputsAgent{inputs: make(map[string]string)}
+	addr := startFakeBatchAgent(t, agent)
+
+	orig := globalAgentResolver
+	t.Cleanup(func() { SetAgentResolver(orig) })
+	SetAgentResolver(&fakeAgentLister{agents: []*pb.AgentInfo{
+		factsAgent("build-arm", addr, "arm64", time.Now(), nil),
+	}})
+
+	byFacts := map[string]interface{}{"facts": map[string]interface{}{"architecture": "arm64"}}
+	tr, err := runDelegatedGroup(t, []types.Task{
+		{Name: "build", DelegateTo: byFacts},
+		{Name: "push", DelegateTo: byFacts, DependsOn: []string{"build"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "", agent.inputs["build"])
+	assert.JSONEq(t, `{"build": {"digest": "sha256:build"}}`, agent.inputs["push"])
+	assert.Equal(t, "sha256:push", fmt.Sprint(tr.Outputs["push"].(map[string]interface{})["digest"]))
+
+	_, err = runDelegatedGroup(t, []types.Task{
+		{Name: "build", DelegateTo: map[string]interface{}{"facts": map[string]interface{}{"architecture": "riscv64"}}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no healthy agent with architecture=riscv64")
+}
+
+// fakeSensitiveAgent returns outputs its task set, one of them sensitive
+type fakeSensitiveAgent struct {
+	pb.UnimplementedAgentServer
+}
+
+func (fakeSensitiveAgent) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest) (*pb.ExecuteTaskResponse, error) {
+	return &pb.ExecuteTaskResponse{
+		Success:        true,
+		Workspace:      in.GetWorkspace(),
+		OutputsJson:    `{"password": "hunter2", "user": "admin", "status": "ok"}`,
+		SetOutputsJson: `{"password": true, "user": false}`,
+	}, nil
+}
+
+// TestRun_DelegatedSensitiveOutputs validates that outputs a delegated task
+// set sensitive are exported and sealed like those of local tasks
+func TestRun_DelegatedSensitiveOutputs(t *testing.T) {
+	addr := startFakeBatchAgent(t, fakeSensitiveAgent{})
+
+	tr, err := runDelegatedGroup(t, []types.Task{{Name: "create_user", DelegateTo: addr}})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"password": "hunter2", "user": "admin"}, tr.Exports,
+		"only outputs set with task.set_output are exported")
+	assert.Equal(t, map[string]bool{"password": true}, tr.Sensitive)
+}
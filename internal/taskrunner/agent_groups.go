@@ -0,0 +1,134 @@
+package taskrunner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// AgentGroupLister is implemented by agent resolvers that can also list the
+// agent groups managed with the group command
+type AgentGroupLister interface {
+	AgentGroups() ([]*pb.AgentGroup, error)
+}
+
+// agentGroupPrefix marks a delegate_to entry naming an agent group, as in
+// delegate_to = "group:webservers"
+const agentGroupPrefix = "group:"
+
+// isAgentGroup reports whether a delegate_to entry names an agent group
+func isAgentGroup(host string) bool {
+	return strings.HasPrefix(host, agentGroupPrefix)
+}
+
+// expandAgentGroups replaces the agent groups among hosts with their
+// members, keeping the first occurrence of an agent listed more than once.
+// grouped reports whether hosts named any group.
+func expandAgentGroups(hosts []string) (expanded []string, grouped bool, err error) {
+	var groups []*pb.AgentGroup
+	seen := make(map[string]bool, len(hosts))
+	add := func(host string) {
+		if !seen[host] {
+			seen[host] = true
+			expanded = append(expanded, host)
+		}
+	}
+
+	for _, host := range hosts {
+		if !isAgentGroup(host) {
+			add(host)
+			continue
+		}
+		if !grouped {
+			lister, ok := globalAgentResolver.(AgentGroupLister)
+			if !ok {
+				return nil, true, fmt.Errorf("no agent registry available to resolve %s", host)
+			}
+			if groups, err = lister.AgentGroups(); err != nil {
+				return nil, true, fmt.Errorf("failed to list agent groups: %w", err)
+			}
+			grouped = true
+		}
+		members, err := agentGroupMembers(groups, strings.TrimPrefix(host, agentGroupPrefix))
+		if err != nil {
+			return nil, true, err
+		}
+		for _, member := range members {
+			add(member)
+		}
+	}
+	return expanded, grouped, nil
+}
+
+// agentGroupMembers returns the agents of the named group
+func agentGroupMembers(groups []*pb.AgentGroup, name string) ([]string, error) {
+	for _, group := range groups {
+		if group.GetName() != name {
+			continue
+		}
+		if len(group.GetAgentNames()) == 0 {
+			return nil, fmt.Errorf("agent group %s has no agents", name)
+		}
+		return group.GetAgentNames(), nil
+	}
+	return nil, fmt.Errorf("agent group not found: %s", name)
+}
+
+// AgentGroupAgents returns the agents of the named agent group, as the
+// agent registry lists them
+func AgentGroupAgents(name string) ([]string, error) {
+	lister, ok := globalAgentResolver.(AgentGroupLister)
+	if !ok {
+		return nil, fmt.Errorf("no agent registry available to resolve group %s", name)
+	}
+	groups, err := lister.AgentGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent groups: %w", err)
+	}
+	for _, group := range groups {
+		if group.GetName() == name {
+			return group.GetAgentNames(), nil
+		}
+	}
+	return nil, fmt.Errorf("agent group not found: %s", name)
+}
+
+// DelegatedAgents returns the agents the tasks of taskGroups are delegated
+// to, each once and with agent groups expanded. Tasks running locally or
+// on a host reached over SSH are left out.
+func DelegatedAgents(taskGroups map[string]types.TaskGroup) ([]string, error) {
+	groupNames := make([]string, 0, len(taskGroups))
+	for name := range taskGroups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+
+	var agents []string
+	seen := make(map[string]bool)
+	for _, groupName := range groupNames {
+		group := taskGroups[groupName]
+		for i := range group.Tasks {
+			delegateTo := effectiveDelegateTo(&group.Tasks[i], group)
+			if delegateTo == nil {
+				continue
+			}
+			if target, _ := sshDelegate(delegateTo); target != nil {
+				continue
+			}
+			hosts, _, err := expandAgentGroups(getHostsList(delegateTo))
+			if err != nil {
+				return nil, err
+			}
+			for _, host := range hosts {
+				if !seen[host] {
+					seen[host] = true
+					agents = append(agents, host)
+				}
+			}
+		}
+	}
+	return agents, nil
+}
@@ -0,0 +1,169 @@
+package taskrunner
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGroupLister resolves agents and lists a fixed set of agent groups
+type fakeGroupLister struct {
+	fakeAgentLister
+	groups []*pb.AgentGroup
+}
+
+func (f *fakeGroupLister) AgentGroups() ([]*pb.AgentGroup, error) {
+	return f.groups, nil
+}
+
+func TestExpandAgentGroups(t *testing.T) {
+	orig := globalAgentResolver
+	defer SetAgentResolver(orig)
+
+	SetAgentResolver(&fakeAgentLister{})
+	_, _, err := expandAgentGroups([]string{"group:webservers"})
+	assert.ErrorContains(t, err, "no agent registry available")
+
+	SetAgentResolver(&fakeGroupLister{groups: []*pb.AgentGroup{
+		{Name: "webservers", AgentNames: []string{"web-01", "web-02"}},
+		{Name: "edge", AgentNames: []string{"web-02", "edge-01"}},
+		{Name: "empty"},
+	}})
+
+	hosts, grouped, err := expandAgentGroups([]string{"db-01"})
+	require.NoError(t, err)
+	assert.False(t, grouped)
+	assert.Equal(t, []string{"db-01"}, hosts)
+
+	hosts, grouped, err = expandAgentGroups([]string{"group:webservers", "db-01", "group:edge"})
+	require.NoError(t, err)
+	assert.True(t, grouped)
+	assert.Equal(t, []string{"web-01", "web-02", "db-01", "edge-01"}, hosts, "members in order, each agent once")
+
+	_, _, err = expandAgentGroups([]string{"group:missing"})
+	assert.ErrorContains(t, err, "agent group not found: missing")
+	_, _, err = expandAgentGroups([]string{"group:empty"})
+	assert.ErrorContains(t, err, "agent group empty has no agents")
+}
+
+func TestAgentGroupAgents(t *testing.T) {
+	orig := globalAgentResolver
+	defer SetAgentResolver(orig)
+
+	SetAgentResolver(&fakeAgentLister{})
+	_, err := AgentGroupAgents("webservers")
+	assert.ErrorContains(t, err, "no agent registry available")
+
+	SetAgentResolver(&fakeGroupLister{groups: []*pb.AgentGroup{
+		{Name: "webservers", AgentNames: []string{"web-01", "web-02"}},
+		{Name: "empty"},
+	}})
+	agents, err := AgentGroupAgents("webservers")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-01", "web-02"}, agents)
+
+	agents, err = AgentGroupAgents("empty")
+	require.NoError(t, err)
+	assert.Empty(t, agents)
+
+	_, err = AgentGroupAgents("missing")
+	assert.ErrorContains(t, err, "agent group not found: missing")
+}
+
+func TestDelegatedAgents(t *testing.T) {
+	orig := globalAgentResolver
+	defer SetAgentResolver(orig)
+	SetAgentResolver(&fakeGroupLister{groups: []*pb.AgentGroup{
+		{Name: "webservers", AgentNames: []string{"web-01", "web-02"}},
+	}})
+
+	taskGroups := map[string]types.TaskGroup{
+		"deploy": {
+			DelegateTo: "db-01",
+			Tasks: []types.Task{
+				{Name: "migrate"},
+				{Name: "build", DelegateTo: "local"},
+				{Name: "rollout", DelegateTo: []interface{}{"group:webservers", "db-01"}},
+				{Name: "legacy", DelegateTo: map[string]interface{}{"ssh": "admin@legacy.example.com"}},
+			},
+		},
+		"report": {
+			Tasks: []types.Task{{Name: "summary"}},
+		},
+	}
+	agents, err := DelegatedAgents(taskGroups)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db-01", "web-01", "web-02"}, agents, "agents once, without local and SSH tasks")
+
+	taskGroups["report"].Tasks[0].DelegateTo = "group:missing"
+	_, err = DelegatedAgents(taskGroups)
+	assert.ErrorContains(t, err, "agent group not found: missing")
+}
+
+func TestFanOut_MaxConcurrency(t *testing.T) {
+	hosts := []string{"web-01", "web-02", "web-03", "web-04", "web-05"}
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	results := fanOut(hosts, 2, false, func(host string) MultiHostResult {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return MultiHostResult{Host: host, Success: host != "web-03"}
+	})
+
+	assert.Equal(t, 2, peak)
+	require.Len(t, results, len(hosts))
+	for i, result := range results {
+		assert.Equal(t, hosts[i], result.Host, "results follow the order of hosts")
+		assert.False(t, result.Skipped, "without fail_fast every host runs")
+	}
+	assert.False(t, results[2].Success)
+}
+
+func TestFanOut_FailFast(t *testing.T) {
+	hosts := []string{"web-01", "web-02", "web-03", "web-04"}
+
+	var started []string
+	results := fanOut(hosts, 1, true, func(host string) MultiHostResult {
+		started = append(started, host)
+		if host == "web-02" {
+			return MultiHostResult{Host: host, Error: errors.New("exit status 1")}
+		}
+		return MultiHostResult{Host: host, Success: true}
+	})
+
+	assert.Equal(t, []string{"web-01", "web-02"}, started)
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Skipped)
+	for _, result := range results[2:] {
+		assert.True(t, result.Skipped, "%s should be skipped", result.Host)
+		assert.ErrorIs(t, result.Error, errSkippedFailFast)
+	}
+
+	outputs := multiHostOutputs(results)
+	assert.Equal(t, map[string]interface{}{
+		"success": false,
+		"skipped": false,
+		"changed": false,
+		"output":  "",
+		"error":   "exit status 1",
+	}, outputs["web-02"])
+	assert.Equal(t, true, outputs["web-01"].(map[string]interface{})["success"])
+	assert.Equal(t, true, outputs["web-04"].(map[string]interface{})["skipped"])
+}
@@ -0,0 +1,240 @@
+package taskrunner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// minAgentBatch is the fewest consecutive tasks delegated to the same agent
+// that are sent together with ExecuteTasks instead of one at a time
+const minAgentBatch = 2
+
+// errSkippedOnAgent marks a batched task the agent skipped
+var errSkippedOnAgent = errors.New("skipped on agent")
+
+// batchable reports whether a task can run on an agent as part of a batch.
+// Conditions and retries are handled by the runner around each task, and
+// the workspace of a batch is returned once for all its tasks, so tasks
+// using them or workspace_diff are sent one at a time.
+func batchable(t *types.Task) bool {
+	return t.RunIf == "" && t.RunIfFunc == nil &&
+		t.AbortIf == "" && t.AbortIfFunc == nil &&
+		t.Retries <= 0 && !t.WorkspaceDiff
+}
+
+// agentBatch returns the task at order[start] followed by the tasks right
+// after it that run on the same agent and can be sent along with it. Tasks
+// with async set are batched with other async tasks and run concurrently
+// on the agent; the others run in order. ensure starts the services of
+// each task added to the batch.
+func (tr *TaskRunner) agentBatch(group types.TaskGroup, taskMap map[string]*types.Task, order []string, start int, taskStatus map[string]string, ensure func(*types.Task) error) (tasks []*types.Task, host string, parallel bool) {
+	first := taskMap[order[start]]
+	hosts := getHostsList(effectiveDelegateTo(first, group))
+	if len(hosts) != 1 || isAgentGroup(hosts[0]) || !batchable(first) {
+		return nil, "", false
+	}
+	host, parallel = hosts[0], first.Async
+
+	tasks = []*types.Task{first}
+	inBatch := map[string]bool{first.Name: true}
+	for _, name := range order[start+1:] {
+		t := taskMap[name]
+		// Artifacts are consumed before a task starts, so only the first
+		// task of a batch may consume any
+		if !batchable(t) || len(t.Consumes) > 0 || t.Async != parallel || t.User != first.User {
+			break
+		}
+		if h := getHostsList(effectiveDelegateTo(t, group)); len(h) != 1 || h[0] != host {
+			break
+		}
+		// Dependencies must have run before the batch, or be in it when
+		// the batch runs in order
+		ready := true
+		for _, dep := range t.DependsOn {
+			if inBatch[dep] {
+				ready = !parallel
+			} else if status, ok := taskStatus[dep]; !ok || (status != "Success" && status != "Skipped" && status != "Quarantined") {
+				ready = false
+			}
+			if !ready {
+				break
+			}
+		}
+		if !ready || ensure(t) != nil {
+			break
+		}
+		tasks = append(tasks, t)
+		inBatch[t.Name] = true
+	}
+	return tasks, host, parallel
+}
+
+// batchWorkspace returns the workspace paths a batch needs: those of all
+// its tasks, or nil for the whole workspace when any task needs all of it
+func batchWorkspace(tasks []*types.Task) []string {
+	var paths []string
+	for _, t := range tasks {
+		if len(t.Workspace) == 0 {
+			return nil
+		}
+		paths = append(paths, t.Workspace...)
+	}
+	return paths
+}
+
+// executeBatchOnAgent runs tasks on an agent with a single workspace
+// transfer and returns each task's error, nil when it succeeded. It returns
+// nil results when the agent doesn't support batches, so the tasks run
+// one at a time instead.
+func (tr *TaskRunner) executeBatchOnAgent(ctx context.Context, tasks []*types.Task, host string, parallel bool, session *types.SharedSession, groupName string) map[string]error {
+	names := make([]string, len(tasks))
+	for i, t := range tasks {
+		names[i] = t.Name
+	}
+	results := make(map[string]error, len(tasks))
+	failAll := func(err error) map[string]error {
+		for _, t := range tasks {
+			results[t.Name] = &TaskExecutionError{TaskName: t.Name, Err: err}
+		}
+		return results
+	}
+
+	agentAddress := host
+	if !strings.Contains(agentAddress, ":") {
+		resolved, err := resolveAgentAddress(agentAddress)
+		if err != nil {
+			return failAll(fmt.Errorf("failed to resolve agent '%s': %w", host, err))
+		}
+		agentAddress = resolved
+	}
+
+	mode := "in order"
+	if parallel {
+		mode = "in parallel"
+	}
+	pterm.DefaultBox.
+		WithTitle("🔗 Agent Connection").
+		WithTitleTopLeft().
+		WithBoxStyle(pterm.NewStyle(pterm.FgCyan)).
+		Printfln("Tasks: %s (%d, %s)\nAgent: %s", pterm.Cyan(strings.Join(names, ", ")), len(tasks), mode, pterm.Yellow(agentAddress))
+
+	conn, err := grpc.Dial(agentAddress, reliability.AgentDialOptions()...)
+	if err != nil {
+		return failAll(fmt.Errorf("failed to connect to agent %s: %w", agentAddress, err))
+	}
+	defer conn.Close()
+
+	var buf bytes.Buffer
+	if err := createTar(session.Workdir, batchWorkspace(tasks), &buf); err != nil {
+		return failAll(fmt.Errorf("failed to create workspace tarball: %w", err))
+	}
+
+	if dispatcher := hooks.GetGlobalDispatcher(); dispatcher != nil {
+		for _, t := range tasks {
+			dispatcher.DispatchTaskStarted(&hooks.TaskEvent{
+				TaskName:  t.Name,
+				AgentName: host,
+				Status:    "started",
+				Stack:     tr.Stack,
+				RunID:     tr.RunID,
+			})
+		}
+	}
+
+	client := pb.NewAgentClient(conn)
+	sudoPassword, err := sealSudoPassword(ctx, client, host)
+	if err != nil {
+		return failAll(err)
+	}
+
+	// Retries of the request reuse the key, so the agent runs the batch once
+	key := uuid.NewString()
+	for _, name := range names {
+		tr.journalTaskStarted(name, host, agentAddress, key)
+	}
+
+	pterm.Info.Printfln("📤 Sending %d tasks to agent...", len(tasks))
+	resp, err := client.ExecuteTasks(ctx, &pb.ExecuteTasksRequest{
+		TaskNames: names,
+		TaskGroup: groupName,
+		LuaScript: tr.LuaScript,
+		Workspace: buf.Bytes(),
+		User:      tasks[0].User,
+		Approvals: taskctx.Approvals(ctx),
+		Parallel:  parallel,
+		IdempotencyKey: key,
+		SudoPassword:   sudoPassword,
+	})
+	if status.Code(err) == codes.Unimplemented {
+		slog.Debug("Agent does not support task batches, sending tasks one at a time", "agent", agentAddress)
+		return nil
+	}
+	if err != nil {
+		slog.Error("Failed to send task batch to agent", "agent_address", agentAddress, "tasks", names, "error", err)
+		return failAll(fmt.Errorf("failed to execute tasks on agent %s: %w", agentAddress, err))
+	}
+
+	if err := extractTar(bytes.NewReader(resp.GetWorkspace()), session.Workdir); err != nil {
+		return failAll(fmt.Errorf("failed to extract updated workspace from agent %s: %w", agentAddress, err))
+	}
+	pterm.Info.Printfln("📥 Workspace synchronized")
+
+	for _, r := range resp.GetResults() {
+		duration := time.Duration(r.GetDurationMs()) * time.Millisecond
+		pterm.Printf("  %s %s\n",
+			pterm.Cyan("▶"),
+			pterm.DefaultBasicText.WithStyle(pterm.NewStyle(pterm.Bold)).Sprint(r.GetTaskName()))
+		switch r.GetStatus() {
+		case "Success":
+			pterm.Printf("    %s %s\n", pterm.Green("✓"), pterm.Green("completed"))
+			results[r.GetTaskName()] = nil
+		case "Skipped":
+			pterm.Printf("    %s %s\n", pterm.Yellow("⊘"), pterm.Gray("skipped on agent"))
+			results[r.GetTaskName()] = errSkippedOnAgent
+			continue
+		default:
+			pterm.Printf("    %s %s\n", pterm.Red("✗"), pterm.Red(r.GetError()))
+			results[r.GetTaskName()] = &TaskExecutionError{TaskName: r.GetTaskName(), Err: fmt.Errorf("agent execution failed on %s:\n%s", agentAddress, r.GetError())}
+		}
+		changes, err := decodeTaskChanges(r.GetChangesJson())
+		if err != nil {
+			slog.Warn("Failed to decode task changes from agent", "agent_address", agentAddress, "task", r.GetTaskName(), "error", err)
+		}
+		slowCalls, err := decodeSlowCalls(r.GetSlowCallsJson(), host)
+		if err != nil {
+			slog.Warn("Failed to decode slow calls from agent", "agent_address", agentAddress, "task", r.GetTaskName(), "error", err)
+		}
+		tr.Results = append(tr.Results, types.TaskResult{
+			Name:     r.GetTaskName(),
+			Status:   r.GetStatus(),
+			Duration: duration,
+			Error:    results[r.GetTaskName()],
+			Usage:     taskusage.FromProto(r.GetUsage()),
+			Changes:   changes,
+			SlowCalls: slowCalls,
+		})
+	}
+	for _, t := range tasks {
+		if _, ok := results[t.Name]; !ok {
+			results[t.Name] = &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("agent %s returned no result", agentAddress)}
+		}
+	}
+	return results
+}
@@ -0,0 +1,186 @@
+package taskrunner
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+)
+
+// fakeBatchAgent records the delegation RPCs it receives
+type fakeBatchAgent struct {
+	pb.UnimplementedAgentServer
+	batches bool // whether ExecuteTasks is implemented
+
+	mu      sync.Mutex
+	calls   [][]string
+	singles []string
+}
+
+func (f *fakeBatchAgent) ExecuteTasks(ctx context.Context, in *pb.ExecuteTasksRequest) (*pb.ExecuteTasksResponse, error) {
+	if !f.batches {
+		return f.UnimplementedAgentServer.ExecuteTasks(ctx, in)
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, in.GetTaskNames())
+	f.mu.Unlock()
+
+	resp := &pb.ExecuteTasksResponse{Workspace: in.GetWorkspace()}
+	for _, name := range in.GetTaskNames() {
+		result := &pb.TaskRunResult{TaskName: name, Status: "Success", DurationMs: 5, Usage: &pb.TaskUsage{CpuTimeMs: 20, PeakRssBytes: 1 << 20}}
+		if name == "broken" {
+			result.Status = "Failed"
+			result.Error = "exit status 1"
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+func (f *fakeBatchAgent) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest) (*pb.ExecuteTaskResponse, error) {
+	f.mu.Lock()
+	f.singles = append(f.singles, in.GetTaskName())
+	f.mu.Unlock()
+	return &pb.ExecuteTaskResponse{Success: true, Workspace: in.GetWorkspace(), Usage: &pb.TaskUsage{CpuTimeMs: 30}}, nil
+}
+
+func startFakeBatchAgent(t *testing.T, agent pb.AgentServer) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	pb.RegisterAgentServer(server, agent)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func runDelegatedGroup(t *testing.T, tasks []types.Task) (*TaskRunner, error) {
+	t.Helper()
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	groups := map[string]types.TaskGroup{"test_group": {Tasks: tasks, Workdir: t.TempDir()}}
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	return tr, tr.Run()
+}
+
+// TestRun_AgentBatch validates that consecutive tasks for the same agent
+// are sent in one ExecuteTasks call
+func TestRun_AgentBatch(t *testing.T) {
+	agent := &fakeBatchAgent{batches: true}
+	addr := startFakeBatchAgent(t, agent)
+
+	tr, err := runDelegatedGroup(t, []types.Task{
+		{Name: "one", DelegateTo: addr},
+		{Name: "two", DelegateTo: addr, DependsOn: []string{"one"}},
+		{Name: "three", DelegateTo: addr},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{{"one", "two", "three"}}, agent.calls)
+	assert.Empty(t, agent.singles)
+	require.Len(t, tr.Results, 3)
+	for _, result := range tr.Results {
+		assert.Equal(t, "Success", result.Status)
+		require.NotNil(t, result.Usage, "the agent's usage should be kept")
+		assert.Equal(t, 20*time.Millisecond, result.Usage.CPUTime)
+		assert.Equal(t, uint64(1<<20), result.Usage.PeakRSS)
+	}
+}
+
+// TestRun_AgentBatchFailure validates per-task results of a batch
+func TestRun_AgentBatchFailure(t *testing.T) {
+	agent := &fakeBatchAgent{batches: true}
+	addr := startFakeBatchAgent(t, agent)
+
+	tr, err := runDelegatedGroup(t, []types.Task{
+		{Name: "broken", DelegateTo: addr},
+		{Name: "after", DelegateTo: addr},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit status 1")
+
+	statuses := make(map[string]string)
+	for _, result := range tr.Results {
+		statuses[result.Name] = result.Status
+	}
+	assert.Equal(t, map[string]string{"broken": "Failed", "after": "Success"}, statuses)
+}
+
+// TestRun_AgentBatchUnsupported validates that tasks are sent one at a time
+// to agents without ExecuteTasks
+func TestRun_AgentBatchUnsupported(t *testing.T) {
+	agent := &fakeBatchAgent{}
+	addr := startFakeBatchAgent(t, agent)
+
+	tr, err := runDelegatedGroup(t, []types.Task{
+		{Name: "one", DelegateTo: addr},
+		{Name: "two", DelegateTo: addr},
+		{Name: "three", DelegateTo: addr},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two", "three"}, agent.singles)
+	require.Len(t, tr.Results, 3)
+	for _, result := range tr.Results {
+		require.NotNil(t, result.Usage)
+		assert.Equal(t, 30*time.Millisecond, result.Usage.CPUTime)
+	}
+}
+
+func TestAgentBatch(t *testing.T) {
+	noServices := func(*types.Task) error { return nil }
+	tasks := map[string]*types.Task{
+		"a":     {Name: "a", DelegateTo: "agent1:50051"},
+		"b":     {Name: "b", DelegateTo: "agent1:50051", DependsOn: []string{"a"}},
+		"retry": {Name: "retry", DelegateTo: "agent1:50051", Retries: 2},
+		"c":     {Name: "c", DelegateTo: "agent1:50051"},
+		"other": {Name: "other", DelegateTo: "agent2:50051"},
+		"x":     {Name: "x", DelegateTo: "agent2:50051", Async: true},
+		"y":     {Name: "y", DelegateTo: "agent2:50051", Async: true, DependsOn: []string{"x"}},
+		"local": {Name: "local"},
+	}
+	tr := &TaskRunner{}
+	names := func(batch []*types.Task) []string {
+		var out []string
+		for _, t := range batch {
+			out = append(out, t.Name)
+		}
+		return out
+	}
+
+	batch, host, parallel := tr.agentBatch(types.TaskGroup{}, tasks, []string{"a", "b", "retry", "c"}, 0, map[string]string{}, noServices)
+	assert.Equal(t, []string{"a", "b"}, names(batch), "stops at a task with retries")
+	assert.Equal(t, "agent1:50051", host)
+	assert.False(t, parallel)
+
+	batch, _, _ = tr.agentBatch(types.TaskGroup{}, tasks, []string{"a", "c", "other"}, 0, map[string]string{}, noServices)
+	assert.Equal(t, []string{"a", "c"}, names(batch), "stops at a task for another agent")
+
+	batch, _, _ = tr.agentBatch(types.TaskGroup{}, tasks, []string{"b", "c"}, 0, map[string]string{"a": "Success"}, noServices)
+	assert.Equal(t, []string{"b", "c"}, names(batch))
+
+	batch, _, parallel = tr.agentBatch(types.TaskGroup{}, tasks, []string{"x", "y"}, 0, map[string]string{}, noServices)
+	assert.Equal(t, []string{"x"}, names(batch), "parallel batches can't hold dependent tasks")
+	assert.True(t, parallel)
+
+	batch, _, _ = tr.agentBatch(types.TaskGroup{}, tasks, []string{"local", "a"}, 0, map[string]string{}, noServices)
+	assert.Empty(t, batch)
+}
+
+func TestBatchWorkspace(t *testing.T) {
+	scripts := &types.Task{Name: "a", Workspace: []string{"scripts/"}}
+	configs := &types.Task{Name: "b", Workspace: []string{"configs/app.yaml"}}
+	assert.Equal(t, []string{"scripts/", "configs/app.yaml"}, batchWorkspace([]*types.Task{scripts, configs}))
+	assert.Nil(t, batchWorkspace([]*types.Task{scripts, {Name: "c"}}), "a task without workspace needs all of it")
+}
@@ -0,0 +1,133 @@
+package taskrunner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	lua "github.com/yuin/gopher-lua"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkAgents returns the agents a delegated task would run on, by name
+// and address, without running anything
+func checkAgents(t *types.Task, delegateSource interface{}) (map[string]string, error) {
+	agents := make(map[string]string)
+	hosts, _, err := expandAgentGroups(getHostsList(delegateSource))
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		address := host
+		if !strings.Contains(address, ":") {
+			resolved, err := resolveAgentAddress(host)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve agent '%s': %w", host, err)
+			}
+			address = resolved
+		}
+		agents[host] = address
+	}
+	if len(agents) > 0 {
+		return agents, nil
+	}
+
+	m, ok := delegateSource.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no agent to check the task on")
+	}
+	if facts, candidates, ok := factsSelector(m); ok {
+		name, address, err := selectAgentByFacts(runtimeFacts(t, facts), candidates)
+		if err != nil {
+			return nil, err
+		}
+		agents[name] = address
+	} else if address, ok := m["address"].(string); ok {
+		agents[address] = address
+	} else {
+		return nil, fmt.Errorf("invalid agent definition in delegate_to: missing address")
+	}
+	return agents, nil
+}
+
+// checkOnAgents runs a delegated task in check mode on each of its agents
+// and returns the changes they report, with the agent in Host. Agents too
+// old to check tasks report an unknown change instead.
+func (tr *TaskRunner) checkOnAgents(ctx context.Context, t *types.Task, delegateSource interface{}, inputs *lua.LTable, session *types.SharedSession, groupName string) ([]taskctx.Change, error) {
+	agents, err := checkAgents(t, delegateSource)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspace bytes.Buffer
+	if err := createTar(session.Workdir, t.Workspace, &workspace); err != nil {
+		return nil, fmt.Errorf("failed to create workspace tarball: %w", err)
+	}
+
+	var changes []taskctx.Change
+	for host, address := range agents {
+		hostChanges, err := tr.checkOnAgent(ctx, t, inputs, host, address, workspace.Bytes(), groupName)
+		if err != nil {
+			return changes, err
+		}
+		for i := range hostChanges {
+			hostChanges[i].Host = host
+		}
+		changes = append(changes, hostChanges...)
+	}
+	return changes, nil
+}
+
+// checkOnAgent runs a task in check mode on an agent
+func (tr *TaskRunner) checkOnAgent(ctx context.Context, t *types.Task, inputs *lua.LTable, host, address string, workspace []byte, groupName string) ([]taskctx.Change, error) {
+	conn, err := grpc.Dial(address, reliability.AgentDialOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	client := pb.NewAgentClient(conn)
+	sudoPassword, err := sealSudoPassword(ctx, client, host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.CheckTasks(ctx, &pb.ExecuteTasksRequest{
+		TaskNames:    []string{t.Name},
+		TaskGroup:    groupName,
+		LuaScript:    tr.LuaScript,
+		Workspace:    workspace,
+		User:         t.User,
+		Approvals:    taskctx.Approvals(ctx),
+		SudoPassword: sudoPassword,
+		InputsJson:   encodeTaskInputs(tr.L, inputs),
+	})
+	if status.Code(err) == codes.Unimplemented {
+		return []taskctx.Change{{Module: "delegate_to", Action: "run", Target: host, Current: "agent too old to check tasks", Unknown: true}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check task on agent %s: %w", address, err)
+	}
+
+	for _, r := range resp.GetResults() {
+		if r.GetTaskName() != t.Name {
+			continue
+		}
+		changes, err := decodeTaskChanges(r.GetChangesJson())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode changes from agent %s: %w", address, err)
+		}
+		if r.GetStatus() == "Failed" {
+			return changes, fmt.Errorf("check failed on agent %s: %s", address, r.GetError())
+		}
+		return changes, nil
+	}
+	return nil, fmt.Errorf("agent %s returned no result", address)
+}
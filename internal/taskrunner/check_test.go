@@ -0,0 +1,82 @@
+package taskrunner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeCheckAgent reports a service it would start for each task it checks
+type fakeCheckAgent struct {
+	fakeBatchAgent
+	checked []string
+}
+
+func (f *fakeCheckAgent) CheckTasks(ctx context.Context, in *pb.ExecuteTasksRequest) (*pb.ExecuteTasksResponse, error) {
+	f.mu.Lock()
+	f.checked = append(f.checked, in.GetTaskNames()...)
+	f.mu.Unlock()
+
+	resp := &pb.ExecuteTasksResponse{}
+	for _, name := range in.GetTaskNames() {
+		resp.Results = append(resp.Results, &pb.TaskRunResult{
+			TaskName:    name,
+			Status:      "DryRun",
+			ChangesJson: `[{"module":"systemd","action":"start","target":"nginx","current":"inactive"}]`,
+		})
+	}
+	return resp, nil
+}
+
+func checkDelegatedGroup(t *testing.T, tasks []types.Task) (*TaskRunner, error) {
+	t.Helper()
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	groups := map[string]types.TaskGroup{"test_group": {Tasks: tasks, Workdir: t.TempDir()}}
+	tr := NewTaskRunner(L, groups, "test_group", nil, true, false, &DefaultSurveyAsker{}, "")
+	tr.CheckDelegated = true
+	return tr, tr.Run()
+}
+
+// TestRun_CheckDelegated validates that a dry run checking delegated tasks
+// gets their changes from the agent, which runs nothing for real
+func TestRun_CheckDelegated(t *testing.T) {
+	agent := &fakeCheckAgent{}
+	addr := startFakeBatchAgent(t, agent)
+
+	tr, err := checkDelegatedGroup(t, []types.Task{{Name: "web", DelegateTo: addr}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"web"}, agent.checked)
+	assert.Empty(t, agent.calls)
+	assert.Empty(t, agent.singles)
+	require.Len(t, tr.Results, 1)
+	assert.Equal(t, "DryRun", tr.Results[0].Status)
+	assert.Equal(t, []taskctx.Change{{Module: "systemd", Action: "start", Target: "nginx", Current: "inactive", Host: addr}}, tr.Results[0].Changes)
+}
+
+// TestRun_CheckDelegatedUnsupported validates that agents without
+// CheckTasks get an unknown change instead of running the task
+func TestRun_CheckDelegatedUnsupported(t *testing.T) {
+	agent := &fakeBatchAgent{batches: true}
+	addr := startFakeBatchAgent(t, agent)
+
+	tr, err := checkDelegatedGroup(t, []types.Task{{Name: "web", DelegateTo: addr}})
+	require.NoError(t, err)
+
+	assert.Empty(t, agent.calls)
+	assert.Empty(t, agent.singles)
+	require.Len(t, tr.Results, 1)
+	require.Len(t, tr.Results[0].Changes, 1)
+	assert.True(t, tr.Results[0].Changes[0].Unknown)
+	assert.Equal(t, addr, tr.Results[0].Changes[0].Host)
+}
@@ -4,24 +4,39 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/download"
 	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/output"
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sudo"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
 	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/google/uuid"
 	"github.com/pterm/pterm"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	lua "github.com/yuin/gopher-lua"
 )
 
-// executeOnAgent handles execution of a task on a remote agent via gRPC
-func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, agentAddress string, session *types.SharedSession, groupName string) error {
+// executeOnAgent handles execution of a task on a remote agent via gRPC.
+// agentName is the agent as delegate_to names it.
+// executeOnAgent runs a task on an agent, giving it inputs, the outputs of
+// its dependencies. It returns what the task's processes consumed there,
+// when the agent measured it, the task's outputs and the changes its
+// module calls made, when the agent sent them.
+func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, inputs *lua.LTable, agentName, agentAddress string, session *types.SharedSession, groupName string) (*agentTaskResult, error) {
 	// Connect to the agent
 	pterm.DefaultBox.
 		WithTitle("🔗 Agent Connection").
@@ -29,7 +44,7 @@ func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, agentAd
 		WithBoxStyle(pterm.NewStyle(pterm.FgCyan)).
 		Printfln("Task:  %s\nAgent: %s", pterm.Cyan(t.Name), pterm.Yellow(agentAddress))
 
-	conn, err := grpc.Dial(agentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(agentAddress, reliability.AgentDialOptions()...)
 	if err != nil {
 		pterm.Println()
 		pterm.DefaultBox.
@@ -53,14 +68,14 @@ func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, agentAd
 			"agent_address", agentAddress,
 			"task", t.Name,
 			"error", err)
-		return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to connect to agent %s: %w", agentAddress, err)}
+		return nil, &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to connect to agent %s: %w", agentAddress, err)}
 	}
 	defer conn.Close()
 	c := pb.NewAgentClient(conn)
 
 	// Create a tarball of the workspace
 	var buf bytes.Buffer
-	if err := createTar(session.Workdir, &buf); err != nil {
+	if err := createTar(session.Workdir, t.Workspace, &buf); err != nil {
 		pterm.Println()
 		pterm.DefaultBox.
 			WithTitle("❌ WORKSPACE ERROR").
@@ -77,22 +92,39 @@ func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, agentAd
 		slog.Error("Failed to create workspace tarball",
 			"task", t.Name,
 			"error", err)
-		return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to create workspace tarball: %w", err)}
+		return nil, &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to create workspace tarball: %w", err)}
 	}
 
 	// Generate a script compatible with agent execution (without delegate_to)
 	agentScript := tr.generateAgentScript(t, groupName)
 
+	sudoPassword, err := sealSudoPassword(ctx, c, agentName)
+	if err != nil {
+		return nil, &TaskExecutionError{TaskName: t.Name, Err: err}
+	}
+
 	pterm.Info.Printfln("📤 Sending task to agent...")
 
-	// Send the task and workspace to the agent
-	r, err := c.ExecuteTask(ctx, &pb.ExecuteTaskRequest{
+	out, flush := liveOutput(ctx, t.Name+"@"+agentName)
+	defer flush()
+
+	// Retries of the request reuse the key, so the agent runs the task
+	// once; the journal keeps it to ask the agent if the run is interrupted
+	key := uuid.NewString()
+	tr.journalTaskStarted(t.Name, agentName, agentAddress, key)
+
+	// Send the task and workspace to the agent, showing its output live
+	r, err := executeTaskStreaming(ctx, c, &pb.ExecuteTaskRequest{
 		TaskName:  t.Name,
 		TaskGroup: groupName,
 		LuaScript: agentScript,
 		Workspace: buf.Bytes(),
 		User:      t.User,
-	})
+		Approvals: taskctx.Approvals(ctx),
+		IdempotencyKey: key,
+		SudoPassword:   sudoPassword,
+		InputsJson:     encodeTaskInputs(tr.L, inputs),
+	}, out)
 	if err != nil {
 		pterm.Error.Println("═════════════════════════════════════════════════════════════════════════════════════")
 		pterm.Error.Printfln("❌ FAILED TO SEND/EXECUTE TASK ON AGENT")
@@ -107,7 +139,7 @@ func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, agentAd
 			"agent_address", agentAddress,
 			"task", t.Name,
 			"error", err)
-		return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to execute task on agent %s: %w", agentAddress, err)}
+		return nil, &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to execute task on agent %s: %w", agentAddress, err)}
 	}
 
 	if !r.GetSuccess() {
@@ -145,7 +177,9 @@ func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, agentAd
 			"error", agentError)
 
 		// Include the actual error from the agent in the returned error
-		return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("agent execution failed on %s:\n%s", agentAddress, agentError)}
+		// Calls that timed out are often why the task failed
+		slowCalls, _ := decodeSlowCalls(r.GetSlowCallsJson(), agentName)
+		return &agentTaskResult{usage: taskusage.FromProto(r.GetUsage()), slowCalls: slowCalls}, &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("agent execution failed on %s:\n%s", agentAddress, agentError)}
 	}
 
 	pterm.DefaultBox.
@@ -175,22 +209,206 @@ func (tr *TaskRunner) executeOnAgent(ctx context.Context, t *types.Task, agentAd
 			"agent_address", agentAddress,
 			"task", t.Name,
 			"error", err)
-		return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to extract updated workspace from agent %s: %w", agentAddress, err)}
+		return nil, &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("failed to extract updated workspace from agent %s: %w", agentAddress, err)}
 	}
 
 	pterm.Info.Printfln("📥 Workspace synchronized")
-	return nil
+
+	result := &agentTaskResult{usage: taskusage.FromProto(r.GetUsage())}
+	if t.WorkspaceDiff {
+		if result.workspaceChanges, err = diffWorkspaces(buf.Bytes(), r.GetWorkspace()); err != nil {
+			slog.Warn("Failed to diff the workspace of task", "agent_address", agentAddress, "task", t.Name, "error", err)
+		} else {
+			printWorkspaceChanges(result.workspaceChanges)
+		}
+	}
+	if result.outputs, err = decodeTaskOutputs(r.GetOutputsJson()); err != nil {
+		slog.Warn("Failed to decode task outputs from agent", "agent_address", agentAddress, "task", t.Name, "error", err)
+	}
+	if result.setOutputs, err = decodeSetOutputs(r.GetSetOutputsJson()); err != nil {
+		slog.Warn("Failed to decode set outputs from agent", "agent_address", agentAddress, "task", t.Name, "error", err)
+	}
+	if result.changes, err = decodeTaskChanges(r.GetChangesJson()); err != nil {
+		slog.Warn("Failed to decode task changes from agent", "agent_address", agentAddress, "task", t.Name, "error", err)
+	}
+	if result.slowCalls, err = decodeSlowCalls(r.GetSlowCallsJson(), agentName); err != nil {
+		slog.Warn("Failed to decode slow calls from agent", "agent_address", agentAddress, "task", t.Name, "error", err)
+	}
+	return result, nil
+}
+
+// agentTaskResult is what a task delegated to an agent reported besides
+// its success
+type agentTaskResult struct {
+	usage            *taskusage.Usage
+	outputs          map[string]interface{}
+	setOutputs       map[string]bool
+	changes          []taskctx.Change
+	workspaceChanges []types.WorkspaceChange
+	slowCalls        []taskctx.SlowCall
+}
+
+// sealSudoPassword returns the run's sudo password for agent sealed to the
+// agent's key, or nil when the agent's sudo doesn't need one
+func sealSudoPassword(ctx context.Context, c pb.AgentClient, agent string) ([]byte, error) {
+	password, err := taskctx.SudoPassword(ctx, agent)
+	if err != nil || password == nil {
+		return nil, err
+	}
+	resp, err := c.GetSudoKey(ctx, &pb.SudoKeyRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, fmt.Errorf("agent %s can't take a sudo password; update it", agent)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the sudo key of agent %s: %w", agent, err)
+	}
+	return sudo.Seal(password, resp.GetPublicKey())
+}
+
+// executeTaskStreaming runs a task on an agent, copying the output modules
+// stream while it runs to out and reporting its progress to ctx. Agents without ExecuteTaskStream run it
+// with ExecuteTask instead.
+func executeTaskStreaming(ctx context.Context, c pb.AgentClient, req *pb.ExecuteTaskRequest, out io.Writer) (*pb.ExecuteTaskResponse, error) {
+	stream, err := c.ExecuteTaskStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		event, err := stream.Recv()
+		if status.Code(err) == codes.Unimplemented {
+			slog.Debug("Agent does not stream task output, falling back to ExecuteTask")
+			return c.ExecuteTask(ctx, req)
+		}
+		if err == io.EOF {
+			return nil, fmt.Errorf("agent closed the stream without a result")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk := event.GetOutputChunk(); chunk != "" {
+			io.WriteString(out, chunk)
+		}
+		if p := event.GetProgress(); p != nil {
+			taskctx.ReportProgress(ctx, taskctx.Progress{Percent: p.GetPercent(), Message: p.GetMessage()})
+		}
+		if result := event.GetResult(); result != nil {
+			return result, nil
+		}
+	}
+}
+
+// encodeTaskInputs encodes the outputs of a task's dependencies for the
+// agent running it
+func encodeTaskInputs(L *lua.LState, inputs *lua.LTable) string {
+	if inputs == nil {
+		return ""
+	}
+	if key, _ := inputs.Next(lua.LNil); key == lua.LNil {
+		return ""
+	}
+	data, err := json.Marshal(luainterface.LuaToGoValue(L, inputs))
+	if err != nil {
+		slog.Warn("Failed to encode task inputs", "error", err)
+		return ""
+	}
+	return string(data)
+}
+
+// decodeTaskOutputs decodes the outputs an agent sent for a task, nil when
+// it sent none
+func decodeTaskOutputs(data string) (map[string]interface{}, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var outputs map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// decodeSetOutputs decodes the names of the outputs a task set with
+// task.set_output on an agent, true for the sensitive ones
+func decodeSetOutputs(data string) (map[string]bool, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var names map[string]bool
+	if err := json.Unmarshal([]byte(data), &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// decodeTaskChanges decodes the changes an agent sent for a task
+func decodeTaskChanges(data string) ([]taskctx.Change, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var changes []taskctx.Change
+	if err := json.Unmarshal([]byte(data), &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// decodeSlowCalls decodes the slow calls an agent reported for a task,
+// marked with the agent they ran on
+func decodeSlowCalls(data, host string) ([]taskctx.SlowCall, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var calls []taskctx.SlowCall
+	if err := json.Unmarshal([]byte(data), &calls); err != nil {
+		return nil, err
+	}
+	for i := range calls {
+		calls[i].Host = host
+	}
+	return calls, nil
+}
+
+// liveOutput returns where the live output of a task goes: the output of
+// the run when it has one, as on agents, or stdout with each line prefixed
+// by label so the output of tasks and agents running at the same time
+// stays apart. flush writes an unfinished last line.
+func liveOutput(ctx context.Context, label string) (io.Writer, func()) {
+	if taskctx.HasOutput(ctx) {
+		return taskctx.Output(ctx), func() {}
+	}
+	w := output.Default().Prefixed(label)
+	return w, func() { w.Flush() }
 }
 
 // executeLocally handles execution of a task locally using Lua
 func (tr *TaskRunner) executeLocally(ctx context.Context, t *types.Task, inputFromDependencies *lua.LTable, session *types.SharedSession, groupName string) error {
-	L := lua.NewState()
-	defer L.Close()
-	luainterface.OpenAll(L)
+	var L *lua.LState
+	if tr.StatePool != nil {
+		L = tr.StatePool.Get()
+		defer tr.StatePool.Put(L)
+	} else {
+		L = lua.NewState()
+		defer L.Close()
+		luainterface.OpenAll(L)
+	}
 
 	localInputFromDependencies := luainterface.CopyTable(inputFromDependencies, L)
 	t.Output = L.NewTable()
 
+	// Downloads of the task share its bandwidth limit
+	ctx = download.WithTask(ctx)
+
+	out, flush := liveOutput(ctx, t.Name)
+	defer flush()
+	ctx = taskctx.WithOutput(ctx, out)
+
+	// Pause here when stepping in the debugger (run --debug)
+	if d := luainterface.ActiveDebugger(); d != nil {
+		if err := d.BeforeTask(L, t.Name, t.Params); err != nil {
+			return &TaskExecutionError{TaskName: t.Name, Err: err}
+		}
+	}
+
 	// Execute pre_exec hook
 	if t.PreExec != nil {
 		success, msg, _, err := luainterface.ExecuteLuaFunction(L, t.PreExec, t.Params, localInputFromDependencies, 2, ctx)
@@ -219,6 +437,10 @@ func (tr *TaskRunner) executeLocally(ctx context.Context, t *types.Task, inputFr
 			}
 		}
 		t.Params["workdir"] = taskWorkdir
+		t.Params["assets_dir"] = session.Workdir
+		if tr.AssetsDir != "" {
+			t.Params["assets_dir"] = tr.AssetsDir
+		}
 
 		var sessionUD *lua.LUserData
 		if session != nil {
@@ -242,6 +464,7 @@ func (tr *TaskRunner) executeLocally(ctx context.Context, t *types.Task, inputFr
 			return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("command function returned failure: %s", msg)}
 		} else if outputTable != nil {
 			t.Output = outputTable
+			t.SetOutputs = luainterface.SetOutputs(L)
 			// Execute OnSuccess handler if command was successful
 			if t.OnSuccess != nil {
 				tr.executeSuccessHandler(L, t, ctx, outputTable)
@@ -271,12 +494,73 @@ func (tr *TaskRunner) executeLocally(ctx context.Context, t *types.Task, inputFr
 	return nil
 }
 
-// createTar creates a tarball from the source directory
-func createTar(source string, writer io.Writer) error {
+// createTar creates a tarball from the source directory. When paths is not
+// empty only those paths of it are packed, as declared by a task's
+// workspace field.
+func createTar(source string, paths []string, writer io.Writer) error {
+	roots, err := workspaceRoots(source, paths)
+	if err != nil {
+		return err
+	}
+
 	tarWriter := tar.NewWriter(writer)
 	defer tarWriter.Close()
 
-	return filepath.Walk(source, func(file string, fi os.FileInfo, err error) error {
+	for _, root := range roots {
+		if err := filepath.Walk(root, tarWalkFunc(tarWriter, source)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspaceRoots resolves the workspace paths a task declared into the
+// files and directories to pack, the whole source directory when none
+// were declared. Paths ending in / must be directories.
+func workspaceRoots(source string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return []string{source}, nil
+	}
+
+	var rels []string
+	for _, p := range paths {
+		rel := filepath.Clean(filepath.FromSlash(p))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("workspace path %q is outside the workspace", p)
+		}
+		if rel == "." {
+			return []string{source}, nil
+		}
+		fi, err := os.Stat(filepath.Join(source, rel))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("workspace path %q not found in %s", p, source)
+			}
+			return nil, err
+		}
+		if strings.HasSuffix(p, "/") && !fi.IsDir() {
+			return nil, fmt.Errorf("workspace path %q is not a directory", p)
+		}
+		rels = append(rels, rel)
+	}
+
+	// Skip paths inside another declared directory so nothing is packed twice
+	sort.Strings(rels)
+	var roots []string
+	var last string
+	for _, rel := range rels {
+		if last != "" && (rel == last || strings.HasPrefix(rel, last+string(filepath.Separator))) {
+			continue
+		}
+		roots = append(roots, filepath.Join(source, rel))
+		last = rel
+	}
+	return roots, nil
+}
+
+// tarWalkFunc writes each file walked to tarWriter, named relative to source
+func tarWalkFunc(tarWriter *tar.Writer, source string) filepath.WalkFunc {
+	return func(file string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -307,7 +591,7 @@ func createTar(source string, writer io.Writer) error {
 			}
 		}
 		return nil
-	})
+	}
 }
 
 // extractTar extracts a tarball to the destination directory
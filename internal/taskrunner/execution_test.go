@@ -1,8 +1,23 @@
 package taskrunner
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
 	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/sudo"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestExecuteShellCondition_Success(t *testing.T) {
@@ -264,3 +279,202 @@ func BenchmarkExecuteShellCondition_WithPipe(b *testing.B) {
 		executeShellCondition("echo test | grep test > /dev/null")
 	}
 }
+
+// fakeTaskStream replays events for executeTaskStreaming
+type fakeTaskStream struct {
+	grpc.ClientStream
+	events []*pb.ExecuteTaskEvent
+	err    error
+}
+
+func (f *fakeTaskStream) Recv() (*pb.ExecuteTaskEvent, error) {
+	if len(f.events) == 0 {
+		if f.err != nil {
+			return nil, f.err
+		}
+		return nil, io.EOF
+	}
+	event := f.events[0]
+	f.events = f.events[1:]
+	return event, nil
+}
+
+type fakeStreamingAgent struct {
+	pb.AgentClient
+	stream    *fakeTaskStream
+	unaryUsed bool
+}
+
+func (f *fakeStreamingAgent) ExecuteTaskStream(ctx context.Context, in *pb.ExecuteTaskRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.ExecuteTaskEvent], error) {
+	return f.stream, nil
+}
+
+func (f *fakeStreamingAgent) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest, opts ...grpc.CallOption) (*pb.ExecuteTaskResponse, error) {
+	f.unaryUsed = true
+	return &pb.ExecuteTaskResponse{Success: true, Output: "unary"}, nil
+}
+
+func TestExecuteTaskStreaming(t *testing.T) {
+	agent := &fakeStreamingAgent{stream: &fakeTaskStream{events: []*pb.ExecuteTaskEvent{
+		{OutputChunk: "building...\n"},
+		{OutputChunk: "done\n"},
+		{Result: &pb.ExecuteTaskResponse{Success: true, Output: "ok"}},
+	}}}
+
+	var out bytes.Buffer
+	resp, err := executeTaskStreaming(context.Background(), agent, &pb.ExecuteTaskRequest{TaskName: "rebuild"}, &out)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !resp.GetSuccess() || resp.GetOutput() != "ok" {
+		t.Errorf("Unexpected result: %v", resp)
+	}
+	if out.String() != "building...\ndone\n" {
+		t.Errorf("Expected streamed output, got %q", out.String())
+	}
+	if agent.unaryUsed {
+		t.Error("Expected the streaming RPC to be used")
+	}
+}
+
+func TestExecuteTaskStreaming_ReportsProgress(t *testing.T) {
+	agent := &fakeStreamingAgent{stream: &fakeTaskStream{events: []*pb.ExecuteTaskEvent{
+		{Progress: &pb.TaskProgress{Percent: 40, Message: "uploading artifacts"}},
+		{Progress: &pb.TaskProgress{Percent: 100}},
+		{Result: &pb.ExecuteTaskResponse{Success: true}},
+	}}}
+
+	var got []taskctx.Progress
+	ctx := taskctx.WithProgress(context.Background(), func(p taskctx.Progress) {
+		got = append(got, p)
+	})
+	if _, err := executeTaskStreaming(ctx, agent, &pb.ExecuteTaskRequest{TaskName: "deploy"}, io.Discard); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(got) != 2 || got[0].Percent != 40 || got[0].Message != "uploading artifacts" || got[1].Percent != 100 {
+		t.Errorf("Unexpected progress: %+v", got)
+	}
+}
+
+func TestTaskProgress_Timeline(t *testing.T) {
+	p := &taskProgress{task: "deploy"}
+	p.report(taskctx.Progress{Percent: 10})
+	p.report(taskctx.Progress{Percent: 10})
+	for i := 0; i < maxProgressEvents+10; i++ {
+		p.report(taskctx.Progress{Percent: float64(i) / 2})
+	}
+
+	timeline := p.timeline()
+	if len(timeline) != maxProgressEvents {
+		t.Fatalf("Expected %d events, got %d", maxProgressEvents, len(timeline))
+	}
+	if timeline[0].Percent != 10 || timeline[1].Percent != 0 {
+		t.Errorf("Expected the first events to be kept, got %+v", timeline[:2])
+	}
+	if last := timeline[len(timeline)-1]; last.Percent != float64(maxProgressEvents+9)/2 {
+		t.Errorf("Expected the timeline to end with the latest update, got %+v", last)
+	}
+}
+
+func TestExecuteTaskStreaming_FallsBackForOldAgents(t *testing.T) {
+	agent := &fakeStreamingAgent{stream: &fakeTaskStream{err: status.Error(codes.Unimplemented, "unknown method")}}
+
+	resp, err := executeTaskStreaming(context.Background(), agent, &pb.ExecuteTaskRequest{TaskName: "rebuild"}, io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !agent.unaryUsed || resp.GetOutput() != "unary" {
+		t.Errorf("Expected fallback to ExecuteTask, got %v", resp)
+	}
+}
+
+type fakeSudoAgent struct {
+	pb.AgentClient
+	key *sudo.KeyPair
+}
+
+func (f *fakeSudoAgent) GetSudoKey(ctx context.Context, in *pb.SudoKeyRequest, opts ...grpc.CallOption) (*pb.SudoKeyResponse, error) {
+	if f.key == nil {
+		return nil, status.Error(codes.Unimplemented, "unknown method")
+	}
+	return &pb.SudoKeyResponse{PublicKey: f.key.PublicKey()}, nil
+}
+
+func TestSealSudoPassword(t *testing.T) {
+	key, err := sudo.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := taskctx.WithSudoPassword(context.Background(), func(agent string) ([]byte, error) {
+		if agent == "web-01" {
+			return []byte("s3cret"), nil
+		}
+		return nil, nil
+	})
+
+	sealed, err := sealSudoPassword(ctx, &fakeSudoAgent{key: key}, "web-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := key.Open(sealed); err != nil || string(password) != "s3cret" {
+		t.Errorf("Expected the sealed password, got %q (%v)", password, err)
+	}
+
+	if sealed, err := sealSudoPassword(ctx, &fakeSudoAgent{key: key}, "web-02"); err != nil || sealed != nil {
+		t.Errorf("Expected no password for an agent that doesn't need one, got %v (%v)", sealed, err)
+	}
+	if _, err := sealSudoPassword(ctx, &fakeSudoAgent{}, "web-01"); err == nil {
+		t.Error("Expected agents without a sudo key to be rejected")
+	}
+}
+
+func TestCreateTar_Workspace(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"scripts/deploy.sh", "scripts/lib/common.sh", "configs/app.yaml", "configs/db.yaml", "data/big.bin"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	packed := func(paths []string) []string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := createTar(dir, paths, &buf); err != nil {
+			t.Fatalf("createTar(%v) failed: %v", paths, err)
+		}
+		var files []string
+		tr := tar.NewReader(&buf)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header.Typeflag == tar.TypeReg {
+				files = append(files, filepath.ToSlash(header.Name))
+			}
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	if files := packed(nil); len(files) != 5 {
+		t.Errorf("Expected the whole workspace, got %v", files)
+	}
+	want := []string{"configs/app.yaml", "scripts/deploy.sh", "scripts/lib/common.sh"}
+	if files := packed([]string{"scripts/", "configs/app.yaml", "scripts/lib/"}); !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+
+	for _, paths := range [][]string{{"missing/"}, {"../outside"}, {"/etc/passwd"}, {"configs/app.yaml/"}} {
+		if err := createTar(dir, paths, io.Discard); err == nil {
+			t.Errorf("Expected workspace %v to be rejected", paths)
+		}
+	}
+}
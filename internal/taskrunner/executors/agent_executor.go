@@ -6,11 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"github.com/pterm/pterm"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // AgentExecutor executes tasks on remote agents via gRPC
@@ -35,7 +35,7 @@ func (ae *AgentExecutor) Execute(
 		WithBoxStyle(pterm.NewStyle(pterm.FgCyan)).
 		Printfln("Task:  %s\nAgent: %s", pterm.Cyan(task.Name), pterm.Yellow(agentAddress))
 
-	conn, err := grpc.Dial(agentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(agentAddress, mtls.DialOption())
 	if err != nil {
 		pterm.Println()
 		pterm.DefaultBox.
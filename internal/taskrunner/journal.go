@@ -0,0 +1,33 @@
+package taskrunner
+
+import "log/slog"
+
+// TaskJournal records what a run does as it does it: a task is recorded
+// before it starts, with the idempotency key of the request when it runs on
+// an agent, and once its status is known
+type TaskJournal interface {
+	TaskStarted(task, agent, agentAddress, key string) error
+	TaskFinished(task, status string, err error, outputs map[string]interface{}) error
+}
+
+// journalTaskStarted records that task is about to start, locally when
+// agent is empty. A journal that can't be written only loses the ability
+// to reconcile the run, so the run goes on.
+func (tr *TaskRunner) journalTaskStarted(task, agent, agentAddress, key string) {
+	if tr.Journal == nil {
+		return
+	}
+	if err := tr.Journal.TaskStarted(task, agent, agentAddress, key); err != nil {
+		slog.Warn("Failed to write run journal", "task", task, "error", err)
+	}
+}
+
+// journalTaskFinished records the status of task
+func (tr *TaskRunner) journalTaskFinished(task, status string, taskErr error, outputs map[string]interface{}) {
+	if tr.Journal == nil {
+		return
+	}
+	if err := tr.Journal.TaskFinished(task, status, taskErr, outputs); err != nil {
+		slog.Warn("Failed to write run journal", "task", task, "error", err)
+	}
+}
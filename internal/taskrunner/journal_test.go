@@ -0,0 +1,66 @@
+package taskrunner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeJournal records the events the runner journals
+type fakeJournal struct {
+	events []string
+}
+
+func (j *fakeJournal) TaskStarted(task, agent, agentAddress, key string) error {
+	j.events = append(j.events, fmt.Sprintf("started %s agent=%q", task, agent))
+	return nil
+}
+
+func (j *fakeJournal) TaskFinished(task, status string, err error, outputs map[string]interface{}) error {
+	j.events = append(j.events, fmt.Sprintf("finished %s %s", task, status))
+	return nil
+}
+
+func TestRun_ResumedTasksAndJournal(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	luainterface.OpenAll(L)
+
+	fail := L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("failed"))
+		L.Push(L.NewTable())
+		return 3
+	})
+	build := types.Task{Name: "build", CommandFunc: fail}
+	deploy := types.Task{Name: "deploy", CommandStr: "true", DependsOn: []string{"build"}}
+	smoke := types.Task{Name: "smoke", CommandFunc: fail, DependsOn: []string{"deploy"}}
+	notify := types.Task{Name: "notify", CommandStr: "true", DependsOn: []string{"smoke"}}
+	groups := map[string]types.TaskGroup{
+		"test_group": {Tasks: []types.Task{build, deploy, smoke, notify}},
+	}
+
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	journal := &fakeJournal{}
+	tr.Journal = journal
+	tr.Resumed = map[string]bool{"build": true}
+	tr.Inputs = map[string]interface{}{"build": map[string]interface{}{"artifact": "app-1.2.3.tar.gz"}}
+	err := tr.Run()
+
+	assert.Error(t, err, "smoke fails")
+	require.NotEmpty(t, tr.Results)
+	assert.Equal(t, types.TaskResult{Name: "build", Status: "Success"}, tr.Results[0], "resumed tasks aren't run again")
+	assert.Equal(t, map[string]interface{}{"artifact": "app-1.2.3.tar.gz"}, tr.Outputs["build"])
+	assert.Equal(t, []string{
+		`started deploy agent=""`,
+		"finished deploy Success",
+		`started smoke agent=""`,
+		"finished smoke Failed",
+		"finished notify Skipped",
+	}, journal.events)
+}
@@ -3,16 +3,20 @@ package taskrunner
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
 	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/google/uuid"
 	"github.com/pterm/pterm"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // MultiHostResult holds the result of task execution on multiple hosts
@@ -21,18 +25,89 @@ type MultiHostResult struct {
 	Success bool
 	Output  string
 	Error   error
+	// Outputs are the outputs the task set on the host
+	Outputs map[string]interface{}
+	// Skipped is set on the hosts fail_fast kept the task from starting on
+	Skipped bool
+	// Changes are what the task's module calls changed on the host
+	Changes []taskctx.Change
 }
 
-// executeTaskOnMultipleHosts executes a task on multiple hosts in parallel
-func (tr *TaskRunner) executeTaskOnMultipleHosts(ctx context.Context, t *types.Task, hosts []string, session *types.SharedSession, groupName string) ([]MultiHostResult, error) {
-	var wg sync.WaitGroup
+// errSkippedFailFast is the error of the hosts a fail_fast task didn't
+// start on after it failed on another host
+var errSkippedFailFast = errors.New("not started, the task failed on another host (fail_fast)")
+
+// fanOut calls run for every host, on at most limit hosts at a time (all of
+// them at once when limit is 0 or less), and returns the results in the
+// order of hosts. Hosts start in order; with failFast, the ones not started
+// when the task fails on a host are skipped while the running ones finish.
+func fanOut(hosts []string, limit int, failFast bool, run func(host string) MultiHostResult) []MultiHostResult {
 	results := make([]MultiHostResult, len(hosts))
+	if limit <= 0 || limit > len(hosts) {
+		limit = len(hosts)
+	}
+
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	slots := make(chan struct{}, limit)
+	for i, host := range hosts {
+		slots <- struct{}{}
+		if failFast && failed.Load() {
+			<-slots
+			results[i] = MultiHostResult{Host: host, Skipped: true, Error: errSkippedFailFast}
+			continue
+		}
+
+		wg.Add(1)
+		go func(index int, host string) {
+			defer wg.Done()
+			result := run(host)
+			if !result.Success || result.Error != nil {
+				failed.Store(true)
+			}
+			results[index] = result
+			<-slots
+		}(i, host)
+	}
+	wg.Wait()
+	return results
+}
+
+// multiHostOutputs are the outputs of a task run on several hosts: a table
+// per host with success, skipped, changed, output, error and the outputs
+// the task set there
+func multiHostOutputs(results []MultiHostResult) map[string]interface{} {
+	outputs := make(map[string]interface{}, len(results))
+	for _, result := range results {
+		host := map[string]interface{}{
+			"success": result.Success && result.Error == nil,
+			"skipped": result.Skipped,
+			"changed": len(result.Changes) > 0,
+			"output":  result.Output,
+		}
+		if result.Error != nil {
+			host["error"] = result.Error.Error()
+		}
+		if result.Outputs != nil {
+			host["outputs"] = result.Outputs
+		}
+		outputs[result.Host] = host
+	}
+	return outputs
+}
 
+// executeTaskOnMultipleHosts executes a task on multiple hosts in parallel,
+// on at most max_concurrency of them at a time
+func (tr *TaskRunner) executeTaskOnMultipleHosts(ctx context.Context, t *types.Task, hosts []string, session *types.SharedSession, groupName string) ([]MultiHostResult, error) {
 	// Show header for multi-host execution
+	title := fmt.Sprintf("🚀 Executing task '%s' on %d hosts", t.Name, len(hosts))
+	if t.MaxConcurrency > 0 && t.MaxConcurrency < len(hosts) {
+		title += fmt.Sprintf(", %d at a time", t.MaxConcurrency)
+	}
 	pterm.DefaultHeader.
 		WithBackgroundStyle(pterm.NewStyle(pterm.BgBlue)).
 		WithTextStyle(pterm.NewStyle(pterm.FgWhite)).
-		Println(fmt.Sprintf("🚀 Executing task '%s' on %d hosts", t.Name, len(hosts)))
+		Println(title)
 	pterm.Println()
 
 	// Create a table for real-time status
@@ -48,91 +123,102 @@ func (tr *TaskRunner) executeTaskOnMultipleHosts(ctx context.Context, t *types.T
 	pterm.Println()
 
 	// Execute on each host in parallel
-	for i, host := range hosts {
-		wg.Add(1)
-		go func(index int, hostAddr string) {
-			defer wg.Done()
+	results := fanOut(hosts, t.MaxConcurrency, t.FailFast, func(hostAddr string) MultiHostResult {
+		result := MultiHostResult{
+			Host: hostAddr,
+		}
 
-			result := MultiHostResult{
-				Host: hostAddr,
+		// Try to resolve host if it's a name
+		agentAddress := hostAddr
+		if !strings.Contains(hostAddr, ":") {
+			// Try to resolve agent name to address
+			resolvedAddress, err := resolveAgentAddress(hostAddr)
+			if err != nil {
+				result.Error = fmt.Errorf("failed to resolve agent '%s': %w", hostAddr, err)
+				pterm.Error.Printf("❌ Failed to resolve host %s: %v\n", hostAddr, err)
+				return result
 			}
+			agentAddress = resolvedAddress
+		}
 
-			// Try to resolve host if it's a name
-			agentAddress := hostAddr
-			if !strings.Contains(hostAddr, ":") {
-				// Try to resolve agent name to address
-				resolvedAddress, err := resolveAgentAddress(hostAddr)
-				if err != nil {
-					result.Error = fmt.Errorf("failed to resolve agent '%s': %w", hostAddr, err)
-					results[index] = result
-					pterm.Error.Printf("❌ Failed to resolve host %s: %v\n", hostAddr, err)
-					return
-				}
-				agentAddress = resolvedAddress
-			}
+		// Connect to the agent
+		pterm.Info.Printf("🔗 Connecting to %s...\n", agentAddress)
+		conn, err := grpc.Dial(agentAddress, reliability.AgentDialOptions()...)
+		if err != nil {
+			result.Error = fmt.Errorf("failed to connect: %w", err)
+			pterm.Error.Printf("❌ Failed to connect to %s: %v\n", agentAddress, err)
+			return result
+		}
+		defer conn.Close()
 
-			// Connect to the agent
-			pterm.Info.Printf("🔗 Connecting to %s...\n", agentAddress)
-			conn, err := grpc.Dial(agentAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
-			if err != nil {
-				result.Error = fmt.Errorf("failed to connect: %w", err)
-				results[index] = result
-				pterm.Error.Printf("❌ Failed to connect to %s: %v\n", agentAddress, err)
-				return
-			}
-			defer conn.Close()
+		c := pb.NewAgentClient(conn)
 
-			c := pb.NewAgentClient(conn)
+		// Create a tarball of the workspace
+		var buf bytes.Buffer
+		if err := createTar(session.Workdir, t.Workspace, &buf); err != nil {
+			result.Error = fmt.Errorf("failed to create workspace tarball: %w", err)
+			return result
+		}
 
-			// Create a tarball of the workspace
-			var buf bytes.Buffer
-			if err := createTar(session.Workdir, &buf); err != nil {
-				result.Error = fmt.Errorf("failed to create workspace tarball: %w", err)
-				results[index] = result
-				return
-			}
+		// Generate a script compatible with agent execution
+		agentScript := tr.generateAgentScript(t, groupName)
 
-			// Generate a script compatible with agent execution
-			agentScript := tr.generateAgentScript(t, groupName)
+		sudoPassword, err := sealSudoPassword(ctx, c, hostAddr)
+		if err != nil {
+			result.Error = err
+			pterm.Error.Printf("❌ Failed on %s: %v\n", agentAddress, err)
+			return result
+		}
 
-			// Send the task and workspace to the agent
-			r, err := c.ExecuteTask(ctx, &pb.ExecuteTaskRequest{
-				TaskName:    t.Name,
-				TaskGroup:   groupName,
-				LuaScript:   agentScript,
-				Workspace:   buf.Bytes(),
-				User:        t.User,
-			})
+		out, flush := liveOutput(ctx, t.Name+"@"+hostAddr)
+		defer flush()
+
+		key := uuid.NewString()
+		tr.journalTaskStarted(t.Name, hostAddr, agentAddress, key)
+
+		// Send the task and workspace to the agent, showing its output
+		// live with the host in front of each line
+		r, err := executeTaskStreaming(ctx, c, &pb.ExecuteTaskRequest{
+			TaskName:       t.Name,
+			TaskGroup:      groupName,
+			LuaScript:      agentScript,
+			Workspace:      buf.Bytes(),
+			User:           t.User,
+			Approvals:      taskctx.Approvals(ctx),
+			IdempotencyKey: key,
+			SudoPassword:   sudoPassword,
+		}, out)
+
+		if err != nil {
+			result.Error = fmt.Errorf("failed to execute: %w", err)
+			pterm.Error.Printf("❌ Failed on %s: %v\n", agentAddress, err)
+			return result
+		}
 
-			if err != nil {
-				result.Error = fmt.Errorf("failed to execute: %w", err)
-				results[index] = result
-				pterm.Error.Printf("❌ Failed on %s: %v\n", agentAddress, err)
-				return
-			}
+		result.Output = r.GetOutput()
+		if !r.GetSuccess() {
+			result.Success = false
+			result.Error = fmt.Errorf("task failed: %s", r.GetOutput())
+			pterm.Error.Printf("❌ Task failed on %s\n", agentAddress)
+			return result
+		}
 
-			if !r.GetSuccess() {
-				result.Success = false
-				result.Output = r.GetOutput()
-				result.Error = fmt.Errorf("task failed: %s", r.GetOutput())
-				pterm.Error.Printf("❌ Task failed on %s\n", agentAddress)
-			} else {
-				result.Success = true
-				result.Output = r.GetOutput()
-				pterm.Success.Printf("✅ Success on %s\n", agentAddress)
-
-				// Extract the updated workspace (only if successful)
-				if err := extractTar(bytes.NewReader(r.GetWorkspace()), session.Workdir); err != nil {
-					slog.Warn("Failed to extract workspace from host", "host", agentAddress, "error", err)
-				}
-			}
+		result.Success = true
+		pterm.Success.Printf("✅ Success on %s\n", agentAddress)
 
-			results[index] = result
-		}(i, host)
-	}
+		if result.Outputs, err = decodeTaskOutputs(r.GetOutputsJson()); err != nil {
+			slog.Warn("Failed to decode task outputs from host", "host", agentAddress, "task", t.Name, "error", err)
+		}
+		if result.Changes, err = decodeTaskChanges(r.GetChangesJson()); err != nil {
+			slog.Warn("Failed to decode task changes from host", "host", agentAddress, "task", t.Name, "error", err)
+		}
 
-	// Wait for all goroutines to complete
-	wg.Wait()
+		// Extract the updated workspace (only if successful)
+		if err := extractTar(bytes.NewReader(r.GetWorkspace()), session.Workdir); err != nil {
+			slog.Warn("Failed to extract workspace from host", "host", agentAddress, "error", err)
+		}
+		return result
+	})
 
 	// Display final results
 	pterm.Println()
@@ -148,12 +234,17 @@ func (tr *TaskRunner) executeTaskOnMultipleHosts(ctx context.Context, t *types.T
 
 	successCount := 0
 	failureCount := 0
+	skippedCount := 0
 
 	for _, result := range results {
 		status := pterm.Green("✅ Success")
 		details := "Completed successfully"
 
-		if !result.Success || result.Error != nil {
+		if result.Skipped {
+			status = pterm.Gray("⏭ Skipped")
+			details = "Not started (fail_fast)"
+			skippedCount++
+		} else if !result.Success || result.Error != nil {
 			status = pterm.Red("❌ Failed")
 			failureCount++
 			if result.Error != nil {
@@ -197,24 +288,31 @@ func (tr *TaskRunner) executeTaskOnMultipleHosts(ctx context.Context, t *types.T
 		}
 	}
 
+	summary := fmt.Sprintf(
+		"Task:      %s\n"+
+			"Total:     %d hosts\n"+
+			"Success:   %s\n"+
+			"Failed:    %s",
+		pterm.Cyan(t.Name),
+		len(hosts),
+		pterm.Green(fmt.Sprintf("%d", successCount)),
+		pterm.Red(fmt.Sprintf("%d", failureCount)),
+	)
+	if skippedCount > 0 {
+		summary += fmt.Sprintf("\nSkipped:   %s", pterm.Gray(fmt.Sprintf("%d", skippedCount)))
+	}
 	pterm.DefaultBox.
 		WithTitle(fmt.Sprintf("%s Execution Summary", summaryIcon)).
 		WithTitleTopCenter().
 		WithBoxStyle(pterm.NewStyle(summaryColor)).
-		Printfln(
-			"Task:      %s\n"+
-			"Total:     %d hosts\n"+
-			"Success:   %s\n"+
-			"Failed:    %s",
-			pterm.Cyan(t.Name),
-			len(hosts),
-			pterm.Green(fmt.Sprintf("%d", successCount)),
-			pterm.Red(fmt.Sprintf("%d", failureCount)),
-		)
+		Println(summary)
 	pterm.Println()
 
 	// Return error if any host failed
 	if failureCount > 0 {
+		if skippedCount > 0 {
+			return results, fmt.Errorf("%d out of %d hosts failed, %d skipped (fail_fast)", failureCount, len(hosts), skippedCount)
+		}
 		return results, fmt.Errorf("%d out of %d hosts failed", failureCount, len(hosts))
 	}
 
@@ -239,6 +337,37 @@ func isMultiHost(delegateTo interface{}) (bool, []string) {
 	}
 }
 
+// localDelegate is the delegate_to value that makes a task run locally
+// even when its group is delegated
+const localDelegate = "local"
+
+// effectiveDelegateTo returns where a task runs: its own delegate_to, or
+// the group's when the task sets none. nil means locally.
+func effectiveDelegateTo(t *types.Task, group types.TaskGroup) interface{} {
+	delegateTo := t.DelegateTo
+	if delegateTo == nil {
+		delegateTo = group.DelegateTo
+	}
+	if s, ok := delegateTo.(string); ok && (s == localDelegate || s == "") {
+		return nil
+	}
+	return delegateTo
+}
+
+// TaskAgents returns the agents a task runs on, the user@host of a host
+// reached over SSH, or "local" when it runs on this machine
+func TaskAgents(t *types.Task, group types.TaskGroup) []string {
+	delegateTo := effectiveDelegateTo(t, group)
+	if target, _ := sshDelegate(delegateTo); target != nil {
+		return []string{target.String()}
+	}
+	hosts := getHostsList(delegateTo)
+	if len(hosts) == 0 {
+		return []string{localDelegate}
+	}
+	return hosts
+}
+
 // getHostsList extracts the list of hosts from delegate_to
 func getHostsList(delegateTo interface{}) []string {
 	switch v := delegateTo.(type) {
@@ -0,0 +1,103 @@
+package taskrunner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeNixosRebuild puts a nixos-rebuild on PATH that prints the units a
+// switch changes and logs the actions it ran to the returned file
+func fakeNixosRebuild(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "actions.log")
+	script := `#!/bin/sh
+echo "$1" >> "` + log + `"
+case "$1" in
+dry-activate) echo "would restart the following units: nginx.service" ;;
+switch) echo "restarting the following units: nginx.service" ;;
+esac
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nixos-rebuild"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// runNixosRebuildTask runs a task calling nixos.rebuild with confirm = true
+// in a run whose context is ctx, as the run command and agents set it up
+func runNixosRebuildTask(t *testing.T, ctx context.Context) (*TaskRunner, error) {
+	t.Helper()
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	luainterface.OpenAll(L)
+	require.NoError(t, L.DoString(`rebuild = function()
+		return nixos.rebuild({ action = "switch", confirm = true, use_sudo = false })
+	end`))
+
+	task := types.Task{Name: "rebuild", CommandFunc: L.GetGlobal("rebuild").(*lua.LFunction)}
+	groups := map[string]types.TaskGroup{"nixos": {Tasks: []types.Task{task}}}
+	tr := NewTaskRunner(L, groups, "nixos", nil, false, false, &DefaultSurveyAsker{}, "")
+	tr.BaseContext = ctx
+	return tr, tr.Run()
+}
+
+// TestRun_NixosRebuildConfirm validates that a rebuild run by a task shows
+// the planned unit changes in the task's output and asks before switching
+func TestRun_NixosRebuildConfirm(t *testing.T) {
+	log := fakeNixosRebuild(t)
+
+	var out bytes.Buffer
+	var asked []string
+	ctx := taskctx.WithOutput(context.Background(), &out)
+	ctx = taskctx.WithConfirm(ctx, func(message string) bool {
+		asked = append(asked, message)
+		return false
+	})
+
+	tr, err := runNixosRebuildTask(t, ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nixos-rebuild switch cancelled by user")
+	assert.Equal(t, []string{"Run nixos-rebuild switch?"}, asked)
+	require.Len(t, tr.Results, 1)
+	assert.Equal(t, "Failed", tr.Results[0].Status)
+
+	// Only the dry run happened, and its plan reached the output
+	actions, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "dry-activate\n", string(actions))
+	assert.Contains(t, out.String(), "would restart the following units: nginx.service")
+	assert.Contains(t, out.String(), "restart:       nginx.service")
+}
+
+// TestRun_NixosRebuildApproved validates that a rebuild in a run that can't
+// prompt, like on agents, switches once approved and streams its output
+func TestRun_NixosRebuildApproved(t *testing.T) {
+	log := fakeNixosRebuild(t)
+
+	var out bytes.Buffer
+	ctx := taskctx.WithOutput(context.Background(), &out)
+	ctx = taskctx.WithApprovals(ctx, []string{"nixos-rebuild"})
+
+	tr, err := runNixosRebuildTask(t, ctx)
+	require.NoError(t, err)
+	require.Len(t, tr.Results, 1)
+	assert.Equal(t, "Success", tr.Results[0].Status)
+
+	actions, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dry-activate", "switch"}, strings.Fields(string(actions)))
+	assert.Contains(t, out.String(), "restarting the following units: nginx.service")
+}
@@ -0,0 +1,56 @@
+package taskrunner
+
+import (
+	"sync"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/output"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/pterm/pterm"
+)
+
+// maxProgressEvents caps the timeline kept of a task's progress, so a task
+// reporting in a tight loop doesn't bloat the run's history
+const maxProgressEvents = 100
+
+// taskProgress prints the progress a task reports and keeps its timeline
+// for the task's result
+type taskProgress struct {
+	task string
+
+	mu     sync.Mutex
+	events []taskctx.Progress
+}
+
+// report prints an update and adds it to the timeline. Repeats of the last
+// update are dropped. Once the timeline is full, updates replace its last
+// entry so that it still ends with the latest one.
+func (p *taskProgress) report(u taskctx.Progress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n := len(p.events); n > 0 {
+		last := p.events[n-1]
+		if last.Percent == u.Percent && last.Message == u.Message {
+			return
+		}
+		if n == maxProgressEvents {
+			p.events[n-1] = u
+		} else {
+			p.events = append(p.events, u)
+		}
+	} else {
+		p.events = append(p.events, u)
+	}
+
+	pterm.Printf("    %s\n", pterm.Cyan(output.FormatProgress(p.task, u.Percent, u.Message)))
+}
+
+// timeline returns the updates reported so far
+func (p *taskProgress) timeline() []taskctx.Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.events) == 0 {
+		return nil
+	}
+	return append([]taskctx.Progress(nil), p.events...)
+}
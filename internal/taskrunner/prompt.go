@@ -0,0 +1,225 @@
+package taskrunner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"golang.org/x/term"
+)
+
+// ErrNonInteractive is returned by SurveyAsker implementations when a
+// prompt cannot be shown because no terminal is attached
+var ErrNonInteractive = errors.New("cannot prompt for input in non-interactive mode")
+
+var (
+	nonInteractive   bool
+	promptTerminal   *os.File
+	nonInteractiveMu sync.RWMutex
+)
+
+// isTerminal is replaced in tests
+var isTerminal = term.IsTerminal
+
+// SetNonInteractive forces every prompt to fail instead of waiting for input
+// (set by the global --non-interactive flag)
+func SetNonInteractive(enabled bool) {
+	nonInteractiveMu.Lock()
+	defer nonInteractiveMu.Unlock()
+	nonInteractive = enabled
+}
+
+// SetPromptTerminal makes prompts draw on out instead of stdout, and decide
+// from it whether a terminal is attached, until the returned function is
+// called. Callers redirecting stdout, like the run log capture, pass the
+// original stdout.
+func SetPromptTerminal(out *os.File) (restore func()) {
+	nonInteractiveMu.Lock()
+	defer nonInteractiveMu.Unlock()
+	prev := promptTerminal
+	promptTerminal = out
+	return func() {
+		nonInteractiveMu.Lock()
+		defer nonInteractiveMu.Unlock()
+		promptTerminal = prev
+	}
+}
+
+// promptOutput returns the file prompts draw on
+func promptOutput() *os.File {
+	nonInteractiveMu.RLock()
+	defer nonInteractiveMu.RUnlock()
+	if promptTerminal != nil {
+		return promptTerminal
+	}
+	return os.Stdout
+}
+
+// IsNonInteractive reports whether prompts must not be shown, either because
+// --non-interactive was set, SLOTH_RUNNER_NON_INTERACTIVE/CI is set, or
+// stdin or the output prompts draw on is not a terminal
+func IsNonInteractive() bool {
+	nonInteractiveMu.RLock()
+	forced := nonInteractive
+	nonInteractiveMu.RUnlock()
+	if forced {
+		return true
+	}
+
+	if isTruthy(os.Getenv("SLOTH_RUNNER_NON_INTERACTIVE")) || isTruthy(os.Getenv("CI")) {
+		return true
+	}
+
+	return !isTerminal(int(os.Stdin.Fd())) || !isTerminal(int(promptOutput().Fd()))
+}
+
+func isTruthy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// MissingInput describes a value that would have been prompted for
+type MissingInput struct {
+	Name    string // short identifier, e.g. "confirmation"
+	Message string // the prompt message
+	Flag    string // the flag that supplies the value, e.g. "--yes"
+}
+
+// MissingInputError lists every input that could not be prompted for
+type MissingInputError struct {
+	Inputs []MissingInput
+}
+
+func (e *MissingInputError) Error() string {
+	var b strings.Builder
+	b.WriteString("cannot prompt for input in non-interactive mode; missing inputs:")
+	for _, in := range e.Inputs {
+		b.WriteString("\n  - ")
+		b.WriteString(in.Name)
+		if in.Message != "" {
+			b.WriteString(fmt.Sprintf(" (%s)", strings.TrimSuffix(in.Message, ":")))
+		}
+		if in.Flag != "" {
+			b.WriteString(fmt.Sprintf(": supply it with %s", in.Flag))
+		}
+	}
+	return b.String()
+}
+
+// Unwrap allows errors.Is(err, ErrNonInteractive)
+func (e *MissingInputError) Unwrap() error {
+	return ErrNonInteractive
+}
+
+// Prompt is a question that can alternatively be answered by a flag
+type Prompt struct {
+	Name   string
+	Flag   string
+	Prompt survey.Prompt
+}
+
+// Ask asks a single prompt, returning a MissingInputError naming the flag
+// to supply when running non-interactively
+func Ask(asker SurveyAsker, p Prompt, response interface{}, opts ...survey.AskOpt) error {
+	c := NewInputCollector(asker)
+	c.Ask(p, response, opts...)
+	return c.Err()
+}
+
+// Confirm asks a yes/no question, returning a MissingInputError naming flag
+// when running non-interactively
+func Confirm(message, flag string) (bool, error) {
+	var confirmed bool
+	err := Ask(nil, Prompt{Name: "confirmation", Flag: flag, Prompt: &survey.Confirm{Message: message}}, &confirmed)
+	return confirmed, err
+}
+
+// InputCollector asks a series of prompts and, when running
+// non-interactively, accumulates all missing inputs so they can be
+// reported together instead of failing on the first one
+type InputCollector struct {
+	asker   SurveyAsker
+	missing []MissingInput
+	err     error
+}
+
+// NewInputCollector creates a collector backed by asker
+func NewInputCollector(asker SurveyAsker) *InputCollector {
+	if asker == nil {
+		asker = NewSurveyAsker()
+	}
+	return &InputCollector{asker: asker}
+}
+
+// Ask asks p unless an earlier prompt failed for a reason other than
+// missing interactivity
+func (c *InputCollector) Ask(p Prompt, response interface{}, opts ...survey.AskOpt) {
+	if c.err != nil {
+		return
+	}
+	err := c.asker.AskOne(p.Prompt, response, opts...)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, ErrNonInteractive) {
+		c.missing = append(c.missing, MissingInput{
+			Name:    p.Name,
+			Message: promptMessage(p.Prompt),
+			Flag:    p.Flag,
+		})
+		return
+	}
+	c.err = err
+}
+
+// Err returns the first hard error, or a MissingInputError if any input was missing
+func (c *InputCollector) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.missing) > 0 {
+		return &MissingInputError{Inputs: c.missing}
+	}
+	return nil
+}
+
+func promptMessage(p survey.Prompt) string {
+	switch v := p.(type) {
+	case *survey.Input:
+		return v.Message
+	case *survey.Confirm:
+		return v.Message
+	case *survey.Select:
+		return v.Message
+	case *survey.MultiSelect:
+		return v.Message
+	case *survey.Password:
+		return v.Message
+	case *survey.Multiline:
+		return v.Message
+	case *survey.Editor:
+		return v.Message
+	}
+	return ""
+}
+
+// NonInteractiveSurveyAsker fails every prompt with ErrNonInteractive
+type NonInteractiveSurveyAsker struct{}
+
+func (n *NonInteractiveSurveyAsker) AskOne(p survey.Prompt, r interface{}, o ...survey.AskOpt) error {
+	return ErrNonInteractive
+}
+
+// NewSurveyAsker returns the asker appropriate for the current environment
+func NewSurveyAsker() SurveyAsker {
+	if IsNonInteractive() {
+		return &NonInteractiveSurveyAsker{}
+	}
+	return &DefaultSurveyAsker{}
+}
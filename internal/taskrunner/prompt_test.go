@@ -0,0 +1,76 @@
+package taskrunner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type answeringAsker struct {
+	answer bool
+}
+
+func (a *answeringAsker) AskOne(p survey.Prompt, r interface{}, o ...survey.AskOpt) error {
+	*(r.(*bool)) = a.answer
+	return nil
+}
+
+func TestAsk_InteractiveAnswer(t *testing.T) {
+	confirm := false
+	err := Ask(&answeringAsker{answer: true}, Prompt{
+		Name:   "confirmation",
+		Flag:   "--yes",
+		Prompt: &survey.Confirm{Message: "Proceed?"},
+	}, &confirm)
+
+	require.NoError(t, err)
+	assert.True(t, confirm)
+}
+
+func TestInputCollector_ReportsAllMissingInputs(t *testing.T) {
+	c := NewInputCollector(&NonInteractiveSurveyAsker{})
+
+	var name string
+	var confirm bool
+	c.Ask(Prompt{Name: "name", Flag: "--name", Prompt: &survey.Input{Message: "Enter workflow name:"}}, &name)
+	c.Ask(Prompt{Name: "confirmation", Flag: "--yes", Prompt: &survey.Confirm{Message: "Proceed?"}}, &confirm)
+
+	err := c.Err()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNonInteractive))
+
+	var missing *MissingInputError
+	require.True(t, errors.As(err, &missing))
+	assert.Len(t, missing.Inputs, 2)
+	assert.Contains(t, err.Error(), "--name")
+	assert.Contains(t, err.Error(), "--yes")
+	assert.Contains(t, err.Error(), "Enter workflow name")
+}
+
+func TestSetNonInteractive(t *testing.T) {
+	SetNonInteractive(true)
+	defer SetNonInteractive(false)
+
+	assert.True(t, IsNonInteractive())
+	_, ok := NewSurveyAsker().(*NonInteractiveSurveyAsker)
+	assert.True(t, ok)
+
+	var confirm bool
+	err := (&DefaultSurveyAsker{}).AskOne(&survey.Confirm{Message: "Proceed?"}, &confirm)
+	assert.ErrorIs(t, err, ErrNonInteractive)
+}
+
+func TestConfirm_NonInteractiveNamesFlag(t *testing.T) {
+	SetNonInteractive(true)
+	defer SetNonInteractive(false)
+
+	confirmed, err := Confirm("Delete the group?", "--force")
+	assert.False(t, confirmed)
+
+	var missing *MissingInputError
+	require.True(t, errors.As(err, &missing))
+	assert.Equal(t, []MissingInput{{Name: "confirmation", Message: "Delete the group?", Flag: "--force"}}, missing.Inputs)
+}
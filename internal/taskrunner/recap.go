@@ -0,0 +1,110 @@
+package taskrunner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/pterm/pterm"
+)
+
+// runRecap counts the tasks of a run by outcome. A task changed the
+// system when one of its module calls reported a change; modules that
+// can't tell, like exec, report none.
+type runRecap struct {
+	Changed   int
+	Unchanged int
+	Failed    int
+	Skipped   int
+}
+
+// recapResults counts results by outcome. In a dry run the tasks that
+// would change something count as changed.
+func recapResults(results []types.TaskResult) runRecap {
+	var recap runRecap
+	for _, result := range results {
+		switch {
+		case result.Error != nil:
+			recap.Failed++
+		case result.Status == "Skipped":
+			recap.Skipped++
+		case len(result.Changes) > 0:
+			recap.Changed++
+		default:
+			recap.Unchanged++
+		}
+	}
+	return recap
+}
+
+// String formats the recap as in changed=1 unchanged=2 failed=0 skipped=0,
+// coloring the counts that aren't zero
+func (r runRecap) String() string {
+	count := func(n int, color func(...interface{}) string) string {
+		if n == 0 {
+			return fmt.Sprint(n)
+		}
+		return color(n)
+	}
+	return fmt.Sprintf("changed=%s unchanged=%s failed=%s skipped=%s",
+		count(r.Changed, pterm.Yellow),
+		count(r.Unchanged, pterm.Green),
+		count(r.Failed, pterm.Red),
+		count(r.Skipped, pterm.Gray))
+}
+
+// maxSlowCalls is how many of the slowest calls the run summary lists
+const maxSlowCalls = 5
+
+// taskSlowCall is a slow call with the task that made it
+type taskSlowCall struct {
+	Task string
+	taskctx.SlowCall
+}
+
+// slowestCalls returns the slow calls of results, timed out first, then
+// the slowest, at most max of them
+func slowestCalls(results []types.TaskResult, max int) []taskSlowCall {
+	var calls []taskSlowCall
+	for _, result := range results {
+		for _, c := range result.SlowCalls {
+			calls = append(calls, taskSlowCall{Task: result.Name, SlowCall: c})
+		}
+	}
+	sort.SliceStable(calls, func(i, j int) bool {
+		if calls[i].TimedOut != calls[j].TimedOut {
+			return calls[i].TimedOut
+		}
+		return calls[i].Duration > calls[j].Duration
+	})
+	if len(calls) > max {
+		calls = calls[:max]
+	}
+	return calls
+}
+
+// formatSlowCalls formats the slowest calls of a run for its summary, one
+// per line, or "" when no call was slow
+func formatSlowCalls(results []types.TaskResult) string {
+	calls := slowestCalls(results, maxSlowCalls)
+	if len(calls) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(pterm.Bold.Sprint("Slowest calls:") + "\n")
+	for _, c := range calls {
+		where := c.Task
+		if c.Host != "" {
+			where += " on " + c.Host
+		}
+		note := pterm.Yellow(fmt.Sprintf("slow after %s", c.Threshold))
+		if c.TimedOut {
+			note = pterm.Red("timed out")
+		}
+		fmt.Fprintf(&b, "  %s.%s %s %s %s\n", c.Module, c.Function, c.Duration.Round(time.Millisecond), pterm.Gray("("+where+")"), note)
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package taskrunner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecapResults(t *testing.T) {
+	install := []taskctx.Change{{Module: "pkg", Action: "install", Target: "nginx"}}
+	recap := recapResults([]types.TaskResult{
+		{Name: "packages", Status: "Success", Changes: install},
+		{Name: "config", Status: "Success"},
+		{Name: "check", Status: "Success"},
+		{Name: "restart", Status: "Failed", Error: errors.New("exit status 1"), Changes: install},
+		{Name: "notify", Status: "Skipped"},
+		{Name: "plan", Status: "DryRun", Changes: install},
+	})
+
+	assert.Equal(t, runRecap{Changed: 2, Unchanged: 2, Failed: 1, Skipped: 1}, recap)
+}
+
+func TestSlowestCalls(t *testing.T) {
+	results := []types.TaskResult{
+		{Name: "packages", SlowCalls: []taskctx.SlowCall{
+			{Module: "pkg", Function: "install", Duration: 3 * time.Minute, Threshold: 2 * time.Minute},
+		}},
+		{Name: "fetch", SlowCalls: []taskctx.SlowCall{
+			{Module: "http", Function: "get", Duration: 40 * time.Second, Threshold: 30 * time.Second},
+			{Module: "http", Function: "download", Duration: 5 * time.Minute, Threshold: 30 * time.Second, TimedOut: true, Host: "web-1"},
+		}},
+		{Name: "config"},
+	}
+
+	calls := slowestCalls(results, 2)
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, "download", calls[0].Function)
+		assert.Equal(t, "fetch", calls[0].Task)
+		assert.Equal(t, "install", calls[1].Function)
+	}
+
+	summary := formatSlowCalls(results)
+	assert.Equal(t, 4, strings.Count(summary, "\n"))
+	assert.Contains(t, summary, "http.download 5m0s")
+	assert.Contains(t, summary, "fetch on web-1")
+	assert.Contains(t, summary, "timed out")
+	assert.Empty(t, formatSlowCalls(results[2:]))
+}
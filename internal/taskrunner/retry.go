@@ -0,0 +1,100 @@
+package taskrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+)
+
+// Backoffs of retry_backoff, growing the wait before each retry of a task
+const (
+	backoffFixed       = "fixed"
+	backoffLinear      = "linear"
+	backoffExponential = "exponential"
+)
+
+// defaultRetryDelay is the wait before the first retry of tasks that set
+// no retry_delay
+const defaultRetryDelay = time.Second
+
+// maxRetryDelay bounds the wait before a retry, however many came before
+const maxRetryDelay = 10 * time.Minute
+
+// retryErrorKinds are the kinds of errors retry_on names, with the words
+// their messages have; other retry_on entries are matched as they are
+var retryErrorKinds = map[string][]string{
+	"timeout":    {"timeout", "timed out", "deadline exceeded"},
+	"connection": {"connection refused", "connection reset", "no such host", "network is unreachable", "broken pipe", "unavailable"},
+}
+
+// retryPolicy is how many times, after which failures and how long after
+// them a task runs again
+type retryPolicy struct {
+	retries int
+	delay   time.Duration
+	backoff string
+	on      []string
+}
+
+// newRetryPolicy returns the retry policy of t, from its retries,
+// retry_delay, retry_backoff and retry_on fields
+func newRetryPolicy(t *types.Task) (*retryPolicy, error) {
+	p := &retryPolicy{retries: max(t.Retries, 0), delay: defaultRetryDelay, backoff: backoffLinear, on: t.RetryOn}
+	if t.RetryDelay != "" {
+		delay, err := time.ParseDuration(t.RetryDelay)
+		if err != nil || delay < 0 {
+			return nil, fmt.Errorf("invalid retry_delay %q", t.RetryDelay)
+		}
+		p.delay = delay
+	}
+	switch t.RetryBackoff {
+	case "":
+	case backoffFixed, backoffLinear, backoffExponential:
+		p.backoff = t.RetryBackoff
+	default:
+		return nil, fmt.Errorf("invalid retry_backoff %q: use fixed, linear or exponential", t.RetryBackoff)
+	}
+	return p, nil
+}
+
+// wait returns how long to wait before the nth retry, counted from 1
+func (p *retryPolicy) wait(retry int) time.Duration {
+	delay := p.delay
+	switch p.backoff {
+	case backoffLinear:
+		delay *= time.Duration(retry)
+	case backoffExponential:
+		for i := 1; i < retry && delay < maxRetryDelay; i++ {
+			delay *= 2
+		}
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// retryable reports whether a task failing with err runs again: always
+// when the task sets no retry_on, otherwise when err is of a kind it lists
+func (p *retryPolicy) retryable(err error) bool {
+	if len(p.on) == 0 {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, kind := range p.on {
+		if kind == "timeout" && errors.Is(err, context.DeadlineExceeded) {
+			return true
+		}
+		words, ok := retryErrorKinds[kind]
+		if !ok {
+			words = []string{strings.ToLower(kind)}
+		}
+		for _, word := range words {
+			if strings.Contains(message, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
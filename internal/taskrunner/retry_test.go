@@ -0,0 +1,46 @@
+package taskrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyWait(t *testing.T) {
+	policy, err := newRetryPolicy(&types.Task{Retries: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		[]time.Duration{policy.wait(1), policy.wait(2), policy.wait(3)}, "the default is a linear backoff from 1s")
+
+	policy, err = newRetryPolicy(&types.Task{Retries: 3, RetryDelay: "10s", RetryBackoff: "exponential"})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second},
+		[]time.Duration{policy.wait(1), policy.wait(2), policy.wait(3)})
+	assert.Equal(t, maxRetryDelay, policy.wait(40), "waits should be capped")
+
+	policy, err = newRetryPolicy(&types.Task{Retries: 2, RetryDelay: "5s", RetryBackoff: "fixed"})
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, policy.wait(2))
+
+	_, err = newRetryPolicy(&types.Task{RetryDelay: "soon"})
+	assert.EqualError(t, err, `invalid retry_delay "soon"`)
+	_, err = newRetryPolicy(&types.Task{RetryBackoff: "random"})
+	assert.Error(t, err)
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	policy, _ := newRetryPolicy(&types.Task{Retries: 3})
+	assert.True(t, policy.retryable(errors.New("exit status 1")), "without retry_on every failure is retried")
+
+	policy, _ = newRetryPolicy(&types.Task{Retries: 3, RetryOn: []string{"timeout", "connection", "HTTP 503"}})
+	assert.True(t, policy.retryable(fmt.Errorf("task failed: %w", context.DeadlineExceeded)))
+	assert.True(t, policy.retryable(errors.New("dial tcp 10.0.0.1:443: connect: connection refused")))
+	assert.True(t, policy.retryable(errors.New("upstream returned http 503")))
+	assert.False(t, policy.retryable(errors.New("syntax error near line 3")))
+}
@@ -0,0 +1,374 @@
+package taskrunner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/pterm/pterm"
+)
+
+const (
+	// defaultServiceReadyTimeout bounds how long a service may take to
+	// become ready when it sets no ready_timeout
+	defaultServiceReadyTimeout = 30 * time.Second
+
+	// serviceStopTimeout is how long a service gets to exit after it is
+	// asked to stop, before it is killed
+	serviceStopTimeout = 10 * time.Second
+
+	// serviceLogTailLines is how many log lines are shown when a service
+	// fails to start
+	serviceLogTailLines = 10
+)
+
+// runningService is a started service process
+type runningService struct {
+	cmd     *exec.Cmd
+	logPath string
+	logFile *os.File
+	// startErr is set when the service failed to start or become ready
+	startErr error
+	// done is closed when the process exits; exitErr is its result
+	done    chan struct{}
+	exitErr error
+	// stopped is set when the process is stopped on purpose
+	stopped bool
+}
+
+// serviceManager starts a group's services before the tasks that need
+// them and stops them all when the group ends, however it ends
+type serviceManager struct {
+	services map[string]types.Service
+	// implicit lists services no task names; every task needs them
+	implicit []string
+	workdir  string
+	logDir   string
+
+	mu       sync.Mutex
+	running  map[string]*runningService
+	started  []string // in start order, so they stop in reverse
+	stopping bool
+	signals  chan os.Signal
+}
+
+func newServiceManager(services []types.Service, workdir, logDir string) *serviceManager {
+	m := &serviceManager{
+		services: make(map[string]types.Service, len(services)),
+		workdir:  workdir,
+		logDir:   logDir,
+		running:  make(map[string]*runningService),
+	}
+	for _, svc := range services {
+		m.services[svc.Name] = svc
+	}
+	return m
+}
+
+// serviceLogDir returns where the logs of a group's services are written
+func serviceLogDir(runID, groupName string) string {
+	if runID == "" {
+		runID = time.Now().Format("20060102-150405")
+	}
+	return filepath.Join(config.GetLogDir(), "services", runID, groupName)
+}
+
+// validate checks the services and the names the tasks use, and works out
+// which services no task names
+func (m *serviceManager) validate(services []types.Service, tasks []*types.Task) error {
+	seen := make(map[string]bool, len(services))
+	for _, svc := range services {
+		if svc.Name == "" {
+			return fmt.Errorf("service without a name")
+		}
+		if seen[svc.Name] {
+			return fmt.Errorf("service '%s' is defined more than once", svc.Name)
+		}
+		seen[svc.Name] = true
+		if strings.TrimSpace(svc.Command) == "" {
+			return fmt.Errorf("service '%s' has no command", svc.Name)
+		}
+		if svc.ReadyTimeout != "" {
+			if _, err := time.ParseDuration(svc.ReadyTimeout); err != nil {
+				return fmt.Errorf("service '%s' has an invalid ready_timeout: %w", svc.Name, err)
+			}
+		}
+	}
+
+	named := make(map[string]bool)
+	for _, t := range tasks {
+		for _, name := range t.Services {
+			if !seen[name] {
+				return fmt.Errorf("task '%s' uses undefined service '%s'", t.Name, name)
+			}
+			named[name] = true
+		}
+	}
+	for _, svc := range services {
+		if !named[svc.Name] {
+			m.implicit = append(m.implicit, svc.Name)
+		}
+	}
+	return nil
+}
+
+// servicesFor returns the services a task needs, in start order
+func (m *serviceManager) servicesFor(t *types.Task) []string {
+	return append(append([]string{}, m.implicit...), t.Services...)
+}
+
+// Ensure starts the named services that aren't running yet and waits for
+// them to become ready. A service that failed to start or exited fails
+// every task that needs it; it is not restarted.
+func (m *serviceManager) Ensure(ctx context.Context, names []string) error {
+	for _, name := range names {
+		m.mu.Lock()
+		rs, ok := m.running[name]
+		m.mu.Unlock()
+
+		if !ok {
+			var err error
+			if rs, err = m.start(ctx, m.services[name]); err != nil {
+				return err
+			}
+		}
+		if rs.startErr != nil {
+			return rs.startErr
+		}
+		select {
+		case <-rs.done:
+			return fmt.Errorf("service '%s' exited: %v (log: %s)", name, exitDescription(rs.exitErr), rs.logPath)
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *serviceManager) start(ctx context.Context, svc types.Service) (*runningService, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopping {
+		return nil, fmt.Errorf("service '%s' not started: the workflow is stopping", svc.Name)
+	}
+	m.watchSignals()
+
+	if err := os.MkdirAll(m.logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create service log directory: %w", err)
+	}
+	rs := &runningService{
+		logPath: filepath.Join(m.logDir, svc.Name+".log"),
+		done:    make(chan struct{}),
+	}
+	m.running[svc.Name] = rs
+
+	logFile, err := os.Create(rs.logPath)
+	if err != nil {
+		rs.startErr = fmt.Errorf("service '%s' failed to start: %w", svc.Name, err)
+		close(rs.done)
+		return rs, nil
+	}
+	rs.logFile = logFile
+
+	cmd := exec.Command("bash", "-c", svc.Command)
+	cmd.Dir = m.serviceWorkdir(svc)
+	cmd.Env = os.Environ()
+	for k, v := range svc.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	setServiceProcessGroup(cmd)
+	rs.cmd = cmd
+
+	pterm.Info.Printfln("Starting service %s (log: %s)", pterm.Cyan(svc.Name), rs.logPath)
+	if err := cmd.Start(); err != nil {
+		rs.startErr = fmt.Errorf("service '%s' failed to start: %w", svc.Name, err)
+		logFile.Close()
+		close(rs.done)
+		return rs, nil
+	}
+	m.started = append(m.started, svc.Name)
+
+	go func() {
+		rs.exitErr = cmd.Wait()
+		close(rs.done)
+
+		m.mu.Lock()
+		expected := m.stopping || rs.stopped
+		m.mu.Unlock()
+		if !expected {
+			pterm.Warning.Printfln("Service %s exited: %s (log: %s)", svc.Name, exitDescription(rs.exitErr), rs.logPath)
+			slog.Warn("service exited", "service", svc.Name, "error", rs.exitErr)
+		}
+	}()
+
+	// Wait for readiness without holding the lock, so other services can
+	// be stopped meanwhile
+	m.mu.Unlock()
+	err = waitServiceReady(ctx, svc, rs, cmd.Dir, cmd.Env)
+	m.mu.Lock()
+	if err != nil {
+		rs.stopped = true
+		m.mu.Unlock()
+		stopServiceProcess(rs)
+		m.mu.Lock()
+		rs.startErr = fmt.Errorf("service '%s' failed to become ready: %w%s", svc.Name, err, logTail(rs.logPath))
+		return rs, nil
+	}
+	pterm.Success.Printfln("Service %s is ready", pterm.Cyan(svc.Name))
+	return rs, nil
+}
+
+func (m *serviceManager) serviceWorkdir(svc types.Service) string {
+	if svc.Workdir == "" {
+		return m.workdir
+	}
+	if filepath.IsAbs(svc.Workdir) {
+		return svc.Workdir
+	}
+	return filepath.Join(m.workdir, svc.Workdir)
+}
+
+// waitServiceReady waits until the service's port accepts connections or
+// its ready command succeeds. Services without either are ready once started.
+func waitServiceReady(ctx context.Context, svc types.Service, rs *runningService, dir string, env []string) error {
+	if svc.ReadyPort == 0 && svc.ReadyCommand == "" {
+		return nil
+	}
+	timeout := defaultServiceReadyTimeout
+	if svc.ReadyTimeout != "" {
+		timeout, _ = time.ParseDuration(svc.ReadyTimeout)
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if serviceReady(ctx, svc, dir, env) {
+			return nil
+		}
+		select {
+		case <-rs.done:
+			return fmt.Errorf("exited before it was ready: %s", exitDescription(rs.exitErr))
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not ready after %s", timeout)
+		}
+	}
+}
+
+func serviceReady(ctx context.Context, svc types.Service, dir string, env []string) bool {
+	if svc.ReadyPort != 0 {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(svc.ReadyPort)), time.Second)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+	}
+	if svc.ReadyCommand != "" {
+		cmd := exec.CommandContext(ctx, "bash", "-c", svc.ReadyCommand)
+		cmd.Dir = dir
+		cmd.Env = env
+		if cmd.Run() != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// watchSignals stops the services when the run is interrupted, since
+// they run in their own process groups and don't get the terminal's
+// signals. Must be called with m.mu held.
+func (m *serviceManager) watchSignals() {
+	if m.signals != nil {
+		return
+	}
+	m.signals = make(chan os.Signal, 1)
+	signal.Notify(m.signals, os.Interrupt, syscall.SIGTERM)
+	go func(signals chan os.Signal) {
+		sig, ok := <-signals
+		if !ok {
+			return
+		}
+		pterm.Warning.Printfln("Received %s, stopping services", sig)
+		m.StopAll()
+		os.Exit(130)
+	}(m.signals)
+}
+
+// StopAll stops the running services, newest first. It is safe to call
+// more than once.
+func (m *serviceManager) StopAll() {
+	m.mu.Lock()
+	if m.stopping {
+		m.mu.Unlock()
+		return
+	}
+	m.stopping = true
+	if m.signals != nil {
+		signal.Stop(m.signals)
+		close(m.signals)
+	}
+	started := m.started
+	m.mu.Unlock()
+
+	for i := len(started) - 1; i >= 0; i-- {
+		name := started[i]
+		rs := m.running[name]
+		if stopServiceProcess(rs) {
+			pterm.Info.Printfln("Stopped service %s", pterm.Cyan(name))
+		}
+		rs.logFile.Close()
+	}
+}
+
+// stopServiceProcess asks a service to exit and kills it if it doesn't
+// within serviceStopTimeout. It reports whether the service was running.
+func stopServiceProcess(rs *runningService) bool {
+	select {
+	case <-rs.done:
+		return false
+	default:
+	}
+	terminateServiceProcess(rs.cmd)
+	select {
+	case <-rs.done:
+	case <-time.After(serviceStopTimeout):
+		killServiceProcess(rs.cmd)
+		<-rs.done
+	}
+	return true
+}
+
+func exitDescription(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
+
+// logTail returns the last lines of a service log, for start errors
+func logTail(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil || len(strings.TrimSpace(string(content))) == 0 {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(lines) > serviceLogTailLines {
+		lines = lines[len(lines)-serviceLogTailLines:]
+	}
+	return fmt.Sprintf("\n  last log lines (%s):\n    %s", path, strings.Join(lines, "\n    "))
+}
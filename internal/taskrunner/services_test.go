@@ -0,0 +1,115 @@
+//go:build unix || darwin || linux
+// +build unix darwin linux
+
+package taskrunner
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func servicePid(t *testing.T, dir string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "pid"))
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+	return pid
+}
+
+// TestRun_ServiceLifecycle validates that a service is ready before the
+// task that needs it and stopped when the group ends
+func TestRun_ServiceLifecycle(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+	dir := t.TempDir()
+
+	L := lua.NewState()
+	defer L.Close()
+
+	var aliveDuringTask bool
+	task := types.Task{
+		Name:     "uses_db",
+		Services: []string{"db"},
+		CommandFunc: L.NewFunction(func(L *lua.LState) int {
+			aliveDuringTask = syscall.Kill(servicePid(t, dir), 0) == nil
+			L.Push(lua.LTrue)
+			L.Push(lua.LString("ok"))
+			L.Push(L.NewTable())
+			return 3
+		}),
+	}
+	groups := map[string]types.TaskGroup{
+		"test_group": {
+			Tasks: []types.Task{task},
+			Services: []types.Service{{
+				Name:         "db",
+				Command:      "echo $$ > pid; echo listening; touch ready; exec sleep 60",
+				Workdir:      dir,
+				ReadyCommand: "test -f ready",
+			}},
+		},
+	}
+
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	tr.RunID = "run-1"
+	require.NoError(t, tr.Run())
+
+	assert.True(t, aliveDuringTask)
+	assert.Error(t, syscall.Kill(servicePid(t, dir), 0), "service should be stopped after the run")
+
+	log, err := os.ReadFile(filepath.Join(serviceLogDir("run-1", "test_group"), "db.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "listening")
+}
+
+// TestRun_ServiceNotReady validates that a service that never becomes
+// ready fails the tasks that need it
+func TestRun_ServiceNotReady(t *testing.T) {
+	t.Setenv("SLOTH_RUNNER_DATA_DIR", t.TempDir())
+
+	L := lua.NewState()
+	defer L.Close()
+
+	groups := map[string]types.TaskGroup{
+		"test_group": {
+			Tasks: []types.Task{{Name: "needs_db", Services: []string{"db"}}},
+			Services: []types.Service{{
+				Name:         "db",
+				Command:      "echo boom; exit 1",
+				ReadyCommand: "false",
+			}},
+		},
+	}
+
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	err := tr.Run()
+	require.Error(t, err)
+	require.Len(t, tr.Results, 1)
+	assert.Equal(t, "Failed", tr.Results[0].Status)
+	assert.Contains(t, tr.Results[0].Error.Error(), "exited before it was ready")
+	assert.Contains(t, tr.Results[0].Error.Error(), "boom")
+}
+
+// TestRun_UndefinedService validates that tasks can only use services of their group
+func TestRun_UndefinedService(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	groups := map[string]types.TaskGroup{
+		"test_group": {Tasks: []types.Task{{Name: "needs_db", Services: []string{"db"}}}},
+	}
+
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	err := tr.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "undefined service 'db'")
+}
@@ -0,0 +1,23 @@
+//go:build unix || darwin || linux
+// +build unix darwin linux
+
+package taskrunner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setServiceProcessGroup runs a service in its own process group, so
+// stopping it also stops the processes its command started
+func setServiceProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func terminateServiceProcess(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+func killServiceProcess(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package taskrunner
+
+import (
+	"os/exec"
+)
+
+func setServiceProcessGroup(cmd *exec.Cmd) {}
+
+// Windows has no SIGTERM, so services are killed right away
+func terminateServiceProcess(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+func killServiceProcess(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
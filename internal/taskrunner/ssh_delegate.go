@@ -0,0 +1,35 @@
+package taskrunner
+
+import (
+	"fmt"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/ssh"
+)
+
+// sshDelegate returns the host of a task delegated to a host without an
+// agent, as delegate_to = {ssh = "user@host", key = "~/.ssh/id_ed25519"},
+// or nil when the task goes to agents or runs locally
+func sshDelegate(delegateTo interface{}) (*ssh.Target, error) {
+	m, ok := delegateTo.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	dest, ok := m["ssh"].(string)
+	if !ok {
+		return nil, nil
+	}
+	target, err := ssh.ParseTarget(dest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delegate_to: %w", err)
+	}
+	if key, ok := m["key"].(string); ok {
+		target.KeyPath = key
+	}
+	if knownHosts, ok := m["known_hosts"].(string); ok {
+		target.KnownHosts = knownHosts
+	}
+	if insecure, ok := m["insecure_ignore_host_key"].(bool); ok {
+		target.InsecureIgnoreHostKey = insecure
+	}
+	return &target, nil
+}
@@ -0,0 +1,32 @@
+package taskrunner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHDelegate(t *testing.T) {
+	target, err := sshDelegate(map[string]interface{}{
+		"ssh":                      "admin@switch-01:2222",
+		"key":                      "~/.ssh/id_ed25519",
+		"insecure_ignore_host_key": true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, target)
+	assert.Equal(t, "admin", target.User)
+	assert.Equal(t, "switch-01", target.Host)
+	assert.Equal(t, 2222, target.Port)
+	assert.Equal(t, "~/.ssh/id_ed25519", target.KeyPath)
+	assert.True(t, target.InsecureIgnoreHostKey)
+
+	for _, delegateTo := range []interface{}{nil, "web-01", []interface{}{"web-01"}, map[string]interface{}{"address": "10.0.0.5:50051"}} {
+		target, err := sshDelegate(delegateTo)
+		assert.NoError(t, err)
+		assert.Nil(t, target, "Expected %v to go to an agent", delegateTo)
+	}
+
+	_, err = sshDelegate(map[string]interface{}{"ssh": "admin@"})
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package taskrunner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -21,6 +22,11 @@ import (
 	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
 	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
 	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface/modules/workdir"
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/chalkan3-sloth/sloth-runner/internal/ssh"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
 	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	"github.com/pterm/pterm"
 	lua "github.com/yuin/gopher-lua"
@@ -61,7 +67,11 @@ type SurveyAsker interface {
 type DefaultSurveyAsker struct{}
 
 func (d *DefaultSurveyAsker) AskOne(p survey.Prompt, r interface{}, o ...survey.AskOpt) error {
-	return survey.AskOne(p, r, o...)
+	if IsNonInteractive() {
+		return ErrNonInteractive
+	}
+	out := promptOutput()
+	return survey.AskOne(p, r, append([]survey.AskOpt{survey.WithStdio(os.Stdin, out, out)}, o...)...)
 }
 
 // executeShellCondition executes a shell command and returns true if it succeeds (exit code 0).
@@ -92,6 +102,10 @@ func (e *TaskExecutionError) Error() string {
 	return fmt.Sprintf("task '%s' failed: %v", e.TaskName, e.Err)
 }
 
+func (e *TaskExecutionError) Unwrap() error {
+	return e.Err
+}
+
 type TaskRunner struct {
 	L           *lua.LState
 	TaskGroups  map[string]types.TaskGroup
@@ -100,7 +114,13 @@ type TaskRunner struct {
 	Results     []types.TaskResult
 	Outputs     map[string]interface{}
 	Exports     map[string]interface{}
+	// Sensitive names the exports marked sensitive with task.set_output:
+	// they are sealed before the run is recorded and masked when shown
+	Sensitive   map[string]bool
 	DryRun      bool
+	// CheckDelegated sends delegated tasks of a dry run to their agents,
+	// which run them in check mode and report what they would change
+	CheckDelegated bool
 	Interactive bool
 	surveyAsker SurveyAsker
 	LuaScript   string
@@ -112,6 +132,44 @@ type TaskRunner struct {
 	// Execution context for events
 	Stack  string  // Stack name being executed
 	RunID  string  // Unique run identifier
+
+	// Quarantine lists tasks known to be flaky; their failures are
+	// reported as warnings and don't fail the run
+	Quarantine map[string]bool
+	// QuarantinedFailures lists quarantined tasks that failed in this run
+	QuarantinedFailures []string
+
+	// Journal, when set, records each task before it starts and once it
+	// ends, so a run whose process stops can be reconciled
+	Journal TaskJournal
+	// Resumed lists the tasks that finished in the interrupted run this
+	// run resumes: they aren't run again, and their outputs are in Inputs
+	Resumed map[string]bool
+
+	// StatePool, when set, provides the Lua states tasks run in instead
+	// of creating one per task
+	StatePool *luainterface.StatePool
+
+	// TrackUsage measures what the processes of each local task consume,
+	// as agents do for the tasks delegated to them
+	TrackUsage bool
+
+	// BaseContext, when set, is the parent of every task's context. It
+	// carries taskctx values such as approvals and the output writer.
+	BaseContext context.Context
+
+	// Inputs are the outputs of tasks that ran elsewhere, by task name,
+	// given to every task along with the outputs of its dependencies. On
+	// agents they are the outputs the delegated tasks depend on.
+	Inputs map[string]interface{}
+
+	// Assets are the files embedded in the sloth file. They are extracted
+	// into each group's workdir, whose tarball carries them to agents.
+	Assets sloth.Assets
+	// AssetsDir, when set, is where asset() finds the embedded files
+	// instead of the group workdir: agents point it at the workspace
+	// they received
+	AssetsDir string
 	
 	// Pulumi-style output (optional)
 	pulumiOutput interface{} // Will be *output.PulumiStyleOutput when set
@@ -137,6 +195,7 @@ func NewTaskRunner(L *lua.LState, groups map[string]types.TaskGroup, targetGroup
 		TargetTasks: targetTasks,
 		Outputs:     make(map[string]interface{}),
 		Exports:     make(map[string]interface{}),
+		Sensitive:   make(map[string]bool),
 		DryRun:      dryRun,
 		Interactive: interactive,
 		surveyAsker: asker,
@@ -224,18 +283,17 @@ func (tr *TaskRunner) executeTaskWithRetries(t *types.Task, inputFromDependencie
 		}
 
 		var taskErr error
-		maxRetries := t.Retries
-		if maxRetries < 0 {
-			maxRetries = 0
+		policy, err := newRetryPolicy(t)
+		if err != nil {
+			return &TaskExecutionError{TaskName: t.Name, Err: err}
 		}
+		maxRetries := policy.retries
 
 		for i := 0; i <= maxRetries; i++ {
 			if i > 0 {
 				// Retry attempt - show retry header
-				backoffDelay := time.Duration(i) * time.Second
-				if i > 3 {
-					backoffDelay = time.Duration(i*i) * time.Second // Exponential backoff
-				}
+				backoffDelay := policy.wait(i)
+				tr.dispatchTaskRetrying(t, i+1, maxRetries+1, backoffDelay, taskErr)
 				pterm.Println()
 				pterm.DefaultHeader.
 					WithFullWidth(false).
@@ -262,11 +320,11 @@ func (tr *TaskRunner) executeTaskWithRetries(t *types.Task, inputFromDependencie
 				if err != nil {
 					return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("invalid timeout duration: %w", err)}
 				}
-				ctx, cancel = context.WithTimeout(context.Background(), timeout)
+				ctx, cancel = context.WithTimeout(tr.baseContext(), timeout)
 			} else {
 				// Use default timeout from core config
 				defaultTimeout := tr.globalCore.Config.TimeoutDefault
-				ctx, cancel = context.WithTimeout(context.Background(), defaultTimeout)
+				ctx, cancel = context.WithTimeout(tr.baseContext(), defaultTimeout)
 			}
 			defer cancel()
 
@@ -298,6 +356,10 @@ func (tr *TaskRunner) executeTaskWithRetries(t *types.Task, inputFromDependencie
 
 			// Log retry attempt for monitoring
 			tr.logger.Warn("task retry", "task", t.Name, "attempt", i+1, "error", taskErr)
+			if i < maxRetries && !policy.retryable(taskErr) {
+				slog.Info("task not retried, its error is not in retry_on", "task", t.Name, "retry_on", policy.on)
+				break
+			}
 		}
 
 		slog.Error("task failed", "task", t.Name, "retries", maxRetries, "err", taskErr)
@@ -305,19 +367,92 @@ func (tr *TaskRunner) executeTaskWithRetries(t *types.Task, inputFromDependencie
 	}, fmt.Sprintf("task_%s_%s", groupName, t.Name))
 }
 
+// dispatchTaskRetrying raises the task.retrying event for the attempt of t
+// that starts after delay, following a failure with err
+func (tr *TaskRunner) dispatchTaskRetrying(t *types.Task, attempt, maxAttempts int, delay time.Duration, err error) {
+	dispatcher := hooks.GetGlobalDispatcher()
+	if dispatcher == nil {
+		return
+	}
+	event := &hooks.TaskEvent{
+		TaskName:    t.Name,
+		AgentName:   "local",
+		Status:      "retrying",
+		Duration:    delay.String(),
+		Stack:       tr.Stack,
+		RunID:       tr.RunID,
+		Attempt:     attempt,
+		MaxAttempts: maxAttempts,
+	}
+	if hosts := getHostsList(t.DelegateTo); len(hosts) > 0 {
+		event.AgentName = hosts[0]
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	dispatcher.DispatchTaskRetrying(event)
+}
+
+// baseContext returns the context tasks derive theirs from
+func (tr *TaskRunner) baseContext() context.Context {
+	if tr.BaseContext != nil {
+		return tr.BaseContext
+	}
+	return context.Background()
+}
+
+// exportSetOutputs exports the outputs a task set with task.set_output
+// from its output table, and notes the sensitive ones so they are sealed
+// when the run is recorded. Callers hold the run's lock.
+func (tr *TaskRunner) exportSetOutputs(setOutputs map[string]bool, output *lua.LTable) {
+	if output == nil {
+		return
+	}
+	for name, sensitive := range setOutputs {
+		tr.Exports[name] = luainterface.LuaToGoValue(tr.L, output.RawGetString(name))
+		if sensitive {
+			tr.Sensitive[name] = true
+		}
+	}
+}
+
 func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDependencies *lua.LTable, mu *sync.Mutex, completedTasks map[string]bool, taskOutputs map[string]*lua.LTable, runningTasks map[string]bool, session *types.SharedSession, groupName string) (taskErr error) {
 	startTime := time.Now()
 
+	// The progress the task reports is printed and kept for its result.
+	// On agents it goes back to the runner that delegated the task instead.
+	progress := &taskProgress{task: t.Name}
+	if !taskctx.HasProgress(ctx) {
+		ctx = taskctx.WithProgress(ctx, progress.report)
+	}
+	if tr.TaskGroups[groupName].StrictTypes {
+		ctx = taskctx.WithStrictTypes(ctx)
+	}
+	if t.NixShell != nil {
+		ctx = taskctx.WithNixShell(ctx, t.NixShell)
+	}
+
 	// Dispatch task.started event
 	dispatcher := hooks.GetGlobalDispatcher()
+	delegateSource := effectiveDelegateTo(t, tr.TaskGroups[groupName])
+	// Hosts without an agent are worked on from here, over SSH
+	sshTarget, err := sshDelegate(delegateSource)
+	if err != nil {
+		return &TaskExecutionError{TaskName: t.Name, Err: err}
+	}
+	if sshTarget != nil {
+		delegateSource = nil
+	}
 	if dispatcher != nil {
 		agentName := "local"
-		if t.DelegateTo != nil {
+		if delegateSource != nil {
 			// Try to extract agent name from delegate_to
-			hosts := getHostsList(t.DelegateTo)
+			hosts := getHostsList(delegateSource)
 			if len(hosts) > 0 {
 				agentName = hosts[0]
 			}
+		} else if sshTarget != nil {
+			agentName = sshTarget.String()
 		}
 
 		taskEvent := &hooks.TaskEvent{
@@ -333,7 +468,47 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 		slog.Warn("dispatcher is nil, cannot dispatch task.started event", "task", t.Name)
 	}
 
-	var agentAddress string
+	// A dry run doesn't send tasks to agents to run: the plan only tells
+	// where they would run, unless the agents are asked to check them
+	if tr.DryRun && delegateSource != nil && tr.CheckDelegated {
+		changes, err := tr.checkOnAgents(ctx, t, delegateSource, inputFromDependencies, session, groupName)
+		status := "DryRun"
+		if err != nil {
+			status = "Failed"
+			err = &TaskExecutionError{TaskName: t.Name, Err: err}
+		}
+		mu.Lock()
+		tr.Results = append(tr.Results, types.TaskResult{
+			Name:     t.Name,
+			Status:   status,
+			Duration: time.Since(startTime),
+			Error:    err,
+			Changes:  changes,
+		})
+		completedTasks[t.Name] = true
+		delete(runningTasks, t.Name)
+		mu.Unlock()
+		return err
+	}
+	if tr.DryRun && delegateSource != nil {
+		target := strings.Join(getHostsList(delegateSource), ", ")
+		if target == "" {
+			target = "an agent selected by its facts"
+		}
+		mu.Lock()
+		tr.Results = append(tr.Results, types.TaskResult{
+			Name:     t.Name,
+			Status:   "DryRun",
+			Duration: time.Since(startTime),
+			Changes:  []taskctx.Change{{Module: "delegate_to", Action: "run", Target: target, Unknown: true}},
+		})
+		completedTasks[t.Name] = true
+		delete(runningTasks, t.Name)
+		mu.Unlock()
+		return nil
+	}
+
+	var agentName, agentAddress string
 
 	// DEBUG: Log delegate_to information
 	slog.Debug("Task delegate_to info", 
@@ -343,18 +518,15 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 		"delegate_to_nil", t.DelegateTo == nil)
 
 	// Check for multi-host delegation first
-	var delegateSource interface{}
-	if t.DelegateTo != nil {
-		delegateSource = t.DelegateTo
-	} else if tr.TaskGroups[groupName].DelegateTo != nil {
-		delegateSource = tr.TaskGroups[groupName].DelegateTo
-	}
-
 	// Handle multi-host execution
 	if delegateSource != nil {
-		hosts := getHostsList(delegateSource)
+		// Agent groups run the task on each of their agents
+		hosts, grouped, err := expandAgentGroups(getHostsList(delegateSource))
+		if err != nil {
+			return &TaskExecutionError{TaskName: t.Name, Err: err}
+		}
 
-		if len(hosts) > 1 {
+		if len(hosts) > 1 || grouped {
 			// Execute on multiple hosts in parallel
 			slog.Info("Executing task on multiple hosts",
 				"task_name", t.Name,
@@ -362,6 +534,11 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 				"count", len(hosts))
 
 			results, err := tr.executeTaskOnMultipleHosts(ctx, t, hosts, session, groupName)
+			mu.Lock()
+			if table, ok := luainterface.GoValueToLua(tr.L, multiHostOutputs(results)).(*lua.LTable); ok {
+				taskOutputs[t.Name] = table
+			}
+			mu.Unlock()
 			if err != nil {
 				// Log detailed results even on failure
 				for _, result := range results {
@@ -379,7 +556,7 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 			return nil
 		} else if len(hosts) == 1 {
 			// Single host execution - resolve the address
-			agentAddress = hosts[0]
+			agentName, agentAddress = hosts[0], hosts[0]
 			if !strings.Contains(agentAddress, ":") {
 				resolvedAddress, err := resolveAgentAddress(agentAddress)
 				if err != nil {
@@ -394,8 +571,16 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 	if agentAddress == "" && delegateSource != nil {
 		// Handle map[string]interface{} format for backward compatibility
 		if m, ok := delegateSource.(map[string]interface{}); ok {
-			if addr, ok := m["address"].(string); ok {
-				agentAddress = addr
+			if facts, candidates, ok := factsSelector(m); ok {
+				facts = runtimeFacts(t, facts)
+				name, addr, err := selectAgentByFacts(facts, candidates)
+				if err != nil {
+					return &TaskExecutionError{TaskName: t.Name, Err: err}
+				}
+				slog.Info("Selected agent by facts", "task_name", t.Name, "agent", name, "facts", describeFacts(facts))
+				agentName, agentAddress = name, addr
+			} else if addr, ok := m["address"].(string); ok {
+				agentName, agentAddress = addr, addr
 			} else {
 				return &TaskExecutionError{TaskName: t.Name, Err: fmt.Errorf("invalid agent definition in delegate_to: missing address")}
 			}
@@ -410,7 +595,53 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 
 	// If agent address is specified, execute on remote agent
 	if agentAddress != "" {
-		return tr.executeOnAgent(ctx, t, agentAddress, session, groupName)
+		agentResult, err := tr.executeOnAgent(ctx, t, inputFromDependencies, agentName, agentAddress, session, groupName)
+		status := "Success"
+		if err != nil {
+			status = "Failed"
+		}
+		if agentResult == nil {
+			agentResult = &agentTaskResult{}
+		}
+		mu.Lock()
+		if agentResult.outputs != nil {
+			if table, ok := luainterface.GoValueToLua(tr.L, agentResult.outputs).(*lua.LTable); ok {
+				taskOutputs[t.Name] = table
+			}
+		}
+		tr.exportSetOutputs(agentResult.setOutputs, taskOutputs[t.Name])
+		tr.Results = append(tr.Results, types.TaskResult{
+			Name:             t.Name,
+			Status:           status,
+			Duration:         time.Since(startTime),
+			Error:            err,
+			Usage:            agentResult.usage,
+			Progress:         progress.timeline(),
+			Changes:          agentResult.changes,
+			WorkspaceChanges: agentResult.workspaceChanges,
+			SlowCalls:        agentResult.slowCalls,
+		})
+		mu.Unlock()
+		return err
+	}
+
+	var tracker *taskusage.Tracker
+	if tr.TrackUsage {
+		tracker = taskusage.NewTracker(t.Name)
+		ctx = taskusage.WithTracker(ctx, tracker)
+	}
+
+	// In a dry run modules record what they would change in the plan,
+	// otherwise they report what they changed to the change log
+	var plan *taskctx.Plan
+	changeLog := &taskctx.ChangeLog{}
+	slowCalls := &taskctx.SlowCalls{}
+	ctx = taskctx.WithSlowCalls(ctx, slowCalls)
+	if tr.DryRun {
+		plan = &taskctx.Plan{}
+		ctx = taskctx.WithPlan(ctx, plan)
+	} else {
+		ctx = taskctx.WithChangeLog(ctx, changeLog)
 	}
 
 	// Execute locally - set up result tracking
@@ -425,17 +656,24 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 		if taskErr != nil {
 			status = "Failed"
 			exitCode = 1
+			// Name the task in errors mapped back to the workflow source
+			var scriptErr *luainterface.ScriptError
+			if errors.As(taskErr, &scriptErr) && scriptErr.Task == "" {
+				scriptErr.Task = t.Name
+			}
 		}
 
 		// Dispatch task.completed or task.failed event
 		dispatcher := hooks.GetGlobalDispatcher()
 		if dispatcher != nil {
 			agentName := "local"
-			if t.DelegateTo != nil {
-				hosts := getHostsList(t.DelegateTo)
+			if delegateSource != nil {
+				hosts := getHostsList(delegateSource)
 				if len(hosts) > 0 {
 					agentName = hosts[0]
 				}
+			} else if sshTarget != nil {
+				agentName = sshTarget.String()
 			}
 
 			if taskErr != nil {
@@ -466,19 +704,57 @@ func (tr *TaskRunner) runTask(ctx context.Context, t *types.Task, inputFromDepen
 			}
 		}
 
+		var usage *taskusage.Usage
+		if tracker != nil {
+			u := tracker.Close()
+			usage = &u
+		}
+
+		changes := changeLog.Changes()
+		if plan != nil {
+			changes = plan.Changes()
+			if taskErr == nil {
+				status = "DryRun"
+			}
+		}
+		calls := slowCalls.Calls()
+		if sshTarget != nil {
+			for i := range changes {
+				changes[i].Host = sshTarget.String()
+			}
+			for i := range calls {
+				calls[i].Host = sshTarget.String()
+			}
+		}
+
 		mu.Lock()
 		tr.Results = append(tr.Results, types.TaskResult{
 			Name:     t.Name,
 			Status:   status,
 			Duration: duration,
 			Error:    taskErr,
+			Usage:    usage,
+			Progress:   progress.timeline(),
+			Changes:    changes,
+			SlowCalls:  calls,
+			SetOutputs: t.SetOutputs,
 		})
 		taskOutputs[t.Name] = luainterface.CopyTable(t.Output, tr.L)
+		tr.exportSetOutputs(t.SetOutputs, taskOutputs[t.Name])
 		completedTasks[t.Name] = true
 		delete(runningTasks, t.Name)
 		mu.Unlock()
 	}()
 
+	if sshTarget != nil {
+		conn, err := ssh.Dial(ctx, *sshTarget)
+		if err != nil {
+			return &TaskExecutionError{TaskName: t.Name, Err: err}
+		}
+		defer conn.Close()
+		ctx = taskctx.WithRemote(ctx, conn)
+	}
+
 	// Execute task locally using helper
 	return tr.executeLocally(ctx, t, inputFromDependencies, session, groupName)
 }
@@ -558,6 +834,9 @@ func (tr *TaskRunner) Run() error {
 		if err := os.MkdirAll(workdir, 0755); err != nil {
 			return fmt.Errorf("failed to create workdir %s: %w", workdir, err)
 		}
+		if err := tr.Assets.Extract(workdir); err != nil {
+			return err
+		}
 
 		artifactsBaseDir := "artifacts" // Persistent artifacts directory in project root
 		artifactsGroupDir := filepath.Join(artifactsBaseDir, groupName)
@@ -587,6 +866,14 @@ func (tr *TaskRunner) Run() error {
 			return err
 		}
 
+		// Services are started before the first task that needs them and
+		// stopped when the group ends, even if it fails or is aborted
+		services := newServiceManager(group.Services, workdir, serviceLogDir(tr.RunID, groupName))
+		if err := services.validate(group.Services, tasksToRun); err != nil {
+			return fmt.Errorf("task group '%s': %w", groupName, err)
+		}
+		defer services.StopAll()
+
 		// Initialize progress bar
 		totalTasks := len(executionOrder)
 		progressBar, err := pterm.DefaultProgressbar.
@@ -605,15 +892,32 @@ func (tr *TaskRunner) Run() error {
 		runningTasks := make(map[string]bool)
 		taskStatus := make(map[string]string)
 		var groupErrors []error
+		// Results of tasks already run on an agent as part of a batch, and
+		// agents too old to run batches
+		batchResults := make(map[string]error)
+		noBatchAgents := make(map[string]bool)
 
-		for _, taskName := range executionOrder {
+		for i, taskName := range executionOrder {
 			task := taskMap[taskName]
 			runningTasks[task.Name] = true
 
+			if tr.Resumed[task.Name] {
+				pterm.Printf("  %s %s %s\n", pterm.Green("✓"), task.Name, pterm.Gray("(finished before the run was interrupted)"))
+				progressBar.Increment()
+				taskStatus[task.Name] = "Success"
+				mu.Lock()
+				tr.Results = append(tr.Results, types.TaskResult{Name: task.Name, Status: "Success"})
+				if output, ok := tr.Inputs[task.Name]; ok {
+					tr.Outputs[task.Name] = output
+				}
+				mu.Unlock()
+				continue
+			}
+
 			// Dependency checks
 			skip := false
 			for _, depName := range task.DependsOn {
-				if status, ok := taskStatus[depName]; !ok || (status != "Success" && status != "Skipped") {
+				if status, ok := taskStatus[depName]; !ok || (status != "Success" && status != "Skipped" && status != "Quarantined") {
 					slog.Warn("Skipping task due to dependency failure", "task", task.Name, "dependency", depName, "dep_status", taskStatus[depName])
 					skip = true
 					break
@@ -621,6 +925,7 @@ func (tr *TaskRunner) Run() error {
 			}
 			if skip {
 				taskStatus[task.Name] = "Skipped"
+				tr.journalTaskFinished(task.Name, "Skipped", nil, nil)
 				continue
 			}
 
@@ -644,6 +949,9 @@ func (tr *TaskRunner) Run() error {
 			}
 
 			inputFromDependencies := tr.L.NewTable()
+			for name, output := range tr.Inputs {
+				inputFromDependencies.RawSetString(name, luainterface.GoValueToLua(tr.L, output))
+			}
 			for _, depName := range task.DependsOn {
 				if output, ok := taskOutputs[depName]; ok {
 					inputFromDependencies.RawSetString(depName, output)
@@ -657,7 +965,9 @@ func (tr *TaskRunner) Run() error {
 					Options: []string{"run", "skip", "abort", "continue"},
 					Default: "run",
 				}
-				tr.surveyAsker.AskOne(prompt, &action)
+				if err := Ask(tr.surveyAsker, Prompt{Name: "task action", Flag: "a run without --interactive", Prompt: prompt}, &action); err != nil {
+					return fmt.Errorf("interactive mode: %w", err)
+				}
 
 				switch action {
 				case "skip":
@@ -674,12 +984,64 @@ func (tr *TaskRunner) Run() error {
 				}
 			}
 
-			err := tr.executeTaskWithRetries(task, inputFromDependencies, &mu, completedTasks, taskOutputs, runningTasks, session, groupName)
+			if !tr.DryRun {
+				if err := services.Ensure(tr.baseContext(), services.servicesFor(task)); err != nil {
+					progressBar.Increment()
+					err = &TaskExecutionError{TaskName: task.Name, Err: err}
+					tr.Results = append(tr.Results, types.TaskResult{Name: task.Name, Status: "Failed", Error: err})
+					groupErrors = append(groupErrors, err)
+					taskStatus[task.Name] = "Failed"
+					tr.journalTaskFinished(task.Name, "Failed", err, nil)
+					continue
+				}
+			}
+
+			// Consecutive tasks for the same agent are sent together, so
+			// the workspace is transferred once for all of them
+			if _, batched := batchResults[task.Name]; !batched && !tr.Interactive && !tr.DryRun {
+				batch, host, parallel := tr.agentBatch(group, taskMap, executionOrder, i, taskStatus, func(t *types.Task) error {
+					return services.Ensure(tr.baseContext(), services.servicesFor(t))
+				})
+				if len(batch) >= minAgentBatch && !noBatchAgents[host] {
+					results := tr.executeBatchOnAgent(tr.baseContext(), batch, host, parallel, session, groupName)
+					if results == nil {
+						noBatchAgents[host] = true
+					}
+					for name, err := range results {
+						batchResults[name] = err
+					}
+				}
+			}
+
+			var err error
+			if result, batched := batchResults[task.Name]; batched {
+				err = result
+			} else {
+				delegateTo := effectiveDelegateTo(task, group)
+				if target, _ := sshDelegate(delegateTo); delegateTo == nil || target != nil {
+					// Delegated tasks are journaled with the request sent
+					tr.journalTaskStarted(task.Name, "", "", "")
+				}
+				err = tr.executeTaskWithRetries(task, inputFromDependencies, &mu, completedTasks, taskOutputs, runningTasks, session, groupName)
+			}
+			if errors.Is(err, errSkippedOnAgent) {
+				progressBar.Increment()
+				taskStatus[task.Name] = "Skipped"
+				tr.journalTaskFinished(task.Name, "Skipped", nil, nil)
+				continue
+			}
 			
 			// Update progress bar
 			progressBar.Increment()
 			
-			if err != nil {
+			if err != nil && tr.Quarantine[task.Name] {
+				pterm.Printf("    %s %s\n",
+					pterm.Yellow("⚠"),
+					pterm.Yellow("failed, ignored because the task is quarantined as flaky"))
+				slog.Warn("quarantined task failed", "task", task.Name, "error", err)
+				tr.QuarantinedFailures = append(tr.QuarantinedFailures, task.Name)
+				taskStatus[task.Name] = "Quarantined"
+			} else if err != nil {
 				groupErrors = append(groupErrors, err)
 				taskStatus[task.Name] = "Failed"
 			} else {
@@ -702,10 +1064,19 @@ func (tr *TaskRunner) Run() error {
 					}
 				}
 			}
+
+			var outputs map[string]interface{}
+			mu.Lock()
+			if output, ok := taskOutputs[task.Name]; ok {
+				outputs, _ = redact.SealOutputs(nil, luainterface.LuaTableToGoMap(tr.L, output), tr.Sensitive)
+			}
+			mu.Unlock()
+			tr.journalTaskFinished(task.Name, taskStatus[task.Name], err, outputs)
 		}
 		
 		// Stop progress bar
 		progressBar.Stop()
+		services.StopAll()
 
 		groupHadSuccess := len(groupErrors) == 0
 		if !groupHadSuccess {
@@ -799,6 +1170,8 @@ func (tr *TaskRunner) Run() error {
 			status = pterm.Yellow("⊘ " + result.Status)
 		} else if result.Status == "DryRun" {
 			status = pterm.Cyan("◈ " + result.Status)
+		} else if len(result.Changes) > 0 {
+			status = pterm.Yellow("✓ Changed")
 		}
 		
 		// Format duration
@@ -819,6 +1192,10 @@ func (tr *TaskRunner) Run() error {
 		WithBoxed().
 		WithData(tableData).
 		Render()
+	pterm.Printfln("%s %s", pterm.Bold.Sprint("Recap:"), recapResults(tr.Results))
+	if slow := formatSlowCalls(tr.Results); slow != "" {
+		pterm.Print(slow)
+	}
 
 	if len(allGroupErrors) > 0 {
 		// Enhanced error display
@@ -1022,6 +1399,9 @@ func (tr *TaskRunner) Run() error {
 	if skippedCount > 0 {
 		pterm.Printf(" | %s %d skipped", pterm.Yellow("⊘"), skippedCount)
 	}
+	if len(tr.QuarantinedFailures) > 0 {
+		pterm.Printf(" | %s %d quarantined failure(s)", pterm.Yellow("⚠"), len(tr.QuarantinedFailures))
+	}
 	pterm.Println()
 	
 	return nil
@@ -1,9 +1,13 @@
 package taskrunner
 
 import (
+	"os/exec"
 	"testing"
+	"time"
 
 	"github.com/chalkan3-sloth/sloth-runner/internal/luainterface"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
 	"github.com/chalkan3-sloth/sloth-runner/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -450,3 +454,130 @@ func TestResolveAgentAddress(t *testing.T) {
 		})
 	}
 }
+
+// TestQuarantinedTaskFailure validates that a quarantined task's failure
+// doesn't fail the run or skip its dependents
+func TestQuarantinedTaskFailure(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	luainterface.OpenAll(L)
+
+	task1 := types.Task{
+		Name: "flaky",
+		CommandFunc: L.NewFunction(func(L *lua.LState) int {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString("intermittent failure"))
+			L.Push(L.NewTable())
+			return 3
+		}),
+	}
+	task2 := types.Task{Name: "after", CommandStr: "true", DependsOn: []string{"flaky"}}
+	groups := map[string]types.TaskGroup{
+		"test_group": {Tasks: []types.Task{task1, task2}},
+	}
+
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	tr.Quarantine = map[string]bool{"flaky": true}
+	err := tr.Run()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flaky"}, tr.QuarantinedFailures)
+	require.Len(t, tr.Results, 2)
+	assert.Equal(t, "Failed", tr.Results[0].Status)
+	assert.Equal(t, "Success", tr.Results[1].Status)
+}
+
+// TestEffectiveDelegateTo validates group-level delegate_to with per-task overrides.
+func TestEffectiveDelegateTo(t *testing.T) {
+	group := types.TaskGroup{DelegateTo: "web-01"}
+
+	assert.Equal(t, "web-01", effectiveDelegateTo(&types.Task{Name: "inherits"}, group))
+	assert.Equal(t, "db-01", effectiveDelegateTo(&types.Task{Name: "overrides", DelegateTo: "db-01"}, group))
+	assert.Nil(t, effectiveDelegateTo(&types.Task{Name: "opts_out", DelegateTo: "local"}, group))
+	assert.Nil(t, effectiveDelegateTo(&types.Task{Name: "no_group"}, types.TaskGroup{}))
+
+	hosts := []interface{}{"web-01", "web-02"}
+	assert.Equal(t, hosts, effectiveDelegateTo(&types.Task{Name: "list"}, types.TaskGroup{DelegateTo: hosts}))
+}
+
+// TestRun_GroupDelegateLocalOptOut validates that a task opting out of the group's agent runs locally.
+func TestRun_GroupDelegateLocalOptOut(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	groups := map[string]types.TaskGroup{
+		"test_group": {
+			// No agent with this address exists, so delegating would fail
+			DelegateTo: "127.0.0.1:1",
+			Tasks:      []types.Task{{Name: "local_task", CommandStr: "true", DelegateTo: "local"}},
+		},
+	}
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	require.NoError(t, tr.Run())
+}
+
+// TestRun_TrackUsage validates that commands modules run for a task are
+// accounted to it
+func TestRun_TrackUsage(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	task := types.Task{
+		Name: "busy",
+		CommandFunc: L.NewFunction(func(L *lua.LState) int {
+			cmd := exec.Command("sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done")
+			done := taskusage.Attach(L.Context(), cmd)
+			err := cmd.Run()
+			done()
+			L.Push(lua.LBool(err == nil))
+			L.Push(lua.LString("ran"))
+			L.Push(L.NewTable())
+			return 3
+		}),
+	}
+	groups := map[string]types.TaskGroup{
+		"test_group": {Tasks: []types.Task{task}},
+	}
+	tr := NewTaskRunner(L, groups, "test_group", nil, false, false, &DefaultSurveyAsker{}, "")
+	tr.TrackUsage = true
+	require.NoError(t, tr.Run())
+
+	require.Len(t, tr.Results, 1)
+	require.NotNil(t, tr.Results[0].Usage)
+	assert.Greater(t, tr.Results[0].Usage.CPUTime, time.Duration(0))
+}
+
+// TestRun_DryRun validates that a dry run records what tasks would change
+// and doesn't send delegated tasks to their agents
+func TestRun_DryRun(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	local := types.Task{
+		Name: "configure",
+		CommandFunc: L.NewFunction(func(L *lua.LState) int {
+			taskctx.RecordChange(L.Context(), taskctx.Change{Module: "pkg", Action: "install", Target: "nginx"})
+			L.Push(lua.LTrue)
+			L.Push(lua.LString("planned"))
+			L.Push(L.NewTable())
+			return 3
+		}),
+	}
+	// No agent with this address exists, so sending the task would fail
+	delegated := types.Task{Name: "remote", CommandStr: "true", DelegateTo: "127.0.0.1:1"}
+	groups := map[string]types.TaskGroup{
+		"test_group": {Tasks: []types.Task{local, delegated}},
+	}
+	tr := NewTaskRunner(L, groups, "test_group", nil, true, false, &DefaultSurveyAsker{}, "")
+	require.NoError(t, tr.Run())
+
+	results := make(map[string]types.TaskResult)
+	for _, r := range tr.Results {
+		results[r.Name] = r
+	}
+	require.Len(t, results, 2)
+	assert.Equal(t, "DryRun", results["configure"].Status)
+	assert.Equal(t, []taskctx.Change{{Module: "pkg", Action: "install", Target: "nginx"}}, results["configure"].Changes)
+	assert.Equal(t, "DryRun", results["remote"].Status)
+	assert.Equal(t, []taskctx.Change{{Module: "delegate_to", Action: "run", Target: "127.0.0.1:1", Unknown: true}}, results["remote"].Changes)
+}
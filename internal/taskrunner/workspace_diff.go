@@ -0,0 +1,89 @@
+package taskrunner
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	"github.com/pterm/pterm"
+)
+
+// workspaceManifest returns the files of a workspace tarball by path, with
+// the checksum of their content or the target of symlinks. Directories are
+// left out: a task changes a workspace through its files.
+func workspaceManifest(data []byte) (map[string]string, error) {
+	manifest := make(map[string]string)
+	tarReader := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return manifest, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.ToSlash(filepath.Clean(header.Name))
+		switch header.Typeflag {
+		case tar.TypeReg:
+			sum := sha256.New()
+			if _, err := io.Copy(sum, tarReader); err != nil {
+				return nil, err
+			}
+			manifest[path] = hex.EncodeToString(sum.Sum(nil))
+		case tar.TypeSymlink:
+			manifest[path] = "-> " + header.Linkname
+		}
+	}
+}
+
+// diffWorkspaces returns the files added, modified or deleted between the
+// workspace tarball sent with a task and the one that came back, by path
+func diffWorkspaces(sent, returned []byte) ([]types.WorkspaceChange, error) {
+	before, err := workspaceManifest(sent)
+	if err != nil {
+		return nil, err
+	}
+	after, err := workspaceManifest(returned)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []types.WorkspaceChange
+	for path, sum := range after {
+		if old, ok := before[path]; !ok {
+			changes = append(changes, types.WorkspaceChange{Path: path, Change: "added"})
+		} else if old != sum {
+			changes = append(changes, types.WorkspaceChange{Path: path, Change: "modified"})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, types.WorkspaceChange{Path: path, Change: "deleted"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// printWorkspaceChanges lists what a task changed in the workspace
+func printWorkspaceChanges(changes []types.WorkspaceChange) {
+	if len(changes) == 0 {
+		pterm.Info.Println("Workspace unchanged")
+		return
+	}
+	marks := map[string]string{
+		"added":    pterm.Green("+"),
+		"modified": pterm.Yellow("~"),
+		"deleted":  pterm.Red("-"),
+	}
+	pterm.Info.Printfln("Workspace changes (%d):", len(changes))
+	for _, c := range changes {
+		pterm.Printfln("  %s %s", marks[c.Change], c.Path)
+	}
+}
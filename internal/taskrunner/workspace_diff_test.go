@@ -0,0 +1,81 @@
+package taskrunner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/types"
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// workspaceTar packs a workspace of files by path
+func workspaceTar(t *testing.T, files map[string]string) []byte {
+	dir := t.TempDir()
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, createTar(dir, nil, &buf))
+	return buf.Bytes()
+}
+
+func TestDiffWorkspaces(t *testing.T) {
+	sent := workspaceTar(t, map[string]string{
+		"app.conf":      "port = 80",
+		"src/main.go":   "package main",
+		"build/old.bin": "v1",
+	})
+	returned := workspaceTar(t, map[string]string{
+		"app.conf":      "port = 8080",
+		"src/main.go":   "package main",
+		"build/app.bin": "v2",
+	})
+
+	changes, err := diffWorkspaces(sent, returned)
+	require.NoError(t, err)
+	assert.Equal(t, []types.WorkspaceChange{
+		{Path: "app.conf", Change: "modified"},
+		{Path: "build/app.bin", Change: "added"},
+		{Path: "build/old.bin", Change: "deleted"},
+	}, changes)
+
+	changes, err = diffWorkspaces(sent, sent)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+// workspaceAgent returns a workspace with a report the task wrote
+type workspaceAgent struct {
+	fakeBatchAgent
+	workspace []byte
+}
+
+func (a *workspaceAgent) ExecuteTask(ctx context.Context, in *pb.ExecuteTaskRequest) (*pb.ExecuteTaskResponse, error) {
+	return &pb.ExecuteTaskResponse{Success: true, Workspace: a.workspace}, nil
+}
+
+// TestRun_WorkspaceDiff validates that delegated tasks with workspace_diff
+// record what they changed, and are sent one at a time
+func TestRun_WorkspaceDiff(t *testing.T) {
+	agent := &workspaceAgent{
+		fakeBatchAgent: fakeBatchAgent{batches: true},
+		workspace:      workspaceTar(t, map[string]string{"report.txt": "ok"}),
+	}
+	addr := startFakeBatchAgent(t, agent)
+
+	tr, err := runDelegatedGroup(t, []types.Task{
+		{Name: "one", DelegateTo: addr, WorkspaceDiff: true},
+		{Name: "two", DelegateTo: addr, WorkspaceDiff: true},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, agent.calls, "tasks with workspace_diff aren't batched")
+	require.Len(t, tr.Results, 2)
+	assert.Equal(t, []types.WorkspaceChange{{Path: "report.txt", Change: "added"}}, tr.Results[0].WorkspaceChanges)
+	assert.Empty(t, tr.Results[1].WorkspaceChanges, "the report was synced back before the second task")
+}
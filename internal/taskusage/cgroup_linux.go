@@ -0,0 +1,236 @@
+//go:build linux
+
+package taskusage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// tasksCgroup is the cgroup task cgroups are created in, next to the
+// one the process moved into
+const tasksCgroup = "sloth-tasks"
+
+var (
+	setupOnce sync.Once
+	tasksDir  string
+	setupErr  error
+	cgroupSeq atomic.Uint64
+)
+
+// cgroup is the cgroup of one task
+type cgroup struct {
+	dir string
+	fd  *os.File
+}
+
+func newCgroup(name string) (*cgroup, error) {
+	setupOnce.Do(func() { tasksDir, setupErr = setupCgroups() })
+	if setupErr != nil {
+		return nil, setupErr
+	}
+
+	dir := filepath.Join(tasksDir, fmt.Sprintf("%s-%d", cgroupName(name), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, err
+	}
+	fd, err := os.Open(dir)
+	if err != nil {
+		os.Remove(dir)
+		return nil, err
+	}
+	return &cgroup{dir: dir, fd: fd}, nil
+}
+
+// setupCgroups prepares the cgroup tasks run in. The process moves into a
+// leaf cgroup of its own, as a cgroup with processes can't give memory
+// and I/O controllers to child cgroups.
+func setupCgroups() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("no cgroup v2 hierarchy at %s", cgroupRoot)
+	}
+	current, err := selfCgroup()
+	if err != nil {
+		return "", err
+	}
+
+	// After a restart from within the leaf, such as an agent update, the
+	// process is already in it
+	base := filepath.Join(cgroupRoot, current)
+	if filepath.Base(current) == "agent" {
+		base = filepath.Dir(base)
+	} else if current != "/" {
+		leaf := filepath.Join(base, "agent")
+		if err := os.MkdirAll(leaf, 0755); err != nil {
+			return "", err
+		}
+		if err := writeCgroupFile(leaf, "cgroup.procs", strconv.Itoa(os.Getpid())); err != nil {
+			return "", fmt.Errorf("failed to move into %s: %w", leaf, err)
+		}
+	}
+
+	dir := filepath.Join(base, tasksCgroup)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// Without the controllers cgroups still account for CPU time, which
+	// is all that is reported then
+	if err := writeCgroupFile(base, "cgroup.subtree_control", "+memory +io"); err == nil {
+		writeCgroupFile(dir, "cgroup.subtree_control", "+memory +io")
+	}
+
+	if err := checkCgroupFD(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// checkCgroupFD starts a process in a cgroup, which needs clone3 (Linux
+// 5.7)
+func checkCgroupFD(dir string) error {
+	path, err := exec.LookPath("true")
+	if err != nil {
+		return nil
+	}
+	cg := &cgroup{dir: filepath.Join(dir, "check")}
+	if err := os.Mkdir(cg.dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	defer os.Remove(cg.dir)
+	if cg.fd, err = os.Open(cg.dir); err != nil {
+		return err
+	}
+	defer cg.fd.Close()
+
+	cmd := exec.Command(path)
+	cg.attach(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("can't start processes in a cgroup: %w", err)
+	}
+	return nil
+}
+
+// selfCgroup returns the cgroup v2 path of this process
+func selfCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+func (c *cgroup) attach(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(c.fd.Fd())
+}
+
+// close reads the usage of the cgroup and removes it. Processes still
+// running in it, such as daemons a task started, keep it around.
+func (c *cgroup) close() (Usage, error) {
+	defer c.fd.Close()
+	usage, err := readCgroupUsage(c.dir)
+	if err != nil {
+		return usage, err
+	}
+	if err := os.Remove(c.dir); err != nil {
+		return usage, fmt.Errorf("cgroup kept, processes still run in it: %w", err)
+	}
+	return usage, nil
+}
+
+// readCgroupUsage reads cpu.stat, memory.peak and io.stat of a cgroup.
+// Files of controllers that aren't enabled are skipped.
+func readCgroupUsage(dir string) (Usage, error) {
+	var usage Usage
+
+	cpu, err := readKeyedFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return usage, err
+	}
+	usage.CPUTime = time.Duration(cpu["usage_usec"]) * time.Microsecond
+
+	if data, err := os.ReadFile(filepath.Join(dir, "memory.peak")); err == nil {
+		usage.PeakRSS, _ = strconv.ParseUint(string(bytes.TrimSpace(data)), 10, 64)
+	}
+
+	if f, err := os.Open(filepath.Join(dir, "io.stat")); err == nil {
+		defer f.Close()
+		// One line per device: "8:0 rbytes=1 wbytes=2 rios=3 ..."
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 {
+				continue
+			}
+			for _, field := range fields[1:] {
+				key, value, _ := strings.Cut(field, "=")
+				n, _ := strconv.ParseUint(value, 10, 64)
+				switch key {
+				case "rbytes":
+					usage.ReadBytes += n
+				case "wbytes":
+					usage.WriteBytes += n
+				}
+			}
+		}
+	}
+	return usage, nil
+}
+
+// readKeyedFile reads a file of "key value" lines
+func readKeyedFile(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, " "); ok {
+			values[key], _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	return values, nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	return os.WriteFile(filepath.Join(dir, name), []byte(value), 0644)
+}
+
+// cgroupName makes a task name usable as a cgroup directory name
+func cgroupName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	if name == "" || name == "." || name == ".." {
+		name = "task"
+	}
+	return name
+}
@@ -0,0 +1,54 @@
+//go:build linux
+
+package taskusage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadCgroupUsage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cpu.stat":    "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n",
+		"memory.peak": "52428800\n",
+		"io.stat":     "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n259:0 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := readCgroupUsage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Usage{CPUTime: 1500 * time.Millisecond, PeakRSS: 52428800, ReadBytes: 5120, WriteBytes: 8192}
+	if usage != want {
+		t.Errorf("Expected %+v, got %+v", want, usage)
+	}
+}
+
+func TestReadCgroupUsage_CPUOnly(t *testing.T) {
+	// Without the memory and io controllers only cpu.stat exists
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cpu.stat"), []byte("usage_usec 42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := readCgroupUsage(dir)
+	if err != nil || usage != (Usage{CPUTime: 42 * time.Microsecond}) {
+		t.Errorf("Unexpected usage %+v, %v", usage, err)
+	}
+}
+
+func TestCgroupName(t *testing.T) {
+	if got := cgroupName("deploy web/01"); got != "deploy_web_01" {
+		t.Errorf("Unexpected name %q", got)
+	}
+	if got := cgroupName(".."); got != "task" {
+		t.Errorf("Unexpected name %q", got)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package taskusage
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// cgroup is the cgroup of one task, Linux only
+type cgroup struct {
+	dir string
+}
+
+func newCgroup(name string) (*cgroup, error) {
+	return nil, errors.New("cgroups are Linux only")
+}
+
+func (c *cgroup) attach(cmd *exec.Cmd) {}
+
+func (c *cgroup) close() (Usage, error) {
+	return Usage{}, nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package taskusage
+
+import "os"
+
+func rusage(state *os.ProcessState) (Usage, bool) {
+	return Usage{}, false
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package taskusage
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// rusage returns the usage of an exited process and the children it waited
+// for
+func rusage(state *os.ProcessState) (Usage, bool) {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return Usage{}, false
+	}
+	// Linux reports maxrss in KiB, macOS in bytes
+	peak := uint64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		peak *= 1024
+	}
+	// Block counts are of 512 bytes
+	return Usage{
+		CPUTime:    time.Duration(syscall.TimevalToNsec(ru.Utime) + syscall.TimevalToNsec(ru.Stime)),
+		PeakRSS:    peak,
+		ReadBytes:  uint64(ru.Inblock) * 512,
+		WriteBytes: uint64(ru.Oublock) * 512,
+	}, true
+}
@@ -0,0 +1,146 @@
+// Package taskusage measures what the processes a task starts consume:
+// CPU time, peak memory and disk I/O, so expensive tasks can be found and
+// agents right-sized.
+//
+// Each task gets a Tracker. On Linux with cgroup v2, commands started with
+// Attach run in a cgroup of the task, which accounts for their whole
+// process tree. Elsewhere the resource usage of each command is added up
+// once it exited, which counts its children that it waited for.
+package taskusage
+
+import (
+	"context"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+
+	pb "github.com/chalkan3-sloth/sloth-runner/proto"
+)
+
+// Usage is what the processes of a task consumed
+type Usage struct {
+	CPUTime time.Duration `json:"cpu_time"`
+	// PeakRSS is the most memory, in bytes, the processes used at once,
+	// or, without cgroup accounting, the most any single one used
+	PeakRSS    uint64 `json:"peak_rss"`
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
+}
+
+// Add adds the usage of other processes
+func (u *Usage) Add(other Usage) {
+	u.CPUTime += other.CPUTime
+	if other.PeakRSS > u.PeakRSS {
+		u.PeakRSS = other.PeakRSS
+	}
+	u.ReadBytes += other.ReadBytes
+	u.WriteBytes += other.WriteBytes
+}
+
+// Tracker accounts for the processes of one task
+type Tracker struct {
+	mu     sync.Mutex
+	usage  Usage
+	cgroup *cgroup
+}
+
+// NewTracker starts accounting for a task. It uses a cgroup of its own
+// when cgroup accounting is available.
+func NewTracker(name string) *Tracker {
+	t := &Tracker{}
+	cg, err := newCgroup(name)
+	if err != nil {
+		slog.Debug("Task cgroup not created, using rusage", "task", name, "error", err)
+	}
+	t.cgroup = cg
+	return t
+}
+
+// Close stops accounting and returns what the task's processes consumed
+func (t *Tracker) Close() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cgroup != nil {
+		usage, err := t.cgroup.close()
+		if err != nil {
+			slog.Debug("Failed to read task cgroup", "dir", t.cgroup.dir, "error", err)
+		}
+		t.usage.Add(usage)
+		t.cgroup = nil
+	}
+	return t.usage
+}
+
+type trackerKey struct{}
+
+// WithTracker returns a context whose commands are accounted by t
+func WithTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, t)
+}
+
+// FromContext returns the tracker of ctx, nil when it has none
+func FromContext(ctx context.Context) *Tracker {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
+
+// Attach accounts cmd to the task of ctx. Call it before cmd starts and
+// the returned function once cmd exited:
+//
+//	defer taskusage.Attach(ctx, cmd)()
+func Attach(ctx context.Context, cmd *exec.Cmd) func() {
+	t := FromContext(ctx)
+	if t == nil {
+		return func() {}
+	}
+
+	t.mu.Lock()
+	inCgroup := t.cgroup != nil
+	if inCgroup {
+		t.cgroup.attach(cmd)
+	}
+	t.mu.Unlock()
+
+	return func() {
+		if inCgroup || cmd.ProcessState == nil {
+			return
+		}
+		usage, ok := rusage(cmd.ProcessState)
+		if !ok {
+			return
+		}
+		t.mu.Lock()
+		t.usage.Add(usage)
+		t.mu.Unlock()
+	}
+}
+
+// Proto returns the usage as sent by agents
+func (u *Usage) Proto() *pb.TaskUsage {
+	if u == nil {
+		return nil
+	}
+	return &pb.TaskUsage{
+		CpuTimeMs:    u.CPUTime.Milliseconds(),
+		PeakRssBytes: u.PeakRSS,
+		ReadBytes:    u.ReadBytes,
+		WriteBytes:   u.WriteBytes,
+	}
+}
+
+// FromProto returns the usage an agent sent, nil when it sent none
+func FromProto(p *pb.TaskUsage) *Usage {
+	if p == nil {
+		return nil
+	}
+	return &Usage{
+		CPUTime:    time.Duration(p.GetCpuTimeMs()) * time.Millisecond,
+		PeakRSS:    p.GetPeakRssBytes(),
+		ReadBytes:  p.GetReadBytes(),
+		WriteBytes: p.GetWriteBytes(),
+	}
+}
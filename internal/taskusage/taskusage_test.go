@@ -0,0 +1,51 @@
+package taskusage
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestAttach_AccountsCommands(t *testing.T) {
+	tracker := &Tracker{}
+	ctx := WithTracker(context.Background(), tracker)
+
+	for i := 0; i < 2; i++ {
+		cmd := exec.Command("sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done")
+		done := Attach(ctx, cmd)
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		done()
+	}
+
+	usage := tracker.Close()
+	if usage.CPUTime <= 0 {
+		t.Errorf("Expected CPU time to be accounted, got %v", usage.CPUTime)
+	}
+	if usage.PeakRSS == 0 {
+		t.Error("Expected peak RSS to be accounted")
+	}
+}
+
+func TestAttach_WithoutTracker(t *testing.T) {
+	cmd := exec.Command("true")
+	done := Attach(context.Background(), cmd)
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	done()
+	if cmd.SysProcAttr != nil {
+		t.Error("Expected commands of untracked contexts to be left alone")
+	}
+}
+
+func TestUsageAdd(t *testing.T) {
+	u := Usage{CPUTime: time.Second, PeakRSS: 100, ReadBytes: 1, WriteBytes: 2}
+	u.Add(Usage{CPUTime: time.Second, PeakRSS: 50, ReadBytes: 10, WriteBytes: 20})
+	want := Usage{CPUTime: 2 * time.Second, PeakRSS: 100, ReadBytes: 11, WriteBytes: 22}
+	if u != want {
+		t.Errorf("Expected %+v, got %+v", want, u)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,6 +20,9 @@ type Server struct {
 	registry   *prometheus.Registry
 	port       int
 	enabled    bool
+
+	// Started once, however many times the server is restarted
+	runtimeMetrics sync.Once
 }
 
 // NewServer creates a new telemetry server
@@ -75,15 +80,21 @@ func (s *Server) Start() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Listen before returning so a port in use is reported to the caller
+	lis, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on metrics port %d: %w", s.port, err)
+	}
+
 	go func() {
 		slog.Info("Starting telemetry server", "port", s.port, "endpoint", "/metrics")
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.httpServer.Serve(lis); err != nil && err != http.ErrServerClosed {
 			slog.Error("Telemetry server failed", "error", err)
 		}
 	}()
 
 	// Start runtime metrics updater
-	go s.updateRuntimeMetricsLoop()
+	s.runtimeMetrics.Do(func() { go s.updateRuntimeMetricsLoop() })
 
 	return nil
 }
@@ -98,6 +109,23 @@ func (s *Server) Stop(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }
 
+// Reconfigure stops the server and starts it again on port, when enabled.
+// Metrics recorded so far are kept.
+func (s *Server) Reconfigure(ctx context.Context, port int, enabled bool) error {
+	if err := s.Stop(ctx); err != nil {
+		return err
+	}
+	s.httpServer = nil
+	s.port = port
+	s.enabled = enabled
+	return s.Start()
+}
+
+// Register adds a collector to the metrics the server exports
+func (s *Server) Register(c prometheus.Collector) error {
+	return s.registry.Register(c)
+}
+
 // GetMetrics returns the metrics instance
 func (s *Server) GetMetrics() *Metrics {
 	return s.metrics
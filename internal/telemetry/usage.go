@@ -0,0 +1,395 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/config"
+	"github.com/google/uuid"
+)
+
+// Usage stats are opt-in: nothing is recorded or sent until they are
+// enabled with 'sloth-runner telemetry enable'. Reports only hold counts
+// of command names, built-in module functions and error categories, never
+// arguments, file names, hosts or error messages.
+
+const (
+	// UsageSendInterval is how often pending usage stats are sent
+	UsageSendInterval = 24 * time.Hour
+
+	// usageSendTimeout bounds how long a command waits for the endpoint
+	usageSendTimeout = 3 * time.Second
+
+	// maxSentReports is how many sent reports are kept for inspection
+	maxSentReports = 20
+)
+
+// UsageConfig holds the usage stats settings
+type UsageConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// InstallID is random, so reports from one install can be grouped
+	// without identifying the host
+	InstallID string    `json:"install_id,omitempty"`
+	LastSent  time.Time `json:"last_sent,omitempty"`
+}
+
+// UsageReport is the payload sent to the endpoint
+type UsageReport struct {
+	InstallID   string           `json:"install_id"`
+	Version     string           `json:"version"`
+	OS          string           `json:"os"`
+	Arch        string           `json:"arch"`
+	PeriodStart time.Time        `json:"period_start"`
+	PeriodEnd   time.Time        `json:"period_end"`
+	Commands    map[string]int64 `json:"commands"`
+	Modules     map[string]int64 `json:"modules"`
+	Errors      map[string]int64 `json:"errors"`
+}
+
+// Empty reports whether the report has nothing to send
+func (r *UsageReport) Empty() bool {
+	return len(r.Commands) == 0 && len(r.Modules) == 0 && len(r.Errors) == 0
+}
+
+// UsageDisabledByEnv reports whether usage stats are turned off through
+// the environment, regardless of the settings
+func UsageDisabledByEnv() bool {
+	if v := os.Getenv("DO_NOT_TRACK"); v != "" && v != "0" {
+		return true
+	}
+	switch strings.ToLower(os.Getenv("SLOTH_RUNNER_TELEMETRY")) {
+	case "0", "off", "false", "disabled":
+		return true
+	}
+	return false
+}
+
+// UsageStore keeps the usage stats settings, the pending report and the
+// reports already sent in a directory
+type UsageStore struct {
+	dir string
+}
+
+// NewUsageStore returns a store in dir
+func NewUsageStore(dir string) *UsageStore {
+	return &UsageStore{dir: dir}
+}
+
+// DefaultUsageStore returns the store in the data directory
+func DefaultUsageStore() *UsageStore {
+	return NewUsageStore(config.GetTelemetryDir())
+}
+
+func (s *UsageStore) configPath() string  { return filepath.Join(s.dir, "config.json") }
+func (s *UsageStore) pendingPath() string { return filepath.Join(s.dir, "pending.json") }
+
+// SentPath returns the file sent reports are logged to
+func (s *UsageStore) SentPath() string { return filepath.Join(s.dir, "sent.jsonl") }
+
+// LoadConfig returns the settings; usage stats are disabled when none are saved
+func (s *UsageStore) LoadConfig() (*UsageConfig, error) {
+	cfg := &UsageConfig{}
+	if err := readJSON(s.configPath(), cfg); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read telemetry settings: %w", err)
+	}
+	return cfg, nil
+}
+
+// SaveConfig writes the settings
+func (s *UsageStore) SaveConfig(cfg *UsageConfig) error {
+	if cfg.InstallID == "" {
+		cfg.InstallID = uuid.New().String()
+	}
+	return writeJSON(s.configPath(), cfg)
+}
+
+// Pending returns the report that will be sent next
+func (s *UsageStore) Pending() (*UsageReport, error) {
+	report := &UsageReport{}
+	if err := readJSON(s.pendingPath(), report); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read pending usage stats: %w", err)
+	}
+	if report.Commands == nil {
+		report.Commands = map[string]int64{}
+	}
+	if report.Modules == nil {
+		report.Modules = map[string]int64{}
+	}
+	if report.Errors == nil {
+		report.Errors = map[string]int64{}
+	}
+	return report, nil
+}
+
+// Record adds a finished command to the pending report
+func (s *UsageStore) Record(cfg *UsageConfig, version, command string, modules map[string]int64, errCategory string) error {
+	report, err := s.Pending()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if report.PeriodStart.IsZero() {
+		report.PeriodStart = now
+	}
+	report.PeriodEnd = now
+	report.InstallID = cfg.InstallID
+	report.Version = version
+	report.OS = runtime.GOOS
+	report.Arch = runtime.GOARCH
+
+	if command != "" {
+		report.Commands[command]++
+	}
+	for name, count := range modules {
+		report.Modules[name] += count
+	}
+	if errCategory != "" {
+		report.Errors[errCategory]++
+	}
+	return writeJSON(s.pendingPath(), report)
+}
+
+// Send posts the pending report to the configured endpoint, logs it as
+// sent and starts a new one
+func (s *UsageStore) Send(ctx context.Context, client *http.Client, cfg *UsageConfig) error {
+	if cfg.Endpoint == "" {
+		return fmt.Errorf("no telemetry endpoint configured")
+	}
+	report, err := s.Pending()
+	if err != nil {
+		return err
+	}
+
+	if !report.Empty() {
+		body, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send usage stats: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("failed to send usage stats: %s", resp.Status)
+		}
+
+		if err := s.logSent(body); err != nil {
+			return err
+		}
+		if err := os.Remove(s.pendingPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	cfg.LastSent = time.Now().UTC()
+	return s.SaveConfig(cfg)
+}
+
+// Sent returns the reports sent most recently, oldest first
+func (s *UsageStore) Sent() ([]UsageReport, error) {
+	lines, err := readLines(s.SentPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	reports := make([]UsageReport, 0, len(lines))
+	for _, line := range lines {
+		var report UsageReport
+		if err := json.Unmarshal([]byte(line), &report); err == nil {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// Reset drops the pending report and the sent log
+func (s *UsageStore) Reset() error {
+	for _, path := range []string{s.pendingPath(), s.SentPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *UsageStore) logSent(body []byte) error {
+	lines, err := readLines(s.SentPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines = append(lines, string(body))
+	if len(lines) > maxSentReports {
+		lines = lines[len(lines)-maxSentReports:]
+	}
+	return writeFile(s.SentPath(), []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// ErrorCategory reduces an error to a coarse category, so reports never
+// carry error messages
+func ErrorCategory(err error) string {
+	if err == nil {
+		return ""
+	}
+	var netErr net.Error
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out"):
+		return "timeout"
+	case errors.As(err, &netErr) || strings.Contains(msg, "connection refused") || strings.Contains(msg, "failed to connect") || strings.Contains(msg, "unavailable"):
+		return "connection"
+	case errors.Is(err, os.ErrPermission) || strings.Contains(msg, "permission denied"):
+		return "permission"
+	case strings.Contains(msg, "denied by policy") || strings.Contains(msg, "requires approval"):
+		return "policy"
+	case errors.Is(err, os.ErrNotExist) || strings.Contains(msg, "not found") || strings.Contains(msg, "no such file"):
+		return "not_found"
+	case strings.Contains(msg, "lua") || strings.Contains(msg, "syntax error"):
+		return "workflow"
+	case strings.Contains(msg, "task") && strings.Contains(msg, "failed"):
+		return "task_failed"
+	case strings.Contains(msg, "unknown command") || strings.Contains(msg, "flag") || strings.Contains(msg, "arg"):
+		return "usage"
+	default:
+		return "other"
+	}
+}
+
+var (
+	usageMu      sync.Mutex
+	usageOnce    sync.Once
+	usageEnabled bool
+	moduleCalls  = map[string]int64{}
+)
+
+// UsageEnabled reports whether usage stats are recorded in this process
+func UsageEnabled() bool {
+	usageOnce.Do(func() {
+		if UsageDisabledByEnv() {
+			return
+		}
+		cfg, err := DefaultUsageStore().LoadConfig()
+		usageEnabled = err == nil && cfg.Enabled
+	})
+	return usageEnabled
+}
+
+// RecordModuleCall counts a call of a built-in module function
+func RecordModuleCall(module, function string) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	moduleCalls[module+"."+function]++
+}
+
+// ModuleCalls returns the module calls counted in this process
+func ModuleCalls() map[string]int64 {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	calls := make(map[string]int64, len(moduleCalls))
+	for name, count := range moduleCalls {
+		calls[name] = count
+	}
+	return calls
+}
+
+// FinishCommand records a finished command in the pending report and
+// sends the report once UsageSendInterval has passed since the last one.
+// It does nothing unless usage stats are enabled, and never fails the
+// command: problems are silently ignored.
+func FinishCommand(version, command string, cmdErr error) {
+	if !UsageEnabled() {
+		return
+	}
+	store := DefaultUsageStore()
+	cfg, err := store.LoadConfig()
+	if err != nil {
+		return
+	}
+	if err := store.Record(cfg, version, command, ModuleCalls(), ErrorCategory(cmdErr)); err != nil {
+		return
+	}
+	if time.Since(cfg.LastSent) < UsageSendInterval {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), usageSendTimeout)
+	defer cancel()
+	store.Send(ctx, http.DefaultClient, cfg)
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(data, '\n'))
+}
+
+// writeFile replaces path atomically, so concurrent commands never read a
+// half-written file
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
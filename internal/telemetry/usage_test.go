@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUsageStore_RecordAndSend(t *testing.T) {
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	store := NewUsageStore(t.TempDir())
+	cfg, err := store.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Enabled {
+		t.Fatal("Expected usage stats to be disabled by default")
+	}
+
+	cfg.Enabled = true
+	cfg.Endpoint = server.URL
+	if err := store.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InstallID == "" {
+		t.Fatal("Expected an install ID to be generated")
+	}
+
+	store.Record(cfg, "v1.0.0", "run", map[string]int64{"pkg.install": 2}, "")
+	store.Record(cfg, "v1.0.0", "run", map[string]int64{"pkg.install": 1, "file.copy": 1}, "timeout")
+
+	pending, err := store.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Commands["run"] != 2 || pending.Modules["pkg.install"] != 3 || pending.Errors["timeout"] != 1 {
+		t.Fatalf("Unexpected pending report: %+v", pending)
+	}
+
+	if err := store.Send(context.Background(), server.Client(), cfg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	var sent UsageReport
+	if err := json.Unmarshal(received, &sent); err != nil {
+		t.Fatalf("Endpoint received invalid JSON: %v", err)
+	}
+	if sent.InstallID != cfg.InstallID || sent.Modules["file.copy"] != 1 {
+		t.Errorf("Unexpected report sent: %+v", sent)
+	}
+
+	if pending, _ := store.Pending(); !pending.Empty() {
+		t.Errorf("Expected a new empty report after sending, got %+v", pending)
+	}
+	reports, err := store.Sent()
+	if err != nil || len(reports) != 1 || reports[0].Commands["run"] != 2 {
+		t.Errorf("Expected the sent report to be kept for inspection, got %+v %v", reports, err)
+	}
+
+	reloaded, _ := store.LoadConfig()
+	if reloaded.LastSent.IsZero() {
+		t.Error("Expected the send time to be saved")
+	}
+}
+
+func TestUsageStore_SendFailureKeepsPending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := NewUsageStore(t.TempDir())
+	cfg := &UsageConfig{Enabled: true, Endpoint: server.URL}
+	store.Record(cfg, "v1.0.0", "agent list", nil, "")
+
+	if err := store.Send(context.Background(), server.Client(), cfg); err == nil {
+		t.Fatal("Expected send to fail")
+	}
+	if pending, _ := store.Pending(); pending.Commands["agent list"] != 1 {
+		t.Errorf("Expected the report to stay pending, got %+v", pending)
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{context.DeadlineExceeded, "timeout"},
+		{fmt.Errorf("dial: %w", errors.New("connection refused")), "connection"},
+		{fmt.Errorf("open /etc/x: %w", os.ErrPermission), "permission"},
+		{errors.New("call of pkg.install denied by policy \"no-prod\""), "policy"},
+		{errors.New("agent 'web-01' not found"), "not_found"},
+		{errors.New("<string>:3: syntax error near 'end'"), "workflow"},
+		{errors.New("something odd"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorCategory(tt.err); got != tt.want {
+			t.Errorf("ErrorCategory(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestUsageDisabledByEnv(t *testing.T) {
+	t.Setenv("DO_NOT_TRACK", "")
+	t.Setenv("SLOTH_RUNNER_TELEMETRY", "")
+	if UsageDisabledByEnv() {
+		t.Error("Expected usage stats not to be disabled without environment")
+	}
+	t.Setenv("DO_NOT_TRACK", "1")
+	if !UsageDisabledByEnv() {
+		t.Error("Expected DO_NOT_TRACK=1 to disable usage stats")
+	}
+	t.Setenv("DO_NOT_TRACK", "")
+	t.Setenv("SLOTH_RUNNER_TELEMETRY", strings.ToUpper("off"))
+	if !UsageDisabledByEnv() {
+		t.Error("Expected SLOTH_RUNNER_TELEMETRY=OFF to disable usage stats")
+	}
+}
@@ -0,0 +1,239 @@
+// Package toolchain checks that the external tools modules drive, such as
+// apt, systemctl, docker or git, are installed and work on a host, so
+// workflows can be validated against an agent before they run on it.
+package toolchain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkTimeout bounds each command run to check a tool
+const checkTimeout = 10 * time.Second
+
+// Tool is an executable a module runs
+type Tool struct {
+	Name string
+	// VersionArgs print the tool's version; the tool works when they
+	// succeed
+	VersionArgs []string
+	// Probe, when set, runs after the version check to tell whether the
+	// tool can do its job, e.g. reach its daemon
+	Probe func(ctx context.Context, path string) error
+}
+
+// Requirement lists the tools a module needs; any one of them is enough
+type Requirement struct {
+	Module string
+	Tools  []Tool
+}
+
+var (
+	aptGet    = Tool{Name: "apt-get", VersionArgs: []string{"--version"}}
+	dnf       = Tool{Name: "dnf", VersionArgs: []string{"--version"}}
+	yum       = Tool{Name: "yum", VersionArgs: []string{"--version"}}
+	pacman    = Tool{Name: "pacman", VersionArgs: []string{"--version"}}
+	zypper    = Tool{Name: "zypper", VersionArgs: []string{"--version"}}
+	apk       = Tool{Name: "apk", VersionArgs: []string{"--version"}}
+	brew      = Tool{Name: "brew", VersionArgs: []string{"--version"}}
+	systemd   = Tool{Name: "systemctl", VersionArgs: []string{"--version"}, Probe: probeSystemd}
+	docker    = Tool{Name: "docker", VersionArgs: []string{"--version"}, Probe: probeDocker}
+	git       = Tool{Name: "git", VersionArgs: []string{"--version"}}
+	terraform = Tool{Name: "terraform", VersionArgs: []string{"version"}}
+)
+
+// Requirements are the modules checked, with the tools they need
+var Requirements = []Requirement{
+	{Module: "pkg", Tools: []Tool{aptGet, dnf, yum, pacman, zypper, apk, brew}},
+	{Module: "systemd", Tools: []Tool{systemd}},
+	{Module: "docker", Tools: []Tool{docker}},
+	{Module: "git", Tools: []Tool{git}},
+	{Module: "terraform", Tools: []Tool{terraform}},
+	{Module: "tofu", Tools: []Tool{{Name: "tofu", VersionArgs: []string{"version"}}, terraform}},
+	{Module: "pulumi", Tools: []Tool{{Name: "pulumi", VersionArgs: []string{"version"}}}},
+	{Module: "kubernetes", Tools: []Tool{{Name: "kubectl", VersionArgs: []string{"version", "--client"}}}},
+	{Module: "helm", Tools: []Tool{{Name: "helm", VersionArgs: []string{"version", "--short"}}}},
+	{Module: "incus", Tools: []Tool{{Name: "incus", VersionArgs: []string{"--version"}}}},
+	{Module: "stow", Tools: []Tool{{Name: "stow", VersionArgs: []string{"--version"}}}},
+	{Module: "aws", Tools: []Tool{{Name: "aws", VersionArgs: []string{"--version"}}}},
+	{Module: "azure", Tools: []Tool{{Name: "az", VersionArgs: []string{"version"}}}},
+	{Module: "gcp", Tools: []Tool{{Name: "gcloud", VersionArgs: []string{"--version"}}}},
+}
+
+// Modules returns the names of the modules checked
+func Modules() []string {
+	names := make([]string, len(Requirements))
+	for i, r := range Requirements {
+		names[i] = r.Module
+	}
+	return names
+}
+
+// requirement returns the requirement of a module
+func requirement(module string) (Requirement, bool) {
+	for _, r := range Requirements {
+		if r.Module == module {
+			return r, true
+		}
+	}
+	return Requirement{}, false
+}
+
+// ToolStatus is the result of checking a tool
+type ToolStatus struct {
+	Name string `json:"name"`
+	// Path is where the tool is installed, empty when it isn't
+	Path       string `json:"path,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Functional bool   `json:"functional"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ModuleStatus tells whether a module can run on the host
+type ModuleStatus struct {
+	Module string `json:"module"`
+	// Available is true when at least one of the module's tools works
+	Available bool         `json:"available"`
+	Tools     []ToolStatus `json:"tools"`
+}
+
+// Check checks the tools of modules, every module when none are given.
+// Tools shared by several modules are only run once.
+func Check(ctx context.Context, modules []string) ([]ModuleStatus, error) {
+	if len(modules) == 0 {
+		modules = Modules()
+	}
+
+	checked := make(map[string]ToolStatus)
+	statuses := make([]ModuleStatus, 0, len(modules))
+	for _, module := range modules {
+		req, ok := requirement(module)
+		if !ok {
+			return nil, fmt.Errorf("unknown module %q (known: %s)", module, strings.Join(Modules(), ", "))
+		}
+		status := ModuleStatus{Module: module}
+		for _, tool := range req.Tools {
+			ts, ok := checked[tool.Name]
+			if !ok {
+				ts = CheckTool(ctx, tool)
+				checked[tool.Name] = ts
+			}
+			// Alternatives that aren't installed are noise once one works
+			if ts.Path == "" && len(req.Tools) > 1 {
+				continue
+			}
+			status.Tools = append(status.Tools, ts)
+			status.Available = status.Available || ts.Functional
+		}
+		if len(status.Tools) == 0 {
+			status.Tools = []ToolStatus{{Name: toolNames(req.Tools), Error: "not installed"}}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func toolNames(tools []Tool) string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	return strings.Join(names, "|")
+}
+
+// versionPattern finds a version number in the output of a tool
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?[\w.+-]*`)
+
+// CheckTool checks that a tool is installed and runs
+func CheckTool(ctx context.Context, tool Tool) ToolStatus {
+	status := ToolStatus{Name: tool.Name}
+	path, err := exec.LookPath(tool.Name)
+	if err != nil {
+		status.Error = "not installed"
+		return status
+	}
+	status.Path = path
+
+	out, err := run(ctx, path, tool.VersionArgs...)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Version = versionPattern.FindString(out)
+
+	if tool.Probe != nil {
+		if err := tool.Probe(ctx, path); err != nil {
+			status.Error = err.Error()
+			return status
+		}
+	}
+	status.Functional = true
+	return status
+}
+
+// run runs a command, returning its output or an error with the first
+// line of it
+func run(ctx context.Context, path string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if err != nil {
+		if line := firstLine(string(out)); line != "" {
+			return "", fmt.Errorf("%s %s: %s", path, strings.Join(args, " "), line)
+		}
+		return "", fmt.Errorf("%s %s: %w", path, strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+// probeDocker checks that the docker daemon answers
+func probeDocker(ctx context.Context, path string) error {
+	if _, err := run(ctx, path, "version", "--format", "{{.Server.Version}}"); err != nil {
+		return fmt.Errorf("docker daemon unreachable: %w", err)
+	}
+	return nil
+}
+
+// probeSystemd checks that systemd is the running init system, which
+// systemctl needs to manage services
+func probeSystemd(ctx context.Context, path string) error {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return fmt.Errorf("systemd is not running on this host")
+	}
+	return nil
+}
+
+// modulePattern matches uses of a module in a Lua script: require("name")
+// or a call of the global, such as pkg.install or docker:run
+var modulePattern = regexp.MustCompile(`require\s*\(?\s*["']([a-z_]+)["']|(?:^|[^\w.:])([a-z_]+)\s*[.:]\s*[a-zA-Z_]+\s*[({"']`)
+
+// ModulesUsed returns the checked modules a Lua script uses, sorted
+func ModulesUsed(script string) []string {
+	seen := make(map[string]bool)
+	for _, m := range modulePattern.FindAllStringSubmatch(script, -1) {
+		name := m[1]
+		if name == "" {
+			name = m[2]
+		}
+		if _, ok := requirement(name); ok {
+			seen[name] = true
+		}
+	}
+	modules := make([]string, 0, len(seen))
+	for m := range seen {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	return modules
+}
@@ -0,0 +1,77 @@
+package toolchain
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTool installs an executable script called name on a PATH of its own
+func fakeTool(t *testing.T, name, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	t.Setenv("PATH", dir)
+}
+
+func TestCheckTool(t *testing.T) {
+	fakeTool(t, "git", `echo "git version 2.43.0"`)
+	status := CheckTool(context.Background(), git)
+	assert.True(t, status.Functional)
+	assert.Equal(t, "2.43.0", status.Version)
+
+	fakeTool(t, "git", `echo "error while loading shared libraries" >&2; exit 127`)
+	status = CheckTool(context.Background(), git)
+	assert.False(t, status.Functional)
+	assert.NotEmpty(t, status.Path)
+	assert.Contains(t, status.Error, "error while loading shared libraries")
+
+	t.Setenv("PATH", t.TempDir())
+	status = CheckTool(context.Background(), git)
+	assert.Equal(t, ToolStatus{Name: "git", Error: "not installed"}, status)
+}
+
+func TestCheckToolProbe(t *testing.T) {
+	// The client works but the daemon doesn't answer
+	fakeTool(t, "docker", `if [ "$1" = "--version" ]; then echo "Docker version 27.1.1, build 6312585"; else echo "Cannot connect to the Docker daemon" >&2; exit 1; fi`)
+	status := CheckTool(context.Background(), docker)
+	assert.False(t, status.Functional)
+	assert.Equal(t, "27.1.1", status.Version)
+	assert.Contains(t, status.Error, "docker daemon unreachable")
+}
+
+func TestCheck(t *testing.T) {
+	fakeTool(t, "dnf", `echo "4.18.0"`)
+	modules, err := Check(context.Background(), []string{"pkg", "git"})
+	require.NoError(t, err)
+	require.Len(t, modules, 2)
+
+	// Only the installed alternative of pkg is reported
+	assert.True(t, modules[0].Available)
+	require.Len(t, modules[0].Tools, 1)
+	assert.Equal(t, "dnf", modules[0].Tools[0].Name)
+
+	assert.False(t, modules[1].Available)
+
+	_, err = Check(context.Background(), []string{"nope"})
+	assert.Error(t, err)
+}
+
+func TestModulesUsed(t *testing.T) {
+	script := `
+local git = require("git")
+workflow.define("deploy", {
+  tasks = {
+    { name = "install", command = function() pkg.install({packages = {"nginx"}}) end },
+    { name = "start", command = function() systemd:restart("nginx") end },
+    { name = "log", command = function() log.info("done") ; state.set("k", "v") end },
+  },
+})
+`
+	assert.Equal(t, []string{"git", "pkg", "systemd"}, ModulesUsed(script))
+	assert.Empty(t, ModulesUsed(`local x = mygit.clone("repo")`))
+}
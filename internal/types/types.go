@@ -5,6 +5,9 @@ import (
 	"os/exec"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/nix"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskctx"
+	"github.com/chalkan3-sloth/sloth-runner/internal/taskusage"
 	"github.com/google/uuid"
 	lua "github.com/yuin/gopher-lua"
 )
@@ -15,6 +18,9 @@ type Task struct {
 	Name        string
 	Description string
 	Workdir     string // ✅ Added individual task workdir support
+	// Workspace lists the paths of the workspace sent along when the task
+	// is delegated, directories ending in /; empty sends all of it
+	Workspace   []string
 	User        string // ✅ User to run the task as (default: root)
 	CommandFunc *lua.LFunction
 	CommandStr  string
@@ -24,6 +30,14 @@ type Task struct {
 	NextIfFail  []string
 	Params      map[string]string
 	Retries     int
+	// RetryDelay is the wait before the first retry, "1s" when empty;
+	// RetryBackoff grows it for the next ones: fixed, linear (default) or
+	// exponential
+	RetryDelay   string
+	RetryBackoff string
+	// RetryOn limits retries to failures of these kinds, like timeout or
+	// connection; empty retries any failure
+	RetryOn     []string
 	Timeout     string
 	Async       bool
 	PreExec     *lua.LFunction
@@ -35,7 +49,24 @@ type Task struct {
 	AbortIf     string
 	AbortIfFunc *lua.LFunction
 	Output      *lua.LTable
+	// SetOutputs names the outputs set with task.set_output, true for the
+	// sensitive ones
+	SetOutputs  map[string]bool
 	DelegateTo  interface{} // Can be string (agent name) or map (inline agent definition)
+	Services    []string    // Services of the group that must be running before the task starts
+
+	// MaxConcurrency limits how many of the hosts a task delegated to
+	// several runs on at a time, 0 runs on all of them at once
+	MaxConcurrency int
+	// FailFast stops a task delegated to several hosts from starting on
+	// more of them once it failed on one
+	FailFast bool
+	// WorkspaceDiff records the workspace files a delegated task added,
+	// modified or deleted
+	WorkspaceDiff bool
+	// NixShell is the Nix shell the task's commands run in, set with
+	// runtime = runtime.nix_shell{...}
+	NixShell *nix.Shell
 }
 
 // TaskGroup represents a collection of related tasks.
@@ -47,6 +78,24 @@ type TaskGroup struct {
 	CreateWorkdirBeforeRun   bool
 	CleanWorkdirAfterRunFunc *lua.LFunction
 	DelegateTo               interface{} `yaml:"delegate_to"` // Can be map[string]Agent or string (default agent)
+	Services                 []Service
+	StrictTypes              bool // Modules reject option values they would coerce
+}
+
+// Service is a long-lived process a workflow keeps running while its tasks
+// run, such as a port-forward or a test database.
+type Service struct {
+	Name    string
+	Command string
+	Workdir string
+	Env     map[string]string
+	// ReadyPort, when set, is a local TCP port that accepts connections
+	// once the service is ready
+	ReadyPort int
+	// ReadyCommand, when set, is a shell command that succeeds once the
+	// service is ready
+	ReadyCommand string
+	ReadyTimeout string
 }
 
 // GenerateTaskID generates a new UUID for a task
@@ -65,6 +114,28 @@ type TaskResult struct {
 	Status   string
 	Duration time.Duration
 	Error    error
+	// Usage is what the task's processes consumed, when it was measured
+	Usage *taskusage.Usage
+	// Progress is the timeline of the progress the task reported
+	Progress []taskctx.Progress
+	// Changes are what the task's module calls changed, or would change
+	// when planned in a dry run
+	Changes []taskctx.Change
+	// WorkspaceChanges are the workspace files the task changed, for
+	// delegated tasks with workspace_diff set
+	WorkspaceChanges []WorkspaceChange
+	// SlowCalls are the task's module calls that ran past the slow
+	// threshold of their category, or timed out
+	SlowCalls []taskctx.SlowCall
+	// SetOutputs names the outputs the task set with task.set_output,
+	// true for the sensitive ones
+	SetOutputs map[string]bool
+}
+
+// WorkspaceChange is a workspace file a delegated task changed
+type WorkspaceChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"` // added, modified or deleted
 }
 
 // SharedSession holds data that can be shared between tasks in a group.
@@ -132,7 +132,7 @@ func (h *AgentDiagnosticsHandler) GetDetailedMetrics(c *gin.Context) {
 }
 
 // GetRecentLogs returns recent log entries for troubleshooting
-// GET /api/v1/agents/:name/logs?max_lines=100&level=error
+// GET /api/v1/agents/:name/logs?max_lines=100&level=error&pattern=timeout&since=&until=&page_token=
 func (h *AgentDiagnosticsHandler) GetRecentLogs(c *gin.Context) {
 	agentName := c.Param("name")
 	if agentName == "" {
@@ -144,6 +144,7 @@ func (h *AgentDiagnosticsHandler) GetRecentLogs(c *gin.Context) {
 	levelFilter := c.Query("level")
 	sourceFilter := c.Query("source")
 	sinceTimestamp, _ := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	untilTimestamp, _ := strconv.ParseInt(c.DefaultQuery("until", "0"), 10, 64)
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
@@ -168,6 +169,9 @@ func (h *AgentDiagnosticsHandler) GetRecentLogs(c *gin.Context) {
 		LevelFilter:    levelFilter,
 		SourceFilter:   sourceFilter,
 		SinceTimestamp: sinceTimestamp,
+		UntilTimestamp: untilTimestamp,
+		Pattern:        c.Query("pattern"),
+		PageToken:      c.Query("page_token"),
 	})
 	if err != nil {
 		st, ok := status.FromError(err)
@@ -177,6 +181,10 @@ func (h *AgentDiagnosticsHandler) GetRecentLogs(c *gin.Context) {
 			})
 			return
 		}
+		if ok && st.Code() == codes.InvalidArgument {
+			c.JSON(http.StatusBadRequest, gin.H{"error": st.Message()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("Failed to get logs: %v", err),
 		})
@@ -194,9 +202,10 @@ func (h *AgentDiagnosticsHandler) GetRecentLogs(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"logs":        logs,
-		"total_count": resp.TotalCount,
-		"has_more":    resp.HasMore,
+		"logs":            logs,
+		"total_count":     resp.TotalCount,
+		"has_more":        resp.HasMore,
+		"next_page_token": resp.NextPageToken,
 	})
 }
 
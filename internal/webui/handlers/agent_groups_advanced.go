@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/mtls"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"google.golang.org/grpc"
 )
@@ -1008,7 +1009,7 @@ func (w *AgentDBWrapper) executeCommandOnAgent(ctx context.Context, agentName, c
 	}
 
 	// Connect to agent via gRPC
-	conn, err := grpc.Dial(agent.Address, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	conn, err := grpc.Dial(agent.Address, mtls.DialOption(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to agent: %w", err)
 	}
@@ -1072,7 +1073,7 @@ func (w *AgentDBWrapper) restartAgentService(ctx context.Context, agentName stri
 	}
 
 	// Connect to agent via gRPC
-	conn, err := grpc.Dial(agent.Address, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	conn, err := grpc.Dial(agent.Address, mtls.DialOption(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
 	if err != nil {
 		return fmt.Errorf("failed to connect to agent: %w", err)
 	}
@@ -1102,7 +1103,7 @@ func (w *AgentDBWrapper) updateAgent(ctx context.Context, agentName string) erro
 	}
 
 	// Connect to agent via gRPC
-	conn, err := grpc.Dial(agent.Address, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	conn, err := grpc.Dial(agent.Address, mtls.DialOption(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
 	if err != nil {
 		return fmt.Errorf("failed to connect to agent: %w", err)
 	}
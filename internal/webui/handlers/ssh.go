@@ -53,6 +53,14 @@ type CreateSSHProfileRequest struct {
 	ConnectionTimeout  int    `json:"connection_timeout"`
 	KeepaliveInterval  int    `json:"keepalive_interval"`
 	StrictHostChecking bool   `json:"strict_host_checking"`
+	Protocol           string `json:"protocol"`
+	AuthMethod         string `json:"auth_method"`
+	KerberosPrincipal  string `json:"kerberos_principal"`
+	KerberosKeytab     string `json:"kerberos_keytab"`
+	KerberosCCache     string `json:"kerberos_ccache"`
+	KerberosDelegate   bool   `json:"kerberos_delegate"`
+	WinRMHTTPS         bool   `json:"winrm_https"`
+	WinRMInsecure      bool   `json:"winrm_insecure"`
 }
 
 // Create creates a new SSH profile
@@ -65,7 +73,14 @@ func (h *SSHHandler) Create(c *gin.Context) {
 
 	// Set defaults
 	if req.Port == 0 {
-		req.Port = 22
+		switch {
+		case req.Protocol == ssh.ProtocolWinRM && req.WinRMHTTPS:
+			req.Port = 5986
+		case req.Protocol == ssh.ProtocolWinRM:
+			req.Port = 5985
+		default:
+			req.Port = 22
+		}
 	}
 	if req.ConnectionTimeout == 0 {
 		req.ConnectionTimeout = 30
@@ -84,6 +99,14 @@ func (h *SSHHandler) Create(c *gin.Context) {
 		ConnectionTimeout:  req.ConnectionTimeout,
 		KeepaliveInterval:  req.KeepaliveInterval,
 		StrictHostChecking: req.StrictHostChecking,
+		Protocol:           req.Protocol,
+		AuthMethod:         req.AuthMethod,
+		KerberosPrincipal:  req.KerberosPrincipal,
+		KerberosKeytab:     req.KerberosKeytab,
+		KerberosCCache:     req.KerberosCCache,
+		KerberosDelegate:   req.KerberosDelegate,
+		WinRMHTTPS:         req.WinRMHTTPS,
+		WinRMInsecure:      req.WinRMInsecure,
 	}
 
 	if err := h.db.AddProfile(profile); err != nil {
@@ -113,6 +136,16 @@ func (h *SSHHandler) Update(c *gin.Context) {
 	updates["connection_timeout"] = req.ConnectionTimeout
 	updates["keepalive_interval"] = req.KeepaliveInterval
 	updates["strict_host_checking"] = req.StrictHostChecking
+	if req.Protocol != "" {
+		updates["protocol"] = req.Protocol
+	}
+	updates["auth_method"] = req.AuthMethod
+	updates["kerberos_principal"] = req.KerberosPrincipal
+	updates["kerberos_keytab"] = req.KerberosKeytab
+	updates["kerberos_ccache"] = req.KerberosCCache
+	updates["kerberos_delegate"] = req.KerberosDelegate
+	updates["winrm_https"] = req.WinRMHTTPS
+	updates["winrm_insecure"] = req.WinRMInsecure
 
 	if err := h.db.UpdateProfile(name, updates); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
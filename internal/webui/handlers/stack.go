@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/redact"
 	"github.com/chalkan3-sloth/sloth-runner/internal/stack"
 	"github.com/gin-gonic/gin"
 )
@@ -28,6 +29,9 @@ func (h *StackHandler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for _, s := range stacks {
+		s.Outputs = redact.MaskOutputs(s.Outputs)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"stacks": stacks,
@@ -61,6 +65,7 @@ func (h *StackHandler) Get(c *gin.Context) {
 		"created_at":      stackState.CreatedAt,
 		"updated_at":      stackState.UpdatedAt,
 		"execution_count": stackState.ExecutionCount,
+		"outputs":         redact.MaskOutputs(stackState.Outputs),
 		"variables":       variables,
 		"secrets":         secrets,
 		"variable_count":  len(variables),
@@ -9,6 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/apitoken"
+	"github.com/chalkan3-sloth/sloth-runner/internal/output"
+	"github.com/chalkan3-sloth/sloth-runner/internal/webui/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -24,10 +27,14 @@ type WorkflowExecutionHandler struct {
 type WorkflowExecution struct {
 	ID         string    `json:"id"`
 	WorkflowID string    `json:"workflow_id"`
+	Stack      string    `json:"stack,omitempty"`
+	TriggeredBy string   `json:"triggered_by,omitempty"`
 	Status     string    `json:"status"`
 	StartTime  time.Time `json:"start_time"`
 	EndTime    *time.Time `json:"end_time,omitempty"`
 	Logs       []string  `json:"logs"`
+	// Progress is the latest progress each task reported, by task name
+	Progress   map[string]TaskProgress `json:"progress,omitempty"`
 	ExitCode   *int      `json:"exit_code,omitempty"`
 	Error      string    `json:"error,omitempty"`
 	cmd        *osexec.Cmd
@@ -35,6 +42,13 @@ type WorkflowExecution struct {
 	cancel     context.CancelFunc
 }
 
+// TaskProgress is the progress a task reported with progress.update
+type TaskProgress struct {
+	Percent   float64   `json:"percent"` // -1 when the update had none
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // NewWorkflowExecutionHandler creates a new workflow execution handler
 func NewWorkflowExecutionHandler(wsHub *WebSocketHub) *WorkflowExecutionHandler {
 	return &WorkflowExecutionHandler{
@@ -43,10 +57,12 @@ func NewWorkflowExecutionHandler(wsHub *WebSocketHub) *WorkflowExecutionHandler
 	}
 }
 
-// ExecuteWorkflow starts a workflow execution
+// ExecuteWorkflow starts a workflow execution. With a stack, the named
+// saved sloth is run on that stack; API tokens must name one.
 func (h *WorkflowExecutionHandler) ExecuteWorkflow(c *gin.Context) {
 	var req struct {
 		WorkflowName string            `json:"workflow_name" binding:"required"`
+		Stack        string            `json:"stack"`
 		DelegateTo   string            `json:"delegate_to"`
 		Variables    map[string]string `json:"variables"`
 	}
@@ -56,25 +72,41 @@ func (h *WorkflowExecutionHandler) ExecuteWorkflow(c *gin.Context) {
 		return
 	}
 
+	token, hasToken := middleware.TokenFromContext(c)
+	if hasToken && req.Stack == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stack is required when using an API token"})
+		return
+	}
+	if !middleware.CheckToken(c, apitoken.ActionRun, req.Stack, req.WorkflowName) {
+		return
+	}
+	if !middleware.CheckTokenDelegation(c, req.DelegateTo) {
+		return
+	}
+
 	executionID := uuid.New().String()
 	ctx, cancel := context.WithCancel(context.Background())
 
 	execution := &WorkflowExecution{
 		ID:         executionID,
 		WorkflowID: req.WorkflowName,
+		Stack:      req.Stack,
 		Status:     "running",
 		StartTime:  time.Now(),
 		Logs:       make([]string, 0),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
+	if hasToken {
+		execution.TriggeredBy = "token:" + token.ID
+	}
 
 	h.mu.Lock()
 	h.executions[executionID] = execution
 	h.mu.Unlock()
 
 	// Start execution in goroutine
-	go h.runWorkflow(execution, req.WorkflowName, req.DelegateTo, req.Variables)
+	go h.runWorkflow(execution, req.Stack, req.WorkflowName, req.DelegateTo, req.Variables)
 
 	c.JSON(http.StatusOK, gin.H{
 		"execution_id": executionID,
@@ -83,9 +115,12 @@ func (h *WorkflowExecutionHandler) ExecuteWorkflow(c *gin.Context) {
 }
 
 // runWorkflow executes the workflow
-func (h *WorkflowExecutionHandler) runWorkflow(exec *WorkflowExecution, workflowName, delegateTo string, variables map[string]string) {
+func (h *WorkflowExecutionHandler) runWorkflow(exec *WorkflowExecution, stack, workflowName, delegateTo string, variables map[string]string) {
 	// Build command
 	args := []string{"run", workflowName}
+	if stack != "" {
+		args = []string{"run", stack, "--sloth", workflowName}
+	}
 
 	if delegateTo != "" {
 		args = append(args, "--delegate-to", delegateTo)
@@ -116,6 +151,9 @@ func (h *WorkflowExecutionHandler) runWorkflow(exec *WorkflowExecution, workflow
 			line := scanner.Text()
 			exec.Logs = append(exec.Logs, line)
 			h.notifyLog(exec.ID, line)
+			if task, percent, message, ok := output.ParseProgress(line); ok {
+				h.updateProgress(exec, task, TaskProgress{Percent: percent, Message: message, UpdatedAt: time.Now()})
+			}
 		}
 	}()
 
@@ -161,6 +199,9 @@ func (h *WorkflowExecutionHandler) GetExecution(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
 		return
 	}
+	if !middleware.CheckToken(c, apitoken.ActionRead, exec.Stack, exec.WorkflowID) {
+		return
+	}
 
 	c.JSON(http.StatusOK, exec)
 }
@@ -190,6 +231,9 @@ func (h *WorkflowExecutionHandler) CancelExecution(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
 		return
 	}
+	if !middleware.CheckToken(c, apitoken.ActionRun, exec.Stack, exec.WorkflowID) {
+		return
+	}
 
 	if exec.Status != "running" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Execution is not running"})
@@ -218,6 +262,9 @@ func (h *WorkflowExecutionHandler) GetExecutionLogs(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
 		return
 	}
+	if !middleware.CheckToken(c, apitoken.ActionRead, exec.Stack, exec.WorkflowID) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"logs": exec.Logs})
 }
@@ -229,6 +276,28 @@ func (h *WorkflowExecutionHandler) notifyUpdate(exec *WorkflowExecution) {
 	}
 }
 
+// updateProgress records the progress of a task and sends it via
+// WebSocket. The map is replaced rather than modified, as it may be
+// serialized for a request at the same time.
+func (h *WorkflowExecutionHandler) updateProgress(exec *WorkflowExecution, task string, progress TaskProgress) {
+	updated := make(map[string]TaskProgress, len(exec.Progress)+1)
+	for name, p := range exec.Progress {
+		updated[name] = p
+	}
+	updated[task] = progress
+	exec.Progress = updated
+
+	if h.wsHub != nil {
+		h.wsHub.Broadcast("workflow_execution_progress", gin.H{
+			"execution_id": exec.ID,
+			"task":         task,
+			"percent":      progress.Percent,
+			"message":      progress.Message,
+			"timestamp":    progress.UpdatedAt.Unix(),
+		})
+	}
+}
+
 // notifyLog sends log line via WebSocket
 func (h *WorkflowExecutionHandler) notifyLog(executionID, logLine string) {
 	if h.wsHub != nil {
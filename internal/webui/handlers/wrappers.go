@@ -8,9 +8,9 @@ import (
 	"path/filepath"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/chalkan3-sloth/sloth-runner/internal/hooks"
 	"github.com/chalkan3-sloth/sloth-runner/internal/sloth"
+	"github.com/chalkan3-sloth/sloth-runner/internal/sqlitedb"
 	"github.com/chalkan3-sloth/sloth-runner/internal/ssh"
 )
 
@@ -39,7 +39,7 @@ func NewAgentDBWrapper(dbPath string) (*AgentDBWrapper, error) {
 		dbPath = filepath.Join(".sloth-cache", "agents.db")
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open agent database: %w", err)
 	}
@@ -162,7 +162,7 @@ func (w *AgentDBWrapper) GetMetricsHistory(ctx context.Context, agentName string
 // Close closes the database connection
 func (w *AgentDBWrapper) Close() error {
 	if w.db != nil {
-		return w.db.Close()
+		return sqlitedb.Close(w.db)
 	}
 	return nil
 }
@@ -315,7 +315,7 @@ func NewSecretsServiceWrapper(dbPath string) (*SecretsServiceWrapper, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sqlitedb.Open(dbPath, sqlitedb.Options{})
 	if err != nil {
 		return nil, err
 	}
@@ -348,7 +348,7 @@ func (w *SecretsServiceWrapper) ListSecrets(ctx context.Context, stackID string)
 // Close closes the database connection
 func (w *SecretsServiceWrapper) Close() error {
 	if w.db != nil {
-		return w.db.Close()
+		return sqlitedb.Close(w.db)
 	}
 	return nil
 }
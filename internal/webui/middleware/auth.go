@@ -30,6 +30,12 @@ func (ba *BasicAuth) Middleware() gin.HandlerFunc {
 			return
 		}
 
+		// Requests authenticated by TokenAuth don't need a password
+		if _, ok := TokenFromContext(c); ok {
+			c.Next()
+			return
+		}
+
 		user, pass, ok := c.Request.BasicAuth()
 		if !ok {
 			ba.unauthorized(c)
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chalkan3-sloth/sloth-runner/internal/apitoken"
+	"github.com/gin-gonic/gin"
+)
+
+const tokenContextKey = "api_token"
+
+// tokenRoutes are the only routes API tokens may call, with the action
+// each needs. Handlers check the token's stack and workflow scope.
+var tokenRoutes = map[string]string{
+	"POST /api/v1/executions":            apitoken.ActionRun,
+	"POST /api/v1/executions/:id/cancel": apitoken.ActionRun,
+	"GET /api/v1/executions/:id/status":  apitoken.ActionRead,
+	"GET /api/v1/executions/:id/logs":    apitoken.ActionRead,
+}
+
+// TokenAuth authenticates requests carrying a scoped API token
+type TokenAuth struct {
+	store *apitoken.Store
+}
+
+// NewTokenAuth creates a new TokenAuth middleware
+func NewTokenAuth(store *apitoken.Store) *TokenAuth {
+	return &TokenAuth{store: store}
+}
+
+// Middleware returns a Gin middleware function. Requests without a
+// "Bearer slr_..." Authorization header pass through untouched.
+func (ta *TokenAuth) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(raw, apitoken.Prefix) {
+			c.Next()
+			return
+		}
+
+		token, err := ta.store.Authenticate(raw)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		action, allowed := tokenRoutes[c.Request.Method+" "+c.FullPath()]
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "API tokens can't access this endpoint",
+			})
+			return
+		}
+		if !token.Can(action) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "role " + string(token.Role) + " can't " + action,
+			})
+			return
+		}
+
+		c.Set(tokenContextKey, token)
+		c.Next()
+	}
+}
+
+// TokenFromContext returns the API token a request was authenticated with
+func TokenFromContext(c *gin.Context) (*apitoken.Token, bool) {
+	v, ok := c.Get(tokenContextKey)
+	if !ok {
+		return nil, false
+	}
+	token, ok := v.(*apitoken.Token)
+	return token, ok
+}
+
+// CheckToken checks that the request's API token, if any, may perform an
+// action on a workflow of a stack, aborting the request when it may not
+func CheckToken(c *gin.Context, action, stack, workflow string) bool {
+	token, ok := TokenFromContext(c)
+	if !ok {
+		return true
+	}
+	if err := token.Allows(action, stack, workflow); err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// CheckTokenDelegation checks that the request's API token, if any, may
+// delegate a run to delegateTo, aborting the request when it may not
+func CheckTokenDelegation(c *gin.Context, delegateTo string) bool {
+	token, ok := TokenFromContext(c)
+	if !ok || delegateTo == "" {
+		return true
+	}
+	if err := token.AllowsDelegation(delegateTo); err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
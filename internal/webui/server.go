@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/chalkan3-sloth/sloth-runner/internal/apitoken"
 	"github.com/chalkan3-sloth/sloth-runner/internal/config"
 	"github.com/chalkan3-sloth/sloth-runner/internal/metrics"
 	"github.com/chalkan3-sloth/sloth-runner/internal/webui/handlers"
@@ -36,6 +37,7 @@ type Server struct {
 	agentClient      *services.AgentClient
 	metricsDB        *metrics.MetricsDB
 	metricsCollector *metrics.Collector
+	tokenStore       *apitoken.Store
 }
 
 // Config holds server configuration
@@ -125,6 +127,12 @@ func NewServer(cfg *Config) (*Server, error) {
 		metricsDB = nil
 	}
 
+	tokenStore, err := apitoken.DefaultStore()
+	if err != nil {
+		slog.Warn("Failed to open API token store, API tokens will be rejected", "error", err)
+		tokenStore = nil
+	}
+
 	server := &Server{
 		router:       router,
 		port:         cfg.Port,
@@ -137,6 +145,7 @@ func NewServer(cfg *Config) (*Server, error) {
 		stackHandler: stackHandler,
 		agentClient:  agentClient,
 		metricsDB:    metricsDB,
+		tokenStore:   tokenStore,
 	}
 
 	// Setup routes
@@ -155,6 +164,11 @@ func (s *Server) setupRoutes(cfg *Config) {
 	templatesFS, _ := fs.Sub(embeddedFS, "templates")
 	s.router.StaticFS("/templates", http.FS(templatesFS))
 
+	// Scoped API tokens for CI, checked before basic auth
+	if s.tokenStore != nil {
+		s.router.Use(middleware.NewTokenAuth(s.tokenStore).Middleware())
+	}
+
 	// Authentication middleware (optional)
 	if cfg.EnableAuth {
 		auth := middleware.NewBasicAuth(cfg.Username, cfg.Password)
@@ -371,6 +385,7 @@ func (s *Server) setupRoutes(cfg *Config) {
 			executions.GET("", handlers.ListExecutionsHandler)
 			executions.GET("/stats", handlers.GetExecutionStatsHandler)
 			executions.GET("/:id", handlers.GetExecutionHandler)
+			executions.GET("/:id/status", execHandler.GetExecution)
 			executions.POST("/:id/cancel", execHandler.CancelExecution)
 			executions.GET("/:id/logs", execHandler.GetExecutionLogs)
 			executions.DELETE("/cleanup", handlers.DeleteOldExecutionsHandler)
@@ -554,6 +569,9 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if s.metricsDB != nil {
 		s.metricsDB.Close()
 	}
+	if s.tokenStore != nil {
+		s.tokenStore.Close()
+	}
 
 	// Shutdown HTTP server
 	if s.httpServer != nil {
@@ -6,9 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chalkan3-sloth/sloth-runner/internal/reliability"
 	pb "github.com/chalkan3-sloth/sloth-runner/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // AgentClient manages connections to agents
@@ -47,11 +47,11 @@ func (ac *AgentClient) GetClient(ctx context.Context, agentAddress string) (pb.A
 		return client, nil
 	}
 
-	conn, err := grpc.Dial(agentAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	opts := append(reliability.AgentDialOptions(),
 		grpc.WithBlock(),
 		grpc.WithTimeout(5*time.Second),
 	)
+	conn, err := grpc.Dial(agentAddress, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to agent %s: %w", agentAddress, err)
 	}
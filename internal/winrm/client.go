@@ -0,0 +1,259 @@
+// Package winrm implements a minimal WinRM (WS-Management remote shell)
+// client for running commands on Windows targets.
+package winrm
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Authentication methods
+const (
+	AuthBasic    = "basic"
+	AuthKerberos = "kerberos"
+)
+
+// Default WinRM listener ports
+const (
+	DefaultHTTPPort  = 5985
+	DefaultHTTPSPort = 5986
+)
+
+// Config configures a WinRM client
+type Config struct {
+	Host     string
+	Port     int
+	HTTPS    bool
+	Insecure bool
+
+	// Auth is AuthBasic (default) or AuthKerberos
+	Auth     string
+	User     string
+	Password string
+	// CCache is the Kerberos credential cache used with AuthKerberos;
+	// empty uses the default cache of the current user
+	CCache string
+
+	Timeout time.Duration
+}
+
+// Result is the outcome of a remote command
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Client runs commands through the WinRM cmd shell
+type Client struct {
+	endpoint         string
+	transport        transport
+	operationTimeout int
+}
+
+// NewClient creates a WinRM client
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("winrm host is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = DefaultHTTPPort
+		if cfg.HTTPS {
+			cfg.Port = DefaultHTTPSPort
+		}
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+
+	scheme := "http"
+	if cfg.HTTPS {
+		scheme = "https"
+	}
+
+	c := &Client{
+		endpoint:         fmt.Sprintf("%s://%s:%d/wsman", scheme, cfg.Host, cfg.Port),
+		operationTimeout: 20,
+	}
+
+	switch cfg.Auth {
+	case "", AuthBasic:
+		if cfg.User == "" || cfg.Password == "" {
+			return nil, fmt.Errorf("basic authentication requires user and password")
+		}
+		c.transport = newBasicTransport(cfg)
+	case AuthKerberos:
+		c.transport = &negotiateTransport{
+			ccache:   cfg.CCache,
+			insecure: cfg.Insecure,
+			timeout:  cfg.Timeout,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported winrm authentication: %s", cfg.Auth)
+	}
+
+	return c, nil
+}
+
+// Endpoint returns the WS-Management URL of the target
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// Run executes command in a new cmd shell and waits for it to finish
+func (c *Client) Run(ctx context.Context, command string) (*Result, error) {
+	shellID, err := c.createShell(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.deleteShell(context.Background(), shellID)
+
+	commandID, err := c.startCommand(ctx, shellID, command)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	for {
+		done, exitCode, err := c.receive(ctx, shellID, commandID, &stdout, &stderr)
+		if err != nil {
+			c.signal(context.Background(), shellID, commandID)
+			return nil, err
+		}
+		if done {
+			c.signal(context.Background(), shellID, commandID)
+			return &Result{
+				Stdout:   stdout.String(),
+				Stderr:   stderr.String(),
+				ExitCode: exitCode,
+			}, nil
+		}
+	}
+}
+
+func (c *Client) createShell(ctx context.Context) (string, error) {
+	data, err := c.send(ctx, &message{
+		action: actionCreate,
+		options: map[string]string{
+			"WINRS_NOPROFILE": "FALSE",
+			"WINRS_CODEPAGE":  "65001",
+		},
+		body: createShellBody(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create shell: %w", err)
+	}
+
+	var resp createResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse create shell response: %w", err)
+	}
+	if resp.ShellID != "" {
+		return resp.ShellID, nil
+	}
+	if resp.Selector != "" {
+		return resp.Selector, nil
+	}
+	return "", fmt.Errorf("create shell response contained no shell id")
+}
+
+func (c *Client) startCommand(ctx context.Context, shellID, command string) (string, error) {
+	data, err := c.send(ctx, &message{
+		action:  actionCommand,
+		shellID: shellID,
+		options: map[string]string{
+			"WINRS_CONSOLEMODE_STDIN": "TRUE",
+			"WINRS_SKIP_CMD_SHELL":    "FALSE",
+		},
+		body: commandBody(command),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start command: %w", err)
+	}
+
+	var resp commandResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse command response: %w", err)
+	}
+	if resp.CommandID == "" {
+		return "", fmt.Errorf("command response contained no command id")
+	}
+	return resp.CommandID, nil
+}
+
+// receive fetches pending output; done is set once the command finished
+func (c *Client) receive(ctx context.Context, shellID, commandID string, stdout, stderr *bytes.Buffer) (done bool, exitCode int, err error) {
+	data, err := c.send(ctx, &message{
+		action:  actionReceive,
+		shellID: shellID,
+		body:    receiveBody(commandID),
+	})
+	if err != nil {
+		var fault *Fault
+		if errors.As(err, &fault) && fault.WSMCode == faultOperationTimeout {
+			// Nothing new yet, poll again
+			return false, 0, nil
+		}
+		return false, 0, fmt.Errorf("failed to receive output: %w", err)
+	}
+
+	var resp receiveResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return false, 0, fmt.Errorf("failed to parse receive response: %w", err)
+	}
+	if err := resp.decodeStreams(stdout, stderr); err != nil {
+		return false, 0, err
+	}
+
+	if resp.State.State == commandStateDone {
+		if resp.State.ExitCode != nil {
+			exitCode = *resp.State.ExitCode
+		}
+		return true, exitCode, nil
+	}
+	return false, 0, nil
+}
+
+func (c *Client) signal(ctx context.Context, shellID, commandID string) {
+	c.send(ctx, &message{
+		action:  actionSignal,
+		shellID: shellID,
+		body:    signalBody(commandID),
+	})
+}
+
+func (c *Client) deleteShell(ctx context.Context, shellID string) {
+	c.send(ctx, &message{
+		action:  actionDelete,
+		shellID: shellID,
+	})
+}
+
+// send posts a message and turns HTTP and SOAP failures into errors
+func (c *Client) send(ctx context.Context, m *message) ([]byte, error) {
+	m.endpoint = c.endpoint
+	m.timeout = c.operationTimeout
+	m.maxEnvelopeSize = 153600
+
+	status, data, err := c.transport.post(ctx, c.endpoint, m.render())
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case status == http.StatusOK:
+		return data, nil
+	case status == http.StatusUnauthorized:
+		return nil, fmt.Errorf("authentication to %s was rejected", c.endpoint)
+	default:
+		if fault := parseFault(data); fault != nil {
+			return nil, fault
+		}
+		return nil, fmt.Errorf("unexpected HTTP status %d from %s", status, c.endpoint)
+	}
+}
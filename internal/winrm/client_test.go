@@ -0,0 +1,138 @@
+package winrm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeWinRM answers the remote shell protocol for a single command
+type fakeWinRM struct {
+	mu       sync.Mutex
+	actions  []string
+	command  string
+	receives int
+}
+
+func (f *fakeWinRM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, pass, ok := r.BasicAuth()
+	if !ok || user != "admin" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	req := string(body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := func(inner string) {
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell"><s:Body>%s</s:Body></s:Envelope>`, inner)
+	}
+
+	switch {
+	case strings.Contains(req, actionCreate):
+		f.actions = append(f.actions, "create")
+		reply(`<rsp:Shell><rsp:ShellId>SHELL-1</rsp:ShellId></rsp:Shell>`)
+	case strings.Contains(req, actionCommand):
+		f.actions = append(f.actions, "command")
+		start := strings.Index(req, "<rsp:Command>") + len("<rsp:Command>")
+		f.command = req[start:strings.Index(req, "</rsp:Command>")]
+		reply(`<rsp:CommandResponse><rsp:CommandId>CMD-1</rsp:CommandId></rsp:CommandResponse>`)
+	case strings.Contains(req, actionReceive):
+		f.actions = append(f.actions, "receive")
+		f.receives++
+		switch f.receives {
+		case 1:
+			// Simulate a long running command hitting the operation timeout
+			w.WriteHeader(http.StatusInternalServerError)
+			reply(`<s:Fault><s:Code><s:Value>s:Receiver</s:Value><s:Subcode><s:Value>w:TimedOut</s:Value></s:Subcode></s:Code><s:Reason><s:Text>timed out</s:Text></s:Reason><s:Detail><f:WSManFault xmlns:f="http://schemas.microsoft.com/wbem/wsman/1/wsmanfault" Code="2150858793"/></s:Detail></s:Fault>`)
+		case 2:
+			reply(`<rsp:ReceiveResponse><rsp:Stream Name="stdout" CommandId="CMD-1">` + b64("hello ") + `</rsp:Stream></rsp:ReceiveResponse>`)
+		default:
+			reply(`<rsp:ReceiveResponse><rsp:Stream Name="stdout" CommandId="CMD-1" End="true">` + b64("world") + `</rsp:Stream><rsp:Stream Name="stderr" CommandId="CMD-1">` + b64("warn") + `</rsp:Stream><rsp:CommandState CommandId="CMD-1" State="` + commandStateDone + `"><rsp:ExitCode>3</rsp:ExitCode></rsp:CommandState></rsp:ReceiveResponse>`)
+		}
+	case strings.Contains(req, actionSignal):
+		f.actions = append(f.actions, "signal")
+		reply(`<rsp:SignalResponse/>`)
+	case strings.Contains(req, actionDelete):
+		f.actions = append(f.actions, "delete")
+		reply(``)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func newTestClient(t *testing.T, handler http.Handler, password string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	host, portStr, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+
+	c, err := NewClient(Config{Host: host, Port: port, User: "admin", Password: password})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return c
+}
+
+func TestClient_Run(t *testing.T) {
+	fake := &fakeWinRM{}
+	c := newTestClient(t, fake, "secret")
+
+	res, err := c.Run(context.Background(), `echo "a" & dir C:\`)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Stdout != "hello world" || res.Stderr != "warn" || res.ExitCode != 3 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if fake.command != `echo &#34;a&#34; &amp; dir C:\` {
+		t.Errorf("Command not escaped: %s", fake.command)
+	}
+
+	want := "create,command,receive,receive,receive,signal,delete"
+	if got := strings.Join(fake.actions, ","); got != want {
+		t.Errorf("Actions = %s, want %s", got, want)
+	}
+}
+
+func TestClient_RunUnauthorized(t *testing.T) {
+	c := newTestClient(t, &fakeWinRM{}, "wrong")
+
+	_, err := c.Run(context.Background(), "hostname")
+	if err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("Expected authentication error, got %v", err)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	c, err := NewClient(Config{Host: "win01", HTTPS: true, Auth: AuthKerberos})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if c.Endpoint() != "https://win01:5986/wsman" {
+		t.Errorf("Unexpected endpoint %s", c.Endpoint())
+	}
+
+	if _, err := NewClient(Config{Host: "win01"}); err == nil {
+		t.Error("Expected error for basic auth without credentials")
+	}
+	if _, err := NewClient(Config{Host: "win01", Auth: "ntlm"}); err == nil {
+		t.Error("Expected error for unsupported auth")
+	}
+}
@@ -0,0 +1,194 @@
+package winrm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// WS-Management actions and URIs used by the remote shell protocol
+const (
+	actionCreate  = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Create"
+	actionDelete  = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Delete"
+	actionCommand = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Command"
+	actionReceive = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Receive"
+	actionSignal  = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Signal"
+
+	resourceCmdShell = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/cmd"
+	signalTerminate  = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/signal/terminate"
+	commandStateDone = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Done"
+
+	// faultOperationTimeout is returned by Receive when no output arrived
+	// within the operation timeout; the command is still running
+	faultOperationTimeout = "2150858793"
+)
+
+// message describes one WS-Management request
+type message struct {
+	action          string
+	shellID         string
+	options         map[string]string
+	body            string
+	timeout         int
+	endpoint        string
+	maxEnvelopeSize int
+}
+
+// render builds the SOAP envelope for the message
+func (m *message) render() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"`)
+	b.WriteString(` xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing"`)
+	b.WriteString(` xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd"`)
+	b.WriteString(` xmlns:p="http://schemas.microsoft.com/wbem/wsman/1/wsman.xsd"`)
+	b.WriteString(` xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">`)
+
+	b.WriteString(`<env:Header>`)
+	fmt.Fprintf(&b, `<a:To>%s</a:To>`, escape(m.endpoint))
+	b.WriteString(`<a:ReplyTo><a:Address env:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</a:Address></a:ReplyTo>`)
+	fmt.Fprintf(&b, `<w:MaxEnvelopeSize env:mustUnderstand="true">%d</w:MaxEnvelopeSize>`, m.maxEnvelopeSize)
+	fmt.Fprintf(&b, `<a:MessageID>uuid:%s</a:MessageID>`, uuid.New().String())
+	b.WriteString(`<w:Locale xml:lang="en-US" env:mustUnderstand="false"/>`)
+	b.WriteString(`<p:DataLocale xml:lang="en-US" env:mustUnderstand="false"/>`)
+	fmt.Fprintf(&b, `<w:OperationTimeout>PT%dS</w:OperationTimeout>`, m.timeout)
+	fmt.Fprintf(&b, `<w:ResourceURI env:mustUnderstand="true">%s</w:ResourceURI>`, resourceCmdShell)
+	fmt.Fprintf(&b, `<a:Action env:mustUnderstand="true">%s</a:Action>`, m.action)
+	if len(m.options) > 0 {
+		b.WriteString(`<w:OptionSet>`)
+		for _, name := range sortedKeys(m.options) {
+			fmt.Fprintf(&b, `<w:Option Name="%s">%s</w:Option>`, name, escape(m.options[name]))
+		}
+		b.WriteString(`</w:OptionSet>`)
+	}
+	if m.shellID != "" {
+		fmt.Fprintf(&b, `<w:SelectorSet><w:Selector Name="ShellId">%s</w:Selector></w:SelectorSet>`, escape(m.shellID))
+	}
+	b.WriteString(`</env:Header>`)
+
+	b.WriteString(`<env:Body>`)
+	b.WriteString(m.body)
+	b.WriteString(`</env:Body></env:Envelope>`)
+	return b.Bytes()
+}
+
+func createShellBody() string {
+	return `<rsp:Shell><rsp:InputStreams>stdin</rsp:InputStreams><rsp:OutputStreams>stdout stderr</rsp:OutputStreams></rsp:Shell>`
+}
+
+func commandBody(command string) string {
+	return fmt.Sprintf(`<rsp:CommandLine><rsp:Command>%s</rsp:Command></rsp:CommandLine>`, escape(command))
+}
+
+func receiveBody(commandID string) string {
+	return fmt.Sprintf(`<rsp:Receive><rsp:DesiredStream CommandId="%s">stdout stderr</rsp:DesiredStream></rsp:Receive>`, escape(commandID))
+}
+
+func signalBody(commandID string) string {
+	return fmt.Sprintf(`<rsp:Signal CommandId="%s"><rsp:Code>%s</rsp:Code></rsp:Signal>`, escape(commandID), signalTerminate)
+}
+
+// createResponse carries the shell ID, either in the body or as selector
+type createResponse struct {
+	ShellID  string `xml:"Body>Shell>ShellId"`
+	Selector string `xml:"Body>ResourceCreated>ReferenceParameters>SelectorSet>Selector"`
+}
+
+type commandResponse struct {
+	CommandID string `xml:"Body>CommandResponse>CommandId"`
+}
+
+type receiveResponse struct {
+	Streams []struct {
+		Name string `xml:"Name,attr"`
+		End  bool   `xml:"End,attr"`
+		Data string `xml:",chardata"`
+	} `xml:"Body>ReceiveResponse>Stream"`
+	State struct {
+		State    string `xml:"State,attr"`
+		ExitCode *int   `xml:"ExitCode"`
+	} `xml:"Body>ReceiveResponse>CommandState"`
+}
+
+type faultResponse struct {
+	Code       string `xml:"Body>Fault>Code>Subcode>Value"`
+	Reason     string `xml:"Body>Fault>Reason>Text"`
+	WSManFault struct {
+		Code    string `xml:"Code,attr"`
+		Message string `xml:"Message"`
+	} `xml:"Body>Fault>Detail>WSManFault"`
+}
+
+// Fault is a SOAP fault returned by the WinRM service
+type Fault struct {
+	Code    string
+	WSMCode string
+	Reason  string
+}
+
+func (f *Fault) Error() string {
+	msg := f.Reason
+	if msg == "" {
+		msg = f.Code
+	}
+	if f.WSMCode != "" {
+		return fmt.Sprintf("winrm fault %s: %s", f.WSMCode, msg)
+	}
+	return fmt.Sprintf("winrm fault: %s", msg)
+}
+
+// parseFault extracts a SOAP fault from a response body, if present
+func parseFault(data []byte) *Fault {
+	var f faultResponse
+	if err := xml.Unmarshal(data, &f); err != nil {
+		return nil
+	}
+	if f.Code == "" && f.Reason == "" && f.WSManFault.Code == "" {
+		return nil
+	}
+	reason := strings.TrimSpace(f.Reason)
+	if reason == "" {
+		reason = strings.TrimSpace(f.WSManFault.Message)
+	}
+	return &Fault{Code: f.Code, WSMCode: f.WSManFault.Code, Reason: reason}
+}
+
+// decodeStreams appends the base64 stream contents to stdout and stderr
+func (r *receiveResponse) decodeStreams(stdout, stderr *bytes.Buffer) error {
+	for _, s := range r.Streams {
+		data := strings.TrimSpace(s.Data)
+		if data == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s stream: %w", s.Name, err)
+		}
+		switch s.Name {
+		case "stdout":
+			stdout.Write(decoded)
+		case "stderr":
+			stderr.Write(decoded)
+		}
+	}
+	return nil
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
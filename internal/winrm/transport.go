@@ -0,0 +1,123 @@
+package winrm
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const soapContentType = "application/soap+xml;charset=UTF-8"
+
+// transport posts SOAP envelopes to the WinRM endpoint and returns the
+// HTTP status code with the response body
+type transport interface {
+	post(ctx context.Context, url string, body []byte) (int, []byte, error)
+}
+
+// basicTransport authenticates with HTTP Basic auth. WinRM only accepts
+// it for local accounts and with AllowUnencrypted or over HTTPS.
+type basicTransport struct {
+	client   *http.Client
+	user     string
+	password string
+}
+
+func newBasicTransport(cfg Config) *basicTransport {
+	return &basicTransport{
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.Insecure},
+			},
+		},
+		user:     cfg.User,
+		password: cfg.Password,
+	}
+}
+
+func (t *basicTransport) post(ctx context.Context, url string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", soapContentType)
+	req.SetBasicAuth(t.user, t.password)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// execCommand is replaced in tests
+var execCommand = exec.CommandContext
+
+// negotiateTransport authenticates with SPNEGO/Kerberos using the ticket
+// cache. It delegates to curl, which uses the system GSSAPI library, so
+// no password ever has to be handed to sloth-runner.
+type negotiateTransport struct {
+	ccache   string
+	insecure bool
+	timeout  time.Duration
+}
+
+func (t *negotiateTransport) post(ctx context.Context, url string, body []byte) (int, []byte, error) {
+	args := []string{
+		"--silent", "--show-error",
+		"--negotiate", "--user", ":",
+		"--request", "POST",
+		"--header", "Content-Type: " + soapContentType,
+		"--data-binary", "@-",
+		"--write-out", "\n%{http_code}",
+	}
+	if t.timeout > 0 {
+		args = append(args, "--max-time", strconv.Itoa(int(t.timeout.Seconds())))
+	}
+	if t.insecure {
+		args = append(args, "--insecure")
+	}
+	args = append(args, url)
+
+	cmd := execCommand(ctx, "curl", args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = os.Environ()
+	if t.ccache != "" {
+		cmd.Env = append(cmd.Env, "KRB5CCNAME="+t.ccache)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if _, lookErr := exec.LookPath("curl"); lookErr != nil {
+			return 0, nil, fmt.Errorf("kerberos authentication for WinRM requires curl with GSSAPI support: %w", lookErr)
+		}
+		return 0, nil, fmt.Errorf("curl failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	idx := bytes.LastIndexByte(out, '\n')
+	if idx < 0 {
+		return 0, nil, fmt.Errorf("unexpected curl output")
+	}
+	status, err := strconv.Atoi(strings.TrimSpace(string(out[idx+1:])))
+	if err != nil {
+		return 0, nil, fmt.Errorf("unexpected curl status %q", out[idx+1:])
+	}
+	return status, out[:idx], nil
+}
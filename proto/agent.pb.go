@@ -93,6 +93,97 @@ func (*ShutdownResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{1}
 }
 
+type RebootRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Refuse new tasks and wait for the running ones before rebooting
+	Drain bool `protobuf:"varint,1,opt,name=drain,proto3" json:"drain,omitempty"`
+	// How long to wait for running tasks; the reboot is refused if some
+	// are still running then
+	DrainTimeoutSeconds int64 `protobuf:"varint,2,opt,name=drain_timeout_seconds,json=drainTimeoutSeconds,proto3" json:"drain_timeout_seconds,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *RebootRequest) Reset() {
+	*x = RebootRequest{}
+	mi := &file_proto_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebootRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebootRequest) ProtoMessage() {}
+
+func (x *RebootRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebootRequest.ProtoReflect.Descriptor instead.
+func (*RebootRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RebootRequest) GetDrain() bool {
+	if x != nil {
+		return x.Drain
+	}
+	return false
+}
+
+func (x *RebootRequest) GetDrainTimeoutSeconds() int64 {
+	if x != nil {
+		return x.DrainTimeoutSeconds
+	}
+	return 0
+}
+
+type RebootResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebootResponse) Reset() {
+	*x = RebootResponse{}
+	mi := &file_proto_agent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebootResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebootResponse) ProtoMessage() {}
+
+func (x *RebootResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebootResponse.ProtoReflect.Descriptor instead.
+func (*RebootResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{3}
+}
+
 type UpdateAgentRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TargetVersion string                 `protobuf:"bytes,1,opt,name=target_version,json=targetVersion,proto3" json:"target_version,omitempty"` // Empty or "latest" for latest version
@@ -104,7 +195,7 @@ type UpdateAgentRequest struct {
 
 func (x *UpdateAgentRequest) Reset() {
 	*x = UpdateAgentRequest{}
-	mi := &file_proto_agent_proto_msgTypes[2]
+	mi := &file_proto_agent_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -116,7 +207,7 @@ func (x *UpdateAgentRequest) String() string {
 func (*UpdateAgentRequest) ProtoMessage() {}
 
 func (x *UpdateAgentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[2]
+	mi := &file_proto_agent_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -129,7 +220,7 @@ func (x *UpdateAgentRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateAgentRequest.ProtoReflect.Descriptor instead.
 func (*UpdateAgentRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{2}
+	return file_proto_agent_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *UpdateAgentRequest) GetTargetVersion() string {
@@ -165,7 +256,7 @@ type UpdateAgentResponse struct {
 
 func (x *UpdateAgentResponse) Reset() {
 	*x = UpdateAgentResponse{}
-	mi := &file_proto_agent_proto_msgTypes[3]
+	mi := &file_proto_agent_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -177,7 +268,7 @@ func (x *UpdateAgentResponse) String() string {
 func (*UpdateAgentResponse) ProtoMessage() {}
 
 func (x *UpdateAgentResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[3]
+	mi := &file_proto_agent_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -190,7 +281,7 @@ func (x *UpdateAgentResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateAgentResponse.ProtoReflect.Descriptor instead.
 func (*UpdateAgentResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{3}
+	return file_proto_agent_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *UpdateAgentResponse) GetSuccess() bool {
@@ -222,19 +313,23 @@ func (x *UpdateAgentResponse) GetNewVersion() string {
 }
 
 type ExecuteTaskRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TaskName      string                 `protobuf:"bytes,1,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"`
-	TaskGroup     string                 `protobuf:"bytes,2,opt,name=task_group,json=taskGroup,proto3" json:"task_group,omitempty"`
-	LuaScript     string                 `protobuf:"bytes,3,opt,name=lua_script,json=luaScript,proto3" json:"lua_script,omitempty"`
-	Workspace     []byte                 `protobuf:"bytes,4,opt,name=workspace,proto3" json:"workspace,omitempty"`
-	User          string                 `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"` // User to run the task as (default: root)
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TaskName       string                 `protobuf:"bytes,1,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"`
+	TaskGroup      string                 `protobuf:"bytes,2,opt,name=task_group,json=taskGroup,proto3" json:"task_group,omitempty"`
+	LuaScript      string                 `protobuf:"bytes,3,opt,name=lua_script,json=luaScript,proto3" json:"lua_script,omitempty"`
+	Workspace      []byte                 `protobuf:"bytes,4,opt,name=workspace,proto3" json:"workspace,omitempty"`
+	User           string                 `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"`                                           // User to run the task as (default: root)
+	Approvals      []string               `protobuf:"bytes,6,rep,name=approvals,proto3" json:"approvals,omitempty"`                                 // Approvals given for the run (run --approve)
+	IdempotencyKey string                 `protobuf:"bytes,7,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"` // Same for retries of a request, so the agent runs it once
+	SudoPassword   []byte                 `protobuf:"bytes,8,opt,name=sudo_password,json=sudoPassword,proto3" json:"sudo_password,omitempty"`       // Sealed to the agent's sudo key (GetSudoKey); the agent's sudo uses it for the run
+	InputsJson     string                 `protobuf:"bytes,9,opt,name=inputs_json,json=inputsJson,proto3" json:"inputs_json,omitempty"`             // Outputs of the task's dependencies by task name, as JSON
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ExecuteTaskRequest) Reset() {
 	*x = ExecuteTaskRequest{}
-	mi := &file_proto_agent_proto_msgTypes[4]
+	mi := &file_proto_agent_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -246,7 +341,7 @@ func (x *ExecuteTaskRequest) String() string {
 func (*ExecuteTaskRequest) ProtoMessage() {}
 
 func (x *ExecuteTaskRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[4]
+	mi := &file_proto_agent_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -259,7 +354,7 @@ func (x *ExecuteTaskRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecuteTaskRequest.ProtoReflect.Descriptor instead.
 func (*ExecuteTaskRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{4}
+	return file_proto_agent_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ExecuteTaskRequest) GetTaskName() string {
@@ -297,18 +392,51 @@ func (x *ExecuteTaskRequest) GetUser() string {
 	return ""
 }
 
+func (x *ExecuteTaskRequest) GetApprovals() []string {
+	if x != nil {
+		return x.Approvals
+	}
+	return nil
+}
+
+func (x *ExecuteTaskRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *ExecuteTaskRequest) GetSudoPassword() []byte {
+	if x != nil {
+		return x.SudoPassword
+	}
+	return nil
+}
+
+func (x *ExecuteTaskRequest) GetInputsJson() string {
+	if x != nil {
+		return x.InputsJson
+	}
+	return ""
+}
+
 type ExecuteTaskResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Output        string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
-	Workspace     []byte                 `protobuf:"bytes,3,opt,name=workspace,proto3" json:"workspace,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Output         string                 `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
+	Workspace      []byte                 `protobuf:"bytes,3,opt,name=workspace,proto3" json:"workspace,omitempty"`
+	Usage          *TaskUsage             `protobuf:"bytes,4,opt,name=usage,proto3" json:"usage,omitempty"`                                           // What the task's processes consumed on the agent
+	OutputsJson    string                 `protobuf:"bytes,5,opt,name=outputs_json,json=outputsJson,proto3" json:"outputs_json,omitempty"`            // Outputs of the task, as JSON
+	ChangesJson    string                 `protobuf:"bytes,6,opt,name=changes_json,json=changesJson,proto3" json:"changes_json,omitempty"`            // Changes the task's module calls made, as JSON
+	SlowCallsJson  string                 `protobuf:"bytes,7,opt,name=slow_calls_json,json=slowCallsJson,proto3" json:"slow_calls_json,omitempty"`    // Module calls that ran past their slow threshold, as JSON
+	SetOutputsJson string                 `protobuf:"bytes,8,opt,name=set_outputs_json,json=setOutputsJson,proto3" json:"set_outputs_json,omitempty"` // Outputs set with task.set_output, true for the sensitive ones, as JSON
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ExecuteTaskResponse) Reset() {
 	*x = ExecuteTaskResponse{}
-	mi := &file_proto_agent_proto_msgTypes[5]
+	mi := &file_proto_agent_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -320,7 +448,7 @@ func (x *ExecuteTaskResponse) String() string {
 func (*ExecuteTaskResponse) ProtoMessage() {}
 
 func (x *ExecuteTaskResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[5]
+	mi := &file_proto_agent_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -333,7 +461,7 @@ func (x *ExecuteTaskResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExecuteTaskResponse.ProtoReflect.Descriptor instead.
 func (*ExecuteTaskResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{5}
+	return file_proto_agent_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ExecuteTaskResponse) GetSuccess() bool {
@@ -357,29 +485,68 @@ func (x *ExecuteTaskResponse) GetWorkspace() []byte {
 	return nil
 }
 
-type RegisterAgentRequest struct {
+func (x *ExecuteTaskResponse) GetUsage() *TaskUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ExecuteTaskResponse) GetOutputsJson() string {
+	if x != nil {
+		return x.OutputsJson
+	}
+	return ""
+}
+
+func (x *ExecuteTaskResponse) GetChangesJson() string {
+	if x != nil {
+		return x.ChangesJson
+	}
+	return ""
+}
+
+func (x *ExecuteTaskResponse) GetSlowCallsJson() string {
+	if x != nil {
+		return x.SlowCallsJson
+	}
+	return ""
+}
+
+func (x *ExecuteTaskResponse) GetSetOutputsJson() string {
+	if x != nil {
+		return x.SetOutputsJson
+	}
+	return ""
+}
+
+// TaskUsage is what the processes a task started consumed, from its cgroup
+// or, without cgroup accounting, from their rusage
+type TaskUsage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	AgentAddress  string                 `protobuf:"bytes,2,opt,name=agent_address,json=agentAddress,proto3" json:"agent_address,omitempty"`
+	CpuTimeMs     int64                  `protobuf:"varint,1,opt,name=cpu_time_ms,json=cpuTimeMs,proto3" json:"cpu_time_ms,omitempty"`
+	PeakRssBytes  uint64                 `protobuf:"varint,2,opt,name=peak_rss_bytes,json=peakRssBytes,proto3" json:"peak_rss_bytes,omitempty"`
+	ReadBytes     uint64                 `protobuf:"varint,3,opt,name=read_bytes,json=readBytes,proto3" json:"read_bytes,omitempty"`
+	WriteBytes    uint64                 `protobuf:"varint,4,opt,name=write_bytes,json=writeBytes,proto3" json:"write_bytes,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterAgentRequest) Reset() {
-	*x = RegisterAgentRequest{}
-	mi := &file_proto_agent_proto_msgTypes[6]
+func (x *TaskUsage) Reset() {
+	*x = TaskUsage{}
+	mi := &file_proto_agent_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterAgentRequest) String() string {
+func (x *TaskUsage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterAgentRequest) ProtoMessage() {}
+func (*TaskUsage) ProtoMessage() {}
 
-func (x *RegisterAgentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[6]
+func (x *TaskUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -390,48 +557,63 @@ func (x *RegisterAgentRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterAgentRequest.ProtoReflect.Descriptor instead.
-func (*RegisterAgentRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use TaskUsage.ProtoReflect.Descriptor instead.
+func (*TaskUsage) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *RegisterAgentRequest) GetAgentName() string {
+func (x *TaskUsage) GetCpuTimeMs() int64 {
 	if x != nil {
-		return x.AgentName
+		return x.CpuTimeMs
 	}
-	return ""
+	return 0
 }
 
-func (x *RegisterAgentRequest) GetAgentAddress() string {
+func (x *TaskUsage) GetPeakRssBytes() uint64 {
 	if x != nil {
-		return x.AgentAddress
+		return x.PeakRssBytes
 	}
-	return ""
+	return 0
 }
 
-type RegisterAgentResponse struct {
+func (x *TaskUsage) GetReadBytes() uint64 {
+	if x != nil {
+		return x.ReadBytes
+	}
+	return 0
+}
+
+func (x *TaskUsage) GetWriteBytes() uint64 {
+	if x != nil {
+		return x.WriteBytes
+	}
+	return 0
+}
+
+type ExecuteTaskEvent struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	OutputChunk   string                 `protobuf:"bytes,1,opt,name=output_chunk,json=outputChunk,proto3" json:"output_chunk,omitempty"`
+	Result        *ExecuteTaskResponse   `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`     // Set on the last event
+	Progress      *TaskProgress          `protobuf:"bytes,3,opt,name=progress,proto3" json:"progress,omitempty"` // Set when the task reports its progress
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterAgentResponse) Reset() {
-	*x = RegisterAgentResponse{}
-	mi := &file_proto_agent_proto_msgTypes[7]
+func (x *ExecuteTaskEvent) Reset() {
+	*x = ExecuteTaskEvent{}
+	mi := &file_proto_agent_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterAgentResponse) String() string {
+func (x *ExecuteTaskEvent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterAgentResponse) ProtoMessage() {}
+func (*ExecuteTaskEvent) ProtoMessage() {}
 
-func (x *RegisterAgentResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[7]
+func (x *ExecuteTaskEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -442,53 +624,56 @@ func (x *RegisterAgentResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterAgentResponse.ProtoReflect.Descriptor instead.
-func (*RegisterAgentResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use ExecuteTaskEvent.ProtoReflect.Descriptor instead.
+func (*ExecuteTaskEvent) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *RegisterAgentResponse) GetSuccess() bool {
+func (x *ExecuteTaskEvent) GetOutputChunk() string {
 	if x != nil {
-		return x.Success
+		return x.OutputChunk
 	}
-	return false
+	return ""
 }
 
-func (x *RegisterAgentResponse) GetMessage() string {
+func (x *ExecuteTaskEvent) GetResult() *ExecuteTaskResponse {
 	if x != nil {
-		return x.Message
+		return x.Result
 	}
-	return ""
+	return nil
 }
 
-type AgentInfo struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	AgentName         string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	AgentAddress      string                 `protobuf:"bytes,2,opt,name=agent_address,json=agentAddress,proto3" json:"agent_address,omitempty"`
-	LastHeartbeat     int64                  `protobuf:"varint,3,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"` // Unix timestamp of the last heartbeat
-	Status            string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
-	LastInfoCollected int64                  `protobuf:"varint,5,opt,name=last_info_collected,json=lastInfoCollected,proto3" json:"last_info_collected,omitempty"` // Unix timestamp of the last system info collection
-	SystemInfoJson    string                 `protobuf:"bytes,6,opt,name=system_info_json,json=systemInfoJson,proto3" json:"system_info_json,omitempty"`           // JSON string with system information
-	Version           string                 `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`                                                 // Agent version
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+func (x *ExecuteTaskEvent) GetProgress() *TaskProgress {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
 }
 
-func (x *AgentInfo) Reset() {
-	*x = AgentInfo{}
-	mi := &file_proto_agent_proto_msgTypes[8]
+// TaskProgress is an update a task reports with progress.update
+type TaskProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Percent       float64                `protobuf:"fixed64,1,opt,name=percent,proto3" json:"percent,omitempty"` // 0-100, or -1 with a message only
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskProgress) Reset() {
+	*x = TaskProgress{}
+	mi := &file_proto_agent_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AgentInfo) String() string {
+func (x *TaskProgress) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AgentInfo) ProtoMessage() {}
+func (*TaskProgress) ProtoMessage() {}
 
-func (x *AgentInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[8]
+func (x *TaskProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -499,81 +684,56 @@ func (x *AgentInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AgentInfo.ProtoReflect.Descriptor instead.
-func (*AgentInfo) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use TaskProgress.ProtoReflect.Descriptor instead.
+func (*TaskProgress) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *AgentInfo) GetAgentName() string {
+func (x *TaskProgress) GetPercent() float64 {
 	if x != nil {
-		return x.AgentName
+		return x.Percent
 	}
-	return ""
+	return 0
 }
 
-func (x *AgentInfo) GetAgentAddress() string {
+func (x *TaskProgress) GetMessage() string {
 	if x != nil {
-		return x.AgentAddress
+		return x.Message
 	}
 	return ""
 }
 
-func (x *AgentInfo) GetLastHeartbeat() int64 {
-	if x != nil {
-		return x.LastHeartbeat
-	}
-	return 0
+type ExecuteTasksRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TaskNames      []string               `protobuf:"bytes,1,rep,name=task_names,json=taskNames,proto3" json:"task_names,omitempty"` // Tasks to run, in order
+	TaskGroup      string                 `protobuf:"bytes,2,opt,name=task_group,json=taskGroup,proto3" json:"task_group,omitempty"`
+	LuaScript      string                 `protobuf:"bytes,3,opt,name=lua_script,json=luaScript,proto3" json:"lua_script,omitempty"`
+	Workspace      []byte                 `protobuf:"bytes,4,opt,name=workspace,proto3" json:"workspace,omitempty"`
+	User           string                 `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"`                                           // User to run the tasks as (default: root)
+	Approvals      []string               `protobuf:"bytes,6,rep,name=approvals,proto3" json:"approvals,omitempty"`                                 // Approvals given for the run (run --approve)
+	Parallel       bool                   `protobuf:"varint,7,opt,name=parallel,proto3" json:"parallel,omitempty"`                                  // Run the tasks concurrently instead of in order
+	IdempotencyKey string                 `protobuf:"bytes,8,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"` // Same for retries of a request, so the agent runs it once
+	SudoPassword   []byte                 `protobuf:"bytes,9,opt,name=sudo_password,json=sudoPassword,proto3" json:"sudo_password,omitempty"`       // Sealed to the agent's sudo key (GetSudoKey); the agent's sudo uses it for the run
+	InputsJson     string                 `protobuf:"bytes,10,opt,name=inputs_json,json=inputsJson,proto3" json:"inputs_json,omitempty"`            // Outputs of tasks that ran elsewhere by task name, as JSON
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *AgentInfo) GetStatus() string {
-	if x != nil {
-		return x.Status
-	}
-	return ""
-}
-
-func (x *AgentInfo) GetLastInfoCollected() int64 {
-	if x != nil {
-		return x.LastInfoCollected
-	}
-	return 0
-}
-
-func (x *AgentInfo) GetSystemInfoJson() string {
-	if x != nil {
-		return x.SystemInfoJson
-	}
-	return ""
-}
-
-func (x *AgentInfo) GetVersion() string {
-	if x != nil {
-		return x.Version
-	}
-	return ""
-}
-
-type ListAgentsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ListAgentsRequest) Reset() {
-	*x = ListAgentsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[9]
+func (x *ExecuteTasksRequest) Reset() {
+	*x = ExecuteTasksRequest{}
+	mi := &file_proto_agent_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListAgentsRequest) String() string {
+func (x *ExecuteTasksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListAgentsRequest) ProtoMessage() {}
+func (*ExecuteTasksRequest) ProtoMessage() {}
 
-func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[9]
+func (x *ExecuteTasksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -584,121 +744,101 @@ func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListAgentsRequest.ProtoReflect.Descriptor instead.
-func (*ListAgentsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{9}
-}
-
-type ListAgentsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Agents        []*AgentInfo           `protobuf:"bytes,1,rep,name=agents,proto3" json:"agents,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ListAgentsResponse) Reset() {
-	*x = ListAgentsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[10]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+// Deprecated: Use ExecuteTasksRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteTasksRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *ListAgentsResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ExecuteTasksRequest) GetTaskNames() []string {
+	if x != nil {
+		return x.TaskNames
+	}
+	return nil
 }
 
-func (*ListAgentsResponse) ProtoMessage() {}
-
-func (x *ListAgentsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[10]
+func (x *ExecuteTasksRequest) GetTaskGroup() string {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.TaskGroup
 	}
-	return mi.MessageOf(x)
+	return ""
 }
 
-// Deprecated: Use ListAgentsResponse.ProtoReflect.Descriptor instead.
-func (*ListAgentsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{10}
+func (x *ExecuteTasksRequest) GetLuaScript() string {
+	if x != nil {
+		return x.LuaScript
+	}
+	return ""
 }
 
-func (x *ListAgentsResponse) GetAgents() []*AgentInfo {
+func (x *ExecuteTasksRequest) GetWorkspace() []byte {
 	if x != nil {
-		return x.Agents
+		return x.Workspace
 	}
 	return nil
 }
 
-type StopAgentRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ExecuteTasksRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
 }
 
-func (x *StopAgentRequest) Reset() {
-	*x = StopAgentRequest{}
-	mi := &file_proto_agent_proto_msgTypes[11]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *ExecuteTasksRequest) GetApprovals() []string {
+	if x != nil {
+		return x.Approvals
+	}
+	return nil
 }
 
-func (x *StopAgentRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ExecuteTasksRequest) GetParallel() bool {
+	if x != nil {
+		return x.Parallel
+	}
+	return false
 }
 
-func (*StopAgentRequest) ProtoMessage() {}
-
-func (x *StopAgentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[11]
+func (x *ExecuteTasksRequest) GetIdempotencyKey() string {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.IdempotencyKey
 	}
-	return mi.MessageOf(x)
+	return ""
 }
 
-// Deprecated: Use StopAgentRequest.ProtoReflect.Descriptor instead.
-func (*StopAgentRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{11}
+func (x *ExecuteTasksRequest) GetSudoPassword() []byte {
+	if x != nil {
+		return x.SudoPassword
+	}
+	return nil
 }
 
-func (x *StopAgentRequest) GetAgentName() string {
+func (x *ExecuteTasksRequest) GetInputsJson() string {
 	if x != nil {
-		return x.AgentName
+		return x.InputsJson
 	}
 	return ""
 }
 
-type StopAgentResponse struct {
+type SudoKeyRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StopAgentResponse) Reset() {
-	*x = StopAgentResponse{}
+func (x *SudoKeyRequest) Reset() {
+	*x = SudoKeyRequest{}
 	mi := &file_proto_agent_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StopAgentResponse) String() string {
+func (x *SudoKeyRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StopAgentResponse) ProtoMessage() {}
+func (*SudoKeyRequest) ProtoMessage() {}
 
-func (x *StopAgentResponse) ProtoReflect() protoreflect.Message {
+func (x *SudoKeyRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -710,46 +850,32 @@ func (x *StopAgentResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StopAgentResponse.ProtoReflect.Descriptor instead.
-func (*StopAgentResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use SudoKeyRequest.ProtoReflect.Descriptor instead.
+func (*SudoKeyRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *StopAgentResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *StopAgentResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
-}
-
-type UnregisterAgentRequest struct {
+type SudoKeyResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	PublicKey     []byte                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"` // NaCl box public key
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UnregisterAgentRequest) Reset() {
-	*x = UnregisterAgentRequest{}
+func (x *SudoKeyResponse) Reset() {
+	*x = SudoKeyResponse{}
 	mi := &file_proto_agent_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UnregisterAgentRequest) String() string {
+func (x *SudoKeyResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UnregisterAgentRequest) ProtoMessage() {}
+func (*SudoKeyResponse) ProtoMessage() {}
 
-func (x *UnregisterAgentRequest) ProtoReflect() protoreflect.Message {
+func (x *SudoKeyResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -761,40 +887,45 @@ func (x *UnregisterAgentRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UnregisterAgentRequest.ProtoReflect.Descriptor instead.
-func (*UnregisterAgentRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SudoKeyResponse.ProtoReflect.Descriptor instead.
+func (*SudoKeyResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *UnregisterAgentRequest) GetAgentName() string {
+func (x *SudoKeyResponse) GetPublicKey() []byte {
 	if x != nil {
-		return x.AgentName
+		return x.PublicKey
 	}
-	return ""
+	return nil
 }
 
-type UnregisterAgentResponse struct {
+type TaskRunResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	TaskName      string                 `protobuf:"bytes,1,opt,name=task_name,json=taskName,proto3" json:"task_name,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // Success, Failed or Skipped
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Usage         *TaskUsage             `protobuf:"bytes,5,opt,name=usage,proto3" json:"usage,omitempty"`
+	ChangesJson   string                 `protobuf:"bytes,6,opt,name=changes_json,json=changesJson,proto3" json:"changes_json,omitempty"`         // Changes the task's module calls made, as JSON
+	SlowCallsJson string                 `protobuf:"bytes,7,opt,name=slow_calls_json,json=slowCallsJson,proto3" json:"slow_calls_json,omitempty"` // Module calls that ran past their slow threshold, as JSON
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UnregisterAgentResponse) Reset() {
-	*x = UnregisterAgentResponse{}
+func (x *TaskRunResult) Reset() {
+	*x = TaskRunResult{}
 	mi := &file_proto_agent_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UnregisterAgentResponse) String() string {
+func (x *TaskRunResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UnregisterAgentResponse) ProtoMessage() {}
+func (*TaskRunResult) ProtoMessage() {}
 
-func (x *UnregisterAgentResponse) ProtoReflect() protoreflect.Message {
+func (x *TaskRunResult) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -806,47 +937,82 @@ func (x *UnregisterAgentResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UnregisterAgentResponse.ProtoReflect.Descriptor instead.
-func (*UnregisterAgentResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use TaskRunResult.ProtoReflect.Descriptor instead.
+func (*TaskRunResult) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *UnregisterAgentResponse) GetSuccess() bool {
+func (x *TaskRunResult) GetTaskName() string {
 	if x != nil {
-		return x.Success
+		return x.TaskName
 	}
-	return false
+	return ""
 }
 
-func (x *UnregisterAgentResponse) GetMessage() string {
+func (x *TaskRunResult) GetStatus() string {
 	if x != nil {
-		return x.Message
+		return x.Status
 	}
 	return ""
 }
 
-type ExecuteCommandRequest struct {
+func (x *TaskRunResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TaskRunResult) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *TaskRunResult) GetUsage() *TaskUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *TaskRunResult) GetChangesJson() string {
+	if x != nil {
+		return x.ChangesJson
+	}
+	return ""
+}
+
+func (x *TaskRunResult) GetSlowCallsJson() string {
+	if x != nil {
+		return x.SlowCallsJson
+	}
+	return ""
+}
+
+type ExecuteTasksResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Results       []*TaskRunResult       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"` // In the order of task_names
+	Workspace     []byte                 `protobuf:"bytes,2,opt,name=workspace,proto3" json:"workspace,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ExecuteCommandRequest) Reset() {
-	*x = ExecuteCommandRequest{}
+func (x *ExecuteTasksResponse) Reset() {
+	*x = ExecuteTasksResponse{}
 	mi := &file_proto_agent_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ExecuteCommandRequest) String() string {
+func (x *ExecuteTasksResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ExecuteCommandRequest) ProtoMessage() {}
+func (*ExecuteTasksResponse) ProtoMessage() {}
 
-func (x *ExecuteCommandRequest) ProtoReflect() protoreflect.Message {
+func (x *ExecuteTasksResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -858,47 +1024,46 @@ func (x *ExecuteCommandRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ExecuteCommandRequest.ProtoReflect.Descriptor instead.
-func (*ExecuteCommandRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ExecuteTasksResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteTasksResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *ExecuteCommandRequest) GetAgentName() string {
+func (x *ExecuteTasksResponse) GetResults() []*TaskRunResult {
 	if x != nil {
-		return x.AgentName
+		return x.Results
 	}
-	return ""
+	return nil
 }
 
-func (x *ExecuteCommandRequest) GetCommand() string {
+func (x *ExecuteTasksResponse) GetWorkspace() []byte {
 	if x != nil {
-		return x.Command
+		return x.Workspace
 	}
-	return ""
+	return nil
 }
 
-type RunCommandRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Command       string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
-	User          string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"` // User to run the command as (default: root)
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type TaskOutcomeRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	IdempotencyKey string                 `protobuf:"bytes,1,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *RunCommandRequest) Reset() {
-	*x = RunCommandRequest{}
+func (x *TaskOutcomeRequest) Reset() {
+	*x = TaskOutcomeRequest{}
 	mi := &file_proto_agent_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RunCommandRequest) String() string {
+func (x *TaskOutcomeRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RunCommandRequest) ProtoMessage() {}
+func (*TaskOutcomeRequest) ProtoMessage() {}
 
-func (x *RunCommandRequest) ProtoReflect() protoreflect.Message {
+func (x *TaskOutcomeRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -910,51 +1075,46 @@ func (x *RunCommandRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RunCommandRequest.ProtoReflect.Descriptor instead.
-func (*RunCommandRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use TaskOutcomeRequest.ProtoReflect.Descriptor instead.
+func (*TaskOutcomeRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *RunCommandRequest) GetCommand() string {
+func (x *TaskOutcomeRequest) GetIdempotencyKey() string {
 	if x != nil {
-		return x.Command
+		return x.IdempotencyKey
 	}
 	return ""
 }
 
-func (x *RunCommandRequest) GetUser() string {
-	if x != nil {
-		return x.User
-	}
-	return ""
+type TaskOutcomeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// unknown when the agent has no record of the request: it never got
+	// it, the task was aborted with its runner or the outcome expired
+	State          string           `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"` // unknown, running or finished
+	Success        bool             `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Error          string           `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	OutputsJson    string           `protobuf:"bytes,4,opt,name=outputs_json,json=outputsJson,proto3" json:"outputs_json,omitempty"`            // Outputs of the task, as JSON
+	Results        []*TaskRunResult `protobuf:"bytes,5,rep,name=results,proto3" json:"results,omitempty"`                                       // Of each task, for ExecuteTasks
+	SetOutputsJson string           `protobuf:"bytes,6,opt,name=set_outputs_json,json=setOutputsJson,proto3" json:"set_outputs_json,omitempty"` // Outputs set with task.set_output, true for the sensitive ones, as JSON
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-// New message for streaming output
-type StreamOutputResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	StdoutChunk   string                 `protobuf:"bytes,1,opt,name=stdout_chunk,json=stdoutChunk,proto3" json:"stdout_chunk,omitempty"`
-	StderrChunk   string                 `protobuf:"bytes,2,opt,name=stderr_chunk,json=stderrChunk,proto3" json:"stderr_chunk,omitempty"`
-	Finished      bool                   `protobuf:"varint,3,opt,name=finished,proto3" json:"finished,omitempty"`
-	ExitCode      int32                  `protobuf:"varint,4,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
-	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *StreamOutputResponse) Reset() {
-	*x = StreamOutputResponse{}
+func (x *TaskOutcomeResponse) Reset() {
+	*x = TaskOutcomeResponse{}
 	mi := &file_proto_agent_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamOutputResponse) String() string {
+func (x *TaskOutcomeResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamOutputResponse) ProtoMessage() {}
+func (*TaskOutcomeResponse) ProtoMessage() {}
 
-func (x *StreamOutputResponse) ProtoReflect() protoreflect.Message {
+func (x *TaskOutcomeResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -966,69 +1126,79 @@ func (x *StreamOutputResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamOutputResponse.ProtoReflect.Descriptor instead.
-func (*StreamOutputResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use TaskOutcomeResponse.ProtoReflect.Descriptor instead.
+func (*TaskOutcomeResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *StreamOutputResponse) GetStdoutChunk() string {
+func (x *TaskOutcomeResponse) GetState() string {
 	if x != nil {
-		return x.StdoutChunk
+		return x.State
 	}
 	return ""
 }
 
-func (x *StreamOutputResponse) GetStderrChunk() string {
+func (x *TaskOutcomeResponse) GetSuccess() bool {
 	if x != nil {
-		return x.StderrChunk
+		return x.Success
+	}
+	return false
+}
+
+func (x *TaskOutcomeResponse) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
 
-func (x *StreamOutputResponse) GetFinished() bool {
+func (x *TaskOutcomeResponse) GetOutputsJson() string {
 	if x != nil {
-		return x.Finished
+		return x.OutputsJson
 	}
-	return false
+	return ""
 }
 
-func (x *StreamOutputResponse) GetExitCode() int32 {
+func (x *TaskOutcomeResponse) GetResults() []*TaskRunResult {
 	if x != nil {
-		return x.ExitCode
+		return x.Results
 	}
-	return 0
+	return nil
 }
 
-func (x *StreamOutputResponse) GetError() string {
+func (x *TaskOutcomeResponse) GetSetOutputsJson() string {
 	if x != nil {
-		return x.Error
+		return x.SetOutputsJson
 	}
 	return ""
 }
 
-type HeartbeatRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	AgentName      string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	SystemInfoJson string                 `protobuf:"bytes,2,opt,name=system_info_json,json=systemInfoJson,proto3" json:"system_info_json,omitempty"` // Optional: agent can send system info with heartbeat
-	Version        string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`                                       // Agent version
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+type RegisterAgentRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	AgentName    string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	AgentAddress string                 `protobuf:"bytes,2,opt,name=agent_address,json=agentAddress,proto3" json:"agent_address,omitempty"`
+	// One-time token from `agent install-command`, checked by the master
+	JoinToken string `protobuf:"bytes,3,opt,name=join_token,json=joinToken,proto3" json:"join_token,omitempty"`
+	// Credential the master issued when the join token was redeemed
+	AgentCredential string `protobuf:"bytes,4,opt,name=agent_credential,json=agentCredential,proto3" json:"agent_credential,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *HeartbeatRequest) Reset() {
-	*x = HeartbeatRequest{}
+func (x *RegisterAgentRequest) Reset() {
+	*x = RegisterAgentRequest{}
 	mi := &file_proto_agent_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HeartbeatRequest) String() string {
+func (x *RegisterAgentRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HeartbeatRequest) ProtoMessage() {}
+func (*RegisterAgentRequest) ProtoMessage() {}
 
-func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+func (x *RegisterAgentRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1040,54 +1210,64 @@ func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
-func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use RegisterAgentRequest.ProtoReflect.Descriptor instead.
+func (*RegisterAgentRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *HeartbeatRequest) GetAgentName() string {
+func (x *RegisterAgentRequest) GetAgentName() string {
 	if x != nil {
 		return x.AgentName
 	}
 	return ""
 }
 
-func (x *HeartbeatRequest) GetSystemInfoJson() string {
+func (x *RegisterAgentRequest) GetAgentAddress() string {
 	if x != nil {
-		return x.SystemInfoJson
+		return x.AgentAddress
 	}
 	return ""
 }
 
-func (x *HeartbeatRequest) GetVersion() string {
+func (x *RegisterAgentRequest) GetJoinToken() string {
 	if x != nil {
-		return x.Version
+		return x.JoinToken
 	}
 	return ""
 }
 
-type HeartbeatResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *RegisterAgentRequest) GetAgentCredential() string {
+	if x != nil {
+		return x.AgentCredential
+	}
+	return ""
 }
 
-func (x *HeartbeatResponse) Reset() {
-	*x = HeartbeatResponse{}
+type RegisterAgentResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Credential to register with from now on, set when a join token was
+	// redeemed
+	AgentCredential string `protobuf:"bytes,3,opt,name=agent_credential,json=agentCredential,proto3" json:"agent_credential,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RegisterAgentResponse) Reset() {
+	*x = RegisterAgentResponse{}
 	mi := &file_proto_agent_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HeartbeatResponse) String() string {
+func (x *RegisterAgentResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HeartbeatResponse) ProtoMessage() {}
+func (*RegisterAgentResponse) ProtoMessage() {}
 
-func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+func (x *RegisterAgentResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1099,46 +1279,60 @@ func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
-func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use RegisterAgentResponse.ProtoReflect.Descriptor instead.
+func (*RegisterAgentResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{19}
 }
 
-func (x *HeartbeatResponse) GetSuccess() bool {
+func (x *RegisterAgentResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *HeartbeatResponse) GetMessage() string {
+func (x *RegisterAgentResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
-type GetAgentInfoRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *RegisterAgentResponse) GetAgentCredential() string {
+	if x != nil {
+		return x.AgentCredential
+	}
+	return ""
 }
 
-func (x *GetAgentInfoRequest) Reset() {
-	*x = GetAgentInfoRequest{}
+type AgentInfo struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	AgentName         string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	AgentAddress      string                 `protobuf:"bytes,2,opt,name=agent_address,json=agentAddress,proto3" json:"agent_address,omitempty"`
+	LastHeartbeat     int64                  `protobuf:"varint,3,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"` // Unix timestamp of the last heartbeat
+	Status            string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	LastInfoCollected int64                  `protobuf:"varint,5,opt,name=last_info_collected,json=lastInfoCollected,proto3" json:"last_info_collected,omitempty"` // Unix timestamp of the last system info collection
+	SystemInfoJson    string                 `protobuf:"bytes,6,opt,name=system_info_json,json=systemInfoJson,proto3" json:"system_info_json,omitempty"`           // JSON string with system information
+	Version           string                 `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`                                                 // Agent version
+	CircuitState      string                 `protobuf:"bytes,8,opt,name=circuit_state,json=circuitState,proto3" json:"circuit_state,omitempty"`                   // Master's circuit breaker for the agent: closed, half-open or open
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *AgentInfo) Reset() {
+	*x = AgentInfo{}
 	mi := &file_proto_agent_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetAgentInfoRequest) String() string {
+func (x *AgentInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetAgentInfoRequest) ProtoMessage() {}
+func (*AgentInfo) ProtoMessage() {}
 
-func (x *GetAgentInfoRequest) ProtoReflect() protoreflect.Message {
+func (x *AgentInfo) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1150,99 +1344,88 @@ func (x *GetAgentInfoRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetAgentInfoRequest.ProtoReflect.Descriptor instead.
-func (*GetAgentInfoRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use AgentInfo.ProtoReflect.Descriptor instead.
+func (*AgentInfo) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *GetAgentInfoRequest) GetAgentName() string {
+func (x *AgentInfo) GetAgentName() string {
 	if x != nil {
 		return x.AgentName
 	}
 	return ""
 }
 
-type GetAgentInfoResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	AgentInfo     *AgentInfo             `protobuf:"bytes,3,opt,name=agent_info,json=agentInfo,proto3" json:"agent_info,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *GetAgentInfoResponse) Reset() {
-	*x = GetAgentInfoResponse{}
-	mi := &file_proto_agent_proto_msgTypes[21]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *AgentInfo) GetAgentAddress() string {
+	if x != nil {
+		return x.AgentAddress
+	}
+	return ""
 }
 
-func (x *GetAgentInfoResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *AgentInfo) GetLastHeartbeat() int64 {
+	if x != nil {
+		return x.LastHeartbeat
+	}
+	return 0
 }
 
-func (*GetAgentInfoResponse) ProtoMessage() {}
-
-func (x *GetAgentInfoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[21]
+func (x *AgentInfo) GetStatus() string {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Status
 	}
-	return mi.MessageOf(x)
+	return ""
 }
 
-// Deprecated: Use GetAgentInfoResponse.ProtoReflect.Descriptor instead.
-func (*GetAgentInfoResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{21}
+func (x *AgentInfo) GetLastInfoCollected() int64 {
+	if x != nil {
+		return x.LastInfoCollected
+	}
+	return 0
 }
 
-func (x *GetAgentInfoResponse) GetSuccess() bool {
+func (x *AgentInfo) GetSystemInfoJson() string {
 	if x != nil {
-		return x.Success
+		return x.SystemInfoJson
 	}
-	return false
+	return ""
 }
 
-func (x *GetAgentInfoResponse) GetMessage() string {
+func (x *AgentInfo) GetVersion() string {
 	if x != nil {
-		return x.Message
+		return x.Version
 	}
 	return ""
 }
 
-func (x *GetAgentInfoResponse) GetAgentInfo() *AgentInfo {
+func (x *AgentInfo) GetCircuitState() string {
 	if x != nil {
-		return x.AgentInfo
+		return x.CircuitState
 	}
-	return nil
+	return ""
 }
 
-type ResourceUsageRequest struct {
+type ListAgentsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ResourceUsageRequest) Reset() {
-	*x = ResourceUsageRequest{}
-	mi := &file_proto_agent_proto_msgTypes[22]
+func (x *ListAgentsRequest) Reset() {
+	*x = ListAgentsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ResourceUsageRequest) String() string {
+func (x *ListAgentsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceUsageRequest) ProtoMessage() {}
+func (*ListAgentsRequest) ProtoMessage() {}
 
-func (x *ResourceUsageRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[22]
+func (x *ListAgentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1253,46 +1436,33 @@ func (x *ResourceUsageRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceUsageRequest.ProtoReflect.Descriptor instead.
-func (*ResourceUsageRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{22}
+// Deprecated: Use ListAgentsRequest.ProtoReflect.Descriptor instead.
+func (*ListAgentsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{21}
 }
 
-type ResourceUsageResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	CpuPercent       float64                `protobuf:"fixed64,1,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
-	MemoryPercent    float64                `protobuf:"fixed64,2,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
-	MemoryUsedBytes  uint64                 `protobuf:"varint,3,opt,name=memory_used_bytes,json=memoryUsedBytes,proto3" json:"memory_used_bytes,omitempty"`
-	MemoryTotalBytes uint64                 `protobuf:"varint,4,opt,name=memory_total_bytes,json=memoryTotalBytes,proto3" json:"memory_total_bytes,omitempty"`
-	DiskPercent      float64                `protobuf:"fixed64,5,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`
-	DiskUsedBytes    uint64                 `protobuf:"varint,6,opt,name=disk_used_bytes,json=diskUsedBytes,proto3" json:"disk_used_bytes,omitempty"`
-	DiskTotalBytes   uint64                 `protobuf:"varint,7,opt,name=disk_total_bytes,json=diskTotalBytes,proto3" json:"disk_total_bytes,omitempty"`
-	ProcessCount     uint32                 `protobuf:"varint,8,opt,name=process_count,json=processCount,proto3" json:"process_count,omitempty"`
-	LoadAvg_1Min     float64                `protobuf:"fixed64,9,opt,name=load_avg_1min,json=loadAvg1min,proto3" json:"load_avg_1min,omitempty"`
-	LoadAvg_5Min     float64                `protobuf:"fixed64,10,opt,name=load_avg_5min,json=loadAvg5min,proto3" json:"load_avg_5min,omitempty"`
-	LoadAvg_15Min    float64                `protobuf:"fixed64,11,opt,name=load_avg_15min,json=loadAvg15min,proto3" json:"load_avg_15min,omitempty"`
-	UptimeSeconds    uint64                 `protobuf:"varint,12,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
-	NetworkRxBytes   uint64                 `protobuf:"varint,13,opt,name=network_rx_bytes,json=networkRxBytes,proto3" json:"network_rx_bytes,omitempty"`
-	NetworkTxBytes   uint64                 `protobuf:"varint,14,opt,name=network_tx_bytes,json=networkTxBytes,proto3" json:"network_tx_bytes,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+type ListAgentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Agents        []*AgentInfo           `protobuf:"bytes,1,rep,name=agents,proto3" json:"agents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ResourceUsageResponse) Reset() {
-	*x = ResourceUsageResponse{}
-	mi := &file_proto_agent_proto_msgTypes[23]
+func (x *ListAgentsResponse) Reset() {
+	*x = ListAgentsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ResourceUsageResponse) String() string {
+func (x *ListAgentsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceUsageResponse) ProtoMessage() {}
+func (*ListAgentsResponse) ProtoMessage() {}
 
-func (x *ResourceUsageResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[23]
+func (x *ListAgentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1303,131 +1473,84 @@ func (x *ResourceUsageResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceUsageResponse.ProtoReflect.Descriptor instead.
-func (*ResourceUsageResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{23}
-}
-
-func (x *ResourceUsageResponse) GetCpuPercent() float64 {
-	if x != nil {
-		return x.CpuPercent
-	}
-	return 0
-}
-
-func (x *ResourceUsageResponse) GetMemoryPercent() float64 {
-	if x != nil {
-		return x.MemoryPercent
-	}
-	return 0
-}
-
-func (x *ResourceUsageResponse) GetMemoryUsedBytes() uint64 {
-	if x != nil {
-		return x.MemoryUsedBytes
-	}
-	return 0
-}
-
-func (x *ResourceUsageResponse) GetMemoryTotalBytes() uint64 {
-	if x != nil {
-		return x.MemoryTotalBytes
-	}
-	return 0
-}
-
-func (x *ResourceUsageResponse) GetDiskPercent() float64 {
-	if x != nil {
-		return x.DiskPercent
-	}
-	return 0
-}
-
-func (x *ResourceUsageResponse) GetDiskUsedBytes() uint64 {
-	if x != nil {
-		return x.DiskUsedBytes
-	}
-	return 0
+// Deprecated: Use ListAgentsResponse.ProtoReflect.Descriptor instead.
+func (*ListAgentsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{22}
 }
 
-func (x *ResourceUsageResponse) GetDiskTotalBytes() uint64 {
+func (x *ListAgentsResponse) GetAgents() []*AgentInfo {
 	if x != nil {
-		return x.DiskTotalBytes
+		return x.Agents
 	}
-	return 0
+	return nil
 }
 
-func (x *ResourceUsageResponse) GetProcessCount() uint32 {
-	if x != nil {
-		return x.ProcessCount
-	}
-	return 0
+type StopAgentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ResourceUsageResponse) GetLoadAvg_1Min() float64 {
-	if x != nil {
-		return x.LoadAvg_1Min
-	}
-	return 0
+func (x *StopAgentRequest) Reset() {
+	*x = StopAgentRequest{}
+	mi := &file_proto_agent_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *ResourceUsageResponse) GetLoadAvg_5Min() float64 {
-	if x != nil {
-		return x.LoadAvg_5Min
-	}
-	return 0
+func (x *StopAgentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *ResourceUsageResponse) GetLoadAvg_15Min() float64 {
-	if x != nil {
-		return x.LoadAvg_15Min
-	}
-	return 0
-}
+func (*StopAgentRequest) ProtoMessage() {}
 
-func (x *ResourceUsageResponse) GetUptimeSeconds() uint64 {
+func (x *StopAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[23]
 	if x != nil {
-		return x.UptimeSeconds
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *ResourceUsageResponse) GetNetworkRxBytes() uint64 {
-	if x != nil {
-		return x.NetworkRxBytes
-	}
-	return 0
+// Deprecated: Use StopAgentRequest.ProtoReflect.Descriptor instead.
+func (*StopAgentRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{23}
 }
 
-func (x *ResourceUsageResponse) GetNetworkTxBytes() uint64 {
+func (x *StopAgentRequest) GetAgentName() string {
 	if x != nil {
-		return x.NetworkTxBytes
+		return x.AgentName
 	}
-	return 0
+	return ""
 }
 
-type ProcessListRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	IncludeChildren bool                   `protobuf:"varint,1,opt,name=include_children,json=includeChildren,proto3" json:"include_children,omitempty"`
-	FilterName      string                 `protobuf:"bytes,2,opt,name=filter_name,json=filterName,proto3" json:"filter_name,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type StopAgentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProcessListRequest) Reset() {
-	*x = ProcessListRequest{}
+func (x *StopAgentResponse) Reset() {
+	*x = StopAgentResponse{}
 	mi := &file_proto_agent_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProcessListRequest) String() string {
+func (x *StopAgentResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProcessListRequest) ProtoMessage() {}
+func (*StopAgentResponse) ProtoMessage() {}
 
-func (x *ProcessListRequest) ProtoReflect() protoreflect.Message {
+func (x *StopAgentResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1439,54 +1562,46 @@ func (x *ProcessListRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProcessListRequest.ProtoReflect.Descriptor instead.
-func (*ProcessListRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use StopAgentResponse.ProtoReflect.Descriptor instead.
+func (*StopAgentResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{24}
 }
 
-func (x *ProcessListRequest) GetIncludeChildren() bool {
+func (x *StopAgentResponse) GetSuccess() bool {
 	if x != nil {
-		return x.IncludeChildren
+		return x.Success
 	}
 	return false
 }
 
-func (x *ProcessListRequest) GetFilterName() string {
+func (x *StopAgentResponse) GetMessage() string {
 	if x != nil {
-		return x.FilterName
+		return x.Message
 	}
 	return ""
 }
 
-type ProcessInfo struct {
+type UnregisterAgentRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
-	CpuPercent    float64                `protobuf:"fixed64,4,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
-	MemoryPercent float64                `protobuf:"fixed64,5,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
-	MemoryBytes   uint64                 `protobuf:"varint,6,opt,name=memory_bytes,json=memoryBytes,proto3" json:"memory_bytes,omitempty"`
-	User          string                 `protobuf:"bytes,7,opt,name=user,proto3" json:"user,omitempty"`
-	Command       string                 `protobuf:"bytes,8,opt,name=command,proto3" json:"command,omitempty"`
-	StartedAt     int64                  `protobuf:"varint,9,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProcessInfo) Reset() {
-	*x = ProcessInfo{}
+func (x *UnregisterAgentRequest) Reset() {
+	*x = UnregisterAgentRequest{}
 	mi := &file_proto_agent_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProcessInfo) String() string {
+func (x *UnregisterAgentRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProcessInfo) ProtoMessage() {}
+func (*UnregisterAgentRequest) ProtoMessage() {}
 
-func (x *ProcessInfo) ProtoReflect() protoreflect.Message {
+func (x *UnregisterAgentRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1498,96 +1613,40 @@ func (x *ProcessInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProcessInfo.ProtoReflect.Descriptor instead.
-func (*ProcessInfo) Descriptor() ([]byte, []int) {
+// Deprecated: Use UnregisterAgentRequest.ProtoReflect.Descriptor instead.
+func (*UnregisterAgentRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{25}
 }
 
-func (x *ProcessInfo) GetPid() int32 {
-	if x != nil {
-		return x.Pid
-	}
-	return 0
-}
-
-func (x *ProcessInfo) GetName() string {
-	if x != nil {
-		return x.Name
-	}
-	return ""
-}
-
-func (x *ProcessInfo) GetStatus() string {
-	if x != nil {
-		return x.Status
-	}
-	return ""
-}
-
-func (x *ProcessInfo) GetCpuPercent() float64 {
-	if x != nil {
-		return x.CpuPercent
-	}
-	return 0
-}
-
-func (x *ProcessInfo) GetMemoryPercent() float64 {
-	if x != nil {
-		return x.MemoryPercent
-	}
-	return 0
-}
-
-func (x *ProcessInfo) GetMemoryBytes() uint64 {
-	if x != nil {
-		return x.MemoryBytes
-	}
-	return 0
-}
-
-func (x *ProcessInfo) GetUser() string {
-	if x != nil {
-		return x.User
-	}
-	return ""
-}
-
-func (x *ProcessInfo) GetCommand() string {
+func (x *UnregisterAgentRequest) GetAgentName() string {
 	if x != nil {
-		return x.Command
+		return x.AgentName
 	}
 	return ""
 }
 
-func (x *ProcessInfo) GetStartedAt() int64 {
-	if x != nil {
-		return x.StartedAt
-	}
-	return 0
-}
-
-type ProcessListResponse struct {
+type UnregisterAgentResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Processes     []*ProcessInfo         `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`
-	TotalCount    uint32                 `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProcessListResponse) Reset() {
-	*x = ProcessListResponse{}
+func (x *UnregisterAgentResponse) Reset() {
+	*x = UnregisterAgentResponse{}
 	mi := &file_proto_agent_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProcessListResponse) String() string {
+func (x *UnregisterAgentResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProcessListResponse) ProtoMessage() {}
+func (*UnregisterAgentResponse) ProtoMessage() {}
 
-func (x *ProcessListResponse) ProtoReflect() protoreflect.Message {
+func (x *UnregisterAgentResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1599,45 +1658,47 @@ func (x *ProcessListResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProcessListResponse.ProtoReflect.Descriptor instead.
-func (*ProcessListResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use UnregisterAgentResponse.ProtoReflect.Descriptor instead.
+func (*UnregisterAgentResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{26}
 }
 
-func (x *ProcessListResponse) GetProcesses() []*ProcessInfo {
+func (x *UnregisterAgentResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Processes
+		return x.Success
 	}
-	return nil
+	return false
 }
 
-func (x *ProcessListResponse) GetTotalCount() uint32 {
+func (x *UnregisterAgentResponse) GetMessage() string {
 	if x != nil {
-		return x.TotalCount
+		return x.Message
 	}
-	return 0
+	return ""
 }
 
-type NetworkInfoRequest struct {
+type ExecuteCommandRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *NetworkInfoRequest) Reset() {
-	*x = NetworkInfoRequest{}
+func (x *ExecuteCommandRequest) Reset() {
+	*x = ExecuteCommandRequest{}
 	mi := &file_proto_agent_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NetworkInfoRequest) String() string {
+func (x *ExecuteCommandRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NetworkInfoRequest) ProtoMessage() {}
+func (*ExecuteCommandRequest) ProtoMessage() {}
 
-func (x *NetworkInfoRequest) ProtoReflect() protoreflect.Message {
+func (x *ExecuteCommandRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1649,39 +1710,47 @@ func (x *NetworkInfoRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NetworkInfoRequest.ProtoReflect.Descriptor instead.
-func (*NetworkInfoRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ExecuteCommandRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteCommandRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{27}
 }
 
-type NetworkInterface struct {
+func (x *ExecuteCommandRequest) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *ExecuteCommandRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+type RunCommandRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	IpAddresses   []string               `protobuf:"bytes,2,rep,name=ip_addresses,json=ipAddresses,proto3" json:"ip_addresses,omitempty"`
-	MacAddress    string                 `protobuf:"bytes,3,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
-	BytesSent     uint64                 `protobuf:"varint,4,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
-	BytesRecv     uint64                 `protobuf:"varint,5,opt,name=bytes_recv,json=bytesRecv,proto3" json:"bytes_recv,omitempty"`
-	PacketsSent   uint32                 `protobuf:"varint,6,opt,name=packets_sent,json=packetsSent,proto3" json:"packets_sent,omitempty"`
-	PacketsRecv   uint32                 `protobuf:"varint,7,opt,name=packets_recv,json=packetsRecv,proto3" json:"packets_recv,omitempty"`
-	IsUp          bool                   `protobuf:"varint,8,opt,name=is_up,json=isUp,proto3" json:"is_up,omitempty"`
+	Command       string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	User          string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"` // User to run the command as (default: root)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *NetworkInterface) Reset() {
-	*x = NetworkInterface{}
+func (x *RunCommandRequest) Reset() {
+	*x = RunCommandRequest{}
 	mi := &file_proto_agent_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NetworkInterface) String() string {
+func (x *RunCommandRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NetworkInterface) ProtoMessage() {}
+func (*RunCommandRequest) ProtoMessage() {}
 
-func (x *NetworkInterface) ProtoReflect() protoreflect.Message {
+func (x *RunCommandRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1693,89 +1762,51 @@ func (x *NetworkInterface) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NetworkInterface.ProtoReflect.Descriptor instead.
-func (*NetworkInterface) Descriptor() ([]byte, []int) {
+// Deprecated: Use RunCommandRequest.ProtoReflect.Descriptor instead.
+func (*RunCommandRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{28}
 }
 
-func (x *NetworkInterface) GetName() string {
+func (x *RunCommandRequest) GetCommand() string {
 	if x != nil {
-		return x.Name
+		return x.Command
 	}
 	return ""
 }
 
-func (x *NetworkInterface) GetIpAddresses() []string {
-	if x != nil {
-		return x.IpAddresses
-	}
-	return nil
-}
-
-func (x *NetworkInterface) GetMacAddress() string {
+func (x *RunCommandRequest) GetUser() string {
 	if x != nil {
-		return x.MacAddress
+		return x.User
 	}
 	return ""
 }
 
-func (x *NetworkInterface) GetBytesSent() uint64 {
-	if x != nil {
-		return x.BytesSent
-	}
-	return 0
-}
-
-func (x *NetworkInterface) GetBytesRecv() uint64 {
-	if x != nil {
-		return x.BytesRecv
-	}
-	return 0
-}
-
-func (x *NetworkInterface) GetPacketsSent() uint32 {
-	if x != nil {
-		return x.PacketsSent
-	}
-	return 0
-}
-
-func (x *NetworkInterface) GetPacketsRecv() uint32 {
-	if x != nil {
-		return x.PacketsRecv
-	}
-	return 0
-}
-
-func (x *NetworkInterface) GetIsUp() bool {
-	if x != nil {
-		return x.IsUp
-	}
-	return false
-}
-
-type NetworkInfoResponse struct {
+// New message for streaming output
+type StreamOutputResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Interfaces    []*NetworkInterface    `protobuf:"bytes,1,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
-	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	StdoutChunk   string                 `protobuf:"bytes,1,opt,name=stdout_chunk,json=stdoutChunk,proto3" json:"stdout_chunk,omitempty"`
+	StderrChunk   string                 `protobuf:"bytes,2,opt,name=stderr_chunk,json=stderrChunk,proto3" json:"stderr_chunk,omitempty"`
+	Finished      bool                   `protobuf:"varint,3,opt,name=finished,proto3" json:"finished,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,4,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *NetworkInfoResponse) Reset() {
-	*x = NetworkInfoResponse{}
+func (x *StreamOutputResponse) Reset() {
+	*x = StreamOutputResponse{}
 	mi := &file_proto_agent_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NetworkInfoResponse) String() string {
+func (x *StreamOutputResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NetworkInfoResponse) ProtoMessage() {}
+func (*StreamOutputResponse) ProtoMessage() {}
 
-func (x *NetworkInfoResponse) ProtoReflect() protoreflect.Message {
+func (x *StreamOutputResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1787,45 +1818,69 @@ func (x *NetworkInfoResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NetworkInfoResponse.ProtoReflect.Descriptor instead.
-func (*NetworkInfoResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use StreamOutputResponse.ProtoReflect.Descriptor instead.
+func (*StreamOutputResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{29}
 }
 
-func (x *NetworkInfoResponse) GetInterfaces() []*NetworkInterface {
+func (x *StreamOutputResponse) GetStdoutChunk() string {
 	if x != nil {
-		return x.Interfaces
+		return x.StdoutChunk
 	}
-	return nil
+	return ""
 }
 
-func (x *NetworkInfoResponse) GetHostname() string {
+func (x *StreamOutputResponse) GetStderrChunk() string {
 	if x != nil {
-		return x.Hostname
+		return x.StderrChunk
 	}
 	return ""
 }
 
-type DiskInfoRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *StreamOutputResponse) GetFinished() bool {
+	if x != nil {
+		return x.Finished
+	}
+	return false
 }
 
-func (x *DiskInfoRequest) Reset() {
-	*x = DiskInfoRequest{}
+func (x *StreamOutputResponse) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *StreamOutputResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type HeartbeatRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AgentName      string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	SystemInfoJson string                 `protobuf:"bytes,2,opt,name=system_info_json,json=systemInfoJson,proto3" json:"system_info_json,omitempty"` // Optional: agent can send system info with heartbeat
+	Version        string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`                                       // Agent version
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
 	mi := &file_proto_agent_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DiskInfoRequest) String() string {
+func (x *HeartbeatRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DiskInfoRequest) ProtoMessage() {}
+func (*HeartbeatRequest) ProtoMessage() {}
 
-func (x *DiskInfoRequest) ProtoReflect() protoreflect.Message {
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1837,38 +1892,54 @@ func (x *DiskInfoRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DiskInfoRequest.ProtoReflect.Descriptor instead.
-func (*DiskInfoRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{30}
 }
 
-type DiskPartition struct {
+func (x *HeartbeatRequest) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetSystemInfoJson() string {
+	if x != nil {
+		return x.SystemInfoJson
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type HeartbeatResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
-	Mountpoint    string                 `protobuf:"bytes,2,opt,name=mountpoint,proto3" json:"mountpoint,omitempty"`
-	Fstype        string                 `protobuf:"bytes,3,opt,name=fstype,proto3" json:"fstype,omitempty"`
-	TotalBytes    uint64                 `protobuf:"varint,4,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
-	UsedBytes     uint64                 `protobuf:"varint,5,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
-	FreeBytes     uint64                 `protobuf:"varint,6,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`
-	Percent       float64                `protobuf:"fixed64,7,opt,name=percent,proto3" json:"percent,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DiskPartition) Reset() {
-	*x = DiskPartition{}
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
 	mi := &file_proto_agent_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DiskPartition) String() string {
+func (x *HeartbeatResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DiskPartition) ProtoMessage() {}
+func (*HeartbeatResponse) ProtoMessage() {}
 
-func (x *DiskPartition) ProtoReflect() protoreflect.Message {
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1880,83 +1951,46 @@ func (x *DiskPartition) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DiskPartition.ProtoReflect.Descriptor instead.
-func (*DiskPartition) Descriptor() ([]byte, []int) {
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{31}
 }
 
-func (x *DiskPartition) GetDevice() string {
+func (x *HeartbeatResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Device
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-func (x *DiskPartition) GetMountpoint() string {
+func (x *HeartbeatResponse) GetMessage() string {
 	if x != nil {
-		return x.Mountpoint
+		return x.Message
 	}
 	return ""
 }
 
-func (x *DiskPartition) GetFstype() string {
-	if x != nil {
-		return x.Fstype
-	}
-	return ""
-}
-
-func (x *DiskPartition) GetTotalBytes() uint64 {
-	if x != nil {
-		return x.TotalBytes
-	}
-	return 0
-}
-
-func (x *DiskPartition) GetUsedBytes() uint64 {
-	if x != nil {
-		return x.UsedBytes
-	}
-	return 0
-}
-
-func (x *DiskPartition) GetFreeBytes() uint64 {
-	if x != nil {
-		return x.FreeBytes
-	}
-	return 0
-}
-
-func (x *DiskPartition) GetPercent() float64 {
-	if x != nil {
-		return x.Percent
-	}
-	return 0
-}
-
-type DiskInfoResponse struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	Partitions        []*DiskPartition       `protobuf:"bytes,1,rep,name=partitions,proto3" json:"partitions,omitempty"`
-	TotalIoReadBytes  uint64                 `protobuf:"varint,2,opt,name=total_io_read_bytes,json=totalIoReadBytes,proto3" json:"total_io_read_bytes,omitempty"`
-	TotalIoWriteBytes uint64                 `protobuf:"varint,3,opt,name=total_io_write_bytes,json=totalIoWriteBytes,proto3" json:"total_io_write_bytes,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+type GetAgentInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DiskInfoResponse) Reset() {
-	*x = DiskInfoResponse{}
+func (x *GetAgentInfoRequest) Reset() {
+	*x = GetAgentInfoRequest{}
 	mi := &file_proto_agent_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DiskInfoResponse) String() string {
+func (x *GetAgentInfoRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DiskInfoResponse) ProtoMessage() {}
+func (*GetAgentInfoRequest) ProtoMessage() {}
 
-func (x *DiskInfoResponse) ProtoReflect() protoreflect.Message {
+func (x *GetAgentInfoRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1968,55 +2002,41 @@ func (x *DiskInfoResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DiskInfoResponse.ProtoReflect.Descriptor instead.
-func (*DiskInfoResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetAgentInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetAgentInfoRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *DiskInfoResponse) GetPartitions() []*DiskPartition {
-	if x != nil {
-		return x.Partitions
-	}
-	return nil
-}
-
-func (x *DiskInfoResponse) GetTotalIoReadBytes() uint64 {
-	if x != nil {
-		return x.TotalIoReadBytes
-	}
-	return 0
-}
-
-func (x *DiskInfoResponse) GetTotalIoWriteBytes() uint64 {
+func (x *GetAgentInfoRequest) GetAgentName() string {
 	if x != nil {
-		return x.TotalIoWriteBytes
+		return x.AgentName
 	}
-	return 0
+	return ""
 }
 
-type StreamLogsRequest struct {
+type GetAgentInfoResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	LogFile       string                 `protobuf:"bytes,1,opt,name=log_file,json=logFile,proto3" json:"log_file,omitempty"`        // Path to log file or service name
-	TailLines     int32                  `protobuf:"varint,2,opt,name=tail_lines,json=tailLines,proto3" json:"tail_lines,omitempty"` // Number of recent lines to stream
-	Follow        bool                   `protobuf:"varint,3,opt,name=follow,proto3" json:"follow,omitempty"`                        // Keep streaming new lines
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	AgentInfo     *AgentInfo             `protobuf:"bytes,3,opt,name=agent_info,json=agentInfo,proto3" json:"agent_info,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StreamLogsRequest) Reset() {
-	*x = StreamLogsRequest{}
+func (x *GetAgentInfoResponse) Reset() {
+	*x = GetAgentInfoResponse{}
 	mi := &file_proto_agent_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamLogsRequest) String() string {
+func (x *GetAgentInfoResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamLogsRequest) ProtoMessage() {}
+func (*GetAgentInfoResponse) ProtoMessage() {}
 
-func (x *StreamLogsRequest) ProtoReflect() protoreflect.Message {
+func (x *GetAgentInfoResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -2028,56 +2048,52 @@ func (x *StreamLogsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamLogsRequest.ProtoReflect.Descriptor instead.
-func (*StreamLogsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetAgentInfoResponse.ProtoReflect.Descriptor instead.
+func (*GetAgentInfoResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{33}
 }
 
-func (x *StreamLogsRequest) GetLogFile() string {
+func (x *GetAgentInfoResponse) GetSuccess() bool {
 	if x != nil {
-		return x.LogFile
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-func (x *StreamLogsRequest) GetTailLines() int32 {
+func (x *GetAgentInfoResponse) GetMessage() string {
 	if x != nil {
-		return x.TailLines
+		return x.Message
 	}
-	return 0
+	return ""
 }
 
-func (x *StreamLogsRequest) GetFollow() bool {
+func (x *GetAgentInfoResponse) GetAgentInfo() *AgentInfo {
 	if x != nil {
-		return x.Follow
+		return x.AgentInfo
 	}
-	return false
+	return nil
 }
 
-type LogEntry struct {
+type ResourceUsageRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
-	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
-	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LogEntry) Reset() {
-	*x = LogEntry{}
+func (x *ResourceUsageRequest) Reset() {
+	*x = ResourceUsageRequest{}
 	mi := &file_proto_agent_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LogEntry) String() string {
+func (x *ResourceUsageRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LogEntry) ProtoMessage() {}
+func (*ResourceUsageRequest) ProtoMessage() {}
 
-func (x *LogEntry) ProtoReflect() protoreflect.Message {
+func (x *ResourceUsageRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -2089,60 +2105,45 @@ func (x *LogEntry) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
-func (*LogEntry) Descriptor() ([]byte, []int) {
+// Deprecated: Use ResourceUsageRequest.ProtoReflect.Descriptor instead.
+func (*ResourceUsageRequest) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{34}
 }
 
-func (x *LogEntry) GetTimestamp() int64 {
-	if x != nil {
-		return x.Timestamp
-	}
-	return 0
-}
-
-func (x *LogEntry) GetLevel() string {
-	if x != nil {
-		return x.Level
-	}
-	return ""
-}
-
-func (x *LogEntry) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
-}
-
-func (x *LogEntry) GetSource() string {
-	if x != nil {
-		return x.Source
-	}
-	return ""
-}
-
-type StreamMetricsRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	IntervalSeconds int32                  `protobuf:"varint,1,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"` // How often to send metrics
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type ResourceUsageResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	CpuPercent       float64                `protobuf:"fixed64,1,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryPercent    float64                `protobuf:"fixed64,2,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	MemoryUsedBytes  uint64                 `protobuf:"varint,3,opt,name=memory_used_bytes,json=memoryUsedBytes,proto3" json:"memory_used_bytes,omitempty"`
+	MemoryTotalBytes uint64                 `protobuf:"varint,4,opt,name=memory_total_bytes,json=memoryTotalBytes,proto3" json:"memory_total_bytes,omitempty"`
+	DiskPercent      float64                `protobuf:"fixed64,5,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`
+	DiskUsedBytes    uint64                 `protobuf:"varint,6,opt,name=disk_used_bytes,json=diskUsedBytes,proto3" json:"disk_used_bytes,omitempty"`
+	DiskTotalBytes   uint64                 `protobuf:"varint,7,opt,name=disk_total_bytes,json=diskTotalBytes,proto3" json:"disk_total_bytes,omitempty"`
+	ProcessCount     uint32                 `protobuf:"varint,8,opt,name=process_count,json=processCount,proto3" json:"process_count,omitempty"`
+	LoadAvg_1Min     float64                `protobuf:"fixed64,9,opt,name=load_avg_1min,json=loadAvg1min,proto3" json:"load_avg_1min,omitempty"`
+	LoadAvg_5Min     float64                `protobuf:"fixed64,10,opt,name=load_avg_5min,json=loadAvg5min,proto3" json:"load_avg_5min,omitempty"`
+	LoadAvg_15Min    float64                `protobuf:"fixed64,11,opt,name=load_avg_15min,json=loadAvg15min,proto3" json:"load_avg_15min,omitempty"`
+	UptimeSeconds    uint64                 `protobuf:"varint,12,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	NetworkRxBytes   uint64                 `protobuf:"varint,13,opt,name=network_rx_bytes,json=networkRxBytes,proto3" json:"network_rx_bytes,omitempty"`
+	NetworkTxBytes   uint64                 `protobuf:"varint,14,opt,name=network_tx_bytes,json=networkTxBytes,proto3" json:"network_tx_bytes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *StreamMetricsRequest) Reset() {
-	*x = StreamMetricsRequest{}
+func (x *ResourceUsageResponse) Reset() {
+	*x = ResourceUsageResponse{}
 	mi := &file_proto_agent_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamMetricsRequest) String() string {
+func (x *ResourceUsageResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamMetricsRequest) ProtoMessage() {}
+func (*ResourceUsageResponse) ProtoMessage() {}
 
-func (x *StreamMetricsRequest) ProtoReflect() protoreflect.Message {
+func (x *ResourceUsageResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_agent_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -2154,169 +2155,132 @@ func (x *StreamMetricsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamMetricsRequest.ProtoReflect.Descriptor instead.
-func (*StreamMetricsRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ResourceUsageResponse.ProtoReflect.Descriptor instead.
+func (*ResourceUsageResponse) Descriptor() ([]byte, []int) {
 	return file_proto_agent_proto_rawDescGZIP(), []int{35}
 }
 
-func (x *StreamMetricsRequest) GetIntervalSeconds() int32 {
+func (x *ResourceUsageResponse) GetCpuPercent() float64 {
 	if x != nil {
-		return x.IntervalSeconds
+		return x.CpuPercent
 	}
 	return 0
 }
 
-type MetricsData struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	CpuPercent    float64                `protobuf:"fixed64,2,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
-	MemoryPercent float64                `protobuf:"fixed64,3,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
-	DiskPercent   float64                `protobuf:"fixed64,4,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`
-	CustomMetrics map[string]float64     `protobuf:"bytes,5,rep,name=custom_metrics,json=customMetrics,proto3" json:"custom_metrics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *MetricsData) Reset() {
-	*x = MetricsData{}
-	mi := &file_proto_agent_proto_msgTypes[36]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *MetricsData) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*MetricsData) ProtoMessage() {}
-
-func (x *MetricsData) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[36]
+func (x *ResourceUsageResponse) GetMemoryPercent() float64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.MemoryPercent
 	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use MetricsData.ProtoReflect.Descriptor instead.
-func (*MetricsData) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{36}
+	return 0
 }
 
-func (x *MetricsData) GetTimestamp() int64 {
+func (x *ResourceUsageResponse) GetMemoryUsedBytes() uint64 {
 	if x != nil {
-		return x.Timestamp
+		return x.MemoryUsedBytes
 	}
 	return 0
 }
 
-func (x *MetricsData) GetCpuPercent() float64 {
+func (x *ResourceUsageResponse) GetMemoryTotalBytes() uint64 {
 	if x != nil {
-		return x.CpuPercent
+		return x.MemoryTotalBytes
 	}
 	return 0
 }
 
-func (x *MetricsData) GetMemoryPercent() float64 {
+func (x *ResourceUsageResponse) GetDiskPercent() float64 {
 	if x != nil {
-		return x.MemoryPercent
+		return x.DiskPercent
 	}
 	return 0
 }
 
-func (x *MetricsData) GetDiskPercent() float64 {
+func (x *ResourceUsageResponse) GetDiskUsedBytes() uint64 {
 	if x != nil {
-		return x.DiskPercent
+		return x.DiskUsedBytes
 	}
 	return 0
 }
 
-func (x *MetricsData) GetCustomMetrics() map[string]float64 {
+func (x *ResourceUsageResponse) GetDiskTotalBytes() uint64 {
 	if x != nil {
-		return x.CustomMetrics
+		return x.DiskTotalBytes
 	}
-	return nil
+	return 0
 }
 
-type RestartServiceRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServiceName   string                 `protobuf:"bytes,1,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
-	Graceful      bool                   `protobuf:"varint,2,opt,name=graceful,proto3" json:"graceful,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ResourceUsageResponse) GetProcessCount() uint32 {
+	if x != nil {
+		return x.ProcessCount
+	}
+	return 0
 }
 
-func (x *RestartServiceRequest) Reset() {
-	*x = RestartServiceRequest{}
-	mi := &file_proto_agent_proto_msgTypes[37]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *ResourceUsageResponse) GetLoadAvg_1Min() float64 {
+	if x != nil {
+		return x.LoadAvg_1Min
+	}
+	return 0
 }
 
-func (x *RestartServiceRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ResourceUsageResponse) GetLoadAvg_5Min() float64 {
+	if x != nil {
+		return x.LoadAvg_5Min
+	}
+	return 0
 }
 
-func (*RestartServiceRequest) ProtoMessage() {}
-
-func (x *RestartServiceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[37]
+func (x *ResourceUsageResponse) GetLoadAvg_15Min() float64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.LoadAvg_15Min
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use RestartServiceRequest.ProtoReflect.Descriptor instead.
-func (*RestartServiceRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{37}
+func (x *ResourceUsageResponse) GetUptimeSeconds() uint64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
 }
 
-func (x *RestartServiceRequest) GetServiceName() string {
+func (x *ResourceUsageResponse) GetNetworkRxBytes() uint64 {
 	if x != nil {
-		return x.ServiceName
+		return x.NetworkRxBytes
 	}
-	return ""
+	return 0
 }
 
-func (x *RestartServiceRequest) GetGraceful() bool {
+func (x *ResourceUsageResponse) GetNetworkTxBytes() uint64 {
 	if x != nil {
-		return x.Graceful
+		return x.NetworkTxBytes
 	}
-	return false
+	return 0
 }
 
-type RestartServiceResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type ProcessListRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IncludeChildren bool                   `protobuf:"varint,1,opt,name=include_children,json=includeChildren,proto3" json:"include_children,omitempty"`
+	FilterName      string                 `protobuf:"bytes,2,opt,name=filter_name,json=filterName,proto3" json:"filter_name,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *RestartServiceResponse) Reset() {
-	*x = RestartServiceResponse{}
-	mi := &file_proto_agent_proto_msgTypes[38]
+func (x *ProcessListRequest) Reset() {
+	*x = ProcessListRequest{}
+	mi := &file_proto_agent_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RestartServiceResponse) String() string {
+func (x *ProcessListRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RestartServiceResponse) ProtoMessage() {}
+func (*ProcessListRequest) ProtoMessage() {}
 
-func (x *RestartServiceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[38]
+func (x *ProcessListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2327,47 +2291,55 @@ func (x *RestartServiceResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RestartServiceResponse.ProtoReflect.Descriptor instead.
-func (*RestartServiceResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{38}
+// Deprecated: Use ProcessListRequest.ProtoReflect.Descriptor instead.
+func (*ProcessListRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{36}
 }
 
-func (x *RestartServiceResponse) GetSuccess() bool {
+func (x *ProcessListRequest) GetIncludeChildren() bool {
 	if x != nil {
-		return x.Success
+		return x.IncludeChildren
 	}
 	return false
 }
 
-func (x *RestartServiceResponse) GetMessage() string {
+func (x *ProcessListRequest) GetFilterName() string {
 	if x != nil {
-		return x.Message
+		return x.FilterName
 	}
 	return ""
 }
 
-type EnvVarsRequest struct {
+type ProcessInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	VarNames      []string               `protobuf:"bytes,1,rep,name=var_names,json=varNames,proto3" json:"var_names,omitempty"` // Empty for all vars
+	Pid           int32                  `protobuf:"varint,1,opt,name=pid,proto3" json:"pid,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	CpuPercent    float64                `protobuf:"fixed64,4,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryPercent float64                `protobuf:"fixed64,5,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	MemoryBytes   uint64                 `protobuf:"varint,6,opt,name=memory_bytes,json=memoryBytes,proto3" json:"memory_bytes,omitempty"`
+	User          string                 `protobuf:"bytes,7,opt,name=user,proto3" json:"user,omitempty"`
+	Command       string                 `protobuf:"bytes,8,opt,name=command,proto3" json:"command,omitempty"`
+	StartedAt     int64                  `protobuf:"varint,9,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *EnvVarsRequest) Reset() {
-	*x = EnvVarsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[39]
+func (x *ProcessInfo) Reset() {
+	*x = ProcessInfo{}
+	mi := &file_proto_agent_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *EnvVarsRequest) String() string {
+func (x *ProcessInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*EnvVarsRequest) ProtoMessage() {}
+func (*ProcessInfo) ProtoMessage() {}
 
-func (x *EnvVarsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[39]
+func (x *ProcessInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2378,86 +2350,97 @@ func (x *EnvVarsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use EnvVarsRequest.ProtoReflect.Descriptor instead.
-func (*EnvVarsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{39}
+// Deprecated: Use ProcessInfo.ProtoReflect.Descriptor instead.
+func (*ProcessInfo) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{37}
 }
 
-func (x *EnvVarsRequest) GetVarNames() []string {
+func (x *ProcessInfo) GetPid() int32 {
 	if x != nil {
-		return x.VarNames
+		return x.Pid
 	}
-	return nil
+	return 0
 }
 
-type EnvVarsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Variables     map[string]string      `protobuf:"bytes,1,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ProcessInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
 }
 
-func (x *EnvVarsResponse) Reset() {
-	*x = EnvVarsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[40]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *ProcessInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
 }
 
-func (x *EnvVarsResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ProcessInfo) GetCpuPercent() float64 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
 }
 
-func (*EnvVarsResponse) ProtoMessage() {}
+func (x *ProcessInfo) GetMemoryPercent() float64 {
+	if x != nil {
+		return x.MemoryPercent
+	}
+	return 0
+}
 
-func (x *EnvVarsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[40]
+func (x *ProcessInfo) GetMemoryBytes() uint64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.MemoryBytes
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use EnvVarsResponse.ProtoReflect.Descriptor instead.
-func (*EnvVarsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{40}
+func (x *ProcessInfo) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
 }
 
-func (x *EnvVarsResponse) GetVariables() map[string]string {
+func (x *ProcessInfo) GetCommand() string {
 	if x != nil {
-		return x.Variables
+		return x.Command
 	}
-	return nil
+	return ""
 }
 
-type SetEnvVarRequest struct {
+func (x *ProcessInfo) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+type ProcessListResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	Persistent    bool                   `protobuf:"varint,3,opt,name=persistent,proto3" json:"persistent,omitempty"` // Save to profile
+	Processes     []*ProcessInfo         `protobuf:"bytes,1,rep,name=processes,proto3" json:"processes,omitempty"`
+	TotalCount    uint32                 `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetEnvVarRequest) Reset() {
-	*x = SetEnvVarRequest{}
-	mi := &file_proto_agent_proto_msgTypes[41]
+func (x *ProcessListResponse) Reset() {
+	*x = ProcessListResponse{}
+	mi := &file_proto_agent_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetEnvVarRequest) String() string {
+func (x *ProcessListResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetEnvVarRequest) ProtoMessage() {}
+func (*ProcessListResponse) ProtoMessage() {}
 
-func (x *SetEnvVarRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[41]
+func (x *ProcessListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2468,55 +2451,46 @@ func (x *SetEnvVarRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetEnvVarRequest.ProtoReflect.Descriptor instead.
-func (*SetEnvVarRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{41}
+// Deprecated: Use ProcessListResponse.ProtoReflect.Descriptor instead.
+func (*ProcessListResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{38}
 }
 
-func (x *SetEnvVarRequest) GetName() string {
+func (x *ProcessListResponse) GetProcesses() []*ProcessInfo {
 	if x != nil {
-		return x.Name
+		return x.Processes
 	}
-	return ""
+	return nil
 }
 
-func (x *SetEnvVarRequest) GetValue() string {
+func (x *ProcessListResponse) GetTotalCount() uint32 {
 	if x != nil {
-		return x.Value
+		return x.TotalCount
 	}
-	return ""
+	return 0
 }
 
-func (x *SetEnvVarRequest) GetPersistent() bool {
-	if x != nil {
-		return x.Persistent
-	}
-	return false
-}
-
-type SetEnvVarResponse struct {
+type NetworkInfoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetEnvVarResponse) Reset() {
-	*x = SetEnvVarResponse{}
-	mi := &file_proto_agent_proto_msgTypes[42]
+func (x *NetworkInfoRequest) Reset() {
+	*x = NetworkInfoRequest{}
+	mi := &file_proto_agent_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetEnvVarResponse) String() string {
+func (x *NetworkInfoRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetEnvVarResponse) ProtoMessage() {}
+func (*NetworkInfoRequest) ProtoMessage() {}
 
-func (x *SetEnvVarResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[42]
+func (x *NetworkInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2527,48 +2501,40 @@ func (x *SetEnvVarResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetEnvVarResponse.ProtoReflect.Descriptor instead.
-func (*SetEnvVarResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{42}
-}
-
-func (x *SetEnvVarResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *SetEnvVarResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+// Deprecated: Use NetworkInfoRequest.ProtoReflect.Descriptor instead.
+func (*NetworkInfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{39}
 }
 
-type InstallModuleRequest struct {
+type NetworkInterface struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ModuleName    string                 `protobuf:"bytes,1,opt,name=module_name,json=moduleName,proto3" json:"module_name,omitempty"`
-	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"` // Empty for latest
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	IpAddresses   []string               `protobuf:"bytes,2,rep,name=ip_addresses,json=ipAddresses,proto3" json:"ip_addresses,omitempty"`
+	MacAddress    string                 `protobuf:"bytes,3,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"`
+	BytesSent     uint64                 `protobuf:"varint,4,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	BytesRecv     uint64                 `protobuf:"varint,5,opt,name=bytes_recv,json=bytesRecv,proto3" json:"bytes_recv,omitempty"`
+	PacketsSent   uint32                 `protobuf:"varint,6,opt,name=packets_sent,json=packetsSent,proto3" json:"packets_sent,omitempty"`
+	PacketsRecv   uint32                 `protobuf:"varint,7,opt,name=packets_recv,json=packetsRecv,proto3" json:"packets_recv,omitempty"`
+	IsUp          bool                   `protobuf:"varint,8,opt,name=is_up,json=isUp,proto3" json:"is_up,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *InstallModuleRequest) Reset() {
-	*x = InstallModuleRequest{}
-	mi := &file_proto_agent_proto_msgTypes[43]
+func (x *NetworkInterface) Reset() {
+	*x = NetworkInterface{}
+	mi := &file_proto_agent_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InstallModuleRequest) String() string {
+func (x *NetworkInterface) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InstallModuleRequest) ProtoMessage() {}
+func (*NetworkInterface) ProtoMessage() {}
 
-func (x *InstallModuleRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[43]
+func (x *NetworkInterface) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2579,49 +2545,90 @@ func (x *InstallModuleRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InstallModuleRequest.ProtoReflect.Descriptor instead.
-func (*InstallModuleRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{43}
+// Deprecated: Use NetworkInterface.ProtoReflect.Descriptor instead.
+func (*NetworkInterface) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{40}
 }
 
-func (x *InstallModuleRequest) GetModuleName() string {
+func (x *NetworkInterface) GetName() string {
 	if x != nil {
-		return x.ModuleName
+		return x.Name
 	}
 	return ""
 }
 
-func (x *InstallModuleRequest) GetVersion() string {
+func (x *NetworkInterface) GetIpAddresses() []string {
 	if x != nil {
-		return x.Version
+		return x.IpAddresses
+	}
+	return nil
+}
+
+func (x *NetworkInterface) GetMacAddress() string {
+	if x != nil {
+		return x.MacAddress
 	}
 	return ""
 }
 
-type InstallModuleResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	Success          bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message          string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	InstalledVersion string                 `protobuf:"bytes,3,opt,name=installed_version,json=installedVersion,proto3" json:"installed_version,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *NetworkInterface) GetBytesSent() uint64 {
+	if x != nil {
+		return x.BytesSent
+	}
+	return 0
 }
 
-func (x *InstallModuleResponse) Reset() {
-	*x = InstallModuleResponse{}
-	mi := &file_proto_agent_proto_msgTypes[44]
+func (x *NetworkInterface) GetBytesRecv() uint64 {
+	if x != nil {
+		return x.BytesRecv
+	}
+	return 0
+}
+
+func (x *NetworkInterface) GetPacketsSent() uint32 {
+	if x != nil {
+		return x.PacketsSent
+	}
+	return 0
+}
+
+func (x *NetworkInterface) GetPacketsRecv() uint32 {
+	if x != nil {
+		return x.PacketsRecv
+	}
+	return 0
+}
+
+func (x *NetworkInterface) GetIsUp() bool {
+	if x != nil {
+		return x.IsUp
+	}
+	return false
+}
+
+type NetworkInfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Interfaces    []*NetworkInterface    `protobuf:"bytes,1,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NetworkInfoResponse) Reset() {
+	*x = NetworkInfoResponse{}
+	mi := &file_proto_agent_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InstallModuleResponse) String() string {
+func (x *NetworkInfoResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InstallModuleResponse) ProtoMessage() {}
+func (*NetworkInfoResponse) ProtoMessage() {}
 
-func (x *InstallModuleResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[44]
+func (x *NetworkInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2632,53 +2639,46 @@ func (x *InstallModuleResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InstallModuleResponse.ProtoReflect.Descriptor instead.
-func (*InstallModuleResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{44}
-}
-
-func (x *InstallModuleResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+// Deprecated: Use NetworkInfoResponse.ProtoReflect.Descriptor instead.
+func (*NetworkInfoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{41}
 }
 
-func (x *InstallModuleResponse) GetMessage() string {
+func (x *NetworkInfoResponse) GetInterfaces() []*NetworkInterface {
 	if x != nil {
-		return x.Message
+		return x.Interfaces
 	}
-	return ""
+	return nil
 }
 
-func (x *InstallModuleResponse) GetInstalledVersion() string {
+func (x *NetworkInfoResponse) GetHostname() string {
 	if x != nil {
-		return x.InstalledVersion
+		return x.Hostname
 	}
 	return ""
 }
 
-type ModulesRequest struct {
+type DiskInfoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ModulesRequest) Reset() {
-	*x = ModulesRequest{}
-	mi := &file_proto_agent_proto_msgTypes[45]
+func (x *DiskInfoRequest) Reset() {
+	*x = DiskInfoRequest{}
+	mi := &file_proto_agent_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ModulesRequest) String() string {
+func (x *DiskInfoRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ModulesRequest) ProtoMessage() {}
+func (*DiskInfoRequest) ProtoMessage() {}
 
-func (x *ModulesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[45]
+func (x *DiskInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2689,36 +2689,39 @@ func (x *ModulesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ModulesRequest.ProtoReflect.Descriptor instead.
-func (*ModulesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{45}
+// Deprecated: Use DiskInfoRequest.ProtoReflect.Descriptor instead.
+func (*DiskInfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{42}
 }
 
-type ModuleInfo struct {
+type DiskPartition struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
-	Installed     bool                   `protobuf:"varint,3,opt,name=installed,proto3" json:"installed,omitempty"`
-	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Mountpoint    string                 `protobuf:"bytes,2,opt,name=mountpoint,proto3" json:"mountpoint,omitempty"`
+	Fstype        string                 `protobuf:"bytes,3,opt,name=fstype,proto3" json:"fstype,omitempty"`
+	TotalBytes    uint64                 `protobuf:"varint,4,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	UsedBytes     uint64                 `protobuf:"varint,5,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	FreeBytes     uint64                 `protobuf:"varint,6,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`
+	Percent       float64                `protobuf:"fixed64,7,opt,name=percent,proto3" json:"percent,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ModuleInfo) Reset() {
-	*x = ModuleInfo{}
-	mi := &file_proto_agent_proto_msgTypes[46]
+func (x *DiskPartition) Reset() {
+	*x = DiskPartition{}
+	mi := &file_proto_agent_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ModuleInfo) String() string {
+func (x *DiskPartition) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ModuleInfo) ProtoMessage() {}
+func (*DiskPartition) ProtoMessage() {}
 
-func (x *ModuleInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[46]
+func (x *DiskPartition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2729,61 +2732,84 @@ func (x *ModuleInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ModuleInfo.ProtoReflect.Descriptor instead.
-func (*ModuleInfo) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{46}
+// Deprecated: Use DiskPartition.ProtoReflect.Descriptor instead.
+func (*DiskPartition) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{43}
 }
 
-func (x *ModuleInfo) GetName() string {
+func (x *DiskPartition) GetDevice() string {
 	if x != nil {
-		return x.Name
+		return x.Device
 	}
 	return ""
 }
 
-func (x *ModuleInfo) GetVersion() string {
+func (x *DiskPartition) GetMountpoint() string {
 	if x != nil {
-		return x.Version
+		return x.Mountpoint
 	}
 	return ""
 }
 
-func (x *ModuleInfo) GetInstalled() bool {
+func (x *DiskPartition) GetFstype() string {
 	if x != nil {
-		return x.Installed
+		return x.Fstype
 	}
-	return false
+	return ""
 }
 
-func (x *ModuleInfo) GetDescription() string {
+func (x *DiskPartition) GetTotalBytes() uint64 {
 	if x != nil {
-		return x.Description
+		return x.TotalBytes
 	}
-	return ""
+	return 0
 }
 
-type ModulesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Modules       []*ModuleInfo          `protobuf:"bytes,1,rep,name=modules,proto3" json:"modules,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
+func (x *DiskPartition) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
 
-func (x *ModulesResponse) Reset() {
-	*x = ModulesResponse{}
-	mi := &file_proto_agent_proto_msgTypes[47]
+func (x *DiskPartition) GetFreeBytes() uint64 {
+	if x != nil {
+		return x.FreeBytes
+	}
+	return 0
+}
+
+func (x *DiskPartition) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+type DiskInfoResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Partitions        []*DiskPartition       `protobuf:"bytes,1,rep,name=partitions,proto3" json:"partitions,omitempty"`
+	TotalIoReadBytes  uint64                 `protobuf:"varint,2,opt,name=total_io_read_bytes,json=totalIoReadBytes,proto3" json:"total_io_read_bytes,omitempty"`
+	TotalIoWriteBytes uint64                 `protobuf:"varint,3,opt,name=total_io_write_bytes,json=totalIoWriteBytes,proto3" json:"total_io_write_bytes,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *DiskInfoResponse) Reset() {
+	*x = DiskInfoResponse{}
+	mi := &file_proto_agent_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ModulesResponse) String() string {
+func (x *DiskInfoResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ModulesResponse) ProtoMessage() {}
+func (*DiskInfoResponse) ProtoMessage() {}
 
-func (x *ModulesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[47]
+func (x *DiskInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2794,43 +2820,58 @@ func (x *ModulesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ModulesResponse.ProtoReflect.Descriptor instead.
-func (*ModulesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{47}
+// Deprecated: Use DiskInfoResponse.ProtoReflect.Descriptor instead.
+func (*DiskInfoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{44}
 }
 
-func (x *ModulesResponse) GetModules() []*ModuleInfo {
+func (x *DiskInfoResponse) GetPartitions() []*DiskPartition {
 	if x != nil {
-		return x.Modules
+		return x.Partitions
 	}
 	return nil
 }
 
-type CreateGroupRequest struct {
+func (x *DiskInfoResponse) GetTotalIoReadBytes() uint64 {
+	if x != nil {
+		return x.TotalIoReadBytes
+	}
+	return 0
+}
+
+func (x *DiskInfoResponse) GetTotalIoWriteBytes() uint64 {
+	if x != nil {
+		return x.TotalIoWriteBytes
+	}
+	return 0
+}
+
+type StreamLogsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
-	AgentNames    []string               `protobuf:"bytes,3,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
-	Tags          map[string]string      `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	LogFile       string                 `protobuf:"bytes,1,opt,name=log_file,json=logFile,proto3" json:"log_file,omitempty"`             // Path to log file or service name
+	TailLines     int32                  `protobuf:"varint,2,opt,name=tail_lines,json=tailLines,proto3" json:"tail_lines,omitempty"`      // Number of recent lines to stream
+	Follow        bool                   `protobuf:"varint,3,opt,name=follow,proto3" json:"follow,omitempty"`                             // Keep streaming new lines
+	LevelFilter   string                 `protobuf:"bytes,4,opt,name=level_filter,json=levelFilter,proto3" json:"level_filter,omitempty"` // Only entries of this level or more severe
+	Pattern       string                 `protobuf:"bytes,5,opt,name=pattern,proto3" json:"pattern,omitempty"`                            // Only entries whose message matches this regex
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateGroupRequest) Reset() {
-	*x = CreateGroupRequest{}
-	mi := &file_proto_agent_proto_msgTypes[48]
+func (x *StreamLogsRequest) Reset() {
+	*x = StreamLogsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[45]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateGroupRequest) String() string {
+func (x *StreamLogsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateGroupRequest) ProtoMessage() {}
+func (*StreamLogsRequest) ProtoMessage() {}
 
-func (x *CreateGroupRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[48]
+func (x *StreamLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[45]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2841,63 +2882,71 @@ func (x *CreateGroupRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateGroupRequest.ProtoReflect.Descriptor instead.
-func (*CreateGroupRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{48}
+// Deprecated: Use StreamLogsRequest.ProtoReflect.Descriptor instead.
+func (*StreamLogsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{45}
 }
 
-func (x *CreateGroupRequest) GetGroupName() string {
+func (x *StreamLogsRequest) GetLogFile() string {
 	if x != nil {
-		return x.GroupName
+		return x.LogFile
 	}
 	return ""
 }
 
-func (x *CreateGroupRequest) GetDescription() string {
+func (x *StreamLogsRequest) GetTailLines() int32 {
 	if x != nil {
-		return x.Description
+		return x.TailLines
 	}
-	return ""
+	return 0
 }
 
-func (x *CreateGroupRequest) GetAgentNames() []string {
+func (x *StreamLogsRequest) GetFollow() bool {
 	if x != nil {
-		return x.AgentNames
+		return x.Follow
 	}
-	return nil
+	return false
 }
 
-func (x *CreateGroupRequest) GetTags() map[string]string {
+func (x *StreamLogsRequest) GetLevelFilter() string {
 	if x != nil {
-		return x.Tags
+		return x.LevelFilter
 	}
-	return nil
+	return ""
 }
 
-type CreateGroupResponse struct {
+func (x *StreamLogsRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+type LogEntry struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	GroupId       string                 `protobuf:"bytes,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateGroupResponse) Reset() {
-	*x = CreateGroupResponse{}
-	mi := &file_proto_agent_proto_msgTypes[49]
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_proto_agent_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateGroupResponse) String() string {
+func (x *LogEntry) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateGroupResponse) ProtoMessage() {}
+func (*LogEntry) ProtoMessage() {}
 
-func (x *CreateGroupResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[49]
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2908,55 +2957,61 @@ func (x *CreateGroupResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateGroupResponse.ProtoReflect.Descriptor instead.
-func (*CreateGroupResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{49}
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{46}
 }
 
-func (x *CreateGroupResponse) GetSuccess() bool {
+func (x *LogEntry) GetTimestamp() int64 {
 	if x != nil {
-		return x.Success
+		return x.Timestamp
 	}
-	return false
+	return 0
 }
 
-func (x *CreateGroupResponse) GetMessage() string {
+func (x *LogEntry) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogEntry) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
-func (x *CreateGroupResponse) GetGroupId() string {
+func (x *LogEntry) GetSource() string {
 	if x != nil {
-		return x.GroupId
+		return x.Source
 	}
 	return ""
 }
 
-type AddToGroupRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	AgentNames    []string               `protobuf:"bytes,2,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type StreamMetricsRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IntervalSeconds int32                  `protobuf:"varint,1,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"` // How often to send metrics
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *AddToGroupRequest) Reset() {
-	*x = AddToGroupRequest{}
-	mi := &file_proto_agent_proto_msgTypes[50]
+func (x *StreamMetricsRequest) Reset() {
+	*x = StreamMetricsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[47]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AddToGroupRequest) String() string {
+func (x *StreamMetricsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AddToGroupRequest) ProtoMessage() {}
+func (*StreamMetricsRequest) ProtoMessage() {}
 
-func (x *AddToGroupRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[50]
+func (x *StreamMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[47]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2967,48 +3022,44 @@ func (x *AddToGroupRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AddToGroupRequest.ProtoReflect.Descriptor instead.
-func (*AddToGroupRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{50}
-}
-
-func (x *AddToGroupRequest) GetGroupName() string {
-	if x != nil {
-		return x.GroupName
-	}
-	return ""
+// Deprecated: Use StreamMetricsRequest.ProtoReflect.Descriptor instead.
+func (*StreamMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{47}
 }
 
-func (x *AddToGroupRequest) GetAgentNames() []string {
+func (x *StreamMetricsRequest) GetIntervalSeconds() int32 {
 	if x != nil {
-		return x.AgentNames
+		return x.IntervalSeconds
 	}
-	return nil
+	return 0
 }
 
-type AddToGroupResponse struct {
+type MetricsData struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	CpuPercent    float64                `protobuf:"fixed64,2,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryPercent float64                `protobuf:"fixed64,3,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	DiskPercent   float64                `protobuf:"fixed64,4,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`
+	CustomMetrics map[string]float64     `protobuf:"bytes,5,rep,name=custom_metrics,json=customMetrics,proto3" json:"custom_metrics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AddToGroupResponse) Reset() {
-	*x = AddToGroupResponse{}
-	mi := &file_proto_agent_proto_msgTypes[51]
+func (x *MetricsData) Reset() {
+	*x = MetricsData{}
+	mi := &file_proto_agent_proto_msgTypes[48]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AddToGroupResponse) String() string {
+func (x *MetricsData) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AddToGroupResponse) ProtoMessage() {}
+func (*MetricsData) ProtoMessage() {}
 
-func (x *AddToGroupResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[51]
+func (x *MetricsData) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[48]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3019,48 +3070,69 @@ func (x *AddToGroupResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AddToGroupResponse.ProtoReflect.Descriptor instead.
-func (*AddToGroupResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{51}
+// Deprecated: Use MetricsData.ProtoReflect.Descriptor instead.
+func (*MetricsData) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{48}
 }
 
-func (x *AddToGroupResponse) GetSuccess() bool {
+func (x *MetricsData) GetTimestamp() int64 {
 	if x != nil {
-		return x.Success
+		return x.Timestamp
 	}
-	return false
+	return 0
 }
 
-func (x *AddToGroupResponse) GetMessage() string {
+func (x *MetricsData) GetCpuPercent() float64 {
 	if x != nil {
-		return x.Message
+		return x.CpuPercent
 	}
-	return ""
+	return 0
 }
 
-type RemoveFromGroupRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	AgentNames    []string               `protobuf:"bytes,2,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+func (x *MetricsData) GetMemoryPercent() float64 {
+	if x != nil {
+		return x.MemoryPercent
+	}
+	return 0
+}
+
+func (x *MetricsData) GetDiskPercent() float64 {
+	if x != nil {
+		return x.DiskPercent
+	}
+	return 0
+}
+
+func (x *MetricsData) GetCustomMetrics() map[string]float64 {
+	if x != nil {
+		return x.CustomMetrics
+	}
+	return nil
+}
+
+type RestartServiceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceName   string                 `protobuf:"bytes,1,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	Graceful      bool                   `protobuf:"varint,2,opt,name=graceful,proto3" json:"graceful,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RemoveFromGroupRequest) Reset() {
-	*x = RemoveFromGroupRequest{}
-	mi := &file_proto_agent_proto_msgTypes[52]
+func (x *RestartServiceRequest) Reset() {
+	*x = RestartServiceRequest{}
+	mi := &file_proto_agent_proto_msgTypes[49]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RemoveFromGroupRequest) String() string {
+func (x *RestartServiceRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RemoveFromGroupRequest) ProtoMessage() {}
+func (*RestartServiceRequest) ProtoMessage() {}
 
-func (x *RemoveFromGroupRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[52]
+func (x *RestartServiceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[49]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3071,26 +3143,26 @@ func (x *RemoveFromGroupRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RemoveFromGroupRequest.ProtoReflect.Descriptor instead.
-func (*RemoveFromGroupRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{52}
+// Deprecated: Use RestartServiceRequest.ProtoReflect.Descriptor instead.
+func (*RestartServiceRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{49}
 }
 
-func (x *RemoveFromGroupRequest) GetGroupName() string {
+func (x *RestartServiceRequest) GetServiceName() string {
 	if x != nil {
-		return x.GroupName
+		return x.ServiceName
 	}
 	return ""
 }
 
-func (x *RemoveFromGroupRequest) GetAgentNames() []string {
+func (x *RestartServiceRequest) GetGraceful() bool {
 	if x != nil {
-		return x.AgentNames
+		return x.Graceful
 	}
-	return nil
+	return false
 }
 
-type RemoveFromGroupResponse struct {
+type RestartServiceResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
@@ -3098,21 +3170,21 @@ type RemoveFromGroupResponse struct {
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RemoveFromGroupResponse) Reset() {
-	*x = RemoveFromGroupResponse{}
-	mi := &file_proto_agent_proto_msgTypes[53]
+func (x *RestartServiceResponse) Reset() {
+	*x = RestartServiceResponse{}
+	mi := &file_proto_agent_proto_msgTypes[50]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RemoveFromGroupResponse) String() string {
+func (x *RestartServiceResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RemoveFromGroupResponse) ProtoMessage() {}
+func (*RestartServiceResponse) ProtoMessage() {}
 
-func (x *RemoveFromGroupResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[53]
+func (x *RestartServiceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[50]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3123,46 +3195,47 @@ func (x *RemoveFromGroupResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RemoveFromGroupResponse.ProtoReflect.Descriptor instead.
-func (*RemoveFromGroupResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{53}
+// Deprecated: Use RestartServiceResponse.ProtoReflect.Descriptor instead.
+func (*RestartServiceResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{50}
 }
 
-func (x *RemoveFromGroupResponse) GetSuccess() bool {
+func (x *RestartServiceResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *RemoveFromGroupResponse) GetMessage() string {
+func (x *RestartServiceResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
-type ListGroupsRequest struct {
+type EnvVarsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	VarNames      []string               `protobuf:"bytes,1,rep,name=var_names,json=varNames,proto3" json:"var_names,omitempty"` // Empty for all vars
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListGroupsRequest) Reset() {
-	*x = ListGroupsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[54]
+func (x *EnvVarsRequest) Reset() {
+	*x = EnvVarsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[51]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListGroupsRequest) String() string {
+func (x *EnvVarsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListGroupsRequest) ProtoMessage() {}
+func (*EnvVarsRequest) ProtoMessage() {}
 
-func (x *ListGroupsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[54]
+func (x *EnvVarsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[51]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3173,38 +3246,40 @@ func (x *ListGroupsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListGroupsRequest.ProtoReflect.Descriptor instead.
-func (*ListGroupsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{54}
+// Deprecated: Use EnvVarsRequest.ProtoReflect.Descriptor instead.
+func (*EnvVarsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{51}
 }
 
-type AgentGroup struct {
+func (x *EnvVarsRequest) GetVarNames() []string {
+	if x != nil {
+		return x.VarNames
+	}
+	return nil
+}
+
+type EnvVarsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
-	AgentNames    []string               `protobuf:"bytes,3,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
-	Tags          map[string]string      `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	CreatedAt     int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	AgentCount    int32                  `protobuf:"varint,6,opt,name=agent_count,json=agentCount,proto3" json:"agent_count,omitempty"`
+	Variables     map[string]string      `protobuf:"bytes,1,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AgentGroup) Reset() {
-	*x = AgentGroup{}
-	mi := &file_proto_agent_proto_msgTypes[55]
+func (x *EnvVarsResponse) Reset() {
+	*x = EnvVarsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[52]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AgentGroup) String() string {
+func (x *EnvVarsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AgentGroup) ProtoMessage() {}
+func (*EnvVarsResponse) ProtoMessage() {}
 
-func (x *AgentGroup) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[55]
+func (x *EnvVarsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[52]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3215,75 +3290,42 @@ func (x *AgentGroup) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AgentGroup.ProtoReflect.Descriptor instead.
-func (*AgentGroup) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{55}
-}
-
-func (x *AgentGroup) GetName() string {
-	if x != nil {
-		return x.Name
-	}
-	return ""
-}
-
-func (x *AgentGroup) GetDescription() string {
-	if x != nil {
-		return x.Description
-	}
-	return ""
-}
-
-func (x *AgentGroup) GetAgentNames() []string {
-	if x != nil {
-		return x.AgentNames
-	}
-	return nil
+// Deprecated: Use EnvVarsResponse.ProtoReflect.Descriptor instead.
+func (*EnvVarsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{52}
 }
 
-func (x *AgentGroup) GetTags() map[string]string {
+func (x *EnvVarsResponse) GetVariables() map[string]string {
 	if x != nil {
-		return x.Tags
+		return x.Variables
 	}
 	return nil
 }
 
-func (x *AgentGroup) GetCreatedAt() int64 {
-	if x != nil {
-		return x.CreatedAt
-	}
-	return 0
-}
-
-func (x *AgentGroup) GetAgentCount() int32 {
-	if x != nil {
-		return x.AgentCount
-	}
-	return 0
-}
-
-type ListGroupsResponse struct {
+type SetEnvVarRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Groups        []*AgentGroup          `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Persistent    bool                   `protobuf:"varint,3,opt,name=persistent,proto3" json:"persistent,omitempty"` // Save to profile
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListGroupsResponse) Reset() {
-	*x = ListGroupsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[56]
+func (x *SetEnvVarRequest) Reset() {
+	*x = SetEnvVarRequest{}
+	mi := &file_proto_agent_proto_msgTypes[53]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListGroupsResponse) String() string {
+func (x *SetEnvVarRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListGroupsResponse) ProtoMessage() {}
+func (*SetEnvVarRequest) ProtoMessage() {}
 
-func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[56]
+func (x *SetEnvVarRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[53]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3294,40 +3336,55 @@ func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListGroupsResponse.ProtoReflect.Descriptor instead.
-func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{56}
+// Deprecated: Use SetEnvVarRequest.ProtoReflect.Descriptor instead.
+func (*SetEnvVarRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{53}
 }
 
-func (x *ListGroupsResponse) GetGroups() []*AgentGroup {
+func (x *SetEnvVarRequest) GetName() string {
 	if x != nil {
-		return x.Groups
+		return x.Name
 	}
-	return nil
+	return ""
 }
 
-type DeleteGroupRequest struct {
+func (x *SetEnvVarRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *SetEnvVarRequest) GetPersistent() bool {
+	if x != nil {
+		return x.Persistent
+	}
+	return false
+}
+
+type SetEnvVarResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteGroupRequest) Reset() {
-	*x = DeleteGroupRequest{}
-	mi := &file_proto_agent_proto_msgTypes[57]
+func (x *SetEnvVarResponse) Reset() {
+	*x = SetEnvVarResponse{}
+	mi := &file_proto_agent_proto_msgTypes[54]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteGroupRequest) String() string {
+func (x *SetEnvVarResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteGroupRequest) ProtoMessage() {}
+func (*SetEnvVarResponse) ProtoMessage() {}
 
-func (x *DeleteGroupRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[57]
+func (x *SetEnvVarResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[54]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3338,41 +3395,48 @@ func (x *DeleteGroupRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteGroupRequest.ProtoReflect.Descriptor instead.
-func (*DeleteGroupRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{57}
+// Deprecated: Use SetEnvVarResponse.ProtoReflect.Descriptor instead.
+func (*SetEnvVarResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{54}
 }
 
-func (x *DeleteGroupRequest) GetGroupName() string {
+func (x *SetEnvVarResponse) GetSuccess() bool {
 	if x != nil {
-		return x.GroupName
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-type DeleteGroupResponse struct {
+func (x *SetEnvVarResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type InstallModuleRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	ModuleName    string                 `protobuf:"bytes,1,opt,name=module_name,json=moduleName,proto3" json:"module_name,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"` // Empty for latest
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteGroupResponse) Reset() {
-	*x = DeleteGroupResponse{}
-	mi := &file_proto_agent_proto_msgTypes[58]
+func (x *InstallModuleRequest) Reset() {
+	*x = InstallModuleRequest{}
+	mi := &file_proto_agent_proto_msgTypes[55]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteGroupResponse) String() string {
+func (x *InstallModuleRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteGroupResponse) ProtoMessage() {}
+func (*InstallModuleRequest) ProtoMessage() {}
 
-func (x *DeleteGroupResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[58]
+func (x *InstallModuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[55]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3383,52 +3447,49 @@ func (x *DeleteGroupResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteGroupResponse.ProtoReflect.Descriptor instead.
-func (*DeleteGroupResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{58}
+// Deprecated: Use InstallModuleRequest.ProtoReflect.Descriptor instead.
+func (*InstallModuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{55}
 }
 
-func (x *DeleteGroupResponse) GetSuccess() bool {
+func (x *InstallModuleRequest) GetModuleName() string {
 	if x != nil {
-		return x.Success
+		return x.ModuleName
 	}
-	return false
+	return ""
 }
 
-func (x *DeleteGroupResponse) GetMessage() string {
+func (x *InstallModuleRequest) GetVersion() string {
 	if x != nil {
-		return x.Message
+		return x.Version
 	}
 	return ""
 }
 
-type BulkExecuteRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	AgentNames     []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
-	GroupName      string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"` // Alternative to agent_names
-	Command        string                 `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
-	WorkflowName   string                 `protobuf:"bytes,4,opt,name=workflow_name,json=workflowName,proto3" json:"workflow_name,omitempty"` // Alternative to command
-	Parallel       bool                   `protobuf:"varint,5,opt,name=parallel,proto3" json:"parallel,omitempty"`
-	TimeoutSeconds int32                  `protobuf:"varint,6,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+type InstallModuleResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Success          bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message          string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	InstalledVersion string                 `protobuf:"bytes,3,opt,name=installed_version,json=installedVersion,proto3" json:"installed_version,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *BulkExecuteRequest) Reset() {
-	*x = BulkExecuteRequest{}
-	mi := &file_proto_agent_proto_msgTypes[59]
+func (x *InstallModuleResponse) Reset() {
+	*x = InstallModuleResponse{}
+	mi := &file_proto_agent_proto_msgTypes[56]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *BulkExecuteRequest) String() string {
+func (x *InstallModuleResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BulkExecuteRequest) ProtoMessage() {}
+func (*InstallModuleResponse) ProtoMessage() {}
 
-func (x *BulkExecuteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[59]
+func (x *InstallModuleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[56]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3439,80 +3500,93 @@ func (x *BulkExecuteRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BulkExecuteRequest.ProtoReflect.Descriptor instead.
-func (*BulkExecuteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{59}
+// Deprecated: Use InstallModuleResponse.ProtoReflect.Descriptor instead.
+func (*InstallModuleResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{56}
 }
 
-func (x *BulkExecuteRequest) GetAgentNames() []string {
+func (x *InstallModuleResponse) GetSuccess() bool {
 	if x != nil {
-		return x.AgentNames
+		return x.Success
 	}
-	return nil
+	return false
 }
 
-func (x *BulkExecuteRequest) GetGroupName() string {
+func (x *InstallModuleResponse) GetMessage() string {
 	if x != nil {
-		return x.GroupName
+		return x.Message
 	}
 	return ""
 }
 
-func (x *BulkExecuteRequest) GetCommand() string {
+func (x *InstallModuleResponse) GetInstalledVersion() string {
 	if x != nil {
-		return x.Command
+		return x.InstalledVersion
 	}
 	return ""
 }
 
-func (x *BulkExecuteRequest) GetWorkflowName() string {
-	if x != nil {
-		return x.WorkflowName
-	}
-	return ""
+type ModulesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *BulkExecuteRequest) GetParallel() bool {
-	if x != nil {
-		return x.Parallel
-	}
-	return false
+func (x *ModulesRequest) Reset() {
+	*x = ModulesRequest{}
+	mi := &file_proto_agent_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *BulkExecuteRequest) GetTimeoutSeconds() int32 {
+func (x *ModulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModulesRequest) ProtoMessage() {}
+
+func (x *ModulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[57]
 	if x != nil {
-		return x.TimeoutSeconds
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-type BulkExecuteResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	AgentName       string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	Success         bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
-	Output          string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"`
-	Error           string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
-	ExitCode        int32                  `protobuf:"varint,5,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
-	ExecutionTimeMs int64                  `protobuf:"varint,6,opt,name=execution_time_ms,json=executionTimeMs,proto3" json:"execution_time_ms,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+// Deprecated: Use ModulesRequest.ProtoReflect.Descriptor instead.
+func (*ModulesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{57}
 }
 
-func (x *BulkExecuteResponse) Reset() {
-	*x = BulkExecuteResponse{}
-	mi := &file_proto_agent_proto_msgTypes[60]
+type ModuleInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Installed     bool                   `protobuf:"varint,3,opt,name=installed,proto3" json:"installed,omitempty"`
+	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModuleInfo) Reset() {
+	*x = ModuleInfo{}
+	mi := &file_proto_agent_proto_msgTypes[58]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *BulkExecuteResponse) String() string {
+func (x *ModuleInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BulkExecuteResponse) ProtoMessage() {}
+func (*ModuleInfo) ProtoMessage() {}
 
-func (x *BulkExecuteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[60]
+func (x *ModuleInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[58]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3523,132 +3597,2530 @@ func (x *BulkExecuteResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BulkExecuteResponse.ProtoReflect.Descriptor instead.
-func (*BulkExecuteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{60}
+// Deprecated: Use ModuleInfo.ProtoReflect.Descriptor instead.
+func (*ModuleInfo) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{58}
 }
 
-func (x *BulkExecuteResponse) GetAgentName() string {
+func (x *ModuleInfo) GetName() string {
 	if x != nil {
-		return x.AgentName
+		return x.Name
 	}
 	return ""
 }
 
-func (x *BulkExecuteResponse) GetSuccess() bool {
+func (x *ModuleInfo) GetVersion() string {
 	if x != nil {
-		return x.Success
+		return x.Version
 	}
-	return false
+	return ""
 }
 
-func (x *BulkExecuteResponse) GetOutput() string {
+func (x *ModuleInfo) GetInstalled() bool {
 	if x != nil {
-		return x.Output
+		return x.Installed
 	}
-	return ""
+	return false
 }
 
-func (x *BulkExecuteResponse) GetError() string {
+func (x *ModuleInfo) GetDescription() string {
 	if x != nil {
-		return x.Error
+		return x.Description
 	}
 	return ""
 }
 
-func (x *BulkExecuteResponse) GetExitCode() int32 {
+type ModulesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Modules       []*ModuleInfo          `protobuf:"bytes,1,rep,name=modules,proto3" json:"modules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModulesResponse) Reset() {
+	*x = ModulesResponse{}
+	mi := &file_proto_agent_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModulesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModulesResponse) ProtoMessage() {}
+
+func (x *ModulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[59]
 	if x != nil {
-		return x.ExitCode
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *BulkExecuteResponse) GetExecutionTimeMs() int64 {
+// Deprecated: Use ModulesResponse.ProtoReflect.Descriptor instead.
+func (*ModulesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *ModulesResponse) GetModules() []*ModuleInfo {
 	if x != nil {
-		return x.ExecutionTimeMs
+		return x.Modules
 	}
-	return 0
+	return nil
 }
 
-type MultipleAgentStatusRequest struct {
+type CreateGroupRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentNames    []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
-	GroupName     string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	AgentNames    []string               `protobuf:"bytes,3,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	Tags          map[string]string      `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MultipleAgentStatusRequest) Reset() {
-	*x = MultipleAgentStatusRequest{}
-	mi := &file_proto_agent_proto_msgTypes[61]
+func (x *CreateGroupRequest) Reset() {
+	*x = CreateGroupRequest{}
+	mi := &file_proto_agent_proto_msgTypes[60]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MultipleAgentStatusRequest) String() string {
+func (x *CreateGroupRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MultipleAgentStatusRequest) ProtoMessage() {}
+func (*CreateGroupRequest) ProtoMessage() {}
 
-func (x *MultipleAgentStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[61]
-	if x != nil {
+func (x *CreateGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGroupRequest.ProtoReflect.Descriptor instead.
+func (*CreateGroupRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *CreateGroupRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+func (x *CreateGroupRequest) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type CreateGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	GroupId       string                 `protobuf:"bytes,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateGroupResponse) Reset() {
+	*x = CreateGroupResponse{}
+	mi := &file_proto_agent_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateGroupResponse) ProtoMessage() {}
+
+func (x *CreateGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGroupResponse.ProtoReflect.Descriptor instead.
+func (*CreateGroupResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *CreateGroupResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CreateGroupResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CreateGroupResponse) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+type AddToGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	AgentNames    []string               `protobuf:"bytes,2,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddToGroupRequest) Reset() {
+	*x = AddToGroupRequest{}
+	mi := &file_proto_agent_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddToGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddToGroupRequest) ProtoMessage() {}
+
+func (x *AddToGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddToGroupRequest.ProtoReflect.Descriptor instead.
+func (*AddToGroupRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *AddToGroupRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *AddToGroupRequest) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+type AddToGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddToGroupResponse) Reset() {
+	*x = AddToGroupResponse{}
+	mi := &file_proto_agent_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddToGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddToGroupResponse) ProtoMessage() {}
+
+func (x *AddToGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddToGroupResponse.ProtoReflect.Descriptor instead.
+func (*AddToGroupResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{63}
+}
+
+func (x *AddToGroupResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AddToGroupResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type RemoveFromGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	AgentNames    []string               `protobuf:"bytes,2,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveFromGroupRequest) Reset() {
+	*x = RemoveFromGroupRequest{}
+	mi := &file_proto_agent_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveFromGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveFromGroupRequest) ProtoMessage() {}
+
+func (x *RemoveFromGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveFromGroupRequest.ProtoReflect.Descriptor instead.
+func (*RemoveFromGroupRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *RemoveFromGroupRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *RemoveFromGroupRequest) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+type RemoveFromGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveFromGroupResponse) Reset() {
+	*x = RemoveFromGroupResponse{}
+	mi := &file_proto_agent_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveFromGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveFromGroupResponse) ProtoMessage() {}
+
+func (x *RemoveFromGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveFromGroupResponse.ProtoReflect.Descriptor instead.
+func (*RemoveFromGroupResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *RemoveFromGroupResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RemoveFromGroupResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ListGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsRequest) Reset() {
+	*x = ListGroupsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsRequest) ProtoMessage() {}
+
+func (x *ListGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsRequest.ProtoReflect.Descriptor instead.
+func (*ListGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{66}
+}
+
+type AgentGroup struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	AgentNames    []string               `protobuf:"bytes,3,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	Tags          map[string]string      `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CreatedAt     int64                  `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	AgentCount    int32                  `protobuf:"varint,6,opt,name=agent_count,json=agentCount,proto3" json:"agent_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentGroup) Reset() {
+	*x = AgentGroup{}
+	mi := &file_proto_agent_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentGroup) ProtoMessage() {}
+
+func (x *AgentGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentGroup.ProtoReflect.Descriptor instead.
+func (*AgentGroup) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{67}
+}
+
+func (x *AgentGroup) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AgentGroup) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *AgentGroup) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+func (x *AgentGroup) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *AgentGroup) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *AgentGroup) GetAgentCount() int32 {
+	if x != nil {
+		return x.AgentCount
+	}
+	return 0
+}
+
+type ListGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Groups        []*AgentGroup          `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsResponse) Reset() {
+	*x = ListGroupsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsResponse) ProtoMessage() {}
+
+func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[68]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *ListGroupsResponse) GetGroups() []*AgentGroup {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+type DeleteGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupName     string                 `protobuf:"bytes,1,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteGroupRequest) Reset() {
+	*x = DeleteGroupRequest{}
+	mi := &file_proto_agent_proto_msgTypes[69]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteGroupRequest) ProtoMessage() {}
+
+func (x *DeleteGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[69]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteGroupRequest.ProtoReflect.Descriptor instead.
+func (*DeleteGroupRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{69}
+}
+
+func (x *DeleteGroupRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+type DeleteGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteGroupResponse) Reset() {
+	*x = DeleteGroupResponse{}
+	mi := &file_proto_agent_proto_msgTypes[70]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteGroupResponse) ProtoMessage() {}
+
+func (x *DeleteGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[70]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteGroupResponse.ProtoReflect.Descriptor instead.
+func (*DeleteGroupResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{70}
+}
+
+func (x *DeleteGroupResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteGroupResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type BulkExecuteRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AgentNames     []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	GroupName      string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"` // Alternative to agent_names
+	Command        string                 `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
+	WorkflowName   string                 `protobuf:"bytes,4,opt,name=workflow_name,json=workflowName,proto3" json:"workflow_name,omitempty"` // Alternative to command
+	Parallel       bool                   `protobuf:"varint,5,opt,name=parallel,proto3" json:"parallel,omitempty"`
+	TimeoutSeconds int32                  `protobuf:"varint,6,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BulkExecuteRequest) Reset() {
+	*x = BulkExecuteRequest{}
+	mi := &file_proto_agent_proto_msgTypes[71]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkExecuteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkExecuteRequest) ProtoMessage() {}
+
+func (x *BulkExecuteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[71]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkExecuteRequest.ProtoReflect.Descriptor instead.
+func (*BulkExecuteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{71}
+}
+
+func (x *BulkExecuteRequest) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+func (x *BulkExecuteRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *BulkExecuteRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *BulkExecuteRequest) GetWorkflowName() string {
+	if x != nil {
+		return x.WorkflowName
+	}
+	return ""
+}
+
+func (x *BulkExecuteRequest) GetParallel() bool {
+	if x != nil {
+		return x.Parallel
+	}
+	return false
+}
+
+func (x *BulkExecuteRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+type BulkExecuteResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AgentName       string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	Success         bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Output          string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"`
+	Error           string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	ExitCode        int32                  `protobuf:"varint,5,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	ExecutionTimeMs int64                  `protobuf:"varint,6,opt,name=execution_time_ms,json=executionTimeMs,proto3" json:"execution_time_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *BulkExecuteResponse) Reset() {
+	*x = BulkExecuteResponse{}
+	mi := &file_proto_agent_proto_msgTypes[72]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkExecuteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkExecuteResponse) ProtoMessage() {}
+
+func (x *BulkExecuteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[72]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkExecuteResponse.ProtoReflect.Descriptor instead.
+func (*BulkExecuteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{72}
+}
+
+func (x *BulkExecuteResponse) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *BulkExecuteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *BulkExecuteResponse) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+func (x *BulkExecuteResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BulkExecuteResponse) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *BulkExecuteResponse) GetExecutionTimeMs() int64 {
+	if x != nil {
+		return x.ExecutionTimeMs
+	}
+	return 0
+}
+
+type MultipleAgentStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentNames    []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	GroupName     string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MultipleAgentStatusRequest) Reset() {
+	*x = MultipleAgentStatusRequest{}
+	mi := &file_proto_agent_proto_msgTypes[73]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MultipleAgentStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultipleAgentStatusRequest) ProtoMessage() {}
+
+func (x *MultipleAgentStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[73]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultipleAgentStatusRequest.ProtoReflect.Descriptor instead.
+func (*MultipleAgentStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{73}
+}
+
+func (x *MultipleAgentStatusRequest) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+func (x *MultipleAgentStatusRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+type AgentStatusInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	CpuPercent    float64                `protobuf:"fixed64,3,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryPercent float64                `protobuf:"fixed64,4,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	LastHeartbeat int64                  `protobuf:"varint,5,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`
+	Healthy       bool                   `protobuf:"varint,6,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentStatusInfo) Reset() {
+	*x = AgentStatusInfo{}
+	mi := &file_proto_agent_proto_msgTypes[74]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentStatusInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentStatusInfo) ProtoMessage() {}
+
+func (x *AgentStatusInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[74]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentStatusInfo.ProtoReflect.Descriptor instead.
+func (*AgentStatusInfo) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{74}
+}
+
+func (x *AgentStatusInfo) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *AgentStatusInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AgentStatusInfo) GetCpuPercent() float64 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
+}
+
+func (x *AgentStatusInfo) GetMemoryPercent() float64 {
+	if x != nil {
+		return x.MemoryPercent
+	}
+	return 0
+}
+
+func (x *AgentStatusInfo) GetLastHeartbeat() int64 {
+	if x != nil {
+		return x.LastHeartbeat
+	}
+	return 0
+}
+
+func (x *AgentStatusInfo) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+type MultipleAgentStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Statuses      []*AgentStatusInfo     `protobuf:"bytes,1,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MultipleAgentStatusResponse) Reset() {
+	*x = MultipleAgentStatusResponse{}
+	mi := &file_proto_agent_proto_msgTypes[75]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MultipleAgentStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultipleAgentStatusResponse) ProtoMessage() {}
+
+func (x *MultipleAgentStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[75]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultipleAgentStatusResponse.ProtoReflect.Descriptor instead.
+func (*MultipleAgentStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{75}
+}
+
+func (x *MultipleAgentStatusResponse) GetStatuses() []*AgentStatusInfo {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+type AggregatedMetricsRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	AgentNames       []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	GroupName        string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	TimeRangeMinutes int32                  `protobuf:"varint,3,opt,name=time_range_minutes,json=timeRangeMinutes,proto3" json:"time_range_minutes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *AggregatedMetricsRequest) Reset() {
+	*x = AggregatedMetricsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[76]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregatedMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregatedMetricsRequest) ProtoMessage() {}
+
+func (x *AggregatedMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[76]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregatedMetricsRequest.ProtoReflect.Descriptor instead.
+func (*AggregatedMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{76}
+}
+
+func (x *AggregatedMetricsRequest) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+func (x *AggregatedMetricsRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *AggregatedMetricsRequest) GetTimeRangeMinutes() int32 {
+	if x != nil {
+		return x.TimeRangeMinutes
+	}
+	return 0
+}
+
+type AggregatedMetricsResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	AvgCpuPercent    float64                `protobuf:"fixed64,1,opt,name=avg_cpu_percent,json=avgCpuPercent,proto3" json:"avg_cpu_percent,omitempty"`
+	AvgMemoryPercent float64                `protobuf:"fixed64,2,opt,name=avg_memory_percent,json=avgMemoryPercent,proto3" json:"avg_memory_percent,omitempty"`
+	AvgDiskPercent   float64                `protobuf:"fixed64,3,opt,name=avg_disk_percent,json=avgDiskPercent,proto3" json:"avg_disk_percent,omitempty"`
+	TotalAgents      int32                  `protobuf:"varint,4,opt,name=total_agents,json=totalAgents,proto3" json:"total_agents,omitempty"`
+	HealthyAgents    int32                  `protobuf:"varint,5,opt,name=healthy_agents,json=healthyAgents,proto3" json:"healthy_agents,omitempty"`
+	UnhealthyAgents  int32                  `protobuf:"varint,6,opt,name=unhealthy_agents,json=unhealthyAgents,proto3" json:"unhealthy_agents,omitempty"`
+	CustomMetrics    map[string]float64     `protobuf:"bytes,7,rep,name=custom_metrics,json=customMetrics,proto3" json:"custom_metrics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *AggregatedMetricsResponse) Reset() {
+	*x = AggregatedMetricsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[77]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregatedMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregatedMetricsResponse) ProtoMessage() {}
+
+func (x *AggregatedMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[77]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregatedMetricsResponse.ProtoReflect.Descriptor instead.
+func (*AggregatedMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{77}
+}
+
+func (x *AggregatedMetricsResponse) GetAvgCpuPercent() float64 {
+	if x != nil {
+		return x.AvgCpuPercent
+	}
+	return 0
+}
+
+func (x *AggregatedMetricsResponse) GetAvgMemoryPercent() float64 {
+	if x != nil {
+		return x.AvgMemoryPercent
+	}
+	return 0
+}
+
+func (x *AggregatedMetricsResponse) GetAvgDiskPercent() float64 {
+	if x != nil {
+		return x.AvgDiskPercent
+	}
+	return 0
+}
+
+func (x *AggregatedMetricsResponse) GetTotalAgents() int32 {
+	if x != nil {
+		return x.TotalAgents
+	}
+	return 0
+}
+
+func (x *AggregatedMetricsResponse) GetHealthyAgents() int32 {
+	if x != nil {
+		return x.HealthyAgents
+	}
+	return 0
+}
+
+func (x *AggregatedMetricsResponse) GetUnhealthyAgents() int32 {
+	if x != nil {
+		return x.UnhealthyAgents
+	}
+	return 0
+}
+
+func (x *AggregatedMetricsResponse) GetCustomMetrics() map[string]float64 {
+	if x != nil {
+		return x.CustomMetrics
+	}
+	return nil
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentNames    []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
+	GroupName     string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	EventTypes    []string               `protobuf:"bytes,3,rep,name=event_types,json=eventTypes,proto3" json:"event_types,omitempty"` // connected, disconnected, error, etc
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[78]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[78]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{78}
+}
+
+func (x *StreamEventsRequest) GetAgentNames() []string {
+	if x != nil {
+		return x.AgentNames
+	}
+	return nil
+}
+
+func (x *StreamEventsRequest) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *StreamEventsRequest) GetEventTypes() []string {
+	if x != nil {
+		return x.EventTypes
+	}
+	return nil
+}
+
+type AgentEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Metadata      map[string]string      `protobuf:"bytes,5,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Severity      string                 `protobuf:"bytes,6,opt,name=severity,proto3" json:"severity,omitempty"` // info, warning, error, critical
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentEvent) Reset() {
+	*x = AgentEvent{}
+	mi := &file_proto_agent_proto_msgTypes[79]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentEvent) ProtoMessage() {}
+
+func (x *AgentEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[79]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentEvent.ProtoReflect.Descriptor instead.
+func (*AgentEvent) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{79}
+}
+
+func (x *AgentEvent) GetAgentName() string {
+	if x != nil {
+		return x.AgentName
+	}
+	return ""
+}
+
+func (x *AgentEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *AgentEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *AgentEvent) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *AgentEvent) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *AgentEvent) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+type DetailedMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DetailedMetricsRequest) Reset() {
+	*x = DetailedMetricsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[80]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DetailedMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DetailedMetricsRequest) ProtoMessage() {}
+
+func (x *DetailedMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[80]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DetailedMetricsRequest.ProtoReflect.Descriptor instead.
+func (*DetailedMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{80}
+}
+
+type CPUDetail struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CoreCount     int32                  `protobuf:"varint,1,opt,name=core_count,json=coreCount,proto3" json:"core_count,omitempty"`
+	PerCoreUsage  []float64              `protobuf:"fixed64,2,rep,packed,name=per_core_usage,json=perCoreUsage,proto3" json:"per_core_usage,omitempty"`
+	UserTime      float64                `protobuf:"fixed64,3,opt,name=user_time,json=userTime,proto3" json:"user_time,omitempty"`
+	SystemTime    float64                `protobuf:"fixed64,4,opt,name=system_time,json=systemTime,proto3" json:"system_time,omitempty"`
+	IdleTime      float64                `protobuf:"fixed64,5,opt,name=idle_time,json=idleTime,proto3" json:"idle_time,omitempty"`
+	IowaitTime    float64                `protobuf:"fixed64,6,opt,name=iowait_time,json=iowaitTime,proto3" json:"iowait_time,omitempty"`
+	ModelName     string                 `protobuf:"bytes,7,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	Mhz           float64                `protobuf:"fixed64,8,opt,name=mhz,proto3" json:"mhz,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CPUDetail) Reset() {
+	*x = CPUDetail{}
+	mi := &file_proto_agent_proto_msgTypes[81]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CPUDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CPUDetail) ProtoMessage() {}
+
+func (x *CPUDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[81]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CPUDetail.ProtoReflect.Descriptor instead.
+func (*CPUDetail) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{81}
+}
+
+func (x *CPUDetail) GetCoreCount() int32 {
+	if x != nil {
+		return x.CoreCount
+	}
+	return 0
+}
+
+func (x *CPUDetail) GetPerCoreUsage() []float64 {
+	if x != nil {
+		return x.PerCoreUsage
+	}
+	return nil
+}
+
+func (x *CPUDetail) GetUserTime() float64 {
+	if x != nil {
+		return x.UserTime
+	}
+	return 0
+}
+
+func (x *CPUDetail) GetSystemTime() float64 {
+	if x != nil {
+		return x.SystemTime
+	}
+	return 0
+}
+
+func (x *CPUDetail) GetIdleTime() float64 {
+	if x != nil {
+		return x.IdleTime
+	}
+	return 0
+}
+
+func (x *CPUDetail) GetIowaitTime() float64 {
+	if x != nil {
+		return x.IowaitTime
+	}
+	return 0
+}
+
+func (x *CPUDetail) GetModelName() string {
+	if x != nil {
+		return x.ModelName
+	}
+	return ""
+}
+
+func (x *CPUDetail) GetMhz() float64 {
+	if x != nil {
+		return x.Mhz
+	}
+	return 0
+}
+
+type MemoryDetail struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TotalBytes     uint64                 `protobuf:"varint,1,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	AvailableBytes uint64                 `protobuf:"varint,2,opt,name=available_bytes,json=availableBytes,proto3" json:"available_bytes,omitempty"`
+	UsedBytes      uint64                 `protobuf:"varint,3,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	FreeBytes      uint64                 `protobuf:"varint,4,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`
+	CachedBytes    uint64                 `protobuf:"varint,5,opt,name=cached_bytes,json=cachedBytes,proto3" json:"cached_bytes,omitempty"`
+	BuffersBytes   uint64                 `protobuf:"varint,6,opt,name=buffers_bytes,json=buffersBytes,proto3" json:"buffers_bytes,omitempty"`
+	SwapTotalBytes uint64                 `protobuf:"varint,7,opt,name=swap_total_bytes,json=swapTotalBytes,proto3" json:"swap_total_bytes,omitempty"`
+	SwapUsedBytes  uint64                 `protobuf:"varint,8,opt,name=swap_used_bytes,json=swapUsedBytes,proto3" json:"swap_used_bytes,omitempty"`
+	SwapFreeBytes  uint64                 `protobuf:"varint,9,opt,name=swap_free_bytes,json=swapFreeBytes,proto3" json:"swap_free_bytes,omitempty"`
+	Percent        float64                `protobuf:"fixed64,10,opt,name=percent,proto3" json:"percent,omitempty"`
+	SwapPercent    float64                `protobuf:"fixed64,11,opt,name=swap_percent,json=swapPercent,proto3" json:"swap_percent,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *MemoryDetail) Reset() {
+	*x = MemoryDetail{}
+	mi := &file_proto_agent_proto_msgTypes[82]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MemoryDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MemoryDetail) ProtoMessage() {}
+
+func (x *MemoryDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[82]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MemoryDetail.ProtoReflect.Descriptor instead.
+func (*MemoryDetail) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{82}
+}
+
+func (x *MemoryDetail) GetTotalBytes() uint64 {
+	if x != nil {
+		return x.TotalBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetAvailableBytes() uint64 {
+	if x != nil {
+		return x.AvailableBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetFreeBytes() uint64 {
+	if x != nil {
+		return x.FreeBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetCachedBytes() uint64 {
+	if x != nil {
+		return x.CachedBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetBuffersBytes() uint64 {
+	if x != nil {
+		return x.BuffersBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetSwapTotalBytes() uint64 {
+	if x != nil {
+		return x.SwapTotalBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetSwapUsedBytes() uint64 {
+	if x != nil {
+		return x.SwapUsedBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetSwapFreeBytes() uint64 {
+	if x != nil {
+		return x.SwapFreeBytes
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *MemoryDetail) GetSwapPercent() float64 {
+	if x != nil {
+		return x.SwapPercent
+	}
+	return 0
+}
+
+type DiskDetail struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Partitions      []*DiskPartition       `protobuf:"bytes,1,rep,name=partitions,proto3" json:"partitions,omitempty"`
+	ReadBytesTotal  uint64                 `protobuf:"varint,2,opt,name=read_bytes_total,json=readBytesTotal,proto3" json:"read_bytes_total,omitempty"`
+	WriteBytesTotal uint64                 `protobuf:"varint,3,opt,name=write_bytes_total,json=writeBytesTotal,proto3" json:"write_bytes_total,omitempty"`
+	ReadCount       uint64                 `protobuf:"varint,4,opt,name=read_count,json=readCount,proto3" json:"read_count,omitempty"`
+	WriteCount      uint64                 `protobuf:"varint,5,opt,name=write_count,json=writeCount,proto3" json:"write_count,omitempty"`
+	ReadTimeMs      uint64                 `protobuf:"varint,6,opt,name=read_time_ms,json=readTimeMs,proto3" json:"read_time_ms,omitempty"`
+	WriteTimeMs     uint64                 `protobuf:"varint,7,opt,name=write_time_ms,json=writeTimeMs,proto3" json:"write_time_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DiskDetail) Reset() {
+	*x = DiskDetail{}
+	mi := &file_proto_agent_proto_msgTypes[83]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiskDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiskDetail) ProtoMessage() {}
+
+func (x *DiskDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[83]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiskDetail.ProtoReflect.Descriptor instead.
+func (*DiskDetail) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{83}
+}
+
+func (x *DiskDetail) GetPartitions() []*DiskPartition {
+	if x != nil {
+		return x.Partitions
+	}
+	return nil
+}
+
+func (x *DiskDetail) GetReadBytesTotal() uint64 {
+	if x != nil {
+		return x.ReadBytesTotal
+	}
+	return 0
+}
+
+func (x *DiskDetail) GetWriteBytesTotal() uint64 {
+	if x != nil {
+		return x.WriteBytesTotal
+	}
+	return 0
+}
+
+func (x *DiskDetail) GetReadCount() uint64 {
+	if x != nil {
+		return x.ReadCount
+	}
+	return 0
+}
+
+func (x *DiskDetail) GetWriteCount() uint64 {
+	if x != nil {
+		return x.WriteCount
+	}
+	return 0
+}
+
+func (x *DiskDetail) GetReadTimeMs() uint64 {
+	if x != nil {
+		return x.ReadTimeMs
+	}
+	return 0
+}
+
+func (x *DiskDetail) GetWriteTimeMs() uint64 {
+	if x != nil {
+		return x.WriteTimeMs
+	}
+	return 0
+}
+
+type NetworkDetail struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Interfaces        []*NetworkInterface    `protobuf:"bytes,1,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	BytesSentTotal    uint64                 `protobuf:"varint,2,opt,name=bytes_sent_total,json=bytesSentTotal,proto3" json:"bytes_sent_total,omitempty"`
+	BytesRecvTotal    uint64                 `protobuf:"varint,3,opt,name=bytes_recv_total,json=bytesRecvTotal,proto3" json:"bytes_recv_total,omitempty"`
+	PacketsSentTotal  uint64                 `protobuf:"varint,4,opt,name=packets_sent_total,json=packetsSentTotal,proto3" json:"packets_sent_total,omitempty"`
+	PacketsRecvTotal  uint64                 `protobuf:"varint,5,opt,name=packets_recv_total,json=packetsRecvTotal,proto3" json:"packets_recv_total,omitempty"`
+	ErrorsIn          uint64                 `protobuf:"varint,6,opt,name=errors_in,json=errorsIn,proto3" json:"errors_in,omitempty"`
+	ErrorsOut         uint64                 `protobuf:"varint,7,opt,name=errors_out,json=errorsOut,proto3" json:"errors_out,omitempty"`
+	DropsIn           uint64                 `protobuf:"varint,8,opt,name=drops_in,json=dropsIn,proto3" json:"drops_in,omitempty"`
+	DropsOut          uint64                 `protobuf:"varint,9,opt,name=drops_out,json=dropsOut,proto3" json:"drops_out,omitempty"`
+	ActiveConnections int32                  `protobuf:"varint,10,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	ListeningPorts    int32                  `protobuf:"varint,11,opt,name=listening_ports,json=listeningPorts,proto3" json:"listening_ports,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *NetworkDetail) Reset() {
+	*x = NetworkDetail{}
+	mi := &file_proto_agent_proto_msgTypes[84]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NetworkDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkDetail) ProtoMessage() {}
+
+func (x *NetworkDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[84]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkDetail.ProtoReflect.Descriptor instead.
+func (*NetworkDetail) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{84}
+}
+
+func (x *NetworkDetail) GetInterfaces() []*NetworkInterface {
+	if x != nil {
+		return x.Interfaces
+	}
+	return nil
+}
+
+func (x *NetworkDetail) GetBytesSentTotal() uint64 {
+	if x != nil {
+		return x.BytesSentTotal
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetBytesRecvTotal() uint64 {
+	if x != nil {
+		return x.BytesRecvTotal
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetPacketsSentTotal() uint64 {
+	if x != nil {
+		return x.PacketsSentTotal
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetPacketsRecvTotal() uint64 {
+	if x != nil {
+		return x.PacketsRecvTotal
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetErrorsIn() uint64 {
+	if x != nil {
+		return x.ErrorsIn
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetErrorsOut() uint64 {
+	if x != nil {
+		return x.ErrorsOut
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetDropsIn() uint64 {
+	if x != nil {
+		return x.DropsIn
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetDropsOut() uint64 {
+	if x != nil {
+		return x.DropsOut
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetActiveConnections() int32 {
+	if x != nil {
+		return x.ActiveConnections
+	}
+	return 0
+}
+
+func (x *NetworkDetail) GetListeningPorts() int32 {
+	if x != nil {
+		return x.ListeningPorts
+	}
+	return 0
+}
+
+type DetailedMetricsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Cpu           *CPUDetail             `protobuf:"bytes,2,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	Memory        *MemoryDetail          `protobuf:"bytes,3,opt,name=memory,proto3" json:"memory,omitempty"`
+	Disk          *DiskDetail            `protobuf:"bytes,4,opt,name=disk,proto3" json:"disk,omitempty"`
+	Network       *NetworkDetail         `protobuf:"bytes,5,opt,name=network,proto3" json:"network,omitempty"`
+	LoadAvg_1Min  float64                `protobuf:"fixed64,6,opt,name=load_avg_1min,json=loadAvg1min,proto3" json:"load_avg_1min,omitempty"`
+	LoadAvg_5Min  float64                `protobuf:"fixed64,7,opt,name=load_avg_5min,json=loadAvg5min,proto3" json:"load_avg_5min,omitempty"`
+	LoadAvg_15Min float64                `protobuf:"fixed64,8,opt,name=load_avg_15min,json=loadAvg15min,proto3" json:"load_avg_15min,omitempty"`
+	UptimeSeconds uint64                 `protobuf:"varint,9,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	ProcessCount  int32                  `protobuf:"varint,10,opt,name=process_count,json=processCount,proto3" json:"process_count,omitempty"`
+	ThreadCount   int32                  `protobuf:"varint,11,opt,name=thread_count,json=threadCount,proto3" json:"thread_count,omitempty"`
+	KernelVersion string                 `protobuf:"bytes,12,opt,name=kernel_version,json=kernelVersion,proto3" json:"kernel_version,omitempty"`
+	OsVersion     string                 `protobuf:"bytes,13,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DetailedMetricsResponse) Reset() {
+	*x = DetailedMetricsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[85]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DetailedMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DetailedMetricsResponse) ProtoMessage() {}
+
+func (x *DetailedMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[85]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DetailedMetricsResponse.ProtoReflect.Descriptor instead.
+func (*DetailedMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{85}
+}
+
+func (x *DetailedMetricsResponse) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *DetailedMetricsResponse) GetCpu() *CPUDetail {
+	if x != nil {
+		return x.Cpu
+	}
+	return nil
+}
+
+func (x *DetailedMetricsResponse) GetMemory() *MemoryDetail {
+	if x != nil {
+		return x.Memory
+	}
+	return nil
+}
+
+func (x *DetailedMetricsResponse) GetDisk() *DiskDetail {
+	if x != nil {
+		return x.Disk
+	}
+	return nil
+}
+
+func (x *DetailedMetricsResponse) GetNetwork() *NetworkDetail {
+	if x != nil {
+		return x.Network
+	}
+	return nil
+}
+
+func (x *DetailedMetricsResponse) GetLoadAvg_1Min() float64 {
+	if x != nil {
+		return x.LoadAvg_1Min
+	}
+	return 0
+}
+
+func (x *DetailedMetricsResponse) GetLoadAvg_5Min() float64 {
+	if x != nil {
+		return x.LoadAvg_5Min
+	}
+	return 0
+}
+
+func (x *DetailedMetricsResponse) GetLoadAvg_15Min() float64 {
+	if x != nil {
+		return x.LoadAvg_15Min
+	}
+	return 0
+}
+
+func (x *DetailedMetricsResponse) GetUptimeSeconds() uint64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *DetailedMetricsResponse) GetProcessCount() int32 {
+	if x != nil {
+		return x.ProcessCount
+	}
+	return 0
+}
+
+func (x *DetailedMetricsResponse) GetThreadCount() int32 {
+	if x != nil {
+		return x.ThreadCount
+	}
+	return 0
+}
+
+func (x *DetailedMetricsResponse) GetKernelVersion() string {
+	if x != nil {
+		return x.KernelVersion
+	}
+	return ""
+}
+
+func (x *DetailedMetricsResponse) GetOsVersion() string {
+	if x != nil {
+		return x.OsVersion
+	}
+	return ""
+}
+
+// Logs are returned newest first, in pages of max_lines entries; each
+// page is in chronological order
+type RecentLogsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MaxLines       int32                  `protobuf:"varint,1,opt,name=max_lines,json=maxLines,proto3" json:"max_lines,omitempty"`                   // Entries per page, default 100
+	LevelFilter    string                 `protobuf:"bytes,2,opt,name=level_filter,json=levelFilter,proto3" json:"level_filter,omitempty"`           // error, warning, info, debug: this level or more severe
+	SourceFilter   string                 `protobuf:"bytes,3,opt,name=source_filter,json=sourceFilter,proto3" json:"source_filter,omitempty"`        // Filter by source/service
+	SinceTimestamp int64                  `protobuf:"varint,4,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"` // Get logs since this timestamp
+	UntilTimestamp int64                  `protobuf:"varint,5,opt,name=until_timestamp,json=untilTimestamp,proto3" json:"until_timestamp,omitempty"` // Get logs up to this timestamp
+	Pattern        string                 `protobuf:"bytes,6,opt,name=pattern,proto3" json:"pattern,omitempty"`                                      // Only entries whose message matches this regex
+	PageToken      string                 `protobuf:"bytes,7,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`                 // next_page_token of the previous page
+	Limit          int32                  `protobuf:"varint,8,opt,name=limit,proto3" json:"limit,omitempty"`                                         // StreamRecentLogs: entries to send in all, 0 for all
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RecentLogsRequest) Reset() {
+	*x = RecentLogsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[86]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecentLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecentLogsRequest) ProtoMessage() {}
+
+func (x *RecentLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[86]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecentLogsRequest.ProtoReflect.Descriptor instead.
+func (*RecentLogsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{86}
+}
+
+func (x *RecentLogsRequest) GetMaxLines() int32 {
+	if x != nil {
+		return x.MaxLines
+	}
+	return 0
+}
+
+func (x *RecentLogsRequest) GetLevelFilter() string {
+	if x != nil {
+		return x.LevelFilter
+	}
+	return ""
+}
+
+func (x *RecentLogsRequest) GetSourceFilter() string {
+	if x != nil {
+		return x.SourceFilter
+	}
+	return ""
+}
+
+func (x *RecentLogsRequest) GetSinceTimestamp() int64 {
+	if x != nil {
+		return x.SinceTimestamp
+	}
+	return 0
+}
+
+func (x *RecentLogsRequest) GetUntilTimestamp() int64 {
+	if x != nil {
+		return x.UntilTimestamp
+	}
+	return 0
+}
+
+func (x *RecentLogsRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *RecentLogsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *RecentLogsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type RecentLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Logs          []*LogEntry            `protobuf:"bytes,1,rep,name=logs,proto3" json:"logs,omitempty"`
+	TotalCount    int32                  `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"` // Entries in this page
+	HasMore       bool                   `protobuf:"varint,3,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,4,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Resumes after this page; empty on the last one
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecentLogsResponse) Reset() {
+	*x = RecentLogsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[87]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecentLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecentLogsResponse) ProtoMessage() {}
+
+func (x *RecentLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[87]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecentLogsResponse.ProtoReflect.Descriptor instead.
+func (*RecentLogsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{87}
+}
+
+func (x *RecentLogsResponse) GetLogs() []*LogEntry {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *RecentLogsResponse) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *RecentLogsResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+func (x *RecentLogsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type ConnectionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StateFilter   string                 `protobuf:"bytes,1,opt,name=state_filter,json=stateFilter,proto3" json:"state_filter,omitempty"`     // ESTABLISHED, LISTEN, TIME_WAIT, etc
+	IncludeLocal  bool                   `protobuf:"varint,2,opt,name=include_local,json=includeLocal,proto3" json:"include_local,omitempty"` // Include localhost connections
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConnectionsRequest) Reset() {
+	*x = ConnectionsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[88]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionsRequest) ProtoMessage() {}
+
+func (x *ConnectionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[88]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionsRequest.ProtoReflect.Descriptor instead.
+func (*ConnectionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{88}
+}
+
+func (x *ConnectionsRequest) GetStateFilter() string {
+	if x != nil {
+		return x.StateFilter
+	}
+	return ""
+}
+
+func (x *ConnectionsRequest) GetIncludeLocal() bool {
+	if x != nil {
+		return x.IncludeLocal
+	}
+	return false
+}
+
+type ConnectionInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LocalAddr     string                 `protobuf:"bytes,1,opt,name=local_addr,json=localAddr,proto3" json:"local_addr,omitempty"`
+	LocalPort     uint32                 `protobuf:"varint,2,opt,name=local_port,json=localPort,proto3" json:"local_port,omitempty"`
+	RemoteAddr    string                 `protobuf:"bytes,3,opt,name=remote_addr,json=remoteAddr,proto3" json:"remote_addr,omitempty"`
+	RemotePort    uint32                 `protobuf:"varint,4,opt,name=remote_port,json=remotePort,proto3" json:"remote_port,omitempty"`
+	State         string                 `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	Pid           int32                  `protobuf:"varint,6,opt,name=pid,proto3" json:"pid,omitempty"`
+	ProcessName   string                 `protobuf:"bytes,7,opt,name=process_name,json=processName,proto3" json:"process_name,omitempty"`
+	BytesSent     uint64                 `protobuf:"varint,8,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	BytesRecv     uint64                 `protobuf:"varint,9,opt,name=bytes_recv,json=bytesRecv,proto3" json:"bytes_recv,omitempty"`
+	EstablishedAt int64                  `protobuf:"varint,10,opt,name=established_at,json=establishedAt,proto3" json:"established_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConnectionInfo) Reset() {
+	*x = ConnectionInfo{}
+	mi := &file_proto_agent_proto_msgTypes[89]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionInfo) ProtoMessage() {}
+
+func (x *ConnectionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[89]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionInfo.ProtoReflect.Descriptor instead.
+func (*ConnectionInfo) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{89}
+}
+
+func (x *ConnectionInfo) GetLocalAddr() string {
+	if x != nil {
+		return x.LocalAddr
+	}
+	return ""
+}
+
+func (x *ConnectionInfo) GetLocalPort() uint32 {
+	if x != nil {
+		return x.LocalPort
+	}
+	return 0
+}
+
+func (x *ConnectionInfo) GetRemoteAddr() string {
+	if x != nil {
+		return x.RemoteAddr
+	}
+	return ""
+}
+
+func (x *ConnectionInfo) GetRemotePort() uint32 {
+	if x != nil {
+		return x.RemotePort
+	}
+	return 0
+}
+
+func (x *ConnectionInfo) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *ConnectionInfo) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *ConnectionInfo) GetProcessName() string {
+	if x != nil {
+		return x.ProcessName
+	}
+	return ""
+}
+
+func (x *ConnectionInfo) GetBytesSent() uint64 {
+	if x != nil {
+		return x.BytesSent
+	}
+	return 0
+}
+
+func (x *ConnectionInfo) GetBytesRecv() uint64 {
+	if x != nil {
+		return x.BytesRecv
+	}
+	return 0
+}
+
+func (x *ConnectionInfo) GetEstablishedAt() int64 {
+	if x != nil {
+		return x.EstablishedAt
+	}
+	return 0
+}
+
+type ConnectionsResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Connections      []*ConnectionInfo      `protobuf:"bytes,1,rep,name=connections,proto3" json:"connections,omitempty"`
+	TotalEstablished int32                  `protobuf:"varint,2,opt,name=total_established,json=totalEstablished,proto3" json:"total_established,omitempty"`
+	TotalListening   int32                  `protobuf:"varint,3,opt,name=total_listening,json=totalListening,proto3" json:"total_listening,omitempty"`
+	TotalTimeWait    int32                  `protobuf:"varint,4,opt,name=total_time_wait,json=totalTimeWait,proto3" json:"total_time_wait,omitempty"`
+	TotalAll         int32                  `protobuf:"varint,5,opt,name=total_all,json=totalAll,proto3" json:"total_all,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ConnectionsResponse) Reset() {
+	*x = ConnectionsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[90]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionsResponse) ProtoMessage() {}
+
+func (x *ConnectionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[90]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionsResponse.ProtoReflect.Descriptor instead.
+func (*ConnectionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{90}
+}
+
+func (x *ConnectionsResponse) GetConnections() []*ConnectionInfo {
+	if x != nil {
+		return x.Connections
+	}
+	return nil
+}
+
+func (x *ConnectionsResponse) GetTotalEstablished() int32 {
+	if x != nil {
+		return x.TotalEstablished
+	}
+	return 0
+}
+
+func (x *ConnectionsResponse) GetTotalListening() int32 {
+	if x != nil {
+		return x.TotalListening
+	}
+	return 0
+}
+
+func (x *ConnectionsResponse) GetTotalTimeWait() int32 {
+	if x != nil {
+		return x.TotalTimeWait
+	}
+	return 0
+}
+
+func (x *ConnectionsResponse) GetTotalAll() int32 {
+	if x != nil {
+		return x.TotalAll
+	}
+	return 0
+}
+
+type SystemErrorsRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	MaxErrors       int32                  `protobuf:"varint,1,opt,name=max_errors,json=maxErrors,proto3" json:"max_errors,omitempty"` // Default 50
+	SinceTimestamp  int64                  `protobuf:"varint,2,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"`
+	IncludeWarnings bool                   `protobuf:"varint,3,opt,name=include_warnings,json=includeWarnings,proto3" json:"include_warnings,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SystemErrorsRequest) Reset() {
+	*x = SystemErrorsRequest{}
+	mi := &file_proto_agent_proto_msgTypes[91]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SystemErrorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SystemErrorsRequest) ProtoMessage() {}
+
+func (x *SystemErrorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[91]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SystemErrorsRequest.ProtoReflect.Descriptor instead.
+func (*SystemErrorsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{91}
+}
+
+func (x *SystemErrorsRequest) GetMaxErrors() int32 {
+	if x != nil {
+		return x.MaxErrors
+	}
+	return 0
+}
+
+func (x *SystemErrorsRequest) GetSinceTimestamp() int64 {
+	if x != nil {
+		return x.SinceTimestamp
+	}
+	return 0
+}
+
+func (x *SystemErrorsRequest) GetIncludeWarnings() bool {
+	if x != nil {
+		return x.IncludeWarnings
+	}
+	return false
+}
+
+type SystemError struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp       int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Severity        string                 `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"` // error, warning, critical
+	Source          string                 `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	Message         string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	StackTrace      string                 `protobuf:"bytes,5,opt,name=stack_trace,json=stackTrace,proto3" json:"stack_trace,omitempty"`
+	Context         map[string]string      `protobuf:"bytes,6,rep,name=context,proto3" json:"context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	OccurrenceCount int32                  `protobuf:"varint,7,opt,name=occurrence_count,json=occurrenceCount,proto3" json:"occurrence_count,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SystemError) Reset() {
+	*x = SystemError{}
+	mi := &file_proto_agent_proto_msgTypes[92]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SystemError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SystemError) ProtoMessage() {}
+
+func (x *SystemError) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[92]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
 		}
 		return ms
 	}
-	return mi.MessageOf(x)
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SystemError.ProtoReflect.Descriptor instead.
+func (*SystemError) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{92}
+}
+
+func (x *SystemError) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *SystemError) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *SystemError) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *SystemError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
 }
 
-// Deprecated: Use MultipleAgentStatusRequest.ProtoReflect.Descriptor instead.
-func (*MultipleAgentStatusRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{61}
+func (x *SystemError) GetStackTrace() string {
+	if x != nil {
+		return x.StackTrace
+	}
+	return ""
 }
 
-func (x *MultipleAgentStatusRequest) GetAgentNames() []string {
+func (x *SystemError) GetContext() map[string]string {
 	if x != nil {
-		return x.AgentNames
+		return x.Context
 	}
 	return nil
 }
 
-func (x *MultipleAgentStatusRequest) GetGroupName() string {
+func (x *SystemError) GetOccurrenceCount() int32 {
 	if x != nil {
-		return x.GroupName
+		return x.OccurrenceCount
 	}
-	return ""
+	return 0
 }
 
-type AgentStatusInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
-	CpuPercent    float64                `protobuf:"fixed64,3,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
-	MemoryPercent float64                `protobuf:"fixed64,4,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
-	LastHeartbeat int64                  `protobuf:"varint,5,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`
-	Healthy       bool                   `protobuf:"varint,6,opt,name=healthy,proto3" json:"healthy,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type SystemErrorsResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Errors          []*SystemError         `protobuf:"bytes,1,rep,name=errors,proto3" json:"errors,omitempty"`
+	TotalErrors     int32                  `protobuf:"varint,2,opt,name=total_errors,json=totalErrors,proto3" json:"total_errors,omitempty"`
+	TotalWarnings   int32                  `protobuf:"varint,3,opt,name=total_warnings,json=totalWarnings,proto3" json:"total_warnings,omitempty"`
+	MostCommonError string                 `protobuf:"bytes,4,opt,name=most_common_error,json=mostCommonError,proto3" json:"most_common_error,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *AgentStatusInfo) Reset() {
-	*x = AgentStatusInfo{}
-	mi := &file_proto_agent_proto_msgTypes[62]
+func (x *SystemErrorsResponse) Reset() {
+	*x = SystemErrorsResponse{}
+	mi := &file_proto_agent_proto_msgTypes[93]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AgentStatusInfo) String() string {
+func (x *SystemErrorsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AgentStatusInfo) ProtoMessage() {}
+func (*SystemErrorsResponse) ProtoMessage() {}
 
-func (x *AgentStatusInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[62]
+func (x *SystemErrorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[93]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3659,75 +6131,62 @@ func (x *AgentStatusInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AgentStatusInfo.ProtoReflect.Descriptor instead.
-func (*AgentStatusInfo) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{62}
-}
-
-func (x *AgentStatusInfo) GetAgentName() string {
-	if x != nil {
-		return x.AgentName
-	}
-	return ""
-}
-
-func (x *AgentStatusInfo) GetStatus() string {
-	if x != nil {
-		return x.Status
-	}
-	return ""
+// Deprecated: Use SystemErrorsResponse.ProtoReflect.Descriptor instead.
+func (*SystemErrorsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{93}
 }
 
-func (x *AgentStatusInfo) GetCpuPercent() float64 {
+func (x *SystemErrorsResponse) GetErrors() []*SystemError {
 	if x != nil {
-		return x.CpuPercent
+		return x.Errors
 	}
-	return 0
+	return nil
 }
 
-func (x *AgentStatusInfo) GetMemoryPercent() float64 {
+func (x *SystemErrorsResponse) GetTotalErrors() int32 {
 	if x != nil {
-		return x.MemoryPercent
+		return x.TotalErrors
 	}
 	return 0
 }
 
-func (x *AgentStatusInfo) GetLastHeartbeat() int64 {
+func (x *SystemErrorsResponse) GetTotalWarnings() int32 {
 	if x != nil {
-		return x.LastHeartbeat
+		return x.TotalWarnings
 	}
 	return 0
 }
 
-func (x *AgentStatusInfo) GetHealthy() bool {
+func (x *SystemErrorsResponse) GetMostCommonError() string {
 	if x != nil {
-		return x.Healthy
+		return x.MostCommonError
 	}
-	return false
+	return ""
 }
 
-type MultipleAgentStatusResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Statuses      []*AgentStatusInfo     `protobuf:"bytes,1,rep,name=statuses,proto3" json:"statuses,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type PerformanceHistoryRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DurationMinutes int32                  `protobuf:"varint,1,opt,name=duration_minutes,json=durationMinutes,proto3" json:"duration_minutes,omitempty"` // Default 60
+	DataPoints      int32                  `protobuf:"varint,2,opt,name=data_points,json=dataPoints,proto3" json:"data_points,omitempty"`                // How many samples to return
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *MultipleAgentStatusResponse) Reset() {
-	*x = MultipleAgentStatusResponse{}
-	mi := &file_proto_agent_proto_msgTypes[63]
+func (x *PerformanceHistoryRequest) Reset() {
+	*x = PerformanceHistoryRequest{}
+	mi := &file_proto_agent_proto_msgTypes[94]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MultipleAgentStatusResponse) String() string {
+func (x *PerformanceHistoryRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MultipleAgentStatusResponse) ProtoMessage() {}
+func (*PerformanceHistoryRequest) ProtoMessage() {}
 
-func (x *MultipleAgentStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[63]
+func (x *PerformanceHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[94]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3738,42 +6197,54 @@ func (x *MultipleAgentStatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MultipleAgentStatusResponse.ProtoReflect.Descriptor instead.
-func (*MultipleAgentStatusResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{63}
+// Deprecated: Use PerformanceHistoryRequest.ProtoReflect.Descriptor instead.
+func (*PerformanceHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{94}
 }
 
-func (x *MultipleAgentStatusResponse) GetStatuses() []*AgentStatusInfo {
+func (x *PerformanceHistoryRequest) GetDurationMinutes() int32 {
 	if x != nil {
-		return x.Statuses
+		return x.DurationMinutes
 	}
-	return nil
+	return 0
 }
 
-type AggregatedMetricsRequest struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	AgentNames       []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
-	GroupName        string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	TimeRangeMinutes int32                  `protobuf:"varint,3,opt,name=time_range_minutes,json=timeRangeMinutes,proto3" json:"time_range_minutes,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *PerformanceHistoryRequest) GetDataPoints() int32 {
+	if x != nil {
+		return x.DataPoints
+	}
+	return 0
 }
 
-func (x *AggregatedMetricsRequest) Reset() {
-	*x = AggregatedMetricsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[64]
+type PerformanceSnapshot struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp             int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	CpuPercent            float64                `protobuf:"fixed64,2,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryPercent         float64                `protobuf:"fixed64,3,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	DiskPercent           float64                `protobuf:"fixed64,4,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`
+	NetworkThroughputMbps float64                `protobuf:"fixed64,5,opt,name=network_throughput_mbps,json=networkThroughputMbps,proto3" json:"network_throughput_mbps,omitempty"`
+	LoadAvg               float64                `protobuf:"fixed64,6,opt,name=load_avg,json=loadAvg,proto3" json:"load_avg,omitempty"`
+	ActiveConnections     int32                  `protobuf:"varint,7,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	ProcessCount          int32                  `protobuf:"varint,8,opt,name=process_count,json=processCount,proto3" json:"process_count,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *PerformanceSnapshot) Reset() {
+	*x = PerformanceSnapshot{}
+	mi := &file_proto_agent_proto_msgTypes[95]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AggregatedMetricsRequest) String() string {
+func (x *PerformanceSnapshot) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AggregatedMetricsRequest) ProtoMessage() {}
+func (*PerformanceSnapshot) ProtoMessage() {}
 
-func (x *AggregatedMetricsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[64]
+func (x *PerformanceSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[95]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3784,60 +6255,92 @@ func (x *AggregatedMetricsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AggregatedMetricsRequest.ProtoReflect.Descriptor instead.
-func (*AggregatedMetricsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{64}
+// Deprecated: Use PerformanceSnapshot.ProtoReflect.Descriptor instead.
+func (*PerformanceSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{95}
 }
 
-func (x *AggregatedMetricsRequest) GetAgentNames() []string {
+func (x *PerformanceSnapshot) GetTimestamp() int64 {
 	if x != nil {
-		return x.AgentNames
+		return x.Timestamp
 	}
-	return nil
+	return 0
 }
 
-func (x *AggregatedMetricsRequest) GetGroupName() string {
+func (x *PerformanceSnapshot) GetCpuPercent() float64 {
 	if x != nil {
-		return x.GroupName
+		return x.CpuPercent
 	}
-	return ""
+	return 0
 }
 
-func (x *AggregatedMetricsRequest) GetTimeRangeMinutes() int32 {
+func (x *PerformanceSnapshot) GetMemoryPercent() float64 {
 	if x != nil {
-		return x.TimeRangeMinutes
+		return x.MemoryPercent
 	}
 	return 0
 }
 
-type AggregatedMetricsResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	AvgCpuPercent    float64                `protobuf:"fixed64,1,opt,name=avg_cpu_percent,json=avgCpuPercent,proto3" json:"avg_cpu_percent,omitempty"`
-	AvgMemoryPercent float64                `protobuf:"fixed64,2,opt,name=avg_memory_percent,json=avgMemoryPercent,proto3" json:"avg_memory_percent,omitempty"`
-	AvgDiskPercent   float64                `protobuf:"fixed64,3,opt,name=avg_disk_percent,json=avgDiskPercent,proto3" json:"avg_disk_percent,omitempty"`
-	TotalAgents      int32                  `protobuf:"varint,4,opt,name=total_agents,json=totalAgents,proto3" json:"total_agents,omitempty"`
-	HealthyAgents    int32                  `protobuf:"varint,5,opt,name=healthy_agents,json=healthyAgents,proto3" json:"healthy_agents,omitempty"`
-	UnhealthyAgents  int32                  `protobuf:"varint,6,opt,name=unhealthy_agents,json=unhealthyAgents,proto3" json:"unhealthy_agents,omitempty"`
-	CustomMetrics    map[string]float64     `protobuf:"bytes,7,rep,name=custom_metrics,json=customMetrics,proto3" json:"custom_metrics,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+func (x *PerformanceSnapshot) GetDiskPercent() float64 {
+	if x != nil {
+		return x.DiskPercent
+	}
+	return 0
 }
 
-func (x *AggregatedMetricsResponse) Reset() {
-	*x = AggregatedMetricsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[65]
+func (x *PerformanceSnapshot) GetNetworkThroughputMbps() float64 {
+	if x != nil {
+		return x.NetworkThroughputMbps
+	}
+	return 0
+}
+
+func (x *PerformanceSnapshot) GetLoadAvg() float64 {
+	if x != nil {
+		return x.LoadAvg
+	}
+	return 0
+}
+
+func (x *PerformanceSnapshot) GetActiveConnections() int32 {
+	if x != nil {
+		return x.ActiveConnections
+	}
+	return 0
+}
+
+func (x *PerformanceSnapshot) GetProcessCount() int32 {
+	if x != nil {
+		return x.ProcessCount
+	}
+	return 0
+}
+
+type PerformanceHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snapshots     []*PerformanceSnapshot `protobuf:"bytes,1,rep,name=snapshots,proto3" json:"snapshots,omitempty"`
+	Avg           *PerformanceSnapshot   `protobuf:"bytes,2,opt,name=avg,proto3" json:"avg,omitempty"`
+	Min           *PerformanceSnapshot   `protobuf:"bytes,3,opt,name=min,proto3" json:"min,omitempty"`
+	Max           *PerformanceSnapshot   `protobuf:"bytes,4,opt,name=max,proto3" json:"max,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PerformanceHistoryResponse) Reset() {
+	*x = PerformanceHistoryResponse{}
+	mi := &file_proto_agent_proto_msgTypes[96]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AggregatedMetricsResponse) String() string {
+func (x *PerformanceHistoryResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AggregatedMetricsResponse) ProtoMessage() {}
+func (*PerformanceHistoryResponse) ProtoMessage() {}
 
-func (x *AggregatedMetricsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[65]
+func (x *PerformanceHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[96]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3848,84 +6351,62 @@ func (x *AggregatedMetricsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AggregatedMetricsResponse.ProtoReflect.Descriptor instead.
-func (*AggregatedMetricsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{65}
-}
-
-func (x *AggregatedMetricsResponse) GetAvgCpuPercent() float64 {
-	if x != nil {
-		return x.AvgCpuPercent
-	}
-	return 0
-}
-
-func (x *AggregatedMetricsResponse) GetAvgMemoryPercent() float64 {
-	if x != nil {
-		return x.AvgMemoryPercent
-	}
-	return 0
-}
-
-func (x *AggregatedMetricsResponse) GetAvgDiskPercent() float64 {
-	if x != nil {
-		return x.AvgDiskPercent
-	}
-	return 0
+// Deprecated: Use PerformanceHistoryResponse.ProtoReflect.Descriptor instead.
+func (*PerformanceHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{96}
 }
 
-func (x *AggregatedMetricsResponse) GetTotalAgents() int32 {
+func (x *PerformanceHistoryResponse) GetSnapshots() []*PerformanceSnapshot {
 	if x != nil {
-		return x.TotalAgents
+		return x.Snapshots
 	}
-	return 0
+	return nil
 }
 
-func (x *AggregatedMetricsResponse) GetHealthyAgents() int32 {
+func (x *PerformanceHistoryResponse) GetAvg() *PerformanceSnapshot {
 	if x != nil {
-		return x.HealthyAgents
+		return x.Avg
 	}
-	return 0
+	return nil
 }
 
-func (x *AggregatedMetricsResponse) GetUnhealthyAgents() int32 {
+func (x *PerformanceHistoryResponse) GetMin() *PerformanceSnapshot {
 	if x != nil {
-		return x.UnhealthyAgents
+		return x.Min
 	}
-	return 0
+	return nil
 }
 
-func (x *AggregatedMetricsResponse) GetCustomMetrics() map[string]float64 {
+func (x *PerformanceHistoryResponse) GetMax() *PerformanceSnapshot {
 	if x != nil {
-		return x.CustomMetrics
+		return x.Max
 	}
 	return nil
 }
 
-type StreamEventsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentNames    []string               `protobuf:"bytes,1,rep,name=agent_names,json=agentNames,proto3" json:"agent_names,omitempty"`
-	GroupName     string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	EventTypes    []string               `protobuf:"bytes,3,rep,name=event_types,json=eventTypes,proto3" json:"event_types,omitempty"` // connected, disconnected, error, etc
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type HealthDiagnosticRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	IncludeSuggestions bool                   `protobuf:"varint,1,opt,name=include_suggestions,json=includeSuggestions,proto3" json:"include_suggestions,omitempty"`
+	DeepCheck          bool                   `protobuf:"varint,2,opt,name=deep_check,json=deepCheck,proto3" json:"deep_check,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
-func (x *StreamEventsRequest) Reset() {
-	*x = StreamEventsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[66]
+func (x *HealthDiagnosticRequest) Reset() {
+	*x = HealthDiagnosticRequest{}
+	mi := &file_proto_agent_proto_msgTypes[97]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StreamEventsRequest) String() string {
+func (x *HealthDiagnosticRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StreamEventsRequest) ProtoMessage() {}
+func (*HealthDiagnosticRequest) ProtoMessage() {}
 
-func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[66]
+func (x *HealthDiagnosticRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[97]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3936,59 +6417,53 @@ func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
-func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{66}
-}
-
-func (x *StreamEventsRequest) GetAgentNames() []string {
-	if x != nil {
-		return x.AgentNames
-	}
-	return nil
+// Deprecated: Use HealthDiagnosticRequest.ProtoReflect.Descriptor instead.
+func (*HealthDiagnosticRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{97}
 }
 
-func (x *StreamEventsRequest) GetGroupName() string {
+func (x *HealthDiagnosticRequest) GetIncludeSuggestions() bool {
 	if x != nil {
-		return x.GroupName
+		return x.IncludeSuggestions
 	}
-	return ""
+	return false
 }
 
-func (x *StreamEventsRequest) GetEventTypes() []string {
+func (x *HealthDiagnosticRequest) GetDeepCheck() bool {
 	if x != nil {
-		return x.EventTypes
+		return x.DeepCheck
 	}
-	return nil
+	return false
 }
 
-type AgentEvent struct {
+type HealthIssue struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
-	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
-	Metadata      map[string]string      `protobuf:"bytes,5,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Severity      string                 `protobuf:"bytes,6,opt,name=severity,proto3" json:"severity,omitempty"` // info, warning, error, critical
+	Category      string                 `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"` // cpu, memory, disk, network, process
+	Severity      string                 `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"` // info, warning, critical
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	CurrentValue  string                 `protobuf:"bytes,4,opt,name=current_value,json=currentValue,proto3" json:"current_value,omitempty"`
+	Threshold     string                 `protobuf:"bytes,5,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Suggestions   []string               `protobuf:"bytes,6,rep,name=suggestions,proto3" json:"suggestions,omitempty"`
+	AutoFixable   bool                   `protobuf:"varint,7,opt,name=auto_fixable,json=autoFixable,proto3" json:"auto_fixable,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AgentEvent) Reset() {
-	*x = AgentEvent{}
-	mi := &file_proto_agent_proto_msgTypes[67]
+func (x *HealthIssue) Reset() {
+	*x = HealthIssue{}
+	mi := &file_proto_agent_proto_msgTypes[98]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AgentEvent) String() string {
+func (x *HealthIssue) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AgentEvent) ProtoMessage() {}
+func (*HealthIssue) ProtoMessage() {}
 
-func (x *AgentEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[67]
+func (x *HealthIssue) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[98]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3999,118 +6474,88 @@ func (x *AgentEvent) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AgentEvent.ProtoReflect.Descriptor instead.
-func (*AgentEvent) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{67}
+// Deprecated: Use HealthIssue.ProtoReflect.Descriptor instead.
+func (*HealthIssue) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{98}
 }
 
-func (x *AgentEvent) GetAgentName() string {
+func (x *HealthIssue) GetCategory() string {
 	if x != nil {
-		return x.AgentName
+		return x.Category
 	}
 	return ""
 }
 
-func (x *AgentEvent) GetEventType() string {
+func (x *HealthIssue) GetSeverity() string {
 	if x != nil {
-		return x.EventType
+		return x.Severity
 	}
 	return ""
 }
 
-func (x *AgentEvent) GetTimestamp() int64 {
+func (x *HealthIssue) GetDescription() string {
 	if x != nil {
-		return x.Timestamp
+		return x.Description
 	}
-	return 0
+	return ""
 }
 
-func (x *AgentEvent) GetMessage() string {
+func (x *HealthIssue) GetCurrentValue() string {
 	if x != nil {
-		return x.Message
+		return x.CurrentValue
 	}
 	return ""
 }
 
-func (x *AgentEvent) GetMetadata() map[string]string {
+func (x *HealthIssue) GetThreshold() string {
 	if x != nil {
-		return x.Metadata
+		return x.Threshold
 	}
-	return nil
+	return ""
 }
 
-func (x *AgentEvent) GetSeverity() string {
+func (x *HealthIssue) GetSuggestions() []string {
 	if x != nil {
-		return x.Severity
+		return x.Suggestions
 	}
-	return ""
-}
-
-type DetailedMetricsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *DetailedMetricsRequest) Reset() {
-	*x = DetailedMetricsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[68]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *DetailedMetricsRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+	return nil
 }
 
-func (*DetailedMetricsRequest) ProtoMessage() {}
-
-func (x *DetailedMetricsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[68]
+func (x *HealthIssue) GetAutoFixable() bool {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.AutoFixable
 	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use DetailedMetricsRequest.ProtoReflect.Descriptor instead.
-func (*DetailedMetricsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{68}
+	return false
 }
 
-type CPUDetail struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	CoreCount     int32                  `protobuf:"varint,1,opt,name=core_count,json=coreCount,proto3" json:"core_count,omitempty"`
-	PerCoreUsage  []float64              `protobuf:"fixed64,2,rep,packed,name=per_core_usage,json=perCoreUsage,proto3" json:"per_core_usage,omitempty"`
-	UserTime      float64                `protobuf:"fixed64,3,opt,name=user_time,json=userTime,proto3" json:"user_time,omitempty"`
-	SystemTime    float64                `protobuf:"fixed64,4,opt,name=system_time,json=systemTime,proto3" json:"system_time,omitempty"`
-	IdleTime      float64                `protobuf:"fixed64,5,opt,name=idle_time,json=idleTime,proto3" json:"idle_time,omitempty"`
-	IowaitTime    float64                `protobuf:"fixed64,6,opt,name=iowait_time,json=iowaitTime,proto3" json:"iowait_time,omitempty"`
-	ModelName     string                 `protobuf:"bytes,7,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
-	Mhz           float64                `protobuf:"fixed64,8,opt,name=mhz,proto3" json:"mhz,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type HealthDiagnosticResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	OverallStatus  string                 `protobuf:"bytes,1,opt,name=overall_status,json=overallStatus,proto3" json:"overall_status,omitempty"` // healthy, degraded, unhealthy
+	HealthScore    int32                  `protobuf:"varint,2,opt,name=health_score,json=healthScore,proto3" json:"health_score,omitempty"`      // 0-100
+	Issues         []*HealthIssue         `protobuf:"bytes,3,rep,name=issues,proto3" json:"issues,omitempty"`
+	Summary        map[string]string      `protobuf:"bytes,4,rep,name=summary,proto3" json:"summary,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CheckTimestamp int64                  `protobuf:"varint,5,opt,name=check_timestamp,json=checkTimestamp,proto3" json:"check_timestamp,omitempty"`
+	TotalWarnings  int32                  `protobuf:"varint,6,opt,name=total_warnings,json=totalWarnings,proto3" json:"total_warnings,omitempty"`
+	TotalErrors    int32                  `protobuf:"varint,7,opt,name=total_errors,json=totalErrors,proto3" json:"total_errors,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *CPUDetail) Reset() {
-	*x = CPUDetail{}
-	mi := &file_proto_agent_proto_msgTypes[69]
+func (x *HealthDiagnosticResponse) Reset() {
+	*x = HealthDiagnosticResponse{}
+	mi := &file_proto_agent_proto_msgTypes[99]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CPUDetail) String() string {
+func (x *HealthDiagnosticResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CPUDetail) ProtoMessage() {}
+func (*HealthDiagnosticResponse) ProtoMessage() {}
 
-func (x *CPUDetail) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[69]
+func (x *HealthDiagnosticResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[99]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4121,99 +6566,83 @@ func (x *CPUDetail) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CPUDetail.ProtoReflect.Descriptor instead.
-func (*CPUDetail) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{69}
-}
-
-func (x *CPUDetail) GetCoreCount() int32 {
-	if x != nil {
-		return x.CoreCount
-	}
-	return 0
+// Deprecated: Use HealthDiagnosticResponse.ProtoReflect.Descriptor instead.
+func (*HealthDiagnosticResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{99}
 }
 
-func (x *CPUDetail) GetPerCoreUsage() []float64 {
+func (x *HealthDiagnosticResponse) GetOverallStatus() string {
 	if x != nil {
-		return x.PerCoreUsage
+		return x.OverallStatus
 	}
-	return nil
+	return ""
 }
 
-func (x *CPUDetail) GetUserTime() float64 {
+func (x *HealthDiagnosticResponse) GetHealthScore() int32 {
 	if x != nil {
-		return x.UserTime
+		return x.HealthScore
 	}
 	return 0
 }
 
-func (x *CPUDetail) GetSystemTime() float64 {
+func (x *HealthDiagnosticResponse) GetIssues() []*HealthIssue {
 	if x != nil {
-		return x.SystemTime
+		return x.Issues
 	}
-	return 0
+	return nil
 }
 
-func (x *CPUDetail) GetIdleTime() float64 {
+func (x *HealthDiagnosticResponse) GetSummary() map[string]string {
 	if x != nil {
-		return x.IdleTime
+		return x.Summary
 	}
-	return 0
+	return nil
 }
 
-func (x *CPUDetail) GetIowaitTime() float64 {
+func (x *HealthDiagnosticResponse) GetCheckTimestamp() int64 {
 	if x != nil {
-		return x.IowaitTime
+		return x.CheckTimestamp
 	}
 	return 0
 }
 
-func (x *CPUDetail) GetModelName() string {
+func (x *HealthDiagnosticResponse) GetTotalWarnings() int32 {
 	if x != nil {
-		return x.ModelName
+		return x.TotalWarnings
 	}
-	return ""
+	return 0
 }
 
-func (x *CPUDetail) GetMhz() float64 {
+func (x *HealthDiagnosticResponse) GetTotalErrors() int32 {
 	if x != nil {
-		return x.Mhz
+		return x.TotalErrors
 	}
 	return 0
 }
 
-type MemoryDetail struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	TotalBytes     uint64                 `protobuf:"varint,1,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
-	AvailableBytes uint64                 `protobuf:"varint,2,opt,name=available_bytes,json=availableBytes,proto3" json:"available_bytes,omitempty"`
-	UsedBytes      uint64                 `protobuf:"varint,3,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
-	FreeBytes      uint64                 `protobuf:"varint,4,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`
-	CachedBytes    uint64                 `protobuf:"varint,5,opt,name=cached_bytes,json=cachedBytes,proto3" json:"cached_bytes,omitempty"`
-	BuffersBytes   uint64                 `protobuf:"varint,6,opt,name=buffers_bytes,json=buffersBytes,proto3" json:"buffers_bytes,omitempty"`
-	SwapTotalBytes uint64                 `protobuf:"varint,7,opt,name=swap_total_bytes,json=swapTotalBytes,proto3" json:"swap_total_bytes,omitempty"`
-	SwapUsedBytes  uint64                 `protobuf:"varint,8,opt,name=swap_used_bytes,json=swapUsedBytes,proto3" json:"swap_used_bytes,omitempty"`
-	SwapFreeBytes  uint64                 `protobuf:"varint,9,opt,name=swap_free_bytes,json=swapFreeBytes,proto3" json:"swap_free_bytes,omitempty"`
-	Percent        float64                `protobuf:"fixed64,10,opt,name=percent,proto3" json:"percent,omitempty"`
-	SwapPercent    float64                `protobuf:"fixed64,11,opt,name=swap_percent,json=swapPercent,proto3" json:"swap_percent,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+// Toolchain Verification Messages
+type VerifyToolchainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Modules       []string               `protobuf:"bytes,1,rep,name=modules,proto3" json:"modules,omitempty"` // Modules to check; every known module when empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MemoryDetail) Reset() {
-	*x = MemoryDetail{}
-	mi := &file_proto_agent_proto_msgTypes[70]
+func (x *VerifyToolchainRequest) Reset() {
+	*x = VerifyToolchainRequest{}
+	mi := &file_proto_agent_proto_msgTypes[100]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MemoryDetail) String() string {
+func (x *VerifyToolchainRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MemoryDetail) ProtoMessage() {}
+func (*VerifyToolchainRequest) ProtoMessage() {}
 
-func (x *MemoryDetail) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[70]
+func (x *VerifyToolchainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[100]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4224,116 +6653,118 @@ func (x *MemoryDetail) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MemoryDetail.ProtoReflect.Descriptor instead.
-func (*MemoryDetail) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{70}
+// Deprecated: Use VerifyToolchainRequest.ProtoReflect.Descriptor instead.
+func (*VerifyToolchainRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{100}
 }
 
-func (x *MemoryDetail) GetTotalBytes() uint64 {
+func (x *VerifyToolchainRequest) GetModules() []string {
 	if x != nil {
-		return x.TotalBytes
+		return x.Modules
 	}
-	return 0
+	return nil
 }
 
-func (x *MemoryDetail) GetAvailableBytes() uint64 {
-	if x != nil {
-		return x.AvailableBytes
-	}
-	return 0
+type ToolStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"` // Empty when the tool isn't installed
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Functional    bool                   `protobuf:"varint,4,opt,name=functional,proto3" json:"functional,omitempty"` // The tool ran, and reached its daemon if it has one
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MemoryDetail) GetUsedBytes() uint64 {
-	if x != nil {
-		return x.UsedBytes
-	}
-	return 0
+func (x *ToolStatus) Reset() {
+	*x = ToolStatus{}
+	mi := &file_proto_agent_proto_msgTypes[101]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *MemoryDetail) GetFreeBytes() uint64 {
-	if x != nil {
-		return x.FreeBytes
-	}
-	return 0
+func (x *ToolStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *MemoryDetail) GetCachedBytes() uint64 {
+func (*ToolStatus) ProtoMessage() {}
+
+func (x *ToolStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[101]
 	if x != nil {
-		return x.CachedBytes
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *MemoryDetail) GetBuffersBytes() uint64 {
-	if x != nil {
-		return x.BuffersBytes
-	}
-	return 0
+// Deprecated: Use ToolStatus.ProtoReflect.Descriptor instead.
+func (*ToolStatus) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{101}
 }
 
-func (x *MemoryDetail) GetSwapTotalBytes() uint64 {
+func (x *ToolStatus) GetName() string {
 	if x != nil {
-		return x.SwapTotalBytes
+		return x.Name
 	}
-	return 0
+	return ""
 }
 
-func (x *MemoryDetail) GetSwapUsedBytes() uint64 {
+func (x *ToolStatus) GetPath() string {
 	if x != nil {
-		return x.SwapUsedBytes
+		return x.Path
 	}
-	return 0
+	return ""
 }
 
-func (x *MemoryDetail) GetSwapFreeBytes() uint64 {
+func (x *ToolStatus) GetVersion() string {
 	if x != nil {
-		return x.SwapFreeBytes
+		return x.Version
 	}
-	return 0
+	return ""
 }
 
-func (x *MemoryDetail) GetPercent() float64 {
+func (x *ToolStatus) GetFunctional() bool {
 	if x != nil {
-		return x.Percent
+		return x.Functional
 	}
-	return 0
+	return false
 }
 
-func (x *MemoryDetail) GetSwapPercent() float64 {
+func (x *ToolStatus) GetError() string {
 	if x != nil {
-		return x.SwapPercent
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-type DiskDetail struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Partitions      []*DiskPartition       `protobuf:"bytes,1,rep,name=partitions,proto3" json:"partitions,omitempty"`
-	ReadBytesTotal  uint64                 `protobuf:"varint,2,opt,name=read_bytes_total,json=readBytesTotal,proto3" json:"read_bytes_total,omitempty"`
-	WriteBytesTotal uint64                 `protobuf:"varint,3,opt,name=write_bytes_total,json=writeBytesTotal,proto3" json:"write_bytes_total,omitempty"`
-	ReadCount       uint64                 `protobuf:"varint,4,opt,name=read_count,json=readCount,proto3" json:"read_count,omitempty"`
-	WriteCount      uint64                 `protobuf:"varint,5,opt,name=write_count,json=writeCount,proto3" json:"write_count,omitempty"`
-	ReadTimeMs      uint64                 `protobuf:"varint,6,opt,name=read_time_ms,json=readTimeMs,proto3" json:"read_time_ms,omitempty"`
-	WriteTimeMs     uint64                 `protobuf:"varint,7,opt,name=write_time_ms,json=writeTimeMs,proto3" json:"write_time_ms,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type ModuleCapability struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Module        string                 `protobuf:"bytes,1,opt,name=module,proto3" json:"module,omitempty"`
+	Available     bool                   `protobuf:"varint,2,opt,name=available,proto3" json:"available,omitempty"` // At least one of the module's tools is functional
+	Tools         []*ToolStatus          `protobuf:"bytes,3,rep,name=tools,proto3" json:"tools,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DiskDetail) Reset() {
-	*x = DiskDetail{}
-	mi := &file_proto_agent_proto_msgTypes[71]
+func (x *ModuleCapability) Reset() {
+	*x = ModuleCapability{}
+	mi := &file_proto_agent_proto_msgTypes[102]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DiskDetail) String() string {
+func (x *ModuleCapability) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DiskDetail) ProtoMessage() {}
+func (*ModuleCapability) ProtoMessage() {}
 
-func (x *DiskDetail) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[71]
+func (x *ModuleCapability) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[102]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4344,92 +6775,132 @@ func (x *DiskDetail) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DiskDetail.ProtoReflect.Descriptor instead.
-func (*DiskDetail) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{71}
+// Deprecated: Use ModuleCapability.ProtoReflect.Descriptor instead.
+func (*ModuleCapability) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{102}
 }
 
-func (x *DiskDetail) GetPartitions() []*DiskPartition {
+func (x *ModuleCapability) GetModule() string {
 	if x != nil {
-		return x.Partitions
+		return x.Module
 	}
-	return nil
+	return ""
 }
 
-func (x *DiskDetail) GetReadBytesTotal() uint64 {
+func (x *ModuleCapability) GetAvailable() bool {
 	if x != nil {
-		return x.ReadBytesTotal
+		return x.Available
 	}
-	return 0
+	return false
 }
 
-func (x *DiskDetail) GetWriteBytesTotal() uint64 {
+func (x *ModuleCapability) GetTools() []*ToolStatus {
 	if x != nil {
-		return x.WriteBytesTotal
+		return x.Tools
 	}
-	return 0
+	return nil
 }
 
-func (x *DiskDetail) GetReadCount() uint64 {
+type VerifyToolchainResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Modules        []*ModuleCapability    `protobuf:"bytes,1,rep,name=modules,proto3" json:"modules,omitempty"`
+	Os             string                 `protobuf:"bytes,2,opt,name=os,proto3" json:"os,omitempty"`
+	Arch           string                 `protobuf:"bytes,3,opt,name=arch,proto3" json:"arch,omitempty"`
+	CheckTimestamp int64                  `protobuf:"varint,4,opt,name=check_timestamp,json=checkTimestamp,proto3" json:"check_timestamp,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *VerifyToolchainResponse) Reset() {
+	*x = VerifyToolchainResponse{}
+	mi := &file_proto_agent_proto_msgTypes[103]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyToolchainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyToolchainResponse) ProtoMessage() {}
+
+func (x *VerifyToolchainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[103]
 	if x != nil {
-		return x.ReadCount
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *DiskDetail) GetWriteCount() uint64 {
+// Deprecated: Use VerifyToolchainResponse.ProtoReflect.Descriptor instead.
+func (*VerifyToolchainResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{103}
+}
+
+func (x *VerifyToolchainResponse) GetModules() []*ModuleCapability {
 	if x != nil {
-		return x.WriteCount
+		return x.Modules
 	}
-	return 0
+	return nil
 }
 
-func (x *DiskDetail) GetReadTimeMs() uint64 {
+func (x *VerifyToolchainResponse) GetOs() string {
 	if x != nil {
-		return x.ReadTimeMs
+		return x.Os
 	}
-	return 0
+	return ""
 }
 
-func (x *DiskDetail) GetWriteTimeMs() uint64 {
+func (x *VerifyToolchainResponse) GetArch() string {
 	if x != nil {
-		return x.WriteTimeMs
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *VerifyToolchainResponse) GetCheckTimestamp() int64 {
+	if x != nil {
+		return x.CheckTimestamp
 	}
 	return 0
 }
 
-type NetworkDetail struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	Interfaces        []*NetworkInterface    `protobuf:"bytes,1,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
-	BytesSentTotal    uint64                 `protobuf:"varint,2,opt,name=bytes_sent_total,json=bytesSentTotal,proto3" json:"bytes_sent_total,omitempty"`
-	BytesRecvTotal    uint64                 `protobuf:"varint,3,opt,name=bytes_recv_total,json=bytesRecvTotal,proto3" json:"bytes_recv_total,omitempty"`
-	PacketsSentTotal  uint64                 `protobuf:"varint,4,opt,name=packets_sent_total,json=packetsSentTotal,proto3" json:"packets_sent_total,omitempty"`
-	PacketsRecvTotal  uint64                 `protobuf:"varint,5,opt,name=packets_recv_total,json=packetsRecvTotal,proto3" json:"packets_recv_total,omitempty"`
-	ErrorsIn          uint64                 `protobuf:"varint,6,opt,name=errors_in,json=errorsIn,proto3" json:"errors_in,omitempty"`
-	ErrorsOut         uint64                 `protobuf:"varint,7,opt,name=errors_out,json=errorsOut,proto3" json:"errors_out,omitempty"`
-	DropsIn           uint64                 `protobuf:"varint,8,opt,name=drops_in,json=dropsIn,proto3" json:"drops_in,omitempty"`
-	DropsOut          uint64                 `protobuf:"varint,9,opt,name=drops_out,json=dropsOut,proto3" json:"drops_out,omitempty"`
-	ActiveConnections int32                  `protobuf:"varint,10,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
-	ListeningPorts    int32                  `protobuf:"varint,11,opt,name=listening_ports,json=listeningPorts,proto3" json:"listening_ports,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+// Interactive Shell Messages
+type ShellInput struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Command            string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`                                                    // Command to execute
+	StdinData          []byte                 `protobuf:"bytes,2,opt,name=stdin_data,json=stdinData,proto3" json:"stdin_data,omitempty"`                               // Input data for interactive commands
+	Terminate          bool                   `protobuf:"varint,3,opt,name=terminate,proto3" json:"terminate,omitempty"`                                               // Signal to terminate the shell session
+	WindowRows         uint32                 `protobuf:"varint,4,opt,name=window_rows,json=windowRows,proto3" json:"window_rows,omitempty"`                           // Terminal window height
+	WindowCols         uint32                 `protobuf:"varint,5,opt,name=window_cols,json=windowCols,proto3" json:"window_cols,omitempty"`                           // Terminal window width
+	Resize             bool                   `protobuf:"varint,6,opt,name=resize,proto3" json:"resize,omitempty"`                                                     // Signal that this is a window resize message
+	Record             bool                   `protobuf:"varint,7,opt,name=record,proto3" json:"record,omitempty"`                                                     // Record the session to a cast file on the agent (first message only)
+	IdleTimeoutSeconds uint32                 `protobuf:"varint,8,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"` // End the session after this long without input, 0 to never (first message only)
+	ReadOnly           bool                   `protobuf:"varint,9,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`                                 // Observe a running session instead of starting one (first message only)
+	SessionId          string                 `protobuf:"bytes,10,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                              // Session to observe with read_only, empty for the only running one
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
-func (x *NetworkDetail) Reset() {
-	*x = NetworkDetail{}
-	mi := &file_proto_agent_proto_msgTypes[72]
+func (x *ShellInput) Reset() {
+	*x = ShellInput{}
+	mi := &file_proto_agent_proto_msgTypes[104]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NetworkDetail) String() string {
+func (x *ShellInput) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NetworkDetail) ProtoMessage() {}
+func (*ShellInput) ProtoMessage() {}
 
-func (x *NetworkDetail) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[72]
+func (x *ShellInput) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[104]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4437,125 +6908,114 @@ func (x *NetworkDetail) ProtoReflect() protoreflect.Message {
 		}
 		return ms
 	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use NetworkDetail.ProtoReflect.Descriptor instead.
-func (*NetworkDetail) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{72}
+	return mi.MessageOf(x)
 }
 
-func (x *NetworkDetail) GetInterfaces() []*NetworkInterface {
-	if x != nil {
-		return x.Interfaces
-	}
-	return nil
+// Deprecated: Use ShellInput.ProtoReflect.Descriptor instead.
+func (*ShellInput) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{104}
 }
 
-func (x *NetworkDetail) GetBytesSentTotal() uint64 {
+func (x *ShellInput) GetCommand() string {
 	if x != nil {
-		return x.BytesSentTotal
+		return x.Command
 	}
-	return 0
+	return ""
 }
 
-func (x *NetworkDetail) GetBytesRecvTotal() uint64 {
+func (x *ShellInput) GetStdinData() []byte {
 	if x != nil {
-		return x.BytesRecvTotal
+		return x.StdinData
 	}
-	return 0
+	return nil
 }
 
-func (x *NetworkDetail) GetPacketsSentTotal() uint64 {
+func (x *ShellInput) GetTerminate() bool {
 	if x != nil {
-		return x.PacketsSentTotal
+		return x.Terminate
 	}
-	return 0
+	return false
 }
 
-func (x *NetworkDetail) GetPacketsRecvTotal() uint64 {
+func (x *ShellInput) GetWindowRows() uint32 {
 	if x != nil {
-		return x.PacketsRecvTotal
+		return x.WindowRows
 	}
 	return 0
 }
 
-func (x *NetworkDetail) GetErrorsIn() uint64 {
+func (x *ShellInput) GetWindowCols() uint32 {
 	if x != nil {
-		return x.ErrorsIn
+		return x.WindowCols
 	}
 	return 0
 }
 
-func (x *NetworkDetail) GetErrorsOut() uint64 {
+func (x *ShellInput) GetResize() bool {
 	if x != nil {
-		return x.ErrorsOut
+		return x.Resize
 	}
-	return 0
+	return false
 }
 
-func (x *NetworkDetail) GetDropsIn() uint64 {
+func (x *ShellInput) GetRecord() bool {
 	if x != nil {
-		return x.DropsIn
+		return x.Record
 	}
-	return 0
+	return false
 }
 
-func (x *NetworkDetail) GetDropsOut() uint64 {
+func (x *ShellInput) GetIdleTimeoutSeconds() uint32 {
 	if x != nil {
-		return x.DropsOut
+		return x.IdleTimeoutSeconds
 	}
 	return 0
 }
 
-func (x *NetworkDetail) GetActiveConnections() int32 {
+func (x *ShellInput) GetReadOnly() bool {
 	if x != nil {
-		return x.ActiveConnections
+		return x.ReadOnly
 	}
-	return 0
+	return false
 }
 
-func (x *NetworkDetail) GetListeningPorts() int32 {
+func (x *ShellInput) GetSessionId() string {
 	if x != nil {
-		return x.ListeningPorts
+		return x.SessionId
 	}
-	return 0
+	return ""
 }
 
-type DetailedMetricsResponse struct {
+type ShellOutput struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Cpu           *CPUDetail             `protobuf:"bytes,2,opt,name=cpu,proto3" json:"cpu,omitempty"`
-	Memory        *MemoryDetail          `protobuf:"bytes,3,opt,name=memory,proto3" json:"memory,omitempty"`
-	Disk          *DiskDetail            `protobuf:"bytes,4,opt,name=disk,proto3" json:"disk,omitempty"`
-	Network       *NetworkDetail         `protobuf:"bytes,5,opt,name=network,proto3" json:"network,omitempty"`
-	LoadAvg_1Min  float64                `protobuf:"fixed64,6,opt,name=load_avg_1min,json=loadAvg1min,proto3" json:"load_avg_1min,omitempty"`
-	LoadAvg_5Min  float64                `protobuf:"fixed64,7,opt,name=load_avg_5min,json=loadAvg5min,proto3" json:"load_avg_5min,omitempty"`
-	LoadAvg_15Min float64                `protobuf:"fixed64,8,opt,name=load_avg_15min,json=loadAvg15min,proto3" json:"load_avg_15min,omitempty"`
-	UptimeSeconds uint64                 `protobuf:"varint,9,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
-	ProcessCount  int32                  `protobuf:"varint,10,opt,name=process_count,json=processCount,proto3" json:"process_count,omitempty"`
-	ThreadCount   int32                  `protobuf:"varint,11,opt,name=thread_count,json=threadCount,proto3" json:"thread_count,omitempty"`
-	KernelVersion string                 `protobuf:"bytes,12,opt,name=kernel_version,json=kernelVersion,proto3" json:"kernel_version,omitempty"`
-	OsVersion     string                 `protobuf:"bytes,13,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+	Stdout        []byte                 `protobuf:"bytes,1,opt,name=stdout,proto3" json:"stdout,omitempty"`                            // Standard output
+	Stderr        []byte                 `protobuf:"bytes,2,opt,name=stderr,proto3" json:"stderr,omitempty"`                            // Standard error
+	ExitCode      int32                  `protobuf:"varint,3,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`       // Exit code (only set when command completes)
+	Completed     bool                   `protobuf:"varint,4,opt,name=completed,proto3" json:"completed,omitempty"`                     // True when command execution is complete
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`                              // Error message if execution failed
+	SessionId     string                 `protobuf:"bytes,6,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`     // Session ID, set on the first message
+	Recording     string                 `protobuf:"bytes,7,opt,name=recording,proto3" json:"recording,omitempty"`                      // Cast file the session is recorded to on the agent, set on the first message
+	WindowRows    uint32                 `protobuf:"varint,8,opt,name=window_rows,json=windowRows,proto3" json:"window_rows,omitempty"` // Terminal size of the session, set on the first message to observers and on resizes
+	WindowCols    uint32                 `protobuf:"varint,9,opt,name=window_cols,json=windowCols,proto3" json:"window_cols,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DetailedMetricsResponse) Reset() {
-	*x = DetailedMetricsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[73]
+func (x *ShellOutput) Reset() {
+	*x = ShellOutput{}
+	mi := &file_proto_agent_proto_msgTypes[105]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DetailedMetricsResponse) String() string {
+func (x *ShellOutput) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DetailedMetricsResponse) ProtoMessage() {}
+func (*ShellOutput) ProtoMessage() {}
 
-func (x *DetailedMetricsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[73]
+func (x *ShellOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[105]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4566,194 +7026,251 @@ func (x *DetailedMetricsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DetailedMetricsResponse.ProtoReflect.Descriptor instead.
-func (*DetailedMetricsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{73}
+// Deprecated: Use ShellOutput.ProtoReflect.Descriptor instead.
+func (*ShellOutput) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{105}
 }
 
-func (x *DetailedMetricsResponse) GetTimestamp() int64 {
+func (x *ShellOutput) GetStdout() []byte {
 	if x != nil {
-		return x.Timestamp
+		return x.Stdout
 	}
-	return 0
+	return nil
 }
 
-func (x *DetailedMetricsResponse) GetCpu() *CPUDetail {
+func (x *ShellOutput) GetStderr() []byte {
 	if x != nil {
-		return x.Cpu
+		return x.Stderr
 	}
 	return nil
 }
 
-func (x *DetailedMetricsResponse) GetMemory() *MemoryDetail {
+func (x *ShellOutput) GetExitCode() int32 {
 	if x != nil {
-		return x.Memory
+		return x.ExitCode
 	}
-	return nil
+	return 0
 }
 
-func (x *DetailedMetricsResponse) GetDisk() *DiskDetail {
+func (x *ShellOutput) GetCompleted() bool {
 	if x != nil {
-		return x.Disk
+		return x.Completed
 	}
-	return nil
+	return false
 }
 
-func (x *DetailedMetricsResponse) GetNetwork() *NetworkDetail {
+func (x *ShellOutput) GetError() string {
 	if x != nil {
-		return x.Network
+		return x.Error
 	}
-	return nil
+	return ""
 }
 
-func (x *DetailedMetricsResponse) GetLoadAvg_1Min() float64 {
+func (x *ShellOutput) GetSessionId() string {
 	if x != nil {
-		return x.LoadAvg_1Min
+		return x.SessionId
 	}
-	return 0
+	return ""
 }
 
-func (x *DetailedMetricsResponse) GetLoadAvg_5Min() float64 {
+func (x *ShellOutput) GetRecording() string {
 	if x != nil {
-		return x.LoadAvg_5Min
+		return x.Recording
 	}
-	return 0
+	return ""
 }
 
-func (x *DetailedMetricsResponse) GetLoadAvg_15Min() float64 {
+func (x *ShellOutput) GetWindowRows() uint32 {
 	if x != nil {
-		return x.LoadAvg_15Min
+		return x.WindowRows
 	}
 	return 0
 }
 
-func (x *DetailedMetricsResponse) GetUptimeSeconds() uint64 {
+func (x *ShellOutput) GetWindowCols() uint32 {
 	if x != nil {
-		return x.UptimeSeconds
+		return x.WindowCols
 	}
 	return 0
 }
 
-func (x *DetailedMetricsResponse) GetProcessCount() int32 {
+// Event Reporting Messages - Agents push events to master
+type EventData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`                                                      // Unique event ID
+	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`                                                // Type of event (task.started, file.created, etc)
+	AgentName     string                 `protobuf:"bytes,3,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`                                                // Agent that generated the event
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                                // Unix timestamp
+	Stack         string                 `protobuf:"bytes,5,opt,name=stack,proto3" json:"stack,omitempty"`                                                                         // Stack name if applicable
+	RunId         string                 `protobuf:"bytes,6,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`                                                            // Run ID if applicable
+	Data          map[string]string      `protobuf:"bytes,7,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Event data as key-value pairs
+	DataJson      string                 `protobuf:"bytes,8,opt,name=data_json,json=dataJson,proto3" json:"data_json,omitempty"`                                                   // Optional: full JSON data
+	Severity      string                 `protobuf:"bytes,9,opt,name=severity,proto3" json:"severity,omitempty"`                                                                   // info, warning, error, critical
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventData) Reset() {
+	*x = EventData{}
+	mi := &file_proto_agent_proto_msgTypes[106]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventData) ProtoMessage() {}
+
+func (x *EventData) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[106]
 	if x != nil {
-		return x.ProcessCount
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *DetailedMetricsResponse) GetThreadCount() int32 {
+// Deprecated: Use EventData.ProtoReflect.Descriptor instead.
+func (*EventData) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{106}
+}
+
+func (x *EventData) GetEventId() string {
 	if x != nil {
-		return x.ThreadCount
+		return x.EventId
 	}
-	return 0
+	return ""
 }
 
-func (x *DetailedMetricsResponse) GetKernelVersion() string {
+func (x *EventData) GetEventType() string {
 	if x != nil {
-		return x.KernelVersion
+		return x.EventType
 	}
 	return ""
 }
 
-func (x *DetailedMetricsResponse) GetOsVersion() string {
+func (x *EventData) GetAgentName() string {
 	if x != nil {
-		return x.OsVersion
+		return x.AgentName
 	}
 	return ""
 }
 
-type RecentLogsRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	MaxLines       int32                  `protobuf:"varint,1,opt,name=max_lines,json=maxLines,proto3" json:"max_lines,omitempty"`                   // Default 100
-	LevelFilter    string                 `protobuf:"bytes,2,opt,name=level_filter,json=levelFilter,proto3" json:"level_filter,omitempty"`           // error, warning, info, debug
-	SourceFilter   string                 `protobuf:"bytes,3,opt,name=source_filter,json=sourceFilter,proto3" json:"source_filter,omitempty"`        // Filter by source/service
-	SinceTimestamp int64                  `protobuf:"varint,4,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"` // Get logs since this timestamp
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+func (x *EventData) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
 }
 
-func (x *RecentLogsRequest) Reset() {
-	*x = RecentLogsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[74]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *EventData) GetStack() string {
+	if x != nil {
+		return x.Stack
+	}
+	return ""
 }
 
-func (x *RecentLogsRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *EventData) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
 }
 
-func (*RecentLogsRequest) ProtoMessage() {}
-
-func (x *RecentLogsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[74]
+func (x *EventData) GetData() map[string]string {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Data
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use RecentLogsRequest.ProtoReflect.Descriptor instead.
-func (*RecentLogsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{74}
+func (x *EventData) GetDataJson() string {
+	if x != nil {
+		return x.DataJson
+	}
+	return ""
 }
 
-func (x *RecentLogsRequest) GetMaxLines() int32 {
+func (x *EventData) GetSeverity() string {
 	if x != nil {
-		return x.MaxLines
+		return x.Severity
 	}
-	return 0
+	return ""
+}
+
+type SendEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         *EventData             `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendEventRequest) Reset() {
+	*x = SendEventRequest{}
+	mi := &file_proto_agent_proto_msgTypes[107]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *RecentLogsRequest) GetLevelFilter() string {
+func (*SendEventRequest) ProtoMessage() {}
+
+func (x *SendEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[107]
 	if x != nil {
-		return x.LevelFilter
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *RecentLogsRequest) GetSourceFilter() string {
-	if x != nil {
-		return x.SourceFilter
-	}
-	return ""
+// Deprecated: Use SendEventRequest.ProtoReflect.Descriptor instead.
+func (*SendEventRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{107}
 }
 
-func (x *RecentLogsRequest) GetSinceTimestamp() int64 {
+func (x *SendEventRequest) GetEvent() *EventData {
 	if x != nil {
-		return x.SinceTimestamp
+		return x.Event
 	}
-	return 0
+	return nil
 }
 
-type RecentLogsResponse struct {
+type SendEventResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Logs          []*LogEntry            `protobuf:"bytes,1,rep,name=logs,proto3" json:"logs,omitempty"`
-	TotalCount    int32                  `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
-	HasMore       bool                   `protobuf:"varint,3,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	EventId       string                 `protobuf:"bytes,3,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"` // Confirmed event ID
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RecentLogsResponse) Reset() {
-	*x = RecentLogsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[75]
+func (x *SendEventResponse) Reset() {
+	*x = SendEventResponse{}
+	mi := &file_proto_agent_proto_msgTypes[108]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RecentLogsResponse) String() string {
+func (x *SendEventResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RecentLogsResponse) ProtoMessage() {}
+func (*SendEventResponse) ProtoMessage() {}
 
-func (x *RecentLogsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[75]
+func (x *SendEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[108]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4764,55 +7281,55 @@ func (x *RecentLogsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RecentLogsResponse.ProtoReflect.Descriptor instead.
-func (*RecentLogsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{75}
+// Deprecated: Use SendEventResponse.ProtoReflect.Descriptor instead.
+func (*SendEventResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{108}
 }
 
-func (x *RecentLogsResponse) GetLogs() []*LogEntry {
+func (x *SendEventResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Logs
+		return x.Success
 	}
-	return nil
+	return false
 }
 
-func (x *RecentLogsResponse) GetTotalCount() int32 {
+func (x *SendEventResponse) GetMessage() string {
 	if x != nil {
-		return x.TotalCount
+		return x.Message
 	}
-	return 0
+	return ""
 }
 
-func (x *RecentLogsResponse) GetHasMore() bool {
+func (x *SendEventResponse) GetEventId() string {
 	if x != nil {
-		return x.HasMore
+		return x.EventId
 	}
-	return false
+	return ""
 }
 
-type ConnectionsRequest struct {
+type SendEventBatchRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	StateFilter   string                 `protobuf:"bytes,1,opt,name=state_filter,json=stateFilter,proto3" json:"state_filter,omitempty"`     // ESTABLISHED, LISTEN, TIME_WAIT, etc
-	IncludeLocal  bool                   `protobuf:"varint,2,opt,name=include_local,json=includeLocal,proto3" json:"include_local,omitempty"` // Include localhost connections
+	Events        []*EventData           `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	BatchSize     int32                  `protobuf:"varint,2,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ConnectionsRequest) Reset() {
-	*x = ConnectionsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[76]
+func (x *SendEventBatchRequest) Reset() {
+	*x = SendEventBatchRequest{}
+	mi := &file_proto_agent_proto_msgTypes[109]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ConnectionsRequest) String() string {
+func (x *SendEventBatchRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ConnectionsRequest) ProtoMessage() {}
+func (*SendEventBatchRequest) ProtoMessage() {}
 
-func (x *ConnectionsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[76]
+func (x *SendEventBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[109]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4823,56 +7340,51 @@ func (x *ConnectionsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ConnectionsRequest.ProtoReflect.Descriptor instead.
-func (*ConnectionsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{76}
+// Deprecated: Use SendEventBatchRequest.ProtoReflect.Descriptor instead.
+func (*SendEventBatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{109}
 }
 
-func (x *ConnectionsRequest) GetStateFilter() string {
+func (x *SendEventBatchRequest) GetEvents() []*EventData {
 	if x != nil {
-		return x.StateFilter
+		return x.Events
 	}
-	return ""
+	return nil
 }
 
-func (x *ConnectionsRequest) GetIncludeLocal() bool {
+func (x *SendEventBatchRequest) GetBatchSize() int32 {
 	if x != nil {
-		return x.IncludeLocal
+		return x.BatchSize
 	}
-	return false
+	return 0
 }
 
-type ConnectionInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	LocalAddr     string                 `protobuf:"bytes,1,opt,name=local_addr,json=localAddr,proto3" json:"local_addr,omitempty"`
-	LocalPort     uint32                 `protobuf:"varint,2,opt,name=local_port,json=localPort,proto3" json:"local_port,omitempty"`
-	RemoteAddr    string                 `protobuf:"bytes,3,opt,name=remote_addr,json=remoteAddr,proto3" json:"remote_addr,omitempty"`
-	RemotePort    uint32                 `protobuf:"varint,4,opt,name=remote_port,json=remotePort,proto3" json:"remote_port,omitempty"`
-	State         string                 `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
-	Pid           int32                  `protobuf:"varint,6,opt,name=pid,proto3" json:"pid,omitempty"`
-	ProcessName   string                 `protobuf:"bytes,7,opt,name=process_name,json=processName,proto3" json:"process_name,omitempty"`
-	BytesSent     uint64                 `protobuf:"varint,8,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
-	BytesRecv     uint64                 `protobuf:"varint,9,opt,name=bytes_recv,json=bytesRecv,proto3" json:"bytes_recv,omitempty"`
-	EstablishedAt int64                  `protobuf:"varint,10,opt,name=established_at,json=establishedAt,proto3" json:"established_at,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type SendEventBatchResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	EventsReceived  int32                  `protobuf:"varint,3,opt,name=events_received,json=eventsReceived,proto3" json:"events_received,omitempty"`
+	EventsProcessed int32                  `protobuf:"varint,4,opt,name=events_processed,json=eventsProcessed,proto3" json:"events_processed,omitempty"`
+	FailedEventIds  []string               `protobuf:"bytes,5,rep,name=failed_event_ids,json=failedEventIds,proto3" json:"failed_event_ids,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *ConnectionInfo) Reset() {
-	*x = ConnectionInfo{}
-	mi := &file_proto_agent_proto_msgTypes[77]
+func (x *SendEventBatchResponse) Reset() {
+	*x = SendEventBatchResponse{}
+	mi := &file_proto_agent_proto_msgTypes[110]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ConnectionInfo) String() string {
+func (x *SendEventBatchResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ConnectionInfo) ProtoMessage() {}
+func (*SendEventBatchResponse) ProtoMessage() {}
 
-func (x *ConnectionInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[77]
+func (x *SendEventBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[110]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4883,181 +7395,226 @@ func (x *ConnectionInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ConnectionInfo.ProtoReflect.Descriptor instead.
-func (*ConnectionInfo) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{77}
+// Deprecated: Use SendEventBatchResponse.ProtoReflect.Descriptor instead.
+func (*SendEventBatchResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{110}
 }
 
-func (x *ConnectionInfo) GetLocalAddr() string {
+func (x *SendEventBatchResponse) GetSuccess() bool {
 	if x != nil {
-		return x.LocalAddr
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-func (x *ConnectionInfo) GetLocalPort() uint32 {
+func (x *SendEventBatchResponse) GetMessage() string {
 	if x != nil {
-		return x.LocalPort
+		return x.Message
 	}
-	return 0
+	return ""
 }
 
-func (x *ConnectionInfo) GetRemoteAddr() string {
+func (x *SendEventBatchResponse) GetEventsReceived() int32 {
 	if x != nil {
-		return x.RemoteAddr
+		return x.EventsReceived
 	}
-	return ""
+	return 0
 }
 
-func (x *ConnectionInfo) GetRemotePort() uint32 {
+func (x *SendEventBatchResponse) GetEventsProcessed() int32 {
 	if x != nil {
-		return x.RemotePort
+		return x.EventsProcessed
 	}
 	return 0
 }
 
-func (x *ConnectionInfo) GetState() string {
+func (x *SendEventBatchResponse) GetFailedEventIds() []string {
 	if x != nil {
-		return x.State
+		return x.FailedEventIds
 	}
-	return ""
+	return nil
 }
 
-func (x *ConnectionInfo) GetPid() int32 {
+// Watcher Management Messages
+type WatcherConfig struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type       string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`             // file, process, port, cpu, memory, etc.
+	Conditions []string               `protobuf:"bytes,3,rep,name=conditions,proto3" json:"conditions,omitempty"` // created, changed, deleted, above, below, etc.
+	Interval   string                 `protobuf:"bytes,4,opt,name=interval,proto3" json:"interval,omitempty"`
+	Group      string                 `protobuf:"bytes,5,opt,name=group,proto3" json:"group,omitempty"` // set on the watcher's events as watcher_group
+	// Type-specific fields
+	FilePath        string  `protobuf:"bytes,10,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"`
+	CheckHash       bool    `protobuf:"varint,11,opt,name=check_hash,json=checkHash,proto3" json:"check_hash,omitempty"`
+	Recursive       bool    `protobuf:"varint,12,opt,name=recursive,proto3" json:"recursive,omitempty"`
+	ProcessName     string  `protobuf:"bytes,20,opt,name=process_name,json=processName,proto3" json:"process_name,omitempty"`
+	Pid             int32   `protobuf:"varint,21,opt,name=pid,proto3" json:"pid,omitempty"`
+	Port            int32   `protobuf:"varint,30,opt,name=port,proto3" json:"port,omitempty"`
+	Protocol        string  `protobuf:"bytes,31,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	CpuThreshold    float64 `protobuf:"fixed64,40,opt,name=cpu_threshold,json=cpuThreshold,proto3" json:"cpu_threshold,omitempty"`
+	MemoryThreshold float64 `protobuf:"fixed64,41,opt,name=memory_threshold,json=memoryThreshold,proto3" json:"memory_threshold,omitempty"`
+	DiskThreshold   float64 `protobuf:"fixed64,42,opt,name=disk_threshold,json=diskThreshold,proto3" json:"disk_threshold,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WatcherConfig) Reset() {
+	*x = WatcherConfig{}
+	mi := &file_proto_agent_proto_msgTypes[111]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatcherConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatcherConfig) ProtoMessage() {}
+
+func (x *WatcherConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[111]
 	if x != nil {
-		return x.Pid
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *ConnectionInfo) GetProcessName() string {
+// Deprecated: Use WatcherConfig.ProtoReflect.Descriptor instead.
+func (*WatcherConfig) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{111}
+}
+
+func (x *WatcherConfig) GetId() string {
 	if x != nil {
-		return x.ProcessName
+		return x.Id
 	}
 	return ""
 }
 
-func (x *ConnectionInfo) GetBytesSent() uint64 {
+func (x *WatcherConfig) GetType() string {
 	if x != nil {
-		return x.BytesSent
+		return x.Type
 	}
-	return 0
+	return ""
 }
 
-func (x *ConnectionInfo) GetBytesRecv() uint64 {
+func (x *WatcherConfig) GetConditions() []string {
 	if x != nil {
-		return x.BytesRecv
+		return x.Conditions
 	}
-	return 0
+	return nil
 }
 
-func (x *ConnectionInfo) GetEstablishedAt() int64 {
+func (x *WatcherConfig) GetInterval() string {
 	if x != nil {
-		return x.EstablishedAt
+		return x.Interval
 	}
-	return 0
-}
-
-type ConnectionsResponse struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	Connections      []*ConnectionInfo      `protobuf:"bytes,1,rep,name=connections,proto3" json:"connections,omitempty"`
-	TotalEstablished int32                  `protobuf:"varint,2,opt,name=total_established,json=totalEstablished,proto3" json:"total_established,omitempty"`
-	TotalListening   int32                  `protobuf:"varint,3,opt,name=total_listening,json=totalListening,proto3" json:"total_listening,omitempty"`
-	TotalTimeWait    int32                  `protobuf:"varint,4,opt,name=total_time_wait,json=totalTimeWait,proto3" json:"total_time_wait,omitempty"`
-	TotalAll         int32                  `protobuf:"varint,5,opt,name=total_all,json=totalAll,proto3" json:"total_all,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	return ""
 }
 
-func (x *ConnectionsResponse) Reset() {
-	*x = ConnectionsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[78]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *WatcherConfig) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
 }
 
-func (x *ConnectionsResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *WatcherConfig) GetFilePath() string {
+	if x != nil {
+		return x.FilePath
+	}
+	return ""
 }
 
-func (*ConnectionsResponse) ProtoMessage() {}
+func (x *WatcherConfig) GetCheckHash() bool {
+	if x != nil {
+		return x.CheckHash
+	}
+	return false
+}
 
-func (x *ConnectionsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[78]
+func (x *WatcherConfig) GetRecursive() bool {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Recursive
 	}
-	return mi.MessageOf(x)
+	return false
 }
 
-// Deprecated: Use ConnectionsResponse.ProtoReflect.Descriptor instead.
-func (*ConnectionsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{78}
+func (x *WatcherConfig) GetProcessName() string {
+	if x != nil {
+		return x.ProcessName
+	}
+	return ""
 }
 
-func (x *ConnectionsResponse) GetConnections() []*ConnectionInfo {
+func (x *WatcherConfig) GetPid() int32 {
 	if x != nil {
-		return x.Connections
+		return x.Pid
 	}
-	return nil
+	return 0
 }
 
-func (x *ConnectionsResponse) GetTotalEstablished() int32 {
+func (x *WatcherConfig) GetPort() int32 {
 	if x != nil {
-		return x.TotalEstablished
+		return x.Port
 	}
 	return 0
 }
 
-func (x *ConnectionsResponse) GetTotalListening() int32 {
+func (x *WatcherConfig) GetProtocol() string {
 	if x != nil {
-		return x.TotalListening
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *WatcherConfig) GetCpuThreshold() float64 {
+	if x != nil {
+		return x.CpuThreshold
 	}
 	return 0
 }
 
-func (x *ConnectionsResponse) GetTotalTimeWait() int32 {
+func (x *WatcherConfig) GetMemoryThreshold() float64 {
 	if x != nil {
-		return x.TotalTimeWait
+		return x.MemoryThreshold
 	}
 	return 0
 }
 
-func (x *ConnectionsResponse) GetTotalAll() int32 {
+func (x *WatcherConfig) GetDiskThreshold() float64 {
 	if x != nil {
-		return x.TotalAll
+		return x.DiskThreshold
 	}
 	return 0
 }
 
-type SystemErrorsRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	MaxErrors       int32                  `protobuf:"varint,1,opt,name=max_errors,json=maxErrors,proto3" json:"max_errors,omitempty"` // Default 50
-	SinceTimestamp  int64                  `protobuf:"varint,2,opt,name=since_timestamp,json=sinceTimestamp,proto3" json:"since_timestamp,omitempty"`
-	IncludeWarnings bool                   `protobuf:"varint,3,opt,name=include_warnings,json=includeWarnings,proto3" json:"include_warnings,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type RegisterWatcherRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Config        *WatcherConfig         `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SystemErrorsRequest) Reset() {
-	*x = SystemErrorsRequest{}
-	mi := &file_proto_agent_proto_msgTypes[79]
+func (x *RegisterWatcherRequest) Reset() {
+	*x = RegisterWatcherRequest{}
+	mi := &file_proto_agent_proto_msgTypes[112]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SystemErrorsRequest) String() string {
+func (x *RegisterWatcherRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SystemErrorsRequest) ProtoMessage() {}
+func (*RegisterWatcherRequest) ProtoMessage() {}
 
-func (x *SystemErrorsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[79]
+func (x *RegisterWatcherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[112]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5068,60 +7625,42 @@ func (x *SystemErrorsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SystemErrorsRequest.ProtoReflect.Descriptor instead.
-func (*SystemErrorsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{79}
-}
-
-func (x *SystemErrorsRequest) GetMaxErrors() int32 {
-	if x != nil {
-		return x.MaxErrors
-	}
-	return 0
-}
-
-func (x *SystemErrorsRequest) GetSinceTimestamp() int64 {
-	if x != nil {
-		return x.SinceTimestamp
-	}
-	return 0
+// Deprecated: Use RegisterWatcherRequest.ProtoReflect.Descriptor instead.
+func (*RegisterWatcherRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{112}
 }
 
-func (x *SystemErrorsRequest) GetIncludeWarnings() bool {
+func (x *RegisterWatcherRequest) GetConfig() *WatcherConfig {
 	if x != nil {
-		return x.IncludeWarnings
+		return x.Config
 	}
-	return false
+	return nil
 }
 
-type SystemError struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Timestamp       int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Severity        string                 `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"` // error, warning, critical
-	Source          string                 `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
-	Message         string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
-	StackTrace      string                 `protobuf:"bytes,5,opt,name=stack_trace,json=stackTrace,proto3" json:"stack_trace,omitempty"`
-	Context         map[string]string      `protobuf:"bytes,6,rep,name=context,proto3" json:"context,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	OccurrenceCount int32                  `protobuf:"varint,7,opt,name=occurrence_count,json=occurrenceCount,proto3" json:"occurrence_count,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type RegisterWatcherResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	WatcherId     string                 `protobuf:"bytes,3,opt,name=watcher_id,json=watcherId,proto3" json:"watcher_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SystemError) Reset() {
-	*x = SystemError{}
-	mi := &file_proto_agent_proto_msgTypes[80]
+func (x *RegisterWatcherResponse) Reset() {
+	*x = RegisterWatcherResponse{}
+	mi := &file_proto_agent_proto_msgTypes[113]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SystemError) String() string {
+func (x *RegisterWatcherResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SystemError) ProtoMessage() {}
+func (*RegisterWatcherResponse) ProtoMessage() {}
 
-func (x *SystemError) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[80]
+func (x *RegisterWatcherResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[113]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5132,85 +7671,90 @@ func (x *SystemError) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SystemError.ProtoReflect.Descriptor instead.
-func (*SystemError) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{80}
+// Deprecated: Use RegisterWatcherResponse.ProtoReflect.Descriptor instead.
+func (*RegisterWatcherResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{113}
 }
 
-func (x *SystemError) GetTimestamp() int64 {
+func (x *RegisterWatcherResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Timestamp
+		return x.Success
 	}
-	return 0
+	return false
 }
 
-func (x *SystemError) GetSeverity() string {
+func (x *RegisterWatcherResponse) GetMessage() string {
 	if x != nil {
-		return x.Severity
+		return x.Message
 	}
 	return ""
 }
 
-func (x *SystemError) GetSource() string {
+func (x *RegisterWatcherResponse) GetWatcherId() string {
 	if x != nil {
-		return x.Source
+		return x.WatcherId
 	}
 	return ""
 }
 
-func (x *SystemError) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+type ListWatchersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SystemError) GetStackTrace() string {
-	if x != nil {
-		return x.StackTrace
-	}
-	return ""
+func (x *ListWatchersRequest) Reset() {
+	*x = ListWatchersRequest{}
+	mi := &file_proto_agent_proto_msgTypes[114]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *SystemError) GetContext() map[string]string {
-	if x != nil {
-		return x.Context
-	}
-	return nil
+func (x *ListWatchersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *SystemError) GetOccurrenceCount() int32 {
+func (*ListWatchersRequest) ProtoMessage() {}
+
+func (x *ListWatchersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[114]
 	if x != nil {
-		return x.OccurrenceCount
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-type SystemErrorsResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Errors          []*SystemError         `protobuf:"bytes,1,rep,name=errors,proto3" json:"errors,omitempty"`
-	TotalErrors     int32                  `protobuf:"varint,2,opt,name=total_errors,json=totalErrors,proto3" json:"total_errors,omitempty"`
-	TotalWarnings   int32                  `protobuf:"varint,3,opt,name=total_warnings,json=totalWarnings,proto3" json:"total_warnings,omitempty"`
-	MostCommonError string                 `protobuf:"bytes,4,opt,name=most_common_error,json=mostCommonError,proto3" json:"most_common_error,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+// Deprecated: Use ListWatchersRequest.ProtoReflect.Descriptor instead.
+func (*ListWatchersRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{114}
 }
 
-func (x *SystemErrorsResponse) Reset() {
-	*x = SystemErrorsResponse{}
-	mi := &file_proto_agent_proto_msgTypes[81]
+type ListWatchersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Watchers      []*WatcherConfig       `protobuf:"bytes,1,rep,name=watchers,proto3" json:"watchers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWatchersResponse) Reset() {
+	*x = ListWatchersResponse{}
+	mi := &file_proto_agent_proto_msgTypes[115]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SystemErrorsResponse) String() string {
+func (x *ListWatchersResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SystemErrorsResponse) ProtoMessage() {}
+func (*ListWatchersResponse) ProtoMessage() {}
 
-func (x *SystemErrorsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[81]
+func (x *ListWatchersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[115]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5221,62 +7765,85 @@ func (x *SystemErrorsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SystemErrorsResponse.ProtoReflect.Descriptor instead.
-func (*SystemErrorsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{81}
+// Deprecated: Use ListWatchersResponse.ProtoReflect.Descriptor instead.
+func (*ListWatchersResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{115}
 }
 
-func (x *SystemErrorsResponse) GetErrors() []*SystemError {
+func (x *ListWatchersResponse) GetWatchers() []*WatcherConfig {
 	if x != nil {
-		return x.Errors
+		return x.Watchers
 	}
 	return nil
 }
 
-func (x *SystemErrorsResponse) GetTotalErrors() int32 {
+type GetWatcherRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WatcherId     string                 `protobuf:"bytes,1,opt,name=watcher_id,json=watcherId,proto3" json:"watcher_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetWatcherRequest) Reset() {
+	*x = GetWatcherRequest{}
+	mi := &file_proto_agent_proto_msgTypes[116]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetWatcherRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetWatcherRequest) ProtoMessage() {}
+
+func (x *GetWatcherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[116]
 	if x != nil {
-		return x.TotalErrors
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *SystemErrorsResponse) GetTotalWarnings() int32 {
-	if x != nil {
-		return x.TotalWarnings
-	}
-	return 0
+// Deprecated: Use GetWatcherRequest.ProtoReflect.Descriptor instead.
+func (*GetWatcherRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{116}
 }
 
-func (x *SystemErrorsResponse) GetMostCommonError() string {
+func (x *GetWatcherRequest) GetWatcherId() string {
 	if x != nil {
-		return x.MostCommonError
+		return x.WatcherId
 	}
 	return ""
 }
 
-type PerformanceHistoryRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	DurationMinutes int32                  `protobuf:"varint,1,opt,name=duration_minutes,json=durationMinutes,proto3" json:"duration_minutes,omitempty"` // Default 60
-	DataPoints      int32                  `protobuf:"varint,2,opt,name=data_points,json=dataPoints,proto3" json:"data_points,omitempty"`                // How many samples to return
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type GetWatcherResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Watcher       *WatcherConfig         `protobuf:"bytes,1,opt,name=watcher,proto3" json:"watcher,omitempty"`
+	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PerformanceHistoryRequest) Reset() {
-	*x = PerformanceHistoryRequest{}
-	mi := &file_proto_agent_proto_msgTypes[82]
+func (x *GetWatcherResponse) Reset() {
+	*x = GetWatcherResponse{}
+	mi := &file_proto_agent_proto_msgTypes[117]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PerformanceHistoryRequest) String() string {
+func (x *GetWatcherResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PerformanceHistoryRequest) ProtoMessage() {}
+func (*GetWatcherResponse) ProtoMessage() {}
 
-func (x *PerformanceHistoryRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[82]
+func (x *GetWatcherResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[117]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5287,54 +7854,47 @@ func (x *PerformanceHistoryRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PerformanceHistoryRequest.ProtoReflect.Descriptor instead.
-func (*PerformanceHistoryRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{82}
+// Deprecated: Use GetWatcherResponse.ProtoReflect.Descriptor instead.
+func (*GetWatcherResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{117}
 }
 
-func (x *PerformanceHistoryRequest) GetDurationMinutes() int32 {
+func (x *GetWatcherResponse) GetWatcher() *WatcherConfig {
 	if x != nil {
-		return x.DurationMinutes
+		return x.Watcher
 	}
-	return 0
+	return nil
 }
 
-func (x *PerformanceHistoryRequest) GetDataPoints() int32 {
+func (x *GetWatcherResponse) GetFound() bool {
 	if x != nil {
-		return x.DataPoints
+		return x.Found
 	}
-	return 0
+	return false
 }
 
-type PerformanceSnapshot struct {
-	state                 protoimpl.MessageState `protogen:"open.v1"`
-	Timestamp             int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	CpuPercent            float64                `protobuf:"fixed64,2,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
-	MemoryPercent         float64                `protobuf:"fixed64,3,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
-	DiskPercent           float64                `protobuf:"fixed64,4,opt,name=disk_percent,json=diskPercent,proto3" json:"disk_percent,omitempty"`
-	NetworkThroughputMbps float64                `protobuf:"fixed64,5,opt,name=network_throughput_mbps,json=networkThroughputMbps,proto3" json:"network_throughput_mbps,omitempty"`
-	LoadAvg               float64                `protobuf:"fixed64,6,opt,name=load_avg,json=loadAvg,proto3" json:"load_avg,omitempty"`
-	ActiveConnections     int32                  `protobuf:"varint,7,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
-	ProcessCount          int32                  `protobuf:"varint,8,opt,name=process_count,json=processCount,proto3" json:"process_count,omitempty"`
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+type RemoveWatcherRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WatcherId     string                 `protobuf:"bytes,1,opt,name=watcher_id,json=watcherId,proto3" json:"watcher_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PerformanceSnapshot) Reset() {
-	*x = PerformanceSnapshot{}
-	mi := &file_proto_agent_proto_msgTypes[83]
+func (x *RemoveWatcherRequest) Reset() {
+	*x = RemoveWatcherRequest{}
+	mi := &file_proto_agent_proto_msgTypes[118]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PerformanceSnapshot) String() string {
+func (x *RemoveWatcherRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PerformanceSnapshot) ProtoMessage() {}
+func (*RemoveWatcherRequest) ProtoMessage() {}
 
-func (x *PerformanceSnapshot) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[83]
+func (x *RemoveWatcherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[118]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5345,92 +7905,92 @@ func (x *PerformanceSnapshot) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PerformanceSnapshot.ProtoReflect.Descriptor instead.
-func (*PerformanceSnapshot) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{83}
+// Deprecated: Use RemoveWatcherRequest.ProtoReflect.Descriptor instead.
+func (*RemoveWatcherRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{118}
 }
 
-func (x *PerformanceSnapshot) GetTimestamp() int64 {
+func (x *RemoveWatcherRequest) GetWatcherId() string {
 	if x != nil {
-		return x.Timestamp
+		return x.WatcherId
 	}
-	return 0
+	return ""
 }
 
-func (x *PerformanceSnapshot) GetCpuPercent() float64 {
-	if x != nil {
-		return x.CpuPercent
-	}
-	return 0
+type RemoveWatcherResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PerformanceSnapshot) GetMemoryPercent() float64 {
-	if x != nil {
-		return x.MemoryPercent
-	}
-	return 0
+func (x *RemoveWatcherResponse) Reset() {
+	*x = RemoveWatcherResponse{}
+	mi := &file_proto_agent_proto_msgTypes[119]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *PerformanceSnapshot) GetDiskPercent() float64 {
-	if x != nil {
-		return x.DiskPercent
-	}
-	return 0
+func (x *RemoveWatcherResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *PerformanceSnapshot) GetNetworkThroughputMbps() float64 {
+func (*RemoveWatcherResponse) ProtoMessage() {}
+
+func (x *RemoveWatcherResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[119]
 	if x != nil {
-		return x.NetworkThroughputMbps
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *PerformanceSnapshot) GetLoadAvg() float64 {
-	if x != nil {
-		return x.LoadAvg
-	}
-	return 0
+// Deprecated: Use RemoveWatcherResponse.ProtoReflect.Descriptor instead.
+func (*RemoveWatcherResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{119}
 }
 
-func (x *PerformanceSnapshot) GetActiveConnections() int32 {
+func (x *RemoveWatcherResponse) GetSuccess() bool {
 	if x != nil {
-		return x.ActiveConnections
+		return x.Success
 	}
-	return 0
+	return false
 }
 
-func (x *PerformanceSnapshot) GetProcessCount() int32 {
+func (x *RemoveWatcherResponse) GetMessage() string {
 	if x != nil {
-		return x.ProcessCount
+		return x.Message
 	}
-	return 0
+	return ""
 }
 
-type PerformanceHistoryResponse struct {
+// Agent Configuration Messages
+type ReloadConfigRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Snapshots     []*PerformanceSnapshot `protobuf:"bytes,1,rep,name=snapshots,proto3" json:"snapshots,omitempty"`
-	Avg           *PerformanceSnapshot   `protobuf:"bytes,2,opt,name=avg,proto3" json:"avg,omitempty"`
-	Min           *PerformanceSnapshot   `protobuf:"bytes,3,opt,name=min,proto3" json:"min,omitempty"`
-	Max           *PerformanceSnapshot   `protobuf:"bytes,4,opt,name=max,proto3" json:"max,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PerformanceHistoryResponse) Reset() {
-	*x = PerformanceHistoryResponse{}
-	mi := &file_proto_agent_proto_msgTypes[84]
+func (x *ReloadConfigRequest) Reset() {
+	*x = ReloadConfigRequest{}
+	mi := &file_proto_agent_proto_msgTypes[120]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PerformanceHistoryResponse) String() string {
+func (x *ReloadConfigRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PerformanceHistoryResponse) ProtoMessage() {}
+func (*ReloadConfigRequest) ProtoMessage() {}
 
-func (x *PerformanceHistoryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[84]
+func (x *ReloadConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[120]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5441,62 +8001,73 @@ func (x *PerformanceHistoryResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PerformanceHistoryResponse.ProtoReflect.Descriptor instead.
-func (*PerformanceHistoryResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{84}
+// Deprecated: Use ReloadConfigRequest.ProtoReflect.Descriptor instead.
+func (*ReloadConfigRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{120}
 }
 
-func (x *PerformanceHistoryResponse) GetSnapshots() []*PerformanceSnapshot {
-	if x != nil {
-		return x.Snapshots
-	}
-	return nil
+type GetConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PerformanceHistoryResponse) GetAvg() *PerformanceSnapshot {
-	if x != nil {
-		return x.Avg
-	}
-	return nil
+func (x *GetConfigRequest) Reset() {
+	*x = GetConfigRequest{}
+	mi := &file_proto_agent_proto_msgTypes[121]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *PerformanceHistoryResponse) GetMin() *PerformanceSnapshot {
-	if x != nil {
-		return x.Min
-	}
-	return nil
+func (x *GetConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *PerformanceHistoryResponse) GetMax() *PerformanceSnapshot {
+func (*GetConfigRequest) ProtoMessage() {}
+
+func (x *GetConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[121]
 	if x != nil {
-		return x.Max
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-type HealthDiagnosticRequest struct {
-	state              protoimpl.MessageState `protogen:"open.v1"`
-	IncludeSuggestions bool                   `protobuf:"varint,1,opt,name=include_suggestions,json=includeSuggestions,proto3" json:"include_suggestions,omitempty"`
-	DeepCheck          bool                   `protobuf:"varint,2,opt,name=deep_check,json=deepCheck,proto3" json:"deep_check,omitempty"`
-	unknownFields      protoimpl.UnknownFields
-	sizeCache          protoimpl.SizeCache
+// Deprecated: Use GetConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetConfigRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{121}
 }
 
-func (x *HealthDiagnosticRequest) Reset() {
-	*x = HealthDiagnosticRequest{}
-	mi := &file_proto_agent_proto_msgTypes[85]
+type AgentConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`                               // config file read, empty when only flags are used
+	ConfigYaml    string                 `protobuf:"bytes,2,opt,name=config_yaml,json=configYaml,proto3" json:"config_yaml,omitempty"` // settings applied, as YAML
+	LoadedAt      int64                  `protobuf:"varint,3,opt,name=loaded_at,json=loadedAt,proto3" json:"loaded_at,omitempty"`      // when they were applied
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                             // why the last reload failed, keeping the settings applied before
+	Changed       []string               `protobuf:"bytes,5,rep,name=changed,proto3" json:"changed,omitempty"`                         // settings the reload changed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentConfigResponse) Reset() {
+	*x = AgentConfigResponse{}
+	mi := &file_proto_agent_proto_msgTypes[122]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthDiagnosticRequest) String() string {
+func (x *AgentConfigResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthDiagnosticRequest) ProtoMessage() {}
+func (*AgentConfigResponse) ProtoMessage() {}
 
-func (x *HealthDiagnosticRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[85]
+func (x *AgentConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[122]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5507,53 +8078,73 @@ func (x *HealthDiagnosticRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthDiagnosticRequest.ProtoReflect.Descriptor instead.
-func (*HealthDiagnosticRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{85}
+// Deprecated: Use AgentConfigResponse.ProtoReflect.Descriptor instead.
+func (*AgentConfigResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{122}
 }
 
-func (x *HealthDiagnosticRequest) GetIncludeSuggestions() bool {
+func (x *AgentConfigResponse) GetPath() string {
 	if x != nil {
-		return x.IncludeSuggestions
+		return x.Path
 	}
-	return false
+	return ""
 }
 
-func (x *HealthDiagnosticRequest) GetDeepCheck() bool {
+func (x *AgentConfigResponse) GetConfigYaml() string {
 	if x != nil {
-		return x.DeepCheck
+		return x.ConfigYaml
 	}
-	return false
+	return ""
 }
 
-type HealthIssue struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Category      string                 `protobuf:"bytes,1,opt,name=category,proto3" json:"category,omitempty"` // cpu, memory, disk, network, process
-	Severity      string                 `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"` // info, warning, critical
-	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
-	CurrentValue  string                 `protobuf:"bytes,4,opt,name=current_value,json=currentValue,proto3" json:"current_value,omitempty"`
-	Threshold     string                 `protobuf:"bytes,5,opt,name=threshold,proto3" json:"threshold,omitempty"`
-	Suggestions   []string               `protobuf:"bytes,6,rep,name=suggestions,proto3" json:"suggestions,omitempty"`
-	AutoFixable   bool                   `protobuf:"varint,7,opt,name=auto_fixable,json=autoFixable,proto3" json:"auto_fixable,omitempty"`
+func (x *AgentConfigResponse) GetLoadedAt() int64 {
+	if x != nil {
+		return x.LoadedAt
+	}
+	return 0
+}
+
+func (x *AgentConfigResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *AgentConfigResponse) GetChanged() []string {
+	if x != nil {
+		return x.Changed
+	}
+	return nil
+}
+
+// Artifact Cache Messages
+type ArtifactPeer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	CacheAddress  string                 `protobuf:"bytes,2,opt,name=cache_address,json=cacheAddress,proto3" json:"cache_address,omitempty"` // host:port of the peer's artifact cache HTTP server
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string                 `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	AnnouncedAt   int64                  `protobuf:"varint,5,opt,name=announced_at,json=announcedAt,proto3" json:"announced_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HealthIssue) Reset() {
-	*x = HealthIssue{}
-	mi := &file_proto_agent_proto_msgTypes[86]
+func (x *ArtifactPeer) Reset() {
+	*x = ArtifactPeer{}
+	mi := &file_proto_agent_proto_msgTypes[123]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthIssue) String() string {
+func (x *ArtifactPeer) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthIssue) ProtoMessage() {}
+func (*ArtifactPeer) ProtoMessage() {}
 
-func (x *HealthIssue) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[86]
+func (x *ArtifactPeer) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[123]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5564,88 +8155,73 @@ func (x *HealthIssue) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthIssue.ProtoReflect.Descriptor instead.
-func (*HealthIssue) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{86}
-}
-
-func (x *HealthIssue) GetCategory() string {
-	if x != nil {
-		return x.Category
-	}
-	return ""
+// Deprecated: Use ArtifactPeer.ProtoReflect.Descriptor instead.
+func (*ArtifactPeer) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{123}
 }
 
-func (x *HealthIssue) GetSeverity() string {
+func (x *ArtifactPeer) GetAgentName() string {
 	if x != nil {
-		return x.Severity
+		return x.AgentName
 	}
 	return ""
 }
 
-func (x *HealthIssue) GetDescription() string {
+func (x *ArtifactPeer) GetCacheAddress() string {
 	if x != nil {
-		return x.Description
+		return x.CacheAddress
 	}
 	return ""
 }
 
-func (x *HealthIssue) GetCurrentValue() string {
+func (x *ArtifactPeer) GetSize() int64 {
 	if x != nil {
-		return x.CurrentValue
+		return x.Size
 	}
-	return ""
+	return 0
 }
 
-func (x *HealthIssue) GetThreshold() string {
+func (x *ArtifactPeer) GetSha256() string {
 	if x != nil {
-		return x.Threshold
+		return x.Sha256
 	}
 	return ""
 }
 
-func (x *HealthIssue) GetSuggestions() []string {
-	if x != nil {
-		return x.Suggestions
-	}
-	return nil
-}
-
-func (x *HealthIssue) GetAutoFixable() bool {
+func (x *ArtifactPeer) GetAnnouncedAt() int64 {
 	if x != nil {
-		return x.AutoFixable
+		return x.AnnouncedAt
 	}
-	return false
+	return 0
 }
 
-type HealthDiagnosticResponse struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	OverallStatus  string                 `protobuf:"bytes,1,opt,name=overall_status,json=overallStatus,proto3" json:"overall_status,omitempty"` // healthy, degraded, unhealthy
-	HealthScore    int32                  `protobuf:"varint,2,opt,name=health_score,json=healthScore,proto3" json:"health_score,omitempty"`      // 0-100
-	Issues         []*HealthIssue         `protobuf:"bytes,3,rep,name=issues,proto3" json:"issues,omitempty"`
-	Summary        map[string]string      `protobuf:"bytes,4,rep,name=summary,proto3" json:"summary,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	CheckTimestamp int64                  `protobuf:"varint,5,opt,name=check_timestamp,json=checkTimestamp,proto3" json:"check_timestamp,omitempty"`
-	TotalWarnings  int32                  `protobuf:"varint,6,opt,name=total_warnings,json=totalWarnings,proto3" json:"total_warnings,omitempty"`
-	TotalErrors    int32                  `protobuf:"varint,7,opt,name=total_errors,json=totalErrors,proto3" json:"total_errors,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+type AnnounceArtifactRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	CacheAddress  string                 `protobuf:"bytes,2,opt,name=cache_address,json=cacheAddress,proto3" json:"cache_address,omitempty"`
+	Key           string                 `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"` // sha256 of the source URL
+	Url           string                 `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	Size          int64                  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string                 `protobuf:"bytes,6,opt,name=sha256,proto3" json:"sha256,omitempty"` // sha256 of the content
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HealthDiagnosticResponse) Reset() {
-	*x = HealthDiagnosticResponse{}
-	mi := &file_proto_agent_proto_msgTypes[87]
+func (x *AnnounceArtifactRequest) Reset() {
+	*x = AnnounceArtifactRequest{}
+	mi := &file_proto_agent_proto_msgTypes[124]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthDiagnosticResponse) String() string {
+func (x *AnnounceArtifactRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthDiagnosticResponse) ProtoMessage() {}
+func (*AnnounceArtifactRequest) ProtoMessage() {}
 
-func (x *HealthDiagnosticResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[87]
+func (x *AnnounceArtifactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[124]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5656,88 +8232,76 @@ func (x *HealthDiagnosticResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthDiagnosticResponse.ProtoReflect.Descriptor instead.
-func (*HealthDiagnosticResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{87}
+// Deprecated: Use AnnounceArtifactRequest.ProtoReflect.Descriptor instead.
+func (*AnnounceArtifactRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{124}
 }
 
-func (x *HealthDiagnosticResponse) GetOverallStatus() string {
+func (x *AnnounceArtifactRequest) GetAgentName() string {
 	if x != nil {
-		return x.OverallStatus
+		return x.AgentName
 	}
 	return ""
 }
 
-func (x *HealthDiagnosticResponse) GetHealthScore() int32 {
-	if x != nil {
-		return x.HealthScore
-	}
-	return 0
-}
-
-func (x *HealthDiagnosticResponse) GetIssues() []*HealthIssue {
+func (x *AnnounceArtifactRequest) GetCacheAddress() string {
 	if x != nil {
-		return x.Issues
+		return x.CacheAddress
 	}
-	return nil
+	return ""
 }
 
-func (x *HealthDiagnosticResponse) GetSummary() map[string]string {
+func (x *AnnounceArtifactRequest) GetKey() string {
 	if x != nil {
-		return x.Summary
+		return x.Key
 	}
-	return nil
+	return ""
 }
 
-func (x *HealthDiagnosticResponse) GetCheckTimestamp() int64 {
+func (x *AnnounceArtifactRequest) GetUrl() string {
 	if x != nil {
-		return x.CheckTimestamp
+		return x.Url
 	}
-	return 0
+	return ""
 }
 
-func (x *HealthDiagnosticResponse) GetTotalWarnings() int32 {
+func (x *AnnounceArtifactRequest) GetSize() int64 {
 	if x != nil {
-		return x.TotalWarnings
+		return x.Size
 	}
 	return 0
 }
 
-func (x *HealthDiagnosticResponse) GetTotalErrors() int32 {
+func (x *AnnounceArtifactRequest) GetSha256() string {
 	if x != nil {
-		return x.TotalErrors
+		return x.Sha256
 	}
-	return 0
+	return ""
 }
 
-// Interactive Shell Messages
-type ShellInput struct {
+type AnnounceArtifactResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Command       string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`                          // Command to execute
-	StdinData     []byte                 `protobuf:"bytes,2,opt,name=stdin_data,json=stdinData,proto3" json:"stdin_data,omitempty"`     // Input data for interactive commands
-	Terminate     bool                   `protobuf:"varint,3,opt,name=terminate,proto3" json:"terminate,omitempty"`                     // Signal to terminate the shell session
-	WindowRows    uint32                 `protobuf:"varint,4,opt,name=window_rows,json=windowRows,proto3" json:"window_rows,omitempty"` // Terminal window height
-	WindowCols    uint32                 `protobuf:"varint,5,opt,name=window_cols,json=windowCols,proto3" json:"window_cols,omitempty"` // Terminal window width
-	Resize        bool                   `protobuf:"varint,6,opt,name=resize,proto3" json:"resize,omitempty"`                           // Signal that this is a window resize message
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ShellInput) Reset() {
-	*x = ShellInput{}
-	mi := &file_proto_agent_proto_msgTypes[88]
+func (x *AnnounceArtifactResponse) Reset() {
+	*x = AnnounceArtifactResponse{}
+	mi := &file_proto_agent_proto_msgTypes[125]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ShellInput) String() string {
+func (x *AnnounceArtifactResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ShellInput) ProtoMessage() {}
+func (*AnnounceArtifactResponse) ProtoMessage() {}
 
-func (x *ShellInput) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[88]
+func (x *AnnounceArtifactResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[125]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5748,79 +8312,149 @@ func (x *ShellInput) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ShellInput.ProtoReflect.Descriptor instead.
-func (*ShellInput) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{88}
+// Deprecated: Use AnnounceArtifactResponse.ProtoReflect.Descriptor instead.
+func (*AnnounceArtifactResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{125}
 }
 
-func (x *ShellInput) GetCommand() string {
+func (x *AnnounceArtifactResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Command
+		return x.Success
+	}
+	return false
+}
+
+func (x *AnnounceArtifactResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
 	}
 	return ""
 }
 
-func (x *ShellInput) GetStdinData() []byte {
+type LookupArtifactRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Key             string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	RequestingAgent string                 `protobuf:"bytes,2,opt,name=requesting_agent,json=requestingAgent,proto3" json:"requesting_agent,omitempty"` // excluded from the returned peers
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *LookupArtifactRequest) Reset() {
+	*x = LookupArtifactRequest{}
+	mi := &file_proto_agent_proto_msgTypes[126]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupArtifactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupArtifactRequest) ProtoMessage() {}
+
+func (x *LookupArtifactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[126]
 	if x != nil {
-		return x.StdinData
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *ShellInput) GetTerminate() bool {
+// Deprecated: Use LookupArtifactRequest.ProtoReflect.Descriptor instead.
+func (*LookupArtifactRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{126}
+}
+
+func (x *LookupArtifactRequest) GetKey() string {
 	if x != nil {
-		return x.Terminate
+		return x.Key
 	}
-	return false
+	return ""
 }
 
-func (x *ShellInput) GetWindowRows() uint32 {
+func (x *LookupArtifactRequest) GetRequestingAgent() string {
 	if x != nil {
-		return x.WindowRows
+		return x.RequestingAgent
 	}
-	return 0
+	return ""
 }
 
-func (x *ShellInput) GetWindowCols() uint32 {
+type LookupArtifactResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*ArtifactPeer        `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupArtifactResponse) Reset() {
+	*x = LookupArtifactResponse{}
+	mi := &file_proto_agent_proto_msgTypes[127]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupArtifactResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupArtifactResponse) ProtoMessage() {}
+
+func (x *LookupArtifactResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[127]
 	if x != nil {
-		return x.WindowCols
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *ShellInput) GetResize() bool {
+// Deprecated: Use LookupArtifactResponse.ProtoReflect.Descriptor instead.
+func (*LookupArtifactResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{127}
+}
+
+func (x *LookupArtifactResponse) GetPeers() []*ArtifactPeer {
 	if x != nil {
-		return x.Resize
+		return x.Peers
 	}
-	return false
+	return nil
 }
 
-type ShellOutput struct {
+// Fact History Messages
+type FactChange struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Stdout        []byte                 `protobuf:"bytes,1,opt,name=stdout,proto3" json:"stdout,omitempty"`                      // Standard output
-	Stderr        []byte                 `protobuf:"bytes,2,opt,name=stderr,proto3" json:"stderr,omitempty"`                      // Standard error
-	ExitCode      int32                  `protobuf:"varint,3,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"` // Exit code (only set when command completes)
-	Completed     bool                   `protobuf:"varint,4,opt,name=completed,proto3" json:"completed,omitempty"`               // True when command execution is complete
-	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`                        // Error message if execution failed
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	Fact          string                 `protobuf:"bytes,2,opt,name=fact,proto3" json:"fact,omitempty"`     // e.g. kernel_version, network.eth0.addresses
+	Change        string                 `protobuf:"bytes,3,opt,name=change,proto3" json:"change,omitempty"` // added, removed or changed
+	OldValue      string                 `protobuf:"bytes,4,opt,name=old_value,json=oldValue,proto3" json:"old_value,omitempty"`
+	NewValue      string                 `protobuf:"bytes,5,opt,name=new_value,json=newValue,proto3" json:"new_value,omitempty"`
+	ChangedAt     int64                  `protobuf:"varint,6,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ShellOutput) Reset() {
-	*x = ShellOutput{}
-	mi := &file_proto_agent_proto_msgTypes[89]
+func (x *FactChange) Reset() {
+	*x = FactChange{}
+	mi := &file_proto_agent_proto_msgTypes[128]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ShellOutput) String() string {
+func (x *FactChange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ShellOutput) ProtoMessage() {}
+func (*FactChange) ProtoMessage() {}
 
-func (x *ShellOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[89]
+func (x *FactChange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[128]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5831,77 +8465,78 @@ func (x *ShellOutput) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ShellOutput.ProtoReflect.Descriptor instead.
-func (*ShellOutput) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{89}
+// Deprecated: Use FactChange.ProtoReflect.Descriptor instead.
+func (*FactChange) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{128}
 }
 
-func (x *ShellOutput) GetStdout() []byte {
+func (x *FactChange) GetAgentName() string {
 	if x != nil {
-		return x.Stdout
+		return x.AgentName
 	}
-	return nil
+	return ""
 }
 
-func (x *ShellOutput) GetStderr() []byte {
+func (x *FactChange) GetFact() string {
 	if x != nil {
-		return x.Stderr
+		return x.Fact
 	}
-	return nil
+	return ""
 }
 
-func (x *ShellOutput) GetExitCode() int32 {
+func (x *FactChange) GetChange() string {
 	if x != nil {
-		return x.ExitCode
+		return x.Change
 	}
-	return 0
+	return ""
 }
 
-func (x *ShellOutput) GetCompleted() bool {
+func (x *FactChange) GetOldValue() string {
 	if x != nil {
-		return x.Completed
+		return x.OldValue
 	}
-	return false
+	return ""
 }
 
-func (x *ShellOutput) GetError() string {
+func (x *FactChange) GetNewValue() string {
 	if x != nil {
-		return x.Error
+		return x.NewValue
 	}
 	return ""
 }
 
-// Event Reporting Messages - Agents push events to master
-type EventData struct {
+func (x *FactChange) GetChangedAt() int64 {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return 0
+}
+
+type FactHistoryRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`                                                      // Unique event ID
-	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`                                                // Type of event (task.started, file.created, etc)
-	AgentName     string                 `protobuf:"bytes,3,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`                                                // Agent that generated the event
-	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                                // Unix timestamp
-	Stack         string                 `protobuf:"bytes,5,opt,name=stack,proto3" json:"stack,omitempty"`                                                                         // Stack name if applicable
-	RunId         string                 `protobuf:"bytes,6,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`                                                            // Run ID if applicable
-	Data          map[string]string      `protobuf:"bytes,7,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Event data as key-value pairs
-	DataJson      string                 `protobuf:"bytes,8,opt,name=data_json,json=dataJson,proto3" json:"data_json,omitempty"`                                                   // Optional: full JSON data
-	Severity      string                 `protobuf:"bytes,9,opt,name=severity,proto3" json:"severity,omitempty"`                                                                   // info, warning, error, critical
+	AgentName     string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	Since         int64                  `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"` // unix timestamp, 0 for all retained history
+	Fact          string                 `protobuf:"bytes,3,opt,name=fact,proto3" json:"fact,omitempty"`    // optional fact name prefix filter
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *EventData) Reset() {
-	*x = EventData{}
-	mi := &file_proto_agent_proto_msgTypes[90]
+func (x *FactHistoryRequest) Reset() {
+	*x = FactHistoryRequest{}
+	mi := &file_proto_agent_proto_msgTypes[129]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *EventData) String() string {
+func (x *FactHistoryRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*EventData) ProtoMessage() {}
+func (*FactHistoryRequest) ProtoMessage() {}
 
-func (x *EventData) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[90]
+func (x *FactHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[129]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -5909,99 +8544,108 @@ func (x *EventData) ProtoReflect() protoreflect.Message {
 		}
 		return ms
 	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use EventData.ProtoReflect.Descriptor instead.
-func (*EventData) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{90}
-}
-
-func (x *EventData) GetEventId() string {
-	if x != nil {
-		return x.EventId
-	}
-	return ""
-}
-
-func (x *EventData) GetEventType() string {
-	if x != nil {
-		return x.EventType
-	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *EventData) GetAgentName() string {
+// Deprecated: Use FactHistoryRequest.ProtoReflect.Descriptor instead.
+func (*FactHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{129}
+}
+
+func (x *FactHistoryRequest) GetAgentName() string {
 	if x != nil {
 		return x.AgentName
 	}
 	return ""
 }
 
-func (x *EventData) GetTimestamp() int64 {
+func (x *FactHistoryRequest) GetSince() int64 {
 	if x != nil {
-		return x.Timestamp
+		return x.Since
 	}
 	return 0
 }
 
-func (x *EventData) GetStack() string {
+func (x *FactHistoryRequest) GetFact() string {
 	if x != nil {
-		return x.Stack
+		return x.Fact
 	}
 	return ""
 }
 
-func (x *EventData) GetRunId() string {
+func (x *FactHistoryRequest) GetLimit() int32 {
 	if x != nil {
-		return x.RunId
+		return x.Limit
 	}
-	return ""
+	return 0
 }
 
-func (x *EventData) GetData() map[string]string {
-	if x != nil {
-		return x.Data
-	}
-	return nil
+type FactHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Changes       []*FactChange          `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *EventData) GetDataJson() string {
+func (x *FactHistoryResponse) Reset() {
+	*x = FactHistoryResponse{}
+	mi := &file_proto_agent_proto_msgTypes[130]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FactHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FactHistoryResponse) ProtoMessage() {}
+
+func (x *FactHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[130]
 	if x != nil {
-		return x.DataJson
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *EventData) GetSeverity() string {
+// Deprecated: Use FactHistoryResponse.ProtoReflect.Descriptor instead.
+func (*FactHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{130}
+}
+
+func (x *FactHistoryResponse) GetChanges() []*FactChange {
 	if x != nil {
-		return x.Severity
+		return x.Changes
 	}
-	return ""
+	return nil
 }
 
-type SendEventRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Event         *EventData             `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type DrainMasterRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TimeoutSeconds int32                  `protobuf:"varint,1,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // how long to wait for in-flight runs
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *SendEventRequest) Reset() {
-	*x = SendEventRequest{}
-	mi := &file_proto_agent_proto_msgTypes[91]
+func (x *DrainMasterRequest) Reset() {
+	*x = DrainMasterRequest{}
+	mi := &file_proto_agent_proto_msgTypes[131]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendEventRequest) String() string {
+func (x *DrainMasterRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendEventRequest) ProtoMessage() {}
+func (*DrainMasterRequest) ProtoMessage() {}
 
-func (x *SendEventRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[91]
+func (x *DrainMasterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[131]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6012,42 +8656,44 @@ func (x *SendEventRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendEventRequest.ProtoReflect.Descriptor instead.
-func (*SendEventRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{91}
+// Deprecated: Use DrainMasterRequest.ProtoReflect.Descriptor instead.
+func (*DrainMasterRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{131}
 }
 
-func (x *SendEventRequest) GetEvent() *EventData {
+func (x *DrainMasterRequest) GetTimeoutSeconds() int32 {
 	if x != nil {
-		return x.Event
+		return x.TimeoutSeconds
 	}
-	return nil
+	return 0
 }
 
-type SendEventResponse struct {
+type DrainMasterResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	EventId       string                 `protobuf:"bytes,3,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"` // Confirmed event ID
+	Drained       bool                   `protobuf:"varint,1,opt,name=drained,proto3" json:"drained,omitempty"` // false if runs were still in flight at the timeout
+	InFlight      int32                  `protobuf:"varint,2,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Executable    string                 `protobuf:"bytes,4,opt,name=executable,proto3" json:"executable,omitempty"` // path of the running master binary
+	DataDir       string                 `protobuf:"bytes,5,opt,name=data_dir,json=dataDir,proto3" json:"data_dir,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SendEventResponse) Reset() {
-	*x = SendEventResponse{}
-	mi := &file_proto_agent_proto_msgTypes[92]
+func (x *DrainMasterResponse) Reset() {
+	*x = DrainMasterResponse{}
+	mi := &file_proto_agent_proto_msgTypes[132]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendEventResponse) String() string {
+func (x *DrainMasterResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendEventResponse) ProtoMessage() {}
+func (*DrainMasterResponse) ProtoMessage() {}
 
-func (x *SendEventResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[92]
+func (x *DrainMasterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[132]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6058,55 +8704,68 @@ func (x *SendEventResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendEventResponse.ProtoReflect.Descriptor instead.
-func (*SendEventResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{92}
+// Deprecated: Use DrainMasterResponse.ProtoReflect.Descriptor instead.
+func (*DrainMasterResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{132}
 }
 
-func (x *SendEventResponse) GetSuccess() bool {
+func (x *DrainMasterResponse) GetDrained() bool {
 	if x != nil {
-		return x.Success
+		return x.Drained
 	}
 	return false
 }
 
-func (x *SendEventResponse) GetMessage() string {
+func (x *DrainMasterResponse) GetInFlight() int32 {
 	if x != nil {
-		return x.Message
+		return x.InFlight
+	}
+	return 0
+}
+
+func (x *DrainMasterResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
 	}
 	return ""
 }
 
-func (x *SendEventResponse) GetEventId() string {
+func (x *DrainMasterResponse) GetExecutable() string {
 	if x != nil {
-		return x.EventId
+		return x.Executable
 	}
 	return ""
 }
 
-type SendEventBatchRequest struct {
+func (x *DrainMasterResponse) GetDataDir() string {
+	if x != nil {
+		return x.DataDir
+	}
+	return ""
+}
+
+type ResumeMasterRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Events        []*EventData           `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
-	BatchSize     int32                  `protobuf:"varint,2,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	Restart       bool                   `protobuf:"varint,1,opt,name=restart,proto3" json:"restart,omitempty"` // re-exec the master binary instead of resuming in place
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SendEventBatchRequest) Reset() {
-	*x = SendEventBatchRequest{}
-	mi := &file_proto_agent_proto_msgTypes[93]
+func (x *ResumeMasterRequest) Reset() {
+	*x = ResumeMasterRequest{}
+	mi := &file_proto_agent_proto_msgTypes[133]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendEventBatchRequest) String() string {
+func (x *ResumeMasterRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendEventBatchRequest) ProtoMessage() {}
+func (*ResumeMasterRequest) ProtoMessage() {}
 
-func (x *SendEventBatchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[93]
+func (x *ResumeMasterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[133]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6117,51 +8776,41 @@ func (x *SendEventBatchRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendEventBatchRequest.ProtoReflect.Descriptor instead.
-func (*SendEventBatchRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{93}
-}
-
-func (x *SendEventBatchRequest) GetEvents() []*EventData {
-	if x != nil {
-		return x.Events
-	}
-	return nil
+// Deprecated: Use ResumeMasterRequest.ProtoReflect.Descriptor instead.
+func (*ResumeMasterRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{133}
 }
 
-func (x *SendEventBatchRequest) GetBatchSize() int32 {
+func (x *ResumeMasterRequest) GetRestart() bool {
 	if x != nil {
-		return x.BatchSize
+		return x.Restart
 	}
-	return 0
+	return false
 }
 
-type SendEventBatchResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	EventsReceived  int32                  `protobuf:"varint,3,opt,name=events_received,json=eventsReceived,proto3" json:"events_received,omitempty"`
-	EventsProcessed int32                  `protobuf:"varint,4,opt,name=events_processed,json=eventsProcessed,proto3" json:"events_processed,omitempty"`
-	FailedEventIds  []string               `protobuf:"bytes,5,rep,name=failed_event_ids,json=failedEventIds,proto3" json:"failed_event_ids,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type ResumeMasterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Restarting    bool                   `protobuf:"varint,2,opt,name=restarting,proto3" json:"restarting,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SendEventBatchResponse) Reset() {
-	*x = SendEventBatchResponse{}
-	mi := &file_proto_agent_proto_msgTypes[94]
+func (x *ResumeMasterResponse) Reset() {
+	*x = ResumeMasterResponse{}
+	mi := &file_proto_agent_proto_msgTypes[134]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendEventBatchResponse) String() string {
+func (x *ResumeMasterResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendEventBatchResponse) ProtoMessage() {}
+func (*ResumeMasterResponse) ProtoMessage() {}
 
-func (x *SendEventBatchResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[94]
+func (x *ResumeMasterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[134]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6172,83 +8821,52 @@ func (x *SendEventBatchResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendEventBatchResponse.ProtoReflect.Descriptor instead.
-func (*SendEventBatchResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{94}
-}
-
-func (x *SendEventBatchResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+// Deprecated: Use ResumeMasterResponse.ProtoReflect.Descriptor instead.
+func (*ResumeMasterResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{134}
 }
 
-func (x *SendEventBatchResponse) GetMessage() string {
+func (x *ResumeMasterResponse) GetVersion() string {
 	if x != nil {
-		return x.Message
+		return x.Version
 	}
 	return ""
 }
 
-func (x *SendEventBatchResponse) GetEventsReceived() int32 {
-	if x != nil {
-		return x.EventsReceived
-	}
-	return 0
-}
-
-func (x *SendEventBatchResponse) GetEventsProcessed() int32 {
-	if x != nil {
-		return x.EventsProcessed
-	}
-	return 0
-}
-
-func (x *SendEventBatchResponse) GetFailedEventIds() []string {
+func (x *ResumeMasterResponse) GetRestarting() bool {
 	if x != nil {
-		return x.FailedEventIds
+		return x.Restarting
 	}
-	return nil
+	return false
 }
 
-// Watcher Management Messages
-type WatcherConfig struct {
-	state      protoimpl.MessageState `protogen:"open.v1"`
-	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Type       string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`             // file, process, port, cpu, memory, etc.
-	Conditions []string               `protobuf:"bytes,3,rep,name=conditions,proto3" json:"conditions,omitempty"` // created, changed, deleted, above, below, etc.
-	Interval   string                 `protobuf:"bytes,4,opt,name=interval,proto3" json:"interval,omitempty"`
-	// Type-specific fields
-	FilePath        string  `protobuf:"bytes,10,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"`
-	CheckHash       bool    `protobuf:"varint,11,opt,name=check_hash,json=checkHash,proto3" json:"check_hash,omitempty"`
-	Recursive       bool    `protobuf:"varint,12,opt,name=recursive,proto3" json:"recursive,omitempty"`
-	ProcessName     string  `protobuf:"bytes,20,opt,name=process_name,json=processName,proto3" json:"process_name,omitempty"`
-	Pid             int32   `protobuf:"varint,21,opt,name=pid,proto3" json:"pid,omitempty"`
-	Port            int32   `protobuf:"varint,30,opt,name=port,proto3" json:"port,omitempty"`
-	Protocol        string  `protobuf:"bytes,31,opt,name=protocol,proto3" json:"protocol,omitempty"`
-	CpuThreshold    float64 `protobuf:"fixed64,40,opt,name=cpu_threshold,json=cpuThreshold,proto3" json:"cpu_threshold,omitempty"`
-	MemoryThreshold float64 `protobuf:"fixed64,41,opt,name=memory_threshold,json=memoryThreshold,proto3" json:"memory_threshold,omitempty"`
-	DiskThreshold   float64 `protobuf:"fixed64,42,opt,name=disk_threshold,json=diskThreshold,proto3" json:"disk_threshold,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type AcquireLockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Host the lock is scoped to, empty for a lock shared by all hosts
+	Scope string `protobuf:"bytes,2,opt,name=scope,proto3" json:"scope,omitempty"`
+	// Shown to runs waiting for the lock
+	Holder        string `protobuf:"bytes,3,opt,name=holder,proto3" json:"holder,omitempty"`
+	TtlSeconds    int64  `protobuf:"varint,4,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WatcherConfig) Reset() {
-	*x = WatcherConfig{}
-	mi := &file_proto_agent_proto_msgTypes[95]
+func (x *AcquireLockRequest) Reset() {
+	*x = AcquireLockRequest{}
+	mi := &file_proto_agent_proto_msgTypes[135]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WatcherConfig) String() string {
+func (x *AcquireLockRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WatcherConfig) ProtoMessage() {}
+func (*AcquireLockRequest) ProtoMessage() {}
 
-func (x *WatcherConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[95]
+func (x *AcquireLockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[135]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6259,131 +8877,139 @@ func (x *WatcherConfig) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WatcherConfig.ProtoReflect.Descriptor instead.
-func (*WatcherConfig) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{95}
+// Deprecated: Use AcquireLockRequest.ProtoReflect.Descriptor instead.
+func (*AcquireLockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{135}
 }
 
-func (x *WatcherConfig) GetId() string {
+func (x *AcquireLockRequest) GetName() string {
 	if x != nil {
-		return x.Id
+		return x.Name
 	}
 	return ""
 }
 
-func (x *WatcherConfig) GetType() string {
+func (x *AcquireLockRequest) GetScope() string {
 	if x != nil {
-		return x.Type
+		return x.Scope
 	}
 	return ""
 }
 
-func (x *WatcherConfig) GetConditions() []string {
+func (x *AcquireLockRequest) GetHolder() string {
 	if x != nil {
-		return x.Conditions
+		return x.Holder
 	}
-	return nil
+	return ""
 }
 
-func (x *WatcherConfig) GetInterval() string {
+func (x *AcquireLockRequest) GetTtlSeconds() int64 {
 	if x != nil {
-		return x.Interval
+		return x.TtlSeconds
 	}
-	return ""
+	return 0
 }
 
-func (x *WatcherConfig) GetFilePath() string {
-	if x != nil {
-		return x.FilePath
-	}
-	return ""
+type AcquireLockResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Acquired bool                   `protobuf:"varint,1,opt,name=acquired,proto3" json:"acquired,omitempty"`
+	LeaseId  string                 `protobuf:"bytes,2,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	// The current holder when the lock is taken
+	Holder        string `protobuf:"bytes,3,opt,name=holder,proto3" json:"holder,omitempty"`
+	ExpiresAt     int64  `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Message       string `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WatcherConfig) GetCheckHash() bool {
-	if x != nil {
-		return x.CheckHash
-	}
-	return false
+func (x *AcquireLockResponse) Reset() {
+	*x = AcquireLockResponse{}
+	mi := &file_proto_agent_proto_msgTypes[136]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *WatcherConfig) GetRecursive() bool {
-	if x != nil {
-		return x.Recursive
-	}
-	return false
+func (x *AcquireLockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *WatcherConfig) GetProcessName() string {
+func (*AcquireLockResponse) ProtoMessage() {}
+
+func (x *AcquireLockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[136]
 	if x != nil {
-		return x.ProcessName
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *WatcherConfig) GetPid() int32 {
-	if x != nil {
-		return x.Pid
-	}
-	return 0
+// Deprecated: Use AcquireLockResponse.ProtoReflect.Descriptor instead.
+func (*AcquireLockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{136}
 }
 
-func (x *WatcherConfig) GetPort() int32 {
+func (x *AcquireLockResponse) GetAcquired() bool {
 	if x != nil {
-		return x.Port
+		return x.Acquired
 	}
-	return 0
+	return false
 }
 
-func (x *WatcherConfig) GetProtocol() string {
+func (x *AcquireLockResponse) GetLeaseId() string {
 	if x != nil {
-		return x.Protocol
+		return x.LeaseId
 	}
 	return ""
 }
 
-func (x *WatcherConfig) GetCpuThreshold() float64 {
+func (x *AcquireLockResponse) GetHolder() string {
 	if x != nil {
-		return x.CpuThreshold
+		return x.Holder
 	}
-	return 0
+	return ""
 }
 
-func (x *WatcherConfig) GetMemoryThreshold() float64 {
+func (x *AcquireLockResponse) GetExpiresAt() int64 {
 	if x != nil {
-		return x.MemoryThreshold
+		return x.ExpiresAt
 	}
 	return 0
 }
 
-func (x *WatcherConfig) GetDiskThreshold() float64 {
+func (x *AcquireLockResponse) GetMessage() string {
 	if x != nil {
-		return x.DiskThreshold
+		return x.Message
 	}
-	return 0
+	return ""
 }
 
-type RegisterWatcherRequest struct {
+type RenewLockRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Config        *WatcherConfig         `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	LeaseId       string                 `protobuf:"bytes,1,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	TtlSeconds    int64                  `protobuf:"varint,2,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterWatcherRequest) Reset() {
-	*x = RegisterWatcherRequest{}
-	mi := &file_proto_agent_proto_msgTypes[96]
+func (x *RenewLockRequest) Reset() {
+	*x = RenewLockRequest{}
+	mi := &file_proto_agent_proto_msgTypes[137]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterWatcherRequest) String() string {
+func (x *RenewLockRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterWatcherRequest) ProtoMessage() {}
+func (*RenewLockRequest) ProtoMessage() {}
 
-func (x *RegisterWatcherRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[96]
+func (x *RenewLockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[137]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6394,42 +9020,48 @@ func (x *RegisterWatcherRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterWatcherRequest.ProtoReflect.Descriptor instead.
-func (*RegisterWatcherRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{96}
+// Deprecated: Use RenewLockRequest.ProtoReflect.Descriptor instead.
+func (*RenewLockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{137}
 }
 
-func (x *RegisterWatcherRequest) GetConfig() *WatcherConfig {
+func (x *RenewLockRequest) GetLeaseId() string {
 	if x != nil {
-		return x.Config
+		return x.LeaseId
 	}
-	return nil
+	return ""
 }
 
-type RegisterWatcherResponse struct {
+func (x *RenewLockRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+type RenewLockResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	WatcherId     string                 `protobuf:"bytes,3,opt,name=watcher_id,json=watcherId,proto3" json:"watcher_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterWatcherResponse) Reset() {
-	*x = RegisterWatcherResponse{}
-	mi := &file_proto_agent_proto_msgTypes[97]
+func (x *RenewLockResponse) Reset() {
+	*x = RenewLockResponse{}
+	mi := &file_proto_agent_proto_msgTypes[138]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterWatcherResponse) String() string {
+func (x *RenewLockResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterWatcherResponse) ProtoMessage() {}
+func (*RenewLockResponse) ProtoMessage() {}
 
-func (x *RegisterWatcherResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[97]
+func (x *RenewLockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[138]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6440,53 +9072,92 @@ func (x *RegisterWatcherResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterWatcherResponse.ProtoReflect.Descriptor instead.
-func (*RegisterWatcherResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{97}
+// Deprecated: Use RenewLockResponse.ProtoReflect.Descriptor instead.
+func (*RenewLockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{138}
 }
 
-func (x *RegisterWatcherResponse) GetSuccess() bool {
+func (x *RenewLockResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *RegisterWatcherResponse) GetMessage() string {
+func (x *RenewLockResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
-func (x *RegisterWatcherResponse) GetWatcherId() string {
+type ReleaseLockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LeaseId       string                 `protobuf:"bytes,1,opt,name=lease_id,json=leaseId,proto3" json:"lease_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseLockRequest) Reset() {
+	*x = ReleaseLockRequest{}
+	mi := &file_proto_agent_proto_msgTypes[139]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseLockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseLockRequest) ProtoMessage() {}
+
+func (x *ReleaseLockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[139]
 	if x != nil {
-		return x.WatcherId
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseLockRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseLockRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{139}
+}
+
+func (x *ReleaseLockRequest) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
 	}
 	return ""
 }
 
-type ListWatchersRequest struct {
+type ReleaseLockResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListWatchersRequest) Reset() {
-	*x = ListWatchersRequest{}
-	mi := &file_proto_agent_proto_msgTypes[98]
+func (x *ReleaseLockResponse) Reset() {
+	*x = ReleaseLockResponse{}
+	mi := &file_proto_agent_proto_msgTypes[140]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListWatchersRequest) String() string {
+func (x *ReleaseLockResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListWatchersRequest) ProtoMessage() {}
+func (*ReleaseLockResponse) ProtoMessage() {}
 
-func (x *ListWatchersRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[98]
+func (x *ReleaseLockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[140]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6497,33 +9168,48 @@ func (x *ListWatchersRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListWatchersRequest.ProtoReflect.Descriptor instead.
-func (*ListWatchersRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{98}
+// Deprecated: Use ReleaseLockResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseLockResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{140}
 }
 
-type ListWatchersResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Watchers      []*WatcherConfig       `protobuf:"bytes,1,rep,name=watchers,proto3" json:"watchers,omitempty"`
+func (x *ReleaseLockResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReleaseLockResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetReleaseRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Tag of the release; empty for the latest release
+	Tag           string `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListWatchersResponse) Reset() {
-	*x = ListWatchersResponse{}
-	mi := &file_proto_agent_proto_msgTypes[99]
+func (x *GetReleaseRequest) Reset() {
+	*x = GetReleaseRequest{}
+	mi := &file_proto_agent_proto_msgTypes[141]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListWatchersResponse) String() string {
+func (x *GetReleaseRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListWatchersResponse) ProtoMessage() {}
+func (*GetReleaseRequest) ProtoMessage() {}
 
-func (x *ListWatchersResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[99]
+func (x *GetReleaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[141]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6534,40 +9220,41 @@ func (x *ListWatchersResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListWatchersResponse.ProtoReflect.Descriptor instead.
-func (*ListWatchersResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{99}
+// Deprecated: Use GetReleaseRequest.ProtoReflect.Descriptor instead.
+func (*GetReleaseRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{141}
 }
 
-func (x *ListWatchersResponse) GetWatchers() []*WatcherConfig {
+func (x *GetReleaseRequest) GetTag() string {
 	if x != nil {
-		return x.Watchers
+		return x.Tag
 	}
-	return nil
+	return ""
 }
 
-type GetWatcherRequest struct {
+type ReleaseAsset struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	WatcherId     string                 `protobuf:"bytes,1,opt,name=watcher_id,json=watcherId,proto3" json:"watcher_id,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetWatcherRequest) Reset() {
-	*x = GetWatcherRequest{}
-	mi := &file_proto_agent_proto_msgTypes[100]
+func (x *ReleaseAsset) Reset() {
+	*x = ReleaseAsset{}
+	mi := &file_proto_agent_proto_msgTypes[142]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetWatcherRequest) String() string {
+func (x *ReleaseAsset) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetWatcherRequest) ProtoMessage() {}
+func (*ReleaseAsset) ProtoMessage() {}
 
-func (x *GetWatcherRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[100]
+func (x *ReleaseAsset) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[142]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6578,41 +9265,51 @@ func (x *GetWatcherRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetWatcherRequest.ProtoReflect.Descriptor instead.
-func (*GetWatcherRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{100}
+// Deprecated: Use ReleaseAsset.ProtoReflect.Descriptor instead.
+func (*ReleaseAsset) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{142}
 }
 
-func (x *GetWatcherRequest) GetWatcherId() string {
+func (x *ReleaseAsset) GetName() string {
 	if x != nil {
-		return x.WatcherId
+		return x.Name
 	}
 	return ""
 }
 
-type GetWatcherResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Watcher       *WatcherConfig         `protobuf:"bytes,1,opt,name=watcher,proto3" json:"watcher,omitempty"`
-	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+func (x *ReleaseAsset) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type GetReleaseResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	TagName string                 `protobuf:"bytes,1,opt,name=tag_name,json=tagName,proto3" json:"tag_name,omitempty"`
+	Name    string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Assets  []*ReleaseAsset        `protobuf:"bytes,3,rep,name=assets,proto3" json:"assets,omitempty"`
+	// When the master last fetched the release from GitHub
+	FetchedAt     int64 `protobuf:"varint,4,opt,name=fetched_at,json=fetchedAt,proto3" json:"fetched_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetWatcherResponse) Reset() {
-	*x = GetWatcherResponse{}
-	mi := &file_proto_agent_proto_msgTypes[101]
+func (x *GetReleaseResponse) Reset() {
+	*x = GetReleaseResponse{}
+	mi := &file_proto_agent_proto_msgTypes[143]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetWatcherResponse) String() string {
+func (x *GetReleaseResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetWatcherResponse) ProtoMessage() {}
+func (*GetReleaseResponse) ProtoMessage() {}
 
-func (x *GetWatcherResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[101]
+func (x *GetReleaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[143]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6623,47 +9320,63 @@ func (x *GetWatcherResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetWatcherResponse.ProtoReflect.Descriptor instead.
-func (*GetWatcherResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{101}
+// Deprecated: Use GetReleaseResponse.ProtoReflect.Descriptor instead.
+func (*GetReleaseResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{143}
 }
 
-func (x *GetWatcherResponse) GetWatcher() *WatcherConfig {
+func (x *GetReleaseResponse) GetTagName() string {
 	if x != nil {
-		return x.Watcher
+		return x.TagName
+	}
+	return ""
+}
+
+func (x *GetReleaseResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetReleaseResponse) GetAssets() []*ReleaseAsset {
+	if x != nil {
+		return x.Assets
 	}
 	return nil
 }
 
-func (x *GetWatcherResponse) GetFound() bool {
+func (x *GetReleaseResponse) GetFetchedAt() int64 {
 	if x != nil {
-		return x.Found
+		return x.FetchedAt
 	}
-	return false
+	return 0
 }
 
-type RemoveWatcherRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	WatcherId     string                 `protobuf:"bytes,1,opt,name=watcher_id,json=watcherId,proto3" json:"watcher_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type RebootAgentRequest struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	AgentName           string                 `protobuf:"bytes,1,opt,name=agent_name,json=agentName,proto3" json:"agent_name,omitempty"`
+	Drain               bool                   `protobuf:"varint,2,opt,name=drain,proto3" json:"drain,omitempty"`
+	DrainTimeoutSeconds int64                  `protobuf:"varint,3,opt,name=drain_timeout_seconds,json=drainTimeoutSeconds,proto3" json:"drain_timeout_seconds,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
-func (x *RemoveWatcherRequest) Reset() {
-	*x = RemoveWatcherRequest{}
-	mi := &file_proto_agent_proto_msgTypes[102]
+func (x *RebootAgentRequest) Reset() {
+	*x = RebootAgentRequest{}
+	mi := &file_proto_agent_proto_msgTypes[144]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RemoveWatcherRequest) String() string {
+func (x *RebootAgentRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RemoveWatcherRequest) ProtoMessage() {}
+func (*RebootAgentRequest) ProtoMessage() {}
 
-func (x *RemoveWatcherRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[102]
+func (x *RebootAgentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[144]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6674,41 +9387,54 @@ func (x *RemoveWatcherRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RemoveWatcherRequest.ProtoReflect.Descriptor instead.
-func (*RemoveWatcherRequest) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{102}
+// Deprecated: Use RebootAgentRequest.ProtoReflect.Descriptor instead.
+func (*RebootAgentRequest) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{144}
 }
 
-func (x *RemoveWatcherRequest) GetWatcherId() string {
+func (x *RebootAgentRequest) GetAgentName() string {
 	if x != nil {
-		return x.WatcherId
+		return x.AgentName
 	}
 	return ""
 }
 
-type RemoveWatcherResponse struct {
+func (x *RebootAgentRequest) GetDrain() bool {
+	if x != nil {
+		return x.Drain
+	}
+	return false
+}
+
+func (x *RebootAgentRequest) GetDrainTimeoutSeconds() int64 {
+	if x != nil {
+		return x.DrainTimeoutSeconds
+	}
+	return 0
+}
+
+type RebootAgentResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	AgentAddress  string                 `protobuf:"bytes,1,opt,name=agent_address,json=agentAddress,proto3" json:"agent_address,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RemoveWatcherResponse) Reset() {
-	*x = RemoveWatcherResponse{}
-	mi := &file_proto_agent_proto_msgTypes[103]
+func (x *RebootAgentResponse) Reset() {
+	*x = RebootAgentResponse{}
+	mi := &file_proto_agent_proto_msgTypes[145]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RemoveWatcherResponse) String() string {
+func (x *RebootAgentResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RemoveWatcherResponse) ProtoMessage() {}
+func (*RebootAgentResponse) ProtoMessage() {}
 
-func (x *RemoveWatcherResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_agent_proto_msgTypes[103]
+func (x *RebootAgentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_agent_proto_msgTypes[145]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -6719,21 +9445,14 @@ func (x *RemoveWatcherResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RemoveWatcherResponse.ProtoReflect.Descriptor instead.
-func (*RemoveWatcherResponse) Descriptor() ([]byte, []int) {
-	return file_proto_agent_proto_rawDescGZIP(), []int{103}
-}
-
-func (x *RemoveWatcherResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
+// Deprecated: Use RebootAgentResponse.ProtoReflect.Descriptor instead.
+func (*RebootAgentResponse) Descriptor() ([]byte, []int) {
+	return file_proto_agent_proto_rawDescGZIP(), []int{145}
 }
 
-func (x *RemoveWatcherResponse) GetMessage() string {
+func (x *RebootAgentResponse) GetAgentAddress() string {
 	if x != nil {
-		return x.Message
+		return x.AgentAddress
 	}
 	return ""
 }
@@ -6744,7 +9463,11 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\n" +
 	"\x11proto/agent.proto\x12\x05agent\"\x11\n" +
 	"\x0fShutdownRequest\"\x12\n" +
-	"\x10ShutdownResponse\"t\n" +
+	"\x10ShutdownResponse\"Y\n" +
+	"\rRebootRequest\x12\x14\n" +
+	"\x05drain\x18\x01 \x01(\bR\x05drain\x122\n" +
+	"\x15drain_timeout_seconds\x18\x02 \x01(\x03R\x13drainTimeoutSeconds\"\x10\n" +
+	"\x0eRebootResponse\"t\n" +
 	"\x12UpdateAgentRequest\x12%\n" +
 	"\x0etarget_version\x18\x01 \x01(\tR\rtargetVersion\x12\x14\n" +
 	"\x05force\x18\x02 \x01(\bR\x05force\x12!\n" +
@@ -6755,7 +9478,7 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\vold_version\x18\x03 \x01(\tR\n" +
 	"oldVersion\x12\x1f\n" +
 	"\vnew_version\x18\x04 \x01(\tR\n" +
-	"newVersion\"\xa1\x01\n" +
+	"newVersion\"\xae\x02\n" +
 	"\x12ExecuteTaskRequest\x12\x1b\n" +
 	"\ttask_name\x18\x01 \x01(\tR\btaskName\x12\x1d\n" +
 	"\n" +
@@ -6763,18 +9486,87 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\n" +
 	"lua_script\x18\x03 \x01(\tR\tluaScript\x12\x1c\n" +
 	"\tworkspace\x18\x04 \x01(\fR\tworkspace\x12\x12\n" +
-	"\x04user\x18\x05 \x01(\tR\x04user\"e\n" +
+	"\x04user\x18\x05 \x01(\tR\x04user\x12\x1c\n" +
+	"\tapprovals\x18\x06 \x03(\tR\tapprovals\x12'\n" +
+	"\x0fidempotency_key\x18\a \x01(\tR\x0eidempotencyKey\x12#\n" +
+	"\rsudo_password\x18\b \x01(\fR\fsudoPassword\x12\x1f\n" +
+	"\vinputs_json\x18\t \x01(\tR\n" +
+	"inputsJson\"\xa5\x02\n" +
 	"\x13ExecuteTaskResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x16\n" +
 	"\x06output\x18\x02 \x01(\tR\x06output\x12\x1c\n" +
-	"\tworkspace\x18\x03 \x01(\fR\tworkspace\"Z\n" +
+	"\tworkspace\x18\x03 \x01(\fR\tworkspace\x12&\n" +
+	"\x05usage\x18\x04 \x01(\v2\x10.agent.TaskUsageR\x05usage\x12!\n" +
+	"\foutputs_json\x18\x05 \x01(\tR\voutputsJson\x12!\n" +
+	"\fchanges_json\x18\x06 \x01(\tR\vchangesJson\x12&\n" +
+	"\x0fslow_calls_json\x18\a \x01(\tR\rslowCallsJson\x12(\n" +
+	"\x10set_outputs_json\x18\b \x01(\tR\x0esetOutputsJson\"\x91\x01\n" +
+	"\tTaskUsage\x12\x1e\n" +
+	"\vcpu_time_ms\x18\x01 \x01(\x03R\tcpuTimeMs\x12$\n" +
+	"\x0epeak_rss_bytes\x18\x02 \x01(\x04R\fpeakRssBytes\x12\x1d\n" +
+	"\n" +
+	"read_bytes\x18\x03 \x01(\x04R\treadBytes\x12\x1f\n" +
+	"\vwrite_bytes\x18\x04 \x01(\x04R\n" +
+	"writeBytes\"\x9a\x01\n" +
+	"\x10ExecuteTaskEvent\x12!\n" +
+	"\foutput_chunk\x18\x01 \x01(\tR\voutputChunk\x122\n" +
+	"\x06result\x18\x02 \x01(\v2\x1a.agent.ExecuteTaskResponseR\x06result\x12/\n" +
+	"\bprogress\x18\x03 \x01(\v2\x13.agent.TaskProgressR\bprogress\"B\n" +
+	"\fTaskProgress\x12\x18\n" +
+	"\apercent\x18\x01 \x01(\x01R\apercent\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xcd\x02\n" +
+	"\x13ExecuteTasksRequest\x12\x1d\n" +
+	"\n" +
+	"task_names\x18\x01 \x03(\tR\ttaskNames\x12\x1d\n" +
+	"\n" +
+	"task_group\x18\x02 \x01(\tR\ttaskGroup\x12\x1d\n" +
+	"\n" +
+	"lua_script\x18\x03 \x01(\tR\tluaScript\x12\x1c\n" +
+	"\tworkspace\x18\x04 \x01(\fR\tworkspace\x12\x12\n" +
+	"\x04user\x18\x05 \x01(\tR\x04user\x12\x1c\n" +
+	"\tapprovals\x18\x06 \x03(\tR\tapprovals\x12\x1a\n" +
+	"\bparallel\x18\a \x01(\bR\bparallel\x12'\n" +
+	"\x0fidempotency_key\x18\b \x01(\tR\x0eidempotencyKey\x12#\n" +
+	"\rsudo_password\x18\t \x01(\fR\fsudoPassword\x12\x1f\n" +
+	"\vinputs_json\x18\n" +
+	" \x01(\tR\n" +
+	"inputsJson\"\x10\n" +
+	"\x0eSudoKeyRequest\"0\n" +
+	"\x0fSudoKeyResponse\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\fR\tpublicKey\"\xee\x01\n" +
+	"\rTaskRunResult\x12\x1b\n" +
+	"\ttask_name\x18\x01 \x01(\tR\btaskName\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1f\n" +
+	"\vduration_ms\x18\x04 \x01(\x03R\n" +
+	"durationMs\x12&\n" +
+	"\x05usage\x18\x05 \x01(\v2\x10.agent.TaskUsageR\x05usage\x12!\n" +
+	"\fchanges_json\x18\x06 \x01(\tR\vchangesJson\x12&\n" +
+	"\x0fslow_calls_json\x18\a \x01(\tR\rslowCallsJson\"d\n" +
+	"\x14ExecuteTasksResponse\x12.\n" +
+	"\aresults\x18\x01 \x03(\v2\x14.agent.TaskRunResultR\aresults\x12\x1c\n" +
+	"\tworkspace\x18\x02 \x01(\fR\tworkspace\"=\n" +
+	"\x12TaskOutcomeRequest\x12'\n" +
+	"\x0fidempotency_key\x18\x01 \x01(\tR\x0eidempotencyKey\"\xd8\x01\n" +
+	"\x13TaskOutcomeResponse\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12!\n" +
+	"\foutputs_json\x18\x04 \x01(\tR\voutputsJson\x12.\n" +
+	"\aresults\x18\x05 \x03(\v2\x14.agent.TaskRunResultR\aresults\x12(\n" +
+	"\x10set_outputs_json\x18\x06 \x01(\tR\x0esetOutputsJson\"\xa4\x01\n" +
 	"\x14RegisterAgentRequest\x12\x1d\n" +
 	"\n" +
 	"agent_name\x18\x01 \x01(\tR\tagentName\x12#\n" +
-	"\ragent_address\x18\x02 \x01(\tR\fagentAddress\"K\n" +
+	"\ragent_address\x18\x02 \x01(\tR\fagentAddress\x12\x1d\n" +
+	"\n" +
+	"join_token\x18\x03 \x01(\tR\tjoinToken\x12)\n" +
+	"\x10agent_credential\x18\x04 \x01(\tR\x0fagentCredential\"v\n" +
 	"\x15RegisterAgentResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"\x82\x02\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12)\n" +
+	"\x10agent_credential\x18\x03 \x01(\tR\x0fagentCredential\"\xa7\x02\n" +
 	"\tAgentInfo\x12\x1d\n" +
 	"\n" +
 	"agent_name\x18\x01 \x01(\tR\tagentName\x12#\n" +
@@ -6783,7 +9575,8 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\x06status\x18\x04 \x01(\tR\x06status\x12.\n" +
 	"\x13last_info_collected\x18\x05 \x01(\x03R\x11lastInfoCollected\x12(\n" +
 	"\x10system_info_json\x18\x06 \x01(\tR\x0esystemInfoJson\x12\x18\n" +
-	"\aversion\x18\a \x01(\tR\aversion\"\x13\n" +
+	"\aversion\x18\a \x01(\tR\aversion\x12#\n" +
+	"\rcircuit_state\x18\b \x01(\tR\fcircuitState\"\x13\n" +
 	"\x11ListAgentsRequest\">\n" +
 	"\x12ListAgentsResponse\x12(\n" +
 	"\x06agents\x18\x01 \x03(\v2\x10.agent.AgentInfoR\x06agents\"1\n" +
@@ -6903,12 +9696,14 @@ const file_proto_agent_proto_rawDesc = "" +
 	"partitions\x18\x01 \x03(\v2\x14.agent.DiskPartitionR\n" +
 	"partitions\x12-\n" +
 	"\x13total_io_read_bytes\x18\x02 \x01(\x04R\x10totalIoReadBytes\x12/\n" +
-	"\x14total_io_write_bytes\x18\x03 \x01(\x04R\x11totalIoWriteBytes\"e\n" +
+	"\x14total_io_write_bytes\x18\x03 \x01(\x04R\x11totalIoWriteBytes\"\xa2\x01\n" +
 	"\x11StreamLogsRequest\x12\x19\n" +
 	"\blog_file\x18\x01 \x01(\tR\alogFile\x12\x1d\n" +
 	"\n" +
 	"tail_lines\x18\x02 \x01(\x05R\ttailLines\x12\x16\n" +
-	"\x06follow\x18\x03 \x01(\bR\x06follow\"p\n" +
+	"\x06follow\x18\x03 \x01(\bR\x06follow\x12!\n" +
+	"\flevel_filter\x18\x04 \x01(\tR\vlevelFilter\x12\x18\n" +
+	"\apattern\x18\x05 \x01(\tR\apattern\"p\n" +
 	"\bLogEntry\x12\x1c\n" +
 	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x14\n" +
 	"\x05level\x18\x02 \x01(\tR\x05level\x12\x18\n" +
@@ -7163,17 +9958,23 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\fthread_count\x18\v \x01(\x05R\vthreadCount\x12%\n" +
 	"\x0ekernel_version\x18\f \x01(\tR\rkernelVersion\x12\x1d\n" +
 	"\n" +
-	"os_version\x18\r \x01(\tR\tosVersion\"\xa1\x01\n" +
+	"os_version\x18\r \x01(\tR\tosVersion\"\x99\x02\n" +
 	"\x11RecentLogsRequest\x12\x1b\n" +
 	"\tmax_lines\x18\x01 \x01(\x05R\bmaxLines\x12!\n" +
 	"\flevel_filter\x18\x02 \x01(\tR\vlevelFilter\x12#\n" +
 	"\rsource_filter\x18\x03 \x01(\tR\fsourceFilter\x12'\n" +
-	"\x0fsince_timestamp\x18\x04 \x01(\x03R\x0esinceTimestamp\"u\n" +
+	"\x0fsince_timestamp\x18\x04 \x01(\x03R\x0esinceTimestamp\x12'\n" +
+	"\x0funtil_timestamp\x18\x05 \x01(\x03R\x0euntilTimestamp\x12\x18\n" +
+	"\apattern\x18\x06 \x01(\tR\apattern\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\a \x01(\tR\tpageToken\x12\x14\n" +
+	"\x05limit\x18\b \x01(\x05R\x05limit\"\x9d\x01\n" +
 	"\x12RecentLogsResponse\x12#\n" +
 	"\x04logs\x18\x01 \x03(\v2\x0f.agent.LogEntryR\x04logs\x12\x1f\n" +
 	"\vtotal_count\x18\x02 \x01(\x05R\n" +
 	"totalCount\x12\x19\n" +
-	"\bhas_more\x18\x03 \x01(\bR\ahasMore\"\\\n" +
+	"\bhas_more\x18\x03 \x01(\bR\ahasMore\x12&\n" +
+	"\x0fnext_page_token\x18\x04 \x01(\tR\rnextPageToken\"\\\n" +
 	"\x12ConnectionsRequest\x12!\n" +
 	"\fstate_filter\x18\x01 \x01(\tR\vstateFilter\x12#\n" +
 	"\rinclude_local\x18\x02 \x01(\bR\fincludeLocal\"\xc0\x02\n" +
@@ -7264,7 +10065,27 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\ftotal_errors\x18\a \x01(\x05R\vtotalErrors\x1a:\n" +
 	"\fSummaryEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xbd\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"2\n" +
+	"\x16VerifyToolchainRequest\x12\x18\n" +
+	"\amodules\x18\x01 \x03(\tR\amodules\"\x84\x01\n" +
+	"\n" +
+	"ToolStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x1e\n" +
+	"\n" +
+	"functional\x18\x04 \x01(\bR\n" +
+	"functional\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"q\n" +
+	"\x10ModuleCapability\x12\x16\n" +
+	"\x06module\x18\x01 \x01(\tR\x06module\x12\x1c\n" +
+	"\tavailable\x18\x02 \x01(\bR\tavailable\x12'\n" +
+	"\x05tools\x18\x03 \x03(\v2\x11.agent.ToolStatusR\x05tools\"\x99\x01\n" +
+	"\x17VerifyToolchainResponse\x121\n" +
+	"\amodules\x18\x01 \x03(\v2\x17.agent.ModuleCapabilityR\amodules\x12\x0e\n" +
+	"\x02os\x18\x02 \x01(\tR\x02os\x12\x12\n" +
+	"\x04arch\x18\x03 \x01(\tR\x04arch\x12'\n" +
+	"\x0fcheck_timestamp\x18\x04 \x01(\x03R\x0echeckTimestamp\"\xc3\x02\n" +
 	"\n" +
 	"ShellInput\x12\x18\n" +
 	"\acommand\x18\x01 \x01(\tR\acommand\x12\x1d\n" +
@@ -7275,13 +10096,26 @@ const file_proto_agent_proto_rawDesc = "" +
 	"windowRows\x12\x1f\n" +
 	"\vwindow_cols\x18\x05 \x01(\rR\n" +
 	"windowCols\x12\x16\n" +
-	"\x06resize\x18\x06 \x01(\bR\x06resize\"\x8e\x01\n" +
+	"\x06resize\x18\x06 \x01(\bR\x06resize\x12\x16\n" +
+	"\x06record\x18\a \x01(\bR\x06record\x120\n" +
+	"\x14idle_timeout_seconds\x18\b \x01(\rR\x12idleTimeoutSeconds\x12\x1b\n" +
+	"\tread_only\x18\t \x01(\bR\breadOnly\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\n" +
+	" \x01(\tR\tsessionId\"\x8d\x02\n" +
 	"\vShellOutput\x12\x16\n" +
 	"\x06stdout\x18\x01 \x01(\fR\x06stdout\x12\x16\n" +
 	"\x06stderr\x18\x02 \x01(\fR\x06stderr\x12\x1b\n" +
 	"\texit_code\x18\x03 \x01(\x05R\bexitCode\x12\x1c\n" +
 	"\tcompleted\x18\x04 \x01(\bR\tcompleted\x12\x14\n" +
-	"\x05error\x18\x05 \x01(\tR\x05error\"\xd1\x02\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x06 \x01(\tR\tsessionId\x12\x1c\n" +
+	"\trecording\x18\a \x01(\tR\trecording\x12\x1f\n" +
+	"\vwindow_rows\x18\b \x01(\rR\n" +
+	"windowRows\x12\x1f\n" +
+	"\vwindow_cols\x18\t \x01(\rR\n" +
+	"windowCols\"\xd1\x02\n" +
 	"\tEventData\x12\x19\n" +
 	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x1d\n" +
 	"\n" +
@@ -7312,14 +10146,15 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12'\n" +
 	"\x0fevents_received\x18\x03 \x01(\x05R\x0eeventsReceived\x12)\n" +
 	"\x10events_processed\x18\x04 \x01(\x05R\x0feventsProcessed\x12(\n" +
-	"\x10failed_event_ids\x18\x05 \x03(\tR\x0efailedEventIds\"\xa5\x03\n" +
+	"\x10failed_event_ids\x18\x05 \x03(\tR\x0efailedEventIds\"\xbb\x03\n" +
 	"\rWatcherConfig\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1e\n" +
 	"\n" +
 	"conditions\x18\x03 \x03(\tR\n" +
 	"conditions\x12\x1a\n" +
-	"\binterval\x18\x04 \x01(\tR\binterval\x12\x1b\n" +
+	"\binterval\x18\x04 \x01(\tR\binterval\x12\x14\n" +
+	"\x05group\x18\x05 \x01(\tR\x05group\x12\x1b\n" +
 	"\tfile_path\x18\n" +
 	" \x01(\tR\bfilePath\x12\x1d\n" +
 	"\n" +
@@ -7353,12 +10188,130 @@ const file_proto_agent_proto_rawDesc = "" +
 	"watcher_id\x18\x01 \x01(\tR\twatcherId\"K\n" +
 	"\x15RemoveWatcherResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xf5\x0e\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x15\n" +
+	"\x13ReloadConfigRequest\"\x12\n" +
+	"\x10GetConfigRequest\"\x97\x01\n" +
+	"\x13AgentConfigResponse\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1f\n" +
+	"\vconfig_yaml\x18\x02 \x01(\tR\n" +
+	"configYaml\x12\x1b\n" +
+	"\tloaded_at\x18\x03 \x01(\x03R\bloadedAt\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x18\n" +
+	"\achanged\x18\x05 \x03(\tR\achanged\"\xa1\x01\n" +
+	"\fArtifactPeer\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\x01 \x01(\tR\tagentName\x12#\n" +
+	"\rcache_address\x18\x02 \x01(\tR\fcacheAddress\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x04 \x01(\tR\x06sha256\x12!\n" +
+	"\fannounced_at\x18\x05 \x01(\x03R\vannouncedAt\"\xad\x01\n" +
+	"\x17AnnounceArtifactRequest\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\x01 \x01(\tR\tagentName\x12#\n" +
+	"\rcache_address\x18\x02 \x01(\tR\fcacheAddress\x12\x10\n" +
+	"\x03key\x18\x03 \x01(\tR\x03key\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x06 \x01(\tR\x06sha256\"N\n" +
+	"\x18AnnounceArtifactResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"T\n" +
+	"\x15LookupArtifactRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
+	"\x10requesting_agent\x18\x02 \x01(\tR\x0frequestingAgent\"C\n" +
+	"\x16LookupArtifactResponse\x12)\n" +
+	"\x05peers\x18\x01 \x03(\v2\x13.agent.ArtifactPeerR\x05peers\"\xb0\x01\n" +
+	"\n" +
+	"FactChange\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\x01 \x01(\tR\tagentName\x12\x12\n" +
+	"\x04fact\x18\x02 \x01(\tR\x04fact\x12\x16\n" +
+	"\x06change\x18\x03 \x01(\tR\x06change\x12\x1b\n" +
+	"\told_value\x18\x04 \x01(\tR\boldValue\x12\x1b\n" +
+	"\tnew_value\x18\x05 \x01(\tR\bnewValue\x12\x1d\n" +
+	"\n" +
+	"changed_at\x18\x06 \x01(\x03R\tchangedAt\"s\n" +
+	"\x12FactHistoryRequest\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\x01 \x01(\tR\tagentName\x12\x14\n" +
+	"\x05since\x18\x02 \x01(\x03R\x05since\x12\x12\n" +
+	"\x04fact\x18\x03 \x01(\tR\x04fact\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"B\n" +
+	"\x13FactHistoryResponse\x12+\n" +
+	"\achanges\x18\x01 \x03(\v2\x11.agent.FactChangeR\achanges\"=\n" +
+	"\x12DrainMasterRequest\x12'\n" +
+	"\x0ftimeout_seconds\x18\x01 \x01(\x05R\x0etimeoutSeconds\"\xa1\x01\n" +
+	"\x13DrainMasterResponse\x12\x18\n" +
+	"\adrained\x18\x01 \x01(\bR\adrained\x12\x1b\n" +
+	"\tin_flight\x18\x02 \x01(\x05R\binFlight\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x1e\n" +
+	"\n" +
+	"executable\x18\x04 \x01(\tR\n" +
+	"executable\x12\x19\n" +
+	"\bdata_dir\x18\x05 \x01(\tR\adataDir\"/\n" +
+	"\x13ResumeMasterRequest\x12\x18\n" +
+	"\arestart\x18\x01 \x01(\bR\arestart\"P\n" +
+	"\x14ResumeMasterResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x1e\n" +
+	"\n" +
+	"restarting\x18\x02 \x01(\bR\n" +
+	"restarting\"w\n" +
+	"\x12AcquireLockRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05scope\x18\x02 \x01(\tR\x05scope\x12\x16\n" +
+	"\x06holder\x18\x03 \x01(\tR\x06holder\x12\x1f\n" +
+	"\vttl_seconds\x18\x04 \x01(\x03R\n" +
+	"ttlSeconds\"\x9d\x01\n" +
+	"\x13AcquireLockResponse\x12\x1a\n" +
+	"\bacquired\x18\x01 \x01(\bR\bacquired\x12\x19\n" +
+	"\blease_id\x18\x02 \x01(\tR\aleaseId\x12\x16\n" +
+	"\x06holder\x18\x03 \x01(\tR\x06holder\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\"N\n" +
+	"\x10RenewLockRequest\x12\x19\n" +
+	"\blease_id\x18\x01 \x01(\tR\aleaseId\x12\x1f\n" +
+	"\vttl_seconds\x18\x02 \x01(\x03R\n" +
+	"ttlSeconds\"G\n" +
+	"\x11RenewLockResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"/\n" +
+	"\x12ReleaseLockRequest\x12\x19\n" +
+	"\blease_id\x18\x01 \x01(\tR\aleaseId\"I\n" +
+	"\x13ReleaseLockResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"%\n" +
+	"\x11GetReleaseRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\"4\n" +
+	"\fReleaseAsset\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\"\x8f\x01\n" +
+	"\x12GetReleaseResponse\x12\x19\n" +
+	"\btag_name\x18\x01 \x01(\tR\atagName\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12+\n" +
+	"\x06assets\x18\x03 \x03(\v2\x13.agent.ReleaseAssetR\x06assets\x12\x1d\n" +
+	"\n" +
+	"fetched_at\x18\x04 \x01(\x03R\tfetchedAt\"}\n" +
+	"\x12RebootAgentRequest\x12\x1d\n" +
+	"\n" +
+	"agent_name\x18\x01 \x01(\tR\tagentName\x12\x14\n" +
+	"\x05drain\x18\x02 \x01(\bR\x05drain\x122\n" +
+	"\x15drain_timeout_seconds\x18\x03 \x01(\x03R\x13drainTimeoutSeconds\":\n" +
+	"\x13RebootAgentResponse\x12#\n" +
+	"\ragent_address\x18\x01 \x01(\tR\fagentAddress2\xb4\x14\n" +
 	"\x05Agent\x12D\n" +
-	"\vExecuteTask\x12\x19.agent.ExecuteTaskRequest\x1a\x1a.agent.ExecuteTaskResponse\x12E\n" +
+	"\vExecuteTask\x12\x19.agent.ExecuteTaskRequest\x1a\x1a.agent.ExecuteTaskResponse\x12I\n" +
+	"\x11ExecuteTaskStream\x12\x19.agent.ExecuteTaskRequest\x1a\x17.agent.ExecuteTaskEvent0\x01\x12G\n" +
+	"\fExecuteTasks\x12\x1a.agent.ExecuteTasksRequest\x1a\x1b.agent.ExecuteTasksResponse\x12E\n" +
+	"\n" +
+	"CheckTasks\x12\x1a.agent.ExecuteTasksRequest\x1a\x1b.agent.ExecuteTasksResponse\x12G\n" +
+	"\x0eGetTaskOutcome\x12\x19.agent.TaskOutcomeRequest\x1a\x1a.agent.TaskOutcomeResponse\x12;\n" +
+	"\n" +
+	"GetSudoKey\x12\x15.agent.SudoKeyRequest\x1a\x16.agent.SudoKeyResponse\x12E\n" +
 	"\n" +
 	"RunCommand\x12\x18.agent.RunCommandRequest\x1a\x1b.agent.StreamOutputResponse0\x01\x12;\n" +
-	"\bShutdown\x12\x16.agent.ShutdownRequest\x1a\x17.agent.ShutdownResponse\x12D\n" +
+	"\bShutdown\x12\x16.agent.ShutdownRequest\x1a\x17.agent.ShutdownResponse\x125\n" +
+	"\x06Reboot\x12\x14.agent.RebootRequest\x1a\x15.agent.RebootResponse\x12D\n" +
 	"\vUpdateAgent\x12\x19.agent.UpdateAgentRequest\x1a\x1a.agent.UpdateAgentResponse\x12M\n" +
 	"\x10GetResourceUsage\x12\x1b.agent.ResourceUsageRequest\x1a\x1c.agent.ResourceUsageResponse\x12G\n" +
 	"\x0eGetProcessList\x12\x19.agent.ProcessListRequest\x1a\x1a.agent.ProcessListResponse\x12G\n" +
@@ -7373,18 +10326,21 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\rInstallModule\x12\x1b.agent.InstallModuleRequest\x1a\x1c.agent.InstallModuleResponse\x12D\n" +
 	"\x13GetInstalledModules\x12\x15.agent.ModulesRequest\x1a\x16.agent.ModulesResponse\x12S\n" +
 	"\x12GetDetailedMetrics\x12\x1d.agent.DetailedMetricsRequest\x1a\x1e.agent.DetailedMetricsResponse\x12D\n" +
-	"\rGetRecentLogs\x12\x18.agent.RecentLogsRequest\x1a\x19.agent.RecentLogsResponse\x12M\n" +
+	"\rGetRecentLogs\x12\x18.agent.RecentLogsRequest\x1a\x19.agent.RecentLogsResponse\x12I\n" +
+	"\x10StreamRecentLogs\x12\x18.agent.RecentLogsRequest\x1a\x19.agent.RecentLogsResponse0\x01\x12M\n" +
 	"\x14GetActiveConnections\x12\x19.agent.ConnectionsRequest\x1a\x1a.agent.ConnectionsResponse\x12J\n" +
 	"\x0fGetSystemErrors\x12\x1a.agent.SystemErrorsRequest\x1a\x1b.agent.SystemErrorsResponse\x12\\\n" +
 	"\x15GetPerformanceHistory\x12 .agent.PerformanceHistoryRequest\x1a!.agent.PerformanceHistoryResponse\x12Q\n" +
-	"\x0eDiagnoseHealth\x12\x1e.agent.HealthDiagnosticRequest\x1a\x1f.agent.HealthDiagnosticResponse\x12=\n" +
+	"\x0eDiagnoseHealth\x12\x1e.agent.HealthDiagnosticRequest\x1a\x1f.agent.HealthDiagnosticResponse\x12P\n" +
+	"\x0fVerifyToolchain\x12\x1d.agent.VerifyToolchainRequest\x1a\x1e.agent.VerifyToolchainResponse\x12=\n" +
 	"\x10InteractiveShell\x12\x11.agent.ShellInput\x1a\x12.agent.ShellOutput(\x010\x01\x12P\n" +
 	"\x0fRegisterWatcher\x12\x1d.agent.RegisterWatcherRequest\x1a\x1e.agent.RegisterWatcherResponse\x12G\n" +
 	"\fListWatchers\x12\x1a.agent.ListWatchersRequest\x1a\x1b.agent.ListWatchersResponse\x12A\n" +
 	"\n" +
 	"GetWatcher\x12\x18.agent.GetWatcherRequest\x1a\x19.agent.GetWatcherResponse\x12J\n" +
-	"\rRemoveWatcher\x12\x1b.agent.RemoveWatcherRequest\x1a\x1c.agent.RemoveWatcherResponse2\xea\n" +
-	"\n" +
+	"\rRemoveWatcher\x12\x1b.agent.RemoveWatcherRequest\x1a\x1c.agent.RemoveWatcherResponse\x12F\n" +
+	"\fReloadConfig\x12\x1a.agent.ReloadConfigRequest\x1a\x1a.agent.AgentConfigResponse\x12@\n" +
+	"\tGetConfig\x12\x17.agent.GetConfigRequest\x1a\x1a.agent.AgentConfigResponse2\xbb\x10\n" +
 	"\rAgentRegistry\x12J\n" +
 	"\rRegisterAgent\x12\x1b.agent.RegisterAgentRequest\x1a\x1c.agent.RegisterAgentResponse\x12A\n" +
 	"\n" +
@@ -7404,7 +10360,18 @@ const file_proto_agent_proto_rawDesc = "" +
 	"\x14GetAggregatedMetrics\x12\x1f.agent.AggregatedMetricsRequest\x1a .agent.AggregatedMetricsResponse\x12D\n" +
 	"\x11StreamAgentEvents\x12\x1a.agent.StreamEventsRequest\x1a\x11.agent.AgentEvent0\x01\x12>\n" +
 	"\tSendEvent\x12\x17.agent.SendEventRequest\x1a\x18.agent.SendEventResponse\x12M\n" +
-	"\x0eSendEventBatch\x12\x1c.agent.SendEventBatchRequest\x1a\x1d.agent.SendEventBatchResponseB.Z,github.com/chalkan3-sloth/sloth-runner/protob\x06proto3"
+	"\x0eSendEventBatch\x12\x1c.agent.SendEventBatchRequest\x1a\x1d.agent.SendEventBatchResponse\x12S\n" +
+	"\x10AnnounceArtifact\x12\x1e.agent.AnnounceArtifactRequest\x1a\x1f.agent.AnnounceArtifactResponse\x12M\n" +
+	"\x0eLookupArtifact\x12\x1c.agent.LookupArtifactRequest\x1a\x1d.agent.LookupArtifactResponse\x12G\n" +
+	"\x0eGetFactHistory\x12\x19.agent.FactHistoryRequest\x1a\x1a.agent.FactHistoryResponse\x12D\n" +
+	"\vDrainMaster\x12\x19.agent.DrainMasterRequest\x1a\x1a.agent.DrainMasterResponse\x12G\n" +
+	"\fResumeMaster\x12\x1a.agent.ResumeMasterRequest\x1a\x1b.agent.ResumeMasterResponse\x12D\n" +
+	"\vAcquireLock\x12\x19.agent.AcquireLockRequest\x1a\x1a.agent.AcquireLockResponse\x12>\n" +
+	"\tRenewLock\x12\x17.agent.RenewLockRequest\x1a\x18.agent.RenewLockResponse\x12D\n" +
+	"\vReleaseLock\x12\x19.agent.ReleaseLockRequest\x1a\x1a.agent.ReleaseLockResponse\x12A\n" +
+	"\n" +
+	"GetRelease\x12\x18.agent.GetReleaseRequest\x1a\x19.agent.GetReleaseResponse\x12D\n" +
+	"\vRebootAgent\x12\x19.agent.RebootAgentRequest\x1a\x1a.agent.RebootAgentResponseB.Z,github.com/chalkan3-sloth/sloth-runner/protob\x06proto3"
 
 var (
 	file_proto_agent_proto_rawDescOnce sync.Once
@@ -7418,252 +10385,345 @@ func file_proto_agent_proto_rawDescGZIP() []byte {
 	return file_proto_agent_proto_rawDescData
 }
 
-var file_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 113)
+var file_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 155)
 var file_proto_agent_proto_goTypes = []any{
 	(*ShutdownRequest)(nil),             // 0: agent.ShutdownRequest
 	(*ShutdownResponse)(nil),            // 1: agent.ShutdownResponse
-	(*UpdateAgentRequest)(nil),          // 2: agent.UpdateAgentRequest
-	(*UpdateAgentResponse)(nil),         // 3: agent.UpdateAgentResponse
-	(*ExecuteTaskRequest)(nil),          // 4: agent.ExecuteTaskRequest
-	(*ExecuteTaskResponse)(nil),         // 5: agent.ExecuteTaskResponse
-	(*RegisterAgentRequest)(nil),        // 6: agent.RegisterAgentRequest
-	(*RegisterAgentResponse)(nil),       // 7: agent.RegisterAgentResponse
-	(*AgentInfo)(nil),                   // 8: agent.AgentInfo
-	(*ListAgentsRequest)(nil),           // 9: agent.ListAgentsRequest
-	(*ListAgentsResponse)(nil),          // 10: agent.ListAgentsResponse
-	(*StopAgentRequest)(nil),            // 11: agent.StopAgentRequest
-	(*StopAgentResponse)(nil),           // 12: agent.StopAgentResponse
-	(*UnregisterAgentRequest)(nil),      // 13: agent.UnregisterAgentRequest
-	(*UnregisterAgentResponse)(nil),     // 14: agent.UnregisterAgentResponse
-	(*ExecuteCommandRequest)(nil),       // 15: agent.ExecuteCommandRequest
-	(*RunCommandRequest)(nil),           // 16: agent.RunCommandRequest
-	(*StreamOutputResponse)(nil),        // 17: agent.StreamOutputResponse
-	(*HeartbeatRequest)(nil),            // 18: agent.HeartbeatRequest
-	(*HeartbeatResponse)(nil),           // 19: agent.HeartbeatResponse
-	(*GetAgentInfoRequest)(nil),         // 20: agent.GetAgentInfoRequest
-	(*GetAgentInfoResponse)(nil),        // 21: agent.GetAgentInfoResponse
-	(*ResourceUsageRequest)(nil),        // 22: agent.ResourceUsageRequest
-	(*ResourceUsageResponse)(nil),       // 23: agent.ResourceUsageResponse
-	(*ProcessListRequest)(nil),          // 24: agent.ProcessListRequest
-	(*ProcessInfo)(nil),                 // 25: agent.ProcessInfo
-	(*ProcessListResponse)(nil),         // 26: agent.ProcessListResponse
-	(*NetworkInfoRequest)(nil),          // 27: agent.NetworkInfoRequest
-	(*NetworkInterface)(nil),            // 28: agent.NetworkInterface
-	(*NetworkInfoResponse)(nil),         // 29: agent.NetworkInfoResponse
-	(*DiskInfoRequest)(nil),             // 30: agent.DiskInfoRequest
-	(*DiskPartition)(nil),               // 31: agent.DiskPartition
-	(*DiskInfoResponse)(nil),            // 32: agent.DiskInfoResponse
-	(*StreamLogsRequest)(nil),           // 33: agent.StreamLogsRequest
-	(*LogEntry)(nil),                    // 34: agent.LogEntry
-	(*StreamMetricsRequest)(nil),        // 35: agent.StreamMetricsRequest
-	(*MetricsData)(nil),                 // 36: agent.MetricsData
-	(*RestartServiceRequest)(nil),       // 37: agent.RestartServiceRequest
-	(*RestartServiceResponse)(nil),      // 38: agent.RestartServiceResponse
-	(*EnvVarsRequest)(nil),              // 39: agent.EnvVarsRequest
-	(*EnvVarsResponse)(nil),             // 40: agent.EnvVarsResponse
-	(*SetEnvVarRequest)(nil),            // 41: agent.SetEnvVarRequest
-	(*SetEnvVarResponse)(nil),           // 42: agent.SetEnvVarResponse
-	(*InstallModuleRequest)(nil),        // 43: agent.InstallModuleRequest
-	(*InstallModuleResponse)(nil),       // 44: agent.InstallModuleResponse
-	(*ModulesRequest)(nil),              // 45: agent.ModulesRequest
-	(*ModuleInfo)(nil),                  // 46: agent.ModuleInfo
-	(*ModulesResponse)(nil),             // 47: agent.ModulesResponse
-	(*CreateGroupRequest)(nil),          // 48: agent.CreateGroupRequest
-	(*CreateGroupResponse)(nil),         // 49: agent.CreateGroupResponse
-	(*AddToGroupRequest)(nil),           // 50: agent.AddToGroupRequest
-	(*AddToGroupResponse)(nil),          // 51: agent.AddToGroupResponse
-	(*RemoveFromGroupRequest)(nil),      // 52: agent.RemoveFromGroupRequest
-	(*RemoveFromGroupResponse)(nil),     // 53: agent.RemoveFromGroupResponse
-	(*ListGroupsRequest)(nil),           // 54: agent.ListGroupsRequest
-	(*AgentGroup)(nil),                  // 55: agent.AgentGroup
-	(*ListGroupsResponse)(nil),          // 56: agent.ListGroupsResponse
-	(*DeleteGroupRequest)(nil),          // 57: agent.DeleteGroupRequest
-	(*DeleteGroupResponse)(nil),         // 58: agent.DeleteGroupResponse
-	(*BulkExecuteRequest)(nil),          // 59: agent.BulkExecuteRequest
-	(*BulkExecuteResponse)(nil),         // 60: agent.BulkExecuteResponse
-	(*MultipleAgentStatusRequest)(nil),  // 61: agent.MultipleAgentStatusRequest
-	(*AgentStatusInfo)(nil),             // 62: agent.AgentStatusInfo
-	(*MultipleAgentStatusResponse)(nil), // 63: agent.MultipleAgentStatusResponse
-	(*AggregatedMetricsRequest)(nil),    // 64: agent.AggregatedMetricsRequest
-	(*AggregatedMetricsResponse)(nil),   // 65: agent.AggregatedMetricsResponse
-	(*StreamEventsRequest)(nil),         // 66: agent.StreamEventsRequest
-	(*AgentEvent)(nil),                  // 67: agent.AgentEvent
-	(*DetailedMetricsRequest)(nil),      // 68: agent.DetailedMetricsRequest
-	(*CPUDetail)(nil),                   // 69: agent.CPUDetail
-	(*MemoryDetail)(nil),                // 70: agent.MemoryDetail
-	(*DiskDetail)(nil),                  // 71: agent.DiskDetail
-	(*NetworkDetail)(nil),               // 72: agent.NetworkDetail
-	(*DetailedMetricsResponse)(nil),     // 73: agent.DetailedMetricsResponse
-	(*RecentLogsRequest)(nil),           // 74: agent.RecentLogsRequest
-	(*RecentLogsResponse)(nil),          // 75: agent.RecentLogsResponse
-	(*ConnectionsRequest)(nil),          // 76: agent.ConnectionsRequest
-	(*ConnectionInfo)(nil),              // 77: agent.ConnectionInfo
-	(*ConnectionsResponse)(nil),         // 78: agent.ConnectionsResponse
-	(*SystemErrorsRequest)(nil),         // 79: agent.SystemErrorsRequest
-	(*SystemError)(nil),                 // 80: agent.SystemError
-	(*SystemErrorsResponse)(nil),        // 81: agent.SystemErrorsResponse
-	(*PerformanceHistoryRequest)(nil),   // 82: agent.PerformanceHistoryRequest
-	(*PerformanceSnapshot)(nil),         // 83: agent.PerformanceSnapshot
-	(*PerformanceHistoryResponse)(nil),  // 84: agent.PerformanceHistoryResponse
-	(*HealthDiagnosticRequest)(nil),     // 85: agent.HealthDiagnosticRequest
-	(*HealthIssue)(nil),                 // 86: agent.HealthIssue
-	(*HealthDiagnosticResponse)(nil),    // 87: agent.HealthDiagnosticResponse
-	(*ShellInput)(nil),                  // 88: agent.ShellInput
-	(*ShellOutput)(nil),                 // 89: agent.ShellOutput
-	(*EventData)(nil),                   // 90: agent.EventData
-	(*SendEventRequest)(nil),            // 91: agent.SendEventRequest
-	(*SendEventResponse)(nil),           // 92: agent.SendEventResponse
-	(*SendEventBatchRequest)(nil),       // 93: agent.SendEventBatchRequest
-	(*SendEventBatchResponse)(nil),      // 94: agent.SendEventBatchResponse
-	(*WatcherConfig)(nil),               // 95: agent.WatcherConfig
-	(*RegisterWatcherRequest)(nil),      // 96: agent.RegisterWatcherRequest
-	(*RegisterWatcherResponse)(nil),     // 97: agent.RegisterWatcherResponse
-	(*ListWatchersRequest)(nil),         // 98: agent.ListWatchersRequest
-	(*ListWatchersResponse)(nil),        // 99: agent.ListWatchersResponse
-	(*GetWatcherRequest)(nil),           // 100: agent.GetWatcherRequest
-	(*GetWatcherResponse)(nil),          // 101: agent.GetWatcherResponse
-	(*RemoveWatcherRequest)(nil),        // 102: agent.RemoveWatcherRequest
-	(*RemoveWatcherResponse)(nil),       // 103: agent.RemoveWatcherResponse
-	nil,                                 // 104: agent.MetricsData.CustomMetricsEntry
-	nil,                                 // 105: agent.EnvVarsResponse.VariablesEntry
-	nil,                                 // 106: agent.CreateGroupRequest.TagsEntry
-	nil,                                 // 107: agent.AgentGroup.TagsEntry
-	nil,                                 // 108: agent.AggregatedMetricsResponse.CustomMetricsEntry
-	nil,                                 // 109: agent.AgentEvent.MetadataEntry
-	nil,                                 // 110: agent.SystemError.ContextEntry
-	nil,                                 // 111: agent.HealthDiagnosticResponse.SummaryEntry
-	nil,                                 // 112: agent.EventData.DataEntry
+	(*RebootRequest)(nil),               // 2: agent.RebootRequest
+	(*RebootResponse)(nil),              // 3: agent.RebootResponse
+	(*UpdateAgentRequest)(nil),          // 4: agent.UpdateAgentRequest
+	(*UpdateAgentResponse)(nil),         // 5: agent.UpdateAgentResponse
+	(*ExecuteTaskRequest)(nil),          // 6: agent.ExecuteTaskRequest
+	(*ExecuteTaskResponse)(nil),         // 7: agent.ExecuteTaskResponse
+	(*TaskUsage)(nil),                   // 8: agent.TaskUsage
+	(*ExecuteTaskEvent)(nil),            // 9: agent.ExecuteTaskEvent
+	(*TaskProgress)(nil),                // 10: agent.TaskProgress
+	(*ExecuteTasksRequest)(nil),         // 11: agent.ExecuteTasksRequest
+	(*SudoKeyRequest)(nil),              // 12: agent.SudoKeyRequest
+	(*SudoKeyResponse)(nil),             // 13: agent.SudoKeyResponse
+	(*TaskRunResult)(nil),               // 14: agent.TaskRunResult
+	(*ExecuteTasksResponse)(nil),        // 15: agent.ExecuteTasksResponse
+	(*TaskOutcomeRequest)(nil),          // 16: agent.TaskOutcomeRequest
+	(*TaskOutcomeResponse)(nil),         // 17: agent.TaskOutcomeResponse
+	(*RegisterAgentRequest)(nil),        // 18: agent.RegisterAgentRequest
+	(*RegisterAgentResponse)(nil),       // 19: agent.RegisterAgentResponse
+	(*AgentInfo)(nil),                   // 20: agent.AgentInfo
+	(*ListAgentsRequest)(nil),           // 21: agent.ListAgentsRequest
+	(*ListAgentsResponse)(nil),          // 22: agent.ListAgentsResponse
+	(*StopAgentRequest)(nil),            // 23: agent.StopAgentRequest
+	(*StopAgentResponse)(nil),           // 24: agent.StopAgentResponse
+	(*UnregisterAgentRequest)(nil),      // 25: agent.UnregisterAgentRequest
+	(*UnregisterAgentResponse)(nil),     // 26: agent.UnregisterAgentResponse
+	(*ExecuteCommandRequest)(nil),       // 27: agent.ExecuteCommandRequest
+	(*RunCommandRequest)(nil),           // 28: agent.RunCommandRequest
+	(*StreamOutputResponse)(nil),        // 29: agent.StreamOutputResponse
+	(*HeartbeatRequest)(nil),            // 30: agent.HeartbeatRequest
+	(*HeartbeatResponse)(nil),           // 31: agent.HeartbeatResponse
+	(*GetAgentInfoRequest)(nil),         // 32: agent.GetAgentInfoRequest
+	(*GetAgentInfoResponse)(nil),        // 33: agent.GetAgentInfoResponse
+	(*ResourceUsageRequest)(nil),        // 34: agent.ResourceUsageRequest
+	(*ResourceUsageResponse)(nil),       // 35: agent.ResourceUsageResponse
+	(*ProcessListRequest)(nil),          // 36: agent.ProcessListRequest
+	(*ProcessInfo)(nil),                 // 37: agent.ProcessInfo
+	(*ProcessListResponse)(nil),         // 38: agent.ProcessListResponse
+	(*NetworkInfoRequest)(nil),          // 39: agent.NetworkInfoRequest
+	(*NetworkInterface)(nil),            // 40: agent.NetworkInterface
+	(*NetworkInfoResponse)(nil),         // 41: agent.NetworkInfoResponse
+	(*DiskInfoRequest)(nil),             // 42: agent.DiskInfoRequest
+	(*DiskPartition)(nil),               // 43: agent.DiskPartition
+	(*DiskInfoResponse)(nil),            // 44: agent.DiskInfoResponse
+	(*StreamLogsRequest)(nil),           // 45: agent.StreamLogsRequest
+	(*LogEntry)(nil),                    // 46: agent.LogEntry
+	(*StreamMetricsRequest)(nil),        // 47: agent.StreamMetricsRequest
+	(*MetricsData)(nil),                 // 48: agent.MetricsData
+	(*RestartServiceRequest)(nil),       // 49: agent.RestartServiceRequest
+	(*RestartServiceResponse)(nil),      // 50: agent.RestartServiceResponse
+	(*EnvVarsRequest)(nil),              // 51: agent.EnvVarsRequest
+	(*EnvVarsResponse)(nil),             // 52: agent.EnvVarsResponse
+	(*SetEnvVarRequest)(nil),            // 53: agent.SetEnvVarRequest
+	(*SetEnvVarResponse)(nil),           // 54: agent.SetEnvVarResponse
+	(*InstallModuleRequest)(nil),        // 55: agent.InstallModuleRequest
+	(*InstallModuleResponse)(nil),       // 56: agent.InstallModuleResponse
+	(*ModulesRequest)(nil),              // 57: agent.ModulesRequest
+	(*ModuleInfo)(nil),                  // 58: agent.ModuleInfo
+	(*ModulesResponse)(nil),             // 59: agent.ModulesResponse
+	(*CreateGroupRequest)(nil),          // 60: agent.CreateGroupRequest
+	(*CreateGroupResponse)(nil),         // 61: agent.CreateGroupResponse
+	(*AddToGroupRequest)(nil),           // 62: agent.AddToGroupRequest
+	(*AddToGroupResponse)(nil),          // 63: agent.AddToGroupResponse
+	(*RemoveFromGroupRequest)(nil),      // 64: agent.RemoveFromGroupRequest
+	(*RemoveFromGroupResponse)(nil),     // 65: agent.RemoveFromGroupResponse
+	(*ListGroupsRequest)(nil),           // 66: agent.ListGroupsRequest
+	(*AgentGroup)(nil),                  // 67: agent.AgentGroup
+	(*ListGroupsResponse)(nil),          // 68: agent.ListGroupsResponse
+	(*DeleteGroupRequest)(nil),          // 69: agent.DeleteGroupRequest
+	(*DeleteGroupResponse)(nil),         // 70: agent.DeleteGroupResponse
+	(*BulkExecuteRequest)(nil),          // 71: agent.BulkExecuteRequest
+	(*BulkExecuteResponse)(nil),         // 72: agent.BulkExecuteResponse
+	(*MultipleAgentStatusRequest)(nil),  // 73: agent.MultipleAgentStatusRequest
+	(*AgentStatusInfo)(nil),             // 74: agent.AgentStatusInfo
+	(*MultipleAgentStatusResponse)(nil), // 75: agent.MultipleAgentStatusResponse
+	(*AggregatedMetricsRequest)(nil),    // 76: agent.AggregatedMetricsRequest
+	(*AggregatedMetricsResponse)(nil),   // 77: agent.AggregatedMetricsResponse
+	(*StreamEventsRequest)(nil),         // 78: agent.StreamEventsRequest
+	(*AgentEvent)(nil),                  // 79: agent.AgentEvent
+	(*DetailedMetricsRequest)(nil),      // 80: agent.DetailedMetricsRequest
+	(*CPUDetail)(nil),                   // 81: agent.CPUDetail
+	(*MemoryDetail)(nil),                // 82: agent.MemoryDetail
+	(*DiskDetail)(nil),                  // 83: agent.DiskDetail
+	(*NetworkDetail)(nil),               // 84: agent.NetworkDetail
+	(*DetailedMetricsResponse)(nil),     // 85: agent.DetailedMetricsResponse
+	(*RecentLogsRequest)(nil),           // 86: agent.RecentLogsRequest
+	(*RecentLogsResponse)(nil),          // 87: agent.RecentLogsResponse
+	(*ConnectionsRequest)(nil),          // 88: agent.ConnectionsRequest
+	(*ConnectionInfo)(nil),              // 89: agent.ConnectionInfo
+	(*ConnectionsResponse)(nil),         // 90: agent.ConnectionsResponse
+	(*SystemErrorsRequest)(nil),         // 91: agent.SystemErrorsRequest
+	(*SystemError)(nil),                 // 92: agent.SystemError
+	(*SystemErrorsResponse)(nil),        // 93: agent.SystemErrorsResponse
+	(*PerformanceHistoryRequest)(nil),   // 94: agent.PerformanceHistoryRequest
+	(*PerformanceSnapshot)(nil),         // 95: agent.PerformanceSnapshot
+	(*PerformanceHistoryResponse)(nil),  // 96: agent.PerformanceHistoryResponse
+	(*HealthDiagnosticRequest)(nil),     // 97: agent.HealthDiagnosticRequest
+	(*HealthIssue)(nil),                 // 98: agent.HealthIssue
+	(*HealthDiagnosticResponse)(nil),    // 99: agent.HealthDiagnosticResponse
+	(*VerifyToolchainRequest)(nil),      // 100: agent.VerifyToolchainRequest
+	(*ToolStatus)(nil),                  // 101: agent.ToolStatus
+	(*ModuleCapability)(nil),            // 102: agent.ModuleCapability
+	(*VerifyToolchainResponse)(nil),     // 103: agent.VerifyToolchainResponse
+	(*ShellInput)(nil),                  // 104: agent.ShellInput
+	(*ShellOutput)(nil),                 // 105: agent.ShellOutput
+	(*EventData)(nil),                   // 106: agent.EventData
+	(*SendEventRequest)(nil),            // 107: agent.SendEventRequest
+	(*SendEventResponse)(nil),           // 108: agent.SendEventResponse
+	(*SendEventBatchRequest)(nil),       // 109: agent.SendEventBatchRequest
+	(*SendEventBatchResponse)(nil),      // 110: agent.SendEventBatchResponse
+	(*WatcherConfig)(nil),               // 111: agent.WatcherConfig
+	(*RegisterWatcherRequest)(nil),      // 112: agent.RegisterWatcherRequest
+	(*RegisterWatcherResponse)(nil),     // 113: agent.RegisterWatcherResponse
+	(*ListWatchersRequest)(nil),         // 114: agent.ListWatchersRequest
+	(*ListWatchersResponse)(nil),        // 115: agent.ListWatchersResponse
+	(*GetWatcherRequest)(nil),           // 116: agent.GetWatcherRequest
+	(*GetWatcherResponse)(nil),          // 117: agent.GetWatcherResponse
+	(*RemoveWatcherRequest)(nil),        // 118: agent.RemoveWatcherRequest
+	(*RemoveWatcherResponse)(nil),       // 119: agent.RemoveWatcherResponse
+	(*ReloadConfigRequest)(nil),         // 120: agent.ReloadConfigRequest
+	(*GetConfigRequest)(nil),            // 121: agent.GetConfigRequest
+	(*AgentConfigResponse)(nil),         // 122: agent.AgentConfigResponse
+	(*ArtifactPeer)(nil),                // 123: agent.ArtifactPeer
+	(*AnnounceArtifactRequest)(nil),     // 124: agent.AnnounceArtifactRequest
+	(*AnnounceArtifactResponse)(nil),    // 125: agent.AnnounceArtifactResponse
+	(*LookupArtifactRequest)(nil),       // 126: agent.LookupArtifactRequest
+	(*LookupArtifactResponse)(nil),      // 127: agent.LookupArtifactResponse
+	(*FactChange)(nil),                  // 128: agent.FactChange
+	(*FactHistoryRequest)(nil),          // 129: agent.FactHistoryRequest
+	(*FactHistoryResponse)(nil),         // 130: agent.FactHistoryResponse
+	(*DrainMasterRequest)(nil),          // 131: agent.DrainMasterRequest
+	(*DrainMasterResponse)(nil),         // 132: agent.DrainMasterResponse
+	(*ResumeMasterRequest)(nil),         // 133: agent.ResumeMasterRequest
+	(*ResumeMasterResponse)(nil),        // 134: agent.ResumeMasterResponse
+	(*AcquireLockRequest)(nil),          // 135: agent.AcquireLockRequest
+	(*AcquireLockResponse)(nil),         // 136: agent.AcquireLockResponse
+	(*RenewLockRequest)(nil),            // 137: agent.RenewLockRequest
+	(*RenewLockResponse)(nil),           // 138: agent.RenewLockResponse
+	(*ReleaseLockRequest)(nil),          // 139: agent.ReleaseLockRequest
+	(*ReleaseLockResponse)(nil),         // 140: agent.ReleaseLockResponse
+	(*GetReleaseRequest)(nil),           // 141: agent.GetReleaseRequest
+	(*ReleaseAsset)(nil),                // 142: agent.ReleaseAsset
+	(*GetReleaseResponse)(nil),          // 143: agent.GetReleaseResponse
+	(*RebootAgentRequest)(nil),          // 144: agent.RebootAgentRequest
+	(*RebootAgentResponse)(nil),         // 145: agent.RebootAgentResponse
+	nil,                                 // 146: agent.MetricsData.CustomMetricsEntry
+	nil,                                 // 147: agent.EnvVarsResponse.VariablesEntry
+	nil,                                 // 148: agent.CreateGroupRequest.TagsEntry
+	nil,                                 // 149: agent.AgentGroup.TagsEntry
+	nil,                                 // 150: agent.AggregatedMetricsResponse.CustomMetricsEntry
+	nil,                                 // 151: agent.AgentEvent.MetadataEntry
+	nil,                                 // 152: agent.SystemError.ContextEntry
+	nil,                                 // 153: agent.HealthDiagnosticResponse.SummaryEntry
+	nil,                                 // 154: agent.EventData.DataEntry
 }
 var file_proto_agent_proto_depIdxs = []int32{
-	8,   // 0: agent.ListAgentsResponse.agents:type_name -> agent.AgentInfo
-	8,   // 1: agent.GetAgentInfoResponse.agent_info:type_name -> agent.AgentInfo
-	25,  // 2: agent.ProcessListResponse.processes:type_name -> agent.ProcessInfo
-	28,  // 3: agent.NetworkInfoResponse.interfaces:type_name -> agent.NetworkInterface
-	31,  // 4: agent.DiskInfoResponse.partitions:type_name -> agent.DiskPartition
-	104, // 5: agent.MetricsData.custom_metrics:type_name -> agent.MetricsData.CustomMetricsEntry
-	105, // 6: agent.EnvVarsResponse.variables:type_name -> agent.EnvVarsResponse.VariablesEntry
-	46,  // 7: agent.ModulesResponse.modules:type_name -> agent.ModuleInfo
-	106, // 8: agent.CreateGroupRequest.tags:type_name -> agent.CreateGroupRequest.TagsEntry
-	107, // 9: agent.AgentGroup.tags:type_name -> agent.AgentGroup.TagsEntry
-	55,  // 10: agent.ListGroupsResponse.groups:type_name -> agent.AgentGroup
-	62,  // 11: agent.MultipleAgentStatusResponse.statuses:type_name -> agent.AgentStatusInfo
-	108, // 12: agent.AggregatedMetricsResponse.custom_metrics:type_name -> agent.AggregatedMetricsResponse.CustomMetricsEntry
-	109, // 13: agent.AgentEvent.metadata:type_name -> agent.AgentEvent.MetadataEntry
-	31,  // 14: agent.DiskDetail.partitions:type_name -> agent.DiskPartition
-	28,  // 15: agent.NetworkDetail.interfaces:type_name -> agent.NetworkInterface
-	69,  // 16: agent.DetailedMetricsResponse.cpu:type_name -> agent.CPUDetail
-	70,  // 17: agent.DetailedMetricsResponse.memory:type_name -> agent.MemoryDetail
-	71,  // 18: agent.DetailedMetricsResponse.disk:type_name -> agent.DiskDetail
-	72,  // 19: agent.DetailedMetricsResponse.network:type_name -> agent.NetworkDetail
-	34,  // 20: agent.RecentLogsResponse.logs:type_name -> agent.LogEntry
-	77,  // 21: agent.ConnectionsResponse.connections:type_name -> agent.ConnectionInfo
-	110, // 22: agent.SystemError.context:type_name -> agent.SystemError.ContextEntry
-	80,  // 23: agent.SystemErrorsResponse.errors:type_name -> agent.SystemError
-	83,  // 24: agent.PerformanceHistoryResponse.snapshots:type_name -> agent.PerformanceSnapshot
-	83,  // 25: agent.PerformanceHistoryResponse.avg:type_name -> agent.PerformanceSnapshot
-	83,  // 26: agent.PerformanceHistoryResponse.min:type_name -> agent.PerformanceSnapshot
-	83,  // 27: agent.PerformanceHistoryResponse.max:type_name -> agent.PerformanceSnapshot
-	86,  // 28: agent.HealthDiagnosticResponse.issues:type_name -> agent.HealthIssue
-	111, // 29: agent.HealthDiagnosticResponse.summary:type_name -> agent.HealthDiagnosticResponse.SummaryEntry
-	112, // 30: agent.EventData.data:type_name -> agent.EventData.DataEntry
-	90,  // 31: agent.SendEventRequest.event:type_name -> agent.EventData
-	90,  // 32: agent.SendEventBatchRequest.events:type_name -> agent.EventData
-	95,  // 33: agent.RegisterWatcherRequest.config:type_name -> agent.WatcherConfig
-	95,  // 34: agent.ListWatchersResponse.watchers:type_name -> agent.WatcherConfig
-	95,  // 35: agent.GetWatcherResponse.watcher:type_name -> agent.WatcherConfig
-	4,   // 36: agent.Agent.ExecuteTask:input_type -> agent.ExecuteTaskRequest
-	16,  // 37: agent.Agent.RunCommand:input_type -> agent.RunCommandRequest
-	0,   // 38: agent.Agent.Shutdown:input_type -> agent.ShutdownRequest
-	2,   // 39: agent.Agent.UpdateAgent:input_type -> agent.UpdateAgentRequest
-	22,  // 40: agent.Agent.GetResourceUsage:input_type -> agent.ResourceUsageRequest
-	24,  // 41: agent.Agent.GetProcessList:input_type -> agent.ProcessListRequest
-	27,  // 42: agent.Agent.GetNetworkInfo:input_type -> agent.NetworkInfoRequest
-	30,  // 43: agent.Agent.GetDiskInfo:input_type -> agent.DiskInfoRequest
-	33,  // 44: agent.Agent.StreamLogs:input_type -> agent.StreamLogsRequest
-	35,  // 45: agent.Agent.StreamMetrics:input_type -> agent.StreamMetricsRequest
-	37,  // 46: agent.Agent.RestartService:input_type -> agent.RestartServiceRequest
-	39,  // 47: agent.Agent.GetEnvironmentVars:input_type -> agent.EnvVarsRequest
-	41,  // 48: agent.Agent.SetEnvironmentVar:input_type -> agent.SetEnvVarRequest
-	43,  // 49: agent.Agent.InstallModule:input_type -> agent.InstallModuleRequest
-	45,  // 50: agent.Agent.GetInstalledModules:input_type -> agent.ModulesRequest
-	68,  // 51: agent.Agent.GetDetailedMetrics:input_type -> agent.DetailedMetricsRequest
-	74,  // 52: agent.Agent.GetRecentLogs:input_type -> agent.RecentLogsRequest
-	76,  // 53: agent.Agent.GetActiveConnections:input_type -> agent.ConnectionsRequest
-	79,  // 54: agent.Agent.GetSystemErrors:input_type -> agent.SystemErrorsRequest
-	82,  // 55: agent.Agent.GetPerformanceHistory:input_type -> agent.PerformanceHistoryRequest
-	85,  // 56: agent.Agent.DiagnoseHealth:input_type -> agent.HealthDiagnosticRequest
-	88,  // 57: agent.Agent.InteractiveShell:input_type -> agent.ShellInput
-	96,  // 58: agent.Agent.RegisterWatcher:input_type -> agent.RegisterWatcherRequest
-	98,  // 59: agent.Agent.ListWatchers:input_type -> agent.ListWatchersRequest
-	100, // 60: agent.Agent.GetWatcher:input_type -> agent.GetWatcherRequest
-	102, // 61: agent.Agent.RemoveWatcher:input_type -> agent.RemoveWatcherRequest
-	6,   // 62: agent.AgentRegistry.RegisterAgent:input_type -> agent.RegisterAgentRequest
-	9,   // 63: agent.AgentRegistry.ListAgents:input_type -> agent.ListAgentsRequest
-	11,  // 64: agent.AgentRegistry.StopAgent:input_type -> agent.StopAgentRequest
-	13,  // 65: agent.AgentRegistry.UnregisterAgent:input_type -> agent.UnregisterAgentRequest
-	15,  // 66: agent.AgentRegistry.ExecuteCommand:input_type -> agent.ExecuteCommandRequest
-	18,  // 67: agent.AgentRegistry.Heartbeat:input_type -> agent.HeartbeatRequest
-	20,  // 68: agent.AgentRegistry.GetAgentInfo:input_type -> agent.GetAgentInfoRequest
-	48,  // 69: agent.AgentRegistry.CreateAgentGroup:input_type -> agent.CreateGroupRequest
-	50,  // 70: agent.AgentRegistry.AddAgentToGroup:input_type -> agent.AddToGroupRequest
-	52,  // 71: agent.AgentRegistry.RemoveAgentFromGroup:input_type -> agent.RemoveFromGroupRequest
-	54,  // 72: agent.AgentRegistry.ListAgentGroups:input_type -> agent.ListGroupsRequest
-	57,  // 73: agent.AgentRegistry.DeleteAgentGroup:input_type -> agent.DeleteGroupRequest
-	59,  // 74: agent.AgentRegistry.ExecuteOnMultipleAgents:input_type -> agent.BulkExecuteRequest
-	61,  // 75: agent.AgentRegistry.GetMultipleAgentStatus:input_type -> agent.MultipleAgentStatusRequest
-	64,  // 76: agent.AgentRegistry.GetAggregatedMetrics:input_type -> agent.AggregatedMetricsRequest
-	66,  // 77: agent.AgentRegistry.StreamAgentEvents:input_type -> agent.StreamEventsRequest
-	91,  // 78: agent.AgentRegistry.SendEvent:input_type -> agent.SendEventRequest
-	93,  // 79: agent.AgentRegistry.SendEventBatch:input_type -> agent.SendEventBatchRequest
-	5,   // 80: agent.Agent.ExecuteTask:output_type -> agent.ExecuteTaskResponse
-	17,  // 81: agent.Agent.RunCommand:output_type -> agent.StreamOutputResponse
-	1,   // 82: agent.Agent.Shutdown:output_type -> agent.ShutdownResponse
-	3,   // 83: agent.Agent.UpdateAgent:output_type -> agent.UpdateAgentResponse
-	23,  // 84: agent.Agent.GetResourceUsage:output_type -> agent.ResourceUsageResponse
-	26,  // 85: agent.Agent.GetProcessList:output_type -> agent.ProcessListResponse
-	29,  // 86: agent.Agent.GetNetworkInfo:output_type -> agent.NetworkInfoResponse
-	32,  // 87: agent.Agent.GetDiskInfo:output_type -> agent.DiskInfoResponse
-	34,  // 88: agent.Agent.StreamLogs:output_type -> agent.LogEntry
-	36,  // 89: agent.Agent.StreamMetrics:output_type -> agent.MetricsData
-	38,  // 90: agent.Agent.RestartService:output_type -> agent.RestartServiceResponse
-	40,  // 91: agent.Agent.GetEnvironmentVars:output_type -> agent.EnvVarsResponse
-	42,  // 92: agent.Agent.SetEnvironmentVar:output_type -> agent.SetEnvVarResponse
-	44,  // 93: agent.Agent.InstallModule:output_type -> agent.InstallModuleResponse
-	47,  // 94: agent.Agent.GetInstalledModules:output_type -> agent.ModulesResponse
-	73,  // 95: agent.Agent.GetDetailedMetrics:output_type -> agent.DetailedMetricsResponse
-	75,  // 96: agent.Agent.GetRecentLogs:output_type -> agent.RecentLogsResponse
-	78,  // 97: agent.Agent.GetActiveConnections:output_type -> agent.ConnectionsResponse
-	81,  // 98: agent.Agent.GetSystemErrors:output_type -> agent.SystemErrorsResponse
-	84,  // 99: agent.Agent.GetPerformanceHistory:output_type -> agent.PerformanceHistoryResponse
-	87,  // 100: agent.Agent.DiagnoseHealth:output_type -> agent.HealthDiagnosticResponse
-	89,  // 101: agent.Agent.InteractiveShell:output_type -> agent.ShellOutput
-	97,  // 102: agent.Agent.RegisterWatcher:output_type -> agent.RegisterWatcherResponse
-	99,  // 103: agent.Agent.ListWatchers:output_type -> agent.ListWatchersResponse
-	101, // 104: agent.Agent.GetWatcher:output_type -> agent.GetWatcherResponse
-	103, // 105: agent.Agent.RemoveWatcher:output_type -> agent.RemoveWatcherResponse
-	7,   // 106: agent.AgentRegistry.RegisterAgent:output_type -> agent.RegisterAgentResponse
-	10,  // 107: agent.AgentRegistry.ListAgents:output_type -> agent.ListAgentsResponse
-	12,  // 108: agent.AgentRegistry.StopAgent:output_type -> agent.StopAgentResponse
-	14,  // 109: agent.AgentRegistry.UnregisterAgent:output_type -> agent.UnregisterAgentResponse
-	17,  // 110: agent.AgentRegistry.ExecuteCommand:output_type -> agent.StreamOutputResponse
-	19,  // 111: agent.AgentRegistry.Heartbeat:output_type -> agent.HeartbeatResponse
-	21,  // 112: agent.AgentRegistry.GetAgentInfo:output_type -> agent.GetAgentInfoResponse
-	49,  // 113: agent.AgentRegistry.CreateAgentGroup:output_type -> agent.CreateGroupResponse
-	51,  // 114: agent.AgentRegistry.AddAgentToGroup:output_type -> agent.AddToGroupResponse
-	53,  // 115: agent.AgentRegistry.RemoveAgentFromGroup:output_type -> agent.RemoveFromGroupResponse
-	56,  // 116: agent.AgentRegistry.ListAgentGroups:output_type -> agent.ListGroupsResponse
-	58,  // 117: agent.AgentRegistry.DeleteAgentGroup:output_type -> agent.DeleteGroupResponse
-	60,  // 118: agent.AgentRegistry.ExecuteOnMultipleAgents:output_type -> agent.BulkExecuteResponse
-	63,  // 119: agent.AgentRegistry.GetMultipleAgentStatus:output_type -> agent.MultipleAgentStatusResponse
-	65,  // 120: agent.AgentRegistry.GetAggregatedMetrics:output_type -> agent.AggregatedMetricsResponse
-	67,  // 121: agent.AgentRegistry.StreamAgentEvents:output_type -> agent.AgentEvent
-	92,  // 122: agent.AgentRegistry.SendEvent:output_type -> agent.SendEventResponse
-	94,  // 123: agent.AgentRegistry.SendEventBatch:output_type -> agent.SendEventBatchResponse
-	80,  // [80:124] is the sub-list for method output_type
-	36,  // [36:80] is the sub-list for method input_type
-	36,  // [36:36] is the sub-list for extension type_name
-	36,  // [36:36] is the sub-list for extension extendee
-	0,   // [0:36] is the sub-list for field type_name
+	8,   // 0: agent.ExecuteTaskResponse.usage:type_name -> agent.TaskUsage
+	7,   // 1: agent.ExecuteTaskEvent.result:type_name -> agent.ExecuteTaskResponse
+	10,  // 2: agent.ExecuteTaskEvent.progress:type_name -> agent.TaskProgress
+	8,   // 3: agent.TaskRunResult.usage:type_name -> agent.TaskUsage
+	14,  // 4: agent.ExecuteTasksResponse.results:type_name -> agent.TaskRunResult
+	14,  // 5: agent.TaskOutcomeResponse.results:type_name -> agent.TaskRunResult
+	20,  // 6: agent.ListAgentsResponse.agents:type_name -> agent.AgentInfo
+	20,  // 7: agent.GetAgentInfoResponse.agent_info:type_name -> agent.AgentInfo
+	37,  // 8: agent.ProcessListResponse.processes:type_name -> agent.ProcessInfo
+	40,  // 9: agent.NetworkInfoResponse.interfaces:type_name -> agent.NetworkInterface
+	43,  // 10: agent.DiskInfoResponse.partitions:type_name -> agent.DiskPartition
+	146, // 11: agent.MetricsData.custom_metrics:type_name -> agent.MetricsData.CustomMetricsEntry
+	147, // 12: agent.EnvVarsResponse.variables:type_name -> agent.EnvVarsResponse.VariablesEntry
+	58,  // 13: agent.ModulesResponse.modules:type_name -> agent.ModuleInfo
+	148, // 14: agent.CreateGroupRequest.tags:type_name -> agent.CreateGroupRequest.TagsEntry
+	149, // 15: agent.AgentGroup.tags:type_name -> agent.AgentGroup.TagsEntry
+	67,  // 16: agent.ListGroupsResponse.groups:type_name -> agent.AgentGroup
+	74,  // 17: agent.MultipleAgentStatusResponse.statuses:type_name -> agent.AgentStatusInfo
+	150, // 18: agent.AggregatedMetricsResponse.custom_metrics:type_name -> agent.AggregatedMetricsResponse.CustomMetricsEntry
+	151, // 19: agent.AgentEvent.metadata:type_name -> agent.AgentEvent.MetadataEntry
+	43,  // 20: agent.DiskDetail.partitions:type_name -> agent.DiskPartition
+	40,  // 21: agent.NetworkDetail.interfaces:type_name -> agent.NetworkInterface
+	81,  // 22: agent.DetailedMetricsResponse.cpu:type_name -> agent.CPUDetail
+	82,  // 23: agent.DetailedMetricsResponse.memory:type_name -> agent.MemoryDetail
+	83,  // 24: agent.DetailedMetricsResponse.disk:type_name -> agent.DiskDetail
+	84,  // 25: agent.DetailedMetricsResponse.network:type_name -> agent.NetworkDetail
+	46,  // 26: agent.RecentLogsResponse.logs:type_name -> agent.LogEntry
+	89,  // 27: agent.ConnectionsResponse.connections:type_name -> agent.ConnectionInfo
+	152, // 28: agent.SystemError.context:type_name -> agent.SystemError.ContextEntry
+	92,  // 29: agent.SystemErrorsResponse.errors:type_name -> agent.SystemError
+	95,  // 30: agent.PerformanceHistoryResponse.snapshots:type_name -> agent.PerformanceSnapshot
+	95,  // 31: agent.PerformanceHistoryResponse.avg:type_name -> agent.PerformanceSnapshot
+	95,  // 32: agent.PerformanceHistoryResponse.min:type_name -> agent.PerformanceSnapshot
+	95,  // 33: agent.PerformanceHistoryResponse.max:type_name -> agent.PerformanceSnapshot
+	98,  // 34: agent.HealthDiagnosticResponse.issues:type_name -> agent.HealthIssue
+	153, // 35: agent.HealthDiagnosticResponse.summary:type_name -> agent.HealthDiagnosticResponse.SummaryEntry
+	101, // 36: agent.ModuleCapability.tools:type_name -> agent.ToolStatus
+	102, // 37: agent.VerifyToolchainResponse.modules:type_name -> agent.ModuleCapability
+	154, // 38: agent.EventData.data:type_name -> agent.EventData.DataEntry
+	106, // 39: agent.SendEventRequest.event:type_name -> agent.EventData
+	106, // 40: agent.SendEventBatchRequest.events:type_name -> agent.EventData
+	111, // 41: agent.RegisterWatcherRequest.config:type_name -> agent.WatcherConfig
+	111, // 42: agent.ListWatchersResponse.watchers:type_name -> agent.WatcherConfig
+	111, // 43: agent.GetWatcherResponse.watcher:type_name -> agent.WatcherConfig
+	123, // 44: agent.LookupArtifactResponse.peers:type_name -> agent.ArtifactPeer
+	128, // 45: agent.FactHistoryResponse.changes:type_name -> agent.FactChange
+	142, // 46: agent.GetReleaseResponse.assets:type_name -> agent.ReleaseAsset
+	6,   // 47: agent.Agent.ExecuteTask:input_type -> agent.ExecuteTaskRequest
+	6,   // 48: agent.Agent.ExecuteTaskStream:input_type -> agent.ExecuteTaskRequest
+	11,  // 49: agent.Agent.ExecuteTasks:input_type -> agent.ExecuteTasksRequest
+	11,  // 50: agent.Agent.CheckTasks:input_type -> agent.ExecuteTasksRequest
+	16,  // 51: agent.Agent.GetTaskOutcome:input_type -> agent.TaskOutcomeRequest
+	12,  // 52: agent.Agent.GetSudoKey:input_type -> agent.SudoKeyRequest
+	28,  // 53: agent.Agent.RunCommand:input_type -> agent.RunCommandRequest
+	0,   // 54: agent.Agent.Shutdown:input_type -> agent.ShutdownRequest
+	2,   // 55: agent.Agent.Reboot:input_type -> agent.RebootRequest
+	4,   // 56: agent.Agent.UpdateAgent:input_type -> agent.UpdateAgentRequest
+	34,  // 57: agent.Agent.GetResourceUsage:input_type -> agent.ResourceUsageRequest
+	36,  // 58: agent.Agent.GetProcessList:input_type -> agent.ProcessListRequest
+	39,  // 59: agent.Agent.GetNetworkInfo:input_type -> agent.NetworkInfoRequest
+	42,  // 60: agent.Agent.GetDiskInfo:input_type -> agent.DiskInfoRequest
+	45,  // 61: agent.Agent.StreamLogs:input_type -> agent.StreamLogsRequest
+	47,  // 62: agent.Agent.StreamMetrics:input_type -> agent.StreamMetricsRequest
+	49,  // 63: agent.Agent.RestartService:input_type -> agent.RestartServiceRequest
+	51,  // 64: agent.Agent.GetEnvironmentVars:input_type -> agent.EnvVarsRequest
+	53,  // 65: agent.Agent.SetEnvironmentVar:input_type -> agent.SetEnvVarRequest
+	55,  // 66: agent.Agent.InstallModule:input_type -> agent.InstallModuleRequest
+	57,  // 67: agent.Agent.GetInstalledModules:input_type -> agent.ModulesRequest
+	80,  // 68: agent.Agent.GetDetailedMetrics:input_type -> agent.DetailedMetricsRequest
+	86,  // 69: agent.Agent.GetRecentLogs:input_type -> agent.RecentLogsRequest
+	86,  // 70: agent.Agent.StreamRecentLogs:input_type -> agent.RecentLogsRequest
+	88,  // 71: agent.Agent.GetActiveConnections:input_type -> agent.ConnectionsRequest
+	91,  // 72: agent.Agent.GetSystemErrors:input_type -> agent.SystemErrorsRequest
+	94,  // 73: agent.Agent.GetPerformanceHistory:input_type -> agent.PerformanceHistoryRequest
+	97,  // 74: agent.Agent.DiagnoseHealth:input_type -> agent.HealthDiagnosticRequest
+	100, // 75: agent.Agent.VerifyToolchain:input_type -> agent.VerifyToolchainRequest
+	104, // 76: agent.Agent.InteractiveShell:input_type -> agent.ShellInput
+	112, // 77: agent.Agent.RegisterWatcher:input_type -> agent.RegisterWatcherRequest
+	114, // 78: agent.Agent.ListWatchers:input_type -> agent.ListWatchersRequest
+	116, // 79: agent.Agent.GetWatcher:input_type -> agent.GetWatcherRequest
+	118, // 80: agent.Agent.RemoveWatcher:input_type -> agent.RemoveWatcherRequest
+	120, // 81: agent.Agent.ReloadConfig:input_type -> agent.ReloadConfigRequest
+	121, // 82: agent.Agent.GetConfig:input_type -> agent.GetConfigRequest
+	18,  // 83: agent.AgentRegistry.RegisterAgent:input_type -> agent.RegisterAgentRequest
+	21,  // 84: agent.AgentRegistry.ListAgents:input_type -> agent.ListAgentsRequest
+	23,  // 85: agent.AgentRegistry.StopAgent:input_type -> agent.StopAgentRequest
+	25,  // 86: agent.AgentRegistry.UnregisterAgent:input_type -> agent.UnregisterAgentRequest
+	27,  // 87: agent.AgentRegistry.ExecuteCommand:input_type -> agent.ExecuteCommandRequest
+	30,  // 88: agent.AgentRegistry.Heartbeat:input_type -> agent.HeartbeatRequest
+	32,  // 89: agent.AgentRegistry.GetAgentInfo:input_type -> agent.GetAgentInfoRequest
+	60,  // 90: agent.AgentRegistry.CreateAgentGroup:input_type -> agent.CreateGroupRequest
+	62,  // 91: agent.AgentRegistry.AddAgentToGroup:input_type -> agent.AddToGroupRequest
+	64,  // 92: agent.AgentRegistry.RemoveAgentFromGroup:input_type -> agent.RemoveFromGroupRequest
+	66,  // 93: agent.AgentRegistry.ListAgentGroups:input_type -> agent.ListGroupsRequest
+	69,  // 94: agent.AgentRegistry.DeleteAgentGroup:input_type -> agent.DeleteGroupRequest
+	71,  // 95: agent.AgentRegistry.ExecuteOnMultipleAgents:input_type -> agent.BulkExecuteRequest
+	73,  // 96: agent.AgentRegistry.GetMultipleAgentStatus:input_type -> agent.MultipleAgentStatusRequest
+	76,  // 97: agent.AgentRegistry.GetAggregatedMetrics:input_type -> agent.AggregatedMetricsRequest
+	78,  // 98: agent.AgentRegistry.StreamAgentEvents:input_type -> agent.StreamEventsRequest
+	107, // 99: agent.AgentRegistry.SendEvent:input_type -> agent.SendEventRequest
+	109, // 100: agent.AgentRegistry.SendEventBatch:input_type -> agent.SendEventBatchRequest
+	124, // 101: agent.AgentRegistry.AnnounceArtifact:input_type -> agent.AnnounceArtifactRequest
+	126, // 102: agent.AgentRegistry.LookupArtifact:input_type -> agent.LookupArtifactRequest
+	129, // 103: agent.AgentRegistry.GetFactHistory:input_type -> agent.FactHistoryRequest
+	131, // 104: agent.AgentRegistry.DrainMaster:input_type -> agent.DrainMasterRequest
+	133, // 105: agent.AgentRegistry.ResumeMaster:input_type -> agent.ResumeMasterRequest
+	135, // 106: agent.AgentRegistry.AcquireLock:input_type -> agent.AcquireLockRequest
+	137, // 107: agent.AgentRegistry.RenewLock:input_type -> agent.RenewLockRequest
+	139, // 108: agent.AgentRegistry.ReleaseLock:input_type -> agent.ReleaseLockRequest
+	141, // 109: agent.AgentRegistry.GetRelease:input_type -> agent.GetReleaseRequest
+	144, // 110: agent.AgentRegistry.RebootAgent:input_type -> agent.RebootAgentRequest
+	7,   // 111: agent.Agent.ExecuteTask:output_type -> agent.ExecuteTaskResponse
+	9,   // 112: agent.Agent.ExecuteTaskStream:output_type -> agent.ExecuteTaskEvent
+	15,  // 113: agent.Agent.ExecuteTasks:output_type -> agent.ExecuteTasksResponse
+	15,  // 114: agent.Agent.CheckTasks:output_type -> agent.ExecuteTasksResponse
+	17,  // 115: agent.Agent.GetTaskOutcome:output_type -> agent.TaskOutcomeResponse
+	13,  // 116: agent.Agent.GetSudoKey:output_type -> agent.SudoKeyResponse
+	29,  // 117: agent.Agent.RunCommand:output_type -> agent.StreamOutputResponse
+	1,   // 118: agent.Agent.Shutdown:output_type -> agent.ShutdownResponse
+	3,   // 119: agent.Agent.Reboot:output_type -> agent.RebootResponse
+	5,   // 120: agent.Agent.UpdateAgent:output_type -> agent.UpdateAgentResponse
+	35,  // 121: agent.Agent.GetResourceUsage:output_type -> agent.ResourceUsageResponse
+	38,  // 122: agent.Agent.GetProcessList:output_type -> agent.ProcessListResponse
+	41,  // 123: agent.Agent.GetNetworkInfo:output_type -> agent.NetworkInfoResponse
+	44,  // 124: agent.Agent.GetDiskInfo:output_type -> agent.DiskInfoResponse
+	46,  // 125: agent.Agent.StreamLogs:output_type -> agent.LogEntry
+	48,  // 126: agent.Agent.StreamMetrics:output_type -> agent.MetricsData
+	50,  // 127: agent.Agent.RestartService:output_type -> agent.RestartServiceResponse
+	52,  // 128: agent.Agent.GetEnvironmentVars:output_type -> agent.EnvVarsResponse
+	54,  // 129: agent.Agent.SetEnvironmentVar:output_type -> agent.SetEnvVarResponse
+	56,  // 130: agent.Agent.InstallModule:output_type -> agent.InstallModuleResponse
+	59,  // 131: agent.Agent.GetInstalledModules:output_type -> agent.ModulesResponse
+	85,  // 132: agent.Agent.GetDetailedMetrics:output_type -> agent.DetailedMetricsResponse
+	87,  // 133: agent.Agent.GetRecentLogs:output_type -> agent.RecentLogsResponse
+	87,  // 134: agent.Agent.StreamRecentLogs:output_type -> agent.RecentLogsResponse
+	90,  // 135: agent.Agent.GetActiveConnections:output_type -> agent.ConnectionsResponse
+	93,  // 136: agent.Agent.GetSystemErrors:output_type -> agent.SystemErrorsResponse
+	96,  // 137: agent.Agent.GetPerformanceHistory:output_type -> agent.PerformanceHistoryResponse
+	99,  // 138: agent.Agent.DiagnoseHealth:output_type -> agent.HealthDiagnosticResponse
+	103, // 139: agent.Agent.VerifyToolchain:output_type -> agent.VerifyToolchainResponse
+	105, // 140: agent.Agent.InteractiveShell:output_type -> agent.ShellOutput
+	113, // 141: agent.Agent.RegisterWatcher:output_type -> agent.RegisterWatcherResponse
+	115, // 142: agent.Agent.ListWatchers:output_type -> agent.ListWatchersResponse
+	117, // 143: agent.Agent.GetWatcher:output_type -> agent.GetWatcherResponse
+	119, // 144: agent.Agent.RemoveWatcher:output_type -> agent.RemoveWatcherResponse
+	122, // 145: agent.Agent.ReloadConfig:output_type -> agent.AgentConfigResponse
+	122, // 146: agent.Agent.GetConfig:output_type -> agent.AgentConfigResponse
+	19,  // 147: agent.AgentRegistry.RegisterAgent:output_type -> agent.RegisterAgentResponse
+	22,  // 148: agent.AgentRegistry.ListAgents:output_type -> agent.ListAgentsResponse
+	24,  // 149: agent.AgentRegistry.StopAgent:output_type -> agent.StopAgentResponse
+	26,  // 150: agent.AgentRegistry.UnregisterAgent:output_type -> agent.UnregisterAgentResponse
+	29,  // 151: agent.AgentRegistry.ExecuteCommand:output_type -> agent.StreamOutputResponse
+	31,  // 152: agent.AgentRegistry.Heartbeat:output_type -> agent.HeartbeatResponse
+	33,  // 153: agent.AgentRegistry.GetAgentInfo:output_type -> agent.GetAgentInfoResponse
+	61,  // 154: agent.AgentRegistry.CreateAgentGroup:output_type -> agent.CreateGroupResponse
+	63,  // 155: agent.AgentRegistry.AddAgentToGroup:output_type -> agent.AddToGroupResponse
+	65,  // 156: agent.AgentRegistry.RemoveAgentFromGroup:output_type -> agent.RemoveFromGroupResponse
+	68,  // 157: agent.AgentRegistry.ListAgentGroups:output_type -> agent.ListGroupsResponse
+	70,  // 158: agent.AgentRegistry.DeleteAgentGroup:output_type -> agent.DeleteGroupResponse
+	72,  // 159: agent.AgentRegistry.ExecuteOnMultipleAgents:output_type -> agent.BulkExecuteResponse
+	75,  // 160: agent.AgentRegistry.GetMultipleAgentStatus:output_type -> agent.MultipleAgentStatusResponse
+	77,  // 161: agent.AgentRegistry.GetAggregatedMetrics:output_type -> agent.AggregatedMetricsResponse
+	79,  // 162: agent.AgentRegistry.StreamAgentEvents:output_type -> agent.AgentEvent
+	108, // 163: agent.AgentRegistry.SendEvent:output_type -> agent.SendEventResponse
+	110, // 164: agent.AgentRegistry.SendEventBatch:output_type -> agent.SendEventBatchResponse
+	125, // 165: agent.AgentRegistry.AnnounceArtifact:output_type -> agent.AnnounceArtifactResponse
+	127, // 166: agent.AgentRegistry.LookupArtifact:output_type -> agent.LookupArtifactResponse
+	130, // 167: agent.AgentRegistry.GetFactHistory:output_type -> agent.FactHistoryResponse
+	132, // 168: agent.AgentRegistry.DrainMaster:output_type -> agent.DrainMasterResponse
+	134, // 169: agent.AgentRegistry.ResumeMaster:output_type -> agent.ResumeMasterResponse
+	136, // 170: agent.AgentRegistry.AcquireLock:output_type -> agent.AcquireLockResponse
+	138, // 171: agent.AgentRegistry.RenewLock:output_type -> agent.RenewLockResponse
+	140, // 172: agent.AgentRegistry.ReleaseLock:output_type -> agent.ReleaseLockResponse
+	143, // 173: agent.AgentRegistry.GetRelease:output_type -> agent.GetReleaseResponse
+	145, // 174: agent.AgentRegistry.RebootAgent:output_type -> agent.RebootAgentResponse
+	111, // [111:175] is the sub-list for method output_type
+	47,  // [47:111] is the sub-list for method input_type
+	47,  // [47:47] is the sub-list for extension type_name
+	47,  // [47:47] is the sub-list for extension extendee
+	0,   // [0:47] is the sub-list for field type_name
 }
 
 func init() { file_proto_agent_proto_init() }
@@ -7677,7 +10737,7 @@ func file_proto_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_agent_proto_rawDesc), len(file_proto_agent_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   113,
+			NumMessages:   155,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
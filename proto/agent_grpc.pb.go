@@ -20,8 +20,14 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	Agent_ExecuteTask_FullMethodName           = "/agent.Agent/ExecuteTask"
+	Agent_ExecuteTaskStream_FullMethodName     = "/agent.Agent/ExecuteTaskStream"
+	Agent_ExecuteTasks_FullMethodName          = "/agent.Agent/ExecuteTasks"
+	Agent_CheckTasks_FullMethodName            = "/agent.Agent/CheckTasks"
+	Agent_GetTaskOutcome_FullMethodName        = "/agent.Agent/GetTaskOutcome"
+	Agent_GetSudoKey_FullMethodName            = "/agent.Agent/GetSudoKey"
 	Agent_RunCommand_FullMethodName            = "/agent.Agent/RunCommand"
 	Agent_Shutdown_FullMethodName              = "/agent.Agent/Shutdown"
+	Agent_Reboot_FullMethodName                = "/agent.Agent/Reboot"
 	Agent_UpdateAgent_FullMethodName           = "/agent.Agent/UpdateAgent"
 	Agent_GetResourceUsage_FullMethodName      = "/agent.Agent/GetResourceUsage"
 	Agent_GetProcessList_FullMethodName        = "/agent.Agent/GetProcessList"
@@ -36,15 +42,19 @@ const (
 	Agent_GetInstalledModules_FullMethodName   = "/agent.Agent/GetInstalledModules"
 	Agent_GetDetailedMetrics_FullMethodName    = "/agent.Agent/GetDetailedMetrics"
 	Agent_GetRecentLogs_FullMethodName         = "/agent.Agent/GetRecentLogs"
+	Agent_StreamRecentLogs_FullMethodName      = "/agent.Agent/StreamRecentLogs"
 	Agent_GetActiveConnections_FullMethodName  = "/agent.Agent/GetActiveConnections"
 	Agent_GetSystemErrors_FullMethodName       = "/agent.Agent/GetSystemErrors"
 	Agent_GetPerformanceHistory_FullMethodName = "/agent.Agent/GetPerformanceHistory"
 	Agent_DiagnoseHealth_FullMethodName        = "/agent.Agent/DiagnoseHealth"
+	Agent_VerifyToolchain_FullMethodName       = "/agent.Agent/VerifyToolchain"
 	Agent_InteractiveShell_FullMethodName      = "/agent.Agent/InteractiveShell"
 	Agent_RegisterWatcher_FullMethodName       = "/agent.Agent/RegisterWatcher"
 	Agent_ListWatchers_FullMethodName          = "/agent.Agent/ListWatchers"
 	Agent_GetWatcher_FullMethodName            = "/agent.Agent/GetWatcher"
 	Agent_RemoveWatcher_FullMethodName         = "/agent.Agent/RemoveWatcher"
+	Agent_ReloadConfig_FullMethodName          = "/agent.Agent/ReloadConfig"
+	Agent_GetConfig_FullMethodName             = "/agent.Agent/GetConfig"
 )
 
 // AgentClient is the client API for Agent service.
@@ -52,8 +62,26 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AgentClient interface {
 	ExecuteTask(ctx context.Context, in *ExecuteTaskRequest, opts ...grpc.CallOption) (*ExecuteTaskResponse, error)
+	// ExecuteTaskStream runs a task like ExecuteTask, streaming output that
+	// modules write while the task runs before the final result
+	ExecuteTaskStream(ctx context.Context, in *ExecuteTaskRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteTaskEvent], error)
+	// ExecuteTasks runs several tasks of a group with a single workspace
+	// transfer, returning a result per task
+	ExecuteTasks(ctx context.Context, in *ExecuteTasksRequest, opts ...grpc.CallOption) (*ExecuteTasksResponse, error)
+	// CheckTasks runs tasks in check mode: their module calls report what
+	// they would change, with the state they found, in changes_json and
+	// leave the system untouched. No workspace is returned.
+	CheckTasks(ctx context.Context, in *ExecuteTasksRequest, opts ...grpc.CallOption) (*ExecuteTasksResponse, error)
+	// GetTaskOutcome tells what became of the task request sent with an
+	// idempotency key, for runs reconciled after their runner stopped
+	GetTaskOutcome(ctx context.Context, in *TaskOutcomeRequest, opts ...grpc.CallOption) (*TaskOutcomeResponse, error)
+	// Key runners seal sudo passwords to, created when the agent starts
+	GetSudoKey(ctx context.Context, in *SudoKeyRequest, opts ...grpc.CallOption) (*SudoKeyResponse, error)
 	RunCommand(ctx context.Context, in *RunCommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamOutputResponse], error)
 	Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error)
+	// Reboot reboots the agent's host, after the tasks running on it finish
+	// when drain is set
+	Reboot(ctx context.Context, in *RebootRequest, opts ...grpc.CallOption) (*RebootResponse, error)
 	UpdateAgent(ctx context.Context, in *UpdateAgentRequest, opts ...grpc.CallOption) (*UpdateAgentResponse, error)
 	// Advanced Management RPCs
 	GetResourceUsage(ctx context.Context, in *ResourceUsageRequest, opts ...grpc.CallOption) (*ResourceUsageResponse, error)
@@ -70,10 +98,16 @@ type AgentClient interface {
 	// Enhanced Troubleshooting RPCs
 	GetDetailedMetrics(ctx context.Context, in *DetailedMetricsRequest, opts ...grpc.CallOption) (*DetailedMetricsResponse, error)
 	GetRecentLogs(ctx context.Context, in *RecentLogsRequest, opts ...grpc.CallOption) (*RecentLogsResponse, error)
+	// Same as GetRecentLogs, sending the pages one after another until there
+	// are no more, or limit entries were sent
+	StreamRecentLogs(ctx context.Context, in *RecentLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecentLogsResponse], error)
 	GetActiveConnections(ctx context.Context, in *ConnectionsRequest, opts ...grpc.CallOption) (*ConnectionsResponse, error)
 	GetSystemErrors(ctx context.Context, in *SystemErrorsRequest, opts ...grpc.CallOption) (*SystemErrorsResponse, error)
 	GetPerformanceHistory(ctx context.Context, in *PerformanceHistoryRequest, opts ...grpc.CallOption) (*PerformanceHistoryResponse, error)
 	DiagnoseHealth(ctx context.Context, in *HealthDiagnosticRequest, opts ...grpc.CallOption) (*HealthDiagnosticResponse, error)
+	// Checks that the tools modules drive (apt, systemctl, docker, git...)
+	// are installed and work, to validate workflows before they run
+	VerifyToolchain(ctx context.Context, in *VerifyToolchainRequest, opts ...grpc.CallOption) (*VerifyToolchainResponse, error)
 	// Interactive Shell RPC
 	InteractiveShell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ShellInput, ShellOutput], error)
 	// Watcher Management RPCs
@@ -81,6 +115,12 @@ type AgentClient interface {
 	ListWatchers(ctx context.Context, in *ListWatchersRequest, opts ...grpc.CallOption) (*ListWatchersResponse, error)
 	GetWatcher(ctx context.Context, in *GetWatcherRequest, opts ...grpc.CallOption) (*GetWatcherResponse, error)
 	RemoveWatcher(ctx context.Context, in *RemoveWatcherRequest, opts ...grpc.CallOption) (*RemoveWatcherResponse, error)
+	// Agent Configuration RPCs
+	// ReloadConfig re-reads the agent's config file and applies it, as
+	// SIGHUP does
+	ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*AgentConfigResponse, error)
+	// GetConfig returns the settings the agent runs with
+	GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*AgentConfigResponse, error)
 }
 
 type agentClient struct {
@@ -101,9 +141,68 @@ func (c *agentClient) ExecuteTask(ctx context.Context, in *ExecuteTaskRequest, o
 	return out, nil
 }
 
+func (c *agentClient) ExecuteTaskStream(ctx context.Context, in *ExecuteTaskRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecuteTaskEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[0], Agent_ExecuteTaskStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExecuteTaskRequest, ExecuteTaskEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_ExecuteTaskStreamClient = grpc.ServerStreamingClient[ExecuteTaskEvent]
+
+func (c *agentClient) ExecuteTasks(ctx context.Context, in *ExecuteTasksRequest, opts ...grpc.CallOption) (*ExecuteTasksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteTasksResponse)
+	err := c.cc.Invoke(ctx, Agent_ExecuteTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) CheckTasks(ctx context.Context, in *ExecuteTasksRequest, opts ...grpc.CallOption) (*ExecuteTasksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteTasksResponse)
+	err := c.cc.Invoke(ctx, Agent_CheckTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) GetTaskOutcome(ctx context.Context, in *TaskOutcomeRequest, opts ...grpc.CallOption) (*TaskOutcomeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TaskOutcomeResponse)
+	err := c.cc.Invoke(ctx, Agent_GetTaskOutcome_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) GetSudoKey(ctx context.Context, in *SudoKeyRequest, opts ...grpc.CallOption) (*SudoKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SudoKeyResponse)
+	err := c.cc.Invoke(ctx, Agent_GetSudoKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentClient) RunCommand(ctx context.Context, in *RunCommandRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamOutputResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[0], Agent_RunCommand_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[1], Agent_RunCommand_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +229,16 @@ func (c *agentClient) Shutdown(ctx context.Context, in *ShutdownRequest, opts ..
 	return out, nil
 }
 
+func (c *agentClient) Reboot(ctx context.Context, in *RebootRequest, opts ...grpc.CallOption) (*RebootResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RebootResponse)
+	err := c.cc.Invoke(ctx, Agent_Reboot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentClient) UpdateAgent(ctx context.Context, in *UpdateAgentRequest, opts ...grpc.CallOption) (*UpdateAgentResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(UpdateAgentResponse)
@@ -182,7 +291,7 @@ func (c *agentClient) GetDiskInfo(ctx context.Context, in *DiskInfoRequest, opts
 
 func (c *agentClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogEntry], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[1], Agent_StreamLogs_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[2], Agent_StreamLogs_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -201,7 +310,7 @@ type Agent_StreamLogsClient = grpc.ServerStreamingClient[LogEntry]
 
 func (c *agentClient) StreamMetrics(ctx context.Context, in *StreamMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MetricsData], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[2], Agent_StreamMetrics_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[3], Agent_StreamMetrics_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -288,6 +397,25 @@ func (c *agentClient) GetRecentLogs(ctx context.Context, in *RecentLogsRequest,
 	return out, nil
 }
 
+func (c *agentClient) StreamRecentLogs(ctx context.Context, in *RecentLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecentLogsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[4], Agent_StreamRecentLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RecentLogsRequest, RecentLogsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_StreamRecentLogsClient = grpc.ServerStreamingClient[RecentLogsResponse]
+
 func (c *agentClient) GetActiveConnections(ctx context.Context, in *ConnectionsRequest, opts ...grpc.CallOption) (*ConnectionsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ConnectionsResponse)
@@ -328,9 +456,19 @@ func (c *agentClient) DiagnoseHealth(ctx context.Context, in *HealthDiagnosticRe
 	return out, nil
 }
 
+func (c *agentClient) VerifyToolchain(ctx context.Context, in *VerifyToolchainRequest, opts ...grpc.CallOption) (*VerifyToolchainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VerifyToolchainResponse)
+	err := c.cc.Invoke(ctx, Agent_VerifyToolchain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentClient) InteractiveShell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ShellInput, ShellOutput], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[3], Agent_InteractiveShell_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[5], Agent_InteractiveShell_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -381,13 +519,51 @@ func (c *agentClient) RemoveWatcher(ctx context.Context, in *RemoveWatcherReques
 	return out, nil
 }
 
+func (c *agentClient) ReloadConfig(ctx context.Context, in *ReloadConfigRequest, opts ...grpc.CallOption) (*AgentConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AgentConfigResponse)
+	err := c.cc.Invoke(ctx, Agent_ReloadConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) GetConfig(ctx context.Context, in *GetConfigRequest, opts ...grpc.CallOption) (*AgentConfigResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AgentConfigResponse)
+	err := c.cc.Invoke(ctx, Agent_GetConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServer is the server API for Agent service.
 // All implementations must embed UnimplementedAgentServer
 // for forward compatibility.
 type AgentServer interface {
 	ExecuteTask(context.Context, *ExecuteTaskRequest) (*ExecuteTaskResponse, error)
+	// ExecuteTaskStream runs a task like ExecuteTask, streaming output that
+	// modules write while the task runs before the final result
+	ExecuteTaskStream(*ExecuteTaskRequest, grpc.ServerStreamingServer[ExecuteTaskEvent]) error
+	// ExecuteTasks runs several tasks of a group with a single workspace
+	// transfer, returning a result per task
+	ExecuteTasks(context.Context, *ExecuteTasksRequest) (*ExecuteTasksResponse, error)
+	// CheckTasks runs tasks in check mode: their module calls report what
+	// they would change, with the state they found, in changes_json and
+	// leave the system untouched. No workspace is returned.
+	CheckTasks(context.Context, *ExecuteTasksRequest) (*ExecuteTasksResponse, error)
+	// GetTaskOutcome tells what became of the task request sent with an
+	// idempotency key, for runs reconciled after their runner stopped
+	GetTaskOutcome(context.Context, *TaskOutcomeRequest) (*TaskOutcomeResponse, error)
+	// Key runners seal sudo passwords to, created when the agent starts
+	GetSudoKey(context.Context, *SudoKeyRequest) (*SudoKeyResponse, error)
 	RunCommand(*RunCommandRequest, grpc.ServerStreamingServer[StreamOutputResponse]) error
 	Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error)
+	// Reboot reboots the agent's host, after the tasks running on it finish
+	// when drain is set
+	Reboot(context.Context, *RebootRequest) (*RebootResponse, error)
 	UpdateAgent(context.Context, *UpdateAgentRequest) (*UpdateAgentResponse, error)
 	// Advanced Management RPCs
 	GetResourceUsage(context.Context, *ResourceUsageRequest) (*ResourceUsageResponse, error)
@@ -404,10 +580,16 @@ type AgentServer interface {
 	// Enhanced Troubleshooting RPCs
 	GetDetailedMetrics(context.Context, *DetailedMetricsRequest) (*DetailedMetricsResponse, error)
 	GetRecentLogs(context.Context, *RecentLogsRequest) (*RecentLogsResponse, error)
+	// Same as GetRecentLogs, sending the pages one after another until there
+	// are no more, or limit entries were sent
+	StreamRecentLogs(*RecentLogsRequest, grpc.ServerStreamingServer[RecentLogsResponse]) error
 	GetActiveConnections(context.Context, *ConnectionsRequest) (*ConnectionsResponse, error)
 	GetSystemErrors(context.Context, *SystemErrorsRequest) (*SystemErrorsResponse, error)
 	GetPerformanceHistory(context.Context, *PerformanceHistoryRequest) (*PerformanceHistoryResponse, error)
 	DiagnoseHealth(context.Context, *HealthDiagnosticRequest) (*HealthDiagnosticResponse, error)
+	// Checks that the tools modules drive (apt, systemctl, docker, git...)
+	// are installed and work, to validate workflows before they run
+	VerifyToolchain(context.Context, *VerifyToolchainRequest) (*VerifyToolchainResponse, error)
 	// Interactive Shell RPC
 	InteractiveShell(grpc.BidiStreamingServer[ShellInput, ShellOutput]) error
 	// Watcher Management RPCs
@@ -415,6 +597,12 @@ type AgentServer interface {
 	ListWatchers(context.Context, *ListWatchersRequest) (*ListWatchersResponse, error)
 	GetWatcher(context.Context, *GetWatcherRequest) (*GetWatcherResponse, error)
 	RemoveWatcher(context.Context, *RemoveWatcherRequest) (*RemoveWatcherResponse, error)
+	// Agent Configuration RPCs
+	// ReloadConfig re-reads the agent's config file and applies it, as
+	// SIGHUP does
+	ReloadConfig(context.Context, *ReloadConfigRequest) (*AgentConfigResponse, error)
+	// GetConfig returns the settings the agent runs with
+	GetConfig(context.Context, *GetConfigRequest) (*AgentConfigResponse, error)
 	mustEmbedUnimplementedAgentServer()
 }
 
@@ -428,12 +616,30 @@ type UnimplementedAgentServer struct{}
 func (UnimplementedAgentServer) ExecuteTask(context.Context, *ExecuteTaskRequest) (*ExecuteTaskResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExecuteTask not implemented")
 }
+func (UnimplementedAgentServer) ExecuteTaskStream(*ExecuteTaskRequest, grpc.ServerStreamingServer[ExecuteTaskEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteTaskStream not implemented")
+}
+func (UnimplementedAgentServer) ExecuteTasks(context.Context, *ExecuteTasksRequest) (*ExecuteTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecuteTasks not implemented")
+}
+func (UnimplementedAgentServer) CheckTasks(context.Context, *ExecuteTasksRequest) (*ExecuteTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckTasks not implemented")
+}
+func (UnimplementedAgentServer) GetTaskOutcome(context.Context, *TaskOutcomeRequest) (*TaskOutcomeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTaskOutcome not implemented")
+}
+func (UnimplementedAgentServer) GetSudoKey(context.Context, *SudoKeyRequest) (*SudoKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSudoKey not implemented")
+}
 func (UnimplementedAgentServer) RunCommand(*RunCommandRequest, grpc.ServerStreamingServer[StreamOutputResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method RunCommand not implemented")
 }
 func (UnimplementedAgentServer) Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Shutdown not implemented")
 }
+func (UnimplementedAgentServer) Reboot(context.Context, *RebootRequest) (*RebootResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reboot not implemented")
+}
 func (UnimplementedAgentServer) UpdateAgent(context.Context, *UpdateAgentRequest) (*UpdateAgentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateAgent not implemented")
 }
@@ -476,6 +682,9 @@ func (UnimplementedAgentServer) GetDetailedMetrics(context.Context, *DetailedMet
 func (UnimplementedAgentServer) GetRecentLogs(context.Context, *RecentLogsRequest) (*RecentLogsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRecentLogs not implemented")
 }
+func (UnimplementedAgentServer) StreamRecentLogs(*RecentLogsRequest, grpc.ServerStreamingServer[RecentLogsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRecentLogs not implemented")
+}
 func (UnimplementedAgentServer) GetActiveConnections(context.Context, *ConnectionsRequest) (*ConnectionsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetActiveConnections not implemented")
 }
@@ -488,6 +697,9 @@ func (UnimplementedAgentServer) GetPerformanceHistory(context.Context, *Performa
 func (UnimplementedAgentServer) DiagnoseHealth(context.Context, *HealthDiagnosticRequest) (*HealthDiagnosticResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DiagnoseHealth not implemented")
 }
+func (UnimplementedAgentServer) VerifyToolchain(context.Context, *VerifyToolchainRequest) (*VerifyToolchainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyToolchain not implemented")
+}
 func (UnimplementedAgentServer) InteractiveShell(grpc.BidiStreamingServer[ShellInput, ShellOutput]) error {
 	return status.Errorf(codes.Unimplemented, "method InteractiveShell not implemented")
 }
@@ -503,6 +715,12 @@ func (UnimplementedAgentServer) GetWatcher(context.Context, *GetWatcherRequest)
 func (UnimplementedAgentServer) RemoveWatcher(context.Context, *RemoveWatcherRequest) (*RemoveWatcherResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveWatcher not implemented")
 }
+func (UnimplementedAgentServer) ReloadConfig(context.Context, *ReloadConfigRequest) (*AgentConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadConfig not implemented")
+}
+func (UnimplementedAgentServer) GetConfig(context.Context, *GetConfigRequest) (*AgentConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfig not implemented")
+}
 func (UnimplementedAgentServer) mustEmbedUnimplementedAgentServer() {}
 func (UnimplementedAgentServer) testEmbeddedByValue()               {}
 
@@ -542,6 +760,89 @@ func _Agent_ExecuteTask_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_ExecuteTaskStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExecuteTaskRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).ExecuteTaskStream(m, &grpc.GenericServerStream[ExecuteTaskRequest, ExecuteTaskEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_ExecuteTaskStreamServer = grpc.ServerStreamingServer[ExecuteTaskEvent]
+
+func _Agent_ExecuteTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ExecuteTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_ExecuteTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ExecuteTasks(ctx, req.(*ExecuteTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_CheckTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).CheckTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_CheckTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).CheckTasks(ctx, req.(*ExecuteTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_GetTaskOutcome_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskOutcomeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).GetTaskOutcome(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_GetTaskOutcome_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).GetTaskOutcome(ctx, req.(*TaskOutcomeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_GetSudoKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SudoKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).GetSudoKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_GetSudoKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).GetSudoKey(ctx, req.(*SudoKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Agent_RunCommand_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(RunCommandRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -571,6 +872,24 @@ func _Agent_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_Reboot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebootRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).Reboot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_Reboot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).Reboot(ctx, req.(*RebootRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Agent_UpdateAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpdateAgentRequest)
 	if err := dec(in); err != nil {
@@ -809,6 +1128,17 @@ func _Agent_GetRecentLogs_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_StreamRecentLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RecentLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).StreamRecentLogs(m, &grpc.GenericServerStream[RecentLogsRequest, RecentLogsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_StreamRecentLogsServer = grpc.ServerStreamingServer[RecentLogsResponse]
+
 func _Agent_GetActiveConnections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ConnectionsRequest)
 	if err := dec(in); err != nil {
@@ -881,6 +1211,24 @@ func _Agent_DiagnoseHealth_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_VerifyToolchain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyToolchainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).VerifyToolchain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_VerifyToolchain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).VerifyToolchain(ctx, req.(*VerifyToolchainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Agent_InteractiveShell_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(AgentServer).InteractiveShell(&grpc.GenericServerStream[ShellInput, ShellOutput]{ServerStream: stream})
 }
@@ -960,6 +1308,42 @@ func _Agent_RemoveWatcher_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_ReloadConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).ReloadConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_ReloadConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).ReloadConfig(ctx, req.(*ReloadConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_GetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_GetConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).GetConfig(ctx, req.(*GetConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Agent_ServiceDesc is the grpc.ServiceDesc for Agent service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -971,10 +1355,30 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ExecuteTask",
 			Handler:    _Agent_ExecuteTask_Handler,
 		},
+		{
+			MethodName: "ExecuteTasks",
+			Handler:    _Agent_ExecuteTasks_Handler,
+		},
+		{
+			MethodName: "CheckTasks",
+			Handler:    _Agent_CheckTasks_Handler,
+		},
+		{
+			MethodName: "GetTaskOutcome",
+			Handler:    _Agent_GetTaskOutcome_Handler,
+		},
+		{
+			MethodName: "GetSudoKey",
+			Handler:    _Agent_GetSudoKey_Handler,
+		},
 		{
 			MethodName: "Shutdown",
 			Handler:    _Agent_Shutdown_Handler,
 		},
+		{
+			MethodName: "Reboot",
+			Handler:    _Agent_Reboot_Handler,
+		},
 		{
 			MethodName: "UpdateAgent",
 			Handler:    _Agent_UpdateAgent_Handler,
@@ -1039,6 +1443,10 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DiagnoseHealth",
 			Handler:    _Agent_DiagnoseHealth_Handler,
 		},
+		{
+			MethodName: "VerifyToolchain",
+			Handler:    _Agent_VerifyToolchain_Handler,
+		},
 		{
 			MethodName: "RegisterWatcher",
 			Handler:    _Agent_RegisterWatcher_Handler,
@@ -1055,8 +1463,21 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveWatcher",
 			Handler:    _Agent_RemoveWatcher_Handler,
 		},
+		{
+			MethodName: "ReloadConfig",
+			Handler:    _Agent_ReloadConfig_Handler,
+		},
+		{
+			MethodName: "GetConfig",
+			Handler:    _Agent_GetConfig_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExecuteTaskStream",
+			Handler:       _Agent_ExecuteTaskStream_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "RunCommand",
 			Handler:       _Agent_RunCommand_Handler,
@@ -1072,6 +1493,11 @@ var Agent_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Agent_StreamMetrics_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "StreamRecentLogs",
+			Handler:       _Agent_StreamRecentLogs_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "InteractiveShell",
 			Handler:       _Agent_InteractiveShell_Handler,
@@ -1101,6 +1527,16 @@ const (
 	AgentRegistry_StreamAgentEvents_FullMethodName       = "/agent.AgentRegistry/StreamAgentEvents"
 	AgentRegistry_SendEvent_FullMethodName               = "/agent.AgentRegistry/SendEvent"
 	AgentRegistry_SendEventBatch_FullMethodName          = "/agent.AgentRegistry/SendEventBatch"
+	AgentRegistry_AnnounceArtifact_FullMethodName        = "/agent.AgentRegistry/AnnounceArtifact"
+	AgentRegistry_LookupArtifact_FullMethodName          = "/agent.AgentRegistry/LookupArtifact"
+	AgentRegistry_GetFactHistory_FullMethodName          = "/agent.AgentRegistry/GetFactHistory"
+	AgentRegistry_DrainMaster_FullMethodName             = "/agent.AgentRegistry/DrainMaster"
+	AgentRegistry_ResumeMaster_FullMethodName            = "/agent.AgentRegistry/ResumeMaster"
+	AgentRegistry_AcquireLock_FullMethodName             = "/agent.AgentRegistry/AcquireLock"
+	AgentRegistry_RenewLock_FullMethodName               = "/agent.AgentRegistry/RenewLock"
+	AgentRegistry_ReleaseLock_FullMethodName             = "/agent.AgentRegistry/ReleaseLock"
+	AgentRegistry_GetRelease_FullMethodName              = "/agent.AgentRegistry/GetRelease"
+	AgentRegistry_RebootAgent_FullMethodName             = "/agent.AgentRegistry/RebootAgent"
 )
 
 // AgentRegistryClient is the client API for AgentRegistry service.
@@ -1129,6 +1565,24 @@ type AgentRegistryClient interface {
 	// Event Reporting - Agents send events to master
 	SendEvent(ctx context.Context, in *SendEventRequest, opts ...grpc.CallOption) (*SendEventResponse, error)
 	SendEventBatch(ctx context.Context, in *SendEventBatchRequest, opts ...grpc.CallOption) (*SendEventBatchResponse, error)
+	// Artifact Cache - Master-coordinated index of agent-local caches
+	AnnounceArtifact(ctx context.Context, in *AnnounceArtifactRequest, opts ...grpc.CallOption) (*AnnounceArtifactResponse, error)
+	LookupArtifact(ctx context.Context, in *LookupArtifactRequest, opts ...grpc.CallOption) (*LookupArtifactResponse, error)
+	// Fact History - Changes in agent facts recorded by the master
+	GetFactHistory(ctx context.Context, in *FactHistoryRequest, opts ...grpc.CallOption) (*FactHistoryResponse, error)
+	// Upgrades - Drain the master before its binary is swapped
+	DrainMaster(ctx context.Context, in *DrainMasterRequest, opts ...grpc.CallOption) (*DrainMasterResponse, error)
+	ResumeMaster(ctx context.Context, in *ResumeMasterRequest, opts ...grpc.CallOption) (*ResumeMasterResponse, error)
+	// Locks - Named leases that serialize work across runs, see lock.acquire
+	AcquireLock(ctx context.Context, in *AcquireLockRequest, opts ...grpc.CallOption) (*AcquireLockResponse, error)
+	RenewLock(ctx context.Context, in *RenewLockRequest, opts ...grpc.CallOption) (*RenewLockResponse, error)
+	ReleaseLock(ctx context.Context, in *ReleaseLockRequest, opts ...grpc.CallOption) (*ReleaseLockResponse, error)
+	// Releases - The master's cached index of sloth-runner releases, asked
+	// by agents instead of the GitHub API
+	GetRelease(ctx context.Context, in *GetReleaseRequest, opts ...grpc.CallOption) (*GetReleaseResponse, error)
+	// Reboots - Reboot an agent's host and mark it offline until it is back,
+	// see host.reboot
+	RebootAgent(ctx context.Context, in *RebootAgentRequest, opts ...grpc.CallOption) (*RebootAgentResponse, error)
 }
 
 type agentRegistryClient struct {
@@ -1346,6 +1800,106 @@ func (c *agentRegistryClient) SendEventBatch(ctx context.Context, in *SendEventB
 	return out, nil
 }
 
+func (c *agentRegistryClient) AnnounceArtifact(ctx context.Context, in *AnnounceArtifactRequest, opts ...grpc.CallOption) (*AnnounceArtifactResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnnounceArtifactResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_AnnounceArtifact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) LookupArtifact(ctx context.Context, in *LookupArtifactRequest, opts ...grpc.CallOption) (*LookupArtifactResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupArtifactResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_LookupArtifact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) GetFactHistory(ctx context.Context, in *FactHistoryRequest, opts ...grpc.CallOption) (*FactHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FactHistoryResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_GetFactHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) DrainMaster(ctx context.Context, in *DrainMasterRequest, opts ...grpc.CallOption) (*DrainMasterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainMasterResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_DrainMaster_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) ResumeMaster(ctx context.Context, in *ResumeMasterRequest, opts ...grpc.CallOption) (*ResumeMasterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResumeMasterResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_ResumeMaster_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) AcquireLock(ctx context.Context, in *AcquireLockRequest, opts ...grpc.CallOption) (*AcquireLockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AcquireLockResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_AcquireLock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) RenewLock(ctx context.Context, in *RenewLockRequest, opts ...grpc.CallOption) (*RenewLockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenewLockResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_RenewLock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) ReleaseLock(ctx context.Context, in *ReleaseLockRequest, opts ...grpc.CallOption) (*ReleaseLockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleaseLockResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_ReleaseLock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) GetRelease(ctx context.Context, in *GetReleaseRequest, opts ...grpc.CallOption) (*GetReleaseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetReleaseResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_GetRelease_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryClient) RebootAgent(ctx context.Context, in *RebootAgentRequest, opts ...grpc.CallOption) (*RebootAgentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RebootAgentResponse)
+	err := c.cc.Invoke(ctx, AgentRegistry_RebootAgent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentRegistryServer is the server API for AgentRegistry service.
 // All implementations must embed UnimplementedAgentRegistryServer
 // for forward compatibility.
@@ -1372,6 +1926,24 @@ type AgentRegistryServer interface {
 	// Event Reporting - Agents send events to master
 	SendEvent(context.Context, *SendEventRequest) (*SendEventResponse, error)
 	SendEventBatch(context.Context, *SendEventBatchRequest) (*SendEventBatchResponse, error)
+	// Artifact Cache - Master-coordinated index of agent-local caches
+	AnnounceArtifact(context.Context, *AnnounceArtifactRequest) (*AnnounceArtifactResponse, error)
+	LookupArtifact(context.Context, *LookupArtifactRequest) (*LookupArtifactResponse, error)
+	// Fact History - Changes in agent facts recorded by the master
+	GetFactHistory(context.Context, *FactHistoryRequest) (*FactHistoryResponse, error)
+	// Upgrades - Drain the master before its binary is swapped
+	DrainMaster(context.Context, *DrainMasterRequest) (*DrainMasterResponse, error)
+	ResumeMaster(context.Context, *ResumeMasterRequest) (*ResumeMasterResponse, error)
+	// Locks - Named leases that serialize work across runs, see lock.acquire
+	AcquireLock(context.Context, *AcquireLockRequest) (*AcquireLockResponse, error)
+	RenewLock(context.Context, *RenewLockRequest) (*RenewLockResponse, error)
+	ReleaseLock(context.Context, *ReleaseLockRequest) (*ReleaseLockResponse, error)
+	// Releases - The master's cached index of sloth-runner releases, asked
+	// by agents instead of the GitHub API
+	GetRelease(context.Context, *GetReleaseRequest) (*GetReleaseResponse, error)
+	// Reboots - Reboot an agent's host and mark it offline until it is back,
+	// see host.reboot
+	RebootAgent(context.Context, *RebootAgentRequest) (*RebootAgentResponse, error)
 	mustEmbedUnimplementedAgentRegistryServer()
 }
 
@@ -1436,6 +2008,36 @@ func (UnimplementedAgentRegistryServer) SendEvent(context.Context, *SendEventReq
 func (UnimplementedAgentRegistryServer) SendEventBatch(context.Context, *SendEventBatchRequest) (*SendEventBatchResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendEventBatch not implemented")
 }
+func (UnimplementedAgentRegistryServer) AnnounceArtifact(context.Context, *AnnounceArtifactRequest) (*AnnounceArtifactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnnounceArtifact not implemented")
+}
+func (UnimplementedAgentRegistryServer) LookupArtifact(context.Context, *LookupArtifactRequest) (*LookupArtifactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupArtifact not implemented")
+}
+func (UnimplementedAgentRegistryServer) GetFactHistory(context.Context, *FactHistoryRequest) (*FactHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFactHistory not implemented")
+}
+func (UnimplementedAgentRegistryServer) DrainMaster(context.Context, *DrainMasterRequest) (*DrainMasterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainMaster not implemented")
+}
+func (UnimplementedAgentRegistryServer) ResumeMaster(context.Context, *ResumeMasterRequest) (*ResumeMasterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeMaster not implemented")
+}
+func (UnimplementedAgentRegistryServer) AcquireLock(context.Context, *AcquireLockRequest) (*AcquireLockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcquireLock not implemented")
+}
+func (UnimplementedAgentRegistryServer) RenewLock(context.Context, *RenewLockRequest) (*RenewLockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewLock not implemented")
+}
+func (UnimplementedAgentRegistryServer) ReleaseLock(context.Context, *ReleaseLockRequest) (*ReleaseLockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseLock not implemented")
+}
+func (UnimplementedAgentRegistryServer) GetRelease(context.Context, *GetReleaseRequest) (*GetReleaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRelease not implemented")
+}
+func (UnimplementedAgentRegistryServer) RebootAgent(context.Context, *RebootAgentRequest) (*RebootAgentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RebootAgent not implemented")
+}
 func (UnimplementedAgentRegistryServer) mustEmbedUnimplementedAgentRegistryServer() {}
 func (UnimplementedAgentRegistryServer) testEmbeddedByValue()                       {}
 
@@ -1760,6 +2362,186 @@ func _AgentRegistry_SendEventBatch_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentRegistry_AnnounceArtifact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnnounceArtifactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).AnnounceArtifact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_AnnounceArtifact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).AnnounceArtifact(ctx, req.(*AnnounceArtifactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_LookupArtifact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupArtifactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).LookupArtifact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_LookupArtifact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).LookupArtifact(ctx, req.(*LookupArtifactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_GetFactHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FactHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).GetFactHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_GetFactHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).GetFactHistory(ctx, req.(*FactHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_DrainMaster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainMasterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).DrainMaster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_DrainMaster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).DrainMaster(ctx, req.(*DrainMasterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_ResumeMaster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeMasterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).ResumeMaster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_ResumeMaster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).ResumeMaster(ctx, req.(*ResumeMasterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_AcquireLock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireLockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).AcquireLock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_AcquireLock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).AcquireLock(ctx, req.(*AcquireLockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_RenewLock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewLockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).RenewLock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_RenewLock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).RenewLock(ctx, req.(*RenewLockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_ReleaseLock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseLockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).ReleaseLock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_ReleaseLock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).ReleaseLock(ctx, req.(*ReleaseLockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_GetRelease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReleaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).GetRelease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_GetRelease_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).GetRelease(ctx, req.(*GetReleaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistry_RebootAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebootAgentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServer).RebootAgent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistry_RebootAgent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServer).RebootAgent(ctx, req.(*RebootAgentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AgentRegistry_ServiceDesc is the grpc.ServiceDesc for AgentRegistry service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -1827,6 +2609,46 @@ var AgentRegistry_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendEventBatch",
 			Handler:    _AgentRegistry_SendEventBatch_Handler,
 		},
+		{
+			MethodName: "AnnounceArtifact",
+			Handler:    _AgentRegistry_AnnounceArtifact_Handler,
+		},
+		{
+			MethodName: "LookupArtifact",
+			Handler:    _AgentRegistry_LookupArtifact_Handler,
+		},
+		{
+			MethodName: "GetFactHistory",
+			Handler:    _AgentRegistry_GetFactHistory_Handler,
+		},
+		{
+			MethodName: "DrainMaster",
+			Handler:    _AgentRegistry_DrainMaster_Handler,
+		},
+		{
+			MethodName: "ResumeMaster",
+			Handler:    _AgentRegistry_ResumeMaster_Handler,
+		},
+		{
+			MethodName: "AcquireLock",
+			Handler:    _AgentRegistry_AcquireLock_Handler,
+		},
+		{
+			MethodName: "RenewLock",
+			Handler:    _AgentRegistry_RenewLock_Handler,
+		},
+		{
+			MethodName: "ReleaseLock",
+			Handler:    _AgentRegistry_ReleaseLock_Handler,
+		},
+		{
+			MethodName: "GetRelease",
+			Handler:    _AgentRegistry_GetRelease_Handler,
+		},
+		{
+			MethodName: "RebootAgent",
+			Handler:    _AgentRegistry_RebootAgent_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{